@@ -1,6 +1,6 @@
 module github.com/OpenDataEnsemble/ode/synkronus-cli
 
-go 1.23.0
+go 1.24.0
 
 toolchain go1.24.2
 
@@ -8,6 +8,8 @@ require (
 	github.com/fatih/color v1.14.1
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/google/uuid v1.6.0
+	github.com/jmespath/go-jmespath v0.4.0
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.8.0
 	github.com/spf13/viper v1.18.2
 	github.com/yeqown/go-qrcode/v2 v2.2.5
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -60,20 +61,16 @@ func Login(username, password string) (*TokenResponse, error) {
 		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
 
-	// Print the raw response for debugging
-	fmt.Printf("DEBUG - Raw API response: %s\n", string(body))
-
 	// Parse response
 	var tokenResp TokenResponse
 	if err := json.Unmarshal(body, &tokenResp); err != nil {
 		return nil, fmt.Errorf("error parsing login response: %w\nResponse body: %s", err, string(body))
 	}
 
-	// Save token to viper config
-	viper.Set("auth.token", tokenResp.Token)
-	viper.Set("auth.refresh_token", tokenResp.RefreshToken)
-	viper.Set("auth.expires_at", tokenResp.ExpiresAt)
-	viper.WriteConfig()
+	// Save the encrypted tokens to the config file
+	if err := saveTokens(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to save tokens: %w", err)
+	}
 
 	return &tokenResp, nil
 }
@@ -82,7 +79,10 @@ func Login(username, password string) (*TokenResponse, error) {
 func RefreshToken() (*TokenResponse, error) {
 	apiURL := viper.GetString("api.url")
 	refreshURL := fmt.Sprintf("%s/auth/refresh", apiURL)
-	refreshToken := viper.GetString("auth.refresh_token")
+	refreshToken, err := storedToken(refreshTokenConfigKey)
+	if err != nil {
+		return nil, err
+	}
 
 	// Prepare refresh request
 	refreshData := map[string]string{
@@ -118,24 +118,37 @@ func RefreshToken() (*TokenResponse, error) {
 		return nil, fmt.Errorf("error parsing refresh response: %w\nResponse body: %s", err, string(body))
 	}
 
-	// Save token to viper config
-	viper.Set("auth.token", tokenResp.Token)
-	viper.Set("auth.refresh_token", tokenResp.RefreshToken)
-	viper.Set("auth.expires_at", tokenResp.ExpiresAt)
-	viper.WriteConfig()
+	// Save the encrypted tokens to the config file
+	if err := saveTokens(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to save tokens: %w", err)
+	}
 
 	return &tokenResp, nil
 }
 
+// APIKeyEnv is the environment variable read for an API key; it takes precedence over api.key
+// in the config file
+const APIKeyEnv = "SYNK_API_KEY"
+
+// GetAPIKey returns the API key to authenticate with instead of the stored tokens, if one is set
+func GetAPIKey() string {
+	if key := os.Getenv(APIKeyEnv); key != "" {
+		return key
+	}
+	return viper.GetString("api.key")
+}
+
 // GetToken returns the current token, refreshing it if necessary
 func GetToken() (string, error) {
-	token := viper.GetString("auth.token")
-	expiresAt := viper.GetInt64("auth.expires_at")
+	token, err := storedToken(tokenConfigKey)
+	if err != nil {
+		return "", err
+	}
+	expiresAt := viper.GetInt64(expiresAtConfigKey)
 
 	// If token is empty or about to expire, try to refresh it
 	if token == "" || time.Now().Unix() > expiresAt-60 {
-		refreshToken := viper.GetString("auth.refresh_token")
-		if refreshToken == "" {
+		if viper.GetString(refreshTokenConfigKey) == "" {
 			return "", fmt.Errorf("no valid token available, please login first")
 		}
 
@@ -146,12 +159,33 @@ func GetToken() (string, error) {
 		return tokenResp.Token, nil
 	}
 
+	// Encrypt tokens left in plaintext by older versions
+	if !IsEncrypted(viper.GetString(tokenConfigKey)) {
+		refreshToken, _ := storedToken(refreshTokenConfigKey)
+		if err := saveTokens(&TokenResponse{Token: token, RefreshToken: refreshToken, ExpiresAt: expiresAt}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encrypt stored tokens: %v\n", err)
+		}
+	}
+
 	return token, nil
 }
 
 // GetUserInfo extracts user information from the JWT token
 func GetUserInfo() (*Claims, error) {
-	tokenString := viper.GetString("auth.token")
+	return parseStoredToken(tokenConfigKey)
+}
+
+// GetRefreshTokenInfo extracts the claims, including the expiry, of the refresh token
+func GetRefreshTokenInfo() (*Claims, error) {
+	return parseStoredToken(refreshTokenConfigKey)
+}
+
+// parseStoredToken decrypts the token stored under configKey and parses its claims unverified
+func parseStoredToken(configKey string) (*Claims, error) {
+	tokenString, err := storedToken(configKey)
+	if err != nil {
+		return nil, err
+	}
 	if tokenString == "" {
 		return nil, fmt.Errorf("no token available, please login first")
 	}
@@ -169,10 +203,40 @@ func GetUserInfo() (*Claims, error) {
 	return nil, fmt.Errorf("invalid token claims")
 }
 
-// Logout clears the authentication tokens
+// Logout ends the session of the stored refresh token on the server and clears the
+// authentication tokens. The tokens are cleared even when the server cannot be reached.
 func Logout() error {
-	viper.Set("auth.token", "")
-	viper.Set("auth.refresh_token", "")
-	viper.Set("auth.expires_at", 0)
+	if refreshToken, err := storedToken(refreshTokenConfigKey); err == nil && refreshToken != "" {
+		if err := endSession(refreshToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to end the session on the server: %v\n", err)
+		}
+	}
+
+	viper.Set(tokenConfigKey, "")
+	viper.Set(refreshTokenConfigKey, "")
+	viper.Set(expiresAtConfigKey, 0)
 	return viper.WriteConfig()
 }
+
+// endSession revokes the session of refreshToken. Servers that predate sessions have no
+// logout endpoint and nothing to revoke.
+func endSession(refreshToken string) error {
+	logoutURL := fmt.Sprintf("%s/auth/logout", viper.GetString("api.url"))
+	jsonData, err := json.Marshal(map[string]string{"refreshToken": refreshToken})
+	if err != nil {
+		return fmt.Errorf("error marshaling logout data: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(logoutURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("logout request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("logout failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringService is the service name the token key is stored under in the OS keyring
+const keyringService = "synkronus-cli"
+
+// keyringAccount is the account name the token key is stored under in the OS keyring
+const keyringAccount = "token-key"
+
+// errNoKeyring is returned when no OS keyring is available on this system
+var errNoKeyring = errors.New("no OS keyring available")
+
+// keyringGet reads a secret from the OS keyring: the login keychain on macOS and the Secret
+// Service (GNOME Keyring, KWallet) through secret-tool on Linux. Secrets never appear in command
+// lines, where other users could see them.
+func keyringGet() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+	default:
+		return "", errNoKeyring
+	}
+	if cmd.Err != nil {
+		return "", errNoKeyring
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to read from OS keyring: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// keyringSet stores a secret in the OS keyring, replacing any previous one
+func keyringSet(secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// Interactive mode reads the command from stdin, keeping the secret out of the process list
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyringService, keyringAccount, secret))
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label=Synkronus CLI token key", "service", keyringService, "account", keyringAccount)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return errNoKeyring
+	}
+	if cmd.Err != nil {
+		return errNoKeyring
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write to OS keyring: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
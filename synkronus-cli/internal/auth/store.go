@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// Config keys of the stored tokens and of the key protecting them
+const (
+	tokenConfigKey        = "auth.token"
+	refreshTokenConfigKey = "auth.refresh_token"
+	expiresAtConfigKey    = "auth.expires_at"
+	keySourceConfigKey    = "auth.key_source"
+	saltConfigKey         = "auth.salt"
+)
+
+// Where the key that encrypts stored tokens comes from
+const (
+	KeySourceKeyring    = "keyring"
+	KeySourcePassphrase = "passphrase"
+)
+
+// PassphraseEnv is the environment variable read for the token passphrase before prompting
+const PassphraseEnv = "SYNK_PASSPHRASE"
+
+// encryptedPrefix marks encrypted values in the config file
+const encryptedPrefix = "enc:v1:"
+
+// pbkdf2Iterations is the work factor for deriving a key from the passphrase
+const pbkdf2Iterations = 600000
+
+// ErrDecrypt is returned when a stored token cannot be decrypted with the available key
+var ErrDecrypt = errors.New("failed to decrypt stored token")
+
+// tokenKey caches the key for the rest of the command, so the passphrase is asked for once
+var tokenKey []byte
+
+// IsEncrypted reports whether a config value is an encrypted token
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encrypt seals plaintext with AES-256-GCM, binding it to the config key it is stored under
+func encrypt(key []byte, configKey, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(configKey))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value produced by encrypt for the same config key
+func decrypt(key []byte, configKey, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%w: value too short", ErrDecrypt)
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(configKey))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+// deriveKey derives a token key from a passphrase
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+}
+
+// loadKey returns the key that encrypts stored tokens. Without a configured key and with create
+// set, it generates one and keeps it in the OS keyring, or derives one from a new passphrase when
+// there is no usable keyring.
+func loadKey(create bool) ([]byte, error) {
+	if tokenKey != nil {
+		return tokenKey, nil
+	}
+
+	switch source := viper.GetString(keySourceConfigKey); source {
+	case KeySourceKeyring:
+		secret, err := keyringGet()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token key, please login again: %w", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("the token key in the OS keyring is invalid, please login again")
+		}
+		tokenKey = key
+
+	case KeySourcePassphrase:
+		salt, err := base64.StdEncoding.DecodeString(viper.GetString(saltConfigKey))
+		if err != nil || len(salt) == 0 {
+			return nil, errors.New("the token passphrase salt is missing from the config, please login again")
+		}
+		passphrase, err := readPassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		if tokenKey, err = deriveKey(passphrase, salt); err != nil {
+			return nil, err
+		}
+
+	case "":
+		if !create {
+			return nil, errors.New("no token key configured, please login again")
+		}
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := keyringSet(base64.StdEncoding.EncodeToString(key)); err == nil {
+			viper.Set(keySourceConfigKey, KeySourceKeyring)
+			tokenKey = key
+			break
+		}
+
+		// Fall back to a passphrase when there is no keyring, as on Windows or headless Linux
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		passphrase, err := readPassphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		if tokenKey, err = deriveKey(passphrase, salt); err != nil {
+			return nil, err
+		}
+		viper.Set(keySourceConfigKey, KeySourcePassphrase)
+		viper.Set(saltConfigKey, base64.StdEncoding.EncodeToString(salt))
+
+	default:
+		return nil, fmt.Errorf("unknown token key source %q in config", source)
+	}
+	return tokenKey, nil
+}
+
+// readPassphrase returns the token passphrase from the environment or asks for it, twice when
+// setting a new one
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("cannot ask for the token passphrase without a terminal; set %s", PassphraseEnv)
+	}
+
+	prompt := "Token passphrase: "
+	if confirm {
+		fmt.Fprintln(os.Stderr, "No OS keyring available; choose a passphrase to encrypt stored tokens.")
+		prompt = "New token passphrase: "
+	}
+	passphrase, err := promptPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the token passphrase cannot be empty")
+	}
+	if confirm {
+		again, err := promptPassword("Repeat token passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// promptPassword asks for a secret on the terminal without echoing it
+func promptPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %w", err)
+	}
+	return string(secret), nil
+}
+
+// saveTokens encrypts the tokens and writes them to the config file
+func saveTokens(tokenResp *TokenResponse) error {
+	key, err := loadKey(true)
+	if err != nil {
+		return err
+	}
+	token, err := encrypt(key, tokenConfigKey, tokenResp.Token)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	refreshToken, err := encrypt(key, refreshTokenConfigKey, tokenResp.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
+	viper.Set(tokenConfigKey, token)
+	viper.Set(refreshTokenConfigKey, refreshToken)
+	viper.Set(expiresAtConfigKey, tokenResp.ExpiresAt)
+	return viper.WriteConfig()
+}
+
+// storedToken returns the decrypted token stored under configKey. Plaintext tokens written by
+// older versions are returned as they are.
+func storedToken(configKey string) (string, error) {
+	value := viper.GetString(configKey)
+	if value == "" || !IsEncrypted(value) {
+		return value, nil
+	}
+	key, err := loadKey(false)
+	if err != nil {
+		return "", err
+	}
+	token, err := decrypt(key, configKey, value)
+	if err != nil {
+		if viper.GetString(keySourceConfigKey) == KeySourcePassphrase {
+			tokenKey = nil
+			return "", fmt.Errorf("%w; is the passphrase correct?", err)
+		}
+		return "", err
+	}
+	return token, nil
+}
+
+// TokenStorage describes how the tokens in the config file are stored
+func TokenStorage() string {
+	token := viper.GetString(tokenConfigKey)
+	switch {
+	case token == "" && viper.GetString(refreshTokenConfigKey) == "":
+		return "none"
+	case !IsEncrypted(token):
+		return "plaintext"
+	case viper.GetString(keySourceConfigKey) == KeySourceKeyring:
+		return "encrypted, key in the OS keyring"
+	default:
+		return "encrypted with a passphrase"
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := deriveKey("correct horse battery staple", []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+
+	sealed, err := encrypt(key, tokenConfigKey, "eyJhbGciOi.token")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if !IsEncrypted(sealed) || strings.Contains(sealed, "eyJhbGciOi") {
+		t.Fatalf("token is not encrypted: %s", sealed)
+	}
+	if got, err := decrypt(key, tokenConfigKey, sealed); err != nil || got != "eyJhbGciOi.token" {
+		t.Fatalf("unexpected decryption %q: %v", got, err)
+	}
+
+	// A wrong key or a value moved to another config key does not decrypt
+	other, _ := deriveKey("wrong", []byte("0123456789abcdef"))
+	if _, err := decrypt(other, tokenConfigKey, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a wrong key, got %v", err)
+	}
+	if _, err := decrypt(key, refreshTokenConfigKey, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for another config key, got %v", err)
+	}
+}
+
+func TestStoredTokenWithPassphrase(t *testing.T) {
+	t.Cleanup(func() { viper.Reset(); tokenKey = nil })
+	viper.Reset()
+	tokenKey = nil
+	t.Setenv(PassphraseEnv, "correct horse battery staple")
+	t.Setenv("PATH", "") // no keyring tools
+
+	// Plaintext tokens from older versions are still read
+	viper.Set(tokenConfigKey, "plain-token")
+	if got, err := storedToken(tokenConfigKey); err != nil || got != "plain-token" {
+		t.Fatalf("unexpected plaintext token %q: %v", got, err)
+	}
+	if TokenStorage() != "plaintext" {
+		t.Fatalf("unexpected storage %q", TokenStorage())
+	}
+
+	// Without a keyring the key comes from the passphrase
+	if _, err := loadKey(true); err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if viper.GetString(keySourceConfigKey) != KeySourcePassphrase || viper.GetString(saltConfigKey) == "" {
+		t.Fatalf("expected a passphrase key with a salt")
+	}
+	sealed, err := encrypt(tokenKey, tokenConfigKey, "secret-token")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	viper.Set(tokenConfigKey, sealed)
+
+	// A later command derives the same key from the passphrase
+	tokenKey = nil
+	if got, err := storedToken(tokenConfigKey); err != nil || got != "secret-token" {
+		t.Fatalf("unexpected token %q: %v", got, err)
+	}
+	if TokenStorage() != "encrypted with a passphrase" {
+		t.Fatalf("unexpected storage %q", TokenStorage())
+	}
+
+	tokenKey = nil
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := storedToken(tokenConfigKey); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a wrong passphrase, got %v", err)
+	}
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/bundlediff"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/bundlelint"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/bundlesign"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/changelog"
 	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/outbox"
 	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/validation"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -35,19 +41,9 @@ func init() {
 			}
 
 			// Format output as JSON
-			jsonOutput, err := cmd.Flags().GetBool("json")
-			if err != nil {
-				return err
-			}
-
-			if jsonOutput {
-				jsonData, err := json.MarshalIndent(manifest, "", "  ")
-				if err != nil {
-					cmd.SilenceUsage = true
-					return fmt.Errorf("error formatting JSON: %w", err)
-				}
-				fmt.Println(string(jsonData))
-				return nil
+			if jsonRequested(cmd) {
+				cmd.SilenceUsage = true
+				return printJSON(cmd, manifest)
 			}
 
 			// Display formatted output
@@ -93,18 +89,8 @@ func init() {
 			}
 
 			// Format output as JSON
-			jsonOutput, err := cmd.Flags().GetBool("json")
-			if err != nil {
-				return err
-			}
-
-			if jsonOutput {
-				jsonData, err := json.MarshalIndent(response, "", "  ")
-				if err != nil {
-					return err
-				}
-				fmt.Println(string(jsonData))
-				return nil
+			if jsonRequested(cmd) {
+				return printJSON(cmd, response)
 			}
 
 			// Display formatted output
@@ -223,7 +209,16 @@ Use the --preview flag to ensure you get the preview version of the app bundle.`
 The bundle will be validated before upload to ensure it has the correct structure.
 Use --skip-validation to bypass validation (not recommended).
 
-After upload, use --activate to automatically activate the new version.`,
+Use --sign-key with an Ed25519 private key in PEM format to sign the bundle, for
+servers that only accept signed bundles (APP_BUNDLE_SIGNING_KEYS). Generate a key with:
+  openssl genpkey -algorithm ed25519 -out bundle-signing.pem
+
+Bundles larger than --chunk-size are sent in chunks, each retried on its own when the
+connection drops. If the upload still fails, continue it later with --resume and the upload ID
+printed with the error.
+
+After upload, use --activate to automatically activate the new version.
+Use --query version to print only the new version, for use in scripts.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			bundlePath := args[0]
@@ -238,21 +233,26 @@ After upload, use --activate to automatically activate the new version.`,
 			skipValidation, _ := cmd.Flags().GetBool("skip-validation")
 			activate, _ := cmd.Flags().GetBool("activate")
 			verbose, _ := cmd.Flags().GetBool("verbose")
+			jsonOutput := jsonRequested(cmd)
 
 			// Validate bundle structure (unless skipped)
 			if !skipValidation {
-				color.Cyan("Validating bundle structure...")
+				if !jsonOutput {
+					color.Cyan("Validating bundle structure...")
+				}
 				if err := validation.ValidateBundle(bundlePath); err != nil {
 					cmd.SilenceUsage = true
 					return fmt.Errorf("bundle validation failed: %w", err)
 				}
-				color.Green("✓ Bundle structure is valid")
-			} else {
+				if !jsonOutput {
+					color.Green("✓ Bundle structure is valid")
+				}
+			} else if !jsonOutput {
 				color.Yellow("⚠ Skipping validation (not recommended)")
 			}
 
 			// Show bundle info
-			if verbose {
+			if verbose && !jsonOutput {
 				info, err := validation.GetBundleInfo(bundlePath)
 				if err == nil {
 					fmt.Println()
@@ -265,18 +265,53 @@ After upload, use --activate to automatically activate the new version.`,
 				}
 			}
 
-			// Upload bundle
-			color.Cyan("Uploading bundle...")
+			// Sign bundle
+			var signature string
+			if keyPath, _ := cmd.Flags().GetString("sign-key"); keyPath != "" {
+				key, err := bundlesign.LoadPrivateKey(keyPath)
+				if err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+				var publicKey string
+				signature, publicKey, err = bundlesign.Sign(bundlePath, key)
+				if err != nil {
+					cmd.SilenceUsage = true
+					return fmt.Errorf("failed to sign app bundle: %w", err)
+				}
+				if !jsonOutput {
+					color.Green("✓ Bundle signed with key %s", publicKey)
+				}
+			}
+
+			// Upload bundle, in chunks when it is large or an upload is resumed
 			c := client.NewClient()
-			response, err := c.UploadAppBundle(bundlePath)
+			chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+			retries, _ := cmd.Flags().GetInt("retries")
+			resumeID, _ := cmd.Flags().GetString("resume")
+			if chunkSize < 0 || chunkSize > 16 {
+				return fmt.Errorf("--chunk-size must be between 0 and 16 MB")
+			}
+			info, err := os.Stat(bundlePath)
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			var response map[string]interface{}
+			if resumeID != "" || (chunkSize > 0 && info.Size() > int64(chunkSize)<<20) {
+				response, err = uploadInChunks(c, bundlePath, resumeID, int64(chunkSize)<<20, retries, signature, jsonOutput)
+			} else {
+				if !jsonOutput {
+					color.Cyan("Uploading bundle...")
+				}
+				response, err = c.UploadAppBundle(bundlePath, signature)
+			}
 			if err != nil {
 				cmd.SilenceUsage = true
 				// Try to parse error message for better output
 				return fmt.Errorf("failed to upload app bundle: %w", err)
 			}
 
-			color.Green("✓ App bundle uploaded successfully!")
-
 			// Extract version from response
 			version, ok := response["version"].(string)
 			if !ok {
@@ -286,6 +321,19 @@ After upload, use --activate to automatically activate the new version.`,
 				}
 			}
 
+			// Print only the response, so scripts can read the new version
+			if jsonOutput {
+				cmd.SilenceUsage = true
+				if activate && version != "" {
+					if _, err := c.SwitchAppBundleVersion(version); err != nil {
+						return fmt.Errorf("uploaded version %s but failed to activate it: %w", version, err)
+					}
+				}
+				return printJSON(cmd, response)
+			}
+
+			color.Green("✓ App bundle uploaded successfully!")
+
 			if version != "" {
 				fmt.Printf("Version: %s\n", version)
 			}
@@ -345,6 +393,11 @@ After upload, use --activate to automatically activate the new version.`,
 	uploadCmd.Flags().Bool("skip-validation", false, "Skip bundle validation before upload (not recommended)")
 	uploadCmd.Flags().BoolP("activate", "a", false, "Automatically activate the uploaded version")
 	uploadCmd.Flags().BoolP("verbose", "v", false, "Show detailed information about the bundle and manifest")
+	uploadCmd.Flags().String("sign-key", "", "Sign the bundle with the Ed25519 private key in this PEM file")
+	uploadCmd.Flags().Int("chunk-size", 4, "Send bundles larger than this many MB in resumable chunks of this size; 0 sends them in one request")
+	uploadCmd.Flags().Int("retries", 3, "Times to retry a chunk that failed because the server was unreachable")
+	uploadCmd.Flags().String("resume", "", "Continue the interrupted chunked upload with this ID")
+	uploadCmd.Flags().BoolP("json", "j", false, "Output the upload response in JSON format")
 	appBundleCmd.AddCommand(uploadCmd)
 
 	// Changes command
@@ -381,56 +434,96 @@ If only one version is specified, compares it with the current version.`,
 			}
 
 			// Format output as JSON if requested
-			jsonOutput, _ := cmd.Flags().GetBool("json")
-			if jsonOutput {
-				jsonData, err := json.MarshalIndent(changes, "", "  ")
-				if err != nil {
-					cmd.SilenceUsage = true
-					return fmt.Errorf("error formatting JSON: %w", err)
-				}
-				fmt.Println(string(jsonData))
-				return nil
+			if jsonRequested(cmd) {
+				cmd.SilenceUsage = true
+				return printJSON(cmd, changes)
 			}
 
 			// Display formatted output
-			fmt.Printf("Changes from version %s to %s\n\n", changes.CurrentVersion, changes.TargetVersion)
+			fmt.Print(changelog.Text(changes))
+			return nil
+		},
+	}
+	changesCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	appBundleCmd.AddCommand(changesCmd)
 
-			// Added files
-			if len(changes.Added) > 0 {
-				fmt.Println("Added files:")
-				for _, file := range changes.Added {
-					fmt.Printf("  - %s\n", file["path"])
-				}
-				fmt.Println()
-			}
+	// Changelog command
+	changelogCmd := &cobra.Command{
+		Use:   "changelog [from-version] [to-version]",
+		Short: "Generate release notes between two app bundle versions",
+		Long: `Generate release notes listing the forms and fields that changed between two
+app bundle versions. Use --markdown for notes to paste into deployment announcements.`,
+		Example: `  synk app-bundle changelog 20250506-101500 20250507-123456
+  synk app-bundle changelog 20250506-101500 20250507-123456 --markdown > RELEASE_NOTES.md`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := client.NewClient()
 
-			// Modified files
-			if len(changes.Modified) > 0 {
-				fmt.Println("Modified files:")
-				for _, file := range changes.Modified {
-					fmt.Printf("  - %s\n", file["path"])
-				}
-				fmt.Println()
+			changes, err := c.GetAppBundleChanges(args[0], args[1])
+			if err != nil {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("failed to get app bundle changes: %w", err)
 			}
 
-			// Removed files
-			if len(changes.Removed) > 0 {
-				fmt.Println("Removed files:")
-				for _, file := range changes.Removed {
-					fmt.Printf("  - %s\n", file["path"])
-				}
-				fmt.Println()
+			markdown, _ := cmd.Flags().GetBool("markdown")
+			if markdown {
+				fmt.Print(changelog.Markdown(changes))
+				return nil
+			}
+			fmt.Print(changelog.Text(changes))
+			return nil
+		},
+	}
+	changelogCmd.Flags().Bool("markdown", false, "Output Markdown release notes")
+	appBundleCmd.AddCommand(changelogCmd)
+
+	// Codebook command
+	codebookCmd := &cobra.Command{
+		Use:   "codebook",
+		Short: "Download printable documentation of the app bundle's forms",
+		Long: `Download a codebook of the forms in an app bundle version: every question with its
+variable name, answer type, choice labels and the conditions under which it is asked. The
+server renders it as HTML or PDF, e.g. for study protocol submissions.`,
+		Example: `  synk app-bundle codebook
+  synk app-bundle codebook --format pdf --version 20250507-123456
+  synk app-bundle codebook --form household --output household.html`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, _ := cmd.Flags().GetString("version")
+			form, _ := cmd.Flags().GetString("form")
+			format, _ := cmd.Flags().GetString("format")
+			output, _ := cmd.Flags().GetString("output")
+			if format != "html" && format != "pdf" {
+				return fmt.Errorf("--format must be html or pdf")
 			}
+			if output == "" {
+				output = "codebook." + format
+			}
+			cmd.SilenceUsage = true
 
-			if len(changes.Added) == 0 && len(changes.Modified) == 0 && len(changes.Removed) == 0 {
-				fmt.Println("No changes found between the specified versions.")
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			c := client.NewClient()
+			err = c.DownloadCodebook(version, form, format, file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(output)
+				return fmt.Errorf("failed to download codebook: %w", err)
 			}
 
+			color.Green("✓ Wrote %s", output)
 			return nil
 		},
 	}
-	changesCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
-	appBundleCmd.AddCommand(changesCmd)
+	codebookCmd.Flags().String("version", "", "App bundle version (default the active version)")
+	codebookCmd.Flags().String("form", "", "Only document this form")
+	codebookCmd.Flags().String("format", "html", "Output format: html or pdf")
+	codebookCmd.Flags().StringP("output", "o", "", "Output file (default codebook.html or codebook.pdf)")
+	appBundleCmd.AddCommand(codebookCmd)
 
 	// Switch version command
 	switchCmd := &cobra.Command{
@@ -455,4 +548,211 @@ If only one version is specified, compares it with the current version.`,
 		},
 	}
 	appBundleCmd.AddCommand(switchCmd)
+
+	// Lint command
+	lintCmd := &cobra.Command{
+		Use:   "lint [bundle]",
+		Short: "Check an app bundle and report every problem found",
+		Long: `Check an app bundle directory or ZIP file without uploading it.
+
+Unlike the validation run by 'upload', lint reports every problem with the file, line and
+column it was found at, and also warns about likely mistakes such as forms without a title.
+The x-visible-if and x-constraint expressions of form schemas are parsed and checked for
+references to missing fields, visibility that depends on itself and conditions that can
+never be true.
+Use --format github in GitHub Actions to annotate pull requests inline, or --format sarif to
+upload the report to code scanning. The command fails when errors are found, or warnings too
+with --fail-on warning.
+
+Examples:
+  synk app-bundle lint ./my-bundle
+  synk app-bundle lint ./my-bundle --format github
+  synk app-bundle lint ./my-bundle --format sarif --output lint.sarif`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundlePath := args[0]
+			format, _ := cmd.Flags().GetString("format")
+			output, _ := cmd.Flags().GetString("output")
+			failOn, _ := cmd.Flags().GetString("fail-on")
+			if failOn != string(bundlelint.SeverityError) && failOn != string(bundlelint.SeverityWarning) {
+				return fmt.Errorf("invalid --fail-on %q (expected error or warning)", failOn)
+			}
+			cmd.SilenceUsage = true
+
+			findings, err := bundlelint.Lint(bundlePath)
+			if err != nil {
+				return fmt.Errorf("failed to lint bundle: %w", err)
+			}
+
+			// Annotations must point at files relative to the repository; paths inside a ZIP
+			// are reported as they are
+			root := ""
+			if info, err := os.Stat(bundlePath); err == nil && info.IsDir() {
+				root = filepath.ToSlash(filepath.Clean(bundlePath))
+			}
+
+			w := os.Stdout
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", output, err)
+				}
+				defer file.Close()
+				w = file
+			}
+			if err := bundlelint.WriteReport(w, format, findings, root, Version); err != nil {
+				return err
+			}
+
+			errorCount := 0
+			for _, f := range findings {
+				if f.Severity == bundlelint.SeverityError {
+					errorCount++
+				}
+			}
+			warningCount := len(findings) - errorCount
+			if format == bundlelint.FormatText && output == "" {
+				if len(findings) == 0 {
+					color.Green("✓ No problems found")
+				} else {
+					fmt.Printf("\n%d errors, %d warnings\n", errorCount, warningCount)
+				}
+			}
+
+			if errorCount > 0 || (failOn == string(bundlelint.SeverityWarning) && warningCount > 0) {
+				return fmt.Errorf("lint found %d errors and %d warnings", errorCount, warningCount)
+			}
+			return nil
+		},
+	}
+	lintCmd.Flags().String("format", bundlelint.FormatText, "Output format: text, github or sarif")
+	lintCmd.Flags().StringP("output", "o", "", "Write the report to a file instead of stdout")
+	lintCmd.Flags().String("fail-on", "error", "Lowest severity that fails the command: error or warning")
+	appBundleCmd.AddCommand(lintCmd)
+
+	// Diff command
+	diffCmd := &cobra.Command{
+		Use:   "diff [old-bundle] [new-bundle]",
+		Short: "Build a ZIP of only the files changed between two app bundles",
+		Long: `Compare two app bundle ZIP files and write a ZIP of the files added or changed in the
+new one, with DIFF.json listing them and the paths removed. This is the format devices get from
+the server's /app-bundle/diff endpoint, e.g. to hand out an update on a memory card to devices
+on slow links.
+
+Examples:
+  synk app-bundle diff bundle-1.0.zip bundle-1.1.zip
+  synk app-bundle diff bundle-1.0.zip bundle-1.1.zip --output update.zip`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = "app-bundle-diff.zip"
+			}
+			cmd.SilenceUsage = true
+
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			diff, err := bundlediff.Write(args[0], args[1], file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(output)
+				return err
+			}
+
+			color.Green("✓ Wrote %s", output)
+			fmt.Printf("%d files changed, %d removed\n", len(diff.Changed), len(diff.Removed))
+			return nil
+		},
+	}
+	diffCmd.Flags().StringP("output", "o", "", "Output ZIP file (default app-bundle-diff.zip)")
+	appBundleCmd.AddCommand(diffCmd)
+}
+
+// uploadInChunks sends a bundle through a chunked upload, or continues the upload resumeID, and
+// pushes it. Chunks are retried on failures that may pass on a later attempt; a chunk refused
+// because the server already has it, e.g. after a lost response, continues from the server's
+// byte count. Errors after the upload started name the upload ID for --resume.
+func uploadInChunks(c *client.Client, bundlePath, resumeID string, chunkSize int64, retries int, signature string, quiet bool) (map[string]interface{}, error) {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if chunkSize <= 0 {
+		chunkSize = 4 << 20
+	}
+
+	backoff := outbox.DefaultBackoff()
+	backoff.Attempts = retries + 1
+	var upload *client.AppBundleUpload
+	if resumeID != "" {
+		if upload, err = c.GetAppBundleUpload(resumeID); err != nil {
+			return nil, err
+		}
+		if upload.Size != size {
+			return nil, fmt.Errorf("upload %s is of a %d byte bundle, but %s has %d bytes", resumeID, upload.Size, bundlePath, size)
+		}
+	} else {
+		err = backoff.Retry(func() error {
+			var err error
+			upload, err = c.CreateAppBundleUpload(size)
+			return err
+		}, client.IsRetryable)
+		// Servers without chunked uploads take the bundle in one request
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed) {
+			if !quiet {
+				color.Yellow("⚠ The server does not support chunked uploads; uploading in one request")
+			}
+			return c.UploadAppBundle(bundlePath, signature)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !quiet {
+		color.Cyan("Uploading bundle in %d MB chunks (upload %s)...", chunkSize>>20, upload.ID)
+	}
+
+	chunk := make([]byte, chunkSize)
+	for upload.Received < size {
+		offset := upload.Received
+		n := int(min(chunkSize, size-offset))
+		if _, err := file.ReadAt(chunk[:n], offset); err != nil {
+			return nil, fmt.Errorf("error reading bundle: %w", err)
+		}
+		err := backoff.Retry(func() error {
+			sent, err := c.UploadAppBundleChunk(upload.ID, offset, chunk[:n], size)
+			var apiErr *client.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+				sent, err = c.GetAppBundleUpload(upload.ID)
+			}
+			if err == nil {
+				upload = sent
+			}
+			return err
+		}, client.IsRetryable)
+		if err != nil {
+			return nil, fmt.Errorf("%w; continue with: synk app-bundle upload %s --resume %s", err, bundlePath, upload.ID)
+		}
+		if !quiet {
+			fmt.Printf("  %d of %d bytes sent\n", upload.Received, size)
+		}
+	}
+
+	// Completing is not retried: a push whose response was lost would be stored twice
+	response, err := c.CompleteAppBundleUpload(upload.ID, signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w; all chunks were sent, continue with: synk app-bundle upload %s --resume %s", err, bundlePath, upload.ID)
+	}
+	return response, nil
 }
@@ -25,6 +25,7 @@ The file will be available at /attachments/<attachment_id>`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filePath := args[0]
 		attachmentID, _ := cmd.Flags().GetString("id")
+		observationID, _ := cmd.Flags().GetString("observation")
 
 		if attachmentID == "" {
 			// If no ID provided, use the filename
@@ -32,11 +33,15 @@ The file will be available at /attachments/<attachment_id>`,
 		}
 
 		c := client.NewClient()
-		_, err := c.UploadAttachment(attachmentID, filePath)
+		result, err := c.UploadAttachment(attachmentID, filePath, observationID)
 		if err != nil {
 			return fmt.Errorf("upload failed: %w", err)
 		}
 
+		if jsonRequested(cmd) {
+			return printJSON(cmd, result)
+		}
+
 		fmt.Printf("Successfully uploaded %s as attachment %s\n", filePath, attachmentID)
 		return nil
 	},
@@ -95,14 +100,103 @@ var existsCmd = &cobra.Command{
 	},
 }
 
+// listAttachmentsCmd represents the list command
+var listAttachmentsCmd = &cobra.Command{
+	Use:   "list <observation_id>",
+	Short: "List the attachments of an observation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := client.NewClient()
+		result, err := c.ListAttachments(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to list attachments: %w", err)
+		}
+
+		if jsonRequested(cmd) {
+			return printJSON(cmd, result)
+		}
+
+		attachments, _ := result["attachments"].([]interface{})
+		if len(attachments) == 0 {
+			fmt.Printf("Observation %s has no attachments\n", args[0])
+			return nil
+		}
+		for _, item := range attachments {
+			a, _ := item.(map[string]interface{})
+			size, _ := a["size"].(float64)
+			fmt.Printf("%s\t%s\t%d bytes\n", a["id"], a["content_type"], int64(size))
+		}
+		return nil
+	},
+}
+
+// attachmentManifestCmd represents the manifest command
+var attachmentManifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Show attachment changes since a data version",
+	Long: `Show the attachments to download and delete since a data version, as a device
+would see them during attachment sync.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientID, _ := cmd.Flags().GetString("client-id")
+		since, _ := cmd.Flags().GetInt64("since")
+
+		c := client.NewClient()
+		result, err := c.GetAttachmentManifest(clientID, since)
+		if err != nil {
+			return fmt.Errorf("failed to get attachment manifest: %w", err)
+		}
+
+		if jsonRequested(cmd) {
+			return printJSON(cmd, result)
+		}
+
+		fmt.Printf("Current version: %v\n", result["current_version"])
+		operations, _ := result["operations"].([]interface{})
+		for _, item := range operations {
+			op, _ := item.(map[string]interface{})
+			fmt.Printf("%-8s %s\n", op["operation"], op["attachment_id"])
+		}
+		fmt.Printf("%d operations, %v bytes to download\n", len(operations), result["total_download_size"])
+		return nil
+	},
+}
+
+// deleteAttachmentCmd represents the delete command
+var deleteAttachmentCmd = &cobra.Command{
+	Use:   "delete <attachment_id>",
+	Short: "Delete an attachment",
+	Long: `Delete an attachment from the server. Devices remove their copy on the next
+attachment sync.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := client.NewClient()
+		if err := c.DeleteAttachment(args[0]); err != nil {
+			return fmt.Errorf("delete failed: %w", err)
+		}
+
+		fmt.Printf("Deleted attachment %s\n", args[0])
+		return nil
+	},
+}
+
 func init() {
 	// Add commands to the attachments command group
 	attachmentsCmd.AddCommand(uploadCmd)
 	attachmentsCmd.AddCommand(downloadCmd)
 	attachmentsCmd.AddCommand(existsCmd)
+	attachmentsCmd.AddCommand(listAttachmentsCmd)
+	attachmentsCmd.AddCommand(attachmentManifestCmd)
+	attachmentsCmd.AddCommand(deleteAttachmentCmd)
 
 	// Add flags
 	uploadCmd.Flags().String("id", "", "Attachment ID (defaults to filename if not provided)")
+	uploadCmd.Flags().String("observation", "", "Observation ID to associate the attachment with")
+	uploadCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	listAttachmentsCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	attachmentManifestCmd.Flags().String("client-id", "", "Client ID to compute the manifest for")
+	attachmentManifestCmd.Flags().Int64("since", 0, "Data version to list changes since")
+	attachmentManifestCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	attachmentManifestCmd.MarkFlagRequired("client-id")
 
 	// Add attachments command to root
 	rootCmd.AddCommand(attachmentsCmd)
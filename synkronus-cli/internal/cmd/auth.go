@@ -7,6 +7,7 @@ import (
 
 	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/auth"
 	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/term"
@@ -60,7 +61,7 @@ func init() {
 	logoutCmd := &cobra.Command{
 		Use:   "logout",
 		Short: "Logout from the Synkronus API",
-		Long:  `Clear authentication tokens for the Synkronus API.`,
+		Long:  `End the current session on the Synkronus API and clear the stored authentication tokens.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := auth.Logout(); err != nil {
 				return fmt.Errorf("logout failed: %w", err)
@@ -101,4 +102,274 @@ func init() {
 		},
 	}
 	rootCmd.AddCommand(statusCmd)
+
+	// Auth command group
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Inspect stored authentication",
+	}
+	rootCmd.AddCommand(authCmd)
+
+	// Auth status command
+	authStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show stored tokens and when they expire",
+		Long: `Display how the stored tokens are protected, when the access and refresh tokens
+expire and who they belong to.
+
+Tokens are encrypted in the config file with a key kept in the OS keyring (macOS
+Keychain, or the Secret Service through secret-tool on Linux). Without a keyring
+the key is derived from a passphrase, read from SYNK_PASSPHRASE or asked for.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			configFile := viper.ConfigFileUsed()
+			if configFile == "" {
+				configFile = "(no config file found, using defaults)"
+			}
+
+			utils.PrintHeading("Configuration")
+			fmt.Printf("%s\n", utils.FormatKeyValue("Config file", configFile))
+			fmt.Printf("%s\n", utils.FormatKeyValue("API endpoint", viper.GetString("api.url")))
+			fmt.Println()
+
+			if key := auth.GetAPIKey(); key != "" {
+				utils.PrintHeading("API key")
+				fmt.Printf("%s\n", utils.FormatKeyValue("Key", maskAPIKey(key)))
+				utils.PrintInfo("Requests authenticate with the API key; stored tokens are not used")
+				return nil
+			}
+
+			storage := auth.TokenStorage()
+			utils.PrintHeading("Tokens")
+			fmt.Printf("%s\n", utils.FormatKeyValue("Storage", storage))
+			if storage == "none" {
+				return fmt.Errorf("not authenticated, please login first")
+			}
+			if storage == "plaintext" {
+				utils.PrintWarning("Tokens are stored in plaintext; they are encrypted the next time they are used")
+			}
+
+			claims, err := auth.GetUserInfo()
+			if err != nil {
+				return fmt.Errorf("failed to read access token: %w", err)
+			}
+			fmt.Printf("%s\n", utils.FormatKeyValue("Access token expires", formatExpiry(claims)))
+			if refreshClaims, err := auth.GetRefreshTokenInfo(); err == nil {
+				fmt.Printf("%s\n", utils.FormatKeyValue("Refresh token expires", formatExpiry(refreshClaims)))
+			}
+			fmt.Println()
+
+			utils.PrintHeading("User")
+			fmt.Printf("%s\n", utils.FormatKeyValue("Username", claims.Username))
+			fmt.Printf("%s\n", utils.FormatKeyValue("Role", claims.Role))
+			return nil
+		},
+	}
+	authCmd.AddCommand(authStatusCmd)
+
+	// API key commands
+	apiKeysCmd := &cobra.Command{
+		Use:   "api-keys",
+		Short: "Manage API keys for scripts and pipelines",
+		Long: `API keys authenticate without a password or refresh tokens. Send a key in the
+X-API-Key header, or set it for synk with SYNK_API_KEY or api.key in the config file.
+
+A key acts with the current role of the user it belongs to. Admins can issue keys
+for other users, such as a read-only service account for a data pipeline.`,
+	}
+	authCmd.AddCommand(apiKeysCmd)
+
+	apiKeysCreateCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an API key",
+		Long: `Create an API key. The key is shown only once; store it somewhere safe.
+
+API keys cannot be created while authenticated with an API key.`,
+		Example: `  synk auth api-keys create "nightly export" --expires 2160h
+  synk auth api-keys create etl --user etl-bot`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			username, _ := cmd.Flags().GetString("user")
+			expires, _ := cmd.Flags().GetDuration("expires")
+
+			req := client.APIKeyCreateRequest{Name: args[0], Username: username}
+			if expires > 0 {
+				req.ExpiresAt = time.Now().Add(expires).UTC().Format(time.RFC3339)
+			}
+			result, err := client.NewClient().CreateAPIKey(req)
+			if err != nil {
+				return fmt.Errorf("failed to create API key: %w", err)
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, result)
+			}
+
+			utils.PrintSuccess("API key '%v' created for %v", result["name"], result["username"])
+			fmt.Printf("%s\n", utils.FormatKeyValue("ID", fmt.Sprint(result["id"])))
+			fmt.Printf("%s\n", utils.FormatKeyValue("Key", fmt.Sprint(result["key"])))
+			utils.PrintWarning("This is the only time the key is shown")
+			return nil
+		},
+	}
+	apiKeysCreateCmd.Flags().String("user", "", "Create the key for another user (admin only)")
+	apiKeysCreateCmd.Flags().Duration("expires", 0, "Expire the key after this long, e.g. 720h (default never)")
+	apiKeysCreateCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	apiKeysCmd.AddCommand(apiKeysCreateCmd)
+
+	apiKeysListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List active API keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			all, _ := cmd.Flags().GetBool("all")
+			keys, err := client.NewClient().ListAPIKeys(all)
+			if err != nil {
+				return fmt.Errorf("failed to list API keys: %w", err)
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, keys)
+			}
+			if len(keys) == 0 {
+				fmt.Println("No API keys found.")
+				return nil
+			}
+
+			fmt.Printf("%-36s  %-20s  %-16s  %-13s  %-20s  %s\n", "ID", "NAME", "USER", "PREFIX", "EXPIRES", "LAST USED")
+			for _, key := range keys {
+				fmt.Printf("%-36v  %-20v  %-16v  %-13v  %-20s  %s\n", key["id"], key["name"], key["username"],
+					key["key_prefix"], formatKeyTime(key["expires_at"]), formatKeyTime(key["last_used_at"]))
+			}
+			return nil
+		},
+	}
+	apiKeysListCmd.Flags().Bool("all", false, "List every user's keys (admin only)")
+	apiKeysListCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	apiKeysCmd.AddCommand(apiKeysListCmd)
+
+	apiKeysRevokeCmd := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if err := client.NewClient().RevokeAPIKey(args[0]); err != nil {
+				return fmt.Errorf("failed to revoke API key: %w", err)
+			}
+			utils.PrintSuccess("API key %s revoked", args[0])
+			return nil
+		},
+	}
+	apiKeysCmd.AddCommand(apiKeysRevokeCmd)
+
+	// Session commands
+	sessionsCmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Manage your login sessions",
+		Long: `Every login starts a session that lasts until its refresh token expires.
+Revoking a session stops its refresh token from working, so the device it belongs
+to has to log in again once its current access token expires.`,
+	}
+	authCmd.AddCommand(sessionsCmd)
+
+	sessionsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List your active sessions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			sessions, err := client.NewClient().ListSessions()
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, sessions)
+			}
+			if len(sessions) == 0 {
+				fmt.Println("No active sessions found.")
+				return nil
+			}
+
+			fmt.Printf("%-36s  %-20s  %-20s  %-15s  %s\n", "ID", "CREATED", "LAST USED", "IP", "CLIENT")
+			for _, session := range sessions {
+				fmt.Printf("%-36v  %-20s  %-20s  %-15v  %v\n", session["id"], formatKeyTime(session["createdAt"]),
+					formatKeyTime(session["lastUsedAt"]), valueOr(session["ipAddress"], "-"), valueOr(session["userAgent"], "-"))
+			}
+			return nil
+		},
+	}
+	sessionsListCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	sessionsCmd.AddCommand(sessionsListCmd)
+
+	sessionsRevokeCmd := &cobra.Command{
+		Use:   "revoke [id]",
+		Short: "Revoke a session, or all of them with --all",
+		Example: `  synk auth sessions revoke 3f0c8a9e-1d2b-4c5d-8e7f-6a5b4c3d2e1f
+  synk auth sessions revoke --all`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+			if all == (len(args) == 1) {
+				return fmt.Errorf("give either a session ID or --all")
+			}
+			cmd.SilenceUsage = true
+
+			if all {
+				count, err := client.NewClient().RevokeAllSessions()
+				if err != nil {
+					return fmt.Errorf("failed to revoke sessions: %w", err)
+				}
+				utils.PrintSuccess("Revoked %d session(s); log in again to start a new one", count)
+				return nil
+			}
+			if err := client.NewClient().RevokeSession(args[0]); err != nil {
+				return fmt.Errorf("failed to revoke session: %w", err)
+			}
+			utils.PrintSuccess("Session %s revoked", args[0])
+			return nil
+		},
+	}
+	sessionsRevokeCmd.Flags().Bool("all", false, "Revoke every session, including this one")
+	sessionsCmd.AddCommand(sessionsRevokeCmd)
+}
+
+// valueOr formats an optional listed value, or returns fallback when it is missing or empty
+func valueOr(value interface{}, fallback string) string {
+	if s, ok := value.(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
+
+// maskAPIKey keeps the prefix the server lists a key by and hides the rest
+func maskAPIKey(key string) string {
+	if len(key) <= 13 {
+		return "****"
+	}
+	return key[:13] + "..."
+}
+
+// formatKeyTime formats an optional timestamp of an API key for display
+func formatKeyTime(value interface{}) string {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return "never"
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// formatExpiry describes when a token expires relative to now
+func formatExpiry(claims *auth.Claims) string {
+	if claims.ExpiresAt == nil {
+		return "never"
+	}
+	expiresAt := claims.ExpiresAt.Time
+	remaining := time.Until(expiresAt).Round(time.Second)
+	if remaining <= 0 {
+		return fmt.Sprintf("%s (expired %s ago)", expiresAt.Local().Format(time.DateTime), -remaining)
+	}
+	return fmt.Sprintf("%s (in %s)", expiresAt.Local().Format(time.DateTime), remaining)
 }
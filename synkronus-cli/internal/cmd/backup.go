@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/backup"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+func init() {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up and restore a server (admin only)",
+		Long: `Capture the state of a Synkronus server in one archive and restore it, e.g. onto a
+fresh server after losing the old one.
+
+A backup holds the users with their password hashes, every app bundle version and a
+snapshot of all observations. Attachments, settings and audit logs are not included;
+back up the server's data directory and database for those.`,
+	}
+	rootCmd.AddCommand(backupCmd)
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Capture users, app bundles and observations in one archive",
+		Long: `Download users, app bundle versions and observations from the server and pack them
+into one archive. The archive is compressed with zstd or gzip by its extension
+(.tar.zst, .tar.gz or .tar).
+
+The archive holds password hashes and all collected data; store it as securely as the
+server itself.`,
+		Example: `  synk backup create -o backup.tar.zst
+  synk backup create -o "backup-$(date +%F).tar.gz"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			output, _ := cmd.Flags().GetString("output")
+
+			staging, err := os.MkdirTemp("", "synk-backup-*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(staging)
+
+			manifest, err := stageBackup(newBackupClient(), staging)
+			if err != nil {
+				return err
+			}
+			if err := backup.Pack(staging, manifest, output); err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+
+			if jsonRequested(cmd) {
+				return printJSON(cmd, manifest)
+			}
+			utils.PrintSuccess("Backup written to %s", output)
+			printBackupManifest(manifest)
+			return nil
+		},
+	}
+	createCmd.Flags().StringP("output", "o", "", "Archive to write (.tar.zst, .tar.gz or .tar)")
+	createCmd.MarkFlagRequired("output")
+	createCmd.Flags().BoolP("json", "j", false, "Output the backup manifest in JSON format")
+	backupCmd.AddCommand(createCmd)
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <archive>",
+		Short: "Restore users, app bundles and observations from an archive",
+		Long: `Restore a backup made with 'synk backup create' into the configured server.
+
+Users are created or have their password and role replaced. App bundle versions are
+pushed oldest first and the version that was active is switched to; on a server that
+already has versions they get new version numbers. Observations are created or
+replaced and get new sync versions, so devices pull them again.`,
+		Example: `  synk backup restore backup.tar.zst
+  synk --api-url https://new-server.example.org backup restore backup.tar.zst --yes`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			yes, _ := cmd.Flags().GetBool("yes")
+			skip, _ := cmd.Flags().GetStringSlice("skip")
+			for _, part := range skip {
+				if part != "users" && part != "bundles" && part != "observations" {
+					return fmt.Errorf("unknown --skip value %q (expected users, bundles or observations)", part)
+				}
+			}
+
+			staging, err := os.MkdirTemp("", "synk-restore-*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(staging)
+
+			manifest, err := backup.Unpack(args[0], staging)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", args[0], err)
+			}
+
+			target := viper.GetString("api.url")
+			if !yes {
+				printBackupManifest(manifest)
+				fmt.Println()
+				if !confirm(fmt.Sprintf("Restore this backup into %s?", target)) {
+					return fmt.Errorf("restore cancelled")
+				}
+			}
+
+			report, err := restoreBackup(newBackupClient(), staging, manifest, skip)
+			if err != nil {
+				return err
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, report)
+			}
+			utils.PrintSuccess("Backup restored into %s", target)
+			if users, ok := report["users"].(map[string]interface{}); ok {
+				fmt.Printf("%s\n", utils.FormatKeyValue("Users", fmt.Sprintf("%v created, %v updated", users["created"], users["updated"])))
+			}
+			if bundles, ok := report["bundles"].(map[string]string); ok {
+				fmt.Printf("%s\n", utils.FormatKeyValue("App bundle versions", fmt.Sprint(len(bundles))))
+				if current, ok := report["current_bundle"].(string); ok {
+					fmt.Printf("%s\n", utils.FormatKeyValue("Active version", current))
+				}
+			}
+			if observations, ok := report["observations"].(map[string]interface{}); ok {
+				fmt.Printf("%s\n", utils.FormatKeyValue("Observations", fmt.Sprintf("%v created, %v updated", observations["created"], observations["updated"])))
+			}
+			return nil
+		},
+	}
+	restoreCmd.Flags().Bool("yes", false, "Restore without asking for confirmation")
+	restoreCmd.Flags().StringSlice("skip", nil, "Parts not to restore: users, bundles, observations")
+	restoreCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	backupCmd.AddCommand(restoreCmd)
+}
+
+// newBackupClient returns a client without the request timeout, since backups stream the whole
+// dataset
+func newBackupClient() *client.Client {
+	c := client.NewClient()
+	c.HTTPClient.Timeout = 0
+	return c
+}
+
+// stageBackup downloads the server state into dir and describes it
+func stageBackup(c *client.Client, dir string) (*backup.Manifest, error) {
+	manifest := &backup.Manifest{CreatedAt: time.Now().UTC(), Server: c.BaseURL}
+	if info, err := c.GetVersion(); err == nil {
+		manifest.ServerVersion = info.Server.Version
+	}
+
+	users, err := c.ExportBackupUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export users: %w", err)
+	}
+	var userList []json.RawMessage
+	if err := json.Unmarshal(users, &userList); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+	manifest.Users = len(userList)
+	if err := os.WriteFile(filepath.Join(dir, backup.UsersFile), users, 0600); err != nil {
+		return nil, err
+	}
+
+	response, err := c.GetAppBundleVersions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app bundle versions: %w", err)
+	}
+	versions, _ := response["versions"].([]interface{})
+	for _, v := range versions {
+		version, _ := v.(string)
+		if name, ok := strings.CutSuffix(version, " *"); ok {
+			version = name
+			manifest.CurrentBundle = name
+		}
+		manifest.Bundles = append(manifest.Bundles, version)
+	}
+	sort.Strings(manifest.Bundles)
+	if err := os.MkdirAll(filepath.Join(dir, backup.BundlesDir), 0700); err != nil {
+		return nil, err
+	}
+	for _, version := range manifest.Bundles {
+		if err := downloadToFile(filepath.Join(dir, filepath.FromSlash(backup.BundlePath(version))), func(f *os.File) error {
+			return c.DownloadBackupAppBundle(version, f)
+		}); err != nil {
+			return nil, fmt.Errorf("failed to export app bundle version %s: %w", version, err)
+		}
+	}
+
+	counter := &lineCounter{}
+	if err := downloadToFile(filepath.Join(dir, backup.ObservationsFile), func(f *os.File) error {
+		return c.DownloadBackupObservations(io.MultiWriter(f, counter))
+	}); err != nil {
+		return nil, fmt.Errorf("failed to export observations: %w", err)
+	}
+	manifest.Observations = counter.lines
+
+	return manifest, nil
+}
+
+// restoreBackup restores the parts of an unpacked backup not skipped and reports the results
+func restoreBackup(c *client.Client, dir string, manifest *backup.Manifest, skip []string) (map[string]interface{}, error) {
+	skipped := func(part string) bool {
+		for _, s := range skip {
+			if s == part {
+				return true
+			}
+		}
+		return false
+	}
+	report := map[string]interface{}{}
+
+	if !skipped("users") {
+		users, err := os.ReadFile(filepath.Join(dir, backup.UsersFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read users: %w", err)
+		}
+		result, err := c.RestoreBackupUsers(users)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore users: %w", err)
+		}
+		report["users"] = result
+	}
+
+	if !skipped("bundles") && len(manifest.Bundles) > 0 {
+		// Pushing assigns the next version number, which may differ from the backed up one
+		restored := map[string]string{}
+		for _, version := range manifest.Bundles {
+			result, err := c.UploadAppBundle(filepath.Join(dir, filepath.FromSlash(backup.BundlePath(version))), "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore app bundle version %s: %w", version, err)
+			}
+			pushed, _ := result["manifest"].(map[string]interface{})
+			restored[version], _ = pushed["version"].(string)
+		}
+		report["bundles"] = restored
+
+		if current := restored[manifest.CurrentBundle]; current != "" {
+			if _, err := c.SwitchAppBundleVersion(current); err != nil {
+				return nil, fmt.Errorf("failed to switch to app bundle version %s: %w", current, err)
+			}
+			report["current_bundle"] = current
+		}
+	}
+
+	if !skipped("observations") {
+		f, err := os.Open(filepath.Join(dir, backup.ObservationsFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read observations: %w", err)
+		}
+		defer f.Close()
+		result, err := c.RestoreBackupObservations(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore observations: %w", err)
+		}
+		report["observations"] = result
+	}
+
+	return report, nil
+}
+
+// downloadToFile creates path and lets download fill it
+func downloadToFile(path string, download func(*os.File) error) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := download(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// lineCounter counts the lines written to it
+type lineCounter struct {
+	lines int
+}
+
+func (l *lineCounter) Write(p []byte) (int, error) {
+	l.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+// printBackupManifest describes a backup
+func printBackupManifest(manifest *backup.Manifest) {
+	utils.PrintHeading("Backup")
+	fmt.Printf("%s\n", utils.FormatKeyValue("Created", manifest.CreatedAt.Local().Format(time.DateTime)))
+	fmt.Printf("%s\n", utils.FormatKeyValue("Server", manifest.Server))
+	if manifest.ServerVersion != "" {
+		fmt.Printf("%s\n", utils.FormatKeyValue("Server version", manifest.ServerVersion))
+	}
+	fmt.Printf("%s\n", utils.FormatKeyValue("Users", fmt.Sprint(manifest.Users)))
+	bundles := fmt.Sprint(len(manifest.Bundles))
+	if manifest.CurrentBundle != "" {
+		bundles += fmt.Sprintf(" (active: %s)", manifest.CurrentBundle)
+	}
+	fmt.Printf("%s\n", utils.FormatKeyValue("App bundle versions", bundles))
+	fmt.Printf("%s\n", utils.FormatKeyValue("Observations", fmt.Sprint(manifest.Observations)))
+}
+
+// confirm asks a yes/no question on the terminal; without a terminal the answer is no
+func confirm(question string) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		utils.PrintWarning("Not asking for confirmation without a terminal; pass --yes")
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
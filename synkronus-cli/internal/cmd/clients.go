@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	clientsCmd := &cobra.Command{
+		Use:   "clients",
+		Short: "Manage the devices that sync with the server",
+		Long: `Every client ID that pulls or pushes is registered on the server the first time it
+syncs. Suspended devices keep pulling but their pushes are refused; blocked devices are
+refused pulls and pushes. Activating a device lets it sync again.`,
+	}
+	rootCmd.AddCommand(clientsCmd)
+
+	clientsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List devices with their last sync",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			status, _ := cmd.Flags().GetString("status")
+			clients, err := client.NewClient().ListClients(status)
+			if err != nil {
+				return fmt.Errorf("failed to list clients: %w", err)
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, clients)
+			}
+			if len(clients) == 0 {
+				fmt.Println("No clients found.")
+				return nil
+			}
+
+			fmt.Printf("%-24s  %-24s  %-9s  %-16s  %-20s  %-20s  %s\n", "CLIENT ID", "LABEL", "STATUS", "USER", "LAST PULL", "LAST PUSH", "VERSION")
+			for _, c := range clients {
+				fmt.Printf("%-24v  %-24s  %-9v  %-16s  %-20s  %-20s  %v\n", c["client_id"], valueOr(c["label"], "-"), c["status"],
+					valueOr(c["last_username"], "-"), formatKeyTime(c["last_pull_at"]), formatKeyTime(c["last_push_at"]), c["last_version"])
+			}
+			return nil
+		},
+	}
+	clientsListCmd.Flags().String("status", "", "List only active, suspended or blocked devices")
+	clientsListCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	clientsCmd.AddCommand(clientsListCmd)
+
+	clientsCmd.AddCommand(clientStatusCmd("block", "blocked", "Block a device from pulling and pushing",
+		`  synk clients block tablet-07 --reason "reported stolen"`))
+	clientsCmd.AddCommand(clientStatusCmd("suspend", "suspended", "Refuse a device's pushes while it keeps pulling",
+		`  synk clients suspend tablet-07 --reason "duplicate submissions"`))
+	clientsCmd.AddCommand(clientStatusCmd("activate", "active", "Let a suspended or blocked device sync again",
+		`  synk clients activate tablet-07`))
+
+	clientsLabelCmd := &cobra.Command{
+		Use:     "label <client-id> <label>",
+		Short:   "Label a device so it can be told apart",
+		Example: `  synk clients label tablet-07 "Kisumu team tablet 3"`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if _, err := client.NewClient().UpdateClient(args[0], client.ClientUpdate{Label: &args[1]}); err != nil {
+				return fmt.Errorf("failed to label client: %w", err)
+			}
+			utils.PrintSuccess("Client %s labelled %q", args[0], args[1])
+			return nil
+		},
+	}
+	clientsCmd.AddCommand(clientsLabelCmd)
+}
+
+// clientStatusCmd builds a command setting the status of a device
+func clientStatusCmd(use, status, short, example string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     use + " <client-id>",
+		Short:   short,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			reason, _ := cmd.Flags().GetString("reason")
+			if _, err := client.NewClient().UpdateClient(args[0], client.ClientUpdate{Status: status, Reason: reason}); err != nil {
+				return fmt.Errorf("failed to %s client: %w", use, err)
+			}
+			utils.PrintSuccess("Client %s is now %s", args[0], status)
+			return nil
+		},
+	}
+	cmd.Flags().String("reason", "", "Why the status changed, kept with the device")
+	return cmd
+}
@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
 	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/datadiff"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/spreadsheet"
 	"github.com/spf13/cobra"
 )
 
@@ -20,19 +26,24 @@ var dataExportCmd = &cobra.Command{
 	Short: "Export data as a Parquet ZIP archive",
 	Long: `Download a ZIP archive of Parquet exports from the Synkronus API.
 
+With --template the export uses a saved export template, which fixes the form types,
+columns and masking profile of a recurring delivery; see 'synk data templates'.
+
 Examples:
   synk data export exports.zip
-  synk data export ./backups/observations_parquet.zip`,
+  synk data export ./backups/observations_parquet.zip
+  synk data export --template monthly-partner partner_$(date +%Y-%m).zip`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		outputFile := args[0]
+		template, _ := cmd.Flags().GetString("template")
 
 		if outputFile == "" {
 			return fmt.Errorf("output_file is required")
 		}
 
 		c := client.NewClient()
-		if err := c.DownloadParquetExport(outputFile); err != nil {
+		if err := c.DownloadParquetExport(outputFile, template); err != nil {
 			return fmt.Errorf("data export failed: %w", err)
 		}
 
@@ -41,7 +52,272 @@ Examples:
 	},
 }
 
+// dataTemplatesCmd represents the data templates command group
+var dataTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Manage saved export templates",
+	Long: `Export templates are named export configurations kept on the server: the form types,
+org unit, columns and masking profile of a recurring delivery. Anyone with read access can
+export with a template; only admins can save or delete them.`,
+}
+
+// dataTemplatesListCmd represents the data templates list command
+var dataTemplatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List export templates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		templates, err := client.NewClient().ListExportTemplates()
+		if err != nil {
+			return fmt.Errorf("failed to list export templates: %w", err)
+		}
+		if jsonRequested(cmd) {
+			return printJSON(cmd, templates)
+		}
+		if len(templates) == 0 {
+			fmt.Println("No export templates found.")
+			return nil
+		}
+
+		fmt.Printf("%-30s  %-15s  %s\n", "NAME", "UPDATED BY", "DESCRIPTION")
+		for _, template := range templates {
+			fmt.Printf("%-30v  %-15s  %s\n", template["name"], valueOr(template["updated_by"], "-"), valueOr(template["description"], ""))
+		}
+		return nil
+	},
+}
+
+// dataTemplatesShowCmd represents the data templates show command
+var dataTemplatesShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show an export template",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		template, err := client.NewClient().GetExportTemplate(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get export template: %w", err)
+		}
+		return printJSON(cmd, template)
+	},
+}
+
+// dataTemplatesSaveCmd represents the data templates save command
+var dataTemplatesSaveCmd = &cobra.Command{
+	Use:   "save <name> <definition_file>",
+	Short: "Create or replace an export template (admin only)",
+	Long: `Create or replace an export template from a JSON definition file, for example:
+
+  {
+    "form_types": ["household_visit"],
+    "columns": ["data_village", "data_household_size", "data_phone"],
+    "masks": [{"column": "data_phone", "method": "hash"}]
+  }
+
+Masked columns are redacted or replaced with a keyed hash. The key is kept per template,
+so the same value hashes the same way in every delivery of a template.`,
+	Example: `  synk data templates save monthly-partner partner.json --description "Monthly partner delivery"`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		description, _ := cmd.Flags().GetString("description")
+		definition, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("error reading definition file: %w", err)
+		}
+		if !json.Valid(definition) {
+			return fmt.Errorf("definition file %s is not valid JSON", args[1])
+		}
+		cmd.SilenceUsage = true
+
+		if _, err := client.NewClient().SaveExportTemplate(args[0], description, definition); err != nil {
+			return fmt.Errorf("failed to save export template: %w", err)
+		}
+		utils.PrintSuccess("Export template %s saved", args[0])
+		return nil
+	},
+}
+
+// dataTemplatesDeleteCmd represents the data templates delete command
+var dataTemplatesDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete an export template (admin only)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := client.NewClient().DeleteExportTemplate(args[0]); err != nil {
+			return fmt.Errorf("failed to delete export template: %w", err)
+		}
+		utils.PrintSuccess("Export template %s deleted", args[0])
+		return nil
+	},
+}
+
+// dataPullXLSXCmd represents the data pull-xlsx command
+var dataPullXLSXCmd = &cobra.Command{
+	Use:   "pull-xlsx",
+	Short: "Pull the records of a form into an XLSX spreadsheet",
+	Long: `Pull every record of a form type through the sync API, flatten it into one row per
+observation and save it as an XLSX spreadsheet. Nested form fields become dotted columns.
+
+This works with read access to sync data and does not need the export API, but it holds all
+records in memory, so it refuses datasets larger than --max-records. Use 'synk data export'
+for large datasets.
+
+Examples:
+  synk data pull-xlsx --form-type survey -o survey.xlsx
+  synk data pull-xlsx --form-type household_visit --max-records 50000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formType, _ := cmd.Flags().GetString("form-type")
+		outputFile, _ := cmd.Flags().GetString("output")
+		clientID, _ := cmd.Flags().GetString("client-id")
+		maxRecords, _ := cmd.Flags().GetInt("max-records")
+		if formType == "" {
+			return fmt.Errorf("--form-type is required")
+		}
+		if outputFile == "" {
+			outputFile = formType + ".xlsx"
+		}
+
+		c := client.NewClient()
+		records, err := c.SyncPullAll(clientID, []string{formType}, maxRecords)
+		if errors.Is(err, client.ErrTooManyRecords) {
+			return fmt.Errorf("%w; raise --max-records or use 'synk data export'", err)
+		} else if err != nil {
+			return fmt.Errorf("sync pull failed: %w", err)
+		}
+
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer f.Close()
+		if err := spreadsheet.WriteXLSX(f, formType, spreadsheet.Flatten(records)); err != nil {
+			return fmt.Errorf("error writing spreadsheet: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("error writing spreadsheet: %w", err)
+		}
+
+		fmt.Printf("Saved %d %s records to %s\n", len(records), formType, outputFile)
+		return nil
+	},
+}
+
+// dataDiffCmd represents the data diff command
+var dataDiffCmd = &cobra.Command{
+	Use:   "diff <old_file> <new_file>",
+	Short: "Compare the records of two pull outputs",
+	Long: `Compare two files written by 'synk sync pull', or two JSON arrays of records, and report
+the records that were added, removed or changed, with the fields that changed.
+
+Records are matched by --key, which may be a nested field such as data.household_id.
+Nested fields are compared one by one and arrays as a whole. Use --ignore to skip fields
+that change on every sync, such as version.
+
+Examples:
+  synk data diff old.json new.json --key observation_id
+  synk data diff before.json after.json --ignore version,synced_at,updated_at
+  synk data diff before.json after.json --json > changes.json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, _ := cmd.Flags().GetString("key")
+		ignore, _ := cmd.Flags().GetStringSlice("ignore")
+		cmd.SilenceUsage = true
+
+		oldRecords, err := datadiff.LoadRecords(args[0])
+		if err != nil {
+			return fmt.Errorf("error reading old file: %w", err)
+		}
+		newRecords, err := datadiff.LoadRecords(args[1])
+		if err != nil {
+			return fmt.Errorf("error reading new file: %w", err)
+		}
+		result, err := datadiff.Diff(oldRecords, newRecords, key, ignore)
+		if err != nil {
+			return err
+		}
+
+		if jsonRequested(cmd) {
+			return printJSON(cmd, result)
+		}
+		printDataDiff(result)
+		return nil
+	},
+}
+
+// printDataDiff prints a record diff for reading in the terminal
+func printDataDiff(result datadiff.Result) {
+	utils.PrintHeading("Summary")
+	fmt.Println(utils.FormatKeyValue("Added", len(result.Added)))
+	fmt.Println(utils.FormatKeyValue("Removed", len(result.Removed)))
+	fmt.Println(utils.FormatKeyValue("Changed", len(result.Changed)))
+	fmt.Println(utils.FormatKeyValue("Unchanged", result.Unchanged))
+	if result.Empty() {
+		return
+	}
+
+	if len(result.Added) > 0 {
+		fmt.Println()
+		utils.PrintHeading("Added")
+		for _, key := range result.Added {
+			fmt.Println(utils.Success("+ " + key))
+		}
+	}
+	if len(result.Removed) > 0 {
+		fmt.Println()
+		utils.PrintHeading("Removed")
+		for _, key := range result.Removed {
+			fmt.Println(utils.Error("- " + key))
+		}
+	}
+	if len(result.Changed) > 0 {
+		fmt.Println()
+		utils.PrintHeading("Changed")
+		for _, record := range result.Changed {
+			fmt.Println(utils.Warning("~ " + record.Key))
+			for _, field := range record.Fields {
+				fmt.Printf("    %s: %s -> %s\n", field.Field, formatDiffValue(field.Old), formatDiffValue(field.New))
+			}
+		}
+	}
+}
+
+// formatDiffValue formats a field value as compact JSON, or "(missing)" for absent fields
+func formatDiffValue(value any) string {
+	if value == nil {
+		return utils.Gray("(missing)")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
 func init() {
+	dataDiffCmd.Flags().String("key", "observation_id", "Field that identifies a record")
+	dataDiffCmd.Flags().StringSlice("ignore", nil, "Fields to leave out of the comparison")
+	dataDiffCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+
+	dataPullXLSXCmd.Flags().String("form-type", "", "Form type to pull (required)")
+	dataPullXLSXCmd.Flags().StringP("output", "o", "", "Output file (default <form-type>.xlsx)")
+	dataPullXLSXCmd.Flags().String("client-id", "synk-cli", "Client ID to pull as")
+	dataPullXLSXCmd.Flags().Int("max-records", 10000, "Maximum number of records to pull")
+
+	dataExportCmd.Flags().String("template", "", "Saved export template to apply")
+
+	dataTemplatesListCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	dataTemplatesSaveCmd.Flags().String("description", "", "Description of the template")
+	dataTemplatesCmd.AddCommand(dataTemplatesListCmd)
+	dataTemplatesCmd.AddCommand(dataTemplatesShowCmd)
+	dataTemplatesCmd.AddCommand(dataTemplatesSaveCmd)
+	dataTemplatesCmd.AddCommand(dataTemplatesDeleteCmd)
+
 	dataCmd.AddCommand(dataExportCmd)
+	dataCmd.AddCommand(dataTemplatesCmd)
+	dataCmd.AddCommand(dataPullXLSXCmd)
+	dataCmd.AddCommand(dataDiffCmd)
 	rootCmd.AddCommand(dataCmd)
 }
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/fleet"
+	"github.com/spf13/cobra"
+)
+
+// fleetCommands are the read-only commands that may run against every profile at once
+var fleetCommands = map[string]bool{
+	"version":              true,
+	"health":               true,
+	"status":               true,
+	"auth status":          true,
+	"app-bundle versions":  true,
+	"app-bundle changes":   true,
+	"app-bundle changelog": true,
+}
+
+// fleetCmd represents the fleet command group
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Run commands against every configured server",
+}
+
+// fleetRunCmd represents the 'fleet run' command
+var fleetRunCmd = &cobra.Command{
+	Use:   "run <command> [args...]",
+	Short: "Run a read-only command against every server profile at once",
+	Long: `Run a read-only command with every server profile concurrently and show the results
+in one table, with a row per profile and a column per value the command prints.
+
+Profiles are the config files $HOME/.synkronus.yaml (named "default") and
+$HOME/.synkronus-<name>.yaml (named <name>), plus the file chosen with 'synk config use'.
+Use --profiles to pick config files explicitly. Flags for fleet run go before the command.
+
+Only read-only commands can run: ` + strings.Join(sortedFleetCommands(), ", ") + `.`,
+	Example: `  synk fleet run version
+  synk fleet run --parallel 4 health
+  synk fleet run --profiles $HOME/.synkronus-kenya.yaml,$HOME/.synkronus-malawi.yaml app-bundle versions
+  synk fleet run --raw status`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		target, _, err := rootCmd.Find(args)
+		if err != nil || target == rootCmd {
+			return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+		}
+		name := strings.TrimPrefix(target.CommandPath(), rootCmd.Name()+" ")
+		if !fleetCommands[name] {
+			return fmt.Errorf("'%s' is not a read-only command; fleet run supports %s", name, strings.Join(sortedFleetCommands(), ", "))
+		}
+
+		paths, _ := cmd.Flags().GetStringSlice("profiles")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		raw, _ := cmd.Flags().GetBool("raw")
+
+		var profiles []fleet.Profile
+		if len(paths) > 0 {
+			profiles = fleet.FromPaths(paths)
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("error getting home directory: %w", err)
+			}
+			if profiles, err = fleet.Discover(home); err != nil {
+				return fmt.Errorf("failed to find profiles: %w", err)
+			}
+		}
+		if len(profiles) == 0 {
+			return fmt.Errorf("no profiles found; create them with 'synk config init -o ~/.synkronus-<name>.yaml'")
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find the synk executable: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		utils.PrintInfo("Running '%s' against %d profiles...", name, len(profiles))
+		results := fleet.Run(ctx, executable, profiles, args, parallel)
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+		}
+
+		if raw {
+			for _, result := range results {
+				fmt.Println()
+				utils.PrintHeading("%s (%s)", result.Profile.Name, result.Profile.Path)
+				fmt.Print(result.Stdout)
+				if result.Err != nil {
+					fmt.Print(utils.Error(result.Stderr))
+				}
+			}
+		} else {
+			header, rows := fleet.Table(results)
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, strings.Join(header, "\t"))
+			for _, row := range rows {
+				fmt.Fprintln(w, strings.Join(row, "\t"))
+			}
+			w.Flush()
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("'%s' failed on %d of %d profiles", name, failed, len(profiles))
+		}
+		return nil
+	},
+}
+
+// sortedFleetCommands lists the commands fleet run supports
+func sortedFleetCommands() []string {
+	var names []string
+	for name := range fleetCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	// Flags after the command name belong to that command
+	fleetRunCmd.Flags().SetInterspersed(false)
+	fleetRunCmd.Flags().StringSlice("profiles", nil, "Config files to use instead of the discovered profiles")
+	fleetRunCmd.Flags().Int("parallel", 8, "Number of profiles to run at the same time")
+	fleetRunCmd.Flags().Bool("raw", false, "Print each profile's full output instead of a table")
+
+	fleetCmd.AddCommand(fleetRunCmd)
+	rootCmd.AddCommand(fleetCmd)
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/formpreview"
+	"github.com/spf13/cobra"
+)
+
+// formsCmd represents the forms command group
+var formsCmd = &cobra.Command{
+	Use:   "forms",
+	Short: "Tools for designing forms",
+}
+
+// previewFormsCmd represents the 'forms preview' command
+var previewFormsCmd = &cobra.Command{
+	Use:   "preview <form-dir>",
+	Short: "Preview a form's schema.json and ui.json locally",
+	Long: `Preview a form in the browser without uploading an app bundle.
+
+The form directory holds schema.json and ui.json, e.g. forms/user of a bundle. The
+preview serves a page approximating the formplayer: one screen per SwipeLayout page,
+with the observation data shown as you answer. Questions answered with device features
+such as photos or signatures are shown as placeholders. The page reloads itself when
+either file changes, so keep it open while editing.
+
+With --text an outline of the form is printed to the terminal instead.`,
+	Example: `  synk forms preview ./forms/user
+  synk forms preview ./forms/user --port 9100
+  synk forms preview ./forms/user --text`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+		text, _ := cmd.Flags().GetBool("text")
+		host, _ := cmd.Flags().GetString("host")
+		port, _ := cmd.Flags().GetInt("port")
+
+		form, err := formpreview.Load(dir)
+		if err != nil {
+			return err
+		}
+		if text {
+			return formpreview.WriteText(os.Stdout, form)
+		}
+
+		server := &http.Server{
+			Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
+			Handler:           formpreview.Handler(dir),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		utils.PrintHeading("Previewing form %s on http://%s", form.Name, server.Addr)
+		for _, problem := range form.Problems {
+			utils.PrintWarning("%s", problem)
+		}
+		fmt.Println(utils.Gray("Press Ctrl+C to stop"))
+
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("preview server failed: %w", err)
+		}
+		return nil
+	},
+}
+
+// convertFormsCmd represents the 'forms convert' command
+var convertFormsCmd = &cobra.Command{
+	Use:   "convert <form.xlsx>",
+	Short: "Convert an ODK XLSForm into a bundle form",
+	Long: `Convert an ODK XLSForm into the schema.json and ui.json of a bundle form (admin only).
+
+The server converts the survey, choices and settings sheets: every top-level question or
+group becomes a page, select questions keep their choice labels and repeats become lists.
+The files are written to forms/<form_id> under --output, ready to preview with
+'synk forms preview' and to add to an app bundle.
+
+XPath logic (relevant, constraint, calculation, choice filters), metadata and question
+types the formplayer has no equivalent for are not converted; they are listed with their
+sheet and row so they can be rebuilt by hand.`,
+	Example: `  synk forms convert household.xlsx
+  synk forms convert household.xlsx --output ./my-bundle --force
+  synk forms convert household.xlsx --json --query 'unsupported[].message'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+
+		conversion, err := client.NewClient().ConvertXLSForm(args[0])
+		if err != nil {
+			return fmt.Errorf("conversion failed: %w", err)
+		}
+		if jsonRequested(cmd) {
+			return printJSON(cmd, conversion)
+		}
+
+		dir := filepath.Join(output, "forms", filepath.Base(conversion.FormType))
+		files := map[string]json.RawMessage{"schema.json": conversion.Schema, "ui.json": conversion.UI}
+		if !force {
+			for name := range files {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					return fmt.Errorf("%s already exists; use --force to overwrite it", filepath.Join(dir, name))
+				}
+			}
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating %s: %w", dir, err)
+		}
+		for name, content := range files {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, content, "", "  "); err != nil {
+				return fmt.Errorf("invalid %s in the response: %w", name, err)
+			}
+			indented.WriteString("\n")
+			if err := os.WriteFile(filepath.Join(dir, name), indented.Bytes(), 0644); err != nil {
+				return fmt.Errorf("error writing %s: %w", name, err)
+			}
+		}
+
+		utils.PrintSuccess("Converted %s to %s", args[0], dir)
+		if len(conversion.Unsupported) == 0 {
+			return nil
+		}
+		utils.PrintHeading("%d parts of the XLSForm were not converted", len(conversion.Unsupported))
+		for _, issue := range conversion.Unsupported {
+			location := fmt.Sprintf("%s row %d", issue.Sheet, issue.Row)
+			if issue.Name != "" {
+				location += " (" + issue.Name + ")"
+			}
+			utils.PrintWarning("%s: %s", location, issue.Message)
+		}
+		return nil
+	},
+}
+
+func init() {
+	convertFormsCmd.Flags().StringP("output", "o", ".", "Bundle directory the form is written to")
+	convertFormsCmd.Flags().Bool("force", false, "Overwrite an existing schema.json and ui.json")
+	convertFormsCmd.Flags().BoolP("json", "j", false, "Print the conversion in JSON format instead of writing files")
+
+	previewFormsCmd.Flags().Bool("text", false, "Print an outline of the form instead of serving a preview")
+	previewFormsCmd.Flags().String("host", "127.0.0.1", "Address to listen on")
+	previewFormsCmd.Flags().IntP("port", "p", 8090, "Port to listen on")
+
+	formsCmd.AddCommand(previewFormsCmd)
+	formsCmd.AddCommand(convertFormsCmd)
+	rootCmd.AddCommand(formsCmd)
+}
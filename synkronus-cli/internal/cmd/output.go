@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/jsonquery"
+	"github.com/spf13/cobra"
+)
+
+// checkQuery rejects --query on commands without JSON output, and invalid expressions, before the
+// command does any work
+func checkQuery(cmd *cobra.Command, args []string) error {
+	expression, _ := cmd.Flags().GetString("query")
+	if expression == "" {
+		return nil
+	}
+	if cmd.Flags().Lookup("json") == nil {
+		return fmt.Errorf("'%s' has no JSON output to query", cmd.CommandPath())
+	}
+	if _, err := jsonquery.Compile(expression); err != nil {
+		return err
+	}
+	return nil
+}
+
+// jsonRequested reports whether the command should print JSON, either because --json or --query
+// was given
+func jsonRequested(cmd *cobra.Command) bool {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	expression, _ := cmd.Flags().GetString("query")
+	return jsonOutput || expression != ""
+}
+
+// printJSON prints value as indented JSON, or the result of the --query expression when one is
+// given
+func printJSON(cmd *cobra.Command, value any) error {
+	expression, _ := cmd.Flags().GetString("query")
+	if expression == "" {
+		jsonData, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error formatting JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	query, err := jsonquery.Compile(expression)
+	if err != nil {
+		return err
+	}
+	result, err := query.Apply(value)
+	if err != nil {
+		return err
+	}
+	output, err := jsonquery.Format(result)
+	if err != nil {
+		return err
+	}
+	fmt.Println(output)
+	return nil
+}
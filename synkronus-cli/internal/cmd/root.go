@@ -18,6 +18,7 @@ var (
 		Short: "Synkronus CLI - A command-line interface for the Synkronus API",
 		Long: `Synkronus CLI is a command-line tool for interacting with the Synkronus API.
 It provides functionality for authentication, sync operations, app bundle management, and more.`,
+		PersistentPreRunE: checkQuery,
 	}
 )
 
@@ -75,6 +76,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.synkronus.yaml)")
 	rootCmd.PersistentFlags().String("api-url", "http://localhost:8080", "Synkronus API URL")
 	rootCmd.PersistentFlags().String("api-version", "1.0.0", "API version to use")
+	rootCmd.PersistentFlags().StringP("query", "q", "", "JMESPath expression applied to the JSON output, e.g. 'versions[0]'")
 
 	viper.BindPFlag("api.url", rootCmd.PersistentFlags().Lookup("api-url"))
 	viper.BindPFlag("api.version", rootCmd.PersistentFlags().Lookup("api-version"))
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/config"
 	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/outbox"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
@@ -26,9 +30,14 @@ func init() {
 		Short: "Pull data from the server",
 		Long: `Pull updated records from the Synkronus API server and save the response to a file.
 
+With --stream the server sends the records one at a time as they are read, and they are
+written to the file as they arrive, one JSON record per line (NDJSON), so large pulls are
+never held in memory.
+
 Examples:
   synk sync pull output.json --client-id my-client
-  synk sync pull data.json --client-id my-client --current-version 123 --limit 100`,
+  synk sync pull data.json --client-id my-client --current-version 123 --limit 100
+  synk sync pull records.ndjson --client-id my-client --stream`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			outputFile := args[0]
@@ -77,7 +86,15 @@ Examples:
 				fmt.Printf("Page Token: %s\n", pageToken)
 			}
 
+			stream, err := cmd.Flags().GetBool("stream")
+			if err != nil {
+				return err
+			}
+
 			c := client.NewClient()
+			if stream {
+				return streamPull(c, outputFile, clientID, currentVersion, schemaTypesStr, limit, pageToken)
+			}
 			response, err := c.SyncPull(clientID, currentVersion, schemaTypesStr, limit, pageToken)
 			if err != nil {
 				return fmt.Errorf("sync pull failed: %w", err)
@@ -117,6 +134,7 @@ Examples:
 	pullCmd.Flags().StringSlice("schema-types", []string{}, "Comma-separated list of schema types to filter")
 	pullCmd.Flags().Int("limit", 0, "Maximum number of records to return")
 	pullCmd.Flags().String("page-token", "", "Pagination token from previous response")
+	pullCmd.Flags().Bool("stream", false, "Stream the records and write them to the file as NDJSON, one record per line")
 	pullCmd.MarkFlagRequired("client-id")
 	syncCmd.AddCommand(pullCmd)
 
@@ -124,8 +142,12 @@ Examples:
 	pushCmd := &cobra.Command{
 		Use:   "push [file]",
 		Short: "Push data to the server",
-		Long:  `Push new or updated records to the Synkronus API server.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Push new or updated records to the Synkronus API server.
+
+Failures that may pass on a later attempt (the server is unreachable, times out or is briefly
+unavailable) are retried with increasing pauses. With --queue, a push that still fails is kept
+in a local outbox instead, to be sent later with 'synk sync flush'.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			inputFile := args[0]
 
@@ -196,62 +218,410 @@ Examples:
 				transmissionID = uuid.New().String()
 			}
 
+			retries, _ := cmd.Flags().GetInt("retries")
 			c := client.NewClient()
-			response, err := c.SyncPush(clientID, transmissionID, recordsFormatted)
+			response, err := pushWithRetry(c, retries, clientID, transmissionID, recordsFormatted)
 			if err != nil {
-				return fmt.Errorf("sync push failed: %w", err)
+				queue, _ := cmd.Flags().GetBool("queue")
+				if !queue || !client.IsRetryable(err) {
+					return fmt.Errorf("sync push failed: %w", err)
+				}
+				return queuePush(cmd, outbox.Entry{
+					TransmissionID: transmissionID,
+					ClientID:       clientID,
+					Server:         c.BaseURL,
+					Records:        recordsFormatted,
+					Attempts:       retries + 1,
+					LastError:      err.Error(),
+				})
 			}
 
 			// Format output as JSON
-			jsonOutput, err := cmd.Flags().GetBool("json")
+			if jsonRequested(cmd) {
+				return printJSON(cmd, response)
+			}
+
+			printPushResponse(response)
+			return nil
+		},
+	}
+	pushCmd.Flags().String("client-id", "", "Client ID for synchronization")
+	pushCmd.Flags().String("transmission-id", "", "Unique ID for this transmission (for idempotency)")
+	pushCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	pushCmd.Flags().Int("retries", 3, "Times to retry a push that failed because the server was unreachable")
+	pushCmd.Flags().Bool("queue", false, "Keep the push in the local outbox if the server stays unreachable")
+	syncCmd.AddCommand(pushCmd)
+
+	// Flush command
+	flushCmd := &cobra.Command{
+		Use:   "flush",
+		Short: "Send pushes queued while the server was unreachable",
+		Long: `Send the pushes kept in the local outbox by 'synk sync push --queue', oldest first.
+
+Each push keeps its transmission ID, so a payload that reached the server before the connection
+dropped is not applied twice. Sent pushes leave the outbox. Flushing stops at the first push that
+fails because the server is still unreachable; pushes the server rejects stay queued with their
+error for inspection. Pushes queued for another server are left alone.
+
+Examples:
+  synk sync flush --list
+  synk sync flush`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			box, err := openOutbox()
+			if err != nil {
+				return err
+			}
+			entries, err := box.List()
 			if err != nil {
 				return err
 			}
 
-			if jsonOutput {
-				jsonData, err := json.MarshalIndent(response, "", "  ")
-				if err != nil {
-					return fmt.Errorf("error formatting JSON: %w", err)
+			if list, _ := cmd.Flags().GetBool("list"); list {
+				if jsonRequested(cmd) {
+					return printJSON(cmd, entries)
+				}
+				if len(entries) == 0 {
+					fmt.Println("The outbox is empty")
+					return nil
+				}
+				for _, entry := range entries {
+					fmt.Printf("%s  %s  %d records  %s  attempts: %d\n",
+						entry.QueuedAt.Local().Format("2006-01-02 15:04:05"), entry.TransmissionID,
+						len(entry.Records), entry.Server, entry.Attempts)
+					if entry.LastError != "" {
+						fmt.Printf("    last error: %s\n", entry.LastError)
+					}
 				}
-				fmt.Println(string(jsonData))
 				return nil
 			}
 
-			// Display formatted output
-			fmt.Println("Sync Push Results:")
-			fmt.Printf("Server Data Version: %v\n", response["current_version"])
-			fmt.Printf("Success Count: %v\n", response["success_count"])
+			retries, _ := cmd.Flags().GetInt("retries")
+			c := client.NewClient()
+			results := make([]flushResult, 0, len(entries))
+			sent, failed, skipped := 0, 0, 0
+			for _, entry := range entries {
+				if entry.Server != c.BaseURL {
+					skipped++
+					continue
+				}
 
-			if failedRecords, ok := response["failed_records"].([]interface{}); ok && len(failedRecords) > 0 {
-				fmt.Printf("Failed Records: %d\n", len(failedRecords))
-				for _, record := range failedRecords {
-					recordMap, ok := record.(map[string]interface{})
-					if ok {
-						fmt.Printf("  - ID: %s, Error: %s\n",
-							recordMap["id"],
-							recordMap["error"])
+				response, err := pushWithRetry(c, retries, entry.ClientID, entry.TransmissionID, entry.Records)
+				if err == nil {
+					if err := box.Remove(entry); err != nil {
+						return err
+					}
+					sent++
+					results = append(results, flushResult{TransmissionID: entry.TransmissionID, Status: "sent", Response: response})
+					if !jsonRequested(cmd) {
+						fmt.Printf("Sent %s (%d records)\n", entry.TransmissionID, len(entry.Records))
 					}
+					continue
 				}
-			}
 
-			if warnings, ok := response["warnings"].([]interface{}); ok && len(warnings) > 0 {
-				fmt.Printf("Warnings: %d\n", len(warnings))
-				for _, warning := range warnings {
-					warningMap, ok := warning.(map[string]interface{})
-					if ok {
-						fmt.Printf("  - ID: %s, Code: %s, Message: %s\n",
-							warningMap["id"],
-							warningMap["code"],
-							warningMap["message"])
+				entry.Attempts += retries + 1
+				entry.LastError = err.Error()
+				if updateErr := box.Update(entry); updateErr != nil {
+					return updateErr
+				}
+				if client.IsRetryable(err) {
+					if jsonRequested(cmd) {
+						results = append(results, flushResult{TransmissionID: entry.TransmissionID, Status: "pending", Error: err.Error()})
+						printJSON(cmd, results)
 					}
+					return fmt.Errorf("server still unreachable, %d pushes remain queued: %w", len(entries)-sent, err)
+				}
+				failed++
+				results = append(results, flushResult{TransmissionID: entry.TransmissionID, Status: "failed", Error: err.Error()})
+				if !jsonRequested(cmd) {
+					fmt.Printf("Failed to send %s: %v\n", entry.TransmissionID, err)
 				}
 			}
 
+			if jsonRequested(cmd) {
+				if err := printJSON(cmd, results); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("Sent %d queued pushes\n", sent)
+				if skipped > 0 {
+					fmt.Printf("Left %d pushes queued for other servers\n", skipped)
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d queued pushes could not be sent; see 'synk sync flush --list'", failed)
+			}
 			return nil
 		},
 	}
-	pushCmd.Flags().String("client-id", "", "Client ID for synchronization")
-	pushCmd.Flags().String("transmission-id", "", "Unique ID for this transmission (for idempotency)")
-	pushCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
-	syncCmd.AddCommand(pushCmd)
+	flushCmd.Flags().Bool("list", false, "List the queued pushes without sending them")
+	flushCmd.Flags().Int("retries", 3, "Times to retry a push that failed because the server was unreachable")
+	flushCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	syncCmd.AddCommand(flushCmd)
+
+	// Compact command
+	compactCmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Purge old deletions and superseded versions from the server's sync log",
+		Long: `Compact the server's sync log (admin only).
+
+Records deleted longer ago than the tombstone retention are purged, and record versions
+superseded longer ago than the history retention are collapsed into the latest one. Omitted
+retentions use the server's configuration. Clients that last pulled before purged deletions are
+told to pull again from scratch, and as-of reads before the compacted versions are refused.
+
+Examples:
+  synk sync compact --dry-run
+  synk sync compact --tombstone-days 30 --history-days 180
+  synk sync compact --list`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := client.NewClient()
+
+			if list, _ := cmd.Flags().GetBool("list"); list {
+				limit, _ := cmd.Flags().GetInt("limit")
+				compactions, err := c.ListSyncCompactions(limit)
+				if err != nil {
+					return fmt.Errorf("failed to list compactions: %w", err)
+				}
+				if jsonRequested(cmd) {
+					return printJSON(cmd, compactions)
+				}
+				if len(compactions) == 0 {
+					fmt.Println("The sync log has not been compacted")
+					return nil
+				}
+				for _, compaction := range compactions {
+					fmt.Printf("%v  by %v  tombstones purged: %v (to version %v)  versions removed: %v (to version %v)\n",
+						compaction["finished_at"], compaction["triggered_by"],
+						compaction["tombstones_purged"], compaction["tombstone_cutoff_version"],
+						compaction["history_versions_removed"], compaction["history_cutoff_version"])
+				}
+				return nil
+			}
+
+			req := client.CompactionRequest{}
+			req.TombstoneRetentionDays, _ = cmd.Flags().GetInt("tombstone-days")
+			req.HistoryRetentionDays, _ = cmd.Flags().GetInt("history-days")
+			req.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			if req.TombstoneRetentionDays < 0 || req.HistoryRetentionDays < 0 {
+				return fmt.Errorf("retention days must not be negative")
+			}
+
+			report, err := c.CompactSyncLog(req)
+			if err != nil {
+				return fmt.Errorf("compaction failed: %w", err)
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, report)
+			}
+
+			verb := "Purged"
+			if req.DryRun {
+				verb = "Would purge"
+			}
+			fmt.Printf("%s %v deleted records up to version %v\n", verb, report["tombstones_purged"], report["tombstone_cutoff_version"])
+			fmt.Printf("%s %v superseded versions up to version %v\n", verb, report["history_versions_removed"], report["history_cutoff_version"])
+			return nil
+		},
+	}
+	compactCmd.Flags().Int("tombstone-days", 0, "Purge records deleted more than this many days ago (default: server setting)")
+	compactCmd.Flags().Int("history-days", 0, "Collapse versions superseded more than this many days ago (default: server setting)")
+	compactCmd.Flags().Bool("dry-run", false, "Report what would be removed without removing it")
+	compactCmd.Flags().Bool("list", false, "List recent compactions instead of compacting")
+	compactCmd.Flags().Int("limit", 20, "Number of compactions to list")
+	compactCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	syncCmd.AddCommand(compactCmd)
+
+	// Verify receipt command
+	verifyReceiptCmd := &cobra.Command{
+		Use:   "verify-receipt <observation_id> <version> <code>",
+		Short: "Check the verification code of a submitted record",
+		Long: `Check a submission receipt: the code a push returned for a version of a record.
+
+A valid code proves that the server accepted that version of the record. Codes are read
+case-insensitively, with or without the dash.
+
+Examples:
+  synk sync verify-receipt household-0012 42 7KQ2-MX9D`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || version <= 0 {
+				return fmt.Errorf("version must be a positive number")
+			}
+
+			c := client.NewClient()
+			result, err := c.VerifySyncReceipt(args[0], version, args[2])
+			if err != nil {
+				return fmt.Errorf("failed to verify receipt: %w", err)
+			}
+			if jsonRequested(cmd) {
+				return printJSON(cmd, result)
+			}
+
+			if valid, _ := result["valid"].(bool); !valid {
+				return fmt.Errorf("the code was not issued for version %d of %s", version, args[0])
+			}
+			fmt.Printf("Valid: version %d of %s reached the server\n", version, args[0])
+			return nil
+		},
+	}
+	verifyReceiptCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	syncCmd.AddCommand(verifyReceiptCmd)
+}
+
+// flushResult is the outcome of sending one queued push
+type flushResult struct {
+	TransmissionID string                 `json:"transmission_id"`
+	Status         string                 `json:"status"`
+	Response       map[string]interface{} `json:"response,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+}
+
+// streamPull writes a streamed pull to outputFile, one record per line. The records go to a
+// partial file first, which only replaces outputFile once the stream is complete.
+func streamPull(c *client.Client, outputFile, clientID string, currentVersion int64, schemaTypes []string, limit int, pageToken string) error {
+	partial := outputFile + ".part"
+	file, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	count := 0
+	summary, err := c.SyncPullStream(clientID, currentVersion, schemaTypes, limit, pageToken, func(record json.RawMessage) error {
+		count++
+		if _, err := out.Write(record); err != nil {
+			return err
+		}
+		return out.WriteByte('\n')
+	})
+	if err != nil {
+		return fmt.Errorf("sync pull failed after %d records: %w", count, err)
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if err := os.Rename(partial, outputFile); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+
+	fmt.Printf("\nSync pull completed successfully!\n")
+	fmt.Printf("Records saved to: %s\n", outputFile)
+	fmt.Printf("Current Version: %v\n", summary["current_version"])
+	fmt.Printf("Records Retrieved: %d\n", count)
+	if hasMore, ok := summary["has_more"].(bool); ok && hasMore {
+		fmt.Printf("More data available. Use --current-version=%v for the next page\n", summary["change_cutoff"])
+	}
+	return nil
+}
+
+// openOutbox returns the outbox in the home directory
+func openOutbox() (*outbox.Outbox, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	return outbox.New(config.OutboxDirPath(home)), nil
+}
+
+// pushWithRetry sends a push, retrying failures that may pass on a later attempt. Retrying is
+// safe because the server applies a transmission ID only once.
+func pushWithRetry(c *client.Client, retries int, clientID, transmissionID string, records []map[string]interface{}) (map[string]interface{}, error) {
+	backoff := outbox.DefaultBackoff()
+	backoff.Attempts = retries + 1
+	var response map[string]interface{}
+	err := backoff.Retry(func() error {
+		var err error
+		response, err = c.SyncPush(clientID, transmissionID, records)
+		return err
+	}, client.IsRetryable)
+	return response, err
+}
+
+// queuePush keeps a push that could not be sent in the outbox
+func queuePush(cmd *cobra.Command, entry outbox.Entry) error {
+	box, err := openOutbox()
+	if err != nil {
+		return err
+	}
+	if entry, err = box.Add(entry); err != nil {
+		return fmt.Errorf("failed to queue push: %w", err)
+	}
+
+	if jsonRequested(cmd) {
+		return printJSON(cmd, map[string]interface{}{
+			"queued":          true,
+			"transmission_id": entry.TransmissionID,
+			"record_count":    len(entry.Records),
+			"error":           entry.LastError,
+		})
+	}
+	fmt.Printf("Server unreachable: %s\n", entry.LastError)
+	fmt.Printf("Queued %d records as transmission %s in %s\n", len(entry.Records), entry.TransmissionID, box.Dir())
+	fmt.Println("Send them later with 'synk sync flush'")
+	return nil
+}
+
+// printPushResponse displays the outcome of a push
+func printPushResponse(response map[string]interface{}) {
+	fmt.Println("Sync Push Results:")
+	fmt.Printf("Server Data Version: %v\n", response["current_version"])
+	fmt.Printf("Success Count: %v\n", response["success_count"])
+
+	if failedRecords, ok := response["failed_records"].([]interface{}); ok && len(failedRecords) > 0 {
+		fmt.Printf("Failed Records: %d\n", len(failedRecords))
+		for _, record := range failedRecords {
+			recordMap, ok := record.(map[string]interface{})
+			if ok {
+				fmt.Printf("  - ID: %s, Error: %s\n",
+					recordMap["id"],
+					recordMap["error"])
+			}
+		}
+	}
+
+	if warnings, ok := response["warnings"].([]interface{}); ok && len(warnings) > 0 {
+		fmt.Printf("Warnings: %d\n", len(warnings))
+		for _, warning := range warnings {
+			warningMap, ok := warning.(map[string]interface{})
+			if ok {
+				fmt.Printf("  - ID: %s, Code: %s, Message: %s\n",
+					warningMap["id"],
+					warningMap["code"],
+					warningMap["message"])
+			}
+		}
+	}
+
+	if conflicts, ok := response["conflicts"].([]interface{}); ok && len(conflicts) > 0 {
+		fmt.Printf("Conflicts: %d\n", len(conflicts))
+		for _, conflict := range conflicts {
+			conflictMap, ok := conflict.(map[string]interface{})
+			if ok {
+				fmt.Printf("  - ID: %s, Outcome: %s\n",
+					conflictMap["observation_id"],
+					conflictMap["outcome"])
+			}
+		}
+	}
+
+	if receipts, ok := response["receipts"].([]interface{}); ok && len(receipts) > 0 {
+		fmt.Printf("Receipts: %d\n", len(receipts))
+		for _, receipt := range receipts {
+			receiptMap, ok := receipt.(map[string]interface{})
+			if ok {
+				fmt.Printf("  - ID: %s, Version: %v, Code: %s\n",
+					receiptMap["observation_id"],
+					receiptMap["version"],
+					receiptMap["code"])
+			}
+		}
+	}
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/internal/utils"
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/webhooks"
+	"github.com/spf13/cobra"
+)
+
+// webhooksCmd represents the webhooks command group
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Tools for developing against Synkronus webhooks",
+}
+
+// listenWebhooksCmd represents the 'webhooks listen' command
+var listenWebhooksCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Run a local webhook receiver that prints deliveries",
+	Long: `Run a local receiver for Synkronus webhook deliveries and print each one.
+
+With --secret the signature of every delivery is verified and deliveries with a bad
+signature are rejected with 401, as a production receiver should. Use --status to
+answer with an error and watch the server retry.
+
+Subscribe the printed URL with POST /webhooks. The server blocks internal addresses,
+so a server on this machine needs OUTBOUND_ALLOWLIST to include the listen address.`,
+	Example: `  synk webhooks listen --port 9000 --secret my-subscription-secret
+  synk webhooks listen --port 9000 --status 503`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _ := cmd.Flags().GetString("host")
+		port, _ := cmd.Flags().GetInt("port")
+		path, _ := cmd.Flags().GetString("path")
+		secret, _ := cmd.Flags().GetString("secret")
+		status, _ := cmd.Flags().GetInt("status")
+		if status < 100 || status > 599 {
+			return fmt.Errorf("invalid --status %d", status)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle(path, &webhooks.Receiver{Secret: secret, Status: status, OnDelivery: printDelivery})
+		server := &http.Server{
+			Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		utils.PrintHeading("Listening for webhook deliveries on http://%s%s", server.Addr, path)
+		if secret == "" {
+			utils.PrintWarning("No --secret given; signatures are not verified")
+		}
+		fmt.Println(utils.Gray("Press Ctrl+C to stop"))
+
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("webhook receiver failed: %w", err)
+		}
+		return nil
+	},
+}
+
+// printDelivery prints a received delivery with its pretty-printed body
+func printDelivery(d webhooks.Delivery) {
+	id, event := d.ID, d.Event
+	if id == "" {
+		id = "-"
+	}
+	if event == "" {
+		event = "(no event header)"
+	}
+	fmt.Println()
+	utils.PrintHeading("%s  delivery %s  %s", d.ReceivedAt.Format(time.TimeOnly), id, event)
+	switch d.Signature {
+	case webhooks.SignatureValid:
+		fmt.Println(utils.FormatKeyValue("Signature", utils.Success("valid")))
+	case webhooks.SignatureInvalid:
+		fmt.Println(utils.FormatKeyValue("Signature", utils.Error("invalid, rejected with 401")))
+	default:
+		fmt.Println(utils.FormatKeyValue("Signature", utils.Gray("not checked")))
+	}
+
+	var body bytes.Buffer
+	if err := json.Indent(&body, d.Body, "", "  "); err != nil {
+		fmt.Println(string(d.Body))
+		return
+	}
+	fmt.Println(body.String())
+}
+
+func init() {
+	listenWebhooksCmd.Flags().String("host", "127.0.0.1", "Address to listen on; use 0.0.0.0 to accept deliveries from other machines")
+	listenWebhooksCmd.Flags().IntP("port", "p", 9000, "Port to listen on")
+	listenWebhooksCmd.Flags().String("path", "/", "URL path to receive deliveries on")
+	listenWebhooksCmd.Flags().String("secret", "", "Subscription secret to verify signatures with")
+	listenWebhooksCmd.Flags().Int("status", http.StatusOK, "HTTP status to answer correctly signed deliveries with")
+
+	webhooksCmd.AddCommand(listenWebhooksCmd)
+	rootCmd.AddCommand(webhooksCmd)
+}
@@ -17,3 +17,8 @@ func DefaultConfig() map[string]interface{} {
 func TokenFilePath(homeDir string) string {
 	return filepath.Join(homeDir, ".synkronus_token")
 }
+
+// OutboxDirPath returns the directory holding sync pushes queued while offline
+func OutboxDirPath(homeDir string) string {
+	return filepath.Join(homeDir, ".synkronus_outbox")
+}
@@ -0,0 +1,259 @@
+// Package backup packs the state of a Synkronus server into a single archive and unpacks it
+// again for a restore.
+//
+// An archive is a tar file, compressed with zstd or gzip depending on its extension, holding:
+//
+//	manifest.json        what the archive contains and where it came from
+//	users.json           users with their password hashes
+//	bundles/<v>.zip      every stored app bundle version
+//	observations.ndjson  every observation, one per line
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// FormatVersion is the archive layout version written by this package
+const FormatVersion = 1
+
+// Names of the entries in an archive
+const (
+	ManifestFile     = "manifest.json"
+	UsersFile        = "users.json"
+	ObservationsFile = "observations.ndjson"
+	BundlesDir       = "bundles"
+)
+
+// Manifest describes an archive
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Server        string    `json:"server"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	// Bundles lists the app bundle versions oldest first, the order they are pushed in on restore
+	Bundles []string `json:"bundles"`
+	// CurrentBundle is the version that was active
+	CurrentBundle string `json:"current_bundle,omitempty"`
+	Users         int    `json:"users"`
+	Observations  int    `json:"observations"`
+}
+
+// BundlePath returns the archive path of an app bundle version
+func BundlePath(version string) string {
+	return path.Join(BundlesDir, version+".zip")
+}
+
+// compression returns the compression an archive path asks for by its extension
+func compression(archivePath string) (string, error) {
+	name := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return "zstd", nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "gzip", nil
+	case strings.HasSuffix(name, ".tar"):
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported archive name %q (expected .tar.zst, .tar.gz or .tar)", filepath.Base(archivePath))
+}
+
+// Pack writes the manifest and the files staged in dir to an archive at archivePath. The
+// manifest comes first so it can be read without unpacking the whole archive.
+func Pack(dir string, manifest *Manifest, archivePath string) (err error) {
+	kind, err := compression(archivePath)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	var compressed io.WriteCloser = nopWriteCloser{out}
+	switch kind {
+	case "zstd":
+		if compressed, err = zstd.NewWriter(out); err != nil {
+			return err
+		}
+	case "gzip":
+		compressed = gzip.NewWriter(out)
+	}
+	tw := tar.NewWriter(compressed)
+
+	manifest.FormatVersion = FormatVersion
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, ManifestFile, int64(len(manifestData)), strings.NewReader(string(manifestData))); err != nil {
+		return err
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel != ManifestFile {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		if err := packFile(tw, dir, name); err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// packFile adds a staged file to the archive
+func packFile(tw *tar.Writer, dir, name string) error {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeEntry(tw, name, info.Size(), f)
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{Name: name, Mode: 0600, Size: size, ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Unpack extracts an archive into dir and returns its manifest
+func Unpack(archivePath, dir string) (*Manifest, error) {
+	kind, err := compression(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	switch kind {
+	case "zstd":
+		decoder, err := zstd.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		r = decoder
+	case "gzip":
+		decoder, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		r = decoder
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("archive entry %q escapes the archive", header.Name)
+		}
+		if err := unpackFile(tr, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+
+	return ReadManifest(dir)
+}
+
+func unpackFile(r io.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ReadManifest reads the manifest of an unpacked archive
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("not a synk backup: %s is missing", ManifestFile)
+		}
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("backup format %d is newer than this synk supports (%d); upgrade synk", manifest.FormatVersion, FormatVersion)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func stage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPackUnpack(t *testing.T) {
+	files := map[string]string{
+		UsersFile:          `[{"username": "admin"}]`,
+		ObservationsFile:   "{\"observation_id\": \"a\"}\n",
+		BundlePath("0001"): "zip one",
+		BundlePath("0002"): "zip two",
+	}
+	manifest := &Manifest{
+		CreatedAt:     time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Server:        "https://synk.example.org",
+		Bundles:       []string{"0001", "0002"},
+		CurrentBundle: "0002",
+		Users:         1,
+		Observations:  1,
+	}
+
+	for _, name := range []string{"backup.tar.zst", "backup.tar.gz", "backup.tar"} {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), name)
+			if err := Pack(stage(t, files), manifest, archive); err != nil {
+				t.Fatalf("Pack failed: %v", err)
+			}
+
+			dir := t.TempDir()
+			got, err := Unpack(archive, dir)
+			if err != nil {
+				t.Fatalf("Unpack failed: %v", err)
+			}
+			if got.FormatVersion != FormatVersion || !reflect.DeepEqual(got.Bundles, manifest.Bundles) || got.CurrentBundle != "0002" || !got.CreatedAt.Equal(manifest.CreatedAt) {
+				t.Errorf("unexpected manifest %+v", got)
+			}
+			for name, content := range files {
+				data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil || string(data) != content {
+					t.Errorf("expected %s to hold %q, got %q (%v)", name, content, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPack_UnsupportedName(t *testing.T) {
+	if err := Pack(t.TempDir(), &Manifest{}, filepath.Join(t.TempDir(), "backup.zip")); err == nil {
+		t.Error("expected an error for a .zip archive name")
+	}
+}
+
+func TestUnpack_Rejects(t *testing.T) {
+	// An archive without a manifest is not a backup
+	archive := filepath.Join(t.TempDir(), "other.tar")
+	writeTar(t, archive, map[string]string{"notes.txt": "hello"})
+	if _, err := Unpack(archive, t.TempDir()); err == nil {
+		t.Error("expected an error for an archive without a manifest")
+	}
+
+	// Entries may not escape the target directory
+	archive = filepath.Join(t.TempDir(), "evil.tar")
+	writeTar(t, archive, map[string]string{ManifestFile: "{}", "../evil": "x"})
+	if _, err := Unpack(archive, t.TempDir()); err == nil {
+		t.Error("expected an error for an entry outside the archive")
+	}
+
+	// Newer formats are refused
+	archive = filepath.Join(t.TempDir(), "future.tar")
+	writeTar(t, archive, map[string]string{ManifestFile: `{"format_version": 99}`})
+	if _, err := Unpack(archive, t.TempDir()); err == nil {
+		t.Error("expected an error for a newer backup format")
+	}
+}
+
+func writeTar(t *testing.T, archive string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
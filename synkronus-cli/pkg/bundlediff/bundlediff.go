@@ -0,0 +1,140 @@
+// Package bundlediff builds differential app bundle ZIPs like the server's /app-bundle/diff
+// endpoint: DIFF.json at the top level, listing the files added or changed and the paths
+// removed, next to the changed files.
+package bundlediff
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DiffFile is the name of the description written at the top level of the ZIP
+const DiffFile = "DIFF.json"
+
+// File is a file added or changed in the new bundle
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// Diff is the content of DIFF.json. Diffs built locally leave out the manifest hashes and
+// versions the server sets, as bundle files do not carry them.
+type Diff struct {
+	Changed []File   `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// Write compares two bundle ZIPs by file content and writes the ZIP of the changes to w
+func Write(oldPath, newPath string, w io.Writer) (*Diff, error) {
+	oldBundle, err := zip.OpenReader(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", oldPath, err)
+	}
+	defer oldBundle.Close()
+	newBundle, err := zip.OpenReader(newPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", newPath, err)
+	}
+	defer newBundle.Close()
+
+	oldFiles, err := hashFiles(&oldBundle.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", oldPath, err)
+	}
+	newFiles, err := hashFiles(&newBundle.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", newPath, err)
+	}
+
+	diff := &Diff{Changed: []File{}, Removed: []string{}}
+	changed := make(map[string]*zip.File)
+	for _, file := range newBundle.File {
+		name, ok := bundlePath(file)
+		if !ok {
+			continue
+		}
+		hash := newFiles[name]
+		if oldFiles[name] == hash {
+			continue
+		}
+		diff.Changed = append(diff.Changed, File{Path: name, Size: int64(file.UncompressedSize64), Hash: hash})
+		changed[name] = file
+	}
+	for name := range oldFiles {
+		if _, ok := newFiles[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Path < diff.Changed[j].Path })
+	sort.Strings(diff.Removed)
+
+	zipWriter := zip.NewWriter(w)
+	dst, err := zipWriter.Create(DiffFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(dst).Encode(diff); err != nil {
+		return nil, err
+	}
+	for _, file := range diff.Changed {
+		if err := copyFile(zipWriter, changed[file.Path], file.Path); err != nil {
+			return nil, fmt.Errorf("failed to add %s: %w", file.Path, err)
+		}
+	}
+	return diff, zipWriter.Close()
+}
+
+// bundlePath returns the cleaned path of a bundle file, and false for directories and paths
+// leaving the bundle
+func bundlePath(file *zip.File) (string, bool) {
+	if file.FileInfo().IsDir() || strings.Contains(file.Name, "..") {
+		return "", false
+	}
+	return path.Clean(strings.ReplaceAll(file.Name, `\`, "/")), true
+}
+
+// hashFiles returns the hex SHA-256 of every file of a bundle by path
+func hashFiles(bundle *zip.Reader) (map[string]string, error) {
+	hashes := make(map[string]string, len(bundle.File))
+	for _, file := range bundle.File {
+		name, ok := bundlePath(file)
+		if !ok {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, src)
+		src.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		hashes[name] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return hashes, nil
+}
+
+// copyFile copies a file of a bundle into the diff
+func copyFile(zipWriter *zip.Writer, file *zip.File, name string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: file.Modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
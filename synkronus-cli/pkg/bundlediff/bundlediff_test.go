@@ -0,0 +1,88 @@
+package bundlediff
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeBundle(t *testing.T, name string, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	zipWriter := zip.NewWriter(out)
+	for name, content := range files {
+		dst, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst.Write([]byte(content))
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWrite(t *testing.T) {
+	oldBundle := writeBundle(t, "old.zip", map[string]string{
+		"app/index.html":           "<html></html>",
+		"forms/survey/schema.json": `{"title":"Survey"}`,
+		"forms/visit/schema.json":  `{"title":"Visit"}`,
+	})
+	newBundle := writeBundle(t, "new.zip", map[string]string{
+		"app/index.html":           "<html></html>",
+		"forms/survey/schema.json": `{"title":"Household survey"}`,
+		"forms/clinic/schema.json": `{"title":"Clinic"}`,
+	})
+
+	var buf bytes.Buffer
+	diff, err := Write(oldBundle, newBundle, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range diff.Changed {
+		paths = append(paths, file.Path)
+	}
+	if want := []string{"forms/clinic/schema.json", "forms/survey/schema.json"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("changed = %v, want %v", paths, want)
+	}
+	if want := []string{"forms/visit/schema.json"}; !reflect.DeepEqual(diff.Removed, want) {
+		t.Errorf("removed = %v, want %v", diff.Removed, want)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string]string)
+	for _, file := range archive.File {
+		src, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(src)
+		src.Close()
+		contents[file.Name] = string(data)
+	}
+	if len(contents) != 3 || contents["forms/survey/schema.json"] != `{"title":"Household survey"}` {
+		t.Errorf("unexpected diff files: %v", contents)
+	}
+	var written Diff
+	if err := json.Unmarshal([]byte(contents[DiffFile]), &written); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&written, diff) {
+		t.Errorf("DIFF.json = %+v, want %+v", written, diff)
+	}
+}
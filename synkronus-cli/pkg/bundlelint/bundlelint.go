@@ -0,0 +1,398 @@
+// Package bundlelint checks an app bundle, as a directory or a ZIP file, and reports every
+// problem found with its location, so CI runs on bundle repositories can annotate the offending
+// lines instead of stopping at the first validation error.
+package bundlelint
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/validation"
+)
+
+// Severity of a finding
+type Severity string
+
+const (
+	// SeverityError marks problems the server rejects or the formplayer cannot render
+	SeverityError Severity = "error"
+	// SeverityWarning marks likely mistakes that still produce a working bundle
+	SeverityWarning Severity = "warning"
+)
+
+// Rules reported by Lint
+const (
+	RuleUnexpectedDirectory = "unexpected-directory"
+	RuleMissingAppIndex     = "missing-app-index"
+	RuleInvalidFormPath     = "invalid-form-path"
+	RuleInvalidRendererPath = "invalid-renderer-path"
+	RuleIncompleteForm      = "incomplete-form"
+	RuleInvalidJSON         = "invalid-json"
+	RuleUnknownRenderer     = "unknown-renderer"
+	RuleUnknownScope        = "unknown-scope"
+	RuleMissingTitle        = "missing-title"
+	RuleEmptyRenderer       = "empty-renderer"
+
+	RuleInvalidExpression     = "invalid-expression"
+	RuleUnknownFieldReference = "unknown-field-reference"
+	RuleCircularLogic         = "circular-logic"
+	RuleAlwaysFalseLogic      = "always-false-logic"
+)
+
+// RuleDescriptions explains each rule, e.g. for SARIF rule metadata
+var RuleDescriptions = map[string]string{
+	RuleUnexpectedDirectory: "Bundles may only contain the app, forms and renderers directories",
+	RuleMissingAppIndex:     "Bundles must contain app/index.html",
+	RuleInvalidFormPath:     "Form directories may only contain schema.json and ui.json",
+	RuleInvalidRendererPath: "Renderers must be laid out as renderers/{name}/renderer.jsx",
+	RuleIncompleteForm:      "Every form needs both schema.json and ui.json",
+	RuleInvalidJSON:         "Form files must be valid JSON",
+	RuleUnknownRenderer:     "Referenced renderers must be built in or part of the bundle",
+	RuleUnknownScope:        "UI controls must point at a property of the form schema",
+	RuleMissingTitle:        "Form schemas should have a title shown to data collectors",
+	RuleEmptyRenderer:       "Renderer files should not be empty",
+
+	RuleInvalidExpression:     "x-visible-if and x-constraint must be valid form logic expressions",
+	RuleUnknownFieldReference: "Form logic may only refer to fields of the same form",
+	RuleCircularLogic:         "The visibility of a question must not depend on itself",
+	RuleAlwaysFalseLogic:      "Form logic should be satisfiable by some answer",
+}
+
+// Finding is a problem found in a bundle
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// File is the slash-separated path of the file within the bundle
+	File string `json:"file"`
+	// Line and Column are 1-based; zero when the finding concerns the whole file
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// HasErrors reports whether any finding is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks the bundle at bundlePath, a directory or a ZIP file. Findings are sorted by file
+// and line.
+func Lint(bundlePath string) ([]Finding, error) {
+	info, err := os.Stat(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return LintFS(os.DirFS(bundlePath))
+	}
+
+	zipFile, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer zipFile.Close()
+	return LintFS(zipFile)
+}
+
+// LintFS checks a bundle laid out at the root of fsys
+func LintFS(fsys fs.FS) ([]Finding, error) {
+	l := &linter{fsys: fsys, renderers: make(map[string]bool)}
+	if err := l.collect(); err != nil {
+		return nil, err
+	}
+	l.checkStructure()
+	l.checkForms()
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i], l.findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return l.findings, nil
+}
+
+// linter accumulates findings for one bundle
+type linter struct {
+	fsys      fs.FS
+	files     []string
+	forms     map[string]bool
+	renderers map[string]bool
+	findings  []Finding
+}
+
+func (l *linter) report(rule string, severity Severity, file string, line, column int, format string, args ...any) {
+	l.findings = append(l.findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		File:     file,
+		Line:     line,
+		Column:   column,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// collect lists the bundle's files, its forms and the renderers it ships
+func (l *linter) collect() error {
+	l.forms = make(map[string]bool)
+	return fs.WalkDir(l.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Version control and editor metadata of bundle repositories is not part of the bundle
+			if name != "." && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		l.files = append(l.files, name)
+		parts := strings.Split(name, "/")
+		if parts[0] == "forms" && len(parts) == 3 {
+			l.forms[parts[1]] = true
+		}
+		if parts[0] == "renderers" && len(parts) == 3 && parts[2] == "renderer.jsx" {
+			l.renderers[parts[1]] = true
+		}
+		return nil
+	})
+}
+
+// checkStructure reports files outside the expected bundle layout
+func (l *linter) checkStructure() {
+	hasAppIndex := false
+	seenDirs := make(map[string]bool)
+
+	for _, name := range l.files {
+		parts := strings.Split(name, "/")
+		switch {
+		case name == "app/index.html":
+			hasAppIndex = true
+		case parts[0] == "app":
+		case parts[0] == "forms":
+			if len(parts) != 3 || (parts[2] != "schema.json" && parts[2] != "ui.json") {
+				l.report(RuleInvalidFormPath, SeverityError, name, 0, 0,
+					"unexpected form file %s (expected forms/{name}/schema.json or forms/{name}/ui.json)", name)
+			}
+		case parts[0] == "renderers":
+			if len(parts) != 3 || parts[2] != "renderer.jsx" {
+				l.report(RuleInvalidRendererPath, SeverityError, name, 0, 0,
+					"unexpected renderer file %s (expected renderers/{name}/renderer.jsx)", name)
+				continue
+			}
+			if data, err := fs.ReadFile(l.fsys, name); err == nil && len(bytes.TrimSpace(data)) == 0 {
+				l.report(RuleEmptyRenderer, SeverityWarning, name, 0, 0, "renderer %s is empty", parts[1])
+			}
+		case len(parts) == 1:
+			// Loose files at the root, such as a README, are left out of uploads
+		default:
+			if !seenDirs[parts[0]] {
+				seenDirs[parts[0]] = true
+				l.report(RuleUnexpectedDirectory, SeverityError, name, 0, 0,
+					"unexpected top-level directory '%s'", parts[0])
+			}
+		}
+	}
+
+	if !hasAppIndex {
+		l.report(RuleMissingAppIndex, SeverityError, "app/index.html", 0, 0, "missing app/index.html")
+	}
+}
+
+// checkForms parses each form and checks the schema's renderer references and the UI's scopes
+func (l *linter) checkForms() {
+	names := make([]string, 0, len(l.forms))
+	for name := range l.forms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		schemaPath := path.Join("forms", name, "schema.json")
+		uiPath := path.Join("forms", name, "ui.json")
+
+		schema, schemaData, schemaOK := l.readForm(name, schemaPath)
+		ui, uiData, uiOK := l.readForm(name, uiPath)
+
+		if schemaOK {
+			if title, _ := schema["title"].(string); strings.TrimSpace(title) == "" {
+				l.report(RuleMissingTitle, SeverityWarning, schemaPath, 1, 1, "form schema of '%s' has no title", name)
+			}
+			l.checkRenderers(schemaPath, schemaData, schema)
+			l.checkLogic(schemaPath, schemaData, schema)
+		}
+		if uiOK && schemaOK {
+			l.checkScopes(uiPath, uiData, ui, schema)
+		}
+	}
+}
+
+// readForm reads and parses a form file, reporting it missing or malformed
+func (l *linter) readForm(form, name string) (map[string]any, []byte, bool) {
+	data, err := fs.ReadFile(l.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.report(RuleIncompleteForm, SeverityError, name, 0, 0,
+			"form '%s' is missing %s", form, path.Base(name))
+		return nil, nil, false
+	}
+	if err != nil {
+		l.report(RuleInvalidJSON, SeverityError, name, 0, 0, "failed to read %s: %v", name, err)
+		return nil, nil, false
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		line, column := 0, 0
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			line, column = position(data, syntaxErr.Offset)
+		case errors.As(err, &typeErr):
+			line, column = position(data, typeErr.Offset)
+		}
+		l.report(RuleInvalidJSON, SeverityError, name, line, column, "invalid JSON: %v", err)
+		return nil, nil, false
+	}
+	return parsed, data, true
+}
+
+// rendererKeys are the properties naming the renderer of a question
+var rendererKeys = []string{"x-renderer", "rendererType", "x-question-type"}
+
+// checkRenderers reports references to renderers neither built in nor part of the bundle
+func (l *linter) checkRenderers(name string, data []byte, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range rendererKeys {
+			renderer, ok := v[key].(string)
+			if !ok || l.renderers[renderer] || validation.IsBuiltInRenderer(renderer) {
+				continue
+			}
+			line, column := locate(data, key, renderer)
+			l.report(RuleUnknownRenderer, SeverityError, name, line, column,
+				"references non-existent renderer '%s' (%s)", renderer, key)
+		}
+		for _, key := range sortedKeys(v) {
+			l.checkRenderers(name, data, v[key])
+		}
+	case []any:
+		for _, item := range v {
+			l.checkRenderers(name, data, item)
+		}
+	}
+}
+
+// checkScopes reports UI controls whose scope does not resolve to a schema property
+func (l *linter) checkScopes(name string, data []byte, value any, schema map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if scope, ok := v["scope"].(string); ok && !resolves(schema, scope) {
+			line, column := locate(data, "scope", scope)
+			l.report(RuleUnknownScope, SeverityError, name, line, column,
+				"control scope %q does not match a schema property", scope)
+		}
+		for _, key := range sortedKeys(v) {
+			l.checkScopes(name, data, v[key], schema)
+		}
+	case []any:
+		for _, item := range v {
+			l.checkScopes(name, data, item, schema)
+		}
+	}
+}
+
+// resolves reports whether a JSON pointer scope such as #/properties/a/properties/b names a
+// property of the schema
+func resolves(schema map[string]any, scope string) bool {
+	pointer, ok := strings.CutPrefix(scope, "#/properties/")
+	if !ok {
+		return false
+	}
+	node := schema
+	for _, segment := range strings.Split(pointer, "/properties/") {
+		properties, _ := node["properties"].(map[string]any)
+		property, ok := properties[segment].(map[string]any)
+		if !ok {
+			return false
+		}
+		node = property
+	}
+	return true
+}
+
+// locate returns the position of the first "key": "value" pair in data, or the first mention of
+// the value, or 0, 0 when neither is found
+func locate(data []byte, key, value string) (int, int) {
+	quotedKey := quote(key)
+	quotedValue := quote(value)
+
+	offset := 0
+	for {
+		i := bytes.Index(data[offset:], quotedKey)
+		if i < 0 {
+			break
+		}
+		keyEnd := offset + i + len(quotedKey)
+		rest := bytes.TrimLeft(data[keyEnd:], " \t\r\n")
+		if rest, ok := bytes.CutPrefix(rest, []byte(":")); ok {
+			if bytes.HasPrefix(bytes.TrimLeft(rest, " \t\r\n"), quotedValue) {
+				return position(data, int64(offset+i))
+			}
+		}
+		offset = keyEnd
+	}
+
+	if i := bytes.Index(data, quotedValue); i >= 0 {
+		return position(data, int64(i))
+	}
+	return 0, 0
+}
+
+// quote returns a string as written in JSON files, where <, > and & are usually not escaped
+func quote(s string) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// sortedKeys returns the keys of a JSON object in order, so findings come out deterministically
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
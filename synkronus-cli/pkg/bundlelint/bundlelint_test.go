@@ -0,0 +1,141 @@
+package bundlelint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func bundle(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestLintFS_ValidBundle(t *testing.T) {
+	findings, err := LintFS(bundle(map[string]string{
+		"README.md":                     "# Household survey",
+		".github/workflows/lint.yml":    "on: pull_request",
+		"app/index.html":                "<html></html>",
+		"forms/user/schema.json":        `{"title": "User", "properties": {"name": {"type": "string", "x-renderer": "button"}}}`,
+		"forms/user/ui.json":            `{"type": "VerticalLayout", "elements": [{"type": "Control", "scope": "#/properties/name"}]}`,
+		"renderers/button/renderer.jsx": "export default function Button() {}",
+	}))
+	if err != nil {
+		t.Fatalf("LintFS failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %+v", findings)
+	}
+}
+
+func TestLintFS_Findings(t *testing.T) {
+	findings, err := LintFS(bundle(map[string]string{
+		"scripts/build.sh":             "#!/bin/sh",
+		"forms/user/schema.json":       "{\n  \"properties\": {\n    \"photo\": {\"x-renderer\": \"camera\"}\n  }\n}",
+		"forms/user/ui.json":           "{\n  \"type\": \"Control\",\n  \"scope\": \"#/properties/age\"\n}",
+		"forms/broken/schema.json":     "{\n  \"title\": \"Broken\",\n  \"properties\": {,}\n}",
+		"forms/broken/ui.json":         "{}",
+		"forms/half/schema.json":       `{"title": "Half"}`,
+		"renderers/empty/renderer.jsx": " \n",
+	}))
+	if err != nil {
+		t.Fatalf("LintFS failed: %v", err)
+	}
+
+	type key struct {
+		rule string
+		file string
+		line int
+	}
+	got := make(map[key]Severity)
+	for _, f := range findings {
+		got[key{f.Rule, f.File, f.Line}] = f.Severity
+	}
+	expected := map[key]Severity{
+		{RuleUnexpectedDirectory, "scripts/build.sh", 0}:       SeverityError,
+		{RuleMissingAppIndex, "app/index.html", 0}:             SeverityError,
+		{RuleMissingTitle, "forms/user/schema.json", 1}:        SeverityWarning,
+		{RuleUnknownRenderer, "forms/user/schema.json", 3}:     SeverityError,
+		{RuleUnknownScope, "forms/user/ui.json", 3}:            SeverityError,
+		{RuleInvalidJSON, "forms/broken/schema.json", 3}:       SeverityError,
+		{RuleIncompleteForm, "forms/half/ui.json", 0}:          SeverityError,
+		{RuleEmptyRenderer, "renderers/empty/renderer.jsx", 0}: SeverityWarning,
+	}
+	for k, severity := range expected {
+		if got[k] != severity {
+			t.Errorf("expected %s finding %+v, got findings %+v", severity, k, findings)
+		}
+	}
+	if len(findings) != len(expected) {
+		t.Errorf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	if !HasErrors(findings) {
+		t.Error("expected HasErrors to report the errors")
+	}
+}
+
+func TestWriteReport_GitHub(t *testing.T) {
+	findings := []Finding{
+		{Rule: RuleUnknownScope, Severity: SeverityError, File: "forms/user/ui.json", Line: 3, Column: 3, Message: "control scope \"#/properties/a,b\" does not match\na schema property"},
+		{Rule: RuleMissingAppIndex, Severity: SeverityError, File: "app/index.html", Message: "missing app/index.html"},
+	}
+
+	var out bytes.Buffer
+	if err := WriteReport(&out, FormatGitHub, findings, "bundles/household", "1.0.0"); err != nil {
+		t.Fatalf("WriteReport failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one annotation per finding, got %q", out.String())
+	}
+	if want := `::error file=bundles/household/forms/user/ui.json,line=3,col=3,title=unknown-scope::control scope "#/properties/a,b" does not match%0Aa schema property`; lines[0] != want {
+		t.Errorf("unexpected annotation\n got: %s\nwant: %s", lines[0], want)
+	}
+	if want := "::error file=bundles/household/app/index.html,title=missing-app-index::missing app/index.html"; lines[1] != want {
+		t.Errorf("unexpected annotation\n got: %s\nwant: %s", lines[1], want)
+	}
+}
+
+func TestWriteReport_SARIF(t *testing.T) {
+	findings := []Finding{
+		{Rule: RuleMissingTitle, Severity: SeverityWarning, File: "forms/user/schema.json", Line: 1, Column: 1, Message: "form schema of 'user' has no title"},
+	}
+
+	var out bytes.Buffer
+	if err := WriteReport(&out, FormatSARIF, findings, "", "1.0.0"); err != nil {
+		t.Fatalf("WriteReport failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log: %s", out.String())
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(RuleDescriptions) {
+		t.Errorf("expected every rule to be described, got %d", len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 1 {
+		t.Fatalf("expected one result, got %d", len(run.Results))
+	}
+	result := run.Results[0]
+	if result.Level != "warning" || result.RuleID != RuleMissingTitle {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if location := result.Locations[0].PhysicalLocation; location.ArtifactLocation.URI != "forms/user/schema.json" || location.Region.StartLine != 1 {
+		t.Errorf("unexpected location %+v", location)
+	}
+}
+
+func TestWriteReport_UnknownFormat(t *testing.T) {
+	if err := WriteReport(&bytes.Buffer{}, "junit", nil, "", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,394 @@
+package bundlelint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Form logic expressions, as written in x-visible-if and x-constraint:
+//
+//	${household_size} > 0 and selected(${assets}, 'radio')
+//	. >= 0 and . <= 120
+//
+// ${name} refers to another field of the form, with dots for nested properties; "." is the value
+// of the field the expression belongs to. Values combine with = != < <= > >= + - * /, and, or
+// and the functions in functions.
+
+// functions holds the minimum and maximum number of arguments of each function
+var functions = map[string][2]int{
+	"not":            {1, 1},
+	"selected":       {2, 2},
+	"count-selected": {1, 1},
+	"string-length":  {1, 1},
+	"contains":       {2, 2},
+	"regex":          {2, 2},
+	"if":             {3, 3},
+	"today":          {0, 0},
+	"now":            {0, 0},
+}
+
+// expr is a parsed expression
+type expr interface{}
+
+// literal is a number, string or boolean written in the expression
+type literal struct{ value any }
+
+// ref is a field reference; an empty name is the field's own value
+type ref struct{ name string }
+
+// binary is an operator applied to two operands
+type binary struct {
+	op          string
+	left, right expr
+}
+
+// call is a function call
+type call struct {
+	name string
+	args []expr
+}
+
+type token struct {
+	kind  string // "number", "string", "ref", "self", "ident", "op" or "end"
+	text  string
+	value any
+	pos   int
+}
+
+// parseExpression parses a form logic expression
+func parseExpression(source string) (expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "end" {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	return e, nil
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(source[i:], "${"):
+			end := strings.IndexByte(source[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field reference at position %d", i+1)
+			}
+			name := strings.TrimSpace(source[i+2 : i+end])
+			if name == "" {
+				return nil, fmt.Errorf("empty field reference at position %d", i+1)
+			}
+			tokens = append(tokens, token{kind: "ref", text: source[i : i+end+1], value: name, pos: i})
+			i += end + 1
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(source[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, token{kind: "string", text: source[i : i+end+2], value: source[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(source) && source[i+1] >= '0' && source[i+1] <= '9':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", source[start:i], start+1)
+			}
+			tokens = append(tokens, token{kind: "number", text: source[start:i], value: value, pos: start})
+		case c == '.':
+			tokens = append(tokens, token{kind: "self", text: ".", pos: i})
+			i++
+		case unicode.IsLetter(rune(c)) || c == '_':
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) || source[i] == '_' || source[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, token{kind: "ident", text: source[start:i], pos: start})
+		default:
+			op := string(c)
+			if i+1 < len(source) && (source[i:i+2] == "!=" || source[i:i+2] == "<=" || source[i:i+2] == ">=") {
+				op = source[i : i+2]
+			}
+			if !strings.Contains("= != < <= > >= + - * / ( ) ,", op) || op == "!" {
+				return nil, fmt.Errorf("unexpected %q at position %d", op, i+1)
+			}
+			tokens = append(tokens, token{kind: "op", text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: "end", text: "end of expression", pos: len(source)}), nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != "end" {
+		p.next++
+	}
+	return t
+}
+
+// accept takes the next token if it is the operator or keyword op
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); (t.kind == "op" || t.kind == "ident") && t.text == op {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q but found %q at position %d", op, t.text, t.pos+1)
+	}
+	return nil
+}
+
+func (p *parser) or() (expr, error) {
+	return p.chain([]string{"or"}, p.and)
+}
+
+func (p *parser) and() (expr, error) {
+	return p.chain([]string{"and"}, p.comparison)
+}
+
+func (p *parser) comparison() (expr, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			return binary{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) additive() (expr, error) {
+	return p.chain([]string{"+", "-"}, p.multiplicative)
+}
+
+func (p *parser) multiplicative() (expr, error) {
+	return p.chain([]string{"*", "/"}, p.unary)
+}
+
+// chain parses operands separated by left-associative operators of one precedence
+func (p *parser) chain(ops []string, operand func() (expr, error)) (expr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		matched := ""
+		for _, op := range ops {
+			if p.accept(op) {
+				matched = op
+				break
+			}
+		}
+		if matched == "" {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: matched, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.accept("-") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: "-", left: literal{0.0}, right: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.take()
+	switch t.kind {
+	case "number", "string":
+		return literal{t.value}, nil
+	case "ref":
+		return ref{name: t.value.(string)}, nil
+	case "self":
+		return ref{}, nil
+	case "op":
+		if t.text == "(" {
+			inner, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		}
+	case "ident":
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		arity, ok := functions[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function or name %q at position %d; refer to fields as ${name}", t.text, t.pos+1)
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var args []expr
+		if !p.accept(")") {
+			for {
+				arg, err := p.or()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.accept(")") {
+					break
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(args) < arity[0] || len(args) > arity[1] {
+			return nil, fmt.Errorf("%s() takes %d arguments, got %d", t.text, arity[0], len(args))
+		}
+		return call{name: t.text, args: args}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+}
+
+// references returns the fields an expression refers to, without its own value
+func references(e expr) []string {
+	var names []string
+	var walk func(expr)
+	walk = func(e expr) {
+		switch v := e.(type) {
+		case ref:
+			if v.name != "" {
+				names = append(names, v.name)
+			}
+		case binary:
+			walk(v.left)
+			walk(v.right)
+		case call:
+			for _, arg := range v.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(e)
+	return names
+}
+
+// constant evaluates an expression that depends on no field and no clock; ok is false otherwise
+func constant(e expr) (any, bool) {
+	switch v := e.(type) {
+	case literal:
+		return v.value, true
+	case binary:
+		left, ok := constant(v.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := constant(v.right)
+		if !ok {
+			return nil, false
+		}
+		return apply(v.op, left, right)
+	case call:
+		if v.name == "not" {
+			if value, ok := constant(v.args[0]); ok {
+				return !truthy(value), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// apply applies an operator to two constant values
+func apply(op string, left, right any) (any, bool) {
+	switch op {
+	case "and":
+		return truthy(left) && truthy(right), true
+	case "or":
+		return truthy(left) || truthy(right), true
+	case "=":
+		return left == right, true
+	case "!=":
+		return left != right, true
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, false
+	}
+	switch op {
+	case "<":
+		return l < r, true
+	case "<=":
+		return l <= r, true
+	case ">":
+		return l > r, true
+	case ">=":
+		return l >= r, true
+	case "+":
+		return l + r, true
+	case "-":
+		return l - r, true
+	case "*":
+		return l * r, true
+	case "/":
+		if r == 0 {
+			return nil, false
+		}
+		return l / r, true
+	}
+	return nil, false
+}
+
+// truthy converts a value to a boolean: zero, empty strings and false are false
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return false
+}
@@ -0,0 +1,392 @@
+package bundlelint
+
+import (
+	"math"
+	"strings"
+)
+
+// Schema keys holding form logic expressions
+const (
+	// VisibleIfKey shows a question only while its expression is true
+	VisibleIfKey = "x-visible-if"
+	// ConstraintKey accepts an answer only when its expression is true for the answer
+	ConstraintKey = "x-constraint"
+)
+
+// logicField is a property of a form schema, with the expressions it declares
+type logicField struct {
+	path       string
+	schema     map[string]any
+	visibleIf  string
+	constraint string
+}
+
+// checkLogic parses the x-visible-if and x-constraint expressions of a form schema and reports
+// those that cannot be evaluated, refer to missing fields, depend on each other in a circle or
+// can never be true. Such mistakes otherwise only show on devices in the field.
+func (l *linter) checkLogic(name string, data []byte, schema map[string]any) {
+	fields := make(map[string]*logicField)
+	var paths []string
+	collectLogicFields(schema, "", fields, &paths)
+
+	visibility := make(map[string][]string)
+	for _, path := range paths {
+		field := fields[path]
+		for _, key := range []string{VisibleIfKey, ConstraintKey} {
+			source := field.visibleIf
+			if key == ConstraintKey {
+				source = field.constraint
+			}
+			if source == "" {
+				continue
+			}
+			line, column := locate(data, key, source)
+
+			e, err := parseExpression(source)
+			if err != nil {
+				l.report(RuleInvalidExpression, SeverityError, name, line, column,
+					"%s of '%s' cannot be parsed: %v", key, path, err)
+				continue
+			}
+
+			known := true
+			for _, reference := range references(e) {
+				if _, ok := fields[reference]; !ok {
+					known = false
+					l.report(RuleUnknownFieldReference, SeverityError, name, line, column,
+						"%s of '%s' refers to ${%s}, which is not a field of the form", key, path, reference)
+				}
+			}
+			if !known {
+				continue
+			}
+			if key == VisibleIfKey {
+				visibility[path] = references(e)
+			}
+
+			if alwaysFalse(e, field, fields) {
+				if key == ConstraintKey {
+					l.report(RuleAlwaysFalseLogic, SeverityError, name, line, column,
+						"%s of '%s' can never be met, so no answer is accepted", key, path)
+				} else {
+					l.report(RuleAlwaysFalseLogic, SeverityWarning, name, line, column,
+						"%s of '%s' is never true, so the question is never shown", key, path)
+				}
+			}
+		}
+	}
+
+	for _, cycle := range visibilityCycles(paths, visibility) {
+		line, column := locate(data, VisibleIfKey, fields[cycle[0]].visibleIf)
+		l.report(RuleCircularLogic, SeverityError, name, line, column,
+			"visibility of '%s' depends on itself: %s", cycle[0], strings.Join(cycle, " → "))
+	}
+}
+
+// collectLogicFields lists the properties of a schema by dotted path, in name order. Items of
+// arrays keep the path of the array.
+func collectLogicFields(schema map[string]any, prefix string, fields map[string]*logicField, paths *[]string) {
+	properties, _ := schema["properties"].(map[string]any)
+	for _, key := range sortedKeys(properties) {
+		property, ok := properties[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		field := &logicField{path: path, schema: property}
+		field.visibleIf, _ = property[VisibleIfKey].(string)
+		field.constraint, _ = property[ConstraintKey].(string)
+		fields[path] = field
+		*paths = append(*paths, path)
+
+		collectLogicFields(property, path, fields, paths)
+		if items, ok := property["items"].(map[string]any); ok {
+			collectLogicFields(items, path, fields, paths)
+		}
+	}
+}
+
+// visibilityCycles returns each circle of fields whose visibility depends on one another, as the
+// fields along it ending with the first again
+func visibilityCycles(paths []string, dependencies map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var cycles [][]string
+	var stack []string
+
+	var visit func(string)
+	visit = func(path string) {
+		state[path] = visiting
+		stack = append(stack, path)
+		for _, dependency := range dependencies[path] {
+			switch state[dependency] {
+			case unvisited:
+				visit(dependency)
+			case visiting:
+				start := 0
+				for i, p := range stack {
+					if p == dependency {
+						start = i
+					}
+				}
+				cycle := append(append([]string(nil), stack[start:]...), dependency)
+				cycles = append(cycles, cycle)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[path] = done
+	}
+	for _, path := range paths {
+		if state[path] == unvisited {
+			visit(path)
+		}
+	}
+	return cycles
+}
+
+// alwaysFalse reports whether an expression is false whatever the answers: it is false as
+// written, or its comparisons of fields with fixed values contradict each other or the schema
+func alwaysFalse(e expr, self *logicField, fields map[string]*logicField) bool {
+	if value, ok := constant(e); ok {
+		return !truthy(value)
+	}
+	switch v := e.(type) {
+	case binary:
+		switch v.op {
+		case "or":
+			return alwaysFalse(v.left, self, fields) && alwaysFalse(v.right, self, fields)
+		case "and":
+			if alwaysFalse(v.left, self, fields) || alwaysFalse(v.right, self, fields) {
+				return true
+			}
+		}
+	case call:
+		if v.name == "selected" {
+			return !selectable(v, self, fields)
+		}
+	}
+
+	// Gather the comparisons joined by and, and check each field can meet all of them
+	domains := make(map[string]*domain)
+	for _, term := range conjuncts(e) {
+		b, ok := term.(binary)
+		if !ok {
+			continue
+		}
+		field, op, value, ok := comparison(b)
+		if !ok {
+			continue
+		}
+		d, ok := domains[field]
+		if !ok {
+			target := self
+			if field != "" {
+				target = fields[field]
+			}
+			d = newDomain(target.schema)
+			domains[field] = d
+		}
+		d.restrict(op, value)
+	}
+	for _, d := range domains {
+		if d.empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// conjuncts splits an expression at its top-level and operators
+func conjuncts(e expr) []expr {
+	if b, ok := e.(binary); ok && b.op == "and" {
+		return append(conjuncts(b.left), conjuncts(b.right)...)
+	}
+	return []expr{e}
+}
+
+// comparison reads a comparison of a field with a fixed value, turned so the field is on the
+// left. The field is "" for the expression's own field.
+func comparison(b binary) (string, string, any, bool) {
+	flipped := map[string]string{"=": "=", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+	if _, ok := flipped[b.op]; !ok {
+		return "", "", nil, false
+	}
+	if r, ok := b.left.(ref); ok {
+		if value, ok := constant(b.right); ok {
+			return r.name, b.op, value, true
+		}
+	}
+	if r, ok := b.right.(ref); ok {
+		if value, ok := constant(b.left); ok {
+			return r.name, flipped[b.op], value, true
+		}
+	}
+	return "", "", nil, false
+}
+
+// selectable reports whether selected(${field}, 'value') can be true, i.e. the value is one of
+// the field's choices or the field has no fixed choices
+func selectable(c call, self *logicField, fields map[string]*logicField) bool {
+	r, ok := c.args[0].(ref)
+	if !ok {
+		return true
+	}
+	value, ok := constant(c.args[1])
+	if !ok {
+		return true
+	}
+	target := self
+	if r.name != "" {
+		target = fields[r.name]
+	}
+	choices := enumOf(target.schema)
+	if items, ok := target.schema["items"].(map[string]any); ok && choices == nil {
+		choices = enumOf(items)
+	}
+	if choices == nil {
+		return true
+	}
+	for _, choice := range choices {
+		if choice == value {
+			return true
+		}
+	}
+	return false
+}
+
+// domain is the set of values a field can still take
+type domain struct {
+	low, high         float64
+	lowOpen, highOpen bool
+	// values lists the values the field is limited to; nil when it is not limited to a list
+	values   []any
+	excluded []any
+}
+
+// newDomain starts from the bounds and choices of a field's schema
+func newDomain(schema map[string]any) *domain {
+	d := &domain{low: math.Inf(-1), high: math.Inf(1)}
+	if n, ok := schema["minimum"].(float64); ok {
+		d.restrict(">=", n)
+	}
+	if n, ok := schema["exclusiveMinimum"].(float64); ok {
+		d.restrict(">", n)
+	}
+	if n, ok := schema["maximum"].(float64); ok {
+		d.restrict("<=", n)
+	}
+	if n, ok := schema["exclusiveMaximum"].(float64); ok {
+		d.restrict("<", n)
+	}
+	if value, ok := schema["const"]; ok {
+		d.values = []any{value}
+	} else if choices := enumOf(schema); choices != nil {
+		d.values = choices
+	}
+	return d
+}
+
+// restrict narrows the domain to the values meeting a comparison with a fixed value
+func (d *domain) restrict(op string, value any) {
+	n, numeric := value.(float64)
+	switch {
+	case op == "=" && numeric:
+		d.restrict(">=", n)
+		d.restrict("<=", n)
+	case op == "=":
+		if d.values == nil {
+			d.values = []any{value}
+			return
+		}
+		var kept []any
+		for _, v := range d.values {
+			if v == value {
+				kept = append(kept, v)
+			}
+		}
+		d.values = append([]any{}, kept...)
+	case op == "!=":
+		d.excluded = append(d.excluded, value)
+	case !numeric:
+		// Ordering against text is left to the formplayer
+	case op == ">" && (n > d.low || n == d.low && !d.lowOpen):
+		d.low, d.lowOpen = n, true
+	case op == ">=" && n > d.low:
+		d.low, d.lowOpen = n, false
+	case op == "<" && (n < d.high || n == d.high && !d.highOpen):
+		d.high, d.highOpen = n, true
+	case op == "<=" && n < d.high:
+		d.high, d.highOpen = n, false
+	}
+}
+
+// empty reports whether no value is left
+func (d *domain) empty() bool {
+	if d.low > d.high || d.low == d.high && (d.lowOpen || d.highOpen) {
+		return true
+	}
+	if d.low == d.high && !math.IsInf(d.low, 0) && contains(d.excluded, d.low) {
+		return true
+	}
+	if d.values == nil {
+		return false
+	}
+	for _, v := range d.values {
+		if contains(d.excluded, v) {
+			continue
+		}
+		if n, ok := v.(float64); ok && !d.within(n) {
+			continue
+		}
+		if _, ok := v.(float64); !ok && d.bounded() {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func (d *domain) within(n float64) bool {
+	return (n > d.low || n == d.low && !d.lowOpen) && (n < d.high || n == d.high && !d.highOpen)
+}
+
+func (d *domain) bounded() bool {
+	return !math.IsInf(d.low, -1) || !math.IsInf(d.high, 1)
+}
+
+// enumOf returns the choices of a schema, or nil when it has none
+func enumOf(schema map[string]any) []any {
+	if choices, ok := schema["enum"].([]any); ok {
+		return choices
+	}
+	if options, ok := schema["oneOf"].([]any); ok {
+		var choices []any
+		for _, option := range options {
+			if option, ok := option.(map[string]any); ok {
+				if value, ok := option["const"]; ok {
+					choices = append(choices, value)
+				}
+			}
+		}
+		if len(choices) > 0 {
+			return choices
+		}
+	}
+	return nil
+}
+
+func contains(values []any, value any) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package bundlelint
+
+import (
+	"strings"
+	"testing"
+)
+
+// logicSchema is a form schema whose x-visible-if and x-constraint expressions hold the usual
+// mistakes, one per line so findings can be told apart by line
+const logicSchema = `{
+  "title": "Household",
+  "properties": {
+    "head_age": {"type": "integer", "minimum": 15, "maximum": 110, "x-constraint": ". >= 18 and . <= 120"},
+    "members": {"type": "integer", "x-constraint": ". > 10 and . < 5"},
+    "has_water": {"type": "string", "enum": ["yes", "no"]},
+    "water_source": {"type": "string", "x-visible-if": "${has_water} = 'yes'"},
+    "water_quality": {"type": "string", "x-visible-if": "${has_water} = 'maybe'"},
+    "latrine": {"type": "string", "x-visible-if": "${latrine_type} != ''"},
+    "latrine_type": {"type": "string", "x-visible-if": "${latrine} = 'yes'"},
+    "phone": {"type": "string", "x-visible-if": "${mobile} = 'yes'"},
+    "income": {"type": "number", "x-constraint": ". >= 0 and"},
+    "assets": {"type": "array", "items": {"enum": ["radio", "bicycle"]}},
+    "bicycle_count": {"type": "integer", "x-visible-if": "selected(${assets}, 'car')"},
+    "notes": {"type": "string", "x-visible-if": "1 = 2"},
+    "address": {"type": "object", "properties": {
+      "village": {"type": "string", "x-constraint": "string-length(.) > 2 or ${address.ward} = ''"},
+      "ward": {"type": "string"}
+    }}
+  }
+}`
+
+func TestLintFS_FormLogic(t *testing.T) {
+	findings, err := LintFS(bundle(map[string]string{
+		"app/index.html":              "<html></html>",
+		"forms/household/schema.json": logicSchema,
+		"forms/household/ui.json":     `{"type": "VerticalLayout", "elements": []}`,
+	}))
+	if err != nil {
+		t.Fatalf("LintFS failed: %v", err)
+	}
+
+	type key struct {
+		rule string
+		line int
+	}
+	got := make(map[key]Severity)
+	for _, f := range findings {
+		got[key{f.Rule, f.Line}] = f.Severity
+	}
+	expected := map[key]Severity{
+		{RuleAlwaysFalseLogic, 5}:       SeverityError,
+		{RuleAlwaysFalseLogic, 8}:       SeverityWarning,
+		{RuleCircularLogic, 9}:          SeverityError,
+		{RuleUnknownFieldReference, 11}: SeverityError,
+		{RuleInvalidExpression, 12}:     SeverityError,
+		{RuleAlwaysFalseLogic, 14}:      SeverityWarning,
+		{RuleAlwaysFalseLogic, 15}:      SeverityWarning,
+	}
+	for k, severity := range expected {
+		if got[k] != severity {
+			t.Errorf("expected %s finding %+v, got findings %+v", severity, k, findings)
+		}
+	}
+	if len(findings) != len(expected) {
+		t.Errorf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	for _, f := range findings {
+		if f.Rule == RuleCircularLogic && !strings.Contains(f.Message, "latrine → latrine_type → latrine") {
+			t.Errorf("expected the circle in the message, got %q", f.Message)
+		}
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	for _, source := range []string{
+		". >= 0 and . <= 120",
+		"${a} = 'yes' or (${b} > 2 and not(${c} = \"x\"))",
+		"count-selected(${assets}) >= 1",
+		"-1 < . * 2 + 3 / 4",
+		"if(${a} > 1, 'many', 'one') != ''",
+		"today() != ''",
+	} {
+		if _, err := parseExpression(source); err != nil {
+			t.Errorf("parseExpression(%q) failed: %v", source, err)
+		}
+	}
+
+	for _, source := range []string{
+		"",
+		"${a} = ",
+		"${a = 1",
+		"a = 1",
+		"'open",
+		"not(1, 2)",
+		"${a} & 1",
+		"(${a} > 1",
+	} {
+		if _, err := parseExpression(source); err == nil {
+			t.Errorf("expected parseExpression(%q) to fail", source)
+		}
+	}
+}
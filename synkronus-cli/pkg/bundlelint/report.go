@@ -0,0 +1,182 @@
+package bundlelint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Output formats of WriteReport
+const (
+	FormatText   = "text"
+	FormatGitHub = "github"
+	FormatSARIF  = "sarif"
+)
+
+// WriteReport writes findings in the given format. root is prepended to file paths so they are
+// relative to the repository the bundle lives in, as CI annotations expect; toolVersion is
+// recorded in SARIF output.
+func WriteReport(w io.Writer, format string, findings []Finding, root, toolVersion string) error {
+	switch format {
+	case FormatText, "":
+		return writeText(w, findings, root)
+	case FormatGitHub:
+		return writeGitHub(w, findings, root)
+	case FormatSARIF:
+		return writeSARIF(w, findings, root, toolVersion)
+	}
+	return fmt.Errorf("unknown format %q (expected %s, %s or %s)", format, FormatText, FormatGitHub, FormatSARIF)
+}
+
+// repoPath joins a bundle file to the bundle's location in the repository
+func repoPath(root, file string) string {
+	if root == "" || root == "." {
+		return file
+	}
+	return path.Join(root, file)
+}
+
+// writeText writes one finding per line in the file:line:column form editors understand
+func writeText(w io.Writer, findings []Finding, root string) error {
+	for _, f := range findings {
+		location := repoPath(root, f.File)
+		if f.Line > 0 {
+			location += fmt.Sprintf(":%d:%d", f.Line, f.Column)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s [%s]\n", location, f.Severity, f.Message, f.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGitHub writes GitHub Actions workflow commands, which the runner turns into annotations on
+// the pull request diff
+func writeGitHub(w io.Writer, findings []Finding, root string) error {
+	for _, f := range findings {
+		properties := []string{"file=" + escapeProperty(repoPath(root, f.File))}
+		if f.Line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", f.Line))
+			if f.Column > 0 {
+				properties = append(properties, fmt.Sprintf("col=%d", f.Column))
+			}
+		}
+		properties = append(properties, "title="+escapeProperty(f.Rule))
+
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", f.Severity, strings.Join(properties, ","), escapeData(f.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// SARIF 2.1.0 structures, limited to what code scanning tools read
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// writeSARIF writes a SARIF 2.1.0 log for code scanning uploads
+func writeSARIF(w io.Writer, findings []Finding, root, toolVersion string) error {
+	rules := make([]sarifRule, 0, len(RuleDescriptions))
+	for id, description := range RuleDescriptions {
+		rules = append(rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: description}})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: repoPath(root, f.File)}}
+		if f.Line > 0 {
+			location.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+		}
+		results = append(results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     string(f.Severity),
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "synk",
+				Version:        toolVersion,
+				InformationURI: "https://github.com/OpenDataEnsemble/ode",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
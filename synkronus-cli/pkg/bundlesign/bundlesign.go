@@ -0,0 +1,105 @@
+// Package bundlesign signs app bundle ZIPs with Ed25519 keys for servers that only accept
+// signed bundles.
+//
+// The signature covers a content digest rather than the ZIP bytes, so devices can check it
+// against the files listed in the manifest: the SHA-256 of one "<sha256 hex>  <path>\n" line per
+// file, sorted by path, leaving out the APP_INFO.json and SIGNATURE.json the server writes.
+package bundlesign
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ContentDigest returns the content digest of a bundle ZIP
+func ContentDigest(bundlePath string) ([]byte, error) {
+	archive, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening bundle: %w", err)
+	}
+	defer archive.Close()
+
+	hashes := make(map[string]string, len(archive.File))
+	for _, file := range archive.File {
+		// The server skips the same entries when extracting a bundle
+		if file.FileInfo().IsDir() || strings.Contains(file.Name, "..") {
+			continue
+		}
+		name := path.Clean(file.Name)
+		if name == "APP_INFO.json" || name == "SIGNATURE.json" {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", file.Name, err)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, src)
+		src.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", file.Name, err)
+		}
+		hashes[name] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return digest(hashes), nil
+}
+
+// digest hashes the "<hash>  <path>" lines of files sorted by path
+func digest(hashes map[string]string) []byte {
+	paths := make([]string, 0, len(hashes))
+	for name := range hashes {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+
+	sum := sha256.New()
+	for _, name := range paths {
+		fmt.Fprintf(sum, "%s  %s\n", hashes[name], name)
+	}
+	return sum.Sum(nil)
+}
+
+// LoadPrivateKey reads an Ed25519 private key from a PEM file, as written by
+// `openssl genpkey -algorithm ed25519`
+func LoadPrivateKey(keyPath string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s is not a PEM private key", keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key: %w", err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", keyPath)
+	}
+	return private, nil
+}
+
+// Sign returns the base64 signature of a bundle ZIP and the base64 public key that verifies
+// it, the form the server's APP_BUNDLE_SIGNING_KEYS expects
+func Sign(bundlePath string, key ed25519.PrivateKey) (signature, publicKey string, err error) {
+	sum, err := ContentDigest(bundlePath)
+	if err != nil {
+		return "", "", err
+	}
+	signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, sum))
+	publicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	return signature, publicKey, nil
+}
@@ -0,0 +1,91 @@
+package bundlesign
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	// The server computes the same digest; keep both in step when changing the format
+	got := hex.EncodeToString(digest(map[string]string{
+		"forms/survey/schema.json": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		"app/index.html":           "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}))
+	if want := "945c6eac7550cedd16bd38c8e64668dbc415f58936a298dcdc29e61e1817bba6"; got != want {
+		t.Errorf("digest = %s, want %s", got, want)
+	}
+}
+
+func TestSign(t *testing.T) {
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "bundle.zip")
+	out, err := os.Create(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(out)
+	for name, content := range map[string]string{"app/": "", "app/index.html": "hello", "forms/survey/schema.json": "{}", "SIGNATURE.json": "{}"} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := LoadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, publicKey, err := Sign(bundlePath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKey != base64.StdEncoding.EncodeToString(public) {
+		t.Errorf("public key = %s", publicKey)
+	}
+
+	sum, err := ContentDigest(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The digest covers the two bundle files only
+	want := digest(map[string]string{
+		"app/index.html":           "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"forms/survey/schema.json": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+	})
+	if hex.EncodeToString(sum) != hex.EncodeToString(want) {
+		t.Errorf("content digest = %x, want %x", sum, want)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(signature)
+	if !ed25519.Verify(public, sum, raw) {
+		t.Error("signature does not verify")
+	}
+
+	if _, err := LoadPrivateKey(bundlePath); err == nil {
+		t.Error("expected an error loading a ZIP as a key")
+	}
+}
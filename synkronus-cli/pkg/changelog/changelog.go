@@ -0,0 +1,116 @@
+// Package changelog renders the form-level changes between two app bundle versions as release
+// notes, either as plain text for the terminal or as Markdown for deployment announcements.
+package changelog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+)
+
+// noChanges is printed when the two versions have the same forms
+const noChanges = "No form changes."
+
+// Markdown renders the changes as Markdown release notes
+func Markdown(changes *client.AppBundleChanges) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## App bundle %s\n\n", changes.CompareVersionB)
+	fmt.Fprintf(&b, "Changes since %s.\n", changes.CompareVersionA)
+	if isEmpty(changes) {
+		fmt.Fprintf(&b, "\n%s\n", noChanges)
+		return b.String()
+	}
+
+	if len(changes.NewForms) > 0 {
+		b.WriteString("\n### New forms\n\n")
+		for _, form := range changes.NewForms {
+			fmt.Fprintf(&b, "- `%s`\n", form.Name)
+		}
+	}
+	if len(changes.RemovedForms) > 0 {
+		b.WriteString("\n### Removed forms\n\n")
+		for _, form := range changes.RemovedForms {
+			fmt.Fprintf(&b, "- `%s`\n", form.Name)
+		}
+	}
+	if len(changes.ModifiedForms) > 0 {
+		b.WriteString("\n### Changed forms\n")
+		for _, form := range changes.ModifiedForms {
+			fmt.Fprintf(&b, "\n#### `%s`\n\n", form.FormName)
+			for _, line := range formLines(form, "`") {
+				fmt.Fprintf(&b, "- %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Text renders the changes as plain text release notes
+func Text(changes *client.AppBundleChanges) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changes from %s to %s\n", changes.CompareVersionA, changes.CompareVersionB)
+	if isEmpty(changes) {
+		fmt.Fprintf(&b, "\n%s\n", noChanges)
+		return b.String()
+	}
+
+	if len(changes.NewForms) > 0 {
+		b.WriteString("\nNew forms:\n")
+		for _, form := range changes.NewForms {
+			fmt.Fprintf(&b, "  - %s\n", form.Name)
+		}
+	}
+	if len(changes.RemovedForms) > 0 {
+		b.WriteString("\nRemoved forms:\n")
+		for _, form := range changes.RemovedForms {
+			fmt.Fprintf(&b, "  - %s\n", form.Name)
+		}
+	}
+	if len(changes.ModifiedForms) > 0 {
+		b.WriteString("\nChanged forms:\n")
+		for _, form := range changes.ModifiedForms {
+			fmt.Fprintf(&b, "  %s\n", form.FormName)
+			for _, line := range formLines(form, "") {
+				fmt.Fprintf(&b, "    - %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
+
+// isEmpty reports whether there is nothing to announce
+func isEmpty(changes *client.AppBundleChanges) bool {
+	return len(changes.NewForms) == 0 && len(changes.RemovedForms) == 0 && len(changes.ModifiedForms) == 0
+}
+
+// formLines describes the changes to one form, quoting field names with quote
+func formLines(form client.FormModification, quote string) []string {
+	var lines []string
+	for _, field := range form.AddedFields {
+		lines = append(lines, fmt.Sprintf("Added field %s%s%s%s", quote, field.Name, quote, fieldType(field)))
+	}
+	for _, field := range form.RemovedFields {
+		lines = append(lines, fmt.Sprintf("Removed field %s%s%s%s", quote, field.Name, quote, fieldType(field)))
+	}
+	if form.CoreChange {
+		lines = append(lines, "Core fields changed")
+	}
+
+	// Schema changes that do not add or remove fields, such as new choices or validation rules
+	if form.SchemaChange && len(form.AddedFields) == 0 && len(form.RemovedFields) == 0 && !form.CoreChange {
+		lines = append(lines, "Questions updated")
+	}
+	if form.UIChange {
+		lines = append(lines, "Layout updated")
+	}
+	return lines
+}
+
+// fieldType formats the type of a field for display after its name
+func fieldType(field client.FieldChange) string {
+	if field.Type == "" {
+		return ""
+	}
+	return " (" + field.Type + ")"
+}
@@ -0,0 +1,90 @@
+package changelog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenDataEnsemble/ode/synkronus-cli/pkg/client"
+)
+
+// changesJSON is a response of the /app-bundle/changes endpoint
+const changesJSON = `{
+  "compare_version_a": "v0041",
+  "compare_version_b": "v0042",
+  "form_changes": true,
+  "ui_changes": true,
+  "new_forms": [{"form": "household_visit"}],
+  "removed_forms": [{"form": "legacy_screening"}],
+  "modified_forms": [
+    {
+      "form": "danger_signs",
+      "schema_changed": true,
+      "ui_changed": true,
+      "core_changed": false,
+      "added_fields": [{"field": "referral_reason", "type": "string"}],
+      "removed_fields": [{"field": "fever_days", "type": "integer"}]
+    },
+    {"form": "registration", "schema_changed": true},
+    {"form": "anc_visit", "ui_changed": true}
+  ]
+}`
+
+func decode(t *testing.T, data string) *client.AppBundleChanges {
+	t.Helper()
+	var changes client.AppBundleChanges
+	if err := json.Unmarshal([]byte(data), &changes); err != nil {
+		t.Fatalf("failed to decode changes: %v", err)
+	}
+	return &changes
+}
+
+func TestMarkdown(t *testing.T) {
+	want := "## App bundle v0042\n\n" +
+		"Changes since v0041.\n" +
+		"\n### New forms\n\n" +
+		"- `household_visit`\n" +
+		"\n### Removed forms\n\n" +
+		"- `legacy_screening`\n" +
+		"\n### Changed forms\n" +
+		"\n#### `danger_signs`\n\n" +
+		"- Added field `referral_reason` (string)\n" +
+		"- Removed field `fever_days` (integer)\n" +
+		"- Layout updated\n" +
+		"\n#### `registration`\n\n" +
+		"- Questions updated\n" +
+		"\n#### `anc_visit`\n\n" +
+		"- Layout updated\n"
+	if got := Markdown(decode(t, changesJSON)); got != want {
+		t.Fatalf("unexpected markdown:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	want := "Changes from v0041 to v0042\n" +
+		"\nNew forms:\n" +
+		"  - household_visit\n" +
+		"\nRemoved forms:\n" +
+		"  - legacy_screening\n" +
+		"\nChanged forms:\n" +
+		"  danger_signs\n" +
+		"    - Added field referral_reason (string)\n" +
+		"    - Removed field fever_days (integer)\n" +
+		"    - Layout updated\n" +
+		"  registration\n" +
+		"    - Questions updated\n" +
+		"  anc_visit\n" +
+		"    - Layout updated\n"
+	if got := Text(decode(t, changesJSON)); got != want {
+		t.Fatalf("unexpected text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNoChanges(t *testing.T) {
+	changes := decode(t, `{"compare_version_a": "v0041", "compare_version_b": "v0042"}`)
+	if got, want := Markdown(changes), "## App bundle v0042\n\nChanges since v0041.\n\nNo form changes.\n"; got != want {
+		t.Fatalf("unexpected markdown:\n%s", got)
+	}
+	if got, want := Text(changes), "Changes from v0041 to v0042\n\nNo form changes.\n"; got != want {
+		t.Fatalf("unexpected text:\n%s", got)
+	}
+}
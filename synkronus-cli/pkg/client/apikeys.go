@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// APIKeyCreateRequest represents the payload for creating an API key
+type APIKeyCreateRequest struct {
+	Name      string `json:"name"`
+	Username  string `json:"username,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKey calls POST /users/api-keys; the response holds the key, which is shown only once
+func (c *Client) CreateAPIKey(reqBody APIKeyCreateRequest) (map[string]interface{}, error) {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	request, err := http.NewRequest("POST", fmt.Sprintf("%s/users/api-keys", c.BaseURL), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// ListAPIKeys calls GET /users/api-keys; all lists every user's keys (admin only)
+func (c *Client) ListAPIKeys(all bool) ([]map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("%s/users/api-keys", c.BaseURL)
+	if all {
+		endpoint += "?" + url.Values{"all": {"true"}}.Encode()
+	}
+	request, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var keys []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey calls DELETE /users/api-keys/{id}
+func (c *Client) RevokeAPIKey(id string) error {
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("%s/users/api-keys/%s", c.BaseURL, url.PathEscape(id)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return nil
+}
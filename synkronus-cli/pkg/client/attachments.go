@@ -7,11 +7,13 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 )
 
-// UploadAttachment uploads a file to the server with the specified attachment ID
-func (c *Client) UploadAttachment(attachmentID string, filePath string) (map[string]interface{}, error) {
+// UploadAttachment uploads a file to the server with the specified attachment ID, optionally
+// associating it with an observation
+func (c *Client) UploadAttachment(attachmentID string, filePath string, observationID string) (map[string]interface{}, error) {
 	// Open the file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -35,6 +37,12 @@ func (c *Client) UploadAttachment(attachmentID string, filePath string) (map[str
 		return nil, fmt.Errorf("error copying file content: %w", err)
 	}
 
+	if observationID != "" {
+		if err := writer.WriteField("observation_id", observationID); err != nil {
+			return nil, fmt.Errorf("error writing form field: %w", err)
+		}
+	}
+
 	// Close the writer to finalize the form
 	writer.Close()
 
@@ -130,3 +138,88 @@ func (c *Client) AttachmentExists(attachmentID string) (bool, error) {
 		return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 }
+
+// ListAttachments lists the attachments associated with an observation
+func (c *Client) ListAttachments(observationID string) (map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/attachments?observation_id=%s", c.BaseURL, url.QueryEscape(observationID))
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetAttachmentManifest retrieves the attachment changes since a data version
+func (c *Client) GetAttachmentManifest(clientID string, sinceVersion int64) (map[string]interface{}, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"client_id":     clientID,
+		"since_version": sinceVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/attachments/manifest", c.BaseURL)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteAttachment deletes an attachment from the server
+func (c *Client) DeleteAttachment(attachmentID string) error {
+	url := fmt.Sprintf("%s/attachments/%s", c.BaseURL, attachmentID)
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ExportBackupUsers calls GET /backup/users and returns the users, with password hashes, as JSON (admin only)
+func (c *Client) ExportBackupUsers() ([]byte, error) {
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/backup/users", c.BaseURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
+
+// RestoreBackupUsers calls POST /backup/users with users as exported by ExportBackupUsers (admin only)
+func (c *Client) RestoreBackupUsers(users []byte) (map[string]interface{}, error) {
+	request, err := http.NewRequest("POST", fmt.Sprintf("%s/backup/users", c.BaseURL), bytes.NewReader(users))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return c.doRestore(request)
+}
+
+// DownloadBackupObservations calls GET /backup/observations and copies the newline-delimited
+// observations to w (admin only)
+func (c *Client) DownloadBackupObservations(w io.Writer) error {
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/backup/observations", c.BaseURL), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.download(request, w)
+}
+
+// RestoreBackupObservations calls POST /backup/observations with newline-delimited observations (admin only)
+func (c *Client) RestoreBackupObservations(observations io.Reader) (map[string]interface{}, error) {
+	request, err := http.NewRequest("POST", fmt.Sprintf("%s/backup/observations", c.BaseURL), observations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	return c.doRestore(request)
+}
+
+// DownloadBackupAppBundle calls GET /backup/app-bundle/{version} and copies the version's zip to w (admin only)
+func (c *Client) DownloadBackupAppBundle(version string, w io.Writer) error {
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/backup/app-bundle/%s", c.BaseURL, url.PathEscape(version)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.download(request, w)
+}
+
+// download performs a request and copies a successful response body to w
+func (c *Client) download(request *http.Request, w io.Writer) error {
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return nil
+}
+
+// doRestore performs a restore request and decodes the created and updated counts
+func (c *Client) doRestore(request *http.Request) (map[string]interface{}, error) {
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -17,13 +18,36 @@ import (
 	"github.com/spf13/viper"
 )
 
-// AppBundleChanges represents the changes between two app bundle versions
+// AppBundleChanges represents the form-level changes between two app bundle versions
 type AppBundleChanges struct {
-	CurrentVersion string           `json:"current_version"`
-	TargetVersion  string           `json:"target_version"`
-	Added          []map[string]any `json:"added"`
-	Modified       []map[string]any `json:"modified"`
-	Removed        []map[string]any `json:"removed"`
+	CompareVersionA string             `json:"compare_version_a"`
+	CompareVersionB string             `json:"compare_version_b"`
+	FormChanges     bool               `json:"form_changes"`
+	UIChanges       bool               `json:"ui_changes"`
+	NewForms        []FormDiff         `json:"new_forms,omitempty"`
+	RemovedForms    []FormDiff         `json:"removed_forms,omitempty"`
+	ModifiedForms   []FormModification `json:"modified_forms,omitempty"`
+}
+
+// FormDiff represents a form that was added or removed
+type FormDiff struct {
+	Name string `json:"form"`
+}
+
+// FieldChange represents a field that was added or removed
+type FieldChange struct {
+	Name string `json:"field"`
+	Type string `json:"type"`
+}
+
+// FormModification represents changes to a form's schema or UI
+type FormModification struct {
+	FormName      string        `json:"form"`
+	SchemaChange  bool          `json:"schema_changed"`
+	UIChange      bool          `json:"ui_changed"`
+	CoreChange    bool          `json:"core_changed"`
+	AddedFields   []FieldChange `json:"added_fields,omitempty"`
+	RemovedFields []FieldChange `json:"removed_fields,omitempty"`
 }
 
 // SystemVersionInfo represents the version information of the Synkronus server
@@ -56,6 +80,33 @@ type BuildInfo struct {
 	GoVersion string `json:"go_version"`
 }
 
+// APIError is returned when the server answers with an unexpected status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// IsRetryable reports whether a request failed in a way that may succeed when sent again: the
+// server could not be reached, timed out, or was overloaded or briefly unavailable. Rejected
+// requests and authentication problems are not retryable.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
 // Client represents a Synkronus API client
 type Client struct {
 	BaseURL    string
@@ -110,6 +161,12 @@ func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 	// Add API version header
 	req.Header.Set("x-api-version", c.APIVersion)
 
+	// An API key replaces the stored tokens, e.g. for pipelines
+	if key := auth.GetAPIKey(); key != "" {
+		req.Header.Set("X-API-Key", key)
+		return c.HTTPClient.Do(req)
+	}
+
 	// Get authentication token
 	token, err := auth.GetToken()
 	if err != nil {
@@ -177,7 +234,8 @@ func (c *Client) GetAppBundleVersions() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// GetAppBundleChanges gets the changes between two app bundle versions
+// GetAppBundleChanges gets the changes from currentVersion to targetVersion. Empty versions
+// default on the server to the active version and the one before it.
 func (c *Client) GetAppBundleChanges(currentVersion, targetVersion string) (*AppBundleChanges, error) {
 	url := fmt.Sprintf("%s/app-bundle/changes", c.BaseURL)
 
@@ -215,6 +273,27 @@ func (c *Client) GetAppBundleChanges(currentVersion, targetVersion string) (*App
 	return &changes, nil
 }
 
+// DownloadCodebook calls GET /app-bundle/codebook and copies the codebook of an app bundle
+// version to w. Empty values default on the server to the active version, all forms and HTML.
+func (c *Client) DownloadCodebook(version, form, format string, w io.Writer) error {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/app-bundle/codebook", c.BaseURL), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	if version != "" {
+		q.Add("version", version)
+	}
+	if form != "" {
+		q.Add("form", form)
+	}
+	if format != "" {
+		q.Add("format", format)
+	}
+	req.URL.RawQuery = q.Encode()
+	return c.download(req, w)
+}
+
 // DownloadAppBundleFile downloads a specific file from the app bundle
 // If preview is true, adds ?preview=true to the request URL
 func (c *Client) DownloadAppBundleFile(path, destPath string, preview bool) error {
@@ -261,11 +340,15 @@ func (c *Client) DownloadAppBundleFile(path, destPath string, preview bool) erro
 	return nil
 }
 
-// DownloadParquetExport downloads the Parquet export ZIP archive to the specified destination path
-func (c *Client) DownloadParquetExport(destPath string) error {
-	url := fmt.Sprintf("%s/dataexport/parquet", c.BaseURL)
+// DownloadParquetExport downloads the Parquet export ZIP archive to the specified destination path.
+// A non-empty template applies that saved export template's form types, columns and masking.
+func (c *Client) DownloadParquetExport(destPath, template string) error {
+	endpoint := fmt.Sprintf("%s/dataexport/parquet", c.BaseURL)
+	if template != "" {
+		endpoint += "?" + url.Values{"template": {template}}.Encode()
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
 		return err
 	}
@@ -303,8 +386,9 @@ func (c *Client) DownloadParquetExport(destPath string) error {
 	return nil
 }
 
-// UploadAppBundle uploads a new app bundle
-func (c *Client) UploadAppBundle(bundlePath string) (map[string]interface{}, error) {
+// UploadAppBundle uploads a new app bundle. A non-empty signature is the base64 Ed25519
+// signature of the bundle, as returned by bundlesign.Sign.
+func (c *Client) UploadAppBundle(bundlePath, signature string) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/app-bundle/push", c.BaseURL)
 
 	// Open the bundle file
@@ -330,6 +414,12 @@ func (c *Client) UploadAppBundle(bundlePath string) (map[string]interface{}, err
 		return nil, err
 	}
 
+	if signature != "" {
+		if err := writer.WriteField("signature", signature); err != nil {
+			return nil, err
+		}
+	}
+
 	// Close multipart writer
 	err = writer.Close()
 	if err != nil {
@@ -395,6 +485,93 @@ func (c *Client) SwitchAppBundleVersion(version string) (map[string]interface{},
 
 // SyncPull pulls updated records from the server
 func (c *Client) SyncPull(clientID string, currentVersion int64, schemaTypes []string, limit int, pageToken string) (map[string]interface{}, error) {
+	req, err := c.newSyncPullRequest(clientID, currentVersion, schemaTypes, limit, pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return result, nil
+}
+
+// ErrIncompleteStream is returned by SyncPullStream when the stream stops before its end line
+var ErrIncompleteStream = errors.New("sync pull stream ended early")
+
+// SyncPullStream pulls like SyncPull but asks the server to stream the records as NDJSON, and
+// passes each record to onRecord as it arrives instead of holding the page in memory. It
+// returns the summary of the end line: current_version, change_cutoff, has_more and warnings.
+func (c *Client) SyncPullStream(clientID string, currentVersion int64, schemaTypes []string, limit int, pageToken string, onRecord func(record json.RawMessage) error) (map[string]interface{}, error) {
+	req, err := c.newSyncPullRequest(clientID, currentVersion, schemaTypes, limit, pageToken)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line struct {
+			Type    string          `json:"type"`
+			Record  json.RawMessage `json:"record"`
+			Message string          `json:"message"`
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil, ErrIncompleteStream
+			}
+			return nil, fmt.Errorf("%w: %v", ErrIncompleteStream, err)
+		}
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, fmt.Errorf("error parsing stream line: %w", err)
+		}
+
+		switch line.Type {
+		case "record":
+			if err := onRecord(line.Record); err != nil {
+				return nil, err
+			}
+		case "end":
+			var summary map[string]interface{}
+			if err := json.Unmarshal(raw, &summary); err != nil {
+				return nil, fmt.Errorf("error parsing stream end: %w", err)
+			}
+			delete(summary, "type")
+			return summary, nil
+		case "error":
+			return nil, fmt.Errorf("%w: server error: %s", ErrIncompleteStream, line.Message)
+		}
+	}
+}
+
+// newSyncPullRequest builds the request of a sync pull
+func (c *Client) newSyncPullRequest(clientID string, currentVersion int64, schemaTypes []string, limit int, pageToken string) (*http.Request, error) {
 	requestURL := fmt.Sprintf("%s/sync/pull", c.BaseURL)
 
 	// Build query parameters
@@ -452,23 +629,57 @@ func (c *Client) SyncPull(clientID string, currentVersion int64, schemaTypes []s
 		req.Header.Set("x-api-version", c.APIVersion)
 	}
 
-	// Send request
-	resp, err := c.doRequest(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
+	return req, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
+// ErrTooManyRecords is returned by SyncPullAll when the dataset exceeds the record limit
+var ErrTooManyRecords = errors.New("too many records")
+
+// SyncPullAll pulls every page of records of the given schema types, keeping the latest state of
+// each observation and dropping deleted ones. It stops with ErrTooManyRecords once more than
+// maxRecords records have been pulled, so it is only meant for small datasets.
+func (c *Client) SyncPullAll(clientID string, schemaTypes []string, maxRecords int) ([]map[string]interface{}, error) {
+	latest := make(map[string]map[string]interface{})
+	var order []string
+	var version int64
+	pulled := 0
+	for {
+		response, err := c.SyncPull(clientID, version, schemaTypes, 0, "")
+		if err != nil {
+			return nil, err
+		}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error parsing response: %w", err)
+		records, _ := response["records"].([]interface{})
+		pulled += len(records)
+		if pulled > maxRecords {
+			return nil, fmt.Errorf("%w: more than %d records", ErrTooManyRecords, maxRecords)
+		}
+		for _, r := range records {
+			record, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := record["observation_id"].(string)
+			if _, seen := latest[id]; !seen {
+				order = append(order, id)
+			}
+			latest[id] = record
+		}
+
+		hasMore, _ := response["has_more"].(bool)
+		cutoff, _ := response["change_cutoff"].(float64)
+		if !hasMore || len(records) == 0 || int64(cutoff) <= version {
+			break
+		}
+		version = int64(cutoff)
 	}
 
+	result := make([]map[string]interface{}, 0, len(order))
+	for _, id := range order {
+		if deleted, _ := latest[id]["deleted"].(bool); !deleted {
+			result = append(result, latest[id])
+		}
+	}
 	return result, nil
 }
 
@@ -505,7 +716,7 @@ func (c *Client) SyncPush(clientID string, transmissionID string, records []map[
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result map[string]interface{}
@@ -515,3 +726,107 @@ func (c *Client) SyncPush(clientID string, transmissionID string, records []map[
 
 	return result, nil
 }
+
+// CompactionRequest represents the payload for compacting the server's sync log
+type CompactionRequest struct {
+	TombstoneRetentionDays int  `json:"tombstone_retention_days,omitempty"`
+	HistoryRetentionDays   int  `json:"history_retention_days,omitempty"`
+	DryRun                 bool `json:"dry_run,omitempty"`
+}
+
+// CompactSyncLog calls POST /sync/compactions to purge old tombstones and superseded versions (admin)
+func (c *Client) CompactSyncLog(reqBody CompactionRequest) (map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/sync/compactions", c.BaseURL)
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	return result, nil
+}
+
+// ListSyncCompactions calls GET /sync/compactions for the most recent compactions (admin)
+func (c *Client) ListSyncCompactions(limit int) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/sync/compactions?limit=%d", c.BaseURL, limit)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		Compactions []map[string]interface{} `json:"compactions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	return result.Compactions, nil
+}
+
+// VerifySyncReceipt calls POST /sync/receipts/verify to check the verification code a push
+// returned for a version of an observation
+func (c *Client) VerifySyncReceipt(observationID string, version int64, code string) (map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/sync/receipts/verify", c.BaseURL)
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"observation_id": observationID,
+		"version":        version,
+		"code":           code,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ClientUpdate represents the payload for labelling a device or changing its status
+type ClientUpdate struct {
+	Label  *string `json:"label,omitempty"`
+	Status string  `json:"status,omitempty"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// ListClients calls GET /clients, listing the devices seen in sync; status narrows the listing
+// to active, suspended or blocked devices
+func (c *Client) ListClients(status string) ([]map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("%s/clients", c.BaseURL)
+	if status != "" {
+		endpoint += "?" + url.Values{"status": {status}}.Encode()
+	}
+	request, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var clients []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&clients); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return clients, nil
+}
+
+// UpdateClient calls PATCH /clients/{clientId} and returns the updated device
+func (c *Client) UpdateClient(clientID string, reqBody ClientUpdate) (map[string]interface{}, error) {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	request, err := http.NewRequest("PATCH", fmt.Sprintf("%s/clients/%s", c.BaseURL, url.PathEscape(clientID)), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ListExportTemplates calls GET /dataexport/templates
+func (c *Client) ListExportTemplates() ([]map[string]interface{}, error) {
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/dataexport/templates", c.BaseURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var result struct {
+		Templates []map[string]interface{} `json:"templates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Templates, nil
+}
+
+// GetExportTemplate calls GET /dataexport/templates/{name}
+func (c *Client) GetExportTemplate(name string) (map[string]interface{}, error) {
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/dataexport/templates/%s", c.BaseURL, url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var template map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&template); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return template, nil
+}
+
+// SaveExportTemplate calls PUT /dataexport/templates/{name} with a description and a
+// definition, which is sent as given
+func (c *Client) SaveExportTemplate(name, description string, definition json.RawMessage) (map[string]interface{}, error) {
+	body, err := json.Marshal(map[string]interface{}{"description": description, "definition": definition})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	request, err := http.NewRequest("PUT", fmt.Sprintf("%s/dataexport/templates/%s", c.BaseURL, url.PathEscape(name)), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var template map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&template); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteExportTemplate calls DELETE /dataexport/templates/{name}
+func (c *Client) DeleteExportTemplate(name string) error {
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("%s/dataexport/templates/%s", c.BaseURL, url.PathEscape(name)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return nil
+}
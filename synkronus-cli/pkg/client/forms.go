@@ -0,0 +1,72 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// XLSFormIssue is a part of an XLSForm the server could not convert
+type XLSFormIssue struct {
+	Sheet   string `json:"sheet"`
+	Row     int    `json:"row"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// XLSFormConversion is an XLSForm converted to the schema.json and ui.json of a bundle form
+type XLSFormConversion struct {
+	FormType    string          `json:"form_type"`
+	Title       string          `json:"title,omitempty"`
+	Version     string          `json:"version,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	UI          json.RawMessage `json:"ui"`
+	Unsupported []XLSFormIssue  `json:"unsupported"`
+}
+
+// ConvertXLSForm calls POST /forms/convert with an XLSForm file
+func (c *Client) ConvertXLSForm(filePath string) (*XLSFormConversion, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("error creating form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("error copying file content: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", fmt.Sprintf("%s/forms/convert", c.BaseURL), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var conversion XLSFormConversion
+	if err := json.NewDecoder(resp.Body).Decode(&conversion); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &conversion, nil
+}
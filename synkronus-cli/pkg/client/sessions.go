@@ -0,0 +1,73 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ListSessions calls GET /auth/sessions, listing the current user's active login sessions
+func (c *Client) ListSessions() ([]map[string]interface{}, error) {
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/sessions", c.BaseURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var sessions []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession calls DELETE /auth/sessions/{id}
+func (c *Client) RevokeSession(id string) error {
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("%s/auth/sessions/%s", c.BaseURL, url.PathEscape(id)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return nil
+}
+
+// RevokeAllSessions calls DELETE /auth/sessions and returns how many sessions were revoked
+func (c *Client) RevokeAllSessions() (int, error) {
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("%s/auth/sessions", c.BaseURL), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.doRequest(request)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var result struct {
+		Revoked int `json:"revoked"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Revoked, nil
+}
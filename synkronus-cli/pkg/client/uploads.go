@@ -0,0 +1,103 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// AppBundleUpload is an app bundle being uploaded in chunks
+type AppBundleUpload struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size"`
+	// Received is the number of bytes the server has; the next chunk starts there
+	Received  int64  `json:"received"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// CreateAppBundleUpload calls POST /app-bundle/uploads to start a chunked upload of a bundle
+func (c *Client) CreateAppBundleUpload(size int64) (*AppBundleUpload, error) {
+	body, err := json.Marshal(map[string]int64{"size": size})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/app-bundle/uploads", c.BaseURL), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doUploadRequest(req, http.StatusCreated)
+}
+
+// GetAppBundleUpload calls GET /app-bundle/uploads/{id}, e.g. to resume an upload at Received
+func (c *Client) GetAppBundleUpload(id string) (*AppBundleUpload, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/app-bundle/uploads/%s", c.BaseURL, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.doUploadRequest(req, http.StatusOK)
+}
+
+// UploadAppBundleChunk calls PUT /app-bundle/uploads/{id} with the chunk of a bundle of the
+// given size starting at offset. A chunk that does not start at the received byte count fails
+// with an APIError of status 409.
+func (c *Client) UploadAppBundleChunk(id string, offset int64, chunk []byte, size int64) (*AppBundleUpload, error) {
+	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/app-bundle/uploads/%s", c.BaseURL, id), bytes.NewReader(chunk))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+	return c.doUploadRequest(req, http.StatusOK)
+}
+
+// CompleteAppBundleUpload calls POST /app-bundle/uploads/{id}/complete, pushing the uploaded
+// bundle with an optional signature. The response is that of UploadAppBundle.
+func (c *Client) CompleteAppBundleUpload(id, signature string) (map[string]interface{}, error) {
+	body, err := json.Marshal(map[string]string{"signature": signature})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/app-bundle/uploads/%s/complete", c.BaseURL, id), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// doUploadRequest sends a chunked upload request and decodes the upload it returns
+func (c *Client) doUploadRequest(req *http.Request, status int) (*AppBundleUpload, error) {
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	var upload AppBundleUpload
+	if err := json.NewDecoder(resp.Body).Decode(&upload); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &upload, nil
+}
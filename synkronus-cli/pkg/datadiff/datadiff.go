@@ -0,0 +1,158 @@
+// Package datadiff compares two sets of pulled records and reports which records were added,
+// removed or changed, and which fields changed.
+package datadiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldChange is a field whose value differs between the two sets. Old or New is nil when the
+// field is missing on that side.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// RecordChange is a record present in both sets with different fields
+type RecordChange struct {
+	Key    string        `json:"key"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// Result is the difference between two sets of records, with keys sorted
+type Result struct {
+	Added     []string       `json:"added"`
+	Removed   []string       `json:"removed"`
+	Changed   []RecordChange `json:"changed"`
+	Unchanged int            `json:"unchanged"`
+}
+
+// Empty reports whether the two sets hold the same records
+func (r Result) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// LoadRecords reads the records of a file written by 'synk sync pull', or of a file holding a
+// JSON array of records
+func LoadRecords(path string) ([]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]any
+	if err := json.Unmarshal(data, &records); err == nil {
+		return records, nil
+	}
+	var response struct {
+		Records *[]map[string]any `json:"records"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil || response.Records == nil {
+		return nil, fmt.Errorf("%s is neither a pull response nor a JSON array of records", path)
+	}
+	return *response.Records, nil
+}
+
+// Diff compares the records by the field named key, which may be a dotted path such as
+// "data.household_id". Fields are compared by dotted path; fields named in ignore, and the fields
+// nested under them, are skipped.
+func Diff(oldRecords, newRecords []map[string]any, key string, ignore []string) (Result, error) {
+	oldByKey, err := index(oldRecords, key, "old")
+	if err != nil {
+		return Result{}, err
+	}
+	newByKey, err := index(newRecords, key, "new")
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Added: []string{}, Removed: []string{}, Changed: []RecordChange{}}
+	for k, oldFields := range oldByKey {
+		newFields, ok := newByKey[k]
+		if !ok {
+			result.Removed = append(result.Removed, k)
+			continue
+		}
+		if changes := compare(oldFields, newFields, ignore); len(changes) > 0 {
+			result.Changed = append(result.Changed, RecordChange{Key: k, Fields: changes})
+		} else {
+			result.Unchanged++
+		}
+	}
+	for k := range newByKey {
+		if _, ok := oldByKey[k]; !ok {
+			result.Added = append(result.Added, k)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Slice(result.Changed, func(i, j int) bool { return result.Changed[i].Key < result.Changed[j].Key })
+	return result, nil
+}
+
+// index flattens the records and maps them by their key
+func index(records []map[string]any, key, side string) (map[string]map[string]any, error) {
+	byKey := make(map[string]map[string]any, len(records))
+	for i, record := range records {
+		fields := make(map[string]any)
+		flatten(fields, "", record)
+		value, ok := fields[key]
+		if !ok || value == nil {
+			return nil, fmt.Errorf("record %d of the %s file has no %s", i+1, side, key)
+		}
+		k := fmt.Sprint(value)
+		if _, dup := byKey[k]; dup {
+			return nil, fmt.Errorf("%s %s appears more than once in the %s file", key, k, side)
+		}
+		byKey[k] = fields
+	}
+	return byKey, nil
+}
+
+// flatten copies value into fields under dotted paths starting with prefix. Arrays are compared
+// as a whole.
+func flatten(fields map[string]any, prefix string, value map[string]any) {
+	for key, v := range value {
+		if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+			flatten(fields, prefix+key+".", nested)
+			continue
+		}
+		fields[prefix+key] = v
+	}
+}
+
+// compare returns the fields that differ, sorted by path
+func compare(oldFields, newFields map[string]any, ignore []string) []FieldChange {
+	var changes []FieldChange
+	for field, oldValue := range oldFields {
+		if ignored(field, ignore) {
+			continue
+		}
+		if newValue, ok := newFields[field]; !ok || !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newFields[field]})
+		}
+	}
+	for field, newValue := range newFields {
+		if _, ok := oldFields[field]; !ok && !ignored(field, ignore) {
+			changes = append(changes, FieldChange{Field: field, New: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// ignored reports whether field is one of the ignored fields or nested under one
+func ignored(field string, ignore []string) bool {
+	for _, prefix := range ignore {
+		if field == prefix || strings.HasPrefix(field, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
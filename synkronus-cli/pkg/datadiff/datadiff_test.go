@@ -0,0 +1,97 @@
+package datadiff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const oldPull = `{
+  "current_version": 10,
+  "records": [
+    {"observation_id": "obs-1", "version": 3, "data": {"name": "Amina", "household": {"size": 4}}},
+    {"observation_id": "obs-2", "version": 4, "data": {"name": "Juma"}},
+    {"observation_id": "obs-3", "version": 5, "data": {"name": "Neema", "tags": ["a"]}}
+  ]
+}`
+
+const newRecords = `[
+  {"observation_id": "obs-1", "version": 11, "data": {"name": "Amina", "household": {"size": 5}, "phone": "0700"}},
+  {"observation_id": "obs-3", "version": 12, "data": {"name": "Neema", "tags": ["a"]}},
+  {"observation_id": "obs-4", "version": 13, "data": {"name": "Baraka"}}
+]`
+
+func write(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func load(t *testing.T, name, content string) []map[string]any {
+	t.Helper()
+	records, err := LoadRecords(write(t, name, content))
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return records
+}
+
+func TestDiff(t *testing.T) {
+	oldRecords := load(t, "old.json", oldPull)
+	newRecords := load(t, "new.json", newRecords)
+
+	result, err := Diff(oldRecords, newRecords, "observation_id", []string{"version"})
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"obs-4"}) || !reflect.DeepEqual(result.Removed, []string{"obs-2"}) {
+		t.Fatalf("unexpected added %v and removed %v", result.Added, result.Removed)
+	}
+	if result.Unchanged != 1 || len(result.Changed) != 1 || result.Changed[0].Key != "obs-1" {
+		t.Fatalf("unexpected changes: %+v", result)
+	}
+	want := []FieldChange{
+		{Field: "data.household.size", Old: 4.0, New: 5.0},
+		{Field: "data.phone", New: "0700"},
+	}
+	if !reflect.DeepEqual(result.Changed[0].Fields, want) {
+		t.Fatalf("unexpected field changes %+v, want %+v", result.Changed[0].Fields, want)
+	}
+
+	// Without ignoring it, the version differs on every record
+	result, err = Diff(oldRecords, newRecords, "observation_id", nil)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if result.Unchanged != 0 || len(result.Changed) != 2 {
+		t.Fatalf("expected version changes on both common records, got %+v", result)
+	}
+
+	// Keys can be nested fields
+	result, err = Diff(oldRecords, newRecords, "data.name", []string{"version", "observation_id", "data"})
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"Baraka"}) || result.Unchanged != 2 || result.Empty() {
+		t.Fatalf("unexpected result for a nested key: %+v", result)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	records := load(t, "new.json", newRecords)
+	if _, err := Diff(records, records, "case_id", nil); err == nil || !strings.Contains(err.Error(), "has no case_id") {
+		t.Fatalf("expected a missing key error, got %v", err)
+	}
+	dup := append(records, records[0])
+	if _, err := Diff(records, dup, "observation_id", nil); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Fatalf("expected a duplicate key error, got %v", err)
+	}
+	if _, err := LoadRecords(write(t, "bad.json", `{"status": "ok"}`)); err == nil {
+		t.Fatalf("expected an error for a file without records")
+	}
+}
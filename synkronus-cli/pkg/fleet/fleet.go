@@ -0,0 +1,207 @@
+// Package fleet runs synk commands against every configured server profile at once and combines
+// their output into one table, for teams operating many Synkronus servers.
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile is a config file pointing the CLI at one server
+type Profile struct {
+	Name string
+	Path string
+}
+
+// Result is the outcome of running a command with one profile
+type Result struct {
+	Profile  Profile
+	Stdout   string
+	Stderr   string
+	Err      error
+	Duration time.Duration
+}
+
+// Discover returns the profiles in home: .synkronus.yaml as "default" and every
+// .synkronus-<name>.yaml as <name>, plus the file selected with 'synk config use' when it lives
+// elsewhere. Profiles are sorted by name.
+func Discover(home string) ([]Profile, error) {
+	matches, err := filepath.Glob(filepath.Join(home, ".synkronus-*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(home, ".synkronus.yaml")); err == nil {
+		matches = append(matches, filepath.Join(home, ".synkronus.yaml"))
+	}
+	if data, err := os.ReadFile(filepath.Join(home, ".synkronus_current")); err == nil {
+		if current := strings.TrimSpace(string(data)); current != "" {
+			if _, err := os.Stat(current); err == nil {
+				matches = append(matches, current)
+			}
+		}
+	}
+	return FromPaths(matches), nil
+}
+
+// FromPaths returns the profiles for config file paths, named after the files and sorted by name
+func FromPaths(paths []string) []Profile {
+	seen := make(map[string]bool)
+	var profiles []Profile
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		profiles = append(profiles, Profile{Name: profileName(abs), Path: abs})
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// profileName derives a short name from a config file path
+func profileName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name = strings.TrimPrefix(name, ".")
+	if name == "synkronus" {
+		return "default"
+	}
+	if rest, ok := strings.CutPrefix(name, "synkronus-"); ok && rest != "" {
+		return rest
+	}
+	return name
+}
+
+// Run runs executable with "--config <profile path>" followed by args for every profile, at most
+// parallel at a time. Results are in the order of profiles.
+func Run(ctx context.Context, executable string, profiles []Profile, args []string, parallel int) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]Result, len(profiles))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, profile := range profiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.CommandContext(ctx, executable, append([]string{"--config", profile.Path}, args...)...)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			// Output is parsed, so keep it free of colour codes
+			cmd.Env = append(os.Environ(), "NO_COLOR=1")
+
+			start := time.Now()
+			err := cmd.Run()
+			results[i] = Result{
+				Profile:  profile,
+				Stdout:   stdout.String(),
+				Stderr:   stderr.String(),
+				Err:      err,
+				Duration: time.Since(start),
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// ansiPattern matches terminal colour codes
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// keyPattern matches the keys of "Key: Value" lines
+var keyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 ()/._-]{0,39}$`)
+
+// itemsColumn is the column that collects "- item" list lines
+const itemsColumn = "Items"
+
+// Table combines the results into a table with a row per profile. "Key: Value" lines of each
+// output become columns, in the order they first appear, and "- item" lines are joined into an
+// Items column. Failed runs show their error message.
+func Table(results []Result) (header []string, rows [][]string) {
+	var columns []string
+	known := make(map[string]bool)
+	values := make([]map[string]string, len(results))
+	for i, result := range results {
+		var keys []string
+		keys, values[i] = parseOutput(result.Stdout)
+		for _, key := range keys {
+			if !known[key] {
+				known[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+
+	header = append([]string{"Profile", "Status"}, columns...)
+	header = append(header, "Error")
+	for i, result := range results {
+		status, errText := "ok", ""
+		if result.Err != nil {
+			status = "failed"
+			errText = errorLine(result.Stderr)
+			if errText == "" {
+				errText = result.Err.Error()
+			}
+		}
+		row := []string{result.Profile.Name, status}
+		for _, column := range columns {
+			row = append(row, values[i][column])
+		}
+		rows = append(rows, append(row, errText))
+	}
+	return header, rows
+}
+
+// parseOutput collects the "Key: Value" and "- item" lines of a command's output, returning the
+// keys in the order they appear
+func parseOutput(output string) (keys []string, values map[string]string) {
+	values = make(map[string]string)
+	var items []string
+	for _, line := range strings.Split(ansiPattern.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimSpace(line)
+		if item, ok := strings.CutPrefix(line, "- "); ok {
+			if len(items) == 0 {
+				keys = append(keys, itemsColumn)
+			}
+			items = append(items, item)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || !keyPattern.MatchString(key) {
+			continue
+		}
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+			values[key] = strings.TrimSpace(value)
+		}
+	}
+	if len(items) > 0 {
+		values[itemsColumn] = strings.Join(items, ", ")
+	}
+	return keys, values
+}
+
+// errorLine returns the message of the "Error: " line cobra prints, or else the last non-empty
+// line of the error output
+func errorLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(ansiPattern.ReplaceAllString(stderr, "")), "\n")
+	for _, line := range lines {
+		if message, ok := strings.CutPrefix(strings.TrimSpace(line), "Error: "); ok {
+			return message
+		}
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	home := t.TempDir()
+	elsewhere := filepath.Join(t.TempDir(), "kenya.yaml")
+	for _, path := range []string{
+		filepath.Join(home, ".synkronus.yaml"),
+		filepath.Join(home, ".synkronus-uganda.yaml"),
+		filepath.Join(home, ".synkronus-malawi.yaml"),
+		filepath.Join(home, ".synkronus_token"),
+		elsewhere,
+	} {
+		if err := os.WriteFile(path, []byte("api:\n  url: http://localhost:8080\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(home, ".synkronus_current"), []byte(elsewhere+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := Discover(home)
+	if err != nil {
+		t.Fatalf("failed to discover profiles: %v", err)
+	}
+	var names []string
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	if want := []string{"default", "kenya", "malawi", "uganda"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected profiles %v, want %v", names, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo not available")
+	}
+	profiles := FromPaths([]string{"/etc/synk/.synkronus-a.yaml", "/etc/synk/.synkronus-b.yaml"})
+	results := Run(t.Context(), echo, profiles, []string{"version"}, 1)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if want := "--config " + profiles[i].Path + " version\n"; result.Err != nil || result.Stdout != want {
+			t.Fatalf("unexpected result for %s: %q %v", result.Profile.Name, result.Stdout, result.Err)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	results := []Result{
+		{
+			Profile: Profile{Name: "kenya"},
+			Stdout: "\x1b[36mServer Version\x1b[0m\n" +
+				"\x1b[1mServer version\x1b[0m: 1.4.0\n" +
+				"Database: postgres 16\n" +
+				"Response time: 12.3ms\n",
+		},
+		{
+			Profile: Profile{Name: "malawi"},
+			Stdout:  "Available App Bundle Versions:\n- 20250506-101500\n- 20250507-123456 *\nServer version: 1.3.2\n",
+		},
+		{
+			Profile: Profile{Name: "uganda"},
+			Stderr:  "Error: failed to get server version: connection refused\nUsage:\n  synk version [flags]\n",
+			Err:     errors.New("exit status 1"),
+		},
+	}
+
+	header, rows := Table(results)
+	wantHeader := []string{"Profile", "Status", "Server version", "Database", "Response time", "Items", "Error"}
+	if !reflect.DeepEqual(header, wantHeader) {
+		t.Fatalf("unexpected header %v, want %v", header, wantHeader)
+	}
+	wantRows := [][]string{
+		{"kenya", "ok", "1.4.0", "postgres 16", "12.3ms", "", ""},
+		{"malawi", "ok", "1.3.2", "", "", "20250506-101500, 20250507-123456 *", ""},
+		{"uganda", "failed", "", "", "", "", "failed to get server version: connection refused"},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Fatalf("unexpected rows:\n%q\nwant:\n%q", rows, wantRows)
+	}
+}
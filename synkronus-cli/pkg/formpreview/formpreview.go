@@ -0,0 +1,351 @@
+// Package formpreview renders a form's schema.json and ui.json without the mobile app, as a page
+// served to a local browser or as an outline for the terminal, so form designers can iterate
+// without uploading an app bundle.
+package formpreview
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Element kinds of the rendered layout
+const (
+	KindGroup   = "group"
+	KindLayout  = "layout"
+	KindLabel   = "label"
+	KindControl = "control"
+)
+
+// Inputs a control is rendered with
+const (
+	InputText        = "text"
+	InputTextarea    = "textarea"
+	InputNumber      = "number"
+	InputInteger     = "integer"
+	InputCheckbox    = "checkbox"
+	InputDate        = "date"
+	InputDateTime    = "datetime"
+	InputTime        = "time"
+	InputSelect      = "select"
+	InputMultiSelect = "multiselect"
+	// InputCapture is a question answered with a device feature such as the camera; the
+	// preview shows a placeholder naming the format
+	InputCapture = "capture"
+	// InputCustom is a question rendered by a custom renderer from the bundle
+	InputCustom = "custom"
+)
+
+// captureFormats are the schema formats the formplayer answers with device features
+var captureFormats = map[string]bool{
+	"photo": true, "signature": true, "audio": true, "video": true,
+	"qrcode": true, "select_file": true, "gps": true,
+}
+
+// Choice is an option of a select or multiselect question
+type Choice struct {
+	Value string
+	Label string
+}
+
+// Field is a question as the formplayer would ask it
+type Field struct {
+	// Name is the dotted path of the answer in the observation data
+	Name        string
+	Input       string
+	Required    bool
+	Description string
+	Choices     []Choice
+	Minimum     *float64
+	Maximum     *float64
+	// Format is the capture format or the custom renderer name
+	Format string
+}
+
+// Element is a node of the rendered layout
+type Element struct {
+	Kind string
+	// Label is the title of a group or control, or the text of a label
+	Label    string
+	Field    *Field
+	Children []Element
+}
+
+// Form is a form prepared for preview
+type Form struct {
+	Name string
+	// Pages hold the swipeable screens of the form; forms without a SwipeLayout have one page
+	Pages [][]Element
+	// Problems lists parts of the definition the preview could not render
+	Problems []string
+}
+
+// Load reads schema.json and ui.json from a form directory, e.g. forms/user of a bundle
+func Load(dir string) (*Form, error) {
+	var schema, ui map[string]any
+	if err := readJSON(filepath.Join(dir, "schema.json"), &schema); err != nil {
+		return nil, err
+	}
+	if err := readJSON(filepath.Join(dir, "ui.json"), &ui); err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Build(filepath.Base(abs), schema, ui), nil
+}
+
+// readJSON decodes a JSON file of the form
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Build lays out a form from its JSON schema and UI schema
+func Build(name string, schema, ui map[string]any) *Form {
+	form := &Form{Name: name}
+	b := &builder{schema: schema, form: form}
+
+	if ui["type"] == "SwipeLayout" {
+		for _, page := range children(ui) {
+			form.Pages = append(form.Pages, b.elements([]any{page}))
+		}
+	} else {
+		form.Pages = [][]Element{b.elements([]any{ui})}
+	}
+	return form
+}
+
+// builder turns UI schema elements into Elements, noting problems on the form
+type builder struct {
+	schema map[string]any
+	form   *Form
+}
+
+func (b *builder) problem(format string, args ...any) {
+	b.form.Problems = append(b.form.Problems, fmt.Sprintf(format, args...))
+}
+
+// elements converts UI schema elements, skipping the ones that cannot be rendered
+func (b *builder) elements(items []any) []Element {
+	var elements []Element
+	for _, item := range items {
+		ui, ok := item.(map[string]any)
+		if !ok {
+			b.problem("UI element %v is not an object", item)
+			continue
+		}
+
+		switch kind, _ := ui["type"].(string); kind {
+		case "VerticalLayout", "HorizontalLayout":
+			elements = append(elements, Element{Kind: KindLayout, Children: b.elements(children(ui))})
+		case "Group":
+			label, _ := ui["label"].(string)
+			elements = append(elements, Element{Kind: KindGroup, Label: label, Children: b.elements(children(ui))})
+		case "Label":
+			text, _ := ui["text"].(string)
+			elements = append(elements, Element{Kind: KindLabel, Label: text})
+		case "Control":
+			if element, ok := b.control(ui); ok {
+				elements = append(elements, element)
+			}
+		case "SwipeLayout":
+			b.problem("SwipeLayout is only supported as the root element")
+		default:
+			b.problem("unsupported UI element type %q", kind)
+		}
+	}
+	return elements
+}
+
+// children returns the elements of a layout
+func children(ui map[string]any) []any {
+	items, _ := ui["elements"].([]any)
+	return items
+}
+
+// control resolves a Control's scope against the schema
+func (b *builder) control(ui map[string]any) (Element, bool) {
+	scope, _ := ui["scope"].(string)
+	path, ok := strings.CutPrefix(scope, "#/properties/")
+	if !ok {
+		b.problem("control scope %q does not start with #/properties/", scope)
+		return Element{}, false
+	}
+
+	segments := strings.Split(path, "/properties/")
+	parent := b.schema
+	var property map[string]any
+	for i, segment := range segments {
+		properties, _ := parent["properties"].(map[string]any)
+		property, _ = properties[segment].(map[string]any)
+		if property == nil {
+			b.problem("control scope %q does not match a schema property", scope)
+			return Element{}, false
+		}
+		if i < len(segments)-1 {
+			parent = property
+		}
+	}
+	name := segments[len(segments)-1]
+
+	field := &Field{
+		Name:     strings.Join(segments, "."),
+		Required: containsValue(parent["required"], name),
+	}
+	field.Description, _ = property["description"].(string)
+	field.Minimum = number(property["minimum"])
+	field.Maximum = number(property["maximum"])
+	options, _ := ui["options"].(map[string]any)
+	b.input(field, property, options)
+
+	label := humanize(name)
+	if title, ok := property["title"].(string); ok && title != "" {
+		label = title
+	}
+	if override, ok := ui["label"].(string); ok {
+		label = override
+	}
+	return Element{Kind: KindControl, Label: label, Field: field}, true
+}
+
+// input picks the input a property is answered with, as the formplayer's renderers would
+func (b *builder) input(field *Field, property, options map[string]any) {
+	format, _ := property["format"].(string)
+	kind, _ := property["type"].(string)
+
+	if renderer, ok := property["x-renderer"].(string); ok && renderer != "" {
+		field.Input, field.Format = InputCustom, renderer
+		return
+	}
+	if captureFormats[format] {
+		field.Input, field.Format = InputCapture, format
+		return
+	}
+	if choices := choicesOf(property); choices != nil {
+		field.Input, field.Choices = InputSelect, choices
+		return
+	}
+
+	switch kind {
+	case "array":
+		items, _ := property["items"].(map[string]any)
+		if choices := choicesOf(items); choices != nil {
+			field.Input, field.Choices = InputMultiSelect, choices
+			return
+		}
+	case "boolean":
+		field.Input = InputCheckbox
+		return
+	case "integer":
+		field.Input = InputInteger
+		return
+	case "number":
+		field.Input = InputNumber
+		return
+	case "string", "":
+		switch format {
+		case "date":
+			field.Input = InputDate
+		case "date-time":
+			field.Input = InputDateTime
+		case "time":
+			field.Input = InputTime
+		default:
+			field.Input = InputText
+			if multi, _ := options["multi"].(bool); multi {
+				field.Input = InputTextarea
+			}
+		}
+		return
+	}
+
+	b.problem("property %s of type %q is previewed as text", field.Name, kind)
+	field.Input = InputText
+}
+
+// choicesOf returns the choices of an enum or oneOf property, or nil
+func choicesOf(property map[string]any) []Choice {
+	if values, ok := property["enum"].([]any); ok {
+		choices := make([]Choice, 0, len(values))
+		for _, value := range values {
+			text := fmt.Sprint(value)
+			choices = append(choices, Choice{Value: text, Label: text})
+		}
+		return choices
+	}
+	if options, ok := property["oneOf"].([]any); ok {
+		choices := make([]Choice, 0, len(options))
+		for _, option := range options {
+			option, _ := option.(map[string]any)
+			value, ok := option["const"]
+			if !ok {
+				return nil
+			}
+			choice := Choice{Value: fmt.Sprint(value), Label: fmt.Sprint(value)}
+			if title, ok := option["title"].(string); ok {
+				choice.Label = title
+			}
+			choices = append(choices, choice)
+		}
+		return choices
+	}
+	return nil
+}
+
+// Fields returns the controls of the form in page order
+func (f *Form) Fields() []*Field {
+	var fields []*Field
+	var walk func([]Element)
+	walk = func(elements []Element) {
+		for _, element := range elements {
+			if element.Field != nil {
+				fields = append(fields, element.Field)
+			}
+			walk(element.Children)
+		}
+	}
+	for _, page := range f.Pages {
+		walk(page)
+	}
+	return fields
+}
+
+// containsValue reports whether a JSON array holds the string
+func containsValue(list any, value string) bool {
+	items, _ := list.([]any)
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// number returns a JSON number, or nil
+func number(v any) *float64 {
+	if n, ok := v.(float64); ok {
+		return &n
+	}
+	return nil
+}
+
+// humanize turns a property name such as date_of_birth into "Date of birth"
+func humanize(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	if len(words) == 0 {
+		return name
+	}
+	text := strings.ToLower(strings.Join(words, " "))
+	return strings.ToUpper(text[:1]) + text[1:]
+}
@@ -0,0 +1,155 @@
+package formpreview
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "title": "Full name", "description": "As on the ID card"},
+    "age": {"type": "integer", "minimum": 0, "maximum": 120},
+    "sex": {"type": "string", "oneOf": [{"const": "f", "title": "Female"}, {"const": "m", "title": "Male"}]},
+    "symptoms": {"type": "array", "items": {"enum": ["fever", "cough"]}},
+    "photo": {"type": "object", "format": "photo"},
+    "visit": {"type": "object", "properties": {"date": {"type": "string", "format": "date"}}}
+  }
+}`
+
+const testUI = `{
+  "type": "SwipeLayout",
+  "elements": [
+    {"type": "VerticalLayout", "elements": [
+      {"type": "Label", "text": "Basic information"},
+      {"type": "Control", "scope": "#/properties/name"},
+      {"type": "Control", "scope": "#/properties/age"}
+    ]},
+    {"type": "Group", "label": "Health", "elements": [
+      {"type": "Control", "scope": "#/properties/sex"},
+      {"type": "Control", "scope": "#/properties/symptoms"},
+      {"type": "Control", "scope": "#/properties/photo"},
+      {"type": "Control", "scope": "#/properties/visit/properties/date"},
+      {"type": "Control", "scope": "#/properties/missing"},
+      {"type": "Categorization"}
+    ]}
+  ]
+}`
+
+func writeForm(t *testing.T, schema, ui string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "registration")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"schema.json": schema, "ui.json": ui} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	form, err := Load(writeForm(t, testSchema, testUI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Name != "registration" || len(form.Pages) != 2 {
+		t.Fatalf("form = %s with %d pages", form.Name, len(form.Pages))
+	}
+
+	want := map[string]string{
+		"name": "text", "age": "integer", "sex": "select", "symptoms": "multiselect",
+		"photo": "capture", "visit.date": "date",
+	}
+	fields := form.Fields()
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for _, field := range fields {
+		if want[field.Name] != field.Input {
+			t.Errorf("field %s input = %s, want %s", field.Name, field.Input, want[field.Name])
+		}
+	}
+	if !fields[0].Required || fields[1].Required {
+		t.Error("only name should be required")
+	}
+	if fields[2].Choices[0] != (Choice{Value: "f", Label: "Female"}) {
+		t.Errorf("sex choices = %v", fields[2].Choices)
+	}
+	if len(form.Problems) != 2 {
+		t.Errorf("problems = %v, want the missing property and the unsupported element", form.Problems)
+	}
+
+	var out bytes.Buffer
+	if err := WriteText(&out, form); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"* Full name  (text)", "  Age  (integer 0..120)", "[Health]", "(select: Female | Male)", "(capture: photo)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("outline is missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(writeForm(t, `{"type": "object"`, testUI)); err == nil || !strings.Contains(err.Error(), "schema.json") {
+		t.Errorf("Load() error = %v, want an error naming schema.json", err)
+	}
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Error("Load() of a directory without a form should fail")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	dir := writeForm(t, testSchema, testUI)
+	server := httptest.NewServer(Handler(dir))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/")
+	if status != http.StatusOK {
+		t.Fatalf("GET / = %d", status)
+	}
+	for _, snippet := range []string{`data-name="visit.date"`, `<option value="f">Female</option>`, `min="0" max="120"`, "photo - captured on the device"} {
+		if !strings.Contains(body, snippet) {
+			t.Errorf("page is missing %s", snippet)
+		}
+	}
+
+	_, before := get("/version")
+	schema := map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}}
+	data, _ := json.Marshal(schema)
+	if err := os.WriteFile(filepath.Join(dir, "schema.json"), append(data, ' '), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, after := get("/version"); after == before {
+		t.Error("version should change when the schema changes")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "ui.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := get("/"); status != http.StatusUnprocessableEntity || !strings.Contains(body, "invalid JSON in ui.json") {
+		t.Errorf("GET / with a broken ui.json = %d", status)
+	}
+}
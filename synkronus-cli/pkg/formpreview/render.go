@@ -0,0 +1,143 @@
+package formpreview
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//go:embed preview.html
+var assets embed.FS
+
+var pageTemplate = template.Must(template.New("preview.html").Funcs(template.FuncMap{
+	"bound": formatBound,
+}).ParseFS(assets, "preview.html"))
+
+// page is the data of the preview template
+type page struct {
+	Form  *Form
+	Error string
+}
+
+// WriteHTML writes the preview page of a form
+func WriteHTML(w io.Writer, form *Form) error {
+	return pageTemplate.Execute(w, page{Form: form})
+}
+
+// Handler serves the preview of the form in dir. The definition is read again on every request,
+// and open pages reload themselves when schema.json or ui.json changes.
+func Handler(dir string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+
+		form, err := Load(dir)
+		if err != nil {
+			// Keep serving a page that reloads itself, so fixing the file brings the form back
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			pageTemplate.Execute(w, page{Error: err.Error()})
+			return
+		}
+		pageTemplate.Execute(w, page{Form: form})
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, Fingerprint(dir))
+	})
+	return mux
+}
+
+// Fingerprint identifies the current contents of a form directory by the size and modification
+// time of its definition files
+func Fingerprint(dir string) string {
+	var parts []string
+	for _, name := range []string{"schema.json", "ui.json"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			parts = append(parts, name+":missing")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", name, info.Size(), info.ModTime().UnixNano()))
+	}
+	return strings.Join(parts, ";")
+}
+
+// WriteText writes an outline of a form for the terminal: its pages, groups and questions with
+// their inputs, marking required questions with an asterisk
+func WriteText(w io.Writer, form *Form) error {
+	fmt.Fprintf(w, "Form %s, %d page(s)\n", form.Name, len(form.Pages))
+	for i, elements := range form.Pages {
+		fmt.Fprintf(w, "\nPage %d\n", i+1)
+		writeElements(w, elements, 1)
+	}
+	if len(form.Problems) > 0 {
+		fmt.Fprintln(w, "\nProblems")
+		for _, problem := range form.Problems {
+			fmt.Fprintf(w, "  - %s\n", problem)
+		}
+	}
+	return nil
+}
+
+// writeElements writes elements of the outline at the given depth
+func writeElements(w io.Writer, elements []Element, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, element := range elements {
+		switch element.Kind {
+		case KindGroup:
+			fmt.Fprintf(w, "%s[%s]\n", indent, element.Label)
+			writeElements(w, element.Children, depth+1)
+		case KindLayout:
+			writeElements(w, element.Children, depth)
+		case KindLabel:
+			fmt.Fprintf(w, "%s%q\n", indent, element.Label)
+		case KindControl:
+			marker := " "
+			if element.Field.Required {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s%s %s  (%s)\n", indent, marker, element.Label, describeInput(element.Field))
+			if element.Field.Description != "" {
+				fmt.Fprintf(w, "%s    %s\n", indent, element.Field.Description)
+			}
+		}
+	}
+}
+
+// describeInput summarizes how a question is answered
+func describeInput(field *Field) string {
+	description := field.Input
+	switch field.Input {
+	case InputCapture, InputCustom:
+		description += ": " + field.Format
+	case InputSelect, InputMultiSelect:
+		labels := make([]string, 0, len(field.Choices))
+		for _, choice := range field.Choices {
+			labels = append(labels, choice.Label)
+		}
+		description += ": " + strings.Join(labels, " | ")
+	}
+	if field.Minimum != nil || field.Maximum != nil {
+		description += fmt.Sprintf(" %s..%s", formatBound(field.Minimum), formatBound(field.Maximum))
+	}
+	return description
+}
+
+// formatBound formats a minimum or maximum, empty when unset
+func formatBound(bound *float64) string {
+	if bound == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*bound, 'f', -1, 64)
+}
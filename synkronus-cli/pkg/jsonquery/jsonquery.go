@@ -0,0 +1,56 @@
+// Package jsonquery applies JMESPath expressions to command output, so scripts can pick fields out
+// of a response without an external tool such as jq.
+package jsonquery
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// Query is a compiled JMESPath expression
+type Query struct {
+	expression string
+	compiled   *jmespath.JMESPath
+}
+
+// Compile parses a JMESPath expression
+func Compile(expression string) (*Query, error) {
+	compiled, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", expression, err)
+	}
+	return &Query{expression: expression, compiled: compiled}, nil
+}
+
+// Apply evaluates the query against value. Value is first converted to its JSON form, so struct
+// fields are matched by their JSON names.
+func (q *Query) Apply(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting JSON: %w", err)
+	}
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error formatting JSON: %w", err)
+	}
+	result, err := q.compiled.Search(document)
+	if err != nil {
+		return nil, fmt.Errorf("query %q failed: %w", q.expression, err)
+	}
+	return result, nil
+}
+
+// Format renders a query result for printing. Strings are printed without quotes so shell scripts
+// can use them directly; everything else is printed as indented JSON.
+func Format(result any) (string, error) {
+	if s, ok := result.(string); ok {
+		return s, nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error formatting JSON: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,57 @@
+package jsonquery
+
+import (
+	"strings"
+	"testing"
+)
+
+type uploadResponse struct {
+	Version  string         `json:"version"`
+	Manifest map[string]any `json:"manifest"`
+}
+
+func TestApply(t *testing.T) {
+	response := uploadResponse{
+		Version: "20250507-123456",
+		Manifest: map[string]any{
+			"files": []map[string]any{
+				{"path": "forms/survey/schema.json", "size": 120},
+				{"path": "app/index.html", "size": 40},
+			},
+		},
+	}
+
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"version", "20250507-123456"},
+		{"manifest.files[0].path", "forms/survey/schema.json"},
+		{"length(manifest.files)", "2"},
+		{"manifest.files[?size > `100`].path", "[\n  \"forms/survey/schema.json\"\n]"},
+		{"missing", "null"},
+	}
+	for _, tt := range tests {
+		query, err := Compile(tt.expression)
+		if err != nil {
+			t.Fatalf("failed to compile %q: %v", tt.expression, err)
+		}
+		result, err := query.Apply(response)
+		if err != nil {
+			t.Fatalf("failed to apply %q: %v", tt.expression, err)
+		}
+		got, err := Format(result)
+		if err != nil {
+			t.Fatalf("failed to format the result of %q: %v", tt.expression, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	if _, err := Compile("files[?"); err == nil || !strings.Contains(err.Error(), "invalid query") {
+		t.Fatalf("expected an invalid query error, got %v", err)
+	}
+}
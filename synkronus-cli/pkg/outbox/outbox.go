@@ -0,0 +1,162 @@
+// Package outbox keeps sync push payloads on disk while the server is unreachable so they can be
+// sent later, the way the mobile app holds observations captured offline.
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a queued sync push. The transmission ID is kept so a payload that reached the server
+// before the connection dropped is not applied twice when it is sent again.
+type Entry struct {
+	TransmissionID string                   `json:"transmission_id"`
+	ClientID       string                   `json:"client_id"`
+	Server         string                   `json:"server"`
+	Records        []map[string]interface{} `json:"records"`
+	QueuedAt       time.Time                `json:"queued_at"`
+	Attempts       int                      `json:"attempts"`
+	LastError      string                   `json:"last_error,omitempty"`
+}
+
+// Outbox is a directory holding one JSON file per queued push
+type Outbox struct {
+	dir string
+}
+
+// New returns the outbox kept in dir. The directory is created on the first Add.
+func New(dir string) *Outbox {
+	return &Outbox{dir: dir}
+}
+
+// Dir returns the directory of the outbox
+func (o *Outbox) Dir() string {
+	return o.dir
+}
+
+// path returns the file of an entry; names sort in queue order
+func (o *Outbox) path(entry Entry) string {
+	return filepath.Join(o.dir, fmt.Sprintf("%020d-%s.json", entry.QueuedAt.UnixNano(), entry.TransmissionID))
+}
+
+// Add queues a push, setting QueuedAt when it is empty
+func (o *Outbox) Add(entry Entry) (Entry, error) {
+	if entry.TransmissionID == "" || strings.ContainsAny(entry.TransmissionID, `/\`) {
+		return entry, fmt.Errorf("invalid transmission ID %q", entry.TransmissionID)
+	}
+	if entry.QueuedAt.IsZero() {
+		entry.QueuedAt = time.Now().UTC()
+	}
+	if err := os.MkdirAll(o.dir, 0700); err != nil {
+		return entry, fmt.Errorf("error creating outbox: %w", err)
+	}
+	return entry, o.write(entry)
+}
+
+// Update replaces a queued entry, e.g. to record a failed attempt
+func (o *Outbox) Update(entry Entry) error {
+	if _, err := os.Stat(o.path(entry)); err != nil {
+		return fmt.Errorf("push %s is not queued: %w", entry.TransmissionID, err)
+	}
+	return o.write(entry)
+}
+
+// write stores an entry through a temporary file so a crash never leaves half a payload
+func (o *Outbox) write(entry Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding queued push: %w", err)
+	}
+	target := o.path(entry)
+	if err := os.WriteFile(target+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("error writing queued push: %w", err)
+	}
+	if err := os.Rename(target+".tmp", target); err != nil {
+		os.Remove(target + ".tmp")
+		return fmt.Errorf("error writing queued push: %w", err)
+	}
+	return nil
+}
+
+// List returns the queued pushes, oldest first
+func (o *Outbox) List() ([]Entry, error) {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading outbox: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	entries := make([]Entry, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(o.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("error reading queued push: %w", err)
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("error parsing queued push %s: %w", name, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Remove deletes a queued push once it has been sent
+func (o *Outbox) Remove(entry Entry) error {
+	if err := os.Remove(o.path(entry)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing queued push: %w", err)
+	}
+	return nil
+}
+
+// Backoff retries an operation with exponentially growing pauses between attempts
+type Backoff struct {
+	// Attempts is the total number of tries, including the first
+	Attempts int
+	// Initial is the pause after the first failure; each later pause doubles up to Max
+	Initial time.Duration
+	Max     time.Duration
+	// Sleep pauses between attempts; time.Sleep when nil
+	Sleep func(time.Duration)
+}
+
+// DefaultBackoff tries three times, pausing one and then two seconds
+func DefaultBackoff() Backoff {
+	return Backoff{Attempts: 3, Initial: time.Second, Max: 30 * time.Second}
+}
+
+// Retry calls fn until it succeeds, it fails with an error retryable rejects, or the attempts
+// run out. It returns the last error.
+func (b Backoff) Retry(fn func() error, retryable func(error) bool) error {
+	sleep := b.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	delay := b.Initial
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !retryable(err) || attempt >= b.Attempts {
+			return err
+		}
+		sleep(delay)
+		if delay *= 2; b.Max > 0 && delay > b.Max {
+			delay = b.Max
+		}
+	}
+}
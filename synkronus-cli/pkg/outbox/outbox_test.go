@@ -0,0 +1,111 @@
+package outbox
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	box := New(filepath.Join(t.TempDir(), "outbox"))
+
+	entries, err := box.List()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("List() on a missing outbox = %v, %v", entries, err)
+	}
+
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	for i, id := range []string{"tx-b", "tx-a", "tx-c"} {
+		_, err := box.Add(Entry{
+			TransmissionID: id,
+			ClientID:       "loader",
+			Server:         "http://localhost:8080",
+			Records:        []map[string]interface{}{{"observation_id": id}},
+			QueuedAt:       start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err = box.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.TransmissionID)
+	}
+	if want := []string{"tx-b", "tx-a", "tx-c"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("queue order = %v, want %v", ids, want)
+	}
+	if entries[0].Records[0]["observation_id"] != "tx-b" {
+		t.Errorf("records not kept: %v", entries[0].Records)
+	}
+
+	entries[1].Attempts = 4
+	entries[1].LastError = "connection refused"
+	if err := box.Update(entries[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Remove(entries[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err = box.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].TransmissionID != "tx-a" || entries[0].Attempts != 4 || entries[0].LastError != "connection refused" {
+		t.Fatalf("after update and remove = %+v", entries)
+	}
+
+	if err := box.Update(Entry{TransmissionID: "tx-missing", QueuedAt: start}); err == nil {
+		t.Error("Update() of an entry that is not queued should fail")
+	}
+	if _, err := box.Add(Entry{TransmissionID: "../escape"}); err == nil {
+		t.Error("Add() should reject transmission IDs with path separators")
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	errUnreachable := errors.New("unreachable")
+	errRejected := errors.New("rejected")
+	retryable := func(err error) bool { return errors.Is(err, errUnreachable) }
+
+	var pauses []time.Duration
+	backoff := Backoff{Attempts: 5, Initial: time.Second, Max: 3 * time.Second, Sleep: func(d time.Duration) {
+		pauses = append(pauses, d)
+	}}
+
+	// Gives up after the last attempt, doubling pauses up to the maximum
+	calls := 0
+	err := backoff.Retry(func() error { calls++; return errUnreachable }, retryable)
+	if !errors.Is(err, errUnreachable) || calls != 5 {
+		t.Fatalf("Retry() = %v after %d calls", err, calls)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}; !reflect.DeepEqual(pauses, want) {
+		t.Errorf("pauses = %v, want %v", pauses, want)
+	}
+
+	// Stops as soon as it succeeds
+	calls = 0
+	err = backoff.Retry(func() error {
+		if calls++; calls < 3 {
+			return errUnreachable
+		}
+		return nil
+	}, retryable)
+	if err != nil || calls != 3 {
+		t.Errorf("Retry() = %v after %d calls, want success after 3", err, calls)
+	}
+
+	// Does not retry errors that would fail again
+	calls = 0
+	err = backoff.Retry(func() error { calls++; return errRejected }, retryable)
+	if !errors.Is(err, errRejected) || calls != 1 {
+		t.Errorf("Retry() = %v after %d calls, want one call", err, calls)
+	}
+}
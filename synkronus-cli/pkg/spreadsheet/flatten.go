@@ -0,0 +1,94 @@
+// Package spreadsheet turns pulled observations into a flat table and writes it as an XLSX
+// workbook, for looking at small datasets without the server export API.
+package spreadsheet
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// metadataColumns are the observation fields placed before the form data, in this order
+var metadataColumns = []string{"observation_id", "form_type", "form_version", "created_at", "updated_at"}
+
+// Table is a header row and the data rows below it. Cells are strings, float64 or bool as
+// decoded from JSON, or nil for empty cells.
+type Table struct {
+	Header []string
+	Rows   [][]any
+}
+
+// Flatten builds a table with one row per observation. Nested objects in the form data and the
+// geolocation become dotted columns such as "household.size" and "geolocation.latitude", and
+// arrays are kept as JSON text in a single cell.
+func Flatten(records []map[string]any) Table {
+	flat := make([]map[string]any, len(records))
+	geoColumns := make(map[string]bool)
+	dataColumns := make(map[string]bool)
+	for i, record := range records {
+		row := make(map[string]any)
+		for _, column := range metadataColumns {
+			row[column] = record[column]
+		}
+		if geo, ok := record["geolocation"].(map[string]any); ok {
+			flattenInto(row, "geolocation.", geo, geoColumns)
+		}
+		if data, ok := record["data"].(map[string]any); ok {
+			fields := flattenInto(make(map[string]any), "", data, nil)
+			for key, value := range fields {
+				// Keep form fields that share a name with an observation field apart
+				if _, taken := row[key]; taken {
+					key = "data." + key
+				}
+				row[key] = value
+				dataColumns[key] = true
+			}
+		}
+		flat[i] = row
+	}
+
+	header := append([]string{}, metadataColumns...)
+	header = append(header, sortedKeys(geoColumns)...)
+	header = append(header, sortedKeys(dataColumns)...)
+
+	table := Table{Header: header, Rows: make([][]any, len(flat))}
+	for i, row := range flat {
+		cells := make([]any, len(header))
+		for j, column := range header {
+			cells[j] = row[column]
+		}
+		table.Rows[i] = cells
+	}
+	return table
+}
+
+// flattenInto copies value into row under dotted keys starting with prefix, recording the keys
+// in columns when it is not nil, and returns row
+func flattenInto(row map[string]any, prefix string, value map[string]any, columns map[string]bool) map[string]any {
+	for key, v := range value {
+		key = prefix + key
+		switch v := v.(type) {
+		case map[string]any:
+			flattenInto(row, key+".", v, columns)
+			continue
+		case []any:
+			encoded, _ := json.Marshal(v)
+			row[key] = string(encoded)
+		default:
+			row[key] = v
+		}
+		if columns != nil {
+			columns[key] = true
+		}
+	}
+	return row
+}
+
+// sortedKeys returns the keys of set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const recordsJSON = `[
+  {
+    "observation_id": "obs-1",
+    "form_type": "survey",
+    "form_version": "1.0",
+    "created_at": "2025-05-01T10:00:00Z",
+    "updated_at": "2025-05-01T10:00:00Z",
+    "geolocation": {"latitude": -1.28, "longitude": 36.82},
+    "data": {"name": "Amina", "household": {"size": 4, "water": true}, "symptoms": ["fever", "cough"]}
+  },
+  {
+    "observation_id": "obs-2",
+    "form_type": "survey",
+    "form_version": "1.1",
+    "created_at": "2025-05-02T10:00:00Z",
+    "updated_at": "2025-05-03T10:00:00Z",
+    "data": {"name": "Juma <Jr> & co", "form_type": "household"}
+  }
+]`
+
+func decode(t *testing.T) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	if err := json.Unmarshal([]byte(recordsJSON), &records); err != nil {
+		t.Fatalf("failed to decode records: %v", err)
+	}
+	return records
+}
+
+// xmlWellFormed parses content to the end
+func xmlWellFormed(content string) error {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	table := Flatten(decode(t))
+
+	wantHeader := []string{
+		"observation_id", "form_type", "form_version", "created_at", "updated_at",
+		"geolocation.latitude", "geolocation.longitude",
+		"data.form_type", "household.size", "household.water", "name", "symptoms",
+	}
+	if !reflect.DeepEqual(table.Header, wantHeader) {
+		t.Fatalf("unexpected header:\n%v\nwant:\n%v", table.Header, wantHeader)
+	}
+
+	wantRows := [][]any{
+		{"obs-1", "survey", "1.0", "2025-05-01T10:00:00Z", "2025-05-01T10:00:00Z",
+			-1.28, 36.82, nil, 4.0, true, "Amina", `["fever","cough"]`},
+		{"obs-2", "survey", "1.1", "2025-05-02T10:00:00Z", "2025-05-03T10:00:00Z",
+			nil, nil, "household", nil, nil, "Juma <Jr> & co", nil},
+	}
+	if !reflect.DeepEqual(table.Rows, wantRows) {
+		t.Fatalf("unexpected rows:\n%v\nwant:\n%v", table.Rows, wantRows)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, "survey", Flatten(decode(t))); err != nil {
+		t.Fatalf("failed to write workbook: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("workbook is missing %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">observation_id</t></is></c>`,
+		`<c r="F2"><v>-1.28</v></c>`,
+		`<c r="J2" t="b"><v>1</v></c>`,
+		`<c r="K3" t="inlineStr"><is><t xml:space="preserve">Juma &lt;Jr&gt; &amp; co</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Fatalf("sheet is missing %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="F3"`) {
+		t.Fatalf("empty cells should be left out:\n%s", sheet)
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="survey"`) {
+		t.Fatalf("unexpected workbook:\n%s", parts["xl/workbook.xml"])
+	}
+
+	// The XML parts must be well formed
+	for name, content := range parts {
+		if err := xmlWellFormed(content); err != nil {
+			t.Fatalf("%s is not well formed: %v", name, err)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Fatalf("columnName(%d) = %s, want %s", index, got, want)
+		}
+	}
+	if got := SheetName("visits/2025: [draft]"); got != "visits_2025_ _draft_" {
+		t.Fatalf("unexpected sheet name %q", got)
+	}
+	if got := SheetName(strings.Repeat("x", 40)); len(got) != 31 {
+		t.Fatalf("sheet name should be cut to 31 characters, got %d", len(got))
+	}
+}
@@ -212,8 +212,8 @@ var builtInRenderers = []string{
 	"image", "signature", "audio", "video", "file", "qrcode",
 }
 
-// isBuiltInRenderer checks if a renderer type is a built-in renderer
-func isBuiltInRenderer(rendererType string) bool {
+// IsBuiltInRenderer reports whether a renderer type is provided by the formplayer rather than a bundle
+func IsBuiltInRenderer(rendererType string) bool {
 	for _, builtIn := range builtInRenderers {
 		if builtIn == rendererType {
 			return true
@@ -228,17 +228,17 @@ func checkRendererReferences(data interface{}, availableRenderers map[string]boo
 	case map[string]interface{}:
 		// Check for renderer type (both x-renderer and rendererType formats)
 		if rendererType, ok := v["x-renderer"].(string); ok {
-			if !availableRenderers[rendererType] && !isBuiltInRenderer(rendererType) {
+			if !availableRenderers[rendererType] && !IsBuiltInRenderer(rendererType) {
 				return fmt.Errorf("references non-existent renderer '%s' (x-renderer)", rendererType)
 			}
 		}
 		if rendererType, ok := v["rendererType"].(string); ok {
-			if !availableRenderers[rendererType] && !isBuiltInRenderer(rendererType) {
+			if !availableRenderers[rendererType] && !IsBuiltInRenderer(rendererType) {
 				return fmt.Errorf("references non-existent renderer '%s' (rendererType)", rendererType)
 			}
 		}
 		if rendererType, ok := v["x-question-type"].(string); ok {
-			if !availableRenderers[rendererType] && !isBuiltInRenderer(rendererType) {
+			if !availableRenderers[rendererType] && !IsBuiltInRenderer(rendererType) {
 				return fmt.Errorf("references non-existent renderer '%s' (x-question-type)", rendererType)
 			}
 		}
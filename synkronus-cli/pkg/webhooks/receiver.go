@@ -0,0 +1,111 @@
+// Package webhooks receives and verifies webhook deliveries from a Synkronus server
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Headers set on every delivery
+const (
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the body keyed with the
+	// subscription secret
+	SignatureHeader = "X-Synkronus-Signature"
+	// EventHeader names the event, e.g. observation.pushed
+	EventHeader = "X-Synkronus-Event"
+	// DeliveryHeader carries the delivery ID, which stays the same across retries
+	DeliveryHeader = "X-Synkronus-Delivery"
+)
+
+// maxBodySize limits the deliveries the receiver reads
+const maxBodySize = 10 << 20
+
+// SignatureStatus is the outcome of checking a delivery's signature
+type SignatureStatus string
+
+const (
+	// SignatureValid means the signature matches the secret
+	SignatureValid SignatureStatus = "valid"
+	// SignatureInvalid means the signature is missing or does not match the secret
+	SignatureInvalid SignatureStatus = "invalid"
+	// SignatureUnchecked means no secret was given to check against
+	SignatureUnchecked SignatureStatus = "unchecked"
+)
+
+// Delivery is a received webhook request
+type Delivery struct {
+	ID         string
+	Event      string
+	Signature  SignatureStatus
+	ReceivedAt time.Time
+	Body       []byte
+}
+
+// Sign returns the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether header is the signature of body under secret
+func VerifySignature(secret string, body []byte, header string) bool {
+	if !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(header), []byte(Sign(secret, body)))
+}
+
+// Receiver is an http.Handler accepting deliveries. Like a production receiver it rejects
+// deliveries with a bad signature with 401 when a secret is set; others are answered with Status,
+// which can be set to an error status to exercise the server's retries.
+type Receiver struct {
+	Secret     string
+	Status     int
+	OnDelivery func(Delivery)
+}
+
+// ServeHTTP reads, verifies and reports a delivery
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	delivery := Delivery{
+		ID:         r.Header.Get(DeliveryHeader),
+		Event:      r.Header.Get(EventHeader),
+		Signature:  SignatureUnchecked,
+		ReceivedAt: time.Now(),
+		Body:       body,
+	}
+	if rc.Secret != "" {
+		delivery.Signature = SignatureInvalid
+		if VerifySignature(rc.Secret, body, r.Header.Get(SignatureHeader)) {
+			delivery.Signature = SignatureValid
+		}
+	}
+	if rc.OnDelivery != nil {
+		rc.OnDelivery(delivery)
+	}
+
+	if delivery.Signature == SignatureInvalid {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	status := rc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
@@ -0,0 +1,48 @@
+package webhooks
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func post(rc *Receiver, body []byte, signature string) int {
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set(EventHeader, "observation.pushed")
+	r.Header.Set(DeliveryHeader, "42")
+	if signature != "" {
+		r.Header.Set(SignatureHeader, signature)
+	}
+	w := httptest.NewRecorder()
+	rc.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestReceiver(t *testing.T) {
+	body := []byte(`{"event":"observation.pushed"}`)
+	var got []Delivery
+	rc := &Receiver{Secret: "s3cret", OnDelivery: func(d Delivery) { got = append(got, d) }}
+
+	if code := post(rc, body, Sign("s3cret", body)); code != http.StatusOK {
+		t.Fatalf("expected 200 for a signed delivery, got %d", code)
+	}
+	if code := post(rc, body, Sign("other", body)); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad signature, got %d", code)
+	}
+	if code := post(rc, body, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a missing signature, got %d", code)
+	}
+	if len(got) != 3 || got[0].Signature != SignatureValid || got[1].Signature != SignatureInvalid || got[0].ID != "42" {
+		t.Fatalf("unexpected deliveries: %+v", got)
+	}
+
+	// Without a secret signatures are not checked and the configured status is returned
+	rc = &Receiver{Status: http.StatusServiceUnavailable, OnDelivery: func(d Delivery) { got = append(got, d) }}
+	if code := post(rc, body, ""); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected configured status 503, got %d", code)
+	}
+	if got[3].Signature != SignatureUnchecked || got[3].Event != "observation.pushed" {
+		t.Fatalf("unexpected delivery: %+v", got[3])
+	}
+}
@@ -15,7 +15,7 @@ $ldflags = "-X 'github.com/opendataensemble/synkronus/pkg/version.version=$versi
            "-X 'github.com/opendataensemble/synkronus/pkg/version.commit=$commit' " +
            "-X 'github.com/opendataensemble/synkronus/pkg/version.buildTime=$buildTime'"
 
-go build -ldflags="$ldflags" -o bin/synkronus.exe cmd/synkronus/main.go
+go build -tags="$env:TAGS" -ldflags="$ldflags" -o bin/synkronus.exe cmd/synkronus/main.go
 
 if ($LASTEXITCODE -eq 0) {
     Write-Host "Build successful! Output: bin/synkronus.exe" -ForegroundColor Green
@@ -0,0 +1,40 @@
+#!/usr/bin/env bash
+# Builds the synkronus server with version metadata embedded.
+#
+# Usage:
+#   ./build.sh                          # build for the host platform
+#   ./build.sh linux/amd64 linux/arm64  # cross-compile, e.g. arm64 for Raspberry Pi edge servers
+#   TAGS=dev ./build.sh                 # dev build with fault injection endpoints, never for production
+#
+# Binaries are written to bin/, named synkronus-<os>-<arch> when platforms are given.
+set -euo pipefail
+
+cd "$(dirname "$0")"
+
+VERSION="${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}"
+COMMIT="${COMMIT:-$(git rev-parse HEAD 2>/dev/null || echo unknown)}"
+BUILD_TIME="${BUILD_TIME:-$(date -u +%Y-%m-%dT%H:%M:%SZ)}"
+
+PKG="github.com/opendataensemble/synkronus/pkg/version"
+TAGS="${TAGS:-}"
+LDFLAGS="-s -w -X '${PKG}.version=${VERSION}' -X '${PKG}.commit=${COMMIT}' -X '${PKG}.buildTime=${BUILD_TIME}'"
+
+mkdir -p bin
+
+if [ "$#" -eq 0 ]; then
+    CGO_ENABLED=0 go build -trimpath -tags="${TAGS}" -ldflags="${LDFLAGS}" -o bin/synkronus ./cmd/synkronus
+    echo "Build successful! Output: bin/synkronus (${VERSION})"
+    exit 0
+fi
+
+for platform in "$@"; do
+    goos="${platform%/*}"
+    goarch="${platform#*/}"
+    ext=""
+    if [ "${goos}" = "windows" ]; then ext=".exe"; fi
+    output="bin/synkronus-${goos}-${goarch}${ext}"
+
+    CGO_ENABLED=0 GOOS="${goos}" GOARCH="${goarch}" \
+        go build -trimpath -tags="${TAGS}" -ldflags="${LDFLAGS}" -o "${output}" ./cmd/synkronus
+    echo "Build successful! Output: ${output} (${VERSION})"
+done
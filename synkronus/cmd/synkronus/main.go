@@ -1,32 +1,75 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/opendataensemble/synkronus/internal/api"
 	"github.com/opendataensemble/synkronus/internal/handlers"
 	"github.com/opendataensemble/synkronus/internal/repository"
+	"github.com/opendataensemble/synkronus/pkg/apikey"
 	"github.com/opendataensemble/synkronus/pkg/appbundle"
 	"github.com/opendataensemble/synkronus/pkg/attachment"
+	"github.com/opendataensemble/synkronus/pkg/audit"
 	"github.com/opendataensemble/synkronus/pkg/auth"
+	"github.com/opendataensemble/synkronus/pkg/backup"
+	"github.com/opendataensemble/synkronus/pkg/bundlechannel"
+	"github.com/opendataensemble/synkronus/pkg/bundleupload"
+	"github.com/opendataensemble/synkronus/pkg/canary"
+	"github.com/opendataensemble/synkronus/pkg/codelist"
 	"github.com/opendataensemble/synkronus/pkg/config"
 	"github.com/opendataensemble/synkronus/pkg/database"
 	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/opendataensemble/synkronus/pkg/demo"
+	"github.com/opendataensemble/synkronus/pkg/device"
+	"github.com/opendataensemble/synkronus/pkg/document"
+	"github.com/opendataensemble/synkronus/pkg/exporttemplate"
+	"github.com/opendataensemble/synkronus/pkg/federation"
+	"github.com/opendataensemble/synkronus/pkg/formcomponent"
+	"github.com/opendataensemble/synkronus/pkg/health"
+	"github.com/opendataensemble/synkronus/pkg/idempotency"
+	"github.com/opendataensemble/synkronus/pkg/importsource"
+	"github.com/opendataensemble/synkronus/pkg/invite"
+	"github.com/opendataensemble/synkronus/pkg/latency"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
 	"github.com/opendataensemble/synkronus/pkg/migrations"
+	"github.com/opendataensemble/synkronus/pkg/objectstore"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+	"github.com/opendataensemble/synkronus/pkg/outbound"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+	"github.com/opendataensemble/synkronus/pkg/replication"
+	"github.com/opendataensemble/synkronus/pkg/report"
+	"github.com/opendataensemble/synkronus/pkg/sampling"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+	"github.com/opendataensemble/synkronus/pkg/seed"
+	"github.com/opendataensemble/synkronus/pkg/settings"
 	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+	"github.com/opendataensemble/synkronus/pkg/terms"
+	"github.com/opendataensemble/synkronus/pkg/tlsserver"
 	"github.com/opendataensemble/synkronus/pkg/user"
 	"github.com/opendataensemble/synkronus/pkg/version"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
 )
 
+// appBundleSwitchChannel is the redis channel on which servers announce app bundle switches
+const appBundleSwitchChannel = "synkronus:app-bundle-switch"
+
 func redactPassword(dsn string) string {
 	u, err := url.Parse(dsn)
 	if err != nil {
@@ -41,6 +84,289 @@ func redactPassword(dsn string) string {
 
 	return u.String()
 }
+
+// activeAppInfo returns the APP_INFO of the active app bundle version, or nil when no version
+// is active
+func activeAppInfo(ctx context.Context, bundle appbundle.AppBundleServiceInterface) (*appbundle.AppInfo, error) {
+	versions, err := bundle.GetVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The active version is marked with an asterisk
+	for _, v := range versions {
+		if strings.HasSuffix(v, " *") {
+			return bundle.GetAppInfo(ctx, strings.TrimSuffix(v, " *"))
+		}
+	}
+	return nil, nil
+}
+
+// fieldAssignmentsFromAppBundle reads the server-assigned fields declared in the form schemas
+// of the active app bundle version
+func fieldAssignmentsFromAppBundle(bundle appbundle.AppBundleServiceInterface) sync.FieldAssignmentSource {
+	return sync.FieldAssignmentSourceFunc(func(ctx context.Context) (map[string][]sync.FieldAssignment, error) {
+		appInfo, err := activeAppInfo(ctx, bundle)
+		if err != nil || appInfo == nil {
+			return nil, err
+		}
+
+		assignments := make(map[string][]sync.FieldAssignment)
+		for formType, form := range appInfo.Forms {
+			for _, field := range form.Fields {
+				if field.ServerAssigned == "" {
+					continue
+				}
+				assignments[formType] = append(assignments[formType], sync.FieldAssignment{
+					Field:   field.Name,
+					Kind:    sync.FieldAssignmentKind(field.ServerAssigned),
+					Prefix:  field.SequencePrefix,
+					Padding: field.SequencePadding,
+				})
+			}
+		}
+		return assignments, nil
+	})
+}
+
+// appBundleCodeLists checks pushed values against the code lists that fields of the active app
+// bundle version reference with x-code-list
+type appBundleCodeLists struct {
+	bundle    appbundle.AppBundleServiceInterface
+	codeLists codelist.Service
+}
+
+// CodedFields implements sync.CodeListSource
+func (a appBundleCodeLists) CodedFields(ctx context.Context) (map[string][]sync.CodedField, error) {
+	appInfo, err := activeAppInfo(ctx, a.bundle)
+	if err != nil || appInfo == nil {
+		return nil, err
+	}
+
+	fields := make(map[string][]sync.CodedField)
+	for formType, form := range appInfo.Forms {
+		for _, field := range form.Fields {
+			if field.CodeList != "" {
+				fields[formType] = append(fields[formType], sync.CodedField{Field: field.Name, List: field.CodeList})
+			}
+		}
+	}
+	return fields, nil
+}
+
+// Codes implements sync.CodeListSource
+func (a appBundleCodeLists) Codes(ctx context.Context, list string) ([]string, error) {
+	codes, err := a.codeLists.Codes(ctx, list)
+	if errors.Is(err, codelist.ErrNotFound) {
+		return nil, nil
+	}
+	return codes, err
+}
+
+// federationConfigFrom builds the upstream federation settings of an edge server
+func federationConfigFrom(cfg *config.Config) federation.Config {
+	clientID := cfg.FederationClientID
+	if clientID == "" {
+		clientID, _ = os.Hostname()
+	}
+
+	return federation.Config{
+		UpstreamURL: cfg.FederationUpstreamURL,
+		Username:    cfg.FederationUsername,
+		Password:    cfg.FederationPassword,
+		ClientID:    clientID,
+		Interval:    time.Duration(cfg.FederationIntervalSeconds) * time.Second,
+		IDBlockSize: cfg.FederationIDBlockSize,
+		IDSequences: splitList(cfg.FederationIDSequences),
+	}
+}
+
+// auditConfigFrom builds the security alert rules and notifiers from the configuration
+func auditConfigFrom(cfg *config.Config) audit.Config {
+	auditConfig := audit.DefaultConfig()
+	auditConfig.Rules = splitList(cfg.AlertRules)
+	auditConfig.FailedLoginThreshold = cfg.AlertFailedLoginThreshold
+	if cfg.AlertFailedLoginWindowMinutes > 0 {
+		auditConfig.FailedLoginWindow = time.Duration(cfg.AlertFailedLoginWindowMinutes) * time.Minute
+	}
+
+	if cfg.AlertWebhookURL != "" {
+		auditConfig.Notifiers = append(auditConfig.Notifiers, audit.NewWebhookNotifier(cfg.AlertWebhookURL))
+	}
+	if recipients := splitList(cfg.AlertEmailTo); len(recipients) > 0 && cfg.SMTPHost != "" {
+		auditConfig.Notifiers = append(auditConfig.Notifiers, audit.NewEmailNotifier(audit.EmailConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			To:       recipients,
+		}))
+	}
+	return auditConfig
+}
+
+// inviteConfigFrom builds the invitation settings; invitations are only emailed when an
+// SMTP server is configured
+func inviteConfigFrom(cfg *config.Config) invite.Config {
+	inviteConfig := invite.Config{
+		Lifetime:  time.Duration(cfg.InviteExpiryHours) * time.Hour,
+		AcceptURL: cfg.InviteURL,
+	}
+	if cfg.SMTPHost != "" && cfg.SMTPFrom != "" {
+		inviteConfig.Mailer = invite.NewSMTPMailer(invite.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	}
+	return inviteConfig
+}
+
+// reportConfigFrom builds the report delivery settings; reports are only emailed when an SMTP
+// server is configured
+func reportConfigFrom(cfg *config.Config) report.Config {
+	reportConfig := report.Config{Outbound: outboundPolicyFrom(cfg)}
+	if cfg.SMTPHost != "" && cfg.SMTPFrom != "" {
+		reportConfig.Mailer = report.NewSMTPMailer(report.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	}
+	return reportConfig
+}
+
+// outboundPolicyFrom builds the destinations requests to admin-supplied URLs may reach
+func outboundPolicyFrom(cfg *config.Config) outbound.Policy {
+	return outbound.Policy{
+		Allow: splitList(cfg.OutboundAllowlist),
+		Deny:  splitList(cfg.OutboundDenylist),
+	}
+}
+
+// appBundleStorageFrom builds the storage of app bundle versions; nil keeps them on local disk
+func appBundleStorageFrom(cfg *config.Config) (appbundle.Storage, error) {
+	switch cfg.AppBundleStorage {
+	case "", "local":
+		return nil, nil
+	case "s3":
+		client, err := objectstore.NewClient(objectstore.Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3PathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return appbundle.NewS3Storage(client, cfg.AppBundleS3Prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown APP_BUNDLE_STORAGE %q, expected local or s3", cfg.AppBundleStorage)
+	}
+}
+
+// replicationTargetFrom builds the secondary location configured by REPLICATION_TARGET; nil
+// disables replication. The bucket's endpoint, region and credentials default to those of the
+// primary bucket.
+func replicationTargetFrom(cfg *config.Config) (replication.Target, error) {
+	switch cfg.ReplicationTarget {
+	case "", "off":
+		return nil, nil
+	case "dir":
+		if cfg.ReplicationDir == "" {
+			return nil, errors.New("REPLICATION_TARGET=dir needs REPLICATION_DIR")
+		}
+		if err := os.MkdirAll(cfg.ReplicationDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create replication directory: %w", err)
+		}
+		return replication.NewDirTarget(cfg.ReplicationDir), nil
+	case "s3":
+		s3 := objectstore.Config{
+			Endpoint:        cmp.Or(cfg.ReplicationS3Endpoint, cfg.S3Endpoint),
+			Region:          cmp.Or(cfg.ReplicationS3Region, cfg.S3Region),
+			Bucket:          cfg.ReplicationS3Bucket,
+			AccessKeyID:     cmp.Or(cfg.ReplicationS3AccessKeyID, cfg.S3AccessKeyID),
+			SecretAccessKey: cmp.Or(cfg.ReplicationS3SecretKey, cfg.S3SecretAccessKey),
+			PathStyle:       cfg.S3PathStyle,
+		}
+		if s3.Bucket != "" && s3.Bucket == cfg.S3Bucket && s3.Endpoint == cfg.S3Endpoint {
+			return nil, errors.New("REPLICATION_S3_BUCKET must not be the primary S3_BUCKET")
+		}
+		client, err := objectstore.NewClient(s3)
+		if err != nil {
+			return nil, err
+		}
+		return replication.NewS3Target(client, s3.Bucket, cfg.ReplicationS3Prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown REPLICATION_TARGET %q, expected off, dir or s3", cfg.ReplicationTarget)
+	}
+}
+
+// tenantBundleStorages lists the app bundle storage of the default tenant and, with
+// multi-tenancy, of every tenant that has users
+type tenantBundleStorages struct {
+	config       appbundle.Config
+	db           *sql.DB
+	multiTenancy bool
+}
+
+// BundleStorages implements replication.BundleSource
+func (t tenantBundleStorages) BundleStorages(ctx context.Context) (map[string]appbundle.Storage, error) {
+	storages := map[string]appbundle.Storage{tenant.Default: t.config.VersionStorage()}
+	if !t.multiTenancy {
+		return storages, nil
+	}
+
+	rows, err := t.db.QueryContext(ctx, "SELECT DISTINCT tenant_id FROM users WHERE tenant_id <> $1", tenant.Default)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		config, err := t.config.ForTenant(id)
+		if err != nil {
+			return nil, err
+		}
+		storages[id] = config.VersionStorage()
+	}
+	return storages, rows.Err()
+}
+
+// splitList splits a comma separated setting, dropping blank entries
+// idempotencyStoreFrom returns the store recognizing retried sync pushes: redis when servers share
+// one, the database otherwise, and nil when disabled
+func idempotencyStoreFrom(cfg *config.Config, shared *redis.Client, db *sql.DB) idempotency.Store {
+	if cfg.SyncPushIdempotencyHours <= 0 {
+		return nil
+	}
+	retention := time.Duration(cfg.SyncPushIdempotencyHours) * time.Hour
+	if shared != nil {
+		return idempotency.NewRedisStore(shared, retention)
+	}
+	return idempotency.NewPostgresStore(db, retention)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func main() {
 	// Temporary logger for configuration loading
 	preLog := logger.NewLogger(
@@ -75,7 +401,7 @@ func main() {
 		logger.WithPrettyPrint(true),
 	)
 
-	log.Info("Starting Synkronus API server", "version", "1.0.23")
+	log.Info("Starting Synkronus API server", "version", version.Version())
 	log.Info("Configuration loaded from", "source", cfg.Source)
 	log.Debug("Configuration details", "port", cfg.Port, "logLevel", cfg.LogLevel, "appBundlePath", cfg.AppBundlePath)
 
@@ -84,8 +410,22 @@ func main() {
 	// Override database config from configuration
 	dbConfig.ConnectionString = cfg.DatabaseURL
 	dbConfig.MigrationsFS = migrations.GetFS()
+	dbConfig.TLS = database.TLSConfig{
+		Mode:           cfg.DatabaseSSLMode,
+		RootCertFile:   cfg.DatabaseSSLRootCert,
+		CertFile:       cfg.DatabaseSSLCert,
+		KeyFile:        cfg.DatabaseSSLKey,
+		ReloadInterval: time.Duration(cfg.DatabaseCertReloadSeconds) * time.Second,
+	}
+	dbConfig.MaxOpenConns = cfg.DatabaseMaxOpenConns
+	dbConfig.MaxIdleConns = cfg.DatabaseMaxIdleConns
+	dbConfig.ConnMaxLifetime = time.Duration(cfg.DatabaseConnMaxLifetimeMinutes) * time.Minute
+	dbConfig.ConnMaxIdleTime = time.Duration(cfg.DatabaseConnMaxIdleMinutes) * time.Minute
+	dbConfig.StatsInterval = time.Duration(cfg.DatabasePoolStatsSeconds) * time.Second
 
-	log.Info("Initializing database connection", "connection_string", redactPassword(cfg.DatabaseURL))
+	log.Info("Initializing database connection", "connection_string", redactPassword(cfg.DatabaseURL),
+		"maxOpenConns", dbConfig.MaxOpenConns, "maxIdleConns", dbConfig.MaxIdleConns,
+		"connMaxLifetime", dbConfig.ConnMaxLifetime, "connMaxIdleTime", dbConfig.ConnMaxIdleTime)
 	db, err := database.New(dbConfig, log)
 	if err != nil {
 		log.Error("Failed to initialize database", "error", err, "error_type", fmt.Sprintf("%T", err), "error_string", err.Error(), "connection_string", redactPassword(cfg.DatabaseURL))
@@ -94,6 +434,12 @@ func main() {
 	}
 	defer db.Close()
 
+	// Pick up rotated database client certificates without a restart, and log pool statistics
+	dbCtx, stopDB := context.WithCancel(context.Background())
+	defer stopDB()
+	go db.Run(dbCtx)
+	go db.LogStats(dbCtx)
+
 	// Run database migrations
 	log.Info("Starting database migrations...")
 	if err := db.Migrate(); err != nil {
@@ -103,6 +449,30 @@ func main() {
 	}
 	log.Info("Database migrations completed successfully")
 
+	// Sync pulls and exports read from a streaming replica while it is healthy and caught up
+	var pool database.PoolReporter = db
+	var readReplica *database.ReadReplica
+	if cfg.DatabaseReplicaURL != "" {
+		replicaConfig := dbConfig
+		replicaConfig.ConnectionString = cfg.DatabaseReplicaURL
+		replicaDB, err := database.New(replicaConfig, log)
+		if err != nil {
+			log.Error("Failed to connect to read replica", "error", err, "connection_string", redactPassword(cfg.DatabaseReplicaURL))
+			log.Info("Exiting due to read replica initialization error")
+			return
+		}
+		readReplica = database.NewReadReplica(db, replicaDB, database.ReplicaConfig{
+			CheckInterval: time.Duration(cfg.DatabaseReplicaCheckSeconds) * time.Second,
+			MaxLag:        time.Duration(cfg.DatabaseReplicaMaxLagSeconds) * time.Second,
+		}, log)
+		defer readReplica.Close()
+		go replicaDB.Run(dbCtx)
+		go readReplica.Run(dbCtx)
+		pool = readReplica
+		log.Info("Read replica enabled", "connection_string", redactPassword(cfg.DatabaseReplicaURL),
+			"maxLagSeconds", cfg.DatabaseReplicaMaxLagSeconds)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db, log)
 
@@ -110,6 +480,29 @@ func main() {
 	authConfig := auth.DefaultConfig()
 	// Override auth config from configuration
 	authConfig.JWTSecret = cfg.JWTSecret
+	authConfig.PasswordHashAlgorithm = cfg.PasswordHashAlgorithm
+	// Out of range costs keep the defaults
+	if cfg.PasswordArgon2MemoryKB > 0 {
+		authConfig.Argon2.Memory = uint32(cfg.PasswordArgon2MemoryKB)
+	}
+	if cfg.PasswordArgon2Iterations > 0 {
+		authConfig.Argon2.Iterations = uint32(cfg.PasswordArgon2Iterations)
+	}
+	if cfg.PasswordArgon2Parallelism > 0 && cfg.PasswordArgon2Parallelism <= 255 {
+		authConfig.Argon2.Parallelism = uint8(cfg.PasswordArgon2Parallelism)
+	}
+	if cfg.PasswordHashAlgorithm != auth.HashAlgorithmArgon2id && cfg.PasswordHashAlgorithm != auth.HashAlgorithmBcrypt {
+		log.Warn("Unknown password hash algorithm, using argon2id", "algorithm", cfg.PasswordHashAlgorithm)
+	}
+	if cfg.JWTSigningAlgorithm == auth.SigningHS256 || auth.IsAsymmetric(cfg.JWTSigningAlgorithm) {
+		authConfig.SigningAlgorithm = cfg.JWTSigningAlgorithm
+	} else {
+		log.Warn("Unknown JWT signing algorithm, using HS256", "algorithm", cfg.JWTSigningAlgorithm)
+	}
+	if cfg.JWTKeyRotationDays > 0 {
+		authConfig.KeyRotationInterval = time.Duration(cfg.JWTKeyRotationDays) * 24 * time.Hour
+	}
+	authConfig.PreviousJWTSecrets = splitList(cfg.JWTPreviousSecrets)
 
 	// These can still be overridden by environment variables for security
 	if adminUsername := os.Getenv("ADMIN_USERNAME"); adminUsername != "" {
@@ -119,7 +512,9 @@ func main() {
 		authConfig.AdminPassword = adminPassword
 	}
 
-	authService := auth.NewService(authConfig, userRepo, log)
+	authService := auth.NewService(authConfig, userRepo, log,
+		auth.WithSigningKeys(repository.NewSigningKeyRepository(db, log)),
+		auth.WithSessions(repository.NewSessionRepository(db, log)))
 
 	// Initialize the auth service and create admin user if needed
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -130,11 +525,58 @@ func main() {
 		return
 	}
 
+	// Share state between servers behind a load balancer when a redis server is configured
+	var shared *redis.Client
+	if cfg.RedisURL != "" {
+		shared, err = redis.New(cfg.RedisURL)
+		if err != nil {
+			log.Error("Invalid redis configuration", "error", err)
+			log.Info("Exiting due to redis configuration error")
+			return
+		}
+		if err := shared.Ping(ctx); err != nil {
+			log.Warn("Redis is unreachable; shared state is retried on use", "error", err)
+		}
+	}
+
 	// Initialize app bundle service
 	appBundleConfig := appbundle.DefaultConfig()
 	// Override app bundle config from configuration
 	appBundleConfig.BundlePath = cfg.AppBundlePath
 	appBundleConfig.MaxVersions = cfg.MaxVersionsKept
+	appBundleConfig.CDNBaseURL = cfg.AppBundleCDNURL
+	appBundleConfig.SyncInterval = time.Duration(cfg.AppBundleSyncSeconds) * time.Second
+	appBundleConfig.Limits = appbundle.Limits{
+		MaxSize:             int64(cfg.AppBundleMaxSizeMB) << 20,
+		MaxFiles:            cfg.AppBundleMaxFiles,
+		MaxUncompressedSize: int64(cfg.AppBundleMaxUncompressedMB) << 20,
+		MaxFileSize:         int64(cfg.AppBundleMaxFileSizeMB) << 20,
+	}
+	if shared != nil {
+		// Other servers load the switched version when told, not within the sync interval
+		appBundleConfig.OnSwitch = func(version string) {
+			publishCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := shared.Publish(publishCtx, appBundleSwitchChannel, version); err != nil {
+				log.Warn("Failed to announce app bundle switch", "version", version, "error", err)
+			}
+		}
+	}
+	appBundleConfig.SigningKeys, err = appbundle.ParseSigningKeys(cfg.AppBundleSigningKeys)
+	if err != nil {
+		log.Error("Invalid app bundle signing keys", "error", err)
+		log.Info("Exiting due to app bundle service initialization error")
+		return
+	}
+	appBundleConfig.Storage, err = appBundleStorageFrom(cfg)
+	if err != nil {
+		log.Error("Invalid app bundle storage configuration", "error", err)
+		log.Info("Exiting due to app bundle service initialization error")
+		return
+	}
+
+	formComponentService := formcomponent.NewService(db.DB(), log)
+	appBundleConfig.Includes = formComponentService
 
 	appBundleService := appbundle.NewService(appBundleConfig, log)
 
@@ -145,10 +587,48 @@ func main() {
 		return
 	}
 
+	// With multi-tenancy, every tenant other than the default one has its own app bundle
+	var bundles appbundle.AppBundleServiceInterface = appBundleService
+	if cfg.MultiTenancyEnabled {
+		bundles = appbundle.NewTenantService(appBundleService, appBundleConfig, log)
+		log.Info("Multi-tenancy enabled", "tenantHeader", cfg.TenantHeader)
+	}
+
 	// Initialize sync service
 	syncConfig := sync.DefaultConfig()
+	syncConfig.MaxClockSkew = time.Duration(cfg.SyncMaxClockSkewMinutes) * time.Minute
+	syncConfig.MinValidTimestamp = time.Date(cfg.SyncMinValidYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	syncConfig.TimestampPolicy = sync.TimestampPolicy(cfg.SyncTimestampPolicy)
+	syncConfig.ConflictPolicy = sync.ConflictPolicy(cfg.SyncConflictPolicy)
+	syncConfig.PullScope = sync.PullScope(cfg.SyncPullScope)
+	syncConfig.TombstoneRetention = time.Duration(cfg.SyncTombstoneRetentionDays) * 24 * time.Hour
+	syncConfig.HistoryRetention = time.Duration(cfg.SyncHistoryRetentionDays) * 24 * time.Hour
+	syncConfig.CompactionInterval = time.Duration(cfg.SyncCompactionIntervalHours) * time.Hour
+
+	// Pushed values of fields referencing code lists are checked against the latest list versions
+	codeListService := codelist.NewService(db.DB(), log)
+
+	// Observations pushed are queued for webhook subscriptions in the push transaction
+	webhookService := webhook.NewService(db.DB(), webhook.Config{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Outbound:    outboundPolicyFrom(cfg),
+	}, log)
+
+	syncOptions := []sync.Option{
+		sync.WithFieldAssignments(fieldAssignmentsFromAppBundle(bundles)),
+		sync.WithCodeLists(appBundleCodeLists{bundle: bundles, codeLists: codeListService}),
+		sync.WithPushListener(webhookService),
+	}
+	federationConfig := federationConfigFrom(cfg)
+	if federationConfig.Enabled() {
+		// ID ranges are carved from blocks reserved upstream so edge numbers never collide
+		syncOptions = append(syncOptions, sync.WithUpstreamIDRanges())
+	}
+	if readReplica != nil {
+		syncOptions = append(syncOptions, sync.WithReadReplica(readReplica))
+	}
 
-	syncService := sync.NewService(db.DB(), syncConfig, log)
+	syncService := sync.NewService(db.DB(), syncConfig, log, syncOptions...)
 
 	// Initialize the sync service
 	if err := syncService.Initialize(ctx); err != nil {
@@ -171,14 +651,199 @@ func main() {
 		return
 	}
 
+	// Move aging attachment content to cold storage and delete attachments past their retention
+	attachmentLifecycle, err := attachment.NewLifecycle(cfg, log)
+	if err != nil {
+		log.Error("Invalid attachment lifecycle configuration", "error", err)
+		log.Info("Exiting due to attachment lifecycle configuration error")
+		return
+	}
+
+	// Keep disaster-recovery copies of app bundle versions and attachments
+	var replicator *replication.Replicator
+	replicationTarget, err := replicationTargetFrom(cfg)
+	if err != nil {
+		log.Error("Invalid replication configuration", "error", err)
+		log.Info("Exiting due to replication configuration error")
+		return
+	}
+	if replicationTarget != nil {
+		replicator = replication.NewReplicator(replication.Config{
+			Bundles:        tenantBundleStorages{config: appBundleConfig, db: db.DB(), multiTenancy: cfg.MultiTenancyEnabled},
+			AttachmentsDir: filepath.Join(cfg.DataDir, "attachments"),
+			Target:         replicationTarget,
+			Interval:       time.Duration(cfg.ReplicationIntervalMinutes) * time.Minute,
+		}, log)
+		log.Info("Replication enabled", "target", replicationTarget.String())
+	}
+
 	// Initialize data export service
 	dataExportDB := dataexport.NewPostgresDB(db.DB())
+	if readReplica != nil {
+		dataExportDB = dataexport.NewPostgresReplicaDB(db.DB(), readReplica)
+	}
 	dataExportService := dataexport.NewService(dataExportDB, cfg)
 
+	// Analysts query flattened tables materialized into a schema, optionally in another database
+	var analyticsSink *dataexport.PostgresSink
+	if cfg.AnalyticsExportIntervalMinutes > 0 {
+		analyticsDB := db.DB()
+		if cfg.AnalyticsDatabaseURL != "" {
+			analyticsConfig := database.DefaultConfig()
+			analyticsConfig.ConnectionString = cfg.AnalyticsDatabaseURL
+			analyticsDatabase, err := database.New(analyticsConfig, log)
+			if err != nil {
+				log.Error("Failed to connect to analytics database", "error", err, "connection_string", redactPassword(cfg.AnalyticsDatabaseURL))
+				log.Info("Exiting due to analytics database initialization error")
+				return
+			}
+			defer analyticsDatabase.Close()
+			analyticsDB = analyticsDatabase.DB()
+		}
+		analyticsSink, err = dataexport.NewPostgresSink(analyticsDB, cfg.AnalyticsSchema)
+		if err != nil {
+			log.Error("Failed to initialize analytics export", "error", err)
+			log.Info("Exiting due to analytics export initialization error")
+			return
+		}
+	}
+
+	// Initialize supporting document service
+	documentConfig := document.DefaultConfig()
+	documentConfig.StoragePath = filepath.Join(cfg.DataDir, "documents")
+	documentConfig.AllowedTypes = strings.Split(cfg.DocumentAllowedTypes, ",")
+	documentConfig.MaxSize = int64(cfg.DocumentMaxSizeMB) << 20
+	documentService, err := document.NewService(db.DB(), documentConfig, log)
+	if err != nil {
+		log.Error("Failed to initialize document service", "error", err)
+		log.Info("Exiting due to document service initialization error")
+		return
+	}
+
+	// Track pull, push and export latencies in daily rollups
+	latencyService := latency.NewService(db.DB(), latency.Config{
+		SlowThreshold: time.Duration(cfg.SlowOperationThresholdMS) * time.Millisecond,
+	}, log)
+
+	// Saved queries aggregate observations for dashboards and the reports built from them
+	savedQueryService := savedquery.NewService(db.DB(), savedquery.Privacy{MinGroupSize: cfg.SavedQueryMinGroupSize, Noise: cfg.SavedQueryNoise}, log)
+	reportService := report.NewService(db.DB(), savedQueryService, reportConfigFrom(cfg), log)
+
+	// Import CSV files of historical observations through the sync push rules
+	dataImportService := dataimport.NewService(db.DB(), appBundleService, syncService, log)
+
+	// Pull ODK Central and KoboToolbox submissions into imports; credentials are encrypted with the JWT secrets
+	sourceSecrets := [][]byte{[]byte(authConfig.JWTSecret)}
+	for _, secret := range authConfig.PreviousJWTSecrets {
+		sourceSecrets = append(sourceSecrets, []byte(secret))
+	}
+	importSourceService := importsource.NewService(db.DB(), dataImportService, appBundleService, importsource.Config{
+		Secrets:  sourceSecrets,
+		Outbound: outboundPolicyFrom(cfg),
+	}, log)
+
+	settingsService := settings.NewService(db.DB(), log)
+	orgUnitService := orgunit.NewService(db.DB(), log)
+
+	// Apply seed files, e.g. of demo and CI environments; "synkronus seed <path>..." applies
+	// them and exits without serving
+	seedOnly := len(os.Args) > 1 && os.Args[1] == "seed"
+	seedFiles := splitList(cfg.SeedFiles)
+	if seedOnly {
+		seedFiles = append(seedFiles, os.Args[2:]...)
+	}
+	if len(seedFiles) > 0 {
+		seedCtx, cancelSeed := context.WithTimeout(context.Background(), 5*time.Minute)
+		seeds, err := seed.Load(seedFiles...)
+		if err == nil {
+			_, err = seed.NewSeeder(userService, orgUnitService, settingsService, log).Apply(seedCtx, seeds)
+		}
+		cancelSeed()
+		if err != nil {
+			log.Error("Failed to apply seed files", "error", err, "files", seedFiles)
+			log.Info("Exiting due to seed error")
+			os.Exit(1)
+		}
+	}
+	if seedOnly {
+		log.Info("Seed files applied", "files", seedFiles)
+		return
+	}
+
+	// Demo mode fills a sandbox for workshops with a sample app bundle, users and observations
+	if cfg.DemoMode || slices.Contains(os.Args[1:], "--demo") {
+		log.Warn("Demo mode is enabled: demo users share a known password; do not use it in production")
+		demoCtx, cancelDemo := context.WithTimeout(context.Background(), 10*time.Minute)
+		provisioner := demo.NewProvisioner(appBundleService, syncService, orgUnitService,
+			seed.NewSeeder(userService, orgUnitService, settingsService, log),
+			demo.Config{Observations: cfg.DemoObservations, Password: cfg.DemoPassword}, log)
+		_, err := provisioner.Provision(demoCtx)
+		cancelDemo()
+		if err != nil {
+			log.Error("Failed to provision the demo sandbox", "error", err)
+			log.Info("Exiting due to demo mode error")
+			return
+		}
+	}
+
+	// Set up federation with the upstream server when running as an edge server
+	handlerOptions := []handlers.Option{
+		handlers.WithSettingsService(settingsService),
+		handlers.WithOrgUnitService(orgUnitService),
+		handlers.WithDocumentService(documentService),
+		handlers.WithSavedQueryService(savedQueryService),
+		handlers.WithSamplingService(sampling.NewService(db.DB(), log)),
+		handlers.WithAuditService(audit.NewService(db.DB(), auditConfigFrom(cfg), log)),
+		handlers.WithTermsService(terms.NewService(db.DB(), log)),
+		handlers.WithInviteService(invite.NewService(db.DB(), userService, inviteConfigFrom(cfg), log)),
+		handlers.WithWebhookService(webhookService),
+		handlers.WithAPIKeyService(apikey.NewService(db.DB(), log)),
+		handlers.WithBackupService(backup.NewService(db.DB(), log)),
+		handlers.WithExportTemplateService(exporttemplate.NewService(db.DB(), log)),
+		handlers.WithLatencyService(latencyService),
+		handlers.WithAttachmentLifecycleService(attachmentLifecycle),
+		handlers.WithBundleChannelService(bundlechannel.NewService(db.DB(), log)),
+		handlers.WithBundleUploadService(bundleupload.NewService(db.DB(), log)),
+		handlers.WithDataImportService(dataImportService),
+		handlers.WithImportSourceService(importSourceService),
+		handlers.WithDeviceService(device.NewService(db.DB(), log)),
+		handlers.WithFormComponentService(formComponentService),
+		handlers.WithCodeListService(codeListService),
+		handlers.WithReportService(reportService),
+		handlers.WithCanaryService(canary.NewService(db.DB(), bundles, log)),
+		handlers.WithDatabasePool(pool),
+		handlers.WithHealthChecker(health.NewChecker(
+			health.Database(db.DB()),
+			health.Migrations(db.DB(), dbConfig.MigrationsFS),
+			health.WritableDir("app_bundle_dir", cfg.AppBundlePath),
+		)),
+	}
+	if replicator != nil {
+		handlerOptions = append(handlerOptions, handlers.WithReplicationService(replicator))
+	}
+	if store := idempotencyStoreFrom(cfg, shared, db.DB()); store != nil {
+		handlerOptions = append(handlerOptions, handlers.WithIdempotencyStore(store))
+	}
+	if cfg.AuthenticatorsFile != "" {
+		authenticators, err := authmw.LoadAuthenticators(cfg.AuthenticatorsFile, userRepo, cfg.MultiTenancyEnabled)
+		if err != nil {
+			log.Error("Failed to load authenticators", "error", err, "file", cfg.AuthenticatorsFile)
+			log.Info("Exiting due to authenticators configuration error")
+			return
+		}
+		log.Info("Additional authenticators enabled", "count", len(authenticators))
+		handlerOptions = append(handlerOptions, handlers.WithAuthenticators(authenticators))
+	}
+	var federationService *federation.Service
+	if federationConfig.Enabled() {
+		federationService = federation.NewService(db.DB(), syncService, federationConfig, log)
+		handlerOptions = append(handlerOptions, handlers.WithFederationService(federationService))
+	}
+
 	// Convert concrete types to interfaces if needed
 	var (
 		authSvc      auth.AuthServiceInterface           = authService
-		appBundleSvc appbundle.AppBundleServiceInterface = appBundleService
+		appBundleSvc appbundle.AppBundleServiceInterface = bundles
 		syncSvc      sync.ServiceInterface               = syncService
 		userSvc      user.UserServiceInterface           = userService
 	)
@@ -194,10 +859,11 @@ func main() {
 		versionService,
 		attachmentManifestService,
 		dataExportService,
+		handlerOptions...,
 	)
 
 	// Create the API router with handlers
-	router := api.NewRouter(log, h)
+	router := api.NewRouter(log, h, shared)
 
 	// Get server port from configuration
 	port := 8080
@@ -216,22 +882,145 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Serve HTTPS directly when a certificate or Let's Encrypt domains are configured
+	tlsConfig := tlsserver.Config{
+		CertFile:     cfg.TLSCertFile,
+		KeyFile:      cfg.TLSKeyFile,
+		Domains:      splitList(cfg.TLSAutocertDomains),
+		Email:        cfg.TLSAutocertEmail,
+		CacheDir:     cfg.TLSAutocertCacheDir,
+		DirectoryURL: cfg.TLSAutocertDirectoryURL,
+		ClientCAFile: cfg.TLSClientCAFile,
+	}
+	if cfg.HTTPRedirectPort != "" {
+		tlsConfig.RedirectAddr = ":" + cfg.HTTPRedirectPort
+	}
+	var redirectServer *http.Server
+	if tlsConfig.Enabled() {
+		if redirectServer, err = tlsserver.Configure(server, tlsConfig, log); err != nil {
+			log.Error("Invalid TLS configuration", "error", err)
+			log.Info("Exiting due to TLS configuration error")
+			return
+		}
+	}
+
 	// Start server in a goroutine so it doesn't block
 	go func() {
-		log.Info("Server listening", "port", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info("Server listening", "port", port, "tls", tlsConfig.Enabled())
+		var err error
+		if tlsConfig.Enabled() {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error("Server failed to start", "error", err.Error())
 			log.Info("Exiting due to server start error")
 			return
 		}
 	}()
 
+	// Redirect plain HTTP to HTTPS and answer Let's Encrypt challenges
+	if redirectServer != nil {
+		go func() {
+			log.Info("Redirecting HTTP to HTTPS", "port", cfg.HTTPRedirectPort)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("HTTP redirect listener failed to start", "error", err.Error())
+			}
+		}()
+	}
+
+	// Replicate with the upstream server in the background when running as an edge server
+	federationCtx, stopFederation := context.WithCancel(context.Background())
+	defer stopFederation()
+	if federationService != nil {
+		go federationService.Run(federationCtx)
+	}
+
+	// Deliver queued webhook events in the background
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	defer stopWebhooks()
+	go webhookService.Run(webhookCtx)
+
+	// Import queued CSV files in the background
+	importCtx, stopImports := context.WithCancel(context.Background())
+	defer stopImports()
+	go dataImportService.Run(importCtx)
+	go importSourceService.Run(importCtx)
+
+	// Compact the sync log on schedule
+	compactionCtx, stopCompaction := context.WithCancel(context.Background())
+	defer stopCompaction()
+	go syncService.RunCompaction(compactionCtx)
+
+	// Publish and activate new JWT signing keys on schedule
+	rotationCtx, stopRotation := context.WithCancel(context.Background())
+	defer stopRotation()
+	go authService.RunKeyRotation(rotationCtx)
+
+	// Delete login sessions whose refresh tokens have expired
+	sessionCtx, stopSessionCleanup := context.WithCancel(context.Background())
+	defer stopSessionCleanup()
+	go authService.RunSessionCleanup(sessionCtx)
+
+	// Copy app bundle versions and attachments to the secondary location
+	replicationCtx, stopReplication := context.WithCancel(context.Background())
+	defer stopReplication()
+	if replicator != nil {
+		go replicator.Run(replicationCtx)
+	}
+
+	// Add the observed latencies to the daily rollups
+	latencyCtx, stopLatency := context.WithCancel(context.Background())
+	defer stopLatency()
+	go latencyService.Run(latencyCtx)
+
+	// Apply the attachment lifecycle policy daily
+	lifecycleCtx, stopLifecycle := context.WithCancel(context.Background())
+	defer stopLifecycle()
+	go attachmentLifecycle.Run(lifecycleCtx)
+
+	// Deliver scheduled reports when they are due
+	reportCtx, stopReports := context.WithCancel(context.Background())
+	defer stopReports()
+	go reportService.Run(reportCtx)
+
+	// Rebuild the analytics schema on schedule
+	analyticsCtx, stopAnalytics := context.WithCancel(context.Background())
+	defer stopAnalytics()
+	if analyticsSink != nil {
+		interval := time.Duration(cfg.AnalyticsExportIntervalMinutes) * time.Minute
+		go dataexport.RunSinkExport(analyticsCtx, dataExportService, analyticsSink, dataexport.ExportOptions{}, interval, log)
+	}
+
+	// Load app bundle versions switched on other servers as soon as they are announced
+	bundleSwitchCtx, stopBundleSwitches := context.WithCancel(context.Background())
+	defer stopBundleSwitches()
+	if shared != nil {
+		go shared.Subscribe(bundleSwitchCtx, appBundleSwitchChannel, func(version string) {
+			if err := appBundleService.SyncCurrentVersion(bundleSwitchCtx); err != nil {
+				log.Warn("Failed to load switched app bundle version", "version", version, "error", err)
+			}
+		})
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info("Shutting down server...")
+	stopFederation()
+	stopWebhooks()
+	stopImports()
+	stopCompaction()
+	stopRotation()
+	stopBundleSwitches()
+	stopLatency()
+	stopLifecycle()
+	stopReplication()
+	stopReports()
+	stopAnalytics()
 
 	// Create a deadline to wait for current operations to complete
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -240,6 +1029,14 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Error("Server forced to shutdown", "error", err.Error())
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
+
+	// Count the requests that finished during shutdown
+	if err := latencyService.Flush(shutdownCtx); err != nil {
+		log.Error("Failed to flush latency histograms", "error", err)
+	}
 
 	log.Info("Server gracefully stopped")
 }
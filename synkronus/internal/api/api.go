@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -11,11 +12,43 @@ import (
 
 	"github.com/opendataensemble/synkronus/internal/handlers"
 	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/adminui"
 	"github.com/opendataensemble/synkronus/pkg/attachment"
+	"github.com/opendataensemble/synkronus/pkg/latency"
 	"github.com/opendataensemble/synkronus/pkg/logger"
 	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/middleware/ratelimit"
+	"github.com/opendataensemble/synkronus/pkg/middleware/respcache"
+	"github.com/opendataensemble/synkronus/pkg/middleware/security"
+	"github.com/opendataensemble/synkronus/pkg/middleware/throttle"
+	"github.com/opendataensemble/synkronus/pkg/redis"
 )
 
+// docsPolicy is the Content Security Policy of the Swagger UI page, which loads its scripts
+// and styles from unpkg
+const docsPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https:; connect-src 'self'"
+
+// adminUIPolicy is the Content Security Policy of the admin UI, which only talks to this server
+const adminUIPolicy = "default-src 'self'"
+
+// tenantRoutes are the routes whose handlers keep tenants apart: sync of observations, transfer
+// of attachments by ID, the app bundle, the tenant's users and backups, plus reading and
+// acknowledging the server's terms of use. Other routes serve the whole server and are refused to tenants other
+// than the default; see auth.TenantConfig for the syntax.
+var tenantRoutes = []string{
+	"/auth/sessions", "/auth/sessions/*",
+	"GET /terms", "POST /terms/acknowledge",
+	"/sync/pull", "/sync/push", "/sync/transmissions/*", "/sync/receipts/verify",
+	"GET /attachments/*", "HEAD /attachments/*", "PUT /attachments/*", "DELETE /attachments/*",
+	"/app-bundle/manifest", "/app-bundle/diff", "/app-bundle/download/*", "/app-bundle/files/*",
+	"/app-bundle/versions", "/app-bundle/changes", "/app-bundle/codebook", "/app-bundle/compatibility",
+	"/app-bundle/canary",
+	"/app-bundle/push", "/app-bundle/switch/*", "/app-bundle/uploads", "/app-bundle/uploads/*",
+	"/users", "/users/create", "/users/delete/*", "/users/reset-password", "/users/change-password", "/users/impersonate",
+	"/backup/*",
+}
+
 // NewRouter creates a new router with all API routes configured
 // FileServer conveniently sets up a http.FileServer handler to serve
 // static files from a http.FileSystem.
@@ -30,22 +63,36 @@ func FileServer(r chi.Router, path string, root http.FileSystem) {
 	})
 }
 
-func NewRouter(log *logger.Logger, h *handlers.Handler) http.Handler {
+func NewRouter(log *logger.Logger, h *handlers.Handler, shared *redis.Client) http.Handler {
 	r := chi.NewRouter()
 
 	// Add middleware
 	r.Use(middleware.RequestID)
+	r.Use(auth.PeerAddrMiddleware) // before RealIP, so proxies can be trusted by their own address
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RedirectSlashes) // redirects /users to /users/ etc.
 
+	// Latency, errors and dropped connections injected for client testing; dev builds only
+	registerFaultRoutes := useFaultInjection(r, log)
+
+	// Browser security headers; routes serving bundle content switch to the content policy
+	cfg := h.GetConfig()
+	headers := security.New(security.Config{
+		APIPolicy:      cfg.SecurityAPIPolicy,
+		ContentPolicy:  cfg.SecurityContentPolicy,
+		FrameAncestors: cfg.SecurityFrameAncestors,
+		ReferrerPolicy: cfg.SecurityReferrerPolicy,
+	})
+	r.Use(headers.Middleware)
+
 	// Add CORS middleware
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"accept", "authorization", "content-type", "x-csrf-token", "if-none-match"},
-		ExposedHeaders:   []string{"link", "etag"},
+		AllowedHeaders:   []string{"accept", "authorization", "content-type", "x-csrf-token", "if-none-match", cfg.TenantHeader},
+		ExposedHeaders:   []string{"link", "etag", "retry-after"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -54,6 +101,9 @@ func NewRouter(log *logger.Logger, h *handlers.Handler) http.Handler {
 
 	// Public endpoints
 	r.Get("/health", h.HealthCheck)
+	r.Get("/health/live", h.Liveness)
+	r.Get("/health/ready", h.Readiness)
+	r.Get("/.well-known/jwks.json", h.JWKS)
 
 	r.Get("/openapi/swagger", http.RedirectHandler("/openapi/swagger-ui.html", http.StatusMovedPermanently).ServeHTTP)
 
@@ -80,20 +130,52 @@ func NewRouter(log *logger.Logger, h *handlers.Handler) http.Handler {
 		rootDir := filepath.Dir(filepath.Dir(execDir))
 		// Serve static files from the static directory
 		staticDir := filepath.Join(rootDir, "static")
-		FileServer(r, "/static", http.Dir(staticDir))
+		FileServer(r.With(headers.Content), "/static", http.Dir(staticDir))
 
 		// Serve OpenAPI documentation (Swagger UI)
 		appDir := filepath.Dir(execDir)
 		openapiDir := filepath.Join(appDir, "openapi")
-		FileServer(r, "/openapi", http.Dir(openapiDir))
+		FileServer(r.With(headers.Policy(docsPolicy)), "/openapi", http.Dir(openapiDir))
+	}
+
+	// Admin UI; it signs in through /auth/login like any other client
+	if cfg.AdminUIEnabled {
+		if files, err := adminui.FS(cfg.AdminUIDir); err != nil {
+			log.Error("Failed to load admin UI", "error", err, "dir", cfg.AdminUIDir)
+		} else {
+			adminUI := adminui.Handler(files)
+			r.With(headers.Policy(adminUIPolicy)).Get(adminui.Prefix, adminUI.ServeHTTP)
+			r.With(headers.Policy(adminUIPolicy)).Get(adminui.Prefix+"/*", adminUI.ServeHTTP)
+		}
+	}
+
+	// Request budgets of login, sync and app bundle clients; nil when disabled
+	rateLimiter, err := ratelimit.New(ratelimit.Config{
+		Backend:          cfg.RateLimit,
+		UserPerMinute:    cfg.RateLimitUserPerMinute,
+		AddressPerMinute: cfg.RateLimitIPPerMinute,
+		Burst:            cfg.RateLimitBurst,
+		TrustedProxies:   cfg.RateLimitProxies,
+		Redis:            shared,
+	})
+	if err != nil {
+		log.Error("Failed to initialize rate limiting; requests are not limited", "error", err)
+	} else if rateLimiter != nil {
+		log.Info("Rate limiting enabled", "backend", cfg.RateLimit, "userPerMinute", cfg.RateLimitUserPerMinute,
+			"ipPerMinute", cfg.RateLimitIPPerMinute, "burst", cfg.RateLimitBurst)
 	}
 
 	// Authentication routes
 	r.Route("/auth", func(r chi.Router) {
-		r.Post("/login", h.Login)
+		r.With(rateLimiter.Middleware).Post("/login", h.Login)
 		r.Post("/refresh", h.RefreshToken)
+		r.Post("/logout", h.Logout)
+		r.Post("/accept-invite", h.AcceptInvitation)
 	})
 
+	// Preview links carry their own scoped token in the path
+	r.With(headers.Content).Get("/preview/{token}/*", h.GetPreviewFile)
+
 	// Create attachment service
 	attachmentService, err := attachment.NewService(h.GetConfig())
 	if err != nil {
@@ -101,36 +183,216 @@ func NewRouter(log *logger.Logger, h *handlers.Handler) http.Handler {
 	}
 
 	// Create attachment handler
-	attachmentHandler := handlers.NewAttachmentHandler(log, attachmentService)
+	attachmentHandler := handlers.NewAttachmentHandler(log, attachmentService, h.GetAttachmentManifestService())
+
+	// Per-client bandwidth shaping of large downloads; nil when disabled
+	limiter := throttle.New(throttle.Config{
+		BytesPerSecond: int64(cfg.BandwidthClientKBps) * 1024,
+		Burst:          int64(cfg.BandwidthBurstKB) * 1024,
+		Redis:          shared,
+	})
+	if limiter != nil {
+		log.Info("Bandwidth shaping enabled", "clientKBps", cfg.BandwidthClientKBps, "burstKB", cfg.BandwidthBurstKB)
+	}
+
+	// Response cache of expensive read endpoints; nil when disabled. Writes invalidate the
+	// scopes they change.
+	cache, err := respcache.New(respcache.Config{
+		Backend: cfg.ResponseCache,
+		TTL:     time.Duration(cfg.ResponseCacheTTLSeconds) * time.Second,
+		Redis:   shared,
+	}, log)
+	if err != nil {
+		log.Error("Failed to initialize response cache; responses are not cached", "error", err)
+	} else if cache != nil {
+		log.Info("Response caching enabled", "backend", cfg.ResponseCache, "ttlSeconds", cfg.ResponseCacheTTLSeconds)
+	}
+
+	// Latency tracking of pull, push and export for SLO reports; passes through when disabled
+	track := func(operation string) func(http.Handler) http.Handler {
+		return latency.Track(h.GetLatencyService(), operation)
+	}
 
 	// Protected routes - require authentication
 	r.Group(func(r chi.Router) {
-		// Add authentication middleware
+		// Add authentication middleware; users authenticated by an SSO gateway or a client
+		// certificate, and API keys of data pipelines and scripts, are accepted alongside JWTs
+		if authenticators := h.GetAuthenticators(); len(authenticators) > 0 {
+			r.Use(auth.AuthenticatorMiddleware(authenticators, log))
+		}
+		if apiKeyService := h.GetAPIKeyService(); apiKeyService != nil {
+			r.Use(auth.APIKeyMiddleware(apiKeyService, log))
+		}
 		r.Use(auth.AuthMiddleware(h.GetAuthService(), log))
 
-		// Register attachment routes (including manifest endpoint)
-		attachmentHandler.RegisterRoutes(r, h.AttachmentManifestHandler)
+		// Requests act for the user's tenant; the default tenant's admins may name another one
+		if cfg.MultiTenancyEnabled {
+			r.Use(auth.TenantMiddleware(auth.TenantConfig{Header: cfg.TenantHeader, Routes: tenantRoutes}, log))
+		}
+
+		// Login sessions of the current user
+		r.Get("/auth/sessions", h.ListSessionsHandler)
+		r.Delete("/auth/sessions", h.RevokeAllSessionsHandler)
+		r.Delete("/auth/sessions/{id}", h.RevokeSessionHandler)
+
+		// Register attachment routes (including manifest endpoint), shaped per client
+		r.Group(func(r chi.Router) {
+			r.Use(limiter.Middleware)
+			attachmentHandler.RegisterRoutes(r, h.AttachmentManifestHandler)
+		})
 
 		// Sync routes
 		r.Route("/sync", func(r chi.Router) {
-			// Pull endpoint - accessible to all authenticated users
-			r.Post("/pull", h.Pull)
+			// Requests are budgeted per user
+			r.Use(rateLimiter.Middleware)
+
+			// Pull endpoint - accessible to all authenticated users, shaped per client
+			r.With(track(latency.OperationPull), limiter.Middleware, h.RequireTermsAcknowledgement).Post("/pull", h.Pull)
 
 			// Push endpoint - requires read-write or admin role
-			r.With(auth.RequireRole(models.RoleReadWrite, models.RoleAdmin)).Post("/push", h.Push)
+			r.With(track(latency.OperationPush), auth.RequireRole(models.RoleReadWrite, models.RoleAdmin), h.RequireTermsAcknowledgement, cache.Invalidates(respcache.ScopeData)).Post("/push", h.Push)
+
+			// Outcome of a pushed transmission, for clients that timed out - requires read-write or admin role
+			r.With(auth.RequireRole(models.RoleReadWrite, models.RoleAdmin)).Get("/transmissions/{transmissionId}", h.GetSyncTransmission)
+
+			// Verification of submission receipts - accessible to all authenticated users
+			r.Post("/receipts/verify", h.VerifySyncReceipt)
+
+			// Conflict inspector - admin only
+			r.Route("/conflicts", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeData))
+				r.Get("/", h.ListSyncConflicts)
+				r.Get("/{id}", h.GetSyncConflict)
+				r.Post("/{id}/resolve", h.ResolveSyncConflict)
+			})
+
+			// Case sync - pull for all authenticated users, push requires read-write or admin role
+			r.With(limiter.Middleware, h.RequireTermsAcknowledgement).Post("/cases/pull", h.PullCases)
+			r.With(auth.RequireRole(models.RoleReadWrite, models.RoleAdmin), h.RequireTermsAcknowledgement, cache.Invalidates(respcache.ScopeData)).Post("/cases/push", h.PushCases)
+
+			// ID range reservations for offline numbering - requires read-write or admin role
+			r.Route("/id-ranges", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleReadWrite, models.RoleAdmin))
+				r.Get("/", h.ListIDRanges)
+				r.Post("/", h.AllocateIDRange)
+			})
+
+			// Record locks for concurrent editing - requires read-write or admin role;
+			// force-releasing another user's lock is checked in the handler
+			r.Route("/locks", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleReadWrite, models.RoleAdmin))
+				r.Get("/", h.ListRecordLocks)
+				r.Post("/{observationId}", h.ClaimRecordLock)
+				r.Delete("/{observationId}", h.ReleaseRecordLock)
+			})
+
+			// Per-form-type pause switches - admin only
+			r.Route("/form-controls", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin))
+				r.Get("/", h.ListFormSyncControls)
+				r.Put("/{formType}", h.SetFormSyncControl)
+				r.Delete("/{formType}", h.DeleteFormSyncControl)
+			})
+
+			// Reporting period locks - admin only
+			r.Route("/period-locks", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin))
+				r.Get("/", h.ListPeriodLocks)
+				r.Post("/", h.CreatePeriodLock)
+				r.Delete("/{id}", h.DeletePeriodLock)
+			})
+
+			// Sync log compaction - admin only
+			r.Route("/compactions", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeData))
+				r.Get("/", h.ListSyncCompactions)
+				r.Post("/", h.CompactSyncLog)
+			})
+		})
+
+		// Case routes - accessible to all authenticated users
+		r.Get("/cases/{caseId}", h.GetCase)
+
+		// Observation ownership routes - admin only
+		r.Route("/observations", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeData))
+			r.Post("/reassign", h.ReassignObservations)
+
+			// Supporting documents attached by office staff
+			r.Route("/{observationId}/documents", func(r chi.Router) {
+				r.Get("/", h.ListObservationDocuments)
+				r.Post("/", h.AttachObservationDocument)
+				r.Get("/audit", h.ListObservationDocumentAudit)
+				r.Get("/{documentId}", h.DownloadObservationDocument)
+				r.Delete("/{documentId}", h.RemoveObservationDocument)
+			})
+		})
+
+		// Observation assignments scoping assignment-based sync pulls - admin only
+		r.Route("/assignments", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/", h.ListAssignments)
+			r.With(cache.Invalidates(respcache.ScopeData)).Post("/", h.AssignObservations)
+			r.With(cache.Invalidates(respcache.ScopeData)).Post("/unassign", h.UnassignObservations)
 		})
 
 		// App bundle routes
 		r.Route("/app-bundle", func(r chi.Router) {
-			// Read endpoints - accessible to all authenticated users
-			r.Get("/manifest", h.GetAppBundleManifest)
-			r.Get("/download/{path}", h.GetAppBundleFile)
-			r.Get("/versions", h.GetAppBundleVersions)
-			r.Get("/changes", h.CompareAppBundleVersions)
+			// Requests are budgeted per user
+			r.Use(rateLimiter.Middleware)
+
+			// Read endpoints - accessible to all authenticated users; polled by every device, so
+			// cached until the bundle changes
+			r.With(cache.Shared(respcache.ScopeBundle)).Get("/manifest", h.GetAppBundleManifest)
+			r.Get("/diff", h.GetAppBundleDiff)
+			r.With(headers.Content).Get("/download/{path}", h.GetAppBundleFile)
+			r.With(headers.Content).Get("/files/{hash}/*", h.GetAppBundleHashedFile)
+			r.With(cache.Shared(respcache.ScopeBundle)).Get("/versions", h.GetAppBundleVersions)
+			r.With(cache.Shared(respcache.ScopeBundle)).Get("/changes", h.CompareAppBundleVersions)
+			r.Get("/codebook", h.GetAppBundleCodebook)
+			r.Get("/compatibility", h.GetAppBundleCompatibility)
 
 			// Write endpoints - require admin role
-			r.With(auth.RequireRole(models.RoleAdmin)).Post("/push", h.PushAppBundle)
-			r.With(auth.RequireRole(models.RoleAdmin)).Post("/switch/{version}", h.SwitchAppBundleVersion)
+			r.With(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeBundle)).Post("/push", h.PushAppBundle)
+			r.With(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeBundle)).Post("/switch/{version}", h.SwitchAppBundleVersion)
+			r.With(auth.RequireRole(models.RoleAdmin)).Post("/preview-tokens", h.CreatePreviewToken)
+
+			// Chunked uploads for large bundles; completing one pushes it like /push
+			r.Route("/uploads", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin))
+				r.Post("/", h.CreateAppBundleUpload)
+				r.Get("/{id}", h.GetAppBundleUpload)
+				r.Put("/{id}", h.UploadAppBundleChunk)
+				r.Delete("/{id}", h.DeleteAppBundleUpload)
+				r.With(cache.Invalidates(respcache.ScopeBundle)).Post("/{id}/complete", h.CompleteAppBundleUpload)
+			})
+
+			// Preview channel - staged versions reach assigned devices until promoted; admin only
+			r.With(auth.RequireRole(models.RoleAdmin)).Post("/canary", h.RunAppBundleCanary)
+			r.With(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeBundle)).Post("/promote", h.PromoteAppBundlePreview)
+			r.With(auth.RequireRole(models.RoleAdmin)).Get("/channels", h.ListAppBundleChannels)
+			r.With(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeBundle)).Put("/channels/{clientId}", h.AssignAppBundleChannel)
+		})
+
+		// XLSForm conversion - converts an upload without storing anything; admin only, like bundle pushes
+		r.With(auth.RequireRole(models.RoleAdmin)).Post("/forms/convert", h.ConvertXLSForm)
+
+		// Shared form components included in forms with x-include - reads for all authenticated
+		// users, publishing requires admin role
+		r.Route("/form-components", func(r chi.Router) {
+			r.Get("/", h.ListFormComponents)
+			r.Get("/{name}", h.ListFormComponentVersions)
+			r.Get("/{name}/versions/{version}", h.GetFormComponent)
+			r.With(auth.RequireRole(models.RoleAdmin)).Post("/{name}", h.PublishFormComponent)
+		})
+
+		// Managed code lists referenced from forms with x-code-list - clients compare the listed
+		// versions to fetch the lists that changed; publishing requires admin role
+		r.Route("/code-lists", func(r chi.Router) {
+			r.Get("/", h.ListCodeLists)
+			r.Get("/{name}", h.ListCodeListVersions)
+			r.Get("/{name}/versions/{version}", h.GetCodeList)
+			r.With(auth.RequireRole(models.RoleAdmin)).Post("/{name}", h.PublishCodeList)
 		})
 
 		// Form specifications routes
@@ -142,19 +404,179 @@ func NewRouter(log *logger.Logger, h *handlers.Handler) http.Handler {
 		r.Route("/users", func(r chi.Router) {
 			// Admin-only routes
 			r.With(auth.RequireRole(models.RoleAdmin)).Post("/create", h.CreateUserHandler)
+			r.With(auth.RequireRole(models.RoleAdmin)).Post("/invite", h.InviteUserHandler)
+			r.With(auth.RequireRole(models.RoleAdmin)).Get("/invitations", h.ListInvitationsHandler)
+			r.With(auth.RequireRole(models.RoleAdmin)).Delete("/invitations/{id}", h.RevokeInvitationHandler)
 			r.With(auth.RequireRole(models.RoleAdmin)).Delete("/delete/{username}", h.DeleteUserHandler)
 			r.With(auth.RequireRole(models.RoleAdmin)).Post("/reset-password", h.ResetPasswordHandler)
 			r.With(auth.RequireRole(models.RoleAdmin)).Get("/", h.ListUsersHandler)
-			// Authenticated user route
+			r.With(auth.RequireRole(models.RoleAdmin)).Get("/password-hashes", h.PasswordHashReportHandler)
+			r.With(auth.RequireRole(models.RoleAdmin)).Get("/auth-events", h.ListAuthEvents)
+			r.With(auth.RequireRole(models.RoleAdmin)).Post("/impersonate", h.ImpersonateUserHandler)
+			// Authenticated user routes
 			r.Post("/change-password", h.ChangePasswordHandler)
+
+			// API keys - users manage their own, admins everyone's
+			r.Get("/api-keys", h.ListAPIKeysHandler)
+			r.Post("/api-keys", h.CreateAPIKeyHandler)
+			r.Delete("/api-keys/{id}", h.RevokeAPIKeyHandler)
+		})
+
+		// Webhook subscriptions delivering pushed observations - admin only
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/", h.ListWebhooksHandler)
+			r.Post("/", h.CreateWebhookHandler)
+			r.Delete("/{id}", h.DeleteWebhookHandler)
+			r.Get("/{id}/deliveries", h.ListWebhookDeliveriesHandler)
+			r.Get("/{id}/deliveries/{deliveryId}/attempts", h.ListWebhookDeliveryAttemptsHandler)
+			r.Get("/dead-letters", h.ListWebhookDeadLettersHandler)
+			r.Post("/dead-letters/replay", h.ReplayWebhookDeliveriesHandler)
+		})
+
+		// Backup and restore of users, app bundles and observations - admin only
+		r.Route("/backup", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeData))
+			r.Get("/users", h.ExportBackupUsers)
+			r.Post("/users", h.RestoreBackupUsers)
+			r.Get("/observations", h.ExportBackupObservations)
+			r.Post("/observations", h.RestoreBackupObservations)
+			r.Get("/app-bundle/{version}", h.ExportBackupAppBundle)
+		})
+
+		// Request audit log of sensitive operations - admin only
+		r.Route("/audit", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/", h.ListAuditLog)
+			r.Get("/export", h.ExportAuditLog)
+		})
+
+		// Fault injection rules - admin only, dev builds only
+		registerFaultRoutes(r)
+
+		// Operational statistics for SLO reporting - admin only
+		r.Route("/stats", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/latency", h.GetLatencyStats)
+			r.Get("/attachments", h.GetAttachmentStats)
+			r.Get("/database", h.GetDatabaseStats)
+			r.Get("/replication", h.GetReplicationStatus)
 		})
 
 		// Data export routes
 		r.Route("/dataexport", func(r chi.Router) {
 			// Parquet export - accessible to read-only users and above
-			r.With(auth.RequireRole(models.RoleReadOnly, models.RoleReadWrite, models.RoleAdmin)).Get("/parquet", h.ParquetExportHandler)
+			r.With(track(latency.OperationExport), auth.RequireRole(models.RoleReadOnly, models.RoleReadWrite, models.RoleAdmin)).Get("/parquet", h.ParquetExportHandler)
+
+			// Saved export templates - readable by everyone who can export, managed by admins
+			r.Route("/templates", func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleReadOnly, models.RoleReadWrite, models.RoleAdmin))
+				r.Get("/", h.ListExportTemplates)
+				r.Get("/{name}", h.GetExportTemplate)
+				r.With(auth.RequireRole(models.RoleAdmin)).Put("/{name}", h.SaveExportTemplate)
+				r.With(auth.RequireRole(models.RoleAdmin)).Delete("/{name}", h.DeleteExportTemplate)
+			})
+		})
+
+		// Observation samples for QA back-checks - accessible to read-only users and above
+		r.With(auth.RequireRole(models.RoleReadOnly, models.RoleReadWrite, models.RoleAdmin)).Post("/data/sample", h.SampleObservations)
+
+		// CSV imports of historical observations - admin only
+		r.Route("/data/import", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/", h.ListDataImports)
+			r.Post("/", h.StartDataImport)
+			r.Get("/{id}", h.GetDataImport)
+
+			// ODK Central and KoboToolbox forms pulled into imports
+			r.Route("/sources", func(r chi.Router) {
+				r.Get("/", h.ListImportSources)
+				r.Post("/", h.CreateImportSource)
+				r.Get("/{id}", h.GetImportSource)
+				r.Delete("/{id}", h.DeleteImportSource)
+				r.Post("/{id}/pull", h.PullImportSource)
+			})
+		})
+
+		// Deployment settings routes
+		r.Route("/settings", func(r chi.Router) {
+			// Read endpoints - accessible to all authenticated users
+			r.Get("/", h.ListSettings)
+			r.Get("/{key}", h.GetSetting)
+
+			// Write endpoints - require admin role
+			r.With(auth.RequireRole(models.RoleAdmin)).Put("/{key}", h.SetSetting)
+			r.With(auth.RequireRole(models.RoleAdmin)).Delete("/{key}", h.DeleteSetting)
+		})
+
+		// Org unit hierarchy routes
+		r.Route("/org-units", func(r chi.Router) {
+			// Read endpoints - accessible to all authenticated users
+			r.Get("/", h.ListOrgUnits)
+			r.Get("/{id}", h.GetOrgUnit)
+
+			// Management endpoints - require admin role
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeData))
+				r.Post("/", h.CreateOrgUnit)
+				r.Put("/{id}", h.UpdateOrgUnit)
+				r.Delete("/{id}", h.DeleteOrgUnit)
+				r.Get("/{id}/users", h.ListOrgUnitUsers)
+				r.Put("/{id}/users/{username}", h.AssignOrgUnitUser)
+				r.Delete("/{id}/users/{username}", h.UnassignOrgUnitUser)
+				r.Post("/{id}/observations", h.AssignOrgUnitObservations)
+			})
 		})
 
+		// Client registry - devices seen in sync, their last sync and status; admin only
+		r.Route("/clients", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/", h.ListClients)
+			r.Get("/{clientId}", h.GetClient)
+			r.Patch("/{clientId}", h.UpdateClient)
+		})
+
+		// Saved dashboard queries
+		r.Route("/queries", func(r chi.Router) {
+			// Running is open to all authenticated users; the query's roles decide who may run it.
+			// Results depend on the user's org unit scope, so they are cached per user.
+			r.With(cache.PerUser(respcache.ScopeData)).Get("/{name}/run", h.RunSavedQuery)
+
+			// Management endpoints - require admin role
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireRole(models.RoleAdmin), cache.Invalidates(respcache.ScopeData))
+				r.Get("/", h.ListSavedQueries)
+				r.Get("/{name}", h.GetSavedQuery)
+				r.Put("/{name}", h.SaveSavedQuery)
+				r.Delete("/{name}", h.DeleteSavedQuery)
+			})
+		})
+
+		// Report templates built from saved queries, rendered on demand or delivered on
+		// schedule - admin only
+		r.Route("/reports", func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			r.Get("/", h.ListReports)
+			r.Get("/{name}", h.GetReport)
+			r.Put("/{name}", h.SaveReport)
+			r.Delete("/{name}", h.DeleteReport)
+			r.Get("/{name}/render", h.RenderReport)
+			r.Post("/{name}/deliver", h.DeliverReport)
+		})
+
+		// Terms of use - pull and push are refused until the current version is acknowledged
+		r.Route("/terms", func(r chi.Router) {
+			r.Get("/", h.GetTerms)
+			r.Post("/acknowledge", h.AcknowledgeTerms)
+
+			// Management endpoints - require admin role
+			r.With(auth.RequireRole(models.RoleAdmin)).Put("/", h.PublishTerms)
+			r.With(auth.RequireRole(models.RoleAdmin)).Get("/acknowledgements", h.ListTermsAcknowledgements)
+		})
+
+		// Replication status of an edge server - admin only
+		r.With(auth.RequireRole(models.RoleAdmin)).Get("/federation/status", h.GetFederationStatus)
+
 		// Version routes
 		r.Get("/version", h.GetVersion)
 		r.Get("/api/versions", h.GetAPIVersions) // Not implemented yet
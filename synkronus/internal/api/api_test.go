@@ -45,7 +45,7 @@ func TestNewRouter(t *testing.T) {
 	)
 
 	// Create a new router
-	router := NewRouter(log, mockHandler)
+	router := NewRouter(log, mockHandler, nil)
 
 	// Ensure router is not nil
 	if router == nil {
@@ -74,6 +74,14 @@ func TestNewRouter(t *testing.T) {
 		t.Errorf("Expected content type %s, got %s", "text/plain", contentType)
 	}
 
+	// Check security headers
+	if got := resp.Header.Get("Content-Security-Policy"); got != "default-src 'none'; frame-ancestors 'none'" {
+		t.Errorf("Unexpected Content-Security-Policy %q", got)
+	}
+	if got := resp.Header.Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("Expected X-Content-Type-Options nosniff, got %q", got)
+	}
+
 	// Check response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -84,3 +92,35 @@ func TestNewRouter(t *testing.T) {
 		t.Errorf("Expected response body %s, got %s", "OK", string(body))
 	}
 }
+
+func TestNewRouter_AdminUI(t *testing.T) {
+	log := logger.NewLogger()
+	mockConfig := mocks.NewTestConfig()
+	mockConfig.AdminUIEnabled = true
+	h := handlers.NewHandler(
+		log,
+		mockConfig,
+		mocks.NewMockAuthService(),
+		mocks.NewMockAppBundleService(),
+		mocks.NewMockSyncService(),
+		mocks.NewMockUserService(),
+		mocks.NewMockVersionService(),
+		&mocks.MockAttachmentManifestService{},
+		mocks.NewMockDataExportService(),
+	)
+	server := httptest.NewServer(NewRouter(log, h, nil))
+	defer server.Close()
+
+	// The page is public; the API calls it makes are authenticated
+	resp, err := http.Get(server.URL + "/admin/users")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Security-Policy"); got != "default-src 'self'; frame-ancestors 'none'" {
+		t.Errorf("Unexpected Content-Security-Policy %q", got)
+	}
+}
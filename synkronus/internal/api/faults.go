@@ -0,0 +1,14 @@
+//go:build !dev
+
+package api
+
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// useFaultInjection does nothing outside dev builds, which alone expose /dev/faults
+func useFaultInjection(r chi.Router, log *logger.Logger) func(chi.Router) {
+	return func(chi.Router) {}
+}
@@ -0,0 +1,42 @@
+//go:build dev
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/middleware/faults"
+)
+
+// faultsPath is where admins manage the injected faults of dev builds
+const faultsPath = "/dev/faults"
+
+// useFaultInjection injects the faults configured at /dev/faults into every other request and
+// returns the registration of the management endpoints, which belong to the protected routes
+func useFaultInjection(r chi.Router, log *logger.Logger) func(chi.Router) {
+	injector := faults.New()
+	r.Use(func(next http.Handler) http.Handler {
+		inject := injector.Middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, faultsPath) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			inject.ServeHTTP(w, r)
+		})
+	})
+	log.Warn("Dev build: fault injection is enabled", "path", faultsPath)
+
+	return func(r chi.Router) {
+		r.Route(faultsPath, func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
+			injector.RegisterRoutes(r)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/apikey"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/user"
+)
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Username issues the key for another user, e.g. a service account; admins only
+	Username string `json:"username,omitempty"`
+	// ExpiresAt is an optional RFC 3339 expiry; keys without one do not expire
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKeyResponse is an issued API key together with the key itself, which is only ever
+// returned here
+type CreateAPIKeyResponse struct {
+	apikey.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyHandler handles POST /users/api-keys
+func (h *Handler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := authmw.GetUserFromContext(r.Context())
+	if currentUser == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	// A leaked key must not be able to mint further keys
+	if authmw.GetAPIKeyFromContext(r.Context()) != nil {
+		SendErrorResponse(w, http.StatusForbidden, nil, "API keys cannot be created with an API key; log in instead")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+
+	owner := currentUser.Username
+	if req.Username != "" && req.Username != owner {
+		if currentUser.Role != models.RoleAdmin {
+			SendErrorResponse(w, http.StatusForbidden, nil, "Only admins can create API keys for other users")
+			return
+		}
+		owner = req.Username
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, "expires_at must be an RFC 3339 timestamp")
+			return
+		}
+		expiresAt = &t
+	}
+
+	key, secret, err := h.apiKeyService.Create(r.Context(), owner, req.Name, expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrInvalidName), errors.Is(err, apikey.ErrInvalidExpiry):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		case errors.Is(err, user.ErrUserNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "User not found")
+		default:
+			h.log.Error("Failed to create API key", "error", err, "username", owner)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to create API key")
+		}
+		return
+	}
+
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventAPIKeyCreated, Username: owner})
+	SendJSONResponse(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: *key, Key: secret})
+}
+
+// ListAPIKeysHandler handles GET /users/api-keys; admins list every user's keys with ?all=true
+func (h *Handler) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := authmw.GetUserFromContext(r.Context())
+	if currentUser == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	username := currentUser.Username
+	if r.URL.Query().Get("all") == "true" {
+		if currentUser.Role != models.RoleAdmin {
+			SendErrorResponse(w, http.StatusForbidden, nil, "Only admins can list every user's API keys")
+			return
+		}
+		username = ""
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), username)
+	if err != nil {
+		h.log.Error("Failed to list API keys", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list API keys")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKeyHandler handles DELETE /users/api-keys/{id}; admins can revoke any user's key
+func (h *Handler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := authmw.GetUserFromContext(r.Context())
+	if currentUser == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	username := currentUser.Username
+	if currentUser.Role == models.RoleAdmin {
+		username = ""
+	}
+
+	key, err := h.apiKeyService.Revoke(r.Context(), chi.URLParam(r, "id"), username)
+	if err != nil {
+		if errors.Is(err, apikey.ErrKeyNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "API key not found")
+			return
+		}
+		h.log.Error("Failed to revoke API key", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to revoke API key")
+		return
+	}
+
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventAPIKeyRevoked, Username: key.Username})
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "API key revoked"})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/apikey"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func apiKeyRequest(method, target, username string, role models.Role) *http.Request {
+	return withRole(httptest.NewRequest(method, target, nil), username, role)
+}
+
+func createAPIKey(h *Handler, username string, role models.Role, req CreateAPIKeyRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	h.CreateAPIKeyHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/users/api-keys", bytes.NewReader(body)), username, role))
+	return w
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := createAPIKey(h, "pipeline", models.RoleReadOnly, CreateAPIKeyRequest{Name: "nightly export"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created CreateAPIKeyResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "pipeline", created.Username)
+	assert.True(t, apikey.IsKey(created.Key))
+	assert.Contains(t, created.Key, created.KeyPrefix)
+
+	// Admins can issue keys for service accounts; other users cannot
+	w = createAPIKey(h, "admin", models.RoleAdmin, CreateAPIKeyRequest{Name: "etl", Username: "etl-bot"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = createAPIKey(h, "pipeline", models.RoleReadOnly, CreateAPIKeyRequest{Name: "etl", Username: "etl-bot"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Invalid names and expiries
+	w = createAPIKey(h, "pipeline", models.RoleReadOnly, CreateAPIKeyRequest{Name: " "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = createAPIKey(h, "pipeline", models.RoleReadOnly, CreateAPIKeyRequest{Name: "old", ExpiresAt: "2020-01-01T00:00:00Z"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = createAPIKey(h, "pipeline", models.RoleReadOnly, CreateAPIKeyRequest{Name: "soon", ExpiresAt: "tomorrow"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A key cannot mint further keys
+	body, _ := json.Marshal(CreateAPIKeyRequest{Name: "another"})
+	r := withRole(httptest.NewRequest(http.MethodPost, "/users/api-keys", bytes.NewReader(body)), "pipeline", models.RoleReadOnly)
+	w = httptest.NewRecorder()
+	h.CreateAPIKeyHandler(w, r.WithContext(context.WithValue(r.Context(), authmw.APIKeyKey, &created.APIKey)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	events := h.auditService.(*mocks.MockAuditService).Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, audit.EventAPIKeyCreated, events[1].Type)
+	assert.Equal(t, "etl-bot", events[1].Username)
+	assert.Equal(t, "admin", events[1].Actor)
+}
+
+func TestListAndRevokeAPIKeys(t *testing.T) {
+	h, _ := createTestHandler()
+	keys := h.apiKeyService.(*mocks.MockAPIKeyService)
+	own, _, err := keys.Create(context.Background(), "alice", "laptop", nil)
+	require.NoError(t, err)
+	other, _, err := keys.Create(context.Background(), "bob", "script", nil)
+	require.NoError(t, err)
+
+	list := func(r *http.Request) []apikey.APIKey {
+		w := httptest.NewRecorder()
+		h.ListAPIKeysHandler(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		var listed []apikey.APIKey
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&listed))
+		return listed
+	}
+	assert.Len(t, list(apiKeyRequest(http.MethodGet, "/users/api-keys", "alice", models.RoleReadWrite)), 1)
+	assert.Len(t, list(apiKeyRequest(http.MethodGet, "/users/api-keys?all=true", "admin", models.RoleAdmin)), 2)
+
+	w := httptest.NewRecorder()
+	h.ListAPIKeysHandler(w, apiKeyRequest(http.MethodGet, "/users/api-keys?all=true", "alice", models.RoleReadWrite))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	revoke := func(id, username string, role models.Role) int {
+		w := httptest.NewRecorder()
+		h.RevokeAPIKeyHandler(w, withURLParams(apiKeyRequest(http.MethodDelete, "/", username, role), "id", id))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, revoke(other.ID, "alice", models.RoleReadWrite))
+	assert.Equal(t, http.StatusOK, revoke(own.ID, "alice", models.RoleReadWrite))
+	assert.Equal(t, http.StatusNotFound, revoke(own.ID, "alice", models.RoleReadWrite))
+	assert.Equal(t, http.StatusOK, revoke(other.ID, "admin", models.RoleAdmin))
+	assert.Empty(t, list(apiKeyRequest(http.MethodGet, "/users/api-keys?all=true", "admin", models.RoleAdmin)))
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	h, _ := createTestHandler()
+	keys := h.apiKeyService.(*mocks.MockAPIKeyService)
+	keys.SetRole("pipeline", models.RoleReadOnly)
+	expiry := time.Now().Add(time.Hour)
+	_, secret, err := keys.Create(context.Background(), "pipeline", "export", &expiry)
+	require.NoError(t, err)
+
+	// The chain used for protected routes, ending in an endpoint requiring the given roles
+	var seen *models.User
+	endpoint := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = authmw.GetUserFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	chain := func(roles ...models.Role) http.Handler {
+		return authmw.APIKeyMiddleware(keys, h.log)(authmw.AuthMiddleware(h.authService, h.log)(authmw.RequireRole(roles...)(endpoint)))
+	}
+	call := func(handler http.Handler, header, value string) int {
+		r := httptest.NewRequest(http.MethodGet, "/sync/pull", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(chain(models.RoleReadOnly), authmw.APIKeyHeader, secret))
+	require.NotNil(t, seen)
+	assert.Equal(t, "pipeline", seen.Username)
+	assert.Equal(t, models.RoleReadOnly, seen.Role)
+
+	assert.Equal(t, http.StatusOK, call(chain(models.RoleReadOnly), "Authorization", "Bearer "+secret))
+	assert.Equal(t, http.StatusForbidden, call(chain(models.RoleAdmin), authmw.APIKeyHeader, secret))
+	assert.Equal(t, http.StatusUnauthorized, call(chain(models.RoleReadOnly), authmw.APIKeyHeader, apikey.Prefix+"unknown"))
+	assert.Equal(t, http.StatusUnauthorized, call(chain(models.RoleReadOnly), "", ""))
+}
@@ -14,13 +14,23 @@ import (
 	"github.com/opendataensemble/synkronus/pkg/appbundle"
 )
 
-// GetAppBundleManifest handles the /app-bundle/manifest endpoint
+// GetAppBundleManifest handles the /app-bundle/manifest endpoint. Clients on the preview channel,
+// named by the client_id query parameter, get the manifest of the preview version.
 func (h *Handler) GetAppBundleManifest(w http.ResponseWriter, r *http.Request) {
 	h.log.Info("App bundle manifest requested")
 	ctx := r.Context()
 
 	// Get the manifest from the service
-	manifest, err := h.appBundleService.GetManifest(ctx)
+	preview := h.onPreviewChannel(r)
+	var (
+		manifest *appbundle.Manifest
+		err      error
+	)
+	if preview {
+		manifest, err = h.appBundleService.GetPreviewManifest(ctx)
+	} else {
+		manifest, err = h.appBundleService.GetManifest(ctx)
+	}
 	if err != nil {
 		h.log.Error("Failed to get app bundle manifest", "error", err)
 		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app bundle manifest")
@@ -34,13 +44,72 @@ func (h *Handler) GetAppBundleManifest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set ETag header
+	// Set ETag header; caches in front of the server must revalidate, as a version switch changes the manifest
 	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if preview {
+		w.Header().Set("x-is-preview", "true")
+	}
 
 	// Send the response
 	SendJSONResponse(w, http.StatusOK, manifest)
 }
 
+// immutableCacheControl lets shared caches such as a CDN keep content-hashed files for a year
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// GetAppBundleHashedFile handles the /app-bundle/files/{hash}/* endpoint. The hash pins the file
+// content, so responses never change and may be cached indefinitely. Files of the current
+// version and of the preview version resolve. Once a version switch changes a file, its old URL
+// stops resolving and the manifest lists the new one.
+func (h *Handler) GetAppBundleHashedFile(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	filePath, escapeErr := url.PathUnescape(chi.URLParam(r, "*"))
+	if escapeErr != nil {
+		SendErrorResponse(w, http.StatusBadRequest, escapeErr, "Invalid file path encoding")
+		return
+	}
+	if hash == "" || filePath == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "File hash and path are required")
+		return
+	}
+
+	file, fileInfo, err := h.appBundleService.GetFile(r.Context(), filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, appbundle.ErrFileNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "File not found")
+		} else {
+			h.log.Error("Failed to get file from app bundle", "error", err, "path", filePath)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get file")
+		}
+		return
+	}
+	if !strings.EqualFold(fileInfo.Hash, hash) {
+		file.Close()
+		// Preview manifests list files of the preview version
+		file, fileInfo, err = h.appBundleService.GetPreviewFile(r.Context(), filePath)
+		if err == nil && !strings.EqualFold(fileInfo.Hash, hash) {
+			file.Close()
+			err = appbundle.ErrFileNotFound
+		}
+		if err != nil {
+			SendErrorResponse(w, http.StatusNotFound, appbundle.ErrFileNotFound, "File version is no longer active")
+			return
+		}
+	}
+
+	etag := fmt.Sprintf("\"%s\"", fileInfo.Hash)
+	w.Header().Set("Cache-Control", immutableCacheControl)
+	if match := r.Header.Get("If-None-Match"); match == etag || match == "*" {
+		file.Close()
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.streamFile(w, file, fileInfo)
+}
+
 // GetAppBundleFile handles the /app-bundle/{path} endpoint
 func (h *Handler) GetAppBundleFile(w http.ResponseWriter, r *http.Request) {
 	// Get and decode the file path from the URL
@@ -66,6 +135,11 @@ func (h *Handler) GetAppBundleFile(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.serveAppBundleFile(w, r, filePath, preview || h.onPreviewChannel(r))
+}
+
+// serveAppBundleFile streams a file of the preview or active bundle version
+func (h *Handler) serveAppBundleFile(w http.ResponseWriter, r *http.Request, filePath string, preview bool) {
 	var (
 		file     io.ReadCloser
 		fileInfo *appbundle.File
@@ -74,7 +148,7 @@ func (h *Handler) GetAppBundleFile(w http.ResponseWriter, r *http.Request) {
 
 	// Get the file from either the preview version or the active version
 	if preview {
-		file, fileInfo, err = h.appBundleService.GetLatestVersionFile(r.Context(), filePath)
+		file, fileInfo, err = h.appBundleService.GetPreviewFile(r.Context(), filePath)
 	} else {
 		file, fileInfo, err = h.appBundleService.GetFile(r.Context(), filePath)
 	}
@@ -95,6 +169,7 @@ func (h *Handler) GetAppBundleFile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", fileInfo.MimeType)
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size))
 	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
 	if preview {
 		w.Header().Set("x-is-preview", "true")
 	}
@@ -136,21 +211,33 @@ func (h *Handler) CompareAppBundleVersions(w http.ResponseWriter, r *http.Reques
 	// Get the current version
 	currentVersion := r.URL.Query().Get("current")
 	if currentVersion == "" {
-		// If no current version is specified, use the latest released version
+		// If no current version is specified, use the active version, or else the newest one
 		versions, err := h.appBundleService.GetVersions(ctx)
 		if err != nil || len(versions) == 0 {
 			h.log.Error("Failed to get current version", "error", err)
 			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get current version")
 			return
 		}
+		currentVersion = versions[0]
+		for _, v := range versions {
+			if strings.HasSuffix(v, " *") {
+				currentVersion = v
+				break
+			}
+		}
 		// Remove asterisk suffix if present
-		currentVersion = strings.TrimSuffix(versions[len(versions)-1], " *")
+		currentVersion = strings.TrimSuffix(currentVersion, " *")
 	}
 
-	// Determine the target version (preview or previous)
-	targetVersion := "latest"
-	if !preview {
-		// If not preview, compare with the previous version
+	// Determine the target version (explicit, preview or previous)
+	targetVersion := r.URL.Query().Get("target")
+	switch {
+	case targetVersion != "":
+		// Compare the two requested versions
+	case preview:
+		targetVersion = "latest"
+	default:
+		// Otherwise compare with the previous version
 		versions, err := h.appBundleService.GetVersions(ctx)
 		if err != nil {
 			h.log.Error("Failed to get versions", "error", err)
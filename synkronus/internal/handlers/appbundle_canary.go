@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/canary"
+)
+
+// RunAppBundleCanary handles POST /app-bundle/canary (admin only), replaying the latest
+// observations of each form type against the form schemas of a version before it is promoted.
+// The version defaults to the one staged for preview; the report lists the records it would
+// reject that the active version accepts.
+func (h *Handler) RunAppBundleCanary(w http.ResponseWriter, r *http.Request) {
+	var req canary.Request
+	// The body is optional; an empty one validates the staged preview with the defaults
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	report, err := h.canaryService.Run(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, canary.ErrInvalidRequest):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		case errors.Is(err, canary.ErrNoVersion):
+			SendErrorResponse(w, http.StatusConflict, err, "No version given and none is staged for preview")
+		case errors.Is(err, appbundle.ErrVersionNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "App bundle version not found")
+		default:
+			h.log.Error("Failed to run app bundle canary", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to run app bundle canary")
+		}
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, report)
+}
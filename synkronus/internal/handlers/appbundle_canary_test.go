@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/canary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCanary(h *Handler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.RunAppBundleCanary(w, httptest.NewRequest(http.MethodPost, "/app-bundle/canary", bytes.NewBufferString(body)))
+	return w
+}
+
+func TestRunAppBundleCanary_DefaultsToStagedPreview(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := runCanary(h, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report canary.Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, "2", report.Version)
+	assert.Equal(t, 1, report.NewlyFailing)
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, "maximum", report.Failures[0].Violations[0].Rule)
+
+	mock := h.canaryService.(*mocks.MockCanaryService)
+	assert.Equal(t, canary.DefaultSampleSize, mock.LastRequest.SampleSize)
+	assert.Equal(t, canary.DefaultDays, mock.LastRequest.Days)
+}
+
+func TestRunAppBundleCanary_Errors(t *testing.T) {
+	h, _ := createTestHandler()
+
+	assert.Equal(t, http.StatusBadRequest, runCanary(h, `{"sample_size": 100000}`).Code)
+	assert.Equal(t, http.StatusBadRequest, runCanary(h, `{"version": `).Code)
+	assert.Equal(t, http.StatusNotFound, runCanary(h, `{"version": "9"}`).Code)
+
+	h.canaryService.(*mocks.MockCanaryService).Staged = ""
+	assert.Equal(t, http.StatusConflict, runCanary(h, `{}`).Code)
+	assert.Equal(t, http.StatusOK, runCanary(h, `{"version": "1", "form_type": "survey"}`).Code)
+}
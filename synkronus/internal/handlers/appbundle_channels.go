@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/bundlechannel"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
+)
+
+// AssignChannelRequest represents the request body for assigning a client to an app bundle channel
+type AssignChannelRequest struct {
+	Channel string `json:"channel"`
+}
+
+// AppBundleChannelsResponse lists the staged preview version and the clients receiving it
+type AppBundleChannelsResponse struct {
+	// PreviewVersion is the version staged for preview, empty when none is staged
+	PreviewVersion string                     `json:"preview_version"`
+	Assignments    []bundlechannel.Assignment `json:"assignments"`
+}
+
+// onPreviewChannel reports whether the client named by the client_id query parameter is
+// assigned to the preview channel. Failures to look up the channel fall back to stable.
+func (h *Handler) onPreviewChannel(r *http.Request) bool {
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" || h.bundleChannelService == nil {
+		return false
+	}
+	channel, err := h.bundleChannelService.Channel(r.Context(), clientID)
+	if err != nil {
+		h.log.Warn("Failed to get app bundle channel; serving stable", "error", err, "clientId", clientID)
+		return false
+	}
+	return channel == bundlechannel.Preview
+}
+
+// PromoteAppBundlePreview handles POST /app-bundle/promote (admin only), making the staged
+// preview version the current one for every device
+func (h *Handler) PromoteAppBundlePreview(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	version, err := h.appBundleService.PromotePreview(r.Context())
+	if err != nil {
+		if errors.Is(err, appbundle.ErrNoPreview) {
+			SendErrorResponse(w, http.StatusConflict, err, "No app bundle version is staged for preview")
+			return
+		}
+		h.log.Error("Failed to promote app bundle preview", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to promote app bundle preview")
+		return
+	}
+
+	h.log.Info("App bundle preview promoted", "version", version, "user", user.Username)
+	h.recordAudit(r, audit.Entry{Action: audit.ActionBundleSwitch, Resource: "app-bundle/" + version})
+	h.notifyWebhooks(r, webhook.Payload{
+		Event:  webhook.EventBundleActivated,
+		Bundle: &webhook.BundleEvent{Version: version, ActivatedBy: user.Username},
+	})
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"message": "Promoted app bundle version " + version,
+		"version": version,
+	})
+}
+
+// ListAppBundleChannels handles GET /app-bundle/channels (admin only)
+func (h *Handler) ListAppBundleChannels(w http.ResponseWriter, r *http.Request) {
+	if h.bundleChannelService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "App bundle channels are not available")
+		return
+	}
+
+	preview, err := h.appBundleService.GetStagedPreview(r.Context())
+	if err != nil {
+		h.log.Error("Failed to get staged preview version", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get staged preview version")
+		return
+	}
+	assignments, err := h.bundleChannelService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list app bundle channels", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list app bundle channels")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, AppBundleChannelsResponse{PreviewVersion: preview, Assignments: assignments})
+}
+
+// AssignAppBundleChannel handles PUT /app-bundle/channels/{clientId} (admin only). Assigning
+// stable removes the client's assignment.
+func (h *Handler) AssignAppBundleChannel(w http.ResponseWriter, r *http.Request) {
+	if h.bundleChannelService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "App bundle channels are not available")
+		return
+	}
+
+	var req AssignChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	assignedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		assignedBy = user.Username
+	}
+
+	clientID := chi.URLParam(r, "clientId")
+	assignment, err := h.bundleChannelService.Assign(r.Context(), clientID, req.Channel, assignedBy)
+	if err != nil {
+		if errors.Is(err, bundlechannel.ErrInvalidChannel) || errors.Is(err, bundlechannel.ErrInvalidClientID) {
+			SendErrorResponse(w, http.StatusBadRequest, err, "Channel must be stable or preview, for a client ID of up to 255 characters")
+			return
+		}
+		h.log.Error("Failed to assign app bundle channel", "error", err, "clientId", clientID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to assign app bundle channel")
+		return
+	}
+	if assignment == nil {
+		assignment = &bundlechannel.Assignment{ClientID: clientID, Channel: bundlechannel.Stable, AssignedBy: assignedBy}
+	}
+	SendJSONResponse(w, http.StatusOK, assignment)
+}
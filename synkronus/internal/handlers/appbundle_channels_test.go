@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/bundlechannel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppBundlePreviewChannel(t *testing.T) {
+	h, bundles := createTestHandler()
+	admin := func(r *http.Request) *http.Request { return withRole(r, "admin", models.RoleAdmin) }
+	manifestFor := func(clientID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.GetAppBundleManifest(w, httptest.NewRequest(http.MethodGet, "/app-bundle/manifest?client_id="+clientID, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	// Pushing with ?preview=true stages the new version
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("bundle", "bundle.zip")
+	require.NoError(t, err)
+	part.Write([]byte("zip"))
+	require.NoError(t, writer.Close())
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/push?preview=true", body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.PushAppBundle(w, admin(r))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"preview_version":"1.0.0"`)
+	require.NoError(t, bundles.SwitchVersion(r.Context(), "0.9.0"))
+
+	// Only the assigned test device gets the preview
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/app-bundle/channels/tablet-1", bytes.NewBufferString(`{"channel":"preview"}`))
+	h.AssignAppBundleChannel(w, withURLParams(admin(r), "clientId", "tablet-1"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var assignment bundlechannel.Assignment
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&assignment))
+	assert.Equal(t, "admin", assignment.AssignedBy)
+
+	w = manifestFor("tablet-1")
+	assert.Equal(t, "true", w.Header().Get("x-is-preview"))
+	var manifest appbundle.Manifest
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&manifest))
+	assert.Equal(t, "1.0.0", manifest.Version)
+
+	w = manifestFor("production-1")
+	assert.Empty(t, w.Header().Get("x-is-preview"))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&manifest))
+	assert.Equal(t, "0.9.0", manifest.Version)
+
+	w = httptest.NewRecorder()
+	h.ListAppBundleChannels(w, admin(httptest.NewRequest(http.MethodGet, "/app-bundle/channels", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var channels AppBundleChannelsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&channels))
+	assert.Equal(t, "1.0.0", channels.PreviewVersion)
+	require.Len(t, channels.Assignments, 1)
+	assert.Equal(t, "tablet-1", channels.Assignments[0].ClientID)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/app-bundle/channels/tablet-1", bytes.NewBufferString(`{"channel":"beta"}`))
+	h.AssignAppBundleChannel(w, withURLParams(admin(r), "clientId", "tablet-1"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Promoting makes the preview current for everyone
+	w = httptest.NewRecorder()
+	h.PromoteAppBundlePreview(w, admin(httptest.NewRequest(http.MethodPost, "/app-bundle/promote", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = manifestFor("production-1")
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&manifest))
+	assert.Equal(t, "1.0.0", manifest.Version)
+
+	w = httptest.NewRecorder()
+	h.PromoteAppBundlePreview(w, admin(httptest.NewRequest(http.MethodPost, "/app-bundle/promote", nil)))
+	assert.Equal(t, http.StatusConflict, w.Code, "nothing is staged after a promotion")
+}
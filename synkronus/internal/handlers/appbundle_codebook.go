@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/codebook"
+)
+
+// GetAppBundleCodebook handles GET /app-bundle/codebook, rendering the forms of an app bundle
+// version as a printable codebook with choice labels and skip logic. The version defaults to
+// the active one; form limits the codebook to one form, and format is html (default) or pdf.
+func (h *Handler) GetAppBundleCodebook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "format must be html or pdf")
+		return
+	}
+
+	versions, err := h.appBundleService.GetVersions(ctx)
+	if err != nil {
+		h.log.Error("Failed to get app bundle versions", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app bundle versions")
+		return
+	}
+	version := query.Get("version")
+	found := false
+	for _, v := range versions {
+		active := strings.HasSuffix(v, " *")
+		v = strings.TrimSuffix(v, " *")
+		if version == "" && active {
+			version = v
+		}
+		found = found || v == version
+	}
+	if version == "" {
+		SendErrorResponse(w, http.StatusNotFound, nil, "No app bundle version is active")
+		return
+	}
+	if !found {
+		SendErrorResponse(w, http.StatusNotFound, appbundle.ErrVersionNotFound, "App bundle version not found")
+		return
+	}
+
+	appInfo, err := h.appBundleService.GetAppInfo(ctx, version)
+	if err != nil {
+		h.log.Error("Failed to get app info", "error", err, "version", version)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app info")
+		return
+	}
+	formTypes := make([]string, 0, len(appInfo.Forms))
+	for formType := range appInfo.Forms {
+		if form := query.Get("form"); form == "" || form == formType {
+			formTypes = append(formTypes, formType)
+		}
+	}
+	if len(formTypes) == 0 && query.Get("form") != "" {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Form not found in app bundle version")
+		return
+	}
+	sort.Strings(formTypes)
+
+	book := &codebook.Codebook{Version: version, GeneratedAt: time.Now().UTC()}
+	for _, formType := range formTypes {
+		var schema, ui map[string]any
+		if err := h.readBundleJSON(r, version, "forms/"+formType+"/schema.json", &schema); err != nil {
+			h.log.Error("Failed to read form schema", "error", err, "version", version, "form", formType)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to read form "+formType)
+			return
+		}
+		// Forms without a UI schema still list their variables
+		if err := h.readBundleJSON(r, version, "forms/"+formType+"/ui.json", &ui); err != nil && !errors.Is(err, appbundle.ErrFileNotFound) {
+			h.log.Error("Failed to read form UI schema", "error", err, "version", version, "form", formType)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to read form "+formType)
+			return
+		}
+		book.Forms = append(book.Forms, codebook.BuildForm(formType, schema, ui))
+	}
+
+	// Render before writing the header so a failure can still be reported with a status
+	var buf bytes.Buffer
+	write, contentType := codebook.WriteHTML, "text/html; charset=utf-8"
+	if format == "pdf" {
+		write, contentType = codebook.WritePDF, "application/pdf"
+		w.Header().Set("Content-Disposition", `attachment; filename="codebook-`+version+`.pdf"`)
+	}
+	if err := write(&buf, book); err != nil {
+		h.log.Error("Failed to render codebook", "error", err, "version", version)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to render codebook")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.log.Error("Failed to send codebook", "error", err, "version", version)
+	}
+}
+
+// readBundleJSON decodes a JSON file of an app bundle version
+func (h *Handler) readBundleJSON(r *http.Request, version, path string, v any) error {
+	file, _, err := h.appBundleService.GetVersionFile(r.Context(), version, path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAppBundleCodebook(t *testing.T) {
+	h, bundle := createTestHandler()
+	bundle.AddFile("forms/visit/schema.json", []byte(`{"title":"Clinic visit","properties":{
+		"outcome":{"type":"string","oneOf":[{"const":"1","title":"Recovered"},{"const":"2","title":"Referred"}]},
+		"referral_site":{"type":"string","x-visible-if":"${outcome} = '2'"}}}`), "application/json", time.Now())
+	bundle.AddFile("forms/visit/ui.json", []byte(`{"type":"VerticalLayout","elements":[
+		{"type":"Control","scope":"#/properties/outcome"},{"type":"Control","scope":"#/properties/referral_site"}]}`), "application/json", time.Now())
+	bundle.AddFile("forms/household/schema.json", []byte(`{"properties":{"head":{"type":"string"}}}`), "application/json", time.Now())
+
+	w := httptest.NewRecorder()
+	h.GetAppBundleCodebook(w, httptest.NewRequest(http.MethodGet, "/app-bundle/codebook?version=20250102-000000", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	page := w.Body.String()
+	assert.Contains(t, page, "Clinic visit")
+	assert.Contains(t, page, "<code>2</code> Referred")
+	assert.Contains(t, page, "${outcome} = &#39;2&#39;")
+	assert.Contains(t, page, "household", "forms without a UI schema are listed")
+
+	w = httptest.NewRecorder()
+	h.GetAppBundleCodebook(w, httptest.NewRequest(http.MethodGet, "/app-bundle/codebook?version=20250102-000000&form=visit&format=pdf", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="codebook-20250102-000000.pdf"`, w.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, w.Body.String(), "(2 = Referred)")
+	assert.NotContains(t, w.Body.String(), "household")
+}
+
+func TestGetAppBundleCodebook_InvalidRequests(t *testing.T) {
+	h, bundle := createTestHandler()
+	bundle.AddFile("forms/visit/schema.json", []byte(`{"properties":{}}`), "application/json", time.Now())
+
+	for path, want := range map[string]int{
+		"/app-bundle/codebook":                                      http.StatusNotFound, // no version is active
+		"/app-bundle/codebook?version=19990101-000000":              http.StatusNotFound,
+		"/app-bundle/codebook?version=20250102-000000&form=unknown": http.StatusNotFound,
+		"/app-bundle/codebook?version=20250102-000000&format=docx":  http.StatusBadRequest,
+		"/app-bundle/codebook?version=20250102-000000&format=pdf":   http.StatusOK,
+		"/app-bundle/codebook?version=20250102-000000&form=visit":   http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		h.GetAppBundleCodebook(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+)
+
+// versionCompatibility is the compatibility of one app bundle version in a compatibility matrix
+type versionCompatibility struct {
+	*appbundle.Compatibility
+	Active bool `json:"active"`
+}
+
+// GetAppBundleCompatibility handles GET /app-bundle/compatibility, listing the mobile app
+// version each app bundle version needs, from its bundle.json and the question types its forms
+// use. With app_version, each version also tells whether that app version runs it, so the app
+// can check before adopting a new bundle; version limits the list to one version.
+func (h *Handler) GetAppBundleCompatibility(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	appVersion := query.Get("app_version")
+	if appVersion != "" && !appbundle.ValidAppVersion(appVersion) {
+		SendErrorResponse(w, http.StatusBadRequest, appbundle.ErrInvalidAppVersion, "app_version must be dotted numbers such as 1.4.2")
+		return
+	}
+
+	versions, err := h.appBundleService.GetVersions(ctx)
+	if err != nil {
+		h.log.Error("Failed to get app bundle versions", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app bundle versions")
+		return
+	}
+
+	matrix := make([]versionCompatibility, 0, len(versions))
+	for _, v := range versions {
+		active := strings.HasSuffix(v, " *")
+		v = strings.TrimSuffix(v, " *")
+		if version := query.Get("version"); version != "" && version != v {
+			continue
+		}
+
+		appInfo, err := h.appBundleService.GetAppInfo(ctx, v)
+		if err != nil {
+			h.log.Error("Failed to get app info", "error", err, "version", v)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app info")
+			return
+		}
+		compatibility, err := appInfo.Compatibility(appVersion)
+		if err != nil {
+			h.log.Error("Failed to check app bundle compatibility", "error", err, "version", v)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to check app bundle compatibility")
+			return
+		}
+		// The version is the one asked about, whatever APP_INFO.json of older versions holds
+		compatibility.Version = v
+		matrix = append(matrix, versionCompatibility{Compatibility: compatibility, Active: active})
+	}
+	if query.Get("version") != "" && len(matrix) == 0 {
+		SendErrorResponse(w, http.StatusNotFound, appbundle.ErrVersionNotFound, "App bundle version not found")
+		return
+	}
+
+	response := map[string]any{"versions": matrix}
+	if appVersion != "" {
+		response["app_version"] = appVersion
+	}
+	SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAppBundleCompatibility(t *testing.T) {
+	h, bundle := createTestHandler()
+	bundle.AddFile("forms/household/schema.json", []byte(`{"properties":{}}`), "application/json", time.Now())
+	bundle.Requirements = map[string]*appbundle.BundleMetadata{
+		"20250102-000000": {MinAppVersion: "1.6.0"},
+	}
+
+	get := func(path string) (*httptest.ResponseRecorder, []versionCompatibility) {
+		w := httptest.NewRecorder()
+		h.GetAppBundleCompatibility(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Versions []versionCompatibility `json:"versions"`
+		}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+		}
+		return w, body.Versions
+	}
+
+	w, matrix := get("/app-bundle/compatibility")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, matrix, 2)
+	assert.Equal(t, "20250101-000000", matrix[0].Version)
+	assert.Empty(t, matrix[0].MinAppVersion)
+	assert.Equal(t, "1.6.0", matrix[1].MinAppVersion)
+	assert.Nil(t, matrix[1].Compatible)
+
+	w, matrix = get("/app-bundle/compatibility?app_version=1.5.3&version=20250102-000000")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, matrix, 1)
+	require.NotNil(t, matrix[0].Compatible)
+	assert.False(t, *matrix[0].Compatible)
+	assert.Equal(t, []appbundle.Requirement{{MinAppVersion: "1.6.0"}}, matrix[0].Unmet)
+
+	w, matrix = get("/app-bundle/compatibility?app_version=1.6&version=20250102-000000")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, *matrix[0].Compatible)
+
+	w, _ = get("/app-bundle/compatibility?app_version=newest")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/app-bundle/compatibility?version=19990101-000000")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+)
+
+// GetAppBundleDiff handles GET /app-bundle/diff, sending a ZIP of only the files that changed
+// since the manifest a device has, named by its hash in the from query parameter or the
+// If-None-Match header. An unknown hash, e.g. of a version removed since, gets every file.
+// Devices on the preview channel get the diff to the preview version.
+func (h *Handler) GetAppBundleDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = strings.Trim(strings.TrimPrefix(r.Header.Get("If-None-Match"), "W/"), `"`)
+	}
+
+	preview := h.onPreviewChannel(r)
+	var (
+		manifest *appbundle.Manifest
+		err      error
+	)
+	if preview {
+		manifest, err = h.appBundleService.GetPreviewManifest(ctx)
+	} else {
+		manifest, err = h.appBundleService.GetManifest(ctx)
+	}
+	if err != nil {
+		h.log.Error("Failed to get app bundle manifest", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app bundle manifest")
+		return
+	}
+	if from == manifest.Hash {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var base *appbundle.Manifest
+	if from != "" {
+		base, err = h.appBundleService.FindManifest(ctx, from)
+		if err != nil && !errors.Is(err, appbundle.ErrVersionNotFound) {
+			h.log.Error("Failed to find app bundle manifest", "error", err, "hash", from)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get app bundle diff")
+			return
+		}
+	}
+	diff := appbundle.DiffManifests(base, manifest)
+	h.log.Info("App bundle diff requested", "from", diff.FromVersion, "to", diff.Version, "changed", len(diff.Changed), "removed", len(diff.Removed))
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="app-bundle-`+diff.Version+`-diff.zip"`)
+	w.Header().Set("ETag", fmt.Sprintf("\"%s\"", manifest.Hash))
+	w.Header().Set("Cache-Control", "no-cache")
+	if preview {
+		w.Header().Set("x-is-preview", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := h.appBundleService.WriteDiff(ctx, diff, w); err != nil {
+		h.log.Error("Failed to send app bundle diff", "error", err, "version", diff.Version)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAppBundleDiff(t *testing.T) {
+	h, bundles := createTestHandler()
+	bundles.History = []*appbundle.Manifest{{
+		Version: "0.9.0",
+		Hash:    "old-manifest-hash",
+		Files: []appbundle.File{
+			{Path: "index.html", Hash: "mock-hash-index.html"},
+			{Path: "styles.css", Hash: "old-hash-styles.css"},
+			{Path: "old.js", Hash: "old-hash-old.js"},
+		},
+	}}
+	diffFrom := func(url, ifNoneMatch string) (*httptest.ResponseRecorder, appbundle.Diff, []string) {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.GetAppBundleDiff(w, r)
+		var diff appbundle.Diff
+		var names []string
+		if w.Code != http.StatusOK {
+			return w, diff, names
+		}
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		for _, file := range archive.File {
+			names = append(names, file.Name)
+			if file.Name == appbundle.DiffFile {
+				src, err := file.Open()
+				require.NoError(t, err)
+				require.NoError(t, json.NewDecoder(src).Decode(&diff))
+				src.Close()
+			}
+		}
+		return w, diff, names
+	}
+
+	// Only the files changed since the device's version are sent
+	w, diff, names := diffFrom("/app-bundle/diff?from=old-manifest-hash", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"mock-manifest-hash"`, w.Header().Get("ETag"))
+	assert.ElementsMatch(t, []string{appbundle.DiffFile, "styles.css", "app.js"}, names)
+	assert.Equal(t, "0.9.0", diff.FromVersion)
+	assert.Equal(t, "mock-manifest-hash", diff.To)
+	assert.Equal(t, []string{"old.js"}, diff.Removed)
+
+	// The manifest ETag works as well
+	_, diff, _ = diffFrom("/app-bundle/diff", `"old-manifest-hash"`)
+	assert.Equal(t, "old-manifest-hash", diff.From)
+
+	// An unknown manifest gets every file
+	w, diff, names = diffFrom("/app-bundle/diff?from=removed-version-hash", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, diff.From)
+	assert.ElementsMatch(t, []string{appbundle.DiffFile, "index.html", "styles.css", "app.js"}, names)
+
+	// A device on the current version has nothing to download
+	w, _, _ = diffFrom("/app-bundle/diff", `"mock-manifest-hash"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// previewEntryFile is served for a preview link without a file path
+const previewEntryFile = "index.html"
+
+// PreviewTokenRequest represents the optional request body for minting a preview token
+type PreviewTokenRequest struct {
+	// ExpiresInMinutes is the token lifetime; zero selects the default of one day
+	ExpiresInMinutes int `json:"expiresInMinutes"`
+}
+
+// PreviewTokenResponse represents a minted preview token and the link to share
+type PreviewTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expiresAt"`
+	URL       string `json:"url"`
+}
+
+// CreatePreviewToken handles POST /app-bundle/preview-tokens (admin only)
+func (h *Handler) CreatePreviewToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	var req PreviewTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+			return
+		}
+	}
+	if req.ExpiresInMinutes < 0 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "expiresInMinutes must not be negative")
+		return
+	}
+
+	token, expiresAt, err := h.authService.GeneratePreviewToken(user.Username, time.Duration(req.ExpiresInMinutes)*time.Minute)
+	if err != nil {
+		h.log.Error("Failed to generate preview token", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to generate preview token")
+		return
+	}
+
+	h.log.Info("Preview token issued", "username", user.Username, "expiresAt", expiresAt)
+	SendJSONResponse(w, http.StatusCreated, PreviewTokenResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.Unix(),
+		URL:       "/preview/" + url.PathEscape(token) + "/" + previewEntryFile,
+	})
+}
+
+// GetPreviewFile handles GET /preview/{token}/*, serving files of the preview bundle version to
+// holders of a preview token. The token is part of the path so relative links in the bundle
+// resolve to further preview files.
+func (h *Handler) GetPreviewFile(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.authService.ValidatePreviewToken(chi.URLParam(r, "token")); err != nil {
+		h.log.Warn("Invalid preview token", "error", err)
+		SendErrorResponse(w, http.StatusUnauthorized, err, "Invalid or expired preview link")
+		return
+	}
+
+	filePath, err := url.PathUnescape(chi.URLParam(r, "*"))
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid file path encoding")
+		return
+	}
+	if filePath == "" {
+		filePath = previewEntryFile
+	}
+
+	// Previews are shared outside the team; keep them out of search engines
+	w.Header().Set("X-Robots-Tag", "noindex")
+	h.serveAppBundleFile(w, r, filePath, true)
+}
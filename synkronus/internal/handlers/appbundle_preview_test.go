@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePreviewToken(t *testing.T) {
+	h, _ := createTestHandler()
+
+	body, _ := json.Marshal(PreviewTokenRequest{ExpiresInMinutes: 90})
+	w := httptest.NewRecorder()
+	h.CreatePreviewToken(w, withRole(httptest.NewRequest(http.MethodPost, "/app-bundle/preview-tokens", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp PreviewTokenResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "mock-preview-token-for-admin", resp.Token)
+	assert.Equal(t, "/preview/mock-preview-token-for-admin/index.html", resp.URL)
+	assert.InDelta(t, time.Now().Add(90*time.Minute).Unix(), resp.ExpiresAt, 5)
+
+	// The body is optional
+	w = httptest.NewRecorder()
+	h.CreatePreviewToken(w, withRole(httptest.NewRequest(http.MethodPost, "/app-bundle/preview-tokens", nil), "admin", models.RoleAdmin))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	h.CreatePreviewToken(w, withRole(httptest.NewRequest(http.MethodPost, "/app-bundle/preview-tokens", bytes.NewBufferString(`{"expiresInMinutes":-1}`)), "admin", models.RoleAdmin))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetPreviewFile(t *testing.T) {
+	h, _ := createTestHandler()
+	r := chi.NewRouter()
+	r.Get("/preview/{token}/*", h.GetPreviewFile)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/preview/mock-preview-token-for-admin/index.html")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("x-is-preview"))
+	assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+	assert.NotEmpty(t, w.Body.Bytes())
+
+	// The entry file is served for a bare link
+	w = get("/preview/mock-preview-token-for-admin/")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get("/preview/mock-preview-token-for-admin/missing.js")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Access tokens are not preview tokens
+	w = get("/preview/adminToken/index.html")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
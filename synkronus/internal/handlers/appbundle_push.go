@@ -1,15 +1,22 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/audit"
 	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
 )
 
-// PushAppBundle handles the /app-bundle/push endpoint
+// PushAppBundle handles the /app-bundle/push endpoint. With ?preview=true the new version is
+// also staged for devices on the preview channel. A base64 Ed25519 signature of the bundle may
+// be sent as the "signature" form field.
 func (h *Handler) PushAppBundle(w http.ResponseWriter, r *http.Request) {
 	h.log.Info("App bundle push requested")
 	ctx := r.Context()
@@ -22,8 +29,18 @@ func (h *Handler) PushAppBundle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse bodies larger than the largest bundle before they are spooled to disk
+	if h.config.AppBundleMaxSizeMB > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(h.config.AppBundleMaxSizeMB)<<20+1<<20)
+	}
+
 	// Check if the request is a multipart form
 	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32MB max
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			SendErrorResponse(w, http.StatusRequestEntityTooLarge, err, h.bundleTooLargeMessage())
+			return
+		}
 		h.log.Error("Failed to parse multipart form", "error", err)
 		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format. Expected multipart form with a 'bundle' file")
 		return
@@ -41,20 +58,59 @@ func (h *Handler) PushAppBundle(w http.ResponseWriter, r *http.Request) {
 	// Log the upload
 	h.log.Info("Processing app bundle upload", "filename", header.Filename, "size", header.Size, "user", user.Username)
 
-	// Push the bundle
-	manifest, err := h.appBundleService.PushBundle(ctx, file)
+	h.pushAppBundle(w, r, user, file, r.FormValue("signature"))
+}
+
+// bundleTooLargeMessage tells the largest app bundle zip file accepted
+func (h *Handler) bundleTooLargeMessage() string {
+	return fmt.Sprintf("App bundles larger than %d MB are not accepted", h.config.AppBundleMaxSizeMB)
+}
+
+// pushAppBundle pushes a bundle and writes the response, staging the new version for preview
+// with ?preview=true. It is shared by PushAppBundle and CompleteAppBundleUpload, and reports
+// whether a new version was stored.
+func (h *Handler) pushAppBundle(w http.ResponseWriter, r *http.Request, user *models.User, bundle io.Reader, signature string) bool {
+	ctx := r.Context()
+
+	// Push the bundle, verifying its signature when one is sent or signing keys are configured
+	manifest, err := h.appBundleService.PushBundle(ctx, bundle, signature)
 	if err != nil {
+		if errors.Is(err, appbundle.ErrSignatureRequired) || errors.Is(err, appbundle.ErrInvalidSignature) ||
+			errors.Is(err, appbundle.ErrInvalidInclude) {
+			h.log.Warn("Refused app bundle push", "error", err, "user", user.Username)
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return false
+		}
+		if errors.Is(err, appbundle.ErrBundleTooLarge) {
+			h.log.Warn("Refused app bundle push", "error", err, "user", user.Username)
+			SendErrorResponse(w, http.StatusRequestEntityTooLarge, err, err.Error())
+			return false
+		}
 		h.log.Error("Failed to push app bundle", "error", err)
 		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to process app bundle")
-		return
+		return false
 	}
 
 	// Return the new manifest
 	h.log.Info("App bundle successfully pushed", "user", user.Username)
-	SendJSONResponse(w, http.StatusOK, map[string]any{
+	h.recordAudit(r, audit.Entry{Action: audit.ActionBundlePush, Resource: "app-bundle/" + manifest.Version})
+	response := map[string]any{
 		"message":  "App bundle successfully pushed",
 		"manifest": manifest,
-	})
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		if err := h.appBundleService.StagePreview(ctx, manifest.Version); err != nil {
+			h.log.Error("Failed to stage app bundle preview", "error", err, "version", manifest.Version)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "App bundle pushed but could not be staged for preview")
+			return true
+		}
+		h.log.Info("App bundle staged for preview", "version", manifest.Version, "user", user.Username)
+		response["message"] = "App bundle successfully pushed and staged for preview"
+		response["preview_version"] = manifest.Version
+	}
+	SendJSONResponse(w, http.StatusOK, response)
+	return true
 }
 
 // GetAppBundleVersions handles the /app-bundle/versions endpoint
@@ -106,6 +162,11 @@ func (h *Handler) SwitchAppBundleVersion(w http.ResponseWriter, r *http.Request)
 
 	// Return success
 	h.log.Info("App bundle version switched", "version", version)
+	h.recordAudit(r, audit.Entry{Action: audit.ActionBundleSwitch, Resource: "app-bundle/" + version})
+	h.notifyWebhooks(r, webhook.Payload{
+		Event:  webhook.EventBundleActivated,
+		Bundle: &webhook.BundleEvent{Version: version, ActivatedBy: user.Username},
+	})
 	SendJSONResponse(w, http.StatusOK, map[string]any{
 		"message": fmt.Sprintf("Switched to app bundle version %s", version),
 	})
@@ -316,3 +316,59 @@ func TestSwitchAppBundleVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestPushSignedAppBundle(t *testing.T) {
+	h, bundles := createTestHandler()
+	bundles.Signature = "c2lnbmF0dXJl"
+
+	push := func(signature string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("bundle", "bundle.zip")
+		require.NoError(t, err)
+		part.Write([]byte("zip"))
+		if signature != "" {
+			require.NoError(t, writer.WriteField("signature", signature))
+		}
+		require.NoError(t, writer.Close())
+		r := httptest.NewRequest(http.MethodPost, "/app-bundle/push", body)
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		h.PushAppBundle(w, withRole(r, "admin", models.RoleAdmin))
+		return w
+	}
+
+	w := push("")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "signature required")
+
+	w = push("b3RoZXI=")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid app bundle signature")
+
+	assert.Equal(t, http.StatusOK, push("c2lnbmF0dXJl").Code)
+}
+
+func TestPushAppBundleTooLarge(t *testing.T) {
+	h, _ := createTestHandler()
+	h.config.AppBundleMaxSizeMB = 1
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("bundle", "bundle.zip")
+	require.NoError(t, err)
+	part.Write(make([]byte, 3<<20))
+	require.NoError(t, writer.Close())
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/push", body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.PushAppBundle(w, withRole(r, "admin", models.RoleAdmin))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "larger than 1 MB")
+
+	// Chunked uploads announcing a larger bundle are refused up front
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/app-bundle/uploads", bytes.NewBufferString(`{"size":2097152}`))
+	h.CreateAppBundleUpload(w, withRole(r, "admin", models.RoleAdmin))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -287,6 +288,7 @@ func TestCompareAppBundleVersions(t *testing.T) {
 	tests := []struct {
 		name           string
 		currentVersion string
+		targetVersion  string
 		preview        string
 		expectedCode   int
 	}{
@@ -301,6 +303,12 @@ func TestCompareAppBundleVersions(t *testing.T) {
 			preview:        "true",
 			expectedCode:   http.StatusOK,
 		},
+		{
+			name:           "compare with explicit target",
+			currentVersion: "20250101-000000",
+			targetVersion:  "20250102-000000",
+			expectedCode:   http.StatusOK,
+		},
 		{
 			name:         "no current version",
 			expectedCode: http.StatusOK,
@@ -315,6 +323,9 @@ func TestCompareAppBundleVersions(t *testing.T) {
 			if tc.currentVersion != "" {
 				q.Add("current", tc.currentVersion)
 			}
+			if tc.targetVersion != "" {
+				q.Add("target", tc.targetVersion)
+			}
 			if tc.preview != "" {
 				q.Add("preview", tc.preview)
 			}
@@ -341,6 +352,45 @@ func TestCompareAppBundleVersions(t *testing.T) {
 				assert.Contains(t, respBody, "compare_version_a")
 				assert.Contains(t, respBody, "compare_version_b")
 			}
+			if tc.targetVersion != "" {
+				assert.Equal(t, tc.currentVersion, respBody["compare_version_a"])
+				assert.Equal(t, tc.targetVersion, respBody["compare_version_b"])
+			}
 		})
 	}
 }
+
+func TestGetAppBundleHashedFile(t *testing.T) {
+	h, _ := createTestHandler()
+
+	r := chi.NewRouter()
+	r.Get("/app-bundle/manifest", h.GetAppBundleManifest)
+	r.Get("/app-bundle/files/{hash}/*", h.GetAppBundleHashedFile)
+
+	// The manifest is revalidated by caches, file URLs come from it
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app-bundle/manifest", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	var manifest appbundle.Manifest
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&manifest))
+
+	var fileURL string
+	for _, f := range manifest.Files {
+		if f.Path == "index.html" {
+			fileURL = f.URL
+		}
+	}
+	require.Equal(t, "/app-bundle/files/mock-hash-index.html/index.html", fileURL)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fileURL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), "Hello World")
+
+	// A hash that is no longer active does not resolve
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app-bundle/files/old-hash/index.html", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/bundleupload"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// CreateAppBundleUploadRequest announces the size of a bundle uploaded in chunks
+type CreateAppBundleUploadRequest struct {
+	Size int64 `json:"size"`
+}
+
+// CompleteAppBundleUploadRequest carries the optional signature of a bundle uploaded in chunks
+type CompleteAppBundleUploadRequest struct {
+	Signature string `json:"signature,omitempty"`
+}
+
+// CreateAppBundleUpload handles POST /app-bundle/uploads (admin only), starting an upload that
+// is sent with PUT /app-bundle/uploads/{id} in chunks and pushed with .../complete
+func (h *Handler) CreateAppBundleUpload(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	if h.bundleUploadService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Chunked app bundle uploads are not available")
+		return
+	}
+
+	var req CreateAppBundleUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if limit := int64(h.config.AppBundleMaxSizeMB) << 20; limit > 0 && req.Size > limit {
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, nil, h.bundleTooLargeMessage())
+		return
+	}
+	upload, err := h.bundleUploadService.Create(r.Context(), req.Size, user.Username)
+	if err != nil {
+		if errors.Is(err, bundleupload.ErrInvalidUpload) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to start app bundle upload", "error", err, "user", user.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to start app bundle upload")
+		return
+	}
+
+	w.Header().Set("Location", "/app-bundle/uploads/"+upload.ID)
+	SendJSONResponse(w, http.StatusCreated, upload)
+}
+
+// GetAppBundleUpload handles GET /app-bundle/uploads/{id}; an interrupted upload resumes at the
+// received byte count
+func (h *Handler) GetAppBundleUpload(w http.ResponseWriter, r *http.Request) {
+	if h.bundleUploadService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Chunked app bundle uploads are not available")
+		return
+	}
+	upload, err := h.bundleUploadService.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendUploadError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, upload)
+}
+
+// UploadAppBundleChunk handles PUT /app-bundle/uploads/{id}. The body is the chunk and the
+// Content-Range header its place in the bundle, e.g. "bytes 0-8388607/20971520". A chunk must
+// start at the received byte count; others are refused with 409 Conflict, so a retried chunk
+// that already arrived is never stored twice.
+func (h *Handler) UploadAppBundleChunk(w http.ResponseWriter, r *http.Request) {
+	if h.bundleUploadService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Chunked app bundle uploads are not available")
+		return
+	}
+
+	start, end, total, err := parseContentRange(r.Header.Get("Content-Range"))
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Content-Range must be of the form 'bytes <start>-<end>/<size>'")
+		return
+	}
+	if end-start+1 > bundleupload.MaxChunkSize {
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, nil, "Chunks larger than 16 MB are not accepted")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, bundleupload.MaxChunkSize))
+	if err != nil {
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, err, "Chunks larger than 16 MB are not accepted")
+		return
+	}
+	if int64(len(data)) != end-start+1 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, fmt.Sprintf("Content-Range announces %d bytes but the body has %d", end-start+1, len(data)))
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	upload, err := h.bundleUploadService.Get(r.Context(), id)
+	if err != nil {
+		h.sendUploadError(w, err)
+		return
+	}
+	if total != upload.Size {
+		SendErrorResponse(w, http.StatusBadRequest, nil, fmt.Sprintf("Content-Range size %d does not match the upload size %d", total, upload.Size))
+		return
+	}
+
+	upload, err = h.bundleUploadService.Append(r.Context(), id, start, data)
+	if err != nil {
+		if errors.Is(err, bundleupload.ErrOffsetMismatch) && upload != nil {
+			SendErrorResponse(w, http.StatusConflict, err, fmt.Sprintf("%d bytes have been received; send the chunk starting there", upload.Received))
+			return
+		}
+		h.sendUploadError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, upload)
+}
+
+// CompleteAppBundleUpload handles POST /app-bundle/uploads/{id}/complete (admin only), pushing
+// the uploaded bundle like /app-bundle/push. The upload is removed once a version is stored;
+// after a refused signature it is kept, so the push can be completed with another one.
+func (h *Handler) CompleteAppBundleUpload(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	if h.bundleUploadService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Chunked app bundle uploads are not available")
+		return
+	}
+
+	var req CompleteAppBundleUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	bundle, err := h.bundleUploadService.Open(r.Context(), id)
+	if err != nil {
+		h.sendUploadError(w, err)
+		return
+	}
+	defer bundle.Close()
+
+	h.log.Info("Processing chunked app bundle upload", "id", id, "user", user.Username)
+	if !h.pushAppBundle(w, r, user, bundle, req.Signature) {
+		return
+	}
+	if err := h.bundleUploadService.Delete(r.Context(), id); err != nil {
+		h.log.Warn("Failed to remove completed app bundle upload", "error", err, "id", id)
+	}
+}
+
+// DeleteAppBundleUpload handles DELETE /app-bundle/uploads/{id}, abandoning an upload
+func (h *Handler) DeleteAppBundleUpload(w http.ResponseWriter, r *http.Request) {
+	if h.bundleUploadService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Chunked app bundle uploads are not available")
+		return
+	}
+	if err := h.bundleUploadService.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.sendUploadError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendUploadError maps chunked upload errors to responses
+func (h *Handler) sendUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, bundleupload.ErrNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Upload not found; it may have expired")
+	case errors.Is(err, bundleupload.ErrIncomplete), errors.Is(err, bundleupload.ErrOffsetMismatch):
+		SendErrorResponse(w, http.StatusConflict, err, err.Error())
+	case errors.Is(err, bundleupload.ErrInvalidUpload):
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+	default:
+		h.log.Error("App bundle upload failed", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "App bundle upload failed")
+	}
+}
+
+// parseContentRange parses a Content-Range header of the form "bytes <start>-<end>/<size>"
+func parseContentRange(value string) (start, end, total int64, err error) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, 0, errors.New("invalid Content-Range unit")
+	}
+	byteRange, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, 0, errors.New("Content-Range has no size")
+	}
+	first, last, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, 0, 0, errors.New("Content-Range has no byte range")
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range start: %w", err)
+	}
+	if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range end: %w", err)
+	}
+	if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range size: %w", err)
+	}
+	if start < 0 || end < start || end >= total {
+		return 0, 0, 0, errors.New("Content-Range is out of bounds")
+	}
+	return start, end, total, nil
+}
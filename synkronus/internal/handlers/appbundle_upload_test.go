@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/bundleupload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendChunk sends the bytes of a bundle from start to end (exclusive) as one chunk
+func sendChunk(h *Handler, id string, bundle []byte, start, end int) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPut, "/app-bundle/uploads/"+id, bytes.NewReader(bundle[start:end]))
+	r.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(bundle)))
+	w := httptest.NewRecorder()
+	h.UploadAppBundleChunk(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "id", id))
+	return w
+}
+
+func TestChunkedAppBundleUpload(t *testing.T) {
+	h, bundles := createTestHandler()
+	bundle := []byte("PK\x03\x04 a bundle sent in three chunks")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/uploads", bytes.NewBufferString(fmt.Sprintf(`{"size":%d}`, len(bundle))))
+	h.CreateAppBundleUpload(w, withRole(r, "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var upload bundleupload.Upload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&upload))
+	assert.Equal(t, "/app-bundle/uploads/"+upload.ID, w.Header().Get("Location"))
+	assert.Equal(t, "admin", upload.CreatedBy)
+
+	w = sendChunk(h, upload.ID, bundle, 0, 10)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&upload))
+	assert.Equal(t, int64(10), upload.Received)
+
+	// A retried chunk that already arrived is refused, and the upload resumes where it stopped
+	w = sendChunk(h, upload.ID, bundle, 0, 10)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "10 bytes have been received")
+
+	w = httptest.NewRecorder()
+	h.GetAppBundleUpload(w, withURLParams(httptest.NewRequest(http.MethodGet, "/app-bundle/uploads/"+upload.ID, nil), "id", upload.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&upload))
+	assert.Equal(t, int64(10), upload.Received)
+
+	require.Equal(t, http.StatusOK, sendChunk(h, upload.ID, bundle, 10, 20).Code)
+
+	// An incomplete upload cannot be pushed
+	complete := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/app-bundle/uploads/"+upload.ID+"/complete?preview=true", nil)
+		h.CompleteAppBundleUpload(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "id", upload.ID))
+		return w
+	}
+	assert.Equal(t, http.StatusConflict, complete().Code)
+
+	require.Equal(t, http.StatusOK, sendChunk(h, upload.ID, bundle, 20, len(bundle)).Code)
+	w = complete()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"preview_version":"1.0.0"`)
+	assert.Equal(t, bundle, bundles.Pushed)
+
+	// The upload is removed once pushed
+	w = httptest.NewRecorder()
+	h.GetAppBundleUpload(w, withURLParams(httptest.NewRequest(http.MethodGet, "/app-bundle/uploads/"+upload.ID, nil), "id", upload.ID))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestChunkedAppBundleUploadSignature(t *testing.T) {
+	h, bundles := createTestHandler()
+	bundles.Signature = "c2lnbmF0dXJl"
+	bundle := []byte("PK\x03\x04 signed")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/uploads", bytes.NewBufferString(fmt.Sprintf(`{"size":%d}`, len(bundle))))
+	h.CreateAppBundleUpload(w, withRole(r, "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var upload bundleupload.Upload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&upload))
+	require.Equal(t, http.StatusOK, sendChunk(h, upload.ID, bundle, 0, len(bundle)).Code)
+
+	complete := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/app-bundle/uploads/"+upload.ID+"/complete", bytes.NewBufferString(body))
+		h.CompleteAppBundleUpload(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "id", upload.ID))
+		return w
+	}
+	// A refused signature keeps the upload for another attempt
+	assert.Equal(t, http.StatusBadRequest, complete("").Code)
+	assert.Equal(t, http.StatusOK, complete(`{"signature":"c2lnbmF0dXJl"}`).Code)
+}
+
+func TestAppBundleUploadChunkValidation(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/uploads", bytes.NewBufferString(`{"size":0}`))
+	h.CreateAppBundleUpload(w, withRole(r, "admin", models.RoleAdmin))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/app-bundle/uploads", bytes.NewBufferString(`{"size":8}`))
+	h.CreateAppBundleUpload(w, withRole(r, "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var upload bundleupload.Upload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&upload))
+
+	for name, tc := range map[string]struct {
+		contentRange string
+		body         string
+		status       int
+	}{
+		"missing range":   {"", "abcd", http.StatusBadRequest},
+		"short body":      {"bytes 0-3/8", "abc", http.StatusBadRequest},
+		"wrong size":      {"bytes 0-3/9", "abcd", http.StatusBadRequest},
+		"past the end":    {"bytes 4-8/8", "abcde", http.StatusBadRequest},
+		"not at received": {"bytes 4-7/8", "efgh", http.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/app-bundle/uploads/"+upload.ID, bytes.NewBufferString(tc.body))
+			if tc.contentRange != "" {
+				r.Header.Set("Content-Range", tc.contentRange)
+			}
+			w := httptest.NewRecorder()
+			h.UploadAppBundleChunk(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "id", upload.ID))
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, "/app-bundle/uploads/"+upload.ID, nil)
+	h.DeleteAppBundleUpload(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "id", upload.ID))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNotFound, sendChunk(h, upload.ID, []byte("abcdefgh"), 0, 4).Code)
+}
@@ -5,21 +5,29 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
 	"github.com/opendataensemble/synkronus/pkg/attachment"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
 )
 
 type AttachmentHandler struct {
-	service attachment.Service
-	log     *logger.Logger
+	service  attachment.Service
+	manifest attachment.ManifestService
+	log      *logger.Logger
 }
 
-func NewAttachmentHandler(log *logger.Logger, service attachment.Service) *AttachmentHandler {
+// NewAttachmentHandler creates an attachment handler. Uploads and deletes are recorded with the
+// manifest service, when given, so devices pick them up on their next attachment sync.
+func NewAttachmentHandler(log *logger.Logger, service attachment.Service, manifest attachment.ManifestService) *AttachmentHandler {
 	return &AttachmentHandler{
-		service: service,
-		log:     log,
+		service:  service,
+		manifest: manifest,
+		log:      log,
 	}
 }
 
@@ -28,12 +36,16 @@ func (h *AttachmentHandler) RegisterRoutes(r chi.Router, manifestHandler func(ht
 	r.Route("/attachments", func(r chi.Router) {
 		// Manifest endpoint
 		r.Post("/manifest", manifestHandler)
-		
+
+		// Attachments of an observation
+		r.Get("/", h.ListAttachments)
+
 		// Individual attachment routes
 		r.Route("/{attachment_id}", func(r chi.Router) {
 			r.Put("/", h.UploadAttachment)
 			r.Get("/", h.DownloadAttachment)
 			r.Head("/", h.CheckAttachment)
+			r.With(auth.RequireRole(models.RoleReadWrite, models.RoleAdmin)).Delete("/", h.DeleteAttachment)
 		})
 	})
 }
@@ -53,9 +65,10 @@ func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Requ
 		SendErrorResponse(w, http.StatusBadRequest, err, "Failed to parse multipart form")
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
 	// Get the file from the form data
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
 		if errors.Is(err, http.ErrMissingFile) {
 			SendErrorResponse(w, http.StatusBadRequest, nil, "file is required")
@@ -66,23 +79,100 @@ func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Requ
 	}
 	defer file.Close()
 
-	// Save the attachment
-	err = h.service.Save(r.Context(), attachmentID, file)
+	options := attachment.UploadOptions{ObservationID: r.FormValue("observation_id")}
+	if contentType := header.Header.Get("Content-Type"); contentType != "application/octet-stream" {
+		options.ContentType = contentType
+	}
+
+	// Save the attachment; identical content already stored under this ID is accepted as is
+	stored, err := h.service.Upload(r.Context(), attachmentID, file, options)
 	if err != nil {
-		if os.IsExist(err) {
+		switch {
+		case os.IsExist(err):
 			SendErrorResponse(w, http.StatusConflict, err, "Attachment already exists")
-			return
+		case errors.Is(err, attachment.ErrTooLarge):
+			SendErrorResponse(w, http.StatusRequestEntityTooLarge, err, "Attachment exceeds the size limit")
+		case errors.Is(err, os.ErrInvalid):
+			SendErrorResponse(w, http.StatusBadRequest, err, "Invalid attachment_id")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to save attachment")
 		}
-		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to save attachment")
 		return
 	}
 
+	h.recordOperation(r, stored.ID, "create", stored)
+
 	// Return success response
-	SendJSONResponse(w, http.StatusOK, map[string]string{
-		"status": "success",
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"status":     "success",
+		"attachment": stored,
+	})
+}
+
+// ListAttachments handles GET /attachments?observation_id=..., listing the attachments of an
+// observation
+func (h *AttachmentHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	observationID := r.URL.Query().Get("observation_id")
+	if observationID == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "observation_id is required")
+		return
+	}
+
+	attachments, err := h.service.List(r.Context(), observationID)
+	if err != nil {
+		h.log.Error("Failed to list attachments", "error", err, "observationId", observationID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list attachments")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"observation_id": observationID,
+		"attachments":    attachments,
 	})
 }
 
+// DeleteAttachment handles DELETE /attachments/{attachment_id}
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentID := chi.URLParam(r, "attachment_id")
+	if attachmentID == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "attachment_id is required")
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), attachmentID); err != nil {
+		switch {
+		case errors.Is(err, attachment.ErrNotFound):
+			SendErrorResponse(w, http.StatusNotFound, nil, "Attachment not found")
+		case errors.Is(err, os.ErrInvalid):
+			SendErrorResponse(w, http.StatusBadRequest, err, "Invalid attachment_id")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete attachment")
+		}
+		return
+	}
+
+	h.recordOperation(r, attachmentID, "delete", nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recordOperation records an upload or delete for attachment sync. A failure is logged rather
+// than returned since the attachment itself was stored or removed.
+func (h *AttachmentHandler) recordOperation(r *http.Request, attachmentID, operation string, stored *attachment.Attachment) {
+	if h.manifest == nil {
+		return
+	}
+	var size *int
+	var contentType *string
+	if stored != nil {
+		sizeInt := int(stored.Size)
+		size = &sizeInt
+		contentType = &stored.ContentType
+	}
+	if err := h.manifest.RecordOperation(r.Context(), attachmentID, operation, "", size, contentType); err != nil {
+		h.log.Error("Failed to record attachment operation", "error", err, "attachmentId", attachmentID, "operation", operation)
+	}
+}
+
 // DownloadAttachment handles GET /attachments/{attachment_id}
 func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
 	// Get attachment ID from URL
@@ -92,14 +182,21 @@ func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check if attachment exists
-	exists, err := h.service.Exists(r.Context(), attachmentID)
+	// Look up the attachment
+	info, err := h.service.Info(r.Context(), attachmentID)
 	if err != nil {
+		if errors.Is(err, attachment.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, nil, "Attachment not found")
+			return
+		}
 		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to check attachment existence")
 		return
 	}
-	if !exists {
-		SendErrorResponse(w, http.StatusNotFound, nil, "Attachment not found")
+
+	// Devices already holding this content can skip the download
+	etag := `"` + info.Hash + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -112,8 +209,10 @@ func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Re
 	defer file.Close()
 
 	// Set headers for file download
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Disposition", "attachment; filename="+attachmentID)
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Disposition", "attachment; filename="+path.Base(attachmentID))
 
 	// Stream the file to the response
 	_, err = io.Copy(w, file)
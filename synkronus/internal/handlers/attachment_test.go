@@ -12,6 +12,8 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/attachment"
 	"github.com/opendataensemble/synkronus/pkg/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,6 +28,14 @@ func (m *mockAttachmentService) Save(ctx context.Context, attachmentID string, f
 	return args.Error(0)
 }
 
+func (m *mockAttachmentService) Upload(ctx context.Context, attachmentID string, file io.Reader, options attachment.UploadOptions) (*attachment.Attachment, error) {
+	args := m.Called(ctx, attachmentID, file, options)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*attachment.Attachment), args.Error(1)
+}
+
 func (m *mockAttachmentService) Get(ctx context.Context, attachmentID string) (io.ReadCloser, error) {
 	args := m.Called(ctx, attachmentID)
 	if args.Get(0) == nil {
@@ -34,11 +44,32 @@ func (m *mockAttachmentService) Get(ctx context.Context, attachmentID string) (i
 	return args.Get(0).(io.ReadCloser), args.Error(1)
 }
 
+func (m *mockAttachmentService) Info(ctx context.Context, attachmentID string) (*attachment.Attachment, error) {
+	args := m.Called(ctx, attachmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*attachment.Attachment), args.Error(1)
+}
+
 func (m *mockAttachmentService) Exists(ctx context.Context, attachmentID string) (bool, error) {
 	args := m.Called(ctx, attachmentID)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockAttachmentService) List(ctx context.Context, observationID string) ([]attachment.Attachment, error) {
+	args := m.Called(ctx, observationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]attachment.Attachment), args.Error(1)
+}
+
+func (m *mockAttachmentService) Delete(ctx context.Context, attachmentID string) error {
+	args := m.Called(ctx, attachmentID)
+	return args.Error(0)
+}
+
 func TestAttachmentHandler_UploadAttachment(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -51,22 +82,32 @@ func TestAttachmentHandler_UploadAttachment(t *testing.T) {
 			name:         "successful upload",
 			attachmentID: "testfile.txt",
 			setupMocks: func(mas *mockAttachmentService) {
-				mas.On("Save", mock.Anything, "testfile.txt", mock.Anything).
-					Return(nil)
+				mas.On("Upload", mock.Anything, "testfile.txt", mock.Anything, attachment.UploadOptions{ObservationID: "obs-1"}).
+					Return(&attachment.Attachment{ID: "testfile.txt", ObservationID: "obs-1", Hash: "abc", Size: 12, ContentType: "text/plain"}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"status":"success"}`,
+			expectedBody: `{"status":"success","attachment":{"id":"testfile.txt","observation_id":"obs-1","hash":"abc",` +
+				`"size":12,"content_type":"text/plain","created_at":"0001-01-01T00:00:00Z"}}`,
 		},
 		{
 			name:         "file already exists",
 			attachmentID: "existing.txt",
 			setupMocks: func(mas *mockAttachmentService) {
-				mas.On("Save", mock.Anything, "existing.txt", mock.Anything).
-					Return(os.ErrExist)
+				mas.On("Upload", mock.Anything, "existing.txt", mock.Anything, mock.Anything).
+					Return(nil, os.ErrExist)
 			},
 			expectedStatus: http.StatusConflict,
 			expectedBody:   `{"error":"file already exists", "message":"Attachment already exists"}`,
 		},
+		{
+			name:         "file too large",
+			attachmentID: "large.mp4",
+			setupMocks: func(mas *mockAttachmentService) {
+				mas.On("Upload", mock.Anything, "large.mp4", mock.Anything, mock.Anything).
+					Return(nil, attachment.ErrTooLarge)
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
 	}
 
 	for _, tc := range tests {
@@ -76,13 +117,14 @@ func TestAttachmentHandler_UploadAttachment(t *testing.T) {
 			tc.setupMocks(mockSvc)
 
 			// Create handler with mock service
-			handler := NewAttachmentHandler(logger.NewLogger(), mockSvc)
+			handler := NewAttachmentHandler(logger.NewLogger(), mockSvc, nil)
 
 			// Create a test file
 			var b bytes.Buffer
 			w := multipart.NewWriter(&b)
 			part, _ := w.CreateFormFile("file", "test.txt")
 			part.Write([]byte("test content"))
+			w.WriteField("observation_id", "obs-1")
 			w.Close()
 
 			// Create request
@@ -118,8 +160,8 @@ func TestAttachmentHandler_DownloadAttachment(t *testing.T) {
 			name:         "successful download",
 			attachmentID: "testfile.txt",
 			setupMocks: func(mas *mockAttachmentService) {
-				mas.On("Exists", mock.Anything, "testfile.txt").
-					Return(true, nil)
+				mas.On("Info", mock.Anything, "testfile.txt").
+					Return(&attachment.Attachment{ID: "testfile.txt", Hash: "abc", Size: 12, ContentType: "text/plain"}, nil)
 				mas.On("Get", mock.Anything, "testfile.txt").
 					Return(io.NopCloser(bytes.NewBufferString("file content")), nil)
 			},
@@ -130,8 +172,8 @@ func TestAttachmentHandler_DownloadAttachment(t *testing.T) {
 			name:         "file not found",
 			attachmentID: "nonexistent.txt",
 			setupMocks: func(mas *mockAttachmentService) {
-				mas.On("Exists", mock.Anything, "nonexistent.txt").
-					Return(false, nil)
+				mas.On("Info", mock.Anything, "nonexistent.txt").
+					Return(nil, attachment.ErrNotFound)
 			},
 			expectedStatus: http.StatusNotFound,
 		},
@@ -144,7 +186,7 @@ func TestAttachmentHandler_DownloadAttachment(t *testing.T) {
 			tc.setupMocks(mockSvc)
 
 			// Create handler with mock service
-			handler := NewAttachmentHandler(logger.NewLogger(), mockSvc)
+			handler := NewAttachmentHandler(logger.NewLogger(), mockSvc, nil)
 
 			// Create request
 			req := httptest.NewRequest("GET", "/attachments/"+tc.attachmentID, nil)
@@ -161,6 +203,8 @@ func TestAttachmentHandler_DownloadAttachment(t *testing.T) {
 			assert.Equal(t, tc.expectedStatus, rr.Code)
 			if tc.expectedBody != "" {
 				assert.Equal(t, tc.expectedBody, rr.Body.String())
+				assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+				assert.Equal(t, `"abc"`, rr.Header().Get("ETag"))
 			}
 		})
 	}
@@ -200,7 +244,7 @@ func TestAttachmentHandler_CheckAttachment(t *testing.T) {
 			tc.setupMocks(mockSvc)
 
 			// Create handler with mock service
-			handler := NewAttachmentHandler(logger.NewLogger(), mockSvc)
+			handler := NewAttachmentHandler(logger.NewLogger(), mockSvc, nil)
 
 			// Create request
 			req := httptest.NewRequest("HEAD", "/attachments/"+tc.attachmentID, nil)
@@ -229,10 +273,10 @@ func TestDownloadAttachment_StreamingErrorLogged(t *testing.T) {
 	log := logger.NewLogger(logger.WithOutputWriter(&buf))
 
 	mockSvc := &mockAttachmentService{}
-	mockSvc.On("Exists", mock.Anything, "badfile").Return(true, nil)
+	mockSvc.On("Info", mock.Anything, "badfile").Return(&attachment.Attachment{ID: "badfile", Hash: "abc", Size: 3}, nil)
 	mockSvc.On("Get", mock.Anything, "badfile").Return(io.NopCloser(errReader{}), nil)
 
-	handler := NewAttachmentHandler(log, mockSvc)
+	handler := NewAttachmentHandler(log, mockSvc, nil)
 
 	req := httptest.NewRequest("GET", "/attachments/badfile", nil)
 	rr := httptest.NewRecorder()
@@ -243,3 +287,70 @@ func TestDownloadAttachment_StreamingErrorLogged(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, buf.String(), "Failed to stream attachment")
 }
+
+func TestDownloadAttachment_NotModified(t *testing.T) {
+	mockSvc := &mockAttachmentService{}
+	mockSvc.On("Info", mock.Anything, "photo.jpg").Return(&attachment.Attachment{ID: "photo.jpg", Hash: "abc", Size: 3}, nil)
+
+	handler := NewAttachmentHandler(logger.NewLogger(), mockSvc, nil)
+
+	req := httptest.NewRequest("GET", "/attachments/photo.jpg", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+	rr := httptest.NewRecorder()
+	r := chi.NewRouter()
+	r.Get("/attachments/{attachment_id}", handler.DownloadAttachment)
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	mockSvc.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestAttachmentHandler_ListAttachments(t *testing.T) {
+	mockSvc := &mockAttachmentService{}
+	mockSvc.On("List", mock.Anything, "obs-1").Return([]attachment.Attachment{
+		{ID: "photo.jpg", ObservationID: "obs-1", Hash: "abc", Size: 3, ContentType: "image/jpeg"},
+	}, nil)
+
+	handler := NewAttachmentHandler(logger.NewLogger(), mockSvc, nil)
+	r := chi.NewRouter()
+	r.Get("/attachments", handler.ListAttachments)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/attachments?observation_id=obs-1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"observation_id":"obs-1","attachments":[{"id":"photo.jpg","observation_id":"obs-1","hash":"abc",`+
+		`"size":3,"content_type":"image/jpeg","created_at":"0001-01-01T00:00:00Z"}]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/attachments", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAttachmentHandler_DeleteAttachment(t *testing.T) {
+	mockSvc := &mockAttachmentService{}
+	mockSvc.On("Delete", mock.Anything, "photo.jpg").Return(nil)
+	mockSvc.On("Delete", mock.Anything, "missing.jpg").Return(attachment.ErrNotFound)
+
+	var recorded []string
+	manifest := &mocks.MockAttachmentManifestService{
+		RecordOperationFunc: func(ctx context.Context, attachmentID, operation, clientID string, size *int, contentType *string) error {
+			recorded = append(recorded, operation+" "+attachmentID)
+			return nil
+		},
+	}
+
+	handler := NewAttachmentHandler(logger.NewLogger(), mockSvc, manifest)
+	r := chi.NewRouter()
+	r.Delete("/attachments/{attachment_id}", handler.DeleteAttachment)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("DELETE", "/attachments/photo.jpg", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("DELETE", "/attachments/missing.jpg", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Only the successful delete is synced to devices
+	assert.Equal(t, []string{"delete photo.jpg"}, recorded)
+}
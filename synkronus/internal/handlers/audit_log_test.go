@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensitiveOperations_RecordAuditLog(t *testing.T) {
+	h, _ := createTestHandler()
+	auditService := h.auditService.(*mocks.MockAuditService)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/switch/0002", nil)
+	h.SwitchAppBundleVersion(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "version", "0002"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, _ := json.Marshal(UserCreateRequest{Username: "eve", Password: "secret", Role: models.RoleReadOnly})
+	w = httptest.NewRecorder()
+	h.CreateUserHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/users/create", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, "/users/delete/eve", nil)
+	h.DeleteUserHandler(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "username", "eve"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Failed operations are not logged
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, "/users/delete/nobody", nil)
+	h.DeleteUserHandler(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "username", "nobody"))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	entries := auditService.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, audit.Entry{ID: 1, Username: "admin", Role: "admin", Action: audit.ActionBundleSwitch, Resource: "app-bundle/0002", IP: "192.0.2.1", At: entries[0].At}, entries[0])
+	assert.Equal(t, audit.ActionUserCreate, entries[1].Action)
+	assert.Equal(t, "users/eve", entries[1].Resource)
+	assert.Equal(t, audit.ActionUserDelete, entries[2].Action)
+	assert.Equal(t, "users/eve", entries[2].Resource)
+}
+
+func auditLogHandler(t *testing.T) *Handler {
+	t.Helper()
+	h, _ := createTestHandler()
+	start := time.Date(2025, 9, 29, 8, 0, 0, 0, time.UTC)
+	for i, e := range []audit.Entry{
+		{Username: "admin", Action: audit.ActionUserCreate, Resource: "users/eve"},
+		{Username: "admin", Action: audit.ActionBundlePush, Resource: "app-bundle/0002"},
+		{Username: "alice", Action: audit.ActionDataExport, Resource: "dataexport/parquet?template=monthly, all"},
+	} {
+		e.At = start.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, h.auditService.Log(t.Context(), e))
+	}
+	return h
+}
+
+func TestListAuditLog(t *testing.T) {
+	h := auditLogHandler(t)
+
+	tests := []struct {
+		name              string
+		query             string
+		expectedStatus    int
+		expectedResources []string
+	}{
+		{name: "all entries", query: "", expectedStatus: http.StatusOK, expectedResources: []string{"dataexport/parquet?template=monthly, all", "app-bundle/0002", "users/eve"}},
+		{name: "by username", query: "?username=admin", expectedStatus: http.StatusOK, expectedResources: []string{"app-bundle/0002", "users/eve"}},
+		{name: "by action", query: "?action=user.create", expectedStatus: http.StatusOK, expectedResources: []string{"users/eve"}},
+		{name: "time range", query: "?from=2025-09-29T09:00:00Z&to=2025-09-29T10:00:00Z", expectedStatus: http.StatusOK, expectedResources: []string{"app-bundle/0002"}},
+		{name: "paginated", query: "?limit=1&offset=1", expectedStatus: http.StatusOK, expectedResources: []string{"app-bundle/0002"}},
+		{name: "invalid from", query: "?from=yesterday", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ListAuditLog(w, httptest.NewRequest(http.MethodGet, "/audit"+tc.query, nil))
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response AuditLogResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, len(tc.expectedResources), response.Count)
+			resources := make([]string, 0, len(response.Entries))
+			for _, e := range response.Entries {
+				resources = append(resources, e.Resource)
+			}
+			assert.Equal(t, tc.expectedResources, resources)
+		})
+	}
+}
+
+func TestExportAuditLog(t *testing.T) {
+	h := auditLogHandler(t)
+
+	w := httptest.NewRecorder()
+	h.ExportAuditLog(w, httptest.NewRequest(http.MethodGet, "/audit/export?action=data.export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"id", "at", "username", "role", "impersonator", "action", "resource", "ip"}, records[0])
+	assert.Equal(t, []string{"3", "2025-09-29T10:00:00Z", "alice", "", "", "data.export", "dataexport/parquet?template=monthly, all", ""}, records[1])
+
+	w = httptest.NewRecorder()
+	h.ExportAuditLog(w, httptest.NewRequest(http.MethodGet, "/audit/export?to=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/auth"
 )
 
 // LoginRequest represents the login request payload
@@ -47,6 +50,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	user, err := h.authService.Authenticate(r.Context(), req.Username, req.Password)
 	if err != nil {
 		h.log.Error("Authentication failed", "username", req.Username, "error", err)
+		h.recordAuthEvent(r, audit.Event{Type: audit.EventLoginFailed, Username: req.Username})
 		SendErrorResponse(w, http.StatusUnauthorized, err, "Invalid credentials")
 		return
 	}
@@ -60,7 +64,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate refresh token
-	refreshToken, err := h.authService.GenerateRefreshToken(user)
+	refreshToken, err := h.authService.GenerateRefreshToken(r.Context(), user, sessionClient(r))
 	if err != nil {
 		h.log.Error("Failed to generate refresh token", "error", err)
 		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to generate refresh token")
@@ -71,6 +75,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	expiresAt := time.Now().Add(h.authService.Config().TokenExpiration).Unix()
 
 	h.log.Info("User logged in successfully", "username", req.Username)
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventLoginSucceeded, Username: user.Username})
 
 	// Send response
 	SendJSONResponse(w, http.StatusOK, LoginResponse{
@@ -123,3 +128,41 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt:    expiresAt,
 	})
 }
+
+// sessionClient describes the client of r for the session a login starts
+func sessionClient(r *http.Request) auth.SessionClient {
+	return auth.SessionClient{UserAgent: r.UserAgent(), IPAddress: clientIP(r)}
+}
+
+// Logout handles the /auth/logout endpoint, revoking the session of a refresh token so it can
+// no longer be refreshed. Unknown and expired tokens are accepted, as there is nothing left to end.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if req.RefreshToken == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "Refresh token is required")
+		return
+	}
+
+	username, err := h.authService.Logout(r.Context(), req.RefreshToken)
+	if err != nil {
+		h.log.Error("Failed to end session", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to log out")
+		return
+	}
+	if username != "" {
+		h.recordAuthEvent(r, audit.Event{Type: audit.EventLogout, Username: username})
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Logged out"})
+}
+
+// JWKS handles the /.well-known/jwks.json endpoint, publishing the public keys tokens are
+// signed with so other services can validate them
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	SendJSONResponse(w, http.StatusOK, h.authService.JWKS())
+}
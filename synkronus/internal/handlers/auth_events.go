@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// maxCountryLength bounds the country code taken from the request header
+const maxCountryLength = 8
+
+// recordAuthEvent adds the client address and country to an event and records it. Auditing
+// never fails the request; errors are logged by the audit service.
+func (h *Handler) recordAuthEvent(r *http.Request, event audit.Event) {
+	if h.auditService == nil {
+		return
+	}
+
+	event.IP = clientIP(r)
+	if header := h.config.AuditCountryHeader; header != "" {
+		if country := r.Header.Get(header); len(country) <= maxCountryLength {
+			event.Country = country
+		}
+	}
+	if claims := authmw.GetClaimsFromContext(r.Context()); claims != nil && event.Impersonator == "" {
+		event.Impersonator = claims.Impersonator()
+	}
+	if event.Actor == "" {
+		if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil && user.Username != event.Username {
+			event.Actor = user.Username
+		}
+	}
+
+	_ = h.auditService.Record(r.Context(), event)
+}
+
+// ListAuthEvents handles GET /users/auth-events (admin only)
+func (h *Handler) ListAuthEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{Username: query.Get("username"), Type: query.Get("type")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	events, err := h.auditService.List(r.Context(), filter)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list auth events")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, events)
+}
+
+// clientIP returns the address of the client making r. RealIP has already replaced RemoteAddr
+// with the forwarded client address.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func login(h *Handler, username, password, country string) int {
+	body, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+	r := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	r.RemoteAddr = "203.0.113.9:51234"
+	if country != "" {
+		r.Header.Set("CF-IPCountry", country)
+	}
+	w := httptest.NewRecorder()
+	h.Login(w, r)
+	return w.Code
+}
+
+func TestLogin_RecordsAuthEvents(t *testing.T) {
+	h, _ := createTestHandler()
+	h.config.AuditCountryHeader = "CF-IPCountry"
+	auditService := h.auditService.(*mocks.MockAuditService)
+
+	require.Equal(t, http.StatusUnauthorized, login(h, "testuser", "wrong", "KE"))
+	require.Equal(t, http.StatusOK, login(h, "testuser", "password123", "KE"))
+
+	events := auditService.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, audit.EventLoginFailed, events[0].Type)
+	assert.Equal(t, audit.EventLoginSucceeded, events[1].Type)
+	assert.Equal(t, "testuser", events[1].Username)
+	assert.Equal(t, "203.0.113.9", events[1].IP)
+	assert.Equal(t, "KE", events[1].Country)
+	assert.Empty(t, events[1].Actor)
+}
+
+func TestCreateUser_RecordsActor(t *testing.T) {
+	h, _ := createTestHandler()
+	auditService := h.auditService.(*mocks.MockAuditService)
+
+	body, _ := json.Marshal(UserCreateRequest{Username: "eve", Password: "secret", Role: models.RoleAdmin})
+	w := httptest.NewRecorder()
+	h.CreateUserHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/users/create", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	events := auditService.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.Event{ID: 1, Type: audit.EventUserCreated, Username: "eve", Actor: "admin", Role: "admin", IP: "192.0.2.1", At: events[0].At}, events[0])
+}
+
+func TestListAuthEvents(t *testing.T) {
+	h, _ := createTestHandler()
+	login(h, "testuser", "wrong", "")
+	login(h, "testuser", "password123", "")
+	login(h, "admin", "wrong", "")
+
+	list := func(query string) (int, []audit.Event) {
+		w := httptest.NewRecorder()
+		h.ListAuthEvents(w, httptest.NewRequest(http.MethodGet, "/users/auth-events"+query, nil))
+		var events []audit.Event
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+		}
+		return w.Code, events
+	}
+
+	code, events := list("")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, events, 3)
+	assert.Equal(t, "admin", events[0].Username)
+
+	_, events = list("?type=login_failed&username=testuser")
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.EventLoginFailed, events[0].Type)
+
+	_, events = list("?limit=2")
+	assert.Len(t, events, 2)
+
+	code, _ = list("?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
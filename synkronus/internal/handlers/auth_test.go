@@ -161,3 +161,21 @@ func TestRefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestJWKS(t *testing.T) {
+	h, _ := createTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	h.JWKS(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=300" {
+		t.Errorf("Expected public cache control, got %q", got)
+	}
+	if body := w.Body.String(); body != "{\"keys\":[]}\n" {
+		t.Errorf("Expected an empty key set, got %q", body)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/backup"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// restoreBatchSize is how many observations a restore writes per transaction
+const restoreBatchSize = 500
+
+// maxBackupLineSize bounds a single observation in a restored NDJSON stream
+const maxBackupLineSize = 16 << 20
+
+// ExportBackupUsers handles GET /backup/users
+func (h *Handler) ExportBackupUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.backupService.ExportUsers(r.Context())
+	if err != nil {
+		h.log.Error("Failed to export users", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to export users")
+		return
+	}
+	h.recordAudit(r, audit.Entry{Action: audit.ActionDataExport, Resource: "backup/users"})
+	SendJSONResponse(w, http.StatusOK, users)
+}
+
+// RestoreBackupUsers handles POST /backup/users
+func (h *Handler) RestoreBackupUsers(w http.ResponseWriter, r *http.Request) {
+	var users []backup.User
+	if err := json.NewDecoder(r.Body).Decode(&users); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+
+	result, err := h.backupService.RestoreUsers(r.Context(), users)
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalidUser) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if errors.Is(err, backup.ErrOtherTenant) {
+			SendErrorResponse(w, http.StatusConflict, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to restore users", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to restore users")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, result)
+}
+
+// ExportBackupObservations handles GET /backup/observations, streaming every observation as
+// newline-delimited JSON
+func (h *Handler) ExportBackupObservations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	count, err := h.backupService.ExportObservations(r.Context(), func(obs sync.Observation) error {
+		return encoder.Encode(obs)
+	})
+	if err != nil {
+		// The status is already sent; the client notices the truncated stream
+		h.log.Error("Failed to export observations", "error", err, "exported", count)
+		return
+	}
+	h.log.Info("Observations exported", "count", count)
+	h.recordAudit(r, audit.Entry{Action: audit.ActionDataExport, Resource: "backup/observations"})
+}
+
+// RestoreBackupObservations handles POST /backup/observations, reading newline-delimited JSON
+// as written by ExportBackupObservations
+func (h *Handler) RestoreBackupObservations(w http.ResponseWriter, r *http.Request) {
+	total := backup.RestoreResult{}
+	batch := make([]sync.Observation, 0, restoreBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := h.backupService.RestoreObservations(r.Context(), batch)
+		if err != nil {
+			return err
+		}
+		total.Created += result.Created
+		total.Updated += result.Updated
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxBackupLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var obs sync.Observation
+		if err := json.Unmarshal(scanner.Bytes(), &obs); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, fmt.Sprintf("Invalid observation on line %d", line))
+			return
+		}
+		batch = append(batch, obs)
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				h.sendRestoreObservationsError(w, err, total)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Failed to read request body")
+		return
+	}
+	if err := flush(); err != nil {
+		h.sendRestoreObservationsError(w, err, total)
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, total)
+}
+
+// sendRestoreObservationsError reports a failed batch; earlier batches stay restored
+func (h *Handler) sendRestoreObservationsError(w http.ResponseWriter, err error, restored backup.RestoreResult) {
+	if errors.Is(err, backup.ErrInvalidObservation) {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	if errors.Is(err, backup.ErrOtherTenant) {
+		SendErrorResponse(w, http.StatusConflict, err, err.Error())
+		return
+	}
+	h.log.Error("Failed to restore observations", "error", err, "created", restored.Created, "updated", restored.Updated)
+	SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to restore observations")
+}
+
+// ExportBackupAppBundle handles GET /backup/app-bundle/{version}, returning the version as a zip
+// that POST /app-bundle/push accepts
+func (h *Handler) ExportBackupAppBundle(w http.ResponseWriter, r *http.Request) {
+	version := chi.URLParam(r, "version")
+
+	// Buffer the zip so a failure can still be reported with a status
+	var buf bytes.Buffer
+	if err := h.appBundleService.ExportVersion(r.Context(), version, &buf); err != nil {
+		if errors.Is(err, appbundle.ErrVersionNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "App bundle version not found")
+			return
+		}
+		h.log.Error("Failed to export app bundle version", "error", err, "version", version)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to export app bundle version")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="app-bundle-`+version+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.log.Error("Failed to send app bundle version", "error", err, "version", version)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/backup"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupUsers(t *testing.T) {
+	h, _ := createTestHandler()
+
+	restore := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.RestoreBackupUsers(w, httptest.NewRequest(http.MethodPost, "/backup/users", strings.NewReader(body)))
+		return w
+	}
+	w := restore(`[{"username": "alice", "password_hash": "$2a$10$abc", "role": "admin"},
+		{"username": "bob", "password_hash": "$2a$10$def", "role": "read-only"}]`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result backup.RestoreResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, backup.RestoreResult{Created: 2}, result)
+
+	w = restore(`[{"username": "bob", "password_hash": "$2a$10$ghi", "role": "read-write"}]`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, backup.RestoreResult{Updated: 1}, result)
+
+	assert.Equal(t, http.StatusBadRequest, restore(`[{"username": "carol", "password_hash": "x", "role": "owner"}]`).Code)
+	assert.Equal(t, http.StatusBadRequest, restore(`{`).Code)
+
+	w = httptest.NewRecorder()
+	h.ExportBackupUsers(w, httptest.NewRequest(http.MethodGet, "/backup/users", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var users []backup.User
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&users))
+	require.Len(t, users, 2)
+	assert.Equal(t, "$2a$10$ghi", users[1].PasswordHash)
+}
+
+func TestBackupObservations(t *testing.T) {
+	h, _ := createTestHandler()
+
+	// More records than one batch, so the restore spans several transactions
+	var body bytes.Buffer
+	count := restoreBatchSize + 20
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&body, `{"observation_id": "obs-%d", "form_type": "survey", "form_version": "1", "data": {"n": %d}, "deleted": %t}`+"\n", i, i, i == 0)
+	}
+	w := httptest.NewRecorder()
+	h.RestoreBackupObservations(w, httptest.NewRequest(http.MethodPost, "/backup/observations", &body))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result backup.RestoreResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, backup.RestoreResult{Created: count}, result)
+
+	w = httptest.NewRecorder()
+	h.ExportBackupObservations(w, httptest.NewRequest(http.MethodGet, "/backup/observations", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	var exported []sync.Observation
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var obs sync.Observation
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &obs))
+		exported = append(exported, obs)
+	}
+	require.Len(t, exported, count)
+	assert.Equal(t, "obs-0", exported[0].ObservationID)
+	assert.True(t, exported[0].Deleted)
+	assert.JSONEq(t, `{"n": 0}`, string(exported[0].Data))
+
+	// Invalid lines and records are rejected
+	w = httptest.NewRecorder()
+	h.RestoreBackupObservations(w, httptest.NewRequest(http.MethodPost, "/backup/observations", strings.NewReader("{\"observation_id\": \"a\"}\nnot json\n")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "line 2")
+	w = httptest.NewRecorder()
+	h.RestoreBackupObservations(w, httptest.NewRequest(http.MethodPost, "/backup/observations", strings.NewReader(`{"observation_id": "a", "data": {}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportBackupAppBundle(t *testing.T) {
+	h, _ := createTestHandler()
+
+	export := func(version string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ExportBackupAppBundle(w, withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), "version", version))
+		return w
+	}
+
+	w := export("20250101-000000")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.ElementsMatch(t, []string{"index.html", "styles.css", "app.js"}, names)
+
+	assert.Equal(t, http.StatusNotFound, export("missing").Code)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/device"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// CasePullRequest represents the body of POST /sync/cases/pull
+type CasePullRequest struct {
+	ClientID     string   `json:"client_id"`
+	SinceVersion int64    `json:"since_version,omitempty"`
+	CaseTypes    []string `json:"case_types,omitempty"`
+}
+
+// CasePushRequest represents the body of POST /sync/cases/push
+type CasePushRequest struct {
+	ClientID string      `json:"client_id"`
+	Cases    []sync.Case `json:"cases"`
+}
+
+// CaseResponse is a case together with its linked observations
+type CaseResponse struct {
+	sync.Case
+	Observations []sync.Observation `json:"observations"`
+}
+
+// PullCases handles POST /sync/cases/pull
+func (h *Handler) PullCases(w http.ResponseWriter, r *http.Request) {
+	var req CasePullRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	if req.ClientID == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "client_id is required")
+		return
+	}
+	if h.refuseClient(w, r, req.ClientID, device.SyncPull) {
+		return
+	}
+
+	limit := 100 // default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	result, err := h.syncService.GetCasesSinceVersion(syncContext(r), req.SinceVersion, req.CaseTypes, limit)
+	if err != nil {
+		h.log.Error("Failed to get cases", "error", err, "clientId", req.ClientID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to retrieve cases")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, result)
+}
+
+// PushCases handles POST /sync/cases/push
+func (h *Handler) PushCases(w http.ResponseWriter, r *http.Request) {
+	var req CasePushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	if req.ClientID == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "client_id is required")
+		return
+	}
+	if h.refuseClient(w, r, req.ClientID, device.SyncPush) {
+		return
+	}
+
+	result, err := h.syncService.ProcessPushedCases(syncContext(r), req.Cases, req.ClientID)
+	if err != nil {
+		h.log.Error("Failed to process pushed cases", "error", err, "clientId", req.ClientID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to process cases")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, SyncPushResponse{
+		CurrentVersion: result.CurrentVersion,
+		SuccessCount:   result.SuccessCount,
+		FailedRecords:  result.FailedRecords,
+	})
+}
+
+// GetCase handles GET /cases/{caseId}
+func (h *Handler) GetCase(w http.ResponseWriter, r *http.Request) {
+	ctx := syncContext(r)
+	caseID := chi.URLParam(r, "caseId")
+
+	c, err := h.syncService.GetCase(ctx, caseID)
+	if err != nil {
+		if errors.Is(err, sync.ErrCaseNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Case not found")
+			return
+		}
+		h.log.Error("Failed to get case", "error", err, "caseId", caseID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get case")
+		return
+	}
+
+	observations, err := h.syncService.GetCaseObservations(ctx, caseID)
+	if err != nil {
+		h.log.Error("Failed to get case observations", "error", err, "caseId", caseID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get case")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, CaseResponse{Case: *c, Observations: observations})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pushCases(t *testing.T, h *Handler, cases ...sync.Case) SyncPushResponse {
+	t.Helper()
+	body, _ := json.Marshal(CasePushRequest{ClientID: "tablet-1", Cases: cases})
+	w := httptest.NewRecorder()
+	h.PushCases(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/cases/push", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp SyncPushResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func pullCases(t *testing.T, h *Handler, sinceVersion int64) sync.CaseSyncResult {
+	t.Helper()
+	body, _ := json.Marshal(CasePullRequest{ClientID: "tablet-2", SinceVersion: sinceVersion})
+	w := httptest.NewRecorder()
+	h.PullCases(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/cases/pull", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp sync.CaseSyncResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestCases_PushPull(t *testing.T) {
+	h, _ := createTestHandler()
+
+	resp := pushCases(t, h,
+		sync.Case{CaseID: "case-1", CaseType: "pregnancy", Data: json.RawMessage(`{"lmp":"2025-01-10"}`)},
+		sync.Case{CaseID: "", CaseType: "pregnancy"},
+		sync.Case{CaseID: "case-2", CaseType: "pregnancy", Status: "archived"},
+	)
+	assert.Equal(t, 1, resp.SuccessCount)
+	assert.Len(t, resp.FailedRecords, 2)
+
+	pulled := pullCases(t, h, 0)
+	require.Len(t, pulled.Cases, 1)
+	assert.Equal(t, sync.CaseStatusOpen, pulled.Cases[0].Status)
+	assert.Equal(t, "alice", *pulled.Cases[0].CreatedBy)
+	cutoff := pulled.ChangeCutoff
+
+	// Closing the case reaches clients as a new version
+	pushCases(t, h, sync.Case{CaseID: "case-1", CaseType: "pregnancy", Status: sync.CaseStatusClosed})
+	pulled = pullCases(t, h, cutoff)
+	require.Len(t, pulled.Cases, 1)
+	assert.Equal(t, sync.CaseStatusClosed, pulled.Cases[0].Status)
+}
+
+func TestGetCase_WithObservations(t *testing.T) {
+	h, _ := createTestHandler()
+	pushCases(t, h, sync.Case{CaseID: "case-1", CaseType: "pregnancy"})
+
+	caseID := "case-1"
+	body, _ := json.Marshal(SyncPushRequest{
+		TransmissionID: "tx-1",
+		ClientID:       "tablet-1",
+		Records: []sync.Observation{
+			{ObservationID: "anc-1", FormType: "anc_visit", Data: json.RawMessage(`{}`), CaseID: &caseID},
+			{ObservationID: "anc-2", FormType: "anc_visit", Data: json.RawMessage(`{}`), CaseID: &caseID},
+			{ObservationID: "other", FormType: "household", Data: json.RawMessage(`{}`)},
+		},
+	})
+	w := httptest.NewRecorder()
+	h.Push(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetCase(w, withURLParams(asUser(httptest.NewRequest(http.MethodGet, "/cases/case-1", nil), "alice"), "caseId", "case-1"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp CaseResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "case-1", resp.CaseID)
+	require.Len(t, resp.Observations, 2)
+	assert.Equal(t, "anc-1", resp.Observations[0].ObservationID)
+
+	w = httptest.NewRecorder()
+	h.GetCase(w, withURLParams(httptest.NewRequest(http.MethodGet, "/cases/missing", nil), "caseId", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
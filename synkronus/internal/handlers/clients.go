@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/device"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// refuseClient answers a sync from a client that is not allowed to make it with 403, and
+// reports whether it did. Clients that have not synced before are allowed. Failures to look up
+// the client let the sync through, so syncing keeps working while the registry is unreachable.
+func (h *Handler) refuseClient(w http.ResponseWriter, r *http.Request, clientID, operation string) bool {
+	if h.deviceService == nil {
+		return false
+	}
+	d, err := h.deviceService.Get(r.Context(), clientID)
+	if errors.Is(err, device.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		h.log.Warn("Failed to check client status", "error", err, "clientId", clientID)
+		return false
+	}
+	if operation == device.SyncPull && d.CanPull() || operation == device.SyncPush && d.CanPush() {
+		return false
+	}
+
+	h.log.Warn("Refused sync from "+d.Status+" client", "clientId", clientID, "operation", operation)
+	SendErrorResponse(w, http.StatusForbidden, nil, "This device is "+d.Status+"; contact your administrator")
+	return true
+}
+
+// recordClientSync records a client's pull or push in the client registry
+func (h *Handler) recordClientSync(r *http.Request, clientID, operation string, version int64) {
+	if h.deviceService == nil {
+		return
+	}
+	sync := device.Sync{ClientID: clientID, Operation: operation, Version: version}
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		sync.Username = user.Username
+	}
+	if err := h.deviceService.RecordSync(r.Context(), sync); err != nil {
+		h.log.Warn("Failed to record client sync", "error", err, "clientId", clientID)
+	}
+}
+
+// ListClients handles GET /clients (admin only), optionally filtered by ?status=
+func (h *Handler) ListClients(w http.ResponseWriter, r *http.Request) {
+	if h.deviceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "The client registry is not available")
+		return
+	}
+
+	filter := device.Filter{Status: r.URL.Query().Get("status")}
+	if filter.Status != "" {
+		if err := device.ValidateStatus(filter.Status); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, "status must be active, suspended or blocked")
+			return
+		}
+	}
+	devices, err := h.deviceService.List(r.Context(), filter)
+	if err != nil {
+		h.log.Error("Failed to list clients", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list clients")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, devices)
+}
+
+// GetClient handles GET /clients/{clientId} (admin only)
+func (h *Handler) GetClient(w http.ResponseWriter, r *http.Request) {
+	if h.deviceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "The client registry is not available")
+		return
+	}
+
+	d, err := h.deviceService.Get(r.Context(), chi.URLParam(r, "clientId"))
+	if err != nil {
+		h.sendClientError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, d)
+}
+
+// UpdateClient handles PATCH /clients/{clientId} (admin only), labelling a client or changing
+// its status
+func (h *Handler) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	if h.deviceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "The client registry is not available")
+		return
+	}
+
+	var update device.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	updatedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		updatedBy = user.Username
+	}
+
+	clientID := chi.URLParam(r, "clientId")
+	d, err := h.deviceService.Update(r.Context(), clientID, update, updatedBy)
+	if err != nil {
+		h.sendClientError(w, err)
+		return
+	}
+	h.recordAudit(r, audit.Entry{Action: audit.ActionClientUpdate, Resource: "clients/" + clientID})
+	SendJSONResponse(w, http.StatusOK, d)
+}
+
+// sendClientError answers a failed client registry request
+func (h *Handler) sendClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, device.ErrNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Client not found")
+	case errors.Is(err, device.ErrInvalidStatus):
+		SendErrorResponse(w, http.StatusBadRequest, err, "status must be active, suspended or blocked")
+	case errors.Is(err, device.ErrInvalidLabel):
+		SendErrorResponse(w, http.StatusBadRequest, err, "label must be at most 255 characters")
+	default:
+		h.log.Error("Failed to access client registry", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to access client registry")
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/device"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncAs pushes one record from a client, or pulls when push is false, as a field user
+func syncAs(h *Handler, clientID string, push bool) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	if push {
+		record := sync.Observation{ObservationID: "obs-" + clientID, FormType: "survey", Data: json.RawMessage(`{}`),
+			CreatedAt: "2025-06-01T08:00:00Z", UpdatedAt: "2025-06-01T08:00:00Z"}
+		body, _ := json.Marshal(SyncPushRequest{TransmissionID: "tx-" + clientID, ClientID: clientID, Records: []sync.Observation{record}})
+		h.Push(w, withRole(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), "enumerator", models.RoleReadWrite))
+		return w
+	}
+	body, _ := json.Marshal(SyncPullRequest{ClientID: clientID})
+	h.Pull(w, withRole(httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)), "enumerator", models.RoleReadWrite))
+	return w
+}
+
+func updateClient(h *Handler, clientID, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPatch, "/clients/"+clientID, bytes.NewBufferString(body))
+	h.UpdateClient(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "clientId", clientID))
+	return w
+}
+
+func TestClients_Registry(t *testing.T) {
+	h, _ := createTestHandler()
+
+	require.Equal(t, http.StatusOK, syncAs(h, "tablet-1", true).Code)
+	require.Equal(t, http.StatusOK, syncAs(h, "tablet-2", false).Code)
+	require.Equal(t, http.StatusOK, syncAs(h, "tablet-1", false).Code)
+
+	w := httptest.NewRecorder()
+	h.ListClients(w, httptest.NewRequest(http.MethodGet, "/clients", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var devices []device.Device
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&devices))
+	require.Len(t, devices, 2)
+	assert.Equal(t, "tablet-1", devices[0].ClientID, "the most recently seen client comes first")
+	assert.Equal(t, device.StatusActive, devices[0].Status)
+	assert.Equal(t, "enumerator", devices[0].LastUsername)
+	assert.NotNil(t, devices[0].LastPushAt)
+	assert.NotNil(t, devices[0].LastPullAt)
+	assert.Positive(t, devices[0].LastVersion)
+	assert.Nil(t, devices[1].LastPushAt, "tablet-2 only pulled")
+
+	w = updateClient(h, "tablet-1", `{"label":"Kisumu team tablet"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	h.GetClient(w, withURLParams(httptest.NewRequest(http.MethodGet, "/clients/tablet-1", nil), "clientId", "tablet-1"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"label":"Kisumu team tablet"`)
+
+	w = httptest.NewRecorder()
+	h.GetClient(w, withURLParams(httptest.NewRequest(http.MethodGet, "/clients/unknown", nil), "clientId", "unknown"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, http.StatusNotFound, updateClient(h, "unknown", `{"status":"blocked"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, updateClient(h, "tablet-1", `{"status":"lost"}`).Code)
+}
+
+func TestClients_SuspendedAndBlocked(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, syncAs(h, "tablet-1", true).Code)
+
+	// Suspended devices keep pulling but cannot push
+	w := updateClient(h, "tablet-1", `{"status":"suspended","reason":"duplicate submissions"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var d device.Device
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&d))
+	assert.Equal(t, "admin", d.StatusChangedBy)
+	assert.Equal(t, "duplicate submissions", d.StatusReason)
+
+	assert.Equal(t, http.StatusOK, syncAs(h, "tablet-1", false).Code)
+	w = syncAs(h, "tablet-1", true)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "suspended")
+
+	// Blocked devices can do neither
+	require.Equal(t, http.StatusOK, updateClient(h, "tablet-1", `{"status":"blocked","reason":"reported stolen"}`).Code)
+	assert.Equal(t, http.StatusForbidden, syncAs(h, "tablet-1", false).Code)
+	assert.Equal(t, http.StatusForbidden, syncAs(h, "tablet-1", true).Code)
+	assert.Equal(t, http.StatusOK, syncAs(h, "tablet-2", true).Code, "other devices are unaffected")
+
+	w = httptest.NewRecorder()
+	h.ListClients(w, httptest.NewRequest(http.MethodGet, "/clients?status=blocked", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var blocked []device.Device
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&blocked))
+	require.Len(t, blocked, 1)
+	assert.Equal(t, "tablet-1", blocked[0].ClientID)
+
+	// Reactivating lets the device sync again
+	require.Equal(t, http.StatusOK, updateClient(h, "tablet-1", `{"status":"active"}`).Code)
+	assert.Equal(t, http.StatusOK, syncAs(h, "tablet-1", true).Code)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/codelist"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// ListCodeLists handles GET /code-lists, summarizing the latest version of every code list.
+// Clients compare the versions with the ones they hold to fetch only the lists that changed.
+func (h *Handler) ListCodeLists(w http.ResponseWriter, r *http.Request) {
+	if h.codeListService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Code lists are not available")
+		return
+	}
+
+	summaries, err := h.codeListService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list code lists", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list code lists")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, summaries)
+}
+
+// ListCodeListVersions handles GET /code-lists/{name}, summarizing every version of a code
+// list, newest first
+func (h *Handler) ListCodeListVersions(w http.ResponseWriter, r *http.Request) {
+	if h.codeListService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Code lists are not available")
+		return
+	}
+
+	versions, err := h.codeListService.Versions(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		h.sendCodeListError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, versions)
+}
+
+// GetCodeList handles GET /code-lists/{name}/versions/{version}; "latest" names the latest
+// version
+func (h *Handler) GetCodeList(w http.ResponseWriter, r *http.Request) {
+	if h.codeListService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Code lists are not available")
+		return
+	}
+
+	version := 0
+	if text := chi.URLParam(r, "version"); text != "latest" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed < 1 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "version must be a positive number or latest")
+			return
+		}
+		version = parsed
+	}
+
+	list, err := h.codeListService.Get(r.Context(), chi.URLParam(r, "name"), version)
+	if err != nil {
+		h.sendCodeListError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, list)
+}
+
+// PublishCodeList handles POST /code-lists/{name} (admin only), adding the next version of a
+// code list. Pushes are checked against it right away; no app bundle push is needed.
+func (h *Handler) PublishCodeList(w http.ResponseWriter, r *http.Request) {
+	if h.codeListService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Code lists are not available")
+		return
+	}
+
+	var input codelist.Input
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	createdBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		createdBy = user.Username
+	}
+
+	list, err := h.codeListService.Publish(r.Context(), chi.URLParam(r, "name"), input, createdBy)
+	if err != nil {
+		h.sendCodeListError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusCreated, list)
+}
+
+// sendCodeListError answers a failed code list request
+func (h *Handler) sendCodeListError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, codelist.ErrNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Code list not found")
+	case errors.Is(err, codelist.ErrInvalidName), errors.Is(err, codelist.ErrInvalidCodeList):
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+	default:
+		h.log.Error("Failed to access code lists", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to access code lists")
+	}
+}
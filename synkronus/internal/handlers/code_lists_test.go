@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/codelist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishCodeList(h *Handler, name, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/code-lists/"+name, bytes.NewBufferString(body))
+	h.PublishCodeList(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", name))
+	return w
+}
+
+func TestCodeLists_PublishAndRead(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := publishCodeList(h, "facilities", `{"description":"Health facilities","entries":[{"code":"101","label":"Central Clinic"}]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = publishCodeList(h, "facilities", `{"entries":[{"code":"101","label":"Central Clinic","retired":true},{"code":"102","label":"North Clinic"}]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var published codelist.CodeList
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&published))
+	assert.Equal(t, 2, published.Version)
+	assert.Equal(t, "admin", published.CreatedBy)
+
+	w = httptest.NewRecorder()
+	h.ListCodeLists(w, httptest.NewRequest(http.MethodGet, "/code-lists", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var latest []codelist.Summary
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&latest))
+	require.Len(t, latest, 1)
+	assert.Equal(t, 2, latest[0].Version)
+	assert.Equal(t, 2, latest[0].EntryCount)
+
+	w = httptest.NewRecorder()
+	h.ListCodeListVersions(w, withURLParams(httptest.NewRequest(http.MethodGet, "/code-lists/facilities", nil), "name", "facilities"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var versions []codelist.Summary
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version, "newest version first")
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/code-lists/facilities/versions/latest", nil)
+	h.GetCodeList(w, withURLParams(r, "name", "facilities", "version", "latest"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list codelist.CodeList
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list.Entries, 2)
+	assert.True(t, list.Entries[0].Retired)
+
+	for version, want := range map[string]int{"1": http.StatusOK, "3": http.StatusNotFound, "first": http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/code-lists/facilities/versions/"+version, nil)
+		h.GetCodeList(w, withURLParams(r, "name", "facilities", "version", version))
+		assert.Equal(t, want, w.Code, "version %s", version)
+	}
+}
+
+func TestCodeLists_PublishRejectsInvalidLists(t *testing.T) {
+	h, _ := createTestHandler()
+
+	assert.Equal(t, http.StatusBadRequest, publishCodeList(h, "Facilities", `{"entries":[{"code":"101"}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishCodeList(h, "facilities", `{"entries":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishCodeList(h, "facilities", `{"entries":[{"code":" "}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishCodeList(h, "facilities", `{"entries":[{"code":"101"},{"code":"101"}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishCodeList(h, "facilities", `not json`).Code)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// maxImportFileSize bounds the CSV files accepted by StartDataImport
+const maxImportFileSize = 20 << 20
+
+// StartDataImport handles POST /data/import. The multipart form carries the CSV file as "file"
+// and its column mapping as JSON in "mapping". The file and mapping are checked against the
+// form schema before the import is queued; rows are imported in the background.
+func (h *Handler) StartDataImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	if h.dataImportService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Data import is not available")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format. Expected multipart form with a 'file' and a 'mapping'")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "file is required")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxImportFileSize {
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, nil, "The file is larger than 20 MB; split it into smaller files")
+		return
+	}
+
+	var mapping dataimport.Mapping
+	if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "mapping must be a JSON column mapping")
+		return
+	}
+
+	job, err := h.dataImportService.Start(r.Context(), header.Filename, file, mapping, user.Username)
+	if err != nil {
+		if errors.Is(err, dataimport.ErrInvalidImport) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to start data import", "error", err, "user", user.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to start data import")
+		return
+	}
+	h.recordAudit(r, audit.Entry{Action: audit.ActionDataImport, Resource: "data/import/" + job.ID})
+
+	w.Header().Set("Location", "/data/import/"+job.ID)
+	SendJSONResponse(w, http.StatusAccepted, job)
+}
+
+// GetDataImport handles GET /data/import/{id}, returning the progress and row results of an import
+func (h *Handler) GetDataImport(w http.ResponseWriter, r *http.Request) {
+	if h.dataImportService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Data import is not available")
+		return
+	}
+
+	job, err := h.dataImportService.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, dataimport.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Import not found")
+			return
+		}
+		h.log.Error("Failed to get data import", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get data import")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, job)
+}
+
+// ListDataImports handles GET /data/import?limit=
+func (h *Handler) ListDataImports(w http.ResponseWriter, r *http.Request) {
+	if h.dataImportService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Data import is not available")
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	jobs, err := h.dataImportService.List(r.Context(), limit)
+	if err != nil {
+		h.log.Error("Failed to list data imports", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list data imports")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, map[string]any{"imports": jobs})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// importRequest builds a multipart import request of a CSV file and its mapping
+func importRequest(t *testing.T, csv, mapping string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "register.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(csv))
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteField("mapping", mapping))
+	require.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/data/import", body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return withRole(r, "admin", models.RoleAdmin)
+}
+
+func TestDataImport(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.StartDataImport(w, importRequest(t, "Name,Age\nAmina,34\nJoseph,51\n", `{"form_type":"survey","columns":{"Name":"name","Age":"age"}}`))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var job dataimport.Job
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	assert.Equal(t, "/data/import/"+job.ID, w.Header().Get("Location"))
+	assert.Equal(t, 2, job.TotalRows)
+	assert.Equal(t, "admin", job.CreatedBy)
+
+	w = httptest.NewRecorder()
+	h.GetDataImport(w, withURLParams(httptest.NewRequest(http.MethodGet, "/data/import/"+job.ID, nil), "id", job.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	require.Len(t, job.Results, 2)
+	assert.Equal(t, 3, job.Results[1].Row)
+
+	w = httptest.NewRecorder()
+	h.ListDataImports(w, httptest.NewRequest(http.MethodGet, "/data/import?limit=10", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), job.ID)
+
+	// Mappings that do not fit the form schema are refused before anything is queued
+	w = httptest.NewRecorder()
+	h.StartDataImport(w, importRequest(t, "Name\nAmina\n", `{"form_type":"census","columns":{"Name":"name"}}`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "census")
+
+	w = httptest.NewRecorder()
+	h.StartDataImport(w, importRequest(t, "Name\nAmina\n", `not json`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetDataImport(w, withURLParams(httptest.NewRequest(http.MethodGet, "/data/import/missing", nil), "id", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
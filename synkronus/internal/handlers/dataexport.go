@@ -1,8 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/exporttemplate"
 )
 
 // ParquetExportHandler handles GET /dataexport/parquet
@@ -10,24 +19,124 @@ import (
 // @Description Returns a ZIP file containing multiple Parquet files, each representing a flattened export of observations per form type. Supports downloading the entire dataset as separate Parquet files bundled together.
 // @Tags DataExport
 // @Produce application/zip
+// @Param as_of_version query int false "Export the dataset as it existed at this sync version"
+// @Param as_of query string false "Export the dataset as it existed at this RFC 3339 timestamp"
+// @Param org_unit query string false "Only export observations in this org unit and its descendants"
+// @Param template query string false "Shape the export with this saved export template"
+// @Param form_types query string false "Comma-separated form types to export"
+// @Param since_version query int false "Only export observations changed after this sync version, including deletions"
+// @Param from query string false "Only export observations created at or after this RFC 3339 timestamp or date"
+// @Param to query string false "Only export observations created before this RFC 3339 timestamp or date"
 // @Success 200 {file} binary "ZIP archive stream containing Parquet files"
+// @Failure 400 {object} ErrorResponse "Bad Request"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
 // @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Export template not found"
 // @Failure 500 {object} ErrorResponse "Internal Server Error"
 // @Security BearerAuth
 // @Router /dataexport/parquet [get]
 func (h *Handler) ParquetExportHandler(w http.ResponseWriter, r *http.Request) {
+	asOfVersion, err := h.exportAsOfVersion(r)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	filter, err := parseExportFilter(r)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	orgUnitID := r.URL.Query().Get("org_unit")
+	if orgUnitID != "" {
+		if _, err := uuid.Parse(orgUnitID); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, "org_unit must be an org unit id")
+			return
+		}
+	}
+
+	// A template supplies the form types, columns and masks; the query can still pick a
+	// point in time, and an org unit when the template has none
+	opts := dataexport.ExportOptions{}
+	filename := "observations_export.zip"
+	name := r.URL.Query().Get("template")
+	if name != "" {
+		if h.exportTemplateService == nil {
+			SendErrorResponse(w, http.StatusNotFound, nil, "Export templates are not available")
+			return
+		}
+		templateOpts, err := h.exportTemplateService.ExportOptions(r.Context(), name)
+		if err != nil {
+			if errors.Is(err, exporttemplate.ErrTemplateNotFound) {
+				SendErrorResponse(w, http.StatusNotFound, err, "Export template not found")
+				return
+			}
+			h.log.Error("Failed to load export template", "error", err, "template", name)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to load export template")
+			return
+		}
+		if orgUnitID != "" && templateOpts.OrgUnitID != "" && orgUnitID != templateOpts.OrgUnitID {
+			SendErrorResponse(w, http.StatusBadRequest, nil, "org_unit cannot override the org unit of the template")
+			return
+		}
+		opts = *templateOpts
+		filename = name + "_export.zip"
+	}
+	opts.AsOfVersion = asOfVersion
+	if orgUnitID != "" {
+		opts.OrgUnitID = orgUnitID
+	}
+	if len(filter.FormTypes) > 0 {
+		// Narrow a template down rather than widen it
+		if len(opts.FormTypes) > 0 {
+			filter.FormTypes = intersectFormTypes(opts.FormTypes, filter.FormTypes)
+			if len(filter.FormTypes) == 0 {
+				SendErrorResponse(w, http.StatusBadRequest, nil, "form_types are not part of the template")
+				return
+			}
+		}
+		opts.FormTypes = filter.FormTypes
+	}
+	opts.SinceVersion = filter.SinceVersion
+	opts.CreatedFrom = filter.CreatedFrom
+	opts.CreatedTo = filter.CreatedTo
+
+	// A delta ends at a fixed version, reported to the client so that the next delta can
+	// start from it. A delta since version 0 is a full export that starts the chain.
+	if filter.Delta {
+		opts.UntilVersion = opts.AsOfVersion
+		if opts.UntilVersion == 0 {
+			if opts.UntilVersion, err = h.syncService.GetCurrentVersion(r.Context()); err != nil {
+				h.log.Error("Failed to get current version for export", "error", err)
+				SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to export parquet data")
+				return
+			}
+		}
+		if opts.AsOfVersion > 0 && opts.SinceVersion >= opts.AsOfVersion {
+			SendErrorResponse(w, http.StatusBadRequest, nil, "since_version must be before the as-of version")
+			return
+		}
+		w.Header().Set("X-Export-Version", strconv.FormatInt(opts.UntilVersion, 10))
+	}
+
 	// Export data as parquet ZIP
-	zipReader, err := h.dataExportService.ExportParquetZip(r.Context())
+	var zipReader io.ReadCloser
+	if opts.AsOfVersion > 0 || opts.OrgUnitID != "" || name != "" || filter.isSet() {
+		zipReader, err = h.dataExportService.ExportParquetZipWithOptions(r.Context(), opts)
+	} else {
+		zipReader, err = h.dataExportService.ExportParquetZip(r.Context())
+	}
 	if err != nil {
 		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to export parquet data")
 		return
 	}
 	defer zipReader.Close()
+	h.recordAudit(r, audit.Entry{Action: audit.ActionDataExport, Resource: requestResource(r)})
 
 	// Set headers for ZIP file download
 	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", "attachment; filename=\"observations_export.zip\"")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
 	w.WriteHeader(http.StatusOK)
 
 	// Stream the ZIP file to the response
@@ -37,3 +146,101 @@ func (h *Handler) ParquetExportHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// exportAsOfVersion resolves the as_of_version or as_of query parameter to a sync version.
+// It returns 0 when the live dataset should be exported.
+func (h *Handler) exportAsOfVersion(r *http.Request) (int64, error) {
+	versionParam := r.URL.Query().Get("as_of_version")
+	timeParam := r.URL.Query().Get("as_of")
+
+	switch {
+	case versionParam != "" && timeParam != "":
+		return 0, errors.New("as_of_version and as_of cannot be combined")
+	case versionParam != "":
+		version, err := strconv.ParseInt(versionParam, 10, 64)
+		if err != nil || version <= 0 {
+			return 0, errors.New("as_of_version must be a positive integer")
+		}
+		return version, nil
+	case timeParam != "":
+		at, err := time.Parse(time.RFC3339, timeParam)
+		if err != nil {
+			return 0, errors.New("as_of must be an RFC 3339 timestamp")
+		}
+		return h.syncService.GetVersionAtTime(r.Context(), at)
+	}
+	return 0, nil
+}
+
+// exportFilter holds the query parameters narrowing an export down to a delta or a subset
+type exportFilter struct {
+	FormTypes    []string
+	Delta        bool
+	SinceVersion int64
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+}
+
+func (f exportFilter) isSet() bool {
+	return len(f.FormTypes) > 0 || f.Delta || !f.CreatedFrom.IsZero() || !f.CreatedTo.IsZero()
+}
+
+// parseExportFilter reads the form_types, since_version, from and to query parameters
+func parseExportFilter(r *http.Request) (exportFilter, error) {
+	query := r.URL.Query()
+	var filter exportFilter
+
+	for _, formType := range strings.Split(query.Get("form_types"), ",") {
+		if formType = strings.TrimSpace(formType); formType != "" {
+			filter.FormTypes = append(filter.FormTypes, formType)
+		}
+	}
+
+	if param := query.Get("since_version"); param != "" {
+		version, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || version < 0 {
+			return filter, errors.New("since_version must be a non-negative integer")
+		}
+		filter.Delta = true
+		filter.SinceVersion = version
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseExportTime(query.Get("from")); err != nil {
+		return filter, errors.New("from must be an RFC 3339 timestamp or a date")
+	}
+	if filter.CreatedTo, err = parseExportTime(query.Get("to")); err != nil {
+		return filter, errors.New("to must be an RFC 3339 timestamp or a date")
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
+
+// parseExportTime parses an RFC 3339 timestamp or a date, which stands for its start in UTC.
+// An empty value gives the zero time.
+func parseExportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// intersectFormTypes returns the requested form types that are also allowed, in the order
+// they were requested
+func intersectFormTypes(allowed, requested []string) []string {
+	intersection := make([]string, 0, len(requested))
+	for _, formType := range requested {
+		for _, a := range allowed {
+			if a == formType {
+				intersection = append(intersection, formType)
+				break
+			}
+		}
+	}
+	return intersection
+}
@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
 )
 
 func TestHandler_ParquetExportHandler(t *testing.T) {
@@ -146,3 +148,43 @@ func TestHandler_ParquetExportHandler_Integration(t *testing.T) {
 		}
 	}
 }
+
+func TestHandler_ParquetExportHandler_Delta(t *testing.T) {
+	h, _ := createTestHandler()
+
+	var got dataexport.ExportOptions
+	h.dataExportService.(*mocks.MockDataExportService).ExportParquetZipWithOptionsFunc = func(ctx context.Context, opts dataexport.ExportOptions) (io.ReadCloser, error) {
+		got = opts
+		return io.NopCloser(bytes.NewReader([]byte("zip"))), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?since_version=0&form_types=household,+visit&from=2025-06-01&to=2025-07-01T00:00:00Z", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(got.FormTypes) != 2 || got.FormTypes[1] != "visit" {
+		t.Errorf("Expected form types household and visit, got %v", got.FormTypes)
+	}
+	if !got.CreatedFrom.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) || got.CreatedTo.Month() != time.July {
+		t.Errorf("Unexpected date range %v to %v", got.CreatedFrom, got.CreatedTo)
+	}
+
+	// A delta ends at the current version, which the next delta starts from
+	w = httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?since_version=0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got.SinceVersion != 0 || got.UntilVersion != 1 || w.Header().Get("X-Export-Version") != "1" {
+		t.Errorf("Expected a delta up to version 1, got %d to %d (header %q)", got.SinceVersion, got.UntilVersion, w.Header().Get("X-Export-Version"))
+	}
+
+	for _, query := range []string{"since_version=-1", "from=yesterday", "from=2025-07-01&to=2025-06-01", "since_version=5&as_of_version=5"} {
+		w = httptest.NewRecorder()
+		h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
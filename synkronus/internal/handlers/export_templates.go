@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/exporttemplate"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// maxExportTemplateSize limits the size of an export template definition
+const maxExportTemplateSize = 64 * 1024
+
+// ExportTemplateRequest represents the body of PUT /dataexport/templates/{name}
+type ExportTemplateRequest struct {
+	Description string                    `json:"description"`
+	Definition  exporttemplate.Definition `json:"definition"`
+}
+
+// ListExportTemplates handles GET /dataexport/templates
+func (h *Handler) ListExportTemplates(w http.ResponseWriter, r *http.Request) {
+	list, err := h.exportTemplateService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list export templates", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list export templates")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"templates": list,
+	})
+}
+
+// GetExportTemplate handles GET /dataexport/templates/{name}
+func (h *Handler) GetExportTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.exportTemplateService.Get(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, exporttemplate.ErrTemplateNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Export template not found")
+			return
+		}
+		h.log.Error("Failed to get export template", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get export template")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, template)
+}
+
+// SaveExportTemplate handles PUT /dataexport/templates/{name}
+func (h *Handler) SaveExportTemplate(w http.ResponseWriter, r *http.Request) {
+	var req ExportTemplateRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxExportTemplateSize)).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	updatedBy := ""
+	if user := authmw.GetUserFromContext(r.Context()); user != nil {
+		updatedBy = user.Username
+	}
+
+	template, err := h.exportTemplateService.Save(r.Context(), exporttemplate.Template{
+		Name:        chi.URLParam(r, "name"),
+		Description: req.Description,
+		Definition:  req.Definition,
+	}, updatedBy)
+	if err != nil {
+		if errors.Is(err, exporttemplate.ErrInvalidTemplate) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to save export template", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to save export template")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, template)
+}
+
+// DeleteExportTemplate handles DELETE /dataexport/templates/{name}
+func (h *Handler) DeleteExportTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.exportTemplateService.Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+		if errors.Is(err, exporttemplate.ErrTemplateNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Export template not found")
+			return
+		}
+		h.log.Error("Failed to delete export template", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete export template")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Export template deleted"})
+}
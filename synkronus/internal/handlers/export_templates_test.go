@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/exporttemplate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ministryTemplate = `{
+	"description": "Monthly delivery to the ministry",
+	"definition": {
+		"form_types": ["household"],
+		"columns": ["data_village", "data_head_name"],
+		"masks": [{"column": "data_head_name", "method": "hash"}]
+	}
+}`
+
+func saveTemplate(h *Handler, name, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/dataexport/templates/", bytes.NewBufferString(body))
+	h.SaveExportTemplate(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", name))
+	return w
+}
+
+func TestExportTemplates_SaveGetListDelete(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := saveTemplate(h, "ministry", ministryTemplate)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var saved exporttemplate.Template
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&saved))
+	assert.Equal(t, exporttemplate.FormatParquet, saved.Definition.Format)
+	require.NotNil(t, saved.UpdatedBy)
+	assert.Equal(t, "admin", *saved.UpdatedBy)
+
+	assert.Equal(t, http.StatusBadRequest, saveTemplate(h, "Ministry Report", ministryTemplate).Code)
+	assert.Equal(t, http.StatusBadRequest, saveTemplate(h, "broken", `{"definition": {"format": "xlsx"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, saveTemplate(h, "broken", `{"definition": `).Code)
+
+	w = httptest.NewRecorder()
+	h.GetExportTemplate(w, withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), "name", "ministry"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ListExportTemplates(w, httptest.NewRequest(http.MethodGet, "/dataexport/templates", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Templates []exporttemplate.Template `json:"templates"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&listed))
+	require.Len(t, listed.Templates, 1)
+
+	w = httptest.NewRecorder()
+	h.DeleteExportTemplate(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/", nil), "name", "ministry"))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	h.DeleteExportTemplate(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/", nil), "name", "ministry"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestParquetExportHandler_Template(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, saveTemplate(h, "ministry", ministryTemplate).Code)
+
+	var got dataexport.ExportOptions
+	h.dataExportService.(*mocks.MockDataExportService).ExportParquetZipWithOptionsFunc = func(ctx context.Context, opts dataexport.ExportOptions) (io.ReadCloser, error) {
+		got = opts
+		return io.NopCloser(bytes.NewReader([]byte("zip"))), nil
+	}
+
+	orgUnit := "3f0c8a9e-1d2b-4c5d-8e7f-6a5b4c3d2e1f"
+	w := httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?template=ministry&as_of_version=7&org_unit="+orgUnit, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "ministry_export.zip")
+	assert.Equal(t, []string{"household"}, got.FormTypes)
+	assert.Equal(t, []string{"data_village", "data_head_name"}, got.Columns)
+	assert.Equal(t, dataexport.MaskHash, got.Masks[0].Method)
+	assert.NotEmpty(t, got.MaskKey)
+	assert.Equal(t, int64(7), got.AsOfVersion)
+	assert.Equal(t, orgUnit, got.OrgUnitID)
+
+	w = httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?template=unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/pkg/federation"
+)
+
+// FederationStatusResponse reports replication of an edge server with its upstream server
+type FederationStatusResponse struct {
+	// Enabled is false on a standalone server, which has no further status
+	Enabled bool `json:"enabled"`
+	*federation.Status
+}
+
+// GetFederationStatus handles GET /federation/status
+func (h *Handler) GetFederationStatus(w http.ResponseWriter, r *http.Request) {
+	if h.federationService == nil {
+		SendJSONResponse(w, http.StatusOK, FederationStatusResponse{Enabled: false})
+		return
+	}
+
+	status, err := h.federationService.Status(r.Context())
+	if err != nil {
+		h.log.Error("Failed to get federation status", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get federation status")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, FederationStatusResponse{Enabled: true, Status: status})
+}
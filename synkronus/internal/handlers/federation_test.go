@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/federation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFederationStatus(t *testing.T) {
+	h, _ := createTestHandler()
+	success := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	failure := success.Add(5 * time.Minute)
+	lastError := "upstream server unavailable: connection refused"
+	h.federationService.(*mocks.MockFederationService).SetStatus(federation.Status{
+		UpstreamURL:      "https://central.example.org",
+		ClientID:         "edge-1",
+		State:            federation.StateFailing,
+		UpstreamVersion:  1042,
+		LastAttemptAt:    &failure,
+		LastSuccessAt:    &success,
+		LastError:        &lastError,
+		LastErrorAt:      &failure,
+		PendingRecords:   17,
+		PendingConflicts: 2,
+	})
+
+	w := httptest.NewRecorder()
+	h.GetFederationStatus(w, httptest.NewRequest(http.MethodGet, "/federation/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]any
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, true, resp["enabled"])
+	assert.Equal(t, "failing", resp["state"])
+	assert.Equal(t, float64(1042), resp["upstream_version"])
+	assert.Equal(t, float64(17), resp["pending_records"])
+	assert.Equal(t, float64(2), resp["pending_conflicts"])
+	assert.Equal(t, lastError, resp["last_error"])
+	assert.Equal(t, false, resp["reachable"])
+}
+
+func TestGetFederationStatus_Standalone(t *testing.T) {
+	h, _ := createTestHandler()
+	h.federationService = nil
+
+	w := httptest.NewRecorder()
+	h.GetFederationStatus(w, httptest.NewRequest(http.MethodGet, "/federation/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]any
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, map[string]any{"enabled": false}, resp)
+}
+
+func TestGetFederationStatus_Error(t *testing.T) {
+	h, _ := createTestHandler()
+	h.federationService.(*mocks.MockFederationService).SetError(errors.New("database unavailable"))
+
+	w := httptest.NewRecorder()
+	h.GetFederationStatus(w, httptest.NewRequest(http.MethodGet, "/federation/status", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/formcomponent"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// ListFormComponents handles GET /form-components, listing the latest version of every shared
+// form component
+func (h *Handler) ListFormComponents(w http.ResponseWriter, r *http.Request) {
+	if h.formComponentService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Shared form components are not available")
+		return
+	}
+
+	components, err := h.formComponentService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list form components", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list form components")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, components)
+}
+
+// ListFormComponentVersions handles GET /form-components/{name}, listing every version of a
+// component, newest first
+func (h *Handler) ListFormComponentVersions(w http.ResponseWriter, r *http.Request) {
+	if h.formComponentService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Shared form components are not available")
+		return
+	}
+
+	versions, err := h.formComponentService.Versions(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		h.sendFormComponentError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, versions)
+}
+
+// GetFormComponent handles GET /form-components/{name}/versions/{version}; "latest" names the
+// latest version
+func (h *Handler) GetFormComponent(w http.ResponseWriter, r *http.Request) {
+	if h.formComponentService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Shared form components are not available")
+		return
+	}
+
+	version := 0
+	if text := chi.URLParam(r, "version"); text != "latest" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed < 1 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "version must be a positive number or latest")
+			return
+		}
+		version = parsed
+	}
+
+	component, err := h.formComponentService.Get(r.Context(), chi.URLParam(r, "name"), version)
+	if err != nil {
+		h.sendFormComponentError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, component)
+}
+
+// PublishFormComponent handles POST /form-components/{name} (admin only), adding the next
+// version of a component. Forms pick it up the next time a bundle including it is pushed.
+func (h *Handler) PublishFormComponent(w http.ResponseWriter, r *http.Request) {
+	if h.formComponentService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Shared form components are not available")
+		return
+	}
+
+	var input formcomponent.Input
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	createdBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		createdBy = user.Username
+	}
+
+	component, err := h.formComponentService.Publish(r.Context(), chi.URLParam(r, "name"), input, createdBy)
+	if err != nil {
+		h.sendFormComponentError(w, err)
+		return
+	}
+	SendJSONResponse(w, http.StatusCreated, component)
+}
+
+// sendFormComponentError answers a failed form component request
+func (h *Handler) sendFormComponentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, formcomponent.ErrNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Form component not found")
+	case errors.Is(err, formcomponent.ErrInvalidName), errors.Is(err, formcomponent.ErrInvalidComponent):
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+	default:
+		h.log.Error("Failed to access form components", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to access form components")
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/formcomponent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishFormComponent(h *Handler, name, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/form-components/"+name, bytes.NewBufferString(body))
+	h.PublishFormComponent(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", name))
+	return w
+}
+
+func TestFormComponents_PublishAndRead(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := publishFormComponent(h, "consent", `{"description":"Consent block","schema":{"properties":{"consent_given":{"type":"boolean"}}}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = publishFormComponent(h, "consent", `{"schema":{"properties":{"consent_given":{"type":"boolean"},"witness":{"type":"string"}},"required":["consent_given"]}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var published formcomponent.Component
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&published))
+	assert.Equal(t, 2, published.Version)
+	assert.Equal(t, "admin", published.CreatedBy)
+
+	w = httptest.NewRecorder()
+	h.ListFormComponents(w, httptest.NewRequest(http.MethodGet, "/form-components", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var latest []formcomponent.Component
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&latest))
+	require.Len(t, latest, 1)
+	assert.Equal(t, 2, latest[0].Version)
+
+	w = httptest.NewRecorder()
+	h.ListFormComponentVersions(w, withURLParams(httptest.NewRequest(http.MethodGet, "/form-components/consent", nil), "name", "consent"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var versions []formcomponent.Component
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version, "newest version first")
+
+	for version, want := range map[string]int{"1": http.StatusOK, "latest": http.StatusOK, "3": http.StatusNotFound, "first": http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/form-components/consent/versions/"+version, nil)
+		h.GetFormComponent(w, withURLParams(r, "name", "consent", "version", version))
+		assert.Equal(t, want, w.Code, "version %s", version)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListFormComponentVersions(w, withURLParams(httptest.NewRequest(http.MethodGet, "/form-components/unknown", nil), "name", "unknown"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFormComponents_PublishRejectsInvalidComponents(t *testing.T) {
+	h, _ := createTestHandler()
+
+	assert.Equal(t, http.StatusBadRequest, publishFormComponent(h, "Consent", `{"schema":{"properties":{"a":{}}}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishFormComponent(h, "consent", `{"schema":{"properties":{}}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishFormComponent(h, "consent", `{"schema":{"properties":{"a":{}},"required":["b"]}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishFormComponent(h, "consent", `{"schema":{"properties":{"a":{}}},"ui":{"x-include":"other"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, publishFormComponent(h, "consent", `not json`).Code)
+}
@@ -1,15 +1,43 @@
 package handlers
 
 import (
+	"github.com/opendataensemble/synkronus/pkg/apikey"
 	"github.com/opendataensemble/synkronus/pkg/appbundle"
 	"github.com/opendataensemble/synkronus/pkg/attachment"
+	"github.com/opendataensemble/synkronus/pkg/audit"
 	"github.com/opendataensemble/synkronus/pkg/auth"
+	"github.com/opendataensemble/synkronus/pkg/backup"
+	"github.com/opendataensemble/synkronus/pkg/bundlechannel"
+	"github.com/opendataensemble/synkronus/pkg/bundleupload"
+	"github.com/opendataensemble/synkronus/pkg/canary"
+	"github.com/opendataensemble/synkronus/pkg/codelist"
 	"github.com/opendataensemble/synkronus/pkg/config"
+	"github.com/opendataensemble/synkronus/pkg/database"
 	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/opendataensemble/synkronus/pkg/device"
+	"github.com/opendataensemble/synkronus/pkg/document"
+	"github.com/opendataensemble/synkronus/pkg/exporttemplate"
+	"github.com/opendataensemble/synkronus/pkg/federation"
+	"github.com/opendataensemble/synkronus/pkg/formcomponent"
+	"github.com/opendataensemble/synkronus/pkg/health"
+	"github.com/opendataensemble/synkronus/pkg/idempotency"
+	"github.com/opendataensemble/synkronus/pkg/importsource"
+	"github.com/opendataensemble/synkronus/pkg/invite"
+	"github.com/opendataensemble/synkronus/pkg/latency"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+	"github.com/opendataensemble/synkronus/pkg/replication"
+	"github.com/opendataensemble/synkronus/pkg/report"
+	"github.com/opendataensemble/synkronus/pkg/sampling"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+	"github.com/opendataensemble/synkronus/pkg/settings"
 	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/terms"
 	"github.com/opendataensemble/synkronus/pkg/user"
 	"github.com/opendataensemble/synkronus/pkg/version"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
 )
 
 // Handler manages all API endpoints
@@ -22,7 +50,247 @@ type Handler struct {
 	userService               user.UserServiceInterface
 	versionService            version.Service
 	attachmentManifestService attachment.ManifestService
+	attachmentLifecycle       attachment.LifecycleService
 	dataExportService         dataexport.Service
+	settingsService           settings.Service
+	orgUnitService            orgunit.Service
+	documentService           document.Service
+	federationService         federation.Reporter
+	savedQueryService         savedquery.Service
+	auditService              audit.Service
+	termsService              terms.Service
+	inviteService             invite.Service
+	webhookService            webhook.Service
+	apiKeyService             apikey.Service
+	backupService             backup.Service
+	exportTemplateService     exporttemplate.Service
+	idempotencyStore          idempotency.Store
+	latencyService            latency.Service
+	samplingService           sampling.Service
+	bundleChannelService      bundlechannel.Service
+	bundleUploadService       bundleupload.Service
+	importSourceService       importsource.Service
+	dataImportService         dataimport.Service
+	deviceService             device.Service
+	formComponentService      formcomponent.Service
+	codeListService           codelist.Service
+	reportService             report.Service
+	healthChecker             *health.Checker
+	canaryService             canary.Service
+	databasePool              database.PoolReporter
+	replicationService        replication.Service
+	authenticators            []authmw.Authenticator
+}
+
+// Option configures an optional service of a Handler
+type Option func(*Handler)
+
+// WithSettingsService sets the deployment settings service
+func WithSettingsService(settingsService settings.Service) Option {
+	return func(h *Handler) {
+		h.settingsService = settingsService
+	}
+}
+
+// WithOrgUnitService sets the org unit hierarchy service
+func WithOrgUnitService(orgUnitService orgunit.Service) Option {
+	return func(h *Handler) {
+		h.orgUnitService = orgUnitService
+	}
+}
+
+// WithDocumentService sets the supporting document service
+func WithDocumentService(documentService document.Service) Option {
+	return func(h *Handler) {
+		h.documentService = documentService
+	}
+}
+
+// WithFederationService sets the upstream federation of an edge server; without it the
+// server runs standalone
+func WithFederationService(federationService federation.Reporter) Option {
+	return func(h *Handler) {
+		h.federationService = federationService
+	}
+}
+
+// WithSavedQueryService sets the saved query service behind dashboard queries
+func WithSavedQueryService(savedQueryService savedquery.Service) Option {
+	return func(h *Handler) {
+		h.savedQueryService = savedQueryService
+	}
+}
+
+// WithAuditService sets the audit log that login and account management events are recorded in
+func WithAuditService(auditService audit.Service) Option {
+	return func(h *Handler) {
+		h.auditService = auditService
+	}
+}
+
+// WithTermsService sets the terms of use users must acknowledge before syncing
+func WithTermsService(termsService terms.Service) Option {
+	return func(h *Handler) {
+		h.termsService = termsService
+	}
+}
+
+// WithInviteService sets the service that invites users by email
+func WithInviteService(inviteService invite.Service) Option {
+	return func(h *Handler) {
+		h.inviteService = inviteService
+	}
+}
+
+// WithWebhookService sets the service delivering observations to webhook subscriptions
+func WithWebhookService(webhookService webhook.Service) Option {
+	return func(h *Handler) {
+		h.webhookService = webhookService
+	}
+}
+
+// WithAPIKeyService sets the service issuing API keys for machine-to-machine clients
+func WithAPIKeyService(apiKeyService apikey.Service) Option {
+	return func(h *Handler) {
+		h.apiKeyService = apiKeyService
+	}
+}
+
+// WithBackupService sets the service exporting and restoring server state for backups
+func WithBackupService(backupService backup.Service) Option {
+	return func(h *Handler) {
+		h.backupService = backupService
+	}
+}
+
+// WithExportTemplateService sets the service holding saved export templates
+func WithExportTemplateService(exportTemplateService exporttemplate.Service) Option {
+	return func(h *Handler) {
+		h.exportTemplateService = exportTemplateService
+	}
+}
+
+// WithIdempotencyStore sets the store that recognizes retried sync push transmissions
+func WithIdempotencyStore(idempotencyStore idempotency.Store) Option {
+	return func(h *Handler) {
+		h.idempotencyStore = idempotencyStore
+	}
+}
+
+// WithAttachmentLifecycleService sets the service moving aging attachments to cold storage and
+// reporting where their content is kept
+func WithAttachmentLifecycleService(lifecycle attachment.LifecycleService) Option {
+	return func(h *Handler) {
+		h.attachmentLifecycle = lifecycle
+	}
+}
+
+// WithLatencyService sets the service tracking pull, push and export latencies
+func WithLatencyService(latencyService latency.Service) Option {
+	return func(h *Handler) {
+		h.latencyService = latencyService
+	}
+}
+
+// WithSamplingService sets the service drawing samples of observations for QA back-checks
+func WithSamplingService(samplingService sampling.Service) Option {
+	return func(h *Handler) {
+		h.samplingService = samplingService
+	}
+}
+
+// WithDataImportService sets the service importing observations from CSV files
+func WithDataImportService(dataImportService dataimport.Service) Option {
+	return func(h *Handler) {
+		h.dataImportService = dataImportService
+	}
+}
+
+// WithImportSourceService sets the service pulling submissions from ODK Central and KoboToolbox
+func WithImportSourceService(importSourceService importsource.Service) Option {
+	return func(h *Handler) {
+		h.importSourceService = importSourceService
+	}
+}
+
+// WithBundleChannelService sets the service assigning clients to app bundle channels
+func WithBundleChannelService(bundleChannelService bundlechannel.Service) Option {
+	return func(h *Handler) {
+		h.bundleChannelService = bundleChannelService
+	}
+}
+
+// WithBundleUploadService sets the service keeping app bundles uploaded in chunks
+func WithBundleUploadService(bundleUploadService bundleupload.Service) Option {
+	return func(h *Handler) {
+		h.bundleUploadService = bundleUploadService
+	}
+}
+
+// WithDeviceService sets the registry of clients seen in sync, which records their last sync and
+// refuses suspended and blocked ones
+func WithDeviceService(deviceService device.Service) Option {
+	return func(h *Handler) {
+		h.deviceService = deviceService
+	}
+}
+
+// WithFormComponentService sets the library of shared form components bundles include
+func WithFormComponentService(formComponentService formcomponent.Service) Option {
+	return func(h *Handler) {
+		h.formComponentService = formComponentService
+	}
+}
+
+// WithCodeListService sets the managed code lists form fields reference and clients sync
+func WithCodeListService(codeListService codelist.Service) Option {
+	return func(h *Handler) {
+		h.codeListService = codeListService
+	}
+}
+
+// WithReportService sets the service rendering report templates and delivering them on schedule
+func WithReportService(reportService report.Service) Option {
+	return func(h *Handler) {
+		h.reportService = reportService
+	}
+}
+
+// WithHealthChecker sets the dependency checks of the readiness endpoint
+func WithHealthChecker(healthChecker *health.Checker) Option {
+	return func(h *Handler) {
+		h.healthChecker = healthChecker
+	}
+}
+
+// WithCanaryService sets the service validating app bundle versions against recent observations
+func WithCanaryService(canaryService canary.Service) Option {
+	return func(h *Handler) {
+		h.canaryService = canaryService
+	}
+}
+
+// WithDatabasePool sets the connection pool whose statistics GET /stats/database reports
+func WithDatabasePool(databasePool database.PoolReporter) Option {
+	return func(h *Handler) {
+		h.databasePool = databasePool
+	}
+}
+
+// WithReplicationService sets the replication of app bundle versions and attachments to a
+// secondary location; without it the status endpoint reports replication as not configured
+func WithReplicationService(replicationService replication.Service) Option {
+	return func(h *Handler) {
+		h.replicationService = replicationService
+	}
+}
+
+// WithAuthenticators sets the authenticators tried ahead of API keys and JWTs, for deployments
+// behind an SSO gateway or requiring client certificates
+func WithAuthenticators(authenticators []authmw.Authenticator) Option {
+	return func(h *Handler) {
+		h.authenticators = authenticators
+	}
 }
 
 // NewHandler creates a new Handler instance
@@ -36,8 +304,9 @@ func NewHandler(
 	versionService version.Service,
 	attachmentManifestService attachment.ManifestService,
 	dataExportService dataexport.Service,
+	opts ...Option,
 ) *Handler {
-	return &Handler{
+	h := &Handler{
 		log:                       log,
 		config:                    config,
 		authService:               authService,
@@ -48,6 +317,12 @@ func NewHandler(
 		attachmentManifestService: attachmentManifestService,
 		dataExportService:         dataExportService,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 // GetAuthService returns the auth service
@@ -55,6 +330,26 @@ func (h *Handler) GetAuthService() auth.AuthServiceInterface {
 	return h.authService
 }
 
+// GetAPIKeyService returns the API key service, or nil when API keys are not enabled
+func (h *Handler) GetAPIKeyService() apikey.Service {
+	return h.apiKeyService
+}
+
+// GetAuthenticators returns the authenticators tried ahead of API keys and JWTs
+func (h *Handler) GetAuthenticators() []authmw.Authenticator {
+	return h.authenticators
+}
+
+// GetLatencyService returns the latency service, or nil when latencies are not tracked
+func (h *Handler) GetLatencyService() latency.Service {
+	return h.latencyService
+}
+
+// GetAttachmentManifestService returns the attachment manifest service
+func (h *Handler) GetAttachmentManifestService() attachment.ManifestService {
+	return h.attachmentManifestService
+}
+
 // GetConfig returns the application configuration
 func (h *Handler) GetConfig() *config.Config {
 	return h.config
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/health"
 )
 
 // HealthCheck handles the /health endpoint
@@ -24,3 +27,34 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// Liveness handles GET /health/live. It answers as long as the process serves requests and
+// checks no dependencies, so an orchestrator restarts the server only when it hangs.
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	SendJSONResponse(w, http.StatusOK, health.Report{
+		Status: health.StatusOK,
+		Checks: []health.Result{},
+		Time:   time.Now().UTC(),
+	})
+}
+
+// Readiness handles GET /health/ready, checking the database, its migrations and the app
+// bundle directory. It answers 503 while any check fails, so load balancers stop routing
+// requests to the server without restarting it.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusOK, Checks: []health.Result{}, Time: time.Now().UTC()}
+	if h.healthChecker != nil {
+		report = h.healthChecker.Ready(r.Context())
+	}
+
+	if report.Status != health.StatusOK {
+		for _, check := range report.Checks {
+			if check.Status != health.StatusOK {
+				h.log.Warn("Readiness check failed", "check", check.Name, "error", check.Error)
+			}
+		}
+		SendJSONResponse(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, report)
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/opendataensemble/synkronus/pkg/health"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHealthCheck(t *testing.T) {
@@ -36,3 +41,43 @@ func TestHealthCheck(t *testing.T) {
 	assert.NoError(t, err, "Failed to read response body")
 	assert.Equal(t, "OK", string(body), "Expected response body 'OK', got '%s'")
 }
+
+func TestLiveness(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.Liveness(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, health.StatusOK, report.Status)
+}
+
+func TestReadiness(t *testing.T) {
+	h, _ := createTestHandler()
+	ready := func() (*httptest.ResponseRecorder, health.Report) {
+		w := httptest.NewRecorder()
+		h.Readiness(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		var report health.Report
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return w, report
+	}
+
+	w, report := ready()
+	assert.Equal(t, http.StatusOK, w.Code, "without checks the server is ready")
+	assert.Empty(t, report.Checks)
+
+	dir := t.TempDir()
+	WithHealthChecker(health.NewChecker(
+		health.WritableDir("app_bundle_dir", dir),
+		health.Check{Name: "database", Run: func(ctx context.Context) error { return errors.New("database is unreachable") }},
+	))(h)
+	w, report = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, health.StatusFailed, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, "app_bundle_dir", report.Checks[0].Name)
+	assert.Equal(t, health.StatusOK, report.Checks[0].Status)
+	assert.Equal(t, "database is unreachable", report.Checks[1].Error)
+}
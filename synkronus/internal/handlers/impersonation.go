@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/auth"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// ImpersonateRequest represents the request body for impersonating a user
+type ImpersonateRequest struct {
+	Username string `json:"username"`
+	// ExpiresInMinutes is the token lifetime; zero selects the default of 30 minutes
+	ExpiresInMinutes int `json:"expiresInMinutes"`
+}
+
+// ImpersonateResponse represents a token acting as another user
+type ImpersonateResponse struct {
+	Token        string      `json:"token"`
+	ExpiresAt    int64       `json:"expiresAt"`
+	Username     string      `json:"username"`
+	Role         models.Role `json:"role"`
+	Impersonator string      `json:"impersonator"`
+}
+
+// canImpersonate reports whether username is listed in IMPERSONATION_ADMINS
+func (h *Handler) canImpersonate(username string) bool {
+	for _, admin := range strings.Split(h.config.ImpersonationAdmins, ",") {
+		if strings.TrimSpace(admin) == username {
+			return true
+		}
+	}
+	return false
+}
+
+// ImpersonateUserHandler handles POST /users/impersonate (admin only). Only admins listed in
+// IMPERSONATION_ADMINS may impersonate; the token carries their username in its "act" claim
+// and everything done with it is logged and audited as impersonated.
+func (h *Handler) ImpersonateUserHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || admin == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	if !h.canImpersonate(admin.Username) {
+		SendErrorResponse(w, http.StatusForbidden, auth.ErrInsufficientPermissions, "Impersonation is limited to the admins in IMPERSONATION_ADMINS")
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	if req.Username == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "Username is required")
+		return
+	}
+	if req.ExpiresInMinutes < 0 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "expiresInMinutes must not be negative")
+		return
+	}
+
+	token, claims, err := h.authService.GenerateImpersonationToken(r.Context(), req.Username, admin.Username,
+		time.Duration(req.ExpiresInMinutes)*time.Minute)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "User not found")
+		case errors.Is(err, auth.ErrCannotImpersonate):
+			SendErrorResponse(w, http.StatusForbidden, err, "Admins and yourself cannot be impersonated")
+		default:
+			h.log.Error("Failed to generate impersonation token", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to generate impersonation token")
+		}
+		return
+	}
+
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventImpersonationStarted, Username: claims.Username, Role: string(claims.Role)})
+	SendJSONResponse(w, http.StatusCreated, ImpersonateResponse{
+		Token:        token,
+		ExpiresAt:    claims.ExpiresAt.Unix(),
+		Username:     claims.Username,
+		Role:         claims.Role,
+		Impersonator: admin.Username,
+	})
+}
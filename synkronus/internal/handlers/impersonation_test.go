@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func impersonate(h *Handler, admin, username string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(ImpersonateRequest{Username: username})
+	w := httptest.NewRecorder()
+	h.ImpersonateUserHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/users/impersonate", bytes.NewReader(body)), admin, models.RoleAdmin))
+	return w
+}
+
+func TestImpersonateUser(t *testing.T) {
+	h, _ := createTestHandler()
+
+	// Disabled until admins are listed
+	assert.Equal(t, http.StatusForbidden, impersonate(h, "admin", "testuser").Code)
+
+	h.config.ImpersonationAdmins = "support, admin"
+	w := impersonate(h, "admin", "testuser")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp ImpersonateResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "testuser", resp.Username)
+	assert.Equal(t, "admin", resp.Impersonator)
+	assert.NotZero(t, resp.ExpiresAt)
+
+	assert.Equal(t, http.StatusNotFound, impersonate(h, "admin", "nobody").Code)
+	assert.Equal(t, http.StatusForbidden, impersonate(h, "support", "admin").Code)
+	assert.Equal(t, http.StatusForbidden, impersonate(h, "other-admin", "testuser").Code)
+
+	events := h.auditService.(*mocks.MockAuditService).Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.EventImpersonationStarted, events[0].Type)
+	assert.Equal(t, "testuser", events[0].Username)
+	assert.Equal(t, "admin", events[0].Actor)
+}
+
+func TestImpersonatedRequestsAreFlagged(t *testing.T) {
+	h, _ := createTestHandler()
+	h.config.ImpersonationAdmins = "admin"
+	var resp ImpersonateResponse
+	require.NoError(t, json.NewDecoder(impersonate(h, "admin", "testuser").Body).Decode(&resp))
+
+	// Requests made with the token act as the user and carry the impersonator in audit events
+	var events []audit.Event
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "testuser", authmw.GetUserFromContext(r.Context()).Username)
+		h.recordAuthEvent(r, audit.Event{Type: audit.EventPasswordChanged, Username: "testuser"})
+		events = h.auditService.(*mocks.MockAuditService).Events()
+	})
+	r := httptest.NewRequest(http.MethodPost, "/users/change-password", nil)
+	r.Header.Set("Authorization", "Bearer "+resp.Token)
+	w := httptest.NewRecorder()
+	authmw.AuthMiddleware(h.GetAuthService(), h.log)(next).ServeHTTP(w, r)
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "admin", events[1].Impersonator)
+	assert.Empty(t, events[1].Actor)
+}
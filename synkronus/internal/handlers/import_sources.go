@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/opendataensemble/synkronus/pkg/importsource"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// CreateImportSource handles POST /data/import/sources, saving an ODK Central or KoboToolbox
+// form whose submissions are pulled into data imports, once or on a schedule
+func (h *Handler) CreateImportSource(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	if h.importSourceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Import sources are not available")
+		return
+	}
+
+	var input importsource.SourceInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	source, err := h.importSourceService.Create(r.Context(), input, user.Username)
+	if err != nil {
+		if errors.Is(err, importsource.ErrInvalidSource) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to create import source", "error", err, "user", user.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to create import source")
+		return
+	}
+
+	w.Header().Set("Location", "/data/import/sources/"+source.ID)
+	SendJSONResponse(w, http.StatusCreated, source)
+}
+
+// ListImportSources handles GET /data/import/sources
+func (h *Handler) ListImportSources(w http.ResponseWriter, r *http.Request) {
+	if h.importSourceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Import sources are not available")
+		return
+	}
+	sources, err := h.importSourceService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list import sources", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list import sources")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, map[string]any{"sources": sources})
+}
+
+// GetImportSource handles GET /data/import/sources/{id}, including the outcome of the last pull
+func (h *Handler) GetImportSource(w http.ResponseWriter, r *http.Request) {
+	if h.importSourceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Import sources are not available")
+		return
+	}
+	source, err := h.importSourceService.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, importsource.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Import source not found")
+			return
+		}
+		h.log.Error("Failed to get import source", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get import source")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, source)
+}
+
+// DeleteImportSource handles DELETE /data/import/sources/{id}; imports already queued are kept
+func (h *Handler) DeleteImportSource(w http.ResponseWriter, r *http.Request) {
+	if h.importSourceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Import sources are not available")
+		return
+	}
+	if err := h.importSourceService.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, importsource.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Import source not found")
+			return
+		}
+		h.log.Error("Failed to delete import source", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete import source")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PullImportSource handles POST /data/import/sources/{id}/pull, fetching the submissions received
+// since the last pull and queuing them as a data import without waiting for the schedule. It
+// answers 204 No Content when there are no new submissions.
+func (h *Handler) PullImportSource(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	if h.importSourceService == nil {
+		SendErrorResponse(w, http.StatusNotImplemented, nil, "Import sources are not available")
+		return
+	}
+
+	job, err := h.importSourceService.Pull(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, importsource.ErrNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "Import source not found")
+		case errors.Is(err, importsource.ErrFetchFailed):
+			SendErrorResponse(w, http.StatusBadGateway, err, err.Error())
+		case errors.Is(err, dataimport.ErrInvalidImport):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		default:
+			h.log.Error("Failed to pull import source", "error", err, "user", user.Username)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to pull import source")
+		}
+		return
+	}
+	if job == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.recordAudit(r, audit.Entry{Action: audit.ActionDataImport, Resource: "data/import/" + job.ID})
+
+	w.Header().Set("Location", "/data/import/"+job.ID)
+	SendJSONResponse(w, http.StatusAccepted, job)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/opendataensemble/synkronus/pkg/importsource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportSources(t *testing.T) {
+	h, _ := createTestHandler()
+	sources := mocks.NewMockImportSourceService()
+	WithImportSourceService(sources)(h)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/data/import/sources", bytes.NewBufferString(body))
+		h.CreateImportSource(w, withRole(r, "admin", models.RoleAdmin))
+		return w
+	}
+	pull := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/data/import/sources/"+id+"/pull", nil)
+		h.PullImportSource(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "id", id))
+		return w
+	}
+
+	w := create(`{"name":"Household survey","kind":"odk-central","url":"https://central.example.org/","project_id":"3","form_id":"household","username":"admin@example.org","password":"secret","mapping":{"form_type":"survey","columns":{"name":"name"}},"interval_minutes":60}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "secret")
+	var source importsource.Source
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&source))
+	assert.Equal(t, "https://central.example.org", source.URL)
+	assert.Equal(t, "__id", source.Mapping.ObservationIDColumn)
+	assert.Equal(t, "__system/submissionDate", source.Mapping.CreatedAtColumn)
+
+	w = create(`{"name":"Kobo","kind":"kobo","url":"ftp://kf.example.org","form_id":"aBc","password":"token","mapping":{"form_type":"survey"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "url must be an http or https URL")
+
+	// No new submissions
+	w = pull(source.ID)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	sources.Pending = 2
+	w = pull(source.ID)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var job dataimport.Job
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	assert.Equal(t, "/data/import/"+job.ID, w.Header().Get("Location"))
+	assert.Equal(t, 2, job.TotalRows)
+
+	w = httptest.NewRecorder()
+	h.GetImportSource(w, withURLParams(httptest.NewRequest(http.MethodGet, "/data/import/sources/"+source.ID, nil), "id", source.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&source))
+	require.NotNil(t, source.LastImportID)
+	assert.Equal(t, job.ID, *source.LastImportID)
+
+	w = httptest.NewRecorder()
+	h.ListImportSources(w, httptest.NewRequest(http.MethodGet, "/data/import/sources", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), source.ID)
+
+	// Servers that cannot be reached fail the pull
+	w = create(`{"name":"Old server","kind":"kobo","url":"https://kobo.invalid","form_id":"aBc","password":"token","mapping":{"form_type":"survey"}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var unreachable importsource.Source
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&unreachable))
+	assert.Equal(t, "_uuid", unreachable.Mapping.ObservationIDColumn)
+	assert.Equal(t, http.StatusBadGateway, pull(unreachable.ID).Code)
+
+	w = httptest.NewRecorder()
+	h.DeleteImportSource(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/data/import/sources/"+source.ID, nil), "id", source.ID))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNotFound, pull(source.ID).Code)
+}
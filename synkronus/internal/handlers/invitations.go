@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/invite"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/user"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
+)
+
+// InviteUserRequest represents the request body for inviting a user
+type InviteUserRequest struct {
+	Username string      `json:"username"`
+	Email    string      `json:"email"`
+	Role     models.Role `json:"role"`
+}
+
+// AcceptInvitationRequest represents the request body for accepting an invitation
+type AcceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// InviteUserHandler handles POST /users/invite (admin only)
+func (h *Handler) InviteUserHandler(w http.ResponseWriter, r *http.Request) {
+	var req InviteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	if req.Username == "" || req.Email == "" || req.Role == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "Missing required fields")
+		return
+	}
+
+	invitedBy := ""
+	if u, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && u != nil {
+		invitedBy = u.Username
+	}
+
+	inv, err := h.inviteService.Invite(r.Context(), req.Username, req.Email, req.Role, invitedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserExists):
+			SendErrorResponse(w, http.StatusConflict, err, "Username already exists")
+		case errors.Is(err, user.ErrInvalidRole), errors.Is(err, invite.ErrInvalidEmail):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		case errors.Is(err, invite.ErrEmailNotConfigured):
+			SendErrorResponse(w, http.StatusServiceUnavailable, err, "Invitations need an SMTP server; set SMTP_HOST and SMTP_FROM")
+		default:
+			h.log.Error("Failed to invite user", "error", err, "username", req.Username)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to send invitation")
+		}
+		return
+	}
+
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventUserInvited, Username: inv.Username, Role: string(inv.Role)})
+	SendJSONResponse(w, http.StatusCreated, inv)
+}
+
+// ListInvitationsHandler handles GET /users/invitations (admin only)
+func (h *Handler) ListInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.inviteService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list invitations", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list invitations")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, invitations)
+}
+
+// RevokeInvitationHandler handles DELETE /users/invitations/{id} (admin only)
+func (h *Handler) RevokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.inviteService.Revoke(r.Context(), chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, invite.ErrInvitationNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Invitation not found")
+			return
+		}
+		h.log.Error("Failed to revoke invitation", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to revoke invitation")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Invitation revoked"})
+}
+
+// AcceptInvitation handles POST /auth/accept-invite. The invitee chooses their password, the
+// account is created and they are logged in.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "Token and password are required")
+		return
+	}
+
+	newUser, inv, err := h.inviteService.Accept(r.Context(), req.Token, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, invite.ErrInvalidInvitation):
+			SendErrorResponse(w, http.StatusUnauthorized, err, "Invitation is invalid or has expired")
+		case errors.Is(err, user.ErrUserExists):
+			SendErrorResponse(w, http.StatusConflict, err, "Username already exists")
+		case errors.Is(err, user.ErrInvalidPassword):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		default:
+			h.log.Error("Failed to accept invitation", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to accept invitation")
+		}
+		return
+	}
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventUserCreated, Username: newUser.Username, Actor: inv.InvitedBy, Role: string(newUser.Role)})
+	// The invitee makes this request themselves, without a token
+	h.recordAudit(r, audit.Entry{Username: newUser.Username, Role: string(newUser.Role), Action: audit.ActionUserCreate, Resource: "users/" + newUser.Username})
+	h.notifyWebhooks(r, webhook.Payload{
+		Event: webhook.EventUserCreated,
+		User:  &webhook.UserEvent{Username: newUser.Username, Role: string(newUser.Role), CreatedBy: inv.InvitedBy},
+	})
+
+	token, err := h.authService.GenerateToken(newUser)
+	if err != nil {
+		h.log.Error("Failed to generate token", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to generate token")
+		return
+	}
+	refreshToken, err := h.authService.GenerateRefreshToken(r.Context(), newUser, sessionClient(r))
+	if err != nil {
+		h.log.Error("Failed to generate refresh token", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to generate refresh token")
+		return
+	}
+
+	h.log.Info("Invitation accepted", "username", newUser.Username)
+	SendJSONResponse(w, http.StatusCreated, LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(h.authService.Config().TokenExpiration).Unix(),
+	})
+}
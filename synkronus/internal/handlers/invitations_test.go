@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/invite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inviteUser(h *Handler, username, email string, role models.Role) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(InviteUserRequest{Username: username, Email: email, Role: role})
+	w := httptest.NewRecorder()
+	h.InviteUserHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/users/invite", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	return w
+}
+
+func acceptInvitation(h *Handler, token, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(AcceptInvitationRequest{Token: token, Password: password})
+	w := httptest.NewRecorder()
+	h.AcceptInvitation(w, httptest.NewRequest(http.MethodPost, "/auth/accept-invite", bytes.NewReader(body)))
+	return w
+}
+
+func TestInviteUser(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := inviteUser(h, "amina", "amina@example.org", models.RoleReadWrite)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var inv invite.Invitation
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&inv))
+	assert.Equal(t, "amina", inv.Username)
+	assert.Equal(t, "admin", inv.InvitedBy)
+	assert.Nil(t, inv.AcceptedAt)
+
+	assert.Equal(t, http.StatusBadRequest, inviteUser(h, "bob", "not an address", models.RoleReadWrite).Code)
+	assert.Equal(t, http.StatusBadRequest, inviteUser(h, "bob", "bob@example.org", "superuser").Code)
+	assert.Equal(t, http.StatusBadRequest, inviteUser(h, "", "bob@example.org", models.RoleReadWrite).Code)
+
+	events := h.auditService.(*mocks.MockAuditService).Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.EventUserInvited, events[0].Type)
+	assert.Equal(t, "admin", events[0].Actor)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	h, _ := createTestHandler()
+	inviteService := h.inviteService.(*mocks.MockInviteService)
+
+	require.Equal(t, http.StatusCreated, inviteUser(h, "amina", "amina@example.org", models.RoleAdmin).Code)
+	token := inviteService.Token("amina")
+	require.NotEmpty(t, token)
+
+	assert.Equal(t, http.StatusUnauthorized, acceptInvitation(h, "wrong-token", "chosen-password").Code)
+	assert.Equal(t, http.StatusBadRequest, acceptInvitation(h, token, "").Code)
+
+	w := acceptInvitation(h, token, "chosen-password")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp LoginResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+
+	// Tokens are single use and the username is now taken
+	assert.Equal(t, http.StatusUnauthorized, acceptInvitation(h, token, "chosen-password").Code)
+	assert.Equal(t, http.StatusConflict, inviteUser(h, "amina", "amina@example.org", models.RoleAdmin).Code)
+
+	// The account creation is attributed to the inviting admin
+	events := h.auditService.(*mocks.MockAuditService).Events()
+	require.Len(t, events, 2)
+	created := events[1]
+	assert.Equal(t, audit.Event{ID: created.ID, Type: audit.EventUserCreated, Username: "amina", Actor: "admin", Role: "admin", IP: "192.0.2.1", At: created.At}, created)
+}
+
+func TestListAndRevokeInvitations(t *testing.T) {
+	h, _ := createTestHandler()
+	inviteUser(h, "amina", "amina@example.org", models.RoleReadWrite)
+	w := inviteUser(h, "amina", "amina@example.net", models.RoleReadOnly)
+	var replacement invite.Invitation
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&replacement))
+
+	list := func() []invite.Invitation {
+		w := httptest.NewRecorder()
+		h.ListInvitationsHandler(w, httptest.NewRequest(http.MethodGet, "/users/invitations", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var invitations []invite.Invitation
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&invitations))
+		return invitations
+	}
+
+	// Inviting the same username again replaces the pending invitation
+	invitations := list()
+	require.Len(t, invitations, 1)
+	assert.Equal(t, "amina@example.net", invitations[0].Email)
+
+	revoke := func(id string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "/users/invitations/"+id, nil)
+		h.RevokeInvitationHandler(w, withURLParams(r, "id", id))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, revoke(replacement.ID))
+	assert.Equal(t, http.StatusNotFound, revoke(replacement.ID))
+	assert.Empty(t, list())
+}
@@ -0,0 +1,111 @@
+package mocks
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/apikey"
+)
+
+// MockAPIKeyService is an in-memory implementation of apikey.Service for testing; key owners
+// have the read-write role unless set with SetRole
+type MockAPIKeyService struct {
+	keys    []apikey.APIKey
+	secrets map[string]string // key -> key ID
+	revoked map[string]bool
+	roles   map[string]models.Role
+}
+
+// NewMockAPIKeyService creates a new mock API key service
+func NewMockAPIKeyService() *MockAPIKeyService {
+	return &MockAPIKeyService{
+		secrets: make(map[string]string),
+		revoked: make(map[string]bool),
+		roles:   make(map[string]models.Role),
+	}
+}
+
+// SetRole sets the role requests made with username's keys act with
+func (m *MockAPIKeyService) SetRole(username string, role models.Role) {
+	m.roles[username] = role
+}
+
+// Create implements apikey.Service
+func (m *MockAPIKeyService) Create(ctx context.Context, username, name string, expiresAt *time.Time) (*apikey.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", apikey.ErrInvalidName
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", apikey.ErrInvalidExpiry
+	}
+
+	id := uuid.NewString()
+	secret := apikey.Prefix + "mock-" + id
+	key := apikey.APIKey{
+		ID:        id,
+		Name:      name,
+		Username:  username,
+		KeyPrefix: secret[:len(apikey.Prefix)+8],
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if expiresAt != nil {
+		formatted := expiresAt.UTC().Format(time.RFC3339)
+		key.ExpiresAt = &formatted
+	}
+	m.keys = append(m.keys, key)
+	m.secrets[secret] = id
+	return &key, secret, nil
+}
+
+// List implements apikey.Service
+func (m *MockAPIKeyService) List(ctx context.Context, username string) ([]apikey.APIKey, error) {
+	keys := make([]apikey.APIKey, 0)
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		key := m.keys[i]
+		if !m.revoked[key.ID] && (username == "" || key.Username == username) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Revoke implements apikey.Service
+func (m *MockAPIKeyService) Revoke(ctx context.Context, id, username string) (*apikey.APIKey, error) {
+	for _, key := range m.keys {
+		if key.ID == id && !m.revoked[id] && (username == "" || key.Username == username) {
+			m.revoked[id] = true
+			return &key, nil
+		}
+	}
+	return nil, apikey.ErrKeyNotFound
+}
+
+// Authenticate implements apikey.Service
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, secret string) (*models.User, *apikey.APIKey, error) {
+	id, ok := m.secrets[secret]
+	if !ok || m.revoked[id] {
+		return nil, nil, apikey.ErrInvalidKey
+	}
+	for i, key := range m.keys {
+		if key.ID != id {
+			continue
+		}
+		if key.ExpiresAt != nil {
+			if expiresAt, err := time.Parse(time.RFC3339, *key.ExpiresAt); err == nil && !expiresAt.After(time.Now()) {
+				return nil, nil, apikey.ErrInvalidKey
+			}
+		}
+		usedAt := time.Now().UTC().Format(time.RFC3339)
+		m.keys[i].LastUsedAt = &usedAt
+
+		role, ok := m.roles[key.Username]
+		if !ok {
+			role = models.RoleReadWrite
+		}
+		return &models.User{Username: key.Username, Role: role}, &m.keys[i], nil
+	}
+	return nil, nil, apikey.ErrInvalidKey
+}
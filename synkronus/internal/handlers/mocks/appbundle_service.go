@@ -1,8 +1,10 @@
 package mocks
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"path/filepath"
 	"strings"
@@ -15,6 +17,16 @@ import (
 type MockAppBundleService struct {
 	manifest *appbundle.Manifest
 	files    map[string]*mockFile
+	preview  string
+
+	// Signature, when set, is the only signature PushBundle accepts
+	Signature string
+	// Pushed is the content of the last bundle PushBundle accepted
+	Pushed []byte
+	// History are manifests of earlier versions FindManifest finds besides the current one
+	History []*appbundle.Manifest
+	// Requirements are what GetAppInfo reports the versions declared in bundle.json
+	Requirements map[string]*appbundle.BundleMetadata
 }
 
 type mockFile struct {
@@ -52,6 +64,7 @@ func (m *MockAppBundleService) AddFile(path string, content []byte, mimeType str
 			Hash:     "mock-hash-" + path, // Simple mock hash
 			MimeType: mimeType,
 			ModTime:  modTime,
+			URL:      appbundle.HashedFilePath("mock-hash-"+path, path),
 		},
 	}
 }
@@ -103,6 +116,19 @@ func (m *MockAppBundleService) GetLatestVersionFile(ctx context.Context, path st
 	return m.GetFile(ctx, path)
 }
 
+// GetVersionFile returns a file of a listed version; every listed version has the same files
+func (m *MockAppBundleService) GetVersionFile(ctx context.Context, version, path string) (io.ReadCloser, *appbundle.File, error) {
+	versions, _ := m.GetVersions(ctx)
+	found := false
+	for _, v := range versions {
+		found = found || strings.TrimSuffix(v, " *") == version
+	}
+	if !found {
+		return nil, nil, appbundle.ErrFileNotFound
+	}
+	return m.GetFile(ctx, path)
+}
+
 // GetFileHash returns the hash for a specific file
 func (m *MockAppBundleService) GetFileHash(ctx context.Context, path string, useLatest bool) (string, error) {
 	file, exists := m.files[path]
@@ -120,7 +146,18 @@ func (m *MockAppBundleService) RefreshManifest() error {
 }
 
 // PushBundle uploads a new app bundle from a zip file
-func (m *MockAppBundleService) PushBundle(ctx context.Context, zipReader io.Reader) (*appbundle.Manifest, error) {
+func (m *MockAppBundleService) PushBundle(ctx context.Context, zipReader io.Reader, signature string) (*appbundle.Manifest, error) {
+	if m.Signature != "" && signature == "" {
+		return nil, appbundle.ErrSignatureRequired
+	}
+	if signature != m.Signature {
+		return nil, appbundle.ErrInvalidSignature
+	}
+	pushed, err := io.ReadAll(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	m.Pushed = pushed
 	// For testing, just return the current manifest
 	return m.manifest, nil
 }
@@ -147,12 +184,117 @@ func (m *MockAppBundleService) SwitchVersion(ctx context.Context, version string
 	return nil
 }
 
+// StagePreview stages a version for the preview channel
+func (m *MockAppBundleService) StagePreview(ctx context.Context, version string) error {
+	m.preview = version
+	return nil
+}
+
+// GetStagedPreview returns the version staged for preview
+func (m *MockAppBundleService) GetStagedPreview(ctx context.Context) (string, error) {
+	return m.preview, nil
+}
+
+// PromotePreview switches to the staged preview version
+func (m *MockAppBundleService) PromotePreview(ctx context.Context) (string, error) {
+	if m.preview == "" {
+		return "", appbundle.ErrNoPreview
+	}
+	version := m.preview
+	m.preview = ""
+	return version, m.SwitchVersion(ctx, version)
+}
+
+// GetPreviewManifest returns the current manifest labelled with the staged preview version
+func (m *MockAppBundleService) GetPreviewManifest(ctx context.Context) (*appbundle.Manifest, error) {
+	if m.preview == "" {
+		return m.manifest, nil
+	}
+	manifest := *m.manifest
+	manifest.Version = m.preview
+	manifest.Hash = "mock-preview-manifest-hash"
+	return &manifest, nil
+}
+
+// GetPreviewFile returns a file of the preview version; the mock's versions share their files
+func (m *MockAppBundleService) GetPreviewFile(ctx context.Context, path string) (io.ReadCloser, *appbundle.File, error) {
+	return m.GetFile(ctx, path)
+}
+
+// FindManifest returns the current manifest or one of History with the given hash
+func (m *MockAppBundleService) FindManifest(ctx context.Context, hash string) (*appbundle.Manifest, error) {
+	for _, manifest := range append([]*appbundle.Manifest{m.manifest}, m.History...) {
+		if manifest.Hash == hash {
+			return manifest, nil
+		}
+	}
+	return nil, appbundle.ErrVersionNotFound
+}
+
+// WriteDiff writes DIFF.json and the changed files from the mock's files
+func (m *MockAppBundleService) WriteDiff(ctx context.Context, diff *appbundle.Diff, w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	dst, err := zipWriter.Create(appbundle.DiffFile)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(dst).Encode(diff); err != nil {
+		return err
+	}
+	for _, changed := range diff.Changed {
+		file, exists := m.files[changed.Path]
+		if !exists {
+			return appbundle.ErrFileNotFound
+		}
+		dst, err := zipWriter.Create(changed.Path)
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(file.content); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// ExportVersion writes the mock's files as a zip; every listed version has the same files
+func (m *MockAppBundleService) ExportVersion(ctx context.Context, version string, w io.Writer) error {
+	versions, _ := m.GetVersions(ctx)
+	found := false
+	for _, v := range versions {
+		found = found || v == version
+	}
+	if !found {
+		return appbundle.ErrVersionNotFound
+	}
+
+	zipWriter := zip.NewWriter(w)
+	for path, file := range m.files {
+		dst, err := zipWriter.Create(path)
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(file.content); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
 // GetAppInfo retrieves the app info for a specific version
 func (m *MockAppBundleService) GetAppInfo(ctx context.Context, version string) (*appbundle.AppInfo, error) {
-	// Return a mock AppInfo
+	// Every form with a schema.json among the mock's files is listed, without fields
+	forms := make(map[string]appbundle.FormInfo)
+	for path := range m.files {
+		parts := strings.Split(path, "/")
+		if len(parts) == 3 && parts[0] == "forms" && parts[2] == "schema.json" {
+			forms[parts[1]] = appbundle.FormInfo{}
+		}
+	}
 	return &appbundle.AppInfo{
 		Version: version,
-		Forms:   make(map[string]appbundle.FormInfo),
+		Forms:   forms,
+		App:     m.Requirements[version],
 	}, nil
 }
 
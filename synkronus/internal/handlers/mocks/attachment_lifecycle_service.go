@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/attachment"
+)
+
+// MockAttachmentLifecycleService is an in-memory implementation of attachment.LifecycleService
+// for testing; applying the policy moves every local attachment to cold storage
+type MockAttachmentLifecycleService struct {
+	stats attachment.StorageStats
+}
+
+// NewMockAttachmentLifecycleService creates a mock with one attachment on local disk
+func NewMockAttachmentLifecycleService() *MockAttachmentLifecycleService {
+	return &MockAttachmentLifecycleService{stats: attachment.StorageStats{
+		Policy: attachment.LifecyclePolicy{ColdStorage: "s3", StorageClass: "STANDARD_IA", ColdAfterDays: 90},
+		Local:  attachment.TierStats{Attachments: 1, Files: 1, Bytes: 2048},
+	}}
+}
+
+// Apply implements attachment.LifecycleService
+func (m *MockAttachmentLifecycleService) Apply(ctx context.Context) (*attachment.LifecycleRun, error) {
+	run := &attachment.LifecycleRun{StartedAt: time.Now().UTC(), Moved: m.stats.Local.Files, MovedBytes: m.stats.Local.Bytes}
+	m.stats.Cold.Attachments += m.stats.Local.Attachments
+	m.stats.Cold.Files += m.stats.Local.Files
+	m.stats.Cold.Bytes += m.stats.Local.Bytes
+	m.stats.Local = attachment.TierStats{}
+	m.stats.LastRun = run
+	return run, nil
+}
+
+// Stats implements attachment.LifecycleService
+func (m *MockAttachmentLifecycleService) Stats(ctx context.Context) (*attachment.StorageStats, error) {
+	stats := m.stats
+	return &stats, nil
+}
@@ -0,0 +1,87 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/audit"
+)
+
+// MockAuditService is an in-memory implementation of audit.Service for testing; it raises no alerts
+type MockAuditService struct {
+	events  []audit.Event
+	entries []audit.Entry
+}
+
+// NewMockAuditService creates a new mock audit service
+func NewMockAuditService() *MockAuditService {
+	return &MockAuditService{}
+}
+
+// Record implements audit.Service
+func (m *MockAuditService) Record(ctx context.Context, event audit.Event) error {
+	event.ID = int64(len(m.events) + 1)
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+// List implements audit.Service
+func (m *MockAuditService) List(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	events := make([]audit.Event, 0)
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+		e := m.events[i]
+		if (filter.Username == "" || e.Username == filter.Username) && (filter.Type == "" || e.Type == filter.Type) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// Events returns the recorded events, oldest first
+func (m *MockAuditService) Events() []audit.Event {
+	return m.events
+}
+
+// Log implements audit.Service
+func (m *MockAuditService) Log(ctx context.Context, entry audit.Entry) error {
+	entry.ID = int64(len(m.entries) + 1)
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+// ListEntries implements audit.Service
+func (m *MockAuditService) ListEntries(ctx context.Context, filter audit.EntryFilter) ([]audit.Entry, error) {
+	entries := make([]audit.Entry, 0)
+	skipped := 0
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+		e := m.entries[i]
+		if (filter.Username != "" && e.Username != filter.Username) || (filter.Action != "" && e.Action != filter.Action) ||
+			(!filter.From.IsZero() && e.At.Before(filter.From)) || (!filter.To.IsZero() && !e.At.Before(filter.To)) ||
+			(filter.BeforeID > 0 && e.ID >= filter.BeforeID) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Entries returns the request audit log entries, oldest first
+func (m *MockAuditService) Entries() []audit.Entry {
+	return m.entries
+}
@@ -3,8 +3,11 @@ package mocks
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/opendataensemble/synkronus/internal/models"
 	"github.com/opendataensemble/synkronus/internal/repository"
@@ -18,10 +21,19 @@ type MockAuthService struct {
 	// Mock data for testing
 	userRepository     repository.UserRepositoryInterface
 	validRefreshTokens map[string]string // map[refreshToken]username
+	sessions           map[string]*mockSession
+	impersonations     map[string]*auth.AuthClaims
 	config             auth.Config
 	log                *logger.Logger
 }
 
+// mockSession is a session started by GenerateRefreshToken together with its current token
+type mockSession struct {
+	username     string
+	refreshToken string
+	session      models.Session
+}
+
 // NewMockAuthService creates a new mock auth service
 func NewMockAuthService(mockUserRepo ...repository.UserRepositoryInterface) *MockAuthService {
 	// Create a default config
@@ -36,6 +48,8 @@ func NewMockAuthService(mockUserRepo ...repository.UserRepositoryInterface) *Moc
 	// Create the mock service
 	mock := &MockAuthService{
 		validRefreshTokens: make(map[string]string),
+		sessions:           make(map[string]*mockSession),
+		impersonations:     make(map[string]*auth.AuthClaims),
 		config:             config,
 		log:                logger.NewLogger(),
 	}
@@ -145,13 +159,26 @@ func (m *MockAuthService) GenerateToken(user *models.User) (string, error) {
 	return "mock-jwt-token-for-" + user.Username, nil
 }
 
-// GenerateRefreshToken mocks refresh token generation
-func (m *MockAuthService) GenerateRefreshToken(user *models.User) (string, error) {
-	// For testing, just return a predictable refresh token
-	refreshToken := "mock-refresh-token-for-" + user.Username
+// GenerateRefreshToken mocks refresh token generation, starting a session for the token
+func (m *MockAuthService) GenerateRefreshToken(ctx context.Context, user *models.User, client auth.SessionClient) (string, error) {
+	id := uuid.New()
+	// For testing, return a predictable refresh token per session
+	refreshToken := "mock-refresh-token-for-" + user.Username + "-" + id.String()[:8]
 
 	// Store it in our valid tokens map
 	m.validRefreshTokens[refreshToken] = user.Username
+	m.sessions[id.String()] = &mockSession{
+		username:     user.Username,
+		refreshToken: refreshToken,
+		session: models.Session{
+			ID:        id,
+			UserID:    user.ID,
+			UserAgent: client.UserAgent,
+			IPAddress: client.IPAddress,
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(m.config.RefreshTokenExpiration),
+		},
+	}
 
 	return refreshToken, nil
 }
@@ -179,17 +206,71 @@ func (m *MockAuthService) RefreshToken(ctx context.Context, refreshToken string)
 		return "", "", err
 	}
 
-	newRefreshToken, err := m.GenerateRefreshToken(user)
+	newRefreshToken, err := m.GenerateRefreshToken(ctx, user, auth.SessionClient{})
 	if err != nil {
 		return "", "", err
 	}
 
 	// Invalidate the old refresh token
-	delete(m.validRefreshTokens, refreshToken)
+	m.endSession(refreshToken)
 
 	return token, newRefreshToken, nil
 }
 
+// endSession invalidates a refresh token and ends its session
+func (m *MockAuthService) endSession(refreshToken string) {
+	delete(m.validRefreshTokens, refreshToken)
+	for id, s := range m.sessions {
+		if s.refreshToken == refreshToken {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// Logout mocks ending the session of a refresh token
+func (m *MockAuthService) Logout(ctx context.Context, refreshToken string) (string, error) {
+	username, valid := m.validRefreshTokens[refreshToken]
+	if !valid {
+		return "", nil
+	}
+	m.endSession(refreshToken)
+	return username, nil
+}
+
+// ListSessions mocks listing a user's sessions, newest first
+func (m *MockAuthService) ListSessions(ctx context.Context, username string) ([]models.Session, error) {
+	sessions := make([]models.Session, 0)
+	for _, s := range m.sessions {
+		if s.username == username {
+			sessions = append(sessions, s.session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// RevokeSession mocks revoking one of a user's sessions
+func (m *MockAuthService) RevokeSession(ctx context.Context, username, id string) error {
+	s, exists := m.sessions[id]
+	if !exists || s.username != username {
+		return auth.ErrSessionNotFound
+	}
+	m.endSession(s.refreshToken)
+	return nil
+}
+
+// RevokeAllSessions mocks revoking every session of a user
+func (m *MockAuthService) RevokeAllSessions(ctx context.Context, username string) (int64, error) {
+	var count int64
+	for _, s := range m.sessions {
+		if s.username == username {
+			m.endSession(s.refreshToken)
+			count++
+		}
+	}
+	return count, nil
+}
+
 // Initialize mocks the initialization process
 func (m *MockAuthService) Initialize(ctx context.Context) error {
 	// Nothing to do for the mock
@@ -220,6 +301,10 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*auth.AuthClaims, e
 		return nil, errors.New("token is expired")
 	}
 
+	if claims, ok := m.impersonations[tokenString]; ok {
+		return claims, nil
+	}
+
 	// Special case for admin token
 	if tokenString == "adminToken" {
 		// Get the admin user from the repository
@@ -277,3 +362,78 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*auth.AuthClaims, e
 func (m *MockAuthService) VerifyPassword(password, hash string) bool {
 	return hash == password+"-hash"
 }
+
+// PasswordHashReport mocks the hash migration report, treating every hash that is not
+// argon2id as pending
+func (m *MockAuthService) PasswordHashReport(ctx context.Context) (*auth.PasswordHashReport, error) {
+	users, err := m.userRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &auth.PasswordHashReport{
+		Algorithm:    auth.HashAlgorithmArgon2id,
+		Total:        len(users),
+		ByAlgorithm:  make(map[string]int),
+		PendingUsers: make([]string, 0),
+	}
+	for _, user := range users {
+		algorithm := auth.HashAlgorithmOf(user.PasswordHash)
+		report.ByAlgorithm[algorithm]++
+		if algorithm != auth.HashAlgorithmArgon2id {
+			report.PendingUsers = append(report.PendingUsers, user.Username)
+		}
+	}
+	sort.Strings(report.PendingUsers)
+	return report, nil
+}
+
+// JWKS mocks the published signing keys; the mock signs with a shared secret, so there are none
+func (m *MockAuthService) JWKS() *auth.JWKS {
+	return &auth.JWKS{Keys: []auth.JWK{}}
+}
+
+// GeneratePreviewToken mocks minting a preview token
+func (m *MockAuthService) GeneratePreviewToken(issuer string, lifetime time.Duration) (string, time.Time, error) {
+	if lifetime <= 0 {
+		lifetime = auth.DefaultPreviewTokenLifetime
+	}
+	return "mock-preview-token-for-" + issuer, time.Now().Add(lifetime).Truncate(time.Second), nil
+}
+
+// GenerateImpersonationToken mocks minting an impersonation token; ValidateToken accepts the
+// tokens it returns
+func (m *MockAuthService) GenerateImpersonationToken(ctx context.Context, username, impersonator string, lifetime time.Duration) (string, *auth.AuthClaims, error) {
+	if lifetime <= 0 {
+		lifetime = auth.DefaultImpersonationLifetime
+	}
+	user, err := m.userRepository.GetByUsername(ctx, username)
+	if err != nil {
+		return "", nil, err
+	}
+	if user == nil {
+		return "", nil, auth.ErrUserNotFound
+	}
+	if user.Role == models.RoleAdmin || user.Username == impersonator {
+		return "", nil, auth.ErrCannotImpersonate
+	}
+	claims := &auth.AuthClaims{
+		Username: user.Username,
+		Role:     user.Role,
+		Act:      &auth.ActorClaim{Username: impersonator},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+		},
+	}
+	token := "mock-impersonation-token-for-" + username
+	m.impersonations[token] = claims
+	return token, claims, nil
+}
+
+// ValidatePreviewToken accepts tokens made by GeneratePreviewToken
+func (m *MockAuthService) ValidatePreviewToken(tokenString string) (*auth.AuthClaims, error) {
+	issuer, ok := strings.CutPrefix(tokenString, "mock-preview-token-for-")
+	if !ok {
+		return nil, auth.ErrScopedToken
+	}
+	return &auth.AuthClaims{Username: issuer, Scope: auth.ScopePreview}, nil
+}
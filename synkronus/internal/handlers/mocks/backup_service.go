@@ -0,0 +1,93 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/backup"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// MockBackupService is an in-memory implementation of backup.Service for testing
+type MockBackupService struct {
+	users        map[string]backup.User
+	observations map[string]sync.Observation
+	version      int64
+}
+
+// NewMockBackupService creates a new mock backup service
+func NewMockBackupService() *MockBackupService {
+	return &MockBackupService{
+		users:        make(map[string]backup.User),
+		observations: make(map[string]sync.Observation),
+	}
+}
+
+// ExportUsers implements backup.Service
+func (m *MockBackupService) ExportUsers(ctx context.Context) ([]backup.User, error) {
+	users := make([]backup.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// RestoreUsers implements backup.Service
+func (m *MockBackupService) RestoreUsers(ctx context.Context, users []backup.User) (*backup.RestoreResult, error) {
+	for _, u := range users {
+		if u.Username == "" || u.PasswordHash == "" ||
+			(u.Role != models.RoleReadOnly && u.Role != models.RoleReadWrite && u.Role != models.RoleAdmin) {
+			return nil, fmt.Errorf("%w: %q", backup.ErrInvalidUser, u.Username)
+		}
+	}
+	result := &backup.RestoreResult{}
+	for _, u := range users {
+		if _, ok := m.users[u.Username]; ok {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		m.users[u.Username] = u
+	}
+	return result, nil
+}
+
+// ExportObservations implements backup.Service
+func (m *MockBackupService) ExportObservations(ctx context.Context, fn func(sync.Observation) error) (int, error) {
+	records := make([]sync.Observation, 0, len(m.observations))
+	for _, obs := range m.observations {
+		records = append(records, obs)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	for i, obs := range records {
+		if err := fn(obs); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// RestoreObservations implements backup.Service
+func (m *MockBackupService) RestoreObservations(ctx context.Context, records []sync.Observation) (*backup.RestoreResult, error) {
+	for _, record := range records {
+		if record.ObservationID == "" || record.FormType == "" || record.FormVersion == "" || !json.Valid(record.Data) {
+			return nil, fmt.Errorf("%w: %q", backup.ErrInvalidObservation, record.ObservationID)
+		}
+	}
+	result := &backup.RestoreResult{}
+	for _, record := range records {
+		if _, ok := m.observations[record.ObservationID]; ok {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		m.version++
+		record.Version = m.version
+		m.observations[record.ObservationID] = record
+	}
+	return result, nil
+}
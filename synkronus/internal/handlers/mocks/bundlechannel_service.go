@@ -0,0 +1,63 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/opendataensemble/synkronus/pkg/bundlechannel"
+)
+
+// MockBundleChannelService is an in-memory implementation of bundlechannel.Service for testing
+type MockBundleChannelService struct {
+	mu          sync.Mutex
+	assignments map[string]bundlechannel.Assignment
+}
+
+// NewMockBundleChannelService creates a new mock bundle channel service
+func NewMockBundleChannelService() *MockBundleChannelService {
+	return &MockBundleChannelService{assignments: make(map[string]bundlechannel.Assignment)}
+}
+
+// Channel implements bundlechannel.Service
+func (m *MockBundleChannelService) Channel(ctx context.Context, clientID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if assignment, ok := m.assignments[clientID]; ok {
+		return assignment.Channel, nil
+	}
+	return bundlechannel.Stable, nil
+}
+
+// Assign implements bundlechannel.Service
+func (m *MockBundleChannelService) Assign(ctx context.Context, clientID, channel, assignedBy string) (*bundlechannel.Assignment, error) {
+	if err := bundlechannel.Validate(clientID, channel); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if channel == bundlechannel.Stable {
+		delete(m.assignments, clientID)
+		return nil, nil
+	}
+	assignment := bundlechannel.Assignment{
+		ClientID:   clientID,
+		Channel:    channel,
+		AssignedBy: assignedBy,
+		AssignedAt: "2025-06-01T08:00:00Z",
+	}
+	m.assignments[clientID] = assignment
+	return &assignment, nil
+}
+
+// List implements bundlechannel.Service
+func (m *MockBundleChannelService) List(ctx context.Context) ([]bundlechannel.Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignments := make([]bundlechannel.Assignment, 0, len(m.assignments))
+	for _, assignment := range m.assignments {
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].ClientID < assignments[j].ClientID })
+	return assignments, nil
+}
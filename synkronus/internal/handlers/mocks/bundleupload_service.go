@@ -0,0 +1,104 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/bundleupload"
+)
+
+// MockBundleUploadService is an in-memory implementation of bundleupload.Service for testing
+type MockBundleUploadService struct {
+	mu      sync.Mutex
+	uploads map[string]*bundleupload.Upload
+	content map[string][]byte
+}
+
+// NewMockBundleUploadService creates a new mock chunked upload service
+func NewMockBundleUploadService() *MockBundleUploadService {
+	return &MockBundleUploadService{
+		uploads: make(map[string]*bundleupload.Upload),
+		content: make(map[string][]byte),
+	}
+}
+
+// Create implements bundleupload.Service
+func (m *MockBundleUploadService) Create(ctx context.Context, size int64, createdBy string) (*bundleupload.Upload, error) {
+	if err := bundleupload.ValidateSize(size); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	upload := &bundleupload.Upload{
+		ID:        fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.uploads)+1),
+		Size:      size,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(bundleupload.Expiry),
+	}
+	m.uploads[upload.ID] = upload
+	copied := *upload
+	return &copied, nil
+}
+
+// Get implements bundleupload.Service
+func (m *MockBundleUploadService) Get(ctx context.Context, id string) (*bundleupload.Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok {
+		return nil, bundleupload.ErrNotFound
+	}
+	copied := *upload
+	return &copied, nil
+}
+
+// Append implements bundleupload.Service
+func (m *MockBundleUploadService) Append(ctx context.Context, id string, offset int64, data []byte) (*bundleupload.Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok {
+		return nil, bundleupload.ErrNotFound
+	}
+	copied := *upload
+	if err := bundleupload.ValidateChunk(&copied, offset, data); err != nil {
+		return &copied, err
+	}
+	m.content[id] = append(m.content[id], data...)
+	upload.Received += int64(len(data))
+	upload.ExpiresAt = time.Now().Add(bundleupload.Expiry)
+	copied = *upload
+	return &copied, nil
+}
+
+// Open implements bundleupload.Service
+func (m *MockBundleUploadService) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok {
+		return nil, bundleupload.ErrNotFound
+	}
+	if !upload.Complete() {
+		return nil, fmt.Errorf("%w: %d of %d bytes received", bundleupload.ErrIncomplete, upload.Received, upload.Size)
+	}
+	return io.NopCloser(bytes.NewReader(m.content[id])), nil
+}
+
+// Delete implements bundleupload.Service
+func (m *MockBundleUploadService) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.uploads[id]; !ok {
+		return bundleupload.ErrNotFound
+	}
+	delete(m.uploads, id)
+	delete(m.content, id)
+	return nil
+}
@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/canary"
+)
+
+// MockCanaryService is an implementation of canary.Service for testing that validates requests
+// and reports one newly failing record
+type MockCanaryService struct {
+	// Staged is the version staged for preview that runs default to; empty when none is
+	Staged string
+	// Versions lists the stored versions runs may validate
+	Versions []string
+	// LastRequest holds the most recent request run
+	LastRequest canary.Request
+}
+
+// NewMockCanaryService creates a new mock canary service with version 2 staged for preview
+func NewMockCanaryService() *MockCanaryService {
+	return &MockCanaryService{Staged: "2", Versions: []string{"1", "2"}}
+}
+
+// Run implements canary.Service
+func (m *MockCanaryService) Run(ctx context.Context, req canary.Request) (*canary.Report, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Version == "" {
+		if m.Staged == "" {
+			return nil, canary.ErrNoVersion
+		}
+		req.Version = m.Staged
+	}
+	found := false
+	for _, v := range m.Versions {
+		found = found || v == req.Version
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", appbundle.ErrVersionNotFound, req.Version)
+	}
+	m.LastRequest = req
+
+	return &canary.Report{
+		Version:        req.Version,
+		CurrentVersion: "1",
+		Since:          time.Now().UTC().AddDate(0, 0, -req.Days),
+		Sampled:        2,
+		Failing:        1,
+		NewlyFailing:   1,
+		Forms:          []canary.FormResult{{FormType: "survey", Sampled: 2, Failing: 1, NewlyFailing: 1}},
+		Failures: []canary.Failure{{
+			ObservationID: "obs-1",
+			FormType:      "survey",
+			FormVersion:   "1",
+			New:           true,
+			Violations:    []canary.Violation{{Field: "age", Rule: "maximum", Message: "must be at most 99, not 120"}},
+		}},
+	}, nil
+}
@@ -0,0 +1,95 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/codelist"
+)
+
+// MockCodeListService is an in-memory implementation of codelist.Service for testing
+type MockCodeListService struct {
+	mu    sync.Mutex
+	lists map[string][]codelist.CodeList
+}
+
+// NewMockCodeListService creates a new mock code list service
+func NewMockCodeListService() *MockCodeListService {
+	return &MockCodeListService{lists: make(map[string][]codelist.CodeList)}
+}
+
+// Publish implements codelist.Service
+func (m *MockCodeListService) Publish(ctx context.Context, name string, input codelist.Input, createdBy string) (*codelist.CodeList, error) {
+	if err := codelist.Validate(name, input); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := codelist.CodeList{
+		Name:        name,
+		Version:     len(m.lists[name]) + 1,
+		Description: input.Description,
+		Entries:     input.Entries,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+	m.lists[name] = append(m.lists[name], c)
+	return &c, nil
+}
+
+// Get implements codelist.Service
+func (m *MockCodeListService) Get(ctx context.Context, name string, version int) (*codelist.CodeList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.lists[name]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, codelist.ErrNotFound
+	}
+	c := versions[version-1]
+	return &c, nil
+}
+
+// List implements codelist.Service
+func (m *MockCodeListService) List(ctx context.Context) ([]codelist.Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summaries := make([]codelist.Summary, 0, len(m.lists))
+	for _, versions := range m.lists {
+		summaries = append(summaries, versions[len(versions)-1].Summarize())
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+// Versions implements codelist.Service
+func (m *MockCodeListService) Versions(ctx context.Context, name string) ([]codelist.Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.lists[name]
+	if len(versions) == 0 {
+		return nil, codelist.ErrNotFound
+	}
+	newestFirst := make([]codelist.Summary, len(versions))
+	for i, c := range versions {
+		newestFirst[len(versions)-1-i] = c.Summarize()
+	}
+	return newestFirst, nil
+}
+
+// Codes implements codelist.Service
+func (m *MockCodeListService) Codes(ctx context.Context, name string) ([]string, error) {
+	c, err := m.Get(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(c.Entries))
+	for _, entry := range c.Entries {
+		codes = append(codes, entry.Code)
+	}
+	return codes, nil
+}
@@ -1,6 +1,9 @@
 package mocks
 
-import "github.com/opendataensemble/synkronus/pkg/config"
+import (
+	"github.com/opendataensemble/synkronus/pkg/config"
+	"github.com/opendataensemble/synkronus/pkg/middleware/security"
+)
 
 // NewTestConfig creates a new test configuration
 func NewTestConfig() *config.Config {
@@ -11,5 +14,10 @@ func NewTestConfig() *config.Config {
 		JWTSecret:     "test-secret",
 		LogLevel:      "debug",
 		DataDir:       "./testdata",
+
+		SecurityAPIPolicy:      security.DefaultAPIPolicy,
+		SecurityContentPolicy:  security.DefaultContentPolicy,
+		SecurityFrameAncestors: security.DefaultFrameAncestors,
+		SecurityReferrerPolicy: security.DefaultReferrerPolicy,
 	}
 }
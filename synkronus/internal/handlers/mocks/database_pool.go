@@ -0,0 +1,27 @@
+package mocks
+
+import "github.com/opendataensemble/synkronus/pkg/database"
+
+// MockDatabasePool is an implementation of database.PoolReporter for testing that reports
+// fixed statistics
+type MockDatabasePool struct {
+	Stats database.PoolStats
+}
+
+// NewMockDatabasePool creates a new mock pool with three of ten connections open
+func NewMockDatabasePool() *MockDatabasePool {
+	return &MockDatabasePool{Stats: database.PoolStats{
+		MaxOpenConnections:     10,
+		MaxIdleConnections:     5,
+		ConnMaxLifetimeSeconds: 3600,
+		ConnMaxIdleTimeSeconds: 300,
+		OpenConnections:        3,
+		InUse:                  1,
+		Idle:                   2,
+	}}
+}
+
+// PoolStats implements database.PoolReporter
+func (m *MockDatabasePool) PoolStats() database.PoolStats {
+	return m.Stats
+}
@@ -9,7 +9,9 @@ import (
 
 // MockDataExportService is a mock implementation of dataexport.Service
 type MockDataExportService struct {
-	ExportParquetZipFunc func(ctx context.Context) (io.ReadCloser, error)
+	ExportParquetZipFunc            func(ctx context.Context) (io.ReadCloser, error)
+	ExportParquetZipWithOptionsFunc func(ctx context.Context, opts dataexport.ExportOptions) (io.ReadCloser, error)
+	ExportToSinkFunc                func(ctx context.Context, sink dataexport.Sink, opts dataexport.ExportOptions) (*dataexport.SinkResult, error)
 }
 
 // NewMockDataExportService creates a new mock data export service
@@ -25,5 +27,21 @@ func (m *MockDataExportService) ExportParquetZip(ctx context.Context) (io.ReadCl
 	return io.NopCloser(io.LimitReader(nil, 0)), nil
 }
 
+// ExportParquetZipWithOptions implements dataexport.Service
+func (m *MockDataExportService) ExportParquetZipWithOptions(ctx context.Context, opts dataexport.ExportOptions) (io.ReadCloser, error) {
+	if m.ExportParquetZipWithOptionsFunc != nil {
+		return m.ExportParquetZipWithOptionsFunc(ctx, opts)
+	}
+	return io.NopCloser(io.LimitReader(nil, 0)), nil
+}
+
+// ExportToSink implements dataexport.Service
+func (m *MockDataExportService) ExportToSink(ctx context.Context, sink dataexport.Sink, opts dataexport.ExportOptions) (*dataexport.SinkResult, error) {
+	if m.ExportToSinkFunc != nil {
+		return m.ExportToSinkFunc(ctx, sink, opts)
+	}
+	return &dataexport.SinkResult{}, nil
+}
+
 // Ensure MockDataExportService implements dataexport.Service
 var _ dataexport.Service = (*MockDataExportService)(nil)
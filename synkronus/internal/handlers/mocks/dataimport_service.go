@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+)
+
+// MockDataImportService is an in-memory implementation of dataimport.Service for testing. Started
+// imports complete at once, with every row imported.
+type MockDataImportService struct {
+	jobs []dataimport.Job
+}
+
+// NewMockDataImportService creates a new mock data import service
+func NewMockDataImportService() *MockDataImportService {
+	return &MockDataImportService{}
+}
+
+// Start implements dataimport.Service; mappings of form types other than "survey" are invalid
+func (m *MockDataImportService) Start(ctx context.Context, filename string, file io.Reader, mapping dataimport.Mapping, username string) (*dataimport.Job, error) {
+	if mapping.FormType != "survey" {
+		return nil, fmt.Errorf("%w: form type %q is not in app bundle version 1", dataimport.ErrInvalidImport, mapping.FormType)
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	rows := strings.Count(strings.TrimSpace(string(content)), "\n")
+	job := dataimport.Job{
+		ID:        fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.jobs)+1),
+		FormType:  mapping.FormType,
+		Filename:  filename,
+		Status:    dataimport.StatusCompleted,
+		Mapping:   mapping,
+		TotalRows: rows,
+		CreatedBy: username,
+		CreatedAt: "2025-06-01T08:00:00Z",
+	}
+	job.ImportedRows = rows
+	for i := 0; i < rows; i++ {
+		job.Results = append(job.Results, dataimport.RowResult{Row: i + 2, Status: dataimport.RowImported})
+	}
+	m.jobs = append(m.jobs, job)
+	return &job, nil
+}
+
+// Get implements dataimport.Service
+func (m *MockDataImportService) Get(ctx context.Context, id string) (*dataimport.Job, error) {
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return &job, nil
+		}
+	}
+	return nil, dataimport.ErrNotFound
+}
+
+// List implements dataimport.Service
+func (m *MockDataImportService) List(ctx context.Context, limit int) ([]dataimport.Job, error) {
+	jobs := []dataimport.Job{}
+	for i := len(m.jobs) - 1; i >= 0 && (limit <= 0 || len(jobs) < limit); i-- {
+		job := m.jobs[i]
+		job.Results = nil
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Run implements dataimport.Service
+func (m *MockDataImportService) Run(ctx context.Context) {}
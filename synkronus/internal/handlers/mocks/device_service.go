@@ -0,0 +1,103 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/device"
+)
+
+// MockDeviceService is an in-memory implementation of device.Service for testing
+type MockDeviceService struct {
+	mu      sync.Mutex
+	devices map[string]device.Device
+	now     time.Time
+}
+
+// NewMockDeviceService creates a new mock client registry
+func NewMockDeviceService() *MockDeviceService {
+	return &MockDeviceService{
+		devices: make(map[string]device.Device),
+		now:     time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+// tick advances the mock clock, so later syncs sort as more recent
+func (m *MockDeviceService) tick() time.Time {
+	m.now = m.now.Add(time.Minute)
+	return m.now
+}
+
+// Get implements device.Service
+func (m *MockDeviceService) Get(ctx context.Context, clientID string) (*device.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.devices[clientID]
+	if !ok {
+		return nil, device.ErrNotFound
+	}
+	return &d, nil
+}
+
+// RecordSync implements device.Service
+func (m *MockDeviceService) RecordSync(ctx context.Context, sync device.Sync) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.tick()
+	d, ok := m.devices[sync.ClientID]
+	if !ok {
+		d = device.Device{ClientID: sync.ClientID, Status: device.StatusActive, FirstSeenAt: now}
+	}
+	d.LastSeenAt = now
+	d.LastUsername = sync.Username
+	d.LastVersion = sync.Version
+	switch sync.Operation {
+	case device.SyncPull:
+		d.LastPullAt = &now
+	case device.SyncPush:
+		d.LastPushAt = &now
+	}
+	m.devices[sync.ClientID] = d
+	return nil
+}
+
+// List implements device.Service
+func (m *MockDeviceService) List(ctx context.Context, filter device.Filter) ([]device.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	devices := make([]device.Device, 0, len(m.devices))
+	for _, d := range m.devices {
+		if filter.Status == "" || d.Status == filter.Status {
+			devices = append(devices, d)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].LastSeenAt.After(devices[j].LastSeenAt) })
+	return devices, nil
+}
+
+// Update implements device.Service
+func (m *MockDeviceService) Update(ctx context.Context, clientID string, update device.Update, updatedBy string) (*device.Device, error) {
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.devices[clientID]
+	if !ok {
+		return nil, device.ErrNotFound
+	}
+	if update.Label != nil {
+		d.Label = *update.Label
+	}
+	if update.Status != nil {
+		now := m.tick()
+		d.Status = *update.Status
+		d.StatusReason = update.Reason
+		d.StatusChangedBy = updatedBy
+		d.StatusChangedAt = &now
+	}
+	m.devices[clientID] = d
+	return &d, nil
+}
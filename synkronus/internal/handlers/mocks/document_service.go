@@ -0,0 +1,144 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/document"
+)
+
+// MockDocumentService is an in-memory implementation of document.Service for testing
+type MockDocumentService struct {
+	observations map[string]bool
+	documents    []document.Document
+	contents     map[string][]byte
+	removed      map[string]bool
+	audit        []document.AuditEntry
+	allowedTypes []string
+	maxSize      int64
+}
+
+// NewMockDocumentService creates a new mock document service using the default type and size limits
+func NewMockDocumentService() *MockDocumentService {
+	cfg := document.DefaultConfig()
+	return &MockDocumentService{
+		observations: make(map[string]bool),
+		contents:     make(map[string][]byte),
+		removed:      make(map[string]bool),
+		allowedTypes: cfg.AllowedTypes,
+		maxSize:      cfg.MaxSize,
+	}
+}
+
+// AddObservation registers an observation documents can be attached to
+func (m *MockDocumentService) AddObservation(observationID string) {
+	m.observations[observationID] = true
+}
+
+func (m *MockDocumentService) record(doc document.Document, action, username string) {
+	m.audit = append(m.audit, document.AuditEntry{
+		ID:            int64(len(m.audit) + 1),
+		DocumentID:    doc.ID,
+		ObservationID: doc.ObservationID,
+		Action:        action,
+		Username:      username,
+		At:            time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func (m *MockDocumentService) find(observationID, documentID string) (*document.Document, error) {
+	for i := range m.documents {
+		d := m.documents[i]
+		if d.ID == documentID && d.ObservationID == observationID && !m.removed[d.ID] {
+			return &d, nil
+		}
+	}
+	return nil, document.ErrDocumentNotFound
+}
+
+// List implements document.Service
+func (m *MockDocumentService) List(ctx context.Context, observationID string) ([]document.Document, error) {
+	result := make([]document.Document, 0)
+	for _, d := range m.documents {
+		if d.ObservationID == observationID && !m.removed[d.ID] {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// Attach implements document.Service
+func (m *MockDocumentService) Attach(ctx context.Context, observationID string, upload document.Upload, username string) (*document.Document, error) {
+	if !m.observations[observationID] {
+		return nil, document.ErrObservationNotFound
+	}
+
+	content, err := io.ReadAll(upload.Content)
+	if err != nil {
+		return nil, err
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(content), ";")
+	allowed := false
+	for _, t := range m.allowedTypes {
+		allowed = allowed || t == contentType
+	}
+	if !allowed {
+		return nil, document.ErrTypeNotAllowed
+	}
+	if int64(len(content)) > m.maxSize {
+		return nil, document.ErrTooLarge
+	}
+
+	doc := document.Document{
+		ID:            uuid.New().String(),
+		ObservationID: observationID,
+		Filename:      filepath.Base(upload.Filename),
+		ContentType:   contentType,
+		Size:          int64(len(content)),
+		Description:   upload.Description,
+		UploadedBy:    username,
+		UploadedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	m.documents = append(m.documents, doc)
+	m.contents[doc.ID] = content
+	m.record(doc, document.ActionAttach, username)
+	return &doc, nil
+}
+
+// Open implements document.Service
+func (m *MockDocumentService) Open(ctx context.Context, observationID, documentID, username string) (*document.Document, io.ReadCloser, error) {
+	doc, err := m.find(observationID, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.record(*doc, document.ActionDownload, username)
+	return doc, io.NopCloser(bytes.NewReader(m.contents[doc.ID])), nil
+}
+
+// Remove implements document.Service
+func (m *MockDocumentService) Remove(ctx context.Context, observationID, documentID, username string) error {
+	doc, err := m.find(observationID, documentID)
+	if err != nil {
+		return err
+	}
+	m.removed[doc.ID] = true
+	m.record(*doc, document.ActionRemove, username)
+	return nil
+}
+
+// ListAudit implements document.Service
+func (m *MockDocumentService) ListAudit(ctx context.Context, observationID string) ([]document.AuditEntry, error) {
+	result := make([]document.AuditEntry, 0)
+	for _, e := range m.audit {
+		if e.ObservationID == observationID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
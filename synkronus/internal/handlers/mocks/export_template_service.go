@@ -0,0 +1,80 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/exporttemplate"
+)
+
+// MockExportTemplateService is an in-memory implementation of exporttemplate.Service for testing
+type MockExportTemplateService struct {
+	templates map[string]exporttemplate.Template
+}
+
+// NewMockExportTemplateService creates a new mock export template service
+func NewMockExportTemplateService() *MockExportTemplateService {
+	return &MockExportTemplateService{templates: make(map[string]exporttemplate.Template)}
+}
+
+// List implements exporttemplate.Service
+func (m *MockExportTemplateService) List(ctx context.Context) ([]exporttemplate.Template, error) {
+	result := make([]exporttemplate.Template, 0, len(m.templates))
+	for _, template := range m.templates {
+		result = append(result, template)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Get implements exporttemplate.Service
+func (m *MockExportTemplateService) Get(ctx context.Context, name string) (*exporttemplate.Template, error) {
+	template, ok := m.templates[name]
+	if !ok {
+		return nil, exporttemplate.ErrTemplateNotFound
+	}
+	return &template, nil
+}
+
+// Save implements exporttemplate.Service
+func (m *MockExportTemplateService) Save(ctx context.Context, template exporttemplate.Template, updatedBy string) (*exporttemplate.Template, error) {
+	if !exporttemplate.ValidName(template.Name) {
+		return nil, fmt.Errorf("%w: invalid name", exporttemplate.ErrInvalidTemplate)
+	}
+	if err := template.Definition.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	template.UpdatedBy = &updatedBy
+	template.UpdatedAt = now
+	template.CreatedAt = now
+	if existing, ok := m.templates[template.Name]; ok {
+		template.CreatedAt = existing.CreatedAt
+	}
+	m.templates[template.Name] = template
+	return &template, nil
+}
+
+// Delete implements exporttemplate.Service
+func (m *MockExportTemplateService) Delete(ctx context.Context, name string) error {
+	if _, ok := m.templates[name]; !ok {
+		return exporttemplate.ErrTemplateNotFound
+	}
+	delete(m.templates, name)
+	return nil
+}
+
+// ExportOptions implements exporttemplate.Service, keying hashes with the template name
+func (m *MockExportTemplateService) ExportOptions(ctx context.Context, name string) (*dataexport.ExportOptions, error) {
+	template, ok := m.templates[name]
+	if !ok {
+		return nil, exporttemplate.ErrTemplateNotFound
+	}
+	return template.Definition.Options([]byte(name)), nil
+}
+
+// Ensure MockExportTemplateService implements exporttemplate.Service
+var _ exporttemplate.Service = (*MockExportTemplateService)(nil)
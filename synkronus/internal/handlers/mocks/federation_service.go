@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/opendataensemble/synkronus/pkg/federation"
+)
+
+// MockFederationService is an in-memory implementation of federation.Reporter for testing
+type MockFederationService struct {
+	status federation.Status
+	err    error
+}
+
+// NewMockFederationService creates a mock federation service that has never replicated
+func NewMockFederationService() *MockFederationService {
+	return &MockFederationService{status: federation.Status{
+		UpstreamURL: "https://central.example.org",
+		ClientID:    "edge-test",
+		State:       federation.StateNeverRun,
+	}}
+}
+
+// SetStatus sets the status returned by Status
+func (m *MockFederationService) SetStatus(status federation.Status) {
+	m.status = status
+}
+
+// SetError makes Status fail with err
+func (m *MockFederationService) SetError(err error) {
+	m.err = err
+}
+
+// Status implements federation.Reporter
+func (m *MockFederationService) Status(ctx context.Context) (*federation.Status, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	status := m.status
+	return &status, nil
+}
@@ -0,0 +1,107 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/formcomponent"
+)
+
+// MockFormComponentService is an in-memory implementation of formcomponent.Service for testing
+type MockFormComponentService struct {
+	mu         sync.Mutex
+	components map[string][]formcomponent.Component
+}
+
+// NewMockFormComponentService creates a new mock form component library
+func NewMockFormComponentService() *MockFormComponentService {
+	return &MockFormComponentService{components: make(map[string][]formcomponent.Component)}
+}
+
+// Publish implements formcomponent.Service
+func (m *MockFormComponentService) Publish(ctx context.Context, name string, input formcomponent.Input, createdBy string) (*formcomponent.Component, error) {
+	if err := formcomponent.Validate(name, input); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := formcomponent.Component{
+		Name:        name,
+		Version:     len(m.components[name]) + 1,
+		Description: input.Description,
+		Schema:      input.Schema,
+		UI:          input.UI,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+	m.components[name] = append(m.components[name], c)
+	return &c, nil
+}
+
+// Get implements formcomponent.Service
+func (m *MockFormComponentService) Get(ctx context.Context, name string, version int) (*formcomponent.Component, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.components[name]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, formcomponent.ErrNotFound
+	}
+	c := versions[version-1]
+	return &c, nil
+}
+
+// List implements formcomponent.Service
+func (m *MockFormComponentService) List(ctx context.Context) ([]formcomponent.Component, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	components := make([]formcomponent.Component, 0, len(m.components))
+	for _, versions := range m.components {
+		components = append(components, versions[len(versions)-1])
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components, nil
+}
+
+// Versions implements formcomponent.Service
+func (m *MockFormComponentService) Versions(ctx context.Context, name string) ([]formcomponent.Component, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.components[name]
+	if len(versions) == 0 {
+		return nil, formcomponent.ErrNotFound
+	}
+	newestFirst := make([]formcomponent.Component, len(versions))
+	for i, c := range versions {
+		newestFirst[len(versions)-1-i] = c
+	}
+	return newestFirst, nil
+}
+
+// ResolveInclude implements formcomponent.Service
+func (m *MockFormComponentService) ResolveInclude(ctx context.Context, ref string) (*appbundle.IncludedComponent, error) {
+	name, version, err := formcomponent.ParseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.Get(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	result := &appbundle.IncludedComponent{Name: c.Name, Version: c.Version}
+	if err := json.Unmarshal(c.Schema, &result.Schema); err != nil {
+		return nil, err
+	}
+	if len(c.UI) > 0 {
+		if err := json.Unmarshal(c.UI, &result.UI); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/opendataensemble/synkronus/pkg/importsource"
+)
+
+// MockImportSourceService is an in-memory implementation of importsource.Service for testing.
+// Pulls queue the Pending submissions as a completed import; sources on hosts ending in
+// ".invalid" cannot be reached.
+type MockImportSourceService struct {
+	sources []importsource.Source
+	imports int
+
+	// Pending is the number of submissions the next pull finds
+	Pending int
+}
+
+// NewMockImportSourceService creates a new mock import source service
+func NewMockImportSourceService() *MockImportSourceService {
+	return &MockImportSourceService{}
+}
+
+// Create implements importsource.Service
+func (m *MockImportSourceService) Create(ctx context.Context, input importsource.SourceInput, username string) (*importsource.Source, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	source := importsource.Source{
+		ID:              fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.sources)+1),
+		Name:            input.Name,
+		Kind:            input.Kind,
+		URL:             input.URL,
+		ProjectID:       input.ProjectID,
+		FormID:          input.FormID,
+		Username:        input.Username,
+		Mapping:         input.Mapping,
+		IntervalMinutes: input.IntervalMinutes,
+		CreatedBy:       username,
+		CreatedAt:       "2025-06-01T08:00:00Z",
+	}
+	m.sources = append(m.sources, source)
+	return &source, nil
+}
+
+// Get implements importsource.Service
+func (m *MockImportSourceService) Get(ctx context.Context, id string) (*importsource.Source, error) {
+	for i := range m.sources {
+		if m.sources[i].ID == id {
+			source := m.sources[i]
+			return &source, nil
+		}
+	}
+	return nil, importsource.ErrNotFound
+}
+
+// List implements importsource.Service
+func (m *MockImportSourceService) List(ctx context.Context) ([]importsource.Source, error) {
+	return append([]importsource.Source{}, m.sources...), nil
+}
+
+// Delete implements importsource.Service
+func (m *MockImportSourceService) Delete(ctx context.Context, id string) error {
+	for i := range m.sources {
+		if m.sources[i].ID == id {
+			m.sources = append(m.sources[:i], m.sources[i+1:]...)
+			return nil
+		}
+	}
+	return importsource.ErrNotFound
+}
+
+// Pull implements importsource.Service
+func (m *MockImportSourceService) Pull(ctx context.Context, id string) (*dataimport.Job, error) {
+	var source *importsource.Source
+	for i := range m.sources {
+		if m.sources[i].ID == id {
+			source = &m.sources[i]
+		}
+	}
+	if source == nil {
+		return nil, importsource.ErrNotFound
+	}
+	if strings.HasSuffix(source.URL, ".invalid") {
+		source.LastError = "connection refused"
+		return nil, fmt.Errorf("%w: %s", importsource.ErrFetchFailed, source.LastError)
+	}
+	if m.Pending == 0 {
+		return nil, nil
+	}
+
+	m.imports++
+	job := dataimport.Job{
+		ID:           fmt.Sprintf("00000000-0000-0000-0001-%012d", m.imports),
+		FormType:     source.Mapping.FormType,
+		Filename:     source.FormID + ".csv",
+		Status:       dataimport.StatusCompleted,
+		Mapping:      source.Mapping,
+		TotalRows:    m.Pending,
+		ImportedRows: m.Pending,
+		CreatedBy:    source.CreatedBy,
+		CreatedAt:    "2025-06-01T08:00:00Z",
+	}
+	m.Pending = 0
+	source.LastImportID = &job.ID
+	source.LastError = ""
+	return &job, nil
+}
+
+// Run implements importsource.Service
+func (m *MockImportSourceService) Run(ctx context.Context) {}
@@ -0,0 +1,111 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/invite"
+	"github.com/opendataensemble/synkronus/pkg/user"
+)
+
+// MockInviteService is an in-memory implementation of invite.Service for testing; instead of
+// emailing tokens it keeps them for Token
+type MockInviteService struct {
+	invitations []invite.Invitation
+	tokens      map[string]string // token -> invitation ID
+	users       map[string]bool
+}
+
+// NewMockInviteService creates a new mock invite service
+func NewMockInviteService() *MockInviteService {
+	return &MockInviteService{tokens: make(map[string]string), users: make(map[string]bool)}
+}
+
+// Invite implements invite.Service
+func (m *MockInviteService) Invite(ctx context.Context, username, email string, role models.Role, invitedBy string) (*invite.Invitation, error) {
+	if role != models.RoleReadOnly && role != models.RoleReadWrite && role != models.RoleAdmin {
+		return nil, user.ErrInvalidRole
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, fmt.Errorf("%w: %v", invite.ErrInvalidEmail, err)
+	}
+	if m.users[username] {
+		return nil, user.ErrUserExists
+	}
+
+	pending := m.invitations[:0]
+	for _, inv := range m.invitations {
+		if inv.Username != username || inv.AcceptedAt != nil {
+			pending = append(pending, inv)
+		}
+	}
+	now := time.Now().UTC()
+	inv := invite.Invitation{
+		ID:        uuid.NewString(),
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(invite.DefaultLifetime).Format(time.RFC3339),
+	}
+	m.invitations = append(pending, inv)
+	m.tokens["token-for-"+inv.ID] = inv.ID
+	return &inv, nil
+}
+
+// Accept implements invite.Service
+func (m *MockInviteService) Accept(ctx context.Context, token, password string) (*models.User, *invite.Invitation, error) {
+	id, ok := m.tokens[token]
+	if !ok {
+		return nil, nil, invite.ErrInvalidInvitation
+	}
+	for i, inv := range m.invitations {
+		if inv.ID != id || inv.AcceptedAt != nil {
+			continue
+		}
+		acceptedAt := time.Now().UTC().Format(time.RFC3339)
+		m.invitations[i].AcceptedAt = &acceptedAt
+		m.users[inv.Username] = true
+		return &models.User{ID: uuid.New(), Username: inv.Username, Role: inv.Role}, &m.invitations[i], nil
+	}
+	return nil, nil, invite.ErrInvalidInvitation
+}
+
+// List implements invite.Service
+func (m *MockInviteService) List(ctx context.Context) ([]invite.Invitation, error) {
+	invitations := make([]invite.Invitation, 0)
+	for i := len(m.invitations) - 1; i >= 0; i-- {
+		if m.invitations[i].AcceptedAt == nil {
+			invitations = append(invitations, m.invitations[i])
+		}
+	}
+	return invitations, nil
+}
+
+// Revoke implements invite.Service
+func (m *MockInviteService) Revoke(ctx context.Context, id string) error {
+	for i, inv := range m.invitations {
+		if inv.ID == id && inv.AcceptedAt == nil {
+			m.invitations = append(m.invitations[:i], m.invitations[i+1:]...)
+			return nil
+		}
+	}
+	return invite.ErrInvitationNotFound
+}
+
+// Token returns the token that was "emailed" for the pending invitation of username
+func (m *MockInviteService) Token(username string) string {
+	for token, id := range m.tokens {
+		for _, inv := range m.invitations {
+			if inv.ID == id && inv.Username == username && inv.AcceptedAt == nil {
+				return token
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/latency"
+)
+
+// MockLatencyService is an in-memory implementation of latency.Service for testing; its
+// reports only count the observed operations
+type MockLatencyService struct {
+	observed   map[string]int64
+	lastFilter latency.Filter
+}
+
+// NewMockLatencyService creates a new mock latency service
+func NewMockLatencyService() *MockLatencyService {
+	return &MockLatencyService{observed: make(map[string]int64)}
+}
+
+// Observe implements latency.Service
+func (m *MockLatencyService) Observe(operation string, duration time.Duration) {
+	m.observed[operation]++
+}
+
+// Flush implements latency.Service
+func (m *MockLatencyService) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Run implements latency.Service
+func (m *MockLatencyService) Run(ctx context.Context) {}
+
+// Report implements latency.Service
+func (m *MockLatencyService) Report(ctx context.Context, filter latency.Filter) (*latency.Report, error) {
+	m.lastFilter = filter
+	report := &latency.Report{
+		From:    filter.From.Format("2006-01-02"),
+		To:      filter.To.Format("2006-01-02"),
+		Days:    []latency.DayStats{},
+		Overall: []latency.Stats{},
+	}
+	for _, operation := range latency.Operations {
+		if count := m.observed[operation]; count > 0 && (filter.Operation == "" || filter.Operation == operation) {
+			report.Overall = append(report.Overall, latency.Stats{Operation: operation, Count: count})
+		}
+	}
+	return report, nil
+}
+
+// LastFilter returns the filter of the last report
+func (m *MockLatencyService) LastFilter() latency.Filter {
+	return m.lastFilter
+}
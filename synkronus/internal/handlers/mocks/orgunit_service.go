@@ -0,0 +1,194 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+)
+
+// MockOrgUnitService is an in-memory implementation of orgunit.Service for testing
+type MockOrgUnitService struct {
+	units        map[string]orgunit.OrgUnit
+	users        map[string][]string
+	observations map[string]string
+}
+
+// NewMockOrgUnitService creates a new mock org unit service
+func NewMockOrgUnitService() *MockOrgUnitService {
+	return &MockOrgUnitService{
+		units:        make(map[string]orgunit.OrgUnit),
+		users:        make(map[string][]string),
+		observations: make(map[string]string),
+	}
+}
+
+// List implements orgunit.Service
+func (m *MockOrgUnitService) List(ctx context.Context) ([]orgunit.OrgUnit, error) {
+	result := make([]orgunit.OrgUnit, 0, len(m.units))
+	for _, unit := range m.units {
+		result = append(result, unit)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// Get implements orgunit.Service
+func (m *MockOrgUnitService) Get(ctx context.Context, id string) (*orgunit.OrgUnit, error) {
+	unit, ok := m.units[id]
+	if !ok {
+		return nil, orgunit.ErrOrgUnitNotFound
+	}
+	return &unit, nil
+}
+
+func (m *MockOrgUnitService) parentPath(parentID *string) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+	parent, ok := m.units[*parentID]
+	if !ok {
+		return "", orgunit.ErrInvalidOrgUnit
+	}
+	return parent.Path, nil
+}
+
+// Create implements orgunit.Service
+func (m *MockOrgUnitService) Create(ctx context.Context, input orgunit.OrgUnitInput) (*orgunit.OrgUnit, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, orgunit.ErrInvalidOrgUnit
+	}
+	parentPath, err := m.parentPath(input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	id := uuid.New().String()
+	unit := orgunit.OrgUnit{
+		ID:        id,
+		Name:      input.Name,
+		Code:      input.Code,
+		Level:     input.Level,
+		ParentID:  input.ParentID,
+		Path:      parentPath + id + "/",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.units[id] = unit
+	return &unit, nil
+}
+
+// Update implements orgunit.Service
+func (m *MockOrgUnitService) Update(ctx context.Context, id string, input orgunit.OrgUnitInput) (*orgunit.OrgUnit, error) {
+	current, ok := m.units[id]
+	if !ok {
+		return nil, orgunit.ErrOrgUnitNotFound
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, orgunit.ErrInvalidOrgUnit
+	}
+	parentPath, err := m.parentPath(input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(parentPath, current.Path) {
+		return nil, orgunit.ErrCycle
+	}
+
+	newPath := parentPath + id + "/"
+	for unitID, unit := range m.units {
+		if strings.HasPrefix(unit.Path, current.Path) {
+			unit.Path = newPath + strings.TrimPrefix(unit.Path, current.Path)
+			m.units[unitID] = unit
+		}
+	}
+
+	unit := m.units[id]
+	unit.Name = input.Name
+	unit.Code = input.Code
+	unit.Level = input.Level
+	unit.ParentID = input.ParentID
+	unit.UpdatedAt = time.Now().Format(time.RFC3339)
+	m.units[id] = unit
+	return &unit, nil
+}
+
+// Delete implements orgunit.Service
+func (m *MockOrgUnitService) Delete(ctx context.Context, id string) error {
+	if _, ok := m.units[id]; !ok {
+		return orgunit.ErrOrgUnitNotFound
+	}
+	for _, unit := range m.units {
+		if unit.ParentID != nil && *unit.ParentID == id {
+			return orgunit.ErrOrgUnitInUse
+		}
+	}
+	for _, orgUnitID := range m.observations {
+		if orgUnitID == id {
+			return orgunit.ErrOrgUnitInUse
+		}
+	}
+	delete(m.units, id)
+	delete(m.users, id)
+	return nil
+}
+
+// ListUsers implements orgunit.Service
+func (m *MockOrgUnitService) ListUsers(ctx context.Context, id string) ([]string, error) {
+	if _, ok := m.units[id]; !ok {
+		return nil, orgunit.ErrOrgUnitNotFound
+	}
+	usernames := slices.Clone(m.users[id])
+	if usernames == nil {
+		usernames = []string{}
+	}
+	sort.Strings(usernames)
+	return usernames, nil
+}
+
+// AssignUser implements orgunit.Service
+func (m *MockOrgUnitService) AssignUser(ctx context.Context, id, username string) error {
+	if _, ok := m.units[id]; !ok {
+		return orgunit.ErrOrgUnitNotFound
+	}
+	if !slices.Contains(m.users[id], username) {
+		m.users[id] = append(m.users[id], username)
+	}
+	return nil
+}
+
+// UnassignUser implements orgunit.Service
+func (m *MockOrgUnitService) UnassignUser(ctx context.Context, id, username string) error {
+	index := slices.Index(m.users[id], username)
+	if index < 0 {
+		return orgunit.ErrOrgUnitNotFound
+	}
+	m.users[id] = slices.Delete(m.users[id], index, index+1)
+	return nil
+}
+
+// AssignObservations implements orgunit.Service
+func (m *MockOrgUnitService) AssignObservations(ctx context.Context, id string, observationIDs []string) (int64, error) {
+	if _, ok := m.units[id]; !ok {
+		return 0, orgunit.ErrOrgUnitNotFound
+	}
+	if len(observationIDs) == 0 {
+		return 0, orgunit.ErrInvalidOrgUnit
+	}
+	var count int64
+	for _, observationID := range observationIDs {
+		if m.observations[observationID] != id {
+			m.observations[observationID] = id
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Ensure MockOrgUnitService implements orgunit.Service
+var _ orgunit.Service = (*MockOrgUnitService)(nil)
@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/replication"
+)
+
+// MockReplicationService is an implementation of replication.Service for testing with one app
+// bundle version waiting to be copied until Replicate is called
+type MockReplicationService struct {
+	Missing []string
+	LastRun *replication.Run
+}
+
+// NewMockReplicationService creates a new mock replication service
+func NewMockReplicationService() *MockReplicationService {
+	return &MockReplicationService{Missing: []string{"0002"}}
+}
+
+// Replicate implements replication.Service
+func (m *MockReplicationService) Replicate(ctx context.Context) (*replication.Run, error) {
+	now := time.Now().UTC()
+	m.LastRun = &replication.Run{StartedAt: now, FinishedAt: now, BundleVersions: len(m.Missing)}
+	m.Missing = nil
+	return m.LastRun, nil
+}
+
+// Status implements replication.Service
+func (m *MockReplicationService) Status(ctx context.Context) (*replication.Status, error) {
+	missing := append([]string{}, m.Missing...)
+	return &replication.Status{
+		Target:          "s3://replica/synkronus",
+		IntervalMinutes: 15,
+		LastRun:         m.LastRun,
+		Bundles: []replication.BundleStatus{{
+			Tenant:                "default",
+			Versions:              2,
+			Missing:               missing,
+			CurrentVersion:        "0002",
+			ReplicaCurrentVersion: "0001",
+		}},
+		BundlesInSync: len(missing) == 0,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/report"
+)
+
+// MockReportService is an in-memory implementation of report.Service for testing. Rendered
+// sections show their text and a one row table naming their query.
+type MockReportService struct {
+	reports map[string]report.Report
+
+	// Delivered lists the names of the reports delivered, in order
+	Delivered []string
+}
+
+// NewMockReportService creates a new mock report service
+func NewMockReportService() *MockReportService {
+	return &MockReportService{reports: make(map[string]report.Report)}
+}
+
+// List implements report.Service
+func (m *MockReportService) List(ctx context.Context) ([]report.Report, error) {
+	result := make([]report.Report, 0, len(m.reports))
+	for _, r := range m.reports {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Get implements report.Service
+func (m *MockReportService) Get(ctx context.Context, name string) (*report.Report, error) {
+	r, ok := m.reports[name]
+	if !ok {
+		return nil, report.ErrNotFound
+	}
+	return &r, nil
+}
+
+// Save implements report.Service
+func (m *MockReportService) Save(ctx context.Context, r report.Report, updatedBy string) (*report.Report, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	r.UpdatedBy = &updatedBy
+	r.UpdatedAt = now
+	if existing, ok := m.reports[r.Name]; ok {
+		r.CreatedAt = existing.CreatedAt
+	} else {
+		r.CreatedAt = now
+	}
+	m.reports[r.Name] = r
+	return &r, nil
+}
+
+// Delete implements report.Service
+func (m *MockReportService) Delete(ctx context.Context, name string) error {
+	if _, ok := m.reports[name]; !ok {
+		return report.ErrNotFound
+	}
+	delete(m.reports, name)
+	return nil
+}
+
+// Render implements report.Service
+func (m *MockReportService) Render(ctx context.Context, name string, username string) (*report.Document, error) {
+	r, ok := m.reports[name]
+	if !ok {
+		return nil, report.ErrNotFound
+	}
+	doc := &report.Document{Name: r.Name, Title: r.Title, Description: r.Description, GeneratedAt: time.Now().UTC()}
+	for _, section := range r.Sections {
+		rendered := report.RenderedSection{Title: section.Title, Text: section.Text}
+		if section.Query != "" {
+			rendered.Table = &report.Table{Header: []string{"query"}, Rows: [][]string{{section.Query}}, Numeric: []bool{false}}
+		}
+		doc.Sections = append(doc.Sections, rendered)
+	}
+	return doc, nil
+}
+
+// Deliver implements report.Service
+func (m *MockReportService) Deliver(ctx context.Context, name string, username string) error {
+	r, ok := m.reports[name]
+	if !ok {
+		return report.ErrNotFound
+	}
+	if len(r.Recipients) == 0 && r.WebhookURL == "" {
+		return report.ErrNoRecipients
+	}
+	m.Delivered = append(m.Delivered, name)
+	return nil
+}
+
+// Run implements report.Service
+func (m *MockReportService) Run(ctx context.Context) {}
@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/opendataensemble/synkronus/pkg/sampling"
+)
+
+// MockSamplingService is an implementation of sampling.Service for testing that validates
+// requests and returns a fixed sample
+type MockSamplingService struct {
+	// LastRequest and LastUsername hold the arguments of the most recent sample
+	LastRequest  sampling.Request
+	LastUsername string
+}
+
+// NewMockSamplingService creates a new mock sampling service
+func NewMockSamplingService() *MockSamplingService {
+	return &MockSamplingService{}
+}
+
+// Sample implements sampling.Service
+func (m *MockSamplingService) Sample(ctx context.Context, req sampling.Request, username string) (*sampling.Sample, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	m.LastRequest = req
+	m.LastUsername = username
+
+	seed := int64(7)
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	return &sampling.Sample{
+		Seed:       seed,
+		Population: 20,
+		Strata:     []sampling.Stratum{{Population: 20, Sampled: 1}},
+		Records: []sampling.Record{{
+			ObservationID: "obs-1",
+			FormType:      "survey",
+			FormVersion:   "1",
+			Data:          []byte(`{}`),
+			CreatedAt:     "2025-06-01T08:00:00Z",
+			UpdatedAt:     "2025-06-01T08:00:00Z",
+		}},
+	}, nil
+}
@@ -0,0 +1,108 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+)
+
+// MockSavedQueryService is an in-memory implementation of savedquery.Service for testing
+type MockSavedQueryService struct {
+	queries map[string]savedquery.Query
+	rows    []map[string]any
+	// LastParams holds the parameters of the most recent run
+	LastParams map[string]string
+}
+
+// NewMockSavedQueryService creates a new mock saved query service
+func NewMockSavedQueryService() *MockSavedQueryService {
+	return &MockSavedQueryService{queries: make(map[string]savedquery.Query)}
+}
+
+// SetRows sets the rows returned by Run
+func (m *MockSavedQueryService) SetRows(rows []map[string]any) {
+	m.rows = rows
+}
+
+// List implements savedquery.Service
+func (m *MockSavedQueryService) List(ctx context.Context) ([]savedquery.Query, error) {
+	result := make([]savedquery.Query, 0, len(m.queries))
+	for _, query := range m.queries {
+		result = append(result, query)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Get implements savedquery.Service
+func (m *MockSavedQueryService) Get(ctx context.Context, name string) (*savedquery.Query, error) {
+	query, ok := m.queries[name]
+	if !ok {
+		return nil, savedquery.ErrQueryNotFound
+	}
+	return &query, nil
+}
+
+// Save implements savedquery.Service
+func (m *MockSavedQueryService) Save(ctx context.Context, query savedquery.Query, updatedBy string) (*savedquery.Query, error) {
+	if !savedquery.ValidName(query.Name) {
+		return nil, fmt.Errorf("%w: invalid name", savedquery.ErrInvalidQuery)
+	}
+	if err := query.Definition.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	query.UpdatedBy = &updatedBy
+	query.UpdatedAt = now
+	query.CreatedAt = now
+	if existing, ok := m.queries[query.Name]; ok {
+		query.CreatedAt = existing.CreatedAt
+	}
+	if query.Roles == nil {
+		query.Roles = []models.Role{}
+	}
+	m.queries[query.Name] = query
+	return &query, nil
+}
+
+// Delete implements savedquery.Service
+func (m *MockSavedQueryService) Delete(ctx context.Context, name string) error {
+	if _, ok := m.queries[name]; !ok {
+		return savedquery.ErrQueryNotFound
+	}
+	delete(m.queries, name)
+	return nil
+}
+
+// Run implements savedquery.Service, checking roles and required parameters and returning
+// the rows set with SetRows
+func (m *MockSavedQueryService) Run(ctx context.Context, name string, params map[string]string, username string, role models.Role) (*savedquery.Result, error) {
+	query, ok := m.queries[name]
+	if !ok {
+		return nil, savedquery.ErrQueryNotFound
+	}
+	allowed := role == models.RoleAdmin
+	for _, r := range query.Roles {
+		allowed = allowed || r == role
+	}
+	if !allowed {
+		return nil, savedquery.ErrForbidden
+	}
+	for _, p := range query.Definition.Parameters {
+		if _, ok := params[p.Name]; p.Required && p.Default == nil && !ok {
+			return nil, fmt.Errorf("%w: %s is required", savedquery.ErrInvalidParameter, p.Name)
+		}
+	}
+	m.LastParams = params
+
+	columns := append(append([]string{}, query.Definition.GroupBy...), "value")
+	rows := m.rows
+	if rows == nil {
+		rows = []map[string]any{}
+	}
+	return &savedquery.Result{Name: name, Columns: columns, Rows: rows}, nil
+}
@@ -0,0 +1,69 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/settings"
+)
+
+// MockSettingsService is an in-memory implementation of settings.Service for testing
+type MockSettingsService struct {
+	settings map[string]settings.Setting
+}
+
+// NewMockSettingsService creates a new mock settings service
+func NewMockSettingsService() *MockSettingsService {
+	return &MockSettingsService{settings: make(map[string]settings.Setting)}
+}
+
+// List implements settings.Service
+func (m *MockSettingsService) List(ctx context.Context) ([]settings.Setting, error) {
+	result := make([]settings.Setting, 0, len(m.settings))
+	for _, setting := range m.settings {
+		result = append(result, setting)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+// Get implements settings.Service
+func (m *MockSettingsService) Get(ctx context.Context, key string) (*settings.Setting, error) {
+	setting, ok := m.settings[key]
+	if !ok {
+		return nil, settings.ErrSettingNotFound
+	}
+	return &setting, nil
+}
+
+// Set implements settings.Service
+func (m *MockSettingsService) Set(ctx context.Context, key string, value json.RawMessage, updatedBy string) (*settings.Setting, error) {
+	if !settings.ValidKey(key) {
+		return nil, settings.ErrInvalidKey
+	}
+	if !json.Valid(value) {
+		return nil, settings.ErrInvalidValue
+	}
+	setting := settings.Setting{
+		Key:       key,
+		Value:     value,
+		UpdatedBy: &updatedBy,
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	m.settings[key] = setting
+	return &setting, nil
+}
+
+// Delete implements settings.Service
+func (m *MockSettingsService) Delete(ctx context.Context, key string) error {
+	if _, ok := m.settings[key]; !ok {
+		return settings.ErrSettingNotFound
+	}
+	delete(m.settings, key)
+	return nil
+}
+
+// Ensure MockSettingsService implements settings.Service
+var _ settings.Service = (*MockSettingsService)(nil)
@@ -2,16 +2,38 @@ package mocks
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 )
 
 // MockSyncService is a mock implementation of the sync.ServiceInterface for testing
 type MockSyncService struct {
 	currentVersion int64
 	observations   []sync.Observation
-	initialized    bool
+	conflicts      []sync.Conflict
+	formControls   map[string]sync.FormSyncControl
+	draftOwners    map[string]string
+	cases          map[string]sync.Case
+	periodLocks    []sync.ReportingPeriodLock
+	assignments    map[string][]sync.FieldAssignment
+	sequences      map[string]int64
+	idSequences    map[string]int64
+	idRanges       []sync.IDRange
+	idLimits       map[string]int64
+	recordLocks    map[string]sync.RecordLock
+	compactions    []sync.CompactionReport
+	pullScope      sync.PullScope
+	recordAssigns  []sync.Assignment
+	// tenants holds the tenant of each pushed observation; others belong to the default tenant
+	tenants     map[string]string
+	initialized bool
 }
 
 // NewMockSyncService creates a new mock sync service
@@ -19,6 +41,14 @@ func NewMockSyncService() *MockSyncService {
 	return &MockSyncService{
 		currentVersion: 1,
 		observations:   make([]sync.Observation, 0), // Initialize as empty slice, not nil
+		formControls:   make(map[string]sync.FormSyncControl),
+		draftOwners:    make(map[string]string),
+		cases:          make(map[string]sync.Case),
+		sequences:      make(map[string]int64),
+		idSequences:    make(map[string]int64),
+		idLimits:       make(map[string]int64),
+		recordLocks:    make(map[string]sync.RecordLock),
+		tenants:        make(map[string]string),
 		initialized:    false,
 	}
 }
@@ -37,6 +67,21 @@ func (m *MockSyncService) GetCurrentVersion(ctx context.Context) (int64, error)
 	return m.currentVersion, nil
 }
 
+// StreamRecordsSinceVersion mocks retrieving records one at a time
+func (m *MockSyncService) StreamRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *sync.SyncPullCursor, fn func(sync.Observation) error) (*sync.SyncResult, error) {
+	result, err := m.GetRecordsSinceVersion(ctx, sinceVersion, clientID, schemaTypes, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+	for _, obs := range result.Records {
+		if err := fn(obs); err != nil {
+			return nil, err
+		}
+	}
+	result.Records = nil
+	return result, nil
+}
+
 // GetRecordsSinceVersion mocks retrieving records that have changed since the specified version
 func (m *MockSyncService) GetRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *sync.SyncPullCursor) (*sync.SyncResult, error) {
 	if !m.initialized {
@@ -44,8 +89,22 @@ func (m *MockSyncService) GetRecordsSinceVersion(ctx context.Context, sinceVersi
 	}
 
 	// Filter observations by version
+	username := sync.UsernameFromContext(ctx)
+	filter := sync.PullFilterFromContext(ctx)
 	var filteredRecords []sync.Observation
 	for _, obs := range m.observations {
+		if m.formControls[obs.FormType].PullPaused || !filter.Matches(obs) || !m.inTenant(ctx, obs) {
+			continue
+		}
+		if obs.Draft && m.draftOwners[obs.ObservationID] != username {
+			continue
+		}
+		if m.pullScope == sync.PullScopeAssigned && username != "" && !sync.IsUnscopedPull(ctx) && !m.assignedTo(obs, username) {
+			continue
+		}
+		if cursor != nil && (obs.Version < cursor.Version || (obs.Version == cursor.Version && obs.ObservationID <= cursor.ID)) {
+			continue
+		}
 		if obs.Version > sinceVersion {
 			// Apply schema type filter if specified
 			if len(schemaTypes) > 0 {
@@ -69,9 +128,17 @@ func (m *MockSyncService) GetRecordsSinceVersion(ctx context.Context, sinceVersi
 		filteredRecords = make([]sync.Observation, 0)
 	}
 
-	// Apply limit
+	// Apply limit in pull order
+	sort.Slice(filteredRecords, func(i, j int) bool {
+		if filteredRecords[i].Version != filteredRecords[j].Version {
+			return filteredRecords[i].Version < filteredRecords[j].Version
+		}
+		return filteredRecords[i].ObservationID < filteredRecords[j].ObservationID
+	})
+	hasMore := false
 	if limit > 0 && len(filteredRecords) > limit {
 		filteredRecords = filteredRecords[:limit]
+		hasMore = true
 	}
 
 	// Determine change cutoff
@@ -80,23 +147,88 @@ func (m *MockSyncService) GetRecordsSinceVersion(ctx context.Context, sinceVersi
 		changeCutoff = filteredRecords[len(filteredRecords)-1].Version
 	}
 
+	var warnings []sync.SyncWarning
+	for formType, control := range m.formControls {
+		if control.PullPaused {
+			warnings = append(warnings, sync.SyncWarning{ID: formType, Code: sync.WarningCodeFormPaused, Message: "sync pull is paused"})
+		}
+	}
+
 	return &sync.SyncResult{
 		CurrentVersion: m.currentVersion,
 		Records:        filteredRecords,
 		ChangeCutoff:   changeCutoff,
-		HasMore:        false, // Mock always returns all data
+		HasMore:        hasMore,
+		Warnings:       warnings,
+	}, nil
+}
+
+// GetRecordsAsOfVersion mocks reconstructing the records as they existed at a past version
+func (m *MockSyncService) GetRecordsAsOfVersion(ctx context.Context, asOfVersion, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *sync.SyncPullCursor) (*sync.SyncResult, error) {
+	if !m.initialized {
+		return nil, fmt.Errorf("sync service not initialized")
+	}
+	if asOfVersion <= 0 || asOfVersion > m.currentVersion {
+		return nil, fmt.Errorf("%w: as_of version out of range", sync.ErrInvalidData)
+	}
+
+	// Every pushed version is kept, so the latest one at or before asOfVersion wins
+	latest := make(map[string]sync.Observation)
+	var order []string
+	for _, obs := range m.observations {
+		if obs.Version > asOfVersion || !m.inTenant(ctx, obs) {
+			continue
+		}
+		if _, ok := latest[obs.ObservationID]; !ok {
+			order = append(order, obs.ObservationID)
+		}
+		latest[obs.ObservationID] = obs
+	}
+
+	records := make([]sync.Observation, 0)
+	for _, id := range order {
+		if obs := latest[id]; obs.Version > sinceVersion && sync.PullFilterFromContext(ctx).Matches(obs) {
+			records = append(records, obs)
+		}
+	}
+
+	changeCutoff := sinceVersion
+	if len(records) > 0 {
+		changeCutoff = records[len(records)-1].Version
+	}
+
+	return &sync.SyncResult{
+		CurrentVersion: asOfVersion,
+		Records:        records,
+		ChangeCutoff:   changeCutoff,
 	}, nil
 }
 
+// inTenant reports whether obs belongs to the tenant of ctx
+func (m *MockSyncService) inTenant(ctx context.Context, obs sync.Observation) bool {
+	return tenant.OrDefault(m.tenants[obs.ObservationID]) == tenant.FromContext(ctx)
+}
+
+// GetVersionAtTime mocks resolving a timestamp to a version; the mock has no clock so it returns the current version
+func (m *MockSyncService) GetVersionAtTime(ctx context.Context, at time.Time) (int64, error) {
+	return m.currentVersion, nil
+}
+
 // ProcessPushedRecords mocks processing records pushed from a client
 func (m *MockSyncService) ProcessPushedRecords(ctx context.Context, records []sync.Observation, clientID string, transmissionID string) (*sync.SyncPushResult, error) {
 	if !m.initialized {
 		return nil, fmt.Errorf("sync service not initialized")
 	}
 
+	if err := sync.VerifyRecordHashes(records); err != nil {
+		return nil, err
+	}
+
 	var successCount int
 	var failedRecords []map[string]interface{}
 	var warnings []sync.SyncWarning
+	assignedFields := make(map[string]map[string]any)
+	versions := make(map[string]int64)
 
 	for i, record := range records {
 		// Basic validation
@@ -109,6 +241,70 @@ func (m *MockSyncService) ProcessPushedRecords(ctx context.Context, records []sy
 			continue
 		}
 
+		// Skip records of paused form types
+		if m.formControls[record.FormType].PushPaused {
+			warnings = append(warnings, sync.SyncWarning{
+				ID:      record.ObservationID,
+				Code:    sync.WarningCodeFormPaused,
+				Message: "sync push is paused",
+			})
+			continue
+		}
+
+		// Reject records locked by another user
+		if lock, ok := m.activeRecordLock(record.ObservationID); ok && lock.LockedBy != sync.UsernameFromContext(ctx) {
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  (&sync.RecordLockedError{Lock: lock}).Error(),
+				"record": record,
+			})
+			continue
+		}
+
+		// Drafts are owned by the pushing user until finalized
+		if record.Draft {
+			username := sync.UsernameFromContext(ctx)
+			if username == "" {
+				failedRecords = append(failedRecords, map[string]interface{}{
+					"index":  i,
+					"error":  "draft records require an authenticated user",
+					"record": record,
+				})
+				continue
+			}
+			if _, ok := m.draftOwners[record.ObservationID]; !ok {
+				m.draftOwners[record.ObservationID] = username
+			}
+		} else {
+			delete(m.draftOwners, record.ObservationID)
+		}
+
+		// Enforce reporting period locks on the collection date
+		if lock := m.periodLockFor(record); lock != nil {
+			if lock.Mode == sync.PeriodLockModeReject {
+				failedRecords = append(failedRecords, map[string]interface{}{
+					"index":  i,
+					"error":  "reporting period is locked",
+					"record": record,
+				})
+				continue
+			}
+			m.conflicts = append(m.conflicts, sync.Conflict{
+				ID:            int64(len(m.conflicts) + 1),
+				ObservationID: record.ObservationID,
+				ClientID:      clientID,
+				ClientRecord:  record,
+				Status:        sync.ConflictStatusPending,
+				Reason:        sync.ConflictReasonPeriodLocked,
+			})
+			warnings = append(warnings, sync.SyncWarning{
+				ID:      record.ObservationID,
+				Code:    sync.WarningCodePendingApproval,
+				Message: "reporting period is locked; the record is held for approval",
+			})
+			continue
+		}
+
 		// Generate warnings for missing optional fields
 		if record.FormType == "" {
 			warnings = append(warnings, sync.SyncWarning{
@@ -118,17 +314,615 @@ func (m *MockSyncService) ProcessPushedRecords(ctx context.Context, records []sy
 			})
 		}
 
+		// The pushing user owns new records
+		if username := sync.UsernameFromContext(ctx); username != "" {
+			record.CreatedBy = &username
+			record.Owner = &username
+		}
+
+		// Fill server-assigned sequence fields, keeping earlier values
+		if assigned := m.assignFields(&record); len(assigned) > 0 {
+			assignedFields[record.ObservationID] = assigned
+		}
+
+		// Records belong to the tenant that first pushed them
+		if _, ok := m.tenants[record.ObservationID]; !ok {
+			m.tenants[record.ObservationID] = tenant.FromContext(ctx)
+		}
+
 		// Mock successful processing - add to observations
 		record.Version = m.currentVersion + 1
 		m.observations = append(m.observations, record)
 		m.currentVersion++
 		successCount++
+		if !record.Draft {
+			versions[record.ObservationID] = record.Version
+		}
 	}
 
-	return &sync.SyncPushResult{
+	result := &sync.SyncPushResult{
 		CurrentVersion: m.currentVersion,
 		SuccessCount:   successCount,
 		FailedRecords:  failedRecords,
 		Warnings:       warnings,
+		Versions:       versions,
+	}
+	if len(assignedFields) > 0 {
+		result.AssignedFields = assignedFields
+	}
+	return result, nil
+}
+
+// SetFieldAssignments sets the server-assigned fields declared per form type
+func (m *MockSyncService) SetFieldAssignments(assignments map[string][]sync.FieldAssignment) {
+	m.assignments = assignments
+}
+
+// assignFields fills the sequence fields of a non-draft record, reusing values of its latest stored version
+func (m *MockSyncService) assignFields(record *sync.Observation) map[string]any {
+	fields := m.assignments[record.FormType]
+	if len(fields) == 0 {
+		return nil
+	}
+
+	data := make(map[string]any)
+	_ = json.Unmarshal(record.Data, &data)
+	stored := make(map[string]any)
+	for _, obs := range m.observations {
+		if obs.ObservationID == record.ObservationID {
+			stored = make(map[string]any)
+			_ = json.Unmarshal(obs.Data, &stored)
+		}
+	}
+
+	assigned := make(map[string]any)
+	for _, field := range fields {
+		if value, ok := stored[field.Field]; ok {
+			assigned[field.Field] = value
+		} else if field.Kind == sync.FieldAssignmentSequence && !record.Draft && !record.Deleted {
+			key := record.FormType + "/" + field.Field
+			m.sequences[key]++
+			assigned[field.Field] = fmt.Sprintf("%s%0*d", field.Prefix, field.Padding, m.sequences[key])
+		} else {
+			delete(data, field.Field)
+			continue
+		}
+		data[field.Field] = assigned[field.Field]
+	}
+	record.Data, _ = json.Marshal(data)
+	return assigned
+}
+
+// AddConflict adds a conflict to the mock conflict backlog
+func (m *MockSyncService) AddConflict(conflict sync.Conflict) {
+	m.conflicts = append(m.conflicts, conflict)
+}
+
+// ListConflicts mocks listing detected sync conflicts
+func (m *MockSyncService) ListConflicts(ctx context.Context, filter sync.ConflictFilter) ([]sync.Conflict, error) {
+	if !m.initialized {
+		return nil, fmt.Errorf("sync service not initialized")
+	}
+
+	conflicts := make([]sync.Conflict, 0)
+	for _, c := range m.conflicts {
+		if filter.Status != "" && c.Status != filter.Status {
+			continue
+		}
+		if filter.ObservationID != "" && c.ObservationID != filter.ObservationID {
+			continue
+		}
+		if filter.Reason != "" && c.Reason != filter.Reason {
+			continue
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+// GetConflict mocks retrieving a single sync conflict
+func (m *MockSyncService) GetConflict(ctx context.Context, id int64) (*sync.Conflict, error) {
+	for i := range m.conflicts {
+		if m.conflicts[i].ID == id {
+			c := m.conflicts[i]
+			return &c, nil
+		}
+	}
+	return nil, sync.ErrConflictNotFound
+}
+
+// ResolveConflict mocks resolving a sync conflict
+func (m *MockSyncService) ResolveConflict(ctx context.Context, id int64, req sync.ConflictResolveRequest, resolvedBy string) (*sync.Conflict, error) {
+	switch req.Resolution {
+	case sync.ResolutionKeepServer, sync.ResolutionKeepClient:
+	case sync.ResolutionMerge:
+		if len(req.Data) == 0 {
+			return nil, sync.ErrInvalidResolution
+		}
+	default:
+		return nil, sync.ErrInvalidResolution
+	}
+
+	for i := range m.conflicts {
+		if m.conflicts[i].ID != id {
+			continue
+		}
+		if m.conflicts[i].Status == sync.ConflictStatusResolved {
+			return nil, sync.ErrConflictAlreadyResolved
+		}
+		resolution := req.Resolution
+		m.conflicts[i].Status = sync.ConflictStatusResolved
+		m.conflicts[i].Resolution = &resolution
+		m.conflicts[i].ResolvedBy = &resolvedBy
+		c := m.conflicts[i]
+		return &c, nil
+	}
+	return nil, sync.ErrConflictNotFound
+}
+
+// ListFormSyncControls mocks listing form sync controls
+func (m *MockSyncService) ListFormSyncControls(ctx context.Context) ([]sync.FormSyncControl, error) {
+	controls := make([]sync.FormSyncControl, 0, len(m.formControls))
+	for _, control := range m.formControls {
+		controls = append(controls, control)
+	}
+	return controls, nil
+}
+
+// SetFormSyncControl mocks pausing or resuming sync of a form type
+func (m *MockSyncService) SetFormSyncControl(ctx context.Context, control sync.FormSyncControl) (*sync.FormSyncControl, error) {
+	if control.FormType == "" {
+		return nil, sync.ErrInvalidData
+	}
+	m.formControls[control.FormType] = control
+	return &control, nil
+}
+
+// DeleteFormSyncControl mocks removing the sync switches of a form type
+func (m *MockSyncService) DeleteFormSyncControl(ctx context.Context, formType string) error {
+	if _, ok := m.formControls[formType]; !ok {
+		return sync.ErrFormControlNotFound
+	}
+	delete(m.formControls, formType)
+	return nil
+}
+
+// AllocateIDRange mocks reserving the next count numbers of a sequence for a client
+func (m *MockSyncService) AllocateIDRange(ctx context.Context, sequence, clientID string, count int) (*sync.IDRange, error) {
+	if sequence == "" || clientID == "" {
+		return nil, fmt.Errorf("%w: sequence and client_id are required", sync.ErrInvalidData)
+	}
+	if count < 1 || count > sync.MaxIDRangeSize {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", sync.ErrInvalidData, sync.MaxIDRangeSize)
+	}
+
+	if limit, ok := m.idLimits[sequence]; ok && m.idSequences[sequence]+int64(count) > limit {
+		return nil, fmt.Errorf("%w %q", sync.ErrIDRangesExhausted, sequence)
+	}
+
+	start := m.idSequences[sequence] + 1
+	m.idSequences[sequence] += int64(count)
+	r := sync.IDRange{
+		ID:          int64(len(m.idRanges) + 1),
+		Sequence:    sequence,
+		ClientID:    clientID,
+		RangeStart:  start,
+		RangeEnd:    m.idSequences[sequence],
+		AllocatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if username := sync.UsernameFromContext(ctx); username != "" {
+		r.AllocatedBy = &username
+	}
+	m.idRanges = append(m.idRanges, r)
+	return &r, nil
+}
+
+// SetIDLimit caps a sequence, as an edge server whose reserved upstream blocks end at limit
+func (m *MockSyncService) SetIDLimit(sequence string, limit int64) {
+	m.idLimits[sequence] = limit
+}
+
+// ListIDRanges mocks listing the ID ranges reserved for a client
+func (m *MockSyncService) ListIDRanges(ctx context.Context, clientID string) ([]sync.IDRange, error) {
+	ranges := make([]sync.IDRange, 0)
+	for _, r := range m.idRanges {
+		if r.ClientID == clientID {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges, nil
+}
+
+// ReassignObservations mocks transferring ownership of observations between users
+func (m *MockSyncService) ReassignObservations(ctx context.Context, req sync.ReassignRequest) (int64, error) {
+	if req.FromUser == "" || req.ToUser == "" || req.FromUser == req.ToUser {
+		return 0, fmt.Errorf("%w: invalid reassignment", sync.ErrInvalidData)
+	}
+
+	var count int64
+	for i := range m.observations {
+		obs := &m.observations[i]
+		if obs.Owner == nil || *obs.Owner != req.FromUser {
+			continue
+		}
+		if req.FormType != "" && obs.FormType != req.FormType {
+			continue
+		}
+		if len(req.ObservationIDs) > 0 && !slices.Contains(req.ObservationIDs, obs.ObservationID) {
+			continue
+		}
+		toUser := req.ToUser
+		obs.Owner = &toUser
+		if obs.Draft {
+			m.draftOwners[obs.ObservationID] = toUser
+		}
+		m.currentVersion++
+		obs.Version = m.currentVersion
+		count++
+	}
+	return count, nil
+}
+
+// SetPullScope sets the pull scope applied to users without an unscoped pull
+func (m *MockSyncService) SetPullScope(scope sync.PullScope) {
+	m.pullScope = scope
+}
+
+// assignedTo reports whether a user owns or is directly assigned an observation. Org unit
+// assignments are not resolved by the mock.
+func (m *MockSyncService) assignedTo(obs sync.Observation, username string) bool {
+	if obs.Owner != nil && *obs.Owner == username {
+		return true
+	}
+	for _, a := range m.recordAssigns {
+		if a.ObservationID == obs.ObservationID && a.Username != nil && *a.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAssignments mocks listing observation assignments
+func (m *MockSyncService) ListAssignments(ctx context.Context, filter sync.AssignmentFilter) ([]sync.Assignment, error) {
+	assignments := make([]sync.Assignment, 0)
+	for _, a := range m.recordAssigns {
+		if filter.ObservationID != "" && a.ObservationID != filter.ObservationID {
+			continue
+		}
+		if filter.Username != "" && (a.Username == nil || *a.Username != filter.Username) {
+			continue
+		}
+		if filter.OrgUnitID != "" && (a.OrgUnitID == nil || *a.OrgUnitID != filter.OrgUnitID) {
+			continue
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// AssignObservations mocks assigning observations, giving newly assigned records a new version
+func (m *MockSyncService) AssignObservations(ctx context.Context, req sync.AssignmentRequest, assignedBy string) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for i := range m.observations {
+		obs := &m.observations[i]
+		if !slices.Contains(req.ObservationIDs, obs.ObservationID) || m.hasAssignment(obs.ObservationID, req) {
+			continue
+		}
+		a := sync.Assignment{ObservationID: obs.ObservationID, AssignedBy: assignedBy, AssignedAt: time.Now().UTC()}
+		if req.Username != "" {
+			username := req.Username
+			a.Username = &username
+		} else {
+			orgUnitID := req.OrgUnitID
+			a.OrgUnitID = &orgUnitID
+		}
+		m.recordAssigns = append(m.recordAssigns, a)
+		m.currentVersion++
+		obs.Version = m.currentVersion
+		count++
+	}
+	return count, nil
+}
+
+// UnassignObservations mocks removing observation assignments
+func (m *MockSyncService) UnassignObservations(ctx context.Context, req sync.AssignmentRequest) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	kept := m.recordAssigns[:0]
+	var count int64
+	for _, a := range m.recordAssigns {
+		if slices.Contains(req.ObservationIDs, a.ObservationID) && assignmentMatches(a, req) {
+			count++
+			continue
+		}
+		kept = append(kept, a)
+	}
+	m.recordAssigns = kept
+	return count, nil
+}
+
+// hasAssignment reports whether an observation is already assigned to the request's assignee
+func (m *MockSyncService) hasAssignment(observationID string, req sync.AssignmentRequest) bool {
+	for _, a := range m.recordAssigns {
+		if a.ObservationID == observationID && assignmentMatches(a, req) {
+			return true
+		}
+	}
+	return false
+}
+
+func assignmentMatches(a sync.Assignment, req sync.AssignmentRequest) bool {
+	if req.Username != "" {
+		return a.Username != nil && *a.Username == req.Username
+	}
+	return a.OrgUnitID != nil && *a.OrgUnitID == req.OrgUnitID
+}
+
+// GetCasesSinceVersion mocks retrieving cases changed since a version
+func (m *MockSyncService) GetCasesSinceVersion(ctx context.Context, sinceVersion int64, caseTypes []string, limit int) (*sync.CaseSyncResult, error) {
+	cases := make([]sync.Case, 0)
+	for _, c := range m.cases {
+		if c.Version <= sinceVersion {
+			continue
+		}
+		if len(caseTypes) > 0 && !slices.Contains(caseTypes, c.CaseType) {
+			continue
+		}
+		cases = append(cases, c)
+	}
+	slices.SortFunc(cases, func(a, b sync.Case) int { return int(a.Version - b.Version) })
+
+	hasMore := limit > 0 && len(cases) > limit
+	if hasMore {
+		cases = cases[:limit]
+	}
+	changeCutoff := sinceVersion
+	if len(cases) > 0 {
+		changeCutoff = cases[len(cases)-1].Version
+	}
+
+	return &sync.CaseSyncResult{
+		CurrentVersion: m.currentVersion,
+		Cases:          cases,
+		ChangeCutoff:   changeCutoff,
+		HasMore:        hasMore,
 	}, nil
 }
+
+// ProcessPushedCases mocks storing pushed cases
+func (m *MockSyncService) ProcessPushedCases(ctx context.Context, cases []sync.Case, clientID string) (*sync.SyncPushResult, error) {
+	var successCount int
+	var failedRecords []map[string]interface{}
+
+	for i, c := range cases {
+		if c.Status == "" {
+			c.Status = sync.CaseStatusOpen
+		}
+		if c.CaseID == "" || c.CaseType == "" || (c.Status != sync.CaseStatusOpen && c.Status != sync.CaseStatusClosed) {
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  "invalid case",
+				"record": c,
+			})
+			continue
+		}
+
+		if existing, ok := m.cases[c.CaseID]; ok {
+			c.CreatedBy = existing.CreatedBy
+		} else if username := sync.UsernameFromContext(ctx); username != "" {
+			c.CreatedBy = &username
+		}
+
+		m.currentVersion++
+		c.Version = m.currentVersion
+		m.cases[c.CaseID] = c
+		successCount++
+	}
+
+	return &sync.SyncPushResult{
+		CurrentVersion: m.currentVersion,
+		SuccessCount:   successCount,
+		FailedRecords:  failedRecords,
+	}, nil
+}
+
+// GetCase mocks retrieving a single case
+func (m *MockSyncService) GetCase(ctx context.Context, caseID string) (*sync.Case, error) {
+	c, ok := m.cases[caseID]
+	if !ok {
+		return nil, sync.ErrCaseNotFound
+	}
+	return &c, nil
+}
+
+// GetCaseObservations mocks retrieving the observations linked to a case
+func (m *MockSyncService) GetCaseObservations(ctx context.Context, caseID string) ([]sync.Observation, error) {
+	records := make([]sync.Observation, 0)
+	for _, obs := range m.observations {
+		if obs.CaseID != nil && *obs.CaseID == caseID && !obs.Deleted {
+			records = append(records, obs)
+		}
+	}
+	return records, nil
+}
+
+// periodLockFor returns the strictest period lock covering the record's created_at date, if any
+func (m *MockSyncService) periodLockFor(record sync.Observation) *sync.ReportingPeriodLock {
+	created, err := time.Parse(time.RFC3339Nano, record.CreatedAt)
+	if err != nil {
+		return nil
+	}
+	day := created.UTC().Format("2006-01-02")
+
+	var match *sync.ReportingPeriodLock
+	for i := range m.periodLocks {
+		lock := &m.periodLocks[i]
+		if lock.FormType != record.FormType || day < lock.PeriodStart || day > lock.PeriodEnd {
+			continue
+		}
+		if match == nil || lock.Mode == sync.PeriodLockModeReject {
+			match = lock
+		}
+	}
+	return match
+}
+
+// ListPeriodLocks mocks listing reporting period locks
+func (m *MockSyncService) ListPeriodLocks(ctx context.Context) ([]sync.ReportingPeriodLock, error) {
+	return slices.Clone(m.periodLocks), nil
+}
+
+// CreatePeriodLock mocks locking a reporting period
+func (m *MockSyncService) CreatePeriodLock(ctx context.Context, lock sync.ReportingPeriodLock) (*sync.ReportingPeriodLock, error) {
+	start, startErr := time.Parse("2006-01-02", lock.PeriodStart)
+	end, endErr := time.Parse("2006-01-02", lock.PeriodEnd)
+	if lock.FormType == "" || startErr != nil || endErr != nil || end.Before(start) {
+		return nil, fmt.Errorf("%w: invalid reporting period", sync.ErrInvalidData)
+	}
+	if lock.Mode == "" {
+		lock.Mode = sync.PeriodLockModeReject
+	}
+	if lock.Mode != sync.PeriodLockModeReject && lock.Mode != sync.PeriodLockModeApproval {
+		return nil, fmt.Errorf("%w: invalid mode", sync.ErrInvalidData)
+	}
+
+	lock.ID = 1
+	if n := len(m.periodLocks); n > 0 {
+		lock.ID = m.periodLocks[n-1].ID + 1
+	}
+	lock.CreatedAt = time.Now().Format(time.RFC3339)
+	m.periodLocks = append(m.periodLocks, lock)
+	return &lock, nil
+}
+
+// DeletePeriodLock mocks reopening a reporting period
+func (m *MockSyncService) DeletePeriodLock(ctx context.Context, id int64) error {
+	for i := range m.periodLocks {
+		if m.periodLocks[i].ID == id {
+			m.periodLocks = slices.Delete(m.periodLocks, i, i+1)
+			return nil
+		}
+	}
+	return sync.ErrPeriodLockNotFound
+}
+
+// Compact mocks compacting the sync log; every tombstone counts as past the retention window
+func (m *MockSyncService) Compact(ctx context.Context, options sync.CompactionOptions) (*sync.CompactionReport, error) {
+	if options.TombstoneRetention < 0 || options.HistoryRetention < 0 {
+		return nil, fmt.Errorf("%w: retention must not be negative", sync.ErrInvalidData)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	report := sync.CompactionReport{
+		TombstoneCutoffVersion: m.currentVersion,
+		DryRun:                 options.DryRun,
+		TriggeredBy:            options.TriggeredBy,
+		StartedAt:              now,
+		FinishedAt:             now,
+	}
+	kept := make([]sync.Observation, 0, len(m.observations))
+	for _, obs := range m.observations {
+		if obs.Deleted {
+			report.TombstonesPurged++
+			continue
+		}
+		kept = append(kept, obs)
+	}
+	if options.DryRun {
+		return &report, nil
+	}
+
+	m.observations = kept
+	report.ID = int64(len(m.compactions) + 1)
+	m.compactions = append(m.compactions, report)
+	return &report, nil
+}
+
+// ListCompactions mocks listing past compactions, newest first
+func (m *MockSyncService) ListCompactions(ctx context.Context, limit int) ([]sync.CompactionReport, error) {
+	reports := slices.Clone(m.compactions)
+	slices.Reverse(reports)
+	if limit > 0 && len(reports) > limit {
+		reports = reports[:limit]
+	}
+	return reports, nil
+}
+
+// activeRecordLock returns the unexpired lock on an observation, if any
+func (m *MockSyncService) activeRecordLock(observationID string) (sync.RecordLock, bool) {
+	lock, ok := m.recordLocks[observationID]
+	if !ok {
+		return lock, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, lock.ExpiresAt)
+	if err != nil || !expiresAt.After(time.Now()) {
+		return lock, false
+	}
+	return lock, true
+}
+
+// ClaimRecordLock mocks locking an observation for the user in the context
+func (m *MockSyncService) ClaimRecordLock(ctx context.Context, observationID string, ttl time.Duration) (*sync.RecordLock, error) {
+	username := sync.UsernameFromContext(ctx)
+	if username == "" {
+		return nil, fmt.Errorf("%w: record locks require an authenticated user", sync.ErrInvalidData)
+	}
+	if ttl == 0 {
+		ttl = sync.DefaultRecordLockTTL
+	}
+	if ttl < time.Second || ttl > sync.MaxRecordLockTTL {
+		return nil, fmt.Errorf("%w: lock duration must be between 1s and %s", sync.ErrInvalidData, sync.MaxRecordLockTTL)
+	}
+
+	now := time.Now().UTC()
+	lock := sync.RecordLock{
+		ObservationID: observationID,
+		LockedBy:      username,
+		LockedAt:      now.Format(time.RFC3339Nano),
+		ExpiresAt:     now.Add(ttl).Format(time.RFC3339Nano),
+	}
+	if held, ok := m.activeRecordLock(observationID); ok {
+		if held.LockedBy != username {
+			return nil, &sync.RecordLockedError{Lock: held}
+		}
+		lock.LockedAt = held.LockedAt
+	}
+	m.recordLocks[observationID] = lock
+	return &lock, nil
+}
+
+// ReleaseRecordLock mocks releasing a record lock
+func (m *MockSyncService) ReleaseRecordLock(ctx context.Context, observationID string, force bool) error {
+	held, ok := m.activeRecordLock(observationID)
+	if !ok {
+		return sync.ErrRecordLockNotFound
+	}
+	if !force && held.LockedBy != sync.UsernameFromContext(ctx) {
+		return &sync.RecordLockedError{Lock: held}
+	}
+	delete(m.recordLocks, observationID)
+	return nil
+}
+
+// ListRecordLocks mocks listing the active record locks
+func (m *MockSyncService) ListRecordLocks(ctx context.Context) ([]sync.RecordLock, error) {
+	locks := make([]sync.RecordLock, 0)
+	for id := range m.recordLocks {
+		if lock, ok := m.activeRecordLock(id); ok {
+			locks = append(locks, lock)
+		}
+	}
+	slices.SortFunc(locks, func(a, b sync.RecordLock) int {
+		if a.ExpiresAt != b.ExpiresAt {
+			return strings.Compare(a.ExpiresAt, b.ExpiresAt)
+		}
+		return strings.Compare(a.ObservationID, b.ObservationID)
+	})
+	return locks, nil
+}
@@ -0,0 +1,92 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/terms"
+)
+
+// MockTermsService is an in-memory implementation of terms.Service for testing
+type MockTermsService struct {
+	documents        []terms.Document
+	acknowledgements []terms.Acknowledgement
+}
+
+// NewMockTermsService creates a new mock terms service
+func NewMockTermsService() *MockTermsService {
+	return &MockTermsService{}
+}
+
+// Current implements terms.Service
+func (m *MockTermsService) Current(ctx context.Context) (*terms.Document, error) {
+	if len(m.documents) == 0 {
+		return nil, terms.ErrNoDocument
+	}
+	doc := m.documents[len(m.documents)-1]
+	return &doc, nil
+}
+
+// Publish implements terms.Service
+func (m *MockTermsService) Publish(ctx context.Context, title, body, publishedBy string) (*terms.Document, error) {
+	if strings.TrimSpace(title) == "" || strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: title and body are required", terms.ErrInvalidDocument)
+	}
+	doc := terms.Document{
+		Version:     len(m.documents) + 1,
+		Title:       title,
+		Body:        body,
+		PublishedBy: publishedBy,
+		PublishedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	m.documents = append(m.documents, doc)
+	return &doc, nil
+}
+
+// Status implements terms.Service
+func (m *MockTermsService) Status(ctx context.Context, username string) (*terms.Status, error) {
+	doc, err := m.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &terms.Status{Document: doc}
+	for _, ack := range m.acknowledgements {
+		if ack.Username == username && ack.Version == doc.Version {
+			status.Acknowledged = true
+			status.AcknowledgedAt = &ack.AcknowledgedAt
+		}
+	}
+	return status, nil
+}
+
+// Acknowledge implements terms.Service
+func (m *MockTermsService) Acknowledge(ctx context.Context, username string, version int) (*terms.Acknowledgement, error) {
+	doc, err := m.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version != doc.Version {
+		return nil, fmt.Errorf("%w: the current version is %d", terms.ErrNotCurrentVersion, doc.Version)
+	}
+	for _, ack := range m.acknowledgements {
+		if ack.Username == username && ack.Version == version {
+			return &ack, nil
+		}
+	}
+	ack := terms.Acknowledgement{Username: username, Version: version, AcknowledgedAt: time.Now().UTC().Format(time.RFC3339)}
+	m.acknowledgements = append(m.acknowledgements, ack)
+	return &ack, nil
+}
+
+// ListAcknowledgements implements terms.Service
+func (m *MockTermsService) ListAcknowledgements(ctx context.Context, version int) ([]terms.Acknowledgement, error) {
+	acks := make([]terms.Acknowledgement, 0)
+	for _, ack := range m.acknowledgements {
+		if ack.Version == version {
+			acks = append(acks, ack)
+		}
+	}
+	return acks, nil
+}
@@ -0,0 +1,245 @@
+package mocks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
+)
+
+// MockWebhookService is an in-memory implementation of webhook.Service for testing
+type MockWebhookService struct {
+	subscriptions []webhook.Subscription
+	deliveries    []webhook.Delivery
+	attempts      []webhook.Attempt
+}
+
+// NewMockWebhookService creates a new mock webhook service
+func NewMockWebhookService() *MockWebhookService {
+	return &MockWebhookService{}
+}
+
+// Create implements webhook.Service
+func (m *MockWebhookService) Create(ctx context.Context, sub webhook.Subscription) (*webhook.Subscription, error) {
+	if strings.TrimSpace(sub.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", webhook.ErrInvalidSubscription)
+	}
+	if u, err := url.Parse(sub.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", webhook.ErrInvalidSubscription)
+	}
+	for _, event := range sub.Events {
+		if !containsString(webhook.Events, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", webhook.ErrInvalidSubscription, event)
+		}
+	}
+	if len(sub.Events) == 0 {
+		sub.Events = []string{webhook.EventObservationPushed}
+	}
+	for _, mask := range sub.Masks {
+		if mask.Method != webhook.MaskRedact && mask.Method != webhook.MaskHash {
+			return nil, fmt.Errorf("%w: invalid mask method", webhook.ErrInvalidSubscription)
+		}
+	}
+	sub.ID = uuid.NewString()
+	if sub.Secret == "" {
+		sub.Secret = "secret-for-" + sub.ID
+	}
+	sub.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	m.subscriptions = append(m.subscriptions, sub)
+	return &sub, nil
+}
+
+// List implements webhook.Service
+func (m *MockWebhookService) List(ctx context.Context) ([]webhook.Subscription, error) {
+	subs := make([]webhook.Subscription, 0, len(m.subscriptions))
+	for _, sub := range m.subscriptions {
+		sub.Secret = ""
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// Delete implements webhook.Service
+func (m *MockWebhookService) Delete(ctx context.Context, id string) error {
+	for i, sub := range m.subscriptions {
+		if sub.ID == id {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return webhook.ErrSubscriptionNotFound
+}
+
+// ListDeliveries implements webhook.Service
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhook.Delivery, error) {
+	found := false
+	for _, sub := range m.subscriptions {
+		found = found || sub.ID == subscriptionID
+	}
+	if !found {
+		return nil, webhook.ErrSubscriptionNotFound
+	}
+	deliveries := make([]webhook.Delivery, 0)
+	for i := len(m.deliveries) - 1; i >= 0 && (limit <= 0 || len(deliveries) < limit); i-- {
+		if m.deliveries[i].SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, m.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+// ObservationsPushed implements sync.PushListener by queueing a pending delivery per matching subscription
+func (m *MockWebhookService) ObservationsPushed(ctx context.Context, tx *sql.Tx, records []sync.Observation) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, record := range records {
+		if record.Draft {
+			continue
+		}
+		for _, sub := range m.subscriptions {
+			if !containsString(sub.Events, webhook.EventObservationPushed) {
+				continue
+			}
+			if len(sub.FormTypes) > 0 && !containsString(sub.FormTypes, record.FormType) {
+				continue
+			}
+			observationID := record.ObservationID
+			m.deliveries = append(m.deliveries, webhook.Delivery{
+				ID:             int64(len(m.deliveries) + 1),
+				SubscriptionID: sub.ID,
+				Event:          webhook.EventObservationPushed,
+				ObservationID:  &observationID,
+				Status:         webhook.DeliveryPending,
+				CreatedAt:      now,
+				NextAttemptAt:  &now,
+			})
+		}
+	}
+	return nil
+}
+
+// Notify implements webhook.Service by queueing a pending delivery per subscription selecting the event
+func (m *MockWebhookService) Notify(ctx context.Context, payload webhook.Payload) error {
+	if payload.Event != webhook.EventBundleActivated && payload.Event != webhook.EventUserCreated {
+		return fmt.Errorf("%w: %q cannot be notified", webhook.ErrInvalidEvent, payload.Event)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, sub := range m.subscriptions {
+		if !containsString(sub.Events, payload.Event) {
+			continue
+		}
+		m.deliveries = append(m.deliveries, webhook.Delivery{
+			ID:             int64(len(m.deliveries) + 1),
+			SubscriptionID: sub.ID,
+			Event:          payload.Event,
+			Status:         webhook.DeliveryPending,
+			CreatedAt:      now,
+			NextAttemptAt:  &now,
+		})
+	}
+	return nil
+}
+
+// ListAttempts implements webhook.Service
+func (m *MockWebhookService) ListAttempts(ctx context.Context, subscriptionID string, deliveryID int64) ([]webhook.Attempt, error) {
+	for _, d := range m.deliveries {
+		if d.ID == deliveryID && d.SubscriptionID == subscriptionID {
+			attempts := make([]webhook.Attempt, 0)
+			for _, a := range m.attempts {
+				if a.DeliveryID == deliveryID {
+					attempts = append(attempts, a)
+				}
+			}
+			return attempts, nil
+		}
+	}
+	return nil, webhook.ErrDeliveryNotFound
+}
+
+// ListDeadLetters implements webhook.Service
+func (m *MockWebhookService) ListDeadLetters(ctx context.Context, subscriptionID string, limit int) ([]webhook.Delivery, error) {
+	deliveries := make([]webhook.Delivery, 0)
+	for i := len(m.deliveries) - 1; i >= 0 && (limit <= 0 || len(deliveries) < limit); i-- {
+		d := m.deliveries[i]
+		if d.Status == webhook.DeliveryFailed && (subscriptionID == "" || d.SubscriptionID == subscriptionID) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// Replay implements webhook.Service
+func (m *MockWebhookService) Replay(ctx context.Context, req webhook.ReplayRequest) (int, error) {
+	if len(req.DeliveryIDs) == 0 && req.SubscriptionID == "" {
+		return 0, fmt.Errorf("%w: select delivery IDs or a subscription", webhook.ErrInvalidReplay)
+	}
+	replayed := 0
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, d := range m.deliveries {
+		if d.Status != webhook.DeliveryFailed {
+			continue
+		}
+		if len(req.DeliveryIDs) > 0 && !containsID(req.DeliveryIDs, d.ID) {
+			continue
+		}
+		if req.SubscriptionID != "" && d.SubscriptionID != req.SubscriptionID {
+			continue
+		}
+		m.deliveries[i].Status = webhook.DeliveryPending
+		m.deliveries[i].Attempts = 0
+		m.deliveries[i].LastError = nil
+		m.deliveries[i].NextAttemptAt = &now
+		replayed++
+	}
+	return replayed, nil
+}
+
+// Fail records a failed attempt at every pending delivery, dead-lettering them as if the
+// receiver had been down for all attempts
+func (m *MockWebhookService) Fail(statusCode int) {
+	message := fmt.Sprintf("webhook responded with status %d", statusCode)
+	for i, d := range m.deliveries {
+		if d.Status != webhook.DeliveryPending {
+			continue
+		}
+		m.attempts = append(m.attempts, webhook.Attempt{
+			ID:          int64(len(m.attempts) + 1),
+			DeliveryID:  d.ID,
+			AttemptedAt: time.Now().UTC().Format(time.RFC3339),
+			StatusCode:  &statusCode,
+			Error:       &message,
+		})
+		m.deliveries[i].Status = webhook.DeliveryFailed
+		m.deliveries[i].Attempts++
+		m.deliveries[i].LastError = &message
+		m.deliveries[i].NextAttemptAt = nil
+	}
+}
+
+// Run implements webhook.Service; the mock delivers nothing
+func (m *MockWebhookService) Run(ctx context.Context) {
+	<-ctx.Done()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(values []int64, value int64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
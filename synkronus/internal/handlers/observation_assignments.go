@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// AssignObservationsResponse reports how many observations were newly assigned
+type AssignObservationsResponse struct {
+	AssignedCount int64 `json:"assigned_count"`
+}
+
+// UnassignObservationsResponse reports how many assignments were removed
+type UnassignObservationsResponse struct {
+	UnassignedCount int64 `json:"unassigned_count"`
+}
+
+// ListAssignments handles GET /assignments, optionally filtered by observation_id, username
+// or org_unit_id
+func (h *Handler) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	assignments, err := h.syncService.ListAssignments(r.Context(), sync.AssignmentFilter{
+		ObservationID: query.Get("observation_id"),
+		Username:      query.Get("username"),
+		OrgUnitID:     query.Get("org_unit_id"),
+	})
+	if err != nil {
+		h.sendAssignmentError(w, err, "Failed to list assignments")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, assignments)
+}
+
+// AssignObservations handles POST /assignments, assigning observations to an enumerator or to
+// the users of an org unit
+func (h *Handler) AssignObservations(w http.ResponseWriter, r *http.Request) {
+	var req sync.AssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	// Records assigned to a user that does not exist would never be pulled
+	if req.Username != "" {
+		found, err := h.userExists(r.Context(), req.Username)
+		if err != nil {
+			h.log.Error("Failed to list users", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to assign observations")
+			return
+		}
+		if !found {
+			SendErrorResponse(w, http.StatusNotFound, nil, "username does not exist")
+			return
+		}
+	}
+
+	assignedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		assignedBy = user.Username
+	}
+	count, err := h.syncService.AssignObservations(r.Context(), req, assignedBy)
+	if err != nil {
+		h.sendAssignmentError(w, err, "Failed to assign observations")
+		return
+	}
+
+	h.recordAudit(r, audit.Entry{Action: audit.ActionObservationAssign, Resource: assignmentResource(req)})
+	SendJSONResponse(w, http.StatusOK, AssignObservationsResponse{AssignedCount: count})
+}
+
+// UnassignObservations handles POST /assignments/unassign
+func (h *Handler) UnassignObservations(w http.ResponseWriter, r *http.Request) {
+	var req sync.AssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	count, err := h.syncService.UnassignObservations(r.Context(), req)
+	if err != nil {
+		h.sendAssignmentError(w, err, "Failed to unassign observations")
+		return
+	}
+
+	h.recordAudit(r, audit.Entry{Action: audit.ActionObservationUnassign, Resource: assignmentResource(req)})
+	SendJSONResponse(w, http.StatusOK, UnassignObservationsResponse{UnassignedCount: count})
+}
+
+// assignmentResource names the assignee of an assignment request in the audit log
+func assignmentResource(req sync.AssignmentRequest) string {
+	if req.OrgUnitID != "" {
+		return "org-units/" + req.OrgUnitID
+	}
+	return "users/" + req.Username
+}
+
+func (h *Handler) sendAssignmentError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, sync.ErrInvalidData) {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	h.log.Error(message, "error", err)
+	SendErrorResponse(w, http.StatusInternalServerError, err, message)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postAssignments(h *Handler, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := withRole(httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)), "admin", models.RoleAdmin)
+	if path == "/assignments/unassign" {
+		h.UnassignObservations(w, r)
+	} else {
+		h.AssignObservations(w, r)
+	}
+	return w
+}
+
+func assignedPullIDs(t *testing.T, resp SyncPullResponse) []string {
+	t.Helper()
+	ids := make([]string, 0, len(resp.Records))
+	for _, record := range resp.Records {
+		ids = append(ids, record.ObservationID)
+	}
+	return ids
+}
+
+func TestAssignments_ScopeAssignedPulls(t *testing.T) {
+	h, _ := createTestHandler()
+	h.syncService.(*mocks.MockSyncService).SetPullScope(sync.PullScopeAssigned)
+	userService := mocks.NewMockUserService()
+	userService.AddUser(&models.User{Username: "amina", Role: models.RoleReadWrite})
+	h.userService = userService
+
+	// alice lists the households; amina only pulls what she is assigned
+	pushObservation(t, h, sync.Observation{ObservationID: "household-1", FormType: "household", Data: json.RawMessage(`{}`)})
+	pushObservation(t, h, sync.Observation{ObservationID: "household-2", FormType: "household", Data: json.RawMessage(`{}`)})
+	assert.Empty(t, pullAs(t, h, "amina").Records)
+	assert.Len(t, pullAs(t, h, "alice").Records, 2, "owners keep pulling their records")
+
+	w := postAssignments(h, "/assignments", `{"observation_ids":["household-2"],"username":"amina"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"assigned_count":1}`, w.Body.String())
+	assert.Equal(t, []string{"household-2"}, assignedPullIDs(t, pullAs(t, h, "amina")))
+
+	// Assigning again changes nothing
+	w = postAssignments(h, "/assignments", `{"observation_ids":["household-2"],"username":"amina"}`)
+	assert.JSONEq(t, `{"assigned_count":0}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ListAssignments(w, httptest.NewRequest(http.MethodGet, "/assignments?username=amina", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var assignments []sync.Assignment
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&assignments))
+	require.Len(t, assignments, 1)
+	assert.Equal(t, "household-2", assignments[0].ObservationID)
+	assert.Equal(t, "admin", assignments[0].AssignedBy)
+
+	// Admins pull every record
+	body, _ := json.Marshal(SyncPullRequest{ClientID: "office"})
+	w = httptest.NewRecorder()
+	h.Pull(w, withRole(httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Records, 2)
+
+	w = postAssignments(h, "/assignments/unassign", `{"observation_ids":["household-2"],"username":"amina"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"unassigned_count":1}`, w.Body.String())
+	assert.Empty(t, pullAs(t, h, "amina").Records)
+}
+
+func TestAssignments_ScopeAllIgnoresAssignments(t *testing.T) {
+	h, _ := createTestHandler()
+
+	pushObservation(t, h, sync.Observation{ObservationID: "household-1", FormType: "household", Data: json.RawMessage(`{}`)})
+	assert.Len(t, pullAs(t, h, "amina").Records, 1)
+}
+
+func TestAssignments_InvalidRequests(t *testing.T) {
+	h, _ := createTestHandler()
+	userService := mocks.NewMockUserService()
+	userService.AddUser(&models.User{Username: "amina", Role: models.RoleReadWrite})
+	h.userService = userService
+
+	assert.Equal(t, http.StatusBadRequest, postAssignments(h, "/assignments", `{"username":"amina"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, postAssignments(h, "/assignments", `{"observation_ids":["obs-1"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		postAssignments(h, "/assignments", `{"observation_ids":["obs-1"],"username":"amina","org_unit_id":"8f14e45f-ceea-467f-a3b4-1b2c3d4e5f60"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, postAssignments(h, "/assignments", `{"observation_ids":["obs-1"],"org_unit_id":"team-a"}`).Code)
+	assert.Equal(t, http.StatusNotFound, postAssignments(h, "/assignments", `{"observation_ids":["obs-1"],"username":"nobody"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, postAssignments(h, "/assignments/unassign", `{"observation_ids":["obs-1"]}`).Code)
+}
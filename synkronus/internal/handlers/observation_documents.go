@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/document"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// sendDocumentError maps supporting document service errors to HTTP responses
+func (h *Handler) sendDocumentError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, document.ErrDocumentNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Document not found")
+	case errors.Is(err, document.ErrObservationNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Observation not found")
+	case errors.Is(err, document.ErrTypeNotAllowed):
+		SendErrorResponse(w, http.StatusUnsupportedMediaType, err, err.Error())
+	case errors.Is(err, document.ErrTooLarge):
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, err, err.Error())
+	default:
+		h.log.Error(message, "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, message)
+	}
+}
+
+// currentUsername returns the username of the authenticated user, or "" if there is none
+func currentUsername(r *http.Request) string {
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		return user.Username
+	}
+	return ""
+}
+
+// ListObservationDocuments handles GET /observations/{observationId}/documents
+func (h *Handler) ListObservationDocuments(w http.ResponseWriter, r *http.Request) {
+	documents, err := h.documentService.List(r.Context(), chi.URLParam(r, "observationId"))
+	if err != nil {
+		h.sendDocumentError(w, err, "Failed to list documents")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"documents": documents,
+	})
+}
+
+// AttachObservationDocument handles POST /observations/{observationId}/documents
+func (h *Handler) AttachObservationDocument(w http.ResponseWriter, r *http.Request) {
+	username := currentUsername(r)
+	if username == "" {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Failed to parse multipart form")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			SendErrorResponse(w, http.StatusBadRequest, nil, "file is required")
+			return
+		}
+		SendErrorResponse(w, http.StatusBadRequest, err, "Failed to get file from form data")
+		return
+	}
+	defer file.Close()
+
+	upload := document.Upload{Filename: header.Filename, Content: file}
+	if description := r.FormValue("description"); description != "" {
+		upload.Description = &description
+	}
+
+	doc, err := h.documentService.Attach(r.Context(), chi.URLParam(r, "observationId"), upload, username)
+	if err != nil {
+		h.sendDocumentError(w, err, "Failed to attach document")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusCreated, doc)
+}
+
+// DownloadObservationDocument handles GET /observations/{observationId}/documents/{documentId}
+func (h *Handler) DownloadObservationDocument(w http.ResponseWriter, r *http.Request) {
+	doc, content, err := h.documentService.Open(r.Context(), chi.URLParam(r, "observationId"), chi.URLParam(r, "documentId"), currentUsername(r))
+	if err != nil {
+		h.sendDocumentError(w, err, "Failed to get document")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.Size, 10))
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(doc.Filename))
+	if _, err := io.Copy(w, content); err != nil {
+		h.log.Error("Failed to stream document", "error", err, "documentId", doc.ID)
+	}
+}
+
+// RemoveObservationDocument handles DELETE /observations/{observationId}/documents/{documentId}
+func (h *Handler) RemoveObservationDocument(w http.ResponseWriter, r *http.Request) {
+	username := currentUsername(r)
+	if username == "" {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	if err := h.documentService.Remove(r.Context(), chi.URLParam(r, "observationId"), chi.URLParam(r, "documentId"), username); err != nil {
+		h.sendDocumentError(w, err, "Failed to remove document")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Document removed"})
+}
+
+// ListObservationDocumentAudit handles GET /observations/{observationId}/documents/audit
+func (h *Handler) ListObservationDocumentAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.documentService.ListAudit(r.Context(), chi.URLParam(r, "observationId"))
+	if err != nil {
+		h.sendDocumentError(w, err, "Failed to list document audit")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"entries": entries,
+	})
+}
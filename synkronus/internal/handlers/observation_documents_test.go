@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uploadDocument(t *testing.T, h *Handler, observationID, filename string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = fw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, mw.WriteField("description", "Signed consent form"))
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/observations/"+observationID+"/documents", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.AttachObservationDocument(w, withURLParams(asUser(r, "clerk"), "observationId", observationID))
+	return w
+}
+
+func TestObservationDocuments(t *testing.T) {
+	h, _ := createTestHandler()
+	h.documentService.(*mocks.MockDocumentService).AddObservation("obs-1")
+
+	pdf := []byte("%PDF-1.4\n% consent scan\n")
+	w := uploadDocument(t, h, "obs-1", "consent.pdf", pdf)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var doc document.Document
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	assert.Equal(t, "application/pdf", doc.ContentType)
+	assert.Equal(t, "clerk", doc.UploadedBy)
+	assert.Equal(t, "Signed consent form", *doc.Description)
+
+	// Executables and other types are refused whatever their name says
+	w = uploadDocument(t, h, "obs-1", "memo.pdf", []byte("MZ\x90\x00binary"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = uploadDocument(t, h, "obs-unknown", "consent.pdf", pdf)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Download returns the stored bytes
+	w = httptest.NewRecorder()
+	h.DownloadObservationDocument(w, withURLParams(asUser(httptest.NewRequest(http.MethodGet, "/", nil), "auditor"), "observationId", "obs-1", "documentId", doc.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, pdf, w.Body.Bytes())
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	h.RemoveObservationDocument(w, withURLParams(asUser(httptest.NewRequest(http.MethodDelete, "/", nil), "clerk"), "observationId", "obs-1", "documentId", doc.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ListObservationDocuments(w, withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), "observationId", "obs-1"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Documents []document.Document `json:"documents"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Empty(t, list.Documents)
+
+	// The audit trail keeps every action
+	w = httptest.NewRecorder()
+	h.ListObservationDocumentAudit(w, withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), "observationId", "obs-1"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var audit struct {
+		Entries []document.AuditEntry `json:"entries"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&audit))
+	require.Len(t, audit.Entries, 3)
+	assert.Equal(t, []string{document.ActionAttach, document.ActionDownload, document.ActionRemove},
+		[]string{audit.Entries[0].Action, audit.Entries[1].Action, audit.Entries[2].Action})
+	assert.Equal(t, "auditor", audit.Entries[1].Username)
+}
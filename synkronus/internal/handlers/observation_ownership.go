@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// ReassignObservationsResponse reports how many observations changed owner
+type ReassignObservationsResponse struct {
+	ReassignedCount int64 `json:"reassigned_count"`
+}
+
+// ReassignObservations handles POST /observations/reassign
+func (h *Handler) ReassignObservations(w http.ResponseWriter, r *http.Request) {
+	var req sync.ReassignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	if req.FromUser == "" || req.ToUser == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "from_user and to_user are required")
+		return
+	}
+
+	// Only hand records over to users that exist, otherwise they would become orphaned
+	found, err := h.userExists(r.Context(), req.ToUser)
+	if err != nil {
+		h.log.Error("Failed to list users", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to reassign observations")
+		return
+	}
+	if !found {
+		SendErrorResponse(w, http.StatusNotFound, nil, "to_user does not exist")
+		return
+	}
+
+	count, err := h.syncService.ReassignObservations(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, sync.ErrInvalidData) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to reassign observations", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to reassign observations")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, ReassignObservationsResponse{ReassignedCount: count})
+}
+
+// userExists reports whether a user with the given username exists
+func (h *Handler) userExists(ctx context.Context, username string) (bool, error) {
+	users, err := h.userService.ListUsers(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range users {
+		if u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReassignObservations(t *testing.T) {
+	h, _ := createTestHandler()
+	userService := mocks.NewMockUserService()
+	userService.AddUser(&models.User{Username: "bob", Role: models.RoleReadWrite})
+	h.userService = userService
+
+	// alice collects two records
+	body, _ := json.Marshal(SyncPushRequest{
+		TransmissionID: "tx-1",
+		ClientID:       "alice-tablet",
+		Records: []sync.Observation{
+			{ObservationID: "obs-1", FormType: "survey", Data: json.RawMessage(`{}`)},
+			{ObservationID: "obs-2", FormType: "household", Data: json.RawMessage(`{}`)},
+		},
+	})
+	w := httptest.NewRecorder()
+	h.Push(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := pullAs(t, h, "alice")
+	require.Len(t, resp.Records, 2)
+	require.NotNil(t, resp.Records[0].CreatedBy)
+	assert.Equal(t, "alice", *resp.Records[0].CreatedBy)
+	cutoff := resp.ChangeCutoff
+
+	// Hand the survey records over to bob
+	reassign, _ := json.Marshal(sync.ReassignRequest{FromUser: "alice", ToUser: "bob", FormType: "survey"})
+	w = httptest.NewRecorder()
+	h.ReassignObservations(w, httptest.NewRequest(http.MethodPost, "/observations/reassign", bytes.NewReader(reassign)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var reassignResp ReassignObservationsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&reassignResp))
+	assert.Equal(t, int64(1), reassignResp.ReassignedCount)
+
+	// The change reaches clients as a new version
+	pullBody, _ := json.Marshal(SyncPullRequest{ClientID: "alice-tablet", Since: &SyncPullRequestSince{Version: cutoff}})
+	w = httptest.NewRecorder()
+	h.Pull(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(pullBody)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pullResp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pullResp))
+	require.Len(t, pullResp.Records, 1)
+	assert.Equal(t, "obs-1", pullResp.Records[0].ObservationID)
+	assert.Equal(t, "bob", *pullResp.Records[0].Owner)
+	assert.Equal(t, "alice", *pullResp.Records[0].CreatedBy)
+}
+
+func TestReassignObservations_UnknownUser(t *testing.T) {
+	h, _ := createTestHandler()
+
+	body, _ := json.Marshal(sync.ReassignRequest{FromUser: "alice", ToUser: "nobody"})
+	w := httptest.NewRecorder()
+	h.ReassignObservations(w, httptest.NewRequest(http.MethodPost, "/observations/reassign", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+)
+
+// AssignOrgUnitObservationsRequest represents the body of POST /org-units/{id}/observations
+type AssignOrgUnitObservationsRequest struct {
+	ObservationIDs []string `json:"observation_ids"`
+}
+
+// AssignOrgUnitObservationsResponse reports how many observations were moved
+type AssignOrgUnitObservationsResponse struct {
+	AssignedCount int64 `json:"assigned_count"`
+}
+
+// sendOrgUnitError maps org unit service errors to HTTP responses
+func (h *Handler) sendOrgUnitError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, orgunit.ErrOrgUnitNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Org unit not found")
+	case errors.Is(err, orgunit.ErrInvalidOrgUnit), errors.Is(err, orgunit.ErrCycle):
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+	case errors.Is(err, orgunit.ErrOrgUnitInUse):
+		SendErrorResponse(w, http.StatusConflict, err, "Org unit still has child units or observations")
+	default:
+		h.log.Error(message, "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, message)
+	}
+}
+
+// ListOrgUnits handles GET /org-units
+func (h *Handler) ListOrgUnits(w http.ResponseWriter, r *http.Request) {
+	units, err := h.orgUnitService.List(r.Context())
+	if err != nil {
+		h.sendOrgUnitError(w, err, "Failed to list org units")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"org_units": units,
+	})
+}
+
+// GetOrgUnit handles GET /org-units/{id}
+func (h *Handler) GetOrgUnit(w http.ResponseWriter, r *http.Request) {
+	unit, err := h.orgUnitService.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendOrgUnitError(w, err, "Failed to get org unit")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, unit)
+}
+
+// CreateOrgUnit handles POST /org-units
+func (h *Handler) CreateOrgUnit(w http.ResponseWriter, r *http.Request) {
+	var input orgunit.OrgUnitInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	unit, err := h.orgUnitService.Create(r.Context(), input)
+	if err != nil {
+		h.sendOrgUnitError(w, err, "Failed to create org unit")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusCreated, unit)
+}
+
+// UpdateOrgUnit handles PUT /org-units/{id}
+func (h *Handler) UpdateOrgUnit(w http.ResponseWriter, r *http.Request) {
+	var input orgunit.OrgUnitInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	unit, err := h.orgUnitService.Update(r.Context(), chi.URLParam(r, "id"), input)
+	if err != nil {
+		h.sendOrgUnitError(w, err, "Failed to update org unit")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, unit)
+}
+
+// DeleteOrgUnit handles DELETE /org-units/{id}
+func (h *Handler) DeleteOrgUnit(w http.ResponseWriter, r *http.Request) {
+	if err := h.orgUnitService.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.sendOrgUnitError(w, err, "Failed to delete org unit")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Org unit deleted"})
+}
+
+// ListOrgUnitUsers handles GET /org-units/{id}/users
+func (h *Handler) ListOrgUnitUsers(w http.ResponseWriter, r *http.Request) {
+	usernames, err := h.orgUnitService.ListUsers(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendOrgUnitError(w, err, "Failed to list org unit users")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"usernames": usernames,
+	})
+}
+
+// AssignOrgUnitUser handles PUT /org-units/{id}/users/{username}
+func (h *Handler) AssignOrgUnitUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+
+	// Only existing users can be assigned
+	users, err := h.userService.ListUsers(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list users", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to assign user")
+		return
+	}
+	found := false
+	for _, u := range users {
+		if u.Username == username {
+			found = true
+			break
+		}
+	}
+	if !found {
+		SendErrorResponse(w, http.StatusNotFound, nil, "User not found")
+		return
+	}
+
+	if err := h.orgUnitService.AssignUser(r.Context(), chi.URLParam(r, "id"), username); err != nil {
+		h.sendOrgUnitError(w, err, "Failed to assign user")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "User assigned to org unit"})
+}
+
+// UnassignOrgUnitUser handles DELETE /org-units/{id}/users/{username}
+func (h *Handler) UnassignOrgUnitUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.orgUnitService.UnassignUser(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "username")); err != nil {
+		h.sendOrgUnitError(w, err, "Failed to unassign user")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "User unassigned from org unit"})
+}
+
+// AssignOrgUnitObservations handles POST /org-units/{id}/observations
+func (h *Handler) AssignOrgUnitObservations(w http.ResponseWriter, r *http.Request) {
+	var req AssignOrgUnitObservationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	count, err := h.orgUnitService.AssignObservations(r.Context(), chi.URLParam(r, "id"), req.ObservationIDs)
+	if err != nil {
+		h.sendOrgUnitError(w, err, "Failed to assign observations")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, AssignOrgUnitObservationsResponse{AssignedCount: count})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withURLParams sets chi URL params given as name/value pairs
+func withURLParams(r *http.Request, params ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(params); i += 2 {
+		rctx.URLParams.Add(params[i], params[i+1])
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func createOrgUnit(t *testing.T, h *Handler, name string, parentID *string) orgunit.OrgUnit {
+	t.Helper()
+	body, _ := json.Marshal(orgunit.OrgUnitInput{Name: name, ParentID: parentID})
+	w := httptest.NewRecorder()
+	h.CreateOrgUnit(w, httptest.NewRequest(http.MethodPost, "/org-units", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var unit orgunit.OrgUnit
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&unit))
+	return unit
+}
+
+func TestOrgUnits_Hierarchy(t *testing.T) {
+	h, _ := createTestHandler()
+
+	region := createOrgUnit(t, h, "North", nil)
+	district := createOrgUnit(t, h, "Hill District", &region.ID)
+	facility := createOrgUnit(t, h, "Hill Clinic", &district.ID)
+	assert.Equal(t, "/"+region.ID+"/"+district.ID+"/"+facility.ID+"/", facility.Path)
+
+	// A unit cannot move below its own descendant
+	body, _ := json.Marshal(orgunit.OrgUnitInput{Name: "North", ParentID: &facility.ID})
+	w := httptest.NewRecorder()
+	h.UpdateOrgUnit(w, withURLParams(httptest.NewRequest(http.MethodPut, "/org-units/"+region.ID, bytes.NewReader(body)), "id", region.ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Moving the district to a new region moves its facilities along
+	south := createOrgUnit(t, h, "South", nil)
+	body, _ = json.Marshal(orgunit.OrgUnitInput{Name: "Hill District", ParentID: &south.ID})
+	w = httptest.NewRecorder()
+	h.UpdateOrgUnit(w, withURLParams(httptest.NewRequest(http.MethodPut, "/org-units/"+district.ID, bytes.NewReader(body)), "id", district.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetOrgUnit(w, withURLParams(httptest.NewRequest(http.MethodGet, "/org-units/"+facility.ID, nil), "id", facility.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var moved orgunit.OrgUnit
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&moved))
+	assert.True(t, strings.HasPrefix(moved.Path, south.Path))
+
+	// Units with children cannot be deleted
+	w = httptest.NewRecorder()
+	h.DeleteOrgUnit(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/org-units/"+south.ID, nil), "id", south.ID))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	h.DeleteOrgUnit(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/org-units/"+region.ID, nil), "id", region.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestOrgUnits_Assignments(t *testing.T) {
+	h, _ := createTestHandler()
+	userService := mocks.NewMockUserService()
+	userService.AddUser(&models.User{Username: "alice", Role: models.RoleReadWrite})
+	h.userService = userService
+
+	facility := createOrgUnit(t, h, "Hill Clinic", nil)
+
+	w := httptest.NewRecorder()
+	h.AssignOrgUnitUser(w, withURLParams(httptest.NewRequest(http.MethodPut, "/", nil), "id", facility.ID, "username", "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.AssignOrgUnitUser(w, withURLParams(httptest.NewRequest(http.MethodPut, "/", nil), "id", facility.ID, "username", "nobody"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ListOrgUnitUsers(w, withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), "id", facility.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var users struct {
+		Usernames []string `json:"usernames"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&users))
+	assert.Equal(t, []string{"alice"}, users.Usernames)
+
+	body, _ := json.Marshal(AssignOrgUnitObservationsRequest{ObservationIDs: []string{"obs-1", "obs-2"}})
+	w = httptest.NewRecorder()
+	h.AssignOrgUnitObservations(w, withURLParams(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), "id", facility.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var assigned AssignOrgUnitObservationsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&assigned))
+	assert.Equal(t, int64(2), assigned.AssignedCount)
+
+	// Units holding observations cannot be deleted
+	w = httptest.NewRecorder()
+	h.DeleteOrgUnit(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/", nil), "id", facility.ID))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestParquetExport_OrgUnit(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?org_unit=not-a-uuid", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
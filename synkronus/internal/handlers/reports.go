@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/report"
+)
+
+// maxReportSize limits the size of a report template
+const maxReportSize = 256 * 1024
+
+// ReportRequest represents the body of PUT /reports/{name}
+type ReportRequest struct {
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Sections    []report.Section `json:"sections"`
+	Schedule    string           `json:"schedule"`
+	Hour        int              `json:"hour"`
+	Format      string           `json:"format"`
+	Recipients  []string         `json:"recipients"`
+	WebhookURL  string           `json:"webhook_url"`
+}
+
+// ListReports handles GET /reports
+func (h *Handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	list, err := h.reportService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list reports", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list reports")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"reports": list,
+	})
+}
+
+// GetReport handles GET /reports/{name}
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	rep, err := h.reportService.Get(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, report.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Report not found")
+			return
+		}
+		h.log.Error("Failed to get report", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get report")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, rep)
+}
+
+// SaveReport handles PUT /reports/{name}
+func (h *Handler) SaveReport(w http.ResponseWriter, r *http.Request) {
+	var req ReportRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxReportSize)).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	updatedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		updatedBy = user.Username
+	}
+
+	rep, err := h.reportService.Save(r.Context(), report.Report{
+		Name:        chi.URLParam(r, "name"),
+		Title:       req.Title,
+		Description: req.Description,
+		Sections:    req.Sections,
+		Schedule:    req.Schedule,
+		Hour:        req.Hour,
+		Format:      req.Format,
+		Recipients:  req.Recipients,
+		WebhookURL:  req.WebhookURL,
+	}, updatedBy)
+	if err != nil {
+		if errors.Is(err, report.ErrInvalidReport) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to save report", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to save report")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, rep)
+}
+
+// DeleteReport handles DELETE /reports/{name}
+func (h *Handler) DeleteReport(w http.ResponseWriter, r *http.Request) {
+	if err := h.reportService.Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+		if errors.Is(err, report.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Report not found")
+			return
+		}
+		h.log.Error("Failed to delete report", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete report")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Report deleted"})
+}
+
+// RenderReport handles GET /reports/{name}/render, running the queries of a report and
+// laying out their results. format is html (default), pdf, or json for the laid out data.
+func (h *Handler) RenderReport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = report.FormatHTML
+	}
+	if format != report.FormatHTML && format != report.FormatPDF && format != "json" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "format must be html, pdf or json")
+		return
+	}
+
+	doc, err := h.reportService.Render(r.Context(), chi.URLParam(r, "name"), user.Username)
+	if err != nil {
+		if errors.Is(err, report.ErrNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Report not found")
+			return
+		}
+		h.log.Error("Failed to render report", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to render report")
+		return
+	}
+	if format == "json" {
+		SendJSONResponse(w, http.StatusOK, doc)
+		return
+	}
+
+	// Render before writing the header so a failure can still be reported with a status
+	var buf bytes.Buffer
+	write, contentType := report.WriteHTML, "text/html; charset=utf-8"
+	if format == report.FormatPDF {
+		write, contentType = report.WritePDF, "application/pdf"
+		w.Header().Set("Content-Disposition", `attachment; filename="`+doc.Name+"-"+doc.GeneratedAt.Format(time.DateOnly)+`.pdf"`)
+	}
+	if err := write(&buf, doc); err != nil {
+		h.log.Error("Failed to render report", "error", err, "name", doc.Name)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to render report")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.log.Error("Failed to send report", "error", err, "name", doc.Name)
+	}
+}
+
+// DeliverReport handles POST /reports/{name}/deliver, sending a report to its recipients and
+// webhook right away
+func (h *Handler) DeliverReport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	if err := h.reportService.Deliver(r.Context(), chi.URLParam(r, "name"), user.Username); err != nil {
+		switch {
+		case errors.Is(err, report.ErrNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "Report not found")
+		case errors.Is(err, report.ErrNoRecipients):
+			SendErrorResponse(w, http.StatusBadRequest, err, "Report has no recipients or webhook")
+		case errors.Is(err, report.ErrEmailNotConfigured):
+			SendErrorResponse(w, http.StatusServiceUnavailable, err, "Emailing reports needs an SMTP server; set SMTP_HOST and SMTP_FROM")
+		case errors.Is(err, report.ErrDeliveryFailed):
+			SendErrorResponse(w, http.StatusBadGateway, err, err.Error())
+		default:
+			h.log.Error("Failed to deliver report", "error", err, "user", user.Username)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to deliver report")
+		}
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Report delivered"})
+}
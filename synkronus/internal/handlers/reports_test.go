@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const monthlyReport = `{
+	"title": "Monthly summary",
+	"sections": [
+		{"title": "Introduction", "text": "Households visited this month."},
+		{"title": "Households", "query": "households", "params": {"since": "2025-09-01"}}
+	],
+	"schedule": "monthly",
+	"hour": 6,
+	"format": "pdf",
+	"recipients": ["team@example.org"]
+}`
+
+func saveReport(t *testing.T, h *Handler, name, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/reports/"+name, bytes.NewBufferString(body))
+	h.SaveReport(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", name))
+	return w
+}
+
+func TestReports_SaveGetListDelete(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := saveReport(t, h, "monthly-summary", monthlyReport)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var saved report.Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&saved))
+	assert.Equal(t, "monthly-summary", saved.Name)
+	assert.Equal(t, report.ScheduleMonthly, saved.Schedule)
+	assert.Equal(t, report.LayoutTable, saved.Sections[1].Layout)
+	require.NotNil(t, saved.UpdatedBy)
+	assert.Equal(t, "admin", *saved.UpdatedBy)
+
+	w = httptest.NewRecorder()
+	h.GetReport(w, withURLParams(httptest.NewRequest(http.MethodGet, "/reports/monthly-summary", nil), "name", "monthly-summary"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ListReports(w, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Reports []report.Report `json:"reports"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Len(t, list.Reports, 1)
+
+	w = httptest.NewRecorder()
+	h.DeleteReport(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/reports/monthly-summary", nil), "name", "monthly-summary"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetReport(w, withURLParams(httptest.NewRequest(http.MethodGet, "/reports/monthly-summary", nil), "name", "monthly-summary"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReports_SaveRejectsInvalidReport(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := saveReport(t, h, "monthly-summary", `{"title": "Monthly summary", "sections": [], "schedule": "hourly"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "schedule must be daily, weekly or monthly")
+
+	w = saveReport(t, h, "monthly-summary", `{"title": `)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReports_Render(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, saveReport(t, h, "monthly-summary", monthlyReport).Code)
+
+	render := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/reports/monthly-summary/render?format="+format, nil)
+		h.RenderReport(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", "monthly-summary"))
+		return w
+	}
+
+	w := render("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<h2>Households</h2>")
+
+	w = render("pdf")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="monthly-summary-`)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+
+	w = render("json")
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc report.Document
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	assert.Len(t, doc.Sections, 2)
+
+	assert.Equal(t, http.StatusBadRequest, render("docx").Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/reports/missing/render", nil)
+	h.RenderReport(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReports_Deliver(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, saveReport(t, h, "monthly-summary", monthlyReport).Code)
+	require.Equal(t, http.StatusOK, saveReport(t, h, "draft", `{"title": "Draft", "sections": [{"title": "Notes", "text": "To do"}]}`).Code)
+
+	deliver := func(name string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/reports/"+name+"/deliver", nil)
+		h.DeliverReport(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", name))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, deliver("monthly-summary").Code)
+	assert.Equal(t, []string{"monthly-summary"}, h.reportService.(*mocks.MockReportService).Delivered)
+	assert.Equal(t, http.StatusBadRequest, deliver("draft").Code)
+	assert.Equal(t, http.StatusNotFound, deliver("missing").Code)
+}
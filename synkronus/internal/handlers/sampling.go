@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sampling"
+)
+
+// SampleObservations handles POST /data/sample, drawing a random or stratified sample of
+// observations for back-checks
+func (h *Handler) SampleObservations(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	var req sampling.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	sample, err := h.samplingService.Sample(r.Context(), req, user.Username)
+	if err != nil {
+		if errors.Is(err, sampling.ErrInvalidRequest) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to sample observations", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to sample observations")
+		return
+	}
+	h.recordAudit(r, audit.Entry{Action: audit.ActionDataSample, Resource: requestResource(r)})
+
+	SendJSONResponse(w, http.StatusOK, sample)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/sampling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleObservations(t *testing.T) {
+	h, _ := createTestHandler()
+	samplingService := h.samplingService.(*mocks.MockSamplingService)
+
+	body := `{"form_type":"household","percent":5,"stratify_by":["enumerator","day"],"seed":42}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/data/sample", bytes.NewBufferString(body))
+	h.SampleObservations(w, withRole(r, "qa-lead", models.RoleReadOnly))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var sample sampling.Sample
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&sample))
+	assert.Equal(t, int64(42), sample.Seed)
+	assert.Len(t, sample.Records, 1)
+	assert.Equal(t, "qa-lead", samplingService.LastUsername)
+	assert.Equal(t, "household", samplingService.LastRequest.FormType)
+	assert.Equal(t, []string{sampling.StratifyEnumerator, sampling.StratifyDay}, samplingService.LastRequest.StratifyBy)
+
+	entries := h.auditService.(*mocks.MockAuditService).Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.ActionDataSample, entries[0].Action)
+}
+
+func TestSampleObservations_Invalid(t *testing.T) {
+	h, _ := createTestHandler()
+
+	for name, body := range map[string]string{
+		"malformed":        `{"percent":`,
+		"no size":          `{"form_type":"household"}`,
+		"unknown stratum":  `{"percent":5,"stratify_by":["village"]}`,
+		"percent and size": `{"percent":5,"size":10}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/data/sample", bytes.NewBufferString(body))
+			h.SampleObservations(w, withRole(r, "qa-lead", models.RoleReadOnly))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.SampleObservations(w, httptest.NewRequest(http.MethodPost, "/data/sample", bytes.NewBufferString(`{"percent":5}`)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
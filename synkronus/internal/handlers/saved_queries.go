@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+)
+
+// maxSavedQuerySize limits the size of a saved query definition
+const maxSavedQuerySize = 64 * 1024
+
+// SavedQueryRequest represents the body of PUT /queries/{name}
+type SavedQueryRequest struct {
+	Description string                `json:"description"`
+	Definition  savedquery.Definition `json:"definition"`
+	Roles       []models.Role         `json:"roles"`
+}
+
+// ListSavedQueries handles GET /queries
+func (h *Handler) ListSavedQueries(w http.ResponseWriter, r *http.Request) {
+	list, err := h.savedQueryService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list saved queries", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list saved queries")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"queries": list,
+	})
+}
+
+// GetSavedQuery handles GET /queries/{name}
+func (h *Handler) GetSavedQuery(w http.ResponseWriter, r *http.Request) {
+	query, err := h.savedQueryService.Get(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, savedquery.ErrQueryNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Saved query not found")
+			return
+		}
+		h.log.Error("Failed to get saved query", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get saved query")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, query)
+}
+
+// SaveSavedQuery handles PUT /queries/{name}
+func (h *Handler) SaveSavedQuery(w http.ResponseWriter, r *http.Request) {
+	var req SavedQueryRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxSavedQuerySize)).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	updatedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		updatedBy = user.Username
+	}
+
+	query, err := h.savedQueryService.Save(r.Context(), savedquery.Query{
+		Name:        chi.URLParam(r, "name"),
+		Description: req.Description,
+		Definition:  req.Definition,
+		Roles:       req.Roles,
+	}, updatedBy)
+	if err != nil {
+		if errors.Is(err, savedquery.ErrInvalidQuery) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to save saved query", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to save saved query")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, query)
+}
+
+// DeleteSavedQuery handles DELETE /queries/{name}
+func (h *Handler) DeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	if err := h.savedQueryService.Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+		if errors.Is(err, savedquery.ErrQueryNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Saved query not found")
+			return
+		}
+		h.log.Error("Failed to delete saved query", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete saved query")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Saved query deleted"})
+}
+
+// RunSavedQuery handles GET /queries/{name}/run. Query string values are the query's parameters.
+func (h *Handler) RunSavedQuery(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	result, err := h.savedQueryService.Run(r.Context(), chi.URLParam(r, "name"), params, user.Username, user.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, savedquery.ErrQueryNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "Saved query not found")
+		case errors.Is(err, savedquery.ErrForbidden):
+			SendErrorResponse(w, http.StatusForbidden, err, "Your role may not run this query")
+		case errors.Is(err, savedquery.ErrInvalidParameter), errors.Is(err, savedquery.ErrInvalidQuery):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		default:
+			h.log.Error("Failed to run saved query", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to run saved query")
+		}
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, result)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withRole(r *http.Request, username string, role models.Role) *http.Request {
+	ctx := context.WithValue(r.Context(), authmw.UserKey, &models.User{Username: username, Role: role})
+	return r.WithContext(ctx)
+}
+
+func saveQuery(t *testing.T, h *Handler, name, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/queries/"+name, bytes.NewBufferString(body))
+	h.SaveSavedQuery(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "name", name))
+	return w
+}
+
+const householdsQuery = `{
+	"description": "Households per village",
+	"definition": {
+		"form_type": "household",
+		"parameters": [{"name": "since", "type": "date", "required": true}],
+		"filters": [{"field": "created_at", "op": "gte", "param": "since"}],
+		"group_by": ["data.village"],
+		"aggregate": {"func": "count"}
+	},
+	"roles": ["read-only"]
+}`
+
+func TestSavedQueries_SaveGetListDelete(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := saveQuery(t, h, "households", householdsQuery)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var saved savedquery.Query
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&saved))
+	assert.Equal(t, "households", saved.Name)
+	assert.Equal(t, []models.Role{models.RoleReadOnly}, saved.Roles)
+	require.NotNil(t, saved.UpdatedBy)
+	assert.Equal(t, "admin", *saved.UpdatedBy)
+
+	w = httptest.NewRecorder()
+	h.GetSavedQuery(w, withURLParams(httptest.NewRequest(http.MethodGet, "/queries/households", nil), "name", "households"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ListSavedQueries(w, httptest.NewRequest(http.MethodGet, "/queries", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Queries []savedquery.Query `json:"queries"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Len(t, list.Queries, 1)
+
+	w = httptest.NewRecorder()
+	h.DeleteSavedQuery(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/queries/households", nil), "name", "households"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetSavedQuery(w, withURLParams(httptest.NewRequest(http.MethodGet, "/queries/households", nil), "name", "households"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSavedQueries_SaveRejectsInvalidDefinition(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := saveQuery(t, h, "households", `{"definition": {"form_type": "household", "aggregate": {"func": "median"}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = saveQuery(t, h, "households", `{"definition": {"form_type": "household", "filters": [{"field": "data.x'); --", "op": "eq", "value": "1"}], "aggregate": {"func": "count"}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = saveQuery(t, h, "Households!", householdsQuery)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSavedQueries_Run(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, saveQuery(t, h, "households", householdsQuery).Code)
+
+	mockService := h.savedQueryService.(*mocks.MockSavedQueryService)
+	mockService.SetRows([]map[string]any{{"data.village": "Kibera", "value": 12.0}})
+
+	run := func(role models.Role, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.RunSavedQuery(w, withURLParams(withRole(httptest.NewRequest(http.MethodGet, target, nil), "alice", role), "name", "households"))
+		return w
+	}
+
+	w := run(models.RoleReadOnly, "/queries/households/run?since=2025-01-01")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result savedquery.Result
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, []string{"data.village", "value"}, result.Columns)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Kibera", result.Rows[0]["data.village"])
+	assert.Equal(t, map[string]string{"since": "2025-01-01"}, mockService.LastParams)
+
+	// Roles not listed on the query are refused; admins may always run it
+	assert.Equal(t, http.StatusForbidden, run(models.RoleReadWrite, "/queries/households/run?since=2025-01-01").Code)
+	assert.Equal(t, http.StatusOK, run(models.RoleAdmin, "/queries/households/run?since=2025-01-01").Code)
+
+	assert.Equal(t, http.StatusBadRequest, run(models.RoleReadOnly, "/queries/households/run").Code)
+
+	w = httptest.NewRecorder()
+	h.RunSavedQuery(w, withURLParams(withRole(httptest.NewRequest(http.MethodGet, "/queries/missing/run", nil), "alice", models.RoleReadOnly), "name", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
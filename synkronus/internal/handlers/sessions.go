@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/opendataensemble/synkronus/pkg/auth"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+)
+
+// ListSessionsHandler handles GET /auth/sessions, listing the current user's active sessions
+func (h *Handler) ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := authmw.GetUserFromContext(r.Context())
+	if currentUser == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), currentUser.Username)
+	if err != nil {
+		h.log.Error("Failed to list sessions", "error", err, "username", currentUser.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list sessions")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, sessions)
+}
+
+// RevokeSessionHandler handles DELETE /auth/sessions/{id}, revoking one of the current user's
+// sessions
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := authmw.GetUserFromContext(r.Context())
+	if currentUser == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), currentUser.Username, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Session not found")
+			return
+		}
+		h.log.Error("Failed to revoke session", "error", err, "username", currentUser.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to revoke session")
+		return
+	}
+
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventSessionRevoked, Username: currentUser.Username})
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Session revoked"})
+}
+
+// RevokeAllSessionsHandler handles DELETE /auth/sessions, signing the current user out
+// everywhere
+func (h *Handler) RevokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := authmw.GetUserFromContext(r.Context())
+	if currentUser == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	count, err := h.authService.RevokeAllSessions(r.Context(), currentUser.Username)
+	if err != nil {
+		h.log.Error("Failed to revoke sessions", "error", err, "username", currentUser.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to revoke sessions")
+		return
+	}
+
+	if count > 0 {
+		h.recordAuthEvent(r, audit.Event{Type: audit.EventSessionRevoked, Username: currentUser.Username})
+	}
+	SendJSONResponse(w, http.StatusOK, map[string]int64{"revoked": count})
+}
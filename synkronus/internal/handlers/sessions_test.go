@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startSession(t *testing.T, h *Handler, userAgent string) LoginResponse {
+	t.Helper()
+	body, _ := json.Marshal(LoginRequest{Username: "testuser", Password: "password123"})
+	r := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	r.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	h.Login(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp LoginResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func logout(h *Handler, refreshToken string) int {
+	body, _ := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+	w := httptest.NewRecorder()
+	h.Logout(w, httptest.NewRequest(http.MethodPost, "/auth/logout", bytes.NewReader(body)))
+	return w.Code
+}
+
+func TestLogout(t *testing.T) {
+	h, _ := createTestHandler()
+	session := startSession(t, h, "synk/1.0")
+
+	assert.Equal(t, http.StatusOK, logout(h, session.RefreshToken))
+	// Unknown and already ended tokens are accepted
+	assert.Equal(t, http.StatusOK, logout(h, session.RefreshToken))
+	assert.Equal(t, http.StatusBadRequest, logout(h, ""))
+
+	body, _ := json.Marshal(RefreshRequest{RefreshToken: session.RefreshToken})
+	w := httptest.NewRecorder()
+	h.RefreshToken(w, httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	events := h.auditService.(*mocks.MockAuditService).Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, audit.EventLogout, events[1].Type)
+	assert.Equal(t, "testuser", events[1].Username)
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	h, _ := createTestHandler()
+	laptop := startSession(t, h, "laptop")
+	startSession(t, h, "phone")
+
+	list := func(username string) []models.Session {
+		w := httptest.NewRecorder()
+		h.ListSessionsHandler(w, withRole(httptest.NewRequest(http.MethodGet, "/auth/sessions", nil), username, models.RoleReadWrite))
+		require.Equal(t, http.StatusOK, w.Code)
+		var sessions []models.Session
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&sessions))
+		return sessions
+	}
+	sessions := list("testuser")
+	require.Len(t, sessions, 2)
+	assert.Empty(t, list("readonly"))
+
+	var laptopID string
+	for _, session := range sessions {
+		if session.UserAgent == "laptop" {
+			laptopID = session.ID.String()
+		}
+	}
+	require.NotEmpty(t, laptopID)
+
+	revoke := func(id, username string) int {
+		w := httptest.NewRecorder()
+		h.RevokeSessionHandler(w, withURLParams(withRole(httptest.NewRequest(http.MethodDelete, "/", nil), username, models.RoleReadWrite), "id", id))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, revoke(laptopID, "readonly"))
+	assert.Equal(t, http.StatusOK, revoke(laptopID, "testuser"))
+	assert.Equal(t, http.StatusNotFound, revoke(laptopID, "testuser"))
+	// The revoked session's refresh token no longer logs out anyone
+	assert.Equal(t, http.StatusOK, logout(h, laptop.RefreshToken))
+	require.Len(t, list("testuser"), 1)
+
+	w := httptest.NewRecorder()
+	h.RevokeAllSessionsHandler(w, withRole(httptest.NewRequest(http.MethodDelete, "/auth/sessions", nil), "testuser", models.RoleReadWrite))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result map[string]int64
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, int64(1), result["revoked"])
+	assert.Empty(t, list("testuser"))
+}
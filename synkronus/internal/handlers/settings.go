@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/settings"
+)
+
+// maxSettingSize limits the size of a single setting value
+const maxSettingSize = 64 * 1024
+
+// SettingRequest represents the body of PUT /settings/{key}
+type SettingRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// ListSettings handles GET /settings
+func (h *Handler) ListSettings(w http.ResponseWriter, r *http.Request) {
+	list, err := h.settingsService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list settings", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list settings")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"settings": list,
+	})
+}
+
+// GetSetting handles GET /settings/{key}
+func (h *Handler) GetSetting(w http.ResponseWriter, r *http.Request) {
+	setting, err := h.settingsService.Get(r.Context(), chi.URLParam(r, "key"))
+	if err != nil {
+		if errors.Is(err, settings.ErrSettingNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Setting not found")
+			return
+		}
+		h.log.Error("Failed to get setting", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get setting")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, setting)
+}
+
+// SetSetting handles PUT /settings/{key}
+func (h *Handler) SetSetting(w http.ResponseWriter, r *http.Request) {
+	var req SettingRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxSettingSize)).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if len(req.Value) == 0 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "value is required")
+		return
+	}
+
+	updatedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		updatedBy = user.Username
+	}
+
+	setting, err := h.settingsService.Set(r.Context(), chi.URLParam(r, "key"), req.Value, updatedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, settings.ErrInvalidKey):
+			SendErrorResponse(w, http.StatusBadRequest, err, "Setting keys must be lowercase letters, digits, '.', '_' or '-' (max 100 characters)")
+		case errors.Is(err, settings.ErrInvalidValue):
+			SendErrorResponse(w, http.StatusBadRequest, err, "value must be valid JSON")
+		default:
+			h.log.Error("Failed to save setting", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to save setting")
+		}
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, setting)
+}
+
+// DeleteSetting handles DELETE /settings/{key}
+func (h *Handler) DeleteSetting(w http.ResponseWriter, r *http.Request) {
+	if err := h.settingsService.Delete(r.Context(), chi.URLParam(r, "key")); err != nil {
+		if errors.Is(err, settings.ErrSettingNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Setting not found")
+			return
+		}
+		h.log.Error("Failed to delete setting", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete setting")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Setting deleted"})
+}
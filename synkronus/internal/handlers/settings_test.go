@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withSettingKey(r *http.Request, key string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("key", key)
+	return asUser(r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)), "admin")
+}
+
+func TestSettings_SetGetDelete(t *testing.T) {
+	h, _ := createTestHandler()
+
+	body := `{"value":{"label":"Q3 2025","ends":"2025-09-30"}}`
+	w := httptest.NewRecorder()
+	h.SetSetting(w, withSettingKey(httptest.NewRequest(http.MethodPut, "/settings/reporting.period", bytes.NewBufferString(body)), "reporting.period"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var saved settings.Setting
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&saved))
+	assert.Equal(t, "reporting.period", saved.Key)
+	require.NotNil(t, saved.UpdatedBy)
+	assert.Equal(t, "admin", *saved.UpdatedBy)
+
+	w = httptest.NewRecorder()
+	h.GetSetting(w, withSettingKey(httptest.NewRequest(http.MethodGet, "/settings/reporting.period", nil), "reporting.period"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched settings.Setting
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&fetched))
+	assert.JSONEq(t, `{"label":"Q3 2025","ends":"2025-09-30"}`, string(fetched.Value))
+
+	w = httptest.NewRecorder()
+	h.ListSettings(w, httptest.NewRequest(http.MethodGet, "/settings", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Settings []settings.Setting `json:"settings"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Len(t, list.Settings, 1)
+
+	w = httptest.NewRecorder()
+	h.DeleteSetting(w, withSettingKey(httptest.NewRequest(http.MethodDelete, "/settings/reporting.period", nil), "reporting.period"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetSetting(w, withSettingKey(httptest.NewRequest(http.MethodGet, "/settings/reporting.period", nil), "reporting.period"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSetSetting_Validation(t *testing.T) {
+	h, _ := createTestHandler()
+
+	tests := []struct {
+		name string
+		key  string
+		body string
+	}{
+		{"invalid key", "Bad_Key", `{"value":"x"}`},
+		{"missing value", "helpdesk.phone", `{}`},
+		{"malformed body", "helpdesk.phone", `{`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.SetSetting(w, withSettingKey(httptest.NewRequest(http.MethodPut, "/settings/"+tt.key, bytes.NewBufferString(tt.body)), tt.key))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
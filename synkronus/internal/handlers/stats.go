@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/latency"
+)
+
+// Bounds on the days of a latency report
+const (
+	defaultLatencyReportDays = 30
+	maxLatencyReportDays     = 366
+)
+
+// GetLatencyStats handles GET /stats/latency (admin only), reporting the p50, p95 and p99
+// latencies of pull, push and export per day. It covers the last 30 days unless from and to
+// are given.
+func (h *Handler) GetLatencyStats(w http.ResponseWriter, r *http.Request) {
+	if h.latencyService == nil {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Latency tracking is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := latency.Filter{Operation: query.Get("operation")}
+	if filter.Operation != "" && !slices.Contains(latency.Operations, filter.Operation) {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "operation must be 'pull', 'push' or 'export'")
+		return
+	}
+
+	var err error
+	filter.To = time.Now().UTC().Truncate(24 * time.Hour)
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.DateOnly, to); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, "to must be a date as YYYY-MM-DD")
+			return
+		}
+	}
+	filter.From = filter.To.AddDate(0, 0, 1-defaultLatencyReportDays)
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.DateOnly, from); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, err, "from must be a date as YYYY-MM-DD")
+			return
+		}
+	}
+	if filter.From.After(filter.To) {
+		SendErrorResponse(w, http.StatusBadRequest, errors.New("from is after to"), "from must not be after to")
+		return
+	}
+	if filter.To.Sub(filter.From) >= maxLatencyReportDays*24*time.Hour {
+		SendErrorResponse(w, http.StatusBadRequest, nil, fmt.Sprintf("A report covers at most %d days", maxLatencyReportDays))
+		return
+	}
+
+	report, err := h.latencyService.Report(r.Context(), filter)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to report latencies")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, report)
+}
+
+// GetAttachmentStats handles GET /stats/attachments (admin only), reporting how many attachments
+// and bytes are kept on local disk and in cold storage, with the lifecycle policy and its last run
+func (h *Handler) GetAttachmentStats(w http.ResponseWriter, r *http.Request) {
+	if h.attachmentLifecycle == nil {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Attachment statistics are not available")
+		return
+	}
+
+	stats, err := h.attachmentLifecycle.Stats(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to report attachment storage")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, stats)
+}
+
+// GetDatabaseStats handles GET /stats/database (admin only), reporting the connections of the
+// database pool, its limits and how often queries waited for a free connection
+func (h *Handler) GetDatabaseStats(w http.ResponseWriter, r *http.Request) {
+	if h.databasePool == nil {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Database statistics are not available")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, h.databasePool.PoolStats())
+}
+
+// GetReplicationStatus handles GET /stats/replication (admin only), reporting which app bundle
+// versions are not yet copied to the secondary location and the latest replication pass
+func (h *Handler) GetReplicationStatus(w http.ResponseWriter, r *http.Request) {
+	if h.replicationService == nil {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Replication is not configured")
+		return
+	}
+
+	status, err := h.replicationService.Status(r.Context())
+	if err != nil {
+		h.log.Error("Failed to get replication status", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get replication status")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, status)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/attachment"
+	"github.com/opendataensemble/synkronus/pkg/database"
+	"github.com/opendataensemble/synkronus/pkg/latency"
+	"github.com/opendataensemble/synkronus/pkg/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLatencyStats(t *testing.T) {
+	h, _ := createTestHandler()
+	latencyService := h.latencyService.(*mocks.MockLatencyService)
+	latencyService.Observe(latency.OperationPull, 120*time.Millisecond)
+	latencyService.Observe(latency.OperationPull, 80*time.Millisecond)
+	latencyService.Observe(latency.OperationExport, 9*time.Second)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedFrom   string
+		expectedTo     string
+		expectedCounts map[string]int64
+	}{
+		{name: "date range", query: "?from=2025-09-01&to=2025-09-30", expectedStatus: http.StatusOK, expectedFrom: "2025-09-01", expectedTo: "2025-09-30",
+			expectedCounts: map[string]int64{latency.OperationPull: 2, latency.OperationExport: 1}},
+		{name: "thirty days before to", query: "?to=2025-09-30", expectedStatus: http.StatusOK, expectedFrom: "2025-09-01", expectedTo: "2025-09-30",
+			expectedCounts: map[string]int64{latency.OperationPull: 2, latency.OperationExport: 1}},
+		{name: "one operation", query: "?from=2025-09-30&to=2025-09-30&operation=export", expectedStatus: http.StatusOK, expectedFrom: "2025-09-30", expectedTo: "2025-09-30",
+			expectedCounts: map[string]int64{latency.OperationExport: 1}},
+		{name: "unknown operation", query: "?operation=login", expectedStatus: http.StatusBadRequest},
+		{name: "invalid date", query: "?from=30/09/2025", expectedStatus: http.StatusBadRequest},
+		{name: "from after to", query: "?from=2025-10-01&to=2025-09-30", expectedStatus: http.StatusBadRequest},
+		{name: "range too long", query: "?from=2024-01-01&to=2025-09-30", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.GetLatencyStats(w, httptest.NewRequest(http.MethodGet, "/stats/latency"+tc.query, nil))
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var report latency.Report
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tc.expectedFrom, report.From)
+			assert.Equal(t, tc.expectedTo, report.To)
+			counts := make(map[string]int64)
+			for _, stats := range report.Overall {
+				counts[stats.Operation] = stats.Count
+			}
+			assert.Equal(t, tc.expectedCounts, counts)
+		})
+	}
+}
+
+func TestGetLatencyStats_DefaultsToLastThirtyDays(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.GetLatencyStats(w, httptest.NewRequest(http.MethodGet, "/stats/latency", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	filter := h.latencyService.(*mocks.MockLatencyService).LastFilter()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	assert.Equal(t, today, filter.To)
+	assert.Equal(t, today.AddDate(0, 0, -29), filter.From)
+}
+
+func TestGetAttachmentStats(t *testing.T) {
+	h, _ := createTestHandler()
+	_, err := h.attachmentLifecycle.Apply(t.Context())
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	h.GetAttachmentStats(w, httptest.NewRequest(http.MethodGet, "/stats/attachments", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats attachment.StorageStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, attachment.TierStats{Attachments: 1, Files: 1, Bytes: 2048}, stats.Cold)
+	assert.Equal(t, "STANDARD_IA", stats.Policy.StorageClass)
+	require.NotNil(t, stats.LastRun)
+	assert.Equal(t, 1, stats.LastRun.Moved)
+
+	h.attachmentLifecycle = nil
+	w = httptest.NewRecorder()
+	h.GetAttachmentStats(w, httptest.NewRequest(http.MethodGet, "/stats/attachments", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetDatabaseStats(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.GetDatabaseStats(w, httptest.NewRequest(http.MethodGet, "/stats/database", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats database.PoolStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 10, stats.MaxOpenConnections)
+	assert.Equal(t, 3, stats.OpenConnections)
+	assert.Equal(t, int64(300), stats.ConnMaxIdleTimeSeconds)
+
+	h.databasePool = nil
+	w = httptest.NewRecorder()
+	h.GetDatabaseStats(w, httptest.NewRequest(http.MethodGet, "/stats/database", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetReplicationStatus(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.GetReplicationStatus(w, httptest.NewRequest(http.MethodGet, "/stats/replication", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status replication.Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "s3://replica/synkronus", status.Target)
+	assert.False(t, status.BundlesInSync)
+	require.Len(t, status.Bundles, 1)
+	assert.Equal(t, []string{"0002"}, status.Bundles[0].Missing)
+
+	h.replicationService = nil
+	w = httptest.NewRecorder()
+	h.GetReplicationStatus(w, httptest.NewRequest(http.MethodGet, "/stats/replication", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
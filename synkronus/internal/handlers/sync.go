@@ -1,18 +1,61 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/device"
+	"github.com/opendataensemble/synkronus/pkg/idempotency"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
 	"github.com/opendataensemble/synkronus/pkg/sync"
 )
 
+// syncContext returns the request context annotated with the authenticated
+// username so the sync service can scope draft records to their owner, and
+// assigned records to their assignees
+func syncContext(r *http.Request) context.Context {
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		ctx := sync.WithUsername(r.Context(), user.Username)
+		// Admins pull every record, whatever the pull scope
+		if user.Role == models.RoleAdmin {
+			ctx = sync.WithUnscopedPull(ctx)
+		}
+		return ctx
+	}
+	return r.Context()
+}
+
 // SyncPullRequest represents the sync pull request payload according to OpenAPI spec
 type SyncPullRequest struct {
 	ClientID    string                `json:"client_id"`
 	Since       *SyncPullRequestSince `json:"since,omitempty"`
 	SchemaTypes []string              `json:"schema_types,omitempty"`
+	AsOf        *SyncPullRequestAsOf  `json:"as_of,omitempty"`
+	// PageToken continues a pull from the next_page_token of its previous page, instead of since
+	PageToken string `json:"page_token,omitempty"`
+	// CreatedAfter and UpdatedAfter only pull records created or last updated after the given time
+	CreatedAfter *time.Time `json:"created_after,omitempty"`
+	UpdatedAfter *time.Time `json:"updated_after,omitempty"`
+	// BoundingBox only pulls records whose geolocation lies within it
+	BoundingBox *sync.BoundingBox `json:"bounding_box,omitempty"`
+	// SyncFormatVersions lists the sync format versions the client understands; the response
+	// uses the highest one the server supports too
+	SyncFormatVersions []string `json:"sync_format_versions,omitempty"`
+}
+
+// SyncPullRequestAsOf selects a past point in time to reconstruct the dataset at.
+// Exactly one of Version and Timestamp must be set.
+type SyncPullRequestAsOf struct {
+	Version   *int64     `json:"version,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
 
 // SyncPullRequestSince represents the pagination cursor in sync pull request
@@ -28,6 +71,9 @@ type SyncPullResponse struct {
 	ChangeCutoff      int64                `json:"change_cutoff"`
 	HasMore           *bool                `json:"has_more,omitempty"`
 	SyncFormatVersion *string              `json:"sync_format_version,omitempty"`
+	Warnings          []sync.SyncWarning   `json:"warnings,omitempty"`
+	// NextPageToken fetches the next page when HasMore is set
+	NextPageToken string `json:"next_page_token,omitempty"`
 }
 
 // Pull handles the /sync/pull endpoint
@@ -43,6 +89,14 @@ func (h *Handler) Pull(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, nil, "client_id is required")
 		return
 	}
+	format, err := sync.NegotiateFormat(req.SyncFormatVersions)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	if h.refuseClient(w, r, req.ClientID, device.SyncPull) {
+		return
+	}
 
 	// Parse query parameters
 	limitStr := r.URL.Query().Get("limit")
@@ -77,25 +131,74 @@ func (h *Handler) Pull(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Call the sync service to get records
-	result, err := h.syncService.GetRecordsSinceVersion(r.Context(), sinceVersion, req.ClientID, schemaTypes, limit, cursor)
+	// Supervisors may narrow the pull to recent or local records
+	filter := sync.PullFilter{
+		CreatedAfter: req.CreatedAfter,
+		UpdatedAfter: req.UpdatedAfter,
+		BoundingBox:  req.BoundingBox,
+	}
+	if err := filter.Validate(); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	if !filter.IsEmpty() {
+		r = r.WithContext(sync.WithPullFilter(r.Context(), filter))
+	}
+
+	// A page token continues a pull with the schema types and filters it was started with
+	filterHash := sync.PullFilterHash(schemaTypes, filter, asOfKey(req.AsOf))
+	if req.PageToken != "" {
+		token, err := h.resumePull(r, req, filterHash)
+		if err != nil {
+			h.sendPageTokenError(w, req, err)
+			return
+		}
+		sinceVersion = token.SinceVersion
+		cursor = &token.Cursor
+	}
+
+	// Large pulls can be streamed record by record instead of buffered in one body
+	if wantsStream(r) {
+		h.streamPull(w, r, req, format, sinceVersion, schemaTypes, limit, cursor, filterHash)
+		return
+	}
+
+	// Call the sync service to get records, reconstructing a past state if requested
+	var result *sync.SyncResult
+	if req.AsOf != nil {
+		result, err = h.pullAsOf(r, req, sinceVersion, schemaTypes, limit, cursor)
+	} else {
+		result, err = h.syncService.GetRecordsSinceVersion(syncContext(r), sinceVersion, req.ClientID, schemaTypes, limit, cursor)
+	}
 	if err != nil {
-		h.log.Error("Failed to get records since version", "error", err)
-		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to retrieve sync data")
+		h.sendPullError(w, err)
 		return
 	}
 
-	// Build response
-	syncFormatVersion := "1.0"
+	// Build response in the negotiated format
+	records := make([]sync.Observation, len(result.Records))
+	for i, record := range result.Records {
+		records[i] = sync.TranslatePulled(format, record)
+	}
 	response := SyncPullResponse{
 		CurrentVersion:    result.CurrentVersion,
-		Records:           result.Records,
+		Records:           records,
 		ChangeCutoff:      result.ChangeCutoff,
 		HasMore:           &result.HasMore,
-		SyncFormatVersion: &syncFormatVersion,
+		SyncFormatVersion: &format,
+		Warnings:          result.Warnings,
 	}
+	if len(result.Records) > 0 {
+		response.NextPageToken, err = h.nextPageToken(req, sinceVersion, filterHash, result, &result.Records[len(result.Records)-1])
+		if err != nil {
+			h.sendPullError(w, err)
+			return
+		}
+	}
+
+	h.recordClientSync(r, req.ClientID, device.SyncPull, result.CurrentVersion)
 
-	// Note: Clients should use change_cutoff as the next since.version for pagination
+	// Note: Clients should use next_page_token, or change_cutoff as the next since.version, for pagination
 
 	h.log.Info("Sync pull request processed", 
 		"clientId", req.ClientID,
@@ -103,32 +206,112 @@ func (h *Handler) Pull(w http.ResponseWriter, r *http.Request) {
 		"currentVersion", result.CurrentVersion,
 		"recordCount", len(result.Records),
 		"hasMore", result.HasMore,
+		"syncFormatVersion", format,
 		"apiVersion", apiVersion)
 
 	SendJSONResponse(w, http.StatusOK, response)
 }
 
+// sendPullError answers a pull that failed before any of its records were sent
+func (h *Handler) sendPullError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sync.ErrInvalidData) {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	h.log.Error("Failed to get records since version", "error", err)
+	SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to retrieve sync data")
+}
+
+// pullAsOf resolves the requested point in time to a version and pulls the dataset as of that version
+func (h *Handler) pullAsOf(r *http.Request, req SyncPullRequest, sinceVersion int64, schemaTypes []string, limit int, cursor *sync.SyncPullCursor) (*sync.SyncResult, error) {
+	ctx := syncContext(r)
+
+	var asOfVersion int64
+	switch {
+	case req.AsOf.Version != nil && req.AsOf.Timestamp != nil:
+		return nil, fmt.Errorf("%w: as_of accepts either version or timestamp, not both", sync.ErrInvalidData)
+	case req.AsOf.Version != nil:
+		asOfVersion = *req.AsOf.Version
+	case req.AsOf.Timestamp != nil:
+		version, err := h.syncService.GetVersionAtTime(ctx, *req.AsOf.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		asOfVersion = version
+	default:
+		return nil, fmt.Errorf("%w: as_of requires a version or timestamp", sync.ErrInvalidData)
+	}
+
+	return h.syncService.GetRecordsAsOfVersion(ctx, asOfVersion, sinceVersion, req.ClientID, schemaTypes, limit, cursor)
+}
+
 // SyncPushRequest represents the sync push request payload according to OpenAPI spec
 type SyncPushRequest struct {
 	TransmissionID string             `json:"transmission_id"`
 	ClientID       string             `json:"client_id"`
 	Records        []sync.Observation `json:"records"`
+	// SyncFormatVersions lists the sync format versions the client understands
+	SyncFormatVersions []string `json:"sync_format_versions,omitempty"`
 }
 
 // SyncPushResponse represents the sync push response payload according to OpenAPI spec
 type SyncPushResponse struct {
-	CurrentVersion int64                      `json:"current_version"`
-	SuccessCount   int                        `json:"success_count"`
-	FailedRecords  []map[string]interface{}   `json:"failed_records,omitempty"`
-	Warnings       []sync.SyncWarning         `json:"warnings,omitempty"`
+	CurrentVersion    int64                     `json:"current_version"`
+	SuccessCount      int                       `json:"success_count"`
+	FailedRecords     []map[string]interface{}  `json:"failed_records,omitempty"`
+	Warnings          []sync.SyncWarning        `json:"warnings,omitempty"`
+	AssignedFields    map[string]map[string]any `json:"assigned_fields,omitempty"`
+	Conflicts         []sync.PushConflict       `json:"conflicts,omitempty"`
+	Receipts          []sync.Receipt            `json:"receipts,omitempty"`
+	SyncFormatVersion string                    `json:"sync_format_version"`
+}
+
+// TransmissionHashHeader carries the optional hex SHA-256 of the raw push request body
+const TransmissionHashHeader = "X-Transmission-Hash"
+
+// IdempotentReplayHeader marks the response to a retried push, which repeats the first response
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// ChecksumErrorResponse is returned when a pushed payload fails checksum verification
+type ChecksumErrorResponse struct {
+	Error      string                  `json:"error"`
+	Code       string                  `json:"code"`
+	Message    string                  `json:"message"`
+	Mismatches []sync.ChecksumMismatch `json:"mismatches,omitempty"`
+}
+
+// sendChecksumError writes a structured checksum failure so clients know to resend
+func sendChecksumError(w http.ResponseWriter, message string, mismatches []sync.ChecksumMismatch) {
+	SendJSONResponse(w, http.StatusBadRequest, ChecksumErrorResponse{
+		Error:      sync.ErrChecksumMismatch.Error(),
+		Code:       "CHECKSUM_MISMATCH",
+		Message:    message,
+		Mismatches: mismatches,
+	})
 }
 
 // Push handles the /sync/push endpoint
 func (h *Handler) Push(w http.ResponseWriter, r *http.Request) {
 	var req SyncPushRequest
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.log.Error("Failed to read sync push request", "error", err)
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	// Verify the transmission hash before decoding so corrupted bodies are never parsed
+	if expected := r.Header.Get(TransmissionHashHeader); expected != "" {
+		if actual := sync.ContentHash(body); !strings.EqualFold(expected, actual) {
+			h.log.Warn("Rejected push with corrupted transmission", "expected", expected, "actual", actual)
+			sendChecksumError(w, "Transmission hash does not match the request body; resend the transmission", nil)
+			return
+		}
+	}
+
 	// Decode request body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		h.log.Error("Failed to decode sync push request", "error", err)
 		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
 		return
@@ -147,13 +330,46 @@ func (h *Handler) Push(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, nil, "records array is required")
 		return
 	}
+	format, err := sync.NegotiateFormat(req.SyncFormatVersions)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	// Suspended and blocked devices are refused before anything they sent is looked at
+	if h.refuseClient(w, r, req.ClientID, device.SyncPush) {
+		return
+	}
+
+	// A transmission retried after its response was lost is answered with the first response
+	// instead of being applied again, whichever server behind the load balancer it reaches
+	idempotencyKey, fingerprint, replayed := h.claimTransmission(w, r, &req)
+	if replayed {
+		return
+	}
+	completed := false
+	if idempotencyKey != "" {
+		defer func() {
+			if !completed {
+				if err := h.idempotencyStore.Release(context.WithoutCancel(r.Context()), idempotencyKey); err != nil {
+					h.log.Warn("Failed to release transmission", "transmissionId", req.TransmissionID, "error", err)
+				}
+			}
+		}()
+	}
 
 	// Parse API version header
 	apiVersion := r.Header.Get("x-api-version")
 
 	// Process the records using the sync service
-	result, err := h.syncService.ProcessPushedRecords(r.Context(), req.Records, req.ClientID, req.TransmissionID)
+	records := sync.TranslatePushed(format, req.Records)
+	result, err := h.syncService.ProcessPushedRecords(syncContext(r), records, req.ClientID, req.TransmissionID)
 	if err != nil {
+		var checksumErr *sync.ChecksumError
+		if errors.As(err, &checksumErr) {
+			sendChecksumError(w, "Record hashes do not match their data; resend the transmission", checksumErr.Mismatches)
+			return
+		}
 		h.log.Error("Failed to process pushed records", "error", err)
 		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to process sync data")
 		return
@@ -161,10 +377,14 @@ func (h *Handler) Push(w http.ResponseWriter, r *http.Request) {
 
 	// Build response from service result
 	response := SyncPushResponse{
-		CurrentVersion: result.CurrentVersion,
-		SuccessCount:   result.SuccessCount,
-		FailedRecords:  result.FailedRecords,
-		Warnings:       result.Warnings,
+		CurrentVersion:    result.CurrentVersion,
+		SuccessCount:      result.SuccessCount,
+		FailedRecords:     result.FailedRecords,
+		Warnings:          result.Warnings,
+		AssignedFields:    result.AssignedFields,
+		Conflicts:         result.Conflicts,
+		Receipts:          h.pushReceipts(r, records, result.Versions),
+		SyncFormatVersion: format,
 	}
 
 	h.log.Info("Sync push request processed", 
@@ -174,9 +394,59 @@ func (h *Handler) Push(w http.ResponseWriter, r *http.Request) {
 		"successCount", result.SuccessCount,
 		"failedCount", len(result.FailedRecords),
 		"warningCount", len(result.Warnings),
+		"conflictCount", len(result.Conflicts),
 		"currentVersion", result.CurrentVersion,
 		"apiVersion", apiVersion)
 
+	h.recordClientSync(r, req.ClientID, device.SyncPush, result.CurrentVersion)
+
+	if idempotencyKey != "" {
+		body, _ := json.Marshal(response)
+		stored := idempotency.Response{Status: http.StatusOK, Body: body}
+		if err := h.idempotencyStore.Complete(r.Context(), idempotencyKey, fingerprint, stored); err != nil {
+			h.log.Warn("Failed to keep push response for retries", "transmissionId", req.TransmissionID, "error", err)
+		}
+		completed = true
+	}
+
 	// Send response
 	SendJSONResponse(w, http.StatusOK, response)
 }
+
+// claimTransmission claims the transmission of a push in the idempotency store. It returns the
+// claimed key and request fingerprint, which are empty when transmissions are not tracked, and
+// whether a response was already sent: the kept response of a processed transmission, or an
+// error for one still being processed or one whose ID was reused for other records.
+func (h *Handler) claimTransmission(w http.ResponseWriter, r *http.Request, req *SyncPushRequest) (string, string, bool) {
+	if h.idempotencyStore == nil {
+		return "", "", false
+	}
+	records, err := json.Marshal(req.Records)
+	if err != nil {
+		return "", "", false
+	}
+	key := transmissionKey(r, req.ClientID, req.TransmissionID)
+	fingerprint := sync.ContentHash(records)
+
+	stored, err := h.idempotencyStore.Claim(r.Context(), key, fingerprint)
+	switch {
+	case errors.Is(err, idempotency.ErrInProgress):
+		SendErrorResponse(w, http.StatusConflict, err, "This transmission is still being processed; retry later")
+		return "", "", true
+	case errors.Is(err, idempotency.ErrKeyReused):
+		SendErrorResponse(w, http.StatusUnprocessableEntity, err, "transmission_id was already used for different records")
+		return "", "", true
+	case err != nil:
+		// Pushes keep working while the store is unreachable, as they did without one
+		h.log.Warn("Failed to check transmission for retries", "transmissionId", req.TransmissionID, "error", err)
+		return "", "", false
+	case stored != nil:
+		h.log.Info("Answered retried transmission with its first response", "transmissionId", req.TransmissionID, "clientId", req.ClientID)
+		w.Header().Set("content-type", "application/json")
+		w.Header().Set(IdempotentReplayHeader, "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Body)
+		return "", "", true
+	}
+	return key, fingerprint, false
+}
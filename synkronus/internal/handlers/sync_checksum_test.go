@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPush_ChecksumVerification(t *testing.T) {
+	data := json.RawMessage(`{"field":"value"}`)
+	pushBody := func(hash string) []byte {
+		body, _ := json.Marshal(SyncPushRequest{
+			TransmissionID: "tx-1",
+			ClientID:       "client-1",
+			Records:        []sync.Observation{{ObservationID: "obs-1", FormType: "survey", Data: data, Hash: hash}},
+		})
+		return body
+	}
+
+	t.Run("valid hashes are accepted", func(t *testing.T) {
+		h, _ := createTestHandler()
+		body := pushBody(sync.ContentHash(data))
+
+		req := httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body))
+		req.Header.Set(TransmissionHashHeader, sync.ContentHash(body))
+		w := httptest.NewRecorder()
+		h.Push(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp SyncPushResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, 1, resp.SuccessCount)
+	})
+
+	t.Run("corrupted transmission is rejected", func(t *testing.T) {
+		h, _ := createTestHandler()
+		body := pushBody("")
+
+		req := httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body))
+		req.Header.Set(TransmissionHashHeader, sync.ContentHash([]byte("something else")))
+		w := httptest.NewRecorder()
+		h.Push(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var resp ChecksumErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "CHECKSUM_MISMATCH", resp.Code)
+	})
+
+	t.Run("corrupted record is rejected", func(t *testing.T) {
+		h, _ := createTestHandler()
+		body := pushBody(sync.ContentHash([]byte(`{"field":"valuf"}`)))
+
+		w := httptest.NewRecorder()
+		h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var resp ChecksumErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(t, resp.Mismatches, 1)
+		assert.Equal(t, "obs-1", resp.Mismatches[0].ObservationID)
+	})
+}
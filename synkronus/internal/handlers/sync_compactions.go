@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// CompactionRequest represents the request body for compacting the sync log. Omitted
+// retentions fall back to the server configuration.
+type CompactionRequest struct {
+	TombstoneRetentionDays int  `json:"tombstone_retention_days,omitempty"`
+	HistoryRetentionDays   int  `json:"history_retention_days,omitempty"`
+	DryRun                 bool `json:"dry_run,omitempty"`
+}
+
+// CompactSyncLog handles POST /sync/compactions
+func (h *Handler) CompactSyncLog(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	// An empty body compacts with the configured retentions
+	var req CompactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if req.TombstoneRetentionDays < 0 || req.HistoryRetentionDays < 0 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "Retention days must not be negative")
+		return
+	}
+
+	report, err := h.syncService.Compact(r.Context(), sync.CompactionOptions{
+		TombstoneRetention: time.Duration(req.TombstoneRetentionDays) * 24 * time.Hour,
+		HistoryRetention:   time.Duration(req.HistoryRetentionDays) * 24 * time.Hour,
+		DryRun:             req.DryRun,
+		TriggeredBy:        user.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sync.ErrInvalidData) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to compact sync log", "error", err, "user", user.Username)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to compact sync log")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, report)
+}
+
+// ListSyncCompactions handles GET /sync/compactions?limit=
+func (h *Handler) ListSyncCompactions(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	compactions, err := h.syncService.ListCompactions(r.Context(), limit)
+	if err != nil {
+		h.log.Error("Failed to list compactions", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list compactions")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"compactions": compactions,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compactSyncLog(t *testing.T, h *Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.CompactSyncLog(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/compactions", strings.NewReader(body)), "admin"))
+	return w
+}
+
+func TestCompactSyncLog(t *testing.T) {
+	h, _ := createTestHandler()
+	pushObservation(t, h, sync.Observation{ObservationID: "obs-kept", FormType: "survey", Data: json.RawMessage(`{}`)})
+	pushObservation(t, h, sync.Observation{ObservationID: "obs-gone", FormType: "survey", Data: json.RawMessage(`{}`), Deleted: true})
+
+	// A dry run counts without removing
+	w := compactSyncLog(t, h, `{"tombstone_retention_days": 30, "dry_run": true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var report sync.CompactionReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.True(t, report.DryRun)
+	assert.EqualValues(t, 1, report.TombstonesPurged)
+
+	// An empty body uses the configured retentions
+	w = compactSyncLog(t, h, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.False(t, report.DryRun)
+	assert.EqualValues(t, 1, report.TombstonesPurged)
+	assert.Equal(t, "admin", report.TriggeredBy)
+
+	w = httptest.NewRecorder()
+	h.ListSyncCompactions(w, httptest.NewRequest(http.MethodGet, "/sync/compactions?limit=5", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Compactions []sync.CompactionReport `json:"compactions"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Compactions, 1)
+}
+
+func TestCompactSyncLog_Invalid(t *testing.T) {
+	h, _ := createTestHandler()
+
+	assert.Equal(t, http.StatusBadRequest, compactSyncLog(t, h, `{"history_retention_days": -1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, compactSyncLog(t, h, `not json`).Code)
+
+	w := httptest.NewRecorder()
+	h.CompactSyncLog(w, httptest.NewRequest(http.MethodPost, "/sync/compactions", bytes.NewReader(nil)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ListSyncCompactions(w, httptest.NewRequest(http.MethodGet, "/sync/compactions?limit=zero", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
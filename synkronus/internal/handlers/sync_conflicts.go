@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// ConflictListResponse represents the response of the conflict listing endpoint
+type ConflictListResponse struct {
+	Conflicts []sync.Conflict `json:"conflicts"`
+	Count     int             `json:"count"`
+}
+
+// ListSyncConflicts handles GET /sync/conflicts
+func (h *Handler) ListSyncConflicts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := sync.ConflictFilter{
+		Status:        sync.ConflictStatus(query.Get("status")),
+		ObservationID: query.Get("observation_id"),
+		Reason:        sync.ConflictReason(query.Get("reason")),
+	}
+
+	if filter.Status != "" && filter.Status != sync.ConflictStatusPending && filter.Status != sync.ConflictStatusResolved {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "status must be 'pending' or 'resolved'")
+		return
+	}
+	if filter.Reason != "" && filter.Reason != sync.ConflictReasonConflict &&
+		filter.Reason != sync.ConflictReasonPeriodLocked && filter.Reason != sync.ConflictReasonUpstream {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "reason must be 'conflict', 'period_locked' or 'upstream'")
+		return
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = parsedLimit
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			filter.Offset = parsedOffset
+		}
+	}
+
+	conflicts, err := h.syncService.ListConflicts(r.Context(), filter)
+	if err != nil {
+		h.log.Error("Failed to list sync conflicts", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list sync conflicts")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, ConflictListResponse{
+		Conflicts: conflicts,
+		Count:     len(conflicts),
+	})
+}
+
+// GetSyncConflict handles GET /sync/conflicts/{id}
+func (h *Handler) GetSyncConflict(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid conflict id")
+		return
+	}
+
+	conflict, err := h.syncService.GetConflict(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sync.ErrConflictNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Conflict not found")
+			return
+		}
+		h.log.Error("Failed to get sync conflict", "error", err, "conflictId", id)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get sync conflict")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, conflict)
+}
+
+// ResolveSyncConflict handles POST /sync/conflicts/{id}/resolve
+func (h *Handler) ResolveSyncConflict(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid conflict id")
+		return
+	}
+
+	var req sync.ConflictResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	conflict, err := h.syncService.ResolveConflict(r.Context(), id, req, user.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, sync.ErrInvalidResolution):
+			SendErrorResponse(w, http.StatusBadRequest, err, "resolution must be 'keep_server', 'keep_client' or 'merge'")
+		case errors.Is(err, sync.ErrConflictNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "Conflict not found")
+		case errors.Is(err, sync.ErrConflictAlreadyResolved):
+			SendErrorResponse(w, http.StatusConflict, err, "Conflict has already been resolved")
+		default:
+			h.log.Error("Failed to resolve sync conflict", "error", err, "conflictId", id)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to resolve sync conflict")
+		}
+		return
+	}
+
+	h.log.Info("Sync conflict resolved", "conflictId", id, "resolution", req.Resolution, "user", user.Username)
+	SendJSONResponse(w, http.StatusOK, conflict)
+}
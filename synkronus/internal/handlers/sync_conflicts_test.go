@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conflictTestHandler returns a handler whose mock sync service holds two conflicts
+func conflictTestHandler() *Handler {
+	h, _ := createTestHandler()
+	mockSync := h.syncService.(*mocks.MockSyncService)
+	mockSync.AddConflict(sync.Conflict{
+		ID:            1,
+		ObservationID: "obs-1",
+		ClientID:      "client-a",
+		ServerRecord:  sync.Observation{ObservationID: "obs-1", Data: json.RawMessage(`{"a":1}`)},
+		ClientRecord:  sync.Observation{ObservationID: "obs-1", Data: json.RawMessage(`{"a":2}`)},
+		Status:        sync.ConflictStatusPending,
+	})
+	resolution := sync.ResolutionKeepServer
+	mockSync.AddConflict(sync.Conflict{
+		ID:            2,
+		ObservationID: "obs-2",
+		ClientID:      "client-b",
+		Status:        sync.ConflictStatusResolved,
+		Resolution:    &resolution,
+	})
+	return h
+}
+
+func withConflictID(r *http.Request, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, authmw.UserKey, &models.User{Username: "admin", Role: models.RoleAdmin})
+	return r.WithContext(ctx)
+}
+
+func TestListSyncConflicts(t *testing.T) {
+	h := conflictTestHandler()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "all conflicts", query: "", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "pending only", query: "?status=pending", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "by observation", query: "?observation_id=obs-2", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "invalid status", query: "?status=bogus", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sync/conflicts"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			h.ListSyncConflicts(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var resp ConflictListResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, tc.expectedCount, resp.Count)
+				assert.Len(t, resp.Conflicts, tc.expectedCount)
+			}
+		})
+	}
+}
+
+func TestGetSyncConflict(t *testing.T) {
+	h := conflictTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{name: "existing conflict", id: "1", expectedStatus: http.StatusOK},
+		{name: "missing conflict", id: "99", expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := withConflictID(httptest.NewRequest(http.MethodGet, "/sync/conflicts/"+tc.id, nil), tc.id)
+			w := httptest.NewRecorder()
+
+			h.GetSyncConflict(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestResolveSyncConflict(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+	}{
+		{name: "keep client", id: "1", body: `{"resolution":"keep_client"}`, expectedStatus: http.StatusOK},
+		{name: "merge with data", id: "1", body: `{"resolution":"merge","data":{"a":3}}`, expectedStatus: http.StatusOK},
+		{name: "merge without data", id: "1", body: `{"resolution":"merge"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown resolution", id: "1", body: `{"resolution":"coin_flip"}`, expectedStatus: http.StatusBadRequest},
+		{name: "already resolved", id: "2", body: `{"resolution":"keep_client"}`, expectedStatus: http.StatusConflict},
+		{name: "missing conflict", id: "99", body: `{"resolution":"keep_server"}`, expectedStatus: http.StatusNotFound},
+		{name: "invalid body", id: "1", body: `{`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := conflictTestHandler()
+			req := withConflictID(httptest.NewRequest(http.MethodPost, "/sync/conflicts/"+tc.id+"/resolve", bytes.NewBufferString(tc.body)), tc.id)
+			w := httptest.NewRecorder()
+
+			h.ResolveSyncConflict(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var conflict sync.Conflict
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&conflict))
+				assert.Equal(t, sync.ConflictStatusResolved, conflict.Status)
+				require.NotNil(t, conflict.ResolvedBy)
+				assert.Equal(t, "admin", *conflict.ResolvedBy)
+			}
+		})
+	}
+}
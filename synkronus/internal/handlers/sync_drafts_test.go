@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asUser(r *http.Request, username string) *http.Request {
+	ctx := context.WithValue(r.Context(), authmw.UserKey, &models.User{Username: username, Role: models.RoleReadWrite})
+	return r.WithContext(ctx)
+}
+
+func pullAs(t *testing.T, h *Handler, username string) SyncPullResponse {
+	t.Helper()
+	body, _ := json.Marshal(SyncPullRequest{ClientID: username + "-device"})
+	w := httptest.NewRecorder()
+	h.Pull(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)), username))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestDraftRecords_VisibleOnlyToOwner(t *testing.T) {
+	h, _ := createTestHandler()
+
+	draft := sync.Observation{
+		ObservationID: "draft-1",
+		FormType:      "household",
+		FormVersion:   "1.0",
+		Data:          json.RawMessage(`{"section":1}`),
+		Draft:         true,
+	}
+	body, _ := json.Marshal(SyncPushRequest{TransmissionID: "tx-1", ClientID: "alice-tablet", Records: []sync.Observation{draft}})
+	w := httptest.NewRecorder()
+	h.Push(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The owner's other devices receive the draft
+	resp := pullAs(t, h, "alice")
+	require.Len(t, resp.Records, 1)
+	assert.True(t, resp.Records[0].Draft)
+
+	// Other users do not
+	assert.Empty(t, pullAs(t, h, "bob").Records)
+
+	// Finalizing the record makes it visible to everyone
+	draft.Draft = false
+	body, _ = json.Marshal(SyncPushRequest{TransmissionID: "tx-2", ClientID: "alice-phone", Records: []sync.Observation{draft}})
+	w = httptest.NewRecorder()
+	h.Push(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp = pullAs(t, h, "bob")
+	require.Len(t, resp.Records, 1)
+	assert.False(t, resp.Records[0].Draft)
+}
+
+func TestDraftRecords_RequireAuthenticatedUser(t *testing.T) {
+	h, _ := createTestHandler()
+
+	body, _ := json.Marshal(SyncPushRequest{
+		TransmissionID: "tx-1",
+		ClientID:       "client-1",
+		Records:        []sync.Observation{{ObservationID: "draft-1", FormType: "household", Draft: true}},
+	})
+	w := httptest.NewRecorder()
+	h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp SyncPushResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 0, resp.SuccessCount)
+	assert.Len(t, resp.FailedRecords, 1)
+}
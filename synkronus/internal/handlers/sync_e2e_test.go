@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
@@ -303,12 +304,24 @@ func (m *mockAuthService) Authenticate(ctx context.Context, username, password s
 	return &models.User{ID: uuid.New(), Username: username, Role: models.RoleReadWrite}, nil
 }
 func (m *mockAuthService) GenerateToken(user *models.User) (string, error) { return "token", nil }
-func (m *mockAuthService) GenerateRefreshToken(user *models.User) (string, error) {
+func (m *mockAuthService) GenerateRefreshToken(ctx context.Context, user *models.User, client auth.SessionClient) (string, error) {
 	return "refresh", nil
 }
 func (m *mockAuthService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
 	return "new-token", "new-refresh", nil
 }
+func (m *mockAuthService) Logout(ctx context.Context, refreshToken string) (string, error) {
+	return "", nil
+}
+func (m *mockAuthService) ListSessions(ctx context.Context, username string) ([]models.Session, error) {
+	return []models.Session{}, nil
+}
+func (m *mockAuthService) RevokeSession(ctx context.Context, username, id string) error {
+	return auth.ErrSessionNotFound
+}
+func (m *mockAuthService) RevokeAllSessions(ctx context.Context, username string) (int64, error) {
+	return 0, nil
+}
 func (m *mockAuthService) ValidateToken(tokenString string) (*auth.AuthClaims, error) {
 	return &auth.AuthClaims{Username: "test", Role: models.RoleReadWrite}, nil
 }
@@ -316,6 +329,25 @@ func (m *mockAuthService) Initialize(ctx context.Context) error         { return
 func (m *mockAuthService) HashPassword(password string) (string, error) { return "hash", nil }
 func (m *mockAuthService) CheckPasswordHash(password, hash string) bool { return true }
 func (m *mockAuthService) VerifyPassword(password, hash string) bool    { return true }
+func (m *mockAuthService) PasswordHashReport(ctx context.Context) (*auth.PasswordHashReport, error) {
+	return &auth.PasswordHashReport{}, nil
+}
+
+func (m *mockAuthService) GeneratePreviewToken(issuer string, lifetime time.Duration) (string, time.Time, error) {
+	return "preview-token", time.Now().Add(lifetime), nil
+}
+
+func (m *mockAuthService) ValidatePreviewToken(tokenString string) (*auth.AuthClaims, error) {
+	return nil, auth.ErrScopedToken
+}
+
+func (m *mockAuthService) GenerateImpersonationToken(ctx context.Context, username, impersonator string, lifetime time.Duration) (string, *auth.AuthClaims, error) {
+	return "", nil, auth.ErrCannotImpersonate
+}
+
+func (m *mockAuthService) JWKS() *auth.JWKS {
+	return &auth.JWKS{Keys: []auth.JWK{}}
+}
 
 type mockAppBundleService struct{}
 
@@ -328,17 +360,29 @@ func (m *mockAppBundleService) GetFile(ctx context.Context, path string) (io.Rea
 func (m *mockAppBundleService) GetLatestVersionFile(ctx context.Context, path string) (io.ReadCloser, *appbundle.File, error) {
 	return nil, nil, nil
 }
+func (m *mockAppBundleService) GetVersionFile(ctx context.Context, version, path string) (io.ReadCloser, *appbundle.File, error) {
+	return nil, nil, nil
+}
 func (m *mockAppBundleService) GetFileHash(ctx context.Context, path string, useLatest bool) (string, error) {
 	return "hash", nil
 }
 func (m *mockAppBundleService) RefreshManifest() error { return nil }
-func (m *mockAppBundleService) PushBundle(ctx context.Context, zipReader io.Reader) (*appbundle.Manifest, error) {
+func (m *mockAppBundleService) PushBundle(ctx context.Context, zipReader io.Reader, signature string) (*appbundle.Manifest, error) {
 	return &appbundle.Manifest{Version: "1.0.0"}, nil
 }
 func (m *mockAppBundleService) GetVersions(ctx context.Context) ([]string, error) {
 	return []string{"1.0.0"}, nil
 }
 func (m *mockAppBundleService) SwitchVersion(ctx context.Context, version string) error { return nil }
+func (m *mockAppBundleService) FindManifest(ctx context.Context, hash string) (*appbundle.Manifest, error) {
+	return nil, appbundle.ErrVersionNotFound
+}
+func (m *mockAppBundleService) WriteDiff(ctx context.Context, diff *appbundle.Diff, w io.Writer) error {
+	return nil
+}
+func (m *mockAppBundleService) ExportVersion(ctx context.Context, version string, w io.Writer) error {
+	return nil
+}
 func (m *mockAppBundleService) GetAppInfo(ctx context.Context, version string) (*appbundle.AppInfo, error) {
 	return &appbundle.AppInfo{}, nil
 }
@@ -348,6 +392,19 @@ func (m *mockAppBundleService) GetLatestAppInfo(ctx context.Context) (*appbundle
 func (m *mockAppBundleService) CompareAppInfos(ctx context.Context, versionA, versionB string) (*appbundle.ChangeLog, error) {
 	return &appbundle.ChangeLog{}, nil
 }
+func (m *mockAppBundleService) StagePreview(ctx context.Context, version string) error { return nil }
+func (m *mockAppBundleService) GetStagedPreview(ctx context.Context) (string, error) {
+	return "", nil
+}
+func (m *mockAppBundleService) PromotePreview(ctx context.Context) (string, error) {
+	return "", appbundle.ErrNoPreview
+}
+func (m *mockAppBundleService) GetPreviewManifest(ctx context.Context) (*appbundle.Manifest, error) {
+	return &appbundle.Manifest{}, nil
+}
+func (m *mockAppBundleService) GetPreviewFile(ctx context.Context, path string) (io.ReadCloser, *appbundle.File, error) {
+	return nil, nil, appbundle.ErrFileNotFound
+}
 
 type mockUserService struct{}
 
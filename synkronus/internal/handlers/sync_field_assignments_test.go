@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPush_AssignedFields(t *testing.T) {
+	h, _ := createTestHandler()
+	h.syncService.(*mocks.MockSyncService).SetFieldAssignments(map[string][]sync.FieldAssignment{
+		"registration": {{Field: "registration_number", Kind: sync.FieldAssignmentSequence, Prefix: "REG-", Padding: 4}},
+	})
+
+	resp := pushObservation(t, h, sync.Observation{ObservationID: "reg-1", FormType: "registration", Data: json.RawMessage(`{"name":"Amina"}`)})
+	assert.Equal(t, "REG-0001", resp.AssignedFields["reg-1"]["registration_number"])
+
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "reg-2", FormType: "registration", Data: json.RawMessage(`{"registration_number":"forged"}`)})
+	assert.Equal(t, "REG-0002", resp.AssignedFields["reg-2"]["registration_number"])
+
+	// Updates keep the number handed out first
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "reg-1", FormType: "registration", Data: json.RawMessage(`{"name":"Amina Juma"}`)})
+	assert.Equal(t, "REG-0001", resp.AssignedFields["reg-1"]["registration_number"])
+
+	// Forms without declarations are untouched
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "visit-1", FormType: "visit", Data: json.RawMessage(`{}`)})
+	assert.Empty(t, resp.AssignedFields)
+}
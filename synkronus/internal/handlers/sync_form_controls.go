@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// FormSyncControlRequest represents the request body for pausing or resuming sync of a form type
+type FormSyncControlRequest struct {
+	PushPaused bool    `json:"push_paused"`
+	PullPaused bool    `json:"pull_paused"`
+	Reason     *string `json:"reason,omitempty"`
+}
+
+// ListFormSyncControls handles GET /sync/form-controls
+func (h *Handler) ListFormSyncControls(w http.ResponseWriter, r *http.Request) {
+	controls, err := h.syncService.ListFormSyncControls(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list form sync controls", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list form sync controls")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"controls": controls,
+	})
+}
+
+// SetFormSyncControl handles PUT /sync/form-controls/{formType}
+func (h *Handler) SetFormSyncControl(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	formType := chi.URLParam(r, "formType")
+	if formType == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "formType is required")
+		return
+	}
+
+	var req FormSyncControlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	control, err := h.syncService.SetFormSyncControl(r.Context(), sync.FormSyncControl{
+		FormType:   formType,
+		PushPaused: req.PushPaused,
+		PullPaused: req.PullPaused,
+		Reason:     req.Reason,
+		UpdatedBy:  &user.Username,
+	})
+	if err != nil {
+		h.log.Error("Failed to set form sync control", "error", err, "formType", formType)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to set form sync control")
+		return
+	}
+
+	h.log.Info("Form sync control set",
+		"formType", formType,
+		"pushPaused", control.PushPaused,
+		"pullPaused", control.PullPaused,
+		"user", user.Username)
+
+	SendJSONResponse(w, http.StatusOK, control)
+}
+
+// DeleteFormSyncControl handles DELETE /sync/form-controls/{formType}
+func (h *Handler) DeleteFormSyncControl(w http.ResponseWriter, r *http.Request) {
+	formType := chi.URLParam(r, "formType")
+	if formType == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "formType is required")
+		return
+	}
+
+	if err := h.syncService.DeleteFormSyncControl(r.Context(), formType); err != nil {
+		if errors.Is(err, sync.ErrFormControlNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "No sync control exists for this form type")
+			return
+		}
+		h.log.Error("Failed to delete form sync control", "error", err, "formType", formType)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete form sync control")
+		return
+	}
+
+	h.log.Info("Form sync control removed", "formType", formType)
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"message": "Sync resumed for form type " + formType,
+	})
+}
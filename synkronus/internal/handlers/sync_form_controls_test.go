@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withFormType(r *http.Request, formType string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("formType", formType)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, authmw.UserKey, &models.User{Username: "admin", Role: models.RoleAdmin})
+	return r.WithContext(ctx)
+}
+
+func TestFormSyncControls_PauseAndResume(t *testing.T) {
+	h, _ := createTestHandler()
+
+	// Pause push and pull for the survey form
+	body := `{"push_paused":true,"pull_paused":true,"reason":"schema migration"}`
+	req := withFormType(httptest.NewRequest(http.MethodPut, "/sync/form-controls/survey", bytes.NewBufferString(body)), "survey")
+	w := httptest.NewRecorder()
+	h.SetFormSyncControl(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var control sync.FormSyncControl
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&control))
+	assert.True(t, control.PushPaused)
+	assert.True(t, control.PullPaused)
+	require.NotNil(t, control.UpdatedBy)
+	assert.Equal(t, "admin", *control.UpdatedBy)
+
+	// Pushing a survey record returns a FORM_PAUSED warning instead of an error
+	pushBody, _ := json.Marshal(SyncPushRequest{
+		TransmissionID: "tx-1",
+		ClientID:       "client-1",
+		Records: []sync.Observation{
+			{ObservationID: "obs-1", FormType: "survey", Data: json.RawMessage(`{}`)},
+			{ObservationID: "obs-2", FormType: "household", Data: json.RawMessage(`{}`)},
+		},
+	})
+	w = httptest.NewRecorder()
+	h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(pushBody)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pushResp SyncPushResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pushResp))
+	assert.Equal(t, 1, pushResp.SuccessCount)
+	assert.Empty(t, pushResp.FailedRecords)
+	require.Len(t, pushResp.Warnings, 1)
+	assert.Equal(t, sync.WarningCodeFormPaused, pushResp.Warnings[0].Code)
+	assert.Equal(t, "obs-1", pushResp.Warnings[0].ID)
+
+	// Pulling reports the paused form type as a warning
+	pullBody, _ := json.Marshal(SyncPullRequest{ClientID: "client-1"})
+	w = httptest.NewRecorder()
+	h.Pull(w, httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(pullBody)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pullResp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pullResp))
+	require.Len(t, pullResp.Warnings, 1)
+	assert.Equal(t, "survey", pullResp.Warnings[0].ID)
+
+	// Listing shows the control
+	w = httptest.NewRecorder()
+	h.ListFormSyncControls(w, httptest.NewRequest(http.MethodGet, "/sync/form-controls", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var listResp struct {
+		Controls []sync.FormSyncControl `json:"controls"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&listResp))
+	assert.Len(t, listResp.Controls, 1)
+
+	// Resuming removes the control
+	w = httptest.NewRecorder()
+	h.DeleteFormSyncControl(w, withFormType(httptest.NewRequest(http.MethodDelete, "/sync/form-controls/survey", nil), "survey"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A second resume reports that nothing is paused
+	w = httptest.NewRecorder()
+	h.DeleteFormSyncControl(w, withFormType(httptest.NewRequest(http.MethodDelete, "/sync/form-controls/survey", nil), "survey"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSetFormSyncControl_InvalidBody(t *testing.T) {
+	h, _ := createTestHandler()
+
+	req := withFormType(httptest.NewRequest(http.MethodPut, "/sync/form-controls/survey", bytes.NewBufferString("{")), "survey")
+	w := httptest.NewRecorder()
+	h.SetFormSyncControl(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync_FormatNegotiation(t *testing.T) {
+	h, _ := createTestHandler()
+
+	records := []sync.Observation{
+		{ObservationID: "live", FormType: "survey", Data: json.RawMessage(`{"name":"a"}`),
+			CreatedAt: "2025-06-01T08:00:00Z", UpdatedAt: "2025-06-01T08:00:00Z"},
+		{ObservationID: "gone", FormType: "survey", Data: json.RawMessage(`{"name":"b"}`), Deleted: true,
+			CreatedAt: "2025-06-01T08:00:00Z", UpdatedAt: "2025-06-01T08:00:00Z"},
+	}
+	body, _ := json.Marshal(SyncPushRequest{TransmissionID: "tx-format", ClientID: "client-1", Records: records,
+		SyncFormatVersions: []string{"1.0", "1.1"}})
+	w := httptest.NewRecorder()
+	h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pushResp SyncPushResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pushResp))
+	assert.Equal(t, sync.FormatVersion11, pushResp.SyncFormatVersion)
+
+	pull := func(versions []string) (int, map[string]json.RawMessage, string) {
+		body, _ := json.Marshal(SyncPullRequest{ClientID: "client-2", SyncFormatVersions: versions})
+		w := httptest.NewRecorder()
+		h.Pull(w, httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			return w.Code, nil, ""
+		}
+		var resp SyncPullResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		data := make(map[string]json.RawMessage)
+		for _, record := range resp.Records {
+			data[record.ObservationID] = record.Data
+		}
+		return w.Code, data, *resp.SyncFormatVersion
+	}
+
+	// Clients that advertise nothing keep getting the original format
+	_, data, format := pull(nil)
+	assert.Equal(t, sync.FormatVersion10, format)
+	assert.JSONEq(t, `{"name":"b"}`, string(data["gone"]))
+
+	_, data, format = pull([]string{"1.0", "1.1", "2.0"})
+	assert.Equal(t, sync.FormatVersion11, format)
+	assert.Equal(t, "null", string(data["gone"]), "1.1 tombstones carry no data")
+	assert.JSONEq(t, `{"name":"a"}`, string(data["live"]))
+
+	code, _, _ := pull([]string{"2.0"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPull_AsOfVersion(t *testing.T) {
+	h, _ := createTestHandler()
+
+	// Store two versions of the same observation
+	for i, data := range []string{`{"count":1}`, `{"count":2}`} {
+		body, _ := json.Marshal(SyncPushRequest{
+			TransmissionID: fmt.Sprintf("tx-%d", i),
+			ClientID:       "client-1",
+			Records:        []sync.Observation{{ObservationID: "obs-1", FormType: "survey", Data: json.RawMessage(data)}},
+		})
+		w := httptest.NewRecorder()
+		h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	firstVersion := int64(2)
+	body, _ := json.Marshal(SyncPullRequest{ClientID: "auditor", AsOf: &SyncPullRequestAsOf{Version: &firstVersion}})
+	w := httptest.NewRecorder()
+	h.Pull(w, httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, firstVersion, resp.CurrentVersion)
+	require.Len(t, resp.Records, 1)
+	assert.JSONEq(t, `{"count":1}`, string(resp.Records[0].Data))
+}
+
+func TestPull_AsOfOtherTenant(t *testing.T) {
+	h, _ := createTestHandler()
+
+	body, _ := json.Marshal(SyncPushRequest{
+		TransmissionID: "tx-acme",
+		ClientID:       "client-1",
+		Records:        []sync.Observation{{ObservationID: "obs-1", FormType: "survey", Data: json.RawMessage(`{"count":1}`)}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	h.Push(w, req.WithContext(tenant.WithTenant(req.Context(), "acme")))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	pull := func(id string) []sync.Observation {
+		t.Helper()
+		version := int64(2)
+		body, _ := json.Marshal(SyncPullRequest{ClientID: "auditor", AsOf: &SyncPullRequestAsOf{Version: &version}})
+		req := httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		h.Pull(w, req.WithContext(tenant.WithTenant(req.Context(), id)))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp SyncPullResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp.Records
+	}
+	assert.Len(t, pull("acme"), 1)
+	assert.Empty(t, pull(tenant.Default))
+	assert.Empty(t, pull("globex"))
+}
+
+func TestPull_AsOfInvalid(t *testing.T) {
+	h, _ := createTestHandler()
+
+	for name, asOf := range map[string]*SyncPullRequestAsOf{
+		"empty":          {},
+		"future version": {Version: func() *int64 { v := int64(99); return &v }()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := json.Marshal(SyncPullRequest{ClientID: "auditor", AsOf: asOf})
+			w := httptest.NewRecorder()
+			h.Pull(w, httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestParquetExport_AsOfVersion(t *testing.T) {
+	h, _ := createTestHandler()
+
+	var exportedVersion int64
+	mockDataExportService := mocks.NewMockDataExportService()
+	mockDataExportService.ExportParquetZipWithOptionsFunc = func(ctx context.Context, opts dataexport.ExportOptions) (io.ReadCloser, error) {
+		exportedVersion = opts.AsOfVersion
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	h.dataExportService = mockDataExportService
+
+	w := httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?as_of_version=42", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(42), exportedVersion)
+
+	w = httptest.NewRecorder()
+	h.ParquetExportHandler(w, httptest.NewRequest(http.MethodGet, "/dataexport/parquet?as_of_version=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
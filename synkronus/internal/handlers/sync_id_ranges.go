@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// IDRangeRequest represents the request body for reserving a block of IDs
+type IDRangeRequest struct {
+	ClientID string `json:"client_id"`
+	Sequence string `json:"sequence"`
+	Count    int    `json:"count"`
+}
+
+// AllocateIDRange handles POST /sync/id-ranges
+func (h *Handler) AllocateIDRange(w http.ResponseWriter, r *http.Request) {
+	var req IDRangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	allocated, err := h.syncService.AllocateIDRange(syncContext(r), req.Sequence, req.ClientID, req.Count)
+	if err != nil {
+		if errors.Is(err, sync.ErrInvalidData) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if errors.Is(err, sync.ErrIDRangesExhausted) {
+			SendErrorResponse(w, http.StatusServiceUnavailable, err, "No IDs left for this sequence until the server reaches its upstream server again")
+			return
+		}
+		h.log.Error("Failed to allocate ID range", "error", err, "sequence", req.Sequence, "clientId", req.ClientID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to allocate ID range")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusCreated, allocated)
+}
+
+// ListIDRanges handles GET /sync/id-ranges?client_id=
+func (h *Handler) ListIDRanges(w http.ResponseWriter, r *http.Request) {
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "client_id is required")
+		return
+	}
+
+	ranges, err := h.syncService.ListIDRanges(r.Context(), clientID)
+	if err != nil {
+		h.log.Error("Failed to list ID ranges", "error", err, "clientId", clientID)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list ID ranges")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"ranges": ranges,
+	})
+}
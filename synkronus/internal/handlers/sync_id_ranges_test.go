@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allocateIDRange(t *testing.T, h *Handler, req IDRangeRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	h.AllocateIDRange(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/id-ranges", bytes.NewReader(body)), "alice"))
+	return w
+}
+
+func TestIDRanges_Allocate(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := allocateIDRange(t, h, IDRangeRequest{ClientID: "tablet-1", Sequence: "registration", Count: 500})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var first sync.IDRange
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&first))
+	assert.Equal(t, int64(1), first.RangeStart)
+	assert.Equal(t, int64(500), first.RangeEnd)
+	assert.Equal(t, "alice", *first.AllocatedBy)
+
+	// Another device continues after the first block
+	w = allocateIDRange(t, h, IDRangeRequest{ClientID: "tablet-2", Sequence: "registration", Count: 100})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var second sync.IDRange
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&second))
+	assert.Equal(t, int64(501), second.RangeStart)
+	assert.Equal(t, int64(600), second.RangeEnd)
+
+	w = httptest.NewRecorder()
+	h.ListIDRanges(w, httptest.NewRequest(http.MethodGet, "/sync/id-ranges?client_id=tablet-1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Ranges []sync.IDRange `json:"ranges"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list.Ranges, 1)
+	assert.Equal(t, first.ID, list.Ranges[0].ID)
+}
+
+func TestIDRanges_Exhausted(t *testing.T) {
+	h, _ := createTestHandler()
+	h.syncService.(*mocks.MockSyncService).SetIDLimit("registration", 100)
+
+	require.Equal(t, http.StatusCreated, allocateIDRange(t, h, IDRangeRequest{ClientID: "tablet-1", Sequence: "registration", Count: 100}).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, allocateIDRange(t, h, IDRangeRequest{ClientID: "tablet-1", Sequence: "registration", Count: 1}).Code)
+}
+
+func TestIDRanges_Invalid(t *testing.T) {
+	h, _ := createTestHandler()
+
+	assert.Equal(t, http.StatusBadRequest, allocateIDRange(t, h, IDRangeRequest{Sequence: "registration", Count: 10}).Code)
+	assert.Equal(t, http.StatusBadRequest, allocateIDRange(t, h, IDRangeRequest{ClientID: "tablet-1", Sequence: "registration"}).Code)
+	assert.Equal(t, http.StatusBadRequest, allocateIDRange(t, h, IDRangeRequest{ClientID: "tablet-1", Sequence: "registration", Count: sync.MaxIDRangeSize + 1}).Code)
+
+	w := httptest.NewRecorder()
+	h.ListIDRanges(w, httptest.NewRequest(http.MethodGet, "/sync/id-ranges", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/idempotency"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushRetriedTransmission(t *testing.T) {
+	h, _ := createTestHandler()
+	WithIdempotencyStore(idempotency.NewMemoryStore(time.Hour))(h)
+
+	push := func(observationID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(SyncPushRequest{
+			TransmissionID: "retried-transmission",
+			ClientID:       "test-client",
+			Records: []sync.Observation{{
+				ObservationID: observationID,
+				FormType:      "test_form",
+				FormVersion:   "1.0",
+				Data:          json.RawMessage(`{"field1":"value1"}`),
+				CreatedAt:     "2025-06-25T12:00:00Z",
+				UpdatedAt:     "2025-06-25T12:00:00Z",
+			}},
+		})
+		rr := httptest.NewRecorder()
+		h.Push(rr, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+		return rr
+	}
+
+	first := push("retried-obs")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayHeader))
+
+	// A retry gets the first response without the records being applied again
+	retry := push("retried-obs")
+	require.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayHeader))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	// Reusing the transmission ID for other records is rejected
+	assert.Equal(t, http.StatusUnprocessableEntity, push("other-obs").Code)
+}
+
+func TestGetSyncTransmission(t *testing.T) {
+	h, _ := createTestHandler()
+
+	lookup := func(transmissionID, clientID string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/sync/transmissions/"+transmissionID+"?client_id="+clientID, nil)
+		h.GetSyncTransmission(rr, withURLParams(r, "transmissionId", transmissionID))
+		return rr
+	}
+
+	// Without an idempotency store nothing is tracked
+	assert.Equal(t, http.StatusNotFound, lookup("tx-1", "test-client").Code)
+
+	WithIdempotencyStore(idempotency.NewMemoryStore(2 * time.Hour))(h)
+	assert.Equal(t, http.StatusBadRequest, lookup("tx-1", "").Code)
+
+	var status SyncTransmissionStatus
+	rr := lookup("tx-1", "test-client")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+	assert.Equal(t, TransmissionUnknown, status.Status)
+	assert.Equal(t, int64(7200), status.RetentionSeconds)
+	assert.Nil(t, status.ExpiresAt)
+
+	body, _ := json.Marshal(SyncPushRequest{
+		TransmissionID: "tx-1",
+		ClientID:       "test-client",
+		Records: []sync.Observation{{
+			ObservationID: "tx-obs",
+			FormType:      "test_form",
+			FormVersion:   "1.0",
+			Data:          json.RawMessage(`{"field1":"value1"}`),
+			CreatedAt:     "2025-06-25T12:00:00Z",
+			UpdatedAt:     "2025-06-25T12:00:00Z",
+		}},
+	})
+	pushed := httptest.NewRecorder()
+	h.Push(pushed, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, pushed.Code)
+
+	// The original response is returned without pushing the records again
+	rr = lookup("tx-1", "test-client")
+	require.Equal(t, http.StatusOK, rr.Code)
+	status = SyncTransmissionStatus{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+	assert.Equal(t, idempotency.StateCompleted, status.Status)
+	assert.Equal(t, http.StatusOK, status.ResponseStatus)
+	assert.JSONEq(t, pushed.Body.String(), string(status.Response))
+	require.NotNil(t, status.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), *status.ExpiresAt, time.Minute)
+
+	// Transmission IDs are scoped to the client
+	rr = lookup("tx-1", "other-client")
+	status = SyncTransmissionStatus{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+	assert.Equal(t, TransmissionUnknown, status.Status)
+}
+
+func TestGetSyncTransmission_OtherUsers(t *testing.T) {
+	h, _ := createTestHandler()
+	WithIdempotencyStore(idempotency.NewMemoryStore(time.Hour))(h)
+
+	as := func(r *http.Request, username, id string) *http.Request {
+		r = withRole(r, username, models.RoleReadWrite)
+		return r.WithContext(tenant.WithTenant(r.Context(), id))
+	}
+	push := func(username, id string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(SyncPushRequest{
+			TransmissionID: "tx-1",
+			ClientID:       "test-client",
+			Records: []sync.Observation{{
+				ObservationID: "tx-obs-" + username + "-" + id,
+				FormType:      "test_form",
+				FormVersion:   "1.0",
+				Data:          json.RawMessage(`{"field1":"value1"}`),
+				CreatedAt:     "2025-06-25T12:00:00Z",
+				UpdatedAt:     "2025-06-25T12:00:00Z",
+			}},
+		})
+		rr := httptest.NewRecorder()
+		h.Push(rr, as(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), username, id))
+		return rr
+	}
+	lookup := func(username, id string) string {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/sync/transmissions/tx-1?client_id=test-client", nil)
+		h.GetSyncTransmission(rr, withURLParams(as(r, username, id), "transmissionId", "tx-1"))
+		require.Equal(t, http.StatusOK, rr.Code)
+		var status SyncTransmissionStatus
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+		return status.Status
+	}
+
+	require.Equal(t, http.StatusOK, push("alice", "acme").Code)
+	assert.Equal(t, idempotency.StateCompleted, lookup("alice", "acme"))
+	assert.Equal(t, TransmissionUnknown, lookup("bob", "acme"), "other users do not see the response")
+	assert.Equal(t, TransmissionUnknown, lookup("alice", "globex"), "other tenants do not see the response")
+
+	// The same client and transmission IDs pushed by another tenant are applied, not replayed
+	rr := push("alice", "globex")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get(IdempotentReplayHeader))
+}
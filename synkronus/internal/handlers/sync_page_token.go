@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// defaultPageTokenMinutes is how long page tokens are valid when the configuration leaves it open
+const defaultPageTokenMinutes = 60
+
+// pageTokens returns the signer of sync pull page tokens
+func (h *Handler) pageTokens() *sync.PageTokenSigner {
+	minutes := h.config.SyncPageTokenMinutes
+	if minutes <= 0 {
+		minutes = defaultPageTokenMinutes
+	}
+	return sync.NewPageTokenSigner(h.config.JWTSecret, time.Duration(minutes)*time.Minute)
+}
+
+// asOfKey tells how a pull selected a past state, for binding page tokens to it
+func asOfKey(asOf *SyncPullRequestAsOf) string {
+	switch {
+	case asOf == nil:
+		return ""
+	case asOf.Version != nil:
+		return "version:" + strconv.FormatInt(*asOf.Version, 10)
+	case asOf.Timestamp != nil:
+		return "timestamp:" + asOf.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// resumePull verifies the page token of a pull and returns the position to continue from
+func (h *Handler) resumePull(r *http.Request, req SyncPullRequest, filterHash string) (*sync.PageToken, error) {
+	if req.Since != nil {
+		return nil, fmt.Errorf("%w: since and page_token cannot be combined", sync.ErrInvalidPageToken)
+	}
+	token, err := h.pageTokens().Verify(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+	currentVersion, err := h.syncService.GetCurrentVersion(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	if err := token.Validate(req.ClientID, filterHash, currentVersion); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// sendPageTokenError answers a pull whose page token cannot be used. The client starts the pull
+// over from its last change_cutoff.
+func (h *Handler) sendPageTokenError(w http.ResponseWriter, req SyncPullRequest, err error) {
+	switch {
+	case errors.Is(err, sync.ErrPageTokenExpired):
+		SendErrorResponse(w, http.StatusGone, err, "page_token has expired; pull again from the last change_cutoff")
+	case errors.Is(err, sync.ErrInvalidPageToken):
+		h.log.Warn("Refused sync pull page token", "clientId", req.ClientID, "error", err)
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+	default:
+		h.log.Error("Failed to check sync pull page token", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to retrieve sync data")
+	}
+}
+
+// nextPageToken issues the token of the page after a pull that has more records; last is the
+// last record sent
+func (h *Handler) nextPageToken(req SyncPullRequest, sinceVersion int64, filterHash string, result *sync.SyncResult, last *sync.Observation) (string, error) {
+	if !result.HasMore || last == nil {
+		return "", nil
+	}
+	return h.pageTokens().Issue(sync.PageToken{
+		SinceVersion: sinceVersion,
+		Cursor:       sync.SyncPullCursor{Version: last.Version, ID: last.ObservationID},
+		Watermark:    result.CurrentVersion,
+		Filter:       filterHash,
+		ClientID:     req.ClientID,
+	})
+}
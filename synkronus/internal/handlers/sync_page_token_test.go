@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pullPage pulls one page of two records
+func pullPage(h *Handler, req SyncPullRequest, headers ...string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	r := httptest.NewRequest(http.MethodPost, "/sync/pull?limit=2", bytes.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.Pull(w, r)
+	return w
+}
+
+func decodePage(t *testing.T, w *httptest.ResponseRecorder) SyncPullResponse {
+	t.Helper()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestPull_PageTokens(t *testing.T) {
+	h, _ := createTestHandler()
+	pushFilterFixtures(t, h)
+
+	first := decodePage(t, pullPage(h, SyncPullRequest{ClientID: "tablet"}))
+	require.Len(t, first.Records, 2)
+	require.True(t, *first.HasMore)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second := decodePage(t, pullPage(h, SyncPullRequest{ClientID: "tablet", PageToken: first.NextPageToken}))
+	require.Len(t, second.Records, 2)
+	assert.NotEqual(t, first.Records[1].ObservationID, second.Records[0].ObservationID)
+	assert.False(t, *second.HasMore)
+	assert.Empty(t, second.NextPageToken, "the last page has no next page")
+
+	// A replayed token returns the same page again, so a page lost in transit can be refetched
+	replayed := decodePage(t, pullPage(h, SyncPullRequest{ClientID: "tablet", PageToken: first.NextPageToken}))
+	assert.Equal(t, second.Records, replayed.Records)
+
+	// Streamed pulls issue and accept the same tokens
+	w := pullPage(h, SyncPullRequest{ClientID: "tablet"}, "Accept", NDJSONContentType)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	var end SyncPullStreamEnd
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &end))
+	assert.Equal(t, StreamLineEnd, end.Type)
+	resumed := decodePage(t, pullPage(h, SyncPullRequest{ClientID: "tablet", PageToken: end.NextPageToken}))
+	assert.Equal(t, second.Records, resumed.Records)
+}
+
+func TestPull_PageTokenRefused(t *testing.T) {
+	h, _ := createTestHandler()
+	pushFilterFixtures(t, h)
+	token := decodePage(t, pullPage(h, SyncPullRequest{ClientID: "tablet", SchemaTypes: []string{"survey"}})).NextPageToken
+	require.NotEmpty(t, token)
+
+	payload, signature, _ := strings.Cut(token, ".")
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(decoded, &claims))
+	claims["c"] = map[string]any{"version": 1, "id": "old-nairobi"}
+	altered, _ := json.Marshal(claims)
+	tampered := base64.RawURLEncoding.EncodeToString(altered) + "." + signature
+
+	may := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	for name, req := range map[string]SyncPullRequest{
+		"tampered payload":    {ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: tampered},
+		"truncated signature": {ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: token[:len(token)-2]},
+		"garbage":             {ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: "not-a-token"},
+		"other client":        {ClientID: "laptop", SchemaTypes: []string{"survey"}, PageToken: token},
+		"other schema types":  {ClientID: "tablet", SchemaTypes: []string{"household"}, PageToken: token},
+		"other filter":        {ClientID: "tablet", SchemaTypes: []string{"survey"}, UpdatedAfter: &may, PageToken: token},
+		"combined with since": {ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: token, Since: &SyncPullRequestSince{Version: 1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := pullPage(h, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "invalid page token")
+		})
+	}
+
+	t.Run("other server secret", func(t *testing.T) {
+		other, _ := createTestHandler()
+		other.config.JWTSecret = "another-secret"
+		pushFilterFixtures(t, other)
+		w := pullPage(other, SyncPullRequest{ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: token})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired, err := sync.NewPageTokenSigner(h.config.JWTSecret, -time.Minute).Issue(sync.PageToken{
+			Cursor:    sync.SyncPullCursor{Version: 2, ID: "new-nairobi"},
+			Watermark: 4,
+			Filter:    sync.PullFilterHash([]string{"survey"}, sync.PullFilter{}, ""),
+			ClientID:  "tablet",
+		})
+		require.NoError(t, err)
+		w := pullPage(h, SyncPullRequest{ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: expired})
+		assert.Equal(t, http.StatusGone, w.Code)
+	})
+
+	t.Run("server behind the watermark", func(t *testing.T) {
+		restored, _ := createTestHandler()
+		w := pullPage(restored, SyncPullRequest{ClientID: "tablet", SchemaTypes: []string{"survey"}, PageToken: token})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// PeriodLockRequest represents the request body for locking a reporting period
+type PeriodLockRequest struct {
+	FormType    string              `json:"form_type"`
+	PeriodStart string              `json:"period_start"`
+	PeriodEnd   string              `json:"period_end"`
+	Mode        sync.PeriodLockMode `json:"mode,omitempty"`
+	Reason      *string             `json:"reason,omitempty"`
+}
+
+// ListPeriodLocks handles GET /sync/period-locks
+func (h *Handler) ListPeriodLocks(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.syncService.ListPeriodLocks(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list reporting period locks", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list reporting period locks")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"locks": locks,
+	})
+}
+
+// CreatePeriodLock handles POST /sync/period-locks
+func (h *Handler) CreatePeriodLock(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	var req PeriodLockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	lock, err := h.syncService.CreatePeriodLock(r.Context(), sync.ReportingPeriodLock{
+		FormType:    req.FormType,
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		Mode:        req.Mode,
+		Reason:      req.Reason,
+		CreatedBy:   &user.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sync.ErrInvalidData) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to create reporting period lock", "error", err, "formType", req.FormType)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to create reporting period lock")
+		return
+	}
+
+	h.log.Info("Reporting period locked",
+		"formType", lock.FormType,
+		"periodStart", lock.PeriodStart,
+		"periodEnd", lock.PeriodEnd,
+		"user", user.Username)
+
+	SendJSONResponse(w, http.StatusCreated, lock)
+}
+
+// DeletePeriodLock handles DELETE /sync/period-locks/{id}
+func (h *Handler) DeletePeriodLock(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid lock id")
+		return
+	}
+
+	if err := h.syncService.DeletePeriodLock(r.Context(), id); err != nil {
+		if errors.Is(err, sync.ErrPeriodLockNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Reporting period lock not found")
+			return
+		}
+		h.log.Error("Failed to delete reporting period lock", "error", err, "lockId", id)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete reporting period lock")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"message": "Reporting period reopened",
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createPeriodLock(t *testing.T, h *Handler, req PeriodLockRequest) sync.ReportingPeriodLock {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	h.CreatePeriodLock(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/period-locks", bytes.NewReader(body)), "admin"))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var lock sync.ReportingPeriodLock
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&lock))
+	return lock
+}
+
+func pushObservation(t *testing.T, h *Handler, record sync.Observation) SyncPushResponse {
+	t.Helper()
+	body, _ := json.Marshal(SyncPushRequest{TransmissionID: "tx-" + record.ObservationID, ClientID: "tablet-1", Records: []sync.Observation{record}})
+	w := httptest.NewRecorder()
+	h.Push(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp SyncPushResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestPeriodLocks_Reject(t *testing.T) {
+	h, _ := createTestHandler()
+	lock := createPeriodLock(t, h, PeriodLockRequest{FormType: "immunization", PeriodStart: "2025-01-01", PeriodEnd: "2025-03-31"})
+	assert.Equal(t, sync.PeriodLockModeReject, lock.Mode)
+	assert.Equal(t, "admin", *lock.CreatedBy)
+
+	resp := pushObservation(t, h, sync.Observation{ObservationID: "obs-q1", FormType: "immunization", Data: json.RawMessage(`{}`), CreatedAt: "2025-03-31T23:00:00Z"})
+	assert.Equal(t, 0, resp.SuccessCount)
+	assert.Len(t, resp.FailedRecords, 1)
+
+	// Other periods and form types are unaffected
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "obs-q2", FormType: "immunization", Data: json.RawMessage(`{}`), CreatedAt: "2025-04-01T08:00:00Z"})
+	assert.Equal(t, 1, resp.SuccessCount)
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "obs-anc", FormType: "anc_visit", Data: json.RawMessage(`{}`), CreatedAt: "2025-02-01T08:00:00Z"})
+	assert.Equal(t, 1, resp.SuccessCount)
+
+	// Reopening the period accepts the record again
+	w := httptest.NewRecorder()
+	h.DeletePeriodLock(w, withURLParams(httptest.NewRequest(http.MethodDelete, "/", nil), "id", "1"))
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "obs-q1", FormType: "immunization", Data: json.RawMessage(`{}`), CreatedAt: "2025-03-31T23:00:00Z"})
+	assert.Equal(t, 1, resp.SuccessCount)
+}
+
+func TestPeriodLocks_Approval(t *testing.T) {
+	h, _ := createTestHandler()
+	mockSync := h.syncService.(*mocks.MockSyncService)
+	createPeriodLock(t, h, PeriodLockRequest{FormType: "immunization", PeriodStart: "2025-01-01", PeriodEnd: "2025-03-31", Mode: sync.PeriodLockModeApproval})
+
+	resp := pushObservation(t, h, sync.Observation{ObservationID: "obs-late", FormType: "immunization", Data: json.RawMessage(`{}`), CreatedAt: "2025-02-10T08:00:00Z"})
+	assert.Equal(t, 0, resp.SuccessCount)
+	require.Len(t, resp.Warnings, 1)
+	assert.Equal(t, sync.WarningCodePendingApproval, resp.Warnings[0].Code)
+
+	conflicts, err := mockSync.ListConflicts(t.Context(), sync.ConflictFilter{Reason: sync.ConflictReasonPeriodLocked})
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "obs-late", conflicts[0].ObservationID)
+}
+
+func TestPeriodLocks_Invalid(t *testing.T) {
+	h, _ := createTestHandler()
+
+	for name, req := range map[string]PeriodLockRequest{
+		"missing form type": {PeriodStart: "2025-01-01", PeriodEnd: "2025-01-31"},
+		"bad date":          {FormType: "immunization", PeriodStart: "01/01/2025", PeriodEnd: "2025-01-31"},
+		"end before start":  {FormType: "immunization", PeriodStart: "2025-02-01", PeriodEnd: "2025-01-31"},
+		"unknown mode":      {FormType: "immunization", PeriodStart: "2025-01-01", PeriodEnd: "2025-01-31", Mode: "warn"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := json.Marshal(req)
+			w := httptest.NewRecorder()
+			h.CreatePeriodLock(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/period-locks", bytes.NewReader(body)), "admin"))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pushFilterFixtures stores observations collected at different times and places
+func pushFilterFixtures(t *testing.T, h *Handler) {
+	records := []sync.Observation{
+		{ObservationID: "old-nairobi", FormType: "survey", CreatedAt: "2025-01-10T08:00:00Z", UpdatedAt: "2025-01-10T08:00:00Z",
+			Geolocation: &sync.Geolocation{Latitude: -1.29, Longitude: 36.82}},
+		{ObservationID: "new-nairobi", FormType: "survey", CreatedAt: "2025-06-01T08:00:00Z", UpdatedAt: "2025-06-02T08:00:00Z",
+			Geolocation: &sync.Geolocation{Latitude: -1.28, Longitude: 36.81}},
+		{ObservationID: "new-lagos", FormType: "survey", CreatedAt: "2025-06-01T09:00:00Z", UpdatedAt: "2025-06-01T09:00:00Z",
+			Geolocation: &sync.Geolocation{Latitude: 6.52, Longitude: 3.38}},
+		{ObservationID: "edited-unlocated", FormType: "survey", CreatedAt: "2025-01-05T08:00:00Z", UpdatedAt: "2025-06-03T08:00:00Z"},
+	}
+	for i := range records {
+		records[i].Data = json.RawMessage(`{}`)
+	}
+	body, _ := json.Marshal(SyncPushRequest{TransmissionID: "tx-filter", ClientID: "client-1", Records: records})
+	w := httptest.NewRecorder()
+	h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func pulledIDs(t *testing.T, h *Handler, req SyncPullRequest) []string {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	h.Pull(w, httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp SyncPullResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	ids := make([]string, 0, len(resp.Records))
+	for _, record := range resp.Records {
+		ids = append(ids, record.ObservationID)
+	}
+	return ids
+}
+
+func TestPull_Filters(t *testing.T) {
+	h, _ := createTestHandler()
+	pushFilterFixtures(t, h)
+
+	may := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	nairobi := &sync.BoundingBox{MinLatitude: -1.5, MinLongitude: 36.6, MaxLatitude: -1.1, MaxLongitude: 37.1}
+
+	assert.Equal(t, []string{"new-nairobi", "new-lagos"}, pulledIDs(t, h, SyncPullRequest{ClientID: "supervisor", CreatedAfter: &may}))
+	assert.Equal(t, []string{"new-nairobi", "new-lagos", "edited-unlocated"}, pulledIDs(t, h, SyncPullRequest{ClientID: "supervisor", UpdatedAfter: &may}))
+	assert.Equal(t, []string{"old-nairobi", "new-nairobi"}, pulledIDs(t, h, SyncPullRequest{ClientID: "supervisor", BoundingBox: nairobi}))
+	assert.Equal(t, []string{"new-nairobi"}, pulledIDs(t, h, SyncPullRequest{ClientID: "supervisor", UpdatedAfter: &may, BoundingBox: nairobi}))
+}
+
+func TestPull_FilterInvalidBoundingBox(t *testing.T) {
+	h, _ := createTestHandler()
+
+	for name, box := range map[string]*sync.BoundingBox{
+		"inverted latitudes": {MinLatitude: 10, MaxLatitude: -10, MinLongitude: 0, MaxLongitude: 1},
+		"longitude range":    {MinLatitude: 0, MaxLatitude: 1, MinLongitude: -200, MaxLongitude: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := json.Marshal(SyncPullRequest{ClientID: "supervisor", BoundingBox: box})
+			w := httptest.NewRecorder()
+			h.Pull(w, httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// ReceiptVerification is the outcome of checking a submission receipt
+type ReceiptVerification struct {
+	ObservationID string `json:"observation_id"`
+	Version       int64  `json:"version"`
+	// Valid tells whether the code was issued by this server for the observation and version
+	Valid bool `json:"valid"`
+}
+
+// receipts returns the signer of submission receipts, or nil when receipts are disabled
+func (h *Handler) receipts() *sync.ReceiptSigner {
+	if !h.config.SyncReceiptsEnabled {
+		return nil
+	}
+	secret := h.config.SyncReceiptSecret
+	if secret == "" {
+		secret = h.config.JWTSecret
+	}
+	return sync.NewReceiptSigner(secret)
+}
+
+// pushReceipts issues the receipts of the records a push accepted, in the order they were pushed
+func (h *Handler) pushReceipts(r *http.Request, records []sync.Observation, versions map[string]int64) []sync.Receipt {
+	signer := h.receipts()
+	if signer == nil || len(versions) == 0 {
+		return nil
+	}
+	tenantID := tenant.FromContext(r.Context())
+	receipts := make([]sync.Receipt, 0, len(versions))
+	issued := make(map[string]bool, len(versions))
+	for _, record := range records {
+		version, ok := versions[record.ObservationID]
+		if !ok || issued[record.ObservationID] {
+			continue
+		}
+		issued[record.ObservationID] = true
+		receipts = append(receipts, signer.Issue(tenantID, record.ObservationID, version))
+	}
+	return receipts
+}
+
+// VerifySyncReceipt handles POST /sync/receipts/verify, letting a supervisor check the code a
+// field worker was given when a record reached the server
+func (h *Handler) VerifySyncReceipt(w http.ResponseWriter, r *http.Request) {
+	signer := h.receipts()
+	if signer == nil {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Submission receipts are not enabled on this server")
+		return
+	}
+
+	var receipt sync.Receipt
+	if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+	if receipt.ObservationID == "" || receipt.Version <= 0 || receipt.Code == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "observation_id, version and code are required")
+		return
+	}
+
+	valid := signer.Verify(tenant.FromContext(r.Context()), receipt)
+	if !valid {
+		h.log.Info("Submission receipt did not verify", "observationId", receipt.ObservationID, "version", receipt.Version)
+	}
+	SendJSONResponse(w, http.StatusOK, ReceiptVerification{
+		ObservationID: receipt.ObservationID,
+		Version:       receipt.Version,
+		Valid:         valid,
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func verifyReceipt(h *Handler, receipt sync.Receipt) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(receipt)
+	w := httptest.NewRecorder()
+	h.VerifySyncReceipt(w, asUser(httptest.NewRequest(http.MethodPost, "/sync/receipts/verify", bytes.NewReader(body)), "supervisor"))
+	return w
+}
+
+func TestSyncReceipts(t *testing.T) {
+	h, _ := createTestHandler()
+	h.config.SyncReceiptsEnabled = true
+
+	resp := pushObservation(t, h, sync.Observation{ObservationID: "household-1", FormType: "household", Data: json.RawMessage(`{}`)})
+	require.Len(t, resp.Receipts, 1)
+	receipt := resp.Receipts[0]
+	assert.Equal(t, "household-1", receipt.ObservationID)
+	assert.Regexp(t, `^[0-9A-Z]{4}-[0-9A-Z]{4}$`, receipt.Code)
+
+	// Drafts have not been submitted yet and get no receipt
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "household-2", FormType: "household", Data: json.RawMessage(`{}`), Draft: true})
+	assert.Empty(t, resp.Receipts)
+
+	var verification ReceiptVerification
+	w := verifyReceipt(h, receipt)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&verification))
+	assert.True(t, verification.Valid)
+
+	receipt.Version++
+	w = verifyReceipt(h, receipt)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&verification))
+	assert.False(t, verification.Valid, "code of another version")
+
+	assert.Equal(t, http.StatusBadRequest, verifyReceipt(h, sync.Receipt{ObservationID: "household-1"}).Code)
+}
+
+func TestSyncReceipts_Disabled(t *testing.T) {
+	h, _ := createTestHandler()
+
+	resp := pushObservation(t, h, sync.Observation{ObservationID: "household-1", FormType: "household", Data: json.RawMessage(`{}`)})
+	assert.Empty(t, resp.Receipts)
+	assert.Equal(t, http.StatusNotFound, verifyReceipt(h, sync.Receipt{ObservationID: "household-1", Version: 1, Code: "ABCD-EFGH"}).Code)
+}
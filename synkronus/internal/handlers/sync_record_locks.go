@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// RecordLockRequest represents the optional request body for claiming a record lock
+type RecordLockRequest struct {
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// RecordLockedResponse is returned when a record is locked by another user
+type RecordLockedResponse struct {
+	Error   string           `json:"error"`
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Lock    *sync.RecordLock `json:"lock,omitempty"`
+}
+
+// sendRecordLockError maps record lock errors to responses
+func (h *Handler) sendRecordLockError(w http.ResponseWriter, err error, message string) {
+	var lockedErr *sync.RecordLockedError
+	switch {
+	case errors.As(err, &lockedErr):
+		resp := RecordLockedResponse{
+			Error:   sync.ErrRecordLocked.Error(),
+			Code:    "RECORD_LOCKED",
+			Message: "Record is being edited by another user",
+		}
+		if lockedErr.Lock.LockedBy != "" {
+			resp.Lock = &lockedErr.Lock
+		}
+		SendJSONResponse(w, http.StatusConflict, resp)
+	case errors.Is(err, sync.ErrRecordLockNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err, "Record lock not found")
+	case errors.Is(err, sync.ErrInvalidData):
+		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+	default:
+		h.log.Error(message, "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, message)
+	}
+}
+
+// ListRecordLocks handles GET /sync/locks
+func (h *Handler) ListRecordLocks(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.syncService.ListRecordLocks(r.Context())
+	if err != nil {
+		h.sendRecordLockError(w, err, "Failed to list record locks")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"locks": locks,
+	})
+}
+
+// ClaimRecordLock handles POST /sync/locks/{observationId}
+func (h *Handler) ClaimRecordLock(w http.ResponseWriter, r *http.Request) {
+	var req RecordLockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if req.TTLSeconds < 0 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "ttl_seconds must not be negative")
+		return
+	}
+
+	lock, err := h.syncService.ClaimRecordLock(syncContext(r), chi.URLParam(r, "observationId"), time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		h.sendRecordLockError(w, err, "Failed to claim record lock")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, lock)
+}
+
+// ReleaseRecordLock handles DELETE /sync/locks/{observationId}; ?force=true releases another
+// user's lock and is restricted to admins
+func (h *Handler) ReleaseRecordLock(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	if force && user.Role != models.RoleAdmin {
+		SendErrorResponse(w, http.StatusForbidden, nil, "Only admins can force-release record locks")
+		return
+	}
+
+	observationID := chi.URLParam(r, "observationId")
+	if err := h.syncService.ReleaseRecordLock(syncContext(r), observationID, force); err != nil {
+		h.sendRecordLockError(w, err, "Failed to release record lock")
+		return
+	}
+
+	if force {
+		h.log.Info("Record lock force-released", "observationId", observationID, "user", user.Username)
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]any{
+		"message": "Record lock released",
+	})
+}
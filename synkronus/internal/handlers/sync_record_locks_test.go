@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func claimRecordLock(t *testing.T, h *Handler, observationID, username string, req RecordLockRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	r := httptest.NewRequest(http.MethodPost, "/sync/locks/"+observationID, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ClaimRecordLock(w, withURLParams(asUser(r, username), "observationId", observationID))
+	return w
+}
+
+func releaseRecordLock(h *Handler, observationID string, user *models.User, force bool) *httptest.ResponseRecorder {
+	target := "/sync/locks/" + observationID
+	if force {
+		target += "?force=true"
+	}
+	r := httptest.NewRequest(http.MethodDelete, target, nil)
+	r = r.WithContext(context.WithValue(r.Context(), authmw.UserKey, user))
+	w := httptest.NewRecorder()
+	h.ReleaseRecordLock(w, withURLParams(r, "observationId", observationID))
+	return w
+}
+
+func TestRecordLocks_ClaimAndRelease(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := claimRecordLock(t, h, "obs-1", "alice", RecordLockRequest{TTLSeconds: 600})
+	require.Equal(t, http.StatusOK, w.Code)
+	var lock sync.RecordLock
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&lock))
+	assert.Equal(t, "alice", lock.LockedBy)
+
+	// The holder may extend the lock, another user is told who holds it
+	assert.Equal(t, http.StatusOK, claimRecordLock(t, h, "obs-1", "alice", RecordLockRequest{}).Code)
+	w = claimRecordLock(t, h, "obs-1", "bob", RecordLockRequest{})
+	require.Equal(t, http.StatusConflict, w.Code)
+	var conflict RecordLockedResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&conflict))
+	assert.Equal(t, "RECORD_LOCKED", conflict.Code)
+	require.NotNil(t, conflict.Lock)
+	assert.Equal(t, "alice", conflict.Lock.LockedBy)
+
+	w = httptest.NewRecorder()
+	h.ListRecordLocks(w, httptest.NewRequest(http.MethodGet, "/sync/locks", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Locks []sync.RecordLock `json:"locks"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list.Locks, 1)
+
+	// Only the holder releases without force
+	bob := &models.User{Username: "bob", Role: models.RoleReadWrite}
+	assert.Equal(t, http.StatusConflict, releaseRecordLock(h, "obs-1", bob, false).Code)
+	assert.Equal(t, http.StatusOK, releaseRecordLock(h, "obs-1", &models.User{Username: "alice", Role: models.RoleReadWrite}, false).Code)
+	assert.Equal(t, http.StatusNotFound, releaseRecordLock(h, "obs-1", bob, false).Code)
+	assert.Equal(t, http.StatusOK, claimRecordLock(t, h, "obs-1", "bob", RecordLockRequest{}).Code)
+}
+
+func TestRecordLocks_ForceRelease(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, claimRecordLock(t, h, "obs-1", "bob", RecordLockRequest{}).Code)
+
+	assert.Equal(t, http.StatusForbidden, releaseRecordLock(h, "obs-1", &models.User{Username: "carol", Role: models.RoleReadWrite}, true).Code)
+	assert.Equal(t, http.StatusOK, releaseRecordLock(h, "obs-1", &models.User{Username: "admin", Role: models.RoleAdmin}, true).Code)
+	assert.Equal(t, http.StatusOK, claimRecordLock(t, h, "obs-1", "carol", RecordLockRequest{}).Code)
+}
+
+func TestRecordLocks_PushRejected(t *testing.T) {
+	h, _ := createTestHandler()
+	require.Equal(t, http.StatusOK, claimRecordLock(t, h, "obs-1", "bob", RecordLockRequest{}).Code)
+
+	// pushObservation pushes as alice
+	resp := pushObservation(t, h, sync.Observation{ObservationID: "obs-1", FormType: "survey", FormVersion: "1", Data: json.RawMessage(`{}`)})
+	assert.Equal(t, 0, resp.SuccessCount)
+	require.Len(t, resp.FailedRecords, 1)
+
+	require.Equal(t, http.StatusOK, claimRecordLock(t, h, "obs-2", "alice", RecordLockRequest{}).Code)
+	resp = pushObservation(t, h, sync.Observation{ObservationID: "obs-2", FormType: "survey", FormVersion: "1", Data: json.RawMessage(`{}`)})
+	assert.Equal(t, 1, resp.SuccessCount)
+}
+
+func TestRecordLocks_Invalid(t *testing.T) {
+	h, _ := createTestHandler()
+	assert.Equal(t, http.StatusBadRequest, claimRecordLock(t, h, "obs-1", "alice", RecordLockRequest{TTLSeconds: -1}).Code)
+	assert.Equal(t, http.StatusBadRequest, claimRecordLock(t, h, "obs-1", "alice", RecordLockRequest{TTLSeconds: int(sync.MaxRecordLockTTL.Seconds()) + 1}).Code)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/device"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// NDJSONContentType is the media type of streamed sync pulls, requested in the Accept header
+const NDJSONContentType = "application/x-ndjson"
+
+// streamFlushEvery is the number of records written between flushes of a streamed pull
+const streamFlushEvery = 100
+
+// Line types of a streamed sync pull
+const (
+	StreamLineRecord = "record"
+	StreamLineEnd    = "end"
+	StreamLineError  = "error"
+)
+
+// SyncPullStreamRecord is a line of a streamed sync pull carrying one record
+type SyncPullStreamRecord struct {
+	Type   string           `json:"type"`
+	Record sync.Observation `json:"record"`
+}
+
+// SyncPullStreamEnd is the last line of a complete streamed sync pull. A stream that stops
+// without it was cut short and must be pulled again.
+type SyncPullStreamEnd struct {
+	Type              string             `json:"type"`
+	CurrentVersion    int64              `json:"current_version"`
+	ChangeCutoff      int64              `json:"change_cutoff"`
+	HasMore           bool               `json:"has_more"`
+	SyncFormatVersion string             `json:"sync_format_version"`
+	RecordCount       int                `json:"record_count"`
+	Warnings          []sync.SyncWarning `json:"warnings,omitempty"`
+	NextPageToken     string             `json:"next_page_token,omitempty"`
+}
+
+// SyncPullStreamError ends a streamed sync pull that failed after records were sent
+type SyncPullStreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// wantsStream reports whether the client asked for a streamed pull
+func wantsStream(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == NDJSONContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// pullStream writes the lines of a streamed sync pull. The status is sent with the first line,
+// so a pull that fails before any record can still be answered with an error response.
+type pullStream struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	format  string
+	started bool
+	count   int
+	// last is the last record written
+	last *sync.Observation
+}
+
+func newPullStream(w http.ResponseWriter, format string) *pullStream {
+	return &pullStream{w: w, encoder: json.NewEncoder(w), format: format}
+}
+
+func (s *pullStream) start() {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", NDJSONContentType)
+		s.w.WriteHeader(http.StatusOK)
+	}
+}
+
+// record writes one record, flushing every streamFlushEvery records so the client can process
+// them while the rest are read
+func (s *pullStream) record(obs sync.Observation) error {
+	s.start()
+	record := sync.TranslatePulled(s.format, obs)
+	if err := s.encoder.Encode(SyncPullStreamRecord{Type: StreamLineRecord, Record: record}); err != nil {
+		return err
+	}
+	s.count++
+	s.last = &obs
+	if s.count%streamFlushEvery == 0 {
+		_ = http.NewResponseController(s.w).Flush()
+	}
+	return nil
+}
+
+// end writes the summary line that completes the stream
+func (s *pullStream) end(result *sync.SyncResult, nextPageToken string) error {
+	s.start()
+	return s.encoder.Encode(SyncPullStreamEnd{
+		Type:              StreamLineEnd,
+		CurrentVersion:    result.CurrentVersion,
+		ChangeCutoff:      result.ChangeCutoff,
+		HasMore:           result.HasMore,
+		SyncFormatVersion: s.format,
+		RecordCount:       s.count,
+		Warnings:          result.Warnings,
+		NextPageToken:     nextPageToken,
+	})
+}
+
+// fail writes an error line after records have been sent; the client may already be gone
+func (s *pullStream) fail(message string) {
+	_ = s.encoder.Encode(SyncPullStreamError{Type: StreamLineError, Message: message})
+}
+
+// streamPull answers a pull with one NDJSON line per record, read from the database one at a
+// time, followed by an end line with the fields of a buffered pull response
+func (h *Handler) streamPull(w http.ResponseWriter, r *http.Request, req SyncPullRequest, format string, sinceVersion int64, schemaTypes []string, limit int, cursor *sync.SyncPullCursor, filterHash string) {
+	stream := newPullStream(w, format)
+
+	var result *sync.SyncResult
+	var err error
+	if req.AsOf != nil {
+		// Past states are reconstructed in memory; they are still sent in the streamed format
+		result, err = h.pullAsOf(r, req, sinceVersion, schemaTypes, limit, cursor)
+		for i := 0; err == nil && i < len(result.Records); i++ {
+			err = stream.record(result.Records[i])
+		}
+	} else {
+		result, err = h.syncService.StreamRecordsSinceVersion(syncContext(r), sinceVersion, req.ClientID, schemaTypes, limit, cursor, stream.record)
+	}
+	if err != nil {
+		if !stream.started {
+			h.sendPullError(w, err)
+			return
+		}
+		h.log.Error("Failed to stream sync pull", "error", err, "clientId", req.ClientID, "recordCount", stream.count)
+		stream.fail("Failed to retrieve sync data")
+		return
+	}
+	nextPageToken, err := h.nextPageToken(req, sinceVersion, filterHash, result, stream.last)
+	if err != nil {
+		h.log.Error("Failed to issue sync pull page token", "error", err, "clientId", req.ClientID)
+		stream.fail("Failed to retrieve sync data")
+		return
+	}
+	if err := stream.end(result, nextPageToken); err != nil {
+		h.log.Error("Failed to finish streamed sync pull", "error", err, "clientId", req.ClientID)
+		return
+	}
+	h.recordClientSync(r, req.ClientID, device.SyncPull, result.CurrentVersion)
+
+	h.log.Info("Streamed sync pull request processed",
+		"clientId", req.ClientID,
+		"sinceVersion", sinceVersion,
+		"currentVersion", result.CurrentVersion,
+		"recordCount", stream.count,
+		"hasMore", result.HasMore,
+		"syncFormatVersion", format)
+}
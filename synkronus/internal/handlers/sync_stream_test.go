@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pushObservations pushes one observation per id
+func pushObservations(t *testing.T, h *Handler, ids ...string) {
+	t.Helper()
+	for i, id := range ids {
+		body, _ := json.Marshal(SyncPushRequest{
+			TransmissionID: fmt.Sprintf("tx-%d", i),
+			ClientID:       "client-1",
+			Records:        []sync.Observation{{ObservationID: id, FormType: "survey", Data: json.RawMessage(`{"n":1}`)}},
+		})
+		w := httptest.NewRecorder()
+		h.Push(w, httptest.NewRequest(http.MethodPost, "/sync/push", bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+// streamLines decodes the lines of a streamed pull
+func streamLines(t *testing.T, w *httptest.ResponseRecorder) []map[string]json.RawMessage {
+	t.Helper()
+	var lines []map[string]json.RawMessage
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), "line %q", scanner.Text())
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestPull_Stream(t *testing.T) {
+	h, _ := createTestHandler()
+	pushObservations(t, h, "obs-1", "obs-2")
+
+	body, _ := json.Marshal(SyncPullRequest{ClientID: "client-2"})
+	r := httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body))
+	r.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
+	w := httptest.NewRecorder()
+	h.Pull(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, NDJSONContentType, w.Header().Get("Content-Type"))
+
+	lines := streamLines(t, w)
+	require.Len(t, lines, 3)
+	var record sync.Observation
+	for i, id := range []string{"obs-1", "obs-2"} {
+		assert.JSONEq(t, `"record"`, string(lines[i]["type"]))
+		require.NoError(t, json.Unmarshal(lines[i]["record"], &record))
+		assert.Equal(t, id, record.ObservationID)
+	}
+
+	var end SyncPullStreamEnd
+	endLine, _ := json.Marshal(lines[2])
+	require.NoError(t, json.Unmarshal(endLine, &end))
+	assert.Equal(t, StreamLineEnd, end.Type)
+	assert.Equal(t, 2, end.RecordCount)
+	assert.Equal(t, record.Version, end.ChangeCutoff)
+	assert.GreaterOrEqual(t, end.CurrentVersion, end.ChangeCutoff)
+	assert.False(t, end.HasMore)
+	assert.Equal(t, "1.0", end.SyncFormatVersion)
+}
+
+func TestPull_StreamEmpty(t *testing.T) {
+	h, _ := createTestHandler()
+
+	body, _ := json.Marshal(SyncPullRequest{ClientID: "client-2"})
+	r := httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body))
+	r.Header.Set("Accept", NDJSONContentType)
+	w := httptest.NewRecorder()
+	h.Pull(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	lines := streamLines(t, w)
+	require.Len(t, lines, 1)
+	assert.JSONEq(t, `"end"`, string(lines[0]["type"]))
+	assert.JSONEq(t, `0`, string(lines[0]["record_count"]))
+}
+
+func TestPull_StreamErrorBeforeRecords(t *testing.T) {
+	h, _ := createTestHandler()
+
+	// A pull that fails before any record is sent is answered like a buffered one
+	body, _ := json.Marshal(SyncPullRequest{ClientID: "auditor", AsOf: &SyncPullRequestAsOf{}})
+	r := httptest.NewRequest(http.MethodPost, "/sync/pull", bytes.NewReader(body))
+	r.Header.Set("Accept", NDJSONContentType)
+	w := httptest.NewRecorder()
+	h.Pull(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEqual(t, NDJSONContentType, w.Header().Get("Content-Type"))
+}
+
+func TestWantsStream(t *testing.T) {
+	for accept, expected := range map[string]bool{
+		"":                     false,
+		"application/json":     false,
+		"application/x-ndjson": true,
+		"application/json, application/x-ndjson; charset=utf-8": true,
+		"application/x-ndjsonx":                                 false,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/sync/pull", nil)
+		r.Header.Set("Accept", accept)
+		assert.Equal(t, expected, wantsStream(r), "Accept: %q", accept)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/pkg/idempotency"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// TransmissionUnknown is the status of a transmission that never arrived or whose response is
+// no longer kept; pushing it again applies it
+const TransmissionUnknown = "unknown"
+
+// SyncTransmissionStatus reports what the server knows of a pushed transmission
+type SyncTransmissionStatus struct {
+	TransmissionID string `json:"transmission_id"`
+	ClientID       string `json:"client_id"`
+	// Status is processing, completed or unknown
+	Status string `json:"status"`
+	// ExpiresAt is when the transmission is forgotten
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// RetentionSeconds is how long the responses of completed transmissions are kept
+	RetentionSeconds int64 `json:"retention_seconds"`
+	// ResponseStatus and Response are the original response of a completed transmission
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Response       json.RawMessage `json:"response,omitempty"`
+}
+
+// GetSyncTransmission handles GET /sync/transmissions/{transmissionId}?client_id=, letting a
+// client that timed out find out whether its push was applied and fetch the original response
+// without sending the records again
+func (h *Handler) GetSyncTransmission(w http.ResponseWriter, r *http.Request) {
+	transmissionID := chi.URLParam(r, "transmissionId")
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "client_id is required")
+		return
+	}
+	if h.idempotencyStore == nil {
+		SendErrorResponse(w, http.StatusNotFound, nil, "Transmissions are not tracked on this server")
+		return
+	}
+
+	// Only the user that pushed a transmission finds it
+	status, err := h.idempotencyStore.Lookup(r.Context(), transmissionKey(r, clientID, transmissionID))
+	if err != nil {
+		h.log.Error("Failed to look up transmission", "transmissionId", transmissionID, "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to look up transmission")
+		return
+	}
+
+	response := SyncTransmissionStatus{
+		TransmissionID:   transmissionID,
+		ClientID:         clientID,
+		Status:           TransmissionUnknown,
+		RetentionSeconds: int64(h.idempotencyStore.Retention() / time.Second),
+	}
+	if status != nil {
+		response.Status = status.State
+		response.ExpiresAt = &status.Expires
+		if status.Response != nil {
+			response.ResponseStatus = status.Response.Status
+			response.Response = status.Response.Body
+		}
+	}
+	SendJSONResponse(w, http.StatusOK, response)
+}
+
+// transmissionKey is the idempotency key of a transmission pushed by the requesting user in the
+// tenant of the request
+func transmissionKey(r *http.Request, clientID, transmissionID string) string {
+	var username string
+	if user := authmw.GetUserFromContext(r.Context()); user != nil {
+		username = user.Username
+	}
+	return idempotency.TransmissionKey(tenant.FromContext(r.Context()), username, clientID, transmissionID)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/terms"
+)
+
+// maxTermsSize limits the size of a published terms document
+const maxTermsSize = 256 * 1024
+
+// TermsRequest represents the body of PUT /terms
+type TermsRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// TermsAcknowledgeRequest represents the body of POST /terms/acknowledge
+type TermsAcknowledgeRequest struct {
+	Version int `json:"version"`
+}
+
+// GetTerms handles GET /terms, returning the current document and whether the caller has
+// acknowledged it
+func (h *Handler) GetTerms(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	status, err := h.termsService.Status(r.Context(), user.Username)
+	if err != nil {
+		if errors.Is(err, terms.ErrNoDocument) {
+			SendErrorResponse(w, http.StatusNotFound, err, "No terms document has been published")
+			return
+		}
+		h.log.Error("Failed to get terms status", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to get terms")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, status)
+}
+
+// PublishTerms handles PUT /terms (admin only). Every publish creates a new version that all
+// users must acknowledge before they can sync again.
+func (h *Handler) PublishTerms(w http.ResponseWriter, r *http.Request) {
+	var req TermsRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxTermsSize)).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	publishedBy := ""
+	if user, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && user != nil {
+		publishedBy = user.Username
+	}
+
+	doc, err := h.termsService.Publish(r.Context(), req.Title, req.Body, publishedBy)
+	if err != nil {
+		if errors.Is(err, terms.ErrInvalidDocument) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to publish terms", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to publish terms")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusCreated, doc)
+}
+
+// AcknowledgeTerms handles POST /terms/acknowledge
+func (h *Handler) AcknowledgeTerms(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+	if !ok || user == nil {
+		SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	var req TermsAcknowledgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+	if req.Version < 1 {
+		SendErrorResponse(w, http.StatusBadRequest, nil, "version is required")
+		return
+	}
+
+	ack, err := h.termsService.Acknowledge(r.Context(), user.Username, req.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, terms.ErrNoDocument):
+			SendErrorResponse(w, http.StatusNotFound, err, "No terms document has been published")
+		case errors.Is(err, terms.ErrNotCurrentVersion):
+			SendErrorResponse(w, http.StatusConflict, err, "The terms have been updated; review and acknowledge the current version")
+		default:
+			h.log.Error("Failed to acknowledge terms", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to acknowledge terms")
+		}
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, ack)
+}
+
+// ListTermsAcknowledgements handles GET /terms/acknowledgements (admin only). The version
+// query parameter defaults to the current version.
+func (h *Handler) ListTermsAcknowledgements(w http.ResponseWriter, r *http.Request) {
+	var version int
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "version must be a positive integer")
+			return
+		}
+		version = n
+	} else {
+		doc, err := h.termsService.Current(r.Context())
+		if err != nil {
+			if errors.Is(err, terms.ErrNoDocument) {
+				SendErrorResponse(w, http.StatusNotFound, err, "No terms document has been published")
+				return
+			}
+			h.log.Error("Failed to get terms document", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list terms acknowledgements")
+			return
+		}
+		version = doc.Version
+	}
+
+	acks, err := h.termsService.ListAcknowledgements(r.Context(), version)
+	if err != nil {
+		h.log.Error("Failed to list terms acknowledgements", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list terms acknowledgements")
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"version":          version,
+		"acknowledgements": acks,
+	})
+}
+
+// RequireTermsAcknowledgement is middleware that refuses sync with 403 until the caller has
+// acknowledged the current terms. Nothing is enforced while no document is published.
+func (h *Handler) RequireTermsAcknowledgement(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.termsService == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := r.Context().Value(authmw.UserKey).(*models.User)
+		if !ok || user == nil {
+			SendErrorResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+			return
+		}
+
+		status, err := h.termsService.Status(r.Context(), user.Username)
+		if err != nil {
+			if errors.Is(err, terms.ErrNoDocument) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.log.Error("Failed to check terms acknowledgement", "error", err, "username", user.Username)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to check terms acknowledgement")
+			return
+		}
+		if !status.Acknowledged {
+			SendErrorResponse(w, http.StatusForbidden, terms.ErrNotAcknowledged,
+				fmt.Sprintf("Acknowledge version %d of the terms of use (GET /terms) before syncing", status.Document.Version))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
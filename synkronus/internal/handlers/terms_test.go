@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/terms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishTerms(t *testing.T, h *Handler, title, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(TermsRequest{Title: title, Body: body})
+	w := httptest.NewRecorder()
+	h.PublishTerms(w, withRole(httptest.NewRequest(http.MethodPut, "/terms", bytes.NewReader(payload)), "admin", models.RoleAdmin))
+	return w
+}
+
+func acknowledgeTerms(h *Handler, username string, version int) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(TermsAcknowledgeRequest{Version: version})
+	w := httptest.NewRecorder()
+	h.AcknowledgeTerms(w, withRole(httptest.NewRequest(http.MethodPost, "/terms/acknowledge", bytes.NewReader(payload)), username, models.RoleReadWrite))
+	return w
+}
+
+// gatedSync runs a request for username through the acknowledgement gate
+func gatedSync(h *Handler, username string) int {
+	w := httptest.NewRecorder()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h.RequireTermsAcknowledgement(next).ServeHTTP(w, withRole(httptest.NewRequest(http.MethodPost, "/sync/pull", nil), username, models.RoleReadWrite))
+	return w.Code
+}
+
+func TestPublishTerms(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := publishTerms(t, h, "Data use agreement", "Collected data stays within the study.")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var doc terms.Document
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	assert.Equal(t, 1, doc.Version)
+	assert.Equal(t, "admin", doc.PublishedBy)
+
+	assert.Equal(t, http.StatusBadRequest, publishTerms(t, h, "No body", " ").Code)
+}
+
+func TestGetTerms(t *testing.T) {
+	h, _ := createTestHandler()
+	get := func() (int, terms.Status) {
+		w := httptest.NewRecorder()
+		h.GetTerms(w, withRole(httptest.NewRequest(http.MethodGet, "/terms", nil), "collector", models.RoleReadWrite))
+		var status terms.Status
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+		}
+		return w.Code, status
+	}
+
+	code, _ := get()
+	assert.Equal(t, http.StatusNotFound, code)
+
+	publishTerms(t, h, "Terms", "v1")
+	code, status := get()
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "v1", status.Document.Body)
+	assert.False(t, status.Acknowledged)
+
+	require.Equal(t, http.StatusOK, acknowledgeTerms(h, "collector", 1).Code)
+	_, status = get()
+	assert.True(t, status.Acknowledged)
+	assert.NotNil(t, status.AcknowledgedAt)
+}
+
+func TestAcknowledgeTerms(t *testing.T) {
+	h, _ := createTestHandler()
+
+	assert.Equal(t, http.StatusNotFound, acknowledgeTerms(h, "collector", 1).Code)
+
+	publishTerms(t, h, "Terms", "v1")
+	publishTerms(t, h, "Terms", "v2")
+	assert.Equal(t, http.StatusBadRequest, acknowledgeTerms(h, "collector", 0).Code)
+	assert.Equal(t, http.StatusConflict, acknowledgeTerms(h, "collector", 1).Code)
+	assert.Equal(t, http.StatusOK, acknowledgeTerms(h, "collector", 2).Code)
+	assert.Equal(t, http.StatusOK, acknowledgeTerms(h, "collector", 2).Code)
+
+	w := httptest.NewRecorder()
+	h.ListTermsAcknowledgements(w, withRole(httptest.NewRequest(http.MethodGet, "/terms/acknowledgements", nil), "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Version          int                     `json:"version"`
+		Acknowledgements []terms.Acknowledgement `json:"acknowledgements"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 2, resp.Version)
+	require.Len(t, resp.Acknowledgements, 1)
+	assert.Equal(t, "collector", resp.Acknowledgements[0].Username)
+}
+
+func TestRequireTermsAcknowledgement(t *testing.T) {
+	h, _ := createTestHandler()
+
+	// Nothing is enforced until a document is published
+	assert.Equal(t, http.StatusOK, gatedSync(h, "collector"))
+
+	publishTerms(t, h, "Terms", "v1")
+	assert.Equal(t, http.StatusForbidden, gatedSync(h, "collector"))
+
+	acknowledgeTerms(h, "collector", 1)
+	assert.Equal(t, http.StatusOK, gatedSync(h, "collector"))
+
+	// A new version must be acknowledged again
+	publishTerms(t, h, "Terms", "v2")
+	assert.Equal(t, http.StatusForbidden, gatedSync(h, "collector"))
+	acknowledgeTerms(h, "collector", 2)
+	assert.Equal(t, http.StatusOK, gatedSync(h, "collector"))
+}
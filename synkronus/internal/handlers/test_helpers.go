@@ -45,6 +45,32 @@ func createTestHandler() (*Handler, *mocks.MockAppBundleService) {
 		mockVersionService,
 		mockAttachmentManifestService,
 		mockDataExportService,
+		WithSettingsService(mocks.NewMockSettingsService()),
+		WithOrgUnitService(mocks.NewMockOrgUnitService()),
+		WithDocumentService(mocks.NewMockDocumentService()),
+		WithFederationService(mocks.NewMockFederationService()),
+		WithSavedQueryService(mocks.NewMockSavedQueryService()),
+		WithAuditService(mocks.NewMockAuditService()),
+		WithTermsService(mocks.NewMockTermsService()),
+		WithInviteService(mocks.NewMockInviteService()),
+		WithWebhookService(mocks.NewMockWebhookService()),
+		WithAPIKeyService(mocks.NewMockAPIKeyService()),
+		WithBackupService(mocks.NewMockBackupService()),
+		WithExportTemplateService(mocks.NewMockExportTemplateService()),
+		WithLatencyService(mocks.NewMockLatencyService()),
+		WithAttachmentLifecycleService(mocks.NewMockAttachmentLifecycleService()),
+		WithSamplingService(mocks.NewMockSamplingService()),
+		WithBundleChannelService(mocks.NewMockBundleChannelService()),
+		WithDataImportService(mocks.NewMockDataImportService()),
+		WithBundleUploadService(mocks.NewMockBundleUploadService()),
+		WithImportSourceService(mocks.NewMockImportSourceService()),
+		WithDeviceService(mocks.NewMockDeviceService()),
+		WithFormComponentService(mocks.NewMockFormComponentService()),
+		WithCodeListService(mocks.NewMockCodeListService()),
+		WithReportService(mocks.NewMockReportService()),
+		WithCanaryService(mocks.NewMockCanaryService()),
+		WithDatabasePool(mocks.NewMockDatabasePool()),
+		WithReplicationService(mocks.NewMockReplicationService()),
 	)
 
 	return h, mockAppBundleService
@@ -6,7 +6,10 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/audit"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
 	"github.com/opendataensemble/synkronus/pkg/user"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
 )
 
 // UserCreateRequest represents the request body for creating a user
@@ -43,6 +46,16 @@ func (h *Handler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
 		return
 	}
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventUserCreated, Username: newUser.Username, Role: string(newUser.Role)})
+	h.recordAudit(r, audit.Entry{Action: audit.ActionUserCreate, Resource: "users/" + newUser.Username})
+	createdBy := ""
+	if admin, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && admin != nil {
+		createdBy = admin.Username
+	}
+	h.notifyWebhooks(r, webhook.Payload{
+		Event: webhook.EventUserCreated,
+		User:  &webhook.UserEvent{Username: newUser.Username, Role: string(newUser.Role), CreatedBy: createdBy},
+	})
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(UserResponse{Username: newUser.Username, Role: newUser.Role}); err != nil {
 		h.log.Error("Failed to encode user response", "error", err)
@@ -65,6 +78,8 @@ func (h *Handler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
 		return
 	}
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventUserDeleted, Username: username})
+	h.recordAudit(r, audit.Entry{Action: audit.ActionUserDelete, Resource: "users/" + username})
 	if err := json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"}); err != nil {
 		h.log.Error("Failed to encode delete response", "error", err)
 	}
@@ -96,6 +111,7 @@ func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
 		return
 	}
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventPasswordReset, Username: req.Username})
 	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"}); err != nil {
 		h.log.Error("Failed to encode reset password response", "error", err)
 	}
@@ -113,6 +129,17 @@ func (h *Handler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// PasswordHashReportHandler handles GET /users/password-hashes (admin only)
+func (h *Handler) PasswordHashReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := h.authService.PasswordHashReport(r.Context())
+	if err != nil {
+		h.log.Error("Failed to build password hash report", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to build password hash report")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, report)
+}
+
 // ChangePasswordRequest represents the request body for changing password
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"currentPassword"`
@@ -141,6 +168,7 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 		SendErrorResponse(w, http.StatusUnauthorized, err, err.Error())
 		return
 	}
+	h.recordAuthEvent(r, audit.Event{Type: audit.EventPasswordChanged, Username: username})
 	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"}); err != nil {
 		h.log.Error("Failed to encode change password response", "error", err)
 	}
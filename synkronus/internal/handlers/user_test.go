@@ -13,6 +13,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
 	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/auth"
 	"github.com/opendataensemble/synkronus/pkg/logger"
 	"github.com/stretchr/testify/assert"
 )
@@ -211,3 +212,21 @@ func TestChangePasswordHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordHashReportHandler(t *testing.T) {
+	h, _ := userHandlerTestHelper()
+
+	w := httptest.NewRecorder()
+	h.PasswordHashReportHandler(w, httptest.NewRequest(http.MethodGet, "/users/password-hashes", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report auth.PasswordHashReport
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, auth.HashAlgorithmArgon2id, report.Algorithm)
+	counted := 0
+	for _, n := range report.ByAlgorithm {
+		counted += n
+	}
+	assert.Equal(t, report.Total, counted)
+	assert.Contains(t, report.PendingUsers, "admin")
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	authmw "github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
+)
+
+// CreateWebhookRequest represents the request body for subscribing a URL to events
+type CreateWebhookRequest struct {
+	Name      string             `json:"name"`
+	URL       string             `json:"url"`
+	Secret    string             `json:"secret"`
+	Events    []string           `json:"events"`
+	FormTypes []string           `json:"form_types"`
+	Fields    []string           `json:"fields"`
+	Masks     []webhook.MaskRule `json:"masks"`
+}
+
+// notifyWebhooks queues a bundle or user event for the subscriptions selecting it. Failures are
+// logged; the change that raised the event has already been made.
+func (h *Handler) notifyWebhooks(r *http.Request, payload webhook.Payload) {
+	if h.webhookService == nil {
+		return
+	}
+	if err := h.webhookService.Notify(r.Context(), payload); err != nil {
+		h.log.Error("Failed to queue webhook event", "event", payload.Event, "error", err)
+	}
+}
+
+// ListWebhooksHandler handles GET /webhooks (admin only)
+func (h *Handler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.List(r.Context())
+	if err != nil {
+		h.log.Error("Failed to list webhook subscriptions", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list webhook subscriptions")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, subs)
+}
+
+// CreateWebhookHandler handles POST /webhooks (admin only). The response is the only place the
+// subscription secret is returned.
+func (h *Handler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+
+	createdBy := ""
+	if u, ok := r.Context().Value(authmw.UserKey).(*models.User); ok && u != nil {
+		createdBy = u.Username
+	}
+
+	sub, err := h.webhookService.Create(r.Context(), webhook.Subscription{
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    req.Events,
+		FormTypes: req.FormTypes,
+		Fields:    req.Fields,
+		Masks:     req.Masks,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		if errors.Is(err, webhook.ErrInvalidSubscription) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to create webhook subscription", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to create webhook subscription")
+		return
+	}
+	SendJSONResponse(w, http.StatusCreated, sub)
+}
+
+// DeleteWebhookHandler handles DELETE /webhooks/{id} (admin only)
+func (h *Handler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.webhookService.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Webhook subscription not found")
+			return
+		}
+		h.log.Error("Failed to delete webhook subscription", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to delete webhook subscription")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Webhook subscription deleted"})
+}
+
+// ListWebhookDeliveriesHandler handles GET /webhooks/{id}/deliveries (admin only)
+func (h *Handler) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "limit must be a positive integer")
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), chi.URLParam(r, "id"), limit)
+	if err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Webhook subscription not found")
+			return
+		}
+		h.log.Error("Failed to list webhook deliveries", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list webhook deliveries")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, deliveries)
+}
+
+// ListWebhookDeliveryAttemptsHandler handles GET /webhooks/{id}/deliveries/{deliveryId}/attempts (admin only)
+func (h *Handler) ListWebhookDeliveryAttemptsHandler(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+	if err != nil {
+		SendErrorResponse(w, http.StatusNotFound, webhook.ErrDeliveryNotFound, "Webhook delivery not found")
+		return
+	}
+
+	attempts, err := h.webhookService.ListAttempts(r.Context(), chi.URLParam(r, "id"), deliveryID)
+	if err != nil {
+		if errors.Is(err, webhook.ErrDeliveryNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Webhook delivery not found")
+			return
+		}
+		h.log.Error("Failed to list webhook delivery attempts", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list webhook delivery attempts")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, attempts)
+}
+
+// ListWebhookDeadLettersHandler handles GET /webhooks/dead-letters (admin only)
+func (h *Handler) ListWebhookDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			SendErrorResponse(w, http.StatusBadRequest, err, "limit must be a positive integer")
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeadLetters(r.Context(), r.URL.Query().Get("subscription_id"), limit)
+	if err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			SendErrorResponse(w, http.StatusNotFound, err, "Webhook subscription not found")
+			return
+		}
+		h.log.Error("Failed to list dead-lettered webhook deliveries", "error", err)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to list dead-lettered webhook deliveries")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, deliveries)
+}
+
+// ReplayWebhookDeliveriesResponse reports how many dead-lettered deliveries were queued again
+type ReplayWebhookDeliveriesResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+// ReplayWebhookDeliveriesHandler handles POST /webhooks/dead-letters/replay (admin only)
+func (h *Handler) ReplayWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	var req webhook.ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+
+	n, err := h.webhookService.Replay(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrInvalidReplay):
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+		case errors.Is(err, webhook.ErrSubscriptionNotFound):
+			SendErrorResponse(w, http.StatusNotFound, err, "Webhook subscription not found")
+		default:
+			h.log.Error("Failed to replay webhook deliveries", "error", err)
+			SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to replay webhook deliveries")
+		}
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, ReplayWebhookDeliveriesResponse{Replayed: n})
+}
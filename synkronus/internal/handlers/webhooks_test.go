@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createWebhook(h *Handler, req CreateWebhookRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	h.CreateWebhookHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	return w
+}
+
+func TestCreateWebhook(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := createWebhook(h, CreateWebhookRequest{
+		Name:      "referrals",
+		URL:       "https://referrals.example.org/hook",
+		FormTypes: []string{"danger_signs"},
+		Fields:    []string{"patient.name", "patient.phone"},
+		Masks:     []webhook.MaskRule{{Field: "patient.phone", Method: webhook.MaskHash}},
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created webhook.Subscription
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, "admin", created.CreatedBy)
+
+	assert.Equal(t, http.StatusBadRequest, createWebhook(h, CreateWebhookRequest{Name: "x", URL: "not a url"}).Code)
+	assert.Equal(t, http.StatusBadRequest, createWebhook(h, CreateWebhookRequest{URL: "https://example.org"}).Code)
+	assert.Equal(t, http.StatusBadRequest, createWebhook(h, CreateWebhookRequest{
+		Name: "x", URL: "https://example.org", Masks: []webhook.MaskRule{{Field: "phone", Method: "rot13"}},
+	}).Code)
+
+	// The secret is only returned on creation
+	w = httptest.NewRecorder()
+	h.ListWebhooksHandler(w, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var subs []webhook.Subscription
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&subs))
+	require.Len(t, subs, 1)
+	assert.Equal(t, created.ID, subs[0].ID)
+	assert.Empty(t, subs[0].Secret)
+}
+
+func TestWebhookDeliveriesAndDelete(t *testing.T) {
+	h, _ := createTestHandler()
+	webhookService := h.webhookService.(*mocks.MockWebhookService)
+
+	var sub webhook.Subscription
+	require.NoError(t, json.NewDecoder(createWebhook(h, CreateWebhookRequest{
+		Name: "referrals", URL: "https://referrals.example.org/hook", FormTypes: []string{"danger_signs"},
+	}).Body).Decode(&sub))
+
+	require.NoError(t, webhookService.ObservationsPushed(t.Context(), nil, []sync.Observation{
+		{ObservationID: "obs-1", FormType: "danger_signs"},
+		{ObservationID: "obs-2", FormType: "household"},
+		{ObservationID: "obs-3", FormType: "danger_signs", Draft: true},
+	}))
+
+	deliveries := func(id, query string) (int, []webhook.Delivery) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/webhooks/"+id+"/deliveries"+query, nil)
+		h.ListWebhookDeliveriesHandler(w, withURLParams(r, "id", id))
+		var list []webhook.Delivery
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		}
+		return w.Code, list
+	}
+
+	// Only finalized records of the subscribed form types are queued
+	code, list := deliveries(sub.ID, "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, list, 1)
+	require.NotNil(t, list[0].ObservationID)
+	assert.Equal(t, "obs-1", *list[0].ObservationID)
+	assert.Equal(t, webhook.EventObservationPushed, list[0].Event)
+	assert.Equal(t, webhook.DeliveryPending, list[0].Status)
+
+	code, _ = deliveries(sub.ID, "?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	del := func(id string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "/webhooks/"+id, nil)
+		h.DeleteWebhookHandler(w, withURLParams(r, "id", id))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, del(sub.ID))
+	assert.Equal(t, http.StatusNotFound, del(sub.ID))
+	code, _ = deliveries(sub.ID, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestWebhookDeadLettersAndReplay(t *testing.T) {
+	h, _ := createTestHandler()
+	webhookService := h.webhookService.(*mocks.MockWebhookService)
+
+	var sub webhook.Subscription
+	require.NoError(t, json.NewDecoder(createWebhook(h, CreateWebhookRequest{
+		Name: "referrals", URL: "https://referrals.example.org/hook",
+	}).Body).Decode(&sub))
+	require.NoError(t, webhookService.ObservationsPushed(t.Context(), nil, []sync.Observation{
+		{ObservationID: "obs-1", FormType: "danger_signs"},
+		{ObservationID: "obs-2", FormType: "danger_signs"},
+	}))
+	webhookService.Fail(http.StatusBadGateway)
+
+	deadLetters := func() []webhook.Delivery {
+		w := httptest.NewRecorder()
+		h.ListWebhookDeadLettersHandler(w, httptest.NewRequest(http.MethodGet, "/webhooks/dead-letters?subscription_id="+sub.ID, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var list []webhook.Delivery
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		return list
+	}
+	list := deadLetters()
+	require.Len(t, list, 2)
+	assert.Equal(t, webhook.DeliveryFailed, list[0].Status)
+
+	// Every attempt is kept with the receiver's response
+	w := httptest.NewRecorder()
+	id := strconv.FormatInt(list[0].ID, 10)
+	r := httptest.NewRequest(http.MethodGet, "/webhooks/"+sub.ID+"/deliveries/"+id+"/attempts", nil)
+	h.ListWebhookDeliveryAttemptsHandler(w, withURLParams(r, "id", sub.ID, "deliveryId", id))
+	require.Equal(t, http.StatusOK, w.Code)
+	var attempts []webhook.Attempt
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&attempts))
+	require.Len(t, attempts, 1)
+	assert.Equal(t, http.StatusBadGateway, *attempts[0].StatusCode)
+
+	replay := func(req webhook.ReplayRequest) (int, ReplayWebhookDeliveriesResponse) {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		h.ReplayWebhookDeliveriesHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/webhooks/dead-letters/replay", bytes.NewReader(body)), "admin", models.RoleAdmin))
+		var resp ReplayWebhookDeliveriesResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w.Code, resp
+	}
+
+	code, _ := replay(webhook.ReplayRequest{})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp := replay(webhook.ReplayRequest{DeliveryIDs: []int64{list[0].ID}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, resp.Replayed)
+	assert.Len(t, deadLetters(), 1)
+
+	code, resp = replay(webhook.ReplayRequest{SubscriptionID: sub.ID})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, resp.Replayed)
+	assert.Empty(t, deadLetters())
+}
+
+func TestWebhookBundleAndUserEvents(t *testing.T) {
+	h, _ := createTestHandler()
+
+	subscribe := func(events ...string) webhook.Subscription {
+		var sub webhook.Subscription
+		require.NoError(t, json.NewDecoder(createWebhook(h, CreateWebhookRequest{
+			Name: "ops", URL: "https://ops.example.org/hook", Events: events,
+		}).Body).Decode(&sub))
+		return sub
+	}
+	ops := subscribe(webhook.EventBundleActivated, webhook.EventUserCreated)
+	observations := subscribe()
+	assert.Equal(t, []string{webhook.EventObservationPushed}, observations.Events, "subscriptions default to observation events")
+	assert.Equal(t, http.StatusBadRequest, createWebhook(h, CreateWebhookRequest{
+		Name: "x", URL: "https://example.org", Events: []string{"bundle.deleted"},
+	}).Code)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/app-bundle/switch/1.0.0-alpha007", nil)
+	h.SwitchAppBundleVersion(w, withURLParams(withRole(r, "admin", models.RoleAdmin), "version", "1.0.0-alpha007"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, _ := json.Marshal(UserCreateRequest{Username: "newcomer", Password: "Secret-password-1", Role: models.RoleReadOnly})
+	w = httptest.NewRecorder()
+	h.CreateUserHandler(w, withRole(httptest.NewRequest(http.MethodPost, "/users/create", bytes.NewReader(body)), "admin", models.RoleAdmin))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	list, err := h.webhookService.ListDeliveries(t.Context(), ops.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, webhook.EventUserCreated, list[0].Event)
+	assert.Equal(t, webhook.EventBundleActivated, list[1].Event)
+	assert.Nil(t, list[0].ObservationID)
+
+	list, err = h.webhookService.ListDeliveries(t.Context(), observations.ID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/opendataensemble/synkronus/pkg/xlsform"
+)
+
+// maxXLSFormSize bounds the XLSX files accepted by ConvertXLSForm
+const maxXLSFormSize = 5 << 20
+
+// ConvertXLSForm handles POST /forms/convert. The multipart form carries an ODK XLSForm as
+// "file"; the response holds the schema.json and ui.json of the converted form and lists what
+// could not be converted. Nothing is stored.
+func (h *Handler) ConvertXLSForm(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxXLSFormSize+1<<20)
+	if err := r.ParseMultipartForm(maxXLSFormSize); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Invalid request format. Expected multipart form with an XLSForm as 'file'")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "file is required")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxXLSFormSize {
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, nil, "The XLSForm is larger than 5 MB")
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, err, "Failed to read file")
+		return
+	}
+
+	form, err := xlsform.Convert(header.Filename, data)
+	if err != nil {
+		if errors.Is(err, xlsform.ErrInvalidForm) {
+			SendErrorResponse(w, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		h.log.Error("Failed to convert XLSForm", "error", err, "file", header.Filename)
+		SendErrorResponse(w, http.StatusInternalServerError, err, "Failed to convert XLSForm")
+		return
+	}
+	SendJSONResponse(w, http.StatusOK, form)
+}
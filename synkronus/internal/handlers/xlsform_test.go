@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/xlsform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// convertRequest builds a multipart conversion request of a file
+func convertRequest(t *testing.T, filename string, data []byte) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/forms/convert", body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return withRole(r, "admin", models.RoleAdmin)
+}
+
+// surveyWorkbook is an XLSX file with a survey sheet of one text question, in inline strings
+func surveyWorkbook(t *testing.T) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="survey" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>type</t></is></c><c r="B1" t="inlineStr"><is><t>name</t></is></c><c r="C1" t="inlineStr"><is><t>label</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>text</t></is></c><c r="B2" t="inlineStr"><is><t>village</t></is></c><c r="C2" t="inlineStr"><is><t>Village</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+func TestConvertXLSForm(t *testing.T) {
+	h, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	h.ConvertXLSForm(w, convertRequest(t, "village_survey.xlsx", surveyWorkbook(t)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var form xlsform.Form
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&form))
+	assert.Equal(t, "village_survey", form.FormType)
+	assert.Equal(t, map[string]any{"village": map[string]any{"type": "string", "title": "Village"}}, form.Schema["properties"])
+	assert.Equal(t, "SwipeLayout", form.UI["type"])
+	assert.Empty(t, form.Unsupported)
+
+	w = httptest.NewRecorder()
+	h.ConvertXLSForm(w, convertRequest(t, "form.csv", []byte("type,name\ntext,village\n")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "not an XLSX file")
+}
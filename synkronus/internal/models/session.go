@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login, kept alive by refresh tokens until it expires or is revoked
+type Session struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"-" db:"user_id"`
+	// TokenHash is the SHA-256 hash of the session's current refresh token
+	TokenHash  string     `json:"-" db:"token_hash"`
+	UserAgent  string     `json:"userAgent,omitempty" db:"user_agent"`
+	IPAddress  string     `json:"ipAddress,omitempty" db:"ip_address"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	// ExpiresAt is when the current refresh token expires; refreshing extends it
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
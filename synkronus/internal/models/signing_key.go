@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SigningKey is an asymmetric key tokens are signed with
+type SigningKey struct {
+	// ID is the key ID tokens name in their kid header
+	ID        string `db:"kid"`
+	Algorithm string `db:"algorithm"`
+	// PrivateKey is the encrypted, DER encoded private key
+	PrivateKey []byte `db:"private_key"`
+	// PublicKey is the DER encoded public key
+	PublicKey []byte    `db:"public_key"`
+	CreatedAt time.Time `db:"created_at"`
+	// ActivatesAt is when the key starts signing; it is published before then
+	ActivatesAt time.Time `db:"activates_at"`
+	// ExpiresAt is when tokens signed with the key stop being accepted
+	ExpiresAt time.Time `db:"expires_at"`
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/opendataensemble/synkronus/internal/models"
@@ -27,3 +28,40 @@ type UserRepositoryInterface interface {
 	// List lists all users
 	List(ctx context.Context) ([]models.User, error)
 }
+
+// SigningKeyRepositoryInterface defines the storage of token signing keys
+type SigningKeyRepositoryInterface interface {
+	// List returns all stored keys ordered by activation time
+	List(ctx context.Context) ([]models.SigningKey, error)
+
+	// Create stores a new key
+	Create(ctx context.Context, key *models.SigningKey) error
+
+	// DeleteExpired removes keys that expired before the given time
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SessionRepositoryInterface defines the storage of login sessions
+type SessionRepositoryInterface interface {
+	// Create stores a new session
+	Create(ctx context.Context, session *models.Session) error
+
+	// GetByID retrieves a session by ID; nil when there is none
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+
+	// Rotate replaces the refresh token of an active session whose current token hash is
+	// oldHash, and reports whether it did
+	Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash string, expiresAt time.Time) (bool, error)
+
+	// ListActive returns the unrevoked, unexpired sessions of a user, newest first
+	ListActive(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+
+	// Revoke revokes an active session of a user and reports whether it did
+	Revoke(ctx context.Context, id, userID uuid.UUID) (bool, error)
+
+	// RevokeAll revokes every active session of a user
+	RevokeAll(ctx context.Context, userID uuid.UUID) (int64, error)
+
+	// DeleteExpired removes sessions that expired before the given time
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
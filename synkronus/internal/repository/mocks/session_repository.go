@@ -0,0 +1,107 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+)
+
+// MockSessionRepository is an in-memory implementation of the repository.SessionRepositoryInterface for testing
+type MockSessionRepository struct {
+	sessions map[uuid.UUID]models.Session
+}
+
+// NewMockSessionRepository creates a new mock session repository
+func NewMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{sessions: make(map[uuid.UUID]models.Session)}
+}
+
+func (m *MockSessionRepository) active(session models.Session) bool {
+	return session.RevokedAt == nil && session.ExpiresAt.After(time.Now())
+}
+
+// Create stores a new session
+func (m *MockSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	if _, exists := m.sessions[session.ID]; exists {
+		return errors.New("session already exists")
+	}
+	m.sessions[session.ID] = *session
+	return nil
+}
+
+// GetByID retrieves a session by ID; nil when there is none
+func (m *MockSessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	session, exists := m.sessions[id]
+	if !exists {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// Rotate replaces the refresh token of an active session whose current token hash is oldHash
+func (m *MockSessionRepository) Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	session, exists := m.sessions[id]
+	if !exists || session.TokenHash != oldHash || !m.active(session) {
+		return false, nil
+	}
+	now := time.Now()
+	session.TokenHash = newHash
+	session.ExpiresAt = expiresAt
+	session.LastUsedAt = &now
+	m.sessions[id] = session
+	return true, nil
+}
+
+// ListActive returns the unrevoked, unexpired sessions of a user, newest first
+func (m *MockSessionRepository) ListActive(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions := make([]models.Session, 0)
+	for _, session := range m.sessions {
+		if session.UserID == userID && m.active(session) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// Revoke revokes an active session of a user
+func (m *MockSessionRepository) Revoke(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	session, exists := m.sessions[id]
+	if !exists || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	m.sessions[id] = session
+	return true, nil
+}
+
+// RevokeAll revokes every active session of a user
+func (m *MockSessionRepository) RevokeAll(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	for _, session := range m.sessions {
+		if session.UserID == userID && m.active(session) {
+			if _, err := m.Revoke(ctx, session.ID, userID); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteExpired removes sessions that expired before the given time
+func (m *MockSessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	for id, session := range m.sessions {
+		if session.ExpiresAt.Before(before) {
+			delete(m.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
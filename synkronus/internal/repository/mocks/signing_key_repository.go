@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+)
+
+// MockSigningKeyRepository is an in-memory implementation of the repository.SigningKeyRepositoryInterface for testing
+type MockSigningKeyRepository struct {
+	keys map[string]models.SigningKey
+}
+
+// NewMockSigningKeyRepository creates a new mock signing key repository
+func NewMockSigningKeyRepository() *MockSigningKeyRepository {
+	return &MockSigningKeyRepository{keys: make(map[string]models.SigningKey)}
+}
+
+// List returns all stored keys ordered by activation time
+func (m *MockSigningKeyRepository) List(ctx context.Context) ([]models.SigningKey, error) {
+	keys := make([]models.SigningKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].ActivatesAt.Equal(keys[j].ActivatesAt) {
+			return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// Create stores a new key
+func (m *MockSigningKeyRepository) Create(ctx context.Context, key *models.SigningKey) error {
+	if _, exists := m.keys[key.ID]; exists {
+		return errors.New("signing key already exists")
+	}
+	m.keys[key.ID] = *key
+	return nil
+}
+
+// DeleteExpired removes keys that expired before the given time
+func (m *MockSigningKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	for id, key := range m.keys {
+		if key.ExpiresAt.Before(before) {
+			delete(m.keys, id)
+			count++
+		}
+	}
+	return count, nil
+}
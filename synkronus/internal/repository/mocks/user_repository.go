@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/opendataensemble/synkronus/internal/models"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 )
 
 // MockUserRepository is a mock implementation of the repository.UserRepositoryInterface for testing
@@ -68,6 +69,7 @@ func (m *MockUserRepository) Create(ctx context.Context, user *models.User) erro
 	if user.ID == uuid.Nil {
 		user.ID = uuid.New()
 	}
+	user.TenantID = tenant.OrDefault(user.TenantID)
 
 	// Set timestamps
 	now := time.Now()
@@ -138,11 +140,13 @@ func (m *MockUserRepository) Count(ctx context.Context) (int, error) {
 	return len(m.users), nil
 }
 
-// List lists all users in the system (admin operation)
+// List lists the users of the tenant in ctx (admin operation)
 func (m *MockUserRepository) List(ctx context.Context) ([]models.User, error) {
 	var users []models.User
 	for _, user := range m.users {
-		users = append(users, *user)
+		if tenant.OrDefault(user.TenantID) == tenant.FromContext(ctx) {
+			users = append(users, *user)
+		}
 	}
 	return users, nil
 }
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/database"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// SigningKeyRepository handles database operations for token signing keys
+// It implements the SigningKeyRepositoryInterface
+type SigningKeyRepository struct {
+	db  *database.Database
+	log *logger.Logger
+}
+
+// NewSigningKeyRepository creates a new signing key repository
+func NewSigningKeyRepository(db *database.Database, log *logger.Logger) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// List returns all stored keys ordered by activation time
+func (r *SigningKeyRepository) List(ctx context.Context) ([]models.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, public_key, created_at, activates_at, expires_at
+		FROM jwt_signing_keys
+		ORDER BY activates_at, kid
+	`
+
+	rows, err := r.db.DB().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.SigningKey
+	for rows.Next() {
+		var key models.SigningKey
+		if err := rows.Scan(
+			&key.ID,
+			&key.Algorithm,
+			&key.PrivateKey,
+			&key.PublicKey,
+			&key.CreatedAt,
+			&key.ActivatesAt,
+			&key.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Create stores a new key
+func (r *SigningKeyRepository) Create(ctx context.Context, key *models.SigningKey) error {
+	query := `
+		INSERT INTO jwt_signing_keys (kid, algorithm, private_key, public_key, created_at, activates_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.DB().ExecContext(ctx, query,
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+		key.PublicKey,
+		key.CreatedAt,
+		key.ActivatesAt,
+		key.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes keys that expired before the given time
+func (r *SigningKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.DB().ExecContext(ctx, "DELETE FROM jwt_signing_keys WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired signing keys: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return count, nil
+}
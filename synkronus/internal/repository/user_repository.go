@@ -11,6 +11,7 @@ import (
 	"github.com/opendataensemble/synkronus/internal/models"
 	"github.com/opendataensemble/synkronus/pkg/database"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 )
 
 // UserRepository handles database operations for users
@@ -31,7 +32,7 @@ func NewUserRepository(db *database.Database, log *logger.Logger) *UserRepositor
 // GetByUsername retrieves a user by username
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, password_hash, role, tenant_id, created_at, updated_at
 		FROM users
 		WHERE username = $1
 	`
@@ -42,6 +43,7 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 		&user.Username,
 		&user.PasswordHash,
 		&user.Role,
+		&user.TenantID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -56,13 +58,14 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 	return &user, nil
 }
 
-// List lists all users in the system (admin operation)
+// List lists the users of the tenant in ctx (admin operation)
 func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 	query := `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, password_hash, role, tenant_id, created_at, updated_at
 		FROM users
+		WHERE tenant_id = $1
 	`
-	rows, err := r.db.DB().QueryContext(ctx, query)
+	rows, err := r.db.DB().QueryContext(ctx, query, tenant.FromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -75,6 +78,7 @@ func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 			&user.Username,
 			&user.PasswordHash,
 			&user.Role,
+			&user.TenantID,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		); err != nil {
@@ -89,20 +93,21 @@ func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 	return users, nil
 }
 
-// Create creates a new user
+// Create creates a new user; users without a tenant join the default tenant
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	// Check if UUID is zero value and generate a new one if needed
 	if user.ID == uuid.Nil {
 		user.ID = uuid.New()
 	}
+	user.TenantID = tenant.OrDefault(user.TenantID)
 
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
 	query := `
-		INSERT INTO users (id, username, password_hash, role, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (id, username, password_hash, role, tenant_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	_, err := r.db.DB().ExecContext(ctx, query,
@@ -110,6 +115,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.Username,
 		user.PasswordHash,
 		user.Role,
+		user.TenantID,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -0,0 +1,91 @@
+// Package adminui serves the admin web UI, a static single-page app that manages the server
+// through the API. The UI built into the binary is used unless a directory is configured.
+package adminui
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Prefix is the path the admin UI is served under
+const Prefix = "/admin"
+
+// indexFile is served for the UI's own routes, which the browser resolves client side
+const indexFile = "index.html"
+
+//go:embed dist
+var embedded embed.FS
+
+// FS returns the files of the admin UI: those in dir, or the embedded ones when dir is empty.
+// The files must include an index.html.
+func FS(dir string) (fs.FS, error) {
+	var files fs.FS
+	if dir == "" {
+		sub, err := fs.Sub(embedded, "dist")
+		if err != nil {
+			return nil, err
+		}
+		files = sub
+	} else {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open admin UI directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("admin UI directory %s is not a directory", dir)
+		}
+		files = os.DirFS(dir)
+	}
+
+	if _, err := fs.Stat(files, indexFile); err != nil {
+		return nil, fmt.Errorf("admin UI has no %s: %w", indexFile, err)
+	}
+	return files, nil
+}
+
+// Handler serves files below Prefix. Paths without a file extension that match no file get
+// index.html, so the UI's own routes can be bookmarked and reloaded.
+func Handler(files fs.FS) http.Handler {
+	fileServer := http.FileServerFS(files)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, Prefix)), "/")
+		if name == "" || name == indexFile {
+			serveIndex(w, r, files)
+			return
+		}
+
+		if _, err := fs.Stat(files, name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+				serveIndex(w, r, files)
+				return
+			}
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/" + name
+		fileServer.ServeHTTP(w, r2)
+	})
+}
+
+// serveIndex serves index.html, which browsers must revalidate to pick up a new build
+func serveIndex(w http.ResponseWriter, r *http.Request, files fs.FS) {
+	data, err := fs.ReadFile(files, indexFile)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
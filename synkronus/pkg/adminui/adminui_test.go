@@ -0,0 +1,57 @@
+package adminui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestHandler_Embedded(t *testing.T) {
+	files, err := FS("")
+	require.NoError(t, err)
+	handler := Handler(files)
+
+	w := get(t, handler, "/admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Synkronus Admin")
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	w = get(t, handler, "/admin/app.js")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+
+	// Routes of the UI get the page, missing assets do not
+	w = get(t, handler, "/admin/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Synkronus Admin")
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/admin/missing.js").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/admin/../../etc/passwd.txt").Code)
+}
+
+func TestFS_Directory(t *testing.T) {
+	dir := t.TempDir()
+	_, err := FS(dir)
+	assert.Error(t, err, "a directory without index.html is refused")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Custom</h1>"), 0o644))
+	files, err := FS(dir)
+	require.NoError(t, err)
+	w := get(t, Handler(files), "/admin/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>Custom</h1>", w.Body.String())
+
+	_, err = FS(filepath.Join(dir, "index.html"))
+	assert.Error(t, err)
+}
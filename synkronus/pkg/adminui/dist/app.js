@@ -0,0 +1,201 @@
+// Admin UI for synkronus. It talks to the API of the server it is served by, keeping the
+// tokens in session storage so they are gone when the tab is closed.
+"use strict";
+
+const tokenKey = "synkronus-admin-token";
+const refreshKey = "synkronus-admin-refresh-token";
+const sections = ["login", "bundles", "users", "webhooks"];
+
+function token() {
+  return sessionStorage.getItem(tokenKey);
+}
+
+function show(message, isError) {
+  const el = document.getElementById("message");
+  el.textContent = message;
+  el.className = isError ? "error" : "";
+  el.hidden = !message;
+}
+
+async function api(method, path, body) {
+  const headers = { Authorization: "Bearer " + token() };
+  if (body !== undefined && !(body instanceof FormData)) {
+    headers["Content-Type"] = "application/json";
+    body = JSON.stringify(body);
+  }
+  const resp = await fetch(path, { method, headers, body });
+  if (resp.status === 401) {
+    sessionStorage.removeItem(tokenKey);
+    sessionStorage.removeItem(refreshKey);
+    route("/admin/login");
+    throw new Error("Your session has expired");
+  }
+  const data = resp.headers.get("Content-Type")?.includes("json") ? await resp.json() : null;
+  if (!resp.ok) {
+    throw new Error((data && data.message) || resp.statusText);
+  }
+  return data;
+}
+
+function cell(row, text) {
+  const td = row.insertCell();
+  td.textContent = text;
+  return td;
+}
+
+function button(td, label, onClick) {
+  const b = document.createElement("button");
+  b.type = "button";
+  b.textContent = label;
+  b.addEventListener("click", () => onClick().catch((err) => show(err.message, true)));
+  td.appendChild(b);
+}
+
+const loaders = {
+  async bundles() {
+    const { versions } = await api("GET", "/app-bundle/versions");
+    const rows = document.getElementById("bundle-rows");
+    rows.replaceChildren();
+    for (const entry of versions) {
+      const current = entry.endsWith("*");
+      const version = current ? entry.slice(0, -1) : entry;
+      const row = rows.insertRow();
+      cell(row, current ? version + " (active)" : version);
+      const actions = row.insertCell();
+      if (!current) {
+        button(actions, "Activate", async () => {
+          await api("POST", "/app-bundle/switch/" + encodeURIComponent(version));
+          show("Activated version " + version);
+          await loaders.bundles();
+        });
+      }
+    }
+  },
+
+  async users() {
+    const users = await api("GET", "/users");
+    const rows = document.getElementById("user-rows");
+    rows.replaceChildren();
+    for (const user of users) {
+      const row = rows.insertRow();
+      cell(row, user.username);
+      cell(row, user.role);
+      button(row.insertCell(), "Delete", async () => {
+        if (!confirm("Delete " + user.username + "?")) {
+          return;
+        }
+        await api("DELETE", "/users/delete/" + encodeURIComponent(user.username));
+        show("Deleted " + user.username);
+        await loaders.users();
+      });
+    }
+  },
+
+  async webhooks() {
+    const subs = await api("GET", "/webhooks");
+    const rows = document.getElementById("webhook-rows");
+    rows.replaceChildren();
+    for (const sub of subs) {
+      const row = rows.insertRow();
+      cell(row, sub.name);
+      cell(row, sub.url);
+      cell(row, (sub.events || []).join(", "));
+      cell(row, (sub.form_types || []).join(", ") || "all");
+    }
+  },
+};
+
+// route shows the section of a path below /admin, asking to log in first
+function route(path, replace) {
+  let name = path.replace(/^\/admin\/?/, "").split("/")[0] || "bundles";
+  if (!sections.includes(name)) {
+    name = "bundles";
+  }
+  if (!token()) {
+    name = "login";
+  } else if (name === "login") {
+    name = "bundles";
+  }
+
+  const target = "/admin/" + name;
+  if (location.pathname !== target) {
+    history[replace ? "replaceState" : "pushState"](null, "", target);
+  }
+  document.getElementById("nav").hidden = !token();
+  for (const section of sections) {
+    document.getElementById(section).hidden = section !== name;
+  }
+  if (loaders[name]) {
+    loaders[name]().catch((err) => show(err.message, true));
+  }
+}
+
+function onSubmit(id, handler) {
+  const form = document.getElementById(id);
+  form.addEventListener("submit", (event) => {
+    event.preventDefault();
+    handler(form)
+      .then(() => form.reset())
+      .catch((err) => show(err.message, true));
+  });
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  onSubmit("login-form", async (form) => {
+    const resp = await fetch("/auth/login", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ username: form.username.value, password: form.password.value }),
+    });
+    if (!resp.ok) {
+      throw new Error("Login failed");
+    }
+    const { token, refreshToken } = await resp.json();
+    sessionStorage.setItem(tokenKey, token);
+    sessionStorage.setItem(refreshKey, refreshToken);
+    show("");
+    route("/admin/bundles");
+  });
+
+  onSubmit("bundle-form", async (form) => {
+    const body = new FormData();
+    body.append("bundle", form.bundle.files[0]);
+    const { manifest } = await api("POST", "/app-bundle/push", body);
+    show("Uploaded version " + manifest.version + "; activate it to roll it out");
+    await loaders.bundles();
+  });
+
+  onSubmit("user-form", async (form) => {
+    await api("POST", "/users/create", {
+      username: form.username.value,
+      password: form.password.value,
+      role: form.role.value,
+    });
+    show("Created " + form.username.value);
+    await loaders.users();
+  });
+
+  document.getElementById("logout").addEventListener("click", () => {
+    // Ends the session on the server; the access token expires on its own
+    fetch("/auth/logout", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refreshToken: sessionStorage.getItem(refreshKey) }),
+    }).catch(() => {});
+    sessionStorage.removeItem(tokenKey);
+    sessionStorage.removeItem(refreshKey);
+    show("");
+    route("/admin/login");
+  });
+
+  document.getElementById("nav").addEventListener("click", (event) => {
+    if (event.target.tagName === "A") {
+      event.preventDefault();
+      show("");
+      route(event.target.getAttribute("href"));
+    }
+  });
+  window.addEventListener("popstate", () => route(location.pathname, true));
+
+  route(location.pathname, true);
+});
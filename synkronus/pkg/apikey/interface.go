@@ -0,0 +1,63 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+)
+
+// Common errors
+var (
+	// ErrKeyNotFound is returned when revoking a key that does not exist, belongs to someone else
+	// or was already revoked
+	ErrKeyNotFound = errors.New("API key not found")
+	// ErrInvalidKey is returned when authenticating with a key that is unknown, revoked, expired
+	// or whose owner no longer exists
+	ErrInvalidKey = errors.New("API key is invalid or has expired")
+	// ErrInvalidName is returned when creating a key without a name
+	ErrInvalidName = errors.New("API key name is required")
+	// ErrInvalidExpiry is returned when creating a key that would already be expired
+	ErrInvalidExpiry = errors.New("API key expiry must be in the future")
+)
+
+// Prefix starts every key, so keys are recognizable in configuration files and secret scanners
+const Prefix = "synk_"
+
+// IsKey reports whether a credential has the shape of an API key rather than a JWT
+func IsKey(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+// APIKey is a long-lived credential a user issues for a data pipeline or script. Requests made
+// with it act as the owner with the owner's current role. The key itself is only returned when
+// it is created; the server keeps a hash of it.
+type APIKey struct {
+	ID       string `json:"id" db:"id"`
+	Name     string `json:"name" db:"name"`
+	Username string `json:"username" db:"username"`
+	// KeyPrefix is the start of the key, shown so owners can tell their keys apart
+	KeyPrefix  string  `json:"key_prefix" db:"key_prefix"`
+	CreatedAt  string  `json:"created_at" db:"created_at"`
+	ExpiresAt  *string `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *string `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+// Service issues, lists, revokes and authenticates API keys
+type Service interface {
+	// Create issues a key for username and returns it with the secret key, which is not
+	// retrievable later. A nil expiresAt creates a key that does not expire.
+	Create(ctx context.Context, username, name string, expiresAt *time.Time) (*APIKey, string, error)
+
+	// List returns the active keys of username, or of every user when username is empty,
+	// newest first
+	List(ctx context.Context, username string) ([]APIKey, error)
+
+	// Revoke revokes a key of username, or any user's key when username is empty
+	Revoke(ctx context.Context, id, username string) (*APIKey, error)
+
+	// Authenticate returns the owner of a key, with the owner's current role, and the key
+	Authenticate(ctx context.Context, key string) (*models.User, *APIKey, error)
+}
@@ -0,0 +1,176 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/user"
+)
+
+// secretBytes is the number of random bytes in a key
+const secretBytes = 32
+
+// prefixLength is how much of a key is kept in the clear to tell keys apart
+const prefixLength = len(Prefix) + 8
+
+// lastUsedInterval limits how often a key's last use is written, so busy pipelines do not
+// update the row on every request
+const lastUsedInterval = time.Minute
+
+// keyColumns lists the columns selected for an APIKey in scan order
+const keyColumns = "k.id, k.name, u.username, k.key_prefix, k.created_at, k.expires_at, k.last_used_at"
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new API key service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// hashKey returns the form a key is stored and looked up in
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func scanKey(row interface{ Scan(...any) error }, key *APIKey, extra ...any) error {
+	return row.Scan(append([]any{&key.ID, &key.Name, &key.Username, &key.KeyPrefix, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt}, extra...)...)
+}
+
+// Create issues a key for username
+func (s *service) Create(ctx context.Context, username, name string, expiresAt *time.Time) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidName
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	raw := make([]byte, secretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	var key APIKey
+	err := scanKey(s.db.QueryRowContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO api_keys (id, user_id, name, key_prefix, key_hash, created_at, expires_at)
+			SELECT $1, id, $3, $4, $5, NOW(), $6 FROM users WHERE username = $2
+			RETURNING *
+		)
+		SELECT `+keyColumns+` FROM inserted k JOIN users u ON u.id = k.user_id`,
+		uuid.New(), username, name, secret[:prefixLength], hashKey(secret), expiresAt,
+	), &key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", user.ErrUserNotFound
+		}
+		s.log.Error("Failed to create API key", "error", err, "username", username)
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	s.log.Info("API key created", "id", key.ID, "username", username, "name", name)
+	return &key, secret, nil
+}
+
+// List returns the active keys of username, or of every user when username is empty
+func (s *service) List(ctx context.Context, username string) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+keyColumns+`
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.revoked_at IS NULL AND ($1 = '' OR u.username = $1)
+		ORDER BY k.created_at DESC`, username)
+	if err != nil {
+		s.log.Error("Failed to query API keys", "error", err)
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		var key APIKey
+		if err := scanKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes a key of username, or any user's key when username is empty
+func (s *service) Revoke(ctx context.Context, id, username string) (*APIKey, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrKeyNotFound
+	}
+
+	var key APIKey
+	err := scanKey(s.db.QueryRowContext(ctx, `
+		UPDATE api_keys k SET revoked_at = NOW()
+		FROM users u
+		WHERE u.id = k.user_id AND k.id = $1 AND k.revoked_at IS NULL AND ($2 = '' OR u.username = $2)
+		RETURNING `+keyColumns, id, username), &key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrKeyNotFound
+		}
+		s.log.Error("Failed to revoke API key", "error", err, "id", id)
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	s.log.Info("API key revoked", "id", id, "username", key.Username)
+	return &key, nil
+}
+
+// Authenticate returns the owner of a key, with the owner's current role and tenant, and the key
+func (s *service) Authenticate(ctx context.Context, secret string) (*models.User, *APIKey, error) {
+	if !IsKey(secret) {
+		return nil, nil, ErrInvalidKey
+	}
+
+	var key APIKey
+	var owner models.User
+	err := scanKey(s.db.QueryRowContext(ctx, `
+		SELECT `+keyColumns+`, u.id, u.role, u.tenant_id
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())`,
+		hashKey(secret)), &key, &owner.ID, &owner.Role, &owner.TenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidKey
+		}
+		s.log.Error("Failed to look up API key", "error", err)
+		return nil, nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	owner.Username = key.Username
+
+	// Failing to note the use does not fail the request
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - $2 * INTERVAL '1 second')`,
+		key.ID, lastUsedInterval.Seconds()); err != nil {
+		s.log.Warn("Failed to record API key use", "error", err, "id", key.ID)
+	}
+
+	return &owner, &key, nil
+}
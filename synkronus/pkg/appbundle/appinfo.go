@@ -16,6 +16,8 @@ type AppInfo struct {
 	Version   string              `json:"version"`
 	Forms     map[string]FormInfo `json:"forms,omitempty"`
 	Timestamp string              `json:"timestamp,omitempty"`
+	// App holds what the bundle declared in bundle.json it needs of the mobile app
+	App *BundleMetadata `json:"app,omitempty"`
 }
 
 // FormInfo contains information about a form
@@ -25,6 +27,9 @@ type FormInfo struct {
 	UIHash        string         `json:"ui_hash"`        // Hash of the UI schema
 	Fields        []FieldInfo    `json:"fields"`         // List of all fields
 	QuestionTypes map[string]any `json:"question_types"` // Map of question types referenced in the UI form
+
+	// Components lists the shared form components the form includes, as name@version
+	Components []string `json:"components,omitempty"`
 }
 
 // FieldInfo contains information about a form field
@@ -35,8 +40,24 @@ type FieldInfo struct {
 	QuestionType string `json:"question_type"`
 	Default      any    `json:"default"`
 	Core         bool   `json:"core"`
+
+	// ServerAssigned names how the server fills the field on push ("sequence" or "org_unit_code")
+	ServerAssigned  string `json:"server_assigned,omitempty"`
+	SequencePrefix  string `json:"sequence_prefix,omitempty"`
+	SequencePadding int    `json:"sequence_padding,omitempty"`
+
+	// CodeList names the managed code list whose codes the field's values must be (x-code-list)
+	CodeList string `json:"code_list,omitempty"`
 }
 
+// Server-assigned field kinds declared with x-server-assigned in form schemas
+const (
+	// ServerAssignedSequence assigns the next number of a per-form, per-field sequence
+	ServerAssignedSequence = "sequence"
+	// ServerAssignedOrgUnitCode assigns the code of the record's org unit
+	ServerAssignedOrgUnitCode = "org_unit_code"
+)
+
 // generateAppInfo generates the APP_INFO.json content for the bundle
 func (s *Service) generateAppInfo(zipReader *zip.Reader, version string) ([]byte, error) {
 	appInfo := AppInfo{
@@ -65,6 +86,13 @@ func (s *Service) generateAppInfo(zipReader *zip.Reader, version string) ([]byte
 				uiSchemas[formName] = file
 			}
 
+		case file.Name == BundleMetadataFile:
+			metadata, err := readBundleMetadata(file)
+			if err != nil {
+				return nil, err
+			}
+			appInfo.App = metadata
+
 		case strings.HasPrefix(file.Name, "renderers/") && strings.HasSuffix(file.Name, "/renderer.jsx"):
 			parts := strings.Split(file.Name, "/")
 			if len(parts) == 3 {
@@ -118,6 +146,7 @@ func (s *Service) generateAppInfo(zipReader *zip.Reader, version string) ([]byte
 			FormHash:      hashData(schema),
 			Fields:        extractFields(schema),
 			QuestionTypes: make(map[string]any),
+			Components:    includedComponents(schema),
 		}
 
 		// Add UI hash if exists
@@ -196,6 +225,12 @@ func extractFields(schema map[string]any) []FieldInfo {
 			Required:     requiredMap[fieldName],
 			Core:         getBool(field, "x-core") || strings.HasPrefix(fieldName, "core_"),
 			Default:      field["default"], // Will be nil if not specified
+
+			ServerAssigned:  getString(field, "x-server-assigned"),
+			SequencePrefix:  getString(field, "x-sequence-prefix"),
+			SequencePadding: getInt(field, "x-sequence-padding"),
+
+			CodeList: getString(field, "x-code-list"),
 		}
 
 		fields = append(fields, fieldInfo)
@@ -259,6 +294,13 @@ func getString(m map[string]any, key string) string {
 	return ""
 }
 
+func getInt(m map[string]any, key string) int {
+	if val, ok := m[key].(float64); ok {
+		return int(val)
+	}
+	return 0
+}
+
 func getBool(m map[string]any, key string) bool {
 	if val, ok := m[key].(bool); ok {
 		return val
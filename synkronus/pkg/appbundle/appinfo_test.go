@@ -240,7 +240,7 @@ func TestBundleChanges_FieldAddition(t *testing.T) {
 	require.NoError(t, err, "Failed to open bundle01")
 	defer bundle01File.Close()
 
-	_, err = service.PushBundle(context.Background(), bundle01File)
+	_, err = service.PushBundle(context.Background(), bundle01File, "")
 	require.NoError(t, err, "Failed to push initial bundle")
 
 	// Get the version number of the first bundle
@@ -273,7 +273,7 @@ func TestBundleChanges_FieldAddition(t *testing.T) {
 	require.NoError(t, err, "Failed to open bundle02")
 	defer bundle02File.Close()
 
-	_, err = service.PushBundle(context.Background(), bundle02File)
+	_, err = service.PushBundle(context.Background(), bundle02File, "")
 	require.NoError(t, err, "Failed to push second bundle")
 
 	// Verify current manifest is still for version 1
@@ -411,7 +411,7 @@ func TestPushBundleGeneratesAppInfo(t *testing.T) {
 			defer bundleFile.Close()
 
 			// Push the bundle
-			_, err = service.PushBundle(context.Background(), bundleFile)
+			_, err = service.PushBundle(context.Background(), bundleFile, "")
 			require.NoError(t, err, "Failed to push bundle")
 
 			// Get the app info for the pushed bundle
@@ -436,10 +436,10 @@ func TestPushBundleGeneratesAppInfo(t *testing.T) {
 			// Log the generated app info for debugging
 			t.Logf("Generated app info: %+v", appInfo)
 
-			// Verify the APP_INFO.json file exists in the version directory
-			appInfoPath := filepath.Join(config.VersionsPath, version, "APP_INFO.json")
-			_, err = os.Stat(appInfoPath)
-			assert.NoError(t, err, "APP_INFO.json should exist in the version directory")
+			// Verify the APP_INFO.json file is stored with the version
+			file, _, err := service.storage.OpenFile(context.Background(), version, "APP_INFO.json")
+			require.NoError(t, err, "APP_INFO.json should be stored with the version")
+			file.Close()
 		})
 	}
 }
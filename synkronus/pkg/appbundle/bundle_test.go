@@ -26,9 +26,9 @@ func TestBundleWithTestData(t *testing.T) {
 	defer zipFile.Close()
 
 	service := &Service{
-		bundlePath:   t.TempDir(),
-		versionsPath: t.TempDir(),
-		maxVersions:  5,
+		bundlePath:  t.TempDir(),
+		storage:     NewLocalStorage(t.TempDir()),
+		maxVersions: 5,
 	}
 
 	t.Run("ValidateBundleStructure", func(t *testing.T) {
@@ -45,9 +45,9 @@ func TestBundleWithTestData(t *testing.T) {
 // TestBundleWithoutRenderers tests that a bundle without a renderers directory is valid
 func TestBundleWithoutRenderers(t *testing.T) {
 	service := &Service{
-		bundlePath:   t.TempDir(),
-		versionsPath: t.TempDir(),
-		maxVersions:  5,
+		bundlePath:  t.TempDir(),
+		storage:     NewLocalStorage(t.TempDir()),
+		maxVersions: 5,
 	}
 
 	// Create a bundle with app and forms but no renderers
@@ -97,9 +97,9 @@ func TestInvalidBundles(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			service := &Service{
-				bundlePath:   t.TempDir(),
-				versionsPath: t.TempDir(),
-				maxVersions:  5,
+				bundlePath:  t.TempDir(),
+				storage:     NewLocalStorage(t.TempDir()),
+				maxVersions: 5,
 			}
 
 			bundlePath, err := createTestBundle(t, tc.includeApp, tc.includeForms, false)
@@ -236,9 +236,9 @@ func createTestFormBundle(t *testing.T, forms map[string]map[string]any) (string
 // TestFormVersionChanges tests validation of form version changes
 func TestFormVersionChanges(t *testing.T) {
 	service := &Service{
-		bundlePath:   t.TempDir(),
-		versionsPath: t.TempDir(),
-		maxVersions:  5,
+		bundlePath:  t.TempDir(),
+		storage:     NewLocalStorage(t.TempDir()),
+		maxVersions: 5,
 	}
 
 	// Initial bundle with one form
@@ -353,9 +353,9 @@ func createFormWithRendererReference(rendererName string) map[string]any {
 // TestMissingRendererReferences tests validation of forms with missing renderer references
 func TestMissingRendererReferences(t *testing.T) {
 	service := &Service{
-		bundlePath:   t.TempDir(),
-		versionsPath: t.TempDir(),
-		maxVersions:  5,
+		bundlePath:  t.TempDir(),
+		storage:     NewLocalStorage(t.TempDir()),
+		maxVersions: 5,
 	}
 
 	t.Run("BuiltInRendererReference", func(t *testing.T) {
@@ -449,17 +449,17 @@ func TestMissingRendererReferences(t *testing.T) {
 // TestCoreFieldsValidation tests validation of core fields in forms
 func TestCoreFieldsValidation(t *testing.T) {
 	service := &Service{
-		bundlePath:   t.TempDir(),
-		versionsPath: t.TempDir(),
-		maxVersions:  5,
+		bundlePath:  t.TempDir(),
+		storage:     NewLocalStorage(t.TempDir()),
+		maxVersions: 5,
 	}
 
 	t.Run("CoreFieldModification", func(t *testing.T) {
 		// Create a new service for this test case to avoid cache pollution
 		service = &Service{
-			bundlePath:   t.TempDir(),
-			versionsPath: t.TempDir(),
-			maxVersions:  5,
+			bundlePath:  t.TempDir(),
+			storage:     NewLocalStorage(t.TempDir()),
+			maxVersions: 5,
 		}
 		// Initial bundle with a form containing core fields
 		bundle1, err := createTestFormBundle(t, map[string]map[string]any{
@@ -518,9 +518,9 @@ func TestCoreFieldsValidation(t *testing.T) {
 	t.Run("NonCoreFieldModification", func(t *testing.T) {
 		// Create a new service for this test case to avoid cache pollution
 		service = &Service{
-			bundlePath:   t.TempDir(),
-			versionsPath: t.TempDir(),
-			maxVersions:  5,
+			bundlePath:  t.TempDir(),
+			storage:     NewLocalStorage(t.TempDir()),
+			maxVersions: 5,
 		}
 
 		// Create a bundle with a non-core form
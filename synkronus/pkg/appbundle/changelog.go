@@ -1,10 +1,10 @@
 package appbundle
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
 )
 
 // ChangeLog represents the structure of CHANGE_LOG.json
@@ -198,12 +198,12 @@ func (s *Service) GenerateChangeLog(oldAppInfo, newAppInfo []byte) ([]byte, erro
 // GetChangeLogBetweenVersions gets the change log between two versions
 func (s *Service) GetChangeLogBetweenVersions(versionA, versionB string) ([]byte, error) {
 	// Read APP_INFO.json from both versions
-	appInfoA, err := s.readAppInfo(versionA)
+	appInfoA, err := s.readAppInfo(context.Background(), versionA)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read app info for version %s: %w", versionA, err)
 	}
 
-	appInfoB, err := s.readAppInfo(versionB)
+	appInfoB, err := s.readAppInfo(context.Background(), versionB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read app info for version %s: %w", versionB, err)
 	}
@@ -212,11 +212,14 @@ func (s *Service) GetChangeLogBetweenVersions(versionA, versionB string) ([]byte
 }
 
 // readAppInfo reads the APP_INFO.json file for a specific version
-func (s *Service) readAppInfo(version string) ([]byte, error) {
-	versionPath := filepath.Join(s.versionsPath, version)
-	appInfoPath := filepath.Join(versionPath, "APP_INFO.json")
+func (s *Service) readAppInfo(ctx context.Context, version string) ([]byte, error) {
+	file, _, err := s.storage.OpenFile(ctx, version, "APP_INFO.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APP_INFO.json: %w", err)
+	}
+	defer file.Close()
 
-	data, err := os.ReadFile(appInfoPath)
+	data, err := io.ReadAll(file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read APP_INFO.json: %w", err)
 	}
@@ -234,7 +234,7 @@ func TestGenerateChangeLog_JSON(t *testing.T) {
 func TestGetChangeLogBetweenVersions(t *testing.T) {
 	// Setup test service with mock file reading
 	service := &Service{
-		versionsPath: "testdata/versions",
+		storage: NewLocalStorage("testdata/versions"),
 	}
 
 	// Test with non-existent versions (should error)
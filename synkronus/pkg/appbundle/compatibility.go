@@ -0,0 +1,200 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BundleMetadataFile is the optional top-level file of a bundle declaring what it needs of the
+// mobile app
+const BundleMetadataFile = "bundle.json"
+
+// ErrInvalidAppVersion is returned for app versions that are not dotted numbers like 1.4.2
+var ErrInvalidAppVersion = errors.New("invalid app version")
+
+// BundleMetadata is the content of bundle.json
+type BundleMetadata struct {
+	// MinAppVersion is the oldest app version that runs the bundle
+	MinAppVersion string `json:"min_app_version,omitempty"`
+	// QuestionTypes maps question types built into the app to the app version that introduced
+	// them; forms using such a type need at least that version
+	QuestionTypes map[string]string `json:"question_types,omitempty"`
+}
+
+// validate checks that every version declared is an app version
+func (m BundleMetadata) validate() error {
+	if m.MinAppVersion != "" {
+		if _, err := parseAppVersion(m.MinAppVersion); err != nil {
+			return err
+		}
+	}
+	for questionType, version := range m.QuestionTypes {
+		if _, err := parseAppVersion(version); err != nil {
+			return fmt.Errorf("question type %s: %w", questionType, err)
+		}
+	}
+	return nil
+}
+
+// readBundleMetadata parses and validates the bundle.json of a bundle
+func readBundleMetadata(file *zip.File) (*BundleMetadata, error) {
+	data, err := readZipFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", BundleMetadataFile, err)
+	}
+	var metadata BundleMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON in %s: %v", ErrInvalidStructure, BundleMetadataFile, err)
+	}
+	if err := metadata.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidStructure, BundleMetadataFile, err)
+	}
+	return &metadata, nil
+}
+
+// Requirement is a reason a bundle needs a minimum app version
+type Requirement struct {
+	// Form and QuestionType name the form and the question type needing the version; both are
+	// empty for the minimum declared for the whole bundle
+	Form          string `json:"form,omitempty"`
+	QuestionType  string `json:"question_type,omitempty"`
+	MinAppVersion string `json:"min_app_version"`
+}
+
+// Compatibility tells which app versions run a bundle version
+type Compatibility struct {
+	Version string `json:"version"`
+	// MinAppVersion is the oldest app version running every form of the bundle; empty when the
+	// bundle declares no requirements
+	MinAppVersion string        `json:"min_app_version,omitempty"`
+	Requirements  []Requirement `json:"requirements"`
+	// Compatible tells whether the app version asked about runs the bundle; nil when none was given
+	Compatible *bool `json:"compatible,omitempty"`
+	// Unmet lists the requirements the app version asked about falls short of
+	Unmet []Requirement `json:"unmet,omitempty"`
+}
+
+// Compatibility lists the requirements of a bundle version on the app. With an appVersion, it
+// also tells whether that version runs the bundle and which requirements it does not meet.
+func (info *AppInfo) Compatibility(appVersion string) (*Compatibility, error) {
+	var app []int
+	if appVersion != "" {
+		var err error
+		if app, err = parseAppVersion(appVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Compatibility{Version: info.Version, Requirements: []Requirement{}}
+	if info.App != nil {
+		if info.App.MinAppVersion != "" {
+			c.Requirements = append(c.Requirements, Requirement{MinAppVersion: info.App.MinAppVersion})
+		}
+		for form, formInfo := range info.Forms {
+			for _, questionType := range formInfo.questionTypes() {
+				if version, ok := info.App.QuestionTypes[questionType]; ok {
+					c.Requirements = append(c.Requirements, Requirement{Form: form, QuestionType: questionType, MinAppVersion: version})
+				}
+			}
+		}
+	}
+	sort.Slice(c.Requirements, func(i, j int) bool {
+		a, b := c.Requirements[i], c.Requirements[j]
+		if a.Form != b.Form {
+			return a.Form < b.Form
+		}
+		return a.QuestionType < b.QuestionType
+	})
+
+	var minimum []int
+	for _, requirement := range c.Requirements {
+		// Versions were validated when the bundle was pushed
+		required, err := parseAppVersion(requirement.MinAppVersion)
+		if err != nil {
+			return nil, err
+		}
+		if minimum == nil || compareAppVersions(required, minimum) > 0 {
+			minimum, c.MinAppVersion = required, requirement.MinAppVersion
+		}
+		if app != nil && compareAppVersions(app, required) < 0 {
+			c.Unmet = append(c.Unmet, requirement)
+		}
+	}
+	if app != nil {
+		compatible := len(c.Unmet) == 0
+		c.Compatible = &compatible
+	}
+	return c, nil
+}
+
+// questionTypes returns the distinct question types of the fields and renderers of a form
+func (f FormInfo) questionTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	add := func(questionType string) {
+		if questionType != "" && !seen[questionType] {
+			seen[questionType] = true
+			types = append(types, questionType)
+		}
+	}
+	for _, field := range f.Fields {
+		add(field.QuestionType)
+	}
+	for questionType := range f.QuestionTypes {
+		add(questionType)
+	}
+	return types
+}
+
+// parseAppVersion parses an app version such as 1.4, 1.4.2 or v1.4.2-beta.1 into its numbers.
+// Pre-release and build suffixes are ignored, so a beta counts as the release it leads to.
+func parseAppVersion(version string) ([]int, error) {
+	core := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if core == "" || len(parts) > 4 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAppVersion, version)
+	}
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAppVersion, version)
+		}
+		numbers[i] = n
+	}
+	return numbers, nil
+}
+
+// ValidAppVersion reports whether version is an app version such as 1.4.2
+func ValidAppVersion(version string) bool {
+	_, err := parseAppVersion(version)
+	return err == nil
+}
+
+// compareAppVersions compares two parsed app versions, missing numbers counting as 0
+func compareAppVersions(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
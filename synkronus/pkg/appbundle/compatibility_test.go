@@ -0,0 +1,87 @@
+package appbundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAppVersion(t *testing.T) {
+	for version, want := range map[string][]int{
+		"1.4":           {1, 4},
+		"1.4.2":         {1, 4, 2},
+		"v2.0.1":        {2, 0, 1},
+		"1.5.0-beta.2":  {1, 5, 0},
+		"1.5.0+build.7": {1, 5, 0},
+	} {
+		got, err := parseAppVersion(version)
+		require.NoError(t, err, version)
+		assert.Equal(t, want, got, version)
+	}
+	for _, version := range []string{"", "latest", "1..2", "1.x", "-1.0", "1.2.3.4.5"} {
+		_, err := parseAppVersion(version)
+		assert.ErrorIs(t, err, ErrInvalidAppVersion, version)
+	}
+
+	assert.Equal(t, 0, compareAppVersions([]int{1, 4}, []int{1, 4, 0}))
+	assert.Equal(t, -1, compareAppVersions([]int{1, 4, 2}, []int{1, 10}))
+	assert.Equal(t, 1, compareAppVersions([]int{2}, []int{1, 9, 9}))
+}
+
+func TestAppInfo_Compatibility(t *testing.T) {
+	info := &AppInfo{
+		Version: "3",
+		Forms: map[string]FormInfo{
+			"household": {Fields: []FieldInfo{{Name: "plot", QuestionType: "gps_area"}, {Name: "head", QuestionType: "text"}}},
+			"visit":     {QuestionTypes: map[string]any{"signature": struct{}{}}},
+		},
+		App: &BundleMetadata{
+			MinAppVersion: "1.4.0",
+			QuestionTypes: map[string]string{"gps_area": "1.6", "signature": "1.5.2", "barcode": "2.0"},
+		},
+	}
+
+	c, err := info.Compatibility("")
+	require.NoError(t, err)
+	assert.Equal(t, "1.6", c.MinAppVersion)
+	assert.Equal(t, []Requirement{
+		{MinAppVersion: "1.4.0"},
+		{Form: "household", QuestionType: "gps_area", MinAppVersion: "1.6"},
+		{Form: "visit", QuestionType: "signature", MinAppVersion: "1.5.2"},
+	}, c.Requirements, "question types no form uses are no requirement")
+	assert.Nil(t, c.Compatible)
+
+	c, err = info.Compatibility("1.5.3")
+	require.NoError(t, err)
+	require.NotNil(t, c.Compatible)
+	assert.False(t, *c.Compatible)
+	assert.Equal(t, []Requirement{{Form: "household", QuestionType: "gps_area", MinAppVersion: "1.6"}}, c.Unmet)
+
+	c, err = info.Compatibility("1.6.0")
+	require.NoError(t, err)
+	assert.True(t, *c.Compatible)
+	assert.Empty(t, c.Unmet)
+
+	_, err = info.Compatibility("next")
+	assert.ErrorIs(t, err, ErrInvalidAppVersion)
+
+	// Bundles without bundle.json run on any app version
+	c, err = (&AppInfo{Version: "1"}).Compatibility("0.1")
+	require.NoError(t, err)
+	assert.True(t, *c.Compatible)
+	assert.Empty(t, c.MinAppVersion)
+}
+
+func TestGenerateAppInfo_BundleMetadata(t *testing.T) {
+	s := &Service{}
+	data, err := s.generateAppInfo(createAppInfoTestZip(t, map[string]string{
+		"bundle.json": `{"min_app_version": "1.4.0"}`,
+	}), "2")
+	require.NoError(t, err)
+	var info AppInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	require.NotNil(t, info.App)
+	assert.Equal(t, "1.4.0", info.App.MinAppVersion)
+}
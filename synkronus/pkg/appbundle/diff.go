@@ -0,0 +1,109 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DiffFile describes a differential download. It sits at the top level of the ZIP, next to the
+// changed files, where no bundle file can be.
+const DiffFile = "DIFF.json"
+
+// Diff lists the files that changed between the version a device has and the one it should get
+type Diff struct {
+	// From is the hash of the manifest the diff starts from; empty when the device's manifest
+	// is unknown, in which case every file is listed as changed
+	From        string `json:"from,omitempty"`
+	FromVersion string `json:"fromVersion,omitempty"`
+	// To is the hash of the target manifest, which the device has once the diff is applied
+	To      string `json:"to"`
+	Version string `json:"version"`
+	// Changed are the files added or modified in the target version, included in the ZIP
+	Changed []File `json:"changed"`
+	// Removed are the paths of files the target version no longer has
+	Removed []string `json:"removed"`
+}
+
+// DiffManifests compares two manifests by file hash. A nil from lists every file as changed.
+func DiffManifests(from, to *Manifest) *Diff {
+	diff := &Diff{To: to.Hash, Version: to.Version, Changed: []File{}, Removed: []string{}}
+	previous := make(map[string]string)
+	if from != nil {
+		diff.From = from.Hash
+		diff.FromVersion = from.Version
+		for _, file := range from.Files {
+			previous[file.Path] = file.Hash
+		}
+	}
+
+	for _, file := range to.Files {
+		if hash, ok := previous[file.Path]; !ok || hash != file.Hash {
+			diff.Changed = append(diff.Changed, file)
+		}
+		delete(previous, file.Path)
+	}
+	for path := range previous {
+		diff.Removed = append(diff.Removed, path)
+	}
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// FindManifest returns the manifest of the stored version with the given manifest hash, or
+// ErrVersionNotFound when none has it, e.g. because the version was removed since
+func (s *Service) FindManifest(ctx context.Context, hash string) (*Manifest, error) {
+	versions, err := s.GetVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions: %w", err)
+	}
+
+	s.diffMutex.Lock()
+	defer s.diffMutex.Unlock()
+	// Stored versions never change, so their manifests are kept while the versions are
+	manifests := make(map[string]*Manifest, len(versions))
+	var found *Manifest
+	for _, version := range versions {
+		version = strings.TrimSuffix(version, " *")
+		manifest := s.versionManifests[version]
+		if manifest == nil {
+			if manifest, err = s.versionManifest(ctx, version); err != nil {
+				return nil, fmt.Errorf("failed to generate manifest of version %s: %w", version, err)
+			}
+		}
+		manifests[version] = manifest
+		if manifest.Hash == hash && found == nil {
+			found = manifest
+		}
+	}
+	s.versionManifests = manifests
+
+	if found == nil {
+		return nil, ErrVersionNotFound
+	}
+	return found, nil
+}
+
+// WriteDiff writes a diff as a ZIP of DIFF.json and the changed files, read from the stored
+// target version
+func (s *Service) WriteDiff(ctx context.Context, diff *Diff, w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	dst, err := zipWriter.Create(DiffFile)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(dst).Encode(diff); err != nil {
+		return err
+	}
+
+	for _, file := range diff.Changed {
+		if err := s.exportFile(ctx, zipWriter, diff.Version, StoredFile{Path: file.Path, ModTime: file.ModTime}); err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.Path, err)
+		}
+	}
+	return zipWriter.Close()
+}
@@ -0,0 +1,80 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffManifests(t *testing.T) {
+	from := &Manifest{Hash: "a", Version: "1", Files: []File{
+		{Path: "app/index.html", Hash: "1"},
+		{Path: "forms/survey/schema.json", Hash: "2"},
+	}}
+	to := &Manifest{Hash: "b", Version: "2", Files: []File{
+		{Path: "app/index.html", Hash: "1"},
+		{Path: "forms/visit/schema.json", Hash: "3"},
+	}}
+
+	diff := DiffManifests(from, to)
+	assert.Equal(t, []File{to.Files[1]}, diff.Changed)
+	assert.Equal(t, []string{"forms/survey/schema.json"}, diff.Removed)
+
+	diff = DiffManifests(nil, to)
+	assert.Equal(t, to.Files, diff.Changed)
+	assert.Empty(t, diff.Removed)
+}
+
+func TestWriteDiff(t *testing.T) {
+	ctx := context.Background()
+	service := newReplica(t, newMemoryStorage())
+	for _, bundle := range []string{"valid_bundle01.zip", "valid_bundle02.zip"} {
+		bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", bundle))
+		require.NoError(t, err)
+		_, err = service.PushBundle(ctx, bundleFile, "")
+		bundleFile.Close()
+		require.NoError(t, err)
+	}
+
+	// The manifest a device got for the old version still identifies it
+	require.NoError(t, service.SwitchVersion(ctx, "0001"))
+	old, err := service.GetManifest(ctx)
+	require.NoError(t, err)
+	require.NoError(t, service.SwitchVersion(ctx, "0002"))
+	current, err := service.GetManifest(ctx)
+	require.NoError(t, err)
+
+	found, err := service.FindManifest(ctx, old.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", found.Version)
+	_, err = service.FindManifest(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	diff := DiffManifests(found, current)
+	assert.Equal(t, current.Hash, diff.To)
+	require.NotEmpty(t, diff.Changed)
+	assert.Less(t, len(diff.Changed), len(current.Files))
+
+	var buf bytes.Buffer
+	require.NoError(t, service.WriteDiff(ctx, diff, &buf))
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, len(diff.Changed)+1)
+	src, err := archive.File[0].Open()
+	require.NoError(t, err)
+	var written Diff
+	require.NoError(t, json.NewDecoder(src).Decode(&written))
+	src.Close()
+	assert.Equal(t, DiffFile, archive.File[0].Name)
+	assert.Equal(t, diff.Version, written.Version)
+	for i, file := range diff.Changed {
+		assert.Equal(t, file.Path, archive.File[i+1].Name)
+	}
+}
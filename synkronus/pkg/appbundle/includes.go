@@ -0,0 +1,302 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Schema keys of shared form components
+const (
+	// IncludeKey lists the components a form schema includes, e.g. ["demographics@2", "consent"],
+	// and marks the place of a component's questions in a UI schema, e.g. {"x-include": "consent"}
+	IncludeKey = "x-include"
+	// IncludedKey lists the components a stored form schema was resolved with, by name and version
+	IncludedKey = "x-included"
+)
+
+// ErrInvalidInclude is returned when the x-include references of a bundle cannot be resolved
+var ErrInvalidInclude = errors.New("invalid x-include")
+
+// IncludedComponent is a version of a shared form component, a group of questions such as a
+// demographics or consent block that many forms include
+type IncludedComponent struct {
+	Name    string
+	Version int
+	// Schema is an object schema whose properties and required fields are merged into the form
+	Schema map[string]any
+	// UI is the UI schema element that replaces {"x-include": ...} in the form's UI schema
+	UI map[string]any
+}
+
+// IncludeResolver looks up the components bundles include
+type IncludeResolver interface {
+	// ResolveInclude returns the component a reference names: "name@version", or "name" for the
+	// latest version
+	ResolveInclude(ctx context.Context, ref string) (*IncludedComponent, error)
+}
+
+// resolveIncludes replaces the x-include references of the bundle's forms with the components
+// they name, writing the rewritten bundle to dst. It reports false, leaving dst empty, when no
+// form includes a component. Resolved schemas list the component versions they were built from
+// under x-included.
+func (s *Service) resolveIncludes(ctx context.Context, zipReader *zip.Reader, dst io.Writer) (bool, error) {
+	rewritten := make(map[string][]byte)
+	for _, file := range zipReader.File {
+		parts := strings.Split(file.Name, "/")
+		if len(parts) != 3 || parts[0] != "forms" || parts[2] != "schema.json" {
+			continue
+		}
+		formName := parts[1]
+		schema, ui, changed, err := s.resolveFormIncludes(ctx, zipReader, formName)
+		if err != nil {
+			return false, err
+		}
+		if changed {
+			rewritten["forms/"+formName+"/schema.json"] = schema
+			if ui != nil {
+				rewritten["forms/"+formName+"/ui.json"] = ui
+			}
+		}
+	}
+	if len(rewritten) == 0 {
+		return false, nil
+	}
+
+	// Write the bundle again with the resolved forms in place of the pushed ones
+	w := zip.NewWriter(dst)
+	for _, file := range zipReader.File {
+		header := file.FileHeader
+		out, err := w.CreateHeader(&header)
+		if err != nil {
+			return false, fmt.Errorf("failed to write resolved bundle: %w", err)
+		}
+		if data, ok := rewritten[file.Name]; ok {
+			_, err = out.Write(data)
+		} else if !file.FileInfo().IsDir() {
+			var src io.ReadCloser
+			if src, err = file.Open(); err == nil {
+				_, err = io.Copy(out, src)
+				src.Close()
+			}
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to write resolved bundle: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return false, fmt.Errorf("failed to write resolved bundle: %w", err)
+	}
+	return true, nil
+}
+
+// resolveFormIncludes resolves the includes of one form and returns its new schema.json and
+// ui.json, and whether the form includes anything. ui.json is nil when it has no includes.
+func (s *Service) resolveFormIncludes(ctx context.Context, zipReader *zip.Reader, formName string) ([]byte, []byte, bool, error) {
+	schemaData, err := readZipFileByName(zipReader, "forms/"+formName+"/schema.json")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		return nil, nil, false, fmt.Errorf("invalid JSON in form schema %s: %w", formName, err)
+	}
+	var ui map[string]any
+	if uiData, err := readZipFileByName(zipReader, "forms/"+formName+"/ui.json"); err == nil {
+		if err := json.Unmarshal(uiData, &ui); err != nil {
+			return nil, nil, false, fmt.Errorf("invalid JSON in UI schema %s: %w", formName, err)
+		}
+	}
+
+	refs, err := includeRefs(schema[IncludeKey])
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%w: form '%s': %v", ErrInvalidInclude, formName, err)
+	}
+	if len(refs) == 0 {
+		if ui != nil && containsInclude(ui) {
+			return nil, nil, false, fmt.Errorf("%w: ui.json of form '%s' includes a component its schema.json does not list under %s",
+				ErrInvalidInclude, formName, IncludeKey)
+		}
+		return nil, nil, false, nil
+	}
+	if s.includes == nil {
+		return nil, nil, false, fmt.Errorf("%w: form '%s' includes components, but shared form components are not available",
+			ErrInvalidInclude, formName)
+	}
+
+	// Merge the questions of each component into the form, in the order they are listed
+	properties, _ := schema["properties"].(map[string]any)
+	if properties == nil {
+		properties = make(map[string]any)
+	}
+	required, _ := schema["required"].([]any)
+	components := make(map[string]*IncludedComponent)
+	var included []any
+	for _, ref := range refs {
+		component, err := s.includes.ResolveInclude(ctx, ref)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("%w: form '%s' includes '%s': %v", ErrInvalidInclude, formName, ref, err)
+		}
+		if _, ok := components[component.Name]; ok {
+			return nil, nil, false, fmt.Errorf("%w: form '%s' includes '%s' twice", ErrInvalidInclude, formName, component.Name)
+		}
+		components[component.Name] = component
+		included = append(included, fmt.Sprintf("%s@%d", component.Name, component.Version))
+
+		componentProperties, _ := component.Schema["properties"].(map[string]any)
+		names := make([]string, 0, len(componentProperties))
+		for name := range componentProperties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, ok := properties[name]; ok {
+				return nil, nil, false, fmt.Errorf("%w: field '%s' of component '%s' is already defined in form '%s'",
+					ErrInvalidInclude, name, component.Name, formName)
+			}
+			properties[name] = componentProperties[name]
+		}
+		if componentRequired, ok := component.Schema["required"].([]any); ok {
+			required = append(required, componentRequired...)
+		}
+	}
+	schema["properties"] = properties
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	delete(schema, IncludeKey)
+	schema[IncludedKey] = included
+
+	resolvedSchema, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to encode resolved form schema %s: %w", formName, err)
+	}
+	if ui == nil || !containsInclude(ui) {
+		return resolvedSchema, nil, true, nil
+	}
+	resolvedUI, err := replaceIncludes(ui, components)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%w: ui.json of form '%s': %v", ErrInvalidInclude, formName, err)
+	}
+	uiData, err := json.MarshalIndent(resolvedUI, "", "  ")
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to encode resolved UI schema %s: %w", formName, err)
+	}
+	return resolvedSchema, uiData, true, nil
+}
+
+// includeRefs reads the x-include value of a form schema: one reference or a list of them
+func includeRefs(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		refs := make([]string, 0, len(v))
+		for _, item := range v {
+			ref, ok := item.(string)
+			if !ok || ref == "" {
+				return nil, fmt.Errorf("%s must list component names", IncludeKey)
+			}
+			refs = append(refs, ref)
+		}
+		return refs, nil
+	}
+	return nil, fmt.Errorf("%s must be a component name or a list of them", IncludeKey)
+}
+
+// containsInclude reports whether a UI schema has an x-include element anywhere
+func containsInclude(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		if _, ok := v[IncludeKey]; ok {
+			return true
+		}
+		for _, child := range v {
+			if containsInclude(child) {
+				return true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if containsInclude(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaceIncludes replaces every {"x-include": "name"} element of a UI schema with the UI of the
+// component, which the form schema must include. A version in the element must match it.
+func replaceIncludes(value any, components map[string]*IncludedComponent) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if ref, ok := v[IncludeKey]; ok {
+			text, _ := ref.(string)
+			name, version, _ := strings.Cut(text, "@")
+			component, ok := components[name]
+			if !ok {
+				return nil, fmt.Errorf("'%v' is not listed under %s in schema.json", ref, IncludeKey)
+			}
+			if version != "" && version != fmt.Sprint(component.Version) {
+				return nil, fmt.Errorf("'%s' does not match version %d included by schema.json", text, component.Version)
+			}
+			if component.UI == nil {
+				return nil, fmt.Errorf("component '%s' has no UI to include", name)
+			}
+			return component.UI, nil
+		}
+		replaced := make(map[string]any, len(v))
+		for key, child := range v {
+			r, err := replaceIncludes(child, components)
+			if err != nil {
+				return nil, err
+			}
+			replaced[key] = r
+		}
+		return replaced, nil
+	case []any:
+		replaced := make([]any, len(v))
+		for i, child := range v {
+			r, err := replaceIncludes(child, components)
+			if err != nil {
+				return nil, err
+			}
+			replaced[i] = r
+		}
+		return replaced, nil
+	}
+	return value, nil
+}
+
+// readZipFileByName reads a file of a bundle by its path
+func readZipFileByName(zipReader *zip.Reader, name string) ([]byte, error) {
+	for _, file := range zipReader.File {
+		if file.Name == name {
+			return readZipFile(file)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+}
+
+// includedComponents reads the x-included list of a resolved form schema
+func includedComponents(schema map[string]any) []string {
+	list, _ := schema[IncludedKey].([]any)
+	components := make([]string, 0, len(list))
+	for _, item := range list {
+		if ref, ok := item.(string); ok {
+			components = append(components, ref)
+		}
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	return components
+}
@@ -0,0 +1,170 @@
+package appbundle
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIncludes resolves references against a fixed set of components, latest version last
+type fakeIncludes map[string][]*IncludedComponent
+
+func (f fakeIncludes) ResolveInclude(ctx context.Context, ref string) (*IncludedComponent, error) {
+	name, version, _ := strings.Cut(ref, "@")
+	versions := f[name]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if version == "" || version == fmt.Sprint(versions[i].Version) {
+			return versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no component %s", ref)
+}
+
+func newIncludingReplica(t *testing.T) *Service {
+	service := newReplica(t, newMemoryStorage())
+	service.includes = fakeIncludes{
+		"demographics": {
+			{Name: "demographics", Version: 1, Schema: map[string]any{
+				"properties": map[string]any{"age": map[string]any{"type": "integer"}},
+			}},
+			{Name: "demographics", Version: 2, Schema: map[string]any{
+				"properties": map[string]any{
+					"age": map[string]any{"type": "integer"},
+					"sex": map[string]any{"type": "string"},
+				},
+				"required": []any{"age"},
+			}, UI: map[string]any{"type": "Group", "label": "Demographics"}},
+		},
+		"consent": {
+			{Name: "consent", Version: 1, Schema: map[string]any{
+				"properties": map[string]any{"consent_given": map[string]any{"type": "boolean"}},
+			}},
+		},
+	}
+	return service
+}
+
+func pushIncludingBundle(t *testing.T, service *Service, schema, ui string) (*Manifest, error) {
+	t.Helper()
+	bundle, err := createTestZip(t, map[string]string{
+		"app/index.html":           "<html></html>",
+		"forms/survey/schema.json": schema,
+		"forms/survey/ui.json":     ui,
+	})
+	require.NoError(t, err)
+	return service.PushBundle(context.Background(), bundle, "")
+}
+
+func readVersionJSON(t *testing.T, service *Service, version, path string) map[string]any {
+	t.Helper()
+	file, _, err := service.versionFile(context.Background(), version, path)
+	require.NoError(t, err)
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	var value map[string]any
+	require.NoError(t, json.Unmarshal(data, &value))
+	return value
+}
+
+func TestPushResolvesIncludes(t *testing.T) {
+	service := newIncludingReplica(t)
+	manifest, err := pushIncludingBundle(t, service,
+		`{"type": "object", "x-include": ["demographics", "consent@1"], "properties": {"village": {"type": "string"}}, "required": ["village"]}`,
+		`{"type": "VerticalLayout", "elements": [{"x-include": "demographics@2"}, {"type": "Control", "scope": "#/properties/village"}]}`)
+	require.NoError(t, err)
+
+	schema := readVersionJSON(t, service, manifest.Version, "forms/survey/schema.json")
+	assert.NotContains(t, schema, IncludeKey)
+	assert.Equal(t, []any{"demographics@2", "consent@1"}, schema[IncludedKey])
+	assert.Equal(t, []any{"village", "age"}, schema["required"])
+	properties := schema["properties"].(map[string]any)
+	for _, field := range []string{"village", "age", "sex", "consent_given"} {
+		assert.Contains(t, properties, field)
+	}
+
+	ui := readVersionJSON(t, service, manifest.Version, "forms/survey/ui.json")
+	elements := ui["elements"].([]any)
+	assert.Equal(t, map[string]any{"type": "Group", "label": "Demographics"}, elements[0])
+
+	appInfo, err := service.GetAppInfo(context.Background(), manifest.Version)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"demographics@2", "consent@1"}, appInfo.Forms["survey"].Components)
+}
+
+func TestPushRejectsInvalidIncludes(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		ui     string
+		errMsg string
+	}{
+		{
+			name:   "unknown component",
+			schema: `{"x-include": "household", "properties": {}}`,
+			ui:     `{}`,
+			errMsg: "household",
+		},
+		{
+			name:   "field collision",
+			schema: `{"x-include": "demographics", "properties": {"age": {"type": "string"}}}`,
+			ui:     `{}`,
+			errMsg: "field 'age'",
+		},
+		{
+			name:   "included twice",
+			schema: `{"x-include": ["consent", "consent@1"], "properties": {}}`,
+			ui:     `{}`,
+			errMsg: "twice",
+		},
+		{
+			name:   "ui include missing from schema",
+			schema: `{"x-include": "consent", "properties": {}}`,
+			ui:     `{"elements": [{"x-include": "demographics"}]}`,
+			errMsg: "not listed",
+		},
+		{
+			name:   "ui include version mismatch",
+			schema: `{"x-include": "demographics@1", "properties": {}}`,
+			ui:     `{"elements": [{"x-include": "demographics@2"}]}`,
+			errMsg: "does not match",
+		},
+		{
+			name:   "ui include without schema includes",
+			schema: `{"properties": {}}`,
+			ui:     `{"elements": [{"x-include": "consent"}]}`,
+			errMsg: "does not list",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := pushIncludingBundle(t, newIncludingReplica(t), tc.schema, tc.ui)
+			assert.ErrorIs(t, err, ErrInvalidInclude)
+			assert.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+}
+
+func TestPushIncludesRequireLibrary(t *testing.T) {
+	_, err := pushIncludingBundle(t, newReplica(t, newMemoryStorage()), `{"x-include": "consent", "properties": {}}`, `{}`)
+	assert.ErrorIs(t, err, ErrInvalidInclude)
+}
+
+func TestSignedPushRejectsIncludes(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	service := newIncludingReplica(t)
+	service.signingKeys = []ed25519.PublicKey{public}
+
+	_, err = pushIncludingBundle(t, service, `{"x-include": "consent", "properties": {}}`, `{}`)
+	assert.ErrorIs(t, err, ErrInvalidInclude)
+	assert.ErrorContains(t, err, "signed")
+}
@@ -10,6 +10,12 @@ import (
 // ErrFileNotFound is returned when a requested file is not found
 var ErrFileNotFound = errors.New("file not found")
 
+// ErrVersionNotFound is returned when a requested version does not exist
+var ErrVersionNotFound = errors.New("version not found")
+
+// ErrNoPreview is returned when promoting while no version is staged for preview
+var ErrNoPreview = errors.New("no version staged for preview")
+
 // File represents a file in the app bundle
 type File struct {
 	Path     string    `json:"path"`
@@ -17,6 +23,9 @@ type File struct {
 	Hash     string    `json:"hash"`
 	MimeType string    `json:"mimeType"`
 	ModTime  time.Time `json:"modTime"`
+	// URL is a content-hashed download location that never changes meaning, so it may be cached
+	// indefinitely by a CDN; a new version yields new URLs for every changed file
+	URL string `json:"url,omitempty"`
 }
 
 // Manifest represents the app bundle manifest
@@ -25,6 +34,8 @@ type Manifest struct {
 	Version     string `json:"version"`
 	GeneratedAt string `json:"generatedAt"`
 	Hash        string `json:"hash"` // Hash of the entire manifest for ETag
+	// Signature is set for versions pushed with a signature verified against a signing key
+	Signature *Signature `json:"signature,omitempty"`
 }
 
 // AppBundleServiceInterface defines the interface for app bundle operations
@@ -38,14 +49,19 @@ type AppBundleServiceInterface interface {
 	// GetLatestVersionFile gets a file from the latest version
 	GetLatestVersionFile(ctx context.Context, path string) (io.ReadCloser, *File, error)
 
+	// GetVersionFile gets a file from a stored version
+	GetVersionFile(ctx context.Context, version, path string) (io.ReadCloser, *File, error)
+
 	// GetFileHash returns the hash for a specific file, optionally from the latest version
 	GetFileHash(ctx context.Context, path string, useLatest bool) (string, error)
 
 	// RefreshManifest forces a refresh of the manifest
 	RefreshManifest() error
 
-	// PushBundle uploads a new app bundle from a zip file
-	PushBundle(ctx context.Context, zipReader io.Reader) (*Manifest, error)
+	// PushBundle uploads a new app bundle from a zip file. The signature is the base64 Ed25519
+	// signature of the bundle's content digest; empty for unsigned bundles, which are refused
+	// with ErrSignatureRequired when signing keys are configured.
+	PushBundle(ctx context.Context, zipReader io.Reader, signature string) (*Manifest, error)
 
 	// VersionInfo holds information about an app bundle version
 	// GetVersions returns a list of available app bundle versions
@@ -55,6 +71,32 @@ type AppBundleServiceInterface interface {
 	// SwitchVersion switches to a specific app bundle version
 	SwitchVersion(ctx context.Context, version string) error
 
+	// StagePreview stages a stored version for devices on the preview channel
+	StagePreview(ctx context.Context, version string) error
+
+	// GetStagedPreview returns the version staged for preview, or "" when none is staged
+	GetStagedPreview(ctx context.Context) (string, error)
+
+	// PromotePreview switches to the staged preview version, ends the preview and returns the version
+	PromotePreview(ctx context.Context) (string, error)
+
+	// GetPreviewManifest retrieves the manifest of the preview version: the staged one, or the
+	// newest version when none is staged
+	GetPreviewManifest(ctx context.Context) (*Manifest, error)
+
+	// GetPreviewFile retrieves a file of the preview version
+	GetPreviewFile(ctx context.Context, path string) (io.ReadCloser, *File, error)
+
+	// FindManifest returns the manifest of the stored version with the given manifest hash, or
+	// ErrVersionNotFound when no stored version has it
+	FindManifest(ctx context.Context, hash string) (*Manifest, error)
+
+	// WriteDiff writes a diff as a ZIP of DIFF.json and the changed files of the target version
+	WriteDiff(ctx context.Context, diff *Diff, w io.Writer) error
+
+	// ExportVersion writes a stored version as a zip that PushBundle accepts, e.g. for backups
+	ExportVersion(ctx context.Context, version string, w io.Writer) error
+
 	// GetAppInfo retrieves the app info for a specific version
 	GetAppInfo(ctx context.Context, version string) (*AppInfo, error)
 
@@ -0,0 +1,70 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrBundleTooLarge is returned when a pushed bundle exceeds one of the configured limits
+var ErrBundleTooLarge = errors.New("app bundle too large")
+
+// Limits bound the bundles that can be pushed, so that a small archive cannot unpack into more
+// than the server can hold. A zero limit is not enforced.
+type Limits struct {
+	// MaxSize is the largest zip file accepted, in bytes
+	MaxSize int64
+	// MaxFiles is the most entries a zip file may have, directories included
+	MaxFiles int
+	// MaxUncompressedSize is the most bytes all files may unpack into
+	MaxUncompressedSize int64
+	// MaxFileSize is the most bytes a single file may unpack into
+	MaxFileSize int64
+}
+
+// DefaultLimits returns limits generous enough for bundles with media and web app assets
+func DefaultLimits() Limits {
+	return Limits{
+		MaxSize:             256 << 20,
+		MaxFiles:            10000,
+		MaxUncompressedSize: 1 << 30,
+		MaxFileSize:         100 << 20,
+	}
+}
+
+// copyBundle copies a pushed zip file to w, failing once it grows past MaxSize
+func (l Limits) copyBundle(w io.Writer, r io.Reader) error {
+	if l.MaxSize <= 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	n, err := io.Copy(w, io.LimitReader(r, l.MaxSize+1))
+	if err != nil {
+		return err
+	}
+	if n > l.MaxSize {
+		return fmt.Errorf("%w: the zip file is larger than %d bytes", ErrBundleTooLarge, l.MaxSize)
+	}
+	return nil
+}
+
+// check rejects archives exceeding the limits before anything is read from them. The sizes
+// checked are those the archive declares; the zip reader fails on files that unpack into more.
+func (l Limits) check(zipReader *zip.Reader) error {
+	if l.MaxFiles > 0 && len(zipReader.File) > l.MaxFiles {
+		return fmt.Errorf("%w: %d files, at most %d are allowed", ErrBundleTooLarge, len(zipReader.File), l.MaxFiles)
+	}
+	var total uint64
+	for _, file := range zipReader.File {
+		if l.MaxFileSize > 0 && file.UncompressedSize64 > uint64(l.MaxFileSize) {
+			return fmt.Errorf("%w: %s unpacks into more than %d bytes", ErrBundleTooLarge, file.Name, l.MaxFileSize)
+		}
+		// Compared against what is left, as declared sizes may be chosen to overflow the total
+		if l.MaxUncompressedSize > 0 && file.UncompressedSize64 > uint64(l.MaxUncompressedSize)-total {
+			return fmt.Errorf("%w: the files unpack into more than %d bytes", ErrBundleTooLarge, l.MaxUncompressedSize)
+		}
+		total += file.UncompressedSize64
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_Check(t *testing.T) {
+	bundle := createAppInfoTestZip(t, map[string]string{
+		"app/index.html":           "<html></html>",
+		"forms/survey/schema.json": strings.Repeat(" ", 2000),
+		"forms/survey/ui.json":     "{}",
+	})
+
+	tests := []struct {
+		name   string
+		limits Limits
+		ok     bool
+	}{
+		{"within limits", DefaultLimits(), true},
+		{"no limits", Limits{}, true},
+		{"too many files", Limits{MaxFiles: 2}, false},
+		{"file too large", Limits{MaxFileSize: 1000}, false},
+		{"unpacks into too much", Limits{MaxUncompressedSize: 2010}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.check(bundle)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrBundleTooLarge)
+			}
+		})
+	}
+}
+
+func TestLimits_OverflowingSizes(t *testing.T) {
+	// Declared sizes that wrap the total around must not slip past the limit
+	bundle := &zip.Reader{File: []*zip.File{
+		{FileHeader: zip.FileHeader{Name: "app/index.html", UncompressedSize64: 1}},
+		{FileHeader: zip.FileHeader{Name: "app/bomb.bin", UncompressedSize64: ^uint64(0)}},
+	}}
+	assert.ErrorIs(t, Limits{MaxUncompressedSize: 1 << 20}.check(bundle), ErrBundleTooLarge)
+}
+
+func TestPushRejectsOversizedBundles(t *testing.T) {
+	ctx := context.Background()
+	bundle, err := os.ReadFile(filepath.Join("..", "..", "testdata", "bundles", "valid_bundle01.zip"))
+	require.NoError(t, err)
+
+	service := newReplica(t, newMemoryStorage())
+	service.limits = Limits{MaxSize: int64(len(bundle)) - 1}
+	_, err = service.PushBundle(ctx, bytes.NewReader(bundle), "")
+	assert.ErrorIs(t, err, ErrBundleTooLarge)
+
+	// A small archive of zeros that would unpack into a lot is refused before extraction
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	w, err := zipWriter.Create("app/index.html")
+	require.NoError(t, err)
+	_, err = w.Write(make([]byte, 4<<20))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	service.limits = Limits{MaxSize: 1 << 20, MaxFileSize: 1 << 20}
+	_, err = service.PushBundle(ctx, bytes.NewReader(buf.Bytes()), "")
+	assert.ErrorIs(t, err, ErrBundleTooLarge)
+
+	versions, err := service.GetVersions(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	service.limits = DefaultLimits()
+	_, err = service.PushBundle(ctx, bytes.NewReader(bundle), "")
+	assert.NoError(t, err)
+}
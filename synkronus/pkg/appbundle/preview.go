@@ -0,0 +1,170 @@
+package appbundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StagePreview stages a stored version for devices on the preview channel. Devices on the stable
+// channel keep the current version until the preview is promoted.
+func (s *Service) StagePreview(ctx context.Context, version string) error {
+	exists, err := s.versionExists(ctx, version)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrVersionNotFound, version)
+	}
+	if err := s.storage.SetPreviewVersion(ctx, version); err != nil {
+		return err
+	}
+	s.log.Info("Staged app bundle version for preview", "version", version)
+	return nil
+}
+
+// GetStagedPreview returns the version staged for preview, or "" when none is staged or the
+// staged version has since been removed
+func (s *Service) GetStagedPreview(ctx context.Context) (string, error) {
+	version, err := s.storage.PreviewVersion(ctx)
+	if err != nil || version == "" {
+		return "", err
+	}
+	exists, err := s.versionExists(ctx, version)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		s.log.Warn("Staged preview version not found in storage", "version", version)
+		return "", nil
+	}
+	return version, nil
+}
+
+// PromotePreview switches to the staged preview version, so every device gets it, and ends the
+// preview
+func (s *Service) PromotePreview(ctx context.Context) (string, error) {
+	version, err := s.GetStagedPreview(ctx)
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		return "", ErrNoPreview
+	}
+	if err := s.SwitchVersion(ctx, version); err != nil {
+		return "", err
+	}
+	if err := s.storage.SetPreviewVersion(ctx, ""); err != nil {
+		return "", err
+	}
+	s.log.Info("Promoted app bundle preview", "version", version)
+	return version, nil
+}
+
+// previewVersion returns the version served to the preview channel: the staged one, or the
+// newest version when none is staged
+func (s *Service) previewVersion(ctx context.Context) (string, error) {
+	version, err := s.GetStagedPreview(ctx)
+	if err != nil || version != "" {
+		return version, err
+	}
+	versions, err := s.GetVersions(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return "", ErrVersionNotFound
+	}
+	return strings.TrimSuffix(versions[0], " *"), nil
+}
+
+// GetPreviewFile retrieves a file of the preview version
+func (s *Service) GetPreviewFile(ctx context.Context, path string) (io.ReadCloser, *File, error) {
+	version, err := s.previewVersion(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.versionFile(ctx, version, path)
+}
+
+// GetPreviewManifest retrieves the manifest of the preview version. Stored versions never
+// change, so the manifest is built once per previewed version.
+func (s *Service) GetPreviewManifest(ctx context.Context) (*Manifest, error) {
+	version, err := s.previewVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.previewMutex.Lock()
+	defer s.previewMutex.Unlock()
+	if s.previewManifest != nil && s.previewManifest.Version == version {
+		return s.previewManifest, nil
+	}
+
+	manifest, err := s.versionManifest(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate preview manifest: %w", err)
+	}
+	s.previewManifest = manifest
+	return manifest, nil
+}
+
+// versionManifest generates the manifest of a stored version
+func (s *Service) versionManifest(ctx context.Context, version string) (*Manifest, error) {
+	stored, err := s.storage.ListFiles(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Files:       make([]File, 0, len(stored)),
+		Version:     version,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, storedFile := range stored {
+		// Files stored as blobs are described by their index without reading them
+		if storedFile.Hash != "" && storedFile.Path != SignatureFile {
+			mimeType := mime.TypeByExtension(filepath.Ext(storedFile.Path))
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			manifest.Files = append(manifest.Files, File{
+				Path:     storedFile.Path,
+				Size:     storedFile.Size,
+				Hash:     storedFile.Hash,
+				MimeType: mimeType,
+				ModTime:  storedFile.ModTime,
+				URL:      s.cdnBaseURL + HashedFilePath(storedFile.Hash, storedFile.Path),
+			})
+			continue
+		}
+		file, info, err := s.versionFile(ctx, version, storedFile.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", storedFile.Path, err)
+		}
+		if storedFile.Path == SignatureFile {
+			manifest.Signature, err = parseSignature(file)
+		}
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		info.URL = s.cdnBaseURL + HashedFilePath(info.Hash, info.Path)
+		manifest.Files = append(manifest.Files, *info)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	manifestHash, err := s.hashManifest(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash manifest: %w", err)
+	}
+	manifest.Hash = manifestHash
+	return manifest, nil
+}
@@ -0,0 +1,62 @@
+package appbundle
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewWorkflow(t *testing.T) {
+	ctx := context.Background()
+	service := newReplica(t, newMemoryStorage())
+
+	for _, bundle := range []string{"valid_bundle01.zip", "valid_bundle02.zip"} {
+		bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", bundle))
+		require.NoError(t, err)
+		_, err = service.PushBundle(ctx, bundleFile, "")
+		bundleFile.Close()
+		require.NoError(t, err)
+	}
+	require.NoError(t, service.SwitchVersion(ctx, "0001"))
+
+	_, err := service.PromotePreview(ctx)
+	assert.ErrorIs(t, err, ErrNoPreview)
+	assert.ErrorIs(t, service.StagePreview(ctx, "0009"), ErrVersionNotFound)
+
+	require.NoError(t, service.StagePreview(ctx, "0002"))
+	staged, err := service.GetStagedPreview(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0002", staged)
+
+	preview, err := service.GetPreviewManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0002", preview.Version)
+	require.NotEmpty(t, preview.Files)
+	assert.NotEmpty(t, preview.Hash)
+	file, info, err := service.GetPreviewFile(ctx, preview.Files[0].Path)
+	require.NoError(t, err)
+	_, err = io.ReadAll(file)
+	file.Close()
+	require.NoError(t, err)
+	assert.Equal(t, preview.Files[0].Hash, info.Hash)
+
+	// Stable devices stay on the current version until the preview is promoted
+	manifest, err := service.GetManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", manifest.Version)
+
+	promoted, err := service.PromotePreview(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0002", promoted)
+	staged, err = service.GetStagedPreview(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, staged)
+	versions, err := service.GetVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0002 *", "0001"}, versions)
+}
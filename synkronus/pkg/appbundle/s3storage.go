@@ -0,0 +1,273 @@
+package appbundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/objectstore"
+)
+
+// S3Storage keeps versions in an S3-compatible bucket such as Amazon S3 or MinIO. Blobs are
+// stored under <prefix>blobs/<hash>, the index of each version under
+// <prefix>indexes/<version>.json, and the current version name under <prefix>CURRENT_VERSION.
+// Versions stored before blobs were shared are read from <prefix>versions/<version>/<path>.
+type S3Storage struct {
+	client *objectstore.Client
+	prefix string
+}
+
+// NewS3Storage creates a storage keeping versions in the bucket under prefix
+func NewS3Storage(client *objectstore.Client, prefix string) *S3Storage {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Storage{client: client, prefix: prefix}
+}
+
+// versionPrefix returns the key prefix of the files of a version stored before blobs were shared
+func (s *S3Storage) versionPrefix(version string) string {
+	return s.prefix + "versions/" + version + "/"
+}
+
+// indexKey returns the key of the index of a version
+func (s *S3Storage) indexKey(version string) string {
+	return s.prefix + indexesDir + "/" + version + ".json"
+}
+
+// blobKey returns the key of a blob
+func (s *S3Storage) blobKey(hash string) string {
+	return s.prefix + blobsDir + "/" + hash
+}
+
+// ListVersions lists the version indexes and the version prefixes stored before them
+func (s *S3Storage) ListVersions(ctx context.Context) ([]string, error) {
+	indexes, _, err := s.client.ListObjects(ctx, s.prefix+indexesDir+"/", "/")
+	if err != nil {
+		return nil, err
+	}
+	_, prefixes, err := s.client.ListObjects(ctx, s.prefix+"versions/", "/")
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(indexes)+len(prefixes))
+	for _, index := range indexes {
+		if name, ok := strings.CutSuffix(path.Base(index.Key), ".json"); ok {
+			versions = append(versions, name)
+		}
+	}
+	for _, prefix := range prefixes {
+		versions = append(versions, path.Base(prefix))
+	}
+	return versions, nil
+}
+
+// ListFiles reads the index of a version, or lists the objects of an older version
+func (s *S3Storage) ListFiles(ctx context.Context, version string) ([]StoredFile, error) {
+	if !validStoragePath(version) {
+		return nil, fmt.Errorf("invalid version: %s", version)
+	}
+	files, err := s.readIndex(ctx, version)
+	if err != nil || files != nil {
+		return files, err
+	}
+	prefix := s.versionPrefix(version)
+	objects, _, err := s.client.ListObjects(ctx, prefix, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("version %s does not exist", version)
+	}
+
+	files = make([]StoredFile, 0, len(objects))
+	for _, object := range objects {
+		files = append(files, StoredFile{
+			Path:    strings.TrimPrefix(object.Key, prefix),
+			Size:    object.Size,
+			ModTime: object.LastModified,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// OpenFile downloads the blob of a file of a version, or the object of an older version
+func (s *S3Storage) OpenFile(ctx context.Context, version, filePath string) (io.ReadCloser, *StoredFile, error) {
+	if !validStoragePath(version) || !validStoragePath(filePath) {
+		return nil, nil, fmt.Errorf("invalid path: %s/%s", version, filePath)
+	}
+	files, err := s.readIndex(ctx, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if files != nil {
+		stored, ok := findFile(files, filePath)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+		}
+		body, _, err := s.client.GetObject(ctx, s.blobKey(stored.Hash))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to download blob of %s: %w", filePath, err)
+		}
+		return body, stored, nil
+	}
+
+	body, object, err := s.client.GetObject(ctx, s.versionPrefix(version)+filePath)
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+		}
+		return nil, nil, err
+	}
+	return body, &StoredFile{Path: filePath, Size: object.Size, ModTime: object.LastModified}, nil
+}
+
+// WriteBlob uploads a blob unless it is already in the bucket
+func (s *S3Storage) WriteBlob(ctx context.Context, hash string, data []byte) error {
+	if !validBlobHash(hash) {
+		return fmt.Errorf("invalid blob hash: %q", hash)
+	}
+	objects, _, err := s.client.ListObjects(ctx, s.blobKey(hash), "")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if object.Key == s.blobKey(hash) {
+			return nil
+		}
+	}
+	return s.client.PutObject(ctx, s.blobKey(hash), data, "application/octet-stream")
+}
+
+// WriteVersion uploads the index of a version
+func (s *S3Storage) WriteVersion(ctx context.Context, version string, files []StoredFile) error {
+	if err := validIndex(version, files); err != nil {
+		return err
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return fmt.Errorf("failed to encode index of version %s: %w", version, err)
+	}
+	return s.client.PutObject(ctx, s.indexKey(version), data, "application/json")
+}
+
+// DeleteVersion deletes the index or the objects of a version and then its orphaned blobs
+func (s *S3Storage) DeleteVersion(ctx context.Context, version string) error {
+	if !validStoragePath(version) {
+		return fmt.Errorf("invalid version: %s", version)
+	}
+	files, err := s.readIndex(ctx, version)
+	if err != nil {
+		return err
+	}
+	keys := []string{}
+	if files != nil {
+		keys = append(keys, s.indexKey(version))
+	}
+	objects, _, err := s.client.ListObjects(ctx, s.versionPrefix(version), "")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	for _, key := range keys {
+		if err := s.client.DeleteObject(ctx, key); err != nil {
+			return fmt.Errorf("failed to remove version %s: %w", version, err)
+		}
+	}
+
+	orphans, err := orphanedBlobs(ctx, s, files)
+	if err != nil {
+		return fmt.Errorf("failed to find blobs of version %s: %w", version, err)
+	}
+	for _, hash := range orphans {
+		if err := s.client.DeleteObject(ctx, s.blobKey(hash)); err != nil {
+			return fmt.Errorf("failed to remove blob %s: %w", hash, err)
+		}
+	}
+	return nil
+}
+
+// readIndex reads the index of a version, returning nil when the version has none
+func (s *S3Storage) readIndex(ctx context.Context, version string) ([]StoredFile, error) {
+	body, _, err := s.client.GetObject(ctx, s.indexKey(version))
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer body.Close()
+
+	files := []StoredFile{}
+	if err := json.NewDecoder(body).Decode(&files); err != nil {
+		return nil, fmt.Errorf("invalid index of version %s: %w", version, err)
+	}
+	return files, nil
+}
+
+// CurrentVersion reads the CURRENT_VERSION object
+func (s *S3Storage) CurrentVersion(ctx context.Context) (string, error) {
+	version, err := s.readPointer(ctx, currentVersionFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read current version: %w", err)
+	}
+	return version, nil
+}
+
+// SetCurrentVersion replaces the CURRENT_VERSION object
+func (s *S3Storage) SetCurrentVersion(ctx context.Context, version string) error {
+	if err := s.client.PutObject(ctx, s.prefix+currentVersionFile, []byte(version), "text/plain"); err != nil {
+		return fmt.Errorf("failed to update current version: %w", err)
+	}
+	return nil
+}
+
+// PreviewVersion reads the PREVIEW_VERSION object
+func (s *S3Storage) PreviewVersion(ctx context.Context) (string, error) {
+	version, err := s.readPointer(ctx, previewVersionFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read preview version: %w", err)
+	}
+	return version, nil
+}
+
+// SetPreviewVersion replaces the PREVIEW_VERSION object, deleting it to clear the preview
+func (s *S3Storage) SetPreviewVersion(ctx context.Context, version string) error {
+	var err error
+	if version == "" {
+		err = s.client.DeleteObject(ctx, s.prefix+previewVersionFile)
+	} else {
+		err = s.client.PutObject(ctx, s.prefix+previewVersionFile, []byte(version), "text/plain")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update preview version: %w", err)
+	}
+	return nil
+}
+
+// readPointer reads an object naming a version, returning "" when it does not exist
+func (s *S3Storage) readPointer(ctx context.Context, name string) (string, error) {
+	body, _, err := s.client.GetObject(ctx, s.prefix+name)
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -1,14 +1,18 @@
 package appbundle
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"mime"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -22,13 +26,28 @@ import (
 // Service provides app bundle functionality
 type Service struct {
 	bundlePath     string
-	versionsPath   string
+	storage        Storage
 	currentVersion string
 	maxVersions    int
+	cdnBaseURL     string
+	syncInterval   time.Duration
+	syncedAt       time.Time
+	onSwitch       func(version string)
+	signingKeys    []ed25519.PublicKey
+	includes       IncludeResolver
+	limits         Limits
 	log            *logger.Logger
 	manifest       *Manifest
 	versionMutex   sync.Mutex
 
+	// Manifest of the preview version, kept until another version is previewed
+	previewMutex    sync.Mutex
+	previewManifest *Manifest
+
+	// Manifests of the stored versions, looked up by hash for differential downloads
+	diffMutex        sync.Mutex
+	versionManifests map[string]*Manifest
+
 	// Core field tracking
 	coreFieldMutex  sync.RWMutex
 	coreFieldHashes map[string]string // formName -> hash
@@ -38,10 +57,29 @@ type Service struct {
 type Config struct {
 	// BundlePath is the path to the app bundle directory
 	BundlePath string
-	// VersionsPath is the path to store versioned app bundles
+	// VersionsPath is the path to store versioned app bundles when Storage is nil
 	VersionsPath string
+	// Storage keeps the versioned app bundles; nil keeps them on local disk under VersionsPath
+	Storage Storage
+	// SyncInterval is how often the service checks storage for a version switched by another
+	// replica; zero only checks at startup
+	SyncInterval time.Duration
 	// MaxVersions is the maximum number of versions to keep
 	MaxVersions int
+	// CDNBaseURL is prepended to the content-hashed file URLs in the manifest; empty keeps them
+	// relative to the API root
+	CDNBaseURL string
+	// OnSwitch, when set, is called after this service switches the current version, so that
+	// other replicas can be told to call SyncCurrentVersion instead of waiting for SyncInterval
+	OnSwitch func(version string)
+	// SigningKeys are the Ed25519 public keys pushed bundles are verified against; when set,
+	// unsigned bundles are refused
+	SigningKeys []ed25519.PublicKey
+	// Includes resolves the shared form components forms include with x-include; nil refuses
+	// bundles whose forms include components
+	Includes IncludeResolver
+	// Limits bound the size of pushed bundles
+	Limits Limits
 }
 
 // DefaultConfig returns a default configuration
@@ -50,15 +88,33 @@ func DefaultConfig() Config {
 		BundlePath:   "./app-bundle",
 		VersionsPath: "./app-bundle-versions",
 		MaxVersions:  5,
+		SyncInterval: 30 * time.Second,
+		Limits:       DefaultLimits(),
+	}
+}
+
+// VersionStorage returns the storage of the versions of a configuration: Storage, or the
+// versions directory on local disk
+func (c Config) VersionStorage() Storage {
+	if c.Storage != nil {
+		return c.Storage
 	}
+	return NewLocalStorage(c.VersionsPath)
 }
 
 // NewService creates a new app bundle service
 func NewService(config Config, log *logger.Logger) *Service {
+	storage := config.VersionStorage()
 	return &Service{
 		bundlePath:     config.BundlePath,
-		versionsPath:   config.VersionsPath,
+		storage:        storage,
 		maxVersions:    config.MaxVersions,
+		cdnBaseURL:     strings.TrimSuffix(config.CDNBaseURL, "/"),
+		syncInterval:   config.SyncInterval,
+		onSwitch:       config.OnSwitch,
+		signingKeys:    config.SigningKeys,
+		includes:       config.Includes,
+		limits:         config.Limits,
 		currentVersion: "current", // Default version name
 		log:            log,
 	}
@@ -74,20 +130,17 @@ func (s *Service) Initialize(ctx context.Context) error {
 		}
 	}
 
-	// Ensure the versions directory exists
-	if _, err := os.Stat(s.versionsPath); os.IsNotExist(err) {
-		s.log.Info("Creating app bundle versions directory", "path", s.versionsPath)
-		if err := os.MkdirAll(s.versionsPath, 0755); err != nil {
-			return fmt.Errorf("failed to create app bundle versions directory: %w", err)
-		}
-	}
-
 	// Check if we have versions but no current version set
 	if err := s.ensureCurrentVersionSet(ctx); err != nil {
 		s.log.Warn("Failed to ensure current version is set", "error", err)
 		// Continue anyway, this is not critical for startup
 	}
 
+	// Serve the current version from storage, which outlives this container
+	if err := s.syncCurrentVersion(ctx, true); err != nil {
+		return fmt.Errorf("failed to load current app bundle version: %w", err)
+	}
+
 	// Generate the initial manifest
 	if _, err := s.GetManifest(ctx); err != nil {
 		return fmt.Errorf("failed to generate initial manifest: %w", err)
@@ -104,6 +157,11 @@ func (s *Service) Initialize(ctx context.Context) error {
 
 // GetManifest retrieves the current app bundle manifest
 func (s *Service) GetManifest(ctx context.Context) (*Manifest, error) {
+	// Pick up a version switched by another replica
+	if err := s.syncCurrentVersion(ctx, false); err != nil {
+		s.log.Warn("Failed to check storage for the current app bundle version", "error", err)
+	}
+
 	// If we already have a manifest, return it
 	if s.manifest != nil {
 		return s.manifest, nil
@@ -189,53 +247,56 @@ func (s *Service) GetLatestVersionFile(ctx context.Context, path string) (io.Rea
 
 	// Get the latest version (remove asterisk if present)
 	latestVersion := strings.TrimSuffix(versions[0], " *")
-	latestPath := filepath.Join(s.versionsPath, latestVersion, path)
-
-	// Get file info
-	fileInfo, err := os.Stat(latestPath)
-	if err != nil {
-		return nil, nil, err
-	}
+	return s.versionFile(ctx, latestVersion, path)
+}
 
-	// Ensure it's a file, not a directory
-	if fileInfo.IsDir() {
-		return nil, nil, fmt.Errorf("path is a directory: %s", path)
+// GetVersionFile retrieves a file of a stored version, returning ErrFileNotFound when the version
+// has no such file
+func (s *Service) GetVersionFile(ctx context.Context, version, path string) (io.ReadCloser, *File, error) {
+	file, info, err := s.versionFile(ctx, version, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, path)
 	}
+	return file, info, err
+}
 
-	// Open the file
-	file, err := os.Open(latestPath)
+// versionFile reads a file of a stored version, returning os.ErrNotExist when it does not exist
+func (s *Service) versionFile(ctx context.Context, version, path string) (io.ReadCloser, *File, error) {
+	file, stored, err := s.storage.OpenFile(ctx, version, path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+		if isNotFound(err) {
+			return nil, nil, os.ErrNotExist
+		}
+		return nil, nil, err
 	}
+	defer file.Close()
 
-	// Get file hash
-	hash, err := s.hashFile(latestPath)
+	// Read the file once to both hash and serve it
+	data, err := io.ReadAll(file)
 	if err != nil {
-		file.Close()
-		return nil, nil, fmt.Errorf("failed to hash file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	hash := sha256.Sum256(data)
 
 	// Determine MIME type
-	mimeType := mime.TypeByExtension(filepath.Ext(latestPath))
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
 	if mimeType == "" {
 		mimeType = "application/octet-stream"
 	}
 
 	fileMetadata := &File{
 		Path:     path,
-		Size:     fileInfo.Size(),
-		Hash:     hash,
+		Size:     int64(len(data)),
+		Hash:     hex.EncodeToString(hash[:]),
 		MimeType: mimeType,
-		ModTime:  fileInfo.ModTime(),
+		ModTime:  stored.ModTime,
 	}
 
-	return file, fileMetadata, nil
+	return io.NopCloser(bytes.NewReader(data)), fileMetadata, nil
 }
 
 // GetFileHash returns the hash for a specific file, optionally from the latest version
 func (s *Service) GetFileHash(ctx context.Context, path string, useLatest bool) (string, error) {
-	var filePath string
-
 	if useLatest {
 		// Get all versions
 		versions, err := s.GetVersions(ctx)
@@ -249,18 +310,31 @@ func (s *Service) GetFileHash(ctx context.Context, path string, useLatest bool)
 
 		// Get the latest version (remove asterisk if present)
 		latestVersion := strings.TrimSuffix(versions[0], " *")
-		filePath = filepath.Join(s.versionsPath, latestVersion, path)
-	} else {
-		// Clean and validate the path
-		cleanPath := filepath.Clean(path)
-		if strings.Contains(cleanPath, "..") {
-			return "", fmt.Errorf("invalid path: %s", path)
+		file, stored, err := s.storage.OpenFile(ctx, latestVersion, path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		// Files stored as blobs are named by their hash
+		if stored.Hash != "" {
+			return stored.Hash, nil
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
 		}
-		filePath = filepath.Join(s.bundlePath, cleanPath)
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	// Clean and validate the path
+	cleanPath := filepath.Clean(path)
+	if strings.Contains(cleanPath, "..") {
+		return "", fmt.Errorf("invalid path: %s", path)
 	}
 
 	// Hash the file
-	return s.hashFile(filePath)
+	return s.hashFile(filepath.Join(s.bundlePath, cleanPath))
 }
 
 // generateManifest generates a new manifest for the app bundle
@@ -316,6 +390,7 @@ func (s *Service) generateManifest() (*Manifest, error) {
 			Hash:     hash,
 			MimeType: mimeType,
 			ModTime:  fileInfo.ModTime(),
+			URL:      s.cdnBaseURL + HashedFilePath(hash, relPath),
 		})
 
 		return nil
@@ -330,6 +405,15 @@ func (s *Service) generateManifest() (*Manifest, error) {
 		return manifest.Files[i].Path < manifest.Files[j].Path
 	})
 
+	// Expose the signature of a signed version
+	if signatureFile, err := os.Open(filepath.Join(s.bundlePath, SignatureFile)); err == nil {
+		manifest.Signature, err = parseSignature(signatureFile)
+		signatureFile.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate a hash for the entire manifest
 	manifestHash, err := s.hashManifest(manifest)
 	if err != nil {
@@ -340,6 +424,16 @@ func (s *Service) generateManifest() (*Manifest, error) {
 	return manifest, nil
 }
 
+// HashedFilePath returns the content-hashed download path of a bundle file. Each path segment
+// is escaped so that relative references between bundle files keep working.
+func HashedFilePath(hash, path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/app-bundle/files/" + hash + "/" + strings.Join(segments, "/")
+}
+
 // hashFile generates a SHA-256 hash for a file
 func (s *Service) hashFile(path string) (string, error) {
 	file, err := os.Open(path)
@@ -391,7 +485,8 @@ func (s *Service) loadCoreFieldHashes() error {
 	return nil
 }
 
-// hashManifest generates a SHA-256 hash for the manifest
+// hashManifest generates a SHA-256 hash for the manifest. It covers the files and the version
+// but not the generation time, so a device's manifest hash identifies the stored version it has.
 func (s *Service) hashManifest(manifest *Manifest) (string, error) {
 	// Create a string representation of the manifest files
 	var sb strings.Builder
@@ -401,7 +496,6 @@ func (s *Service) hashManifest(manifest *Manifest) (string, error) {
 		sb.WriteString(fmt.Sprintf("%d", file.Size))
 	}
 	sb.WriteString(manifest.Version)
-	sb.WriteString(manifest.GeneratedAt)
 
 	// Hash the string
 	hash := sha256.New()
@@ -412,29 +506,19 @@ func (s *Service) hashManifest(manifest *Manifest) (string, error) {
 // ensureCurrentVersionSet checks if a current version is set, and if not,
 // sets the latest available version as current
 func (s *Service) ensureCurrentVersionSet(ctx context.Context) error {
-	// Check if CURRENT_VERSION file exists
-	versionFile := filepath.Join(s.versionsPath, "CURRENT_VERSION")
-	if _, err := os.Stat(versionFile); err == nil {
-		// File exists, current version is already set
+	current, err := s.storage.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if current != "" {
+		// Current version is already set
 		return nil
 	}
 
 	// Read available versions
-	entries, err := os.ReadDir(s.versionsPath)
+	versions, err := s.storage.ListVersions(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// No versions directory yet, nothing to do
-			return nil
-		}
-		return fmt.Errorf("failed to read versions directory: %w", err)
-	}
-
-	// Collect version directories
-	var versions []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			versions = append(versions, entry.Name())
-		}
+		return err
 	}
 
 	// If no versions exist, nothing to do
@@ -451,17 +535,49 @@ func (s *Service) ensureCurrentVersionSet(ctx context.Context) error {
 	latestVersion := versions[0]
 	s.log.Info("Setting initial current version", "version", latestVersion)
 
-	// Set the latest version as current
-	if err := os.WriteFile(versionFile, []byte(latestVersion), 0644); err != nil {
-		return fmt.Errorf("failed to write current version file: %w", err)
+	// Set the latest version as current; syncCurrentVersion copies it to the bundle directory
+	return s.storage.SetCurrentVersion(ctx, latestVersion)
+}
+
+// syncCurrentVersion copies the current version from storage to the bundle directory when the
+// directory holds another version, as after a restart or a switch on another replica. Unless
+// forced, storage is checked at most once per sync interval.
+func (s *Service) syncCurrentVersion(ctx context.Context, force bool) error {
+	s.versionMutex.Lock()
+	defer s.versionMutex.Unlock()
+
+	if !force && (s.syncInterval <= 0 || time.Since(s.syncedAt) < s.syncInterval) {
+		return nil
 	}
+	s.syncedAt = time.Now()
 
-	// Update in-memory state
-	s.currentVersion = latestVersion
+	version, err := s.storedCurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version == "" || version == s.currentVersion {
+		return nil
+	}
+
+	if err := s.installVersion(ctx, version); err != nil {
+		return err
+	}
+	s.currentVersion = version
+	s.manifest = nil // Force regeneration of manifest
+	if err := s.loadCoreFieldHashes(); err != nil {
+		s.log.Warn("Failed to load core field hashes", "error", err)
+	}
 
+	s.log.Info("Loaded app bundle version from storage", "version", version)
 	return nil
 }
 
+// SyncCurrentVersion checks storage for a version switched by another replica now, rather than
+// within the sync interval
+func (s *Service) SyncCurrentVersion(ctx context.Context) error {
+	return s.syncCurrentVersion(ctx, true)
+}
+
 // RefreshManifest forces a refresh of the manifest
 func (s *Service) RefreshManifest() error {
 	manifest, err := s.generateManifest()
@@ -0,0 +1,177 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SignatureFile holds the signature of a signed version. Like APP_INFO.json it is written by
+// the server and not covered by the signature.
+const SignatureFile = "SIGNATURE.json"
+
+// SignatureAlgorithm is the only supported signature algorithm
+const SignatureAlgorithm = "ed25519"
+
+var (
+	// ErrSignatureRequired is returned when an unsigned bundle is pushed while signing keys are configured
+	ErrSignatureRequired = errors.New("app bundle signature required")
+	// ErrInvalidSignature is returned when a bundle signature does not verify against any signing key
+	ErrInvalidSignature = errors.New("invalid app bundle signature")
+)
+
+// Signature is an Ed25519 signature of the content digest of a bundle, exposed in the manifest
+// so devices can check the files they download against a key they trust
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	// PublicKey is the base64 signing key the signature verified against
+	PublicKey string `json:"publicKey"`
+	// Digest is the hex content digest that was signed; see ContentDigest
+	Digest string `json:"digest"`
+	// Signature is the base64 signature of the raw digest bytes
+	Signature string `json:"signature"`
+}
+
+// ContentDigest returns the digest a bundle signature covers, given the hex SHA-256 hash of
+// every file by path. It is the SHA-256 of one "<hash>  <path>\n" line per file, sorted by path,
+// leaving out APP_INFO.json and SIGNATURE.json, so it can be computed both from a bundle ZIP and
+// from the files listed in a manifest.
+func ContentDigest(hashes map[string]string) []byte {
+	paths := make([]string, 0, len(hashes))
+	for path := range hashes {
+		if path == "APP_INFO.json" || path == SignatureFile {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	digest := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(digest, "%s  %s\n", hashes[path], path)
+	}
+	return digest.Sum(nil)
+}
+
+// ParseSigningKeys parses comma separated base64 Ed25519 public keys
+func ParseSigningKeys(value string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, text := range strings.Split(value, ",") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(text)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("signing key %q is not a base64 Ed25519 public key", text)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	return keys, nil
+}
+
+// zipContentDigest computes the content digest of the files a push extracts from a bundle
+func zipContentDigest(zipReader *zip.Reader) ([]byte, error) {
+	hashes := make(map[string]string, len(zipReader.File))
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() || strings.Contains(file.Name, "..") {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s from zip: %w", file.Name, err)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, src)
+		src.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s from zip: %w", file.Name, err)
+		}
+		hashes[filepath.ToSlash(filepath.Clean(file.Name))] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return ContentDigest(hashes), nil
+}
+
+// verifySignature checks the signature of a pushed bundle against the signing keys. The
+// signature is the base64 signature sent with the push or, when none is sent, the one in the
+// bundle's SIGNATURE.json, as in bundles exported for backup. It returns nil for unsigned
+// bundles when no signing keys are configured.
+func (s *Service) verifySignature(zipReader *zip.Reader, signature string) (*Signature, error) {
+	if signature == "" {
+		embedded, err := embeddedSignature(zipReader)
+		if err != nil {
+			return nil, err
+		}
+		// A signature exported by a server with signing keys cannot be checked without them
+		if embedded != "" && len(s.signingKeys) > 0 {
+			signature = embedded
+		}
+	}
+	if signature == "" {
+		if len(s.signingKeys) > 0 {
+			return nil, ErrSignatureRequired
+		}
+		return nil, nil
+	}
+	if len(s.signingKeys) == 0 {
+		return nil, fmt.Errorf("%w: the server has no signing keys configured", ErrInvalidSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: not a base64 Ed25519 signature", ErrInvalidSignature)
+	}
+	digest, err := zipContentDigest(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range s.signingKeys {
+		if ed25519.Verify(key, digest, sig) {
+			return &Signature{
+				Algorithm: SignatureAlgorithm,
+				PublicKey: base64.StdEncoding.EncodeToString(key),
+				Digest:    hex.EncodeToString(digest),
+				Signature: signature,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: the signature does not match the bundle or any signing key", ErrInvalidSignature)
+}
+
+// embeddedSignature returns the signature of the bundle's SIGNATURE.json, if it has one
+func embeddedSignature(zipReader *zip.Reader) (string, error) {
+	for _, file := range zipReader.File {
+		if file.Name != SignatureFile {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s from zip: %w", SignatureFile, err)
+		}
+		defer src.Close()
+		var signature Signature
+		if err := json.NewDecoder(src).Decode(&signature); err != nil {
+			return "", fmt.Errorf("%w: invalid %s", ErrInvalidSignature, SignatureFile)
+		}
+		return signature.Signature, nil
+	}
+	return "", nil
+}
+
+// parseSignature decodes a stored SIGNATURE.json
+func parseSignature(r io.Reader) (*Signature, error) {
+	var signature Signature
+	if err := json.NewDecoder(r).Decode(&signature); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SignatureFile, err)
+	}
+	return &signature, nil
+}
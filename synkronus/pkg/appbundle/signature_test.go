@@ -0,0 +1,96 @@
+package appbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentDigest(t *testing.T) {
+	// The CLI computes the same digest; keep both in step when changing the format
+	digest := ContentDigest(map[string]string{
+		"forms/survey/schema.json": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		"app/index.html":           "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"APP_INFO.json":            "ignored",
+		SignatureFile:              "ignored",
+	})
+	assert.Equal(t, "945c6eac7550cedd16bd38c8e64668dbc415f58936a298dcdc29e61e1817bba6", hex.EncodeToString(digest))
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString(public)
+
+	keys, err := ParseSigningKeys(" " + encoded + ", ")
+	require.NoError(t, err)
+	assert.Equal(t, []ed25519.PublicKey{public}, keys)
+
+	_, err = ParseSigningKeys(encoded + ",c2hvcnQ=")
+	assert.ErrorContains(t, err, "c2hvcnQ=")
+}
+
+func TestSignedPush(t *testing.T) {
+	ctx := context.Background()
+	bundle, err := os.ReadFile(filepath.Join("..", "..", "testdata", "bundles", "valid_bundle01.zip"))
+	require.NoError(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	require.NoError(t, err)
+	digest, err := zipContentDigest(zipReader)
+	require.NoError(t, err)
+
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, digest))
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	newSigned := func() *Service {
+		service := newReplica(t, newMemoryStorage())
+		service.signingKeys = []ed25519.PublicKey{public}
+		return service
+	}
+	service := newSigned()
+
+	_, err = service.PushBundle(ctx, bytes.NewReader(bundle), "")
+	assert.ErrorIs(t, err, ErrSignatureRequired)
+	_, err = service.PushBundle(ctx, bytes.NewReader(bundle), base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, digest)))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = service.PushBundle(ctx, bytes.NewReader(bundle), "not base64")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	manifest, err := service.PushBundle(ctx, bytes.NewReader(bundle), signature)
+	require.NoError(t, err)
+	require.NotNil(t, manifest.Signature)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(public), manifest.Signature.PublicKey)
+	assert.Equal(t, hex.EncodeToString(digest), manifest.Signature.Digest)
+
+	// The current and preview manifests expose the signature
+	require.NoError(t, service.SwitchVersion(ctx, manifest.Version))
+	current, err := service.GetManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Signature, current.Signature)
+	preview, err := service.GetPreviewManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Signature, preview.Signature)
+
+	// An exported version carries its signature to another server with the same key
+	var exported bytes.Buffer
+	require.NoError(t, service.ExportVersion(ctx, manifest.Version, &exported))
+	restored, err := newSigned().PushBundle(ctx, &exported, "")
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Signature, restored.Signature)
+
+	// Without signing keys signatures cannot be checked
+	_, err = newReplica(t, newMemoryStorage()).PushBundle(ctx, bytes.NewReader(bundle), signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
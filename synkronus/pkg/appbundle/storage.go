@@ -0,0 +1,463 @@
+package appbundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StoredFile describes a file of a stored version
+type StoredFile struct {
+	Path string `json:"path"`
+	// Hash is the hex SHA-256 of the content, which the file's blob is stored under; it is empty
+	// for files of versions stored before blobs were shared between versions
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Storage keeps the app bundle versions and the name of the current version. File content is
+// stored once as a blob named by its SHA-256 hash, and a version is an index of paths to blobs,
+// so versions that differ in one form share the rest of their files. The service copies the
+// current version to its local bundle directory to serve it, so a storage shared by several
+// replicas keeps them on the same version.
+type Storage interface {
+	// ListVersions returns the names of the stored versions in no particular order
+	ListVersions(ctx context.Context) ([]string, error)
+
+	// ListFiles returns the files of a version with slash separated paths
+	ListFiles(ctx context.Context, version string) ([]StoredFile, error)
+
+	// OpenFile opens a file of a version, returning ErrFileNotFound when it does not exist
+	OpenFile(ctx context.Context, version, path string) (io.ReadCloser, *StoredFile, error)
+
+	// WriteBlob stores file content under its hex SHA-256 hash; content already stored for
+	// another version is not stored again
+	WriteBlob(ctx context.Context, hash string, data []byte) error
+
+	// WriteVersion creates a version from files whose blobs are already written. The version
+	// is listed only once its index is complete.
+	WriteVersion(ctx context.Context, version string, files []StoredFile) error
+
+	// DeleteVersion removes a version and the blobs no other version refers to
+	DeleteVersion(ctx context.Context, version string) error
+
+	// CurrentVersion returns the name of the current version, or "" when none is set
+	CurrentVersion(ctx context.Context) (string, error)
+
+	// SetCurrentVersion records the name of the current version
+	SetCurrentVersion(ctx context.Context, version string) error
+
+	// PreviewVersion returns the name of the version staged for preview, or "" when none is staged
+	PreviewVersion(ctx context.Context) (string, error)
+
+	// SetPreviewVersion records the name of the version staged for preview; "" clears it
+	SetPreviewVersion(ctx context.Context, version string) error
+}
+
+// currentVersionFile holds the name of the current version
+const currentVersionFile = "CURRENT_VERSION"
+
+// previewVersionFile holds the name of the version staged for preview
+const previewVersionFile = "PREVIEW_VERSION"
+
+// blobsDir and indexesDir hold the blobs and the version indexes. Versions stored before blobs
+// were shared are directories of files next to them, which are read as before until they are
+// cleaned up.
+const (
+	blobsDir   = "blobs"
+	indexesDir = "indexes"
+)
+
+// blobHash returns the hash a blob of content is stored under
+func blobHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// validBlobHash reports whether hash is a hex SHA-256 hash
+func validBlobHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
+
+// validIndex checks the files of a version before its index is written
+func validIndex(version string, files []StoredFile) error {
+	if !validStoragePath(version) || strings.Contains(version, "/") {
+		return fmt.Errorf("invalid version: %s", version)
+	}
+	for _, file := range files {
+		if !validStoragePath(file.Path) {
+			return fmt.Errorf("invalid path: %s/%s", version, file.Path)
+		}
+		if !validBlobHash(file.Hash) {
+			return fmt.Errorf("invalid hash of %s: %q", file.Path, file.Hash)
+		}
+	}
+	return nil
+}
+
+// findFile returns the file of an index with a path
+func findFile(files []StoredFile, path string) (*StoredFile, bool) {
+	for i := range files {
+		if files[i].Path == path {
+			return &files[i], true
+		}
+	}
+	return nil, false
+}
+
+// orphanedBlobs returns the blobs of a deleted version that no remaining version refers to
+func orphanedBlobs(ctx context.Context, storage Storage, deleted []StoredFile) ([]string, error) {
+	versions, err := storage.ListVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, version := range versions {
+		files, err := storage.ListFiles(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			referenced[file.Hash] = true
+		}
+	}
+
+	var orphans []string
+	for _, file := range deleted {
+		if file.Hash != "" && !referenced[file.Hash] {
+			referenced[file.Hash] = true
+			orphans = append(orphans, file.Hash)
+		}
+	}
+	return orphans, nil
+}
+
+// validStoragePath reports whether a version name or file path stays inside its parent
+func validStoragePath(path string) bool {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return false
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(path), "/") {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalStorage keeps versions on local disk: blobs under blobs/, an index per version under
+// indexes/ and a CURRENT_VERSION file naming the current version
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a storage keeping versions under root
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+// ListVersions returns the indexed versions and the version directories stored before them
+func (l *LocalStorage) ListVersions(ctx context.Context) ([]string, error) {
+	versions := []string{}
+	indexes, err := os.ReadDir(filepath.Join(l.root, indexesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read versions directory: %w", err)
+	}
+	for _, entry := range indexes {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			versions = append(versions, name)
+		}
+	}
+
+	entries, err := os.ReadDir(l.root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return versions, nil
+		}
+		return nil, fmt.Errorf("failed to read versions directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != blobsDir && entry.Name() != indexesDir {
+			versions = append(versions, entry.Name())
+		}
+	}
+	return versions, nil
+}
+
+// ListFiles reads the index of a version, or walks the directory of an older version
+func (l *LocalStorage) ListFiles(ctx context.Context, version string) ([]StoredFile, error) {
+	if !validStoragePath(version) {
+		return nil, fmt.Errorf("invalid version: %s", version)
+	}
+	files, err := l.readIndex(version)
+	if err != nil || files != nil {
+		return files, err
+	}
+	versionPath := filepath.Join(l.root, version)
+
+	err = filepath.WalkDir(versionPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(versionPath, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to get file info: %w", err)
+		}
+		files = append(files, StoredFile{Path: filepath.ToSlash(relPath), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("version %s does not exist", version)
+		}
+		return nil, fmt.Errorf("failed to walk version directory: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// OpenFile opens the blob of a file of a version, or the file of an older version directory
+func (l *LocalStorage) OpenFile(ctx context.Context, version, path string) (io.ReadCloser, *StoredFile, error) {
+	if !validStoragePath(version) || !validStoragePath(path) {
+		return nil, nil, fmt.Errorf("invalid path: %s/%s", version, path)
+	}
+	files, err := l.readIndex(version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if files != nil {
+		stored, ok := findFile(files, path)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, path)
+		}
+		file, err := os.Open(l.blobPath(stored.Hash))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open blob of %s: %w", path, err)
+		}
+		return file, stored, nil
+	}
+	fullPath := filepath.Join(l.root, version, filepath.FromSlash(path))
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, path)
+		}
+		return nil, nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, nil, fmt.Errorf("path is a directory: %s", path)
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, &StoredFile{Path: path, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// WriteBlob writes a blob unless it is already stored
+func (l *LocalStorage) WriteBlob(ctx context.Context, hash string, data []byte) error {
+	if !validBlobHash(hash) {
+		return fmt.Errorf("invalid blob hash: %q", hash)
+	}
+	blobPath := l.blobPath(hash)
+	if _, err := os.Stat(blobPath); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := writeAtomic(blobPath, data); err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", hash, err)
+	}
+	return nil
+}
+
+// WriteVersion writes the index of a version atomically
+func (l *LocalStorage) WriteVersion(ctx context.Context, version string, files []StoredFile) error {
+	if err := validIndex(version, files); err != nil {
+		return err
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return fmt.Errorf("failed to encode index of version %s: %w", version, err)
+	}
+	if err := os.MkdirAll(filepath.Join(l.root, indexesDir), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	if err := writeAtomic(l.indexPath(version), data); err != nil {
+		return fmt.Errorf("failed to write index of version %s: %w", version, err)
+	}
+	return nil
+}
+
+// DeleteVersion removes the index or directory of a version and then its orphaned blobs
+func (l *LocalStorage) DeleteVersion(ctx context.Context, version string) error {
+	if !validStoragePath(version) {
+		return fmt.Errorf("invalid version: %s", version)
+	}
+	files, err := l.readIndex(version)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(l.indexPath(version)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove version %s: %w", version, err)
+	}
+	if err := os.RemoveAll(filepath.Join(l.root, version)); err != nil {
+		return fmt.Errorf("failed to remove version %s: %w", version, err)
+	}
+
+	orphans, err := orphanedBlobs(ctx, l, files)
+	if err != nil {
+		return fmt.Errorf("failed to find blobs of version %s: %w", version, err)
+	}
+	for _, hash := range orphans {
+		if err := os.Remove(l.blobPath(hash)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove blob %s: %w", hash, err)
+		}
+	}
+	return nil
+}
+
+// blobPath returns the path of a blob, spread over directories by its first two hex digits
+func (l *LocalStorage) blobPath(hash string) string {
+	return filepath.Join(l.root, blobsDir, hash[:2], hash)
+}
+
+// indexPath returns the path of the index of a version
+func (l *LocalStorage) indexPath(version string) string {
+	return filepath.Join(l.root, indexesDir, version+".json")
+}
+
+// readIndex reads the index of a version, returning nil when the version has none
+func (l *LocalStorage) readIndex(version string) ([]StoredFile, error) {
+	data, err := os.ReadFile(l.indexPath(version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read index of version %s: %w", version, err)
+	}
+	files := []StoredFile{}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("invalid index of version %s: %w", version, err)
+	}
+	return files, nil
+}
+
+// CurrentVersion reads the CURRENT_VERSION file
+func (l *LocalStorage) CurrentVersion(ctx context.Context) (string, error) {
+	version, err := l.readPointer(currentVersionFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read current version: %w", err)
+	}
+	return version, nil
+}
+
+// SetCurrentVersion replaces the CURRENT_VERSION file atomically
+func (l *LocalStorage) SetCurrentVersion(ctx context.Context, version string) error {
+	if err := l.writePointer(currentVersionFile, version); err != nil {
+		return fmt.Errorf("failed to update current version: %w", err)
+	}
+	return nil
+}
+
+// PreviewVersion reads the PREVIEW_VERSION file
+func (l *LocalStorage) PreviewVersion(ctx context.Context) (string, error) {
+	version, err := l.readPointer(previewVersionFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read preview version: %w", err)
+	}
+	return version, nil
+}
+
+// SetPreviewVersion replaces the PREVIEW_VERSION file atomically, removing it to clear the preview
+func (l *LocalStorage) SetPreviewVersion(ctx context.Context, version string) error {
+	if version == "" {
+		if err := os.Remove(filepath.Join(l.root, previewVersionFile)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear preview version: %w", err)
+		}
+		return nil
+	}
+	if err := l.writePointer(previewVersionFile, version); err != nil {
+		return fmt.Errorf("failed to update preview version: %w", err)
+	}
+	return nil
+}
+
+// readPointer reads a file naming a version, returning "" when it does not exist
+func (l *LocalStorage) readPointer(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(l.root, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writePointer replaces a file naming a version atomically
+func (l *LocalStorage) writePointer(name, version string) error {
+	if err := os.MkdirAll(l.root, 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+	if err := writeAtomic(filepath.Join(l.root, name), []byte(version)); err != nil {
+		return fmt.Errorf("failed to write version file: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic replaces a file by writing a temporary file first and renaming it, so readers
+// never see part of it
+func writeAtomic(path string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFile.Name(), 0644)
+	}
+	if err == nil {
+		// Atomic rename (works across all platforms)
+		err = os.Rename(tempFile.Name(), path)
+	}
+	if err != nil {
+		// Clean up temp file if rename fails
+		os.Remove(tempFile.Name())
+	}
+	return err
+}
+
+// isNotFound reports whether err means a stored file does not exist
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrFileNotFound)
+}
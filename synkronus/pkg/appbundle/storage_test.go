@@ -0,0 +1,308 @@
+package appbundle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage is a Storage shared by services standing in for replicas
+type memoryStorage struct {
+	mu       sync.Mutex
+	blobs    map[string][]byte
+	versions map[string][]StoredFile
+	current  string
+	preview  string
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{blobs: map[string][]byte{}, versions: map[string][]StoredFile{}}
+}
+
+func (m *memoryStorage) ListVersions(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := []string{}
+	for version := range m.versions {
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (m *memoryStorage) ListFiles(ctx context.Context, version string) ([]StoredFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.versions[version], nil
+}
+
+func (m *memoryStorage) OpenFile(ctx context.Context, version, path string) (io.ReadCloser, *StoredFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := findFile(m.versions[version], path)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, path)
+	}
+	return io.NopCloser(bytes.NewReader(m.blobs[file.Hash])), file, nil
+}
+
+func (m *memoryStorage) WriteBlob(ctx context.Context, hash string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[hash] = data
+	return nil
+}
+
+func (m *memoryStorage) WriteVersion(ctx context.Context, version string, files []StoredFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[version] = files
+	return nil
+}
+
+func (m *memoryStorage) DeleteVersion(ctx context.Context, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.versions, version)
+	return nil
+}
+
+func (m *memoryStorage) CurrentVersion(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current, nil
+}
+
+func (m *memoryStorage) SetCurrentVersion(ctx context.Context, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = version
+	return nil
+}
+
+func (m *memoryStorage) PreviewVersion(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.preview, nil
+}
+
+func (m *memoryStorage) SetPreviewVersion(ctx context.Context, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.preview = version
+	return nil
+}
+
+// newReplica creates a service with its own bundle directory on the shared storage
+func newReplica(t *testing.T, storage Storage) *Service {
+	t.Helper()
+	config := DefaultConfig()
+	config.BundlePath = filepath.Join(t.TempDir(), "bundle")
+	config.Storage = storage
+	config.SyncInterval = time.Millisecond
+	service := NewService(config, logger.NewLogger())
+	require.NoError(t, service.Initialize(context.Background()))
+	return service
+}
+
+func TestSharedStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := newMemoryStorage()
+	first := newReplica(t, storage)
+	second := newReplica(t, storage)
+
+	for _, bundle := range []string{"valid_bundle01.zip", "valid_bundle02.zip"} {
+		bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", bundle))
+		require.NoError(t, err)
+		_, err = first.PushBundle(ctx, bundleFile, "")
+		bundleFile.Close()
+		require.NoError(t, err)
+	}
+	require.NoError(t, first.SwitchVersion(ctx, "0001"))
+
+	// The second replica sees the pushed versions and the switch
+	versions, err := second.GetVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0002", "0001 *"}, versions)
+
+	time.Sleep(2 * time.Millisecond)
+	manifest, err := second.GetManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", manifest.Version)
+	assert.NotEmpty(t, manifest.Files)
+	assert.FileExists(t, filepath.Join(second.bundlePath, "APP_INFO.json"))
+
+	// A restarted replica starts on the current version
+	restarted := newReplica(t, storage)
+	manifest, err = restarted.GetManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", manifest.Version)
+
+	// Files of the latest version are read from storage
+	file, info, err := second.GetLatestVersionFile(ctx, "APP_INFO.json")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, int64(len(data)), info.Size)
+	assert.True(t, strings.Contains(string(data), `"version"`))
+	hash, err := second.GetFileHash(ctx, "APP_INFO.json", true)
+	require.NoError(t, err)
+	assert.Equal(t, info.Hash, hash)
+
+	_, _, err = second.GetLatestVersionFile(ctx, "missing.json")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "versions")
+	storage := NewLocalStorage(root)
+
+	versions, err := storage.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+	current, err := storage.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, current)
+
+	schema := []byte("{}")
+	require.NoError(t, storage.WriteBlob(ctx, blobHash(schema), schema))
+	files := []StoredFile{
+		{Path: "APP_INFO.json", Hash: blobHash(schema), Size: 2},
+		{Path: "forms/survey/schema.json", Hash: blobHash(schema), Size: 2},
+	}
+	require.NoError(t, storage.WriteVersion(ctx, "0001", files))
+	require.NoError(t, storage.SetCurrentVersion(ctx, "0001"))
+
+	versions, err = storage.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0001"}, versions)
+	listed, err := storage.ListFiles(ctx, "0001")
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "APP_INFO.json", listed[0].Path)
+	assert.Equal(t, "forms/survey/schema.json", listed[1].Path)
+	file, stored, err := storage.OpenFile(ctx, "0001", "forms/survey/schema.json")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, schema, data)
+	assert.Equal(t, blobHash(schema), stored.Hash)
+	current, err = storage.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", current)
+
+	require.NoError(t, storage.SetPreviewVersion(ctx, "0001"))
+	preview, err := storage.PreviewVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", preview)
+	versions, err = storage.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0001"}, versions, "pointers are not versions")
+	require.NoError(t, storage.SetPreviewVersion(ctx, ""))
+	preview, err = storage.PreviewVersion(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, preview)
+
+	_, _, err = storage.OpenFile(ctx, "0001", "missing.json")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+	assert.Error(t, storage.WriteVersion(ctx, "0002", []StoredFile{{Path: "../../escape.json", Hash: blobHash(schema)}}))
+	assert.Error(t, storage.WriteVersion(ctx, "0002", []StoredFile{{Path: "schema.json", Hash: "../escape"}}))
+	assert.Error(t, storage.WriteBlob(ctx, "../escape", schema))
+
+	// A second version shares the blob of the unchanged file
+	changed := []byte(`{"type":"object"}`)
+	require.NoError(t, storage.WriteBlob(ctx, blobHash(schema), schema))
+	require.NoError(t, storage.WriteBlob(ctx, blobHash(changed), changed))
+	require.NoError(t, storage.WriteVersion(ctx, "0002", []StoredFile{
+		{Path: "APP_INFO.json", Hash: blobHash(schema), Size: 2},
+		{Path: "forms/survey/schema.json", Hash: blobHash(changed), Size: int64(len(changed))},
+	}))
+	blobs, err := filepath.Glob(filepath.Join(root, blobsDir, "*", "*"))
+	require.NoError(t, err)
+	assert.Len(t, blobs, 2)
+
+	// Deleting a version removes only the blobs no other version refers to
+	require.NoError(t, storage.DeleteVersion(ctx, "0002"))
+	assert.NoFileExists(t, storage.blobPath(blobHash(changed)))
+	assert.FileExists(t, storage.blobPath(blobHash(schema)))
+	require.NoError(t, storage.DeleteVersion(ctx, "0001"))
+	assert.NoFileExists(t, storage.blobPath(blobHash(schema)))
+	versions, err = storage.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestLocalStorageDirectoryVersions(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	storage := NewLocalStorage(root)
+
+	// Versions stored as directories of files are still read and deleted
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "0001", "forms", "survey"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "0001", "forms", "survey", "schema.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "0001", "APP_INFO.json"), []byte("{}"), 0644))
+	data := []byte("{}")
+	require.NoError(t, storage.WriteBlob(ctx, blobHash(data), data))
+	require.NoError(t, storage.WriteVersion(ctx, "0002", []StoredFile{{Path: "APP_INFO.json", Hash: blobHash(data), Size: 2}}))
+
+	versions, err := storage.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"0001", "0002"}, versions)
+	files, err := storage.ListFiles(ctx, "0001")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "forms/survey/schema.json", files[1].Path)
+	assert.Empty(t, files[1].Hash)
+	file, _, err := storage.OpenFile(ctx, "0001", "forms/survey/schema.json")
+	require.NoError(t, err)
+	file.Close()
+
+	require.NoError(t, storage.DeleteVersion(ctx, "0001"))
+	assert.NoDirExists(t, filepath.Join(root, "0001"))
+	assert.FileExists(t, storage.blobPath(blobHash(data)))
+	versions, err = storage.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0002"}, versions)
+}
+
+func TestSwitchNotification(t *testing.T) {
+	ctx := context.Background()
+	storage := newMemoryStorage()
+	second := newReplica(t, storage)
+	second.syncInterval = time.Hour
+
+	// The first replica tells the second about its switches, as a message over redis would
+	config := DefaultConfig()
+	config.BundlePath = filepath.Join(t.TempDir(), "bundle")
+	config.Storage = storage
+	var notified []string
+	config.OnSwitch = func(version string) {
+		notified = append(notified, version)
+		require.NoError(t, second.SyncCurrentVersion(ctx))
+	}
+	first := NewService(config, logger.NewLogger())
+	require.NoError(t, first.Initialize(ctx))
+
+	bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", "valid_bundle01.zip"))
+	require.NoError(t, err)
+	defer bundleFile.Close()
+	_, err = first.PushBundle(ctx, bundleFile, "")
+	require.NoError(t, err)
+	require.NoError(t, first.SwitchVersion(ctx, "0001"))
+
+	assert.Equal(t, []string{"0001"}, notified)
+	manifest, err := second.GetManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0001", manifest.Version)
+}
@@ -0,0 +1,276 @@
+package appbundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// TenantStorage is implemented by storages that can keep the versions of each tenant apart
+type TenantStorage interface {
+	// ForTenant returns the storage of a tenant's versions
+	ForTenant(tenant string) Storage
+}
+
+// ForTenant keeps a tenant's versions under <prefix>tenants/<tenant>/
+func (s *S3Storage) ForTenant(tenant string) Storage {
+	return &S3Storage{client: s.client, prefix: s.prefix + "tenants/" + tenant + "/"}
+}
+
+// ForTenant returns the configuration of a tenant's app bundle. Its bundle and versions are
+// kept in a tenants/<tenant> directory next to the default ones, or in the tenant's part of
+// storage. Other replicas pick up versions a tenant switches to within SyncInterval.
+func (c Config) ForTenant(id string) (Config, error) {
+	if err := tenant.Validate(id); err != nil {
+		return Config{}, err
+	}
+	config := c
+	config.BundlePath = tenantPath(c.BundlePath, id)
+	config.VersionsPath = tenantPath(c.VersionsPath, id)
+	config.OnSwitch = nil
+	if c.Storage != nil {
+		storage, ok := c.Storage.(TenantStorage)
+		if !ok {
+			return Config{}, errors.New("app bundle storage cannot keep tenants apart")
+		}
+		config.Storage = storage.ForTenant(id)
+	}
+	return config, nil
+}
+
+// tenantPath returns the directory of a tenant next to dir, e.g. tenants/acme/app-bundle for
+// app-bundle
+func tenantPath(dir, id string) string {
+	return filepath.Join(filepath.Dir(dir), "tenants", id, filepath.Base(dir))
+}
+
+// TenantService routes app bundle operations to the bundle of the tenant in the context. The
+// default tenant keeps the bundle of the service it wraps; every other tenant gets its own,
+// configured with Config.ForTenant and initialized on first use.
+type TenantService struct {
+	defaultService *Service
+	config         Config
+	log            *logger.Logger
+
+	mutex   sync.Mutex
+	tenants map[string]*Service
+}
+
+var _ AppBundleServiceInterface = (*TenantService)(nil)
+
+// NewTenantService creates a service routing to defaultService and the bundles of other tenants
+func NewTenantService(defaultService *Service, config Config, log *logger.Logger) *TenantService {
+	return &TenantService{
+		defaultService: defaultService,
+		config:         config,
+		log:            log,
+		tenants:        make(map[string]*Service),
+	}
+}
+
+// service returns the app bundle service of the tenant in ctx
+func (t *TenantService) service(ctx context.Context) (*Service, error) {
+	id := tenant.FromContext(ctx)
+	if id == tenant.Default {
+		return t.defaultService, nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if s, ok := t.tenants[id]; ok {
+		return s, nil
+	}
+	config, err := t.config.ForTenant(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure app bundle of tenant %s: %w", id, err)
+	}
+	s := NewService(config, t.log)
+	if err := s.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize app bundle of tenant %s: %w", id, err)
+	}
+	t.log.Info("Initialized app bundle of tenant", "tenant", id, "path", config.BundlePath)
+	t.tenants[id] = s
+	return s, nil
+}
+
+// GetManifest retrieves the current manifest of the tenant's app bundle
+func (t *TenantService) GetManifest(ctx context.Context) (*Manifest, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetManifest(ctx)
+}
+
+// GetFile retrieves a file of the tenant's current version
+func (t *TenantService) GetFile(ctx context.Context, path string) (io.ReadCloser, *File, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.GetFile(ctx, path)
+}
+
+// GetLatestVersionFile gets a file from the tenant's latest version
+func (t *TenantService) GetLatestVersionFile(ctx context.Context, path string) (io.ReadCloser, *File, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.GetLatestVersionFile(ctx, path)
+}
+
+// GetVersionFile gets a file from a stored version of the tenant
+func (t *TenantService) GetVersionFile(ctx context.Context, version, path string) (io.ReadCloser, *File, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.GetVersionFile(ctx, version, path)
+}
+
+// GetFileHash returns the hash of a file of the tenant's bundle
+func (t *TenantService) GetFileHash(ctx context.Context, path string, useLatest bool) (string, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.GetFileHash(ctx, path, useLatest)
+}
+
+// RefreshManifest refreshes the manifest of the default tenant's bundle
+func (t *TenantService) RefreshManifest() error {
+	return t.defaultService.RefreshManifest()
+}
+
+// PushBundle pushes a new version of the tenant's app bundle
+func (t *TenantService) PushBundle(ctx context.Context, zipReader io.Reader, signature string) (*Manifest, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.PushBundle(ctx, zipReader, signature)
+}
+
+// GetVersions returns the versions of the tenant's app bundle
+func (t *TenantService) GetVersions(ctx context.Context) ([]string, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetVersions(ctx)
+}
+
+// SwitchVersion switches the tenant's app bundle to a stored version
+func (t *TenantService) SwitchVersion(ctx context.Context, version string) error {
+	s, err := t.service(ctx)
+	if err != nil {
+		return err
+	}
+	return s.SwitchVersion(ctx, version)
+}
+
+// StagePreview stages a stored version of the tenant for preview
+func (t *TenantService) StagePreview(ctx context.Context, version string) error {
+	s, err := t.service(ctx)
+	if err != nil {
+		return err
+	}
+	return s.StagePreview(ctx, version)
+}
+
+// GetStagedPreview returns the tenant's version staged for preview
+func (t *TenantService) GetStagedPreview(ctx context.Context) (string, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.GetStagedPreview(ctx)
+}
+
+// PromotePreview switches the tenant's app bundle to its staged preview version
+func (t *TenantService) PromotePreview(ctx context.Context) (string, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.PromotePreview(ctx)
+}
+
+// GetPreviewManifest retrieves the manifest of the tenant's preview version
+func (t *TenantService) GetPreviewManifest(ctx context.Context) (*Manifest, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPreviewManifest(ctx)
+}
+
+// GetPreviewFile retrieves a file of the tenant's preview version
+func (t *TenantService) GetPreviewFile(ctx context.Context, path string) (io.ReadCloser, *File, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.GetPreviewFile(ctx, path)
+}
+
+// FindManifest returns the manifest of the tenant's stored version with the given hash
+func (t *TenantService) FindManifest(ctx context.Context, hash string) (*Manifest, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.FindManifest(ctx, hash)
+}
+
+// WriteDiff writes a diff between versions of the tenant's app bundle
+func (t *TenantService) WriteDiff(ctx context.Context, diff *Diff, w io.Writer) error {
+	s, err := t.service(ctx)
+	if err != nil {
+		return err
+	}
+	return s.WriteDiff(ctx, diff, w)
+}
+
+// ExportVersion writes a stored version of the tenant's app bundle as a zip
+func (t *TenantService) ExportVersion(ctx context.Context, version string, w io.Writer) error {
+	s, err := t.service(ctx)
+	if err != nil {
+		return err
+	}
+	return s.ExportVersion(ctx, version, w)
+}
+
+// GetAppInfo retrieves the app info of a version of the tenant's app bundle
+func (t *TenantService) GetAppInfo(ctx context.Context, version string) (*AppInfo, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAppInfo(ctx, version)
+}
+
+// GetLatestAppInfo retrieves the app info of the tenant's latest version
+func (t *TenantService) GetLatestAppInfo(ctx context.Context) (*AppInfo, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetLatestAppInfo(ctx)
+}
+
+// CompareAppInfos compares two versions of the tenant's app bundle
+func (t *TenantService) CompareAppInfos(ctx context.Context, versionA, versionB string) (*ChangeLog, error) {
+	s, err := t.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.CompareAppInfos(ctx, versionA, versionB)
+}
@@ -0,0 +1,63 @@
+package appbundle
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantService(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.BundlePath = filepath.Join(dir, "app-bundle")
+	config.VersionsPath = filepath.Join(dir, "app-bundle-versions")
+	defaultService := NewService(config, logger.NewLogger())
+	require.NoError(t, defaultService.Initialize(context.Background()))
+	service := NewTenantService(defaultService, config, logger.NewLogger())
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", "valid_bundle01.zip"))
+	require.NoError(t, err)
+	defer bundleFile.Close()
+	_, err = service.PushBundle(acme, bundleFile, "")
+	require.NoError(t, err)
+	require.NoError(t, service.SwitchVersion(acme, "0001"))
+
+	// The tenant's version is stored next to the default bundle, which stays empty
+	versions, err := service.GetVersions(acme)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0001 *"}, versions)
+	assert.DirExists(t, filepath.Join(dir, "tenants", "acme", "app-bundle-versions"))
+
+	versions, err = service.GetVersions(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+	_, _, err = service.GetFile(context.Background(), "APP_INFO.json")
+	assert.Error(t, err)
+
+	file, _, err := service.GetFile(acme, "APP_INFO.json")
+	require.NoError(t, err)
+	file.Close()
+
+	_, err = service.GetVersions(tenant.WithTenant(context.Background(), "../escape"))
+	assert.ErrorIs(t, err, tenant.ErrInvalidID)
+}
+
+func TestConfigForTenant(t *testing.T) {
+	config := DefaultConfig()
+	config.Storage = newMemoryStorage()
+	_, err := config.ForTenant("acme")
+	assert.Error(t, err, "storage that cannot keep tenants apart")
+
+	config.Storage = NewS3Storage(nil, "bundles")
+	tenantConfig, err := config.ForTenant("acme")
+	require.NoError(t, err)
+	assert.Equal(t, "bundles/tenants/acme/", tenantConfig.Storage.(*S3Storage).prefix)
+	assert.Equal(t, filepath.Join("tenants", "acme", "app-bundle"), tenantConfig.BundlePath)
+}
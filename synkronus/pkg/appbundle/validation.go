@@ -35,12 +35,19 @@ func (s *Service) validateBundleStructure(zipReader *zip.Reader) error {
 			continue
 		}
 
+		// Exported signed versions also carry a top-level SIGNATURE.json, and bundles may declare
+		// the app version they need in bundle.json
 		topDir := parts[0]
 		if topDir == "app" || topDir == "forms" || topDir == "renderers" {
 			topDirs[topDir] = true
-		} else if topDir != "" {
+		} else if topDir != "" && file.Name != SignatureFile && file.Name != BundleMetadataFile {
 			return fmt.Errorf("%w: unexpected top-level directory '%s'", ErrInvalidStructure, topDir)
 		}
+		if file.Name == BundleMetadataFile {
+			if _, err := readBundleMetadata(file); err != nil {
+				return err
+			}
+		}
 
 		// Check for app/index.html
 		if file.Name == "app/index.html" {
@@ -143,6 +150,16 @@ func (s *Service) validateFormSchema(file *zip.File) error {
 	}
 	formName := parts[1]
 
+	// Check server-assigned field declarations
+	for _, field := range extractFields(schema) {
+		switch field.ServerAssigned {
+		case "", ServerAssignedSequence, ServerAssignedOrgUnitCode:
+		default:
+			return fmt.Errorf("%w: field '%s' of form '%s' has unknown x-server-assigned kind '%s'",
+				ErrInvalidFormStructure, field.Name, formName, field.ServerAssigned)
+		}
+	}
+
 	// Check for core field modifications
 	if currentHash, exists := s.getCoreFieldsHash(formName); exists {
 		// Get current core fields
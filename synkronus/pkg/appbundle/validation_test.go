@@ -66,6 +66,23 @@ func TestValidateBundleStructure(t *testing.T) {
 			wantErr: true,
 			err:     ErrInvalidStructure,
 		},
+		{
+			name: "bundle.json declaring the app version",
+			files: map[string]string{
+				"app/index.html": "<html></html>",
+				"bundle.json":    `{"min_app_version": "1.4.0", "question_types": {"gps_area": "1.6"}}`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "bundle.json with an invalid app version",
+			files: map[string]string{
+				"app/index.html": "<html></html>",
+				"bundle.json":    `{"min_app_version": "latest"}`,
+			},
+			wantErr: true,
+			err:     ErrInvalidStructure,
+		},
 		{
 			name: "invalid form structure - missing schema.json",
 			files: map[string]string{
@@ -104,9 +121,9 @@ func TestValidateBundleStructure(t *testing.T) {
 			defer zipFile.Close()
 
 			service := &Service{
-				bundlePath:   filepath.Join(t.TempDir(), "bundle"),
-				versionsPath: filepath.Join(t.TempDir(), "versions"),
-				maxVersions:  5,
+				bundlePath:  filepath.Join(t.TempDir(), "bundle"),
+				storage:     NewLocalStorage(filepath.Join(t.TempDir(), "versions")),
+				maxVersions: 5,
 			}
 
 			err = service.validateBundleStructure(&zipFile.Reader)
@@ -192,9 +209,9 @@ func TestValidateFormRendererReferences(t *testing.T) {
 			defer zipFile.Close()
 
 			service := &Service{
-				bundlePath:   filepath.Join(t.TempDir(), "bundle"),
-				versionsPath: filepath.Join(t.TempDir(), "versions"),
-				maxVersions:  5,
+				bundlePath:  filepath.Join(t.TempDir(), "bundle"),
+				storage:     NewLocalStorage(filepath.Join(t.TempDir(), "versions")),
+				maxVersions: 5,
 			}
 
 			err = service.validateFormRendererReferences(&zipFile.Reader)
@@ -329,6 +346,27 @@ func TestExtractFields(t *testing.T) {
 				Required:     true,
 			}},
 		},
+		{
+			name: "server-assigned sequence field",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"registration_number": map[string]any{
+						"type":               "string",
+						"x-server-assigned":  "sequence",
+						"x-sequence-prefix":  "REG-",
+						"x-sequence-padding": float64(6),
+					},
+				},
+			},
+			want: []FieldInfo{{
+				Name:            "registration_number",
+				Type:            "string",
+				ServerAssigned:  ServerAssignedSequence,
+				SequencePrefix:  "REG-",
+				SequencePadding: 6,
+			}},
+		},
 		{
 			name: "single core field",
 			schema: map[string]any{
@@ -624,6 +662,25 @@ func TestValidateFormSchema(t *testing.T) {
 			schema:  `{invalid: json}`,
 			isValid: false,
 		},
+		{
+			name: "server-assigned fields",
+			schema: `{
+				"properties": {
+					"registration_number": {"type": "string", "x-server-assigned": "sequence", "x-sequence-prefix": "REG-"},
+					"facility_code": {"type": "string", "x-server-assigned": "org_unit_code"}
+				}
+			}`,
+			isValid: true,
+		},
+		{
+			name: "unknown server-assigned kind",
+			schema: `{
+				"properties": {
+					"registration_number": {"type": "string", "x-server-assigned": "uuid"}
+				}
+			}`,
+			isValid: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -654,9 +711,9 @@ func TestValidateFormSchema(t *testing.T) {
 
 			// Create a test service
 			service := &Service{
-				bundlePath:   filepath.Join(t.TempDir(), "bundle"),
-				versionsPath: filepath.Join(t.TempDir(), "versions"),
-				maxVersions:  5,
+				bundlePath:  filepath.Join(t.TempDir(), "bundle"),
+				storage:     NewLocalStorage(filepath.Join(t.TempDir(), "versions")),
+				maxVersions: 5,
 			}
 
 			// Find the schema file
@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -15,7 +16,7 @@ import (
 )
 
 // PushBundle uploads a new app bundle from a zip file
-func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader) (*Manifest, error) {
+func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader, signature string) (*Manifest, error) {
 	// Create a temporary file to store the zip content
 	tempZipFile, err := os.CreateTemp("", "appbundle-*.zip")
 	if err != nil {
@@ -25,7 +26,10 @@ func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader) (*Manifes
 	defer tempZipFile.Close()
 
 	// Copy the zip content to the temporary file
-	if _, err := io.Copy(tempZipFile, zipReader); err != nil {
+	if err := s.limits.copyBundle(tempZipFile, zipReader); err != nil {
+		if errors.Is(err, ErrBundleTooLarge) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to copy zip content: %w", err)
 	}
 
@@ -41,48 +45,97 @@ func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader) (*Manifes
 	}
 	defer zipFile.Close()
 
+	// Refuse archives that would unpack into too much before reading any of their files
+	if err := s.limits.check(&zipFile.Reader); err != nil {
+		return nil, err
+	}
+
+	// Replace the shared form components forms include with their questions. The signature of a
+	// bundle covers the files devices download, which the server would change, so signed bundles
+	// cannot include components.
+	bundle := &zipFile.Reader
+	resolvedZipFile, err := os.CreateTemp("", "appbundle-resolved-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(resolvedZipFile.Name())
+	defer resolvedZipFile.Close()
+	resolved, err := s.resolveIncludes(ctx, bundle, resolvedZipFile)
+	if err != nil {
+		return nil, fmt.Errorf("bundle validation failed: %w", err)
+	}
+	if resolved {
+		if signature != "" || len(s.signingKeys) > 0 {
+			return nil, fmt.Errorf("%w: signed bundles cannot include shared form components", ErrInvalidInclude)
+		}
+		resolvedZip, err := zip.OpenReader(resolvedZipFile.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open resolved zip file: %w", err)
+		}
+		defer resolvedZip.Close()
+		bundle = &resolvedZip.Reader
+	}
+
 	// Validate the bundle structure
-	if err := s.validateBundleStructure(&zipFile.Reader); err != nil {
+	if err := s.validateBundleStructure(bundle); err != nil {
 		return nil, fmt.Errorf("bundle validation failed: %w", err)
 	}
 
+	// Check the signature before anything is stored
+	verified, err := s.verifySignature(bundle, signature)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get the next version number after validation passes
-	versionNumber, err := s.getNextVersionNumber()
+	versionNumber, err := s.getNextVersionNumber(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get next version number: %w", err)
 	}
 
 	// Create version name with leading zeros for sorting (e.g., 0001, 0002, etc.)
 	versionName := fmt.Sprintf("%04d", versionNumber)
-	versionPath := filepath.Join(s.versionsPath, versionName)
-
-	// Create the version directory
 	s.log.Info("Creating new app bundle version", "version", versionName)
-	if err := os.MkdirAll(versionPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create version directory: %w", err)
-	}
 
 	// Generate app info with the new version number
-	appInfoData, err := s.generateAppInfo(&zipFile.Reader, fmt.Sprint(versionNumber))
+	appInfoData, err := s.generateAppInfo(bundle, fmt.Sprint(versionNumber))
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate app info: %w", err)
 	}
 
-	// Write APP_INFO.json directly to the version directory
-	appInfoPath := filepath.Join(versionPath, "APP_INFO.json")
-	if err := os.WriteFile(appInfoPath, appInfoData, 0644); err != nil {
+	// Store the content of each file as a blob, shared with the versions that have the same
+	// content, and collect the index of the version
+	files := make(map[string]StoredFile)
+	storedAt := time.Now().UTC()
+	writeFile := func(path string, data []byte) error {
+		hash := blobHash(data)
+		if err := s.storage.WriteBlob(ctx, hash, data); err != nil {
+			return fmt.Errorf("failed to store file %s: %w", path, err)
+		}
+		files[path] = StoredFile{Path: path, Hash: hash, Size: int64(len(data)), ModTime: storedAt}
+		return nil
+	}
+
+	// Write APP_INFO.json directly to the version
+	if err := writeFile("APP_INFO.json", appInfoData); err != nil {
 		return nil, fmt.Errorf("failed to write APP_INFO.json: %w", err)
 	}
 
-	// Rewind the zip file for extraction
-	if _, err := tempZipFile.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("failed to rewind temporary file: %w", err)
+	// Write the verified signature; a SIGNATURE.json in the zip is never stored as is
+	if verified != nil {
+		signatureData, err := json.MarshalIndent(verified, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode signature: %w", err)
+		}
+		if err := writeFile(SignatureFile, signatureData); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", SignatureFile, err)
+		}
 	}
 
-	// Extract the zip file to the version directory (using the original zip file)
-	for _, file := range zipFile.File {
-		// Skip directories and files with paths containing ".."
-		if file.FileInfo().IsDir() || strings.Contains(file.Name, "..") {
+	// Extract the zip file to the version
+	for _, file := range bundle.File {
+		// Skip directories, files with paths containing ".." and the signature
+		if file.FileInfo().IsDir() || strings.Contains(file.Name, "..") || file.Name == SignatureFile {
 			continue
 		}
 
@@ -90,41 +143,37 @@ func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader) (*Manifes
 		cleanPath := filepath.Clean(file.Name)
 		cleanPath = filepath.ToSlash(cleanPath)
 
-		// Create the target file path
-		targetPath := filepath.Join(versionPath, cleanPath)
-
-		// Ensure the parent directory exists
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-			return nil, fmt.Errorf("failed to create directory for file %s: %w", cleanPath, err)
-		}
-
 		// Open the file from the zip
 		srcFile, err := file.Open()
 		if err != nil {
 			return nil, fmt.Errorf("failed to open file %s from zip: %w", cleanPath, err)
 		}
 
-		// Create the target file
-		dstFile, err := os.Create(targetPath)
+		// Read the content
+		data, err := io.ReadAll(srcFile)
+		srcFile.Close()
 		if err != nil {
-			srcFile.Close()
-			return nil, fmt.Errorf("failed to create file %s: %w", cleanPath, err)
+			return nil, fmt.Errorf("failed to copy file %s: %w", cleanPath, err)
 		}
 
-		// Copy the content
-		if _, err := io.Copy(dstFile, srcFile); err != nil {
-			srcFile.Close()
-			dstFile.Close()
-			return nil, fmt.Errorf("failed to copy file %s: %w", cleanPath, err)
+		// Store the file in the version
+		if err := writeFile(cleanPath, data); err != nil {
+			return nil, err
 		}
+	}
 
-		// Close the files
-		srcFile.Close()
-		dstFile.Close()
+	// The version is listed once its index is written, so replicas never see part of it
+	index := make([]StoredFile, 0, len(files))
+	for _, file := range files {
+		index = append(index, file)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Path < index[j].Path })
+	if err := s.storage.WriteVersion(ctx, versionName, index); err != nil {
+		return nil, fmt.Errorf("failed to write version %s: %w", versionName, err)
 	}
 
 	// Clean up old versions if needed
-	if err := s.cleanupOldVersions(); err != nil {
+	if err := s.cleanupOldVersions(ctx); err != nil {
 		s.log.Error("Failed to clean up old versions", "error", err)
 		// Continue even if cleanup fails
 	}
@@ -133,6 +182,7 @@ func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader) (*Manifes
 	return &Manifest{
 		Version:     versionName,
 		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Signature:   verified,
 		// Files will be populated when the manifest is generated
 	}, nil
 }
@@ -140,32 +190,26 @@ func (s *Service) PushBundle(ctx context.Context, zipReader io.Reader) (*Manifes
 // GetVersions returns a list of available app bundle versions
 // The current version is marked with an asterisk (*) at the end
 func (s *Service) GetVersions(ctx context.Context) ([]string, error) {
-	// Read the versions directory
-	entries, err := os.ReadDir(s.versionsPath)
+	// Read the stored versions
+	stored, err := s.storage.ListVersions(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil
-		}
-		return nil, fmt.Errorf("failed to read versions directory: %w", err)
+		return nil, fmt.Errorf("failed to read versions: %w", err)
 	}
 
 	// Get current version
-	currentVersion, err := s.getCurrentVersion()
+	currentVersion, err := s.getCurrentVersion(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current version: %w", err)
 	}
 
-	// Filter directories and collect versions
-	versions := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		if entry.IsDir() {
-			version := entry.Name()
-			// Mark current version with an asterisk
-			if version == currentVersion {
-				version += " *"
-			}
-			versions = append(versions, version)
+	// Collect versions
+	versions := make([]string, 0, len(stored))
+	for _, version := range stored {
+		// Mark current version with an asterisk
+		if version == currentVersion {
+			version += " *"
 		}
+		versions = append(versions, version)
 	}
 
 	// Sort versions in descending order (newest first)
@@ -180,77 +224,73 @@ func (s *Service) GetVersions(ctx context.Context) ([]string, error) {
 }
 
 // getCurrentVersion returns the name of the currently active version
-func (s *Service) getCurrentVersion() (string, error) {
+func (s *Service) getCurrentVersion(ctx context.Context) (string, error) {
 	s.versionMutex.Lock()
 	defer s.versionMutex.Unlock()
 
-	// Read the current version from the version file
-	versionFile := filepath.Join(s.versionsPath, "CURRENT_VERSION")
-	data, err := os.ReadFile(versionFile)
+	return s.storedCurrentVersion(ctx)
+}
+
+// storedCurrentVersion reads the current version from storage, ignoring a version that no
+// longer exists. The caller holds versionMutex.
+func (s *Service) storedCurrentVersion(ctx context.Context) (string, error) {
+	version, err := s.storage.CurrentVersion(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil // No current version set
-		}
-		return "", fmt.Errorf("failed to read current version: %w", err)
+		return "", err
 	}
-
-	// Clean and validate the version
-	version := strings.TrimSpace(string(data))
 	if version == "" {
-		return "", nil
+		return "", nil // No current version set
 	}
 
-	// Verify the version directory exists
-	versionPath := filepath.Join(s.versionsPath, version)
-	if _, err := os.Stat(versionPath); err != nil {
-		if os.IsNotExist(err) {
-			s.log.Warn("Current version directory not found", "version", version)
-			return "", nil
-		}
-		return "", fmt.Errorf("failed to verify version directory: %w", err)
+	// Verify the version exists
+	exists, err := s.versionExists(ctx, version)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		s.log.Warn("Current version not found in storage", "version", version)
+		return "", nil
 	}
 
 	return version, nil
 }
 
+// versionExists reports whether a version is stored
+func (s *Service) versionExists(ctx context.Context, version string) (bool, error) {
+	versions, err := s.storage.ListVersions(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to read versions: %w", err)
+	}
+	for _, v := range versions {
+		if v == version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // SwitchVersion switches to a specific app bundle version
 func (s *Service) SwitchVersion(ctx context.Context, version string) error {
 	s.versionMutex.Lock()
 	defer s.versionMutex.Unlock()
 
 	// Validate the version
-	versionPath := filepath.Join(s.versionsPath, version)
-	if _, err := os.Stat(versionPath); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("version %s does not exist", version)
-		}
-		return fmt.Errorf("failed to stat version directory: %w", err)
+	exists, err := s.versionExists(ctx, version)
+	if err != nil {
+		return err
 	}
-
-	// Clear the current bundle directory
-	if err := s.clearDirectory(s.bundlePath); err != nil {
-		return fmt.Errorf("failed to clear bundle directory: %w", err)
+	if !exists {
+		return fmt.Errorf("version %s does not exist", version)
 	}
 
 	// Copy the version to the bundle directory
-	if err := s.copyDirectory(versionPath, s.bundlePath); err != nil {
-		return fmt.Errorf("failed to copy version to bundle directory: %w", err)
+	if err := s.installVersion(ctx, version); err != nil {
+		return err
 	}
 
-	// Update the current version file atomically
-	versionFile := filepath.Join(s.versionsPath, "CURRENT_VERSION")
-	tempFile := versionFile + ".tmp"
-
-	// Write to a temporary file first
-	if err := os.WriteFile(tempFile, []byte(version), 0644); err != nil {
-		return fmt.Errorf("failed to write version file: %w", err)
-	}
-
-	// Atomic rename (works across all platforms)
-	if err := os.Rename(tempFile, versionFile); err != nil {
-		// Clean up temp file if rename fails
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to update current version: %w", err)
+	// Update the current version, which other replicas pick up from storage
+	if err := s.storage.SetCurrentVersion(ctx, version); err != nil {
+		return err
 	}
 
 	// Update in-memory state
@@ -258,77 +298,93 @@ func (s *Service) SwitchVersion(ctx context.Context, version string) error {
 	s.manifest = nil // Force regeneration of manifest
 
 	s.log.Info("Switched to app bundle version", "version", version)
+	if s.onSwitch != nil {
+		s.onSwitch(version)
+	}
 	return nil
 }
 
-// clearDirectory removes all files and subdirectories in a directory
-func (s *Service) clearDirectory(dir string) error {
-	// Read the directory
-	entries, err := os.ReadDir(dir)
+// ExportVersion writes a stored version as a zip that PushBundle accepts
+func (s *Service) ExportVersion(ctx context.Context, version string, w io.Writer) error {
+	exists, err := s.versionExists(ctx, version)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to read directory: %w", err)
+		return err
+	}
+	if !exists {
+		return ErrVersionNotFound
 	}
 
-	// Remove each entry
-	for _, entry := range entries {
-		path := filepath.Join(dir, entry.Name())
-		if err := os.RemoveAll(path); err != nil {
-			return fmt.Errorf("failed to remove %s: %w", path, err)
-		}
+	files, err := s.storage.ListFiles(ctx, version)
+	if err != nil {
+		return fmt.Errorf("failed to list files of version %s: %w", version, err)
 	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
 
-	return nil
+	zipWriter := zip.NewWriter(w)
+	for _, file := range files {
+		// APP_INFO.json is generated again when the bundle is pushed
+		if file.Path == "APP_INFO.json" {
+			continue
+		}
+		if err := s.exportFile(ctx, zipWriter, version, file); err != nil {
+			return fmt.Errorf("failed to export %s: %w", file.Path, err)
+		}
+	}
+	return zipWriter.Close()
 }
 
-// copyDirectory copies all files and subdirectories from src to dst
-func (s *Service) copyDirectory(src, dst string) error {
-	// Walk the source directory
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+// exportFile adds a file of a stored version to a zip
+func (s *Service) exportFile(ctx context.Context, zipWriter *zip.Writer, version string, file StoredFile) error {
+	src, _, err := s.storage.OpenFile(ctx, version, file.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
 
-		// Get the relative path
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
-		}
+	dst, err := zipWriter.CreateHeader(&zip.FileHeader{Name: file.Path, Method: zip.Deflate, Modified: file.ModTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
 
-		// Skip the root directory
-		if relPath == "." {
-			return nil
-		}
+// installVersion replaces the contents of the bundle directory with a stored version
+func (s *Service) installVersion(ctx context.Context, version string) error {
+	files, err := s.storage.ListFiles(ctx, version)
+	if err != nil {
+		return fmt.Errorf("failed to list files of version %s: %w", version, err)
+	}
 
-		// Create the destination path
-		dstPath := filepath.Join(dst, relPath)
+	// Clear the current bundle directory
+	if err := s.clearDirectory(s.bundlePath); err != nil {
+		return fmt.Errorf("failed to clear bundle directory: %w", err)
+	}
 
-		// If it's a directory, create it
-		if info.IsDir() {
-			if err := os.MkdirAll(dstPath, info.Mode()); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", dstPath, err)
-			}
-			return nil
+	// Copy the version to the bundle directory
+	for _, file := range files {
+		if err := s.installFile(ctx, version, file.Path); err != nil {
+			return fmt.Errorf("failed to copy version to bundle directory: %w", err)
 		}
-
-		// Copy the file
-		return s.copyFile(path, dstPath, info.Mode())
-	})
+	}
+	return nil
 }
 
-// copyFile copies a file from src to dst
-func (s *Service) copyFile(src, dst string, mode os.FileMode) error {
-	// Open the source file
-	srcFile, err := os.Open(src)
+// installFile copies a file of a stored version into the bundle directory
+func (s *Service) installFile(ctx context.Context, version, path string) error {
+	// Open the stored file
+	srcFile, _, err := s.storage.OpenFile(ctx, version, path)
 	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+		return err
 	}
 	defer srcFile.Close()
 
 	// Create the destination file
-	dstFile, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+	dst := filepath.Join(s.bundlePath, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(dst), err)
+	}
+	dstFile, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %w", err)
 	}
@@ -342,32 +398,45 @@ func (s *Service) copyFile(src, dst string, mode os.FileMode) error {
 	return nil
 }
 
-func (s *Service) getNextVersionNumber() (int, error) {
-	s.versionMutex.Lock()
-	defer s.versionMutex.Unlock()
+// clearDirectory removes all files and subdirectories in a directory
+func (s *Service) clearDirectory(dir string) error {
+	// Read the directory
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
 
-	// Ensure versions directory exists
-	if err := os.MkdirAll(s.versionsPath, 0755); err != nil {
-		return 0, fmt.Errorf("failed to create versions directory: %w", err)
+	// Remove each entry
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
 	}
 
-	// List all version directories
-	entries, err := os.ReadDir(s.versionsPath)
+	return nil
+}
+
+func (s *Service) getNextVersionNumber(ctx context.Context) (int, error) {
+	s.versionMutex.Lock()
+	defer s.versionMutex.Unlock()
+
+	// List all versions
+	versions, err := s.storage.ListVersions(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read versions directory: %w", err)
+		return 0, fmt.Errorf("failed to read versions: %w", err)
 	}
 
 	// Find the highest version number
 	highestVersion := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		// Parse version number from directory name
-		version, err := strconv.Atoi(entry.Name())
+	for _, name := range versions {
+		// Parse version number from the version name
+		version, err := strconv.Atoi(name)
 		if err != nil {
-			// Skip non-numeric directories
+			// Skip non-numeric versions
 			continue
 		}
 
@@ -382,12 +451,9 @@ func (s *Service) getNextVersionNumber() (int, error) {
 
 // GetAppInfo retrieves the app info for a specific version
 func (s *Service) GetAppInfo(ctx context.Context, version string) (*AppInfo, error) {
-	versionDir := filepath.Join(s.versionsPath, version)
-	appInfoPath := filepath.Join(versionDir, "APP_INFO.json")
-
-	data, err := os.ReadFile(appInfoPath)
+	data, err := s.readAppInfo(ctx, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read APP_INFO.json: %w", err)
+		return nil, err
 	}
 
 	var appInfo AppInfo
@@ -401,14 +467,7 @@ func (s *Service) GetAppInfo(ctx context.Context, version string) (*AppInfo, err
 
 // GetLatestAppInfo retrieves the app info for the latest version (including unreleased)
 func (s *Service) GetLatestAppInfo(ctx context.Context) (*AppInfo, error) {
-	// First check for an unreleased version
-	tempAppInfoPath := filepath.Join(s.versionsPath, "temp_APP_INFO.json")
-	if _, err := os.Stat(tempAppInfoPath); err == nil {
-		// Found an unreleased version
-		return s.GetAppInfo(ctx, "temp")
-	}
-
-	// Otherwise, get the latest released version
+	// Get the latest version
 	versions, err := s.GetVersions(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get versions: %w", err)
@@ -452,9 +511,9 @@ func (s *Service) CompareAppInfos(ctx context.Context, versionA, versionB string
 }
 
 // cleanupOldVersions removes old versions to keep only the maximum number of versions
-func (s *Service) cleanupOldVersions() error {
+func (s *Service) cleanupOldVersions(ctx context.Context) error {
 	// Get all versions
-	versions, err := s.GetVersions(context.Background())
+	versions, err := s.GetVersions(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get versions: %w", err)
 	}
@@ -468,9 +527,8 @@ func (s *Service) cleanupOldVersions() error {
 	for i := s.maxVersions; i < len(versions); i++ {
 		// Remove asterisk from the version if present
 		version := strings.TrimSuffix(versions[i], " *")
-		versionPath := filepath.Join(s.versionsPath, version)
 		s.log.Info("Removing old app bundle version", "version", version)
-		if err := os.RemoveAll(versionPath); err != nil {
+		if err := s.storage.DeleteVersion(ctx, version); err != nil {
 			return fmt.Errorf("failed to remove old version %s: %w", version, err)
 		}
 	}
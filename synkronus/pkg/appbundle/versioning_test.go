@@ -0,0 +1,65 @@
+package appbundle
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportVersion(t *testing.T) {
+	ctx := context.Background()
+	source := newReplica(t, newMemoryStorage())
+
+	bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", "valid_bundle01.zip"))
+	require.NoError(t, err)
+	_, err = source.PushBundle(ctx, bundleFile, "")
+	bundleFile.Close()
+	require.NoError(t, err)
+
+	var exported bytes.Buffer
+	require.NoError(t, source.ExportVersion(ctx, "0001", &exported))
+	assert.ErrorIs(t, source.ExportVersion(ctx, "0002", &bytes.Buffer{}), ErrVersionNotFound)
+
+	// The export restores to the same files on another server
+	restoredStorage := newMemoryStorage()
+	restored := newReplica(t, restoredStorage)
+	manifest, err := restored.PushBundle(ctx, &exported, "")
+	require.NoError(t, err)
+	assert.Equal(t, "0001", manifest.Version)
+
+	sourceStorage := source.storage.(*memoryStorage)
+	require.Equal(t, len(sourceStorage.versions["0001"]), len(restoredStorage.versions["0001"]))
+	for i, file := range sourceStorage.versions["0001"] {
+		if file.Path != "APP_INFO.json" {
+			assert.Equal(t, file.Hash, restoredStorage.versions["0001"][i].Hash, file.Path)
+		}
+	}
+}
+
+func TestPushSharesBlobs(t *testing.T) {
+	ctx := context.Background()
+	storage := newMemoryStorage()
+	service := newReplica(t, storage)
+
+	for i := 0; i < 2; i++ {
+		bundleFile, err := os.Open(filepath.Join("..", "..", "testdata", "bundles", "valid_bundle01.zip"))
+		require.NoError(t, err)
+		_, err = service.PushBundle(ctx, bundleFile, "")
+		bundleFile.Close()
+		require.NoError(t, err)
+	}
+
+	// Only APP_INFO.json, which records the version number, differs between the versions
+	files := storage.versions["0001"]
+	require.Len(t, storage.versions["0002"], len(files))
+	assert.Len(t, storage.blobs, len(files)+1)
+	require.NoError(t, service.SwitchVersion(ctx, "0002"))
+	hash, err := service.GetFileHash(ctx, "APP_INFO.json", false)
+	require.NoError(t, err)
+	assert.Equal(t, storage.versions["0002"][0].Hash, hash)
+}
@@ -0,0 +1,332 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/config"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/objectstore"
+)
+
+// lifecycleInterval is how often the lifecycle policy is applied in the background
+const lifecycleInterval = 24 * time.Hour
+
+// coldStorageClasses lists the storage classes content can be moved to. Archive classes such as
+// GLACIER and DEEP_ARCHIVE need a restore before an object can be read, so devices could no
+// longer download attachments kept there.
+var coldStorageClasses = map[string]bool{
+	"STANDARD":            true,
+	"STANDARD_IA":         true,
+	"ONEZONE_IA":          true,
+	"INTELLIGENT_TIERING": true,
+	"GLACIER_IR":          true,
+}
+
+// ColdStore keeps attachment content moved off local disk, by content hash
+type ColdStore interface {
+	// Put stores the content with the given hash
+	Put(ctx context.Context, hash string, data []byte) error
+	// Open reads the content with the given hash, or fails with ErrNotFound
+	Open(ctx context.Context, hash string) (io.ReadCloser, error)
+	// Delete removes the content with the given hash; removing missing content is not an error
+	Delete(ctx context.Context, hash string) error
+}
+
+// S3ColdStore keeps attachment content in an S3-compatible bucket under <prefix>blobs/<hash>
+type S3ColdStore struct {
+	client       *objectstore.Client
+	prefix       string
+	storageClass string
+}
+
+// NewS3ColdStore creates a cold store putting content in the bucket under prefix with the given
+// storage class
+func NewS3ColdStore(client *objectstore.Client, prefix, storageClass string) *S3ColdStore {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3ColdStore{client: client, prefix: prefix, storageClass: storageClass}
+}
+
+func (s *S3ColdStore) key(hash string) string {
+	return s.prefix + blobsDir + "/" + hash
+}
+
+// Put stores the content with the given hash in the configured storage class
+func (s *S3ColdStore) Put(ctx context.Context, hash string, data []byte) error {
+	return s.client.PutObjectWithClass(ctx, s.key(hash), data, "application/octet-stream", s.storageClass)
+}
+
+// Open reads the content with the given hash
+func (s *S3ColdStore) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	body, _, err := s.client.GetObject(ctx, s.key(hash))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return body, err
+}
+
+// Delete removes the content with the given hash
+func (s *S3ColdStore) Delete(ctx context.Context, hash string) error {
+	return s.client.DeleteObject(ctx, s.key(hash))
+}
+
+// coldStoreFrom builds the cold store configured by ATTACHMENT_COLD_STORAGE; nil keeps all
+// content on local disk
+func coldStoreFrom(cfg *config.Config) (ColdStore, error) {
+	switch cfg.AttachmentColdStorage {
+	case "", "off":
+		return nil, nil
+	case "s3":
+		if !coldStorageClasses[cfg.AttachmentS3StorageClass] {
+			return nil, fmt.Errorf("attachment storage class %q cannot be read without a restore, use STANDARD_IA, ONEZONE_IA, INTELLIGENT_TIERING or GLACIER_IR", cfg.AttachmentS3StorageClass)
+		}
+		client, err := objectstore.NewClient(objectstore.Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3PathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewS3ColdStore(client, cfg.AttachmentS3Prefix, cfg.AttachmentS3StorageClass), nil
+	default:
+		return nil, fmt.Errorf("unknown ATTACHMENT_COLD_STORAGE %q, expected off or s3", cfg.AttachmentColdStorage)
+	}
+}
+
+// LifecyclePolicy describes how attachments age
+type LifecyclePolicy struct {
+	// ColdStorage is "s3" when content can be moved off local disk, "off" otherwise
+	ColdStorage  string `json:"cold_storage"`
+	StorageClass string `json:"storage_class,omitempty"`
+	// ColdAfterDays moves the content of older attachments to cold storage; 0 never moves it
+	ColdAfterDays int `json:"cold_after_days"`
+	// DeleteAfterDays deletes older attachments; 0 keeps them
+	DeleteAfterDays int `json:"delete_after_days"`
+}
+
+// LifecycleRun summarizes one application of the lifecycle policy
+type LifecycleRun struct {
+	StartedAt time.Time `json:"started_at"`
+	// Moved counts the files of content moved to cold storage, MovedBytes their size
+	Moved      int   `json:"moved"`
+	MovedBytes int64 `json:"moved_bytes"`
+	// Deleted counts the attachments deleted for being past their retention
+	Deleted int `json:"deleted"`
+	// Failed counts the files and attachments left for the next run after an error
+	Failed int `json:"failed"`
+}
+
+// TierStats counts the attachments and distinct content kept in one storage tier
+type TierStats struct {
+	Attachments int   `json:"attachments"`
+	Files       int   `json:"files"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// StorageStats reports where attachment content is kept. Attachments stored before content
+// deduplication are not covered.
+type StorageStats struct {
+	Policy LifecyclePolicy `json:"policy"`
+	Local  TierStats       `json:"local"`
+	Cold   TierStats       `json:"cold"`
+	// LastRun is the latest application of the policy by this server, if any
+	LastRun *LifecycleRun `json:"last_run,omitempty"`
+}
+
+// LifecycleService applies the lifecycle policy of attachments and reports on their storage
+type LifecycleService interface {
+	// Apply moves the content of attachments past the cold age to cold storage and deletes
+	// attachments past their retention
+	Apply(ctx context.Context) (*LifecycleRun, error)
+
+	// Stats reports the attachments and content kept in each storage tier
+	Stats(ctx context.Context) (*StorageStats, error)
+}
+
+// Lifecycle applies the lifecycle policy configured with ATTACHMENT_COLD_AFTER_DAYS and
+// ATTACHMENT_DELETE_AFTER_DAYS to the attachment storage
+type Lifecycle struct {
+	service     *service
+	policy      LifecyclePolicy
+	coldAfter   time.Duration
+	deleteAfter time.Duration
+	log         *logger.Logger
+	now         func() time.Time
+
+	mu      sync.Mutex
+	lastRun *LifecycleRun
+}
+
+var _ LifecycleService = (*Lifecycle)(nil)
+
+// NewLifecycle creates the lifecycle of the attachment storage configured in cfg
+func NewLifecycle(cfg *config.Config, log *logger.Logger) (*Lifecycle, error) {
+	svc, err := newService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AttachmentColdAfterDays > 0 && svc.cold == nil {
+		return nil, errors.New("ATTACHMENT_COLD_AFTER_DAYS needs ATTACHMENT_COLD_STORAGE=s3")
+	}
+
+	policy := LifecyclePolicy{
+		ColdStorage:     "off",
+		ColdAfterDays:   max(cfg.AttachmentColdAfterDays, 0),
+		DeleteAfterDays: max(cfg.AttachmentDeleteAfterDays, 0),
+	}
+	if svc.cold != nil {
+		policy.ColdStorage = cfg.AttachmentColdStorage
+		policy.StorageClass = cfg.AttachmentS3StorageClass
+	}
+	return &Lifecycle{
+		service:     svc,
+		policy:      policy,
+		coldAfter:   time.Duration(policy.ColdAfterDays) * 24 * time.Hour,
+		deleteAfter: time.Duration(policy.DeleteAfterDays) * 24 * time.Hour,
+		log:         log,
+		now:         time.Now,
+	}, nil
+}
+
+// Run applies the policy once a day until ctx is cancelled. Failures are logged and retried on
+// the next run.
+func (l *Lifecycle) Run(ctx context.Context) {
+	if l.coldAfter <= 0 && l.deleteAfter <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(lifecycleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := l.Apply(ctx); err != nil && ctx.Err() == nil {
+			l.log.Warn("Attachment lifecycle run failed", "error", err)
+		}
+	}
+}
+
+// Apply moves the content of attachments past the cold age to cold storage and deletes
+// attachments past their retention. Content shared by several attachments moves once the
+// newest of them is old enough.
+func (l *Lifecycle) Apply(ctx context.Context) (*LifecycleRun, error) {
+	now := l.now()
+	run := &LifecycleRun{StartedAt: now.UTC()}
+
+	records, err := l.service.records()
+	if err != nil {
+		return nil, err
+	}
+
+	if l.deleteAfter > 0 {
+		cutoff := now.Add(-l.deleteAfter)
+		kept := make([]Attachment, 0, len(records))
+		for _, record := range records {
+			if !record.CreatedAt.Before(cutoff) {
+				kept = append(kept, record)
+				continue
+			}
+			if err := l.service.Delete(ctx, record.ID); err != nil && !errors.Is(err, ErrNotFound) {
+				l.log.Warn("Failed to delete expired attachment", "attachmentId", record.ID, "error", err)
+				run.Failed++
+				continue
+			}
+			run.Deleted++
+		}
+		records = kept
+	}
+
+	if l.coldAfter > 0 && l.service.cold != nil {
+		cutoff := now.Add(-l.coldAfter)
+		newest := make(map[string]time.Time)
+		for _, record := range records {
+			if record.CreatedAt.After(newest[record.Hash]) {
+				newest[record.Hash] = record.CreatedAt
+			}
+		}
+		hashes := make([]string, 0, len(newest))
+		for hash, createdAt := range newest {
+			if createdAt.Before(cutoff) {
+				hashes = append(hashes, hash)
+			}
+		}
+		sort.Strings(hashes)
+
+		for _, hash := range hashes {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			size, err := l.service.moveToCold(ctx, hash)
+			if errors.Is(err, os.ErrNotExist) {
+				// Already in cold storage
+				continue
+			}
+			if err != nil {
+				l.log.Warn("Failed to move attachment content to cold storage", "hash", hash, "error", err)
+				run.Failed++
+				continue
+			}
+			run.Moved++
+			run.MovedBytes += size
+		}
+	}
+
+	l.mu.Lock()
+	l.lastRun = run
+	l.mu.Unlock()
+
+	l.log.Info("Attachment lifecycle applied", "moved", run.Moved, "movedBytes", run.MovedBytes,
+		"deleted", run.Deleted, "failed", run.Failed)
+	return run, nil
+}
+
+// Stats reports the attachments and content kept on local disk and in cold storage
+func (l *Lifecycle) Stats(ctx context.Context) (*StorageStats, error) {
+	records, err := l.service.records()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &StorageStats{Policy: l.policy}
+	local := make(map[string]bool)
+	for _, record := range records {
+		isLocal, seen := local[record.Hash]
+		if !seen {
+			_, err := os.Stat(l.service.blobPath(record.Hash))
+			isLocal = err == nil
+			local[record.Hash] = isLocal
+		}
+		tier := &stats.Cold
+		if isLocal {
+			tier = &stats.Local
+		}
+		tier.Attachments++
+		if !seen {
+			tier.Files++
+			tier.Bytes += record.Size
+		}
+	}
+
+	l.mu.Lock()
+	stats.LastRun = l.lastRun
+	l.mu.Unlock()
+	return stats, nil
+}
@@ -0,0 +1,161 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/config"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryColdStore is a cold store kept in memory
+type memoryColdStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memoryColdStore) Put(ctx context.Context, hash string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[hash] = data
+	return nil
+}
+
+func (m *memoryColdStore) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memoryColdStore) Delete(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, hash)
+	return nil
+}
+
+func newTestLifecycle(t *testing.T, coldAfterDays, deleteAfterDays int) (*Lifecycle, *memoryColdStore) {
+	t.Helper()
+	svc, err := newService(&config.Config{DataDir: t.TempDir()})
+	require.NoError(t, err)
+	cold := &memoryColdStore{objects: map[string][]byte{}}
+	svc.cold = cold
+	return &Lifecycle{
+		service:     svc,
+		policy:      LifecyclePolicy{ColdStorage: "s3", ColdAfterDays: coldAfterDays, DeleteAfterDays: deleteAfterDays},
+		coldAfter:   time.Duration(coldAfterDays) * 24 * time.Hour,
+		deleteAfter: time.Duration(deleteAfterDays) * 24 * time.Hour,
+		log:         logger.NewLogger(),
+		now:         time.Now,
+	}, cold
+}
+
+func TestLifecycle_MovesAgingContent(t *testing.T) {
+	ctx := context.Background()
+	l, cold := newTestLifecycle(t, 30, 0)
+	photo, err := l.service.Upload(ctx, "photo-1.jpg", strings.NewReader("jpeg bytes"), UploadOptions{})
+	require.NoError(t, err)
+
+	run, err := l.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, run.Moved, "content is not old enough yet")
+
+	l.now = func() time.Time { return time.Now().Add(31 * 24 * time.Hour) }
+	run, err = l.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Moved)
+	assert.Equal(t, int64(10), run.MovedBytes)
+	assert.Contains(t, cold.objects, photo.Hash)
+
+	// Moved content is still downloaded, now from cold storage
+	file, err := l.service.Get(ctx, "photo-1.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "jpeg bytes", string(data))
+
+	stats, err := l.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TierStats{}, stats.Local)
+	assert.Equal(t, TierStats{Attachments: 1, Files: 1, Bytes: 10}, stats.Cold)
+	assert.Equal(t, run, stats.LastRun)
+
+	run, err = l.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, run.Moved, "content already moved")
+
+	// Deleting the attachment removes its content from cold storage
+	require.NoError(t, l.service.Delete(ctx, "photo-1.jpg"))
+	assert.Empty(t, cold.objects)
+}
+
+func TestLifecycle_SharedContentMovesWithNewestAttachment(t *testing.T) {
+	ctx := context.Background()
+	l, cold := newTestLifecycle(t, 30, 0)
+	_, err := l.service.Upload(ctx, "photo-1.jpg", strings.NewReader("jpeg bytes"), UploadOptions{})
+	require.NoError(t, err)
+
+	// The same content uploaded later keeps it on local disk
+	shared, err := l.service.readRecord(l.service.metaPath("photo-1.jpg"))
+	require.NoError(t, err)
+	shared.ID = "photo-2.jpg"
+	shared.CreatedAt = time.Now().Add(10 * 24 * time.Hour)
+	require.NoError(t, l.service.writeRecord(shared))
+
+	l.now = func() time.Time { return time.Now().Add(31 * 24 * time.Hour) }
+	run, err := l.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, run.Moved)
+	assert.Empty(t, cold.objects)
+
+	stats, err := l.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TierStats{Attachments: 2, Files: 1, Bytes: 10}, stats.Local)
+}
+
+func TestLifecycle_DeletesExpiredAttachments(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLifecycle(t, 0, 365)
+	_, err := l.service.Upload(ctx, "photo-1.jpg", strings.NewReader("jpeg bytes"), UploadOptions{})
+	require.NoError(t, err)
+
+	l.now = func() time.Time { return time.Now().Add(366 * 24 * time.Hour) }
+	run, err := l.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Deleted)
+
+	exists, err := l.service.Exists(ctx, "photo-1.jpg")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestNewLifecycleConfiguration(t *testing.T) {
+	log := logger.NewLogger()
+	cfg := &config.Config{DataDir: t.TempDir(), AttachmentColdAfterDays: 30}
+	_, err := NewLifecycle(cfg, log)
+	assert.ErrorContains(t, err, "ATTACHMENT_COLD_STORAGE")
+
+	cfg.AttachmentColdStorage = "s3"
+	cfg.AttachmentS3StorageClass = "DEEP_ARCHIVE"
+	_, err = NewLifecycle(cfg, log)
+	assert.ErrorContains(t, err, "restore")
+
+	cfg.AttachmentColdStorage = "tape"
+	_, err = NewLifecycle(cfg, log)
+	assert.ErrorContains(t, err, "unknown ATTACHMENT_COLD_STORAGE")
+
+	l, err := NewLifecycle(&config.Config{DataDir: t.TempDir(), AttachmentDeleteAfterDays: 90}, log)
+	require.NoError(t, err)
+	assert.Equal(t, LifecyclePolicy{ColdStorage: "off", DeleteAfterDays: 90}, l.policy)
+}
@@ -102,7 +102,7 @@ func (s *manifestService) Initialize(ctx context.Context) error {
 func (s *manifestService) GetManifest(ctx context.Context, req AttachmentManifestRequest) (*AttachmentManifestResponse, error) {
 	// Get current version
 	var currentVersion int64
-	err := s.db.QueryRowContext(ctx, "SELECT current_version FROM sync_version WHERE id = 1").Scan(&currentVersion)
+	err := s.db.QueryRowContext(ctx, "SELECT sync_stable_version()").Scan(&currentVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current version: %w", err)
 	}
@@ -2,37 +2,115 @@ package attachment
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/opendataensemble/synkronus/pkg/config"
 )
 
+// Common errors
+var (
+	// ErrNotFound is returned when an attachment does not exist
+	ErrNotFound = errors.New("attachment not found")
+	// ErrTooLarge is returned when the uploaded content exceeds the size limit
+	ErrTooLarge = errors.New("attachment too large")
+)
+
+// Attachment describes a stored attachment such as a photo, audio recording or signature
+type Attachment struct {
+	ID            string    `json:"id"`
+	ObservationID string    `json:"observation_id,omitempty"`
+	Hash          string    `json:"hash"`
+	Size          int64     `json:"size"`
+	ContentType   string    `json:"content_type"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UploadOptions holds the metadata of an uploaded attachment
+type UploadOptions struct {
+	// ObservationID associates the attachment with an observation
+	ObservationID string
+	// ContentType is the type reported by the client; guessed from the ID when empty
+	ContentType string
+}
+
 type Service interface {
 	// Save stores the attachment with the given ID
 	Save(ctx context.Context, attachmentID string, file io.Reader) error
-	
+
+	// Upload stores the attachment with the given ID. Uploading the same content again returns
+	// the stored attachment; different content under an existing ID fails with os.ErrExist.
+	Upload(ctx context.Context, attachmentID string, file io.Reader, options UploadOptions) (*Attachment, error)
+
 	// Get retrieves the attachment with the given ID
 	Get(ctx context.Context, attachmentID string) (io.ReadCloser, error)
-	
+
+	// Info returns the description of an attachment, or ErrNotFound
+	Info(ctx context.Context, attachmentID string) (*Attachment, error)
+
 	// Exists checks if an attachment with the given ID exists
 	Exists(ctx context.Context, attachmentID string) (bool, error)
+
+	// List returns the attachments of an observation, oldest first
+	List(ctx context.Context, observationID string) ([]Attachment, error)
+
+	// Delete removes an attachment, and its content once no other attachment shares it
+	Delete(ctx context.Context, attachmentID string) error
 }
 
+// The storage directory holds one content file per distinct SHA-256 under .blobs and one JSON
+// record per attachment under .meta. Attachments saved before content deduplication are plain
+// files named after their ID and stay readable.
+const (
+	blobsDir = ".blobs"
+	metaDir  = ".meta"
+)
+
 type service struct {
 	storagePath string
+	maxSize     int64
+	// cold holds content moved off local disk by the lifecycle policy; nil when not configured
+	cold ColdStore
+
+	// mu serializes changes to records and blobs
+	mu sync.Mutex
 }
 
 func NewService(cfg *config.Config) (Service, error) {
+	return newService(cfg)
+}
+
+func newService(cfg *config.Config) (*service, error) {
+	cold, err := coldStoreFrom(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ensure storage directory exists
 	storagePath := filepath.Join(cfg.DataDir, "attachments")
-	if err := os.MkdirAll(storagePath, 0755); err != nil {
-		return nil, err
+	for _, dir := range []string{blobsDir, metaDir} {
+		if err := os.MkdirAll(filepath.Join(storagePath, dir), 0755); err != nil {
+			return nil, err
+		}
 	}
-	
+
 	return &service{
 		storagePath: storagePath,
+		maxSize:     int64(cfg.AttachmentMaxSizeMB) << 20,
+		cold:        cold,
 	}, nil
 }
 
@@ -41,44 +119,179 @@ func (s *service) getAttachmentPath(attachmentID string) (string, error) {
 	if filepath.IsAbs(attachmentID) || filepath.VolumeName(attachmentID) != "" {
 		return "", os.ErrInvalid
 	}
-	
-	// Clean the path to prevent directory traversal
+
+	// Clean the path to prevent directory traversal; dot names are reserved for the store itself
 	cleanPath := filepath.Clean(attachmentID)
-	if cleanPath == "." || cleanPath == ".." {
+	if cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, ".") {
 		return "", os.ErrInvalid
 	}
-	
+
 	return filepath.Join(s.storagePath, cleanPath), nil
 }
 
+// metaPath returns the location of the record of an attachment
+func (s *service) metaPath(attachmentID string) string {
+	return filepath.Join(s.storagePath, metaDir, url.PathEscape(attachmentID)+".json")
+}
+
+// blobPath returns the location of the content with the given hash
+func (s *service) blobPath(hash string) string {
+	return filepath.Join(s.storagePath, blobsDir, hash)
+}
+
 func (s *service) Save(ctx context.Context, attachmentID string, file io.Reader) error {
-	path, err := s.getAttachmentPath(attachmentID)
+	_, err := s.Upload(ctx, attachmentID, file, UploadOptions{})
+	return err
+}
+
+func (s *service) Upload(ctx context.Context, attachmentID string, file io.Reader, options UploadOptions) (*Attachment, error) {
+	if _, err := s.getAttachmentPath(attachmentID); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so a failed upload never leaves partial content
+	tmp, err := os.CreateTemp(filepath.Join(s.storagePath, blobsDir), ".upload-*")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to create attachment file: %w", err)
 	}
-	
-	// Check if file already exists
-	if _, err := os.Stat(path); err == nil {
-		return os.ErrExist
-	} else if !os.IsNotExist(err) {
-		return err
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	content := file
+	if s.maxSize > 0 {
+		content = io.LimitReader(file, s.maxSize+1)
 	}
-	
-	// Create all parent directories
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+	size, err := io.Copy(io.MultiWriter(tmp, hash), content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
 	}
-	
-	// Create new file
-	dst, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if s.maxSize > 0 && size > s.maxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, s.maxSize)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.info(attachmentID)
+	if err == nil {
+		if existing.Hash == sum {
+			return existing, nil
+		}
+		return nil, os.ErrExist
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	// Identical content uploaded under another ID is stored once
+	if _, err := os.Stat(s.blobPath(sum)); os.IsNotExist(err) {
+		if err := os.Rename(tmp.Name(), s.blobPath(sum)); err != nil {
+			return nil, fmt.Errorf("failed to store attachment: %w", err)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(attachmentID))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	record := &Attachment{
+		ID:            attachmentID,
+		ObservationID: options.ObservationID,
+		Hash:          sum,
+		Size:          size,
+		ContentType:   contentType,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := s.writeRecord(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// writeRecord replaces the record of an attachment atomically
+func (s *service) writeRecord(record *Attachment) error {
+	data, err := json.Marshal(record)
 	if err != nil {
 		return err
 	}
-	defer dst.Close()
-	
-	// Copy file content
-	_, err = io.Copy(dst, file)
-	return err
+	target := s.metaPath(record.ID)
+	if err := os.WriteFile(target+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write attachment record: %w", err)
+	}
+	if err := os.Rename(target+".tmp", target); err != nil {
+		os.Remove(target + ".tmp")
+		return fmt.Errorf("failed to write attachment record: %w", err)
+	}
+	return nil
+}
+
+// readRecord reads the record of an attachment
+func (s *service) readRecord(metaPath string) (*Attachment, error) {
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	var record Attachment
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid attachment record %s: %w", filepath.Base(metaPath), err)
+	}
+	return &record, nil
+}
+
+// info returns the record of an attachment, describing legacy files from their content
+func (s *service) info(attachmentID string) (*Attachment, error) {
+	legacyPath, err := s.getAttachmentPath(attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.readRecord(s.metaPath(attachmentID))
+	if err == nil {
+		return record, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.Open(legacyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, ErrNotFound
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("failed to hash attachment: %w", err)
+	}
+	contentType := mime.TypeByExtension(path.Ext(attachmentID))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Attachment{
+		ID:          attachmentID,
+		Hash:        hex.EncodeToString(hash.Sum(nil)),
+		Size:        stat.Size(),
+		ContentType: contentType,
+		CreatedAt:   stat.ModTime().UTC(),
+	}, nil
 }
 
 func (s *service) Get(ctx context.Context, attachmentID string) (io.ReadCloser, error) {
@@ -86,22 +299,153 @@ func (s *service) Get(ctx context.Context, attachmentID string) (io.ReadCloser,
 	if err != nil {
 		return nil, err
 	}
-	
+
+	record, err := s.readRecord(s.metaPath(attachmentID))
+	if err == nil {
+		file, err := os.Open(s.blobPath(record.Hash))
+		if os.IsNotExist(err) && s.cold != nil {
+			// Moved to cold storage by the lifecycle policy
+			return s.cold.Open(ctx, record.Hash)
+		}
+		return file, err
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
 	return os.Open(path)
 }
 
+func (s *service) Info(ctx context.Context, attachmentID string) (*Attachment, error) {
+	return s.info(attachmentID)
+}
+
 func (s *service) Exists(ctx context.Context, attachmentID string) (bool, error) {
 	path, err := s.getAttachmentPath(attachmentID)
 	if err != nil {
 		return false, err
 	}
-	
-	_, err = os.Stat(path)
-	if err == nil {
-		return true, nil
+
+	for _, candidate := range []string{s.metaPath(attachmentID), path} {
+		_, err = os.Stat(candidate)
+		if err == nil {
+			return true, nil
+		}
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// records returns every attachment record
+func (s *service) records() ([]Attachment, error) {
+	entries, err := os.ReadDir(filepath.Join(s.storagePath, metaDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment records: %w", err)
+	}
+
+	records := make([]Attachment, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		record, err := s.readRecord(filepath.Join(s.storagePath, metaDir, entry.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		records = append(records, *record)
 	}
+	return records, nil
+}
+
+func (s *service) List(ctx context.Context, observationID string) ([]Attachment, error) {
+	records, err := s.records()
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := make([]Attachment, 0)
+	for _, record := range records {
+		if record.ObservationID == observationID {
+			attachments = append(attachments, record)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		if !attachments[i].CreatedAt.Equal(attachments[j].CreatedAt) {
+			return attachments[i].CreatedAt.Before(attachments[j].CreatedAt)
+		}
+		return attachments[i].ID < attachments[j].ID
+	})
+	return attachments, nil
+}
+
+func (s *service) Delete(ctx context.Context, attachmentID string) error {
+	path, err := s.getAttachmentPath(attachmentID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.readRecord(s.metaPath(attachmentID))
 	if os.IsNotExist(err) {
-		return false, nil
+		// Attachments saved before deduplication are removed directly
+		if err := os.Remove(path); err != nil {
+			if os.IsNotExist(err) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to remove attachment: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.metaPath(attachmentID)); err != nil {
+		return fmt.Errorf("failed to remove attachment: %w", err)
+	}
+
+	// Keep the content while another attachment shares it
+	records, err := s.records()
+	if err != nil {
+		return err
+	}
+	for _, other := range records {
+		if other.Hash == record.Hash {
+			return nil
+		}
+	}
+	if err := os.Remove(s.blobPath(record.Hash)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove attachment content: %w", err)
+	}
+	if s.cold != nil {
+		if err := s.cold.Delete(ctx, record.Hash); err != nil {
+			return fmt.Errorf("failed to remove attachment content from cold storage: %w", err)
+		}
+	}
+	return nil
+}
+
+// moveToCold copies content to cold storage and removes it from local disk, returning its size.
+// It fails with os.ErrNotExist when the content is no longer on local disk.
+func (s *service) moveToCold(ctx context.Context, hash string) (int64, error) {
+	data, err := os.ReadFile(s.blobPath(hash))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.cold.Put(ctx, hash, data); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.blobPath(hash)); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to remove moved attachment content: %w", err)
 	}
-	return false, err
+	return int64(len(data)), nil
 }
@@ -0,0 +1,141 @@
+package attachment
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (Service, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	svc, err := NewService(&config.Config{DataDir: dataDir, AttachmentMaxSizeMB: 1})
+	require.NoError(t, err)
+	return svc, filepath.Join(dataDir, "attachments")
+}
+
+func blobCount(t *testing.T, storagePath string) int {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(storagePath, blobsDir))
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestUploadDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	svc, storagePath := newTestService(t)
+
+	photo, err := svc.Upload(ctx, "photo-1.jpg", strings.NewReader("jpeg bytes"), UploadOptions{ObservationID: "obs-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "obs-1", photo.ObservationID)
+	assert.Equal(t, "image/jpeg", photo.ContentType)
+	assert.Equal(t, int64(10), photo.Size)
+	assert.Len(t, photo.Hash, 64)
+
+	// Uploading the same content again is a no-op
+	again, err := svc.Upload(ctx, "photo-1.jpg", strings.NewReader("jpeg bytes"), UploadOptions{ObservationID: "obs-1"})
+	require.NoError(t, err)
+	assert.Equal(t, photo.CreatedAt, again.CreatedAt)
+
+	// Different content under the same ID is a conflict
+	_, err = svc.Upload(ctx, "photo-1.jpg", strings.NewReader("other bytes"), UploadOptions{})
+	assert.ErrorIs(t, err, os.ErrExist)
+
+	// The same content under another ID shares the stored content
+	duplicate, err := svc.Upload(ctx, "photo-2.jpg", strings.NewReader("jpeg bytes"), UploadOptions{ObservationID: "obs-2"})
+	require.NoError(t, err)
+	assert.Equal(t, photo.Hash, duplicate.Hash)
+	assert.Equal(t, 1, blobCount(t, storagePath))
+
+	file, err := svc.Get(ctx, "photo-2.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "jpeg bytes", string(data))
+
+	// Shared content is kept until its last attachment is deleted
+	require.NoError(t, svc.Delete(ctx, "photo-1.jpg"))
+	assert.Equal(t, 1, blobCount(t, storagePath))
+	require.NoError(t, svc.Delete(ctx, "photo-2.jpg"))
+	assert.Equal(t, 0, blobCount(t, storagePath))
+	assert.ErrorIs(t, svc.Delete(ctx, "photo-2.jpg"), ErrNotFound)
+
+	exists, err := svc.Exists(ctx, "photo-2.jpg")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUploadSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, storagePath := newTestService(t)
+
+	_, err := svc.Upload(ctx, "audio.m4a", strings.NewReader(strings.Repeat("a", 1<<20+1)), UploadOptions{})
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, 0, blobCount(t, storagePath))
+
+	_, err = svc.Upload(ctx, "audio.m4a", strings.NewReader(strings.Repeat("a", 1<<20)), UploadOptions{})
+	assert.NoError(t, err)
+}
+
+func TestListByObservation(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t)
+
+	for _, id := range []string{"a.png", "b.png", "c.png"} {
+		observationID := "obs-1"
+		if id == "b.png" {
+			observationID = "obs-2"
+		}
+		_, err := svc.Upload(ctx, id, strings.NewReader(id), UploadOptions{ObservationID: observationID, ContentType: "image/png"})
+		require.NoError(t, err)
+	}
+
+	attachments, err := svc.List(ctx, "obs-1")
+	require.NoError(t, err)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "a.png", attachments[0].ID)
+	assert.Equal(t, "c.png", attachments[1].ID)
+
+	attachments, err = svc.List(ctx, "obs-3")
+	require.NoError(t, err)
+	assert.Empty(t, attachments)
+}
+
+func TestLegacyAttachments(t *testing.T) {
+	ctx := context.Background()
+	svc, storagePath := newTestService(t)
+
+	// Attachments saved before deduplication are plain files named after their ID
+	require.NoError(t, os.WriteFile(filepath.Join(storagePath, "legacy.txt"), []byte("old"), 0644))
+
+	info, err := svc.Info(ctx, "legacy.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Size)
+	assert.Empty(t, info.ObservationID)
+	assert.Equal(t, "text/plain; charset=utf-8", info.ContentType)
+
+	file, err := svc.Get(ctx, "legacy.txt")
+	require.NoError(t, err)
+	file.Close()
+
+	require.NoError(t, svc.Delete(ctx, "legacy.txt"))
+	_, err = svc.Info(ctx, "legacy.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestInvalidAttachmentIDs(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t)
+
+	for _, id := range []string{"../escape", ".blobs/x", ".meta", "/abs"} {
+		_, err := svc.Upload(ctx, id, strings.NewReader("x"), UploadOptions{})
+		assert.ErrorIs(t, err, os.ErrInvalid, id)
+	}
+}
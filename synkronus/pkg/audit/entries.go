@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Log stores an entry in the request audit log
+func (s *service) Log(ctx context.Context, entry Entry) error {
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	if err := s.store.insertEntry(ctx, &entry); err != nil {
+		s.log.Error("Failed to record audit log entry", "error", err, "action", entry.Action, "username", entry.Username)
+		return fmt.Errorf("failed to record audit log entry: %w", err)
+	}
+	return nil
+}
+
+// ListEntries returns request audit log entries, newest first
+func (s *service) ListEntries(ctx context.Context, filter EntryFilter) ([]Entry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	entries, err := s.store.listEntries(ctx, filter)
+	if err != nil {
+		s.log.Error("Failed to query audit log", "error", err)
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	return entries, nil
+}
+
+func (st *sqlStore) insertEntry(ctx context.Context, entry *Entry) error {
+	return st.db.QueryRowContext(ctx, `
+		INSERT INTO audit_log (username, role, impersonator, action, resource, ip, at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		entry.Username, entry.Role, entry.Impersonator, entry.Action, entry.Resource, entry.IP, entry.At).Scan(&entry.ID)
+}
+
+func (st *sqlStore) listEntries(ctx context.Context, filter EntryFilter) ([]Entry, error) {
+	var where []string
+	var args []any
+	if filter.Username != "" {
+		args = append(args, filter.Username)
+		where = append(where, fmt.Sprintf("username = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		where = append(where, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		where = append(where, fmt.Sprintf("at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		where = append(where, fmt.Sprintf("at < $%d", len(args)))
+	}
+	if filter.BeforeID > 0 {
+		args = append(args, filter.BeforeID)
+		where = append(where, fmt.Sprintf("id < $%d", len(args)))
+	}
+	query := "SELECT id, username, role, impersonator, action, resource, ip, at FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Username, &e.Role, &e.Impersonator, &e.Action, &e.Resource, &e.IP, &e.At); err != nil {
+			return nil, fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return entries, nil
+}
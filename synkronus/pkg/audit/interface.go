@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Authentication events recorded in the audit log
+const (
+	EventLoginSucceeded       = "login_succeeded"
+	EventLoginFailed          = "login_failed"
+	EventUserInvited          = "user_invited"
+	EventUserCreated          = "user_created"
+	EventUserDeleted          = "user_deleted"
+	EventPasswordReset        = "password_reset"
+	EventPasswordChanged      = "password_changed"
+	EventImpersonationStarted = "impersonation_started"
+	EventAPIKeyCreated        = "api_key_created"
+	EventAPIKeyRevoked        = "api_key_revoked"
+	EventLogout               = "logout"
+	EventSessionRevoked       = "session_revoked"
+)
+
+// Sensitive operations recorded in the request audit log
+const (
+	ActionUserCreate          = "user.create"
+	ActionUserDelete          = "user.delete"
+	ActionBundlePush          = "app_bundle.push"
+	ActionBundleSwitch        = "app_bundle.switch"
+	ActionDataExport          = "data.export"
+	ActionDataSample          = "data.sample"
+	ActionDataImport          = "data.import"
+	ActionClientUpdate        = "client.update"
+	ActionObservationAssign   = "observation.assign"
+	ActionObservationUnassign = "observation.unassign"
+)
+
+// Alert rules evaluated as events are recorded
+const (
+	// RuleFailedLogins alerts when a username reaches the failed login threshold within the window
+	RuleFailedLogins = "failed_logins"
+	// RuleNewCountry alerts when a user logs in from a country none of their earlier logins came from
+	RuleNewCountry = "new_country"
+	// RuleAdminGrant alerts when an account with the admin role is created
+	RuleAdminGrant = "admin_grant"
+)
+
+// Event is an authentication or account management event
+type Event struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Username is the account the event concerns; for failed logins, the name that was tried
+	Username string `json:"username"`
+	// Actor is the admin who acted, for account management events
+	Actor string `json:"actor,omitempty"`
+	// Role is the role granted by user_created events
+	Role string `json:"role,omitempty"`
+	// Impersonator is the admin who made the request while impersonating Username
+	Impersonator string    `json:"impersonator,omitempty"`
+	IP           string    `json:"ip,omitempty"`
+	Country      string    `json:"country,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// Filter narrows the events returned by List
+type Filter struct {
+	Username string
+	Type     string
+	// Limit caps the number of events returned, newest first
+	Limit int
+}
+
+// Entry is a sensitive operation in the request audit log
+type Entry struct {
+	ID int64 `json:"id"`
+	// Username and Role identify who made the request
+	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
+	// Impersonator is the admin who made the request while impersonating Username
+	Impersonator string `json:"impersonator,omitempty"`
+	Action       string `json:"action"`
+	// Resource names what the action applied to, e.g. "users/alice" or "app-bundle/0003"
+	Resource string    `json:"resource"`
+	IP       string    `json:"ip,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// EntryFilter narrows the entries returned by ListEntries
+type EntryFilter struct {
+	Username string
+	Action   string
+	// From and To bound the time of the entries; zero values leave that end open
+	From time.Time
+	To   time.Time
+	// BeforeID returns only entries older than the given entry, for paging through the log
+	// while it grows
+	BeforeID int64
+	// Limit and Offset page through the entries, newest first
+	Limit  int
+	Offset int
+}
+
+// Alert is a notification raised by a rule
+type Alert struct {
+	Rule    string `json:"rule"`
+	Summary string `json:"summary"`
+	Event   Event  `json:"event"`
+}
+
+// Notifier delivers alerts, e.g. by email or webhook
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Config contains the alert rules and where alerts are sent
+type Config struct {
+	// Rules lists the enabled alert rules
+	Rules []string
+
+	// FailedLoginThreshold is the number of failures for one username within
+	// FailedLoginWindow that raises an alert
+	FailedLoginThreshold int
+	FailedLoginWindow    time.Duration
+
+	// Notifiers receive every alert; without any, alerts are only logged
+	Notifiers []Notifier
+}
+
+// DefaultConfig returns a configuration with all rules enabled, alerting on 5 failed logins
+// within 15 minutes
+func DefaultConfig() Config {
+	return Config{
+		Rules:                []string{RuleFailedLogins, RuleNewCountry, RuleAdminGrant},
+		FailedLoginThreshold: 5,
+		FailedLoginWindow:    15 * time.Minute,
+	}
+}
+
+// Service records authentication events and raises alerts on suspicious ones. It also keeps
+// the request audit log of sensitive operations.
+type Service interface {
+	// Record stores an event and evaluates the alert rules against it. Alerts are delivered
+	// in the background.
+	Record(ctx context.Context, event Event) error
+
+	// List returns recorded events, newest first
+	List(ctx context.Context, filter Filter) ([]Event, error)
+
+	// Log stores an entry in the request audit log
+	Log(ctx context.Context, entry Entry) error
+
+	// ListEntries returns request audit log entries, newest first
+	ListEntries(ctx context.Context, filter EntryFilter) ([]Entry, error)
+}
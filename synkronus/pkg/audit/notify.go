@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier posts alerts as JSON to a URL, e.g. a chat or incident tool integration
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: notifyTimeout}}
+}
+
+// Notify posts the alert
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailConfig contains the SMTP server alerts are sent through
+type EmailConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with the server; empty for an open relay
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailNotifier mails alerts to the configured recipients
+type EmailNotifier struct {
+	config EmailConfig
+	send   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailNotifier creates a notifier mailing alerts through an SMTP server
+func NewEmailNotifier(config EmailConfig) *EmailNotifier {
+	return &EmailNotifier{config: config, send: smtp.SendMail}
+}
+
+// Notify mails the alert. net/smtp cannot be cancelled, so ctx is not honoured once sending started.
+func (n *EmailNotifier) Notify(ctx context.Context, alert Alert) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	if err := n.send(addr, auth, n.config.From, n.config.To, n.message(alert)); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err)
+	}
+	return nil
+}
+
+// message formats an alert as a plain text email
+func (n *EmailNotifier) message(alert Alert) []byte {
+	e := alert.Event
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	fmt.Fprintf(&b, "Subject: [synkronus] Security alert: %s\r\n", strings.ReplaceAll(alert.Summary, "\n", " "))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "%s\r\n\r\n", alert.Summary)
+	fmt.Fprintf(&b, "Rule:     %s\r\n", alert.Rule)
+	fmt.Fprintf(&b, "Event:    %s\r\n", e.Type)
+	fmt.Fprintf(&b, "Username: %s\r\n", e.Username)
+	if e.Actor != "" {
+		fmt.Fprintf(&b, "Actor:    %s\r\n", e.Actor)
+	}
+	if e.IP != "" {
+		fmt.Fprintf(&b, "IP:       %s\r\n", e.IP)
+	}
+	if e.Country != "" {
+		fmt.Fprintf(&b, "Country:  %s\r\n", e.Country)
+	}
+	fmt.Fprintf(&b, "Time:     %s\r\n", e.At.UTC().Format(time.RFC3339))
+	return []byte(b.String())
+}
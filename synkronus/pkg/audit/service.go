@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// Limits on the number of events List returns
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// notifyTimeout bounds the delivery of one alert to one notifier
+const notifyTimeout = 30 * time.Second
+
+// store persists events; the rules read the history they need through it
+type store interface {
+	insert(ctx context.Context, event *Event) error
+	// countSince counts events of a type for a username at or after since
+	countSince(ctx context.Context, eventType, username string, since time.Time) (int, error)
+	// loginCountries returns the countries of a user's successful logins before the given event
+	loginCountries(ctx context.Context, username string, beforeID int64) (map[string]bool, error)
+	list(ctx context.Context, filter Filter) ([]Event, error)
+	insertEntry(ctx context.Context, entry *Entry) error
+	listEntries(ctx context.Context, filter EntryFilter) ([]Entry, error)
+}
+
+type service struct {
+	store  store
+	config Config
+	log    *logger.Logger
+	// pending tracks alerts still being delivered
+	pending sync.WaitGroup
+}
+
+// NewService creates a new audit service storing events in the auth_events table and the
+// request audit log in the audit_log table
+func NewService(db *sql.DB, config Config, log *logger.Logger) Service {
+	return newService(&sqlStore{db: db}, config, log)
+}
+
+func newService(store store, config Config, log *logger.Logger) *service {
+	return &service{store: store, config: config, log: log}
+}
+
+// Record stores an event and raises alerts for the rules it trips
+func (s *service) Record(ctx context.Context, event Event) error {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	if err := s.store.insert(ctx, &event); err != nil {
+		s.log.Error("Failed to record auth event", "error", err, "type", event.Type, "username", event.Username)
+		return fmt.Errorf("failed to record auth event: %w", err)
+	}
+
+	alerts, err := s.evaluate(ctx, event)
+	if err != nil {
+		// The event is stored; a missed alert must not fail the request that caused it
+		s.log.Error("Failed to evaluate alert rules", "error", err, "type", event.Type, "username", event.Username)
+	}
+	for _, alert := range alerts {
+		s.raise(alert)
+	}
+	return nil
+}
+
+// evaluate returns the alerts an event raises under the enabled rules
+func (s *service) evaluate(ctx context.Context, event Event) ([]Alert, error) {
+	var alerts []Alert
+	enabled := func(rule string) bool { return slices.Contains(s.config.Rules, rule) }
+
+	switch event.Type {
+	case EventLoginFailed:
+		if !enabled(RuleFailedLogins) || s.config.FailedLoginThreshold <= 0 {
+			break
+		}
+		failures, err := s.store.countSince(ctx, EventLoginFailed, event.Username, event.At.Add(-s.config.FailedLoginWindow))
+		if err != nil {
+			return alerts, err
+		}
+		// Alert once per burst, when the threshold is reached
+		if failures == s.config.FailedLoginThreshold {
+			alerts = append(alerts, Alert{
+				Rule:    RuleFailedLogins,
+				Summary: fmt.Sprintf("%d failed logins for %q within %s", failures, event.Username, s.config.FailedLoginWindow),
+				Event:   event,
+			})
+		}
+
+	case EventLoginSucceeded:
+		if !enabled(RuleNewCountry) || event.Country == "" {
+			break
+		}
+		countries, err := s.store.loginCountries(ctx, event.Username, event.ID)
+		if err != nil {
+			return alerts, err
+		}
+		// A user's first login sets their baseline
+		if len(countries) > 0 && !countries[event.Country] {
+			alerts = append(alerts, Alert{
+				Rule:    RuleNewCountry,
+				Summary: fmt.Sprintf("%q logged in from a new country: %s", event.Username, event.Country),
+				Event:   event,
+			})
+		}
+
+	case EventUserCreated:
+		if enabled(RuleAdminGrant) && event.Role == string(models.RoleAdmin) {
+			alerts = append(alerts, Alert{
+				Rule:    RuleAdminGrant,
+				Summary: fmt.Sprintf("%q was granted the admin role by %q", event.Username, event.Actor),
+				Event:   event,
+			})
+		}
+	}
+	return alerts, nil
+}
+
+// raise logs an alert and delivers it to every notifier in the background
+func (s *service) raise(alert Alert) {
+	s.log.Warn("Security alert", "rule", alert.Rule, "summary", alert.Summary)
+	for _, notifier := range s.config.Notifiers {
+		s.pending.Add(1)
+		go func(notifier Notifier) {
+			defer s.pending.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := notifier.Notify(ctx, alert); err != nil {
+				s.log.Error("Failed to deliver security alert", "error", err, "rule", alert.Rule)
+			}
+		}(notifier)
+	}
+}
+
+// List returns recorded events, newest first
+func (s *service) List(ctx context.Context, filter Filter) ([]Event, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	events, err := s.store.list(ctx, filter)
+	if err != nil {
+		s.log.Error("Failed to query auth events", "error", err)
+		return nil, fmt.Errorf("failed to query auth events: %w", err)
+	}
+	return events, nil
+}
+
+// sqlStore keeps events in the auth_events table
+type sqlStore struct {
+	db *sql.DB
+}
+
+func (st *sqlStore) insert(ctx context.Context, event *Event) error {
+	return st.db.QueryRowContext(ctx, `
+		INSERT INTO auth_events (type, username, actor, role, impersonator, ip, country, at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		event.Type, event.Username, event.Actor, event.Role, event.Impersonator, event.IP, event.Country, event.At).Scan(&event.ID)
+}
+
+func (st *sqlStore) countSince(ctx context.Context, eventType, username string, since time.Time) (int, error) {
+	var count int
+	err := st.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM auth_events WHERE type = $1 AND username = $2 AND at >= $3",
+		eventType, username, since).Scan(&count)
+	return count, err
+}
+
+func (st *sqlStore) loginCountries(ctx context.Context, username string, beforeID int64) (map[string]bool, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT DISTINCT country FROM auth_events
+		WHERE type = $1 AND username = $2 AND id < $3 AND country <> ''`,
+		EventLoginSucceeded, username, beforeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	countries := make(map[string]bool)
+	for rows.Next() {
+		var country string
+		if err := rows.Scan(&country); err != nil {
+			return nil, err
+		}
+		countries[country] = true
+	}
+	return countries, rows.Err()
+}
+
+func (st *sqlStore) list(ctx context.Context, filter Filter) ([]Event, error) {
+	var where []string
+	var args []any
+	if filter.Username != "" {
+		args = append(args, filter.Username)
+		where = append(where, fmt.Sprintf("username = $%d", len(args)))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		where = append(where, fmt.Sprintf("type = $%d", len(args)))
+	}
+	query := "SELECT id, type, username, actor, role, impersonator, ip, country, at FROM auth_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Username, &e.Actor, &e.Role, &e.Impersonator, &e.IP, &e.Country, &e.At); err != nil {
+			return nil, fmt.Errorf("failed to scan auth event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,296 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore keeps events and audit log entries in memory
+type memStore struct {
+	events  []Event
+	entries []Entry
+}
+
+func (m *memStore) insert(ctx context.Context, event *Event) error {
+	event.ID = int64(len(m.events) + 1)
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func (m *memStore) countSince(ctx context.Context, eventType, username string, since time.Time) (int, error) {
+	count := 0
+	for _, e := range m.events {
+		if e.Type == eventType && e.Username == username && !e.At.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memStore) loginCountries(ctx context.Context, username string, beforeID int64) (map[string]bool, error) {
+	countries := make(map[string]bool)
+	for _, e := range m.events {
+		if e.Type == EventLoginSucceeded && e.Username == username && e.ID < beforeID && e.Country != "" {
+			countries[e.Country] = true
+		}
+	}
+	return countries, nil
+}
+
+func (m *memStore) list(ctx context.Context, filter Filter) ([]Event, error) {
+	events := make([]Event, 0)
+	for i := len(m.events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		e := m.events[i]
+		if (filter.Username == "" || e.Username == filter.Username) && (filter.Type == "" || e.Type == filter.Type) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *memStore) insertEntry(ctx context.Context, entry *Entry) error {
+	entry.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *memStore) listEntries(ctx context.Context, filter EntryFilter) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	skipped := 0
+	for i := len(m.entries) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		e := m.entries[i]
+		if (filter.Username != "" && e.Username != filter.Username) || (filter.Action != "" && e.Action != filter.Action) ||
+			(!filter.From.IsZero() && e.At.Before(filter.From)) || (!filter.To.IsZero() && !e.At.Before(filter.To)) ||
+			(filter.BeforeID > 0 && e.ID >= filter.BeforeID) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// recorder collects delivered alerts
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Notify(ctx context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *recorder) rules() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rules := make([]string, 0, len(r.alerts))
+	for _, alert := range r.alerts {
+		rules = append(rules, alert.Rule)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func setupService() (*service, *recorder) {
+	notifier := &recorder{}
+	config := DefaultConfig()
+	config.Notifiers = []Notifier{notifier}
+	return newService(&memStore{}, config, logger.NewLogger()), notifier
+}
+
+func TestRecord_FailedLogins(t *testing.T) {
+	s, notifier := setupService()
+	ctx := context.Background()
+	start := time.Date(2025, 9, 18, 8, 0, 0, 0, time.UTC)
+
+	// Four failures spread over more than the window stay quiet
+	for i := 0; i < 4; i++ {
+		require.NoError(t, s.Record(ctx, Event{Type: EventLoginFailed, Username: "admin", At: start.Add(time.Duration(i) * 10 * time.Minute)}))
+	}
+	s.pending.Wait()
+	assert.Empty(t, notifier.rules())
+
+	// Five within 15 minutes alert once, further failures do not repeat it
+	for i := 0; i < 7; i++ {
+		require.NoError(t, s.Record(ctx, Event{Type: EventLoginFailed, Username: "admin", At: start.Add(time.Hour + time.Duration(i)*time.Minute)}))
+	}
+	s.pending.Wait()
+	assert.Equal(t, []string{RuleFailedLogins}, notifier.rules())
+	assert.Contains(t, notifier.alerts[0].Summary, `5 failed logins for "admin"`)
+
+	// Failures of other usernames count separately
+	require.NoError(t, s.Record(ctx, Event{Type: EventLoginFailed, Username: "bob", At: start.Add(time.Hour)}))
+	s.pending.Wait()
+	assert.Len(t, notifier.rules(), 1)
+}
+
+func TestRecord_NewCountry(t *testing.T) {
+	s, notifier := setupService()
+	ctx := context.Background()
+
+	login := func(country string) {
+		require.NoError(t, s.Record(ctx, Event{Type: EventLoginSucceeded, Username: "alice", Country: country}))
+		s.pending.Wait()
+	}
+
+	// The first login and logins without a known country set no alert
+	login("KE")
+	login("")
+	login("KE")
+	assert.Empty(t, notifier.rules())
+
+	login("NL")
+	assert.Equal(t, []string{RuleNewCountry}, notifier.rules())
+	login("NL")
+	assert.Len(t, notifier.rules(), 1)
+}
+
+func TestRecord_AdminGrant(t *testing.T) {
+	s, notifier := setupService()
+	ctx := context.Background()
+
+	require.NoError(t, s.Record(ctx, Event{Type: EventUserCreated, Username: "bob", Actor: "admin", Role: "read-write"}))
+	require.NoError(t, s.Record(ctx, Event{Type: EventUserCreated, Username: "eve", Actor: "admin", Role: "admin"}))
+	s.pending.Wait()
+	assert.Equal(t, []string{RuleAdminGrant}, notifier.rules())
+	assert.Equal(t, "eve", notifier.alerts[0].Event.Username)
+}
+
+func TestRecord_RulesDisabled(t *testing.T) {
+	s, notifier := setupService()
+	s.config.Rules = []string{RuleNewCountry}
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Record(ctx, Event{Type: EventLoginFailed, Username: "admin"}))
+	}
+	require.NoError(t, s.Record(ctx, Event{Type: EventUserCreated, Username: "eve", Role: "admin"}))
+	s.pending.Wait()
+	assert.Empty(t, notifier.rules())
+}
+
+func TestList(t *testing.T) {
+	s, _ := setupService()
+	ctx := context.Background()
+	for _, e := range []Event{
+		{Type: EventLoginSucceeded, Username: "alice"},
+		{Type: EventLoginFailed, Username: "bob"},
+		{Type: EventLoginSucceeded, Username: "bob"},
+	} {
+		require.NoError(t, s.Record(ctx, e))
+	}
+
+	events, err := s.List(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, int64(3), events[0].ID)
+	assert.False(t, events[0].At.IsZero())
+
+	events, err = s.List(ctx, Filter{Username: "bob", Type: EventLoginFailed})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].ID)
+}
+
+func TestListEntries(t *testing.T) {
+	s, notifier := setupService()
+	ctx := context.Background()
+	start := time.Date(2025, 9, 29, 8, 0, 0, 0, time.UTC)
+	for i, e := range []Entry{
+		{Username: "admin", Action: ActionUserCreate, Resource: "users/eve"},
+		{Username: "admin", Action: ActionBundlePush, Resource: "app-bundle/0002"},
+		{Username: "alice", Action: ActionDataExport, Resource: "dataexport/parquet"},
+		{Username: "admin", Action: ActionDataExport, Resource: "backup/users"},
+	} {
+		e.At = start.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, s.Log(ctx, e))
+	}
+	// The request audit log raises no alerts, even for admin accounts
+	s.pending.Wait()
+	assert.Empty(t, notifier.rules())
+
+	entries, err := s.ListEntries(ctx, EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "backup/users", entries[0].Resource)
+
+	entries, err = s.ListEntries(ctx, EntryFilter{Action: ActionDataExport})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "alice", entries[1].Username)
+
+	entries, err = s.ListEntries(ctx, EntryFilter{Username: "admin", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActionBundlePush, entries[0].Action)
+
+	entries, err = s.ListEntries(ctx, EntryFilter{From: start.Add(time.Hour), To: start.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, int64(3), entries[0].ID)
+	assert.Equal(t, int64(2), entries[1].ID)
+
+	entries, err = s.ListEntries(ctx, EntryFilter{BeforeID: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActionUserCreate, entries[0].Action)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	alert := Alert{Rule: RuleAdminGrant, Summary: "granted", Event: Event{Type: EventUserCreated, Username: "eve"}}
+	require.NoError(t, NewWebhookNotifier(server.URL).Notify(context.Background(), alert))
+	assert.Equal(t, RuleAdminGrant, received.Rule)
+	assert.Equal(t, "eve", received.Event.Username)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	assert.Error(t, NewWebhookNotifier(failing.URL).Notify(context.Background(), alert))
+}
+
+func TestEmailNotifier(t *testing.T) {
+	notifier := NewEmailNotifier(EmailConfig{Host: "smtp.example.org", Port: 587, From: "synkronus@example.org", To: []string{"ops@example.org"}})
+	var addr string
+	var message string
+	notifier.send = func(a string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		addr, message = a, string(msg)
+		assert.Nil(t, auth)
+		assert.Equal(t, []string{"ops@example.org"}, to)
+		return nil
+	}
+
+	alert := Alert{Rule: RuleNewCountry, Summary: `"alice" logged in from a new country: NL`,
+		Event: Event{Type: EventLoginSucceeded, Username: "alice", IP: "203.0.113.9", Country: "NL"}}
+	require.NoError(t, notifier.Notify(context.Background(), alert))
+	assert.Equal(t, "smtp.example.org:587", addr)
+	assert.Contains(t, message, "Subject: [synkronus] Security alert: \"alice\" logged in from a new country: NL\r\n")
+	assert.Contains(t, message, "IP:       203.0.113.9\r\n")
+	assert.True(t, strings.HasPrefix(message, "From: synkronus@example.org\r\n"))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/opendataensemble/synkronus/internal/models"
 	"github.com/opendataensemble/synkronus/internal/repository"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,6 +26,18 @@ type Config struct {
 	AdminUsername string
 	// AdminPassword is the default admin password
 	AdminPassword string
+	// PasswordHashAlgorithm is the algorithm new password hashes are created with: argon2id or
+	// bcrypt. Hashes made otherwise still verify and are replaced at the user's next login.
+	PasswordHashAlgorithm string
+	// Argon2 holds the argon2id cost parameters
+	Argon2 Argon2Params
+	// SigningAlgorithm is HS256 to sign tokens with JWTSecret, or ES256, RS256 or EdDSA to sign
+	// them with rotating keys that other services can fetch from the JWKS endpoint
+	SigningAlgorithm string
+	// KeyRotationInterval is how long each asymmetric key signs tokens before the next takes over
+	KeyRotationInterval time.Duration
+	// PreviousJWTSecrets still validate HS256 tokens and decrypt stored keys after JWTSecret changed
+	PreviousJWTSecrets []string
 }
 
 // DefaultConfig returns a default configuration
@@ -35,6 +48,10 @@ func DefaultConfig() Config {
 		RefreshTokenExpiration: time.Hour * 24 * 7,
 		AdminUsername:          "admin",
 		AdminPassword:          "admin",
+		PasswordHashAlgorithm:  HashAlgorithmArgon2id,
+		Argon2:                 DefaultArgon2Params(),
+		SigningAlgorithm:       SigningHS256,
+		KeyRotationInterval:    DefaultKeyRotationInterval,
 	}
 }
 
@@ -42,14 +59,70 @@ func DefaultConfig() Config {
 type AuthClaims struct {
 	Username string      `json:"username"`
 	Role     models.Role `json:"role"`
+	// Scope limits what the token grants; empty for full access and refresh tokens
+	Scope string `json:"scope,omitempty"`
+	// Act names the admin impersonating the user; nil for the user's own tokens
+	Act *ActorClaim `json:"act,omitempty"`
+	// SessionID names the session a refresh token belongs to; empty for access tokens and for
+	// refresh tokens when sessions are not stored
+	SessionID string `json:"sid,omitempty"`
+	// Tenant names the tenant of the user; empty for users of the default tenant
+	Tenant string `json:"tenant,omitempty"`
+	// TokenType is TokenTypeRefresh for refresh tokens and empty for all others
+	TokenType string `json:"typ,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// TokenTypeRefresh marks refresh tokens, which are only accepted by RefreshToken and Logout
+const TokenTypeRefresh = "refresh"
+
+// tenantClaim returns the tenant claim of a user's tokens
+func tenantClaim(user *models.User) string {
+	if user.TenantID == tenant.Default {
+		return ""
+	}
+	return user.TenantID
+}
+
 // Service provides authentication functionality
 type Service struct {
 	config         Config
 	userRepository repository.UserRepositoryInterface
 	log            *logger.Logger
+	// keys signs and validates asymmetrically signed tokens; nil when tokens are signed with the JWT secret
+	keys *keyring
+	// sessions stores refresh token sessions; nil when refresh tokens are stateless
+	sessions repository.SessionRepositoryInterface
+}
+
+// Option configures an optional dependency of a Service
+type Option func(*Service)
+
+// WithSigningKeys stores the rotating signing keys in repo. It only takes effect when
+// Config.SigningAlgorithm is asymmetric.
+func WithSigningKeys(repo repository.SigningKeyRepositoryInterface) Option {
+	return func(s *Service) {
+		if !IsAsymmetric(s.config.SigningAlgorithm) {
+			return
+		}
+		interval := s.config.KeyRotationInterval
+		if interval <= keyPrepublish {
+			interval = DefaultKeyRotationInterval
+		}
+		lifetime := s.config.TokenExpiration
+		if s.config.RefreshTokenExpiration > lifetime {
+			lifetime = s.config.RefreshTokenExpiration
+		}
+		s.keys = &keyring{
+			repo:          repo,
+			log:           s.log,
+			algorithm:     s.config.SigningAlgorithm,
+			secrets:       s.secrets(),
+			interval:      interval,
+			tokenLifetime: lifetime,
+			now:           time.Now,
+		}
+	}
 }
 
 // Config returns the service configuration
@@ -58,16 +131,39 @@ func (s *Service) Config() Config {
 }
 
 // NewService creates a new authentication service
-func NewService(config Config, userRepo repository.UserRepositoryInterface, log *logger.Logger) *Service {
-	return &Service{
+func NewService(config Config, userRepo repository.UserRepositoryInterface, log *logger.Logger, opts ...Option) *Service {
+	config.Argon2 = config.Argon2.withDefaults()
+	s := &Service{
 		config:         config,
 		userRepository: userRepo,
 		log:            log,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// secrets returns the current JWT secret followed by the previous ones
+func (s *Service) secrets() [][]byte {
+	secrets := [][]byte{[]byte(s.config.JWTSecret)}
+	for _, secret := range s.config.PreviousJWTSecrets {
+		if secret != "" {
+			secrets = append(secrets, []byte(secret))
+		}
+	}
+	return secrets
 }
 
 // Initialize sets up the authentication service
 func (s *Service) Initialize(ctx context.Context) error {
+	// Make sure a signing key exists before the first login
+	if s.keys != nil {
+		if err := s.keys.rotate(ctx); err != nil {
+			return fmt.Errorf("failed to prepare signing keys: %w", err)
+		}
+	}
+
 	// Hash the admin password
 	hashedPassword, err := s.HashPassword(s.config.AdminPassword)
 	if err != nil {
@@ -82,8 +178,15 @@ func (s *Service) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// HashPassword hashes a password using bcrypt
+// HashPassword hashes a password using the configured algorithm
 func (s *Service) HashPassword(password string) (string, error) {
+	if s.hashAlgorithm() == HashAlgorithmArgon2id {
+		hash, err := hashArgon2id(password, s.config.Argon2)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return hash, nil
+	}
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
@@ -91,10 +194,9 @@ func (s *Service) HashPassword(password string) (string, error) {
 	return string(hashedBytes), nil
 }
 
-// VerifyPassword checks if a password matches a hash
+// VerifyPassword checks if a password matches a bcrypt or argon2id hash
 func (s *Service) VerifyPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return verifyHash(password, hash)
 }
 
 // CheckPasswordHash is an alias for VerifyPassword to implement the ServiceInterface
@@ -117,6 +219,11 @@ func (s *Service) Authenticate(ctx context.Context, username, password string) (
 		return nil, errors.New("invalid credentials")
 	}
 
+	// Upgrade hashes made with another algorithm or weaker parameters while the password is at hand
+	if s.NeedsRehash(user.PasswordHash) {
+		s.rehash(ctx, user, password)
+	}
+
 	return user, nil
 }
 
@@ -127,6 +234,7 @@ func (s *Service) GenerateToken(user *models.User) (string, error) {
 	claims := &AuthClaims{
 		Username: user.Username,
 		Role:     user.Role,
+		Tenant:   tenantClaim(user),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -134,9 +242,7 @@ func (s *Service) GenerateToken(user *models.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString([]byte(s.config.JWTSecret))
+	tokenString, err := s.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -144,40 +250,108 @@ func (s *Service) GenerateToken(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
-// GenerateRefreshToken creates a new refresh token for a user
-func (s *Service) GenerateRefreshToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(s.config.RefreshTokenExpiration)
-
-	claims := &AuthClaims{
-		Username: user.Username,
-		Role:     user.Role, // Include role in refresh token as well
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   user.ID.String(),
-		},
+// GenerateRefreshToken creates a new refresh token for a user. When sessions are stored it
+// starts a session for client that the token belongs to.
+func (s *Service) GenerateRefreshToken(ctx context.Context, user *models.User, client SessionClient) (string, error) {
+	if s.sessions != nil {
+		return s.startSession(ctx, user, client)
 	}
+	return s.signRefreshToken(user, "", time.Now().Add(s.config.RefreshTokenExpiration))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// sign signs claims with the current signing key, or with the JWT secret
+func (s *Service) sign(claims *AuthClaims) (string, error) {
+	if s.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.JWTSecret))
+	}
 
-	tokenString, err := token.SignedString([]byte(s.config.JWTSecret))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	key, err := s.keys.signer(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign refresh token: %w", err)
+		return "", err
 	}
+	token := jwt.NewWithClaims(signingMethods[key.algorithm], claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
 
-	return tokenString, nil
+// verificationKey picks the key a token is verified with. HS256 tokens are checked against the
+// current and previous JWT secrets, so tokens issued before switching to key pairs or before a
+// secret change stay valid until they expire. Other tokens are checked against the stored key
+// their kid header names, which must have been made for the token's algorithm.
+func (s *Service) verificationKey(token *jwt.Token) (any, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		set := jwt.VerificationKeySet{}
+		for _, secret := range s.secrets() {
+			set.Keys = append(set.Keys, secret)
+		}
+		return set, nil
+	case *jwt.SigningMethodECDSA, *jwt.SigningMethodRSA, *jwt.SigningMethodEd25519:
+		if s.keys == nil {
+			return nil, fmt.Errorf("%s tokens are not accepted", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		key, ok := s.keys.publicKey(ctx, kid, token.Method.Alg())
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+// JWKS returns the public keys tokens are signed with; empty when tokens are signed with the JWT secret
+func (s *Service) JWKS() *JWKS {
+	if s.keys == nil {
+		return &JWKS{Keys: []JWK{}}
+	}
+	return s.keys.jwks()
+}
+
+// RunKeyRotation publishes and activates new signing keys on schedule until ctx is
+// cancelled. It returns at once when tokens are signed with the JWT secret.
+func (s *Service) RunKeyRotation(ctx context.Context) {
+	if s.keys == nil {
+		return
+	}
+	ticker := time.NewTicker(keyCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.keys.rotate(ctx); err != nil {
+				s.log.Error("Failed to rotate signing keys", "error", err)
+			}
+		}
+	}
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and returns the claims. Scoped tokens, such as preview
+// tokens, and refresh tokens are rejected.
 func (s *Service) ValidateToken(tokenString string) (*AuthClaims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Scope != "" || claims.TokenType != "" {
+		return nil, ErrScopedToken
+	}
+	return claims, nil
+}
+
+// parseToken verifies a token's signature and expiry and returns its claims
+func (s *Service) parseToken(tokenString string) (*AuthClaims, error) {
 	claims := &AuthClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.verificationKey,
+		jwt.WithValidMethods([]string{SigningHS256, SigningES256, SigningRS256, SigningEdDSA}))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -190,13 +364,22 @@ func (s *Service) ValidateToken(tokenString string) (*AuthClaims, error) {
 	return claims, nil
 }
 
-// RefreshToken validates a refresh token and generates a new access token
+// RefreshToken validates a refresh token and generates a new access token. When sessions are
+// stored the token's session must still be active, and the new refresh token replaces the old
+// one in it.
 func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
-	// Validate the refresh token
-	claims, err := s.ValidateToken(refreshToken)
+	// Validate the refresh token; access tokens cannot be traded for new ones
+	claims, err := s.parseToken(refreshToken)
 	if err != nil {
 		return "", "", fmt.Errorf("invalid refresh token: %w", err)
 	}
+	if claims.TokenType != TokenTypeRefresh || claims.Scope != "" {
+		return "", "", fmt.Errorf("invalid refresh token: %w", ErrScopedToken)
+	}
+	// Impersonation must end when its token expires
+	if claims.Act != nil {
+		return "", "", fmt.Errorf("invalid refresh token: %w", ErrScopedToken)
+	}
 
 	// Get the user
 	user, err := s.userRepository.GetByUsername(ctx, claims.Username)
@@ -214,7 +397,19 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (string
 		return "", "", fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	newRefreshToken, err := s.GenerateRefreshToken(user)
+	var newRefreshToken string
+	switch {
+	case s.sessions == nil:
+		newRefreshToken, err = s.signRefreshToken(user, "", time.Now().Add(s.config.RefreshTokenExpiration))
+	case claims.SessionID == "":
+		// Issued before sessions were stored; it could never be revoked, so it is not honoured
+		err = ErrSessionRevoked
+	default:
+		newRefreshToken, err = s.rotateSession(ctx, user, claims, refreshToken)
+	}
+	if errors.Is(err, ErrSessionRevoked) {
+		return "", "", fmt.Errorf("invalid refresh token: %w", err)
+	}
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
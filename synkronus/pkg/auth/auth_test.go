@@ -127,7 +127,7 @@ func TestRefreshToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// Generate a valid refresh token
-	refreshToken, err := service.GenerateRefreshToken(user)
+	refreshToken, err := service.GenerateRefreshToken(ctx, user, SessionClient{})
 	require.NoError(t, err)
 	assert.NotEmpty(t, refreshToken)
 
@@ -144,6 +144,35 @@ func TestRefreshToken(t *testing.T) {
 	// the token based on JWT claims rather than storing it in a map like our mock did
 }
 
+func TestRefreshToken_NotAnAccessToken(t *testing.T) {
+	service, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.New(), Username: "acme-admin", Role: models.RoleAdmin, TenantID: "acme"}
+	require.NoError(t, mockRepo.Create(ctx, user))
+
+	refreshToken, err := service.GenerateRefreshToken(ctx, user, SessionClient{})
+	require.NoError(t, err)
+
+	// A refresh token sent as a bearer token does not authenticate anyone, least of all an
+	// admin of the default tenant
+	_, err = service.ValidateToken(refreshToken)
+	assert.ErrorIs(t, err, ErrScopedToken)
+
+	// Refreshing keeps the tenant, and only refresh tokens can be refreshed
+	token, newRefreshToken, err := service.RefreshToken(ctx, refreshToken)
+	require.NoError(t, err)
+	claims, err := service.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.Tenant)
+	refreshClaims, err := service.parseToken(newRefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", refreshClaims.Tenant)
+
+	_, _, err = service.RefreshToken(ctx, token)
+	assert.ErrorIs(t, err, ErrScopedToken)
+}
+
 func TestHashPassword(t *testing.T) {
 	// Setup
 	service, _ := setupTestService()
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationToken(t *testing.T) {
+	service, _ := setupTestService()
+
+	token, claims, err := service.GenerateImpersonationToken(t.Context(), "testuser", "admin", 10*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", claims.Username)
+	assert.Equal(t, "admin", claims.Impersonator())
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), claims.ExpiresAt.Time, 2*time.Second)
+
+	// The token acts as the user and stays flagged
+	validated, err := service.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", validated.Username)
+	assert.Equal(t, "admin", validated.Impersonator())
+
+	// It cannot be traded for tokens without the flag
+	_, _, err = service.RefreshToken(t.Context(), token)
+	assert.ErrorIs(t, err, ErrScopedToken)
+}
+
+func TestImpersonationToken_Restrictions(t *testing.T) {
+	service, _ := setupTestService()
+
+	_, _, err := service.GenerateImpersonationToken(t.Context(), "nobody", "admin", 0)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, _, err = service.GenerateImpersonationToken(t.Context(), "admin", "support", 0)
+	assert.ErrorIs(t, err, ErrCannotImpersonate)
+	_, _, err = service.GenerateImpersonationToken(t.Context(), "testuser", "testuser", 0)
+	assert.ErrorIs(t, err, ErrCannotImpersonate)
+
+	_, claims, err := service.GenerateImpersonationToken(t.Context(), "testuser", "admin", 0)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultImpersonationLifetime), claims.ExpiresAt.Time, 2*time.Second)
+	_, claims, err = service.GenerateImpersonationToken(t.Context(), "testuser", "admin", 24*time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(MaxImpersonationLifetime), claims.ExpiresAt.Time, 2*time.Second)
+}
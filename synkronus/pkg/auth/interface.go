@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	"github.com/opendataensemble/synkronus/internal/models"
 )
@@ -17,19 +18,44 @@ type AuthServiceInterface interface {
 	// GenerateToken generates a JWT token for the given user
 	GenerateToken(user *models.User) (string, error)
 
-	// GenerateRefreshToken generates a refresh token for the given user
-	GenerateRefreshToken(user *models.User) (string, error)
+	// GenerateRefreshToken generates a refresh token for the given user, starting a session
+	// for the client when sessions are stored
+	GenerateRefreshToken(ctx context.Context, user *models.User, client SessionClient) (string, error)
 
-	// RefreshToken refreshes a token using the given refresh token
+	// RefreshToken refreshes a token using the given refresh token; tokens of revoked sessions
+	// are rejected
 	RefreshToken(ctx context.Context, refreshToken string) (string, string, error)
 
-	// ValidateToken validates a JWT token and returns the claims
+	// Logout revokes the session of a refresh token and returns the username it belonged to,
+	// or "" when the token named no active session
+	Logout(ctx context.Context, refreshToken string) (string, error)
+
+	// ListSessions returns the active sessions of a user, newest first
+	ListSessions(ctx context.Context, username string) ([]models.Session, error)
+
+	// RevokeSession revokes a session of a user
+	RevokeSession(ctx context.Context, username, id string) error
+
+	// RevokeAllSessions revokes every session of a user and returns how many there were
+	RevokeAllSessions(ctx context.Context, username string) (int64, error)
+
+	// ValidateToken validates a JWT token and returns the claims; scoped and refresh tokens are rejected
 	ValidateToken(tokenString string) (*AuthClaims, error)
 
+	// GeneratePreviewToken mints a short-lived token that only grants access to preview bundle files
+	GeneratePreviewToken(issuer string, lifetime time.Duration) (string, time.Time, error)
+
+	// ValidatePreviewToken validates a token minted by GeneratePreviewToken
+	ValidatePreviewToken(tokenString string) (*AuthClaims, error)
+
+	// GenerateImpersonationToken mints a short-lived access token acting as username, flagged
+	// with the impersonating admin
+	GenerateImpersonationToken(ctx context.Context, username, impersonator string, lifetime time.Duration) (string, *AuthClaims, error)
+
 	// Initialize initializes the authentication service
 	Initialize(ctx context.Context) error
 
-	// HashPassword hashes a password using bcrypt
+	// HashPassword hashes a password using the configured algorithm
 	HashPassword(password string) (string, error)
 
 	// CheckPasswordHash compares a password with a hash
@@ -37,4 +63,10 @@ type AuthServiceInterface interface {
 
 	// VerifyPassword checks if a password matches a hash
 	VerifyPassword(password, hash string) bool
+
+	// PasswordHashReport summarizes which users still have hashes made with another algorithm
+	PasswordHashReport(ctx context.Context) (*PasswordHashReport, error)
+
+	// JWKS returns the public keys tokens are signed with
+	JWKS() *JWKS
 }
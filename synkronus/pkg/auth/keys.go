@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/internal/repository"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/secretbox"
+)
+
+// Token signing algorithms
+const (
+	// SigningHS256 signs tokens with the shared JWT secret
+	SigningHS256 = "HS256"
+	// SigningES256 signs tokens with rotating P-256 keys published at /.well-known/jwks.json
+	SigningES256 = "ES256"
+	// SigningRS256 signs tokens with rotating RSA keys, for verifiers without ECDSA support
+	SigningRS256 = "RS256"
+	// SigningEdDSA signs tokens with rotating Ed25519 keys
+	SigningEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA signing keys
+const rsaKeyBits = 2048
+
+// signingMethods maps the asymmetric algorithms to their JWT signing methods
+var signingMethods = map[string]jwt.SigningMethod{
+	SigningES256: jwt.SigningMethodES256,
+	SigningRS256: jwt.SigningMethodRS256,
+	SigningEdDSA: jwt.SigningMethodEdDSA,
+}
+
+// IsAsymmetric reports whether algorithm signs tokens with rotating key pairs
+func IsAsymmetric(algorithm string) bool {
+	_, ok := signingMethods[algorithm]
+	return ok
+}
+
+// DefaultKeyRotationInterval is how long each signing key signs tokens
+const DefaultKeyRotationInterval = 30 * 24 * time.Hour
+
+// keyPrepublish is how long a new key is published before it signs, so services caching
+// the JWKS know it before they see tokens signed with it
+const keyPrepublish = time.Hour
+
+// keyReloadInterval limits how often an unknown key ID reloads the keys from the database
+const keyReloadInterval = time.Minute
+
+// keyCheckInterval is how often RunKeyRotation checks whether a new key is due
+const keyCheckInterval = time.Hour
+
+// ErrNoSigningKey is returned when no key can sign tokens
+var ErrNoSigningKey = errors.New("no signing key available")
+
+// JWK is a public key in JSON Web Key format. EC keys set Crv, X and Y; RSA keys N and E;
+// Ed25519 keys Crv and X.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKS is the set of public keys other services validate tokens with
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// signingKey is a loaded key; private is nil when it cannot be decrypted with the configured secrets
+type signingKey struct {
+	id          string
+	algorithm   string
+	private     crypto.Signer
+	public      crypto.PublicKey
+	activatesAt time.Time
+	expiresAt   time.Time
+}
+
+// keyring holds the signing keys shared by all servers through the database
+type keyring struct {
+	repo repository.SigningKeyRepositoryInterface
+	log  *logger.Logger
+	// algorithm is the algorithm new keys are made for; keys of other algorithms only validate
+	algorithm string
+	// secrets encrypt private keys at rest; the first encrypts, all are tried to decrypt
+	secrets [][]byte
+	// interval is how long a key signs; tokenLifetime how long its tokens stay valid after
+	interval      time.Duration
+	tokenLifetime time.Duration
+	now           func() time.Time
+
+	mu       sync.RWMutex
+	keys     []signingKey // ordered by activation time
+	loadedAt time.Time
+}
+
+// load replaces the keys with those stored in the database
+func (k *keyring) load(ctx context.Context) error {
+	stored, err := k.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := k.now()
+	keys := make([]signingKey, 0, len(stored))
+	for _, s := range stored {
+		if !IsAsymmetric(s.Algorithm) || !s.ExpiresAt.After(now) {
+			continue
+		}
+		public, err := x509.ParsePKIXPublicKey(s.PublicKey)
+		if err != nil {
+			k.log.Warn("Skipping unreadable signing key", "kid", s.ID, "error", err)
+			continue
+		}
+		key := signingKey{id: s.ID, algorithm: s.Algorithm, public: public, activatesAt: s.ActivatesAt, expiresAt: s.ExpiresAt}
+		if der, err := openKey(k.secrets, s.PrivateKey); err == nil {
+			key.private = parsePrivateKey(der)
+		}
+		if key.private == nil {
+			k.log.Warn("Signing key cannot be decrypted with the JWT secret, using it for validation only", "kid", s.ID)
+		}
+		keys = append(keys, key)
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loadedAt = now
+	k.mu.Unlock()
+	return nil
+}
+
+// current returns the newest active key able to sign tokens that stay valid for their full lifetime
+func (k *keyring) current() *signingKey {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		key := k.keys[i]
+		if key.algorithm != k.algorithm || key.activatesAt.After(now) || key.private == nil {
+			continue
+		}
+		if key.expiresAt.Before(now.Add(k.tokenLifetime)) {
+			return nil
+		}
+		return &key
+	}
+	return nil
+}
+
+// rotate reloads the keys, adds the next key when it is due and removes expired ones
+func (k *keyring) rotate(ctx context.Context) error {
+	if err := k.load(ctx); err != nil {
+		return err
+	}
+
+	now := k.now()
+	k.mu.RLock()
+	var latest *signingKey
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if k.keys[i].algorithm == k.algorithm && k.keys[i].private != nil {
+			latest = &k.keys[i]
+			break
+		}
+	}
+	k.mu.RUnlock()
+
+	switch {
+	case k.current() == nil:
+		// Nothing can sign now, e.g. on first start or after the algorithm changed: start
+		// signing with a new key right away
+		if err := k.create(ctx, now); err != nil {
+			return err
+		}
+	case !latest.activatesAt.After(now) && !now.Before(latest.activatesAt.Add(k.interval-keyPrepublish)):
+		// Publish the successor ahead of its activation
+		activatesAt := latest.activatesAt.Add(k.interval)
+		if earliest := now.Add(keyPrepublish); activatesAt.Before(earliest) {
+			activatesAt = earliest
+		}
+		if err := k.create(ctx, activatesAt); err != nil {
+			return err
+		}
+	}
+
+	if removed, err := k.repo.DeleteExpired(ctx, now); err != nil {
+		k.log.Warn("Failed to remove expired signing keys", "error", err)
+	} else if removed > 0 {
+		k.log.Info("Removed expired signing keys", "count", removed)
+	}
+	return nil
+}
+
+// create generates and stores a key that signs from activatesAt on
+func (k *keyring) create(ctx context.Context, activatesAt time.Time) error {
+	private, err := generateKey(k.algorithm)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	sealed, err := sealKey(k.secrets[0], privateDER)
+	if err != nil {
+		return err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate key ID: %w", err)
+	}
+
+	key := &models.SigningKey{
+		ID:          hex.EncodeToString(id),
+		Algorithm:   k.algorithm,
+		PrivateKey:  sealed,
+		PublicKey:   publicDER,
+		CreatedAt:   k.now(),
+		ActivatesAt: activatesAt,
+		// The key may keep signing a little past its interval if the next key is late
+		ExpiresAt: activatesAt.Add(k.interval + keyPrepublish + k.tokenLifetime),
+	}
+	if err := k.repo.Create(ctx, key); err != nil {
+		return err
+	}
+	k.log.Info("Created signing key", "kid", key.ID, "activatesAt", key.ActivatesAt, "expiresAt", key.ExpiresAt)
+	return k.load(ctx)
+}
+
+// signer returns the key to sign with, creating one if none can
+func (k *keyring) signer(ctx context.Context) (*signingKey, error) {
+	if key := k.current(); key != nil {
+		return key, nil
+	}
+	if err := k.rotate(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoSigningKey, err)
+	}
+	if key := k.current(); key != nil {
+		return key, nil
+	}
+	return nil, ErrNoSigningKey
+}
+
+// publicKey returns the key tokens with the given key ID and algorithm are verified with.
+// Unknown IDs reload the keys, at most once per keyReloadInterval, to pick up keys made by
+// other servers.
+func (k *keyring) publicKey(ctx context.Context, id, algorithm string) (crypto.PublicKey, bool) {
+	if key, ok := k.lookup(id, algorithm); ok {
+		return key, true
+	}
+	k.mu.RLock()
+	stale := k.now().Sub(k.loadedAt) >= keyReloadInterval
+	k.mu.RUnlock()
+	if !stale {
+		return nil, false
+	}
+	if err := k.load(ctx); err != nil {
+		k.log.Warn("Failed to reload signing keys", "error", err)
+		return nil, false
+	}
+	return k.lookup(id, algorithm)
+}
+
+// lookup finds an unexpired key; a token naming a key of another algorithm is not verified with it
+func (k *keyring) lookup(id, algorithm string) (crypto.PublicKey, bool) {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.id == id && key.algorithm == algorithm && key.expiresAt.After(now) {
+			return key.public, true
+		}
+	}
+	return nil, false
+}
+
+// jwks returns the public keys of all unexpired keys, including ones not yet active
+func (k *keyring) jwks() *JWKS {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := &JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		if !key.expiresAt.After(now) {
+			continue
+		}
+		jwk, ok := publicJWK(key.public)
+		if !ok {
+			continue
+		}
+		jwk.Kid = key.id
+		jwk.Alg = key.algorithm
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// generateKey creates a private key for an asymmetric algorithm
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case SigningES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case SigningRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case SigningEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+// parsePrivateKey decodes a PKCS #8 private key
+func parsePrivateKey(der []byte) crypto.Signer {
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil
+	}
+	signer, _ := private.(crypto.Signer)
+	return signer
+}
+
+// publicJWK encodes the key material of a public key as a JWK
+func publicJWK(public crypto.PublicKey) (JWK, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch key := public.(type) {
+	case *ecdsa.PublicKey:
+		point, err := key.ECDH()
+		if err != nil {
+			return JWK{}, false
+		}
+		// Uncompressed point: 0x04 || X || Y
+		raw := point.Bytes()
+		return JWK{Kty: "EC", Crv: "P-256", X: encode(raw[1:33]), Y: encode(raw[33:])}, true
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: encode(key)}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// signingKeyPurpose separates the encryption key of signing keys from other sealed values
+const signingKeyPurpose = "synkronus signing key:"
+
+// sealKey encrypts a private key with a JWT secret
+func sealKey(secret, plaintext []byte) ([]byte, error) {
+	sealed, err := secretbox.Seal(signingKeyPurpose, secret, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	return sealed, nil
+}
+
+// openKey decrypts a private key with the first secret that fits
+func openKey(secrets [][]byte, sealed []byte) ([]byte, error) {
+	plaintext, err := secretbox.Open(signingKeyPurpose, secrets, sealed)
+	if errors.Is(err, secretbox.ErrNoSecret) {
+		return nil, errors.New("no secret decrypts the signing key")
+	}
+	return plaintext, err
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupES256Service returns a service signing with rotating keys on a clock the test controls
+func setupES256Service(t *testing.T) (*Service, *mocks.MockSigningKeyRepository, *time.Time) {
+	return setupKeyService(t, SigningES256, mocks.NewMockSigningKeyRepository())
+}
+
+func setupKeyService(t *testing.T, algorithm string, keyRepo *mocks.MockSigningKeyRepository) (*Service, *mocks.MockSigningKeyRepository, *time.Time) {
+	service, _ := setupTestService()
+	service.config.SigningAlgorithm = algorithm
+	service.config.KeyRotationInterval = 30 * 24 * time.Hour
+	WithSigningKeys(keyRepo)(service)
+	require.NotNil(t, service.keys)
+
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	service.keys.now = func() time.Time { return now }
+	require.NoError(t, service.Initialize(context.Background()))
+	return service, keyRepo, &now
+}
+
+func tokenKeyID(t *testing.T, tokenString string) string {
+	return tokenHeader(t, tokenString, SigningES256)
+}
+
+// tokenHeader checks the token's algorithm and returns its key ID
+func tokenHeader(t *testing.T, tokenString, algorithm string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &AuthClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, algorithm, token.Method.Alg())
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+// jwkPublicKey rebuilds a public key from its JWK, as a service validating tokens would
+func jwkPublicKey(t *testing.T, jwk JWK) any {
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		require.NoError(t, err)
+		return b
+	}
+	switch jwk.Kty {
+	case "EC":
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(decode(jwk.X)), Y: new(big.Int).SetBytes(decode(jwk.Y))}
+	case "RSA":
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(decode(jwk.N)), E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64())}
+	case "OKP":
+		return ed25519.PublicKey(decode(jwk.X))
+	}
+	t.Fatalf("unexpected key type %q", jwk.Kty)
+	return nil
+}
+
+func TestSigningKeys_HS256ByDefault(t *testing.T) {
+	service, _ := setupTestService()
+	WithSigningKeys(mocks.NewMockSigningKeyRepository())(service)
+
+	assert.Nil(t, service.keys)
+	assert.Empty(t, service.JWKS().Keys)
+}
+
+func TestSigningKeys_Rotation(t *testing.T) {
+	service, keyRepo, now := setupES256Service(t)
+	ctx := context.Background()
+	user := &models.User{Username: "testuser", Role: models.RoleReadOnly}
+
+	stored, _ := keyRepo.List(ctx)
+	require.Len(t, stored, 1)
+	first := stored[0].ID
+
+	token, err := service.GenerateToken(user)
+	require.NoError(t, err)
+	assert.Equal(t, first, tokenKeyID(t, token))
+
+	jwks := service.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, JWK{Kty: "EC", Crv: "P-256", X: jwks.Keys[0].X, Y: jwks.Keys[0].Y, Kid: first, Alg: SigningES256, Use: "sig"}, jwks.Keys[0])
+
+	// Nothing is due until the prepublish window before the interval ends
+	*now = now.Add(29 * 24 * time.Hour)
+	require.NoError(t, service.keys.rotate(ctx))
+	stored, _ = keyRepo.List(ctx)
+	assert.Len(t, stored, 1)
+
+	// The successor is published before it signs
+	*now = now.Add(24*time.Hour - 30*time.Minute)
+	require.NoError(t, service.keys.rotate(ctx))
+	stored, _ = keyRepo.List(ctx)
+	require.Len(t, stored, 2)
+	second := stored[1].ID
+	assert.Len(t, service.JWKS().Keys, 2)
+
+	token, err = service.GenerateToken(user)
+	require.NoError(t, err)
+	assert.Equal(t, first, tokenKeyID(t, token))
+
+	// Once active it signs, and tokens from the first key stay valid
+	*now = now.Add(time.Hour)
+	rotated, err := service.GenerateToken(user)
+	require.NoError(t, err)
+	assert.Equal(t, second, tokenKeyID(t, rotated))
+
+	claims, err := service.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", claims.Username)
+	_, err = service.ValidateToken(rotated)
+	require.NoError(t, err)
+}
+
+func TestSigningKeys_ExpiredKeysRemoved(t *testing.T) {
+	service, keyRepo, now := setupES256Service(t)
+	ctx := context.Background()
+
+	stored, _ := keyRepo.List(ctx)
+	first := stored[0]
+
+	// Check hourly, as RunKeyRotation does, for two full intervals
+	for i := 0; i < 2*30*24; i++ {
+		*now = now.Add(time.Hour)
+		require.NoError(t, service.keys.rotate(ctx))
+	}
+
+	stored, _ = keyRepo.List(ctx)
+	for _, key := range stored {
+		assert.NotEqual(t, first.ID, key.ID)
+		assert.True(t, key.ExpiresAt.After(*now))
+	}
+	_, ok := service.keys.publicKey(ctx, first.ID, SigningES256)
+	assert.False(t, ok)
+}
+
+func TestSigningKeys_SharedBetweenServers(t *testing.T) {
+	service, keyRepo, now := setupES256Service(t)
+	user := &models.User{Username: "testuser", Role: models.RoleReadOnly}
+
+	// A second server with the same secret signs with the key the first created
+	other, _ := setupTestService()
+	other.config.SigningAlgorithm = SigningES256
+	WithSigningKeys(keyRepo)(other)
+	other.keys.now = func() time.Time { return *now }
+
+	token, err := other.GenerateToken(user)
+	require.NoError(t, err)
+	stored, _ := keyRepo.List(context.Background())
+	require.Len(t, stored, 1)
+	assert.Equal(t, stored[0].ID, tokenKeyID(t, token))
+
+	_, err = service.ValidateToken(token)
+	require.NoError(t, err)
+}
+
+func TestSigningKeys_UnknownKeyRejected(t *testing.T) {
+	service, _, _ := setupES256Service(t)
+
+	stranger, _, _ := setupES256Service(t)
+	token, err := stranger.GenerateToken(&models.User{Username: "testuser", Role: models.RoleReadOnly})
+	require.NoError(t, err)
+
+	_, err = service.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+func TestValidateToken_PreviousSecret(t *testing.T) {
+	old, _ := setupTestService()
+	user := &models.User{Username: "testuser", Role: models.RoleReadOnly}
+	token, err := old.GenerateToken(user)
+	require.NoError(t, err)
+
+	service, _ := setupTestService()
+	service.config.JWTSecret = "new-secret"
+	_, err = service.ValidateToken(token)
+	assert.Error(t, err)
+
+	service.config.PreviousJWTSecrets = []string{"test-secret"}
+	claims, err := service.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", claims.Username)
+
+	// HS256 tokens issued before switching to ES256 keep working until they expire
+	es256, _, _ := setupES256Service(t)
+	_, err = es256.ValidateToken(token)
+	require.NoError(t, err)
+}
+
+func TestSigningKeys_Algorithms(t *testing.T) {
+	user := &models.User{Username: "testuser", Role: models.RoleReadOnly}
+	tests := []struct {
+		algorithm string
+		kty       string
+	}{
+		{SigningES256, "EC"},
+		{SigningRS256, "RSA"},
+		{SigningEdDSA, "OKP"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			service, _, _ := setupKeyService(t, tc.algorithm, mocks.NewMockSigningKeyRepository())
+
+			token, err := service.GenerateToken(user)
+			require.NoError(t, err)
+			kid := tokenHeader(t, token, tc.algorithm)
+			_, err = service.ValidateToken(token)
+			require.NoError(t, err)
+
+			// A downstream service verifies the token with the published public key alone
+			jwks := service.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			jwk := jwks.Keys[0]
+			assert.Equal(t, tc.kty, jwk.Kty)
+			assert.Equal(t, tc.algorithm, jwk.Alg)
+			assert.Equal(t, kid, jwk.Kid)
+
+			parsed, err := jwt.ParseWithClaims(token, &AuthClaims{}, func(*jwt.Token) (any, error) {
+				return jwkPublicKey(t, jwk), nil
+			}, jwt.WithValidMethods([]string{tc.algorithm}))
+			require.NoError(t, err)
+			assert.Equal(t, "testuser", parsed.Claims.(*AuthClaims).Username)
+		})
+	}
+}
+
+func TestSigningKeys_AlgorithmChange(t *testing.T) {
+	user := &models.User{Username: "testuser", Role: models.RoleReadOnly}
+	es256, keyRepo, _ := setupES256Service(t)
+	token, err := es256.GenerateToken(user)
+	require.NoError(t, err)
+	oldKid := tokenKeyID(t, token)
+
+	// Switching to EdDSA starts signing with a new key at once; ES256 tokens stay valid
+	eddsa, _, _ := setupKeyService(t, SigningEdDSA, keyRepo)
+	rotated, err := eddsa.GenerateToken(user)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKid, tokenHeader(t, rotated, SigningEdDSA))
+	assert.Len(t, eddsa.JWKS().Keys, 2)
+
+	_, err = eddsa.ValidateToken(token)
+	require.NoError(t, err)
+
+	// A key is only used for the algorithm it was made for
+	_, ok := eddsa.keys.publicKey(context.Background(), oldKid, SigningRS256)
+	assert.False(t, ok)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash algorithms
+const (
+	// HashAlgorithmBcrypt is the algorithm all passwords were hashed with originally
+	HashAlgorithmBcrypt = "bcrypt"
+	// HashAlgorithmArgon2id is the memory-hard algorithm recommended for new hashes
+	HashAlgorithmArgon2id = "argon2id"
+	// HashAlgorithmUnknown is reported for hashes neither algorithm produced
+	HashAlgorithmUnknown = "unknown"
+)
+
+// ErrInvalidHash is returned when a stored argon2id hash cannot be decoded
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Argon2Params are the cost parameters of argon2id hashes
+type Argon2Params struct {
+	// Memory is the memory used per hash in KiB
+	Memory uint32
+	// Iterations is the number of passes over the memory
+	Iterations uint32
+	// Parallelism is the number of threads used per hash
+	Parallelism uint8
+	// SaltLength and KeyLength are in bytes
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultArgon2Params returns the OWASP recommended minimum: 19 MiB, 2 iterations, 1 thread.
+// It keeps concurrent logins affordable on small edge servers.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// withDefaults fills unset parameters from DefaultArgon2Params
+func (p Argon2Params) withDefaults() Argon2Params {
+	defaults := DefaultArgon2Params()
+	if p.Memory == 0 {
+		p.Memory = defaults.Memory
+	}
+	if p.Iterations == 0 {
+		p.Iterations = defaults.Iterations
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = defaults.Parallelism
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = defaults.SaltLength
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = defaults.KeyLength
+	}
+	return p
+}
+
+// PasswordHashReport summarizes how far stored passwords have moved to the configured algorithm
+type PasswordHashReport struct {
+	// Algorithm is the algorithm new hashes are created with
+	Algorithm string `json:"algorithm"`
+	// Total is the number of users
+	Total int `json:"total"`
+	// ByAlgorithm counts users per hash algorithm
+	ByAlgorithm map[string]int `json:"by_algorithm"`
+	// PendingUsers lists users whose hash is rehashed at their next login
+	PendingUsers []string `json:"pending_users"`
+}
+
+// HashAlgorithmOf returns the algorithm that produced a stored hash
+func HashAlgorithmOf(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return HashAlgorithmArgon2id
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return HashAlgorithmBcrypt
+	default:
+		return HashAlgorithmUnknown
+	}
+}
+
+// hashArgon2id hashes a password into the PHC string format,
+// e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func hashArgon2id(password string, params Argon2Params) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// decodeArgon2id parses a PHC string into its parameters, salt and key
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// verifyArgon2id checks a password against an argon2id hash using the hash's own parameters
+func verifyArgon2id(password, hash string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, candidate) == 1
+}
+
+// hashAlgorithm returns the configured algorithm for new hashes
+func (s *Service) hashAlgorithm() string {
+	if s.config.PasswordHashAlgorithm == HashAlgorithmBcrypt {
+		return HashAlgorithmBcrypt
+	}
+	return HashAlgorithmArgon2id
+}
+
+// NeedsRehash reports whether a stored hash was made with another algorithm or other
+// argon2id parameters than currently configured
+func (s *Service) NeedsRehash(hash string) bool {
+	algorithm := s.hashAlgorithm()
+	if HashAlgorithmOf(hash) != algorithm {
+		return true
+	}
+	if algorithm == HashAlgorithmBcrypt {
+		return false
+	}
+	params, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	wanted := s.config.Argon2
+	return params.Memory != wanted.Memory || params.Iterations != wanted.Iterations ||
+		params.Parallelism != wanted.Parallelism || params.KeyLength != wanted.KeyLength
+}
+
+// rehash replaces a user's verified password hash with one made with the configured algorithm.
+// Failures are only logged: the login itself has already succeeded.
+func (s *Service) rehash(ctx context.Context, user *models.User, password string) {
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		s.log.Warn("Failed to rehash password", "username", user.Username, "error", err)
+		return
+	}
+	previous := user.PasswordHash
+	user.PasswordHash = hash
+	if err := s.userRepository.Update(ctx, user); err != nil {
+		user.PasswordHash = previous
+		s.log.Warn("Failed to store rehashed password", "username", user.Username, "error", err)
+		return
+	}
+	s.log.Info("Password rehashed", "username", user.Username, "algorithm", s.hashAlgorithm())
+}
+
+// PasswordHashReport summarizes which users still have hashes made with another algorithm
+func (s *Service) PasswordHashReport(ctx context.Context) (*PasswordHashReport, error) {
+	users, err := s.userRepository.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	report := &PasswordHashReport{
+		Algorithm:    s.hashAlgorithm(),
+		Total:        len(users),
+		ByAlgorithm:  make(map[string]int),
+		PendingUsers: make([]string, 0),
+	}
+	for _, user := range users {
+		report.ByAlgorithm[HashAlgorithmOf(user.PasswordHash)]++
+		if s.NeedsRehash(user.PasswordHash) {
+			report.PendingUsers = append(report.PendingUsers, user.Username)
+		}
+	}
+	sort.Strings(report.PendingUsers)
+	return report, nil
+}
+
+// verifyHash checks a password against a bcrypt or argon2id hash
+func verifyHash(password, hash string) bool {
+	switch HashAlgorithmOf(hash) {
+	case HashAlgorithmArgon2id:
+		return verifyArgon2id(password, hash)
+	case HashAlgorithmBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgon2idHash(t *testing.T) {
+	service, _ := setupTestService()
+
+	hash, err := service.HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"))
+	assert.Equal(t, HashAlgorithmArgon2id, HashAlgorithmOf(hash))
+
+	assert.True(t, service.VerifyPassword("correct horse", hash))
+	assert.False(t, service.VerifyPassword("wrong horse", hash))
+	assert.False(t, service.VerifyPassword("correct horse", "$argon2id$v=19$m=19456,t=2,p=1$bad"))
+	assert.False(t, service.NeedsRehash(hash))
+
+	// Salts differ, so equal passwords give different hashes
+	other, err := service.HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestAuthenticate_RehashesBcrypt(t *testing.T) {
+	service, repo := setupTestService()
+	ctx := context.Background()
+
+	before, err := repo.GetByUsername(ctx, "testuser")
+	require.NoError(t, err)
+	require.Equal(t, HashAlgorithmBcrypt, HashAlgorithmOf(before.PasswordHash))
+
+	_, err = service.Authenticate(ctx, "testuser", "password123")
+	require.NoError(t, err)
+
+	after, err := repo.GetByUsername(ctx, "testuser")
+	require.NoError(t, err)
+	assert.Equal(t, HashAlgorithmArgon2id, HashAlgorithmOf(after.PasswordHash))
+
+	// The new hash keeps working, and failed logins do not touch it
+	_, err = service.Authenticate(ctx, "testuser", "password123")
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, "admin", "wrong")
+	require.Error(t, err)
+	admin, _ := repo.GetByUsername(ctx, "admin")
+	assert.Equal(t, HashAlgorithmBcrypt, HashAlgorithmOf(admin.PasswordHash))
+}
+
+func TestAuthenticate_RehashesChangedParameters(t *testing.T) {
+	service, repo := setupTestService()
+	ctx := context.Background()
+
+	_, err := service.Authenticate(ctx, "testuser", "password123")
+	require.NoError(t, err)
+
+	service.config.Argon2.Iterations = 3
+	user, _ := repo.GetByUsername(ctx, "testuser")
+	assert.True(t, service.NeedsRehash(user.PasswordHash))
+
+	_, err = service.Authenticate(ctx, "testuser", "password123")
+	require.NoError(t, err)
+	user, _ = repo.GetByUsername(ctx, "testuser")
+	assert.Contains(t, user.PasswordHash, "t=3")
+}
+
+func TestAuthenticate_KeepsBcryptWhenConfigured(t *testing.T) {
+	service, repo := setupTestService()
+	service.config.PasswordHashAlgorithm = HashAlgorithmBcrypt
+	ctx := context.Background()
+
+	_, err := service.Authenticate(ctx, "testuser", "password123")
+	require.NoError(t, err)
+	user, _ := repo.GetByUsername(ctx, "testuser")
+	assert.Equal(t, HashAlgorithmBcrypt, HashAlgorithmOf(user.PasswordHash))
+}
+
+func TestPasswordHashReport(t *testing.T) {
+	service, _ := setupTestService()
+	ctx := context.Background()
+
+	report, err := service.PasswordHashReport(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, HashAlgorithmArgon2id, report.Algorithm)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, map[string]int{HashAlgorithmBcrypt: 2}, report.ByAlgorithm)
+	assert.Equal(t, []string{"admin", "testuser"}, report.PendingUsers)
+
+	_, err = service.Authenticate(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	report, err = service.PasswordHashReport(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{HashAlgorithmBcrypt: 1, HashAlgorithmArgon2id: 1}, report.ByAlgorithm)
+	assert.Equal(t, []string{"testuser"}, report.PendingUsers)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewToken(t *testing.T) {
+	service, _ := setupTestService()
+
+	token, expiresAt, err := service.GeneratePreviewToken("designer", time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, 2*time.Second)
+
+	claims, err := service.ValidatePreviewToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "designer", claims.Username)
+	assert.Equal(t, ScopePreview, claims.Scope)
+	assert.Empty(t, claims.Role)
+
+	// A preview token grants no API access, and an access token is no preview token
+	_, err = service.ValidateToken(token)
+	assert.ErrorIs(t, err, ErrScopedToken)
+	_, _, err = service.RefreshToken(t.Context(), token)
+	assert.Error(t, err)
+
+	access, err := service.GenerateToken(&models.User{Username: "testuser", Role: models.RoleAdmin})
+	require.NoError(t, err)
+	_, err = service.ValidatePreviewToken(access)
+	assert.ErrorIs(t, err, ErrScopedToken)
+}
+
+func TestPreviewToken_Lifetime(t *testing.T) {
+	service, _ := setupTestService()
+
+	_, expiresAt, err := service.GeneratePreviewToken("designer", 0)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultPreviewTokenLifetime), expiresAt, 2*time.Second)
+
+	_, expiresAt, err = service.GeneratePreviewToken("designer", 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(MaxPreviewTokenLifetime), expiresAt, 2*time.Second)
+}
+
+func TestPreviewToken_SignedWithKeys(t *testing.T) {
+	service, _, _ := setupES256Service(t)
+
+	token, _, err := service.GeneratePreviewToken("designer", time.Hour)
+	require.NoError(t, err)
+	tokenKeyID(t, token)
+	_, err = service.ValidatePreviewToken(token)
+	require.NoError(t, err)
+	_, err = service.ValidateToken(token)
+	assert.ErrorIs(t, err, ErrScopedToken)
+}
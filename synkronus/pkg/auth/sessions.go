@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/internal/repository"
+)
+
+// sessionCleanupInterval is how often sessions that have expired are deleted
+const sessionCleanupInterval = time.Hour
+
+// maxUserAgentLength caps the user agent stored with a session
+const maxUserAgentLength = 512
+
+// Session errors
+var (
+	// ErrSessionRevoked is returned when a refresh token's session was revoked or has expired,
+	// or the token was already exchanged for a newer one
+	ErrSessionRevoked = errors.New("session has been revoked")
+	// ErrSessionNotFound is returned when revoking a session the user does not have
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionClient describes the client a session is started from
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// WithSessions stores a session for each login in repo, so refresh tokens can be listed and
+// revoked. Without it refresh tokens are stateless and stay valid until they expire.
+func WithSessions(repo repository.SessionRepositoryInterface) Option {
+	return func(s *Service) {
+		s.sessions = repo
+	}
+}
+
+// hashToken returns the form a refresh token is stored and compared in
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// signRefreshToken signs a refresh token for user that expires at expiresAt. Every token gets
+// its own ID, so tokens of the same session never hash alike.
+func (s *Service) signRefreshToken(user *models.User, sessionID string, expiresAt time.Time) (string, error) {
+	claims := &AuthClaims{
+		Username:  user.Username,
+		Role:      user.Role, // Include role in refresh token as well
+		Tenant:    tenantClaim(user),
+		SessionID: sessionID,
+		TokenType: TokenTypeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   user.ID.String(),
+		},
+	}
+
+	tokenString, err := s.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign refresh token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// startSession stores a new session for user and returns its first refresh token
+func (s *Service) startSession(ctx context.Context, user *models.User, client SessionClient) (string, error) {
+	if len(client.UserAgent) > maxUserAgentLength {
+		client.UserAgent = client.UserAgent[:maxUserAgentLength]
+	}
+	now := time.Now()
+	session := &models.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.RefreshTokenExpiration),
+	}
+
+	token, err := s.signRefreshToken(user, session.ID.String(), session.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	session.TokenHash = hashToken(token)
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return "", fmt.Errorf("failed to store session: %w", err)
+	}
+	return token, nil
+}
+
+// rotateSession checks that refreshToken is the current token of an active session of user and
+// replaces it with a new one, which it returns
+func (s *Service) rotateSession(ctx context.Context, user *models.User, claims *AuthClaims, refreshToken string) (string, error) {
+	id, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return "", ErrSessionRevoked
+	}
+	session, err := s.sessions.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if session == nil || session.UserID != user.ID || session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return "", ErrSessionRevoked
+	}
+
+	oldHash := hashToken(refreshToken)
+	if session.TokenHash != oldHash {
+		// An exchanged token is being replayed, possibly by someone who stole it; ending the
+		// session locks them out at the cost of a new login for its rightful client
+		if _, err := s.sessions.Revoke(ctx, id, user.ID); err != nil {
+			s.log.Error("Failed to revoke session after refresh token reuse", "error", err, "session", id)
+		}
+		s.log.Warn("Refresh token reused, session revoked", "username", user.Username, "session", id)
+		return "", ErrSessionRevoked
+	}
+
+	expiresAt := time.Now().Add(s.config.RefreshTokenExpiration)
+	token, err := s.signRefreshToken(user, session.ID.String(), expiresAt)
+	if err != nil {
+		return "", err
+	}
+	rotated, err := s.sessions.Rotate(ctx, id, oldHash, hashToken(token), expiresAt)
+	if err != nil {
+		return "", err
+	}
+	if !rotated {
+		// Revoked or refreshed concurrently since it was read
+		return "", ErrSessionRevoked
+	}
+	return token, nil
+}
+
+// Logout revokes the session of a refresh token and returns the username it belonged to. Only
+// a session's current token ends it; unknown, expired and stale tokens are ignored and ""
+// is returned.
+func (s *Service) Logout(ctx context.Context, refreshToken string) (string, error) {
+	if s.sessions == nil {
+		return "", nil
+	}
+	claims, err := s.parseToken(refreshToken)
+	if err != nil || claims.TokenType != TokenTypeRefresh {
+		return "", nil
+	}
+	id, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return "", nil
+	}
+
+	session, err := s.sessions.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if session == nil || session.TokenHash != hashToken(refreshToken) {
+		return "", nil
+	}
+	revoked, err := s.sessions.Revoke(ctx, id, session.UserID)
+	if err != nil || !revoked {
+		return "", err
+	}
+
+	s.log.Info("Session ended", "username", claims.Username, "session", id)
+	return claims.Username, nil
+}
+
+// sessionUser returns the user whose sessions are managed
+func (s *Service) sessionUser(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.userRepository.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ListSessions returns the active sessions of username, newest first
+func (s *Service) ListSessions(ctx context.Context, username string) ([]models.Session, error) {
+	if s.sessions == nil {
+		return []models.Session{}, nil
+	}
+	user, err := s.sessionUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.sessions.ListActive(ctx, user.ID)
+}
+
+// RevokeSession revokes a session of username, so its refresh token stops working
+func (s *Service) RevokeSession(ctx context.Context, username, id string) error {
+	sessionID, err := uuid.Parse(id)
+	if err != nil || s.sessions == nil {
+		return ErrSessionNotFound
+	}
+	user, err := s.sessionUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.sessions.Revoke(ctx, sessionID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	s.log.Info("Session revoked", "username", username, "session", id)
+	return nil
+}
+
+// RevokeAllSessions revokes every session of username and returns how many there were
+func (s *Service) RevokeAllSessions(ctx context.Context, username string) (int64, error) {
+	if s.sessions == nil {
+		return 0, nil
+	}
+	user, err := s.sessionUser(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.sessions.RevokeAll(ctx, user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.log.Info("Sessions revoked", "username", username, "count", count)
+	return count, nil
+}
+
+// RunSessionCleanup deletes expired sessions on schedule until ctx is cancelled. It returns
+// at once when sessions are not stored.
+func (s *Service) RunSessionCleanup(ctx context.Context) {
+	if s.sessions == nil {
+		return
+	}
+	ticker := time.NewTicker(sessionCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.sessions.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				s.log.Error("Failed to delete expired sessions", "error", err)
+			} else if count > 0 {
+				s.log.Info("Deleted expired sessions", "count", count)
+			}
+		}
+	}
+}
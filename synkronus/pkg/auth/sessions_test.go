@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/internal/repository/mocks"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSessionService(t *testing.T) (*Service, *mocks.MockSessionRepository, *models.User) {
+	users := mocks.NewMockUserRepository()
+	sessions := mocks.NewMockSessionRepository()
+	config := Config{
+		JWTSecret:              "test-secret",
+		TokenExpiration:        time.Hour,
+		RefreshTokenExpiration: time.Hour * 24,
+	}
+	service := NewService(config, users, logger.NewLogger(), WithSessions(sessions))
+
+	user := &models.User{ID: uuid.New(), Username: "sessionuser", Role: models.RoleReadWrite}
+	require.NoError(t, users.Create(context.Background(), user))
+	return service, sessions, user
+}
+
+func TestRefreshTokenRotatesSession(t *testing.T) {
+	service, _, user := setupSessionService(t)
+	ctx := context.Background()
+
+	refreshToken, err := service.GenerateRefreshToken(ctx, user, SessionClient{UserAgent: "synk/1.0", IPAddress: "192.0.2.1"})
+	require.NoError(t, err)
+
+	sessions, err := service.ListSessions(ctx, user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "synk/1.0", sessions[0].UserAgent)
+	assert.Equal(t, "192.0.2.1", sessions[0].IPAddress)
+
+	_, rotated, err := service.RefreshToken(ctx, refreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, refreshToken, rotated)
+
+	// The session carries on with the new token
+	sessions, err = service.ListSessions(ctx, user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.NotNil(t, sessions[0].LastUsedAt)
+
+	// Replaying the exchanged token ends the session, so the new token stops working too
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, _, err = service.RefreshToken(ctx, rotated)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	sessions, err = service.ListSessions(ctx, user.Username)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestLogout(t *testing.T) {
+	service, _, user := setupSessionService(t)
+	ctx := context.Background()
+
+	refreshToken, err := service.GenerateRefreshToken(ctx, user, SessionClient{})
+	require.NoError(t, err)
+
+	username, err := service.Logout(ctx, "not-a-token")
+	require.NoError(t, err)
+	assert.Empty(t, username)
+
+	username, err = service.Logout(ctx, refreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.Username, username)
+
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	// Logging out twice is harmless
+	username, err = service.Logout(ctx, refreshToken)
+	require.NoError(t, err)
+	assert.Empty(t, username)
+}
+
+func TestRevokeSessions(t *testing.T) {
+	service, _, user := setupSessionService(t)
+	ctx := context.Background()
+
+	laptop, err := service.GenerateRefreshToken(ctx, user, SessionClient{UserAgent: "laptop"})
+	require.NoError(t, err)
+	phone, err := service.GenerateRefreshToken(ctx, user, SessionClient{UserAgent: strings.Repeat("x", 1000)})
+	require.NoError(t, err)
+
+	sessions, err := service.ListSessions(ctx, user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	var laptopID string
+	for _, session := range sessions {
+		assert.LessOrEqual(t, len(session.UserAgent), maxUserAgentLength)
+		if session.UserAgent == "laptop" {
+			laptopID = session.ID.String()
+		}
+	}
+
+	// Sessions of other users cannot be revoked
+	assert.ErrorIs(t, service.RevokeSession(ctx, "admin", laptopID), ErrSessionNotFound)
+	assert.ErrorIs(t, service.RevokeSession(ctx, user.Username, "not-an-id"), ErrSessionNotFound)
+
+	require.NoError(t, service.RevokeSession(ctx, user.Username, laptopID))
+	_, _, err = service.RefreshToken(ctx, laptop)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, phone, err = service.RefreshToken(ctx, phone)
+	require.NoError(t, err)
+
+	count, err := service.RevokeAllSessions(ctx, user.Username)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, _, err = service.RefreshToken(ctx, phone)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestRefreshTokenWithoutSession(t *testing.T) {
+	service, _, user := setupSessionService(t)
+	ctx := context.Background()
+
+	// Tokens issued before sessions were stored cannot outlive "revoke all"
+	legacy, err := service.signRefreshToken(user, "", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, _, err = service.RefreshToken(ctx, legacy)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	// Nor can an access token be traded for a fresh session
+	token, err := service.GenerateToken(user)
+	require.NoError(t, err)
+	_, err = service.RevokeAllSessions(ctx, user.Username)
+	require.NoError(t, err)
+	_, _, err = service.RefreshToken(ctx, token)
+	assert.Error(t, err)
+}
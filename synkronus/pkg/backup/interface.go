@@ -0,0 +1,57 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// Common errors
+var (
+	// ErrInvalidUser is returned when restoring a user without a username or password hash, or
+	// with an unknown role
+	ErrInvalidUser = errors.New("backed up user needs a username, password hash and valid role")
+	// ErrInvalidObservation is returned when restoring an observation without an id, form type,
+	// form version or data
+	ErrInvalidObservation = errors.New("backed up observation needs an id, form type, form version and data")
+	// ErrOtherTenant is returned when restoring a user or observation whose username or id is
+	// taken by another tenant
+	ErrOtherTenant = errors.New("backed up record is taken by another tenant")
+)
+
+// User is a user account as backed up. The password hash is included so restored users keep
+// their passwords.
+type User struct {
+	Username     string      `json:"username"`
+	PasswordHash string      `json:"password_hash"`
+	Role         models.Role `json:"role"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// RestoreResult counts the records a restore created and the existing records it replaced
+type RestoreResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// Service exports server state for backups and restores it, e.g. onto a fresh server. App
+// bundles are backed up through the app bundle service.
+type Service interface {
+	// ExportUsers returns every user of the tenant in ctx with their password hash
+	ExportUsers(ctx context.Context) ([]User, error)
+
+	// RestoreUsers creates missing users in the tenant in ctx and replaces the password hash and
+	// role of existing ones
+	RestoreUsers(ctx context.Context, users []User) (*RestoreResult, error)
+
+	// ExportObservations calls fn with every observation of the tenant in ctx, deleted ones
+	// included, in version order, and returns how many there were
+	ExportObservations(ctx context.Context, fn func(sync.Observation) error) (int, error)
+
+	// RestoreObservations creates or replaces observations of the tenant in ctx, keeping their
+	// ids, authors and owners. Restored records get new versions so devices pull them again.
+	RestoreObservations(ctx context.Context, records []sync.Observation) (*RestoreResult, error)
+}
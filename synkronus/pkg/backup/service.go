@@ -0,0 +1,233 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new backup service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// ExportUsers returns every user of the tenant with their password hash
+func (s *service) ExportUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT username, password_hash, role, created_at FROM users WHERE tenant_id = $1 ORDER BY created_at, username`,
+		tenant.FromContext(ctx))
+	if err != nil {
+		s.log.Error("Failed to query users for backup", "error", err)
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// RestoreUsers creates missing users and replaces the password hash and role of existing ones.
+// Users of other tenants are left alone and fail the restore.
+func (s *service) RestoreUsers(ctx context.Context, users []User) (*RestoreResult, error) {
+	for _, u := range users {
+		if u.Username == "" || u.PasswordHash == "" ||
+			(u.Role != models.RoleReadOnly && u.Role != models.RoleReadWrite && u.Role != models.RoleAdmin) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidUser, u.Username)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tenantID := tenant.FromContext(ctx)
+	result := &RestoreResult{}
+	for _, u := range users {
+		createdAt := u.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+
+		// xmax is only zero for rows the statement inserted
+		var inserted bool
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (id, username, password_hash, role, created_at, updated_at, tenant_id)
+			VALUES ($1, $2, $3, $4, $5, NOW(), $6)
+			ON CONFLICT (username) DO UPDATE SET
+				password_hash = EXCLUDED.password_hash,
+				role = EXCLUDED.role,
+				updated_at = NOW()
+			WHERE users.tenant_id = EXCLUDED.tenant_id
+			RETURNING xmax = 0`,
+			uuid.New(), u.Username, u.PasswordHash, u.Role, createdAt, tenantID).Scan(&inserted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %q", ErrOtherTenant, u.Username)
+		}
+		if err != nil {
+			s.log.Error("Failed to restore user", "error", err, "username", u.Username)
+			return nil, fmt.Errorf("failed to restore user %s: %w", u.Username, err)
+		}
+		if inserted {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.Info("Users restored", "created", result.Created, "updated", result.Updated)
+	return result, nil
+}
+
+// ExportObservations calls fn with every observation of the tenant in version order
+func (s *service) ExportObservations(ctx context.Context, fn func(sync.Observation) error) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT observation_id, form_type, form_version, data, created_at, updated_at, synced_at,
+			deleted, version, geolocation, draft, created_by, owner, org_unit_id, case_id
+		FROM observations
+		WHERE tenant_id = $1
+		ORDER BY version`, tenant.FromContext(ctx))
+	if err != nil {
+		s.log.Error("Failed to query observations for backup", "error", err)
+		return 0, fmt.Errorf("failed to query observations: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var obs sync.Observation
+		var syncedAt sql.NullString
+		var geolocation []byte
+		err := rows.Scan(
+			&obs.ObservationID, &obs.FormType, &obs.FormVersion, &obs.Data, &obs.CreatedAt, &obs.UpdatedAt, &syncedAt,
+			&obs.Deleted, &obs.Version, &geolocation, &obs.Draft, &obs.CreatedBy, &obs.Owner, &obs.OrgUnitID, &obs.CaseID,
+		)
+		if err != nil {
+			return count, fmt.Errorf("failed to scan observation: %w", err)
+		}
+		if syncedAt.Valid {
+			obs.SyncedAt = &syncedAt.String
+		}
+		if len(geolocation) > 0 {
+			if err := json.Unmarshal(geolocation, &obs.Geolocation); err != nil {
+				return count, fmt.Errorf("failed to decode geolocation of %s: %w", obs.ObservationID, err)
+			}
+		}
+
+		if err := fn(obs); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// RestoreObservations creates or replaces observations of the tenant in one transaction
+func (s *service) RestoreObservations(ctx context.Context, records []sync.Observation) (*RestoreResult, error) {
+	for _, record := range records {
+		if record.ObservationID == "" || record.FormType == "" || record.FormVersion == "" || !json.Valid(record.Data) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidObservation, record.ObservationID)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tenantID := tenant.FromContext(ctx)
+	result := &RestoreResult{}
+	for _, record := range records {
+		var geolocation any
+		if record.Geolocation != nil {
+			encoded, err := json.Marshal(record.Geolocation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode geolocation of %s: %w", record.ObservationID, err)
+			}
+			geolocation = string(encoded)
+		}
+
+		// Org units missing on this server are dropped rather than failing the restore; the
+		// version trigger assigns a new version and updated_at
+		var inserted bool
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO observations (observation_id, form_type, form_version, data, created_at, deleted, draft,
+				draft_owner, geolocation, created_by, owner, org_unit_id, case_id, tenant_id)
+			VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, '')::TIMESTAMPTZ, NOW()), $6, $7,
+				CASE WHEN $7 THEN $10 END, $8, $9, $10, (SELECT id FROM org_units WHERE id::TEXT = $11), $12, $13)
+			ON CONFLICT (observation_id) DO UPDATE SET
+				form_type = EXCLUDED.form_type,
+				form_version = EXCLUDED.form_version,
+				data = EXCLUDED.data,
+				created_at = EXCLUDED.created_at,
+				deleted = EXCLUDED.deleted,
+				draft = EXCLUDED.draft,
+				draft_owner = EXCLUDED.draft_owner,
+				geolocation = EXCLUDED.geolocation,
+				created_by = EXCLUDED.created_by,
+				owner = EXCLUDED.owner,
+				org_unit_id = EXCLUDED.org_unit_id,
+				case_id = EXCLUDED.case_id
+			WHERE observations.tenant_id = EXCLUDED.tenant_id
+			RETURNING xmax = 0`,
+			record.ObservationID, record.FormType, record.FormVersion, record.Data, record.CreatedAt,
+			record.Deleted, record.Draft, geolocation, record.CreatedBy, record.Owner, record.OrgUnitID, record.CaseID,
+			tenantID,
+		).Scan(&inserted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %q", ErrOtherTenant, record.ObservationID)
+		}
+		if err != nil {
+			s.log.Error("Failed to restore observation", "error", err, "observationId", record.ObservationID)
+			return nil, fmt.Errorf("failed to restore observation %s: %w", record.ObservationID, err)
+		}
+		if inserted {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.Info("Observations restored", "created", result.Created, "updated", result.Updated)
+	return result, nil
+}
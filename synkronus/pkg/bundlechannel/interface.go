@@ -0,0 +1,57 @@
+package bundlechannel
+
+import (
+	"context"
+	"errors"
+)
+
+// Channels a client can be assigned to
+const (
+	// Stable gets the current app bundle version; clients without an assignment are on it
+	Stable = "stable"
+	// Preview gets the version staged for preview, e.g. on test devices
+	Preview = "preview"
+)
+
+// Common errors
+var (
+	// ErrInvalidChannel is returned when a channel is neither stable nor preview
+	ErrInvalidChannel = errors.New("invalid channel")
+	// ErrInvalidClientID is returned when a client ID is empty or too long
+	ErrInvalidClientID = errors.New("invalid client ID")
+)
+
+// maxClientIDLength matches the client_id columns of the sync tables
+const maxClientIDLength = 255
+
+// Assignment puts a client on a channel other than stable
+type Assignment struct {
+	ClientID   string `json:"client_id" db:"client_id"`
+	Channel    string `json:"channel" db:"channel"`
+	AssignedBy string `json:"assigned_by" db:"assigned_by"`
+	AssignedAt string `json:"assigned_at" db:"assigned_at"`
+}
+
+// Service assigns clients to app bundle channels
+type Service interface {
+	// Channel returns the channel of a client, Stable when it has no assignment
+	Channel(ctx context.Context, clientID string) (string, error)
+
+	// Assign puts a client on a channel. Assigning Stable removes the client's assignment, and
+	// nil is returned.
+	Assign(ctx context.Context, clientID, channel, assignedBy string) (*Assignment, error)
+
+	// List returns all assignments ordered by client ID
+	List(ctx context.Context) ([]Assignment, error)
+}
+
+// Validate checks a client ID and channel before they are assigned
+func Validate(clientID, channel string) error {
+	if clientID == "" || len(clientID) > maxClientIDLength {
+		return ErrInvalidClientID
+	}
+	if channel != Stable && channel != Preview {
+		return ErrInvalidChannel
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package bundlechannel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new channel assignment service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Channel returns the channel of a client, Stable when it has no assignment
+func (s *service) Channel(ctx context.Context, clientID string) (string, error) {
+	var channel string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT channel FROM app_bundle_channels WHERE client_id = $1", clientID,
+	).Scan(&channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Stable, nil
+		}
+		return "", fmt.Errorf("failed to get app bundle channel: %w", err)
+	}
+	return channel, nil
+}
+
+// Assign puts a client on a channel; assigning Stable removes the client's assignment
+func (s *service) Assign(ctx context.Context, clientID, channel, assignedBy string) (*Assignment, error) {
+	if err := Validate(clientID, channel); err != nil {
+		return nil, err
+	}
+
+	if channel == Stable {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM app_bundle_channels WHERE client_id = $1", clientID); err != nil {
+			return nil, fmt.Errorf("failed to remove app bundle channel: %w", err)
+		}
+		s.log.Info("Client moved to the stable app bundle channel", "clientId", clientID, "assignedBy", assignedBy)
+		return nil, nil
+	}
+
+	assignment := Assignment{ClientID: clientID, Channel: channel, AssignedBy: assignedBy}
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO app_bundle_channels (client_id, channel, assigned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (client_id) DO UPDATE SET channel = EXCLUDED.channel, assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+		RETURNING assigned_at`,
+		clientID, channel, assignedBy,
+	).Scan(&assignment.AssignedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign app bundle channel: %w", err)
+	}
+
+	s.log.Info("Client assigned to app bundle channel", "clientId", clientID, "channel", channel, "assignedBy", assignedBy)
+	return &assignment, nil
+}
+
+// List returns all assignments ordered by client ID
+func (s *service) List(ctx context.Context) ([]Assignment, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT client_id, channel, assigned_by, assigned_at FROM app_bundle_channels ORDER BY client_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app bundle channels: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := make([]Assignment, 0)
+	for rows.Next() {
+		var a Assignment
+		if err := rows.Scan(&a.ClientID, &a.Channel, &a.AssignedBy, &a.AssignedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app bundle channel: %w", err)
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
@@ -0,0 +1,96 @@
+package bundleupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Common errors
+var (
+	// ErrNotFound is returned when an upload does not exist or has expired
+	ErrNotFound = errors.New("upload not found")
+	// ErrInvalidUpload is returned for sizes and chunks that cannot be accepted
+	ErrInvalidUpload = errors.New("invalid upload")
+	// ErrOffsetMismatch is returned when a chunk does not start at the received byte count
+	ErrOffsetMismatch = errors.New("chunk does not start at the end of the received bytes")
+	// ErrIncomplete is returned when reading an upload that has not received all its bytes
+	ErrIncomplete = errors.New("upload is incomplete")
+)
+
+const (
+	// MaxSize is the largest app bundle that can be uploaded in chunks
+	MaxSize = 1 << 30
+	// MaxChunkSize is the largest chunk accepted in one request
+	MaxChunkSize = 16 << 20
+	// Expiry is how long an upload is kept after its last chunk
+	Expiry = 24 * time.Hour
+)
+
+// Upload is an app bundle being uploaded in chunks
+type Upload struct {
+	ID string `json:"id"`
+	// Size is the size of the bundle in bytes, announced when the upload starts
+	Size int64 `json:"size"`
+	// Received is the number of bytes received; the next chunk starts there
+	Received  int64     `json:"received"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the upload is removed unless another chunk arrives
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Complete reports whether all bytes of the bundle have been received
+func (u *Upload) Complete() bool {
+	return u.Received == u.Size
+}
+
+// Service keeps app bundles uploaded in chunks until they are pushed. Uploads are stored in the
+// database, so the chunks of one upload may reach different replicas.
+type Service interface {
+	// Create starts an upload of a bundle of the given size
+	Create(ctx context.Context, size int64, createdBy string) (*Upload, error)
+
+	// Get returns an upload, e.g. to resume it at Received after an interruption
+	Get(ctx context.Context, id string) (*Upload, error)
+
+	// Append adds a chunk starting at offset, which must equal the received byte count;
+	// ErrOffsetMismatch is returned otherwise, e.g. for a retried chunk that already arrived
+	Append(ctx context.Context, id string, offset int64, data []byte) (*Upload, error)
+
+	// Open reads the bundle of a complete upload
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+
+	// Delete removes an upload and its chunks
+	Delete(ctx context.Context, id string) error
+}
+
+// ValidateSize checks the announced size of a bundle
+func ValidateSize(size int64) error {
+	if size <= 0 {
+		return fmt.Errorf("%w: size must be positive", ErrInvalidUpload)
+	}
+	if size > MaxSize {
+		return fmt.Errorf("%w: bundles larger than 1 GB cannot be uploaded", ErrInvalidUpload)
+	}
+	return nil
+}
+
+// ValidateChunk checks a chunk against an upload before it is appended
+func ValidateChunk(upload *Upload, offset int64, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty chunk", ErrInvalidUpload)
+	}
+	if len(data) > MaxChunkSize {
+		return fmt.Errorf("%w: chunks larger than 16 MB are not accepted", ErrInvalidUpload)
+	}
+	if offset != upload.Received {
+		return ErrOffsetMismatch
+	}
+	if offset+int64(len(data)) > upload.Size {
+		return fmt.Errorf("%w: chunk ends past the announced size of %d bytes", ErrInvalidUpload, upload.Size)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package bundleupload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new chunked upload service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Create starts an upload, first removing the uploads that have expired
+func (s *service) Create(ctx context.Context, size int64, createdBy string) (*Upload, error) {
+	if err := ValidateSize(size); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM app_bundle_uploads WHERE updated_at < $1", time.Now().Add(-Expiry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove expired uploads: %w", err)
+	}
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		s.log.Info("Removed expired app bundle uploads", "count", removed)
+	}
+
+	upload := &Upload{ID: uuid.New().String(), Size: size, CreatedBy: createdBy}
+	var updatedAt time.Time
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO app_bundle_uploads (id, size, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING created_at, updated_at`,
+		upload.ID, size, createdBy,
+	).Scan(&upload.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	upload.ExpiresAt = updatedAt.Add(Expiry)
+
+	s.log.Info("App bundle upload started", "id", upload.ID, "size", size, "user", createdBy)
+	return upload, nil
+}
+
+// Get returns an upload that has not expired
+func (s *service) Get(ctx context.Context, id string) (*Upload, error) {
+	return s.get(ctx, s.db, id, "")
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// get reads an upload, with an optional locking clause such as FOR UPDATE
+func (s *service) get(ctx context.Context, q queryRower, id, lock string) (*Upload, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	upload := &Upload{}
+	var updatedAt time.Time
+	err := q.QueryRowContext(ctx, `
+		SELECT id, size, received, created_by, created_at, updated_at
+		FROM app_bundle_uploads WHERE id = $1 AND updated_at >= $2 `+lock,
+		id, time.Now().Add(-Expiry),
+	).Scan(&upload.ID, &upload.Size, &upload.Received, &upload.CreatedBy, &upload.CreatedAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	upload.ExpiresAt = updatedAt.Add(Expiry)
+	return upload, nil
+}
+
+// Append adds a chunk at the received byte count. The upload row is locked, so concurrent
+// retries of the same chunk cannot both be appended.
+func (s *service) Append(ctx context.Context, id string, offset int64, data []byte) (*Upload, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upload, err := s.get(ctx, tx, id, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateChunk(upload, offset, data); err != nil {
+		return upload, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO app_bundle_upload_chunks (upload_id, start_offset, data) VALUES ($1, $2, $3)",
+		id, offset, data,
+	); err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+	var updatedAt time.Time
+	if err := tx.QueryRowContext(ctx,
+		"UPDATE app_bundle_uploads SET received = received + $2, updated_at = NOW() WHERE id = $1 RETURNING received, updated_at",
+		id, len(data),
+	).Scan(&upload.Received, &updatedAt); err != nil {
+		return nil, fmt.Errorf("failed to update upload: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit chunk: %w", err)
+	}
+	upload.ExpiresAt = updatedAt.Add(Expiry)
+	return upload, nil
+}
+
+// Open reads the chunks of a complete upload in order, one at a time
+func (s *service) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	upload, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.Complete() {
+		return nil, fmt.Errorf("%w: %d of %d bytes received", ErrIncomplete, upload.Received, upload.Size)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT data FROM app_bundle_upload_chunks WHERE upload_id = $1 ORDER BY start_offset", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return &chunkReader{rows: rows}, nil
+}
+
+// chunkReader reads the chunks of an upload as one stream
+type chunkReader struct {
+	rows  *sql.Rows
+	chunk []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if !r.rows.Next() {
+			if err := r.rows.Err(); err != nil {
+				return 0, fmt.Errorf("failed to read upload: %w", err)
+			}
+			return 0, io.EOF
+		}
+		if err := r.rows.Scan(&r.chunk); err != nil {
+			return 0, fmt.Errorf("failed to read upload: %w", err)
+		}
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func (r *chunkReader) Close() error {
+	return r.rows.Close()
+}
+
+// Delete removes an upload; its chunks are removed with it
+func (s *service) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	result, err := s.db.ExecContext(ctx, "DELETE FROM app_bundle_uploads WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package canary replays recent observations against the form schemas of a new app bundle
+// version before it is promoted, so that schema changes that would reject data devices already
+// collect are caught before they reach the field.
+package canary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Validate checks a canary request, filling in the defaults
+func (req *Request) Validate() error {
+	if req.SampleSize == 0 {
+		req.SampleSize = DefaultSampleSize
+	}
+	if req.Days == 0 {
+		req.Days = DefaultDays
+	}
+	switch {
+	case req.SampleSize < 0 || req.SampleSize > MaxSampleSize:
+		return fmt.Errorf("%w: sample_size must be between 1 and %d", ErrInvalidRequest, MaxSampleSize)
+	case req.Days < 0:
+		return fmt.Errorf("%w: days must be positive", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// record is an observation replayed against the form schemas
+type record struct {
+	ObservationID string
+	FormType      string
+	FormVersion   string
+	UpdatedAt     time.Time
+	Data          []byte
+}
+
+// evaluate validates records against the form schemas of the version being validated and of
+// the active version, adding the outcome to the report. Records of form types the validated
+// version has no schema for are counted as removed rather than validated.
+func evaluate(report *Report, records []record, next, current map[string]map[string]any) {
+	forms := make(map[string]*FormResult)
+	var failures []Failure
+	for _, rec := range records {
+		form, ok := forms[rec.FormType]
+		if !ok {
+			form = &FormResult{FormType: rec.FormType}
+			forms[rec.FormType] = form
+		}
+		form.Sampled++
+		report.Sampled++
+
+		schema, ok := next[rec.FormType]
+		if !ok {
+			form.Removed = true
+			continue
+		}
+		data, err := decode(rec.Data)
+		if err != nil {
+			failures = append(failures, failure(rec, true, []Violation{{Rule: "type", Message: "is not valid JSON"}}))
+			form.Failing++
+			form.NewlyFailing++
+			continue
+		}
+		violations := Validate(schema, data)
+		if len(violations) == 0 {
+			continue
+		}
+
+		// Records the active version rejects as well were broken before this version
+		isNew := true
+		if schema, ok := current[rec.FormType]; ok {
+			isNew = len(Validate(schema, data)) == 0
+		}
+		failures = append(failures, failure(rec, isNew, violations))
+		form.Failing++
+		if isNew {
+			form.NewlyFailing++
+		}
+	}
+
+	report.Forms = make([]FormResult, 0, len(forms))
+	for _, form := range forms {
+		report.Forms = append(report.Forms, *form)
+		report.Failing += form.Failing
+		report.NewlyFailing += form.NewlyFailing
+	}
+	sort.Slice(report.Forms, func(i, j int) bool { return report.Forms[i].FormType < report.Forms[j].FormType })
+
+	// Records are read by form type, latest first; newly failing ones matter most
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].New && !failures[j].New })
+	if len(failures) > MaxFailures {
+		failures, report.Truncated = failures[:MaxFailures], true
+	}
+	report.Failures = failures
+	if report.Failures == nil {
+		report.Failures = []Failure{}
+	}
+}
+
+func failure(rec record, isNew bool, violations []Violation) Failure {
+	return Failure{
+		ObservationID: rec.ObservationID,
+		FormType:      rec.FormType,
+		FormVersion:   rec.FormVersion,
+		UpdatedAt:     rec.UpdatedAt,
+		New:           isNew,
+		Violations:    violations,
+	}
+}
+
+// decode parses observation data keeping numbers exact, as sync does when checking codes
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
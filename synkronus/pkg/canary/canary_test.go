@@ -0,0 +1,79 @@
+package canary
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestValidate(t *testing.T) {
+	req := Request{}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, DefaultSampleSize, req.SampleSize)
+	assert.Equal(t, DefaultDays, req.Days)
+
+	for _, req := range []Request{{SampleSize: MaxSampleSize + 1}, {SampleSize: -1}, {Days: -7}} {
+		err := req.Validate()
+		assert.True(t, errors.Is(err, ErrInvalidRequest), "request %+v", req)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	// The new version makes age required and caps it at 99; the active one only caps it at 120
+	next := map[string]map[string]any{
+		"person": parse(t, `{"type": "object", "required": ["age"], "properties": {"age": {"type": "integer", "maximum": 99}}}`),
+	}
+	current := map[string]map[string]any{
+		"person": parse(t, `{"type": "object", "properties": {"age": {"type": "integer", "maximum": 120}}}`),
+		"visit":  parse(t, `{"type": "object"}`),
+	}
+	updated := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	records := []record{
+		{ObservationID: "p1", FormType: "person", Data: []byte(`{"age": 30}`), UpdatedAt: updated},
+		{ObservationID: "p2", FormType: "person", Data: []byte(`{"age": 130}`), UpdatedAt: updated},
+		{ObservationID: "p3", FormType: "person", Data: []byte(`{"age": 105}`), UpdatedAt: updated},
+		{ObservationID: "p4", FormType: "person", Data: []byte(`{}`), UpdatedAt: updated},
+		{ObservationID: "v1", FormType: "visit", Data: []byte(`{}`), UpdatedAt: updated},
+	}
+
+	report := &Report{Version: "2", CurrentVersion: "1"}
+	evaluate(report, records, next, current)
+
+	assert.Equal(t, 5, report.Sampled)
+	assert.Equal(t, 3, report.Failing)
+	assert.Equal(t, 2, report.NewlyFailing)
+	assert.Equal(t, []FormResult{
+		{FormType: "person", Sampled: 4, Failing: 3, NewlyFailing: 2},
+		{FormType: "visit", Sampled: 1, Removed: true},
+	}, report.Forms)
+
+	ids := make([]string, len(report.Failures))
+	for i, f := range report.Failures {
+		ids[i] = f.ObservationID
+	}
+	// Newly failing records come first; p2 was already rejected by the active version
+	assert.Equal(t, []string{"p3", "p4", "p2"}, ids)
+	assert.False(t, report.Failures[2].New)
+	assert.Equal(t, "required", report.Failures[1].Violations[0].Rule)
+	assert.False(t, report.Truncated)
+}
+
+func TestEvaluate_ListsAtMostMaxFailures(t *testing.T) {
+	next := map[string]map[string]any{"person": parse(t, `{"type": "object", "required": ["age"]}`)}
+	records := make([]record, MaxFailures+5)
+	for i := range records {
+		records[i] = record{ObservationID: fmt.Sprintf("p%d", i), FormType: "person", Data: []byte(`{}`)}
+	}
+
+	report := &Report{}
+	evaluate(report, records, next, nil)
+
+	assert.Equal(t, MaxFailures+5, report.Failing)
+	assert.Equal(t, MaxFailures+5, report.NewlyFailing)
+	assert.Len(t, report.Failures, MaxFailures)
+	assert.True(t, report.Truncated)
+}
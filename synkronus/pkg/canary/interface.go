@@ -0,0 +1,95 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidRequest is returned when a canary request is not valid
+var ErrInvalidRequest = errors.New("invalid canary request")
+
+// ErrNoVersion is returned when no version is given and none is staged for preview
+var ErrNoVersion = errors.New("no app bundle version to validate")
+
+// Defaults and guardrails applied to every run
+const (
+	// DefaultSampleSize is how many of the latest records of each form type are replayed
+	DefaultSampleSize = 200
+	// MaxSampleSize is the most records replayed per form type
+	MaxSampleSize = 5000
+	// DefaultDays is how far back records are replayed from
+	DefaultDays = 30
+	// MaxFailures is the most failing records listed in a report; all of them are counted
+	MaxFailures = 100
+	// StatementTimeout bounds the query reading the records to replay
+	StatementTimeout = 30 * time.Second
+)
+
+// Request describes which version to validate and which records to replay against it. Deleted
+// records and drafts are never replayed.
+type Request struct {
+	// Version is the app bundle version to validate; it defaults to the one staged for preview
+	Version string `json:"version,omitempty"`
+	// FormType restricts the run to one form type; empty replays all form types
+	FormType string `json:"form_type,omitempty"`
+	// SampleSize is how many of the latest records of each form type are replayed
+	SampleSize int `json:"sample_size,omitempty"`
+	// Days restricts the records to those updated within the last days
+	Days int `json:"days,omitempty"`
+}
+
+// Violation is a rule of a form schema a record breaks
+type Violation struct {
+	// Field is the path of the value in the observation data, e.g. members[2].age; empty for
+	// the record as a whole
+	Field string `json:"field"`
+	// Rule is the schema keyword broken, e.g. required, enum or maximum
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Failure is a replayed record the validated version rejects
+type Failure struct {
+	ObservationID string    `json:"observation_id"`
+	FormType      string    `json:"form_type"`
+	FormVersion   string    `json:"form_version"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// New is true when the active version accepts the record, so the new version breaks it
+	New        bool        `json:"new"`
+	Violations []Violation `json:"violations"`
+}
+
+// FormResult sums up the replay of one form type
+type FormResult struct {
+	FormType     string `json:"form_type"`
+	Sampled      int    `json:"sampled"`
+	Failing      int    `json:"failing"`
+	NewlyFailing int    `json:"newly_failing"`
+	// Removed marks form types the validated version no longer has; their records are not
+	// validated
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Report is the outcome of a canary run
+type Report struct {
+	Version string `json:"version"`
+	// CurrentVersion is the active version records were also validated against; empty when
+	// no version is active
+	CurrentVersion string       `json:"current_version,omitempty"`
+	Since          time.Time    `json:"since"`
+	Sampled        int          `json:"sampled"`
+	Failing        int          `json:"failing"`
+	NewlyFailing   int          `json:"newly_failing"`
+	Forms          []FormResult `json:"forms"`
+	// Failures lists the failing records, newly failing ones first, up to MaxFailures
+	Failures  []Failure `json:"failures"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// Service validates app bundle versions against recent observations
+type Service interface {
+	// Run replays the latest observations of the tenant against the form schemas of a version
+	// and of the active version, reporting the records the version would reject
+	Run(ctx context.Context, req Request) (*Report, error)
+}
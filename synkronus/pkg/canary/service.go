@@ -0,0 +1,169 @@
+package canary
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// Bundles is the part of the app bundle service a canary run reads form schemas from
+type Bundles interface {
+	GetVersions(ctx context.Context) ([]string, error)
+	GetStagedPreview(ctx context.Context) (string, error)
+	GetAppInfo(ctx context.Context, version string) (*appbundle.AppInfo, error)
+	GetVersionFile(ctx context.Context, version, path string) (io.ReadCloser, *appbundle.File, error)
+}
+
+type service struct {
+	db      *sql.DB
+	bundles Bundles
+	log     *logger.Logger
+}
+
+// NewService creates a canary service reading form schemas from bundles
+func NewService(db *sql.DB, bundles Bundles, log *logger.Logger) Service {
+	return &service{db: db, bundles: bundles, log: log}
+}
+
+// Run replays the latest observations of the tenant against the form schemas of a version and
+// of the active version. Records are read in a read-only transaction with a statement timeout.
+func (s *service) Run(ctx context.Context, req Request) (*Report, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	version, current, err := s.versions(ctx, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	next, err := s.schemas(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	var active map[string]map[string]any
+	if current != "" {
+		if active, err = s.schemas(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -req.Days)
+	records, err := s.readRecords(ctx, req, since)
+	if err != nil {
+		s.log.Error("Failed to read records for canary run", "error", err)
+		return nil, err
+	}
+
+	report := &Report{Version: version, CurrentVersion: current, Since: since}
+	evaluate(report, records, next, active)
+	s.log.Info("Canary run completed", "version", version, "sampled", report.Sampled,
+		"failing", report.Failing, "newlyFailing", report.NewlyFailing)
+	return report, nil
+}
+
+// versions returns the version to validate, defaulting to the staged preview, and the active
+// version
+func (s *service) versions(ctx context.Context, version string) (string, string, error) {
+	if version == "" {
+		staged, err := s.bundles.GetStagedPreview(ctx)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get staged preview: %w", err)
+		}
+		if staged == "" {
+			return "", "", fmt.Errorf("%w: give a version or stage one for preview", ErrNoVersion)
+		}
+		version = staged
+	}
+
+	versions, err := s.bundles.GetVersions(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get app bundle versions: %w", err)
+	}
+	var current string
+	found := false
+	for _, v := range versions {
+		if strings.HasSuffix(v, " *") {
+			v = strings.TrimSuffix(v, " *")
+			current = v
+		}
+		found = found || v == version
+	}
+	if !found {
+		return "", "", fmt.Errorf("%w: %s", appbundle.ErrVersionNotFound, version)
+	}
+	return version, current, nil
+}
+
+// schemas reads the form schemas of a version by form type
+func (s *service) schemas(ctx context.Context, version string) (map[string]map[string]any, error) {
+	info, err := s.bundles.GetAppInfo(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app info of version %s: %w", version, err)
+	}
+	schemas := make(map[string]map[string]any, len(info.Forms))
+	for formType := range info.Forms {
+		file, _, err := s.bundles.GetVersionFile(ctx, version, "forms/"+formType+"/schema.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema of form %s in version %s: %w", formType, version, err)
+		}
+		var schema map[string]any
+		err = json.NewDecoder(file).Decode(&schema)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema of form %s in version %s: %w", formType, version, err)
+		}
+		schemas[formType] = schema
+	}
+	return schemas, nil
+}
+
+// readRecords returns the latest records of each form type of the tenant updated since a time,
+// ordered by form type and latest first
+func (s *service) readRecords(ctx context.Context, req Request, since time.Time) ([]record, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Read-only, so rolling back is all that is ever needed
+	defer tx.Rollback()
+
+	timeout := strconv.FormatInt(StatementTimeout.Milliseconds(), 10)
+	if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = "+timeout); err != nil {
+		return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	query := `SELECT observation_id, form_type, form_version, data, updated_at FROM (
+			SELECT observation_id, form_type, form_version, data, updated_at,
+				ROW_NUMBER() OVER (PARTITION BY form_type ORDER BY updated_at DESC, observation_id) AS n
+			FROM observations
+			WHERE NOT deleted AND NOT draft AND tenant_id = $1 AND updated_at >= $2 AND ($3 = '' OR form_type = $3)
+		) latest WHERE n <= $4
+		ORDER BY form_type, updated_at DESC, observation_id`
+	rows, err := tx.QueryContext(ctx, query, tenant.FromContext(ctx), since, req.FormType, req.SampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]record, 0)
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.ObservationID, &rec.FormType, &rec.FormVersion, &rec.Data, &rec.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan record: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating records: %w", err)
+	}
+	return records, nil
+}
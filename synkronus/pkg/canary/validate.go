@@ -0,0 +1,307 @@
+package canary
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validate checks a value against a form schema, returning the rules it breaks. It supports the
+// JSON Schema keywords form schemas use: type, enum, const, required, properties,
+// additionalProperties, items, minItems, maxItems, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minLength, maxLength, pattern, format (date, date-time and time), allOf,
+// anyOf and oneOf. Other keywords are ignored. Numbers may be float64 or json.Number.
+func Validate(schema map[string]any, value any) []Violation {
+	var violations []Violation
+	validate("", schema, value, &violations)
+	return violations
+}
+
+func validate(field string, schema map[string]any, value any, out *[]Violation) {
+	add := func(rule, message string, args ...any) {
+		*out = append(*out, Violation{Field: field, Rule: rule, Message: fmt.Sprintf(message, args...)})
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesType(value, types) {
+		add("type", "must be %s, not %s", strings.Join(types, " or "), typeOf(value))
+		// The other rules only make sense for values of the right type
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !contains(enum, value) {
+		add("enum", "must be one of %s, not %s", display(enum), display(value))
+	}
+	if constant, ok := schema["const"]; ok && !equal(constant, value) {
+		add("const", "must be %s, not %s", display(constant), display(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		validateObject(field, schema, v, out)
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			add("minItems", "must have at least %s items, not %d", formatNumber(n), len(v))
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			add("maxItems", "must have at most %s items, not %d", formatNumber(n), len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validate(field+"["+strconv.Itoa(i)+"]", items, item, out)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := number(schema["minLength"]); ok && length < n {
+			add("minLength", "must be at least %s characters long", formatNumber(n))
+		}
+		if n, ok := number(schema["maxLength"]); ok && length > n {
+			add("maxLength", "must be at most %s characters long", formatNumber(n))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			// Schemas were checked when the bundle was pushed; a pattern Go cannot compile is skipped
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				add("pattern", "must match %s", pattern)
+			}
+		}
+		if format, ok := schema["format"].(string); ok && !matchesFormat(format, v) {
+			add("format", "must be a %s", format)
+		}
+	default:
+		if n, ok := number(value); ok {
+			validateNumber(schema, n, add)
+		}
+	}
+
+	for _, sub := range subschemas(schema["allOf"]) {
+		validate(field, sub, value, out)
+	}
+	if anyOf := subschemas(schema["anyOf"]); len(anyOf) > 0 && countMatches(anyOf, value) == 0 {
+		add("anyOf", "must match at least one of the allowed schemas")
+	}
+	if oneOf := subschemas(schema["oneOf"]); len(oneOf) > 0 && countMatches(oneOf, value) != 1 {
+		add("oneOf", "must match exactly one of the allowed schemas")
+	}
+}
+
+// validateObject checks the required fields and properties of an object
+func validateObject(field string, schema map[string]any, value map[string]any, out *[]Violation) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := value[name]; !present {
+					*out = append(*out, Violation{Field: join(field, name), Rule: "required", Message: "is required"})
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]any); ok {
+			validate(join(field, name), property, value[name], out)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*out = append(*out, Violation{Field: join(field, name), Rule: "additionalProperties", Message: "is not a field of the form"})
+			}
+		case map[string]any:
+			validate(join(field, name), additional, value[name], out)
+		}
+	}
+}
+
+// validateNumber checks the bounds of a number
+func validateNumber(schema map[string]any, n float64, add func(rule, message string, args ...any)) {
+	if min, ok := number(schema["minimum"]); ok && n < min {
+		add("minimum", "must be at least %s, not %s", formatNumber(min), formatNumber(n))
+	}
+	if max, ok := number(schema["maximum"]); ok && n > max {
+		add("maximum", "must be at most %s, not %s", formatNumber(max), formatNumber(n))
+	}
+	if min, ok := number(schema["exclusiveMinimum"]); ok && n <= min {
+		add("exclusiveMinimum", "must be more than %s, not %s", formatNumber(min), formatNumber(n))
+	}
+	if max, ok := number(schema["exclusiveMaximum"]); ok && n >= max {
+		add("exclusiveMaximum", "must be less than %s, not %s", formatNumber(max), formatNumber(n))
+	}
+}
+
+// countMatches returns how many of the schemas a value is valid against
+func countMatches(schemas []map[string]any, value any) int {
+	matches := 0
+	for _, schema := range schemas {
+		var violations []Violation
+		validate("", schema, value, &violations)
+		if len(violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// subschemas returns the object schemas of an allOf, anyOf or oneOf list
+func subschemas(list any) []map[string]any {
+	items, _ := list.([]any)
+	schemas := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if schema, ok := item.(map[string]any); ok {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+// schemaTypes returns the types a schema allows, from a type name or a list of them
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesType reports whether a value is of one of the types; integers are numbers too, and
+// numbers without a fraction are integers
+func matchesType(value any, types []string) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		switch {
+		case t == actual:
+			return true
+		case t == "number" && actual == "integer":
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of a decoded JSON value
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	if n, ok := number(value); ok {
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// number returns a decoded JSON number as a float64
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// matchesFormat checks the string formats forms collect; other formats are not checked
+func matchesFormat(format, value string) bool {
+	var err error
+	switch format {
+	case "date":
+		_, err = time.Parse(time.DateOnly, value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "time":
+		if _, err = time.Parse("15:04:05Z07:00", value); err != nil {
+			_, err = time.Parse(time.TimeOnly, value)
+		}
+	}
+	return err == nil
+}
+
+// contains reports whether a list holds a value
+func contains(list []any, value any) bool {
+	for _, item := range list {
+		if equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares decoded JSON values, numbers by value whether float64 or json.Number
+func equal(a, b any) bool {
+	return reflect.DeepEqual(plain(a), plain(b))
+}
+
+// plain replaces the json.Numbers of a decoded JSON value with float64s
+func plain(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		n, _ := v.Float64()
+		return n
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = plain(item)
+		}
+		return m
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = plain(item)
+		}
+		return list
+	}
+	return value
+}
+
+// display renders a value for a message
+func display(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// join appends a property name to a field path
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
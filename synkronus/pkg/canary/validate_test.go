@@ -0,0 +1,107 @@
+package canary
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const householdSchema = `{
+	"type": "object",
+	"required": ["head_name", "members"],
+	"additionalProperties": false,
+	"properties": {
+		"head_name": {"type": "string", "minLength": 2, "maxLength": 40},
+		"members": {"type": "integer", "minimum": 1, "maximum": 30},
+		"water_source": {"type": "string", "enum": ["piped", "well", "river"]},
+		"visit_date": {"type": "string", "format": "date"},
+		"phone": {"type": ["string", "null"], "pattern": "^\\+?[0-9]{8,15}$"},
+		"income": {"type": "number", "exclusiveMinimum": 0},
+		"consent": {"const": true},
+		"children": {
+			"type": "array",
+			"maxItems": 2,
+			"items": {"type": "object", "required": ["age"], "properties": {"age": {"type": "integer", "maximum": 17}}}
+		},
+		"contact": {"oneOf": [{"type": "string", "format": "date"}, {"type": "integer"}]}
+	}
+}`
+
+func parse(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s), &schema))
+	return schema
+}
+
+func rules(violations []Violation) map[string]string {
+	byField := make(map[string]string, len(violations))
+	for _, v := range violations {
+		byField[v.Field] = v.Rule
+	}
+	return byField
+}
+
+func TestValidate_AcceptsValidRecord(t *testing.T) {
+	data, err := decode([]byte(`{
+		"head_name": "Amina", "members": 4, "water_source": "well", "visit_date": "2025-10-01",
+		"phone": null, "income": 12.5, "consent": true, "children": [{"age": 3}, {"age": 7}], "contact": 5
+	}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, Validate(parse(t, householdSchema), data))
+}
+
+func TestValidate_ReportsBrokenRules(t *testing.T) {
+	data, err := decode([]byte(`{
+		"head_name": "A", "members": 2.5, "water_source": "lake", "visit_date": "01/10/2025",
+		"phone": "12", "income": 0, "consent": false, "children": [{"age": 18}, {}, {"age": 1}],
+		"contact": "tomorrow", "notes": "extra"
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"head_name":       "minLength",
+		"members":         "type",
+		"water_source":    "enum",
+		"visit_date":      "format",
+		"phone":           "pattern",
+		"income":          "exclusiveMinimum",
+		"consent":         "const",
+		"children":        "maxItems",
+		"children[0].age": "maximum",
+		"children[1].age": "required",
+		"contact":         "oneOf",
+		"notes":           "additionalProperties",
+	}, rules(Validate(parse(t, householdSchema), data)))
+}
+
+func TestValidate_RequiredAndMessages(t *testing.T) {
+	data, err := decode([]byte(`{"members": 40}`))
+	require.NoError(t, err)
+
+	violations := Validate(parse(t, householdSchema), data)
+	assert.Equal(t, []Violation{
+		{Field: "head_name", Rule: "required", Message: "is required"},
+		{Field: "members", Rule: "maximum", Message: "must be at most 30, not 40"},
+	}, violations)
+}
+
+func TestValidate_NumbersMatchWhateverTheirDecoding(t *testing.T) {
+	schema := parse(t, `{"type": "object", "properties": {"code": {"enum": [1, 2, 3]}, "size": {"type": "integer"}}}`)
+
+	assert.Empty(t, Validate(schema, map[string]any{"code": json.Number("2"), "size": json.Number("3.0")}))
+	assert.Empty(t, Validate(schema, map[string]any{"code": 2.0, "size": 3.0}))
+	assert.Equal(t, map[string]string{"code": "enum"}, rules(Validate(schema, map[string]any{"code": json.Number("4")})))
+}
+
+func TestValidate_WrongTypeSkipsOtherRules(t *testing.T) {
+	schema := parse(t, `{"type": "string", "minLength": 3, "enum": ["yes", "no"]}`)
+
+	violations := Validate(schema, json.Number("1"))
+	require.Len(t, violations, 1)
+	assert.Equal(t, "type", violations[0].Rule)
+	assert.Equal(t, "must be string, not integer", violations[0].Message)
+}
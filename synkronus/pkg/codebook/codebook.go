@@ -0,0 +1,439 @@
+// Package codebook renders the forms of an app bundle version as a printable codebook: every
+// question with its variable name, answer type, choice labels and the logic deciding when it is
+// asked, as study protocol submissions require.
+package codebook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema keys holding form logic expressions
+const (
+	// VisibleIfKey shows a question only while its expression is true
+	VisibleIfKey = "x-visible-if"
+	// ConstraintKey accepts an answer only when its expression is true for the answer
+	ConstraintKey = "x-constraint"
+)
+
+// Choice is an option of a single or multiple choice question
+type Choice struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// Question is a variable of a form as it is asked
+type Question struct {
+	// Name is the dotted path of the answer in the observation data
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Hint  string `json:"hint,omitempty"`
+	// Section is the label of the group or page the question is asked in
+	Section string `json:"section,omitempty"`
+	// Answer describes how the question is answered, e.g. "Single choice" or "Date"
+	Answer   string   `json:"answer"`
+	Required bool     `json:"required"`
+	Choices  []Choice `json:"choices,omitempty"`
+	Minimum  *float64 `json:"minimum,omitempty"`
+	Maximum  *float64 `json:"maximum,omitempty"`
+	// ShownWhen lists the conditions under which the question is asked: its x-visible-if
+	// expression and the rules of the UI elements around it
+	ShownWhen  []string `json:"shown_when,omitempty"`
+	Constraint string   `json:"constraint,omitempty"`
+	// CodeList names the code list the answer's codes come from
+	CodeList string `json:"code_list,omitempty"`
+	// ServerAssigned names how the server fills the variable on push
+	ServerAssigned string `json:"server_assigned,omitempty"`
+	// NotAsked marks variables of the schema that no UI element asks, such as server-assigned
+	// or calculated ones
+	NotAsked bool `json:"not_asked,omitempty"`
+}
+
+// Form is the codebook of one form
+type Form struct {
+	FormType    string     `json:"form_type"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Questions   []Question `json:"questions"`
+}
+
+// Codebook documents the forms of an app bundle version
+type Codebook struct {
+	Version     string    `json:"version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Forms       []Form    `json:"forms"`
+}
+
+// captureFormats names the schema formats the formplayer answers with device features
+var captureFormats = map[string]string{
+	"photo":       "Photo",
+	"signature":   "Signature",
+	"audio":       "Audio recording",
+	"video":       "Video recording",
+	"qrcode":      "Barcode scan",
+	"select_file": "File",
+	"gps":         "Location",
+}
+
+// BuildForm lays out the codebook of a form from its JSON schema and UI schema. Questions are
+// listed in the order the UI asks them, followed by the schema's variables no UI element asks.
+func BuildForm(formType string, schema, ui map[string]any) Form {
+	form := Form{FormType: formType, Title: formType}
+	if title, ok := schema["title"].(string); ok && title != "" {
+		form.Title = title
+	}
+	form.Description, _ = schema["description"].(string)
+
+	b := &builder{asked: make(map[string]bool)}
+	if ui != nil {
+		if ui["type"] == "SwipeLayout" {
+			for i, page := range children(ui) {
+				b.elements([]any{page}, schema, "", fmt.Sprintf("Page %d", i+1), nil)
+			}
+		} else {
+			b.elements([]any{ui}, schema, "", "", nil)
+		}
+	}
+	b.notAsked(schema, "")
+
+	form.Questions = b.questions
+	return form
+}
+
+// builder collects the questions of a form
+type builder struct {
+	questions []Question
+	// asked holds the names of the variables a UI element asks
+	asked map[string]bool
+}
+
+// elements walks UI schema elements. Scopes resolve against object, the schema of the array
+// items for the detail of a repeated group, and names are prefixed with the group's name.
+// Conditions are the rules of the enclosing elements.
+func (b *builder) elements(items []any, object map[string]any, prefix, section string, conditions []string) {
+	for _, item := range items {
+		ui, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		shownWhen := conditions
+		if rule := describeRule(ui["rule"], prefix); rule != "" {
+			shownWhen = append(append([]string(nil), conditions...), rule)
+		}
+
+		switch ui["type"] {
+		case "Group":
+			groupSection := section
+			if label, ok := ui["label"].(string); ok && label != "" {
+				groupSection = label
+			}
+			b.elements(children(ui), object, prefix, groupSection, shownWhen)
+		case "Control":
+			b.control(ui, object, prefix, section, shownWhen)
+		default:
+			b.elements(children(ui), object, prefix, section, shownWhen)
+		}
+	}
+}
+
+// control adds the question a Control asks, and the questions of a repeated group's detail
+func (b *builder) control(ui, object map[string]any, prefix, section string, conditions []string) {
+	scope, _ := ui["scope"].(string)
+	segments, ok := scopeSegments(scope)
+	if !ok {
+		return
+	}
+	parent := object
+	var property map[string]any
+	for i, segment := range segments {
+		properties, _ := parent["properties"].(map[string]any)
+		property, _ = properties[segment].(map[string]any)
+		if property == nil {
+			return
+		}
+		if i < len(segments)-1 {
+			parent = property
+		}
+	}
+	name := joinName(prefix, strings.Join(segments, "."))
+	if b.asked[name] {
+		return
+	}
+
+	question := newQuestion(name, segments[len(segments)-1], property, parent)
+	question.Section = section
+	question.ShownWhen = append(question.ShownWhen, conditions...)
+	if label, ok := ui["label"].(string); ok && label != "" {
+		question.Label = label
+	}
+	b.asked[name] = true
+	b.questions = append(b.questions, question)
+
+	// The questions of a repeated group are asked once per item
+	options, _ := ui["options"].(map[string]any)
+	if detail, ok := options["detail"].(map[string]any); ok {
+		if items, ok := property["items"].(map[string]any); ok {
+			b.elements([]any{detail}, items, name, question.Label, conditions)
+		}
+	}
+}
+
+// notAsked adds the variables of a schema object no UI element asks, in name order. Objects
+// are only listed through their properties.
+func (b *builder) notAsked(object map[string]any, prefix string) {
+	properties, _ := object["properties"].(map[string]any)
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		property, ok := properties[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		name := joinName(prefix, key)
+		_, nested := property["properties"].(map[string]any)
+		if !b.asked[name] && !(property["type"] == "object" && nested) {
+			question := newQuestion(name, key, property, object)
+			question.NotAsked = true
+			b.asked[name] = true
+			b.questions = append(b.questions, question)
+		}
+		b.notAsked(property, name)
+		if items, ok := property["items"].(map[string]any); ok {
+			b.notAsked(items, name)
+		}
+	}
+}
+
+// newQuestion describes the variable name of property, a property of parent
+func newQuestion(name, key string, property, parent map[string]any) Question {
+	question := Question{
+		Name:     name,
+		Label:    humanize(key),
+		Required: containsValue(parent["required"], key),
+		Minimum:  number(property["minimum"]),
+		Maximum:  number(property["maximum"]),
+	}
+	if title, ok := property["title"].(string); ok && title != "" {
+		question.Label = title
+	}
+	question.Hint, _ = property["description"].(string)
+	question.Constraint, _ = property[ConstraintKey].(string)
+	question.CodeList, _ = property["x-code-list"].(string)
+	question.ServerAssigned, _ = property["x-server-assigned"].(string)
+	if visibleIf, ok := property[VisibleIfKey].(string); ok && visibleIf != "" {
+		question.ShownWhen = append(question.ShownWhen, visibleIf)
+	}
+	question.Answer, question.Choices = answer(property)
+	return question
+}
+
+// answer describes how a property is answered, as the formplayer's renderers would ask it
+func answer(property map[string]any) (string, []Choice) {
+	format, _ := property["format"].(string)
+	kind, _ := property["type"].(string)
+
+	description := ""
+	var choices []Choice
+	switch {
+	case captureFormats[format] != "":
+		description = captureFormats[format]
+	case choicesOf(property) != nil:
+		description, choices = "Single choice", choicesOf(property)
+	case kind == "array":
+		items, _ := property["items"].(map[string]any)
+		if choices = choicesOf(items); choices != nil {
+			description = "Multiple choice"
+		} else {
+			description = "Repeated group"
+		}
+	case kind == "boolean":
+		description = "Yes/No"
+	case kind == "integer":
+		description = "Integer"
+	case kind == "number":
+		description = "Decimal number"
+	case kind == "object":
+		description = "Group"
+	case format == "date":
+		description = "Date"
+	case format == "date-time":
+		description = "Date and time"
+	case format == "time":
+		description = "Time"
+	default:
+		description = "Text"
+	}
+
+	if questionType, ok := property["x-question-type"].(string); ok && questionType != "" {
+		description += " (" + questionType + ")"
+	}
+	return description, choices
+}
+
+// choicesOf returns the choices of an enum or oneOf property, or nil
+func choicesOf(property map[string]any) []Choice {
+	if values, ok := property["enum"].([]any); ok {
+		choices := make([]Choice, 0, len(values))
+		for _, value := range values {
+			text := fmt.Sprint(value)
+			choices = append(choices, Choice{Value: text, Label: text})
+		}
+		return choices
+	}
+	if options, ok := property["oneOf"].([]any); ok {
+		choices := make([]Choice, 0, len(options))
+		for _, option := range options {
+			option, _ := option.(map[string]any)
+			value, ok := option["const"]
+			if !ok {
+				return nil
+			}
+			choice := Choice{Value: fmt.Sprint(value), Label: fmt.Sprint(value)}
+			if title, ok := option["title"].(string); ok {
+				choice.Label = title
+			}
+			choices = append(choices, choice)
+		}
+		return choices
+	}
+	return nil
+}
+
+// describeRule describes the condition under which a JSON Forms rule shows an element, e.g.
+// "consent = true", or "" when the element has no rule deciding whether it is shown. Scopes are
+// named relative to prefix, like the questions.
+func describeRule(value any, prefix string) string {
+	rule, ok := value.(map[string]any)
+	if !ok {
+		return ""
+	}
+	condition, _ := rule["condition"].(map[string]any)
+	if condition == nil {
+		return ""
+	}
+	switch rule["effect"] {
+	case "SHOW":
+		return describeCondition(condition, prefix)
+	case "HIDE":
+		return "not " + describeCondition(condition, prefix)
+	}
+	return ""
+}
+
+// describeCondition describes a JSON Forms rule condition
+func describeCondition(condition map[string]any, prefix string) string {
+	if conditions, ok := condition["conditions"].([]any); ok {
+		parts := make([]string, 0, len(conditions))
+		for _, c := range conditions {
+			if c, ok := c.(map[string]any); ok {
+				parts = append(parts, describeCondition(c, prefix))
+			}
+		}
+		joiner := " and "
+		if condition["type"] == "OR" {
+			joiner = " or "
+		}
+		return "(" + strings.Join(parts, joiner) + ")"
+	}
+
+	scope, _ := condition["scope"].(string)
+	name := scope
+	if segments, ok := scopeSegments(scope); ok {
+		name = joinName(prefix, strings.Join(segments, "."))
+	}
+	schema, _ := condition["schema"].(map[string]any)
+	switch {
+	case schema == nil:
+		return name + " is answered"
+	case schema["const"] != nil:
+		return name + " = " + literal(schema["const"])
+	case schema["enum"] != nil:
+		values, _ := schema["enum"].([]any)
+		texts := make([]string, 0, len(values))
+		for _, v := range values {
+			texts = append(texts, literal(v))
+		}
+		return name + " is one of " + strings.Join(texts, ", ")
+	case schema["minimum"] != nil && schema["maximum"] != nil:
+		return fmt.Sprintf("%s is between %v and %v", name, schema["minimum"], schema["maximum"])
+	case schema["minimum"] != nil:
+		return fmt.Sprintf("%s >= %v", name, schema["minimum"])
+	case schema["maximum"] != nil:
+		return fmt.Sprintf("%s <= %v", name, schema["maximum"])
+	case schema["not"] != nil:
+		not, _ := schema["not"].(map[string]any)
+		if not != nil && not["const"] != nil {
+			return name + " != " + literal(not["const"])
+		}
+	}
+	text, _ := json.Marshal(schema)
+	return name + " matches " + string(text)
+}
+
+// literal writes a condition value as it would appear in JSON
+func literal(v any) string {
+	text, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(text)
+}
+
+// scopeSegments splits a scope such as #/properties/a/properties/b into its property names
+func scopeSegments(scope string) ([]string, bool) {
+	path, ok := strings.CutPrefix(scope, "#/properties/")
+	if !ok || path == "" {
+		return nil, false
+	}
+	return strings.Split(path, "/properties/"), true
+}
+
+// joinName prefixes a variable name with the name of its group
+func joinName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// children returns the elements of a layout
+func children(ui map[string]any) []any {
+	items, _ := ui["elements"].([]any)
+	return items
+}
+
+// containsValue reports whether a JSON array holds the string
+func containsValue(list any, value string) bool {
+	items, _ := list.([]any)
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// number returns a JSON number, or nil
+func number(v any) *float64 {
+	if n, ok := v.(float64); ok {
+		return &n
+	}
+	return nil
+}
+
+// humanize turns a property name such as date_of_birth into "Date of birth"
+func humanize(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	if len(words) == 0 {
+		return name
+	}
+	text := strings.ToLower(strings.Join(words, " "))
+	return strings.ToUpper(text[:1]) + text[1:]
+}
@@ -0,0 +1,162 @@
+package codebook
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"title": "Household visit",
+	"properties": {
+		"consent": {"type": "boolean", "title": "Consent given?"},
+		"age": {"type": "integer", "minimum": 0, "maximum": 120, "x-visible-if": "${consent} = true"},
+		"sex": {"type": "string", "oneOf": [{"const": "f", "title": "Female"}, {"const": "m", "title": "Male"}]},
+		"symptoms": {"type": "array", "items": {"type": "string", "enum": ["fever", "cough"]}},
+		"facility": {"type": "string", "x-code-list": "facilities"},
+		"members": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}},
+		"registration_number": {"type": "string", "x-server-assigned": "sequence"}
+	},
+	"required": ["consent"]
+}`
+
+const testUI = `{
+	"type": "VerticalLayout",
+	"elements": [
+		{"type": "Control", "scope": "#/properties/consent"},
+		{"type": "Group", "label": "Demographics",
+			"rule": {"effect": "SHOW", "condition": {"scope": "#/properties/consent", "schema": {"const": true}}},
+			"elements": [
+				{"type": "Control", "scope": "#/properties/age"},
+				{"type": "Control", "scope": "#/properties/sex", "label": "Sex of respondent"},
+				{"type": "Control", "scope": "#/properties/symptoms",
+					"rule": {"effect": "HIDE", "condition": {"scope": "#/properties/age", "schema": {"maximum": 1}}}},
+				{"type": "Control", "scope": "#/properties/facility"},
+				{"type": "Control", "scope": "#/properties/members", "options": {"detail": {
+					"type": "VerticalLayout", "elements": [{"type": "Control", "scope": "#/properties/name"}]}}}
+			]}
+	]
+}`
+
+func testForm(t *testing.T) Form {
+	t.Helper()
+	var schema, ui map[string]any
+	require.NoError(t, json.Unmarshal([]byte(testSchema), &schema))
+	require.NoError(t, json.Unmarshal([]byte(testUI), &ui))
+	return BuildForm("household", schema, ui)
+}
+
+func TestBuildForm(t *testing.T) {
+	form := testForm(t)
+	assert.Equal(t, "Household visit", form.Title)
+
+	names := make([]string, 0, len(form.Questions))
+	questions := make(map[string]Question)
+	for _, q := range form.Questions {
+		names = append(names, q.Name)
+		questions[q.Name] = q
+	}
+	assert.Equal(t, []string{"consent", "age", "sex", "symptoms", "facility", "members", "members.name", "registration_number"}, names,
+		"questions in the order they are asked, then the ones not asked")
+
+	assert.True(t, questions["consent"].Required)
+	assert.Equal(t, "Yes/No", questions["consent"].Answer)
+	assert.Empty(t, questions["consent"].Section)
+
+	age := questions["age"]
+	assert.Equal(t, "Demographics", age.Section)
+	assert.Equal(t, []string{"${consent} = true", "consent = true"}, age.ShownWhen)
+	assert.Equal(t, "from 0 to 120", bounds(age))
+
+	sex := questions["sex"]
+	assert.Equal(t, "Sex of respondent", sex.Label)
+	assert.Equal(t, "Single choice", sex.Answer)
+	assert.Equal(t, []Choice{{Value: "f", Label: "Female"}, {Value: "m", Label: "Male"}}, sex.Choices)
+
+	symptoms := questions["symptoms"]
+	assert.Equal(t, "Multiple choice", symptoms.Answer)
+	assert.Equal(t, []Choice{{Value: "fever", Label: "fever"}, {Value: "cough", Label: "cough"}}, symptoms.Choices)
+	assert.Equal(t, []string{"consent = true", "not age <= 1"}, symptoms.ShownWhen)
+
+	assert.Equal(t, "facilities", questions["facility"].CodeList)
+	assert.Equal(t, "Repeated group", questions["members"].Answer)
+	assert.Equal(t, "Members", questions["members.name"].Section)
+	assert.True(t, questions["members.name"].Required)
+
+	registration := questions["registration_number"]
+	assert.True(t, registration.NotAsked)
+	assert.Equal(t, "sequence", registration.ServerAssigned)
+}
+
+func TestBuildFormSwipePages(t *testing.T) {
+	schema := map[string]any{"properties": map[string]any{"a": map[string]any{"type": "string"}, "b": map[string]any{"type": "number"}}}
+	ui := map[string]any{"type": "SwipeLayout", "elements": []any{
+		map[string]any{"type": "VerticalLayout", "elements": []any{map[string]any{"type": "Control", "scope": "#/properties/b"}}},
+		map[string]any{"type": "VerticalLayout", "elements": []any{map[string]any{"type": "Control", "scope": "#/properties/a"}}},
+	}}
+
+	form := BuildForm("pages", schema, ui)
+	require.Len(t, form.Questions, 2)
+	assert.Equal(t, "pages", form.Title)
+	assert.Equal(t, "b", form.Questions[0].Name)
+	assert.Equal(t, "Page 1", form.Questions[0].Section)
+	assert.Equal(t, "Decimal number", form.Questions[0].Answer)
+	assert.Equal(t, "Page 2", form.Questions[1].Section)
+}
+
+func TestWriteHTML(t *testing.T) {
+	form := testForm(t)
+	form.Description = "Asked at <every> visit"
+	book := &Codebook{Version: "0003", GeneratedAt: time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC), Forms: []Form{form}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, book))
+	page := buf.String()
+	assert.Contains(t, page, "App bundle version 0003")
+	assert.Contains(t, page, "Asked at &lt;every&gt; visit")
+	assert.Contains(t, page, "<code>f</code> Female")
+	assert.Contains(t, page, `<tr class="section"><td colspan="5">Demographics</td></tr>`)
+	assert.Contains(t, page, "not asked in the form")
+}
+
+func TestWritePDF(t *testing.T) {
+	form := testForm(t)
+	// Enough questions to fill more than one page
+	for i := 0; i < 80; i++ {
+		form.Questions = append(form.Questions, Question{Name: "extra_" + strconv.Itoa(i), Label: "Extra (é) – question", Answer: "Text"})
+	}
+	book := &Codebook{Version: "0003", Forms: []Form{form, form}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, book))
+	doc := buf.Bytes()
+	require.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+	assert.Contains(t, string(doc), `(extra_0 - Extra \(\351\) - question)`)
+
+	// Every cross-reference entry points at its object
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(doc)
+	require.NotNil(t, match)
+	xref, err := strconv.Atoi(string(match[1]))
+	require.NoError(t, err)
+	lines := strings.Split(string(doc[xref:]), "\n")
+	require.Equal(t, "xref", lines[0])
+	count, err := strconv.Atoi(strings.Fields(lines[1])[1])
+	require.NoError(t, err)
+	for i := 1; i < count; i++ {
+		offset, err := strconv.Atoi(strings.Fields(lines[2+i])[0])
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(doc[offset:], []byte(strconv.Itoa(i)+" 0 obj")), "object %d", i)
+	}
+
+	pages := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(doc)
+	require.NotNil(t, pages)
+	n, _ := strconv.Atoi(string(pages[1]))
+	assert.GreaterOrEqual(t, n, 4, "each form starts a page and fills more than one")
+}
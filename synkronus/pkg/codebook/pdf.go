@@ -0,0 +1,99 @@
+package codebook
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opendataensemble/synkronus/pkg/pdfdoc"
+)
+
+// Fonts of PDF codebooks
+const (
+	fontRegular = pdfdoc.FontRegular
+	fontBold    = pdfdoc.FontBold
+)
+
+// WritePDF writes a codebook as a PDF document starting each form on a new page. Text is set in
+// Helvetica; characters outside its Latin-1 range are replaced.
+func WritePDF(w io.Writer, book *Codebook) error {
+	doc := pdfdoc.New("Codebook - app bundle version " + book.Version)
+	doc.Line(fontBold, 18, 0, "Codebook")
+	doc.Line(fontRegular, 10, 0, fmt.Sprintf("App bundle version %s, generated %s", book.Version, book.GeneratedAt.Format("2006-01-02 15:04 MST")))
+
+	for i, form := range book.Forms {
+		if i > 0 {
+			doc.NewPage()
+		} else {
+			doc.Space(16)
+		}
+		doc.Line(fontBold, 14, 0, form.Title+" ("+form.FormType+")")
+		if form.Description != "" {
+			doc.Line(fontRegular, 10, 0, form.Description)
+		}
+
+		section := ""
+		for _, q := range form.Questions {
+			if q.Section != section {
+				section = q.Section
+				if section != "" {
+					doc.Space(8)
+					doc.Line(fontBold, 11, 0, section)
+				}
+			}
+			doc.Space(6)
+			doc.Line(fontBold, 10, 0, q.Name+" - "+q.Label)
+			for _, detail := range details(q) {
+				doc.Line(fontRegular, 9, detail.indent, detail.text)
+			}
+		}
+	}
+	return doc.Write(w)
+}
+
+// detail is a line describing a question, indented in points
+type detail struct {
+	indent float64
+	text   string
+}
+
+// details describes a question below its name and label
+func details(q Question) []detail {
+	var lines []detail
+	add := func(indent float64, text string) {
+		lines = append(lines, detail{indent: indent, text: text})
+	}
+
+	if q.Hint != "" {
+		add(12, q.Hint)
+	}
+	answer := "Answer: " + q.Answer
+	if q.Required {
+		answer += ", required"
+	}
+	if b := bounds(q); b != "" {
+		answer += ", " + b
+	}
+	add(12, answer)
+	if q.CodeList != "" {
+		add(12, "Codes of list "+q.CodeList)
+	}
+	if q.ServerAssigned != "" {
+		add(12, "Assigned by the server ("+q.ServerAssigned+")")
+	}
+	if q.NotAsked {
+		add(12, "Not asked in the form")
+	}
+	if len(q.Choices) > 0 {
+		add(12, "Choices:")
+		for _, choice := range q.Choices {
+			add(24, choice.Value+" = "+choice.Label)
+		}
+	}
+	for _, condition := range q.ShownWhen {
+		add(12, "Asked when: "+condition)
+	}
+	if q.Constraint != "" {
+		add(12, "Constraint: "+q.Constraint)
+	}
+	return lines
+}
@@ -0,0 +1,37 @@
+package codebook
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strconv"
+)
+
+//go:embed codebook.html
+var pageSource string
+
+var pageTemplate = template.Must(template.New("codebook.html").Funcs(template.FuncMap{
+	"bounds": bounds,
+}).Parse(pageSource))
+
+// WriteHTML writes a codebook as an HTML page that prints one form per page
+func WriteHTML(w io.Writer, book *Codebook) error {
+	return pageTemplate.Execute(w, book)
+}
+
+// bounds describes the range of numbers a question accepts, or "" when it has none
+func bounds(q Question) string {
+	switch {
+	case q.Minimum != nil && q.Maximum != nil:
+		return "from " + formatNumber(*q.Minimum) + " to " + formatNumber(*q.Maximum)
+	case q.Minimum != nil:
+		return "at least " + formatNumber(*q.Minimum)
+	case q.Maximum != nil:
+		return "at most " + formatNumber(*q.Maximum)
+	}
+	return ""
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,111 @@
+package codelist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Common errors
+var (
+	// ErrNotFound is returned when a code list or version does not exist
+	ErrNotFound = errors.New("code list not found")
+	// ErrInvalidName is returned when a code list name is malformed
+	ErrInvalidName = errors.New("invalid code list name")
+	// ErrInvalidCodeList is returned when the entries of a code list are missing or duplicated
+	ErrInvalidCodeList = errors.New("invalid code list")
+)
+
+// namePattern is the form of code list names, as written in x-code-list references
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+// Entry is a code of a code list
+type Entry struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+	// Retired codes are no longer offered for new records but are still accepted on push, so
+	// records captured on devices that have not pulled the new version yet are not rejected
+	Retired bool `json:"retired,omitempty"`
+}
+
+// CodeList is a published version of a managed code list, such as an ICD subset or a facility
+// registry, that form fields reference with x-code-list
+type CodeList struct {
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Entries     []Entry   `json:"entries"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Summary describes the latest version of a code list without its entries; clients compare
+// versions to fetch only the lists that changed
+type Summary struct {
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	EntryCount  int       `json:"entry_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Input is the content of a new code list version
+type Input struct {
+	Description string  `json:"description"`
+	Entries     []Entry `json:"entries"`
+}
+
+// Service keeps the managed code lists
+type Service interface {
+	// Publish adds the next version of a code list, starting at 1 for a new list
+	Publish(ctx context.Context, name string, input Input, createdBy string) (*CodeList, error)
+
+	// Get returns a version of a code list; version 0 returns the latest
+	Get(ctx context.Context, name string, version int) (*CodeList, error)
+
+	// List returns a summary of the latest version of every code list, by name
+	List(ctx context.Context) ([]Summary, error)
+
+	// Versions returns a summary of every version of a code list, newest first
+	Versions(ctx context.Context, name string) ([]Summary, error)
+
+	// Codes returns the codes of the latest version of a code list, retired ones included
+	Codes(ctx context.Context, name string) ([]string, error)
+}
+
+// Validate checks a code list before it is published: the name must be a valid reference name
+// and every entry needs a code that no other entry of the list has
+func Validate(name string, input Input) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: use lowercase letters, digits, '-' and '_', starting with a letter", ErrInvalidName)
+	}
+	if len(input.Entries) == 0 {
+		return fmt.Errorf("%w: entries are required", ErrInvalidCodeList)
+	}
+
+	seen := make(map[string]bool, len(input.Entries))
+	for i, entry := range input.Entries {
+		if strings.TrimSpace(entry.Code) == "" {
+			return fmt.Errorf("%w: entry %d has no code", ErrInvalidCodeList, i)
+		}
+		if seen[entry.Code] {
+			return fmt.Errorf("%w: code %q appears more than once", ErrInvalidCodeList, entry.Code)
+		}
+		seen[entry.Code] = true
+	}
+	return nil
+}
+
+// Summarize returns the summary of a code list
+func (c *CodeList) Summarize() Summary {
+	return Summary{
+		Name:        c.Name,
+		Version:     c.Version,
+		Description: c.Description,
+		EntryCount:  len(c.Entries),
+		CreatedAt:   c.CreatedAt,
+	}
+}
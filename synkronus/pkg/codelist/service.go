@@ -0,0 +1,129 @@
+package codelist
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// columns are the code_lists columns scanned by scanCodeList
+const columns = "name, version, description, entries, created_by, created_at"
+
+// summaryColumns are the code_lists columns scanned by query
+const summaryColumns = "name, version, description, jsonb_array_length(entries), created_at"
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new code list service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Publish adds the next version of a code list, starting at 1 for a new list
+func (s *service) Publish(ctx context.Context, name string, input Input, createdBy string) (*CodeList, error) {
+	if err := Validate(name, input); err != nil {
+		return nil, err
+	}
+
+	entries, err := json.Marshal(input.Entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode code list entries: %w", err)
+	}
+	c, err := scanCodeList(s.db.QueryRowContext(ctx, `
+		INSERT INTO code_lists (name, version, description, entries, created_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4 FROM code_lists WHERE name = $1
+		RETURNING `+columns,
+		name, input.Description, entries, createdBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish code list: %w", err)
+	}
+
+	s.log.Info("Code list published", "name", c.Name, "version", c.Version, "entryCount", len(c.Entries), "createdBy", createdBy)
+	return c, nil
+}
+
+// Get returns a version of a code list; version 0 returns the latest
+func (s *service) Get(ctx context.Context, name string, version int) (*CodeList, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+columns+" FROM code_lists WHERE name = $1 ORDER BY version DESC LIMIT 1", name)
+	if version > 0 {
+		row = s.db.QueryRowContext(ctx,
+			"SELECT "+columns+" FROM code_lists WHERE name = $1 AND version = $2", name, version)
+	}
+	c, err := scanCodeList(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get code list: %w", err)
+	}
+	return c, nil
+}
+
+// List returns a summary of the latest version of every code list, by name
+func (s *service) List(ctx context.Context) ([]Summary, error) {
+	return s.query(ctx, "SELECT DISTINCT ON (name) "+summaryColumns+" FROM code_lists ORDER BY name, version DESC")
+}
+
+// Versions returns a summary of every version of a code list, newest first
+func (s *service) Versions(ctx context.Context, name string) ([]Summary, error) {
+	summaries, err := s.query(ctx, "SELECT "+summaryColumns+" FROM code_lists WHERE name = $1 ORDER BY version DESC", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrNotFound
+	}
+	return summaries, nil
+}
+
+// Codes returns the codes of the latest version of a code list, retired ones included
+func (s *service) Codes(ctx context.Context, name string) ([]string, error) {
+	c, err := s.Get(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(c.Entries))
+	for _, entry := range c.Entries {
+		codes = append(codes, entry.Code)
+	}
+	return codes, nil
+}
+
+func (s *service) query(ctx context.Context, query string, args ...any) ([]Summary, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list code lists: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]Summary, 0)
+	for rows.Next() {
+		var summary Summary
+		if err := rows.Scan(&summary.Name, &summary.Version, &summary.Description, &summary.EntryCount, &summary.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan code list: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// scanCodeList reads a code list selected with columns
+func scanCodeList(row interface{ Scan(...any) error }) (*CodeList, error) {
+	var c CodeList
+	var entries []byte
+	if err := row.Scan(&c.Name, &c.Version, &c.Description, &entries, &c.CreatedBy, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(entries, &c.Entries); err != nil {
+		return nil, fmt.Errorf("failed to decode entries of code list %s@%d: %w", c.Name, c.Version, err)
+	}
+	return &c, nil
+}
@@ -7,18 +7,50 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/middleware/security"
 )
 
 // Config holds all configuration for the application
 type Config struct {
 	// Server settings
 	Port string
+	// HTTPS without a reverse proxy, from certificate files or certificates obtained over ACME
+	TLSCertFile             string // PEM certificate chain; read again when renewed on disk
+	TLSKeyFile              string // PEM private key of TLSCertFile
+	TLSAutocertDomains      string // Comma separated host names to obtain Let's Encrypt certificates for
+	TLSAutocertEmail        string // Contact address given to the CA
+	TLSAutocertCacheDir     string // Directory keeping obtained certificates across restarts
+	TLSAutocertDirectoryURL string // ACME directory; empty uses Let's Encrypt production
+	TLSClientCAFile         string // PEM CA certificates client certificates are verified against
+	HTTPRedirectPort        string // Plain HTTP port redirecting to HTTPS and answering ACME challenges; empty disables it
 
 	// Database settings
 	DatabaseURL string
+	// TLS to PostgreSQL; the files are read again for new connections, so rotated certificates
+	// are used without a restart
+	DatabaseSSLMode           string // "require", "verify-ca" or "verify-full"; defaults to verify-full with a root certificate
+	DatabaseSSLRootCert       string // PEM file of the CAs the server certificate must be signed by
+	DatabaseSSLCert           string // PEM client certificate for mutual TLS
+	DatabaseSSLKey            string // PEM private key of the client certificate
+	DatabaseCertReloadSeconds int    // How often the files are checked for rotated certificates
+	// Connection pool; connections are closed after their lifetime or idle time so that
+	// long-running servers give them back to PostgreSQL
+	DatabaseMaxOpenConns           int // Most connections open at once; 0 is unlimited
+	DatabaseMaxIdleConns           int // Most idle connections kept open
+	DatabaseConnMaxLifetimeMinutes int // Age after which connections are closed; 0 keeps them
+	DatabaseConnMaxIdleMinutes     int // Idle time after which connections are closed; 0 keeps them
+	DatabasePoolStatsSeconds       int // How often pool statistics are logged; 0 disables logging
+	// Streaming replica sync pulls and exports are read from while it is healthy and caught up;
+	// it uses the TLS and pool settings of the primary
+	DatabaseReplicaURL           string // Connection string of the replica; empty reads from the primary
+	DatabaseReplicaMaxLagSeconds int    // Replay lag beyond which reads go to the primary; 0 allows any lag
+	DatabaseReplicaCheckSeconds  int    // How often the replica's health and lag are checked
 
 	// Authentication
-	JWTSecret string
+	JWTSecret           string
+	JWTPreviousSecrets  string // Comma separated former JWT secrets still accepted after a secret change
+	JWTSigningAlgorithm string // "HS256" signs with JWT_SECRET; "ES256", "RS256" or "EdDSA" with rotating keys published as a JWKS
+	JWTKeyRotationDays  int    // Days each asymmetric signing key signs tokens
 
 	// Logging
 	LogLevel string
@@ -29,6 +61,174 @@ type Config struct {
 	// App Bundle settings
 	AppBundlePath   string
 	MaxVersionsKept int
+	AppBundleCDNURL string // Base URL of a CDN in front of the server, used for content-hashed bundle file URLs
+	// Comma separated base64 Ed25519 public keys; when set, pushed bundles must be signed by one of them
+	AppBundleSigningKeys string
+
+	// App bundle version storage; AppBundlePath stays a local copy of the current version
+	AppBundleStorage     string // "local" keeps versions on disk, "s3" in an S3-compatible bucket
+	AppBundleS3Prefix    string // Key prefix of the app bundle objects in the bucket
+	AppBundleSyncSeconds int    // How often replicas check storage for a switched version
+
+	// Limits of pushed app bundles; 0 disables a limit
+	AppBundleMaxSizeMB         int // Largest zip file accepted
+	AppBundleMaxFiles          int // Most files a zip file may hold
+	AppBundleMaxUncompressedMB int // Most a zip file may unpack into
+	AppBundleMaxFileSizeMB     int // Most a single file in a zip file may unpack into
+
+	// S3-compatible object storage such as Amazon S3 or MinIO
+	S3Endpoint        string // Base URL, e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool // Address the bucket as endpoint/bucket, as MinIO needs
+
+	// Sync timestamp validation
+	SyncMaxClockSkewMinutes int    // How far client timestamps may lie in the future
+	SyncMinValidYear        int    // Client timestamps before this year are treated as a dead clock
+	SyncTimestampPolicy     string // "flag" keeps skewed timestamps with a warning, "correct" replaces them
+
+	// Sync conflict handling
+	SyncConflictPolicy string // "last-write-wins", "server-wins" or "reject-and-report" for stale pushes
+
+	// Sync pull scope
+	SyncPullScope string // "all" pulls every record, "assigned" only records a user owns or is assigned
+
+	// Soft multi-tenancy
+	MultiTenancyEnabled bool   // Keep the users, observations and app bundles of tenants apart
+	TenantHeader        string // Header in which default-tenant admins name the tenant they act for
+
+	// Sync pull pagination
+	SyncPageTokenMinutes int // How long the page token of a pull can be used to fetch the next page
+
+	// Submission receipts
+	SyncReceiptsEnabled bool   // Return a verification code for each record accepted by a push
+	SyncReceiptSecret   string // Key of the verification codes; empty uses the JWT secret
+
+	// Sync log compaction
+	SyncTombstoneRetentionDays  int // Deleted records older than this are purged; 0 keeps them
+	SyncHistoryRetentionDays    int // Superseded record versions older than this are collapsed; 0 keeps them
+	SyncCompactionIntervalHours int // How often compaction runs in the background; 0 only on request
+
+	// Photos, audio and signatures collected with observations
+	AttachmentMaxSizeMB int // Largest accepted attachment in megabytes; 0 disables the limit
+
+	// Lifecycle of attachment content
+	AttachmentColdStorage     string // "s3" moves aging content to the S3 bucket, "off" keeps it on local disk
+	AttachmentS3Prefix        string // Key prefix of attachment content in the bucket
+	AttachmentS3StorageClass  string // Storage class of content moved to the bucket, e.g. STANDARD_IA or GLACIER_IR
+	AttachmentColdAfterDays   int    // Content of attachments older than this moves to cold storage; 0 never moves it
+	AttachmentDeleteAfterDays int    // Attachments older than this are deleted; 0 keeps them
+
+	// Disaster-recovery copies of app bundle versions and attachments in a secondary location
+	ReplicationTarget          string // "dir" copies to ReplicationDir, "s3" to ReplicationS3Bucket, "off" disables replication
+	ReplicationDir             string // Directory on another volume, such as a network share
+	ReplicationS3Bucket        string // Bucket of the copies, usually in another region than S3_BUCKET
+	ReplicationS3Prefix        string // Key prefix of the copies in the bucket
+	ReplicationS3Endpoint      string // Endpoint of the bucket; empty uses S3_ENDPOINT
+	ReplicationS3Region        string // Region of the bucket; empty uses S3_REGION
+	ReplicationS3AccessKeyID   string // Credentials of the bucket; empty uses S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY
+	ReplicationS3SecretKey     string
+	ReplicationIntervalMinutes int // Time between replication passes
+
+	// Supporting documents attached to observations by admins
+	DocumentAllowedTypes string // Comma separated content types accepted for upload
+	DocumentMaxSizeMB    int    // Largest accepted document in megabytes
+
+	// Bandwidth shaping of pull responses and attachment downloads
+	BandwidthClientKBps int // Sustained throughput per client in kilobytes per second; 0 disables shaping
+	BandwidthBurstKB    int // Kilobytes an idle client may receive at full speed
+
+	// Rate limiting of login, sync and app bundle requests
+	RateLimit              string // "memory", "redis" or "off"
+	RateLimitUserPerMinute int    // Sustained requests per minute of each authenticated user
+	RateLimitIPPerMinute   int    // Sustained requests per minute of each address, for unauthenticated requests
+	RateLimitBurst         int    // Requests an idle client may make at once
+	RateLimitProxies       string // Comma separated addresses and CIDR ranges of proxies whose X-Forwarded-For is trusted
+
+	// Privacy of saved query results shared on dashboards
+	SavedQueryMinGroupSize int     // Values of groups covering fewer records are withheld; 0 disables
+	SavedQueryNoise        float64 // Scale of the Laplace noise added to counts and sums; 0 disables
+
+	// Response caching of expensive read endpoints
+	ResponseCache           string // "memory", "redis" or "off"
+	ResponseCacheTTLSeconds int    // How long a cached response is served at most
+
+	// State shared by servers behind a load balancer
+	RedisURL                 string // redis://[:password@]host[:port][/database]; empty keeps state per server
+	SyncPushIdempotencyHours int    // How long push responses are kept to answer retried transmissions; 0 disables
+
+	// Latency tracking of pull, push and export
+	SlowOperationThresholdMS int // Pulls, pushes and exports taking longer are logged as warnings; 0 disables
+
+	// Password hashing
+	PasswordHashAlgorithm     string // "argon2id" or "bcrypt" for new hashes; other hashes are upgraded at login
+	PasswordArgon2MemoryKB    int    // Memory per argon2id hash in KiB
+	PasswordArgon2Iterations  int    // Passes over the memory per argon2id hash
+	PasswordArgon2Parallelism int    // Threads per argon2id hash
+
+	// Browser security headers; an empty value omits the header
+	SecurityAPIPolicy      string // Content Security Policy of API responses
+	SecurityContentPolicy  string // Content Security Policy of served app bundle content
+	SecurityFrameAncestors string // Who may embed responses in a frame: 'none', 'self' or origins
+	SecurityReferrerPolicy string
+
+	// Auth event audit log and security alerts
+	AuditCountryHeader            string // Request header carrying the client's country code, e.g. CF-IPCountry behind Cloudflare; empty ignores countries
+	AlertRules                    string // Comma separated alert rules: failed_logins, new_country, admin_grant
+	AlertFailedLoginThreshold     int    // Failed logins for one username within the window that raise an alert
+	AlertFailedLoginWindowMinutes int
+	AlertWebhookURL               string // Alerts are posted here as JSON
+	AlertEmailTo                  string // Comma separated alert recipients
+	SMTPHost                      string
+	SMTPPort                      int
+	SMTPUsername                  string
+	SMTPPassword                  string
+	SMTPFrom                      string
+
+	// Admins allowed to impersonate other users; empty disables impersonation
+	ImpersonationAdmins string // Comma separated usernames
+
+	// JSON file declaring authenticators tried ahead of API keys and JWTs, e.g. for an SSO gateway
+	AuthenticatorsFile string
+
+	// YAML or JSON seed files, or directories of them, applied at startup
+	SeedFiles string // Comma separated paths
+
+	// Demo mode provisions a sample app bundle, demo users and synthetic observations for workshops
+	DemoMode         bool
+	DemoObservations int
+	DemoPassword     string
+
+	// Attempts at delivering a webhook event before it is moved to the dead-letter list
+	WebhookMaxAttempts int
+
+	// Destinations requests to admin-supplied URLs may reach; internal addresses need allowing
+	OutboundAllowlist string // Comma separated hostnames, *.domains and IP prefixes
+	OutboundDenylist  string // Comma separated hostnames, *.domains and IP prefixes
+
+	// User invitations, emailed through the SMTP server above
+	InviteURL         string // Page invitees choose their password on; the token is appended as ?token=
+	InviteExpiryHours int
+
+	// Edge server federation with an upstream server
+	FederationUpstreamURL     string // Base URL of the central server; empty runs as a standalone server
+	FederationUsername        string // Read-write account on the upstream server
+	FederationPassword        string
+	FederationClientID        string // Identifies this edge server upstream; defaults to the host name
+	FederationIntervalSeconds int    // Time between replication attempts
+	FederationIDBlockSize     int    // IDs reserved upstream at a time per sequence
+	FederationIDSequences     string // Comma separated ID sequences handed out locally
+
+	// Flattened observation tables materialized for analysts
+	AnalyticsDatabaseURL           string // Database the analytics schema is written to; empty uses the synkronus database
+	AnalyticsSchema                string // Schema rebuilt on every export
+	AnalyticsExportIntervalMinutes int    // Time between exports; 0 disables them
+
+	// Admin web UI served at /admin
+	AdminUIEnabled bool
+	AdminUIDir     string // Directory holding a build of the UI; empty serves the one built into the binary
 
 	// Internal tracking
 	Source string // Source of the configuration (env, .env file path, etc.)
@@ -139,7 +339,172 @@ func Load(log *logger.Logger) (*Config, error) {
 		LogLevel:        getEnvOrDefault("LOG_LEVEL", "info"),
 		AppBundlePath:   getEnvOrDefault("APP_BUNDLE_PATH", "./data/app-bundles"),
 		MaxVersionsKept: getEnvIntOrDefault("MAX_VERSIONS_KEPT", 5),
-		Source:          configSource,
+		AppBundleCDNURL: getEnvOrDefault("APP_BUNDLE_CDN_URL", ""),
+
+		TLSCertFile:             getEnvOrDefault("TLS_CERT_FILE", ""),
+		TLSKeyFile:              getEnvOrDefault("TLS_KEY_FILE", ""),
+		TLSAutocertDomains:      getEnvOrDefault("TLS_AUTOCERT_DOMAINS", ""),
+		TLSAutocertEmail:        getEnvOrDefault("TLS_AUTOCERT_EMAIL", ""),
+		TLSAutocertCacheDir:     getEnvOrDefault("TLS_AUTOCERT_CACHE_DIR", "./data/autocert"),
+		TLSAutocertDirectoryURL: getEnvOrDefault("TLS_AUTOCERT_DIRECTORY_URL", ""),
+		TLSClientCAFile:         getEnvOrDefault("TLS_CLIENT_CA_FILE", ""),
+		HTTPRedirectPort:        getEnvOrDefault("HTTP_REDIRECT_PORT", ""),
+
+		DatabaseSSLMode:           getEnvOrDefault("DB_SSL_MODE", ""),
+		DatabaseSSLRootCert:       getEnvOrDefault("DB_SSL_ROOT_CERT", ""),
+		DatabaseSSLCert:           getEnvOrDefault("DB_SSL_CERT", ""),
+		DatabaseSSLKey:            getEnvOrDefault("DB_SSL_KEY", ""),
+		DatabaseCertReloadSeconds: getEnvIntOrDefault("DB_CERT_RELOAD_SECONDS", 60),
+
+		DatabaseMaxOpenConns:           getEnvIntOrDefault("DB_MAX_OPEN_CONNS", 10),
+		DatabaseMaxIdleConns:           getEnvIntOrDefault("DB_MAX_IDLE_CONNS", 5),
+		DatabaseConnMaxLifetimeMinutes: getEnvIntOrDefault("DB_CONN_MAX_LIFETIME_MINUTES", 60),
+		DatabaseConnMaxIdleMinutes:     getEnvIntOrDefault("DB_CONN_MAX_IDLE_MINUTES", 5),
+		DatabasePoolStatsSeconds:       getEnvIntOrDefault("DB_POOL_STATS_INTERVAL_SECONDS", 300),
+
+		DatabaseReplicaURL:           getEnvOrDefault("DB_READ_REPLICA_CONNECTION", ""),
+		DatabaseReplicaMaxLagSeconds: getEnvIntOrDefault("DB_READ_REPLICA_MAX_LAG_SECONDS", 30),
+		DatabaseReplicaCheckSeconds:  getEnvIntOrDefault("DB_READ_REPLICA_CHECK_SECONDS", 10),
+
+		AppBundleSigningKeys: getEnvOrDefault("APP_BUNDLE_SIGNING_KEYS", ""),
+
+		AppBundleStorage:     getEnvOrDefault("APP_BUNDLE_STORAGE", "local"),
+		AppBundleS3Prefix:    getEnvOrDefault("APP_BUNDLE_S3_PREFIX", "app-bundles"),
+		AppBundleSyncSeconds: getEnvIntOrDefault("APP_BUNDLE_SYNC_SECONDS", 30),
+
+		AppBundleMaxSizeMB:         getEnvIntOrDefault("APP_BUNDLE_MAX_SIZE_MB", 256),
+		AppBundleMaxFiles:          getEnvIntOrDefault("APP_BUNDLE_MAX_FILES", 10000),
+		AppBundleMaxUncompressedMB: getEnvIntOrDefault("APP_BUNDLE_MAX_UNCOMPRESSED_MB", 1024),
+		AppBundleMaxFileSizeMB:     getEnvIntOrDefault("APP_BUNDLE_MAX_FILE_SIZE_MB", 100),
+
+		S3Endpoint:        getEnvOrDefault("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		S3Region:          getEnvOrDefault("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnvOrDefault("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnvOrDefault("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnvOrDefault("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:       getEnvBoolOrDefault("S3_PATH_STYLE", true),
+
+		SyncMaxClockSkewMinutes: getEnvIntOrDefault("SYNC_MAX_CLOCK_SKEW_MINUTES", 1440),
+		SyncMinValidYear:        getEnvIntOrDefault("SYNC_MIN_VALID_YEAR", 2000),
+		SyncTimestampPolicy:     getEnvOrDefault("SYNC_TIMESTAMP_POLICY", "flag"),
+
+		SyncConflictPolicy: getEnvOrDefault("SYNC_CONFLICT_POLICY", "last-write-wins"),
+
+		SyncPullScope: getEnvOrDefault("SYNC_PULL_SCOPE", "all"),
+
+		MultiTenancyEnabled: getEnvBoolOrDefault("MULTI_TENANCY_ENABLED", false),
+		TenantHeader:        getEnvOrDefault("TENANT_HEADER", "X-Tenant-ID"),
+
+		SyncPageTokenMinutes: getEnvIntOrDefault("SYNC_PAGE_TOKEN_MINUTES", 60),
+
+		SyncReceiptsEnabled: getEnvBoolOrDefault("SYNC_RECEIPTS_ENABLED", false),
+		SyncReceiptSecret:   getEnvOrDefault("SYNC_RECEIPT_SECRET", ""),
+
+		SyncTombstoneRetentionDays:  getEnvIntOrDefault("SYNC_TOMBSTONE_RETENTION_DAYS", 90),
+		SyncHistoryRetentionDays:    getEnvIntOrDefault("SYNC_HISTORY_RETENTION_DAYS", 365),
+		SyncCompactionIntervalHours: getEnvIntOrDefault("SYNC_COMPACTION_INTERVAL_HOURS", 24),
+
+		AttachmentMaxSizeMB: getEnvIntOrDefault("ATTACHMENT_MAX_SIZE_MB", 50),
+
+		AttachmentColdStorage:     getEnvOrDefault("ATTACHMENT_COLD_STORAGE", "off"),
+		AttachmentS3Prefix:        getEnvOrDefault("ATTACHMENT_S3_PREFIX", "attachments"),
+		AttachmentS3StorageClass:  getEnvOrDefault("ATTACHMENT_S3_STORAGE_CLASS", "STANDARD_IA"),
+		AttachmentColdAfterDays:   getEnvIntOrDefault("ATTACHMENT_COLD_AFTER_DAYS", 0),
+		AttachmentDeleteAfterDays: getEnvIntOrDefault("ATTACHMENT_DELETE_AFTER_DAYS", 0),
+
+		ReplicationTarget:          getEnvOrDefault("REPLICATION_TARGET", "off"),
+		ReplicationDir:             getEnvOrDefault("REPLICATION_DIR", ""),
+		ReplicationS3Bucket:        getEnvOrDefault("REPLICATION_S3_BUCKET", ""),
+		ReplicationS3Prefix:        getEnvOrDefault("REPLICATION_S3_PREFIX", "replica"),
+		ReplicationS3Endpoint:      getEnvOrDefault("REPLICATION_S3_ENDPOINT", ""),
+		ReplicationS3Region:        getEnvOrDefault("REPLICATION_S3_REGION", ""),
+		ReplicationS3AccessKeyID:   getEnvOrDefault("REPLICATION_S3_ACCESS_KEY_ID", ""),
+		ReplicationS3SecretKey:     getEnvOrDefault("REPLICATION_S3_SECRET_ACCESS_KEY", ""),
+		ReplicationIntervalMinutes: getEnvIntOrDefault("REPLICATION_INTERVAL_MINUTES", 15),
+
+		DocumentAllowedTypes: getEnvOrDefault("DOCUMENT_ALLOWED_TYPES", "application/pdf,image/jpeg,image/png"),
+		DocumentMaxSizeMB:    getEnvIntOrDefault("DOCUMENT_MAX_SIZE_MB", 20),
+
+		BandwidthClientKBps: getEnvIntOrDefault("BANDWIDTH_CLIENT_KBPS", 0),
+		BandwidthBurstKB:    getEnvIntOrDefault("BANDWIDTH_BURST_KB", 256),
+
+		RateLimit:              getEnvOrDefault("RATE_LIMIT", "off"),
+		RateLimitUserPerMinute: getEnvIntOrDefault("RATE_LIMIT_USER_PER_MINUTE", 120),
+		RateLimitIPPerMinute:   getEnvIntOrDefault("RATE_LIMIT_IP_PER_MINUTE", 30),
+		RateLimitBurst:         getEnvIntOrDefault("RATE_LIMIT_BURST", 20),
+		RateLimitProxies:       getEnvOrDefault("RATE_LIMIT_TRUSTED_PROXIES", ""),
+
+		SavedQueryMinGroupSize: getEnvIntOrDefault("SAVED_QUERY_MIN_GROUP_SIZE", 0),
+		SavedQueryNoise:        getEnvFloatOrDefault("SAVED_QUERY_NOISE", 0),
+
+		ResponseCache:           getEnvOrDefault("RESPONSE_CACHE", "off"),
+		ResponseCacheTTLSeconds: getEnvIntOrDefault("RESPONSE_CACHE_TTL_SECONDS", 60),
+
+		RedisURL:                 getEnvOrDefault("REDIS_URL", ""),
+		SyncPushIdempotencyHours: getEnvIntOrDefault("SYNC_PUSH_IDEMPOTENCY_HOURS", 24),
+
+		SlowOperationThresholdMS: getEnvIntOrDefault("SLOW_OPERATION_THRESHOLD_MS", 10000),
+
+		JWTPreviousSecrets:  getEnvOrDefault("JWT_PREVIOUS_SECRETS", ""),
+		JWTSigningAlgorithm: getEnvOrDefault("JWT_SIGNING_ALGORITHM", "HS256"),
+		JWTKeyRotationDays:  getEnvIntOrDefault("JWT_KEY_ROTATION_DAYS", 30),
+
+		PasswordHashAlgorithm:     getEnvOrDefault("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		PasswordArgon2MemoryKB:    getEnvIntOrDefault("PASSWORD_ARGON2_MEMORY_KB", 19456),
+		PasswordArgon2Iterations:  getEnvIntOrDefault("PASSWORD_ARGON2_ITERATIONS", 2),
+		PasswordArgon2Parallelism: getEnvIntOrDefault("PASSWORD_ARGON2_PARALLELISM", 1),
+
+		SecurityAPIPolicy:      getEnvOrDefault("SECURITY_CSP_API", security.DefaultAPIPolicy),
+		SecurityContentPolicy:  getEnvOrDefault("SECURITY_CSP_CONTENT", security.DefaultContentPolicy),
+		SecurityFrameAncestors: getEnvOrDefault("SECURITY_FRAME_ANCESTORS", security.DefaultFrameAncestors),
+		SecurityReferrerPolicy: getEnvOrDefault("SECURITY_REFERRER_POLICY", security.DefaultReferrerPolicy),
+
+		AuditCountryHeader:            getEnvOrDefault("AUDIT_COUNTRY_HEADER", ""),
+		AlertRules:                    getEnvOrDefault("ALERT_RULES", "failed_logins,new_country,admin_grant"),
+		AlertFailedLoginThreshold:     getEnvIntOrDefault("ALERT_FAILED_LOGIN_THRESHOLD", 5),
+		AlertFailedLoginWindowMinutes: getEnvIntOrDefault("ALERT_FAILED_LOGIN_WINDOW_MINUTES", 15),
+		AlertWebhookURL:               getEnvOrDefault("ALERT_WEBHOOK_URL", ""),
+		AlertEmailTo:                  getEnvOrDefault("ALERT_EMAIL_TO", ""),
+		SMTPHost:                      getEnvOrDefault("SMTP_HOST", ""),
+		SMTPPort:                      getEnvIntOrDefault("SMTP_PORT", 587),
+		SMTPUsername:                  getEnvOrDefault("SMTP_USERNAME", ""),
+		SMTPPassword:                  getEnvOrDefault("SMTP_PASSWORD", ""),
+		SMTPFrom:                      getEnvOrDefault("SMTP_FROM", ""),
+
+		ImpersonationAdmins: getEnvOrDefault("IMPERSONATION_ADMINS", ""),
+
+		AuthenticatorsFile: getEnvOrDefault("AUTHENTICATORS_FILE", ""),
+
+		SeedFiles: getEnvOrDefault("SEED_FILES", ""),
+
+		DemoMode:         getEnvBoolOrDefault("DEMO_MODE", false),
+		DemoObservations: getEnvIntOrDefault("DEMO_OBSERVATIONS", 500),
+		DemoPassword:     getEnvOrDefault("DEMO_PASSWORD", "demo"),
+
+		WebhookMaxAttempts: getEnvIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 10),
+
+		OutboundAllowlist: getEnvOrDefault("OUTBOUND_ALLOWLIST", ""),
+		OutboundDenylist:  getEnvOrDefault("OUTBOUND_DENYLIST", ""),
+
+		InviteURL:         getEnvOrDefault("INVITE_URL", ""),
+		InviteExpiryHours: getEnvIntOrDefault("INVITE_EXPIRY_HOURS", 72),
+
+		FederationUpstreamURL:     getEnvOrDefault("FEDERATION_UPSTREAM_URL", ""),
+		FederationUsername:        getEnvOrDefault("FEDERATION_USERNAME", ""),
+		FederationPassword:        getEnvOrDefault("FEDERATION_PASSWORD", ""),
+		FederationClientID:        getEnvOrDefault("FEDERATION_CLIENT_ID", ""),
+		FederationIntervalSeconds: getEnvIntOrDefault("FEDERATION_INTERVAL_SECONDS", 300),
+		FederationIDBlockSize:     getEnvIntOrDefault("FEDERATION_ID_BLOCK_SIZE", 1000),
+		FederationIDSequences:     getEnvOrDefault("FEDERATION_ID_SEQUENCES", ""),
+
+		AnalyticsDatabaseURL:           getEnvOrDefault("ANALYTICS_DATABASE_URL", ""),
+		AnalyticsSchema:                getEnvOrDefault("ANALYTICS_SCHEMA", "analytics"),
+		AnalyticsExportIntervalMinutes: getEnvIntOrDefault("ANALYTICS_EXPORT_INTERVAL_MINUTES", 0),
+
+		AdminUIEnabled: getEnvBoolOrDefault("ADMIN_UI_ENABLED", false),
+		AdminUIDir:     getEnvOrDefault("ADMIN_UI_DIR", ""),
+
+		Source: configSource,
 	}, nil
 }
 
@@ -160,3 +525,23 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvFloatOrDefault retrieves an environment variable as a number or returns a default value
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBoolOrDefault retrieves an environment variable as a boolean or returns a default value
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
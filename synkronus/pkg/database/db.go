@@ -24,6 +24,12 @@ type Config struct {
 	MaxIdleConns int
 	// ConnMaxLifetime is the maximum lifetime of a connection
 	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is how long a connection may stay idle before it is closed
+	ConnMaxIdleTime time.Duration
+	// StatsInterval is how often LogStats logs the pool statistics; 0 disables logging
+	StatsInterval time.Duration
+	// TLS adds TLS settings to the connection string, such as a client certificate
+	TLS TLSConfig
 }
 
 // DefaultConfig returns a default configuration
@@ -34,29 +40,43 @@ func DefaultConfig() Config {
 		MaxOpenConns:     10,
 		MaxIdleConns:     5,
 		ConnMaxLifetime:  time.Hour,
+		ConnMaxIdleTime:  5 * time.Minute,
+		TLS:              TLSConfig{ReloadInterval: time.Minute},
 	}
 }
 
 // Database represents a database connection
 type Database struct {
-	db     *sql.DB
-	config Config
-	log    *logger.Logger
+	db        *sql.DB
+	connector *connector
+	config    Config
+	log       *logger.Logger
 }
 
 // New creates a new database connection
 func New(config Config, log *logger.Logger) (*Database, error) {
-	// Open database connection
-	db, err := sql.Open("postgres", config.ConnectionString)
-	if err != nil {
-		log.Error("Failed to open database connection", "error", err)
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	// Open database connection, reading TLS certificates from disk for each new connection
+	var db *sql.DB
+	var tlsConnector *connector
+	if config.TLS.Enabled() {
+		var err error
+		if tlsConnector, err = newConnector(config.ConnectionString, config.TLS, log); err != nil {
+			return nil, fmt.Errorf("invalid database TLS configuration: %w", err)
+		}
+		db = sql.OpenDB(tlsConnector)
+	} else {
+		var err error
+		if db, err = sql.Open("postgres", config.ConnectionString); err != nil {
+			log.Error("Failed to open database connection", "error", err)
+			return nil, fmt.Errorf("failed to open database connection: %w", err)
+		}
 	}
 
 	// Configure connection pool
 	db.SetMaxOpenConns(config.MaxOpenConns)
 	db.SetMaxIdleConns(config.MaxIdleConns)
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 
 	// Check connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -68,9 +88,10 @@ func New(config Config, log *logger.Logger) (*Database, error) {
 	}
 
 	return &Database{
-		db:     db,
-		config: config,
-		log:    log,
+		db:        db,
+		connector: tlsConnector,
+		config:    config,
+		log:       log,
 	}, nil
 }
 
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PoolStats are the statistics of a connection pool with its limits
+type PoolStats struct {
+	// Limits the pool was configured with; durations of 0 keep connections
+	MaxOpenConnections     int   `json:"max_open_connections"`
+	MaxIdleConnections     int   `json:"max_idle_connections"`
+	ConnMaxLifetimeSeconds int64 `json:"conn_max_lifetime_seconds"`
+	ConnMaxIdleTimeSeconds int64 `json:"conn_max_idle_time_seconds"`
+
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+	// WaitCount and WaitDurationMS count the queries that waited for a free connection since
+	// the server started, and how long they waited in total
+	WaitCount      int64 `json:"wait_count"`
+	WaitDurationMS int64 `json:"wait_duration_ms"`
+	// Connections closed by the limits since the server started
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+	// Replica reports the read replica, if one is configured
+	Replica *ReplicaStats `json:"replica,omitempty"`
+}
+
+// PoolReporter reports the statistics of a connection pool
+type PoolReporter interface {
+	PoolStats() PoolStats
+}
+
+// PoolStats returns the statistics of the connection pool
+func (d *Database) PoolStats() PoolStats {
+	return poolStats(d.db.Stats(), d.config)
+}
+
+func poolStats(stats sql.DBStats, config Config) PoolStats {
+	return PoolStats{
+		MaxOpenConnections:     stats.MaxOpenConnections,
+		MaxIdleConnections:     config.MaxIdleConns,
+		ConnMaxLifetimeSeconds: int64(config.ConnMaxLifetime.Seconds()),
+		ConnMaxIdleTimeSeconds: int64(config.ConnMaxIdleTime.Seconds()),
+		OpenConnections:        stats.OpenConnections,
+		InUse:                  stats.InUse,
+		Idle:                   stats.Idle,
+		WaitCount:              stats.WaitCount,
+		WaitDurationMS:         stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:          stats.MaxIdleClosed,
+		MaxIdleTimeClosed:      stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:      stats.MaxLifetimeClosed,
+	}
+}
+
+// LogStats logs the pool statistics every StatsInterval until ctx is cancelled, warning when
+// queries had to wait for a free connection since the last time. It returns at once when
+// StatsInterval is 0.
+func (d *Database) LogStats(ctx context.Context) {
+	if d.config.StatsInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.config.StatsInterval)
+	defer ticker.Stop()
+	last := d.PoolStats()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := d.PoolStats()
+			d.logStats(last, stats)
+			last = stats
+		}
+	}
+}
+
+// logStats logs the statistics of one interval, given those at its start
+func (d *Database) logStats(last, stats PoolStats) {
+	waits := stats.WaitCount - last.WaitCount
+	args := []any{
+		"open", stats.OpenConnections,
+		"inUse", stats.InUse,
+		"idle", stats.Idle,
+		"maxOpen", stats.MaxOpenConnections,
+		"waits", waits,
+		"waitMs", stats.WaitDurationMS - last.WaitDurationMS,
+		"closedIdle", stats.MaxIdleClosed - last.MaxIdleClosed + stats.MaxIdleTimeClosed - last.MaxIdleTimeClosed,
+		"closedLifetime", stats.MaxLifetimeClosed - last.MaxLifetimeClosed,
+	}
+	if waits > 0 {
+		d.log.Warn("Database connection pool exhausted, queries waited for a free connection", args...)
+		return
+	}
+	d.log.Info("Database connection pool statistics", args...)
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolStats(t *testing.T) {
+	config := DefaultConfig()
+	stats := poolStats(sql.DBStats{
+		MaxOpenConnections: 10,
+		OpenConnections:    4,
+		InUse:              3,
+		Idle:               1,
+		WaitCount:          2,
+		WaitDuration:       1500 * time.Millisecond,
+		MaxLifetimeClosed:  7,
+	}, config)
+
+	assert.Equal(t, PoolStats{
+		MaxOpenConnections:     10,
+		MaxIdleConnections:     5,
+		ConnMaxLifetimeSeconds: 3600,
+		ConnMaxIdleTimeSeconds: 300,
+		OpenConnections:        4,
+		InUse:                  3,
+		Idle:                   1,
+		WaitCount:              2,
+		WaitDurationMS:         1500,
+		MaxLifetimeClosed:      7,
+	}, stats)
+}
+
+func TestLogStats(t *testing.T) {
+	var out bytes.Buffer
+	d := &Database{log: logger.NewLogger(logger.WithOutputWriter(&out))}
+
+	last := PoolStats{OpenConnections: 2, WaitCount: 5, WaitDurationMS: 100}
+	d.logStats(last, PoolStats{OpenConnections: 3, WaitCount: 5, WaitDurationMS: 100})
+	assert.Contains(t, out.String(), "Database connection pool statistics")
+	assert.Contains(t, out.String(), `"waits":0`)
+
+	out.Reset()
+	d.logStats(last, PoolStats{OpenConnections: 10, InUse: 10, WaitCount: 8, WaitDurationMS: 400})
+	assert.Contains(t, out.String(), "Database connection pool exhausted")
+	assert.Contains(t, out.String(), `"waits":3`)
+	assert.Contains(t, out.String(), `"waitMs":300`)
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// errNotStandby is reported for a replica that is not a streaming standby of the primary, whose
+// WAL positions cannot be compared with those of the primary
+var errNotStandby = errors.New("read replica is not a standby server")
+
+// replicaCheckTimeout bounds a health check, so that an unreachable replica is taken out of use
+// before the next check is due
+const replicaCheckTimeout = 5 * time.Second
+
+// replicaHealthQuery selects whether the server is a standby and its replay lag: zero when it has
+// replayed all WAL received, otherwise the time since the last transaction it replayed
+const replicaHealthQuery = `
+	SELECT pg_is_in_recovery(),
+	       CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	            ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	       END`
+
+// primaryPositionQuery selects the position of the last WAL record inserted on the primary, which
+// follows the commit records of all transactions committed before
+const primaryPositionQuery = "SELECT pg_current_wal_insert_lsn()::TEXT"
+
+// replicaCaughtUpQuery selects whether the replica has replayed the WAL up to $1
+const replicaCaughtUpQuery = "SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)"
+
+// ReplicaConfig configures the checks of a read replica
+type ReplicaConfig struct {
+	// CheckInterval is how often the health and lag of the replica are checked
+	CheckInterval time.Duration
+	// MaxLag is the replay lag beyond which reads go to the primary; 0 allows any lag
+	MaxLag time.Duration
+}
+
+// ReplicaStats reports the health of a read replica with its connection pool
+type ReplicaStats struct {
+	Healthy    bool    `json:"healthy"`
+	LagSeconds float64 `json:"lag_seconds"`
+	// LastError is the error of the latest health check, if it failed
+	LastError string     `json:"last_error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	// Reads counts the reads sent to the replica since the server started, Fallbacks those sent
+	// to the primary because the replica was unhealthy or had not caught up
+	Reads     int64     `json:"reads"`
+	Fallbacks int64     `json:"fallbacks"`
+	Pool      PoolStats `json:"pool"`
+}
+
+// ReadReplica sends reads that do not need to be in a transaction with writes, such as sync
+// pulls and exports, to a streaming replica of the primary. Reads go to the primary while the
+// replica fails its health checks or lags too far behind, and whenever it has not yet replayed
+// what the primary committed before the read started.
+type ReadReplica struct {
+	primary *Database
+	replica *Database
+	config  ReplicaConfig
+	log     *logger.Logger
+
+	healthy   atomic.Bool
+	reads     atomic.Int64
+	fallbacks atomic.Int64
+
+	mu        sync.Mutex
+	lag       time.Duration
+	lastErr   error
+	checkedAt *time.Time
+}
+
+var _ PoolReporter = (*ReadReplica)(nil)
+
+// NewReadReplica creates a read replica of primary. Reads go to the primary until Run has found
+// the replica healthy.
+func NewReadReplica(primary, replica *Database, config ReplicaConfig, log *logger.Logger) *ReadReplica {
+	return &ReadReplica{primary: primary, replica: replica, config: config, log: log}
+}
+
+// Run checks the replica at once and then every CheckInterval until ctx is cancelled
+func (r *ReadReplica) Run(ctx context.Context) {
+	r.check(ctx)
+	if r.config.CheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
+
+// check queries the health of the replica and records it
+func (r *ReadReplica) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	var standby bool
+	var lag float64
+	err := r.replica.db.QueryRowContext(ctx, replicaHealthQuery).Scan(&standby, &lag)
+	if err == nil && !standby {
+		err = errNotStandby
+	}
+	r.update(time.Duration(lag*float64(time.Second)), err)
+}
+
+// update records the result of a health check, logging when the replica is taken out of use or
+// used again
+func (r *ReadReplica) update(lag time.Duration, err error) {
+	healthy := err == nil && (r.config.MaxLag <= 0 || lag <= r.config.MaxLag)
+	now := time.Now().UTC()
+
+	r.mu.Lock()
+	first := r.checkedAt == nil
+	r.lag = lag
+	r.lastErr = err
+	r.checkedAt = &now
+	r.mu.Unlock()
+
+	was := r.healthy.Swap(healthy)
+	switch {
+	case err != nil && (was || first):
+		r.log.Warn("Read replica unavailable, reading from the primary", "error", err)
+	case !healthy && (was || first):
+		r.log.Warn("Read replica is behind, reading from the primary", "lag", lag, "maxLag", r.config.MaxLag)
+	case healthy && !was:
+		r.log.Info("Reading from the read replica", "lag", lag)
+	}
+}
+
+// Reader returns the database to read from: the replica while it is healthy and has replayed
+// every transaction the primary committed before the call, the primary otherwise. Reads from it
+// never see older data than the primary did when Reader was called.
+func (r *ReadReplica) Reader(ctx context.Context) *sql.DB {
+	if !r.healthy.Load() {
+		r.fallbacks.Add(1)
+		return r.primary.db
+	}
+
+	var position string
+	err := r.primary.db.QueryRowContext(ctx, primaryPositionQuery).Scan(&position)
+	var caughtUp bool
+	if err == nil {
+		err = r.replica.db.QueryRowContext(ctx, replicaCaughtUpQuery, position).Scan(&caughtUp)
+	}
+	if err != nil || !caughtUp {
+		if err != nil {
+			r.log.Debug("Failed to compare read replica with the primary", "error", err)
+		}
+		r.fallbacks.Add(1)
+		return r.primary.db
+	}
+	r.reads.Add(1)
+	return r.replica.db
+}
+
+// Close closes the connections to the replica; the primary is closed by its owner
+func (r *ReadReplica) Close() error {
+	return r.replica.Close()
+}
+
+// PoolStats returns the statistics of the primary's pool with the health and pool of the replica
+func (r *ReadReplica) PoolStats() PoolStats {
+	stats := r.primary.PoolStats()
+	stats.Replica = r.Stats()
+	return stats
+}
+
+// Stats reports the health of the replica
+func (r *ReadReplica) Stats() *ReplicaStats {
+	stats := &ReplicaStats{
+		Healthy:   r.healthy.Load(),
+		Reads:     r.reads.Load(),
+		Fallbacks: r.fallbacks.Load(),
+		Pool:      r.replica.PoolStats(),
+	}
+	r.mu.Lock()
+	stats.LagSeconds = r.lag.Seconds()
+	stats.CheckedAt = r.checkedAt
+	if r.lastErr != nil {
+		stats.LastError = r.lastErr.Error()
+	}
+	r.mu.Unlock()
+	return stats
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReplica(t *testing.T, out *bytes.Buffer) (*ReadReplica, *Database, *Database, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	t.Helper()
+	log := logger.NewLogger(logger.WithOutputWriter(out))
+	primaryDB, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	replicaDB, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		primaryDB.Close()
+		replicaDB.Close()
+	})
+	primary := &Database{db: primaryDB, config: DefaultConfig(), log: log}
+	replica := &Database{db: replicaDB, config: DefaultConfig(), log: log}
+	r := NewReadReplica(primary, replica, ReplicaConfig{MaxLag: 30 * time.Second}, log)
+	return r, primary, replica, primaryMock, replicaMock
+}
+
+func expectHealth(mock sqlmock.Sqlmock, standby bool, lag float64) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_is_in_recovery()")).
+		WillReturnRows(sqlmock.NewRows([]string{"standby", "lag"}).AddRow(standby, lag))
+}
+
+func TestReadReplicaHealth(t *testing.T) {
+	ctx := context.Background()
+	var out bytes.Buffer
+	r, _, _, _, replicaMock := newTestReplica(t, &out)
+
+	assert.False(t, r.Stats().Healthy, "reads go to the primary until the first check")
+
+	expectHealth(replicaMock, true, 2)
+	r.check(ctx)
+	assert.True(t, r.Stats().Healthy)
+	assert.Equal(t, 2.0, r.Stats().LagSeconds)
+	assert.Contains(t, out.String(), "Reading from the read replica")
+
+	out.Reset()
+	expectHealth(replicaMock, true, 45)
+	r.check(ctx)
+	assert.False(t, r.Stats().Healthy)
+	assert.Contains(t, out.String(), "Read replica is behind")
+
+	out.Reset()
+	replicaMock.ExpectQuery(regexp.QuoteMeta("SELECT pg_is_in_recovery()")).WillReturnError(errors.New("connection refused"))
+	r.check(ctx)
+	stats := r.Stats()
+	assert.False(t, stats.Healthy)
+	assert.Equal(t, "connection refused", stats.LastError)
+	assert.Empty(t, out.String(), "an unhealthy replica is only reported once")
+
+	expectHealth(replicaMock, false, 0)
+	r.check(ctx)
+	assert.False(t, r.Stats().Healthy)
+	assert.Equal(t, errNotStandby.Error(), r.Stats().LastError)
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestReadReplicaReader(t *testing.T) {
+	ctx := context.Background()
+	var out bytes.Buffer
+	r, primary, replica, primaryMock, replicaMock := newTestReplica(t, &out)
+
+	// Unhealthy replicas are not asked
+	assert.Same(t, primary.DB(), r.Reader(ctx))
+
+	expectHealth(replicaMock, true, 0)
+	r.check(ctx)
+
+	primaryMock.ExpectQuery(regexp.QuoteMeta(primaryPositionQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"lsn"}).AddRow("0/3000060"))
+	replicaMock.ExpectQuery(regexp.QuoteMeta(replicaCaughtUpQuery)).WithArgs("0/3000060").
+		WillReturnRows(sqlmock.NewRows([]string{"caught_up"}).AddRow(true))
+	assert.Same(t, replica.DB(), r.Reader(ctx))
+
+	// A replica that has not replayed the primary's latest commits is skipped
+	primaryMock.ExpectQuery(regexp.QuoteMeta(primaryPositionQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"lsn"}).AddRow("0/3000100"))
+	replicaMock.ExpectQuery(regexp.QuoteMeta(replicaCaughtUpQuery)).WithArgs("0/3000100").
+		WillReturnRows(sqlmock.NewRows([]string{"caught_up"}).AddRow(false))
+	assert.Same(t, primary.DB(), r.Reader(ctx))
+
+	stats := r.Stats()
+	assert.Equal(t, int64(1), stats.Reads)
+	assert.Equal(t, int64(2), stats.Fallbacks)
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
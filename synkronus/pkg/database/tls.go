@@ -0,0 +1,218 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// TLSConfig secures connections to PostgreSQL with TLS, optionally authenticating with a client
+// certificate. The files are read again for every new connection, so certificates rotated on
+// disk are used without a restart.
+type TLSConfig struct {
+	// Mode is the sslmode: "require", "verify-ca" or "verify-full". It defaults to "verify-full"
+	// with a RootCertFile and to "require" without one.
+	Mode string
+	// RootCertFile is a PEM file of the CAs the server certificate must be signed by; without
+	// it the server certificate is verified against the system CAs
+	RootCertFile string
+	// CertFile and KeyFile are the PEM client certificate and its private key
+	CertFile string
+	KeyFile  string
+	// ReloadInterval is how often the files are checked for rotated certificates, after which
+	// idle connections are closed so that new ones use them
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether any TLS setting is configured
+func (c TLSConfig) Enabled() bool {
+	return c.Mode != "" || c.RootCertFile != "" || c.CertFile != "" || c.KeyFile != ""
+}
+
+// mode returns the sslmode connections are opened with
+func (c TLSConfig) mode() string {
+	switch {
+	case c.Mode != "":
+		return c.Mode
+	case c.RootCertFile != "":
+		return "verify-full"
+	default:
+		return "require"
+	}
+}
+
+// validate checks the settings before the first connection
+func (c TLSConfig) validate() error {
+	switch c.mode() {
+	case "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("unsupported TLS mode %q: use require, verify-ca or verify-full", c.Mode)
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("a client certificate needs both a certificate and a key file")
+	}
+	return nil
+}
+
+// certificates is the PEM content of the TLS files
+type certificates struct {
+	rootCert []byte
+	cert     []byte
+	key      []byte
+}
+
+// equal reports whether two sets of certificates have the same content
+func (c certificates) equal(other certificates) bool {
+	return bytes.Equal(c.rootCert, other.rootCert) && bytes.Equal(c.cert, other.cert) && bytes.Equal(c.key, other.key)
+}
+
+// connector opens lib/pq connections with the certificates last read from disk. A rotation
+// that is half written, such as a new certificate next to the old key, keeps the previous
+// certificates until both files match.
+type connector struct {
+	dsn    string
+	config TLSConfig
+	log    *logger.Logger
+
+	mu      sync.Mutex
+	current *certificates
+}
+
+// newConnector creates a connector for a URL or key=value connection string
+func newConnector(connectionString string, config TLSConfig, log *logger.Logger) (*connector, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	dsn := connectionString
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return nil, fmt.Errorf("invalid connection string: %w", err)
+		}
+	}
+	c := &connector{dsn: dsn, config: config, log: log}
+	if _, err := c.refresh(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Connect implements driver.Connector
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	certs, err := c.refresh()
+	if err != nil {
+		return nil, err
+	}
+	pqConnector, err := pq.NewConnector(c.dsn + c.options(certs))
+	if err != nil {
+		return nil, err
+	}
+	return pqConnector.Connect(ctx)
+}
+
+// Driver implements driver.Connector
+func (c *connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// refresh reads the files and returns the certificates to connect with
+func (c *connector) refresh() (certificates, error) {
+	certs, err := c.read()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if c.current == nil {
+			return certificates{}, err
+		}
+		c.log.Warn("Failed to read database TLS certificates, using the previous ones", "error", err)
+		return *c.current, nil
+	}
+	c.current = &certs
+	return certs, nil
+}
+
+// read reads and checks the TLS files
+func (c *connector) read() (certificates, error) {
+	var certs certificates
+	var err error
+	if c.config.RootCertFile != "" {
+		if certs.rootCert, err = os.ReadFile(c.config.RootCertFile); err != nil {
+			return certs, fmt.Errorf("failed to read root certificate: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(certs.rootCert) {
+			return certs, fmt.Errorf("root certificate %s has no PEM certificates", c.config.RootCertFile)
+		}
+	}
+	if c.config.CertFile != "" {
+		if certs.cert, err = os.ReadFile(c.config.CertFile); err != nil {
+			return certs, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		if certs.key, err = os.ReadFile(c.config.KeyFile); err != nil {
+			return certs, fmt.Errorf("failed to read client key: %w", err)
+		}
+		if _, err := tls.X509KeyPair(certs.cert, certs.key); err != nil {
+			return certs, fmt.Errorf("invalid client certificate: %w", err)
+		}
+	}
+	return certs, nil
+}
+
+// options returns the connection string options for the certificates. Client certificates are
+// passed inline, so lib/pq does not read the files again nor check the permissions of the key.
+func (c *connector) options(certs certificates) string {
+	options := " sslmode=" + c.config.mode()
+	if certs.cert == nil {
+		if c.config.RootCertFile != "" {
+			options += " sslrootcert=" + quoteOption(c.config.RootCertFile)
+		}
+		return options
+	}
+	options += " sslinline=true sslcert=" + quoteOption(string(certs.cert)) + " sslkey=" + quoteOption(string(certs.key))
+	if certs.rootCert != nil {
+		options += " sslrootcert=" + quoteOption(string(certs.rootCert))
+	}
+	return options
+}
+
+// quoteOption quotes a key=value connection string value
+func quoteOption(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// Run checks the TLS files for rotated certificates until ctx is cancelled, closing idle
+// connections when they change so the pool moves to the new ones. It returns at once without
+// TLS settings.
+func (d *Database) Run(ctx context.Context) {
+	interval := d.config.TLS.ReloadInterval
+	if d.connector == nil || interval <= 0 {
+		return
+	}
+	last, _ := d.connector.refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			certs, _ := d.connector.refresh()
+			if !certs.equal(last) {
+				last = certs
+				d.log.Info("Database TLS certificates changed, closing idle connections")
+				d.db.SetMaxIdleConns(0)
+				d.db.SetMaxIdleConns(d.config.MaxIdleConns)
+			}
+		}
+	}
+}
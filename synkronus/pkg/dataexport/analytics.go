@@ -0,0 +1,196 @@
+package dataexport
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// reservedSchemas cannot be used as analytics schemas, which are dropped on every export
+var reservedSchemas = map[string]bool{"public": true, "information_schema": true}
+
+// invalidTableChars are replaced in form types to name their analytics tables
+var invalidTableChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// maxIdentifierLength is the longest identifier PostgreSQL keeps without truncating it
+const maxIdentifierLength = 63
+
+// baseColumns are the columns of every analytics table, ahead of the data columns
+var baseColumns = []struct{ name, sqlType string }{
+	{"observation_id", "text PRIMARY KEY"},
+	{"form_type", "text NOT NULL"},
+	{"form_version", "text NOT NULL"},
+	{"created_at", "timestamptz NOT NULL"},
+	{"updated_at", "timestamptz NOT NULL"},
+	{"synced_at", "timestamptz"},
+	{"deleted", "boolean NOT NULL"},
+	{"version", "bigint NOT NULL"},
+	{"geolocation", "jsonb"},
+}
+
+// PostgresSink materializes the flattened observation tables into a schema of a PostgreSQL
+// database, either the synkronus database or a separate analytics database. The schema belongs
+// to the sink: it is dropped and rebuilt in one transaction on every export, so analysts
+// always see a complete snapshot.
+type PostgresSink struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewPostgresSink creates a sink writing to schema of db
+func NewPostgresSink(db *sql.DB, schema string) (*PostgresSink, error) {
+	if schema == "" || reservedSchemas[schema] || strings.HasPrefix(schema, "pg_") {
+		return nil, fmt.Errorf("invalid analytics schema %q", schema)
+	}
+	return &PostgresSink{db: db, schema: schema}, nil
+}
+
+// Name identifies the sink in logs
+func (p *PostgresSink) Name() string {
+	return "postgres schema " + p.schema
+}
+
+// WriteTables replaces the analytics schema with one table per form type
+func (p *PostgresSink) WriteTables(ctx context.Context, tables []Table) error {
+	names := make(map[string]string, len(tables))
+	for _, table := range tables {
+		name := tableName(table.FormType)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("form types %s and %s both map to table %s", other, table.FormType, name)
+		}
+		names[name] = table.FormType
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	schema := pq.QuoteIdentifier(p.schema)
+	if _, err := tx.ExecContext(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE"); err != nil {
+		return fmt.Errorf("failed to drop analytics schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		return fmt.Errorf("failed to create analytics schema: %w", err)
+	}
+
+	for _, table := range tables {
+		if err := p.writeTable(ctx, tx, tableName(table.FormType), table); err != nil {
+			return fmt.Errorf("failed to write table for form type %s: %w", table.FormType, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit analytics schema: %w", err)
+	}
+	return nil
+}
+
+// writeTable creates the table of a form type and copies its observations into it
+func (p *PostgresSink) writeTable(ctx context.Context, tx *sql.Tx, name string, table Table) error {
+	columns := make([]string, 0, len(baseColumns)+len(table.Schema.Columns))
+	definitions := make([]string, 0, cap(columns))
+	for _, col := range baseColumns {
+		columns = append(columns, col.name)
+		definitions = append(definitions, pq.QuoteIdentifier(col.name)+" "+col.sqlType)
+	}
+	for _, col := range table.Schema.Columns {
+		column := "data_" + col.Key
+		columns = append(columns, column)
+		definitions = append(definitions, pq.QuoteIdentifier(column)+" "+sinkColumnType(col))
+	}
+
+	create := fmt.Sprintf("CREATE TABLE %s.%s (%s)", pq.QuoteIdentifier(p.schema), pq.QuoteIdentifier(name), strings.Join(definitions, ", "))
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	if len(table.Observations) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema(p.schema, name, columns...))
+	if err != nil {
+		return fmt.Errorf("failed to prepare copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, obs := range table.Observations {
+		values := make([]interface{}, 0, len(columns))
+		values = append(values, obs.ObservationID, obs.FormType, obs.FormVersion, obs.CreatedAt, obs.UpdatedAt)
+		if obs.SyncedAt != nil {
+			values = append(values, *obs.SyncedAt)
+		} else {
+			values = append(values, nil)
+		}
+		values = append(values, obs.Deleted, obs.Version)
+		if obs.Geolocation != nil {
+			values = append(values, string(obs.Geolocation))
+		} else {
+			values = append(values, nil)
+		}
+		for _, col := range table.Schema.Columns {
+			values = append(values, sinkValue(col, obs.DataFields["data_"+col.Key]))
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("failed to copy observation %s: %w", obs.ObservationID, err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to complete copy: %w", err)
+	}
+	return nil
+}
+
+// tableName returns the analytics table name of a form type
+func tableName(formType string) string {
+	name := strings.Trim(invalidTableChars.ReplaceAllString(strings.ToLower(formType), "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "form_" + name
+	}
+	if len(name) > maxIdentifierLength {
+		name = name[:maxIdentifierLength]
+	}
+	return name
+}
+
+// sinkColumnType returns the PostgreSQL type of a data column, matching the Parquet types
+func sinkColumnType(col FormTypeColumn) string {
+	switch col.SQLType {
+	case "numeric":
+		return "double precision"
+	case "boolean":
+		return "boolean"
+	default:
+		return "text"
+	}
+}
+
+// sinkValue converts a flattened data value to the type of its column; values of another
+// type are dropped from numeric and boolean columns, as in Parquet exports
+func sinkValue(col FormTypeColumn, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch col.SQLType {
+	case "numeric":
+		if v, ok := value.(float64); ok {
+			return v
+		}
+		return nil
+	case "boolean":
+		if v, ok := value.(bool); ok {
+			return v
+		}
+		return nil
+	default:
+		if v, ok := value.(string); ok {
+			return v
+		}
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,102 @@
+package dataexport
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNewPostgresSink_RefusesReservedSchemas(t *testing.T) {
+	for _, schema := range []string{"", "public", "pg_catalog", "information_schema"} {
+		if _, err := NewPostgresSink(nil, schema); err == nil {
+			t.Errorf("Expected schema %q to be refused", schema)
+		}
+	}
+}
+
+func TestPostgresSink_WriteTables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sink, err := NewPostgresSink(db, "analytics")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	synced := "2025-06-02T00:00:00Z"
+	tables := []Table{
+		{
+			FormType: "Household Survey",
+			Schema: &FormTypeSchema{FormType: "Household Survey", Columns: []FormTypeColumn{
+				{Key: "members", DataType: "number", SQLType: "numeric"},
+				{Key: "village", DataType: "string", SQLType: "text"},
+			}},
+			Observations: []ObservationRow{{
+				ObservationID: "obs1", FormType: "Household Survey", FormVersion: "1.0",
+				CreatedAt: "2025-06-01T00:00:00Z", UpdatedAt: "2025-06-01T00:00:00Z", SyncedAt: &synced,
+				Version: 3, Geolocation: json.RawMessage(`{"latitude":-1.3}`),
+				DataFields: map[string]interface{}{"data_members": "five", "data_village": "Ngong"},
+			}},
+		},
+		{FormType: "visit", Schema: &FormTypeSchema{FormType: "visit"}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DROP SCHEMA IF EXISTS "analytics" CASCADE`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE SCHEMA "analytics"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE "analytics"."household_survey" \(.*"data_members" double precision, "data_village" text\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn := mock.ExpectPrepare(`COPY "analytics"."household_survey"`)
+	// A value of the wrong type is dropped from a numeric column
+	copyIn.ExpectExec().
+		WithArgs("obs1", "Household Survey", "1.0", "2025-06-01T00:00:00Z", "2025-06-01T00:00:00Z", synced,
+			false, int64(3), `{"latitude":-1.3}`, nil, "Ngong").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	copyIn.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE "analytics"."visit"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := sink.WriteTables(context.Background(), tables); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresSink_WriteTablesRefusesCollidingNames(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sink, _ := NewPostgresSink(db, "analytics")
+	tables := []Table{
+		{FormType: "household-survey", Schema: &FormTypeSchema{}},
+		{FormType: "Household Survey", Schema: &FormTypeSchema{}},
+	}
+	if err := sink.WriteTables(context.Background(), tables); err == nil {
+		t.Error("Expected form types mapping to the same table to be refused")
+	}
+}
+
+func TestTableName(t *testing.T) {
+	tests := map[string]string{
+		"household":        "household",
+		"Household Survey": "household_survey",
+		"2024/census":      "form_2024_census",
+		"ümlaut":           "mlaut",
+		"%%%":              "form_",
+	}
+	for formType, want := range tests {
+		if got := tableName(formType); got != want {
+			t.Errorf("tableName(%q) = %q, want %q", formType, got, want)
+		}
+	}
+}
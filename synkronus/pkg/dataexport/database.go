@@ -3,6 +3,7 @@ package dataexport
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // FormTypeColumn represents a column definition for a specific form type
@@ -42,4 +43,54 @@ type DatabaseInterface interface {
 	
 	// GetObservationsForFormType returns all observations for a specific form type with flattened data
 	GetObservationsForFormType(ctx context.Context, formType string, schema *FormTypeSchema) ([]ObservationRow, error)
+
+	// WithOptions returns a view of the database restricted by the given export options
+	WithOptions(opts ExportOptions) DatabaseInterface
+}
+
+// ExportOptions narrows down the observations included in an export
+type ExportOptions struct {
+	// AsOfVersion, when positive, reconstructs observations as they existed at a past sync version
+	AsOfVersion int64
+	// OrgUnitID, when set, limits the export to observations in that org unit and its descendants
+	OrgUnitID string
+	// FormTypes, when set, limits the export to these form types
+	FormTypes []string
+	// SinceVersion, when positive, limits the export to observations changed after this sync
+	// version, so scheduled jobs can export deltas. Deletions are included as rows with
+	// deleted set, so that they can be applied to an earlier export.
+	SinceVersion int64
+	// UntilVersion, when positive, leaves out observations changed after this sync version,
+	// so that the next delta can start from it without gaps or overlap. A delta is empty when
+	// it is not above SinceVersion.
+	UntilVersion int64
+	// CreatedFrom and CreatedTo, when set, limit the export to observations created in
+	// [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Columns, when set, limits the form data columns to these, named as in the export,
+	// e.g. data_household_size; the observation columns are always included
+	Columns []string
+	// Masks rewrite form data columns before they are written
+	Masks []ColumnMask
+	// MaskKey keys the HMAC of hashed columns, so the same value hashes alike across exports
+	// made with the same key
+	MaskKey []byte
+}
+
+// MaskMethod selects how a masked column is rewritten
+type MaskMethod string
+
+const (
+	// MaskRedact replaces every value with null
+	MaskRedact MaskMethod = "redact"
+	// MaskHash replaces every value with its hex encoded HMAC-SHA256, so records can still be
+	// joined on the column without revealing it
+	MaskHash MaskMethod = "hash"
+)
+
+// ColumnMask masks one form data column, named as in the export
+type ColumnMask struct {
+	Column string     `json:"column"`
+	Method MaskMethod `json:"method"`
 }
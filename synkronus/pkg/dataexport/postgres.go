@@ -8,9 +8,18 @@ import (
 	"strings"
 )
 
+// ReadReplica picks the database exports are read from
+type ReadReplica interface {
+	// Reader returns a database that sees every transaction committed on the primary before the
+	// call: a read replica that has caught up, or the primary
+	Reader(ctx context.Context) *sql.DB
+}
+
 // postgresDB implements DatabaseInterface for PostgreSQL
 type postgresDB struct {
-	db *sql.DB
+	db      *sql.DB
+	replica ReadReplica
+	opts    ExportOptions
 }
 
 // NewPostgresDB creates a new PostgreSQL database adapter
@@ -18,16 +27,88 @@ func NewPostgresDB(db *sql.DB) DatabaseInterface {
 	return &postgresDB{db: db}
 }
 
+// NewPostgresReplicaDB creates an adapter reading from a read replica of db once it has caught
+// up, so that a delta export ending at the current version misses no observation
+func NewPostgresReplicaDB(db *sql.DB, replica ReadReplica) DatabaseInterface {
+	return &postgresDB{db: db, replica: replica}
+}
+
+// WithOptions returns an adapter reading observations restricted by opts. The org unit
+// id is embedded in the generated SQL, so callers must pass a validated UUID.
+func (p *postgresDB) WithOptions(opts ExportOptions) DatabaseInterface {
+	return &postgresDB{db: p.db, replica: p.replica, opts: opts}
+}
+
+// reader returns the database to query
+func (p *postgresDB) reader(ctx context.Context) *sql.DB {
+	if p.replica == nil {
+		return p.db
+	}
+	return p.replica.Reader(ctx)
+}
+
+// source returns the relation observations are read from: the live table, or the
+// latest history entry of each observation at or before the as-of version, optionally
+// limited to an org unit subtree
+func (p *postgresDB) source() string {
+	source := "observations"
+	if p.opts.AsOfVersion > 0 {
+		source = fmt.Sprintf(`(
+			SELECT DISTINCT ON (observation_id) *
+			FROM observation_history
+			WHERE version <= %d
+			ORDER BY observation_id, version DESC
+		) AS observations`, p.opts.AsOfVersion)
+	}
+	if p.opts.OrgUnitID != "" {
+		source = fmt.Sprintf(`(
+			SELECT * FROM %s
+			WHERE org_unit_id IN (
+				SELECT id FROM org_units
+				WHERE path LIKE (SELECT path FROM org_units WHERE id = '%s') || '%%'
+			)
+		) AS observations`, source, p.opts.OrgUnitID)
+	}
+	return source
+}
+
+// rowFilter returns the conditions selecting exported rows, appending their arguments to args.
+// Drafts are never exported, and deleted observations only appear in delta exports. Form type
+// schemas are analyzed without the delta and date conditions, so that a delta has the
+// columns of a full export.
+func (p *postgresDB) rowFilter(args []interface{}) (string, []interface{}) {
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"deleted = false", "draft = false"}
+	if p.opts.SinceVersion > 0 {
+		conditions = []string{"draft = false", "version > " + arg(p.opts.SinceVersion)}
+	}
+	if p.opts.UntilVersion > 0 {
+		conditions = append(conditions, "version <= "+arg(p.opts.UntilVersion))
+	}
+	if !p.opts.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(p.opts.CreatedFrom))
+	}
+	if !p.opts.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(p.opts.CreatedTo))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
 // GetFormTypes returns all distinct form types in the observations table
 func (p *postgresDB) GetFormTypes(ctx context.Context) ([]string, error) {
+	filter, args := p.rowFilter(nil)
 	query := `
 		SELECT DISTINCT form_type 
-		FROM observations 
-		WHERE deleted = false 
+		FROM ` + p.source() + ` 
+		WHERE ` + filter + ` 
 		ORDER BY form_type
 	`
 	
-	rows, err := p.db.QueryContext(ctx, query)
+	rows, err := p.reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query form types: %w", err)
 	}
@@ -59,10 +140,10 @@ func (p *postgresDB) GetFormTypeSchema(ctx context.Context, formType string) (*F
 				key,
 				jsonb_typeof(data -> key) AS type
 			FROM
-				public.observations,
+				` + p.source() + `,
 				LATERAL jsonb_object_keys(data) AS key
 			WHERE
-				form_type = $1 AND deleted = false
+				form_type = $1 AND deleted = false AND draft = false
 		),
 		agg_types AS (
 			SELECT
@@ -92,7 +173,7 @@ func (p *postgresDB) GetFormTypeSchema(ctx context.Context, formType string) (*F
 		ORDER BY key
 	`
 	
-	rows, err := p.db.QueryContext(ctx, query, formType)
+	rows, err := p.reader(ctx).QueryContext(ctx, query, formType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze form type schema: %w", err)
 	}
@@ -144,6 +225,7 @@ func (p *postgresDB) GetObservationsForFormType(ctx context.Context, formType st
 		selectClause = ", " + strings.Join(selectParts, ", ")
 	}
 	
+	filter, args := p.rowFilter([]interface{}{formType})
 	query := fmt.Sprintf(`
 		SELECT 
 			observation_id,
@@ -156,12 +238,12 @@ func (p *postgresDB) GetObservationsForFormType(ctx context.Context, formType st
 			version,
 			geolocation
 			%s
-		FROM observations 
-		WHERE form_type = $1 AND deleted = false
+		FROM %s 
+		WHERE form_type = $1 AND %s
 		ORDER BY created_at
-	`, selectClause)
+	`, selectClause, p.source(), filter)
 	
-	rows, err := p.db.QueryContext(ctx, query, formType)
+	rows, err := p.reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query observations for form type %s: %w", formType, err)
 	}
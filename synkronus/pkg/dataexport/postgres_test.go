@@ -2,7 +2,10 @@ package dataexport
 
 import (
 	"context"
+	"database/sql"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -41,7 +44,7 @@ func TestPostgresDB_GetFormTypes(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			expectedQuery := `SELECT DISTINCT form_type FROM observations WHERE deleted = false ORDER BY form_type`
+			expectedQuery := `SELECT DISTINCT form_type FROM observations WHERE deleted = false AND draft = false ORDER BY form_type`
 			mock.ExpectQuery(expectedQuery).WillReturnRows(tt.mockRows)
 
 			formTypes, err := pgDB.GetFormTypes(context.Background())
@@ -241,3 +244,80 @@ func TestPostgresDB_GetObservationsForFormType(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresDB_DeltaExport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	pgDB := NewPostgresDB(db).WithOptions(ExportOptions{SinceVersion: 10, UntilVersion: 20, CreatedFrom: from})
+
+	// Deltas keep deleted observations so that consumers can apply deletions
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE draft = false AND version > $1 AND version <= $2 AND created_at >= $3`)).
+		WithArgs(int64(10), int64(20), from).
+		WillReturnRows(sqlmock.NewRows([]string{"form_type"}).AddRow("survey"))
+	formTypes, err := pgDB.GetFormTypes(context.Background())
+	if err != nil || len(formTypes) != 1 {
+		t.Fatalf("Expected one form type, got %v (%v)", formTypes, err)
+	}
+
+	schema := &FormTypeSchema{FormType: "survey"}
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE form_type = $1 AND draft = false AND version > $2 AND version <= $3 AND created_at >= $4`)).
+		WithArgs("survey", int64(10), int64(20), from).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"observation_id", "form_type", "form_version", "created_at", "updated_at",
+			"synced_at", "deleted", "version", "geolocation",
+		}).AddRow("obs1", "survey", "1.0", "2025-06-02T00:00:00Z", "2025-06-03T00:00:00Z", nil, true, int64(12), nil))
+	observations, err := pgDB.GetObservationsForFormType(context.Background(), "survey", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(observations) != 1 || !observations[0].Deleted {
+		t.Errorf("Expected the deleted observation in the delta, got %+v", observations)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// fixedReplica is a ReadReplica that always returns the same database
+type fixedReplica struct {
+	db *sql.DB
+}
+
+func (f fixedReplica) Reader(ctx context.Context) *sql.DB {
+	return f.db
+}
+
+func TestPostgresDB_ReadReplica(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer primary.Close()
+	replica, replicaMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer replica.Close()
+
+	// Options keep reading from the replica
+	pgDB := NewPostgresReplicaDB(primary, fixedReplica{db: replica}).WithOptions(ExportOptions{SinceVersion: 10})
+	replicaMock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT form_type`)).
+		WillReturnRows(sqlmock.NewRows([]string{"form_type"}).AddRow("survey"))
+	formTypes, err := pgDB.GetFormTypes(context.Background())
+	if err != nil || len(formTypes) != 1 {
+		t.Fatalf("Expected one form type, got %v (%v)", formTypes, err)
+	}
+
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected primary queries: %v", err)
+	}
+}
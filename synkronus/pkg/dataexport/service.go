@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/apache/arrow/go/v14/arrow"
 	"github.com/apache/arrow/go/v14/arrow/array"
 	"github.com/apache/arrow/go/v14/arrow/memory"
 	"github.com/apache/arrow/go/v14/parquet"
 	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/google/uuid"
 	"github.com/opendataensemble/synkronus/pkg/config"
 )
 
@@ -20,6 +22,14 @@ import (
 type Service interface {
 	// ExportParquetZip exports observations data as a ZIP file containing Parquet files per form type
 	ExportParquetZip(ctx context.Context) (io.ReadCloser, error)
+
+	// ExportParquetZipWithOptions exports observations data narrowed down by opts,
+	// e.g. as it existed at a past sync version or for a single org unit
+	ExportParquetZipWithOptions(ctx context.Context, opts ExportOptions) (io.ReadCloser, error)
+
+	// ExportToSink writes the flattened observation table of each form type to sink, shaped
+	// by opts like a Parquet export
+	ExportToSink(ctx context.Context, sink Sink, opts ExportOptions) (*SinkResult, error)
 }
 
 // service implements the Service interface
@@ -38,11 +48,50 @@ func NewService(db DatabaseInterface, cfg *config.Config) Service {
 
 // ExportParquetZip exports observations data as a ZIP file containing Parquet files per form type
 func (s *service) ExportParquetZip(ctx context.Context) (io.ReadCloser, error) {
+	return s.exportParquetZip(ctx, s.db, ExportOptions{})
+}
+
+// ExportParquetZipWithOptions exports observations data narrowed down by opts
+func (s *service) ExportParquetZipWithOptions(ctx context.Context, opts ExportOptions) (io.ReadCloser, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+	return s.exportParquetZip(ctx, s.db.WithOptions(opts), opts)
+}
+
+// validateOptions checks export options given by clients
+func validateOptions(opts ExportOptions) error {
+	if opts.AsOfVersion < 0 {
+		return fmt.Errorf("invalid export version %d", opts.AsOfVersion)
+	}
+	if opts.SinceVersion < 0 || opts.UntilVersion < 0 {
+		return fmt.Errorf("invalid export version range %d to %d", opts.SinceVersion, opts.UntilVersion)
+	}
+	if !opts.CreatedFrom.IsZero() && !opts.CreatedTo.IsZero() && !opts.CreatedFrom.Before(opts.CreatedTo) {
+		return fmt.Errorf("invalid export date range %s to %s", opts.CreatedFrom.Format(time.RFC3339), opts.CreatedTo.Format(time.RFC3339))
+	}
+	if opts.OrgUnitID != "" {
+		if _, err := uuid.Parse(opts.OrgUnitID); err != nil {
+			return fmt.Errorf("invalid org unit id %q", opts.OrgUnitID)
+		}
+	}
+	for _, mask := range opts.Masks {
+		if mask.Method != MaskRedact && mask.Method != MaskHash {
+			return fmt.Errorf("invalid mask method %q for column %s", mask.Method, mask.Column)
+		}
+	}
+	return nil
+}
+
+// exportParquetZip writes every form type read from db as a parquet file into a ZIP archive,
+// shaped by the form type, column and mask options
+func (s *service) exportParquetZip(ctx context.Context, db DatabaseInterface, opts ExportOptions) (io.ReadCloser, error) {
 	// Get all form types
-	formTypes, err := s.db.GetFormTypes(ctx)
+	formTypes, err := db.GetFormTypes(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get form types: %w", err)
 	}
+	formTypes = selectFormTypes(formTypes, opts.FormTypes)
 
 	// Create ZIP buffer
 	zipBuffer := &bytes.Buffer{}
@@ -50,7 +99,7 @@ func (s *service) ExportParquetZip(ctx context.Context) (io.ReadCloser, error) {
 
 	// Process each form type
 	for _, formType := range formTypes {
-		if err := s.exportFormTypeToZip(ctx, formType, zipWriter); err != nil {
+		if err := s.exportFormTypeToZip(ctx, db, formType, zipWriter, opts); err != nil {
 			zipWriter.Close()
 			return nil, fmt.Errorf("failed to export form type %s: %w", formType, err)
 		}
@@ -65,24 +114,37 @@ func (s *service) ExportParquetZip(ctx context.Context) (io.ReadCloser, error) {
 	return io.NopCloser(bytes.NewReader(zipBuffer.Bytes())), nil
 }
 
-// exportFormTypeToZip exports a single form type as a parquet file to the ZIP archive
-func (s *service) exportFormTypeToZip(ctx context.Context, formType string, zipWriter *zip.Writer) error {
+// readTable reads the flattened observations of a form type, shaped by the column and mask
+// options. Form types without observations give an empty table.
+func readTable(ctx context.Context, db DatabaseInterface, formType string, opts ExportOptions) (*Table, error) {
 	// Get schema for this form type
-	schema, err := s.db.GetFormTypeSchema(ctx, formType)
+	schema, err := db.GetFormTypeSchema(ctx, formType)
 	if err != nil {
-		return fmt.Errorf("failed to get schema for form type %s: %w", formType, err)
+		return nil, fmt.Errorf("failed to get schema for form type %s: %w", formType, err)
 	}
+	schema = selectColumns(schema, opts.Columns)
 
 	// Get observations for this form type
-	observations, err := s.db.GetObservationsForFormType(ctx, formType, schema)
+	observations, err := db.GetObservationsForFormType(ctx, formType, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observations for form type %s: %w", formType, err)
+	}
+	schema = applyMasks(schema, observations, opts.Masks, opts.MaskKey)
+	return &Table{FormType: formType, Schema: schema, Observations: observations}, nil
+}
+
+// exportFormTypeToZip exports a single form type as a parquet file to the ZIP archive
+func (s *service) exportFormTypeToZip(ctx context.Context, db DatabaseInterface, formType string, zipWriter *zip.Writer, opts ExportOptions) error {
+	table, err := readTable(ctx, db, formType, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get observations for form type %s: %w", formType, err)
+		return err
 	}
 
 	// Skip if no observations
-	if len(observations) == 0 {
+	if len(table.Observations) == 0 {
 		return nil
 	}
+	observations, schema := table.Observations, table.Schema
 
 	// Create parquet file in ZIP
 	filename := s.sanitizeFilename(formType) + ".parquet"
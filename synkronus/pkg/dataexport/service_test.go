@@ -18,6 +18,7 @@ type MockDatabaseInterface struct {
 	GetFormTypesError   error
 	GetSchemaError      error
 	GetObservationsError error
+	Options             ExportOptions
 }
 
 func (m *MockDatabaseInterface) GetFormTypes(ctx context.Context) ([]string, error) {
@@ -38,6 +39,11 @@ func (m *MockDatabaseInterface) GetFormTypeSchema(ctx context.Context, formType
 	return schema, nil
 }
 
+func (m *MockDatabaseInterface) WithOptions(opts ExportOptions) DatabaseInterface {
+	m.Options = opts
+	return m
+}
+
 func (m *MockDatabaseInterface) GetObservationsForFormType(ctx context.Context, formType string, schema *FormTypeSchema) ([]ObservationRow, error) {
 	if m.GetObservationsError != nil {
 		return nil, m.GetObservationsError
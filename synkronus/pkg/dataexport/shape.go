@@ -0,0 +1,86 @@
+package dataexport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// selectFormTypes keeps the form types listed in wanted, in their original order; all of them
+// when wanted is empty
+func selectFormTypes(formTypes, wanted []string) []string {
+	if len(wanted) == 0 {
+		return formTypes
+	}
+	keep := make(map[string]bool, len(wanted))
+	for _, formType := range wanted {
+		keep[formType] = true
+	}
+	selected := make([]string, 0, len(wanted))
+	for _, formType := range formTypes {
+		if keep[formType] {
+			selected = append(selected, formType)
+		}
+	}
+	return selected
+}
+
+// selectColumns returns schema limited to the listed data columns; schema itself when columns
+// is empty. Columns a form type does not have are ignored, as templates span form types.
+func selectColumns(schema *FormTypeSchema, columns []string) *FormTypeSchema {
+	if len(columns) == 0 {
+		return schema
+	}
+	keep := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		keep[column] = true
+	}
+	selected := &FormTypeSchema{FormType: schema.FormType, Columns: make([]FormTypeColumn, 0, len(columns))}
+	for _, col := range schema.Columns {
+		if keep["data_"+col.Key] {
+			selected.Columns = append(selected.Columns, col)
+		}
+	}
+	return selected
+}
+
+// applyMasks rewrites the masked data columns of observations in place and returns schema with
+// hashed columns retyped as text, as the hashes of numbers and booleans are strings
+func applyMasks(schema *FormTypeSchema, observations []ObservationRow, masks []ColumnMask, key []byte) *FormTypeSchema {
+	if len(masks) == 0 {
+		return schema
+	}
+	methods := make(map[string]MaskMethod, len(masks))
+	for _, mask := range masks {
+		methods[mask.Column] = mask.Method
+	}
+
+	masked := &FormTypeSchema{FormType: schema.FormType, Columns: make([]FormTypeColumn, len(schema.Columns))}
+	copy(masked.Columns, schema.Columns)
+	for i, col := range masked.Columns {
+		column := "data_" + col.Key
+		method, ok := methods[column]
+		if !ok {
+			continue
+		}
+		if method == MaskHash {
+			masked.Columns[i].DataType = "string"
+			masked.Columns[i].SQLType = "text"
+		}
+		for _, obs := range observations {
+			value, exists := obs.DataFields[column]
+			if !exists || value == nil {
+				continue
+			}
+			if method == MaskRedact {
+				obs.DataFields[column] = nil
+				continue
+			}
+			mac := hmac.New(sha256.New, key)
+			fmt.Fprintf(mac, "%v", value)
+			obs.DataFields[column] = hex.EncodeToString(mac.Sum(nil))
+		}
+	}
+	return masked
+}
@@ -0,0 +1,89 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/config"
+)
+
+func TestService_ExportParquetZipWithOptions_Shaped(t *testing.T) {
+	mockDB := &MockDatabaseInterface{
+		FormTypes: []string{"survey", "inspection"},
+		FormTypeSchemas: map[string]*FormTypeSchema{
+			"survey": {FormType: "survey", Columns: []FormTypeColumn{
+				{Key: "name", DataType: "string", SQLType: "text"},
+				{Key: "phone", DataType: "string", SQLType: "text"},
+				{Key: "rating", DataType: "number", SQLType: "numeric"},
+			}},
+		},
+		ObservationsData: map[string][]ObservationRow{
+			"survey":     {{ObservationID: "obs1", FormType: "survey", DataFields: map[string]interface{}{"data_name": "Ada", "data_phone": "555-0100", "data_rating": 4.5}}},
+			"inspection": {{ObservationID: "obs2", FormType: "inspection", DataFields: map[string]interface{}{}}},
+		},
+	}
+	service := NewService(mockDB, &config.Config{})
+
+	reader, err := service.ExportParquetZipWithOptions(context.Background(), ExportOptions{
+		FormTypes: []string{"survey"},
+		Columns:   []string{"data_name", "data_rating"},
+		Masks:     []ColumnMask{{Column: "data_name", Method: MaskHash}},
+		MaskKey:   []byte("key"),
+	})
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid ZIP: %v", err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "survey.parquet" {
+		t.Errorf("expected only survey.parquet, got %d files", len(archive.File))
+	}
+
+	_, err = service.ExportParquetZipWithOptions(context.Background(), ExportOptions{
+		Masks: []ColumnMask{{Column: "data_name", Method: "shuffle"}},
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown mask method")
+	}
+}
+
+func TestApplyMasks(t *testing.T) {
+	schema := selectColumns(&FormTypeSchema{FormType: "survey", Columns: []FormTypeColumn{
+		{Key: "name", SQLType: "text"},
+		{Key: "age", SQLType: "numeric"},
+		{Key: "phone", SQLType: "text"},
+		{Key: "notes", SQLType: "text"},
+	}}, []string{"data_name", "data_age", "data_phone"})
+	if len(schema.Columns) != 3 {
+		t.Fatalf("expected 3 selected columns, got %d", len(schema.Columns))
+	}
+
+	observations := []ObservationRow{
+		{DataFields: map[string]interface{}{"data_name": "Ada", "data_age": 36.0, "data_phone": "555-0100"}},
+		{DataFields: map[string]interface{}{"data_name": "Ada", "data_age": nil}},
+	}
+	masks := []ColumnMask{{Column: "data_age", Method: MaskHash}, {Column: "data_phone", Method: MaskRedact}}
+	masked := applyMasks(schema, observations, masks, []byte("key"))
+
+	if masked.Columns[1].SQLType != "text" || schema.Columns[1].SQLType != "numeric" {
+		t.Errorf("expected only the masked schema to retype the hashed column, got %+v and %+v", masked.Columns[1], schema.Columns[1])
+	}
+	if hash, ok := observations[0].DataFields["data_age"].(string); !ok || len(hash) != 64 {
+		t.Errorf("expected a hex HMAC, got %v", observations[0].DataFields["data_age"])
+	}
+	if observations[1].DataFields["data_age"] != nil {
+		t.Errorf("expected missing values to stay null, got %v", observations[1].DataFields["data_age"])
+	}
+	if observations[0].DataFields["data_phone"] != nil {
+		t.Errorf("expected redacted column to be null, got %v", observations[0].DataFields["data_phone"])
+	}
+	if observations[0].DataFields["data_name"] != "Ada" {
+		t.Errorf("expected unmasked column to be kept, got %v", observations[0].DataFields["data_name"])
+	}
+}
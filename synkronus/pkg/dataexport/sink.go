@@ -0,0 +1,95 @@
+package dataexport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// Table is the flattened observation table of one form type
+type Table struct {
+	FormType     string
+	Schema       *FormTypeSchema
+	Observations []ObservationRow
+}
+
+// Sink receives flattened observation tables, as an alternative to Parquet files for
+// consumers that query the data directly
+type Sink interface {
+	// Name identifies the sink in logs
+	Name() string
+	// WriteTables replaces the tables held by the sink with tables, all at once
+	WriteTables(ctx context.Context, tables []Table) error
+}
+
+// SinkResult summarizes an export to a sink
+type SinkResult struct {
+	Tables       int
+	Observations int
+	Duration     time.Duration
+}
+
+// ExportToSink writes the flattened observation table of each form type to sink, shaped by
+// opts like a Parquet export
+func (s *service) ExportToSink(ctx context.Context, sink Sink, opts ExportOptions) (*SinkResult, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	db := s.db.WithOptions(opts)
+
+	formTypes, err := db.GetFormTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get form types: %w", err)
+	}
+	formTypes = selectFormTypes(formTypes, opts.FormTypes)
+
+	result := &SinkResult{}
+	tables := make([]Table, 0, len(formTypes))
+	for _, formType := range formTypes {
+		table, err := readTable(ctx, db, formType, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export form type %s: %w", formType, err)
+		}
+		tables = append(tables, *table)
+		result.Observations += len(table.Observations)
+	}
+
+	if err := sink.WriteTables(ctx, tables); err != nil {
+		return nil, fmt.Errorf("failed to write to %s: %w", sink.Name(), err)
+	}
+	result.Tables = len(tables)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// RunSinkExport exports to sink every interval until ctx is canceled, starting right away so
+// that the sink is current after a restart. It returns immediately when interval is not
+// positive.
+func RunSinkExport(ctx context.Context, service Service, sink Sink, opts ExportOptions, interval time.Duration, log *logger.Logger) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := service.ExportToSink(ctx, sink, opts)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Warn("Scheduled export to sink failed", "sink", sink.Name(), "error", err)
+		case err == nil:
+			log.Info("Scheduled export to sink completed", "sink", sink.Name(), "tables", result.Tables,
+				"observations", result.Observations, "duration_ms", result.Duration.Milliseconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
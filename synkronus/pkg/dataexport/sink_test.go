@@ -0,0 +1,66 @@
+package dataexport
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/config"
+)
+
+// recordingSink keeps the tables written to it
+type recordingSink struct {
+	tables []Table
+	err    error
+}
+
+func (r *recordingSink) Name() string { return "recording" }
+
+func (r *recordingSink) WriteTables(ctx context.Context, tables []Table) error {
+	r.tables = tables
+	return r.err
+}
+
+func TestService_ExportToSink(t *testing.T) {
+	db := &MockDatabaseInterface{
+		FormTypes: []string{"household", "visit"},
+		FormTypeSchemas: map[string]*FormTypeSchema{
+			"household": {FormType: "household", Columns: []FormTypeColumn{
+				{Key: "village", DataType: "string", SQLType: "text"},
+				{Key: "head_name", DataType: "string", SQLType: "text"},
+			}},
+		},
+		ObservationsData: map[string][]ObservationRow{
+			"household": {{ObservationID: "obs1", FormType: "household", DataFields: map[string]interface{}{
+				"data_village": "Ngong", "data_head_name": "Wanjiru",
+			}}},
+		},
+	}
+	svc := NewService(db, &config.Config{})
+
+	sink := &recordingSink{}
+	result, err := svc.ExportToSink(context.Background(), sink, ExportOptions{
+		Masks: []ColumnMask{{Column: "data_head_name", Method: MaskRedact}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Tables != 2 || result.Observations != 1 {
+		t.Errorf("Expected 2 tables with 1 observation, got %+v", result)
+	}
+	// Form types without observations still get a table, so that stale rows disappear
+	if len(sink.tables) != 2 || sink.tables[1].FormType != "visit" || len(sink.tables[1].Observations) != 0 {
+		t.Fatalf("Unexpected tables %+v", sink.tables)
+	}
+	if value := sink.tables[0].Observations[0].DataFields["data_head_name"]; value != nil {
+		t.Errorf("Expected the masked column to be redacted, got %v", value)
+	}
+
+	sink.err = errors.New("connection refused")
+	if _, err := svc.ExportToSink(context.Background(), sink, ExportOptions{}); err == nil {
+		t.Error("Expected the sink error to be returned")
+	}
+	if _, err := svc.ExportToSink(context.Background(), sink, ExportOptions{OrgUnitID: "not-a-uuid"}); err == nil {
+		t.Error("Expected invalid options to be refused")
+	}
+}
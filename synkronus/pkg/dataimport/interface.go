@@ -0,0 +1,117 @@
+package dataimport
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// Common errors
+var (
+	// ErrInvalidImport is returned when a CSV file or its mapping cannot be imported
+	ErrInvalidImport = errors.New("invalid import")
+	// ErrNotFound is returned when an import does not exist
+	ErrNotFound = errors.New("import not found")
+)
+
+// Import statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Row outcomes
+const (
+	// RowImported means the row was stored as an observation
+	RowImported = "imported"
+	// RowHeld means the row awaits an admin, in a locked reporting period or as a conflict
+	RowHeld = "held"
+	// RowFailed means the row was not stored
+	RowFailed = "failed"
+)
+
+// MaxRows is the most data rows a CSV file may hold
+const MaxRows = 50000
+
+// ClientID is the sync client ID imported rows are pushed as
+const ClientID = "data-import"
+
+// Mapping maps the columns of a CSV file onto the data fields of a form type
+type Mapping struct {
+	FormType string `json:"form_type"`
+	// FormVersion is recorded on every observation; defaults to the active app bundle version
+	FormVersion string `json:"form_version,omitempty"`
+	// Columns maps CSV headers to data fields of the form
+	Columns map[string]string `json:"columns"`
+	// Constants sets data fields to the same value on every row, e.g. the source register
+	Constants map[string]any `json:"constants,omitempty"`
+	// ObservationIDColumn names a column of observation IDs. Without it, IDs are derived from
+	// the import and the row, so a resumed import does not store rows twice.
+	ObservationIDColumn string `json:"observation_id_column,omitempty"`
+	// CreatedAtColumn names a column of collection dates, in RFC 3339 or YYYY-MM-DD. Without
+	// it, rows are dated when the import was queued.
+	CreatedAtColumn string `json:"created_at_column,omitempty"`
+	// OrgUnitIDColumn names a column of org unit IDs
+	OrgUnitIDColumn string `json:"org_unit_id_column,omitempty"`
+}
+
+// RowResult is the outcome of one row of a CSV file
+type RowResult struct {
+	// Row is the line of the row in the file; the header is line 1
+	Row           int      `json:"row"`
+	ObservationID string   `json:"observation_id,omitempty"`
+	Status        string   `json:"status"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// Job is an import of a CSV file. Results are filled in as rows are imported.
+type Job struct {
+	ID           string      `json:"id"`
+	FormType     string      `json:"form_type"`
+	Filename     string      `json:"filename"`
+	Status       string      `json:"status"`
+	Mapping      Mapping     `json:"mapping"`
+	TotalRows    int         `json:"total_rows"`
+	ImportedRows int         `json:"imported_rows"`
+	HeldRows     int         `json:"held_rows"`
+	FailedRows   int         `json:"failed_rows"`
+	Error        string      `json:"error,omitempty"`
+	Results      []RowResult `json:"results,omitempty"`
+	CreatedBy    string      `json:"created_by"`
+	CreatedAt    string      `json:"created_at"`
+	StartedAt    *string     `json:"started_at,omitempty"`
+	FinishedAt   *string     `json:"finished_at,omitempty"`
+}
+
+// Forms provides the form schemas rows are validated against; implemented by the app bundle service
+type Forms interface {
+	GetManifest(ctx context.Context) (*appbundle.Manifest, error)
+	GetAppInfo(ctx context.Context, version string) (*appbundle.AppInfo, error)
+}
+
+// Pusher stores imported rows through the sync push rules; implemented by the sync service
+type Pusher interface {
+	ProcessPushedRecords(ctx context.Context, records []sync.Observation, clientID string, transmissionID string) (*sync.SyncPushResult, error)
+}
+
+// Service imports observations from CSV files, e.g. historical paper registers
+type Service interface {
+	// Start checks a CSV file and its mapping against the form schema of the active app bundle
+	// and queues the import. Rows are validated and imported in the background by Run.
+	Start(ctx context.Context, filename string, file io.Reader, mapping Mapping, username string) (*Job, error)
+
+	// Get returns an import with its row results
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// List returns the most recent imports without their row results, newest first
+	List(ctx context.Context, limit int) ([]Job, error)
+
+	// Run imports queued files until ctx is cancelled. Imports interrupted by a shutdown are
+	// resumed where they stopped.
+	Run(ctx context.Context)
+}
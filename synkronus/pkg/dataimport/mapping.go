@@ -0,0 +1,276 @@
+package dataimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// plan is a mapping checked against the header of a CSV file and the fields of its form
+type plan struct {
+	formType    string
+	formVersion string
+	fields      map[string]appbundle.FieldInfo
+	// columns holds the column index of each mapped field
+	columns   map[string]int
+	constants map[string]any
+	// Column indexes of the observation ID, collection date and org unit; -1 when not mapped
+	idColumn        int
+	createdAtColumn int
+	orgUnitColumn   int
+}
+
+// newPlan checks that every mapped column is in the header, that every mapped field is a field
+// of the form the server does not assign, and that every required field is mapped
+func newPlan(mapping Mapping, header []string, form appbundle.FormInfo, formVersion string) (*plan, error) {
+	if mapping.FormVersion != "" {
+		formVersion = mapping.FormVersion
+	}
+	p := &plan{
+		formType:    mapping.FormType,
+		formVersion: formVersion,
+		fields:      make(map[string]appbundle.FieldInfo, len(form.Fields)),
+		columns:     make(map[string]int, len(mapping.Columns)),
+		constants:   mapping.Constants,
+	}
+	for _, field := range form.Fields {
+		p.fields[field.Name] = field
+	}
+
+	index := make(map[string]int, len(header))
+	var problems []string
+	for i, name := range header {
+		if _, ok := index[name]; ok {
+			problems = append(problems, fmt.Sprintf("column %q appears more than once", name))
+		}
+		index[name] = i
+	}
+	column := func(name, purpose string) int {
+		if name == "" {
+			return -1
+		}
+		i, ok := index[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s column %q is not in the file", purpose, name))
+			return -1
+		}
+		return i
+	}
+	mapped := make(map[string]bool)
+	// checkField reports whether a mapped field can be filled from the file
+	checkField := func(name, source string) bool {
+		field, ok := p.fields[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s maps to %q, which is not a field of form %q", source, name, mapping.FormType))
+		case field.ServerAssigned != "":
+			problems = append(problems, fmt.Sprintf("%s maps to %q, which the server assigns", source, name))
+		case mapped[name]:
+			problems = append(problems, fmt.Sprintf("field %q is mapped more than once", name))
+		default:
+			mapped[name] = true
+			return true
+		}
+		return false
+	}
+
+	if len(mapping.Columns) == 0 {
+		problems = append(problems, "no columns are mapped")
+	}
+	for _, header := range sortedKeys(mapping.Columns) {
+		name := mapping.Columns[header]
+		i := column(header, "mapped")
+		if checkField(name, fmt.Sprintf("column %q", header)) && i >= 0 {
+			p.columns[name] = i
+		}
+	}
+	for _, name := range sortedKeys(mapping.Constants) {
+		if checkField(name, "constant") {
+			if _, err := convertValue(p.fields[name], mapping.Constants[name]); err != nil {
+				problems = append(problems, fmt.Sprintf("constant %q: %v", name, err))
+			}
+		}
+	}
+	p.idColumn = column(mapping.ObservationIDColumn, "observation ID")
+	p.createdAtColumn = column(mapping.CreatedAtColumn, "created at")
+	p.orgUnitColumn = column(mapping.OrgUnitIDColumn, "org unit ID")
+
+	for _, field := range form.Fields {
+		if field.Required && field.ServerAssigned == "" && !mapped[field.Name] {
+			problems = append(problems, fmt.Sprintf("required field %q is not mapped", field.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImport, strings.Join(problems, "; "))
+	}
+	return p, nil
+}
+
+// record converts a row of the file, on the given line, to an observation. It returns every
+// problem of the row instead of the observation when the row does not fit the form.
+func (p *plan) record(jobID uuid.UUID, line int, row []string, queuedAt time.Time) (sync.Observation, []string) {
+	var problems []string
+	cell := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	data := make(map[string]any, len(p.constants)+len(p.columns))
+	for name, value := range p.constants {
+		data[name], _ = convertValue(p.fields[name], value)
+	}
+	for name, i := range p.columns {
+		field := p.fields[name]
+		text := cell(i)
+		if text == "" {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("%s is required", name))
+			}
+			continue
+		}
+		value, err := parseCell(field, text)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		data[name] = value
+	}
+
+	obs := sync.Observation{FormType: p.formType, FormVersion: p.formVersion}
+	obs.ObservationID = uuid.NewSHA1(jobID, []byte(strconv.Itoa(line))).String()
+	if p.idColumn >= 0 {
+		if obs.ObservationID = cell(p.idColumn); obs.ObservationID == "" {
+			problems = append(problems, "observation ID is required")
+		}
+	}
+
+	createdAt := queuedAt
+	if p.createdAtColumn >= 0 {
+		var err error
+		if createdAt, err = parseDate(cell(p.createdAtColumn)); err != nil {
+			problems = append(problems, fmt.Sprintf("created at: %v", err))
+		}
+	}
+	obs.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	obs.UpdatedAt = obs.CreatedAt
+
+	if id := cell(p.orgUnitColumn); id != "" {
+		obs.OrgUnitID = &id
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return sync.Observation{}, problems
+	}
+	obs.Data, _ = json.Marshal(data)
+	return obs, nil
+}
+
+// parseCell converts the text of a cell to a value of the field's JSON schema type. Arrays are
+// written as JSON or as values separated by semicolons; objects only as JSON.
+func parseCell(field appbundle.FieldInfo, text string) (any, error) {
+	switch field.Type {
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		return n, nil
+	case "integer":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a whole number", text)
+		}
+		return n, nil
+	case "boolean":
+		switch strings.ToLower(text) {
+		case "true", "yes", "y", "1":
+			return true, nil
+		case "false", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not yes or no", text)
+	case "array":
+		if strings.HasPrefix(text, "[") {
+			var values []any
+			if err := json.Unmarshal([]byte(text), &values); err != nil {
+				return nil, fmt.Errorf("%q is not a JSON array", text)
+			}
+			return values, nil
+		}
+		parts := strings.Split(text, ";")
+		values := make([]any, 0, len(parts))
+		for _, part := range parts {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+		return values, nil
+	case "object":
+		var value map[string]any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("%q is not a JSON object", text)
+		}
+		return value, nil
+	}
+	return text, nil
+}
+
+// convertValue checks that a constant decoded from JSON fits the field's type; whole numbers are
+// kept as integers for integer fields
+func convertValue(field appbundle.FieldInfo, value any) (any, error) {
+	ok := true
+	switch field.Type {
+	case "number":
+		_, ok = value.(float64)
+	case "integer":
+		n, isNumber := value.(float64)
+		if ok = isNumber && n == math.Trunc(n); ok {
+			return int64(n), nil
+		}
+	case "boolean":
+		_, ok = value.(bool)
+	case "array":
+		_, ok = value.([]any)
+	case "object":
+		_, ok = value.(map[string]any)
+	case "string":
+		_, ok = value.(string)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%v is not of type %s", value, field.Type)
+	}
+	return value, nil
+}
+
+// parseDate parses an RFC 3339 timestamp or a date, which is taken as midnight UTC
+func parseDate(text string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, text); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", text)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
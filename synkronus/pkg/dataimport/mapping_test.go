@@ -0,0 +1,137 @@
+package dataimport
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerForm is the form historical register rows are imported into
+var registerForm = appbundle.FormInfo{Fields: []appbundle.FieldInfo{
+	{Name: "name", Type: "string", Required: true},
+	{Name: "age", Type: "integer"},
+	{Name: "weight", Type: "number"},
+	{Name: "vaccinated", Type: "boolean"},
+	{Name: "symptoms", Type: "array"},
+	{Name: "source", Type: "string"},
+	{Name: "register_no", Type: "string", Required: true, ServerAssigned: appbundle.ServerAssignedSequence},
+}}
+
+var registerHeader = []string{"Name", "Age", "Weight", "Vaccinated", "Symptoms", "Visit date", "ID"}
+
+func registerMapping() Mapping {
+	return Mapping{
+		FormType: "register",
+		Columns: map[string]string{
+			"Name": "name", "Age": "age", "Weight": "weight", "Vaccinated": "vaccinated", "Symptoms": "symptoms",
+		},
+		Constants:       map[string]any{"source": "paper register 2019"},
+		CreatedAtColumn: "Visit date",
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	p, err := newPlan(registerMapping(), registerHeader, registerForm, "3")
+	require.NoError(t, err)
+	assert.Equal(t, "3", p.formVersion)
+	assert.Equal(t, 1, p.columns["age"])
+	assert.Equal(t, 5, p.createdAtColumn)
+	assert.Equal(t, -1, p.idColumn)
+
+	tests := []struct {
+		name    string
+		mapping func(*Mapping)
+		problem string
+	}{
+		{"missing column", func(m *Mapping) { m.Columns["Village"] = "source"; delete(m.Constants, "source") }, `mapped column "Village" is not in the file`},
+		{"unknown field", func(m *Mapping) { m.Columns["ID"] = "patient_id" }, `which is not a field of form "register"`},
+		{"server-assigned field", func(m *Mapping) { m.Columns["ID"] = "register_no" }, "which the server assigns"},
+		{"field mapped twice", func(m *Mapping) { m.Constants["name"] = "unknown" }, `field "name" is mapped more than once`},
+		{"constant of the wrong type", func(m *Mapping) { delete(m.Columns, "Age"); m.Constants["age"] = 4.5 }, `constant "age"`},
+		{"required field unmapped", func(m *Mapping) { delete(m.Columns, "Name") }, `required field "name" is not mapped`},
+		{"missing date column", func(m *Mapping) { m.CreatedAtColumn = "Date" }, `created at column "Date"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := registerMapping()
+			tt.mapping(&mapping)
+			_, err := newPlan(mapping, registerHeader, registerForm, "3")
+			assert.ErrorIs(t, err, ErrInvalidImport)
+			assert.ErrorContains(t, err, tt.problem)
+		})
+	}
+
+	_, err = newPlan(registerMapping(), []string{"Name", "Name"}, registerForm, "3")
+	assert.ErrorContains(t, err, `column "Name" appears more than once`)
+}
+
+func TestPlanRecord(t *testing.T) {
+	p, err := newPlan(registerMapping(), registerHeader, registerForm, "3")
+	require.NoError(t, err)
+	jobID := uuid.New()
+	queuedAt := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	record, problems := p.record(jobID, 2, []string{"Amina", "34", "61.5", "yes", "fever; cough", "2019-03-14", ""}, queuedAt)
+	require.Empty(t, problems)
+	assert.Equal(t, "register", record.FormType)
+	assert.Equal(t, "2019-03-14T00:00:00Z", record.CreatedAt)
+	assert.Equal(t, record.CreatedAt, record.UpdatedAt)
+	var data map[string]any
+	require.NoError(t, json.Unmarshal(record.Data, &data))
+	assert.Equal(t, map[string]any{
+		"name": "Amina", "age": 34.0, "weight": 61.5, "vaccinated": true,
+		"symptoms": []any{"fever", "cough"}, "source": "paper register 2019",
+	}, data)
+
+	// IDs are derived from the import and line, so a resumed import pushes the same records
+	again, _ := p.record(jobID, 2, []string{"Amina", "34", "61.5", "yes", "fever; cough", "2019-03-14", ""}, queuedAt)
+	assert.Equal(t, record.ObservationID, again.ObservationID)
+	other, _ := p.record(jobID, 3, []string{"Amina", "", "", "", "", "2019-03-14"}, queuedAt)
+	assert.NotEqual(t, record.ObservationID, other.ObservationID)
+
+	_, problems = p.record(jobID, 4, []string{"", "thirty", "", "maybe", "", "14/03/2019"}, queuedAt)
+	assert.Equal(t, []string{
+		`age: "thirty" is not a whole number`,
+		`created at: "14/03/2019" is not a date`,
+		"name is required",
+		`vaccinated: "maybe" is not yes or no`,
+	}, problems)
+}
+
+func TestApplyOutcome(t *testing.T) {
+	results := []RowResult{
+		{Row: 2, ObservationID: "a", Status: RowImported},
+		{Row: 3, Status: RowFailed, Errors: []string{"name is required"}},
+		{Row: 4, ObservationID: "b", Status: RowImported},
+		{Row: 5, ObservationID: "c", Status: RowImported},
+		{Row: 6, ObservationID: "d", Status: RowImported},
+	}
+	applyOutcome(results, []int{0, 2, 3, 4}, &sync.SyncPushResult{
+		FailedRecords: []map[string]interface{}{{"index": 1, "error": "record is locked"}},
+		Warnings:      []sync.SyncWarning{{ID: "c", Code: sync.WarningCodePendingApproval, Message: "period is locked"}},
+		Conflicts:     []sync.PushConflict{{ObservationID: "d", Outcome: sync.PushConflictServerKept}},
+	})
+
+	statuses := make([]string, len(results))
+	for i, result := range results {
+		statuses[i] = result.Status
+	}
+	assert.Equal(t, []string{RowImported, RowFailed, RowFailed, RowHeld, RowFailed}, statuses)
+	assert.Equal(t, []string{"record is locked"}, results[2].Errors)
+}
+
+func TestParseCSV(t *testing.T) {
+	header, rows, err := parseCSV([]byte("\ufeffName , Age\nAmina,34\nJoseph\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Name", "Age"}, header)
+	assert.Equal(t, [][]string{{"Amina", "34"}, {"Joseph"}}, rows)
+
+	_, _, err = parseCSV([]byte("Name\n\"unterminated\n"))
+	assert.ErrorIs(t, err, ErrInvalidImport)
+}
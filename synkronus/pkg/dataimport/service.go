@@ -0,0 +1,419 @@
+package dataimport
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+const (
+	// batchSize is the number of rows pushed per transaction
+	batchSize = 500
+	// pollInterval is how often queued imports are looked for when none is started locally
+	pollInterval = 10 * time.Second
+	// claimLease is how long a running import may go without progress before another worker
+	// resumes it
+	claimLease = 5 * time.Minute
+	// defaultListLimit is the number of imports listed when no limit is given
+	defaultListLimit = 50
+)
+
+type service struct {
+	db     *sql.DB
+	forms  Forms
+	pusher Pusher
+	log    *logger.Logger
+	// wake starts the worker on an import queued by this server without waiting for the poll
+	wake chan struct{}
+}
+
+// NewService creates a CSV import service; start Run to import queued files
+func NewService(db *sql.DB, forms Forms, pusher Pusher, log *logger.Logger) Service {
+	return &service{db: db, forms: forms, pusher: pusher, log: log, wake: make(chan struct{}, 1)}
+}
+
+// Start checks a CSV file and its mapping and queues the import
+func (s *service) Start(ctx context.Context, filename string, file io.Reader, mapping Mapping, username string) (*Job, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	header, rows, err := parseCSV(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file has no rows", ErrInvalidImport)
+	}
+	if len(rows) > MaxRows {
+		return nil, fmt.Errorf("%w: the file has %d rows; split it into files of at most %d", ErrInvalidImport, len(rows), MaxRows)
+	}
+
+	if mapping.FormType == "" {
+		return nil, fmt.Errorf("%w: form_type is required", ErrInvalidImport)
+	}
+	manifest, err := s.forms.GetManifest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app bundle manifest: %w", err)
+	}
+	if manifest.Version == "" {
+		return nil, fmt.Errorf("%w: no app bundle is active", ErrInvalidImport)
+	}
+	if _, err := s.plan(ctx, mapping, header, manifest.Version); err != nil {
+		return nil, err
+	}
+
+	mappingJSON, err := json.Marshal(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode mapping: %w", err)
+	}
+	job := &Job{
+		ID:        uuid.New().String(),
+		FormType:  mapping.FormType,
+		Filename:  filename,
+		Status:    StatusQueued,
+		Mapping:   mapping,
+		TotalRows: len(rows),
+		CreatedBy: username,
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO data_imports (id, form_type, filename, mapping, bundle_version, content, total_rows, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`,
+		job.ID, job.FormType, job.Filename, mappingJSON, manifest.Version, content, job.TotalRows, username,
+	).Scan(&job.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue import: %w", err)
+	}
+
+	s.log.Info("Data import queued", "id", job.ID, "formType", job.FormType, "rows", job.TotalRows, "user", username)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// plan checks a mapping against the form schema of an app bundle version
+func (s *service) plan(ctx context.Context, mapping Mapping, header []string, version string) (*plan, error) {
+	info, err := s.forms.GetAppInfo(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get form schemas: %w", err)
+	}
+	form, ok := info.Forms[mapping.FormType]
+	if !ok {
+		return nil, fmt.Errorf("%w: form type %q is not in app bundle version %s", ErrInvalidImport, mapping.FormType, version)
+	}
+	return newPlan(mapping, header, form, info.Version)
+}
+
+// parseCSV returns the header and rows of a CSV file. A byte order mark, as written by
+// spreadsheet programs, is dropped; rows may have fewer or more cells than the header.
+func parseCSV(content []byte) ([]string, [][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+	header := records[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	return header, records[1:], nil
+}
+
+// Get returns an import with its row results
+func (s *service) Get(ctx context.Context, id string) (*Job, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, form_type, filename, status, mapping, total_rows, imported_rows, held_rows, failed_rows,
+			error, results, created_by, created_at, started_at, finished_at
+		FROM data_imports WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+	jobs, err := scanJobs(rows, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &jobs[0], nil
+}
+
+// List returns the most recent imports without their row results, newest first
+func (s *service) List(ctx context.Context, limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, form_type, filename, status, mapping, total_rows, imported_rows, held_rows, failed_rows,
+			error, NULL, created_by, created_at, started_at, finished_at
+		FROM data_imports
+		ORDER BY created_at DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list imports: %w", err)
+	}
+	return scanJobs(rows, false)
+}
+
+// scanJobs reads imports; results are only decoded when withResults is set
+func scanJobs(rows *sql.Rows, withResults bool) ([]Job, error) {
+	defer rows.Close()
+	jobs := []Job{}
+	for rows.Next() {
+		var job Job
+		var mapping, results []byte
+		if err := rows.Scan(&job.ID, &job.FormType, &job.Filename, &job.Status, &mapping, &job.TotalRows,
+			&job.ImportedRows, &job.HeldRows, &job.FailedRows, &job.Error, &results, &job.CreatedBy,
+			&job.CreatedAt, &job.StartedAt, &job.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan import: %w", err)
+		}
+		if err := json.Unmarshal(mapping, &job.Mapping); err != nil {
+			return nil, fmt.Errorf("failed to decode import mapping: %w", err)
+		}
+		if withResults {
+			if err := json.Unmarshal(results, &job.Results); err != nil {
+				return nil, fmt.Errorf("failed to decode import results: %w", err)
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list imports: %w", err)
+	}
+	return jobs, nil
+}
+
+// Run imports queued files immediately, then whenever one is queued and every poll interval,
+// until ctx is cancelled
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			ran, err := s.runNext(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.log.Warn("Failed to run data import", "error", err)
+			}
+			if err != nil || !ran {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimedJob is an import claimed by a worker
+type claimedJob struct {
+	id            uuid.UUID
+	mapping       Mapping
+	bundleVersion string
+	content       []byte
+	createdBy     string
+	createdAt     time.Time
+	// done is the number of rows with results, from which an interrupted import resumes
+	done int
+}
+
+// runNext claims the oldest queued import, or a running one whose worker stopped, and imports
+// it. It reports whether an import was run.
+func (s *service) runNext(ctx context.Context) (bool, error) {
+	var job claimedJob
+	var mapping []byte
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE data_imports SET status = 'running', started_at = COALESCE(started_at, NOW()), heartbeat_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_imports
+			WHERE status = 'queued' OR (status = 'running' AND heartbeat_at < NOW() - $1 * INTERVAL '1 second')
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, mapping, bundle_version, content, created_by, created_at, jsonb_array_length(results)`,
+		claimLease.Seconds(),
+	).Scan(&job.id, &mapping, &job.bundleVersion, &job.content, &job.createdBy, &job.createdAt, &job.done)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim import: %w", err)
+	}
+	if err := json.Unmarshal(mapping, &job.mapping); err != nil {
+		return true, s.fail(ctx, job.id, fmt.Errorf("invalid mapping: %w", err))
+	}
+
+	if job.done > 0 {
+		s.log.Info("Resuming data import", "id", job.id, "row", job.done+2)
+	}
+	if err := s.run(ctx, job); err != nil {
+		if ctx.Err() != nil {
+			// Shutting down; the lease expires and the import is resumed after restart
+			return true, err
+		}
+		return true, s.fail(ctx, job.id, err)
+	}
+	return true, nil
+}
+
+// run validates the rows of a claimed import and pushes them in batches, saving the results of
+// every batch so an interrupted import can resume after the last saved row
+func (s *service) run(ctx context.Context, job claimedJob) error {
+	header, rows, err := parseCSV(job.content)
+	if err != nil {
+		return err
+	}
+	p, err := s.plan(ctx, job.mapping, header, job.bundleVersion)
+	if err != nil {
+		return err
+	}
+
+	pushCtx := sync.WithUsername(ctx, job.createdBy)
+	for start := job.done; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+		results := make([]RowResult, 0, end-start)
+		var records []sync.Observation
+		var pushed []int
+		for i := start; i < end; i++ {
+			line := i + 2
+			record, problems := p.record(job.id, line, rows[i], job.createdAt)
+			result := RowResult{Row: line, ObservationID: record.ObservationID}
+			if len(problems) > 0 {
+				result.Status, result.Errors = RowFailed, problems
+			} else {
+				result.Status = RowImported
+				pushed = append(pushed, len(results))
+				records = append(records, record)
+			}
+			results = append(results, result)
+		}
+
+		if len(records) > 0 {
+			transmissionID := job.id.String() + "-" + strconv.Itoa(start)
+			outcome, err := s.pusher.ProcessPushedRecords(pushCtx, records, ClientID, transmissionID)
+			if err != nil {
+				return fmt.Errorf("failed to import rows %d to %d: %w", start+2, end+1, err)
+			}
+			applyOutcome(results, pushed, outcome)
+		}
+		if err := s.saveResults(ctx, job.id, results); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE data_imports SET status = 'completed', content = NULL, finished_at = NOW()
+		WHERE id = $1`, job.id)
+	if err != nil {
+		return fmt.Errorf("failed to complete import: %w", err)
+	}
+	s.log.Info("Data import completed", "id", job.id, "rows", len(rows))
+	return nil
+}
+
+// applyOutcome marks the results of pushed rows, at the positions given by pushed, that the push
+// rejected or held for an admin
+func applyOutcome(results []RowResult, pushed []int, outcome *sync.SyncPushResult) {
+	for _, failed := range outcome.FailedRecords {
+		index, ok := failed["index"].(int)
+		if !ok || index < 0 || index >= len(pushed) {
+			continue
+		}
+		result := &results[pushed[index]]
+		result.Status = RowFailed
+		if message, ok := failed["error"].(string); ok {
+			result.Errors = append(result.Errors, message)
+		}
+	}
+
+	byID := make(map[string]*RowResult, len(pushed))
+	for _, i := range pushed {
+		byID[results[i].ObservationID] = &results[i]
+	}
+	for _, warning := range outcome.Warnings {
+		if result, ok := byID[warning.ID]; ok && warning.Code == sync.WarningCodePendingApproval {
+			result.Status = RowHeld
+			result.Errors = append(result.Errors, warning.Message)
+		}
+	}
+	for _, conflict := range outcome.Conflicts {
+		result, ok := byID[conflict.ObservationID]
+		if !ok {
+			continue
+		}
+		switch conflict.Outcome {
+		case sync.PushConflictServerKept:
+			result.Status = RowFailed
+			result.Errors = append(result.Errors, "a newer stored version of the observation was kept")
+		case sync.PushConflictPendingReview:
+			result.Status = RowHeld
+			result.Errors = append(result.Errors, "conflicts with the stored observation and awaits review")
+		}
+	}
+}
+
+// saveResults appends the results of a batch to an import and renews its lease
+func (s *service) saveResults(ctx context.Context, id uuid.UUID, results []RowResult) error {
+	var imported, held, failed int
+	for _, result := range results {
+		switch result.Status {
+		case RowImported:
+			imported++
+		case RowHeld:
+			held++
+		default:
+			failed++
+		}
+	}
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode import results: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE data_imports
+		SET results = results || $2::jsonb, imported_rows = imported_rows + $3, held_rows = held_rows + $4,
+			failed_rows = failed_rows + $5, heartbeat_at = NOW()
+		WHERE id = $1`,
+		id, encoded, imported, held, failed)
+	if err != nil {
+		return fmt.Errorf("failed to save import results: %w", err)
+	}
+	return nil
+}
+
+// fail ends an import with an error, keeping the results of the rows already imported
+func (s *service) fail(ctx context.Context, id uuid.UUID, cause error) error {
+	s.log.Error("Data import failed", "id", id, "error", cause)
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE data_imports SET status = 'failed', error = $2, content = NULL, finished_at = NOW()
+		WHERE id = $1`, id, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to record import failure: %w", err)
+	}
+	return nil
+}
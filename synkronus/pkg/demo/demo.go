@@ -0,0 +1,288 @@
+// Package demo provisions a sandbox for trainings and workshops: a sample app bundle, demo users
+// in a small org unit hierarchy and synthetic observations generated from the form schemas.
+package demo
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+	"github.com/opendataensemble/synkronus/pkg/seed"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// ClientID is the client ID synthetic observations are pushed with
+const ClientID = "demo-generator"
+
+// batchSize is the number of observations pushed at once
+const batchSize = 100
+
+//go:embed all:bundle
+var bundleFiles embed.FS
+
+// Config configures the sandbox
+type Config struct {
+	// Observations is the number of synthetic observations generated into an empty database
+	Observations int
+	// Password is the password of every demo user
+	Password string
+	// Seed makes the generated observations reproducible; zero uses the current time
+	Seed int64
+}
+
+// Bundles is the part of the app bundle service the sandbox uses
+type Bundles interface {
+	GetVersions(ctx context.Context) ([]string, error)
+	PushBundle(ctx context.Context, zipReader io.Reader, signature string) (*appbundle.Manifest, error)
+	SwitchVersion(ctx context.Context, version string) error
+	GetManifest(ctx context.Context) (*appbundle.Manifest, error)
+	GetFile(ctx context.Context, path string) (io.ReadCloser, *appbundle.File, error)
+}
+
+// Observations is the part of the sync service the sandbox uses
+type Observations interface {
+	GetRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *sync.SyncPullCursor) (*sync.SyncResult, error)
+	ProcessPushedRecords(ctx context.Context, records []sync.Observation, clientID string, transmissionID string) (*sync.SyncPushResult, error)
+}
+
+// Result tells what provisioning created
+type Result struct {
+	BundleVersion string
+	Seed          *seed.Result
+	Observations  int
+}
+
+// Provisioner sets up the sandbox
+type Provisioner struct {
+	bundles      Bundles
+	observations Observations
+	orgUnits     orgunit.Service
+	seeder       *seed.Seeder
+	config       Config
+	log          *logger.Logger
+}
+
+// NewProvisioner creates a new provisioner
+func NewProvisioner(bundles Bundles, observations Observations, orgUnits orgunit.Service, seeder *seed.Seeder, config Config, log *logger.Logger) *Provisioner {
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	return &Provisioner{bundles: bundles, observations: observations, orgUnits: orgUnits, seeder: seeder, config: config, log: log}
+}
+
+// Users returns the seed of the demo org units and users
+func Users(password string) *seed.Seed {
+	users := []seed.User{
+		{Username: "demo-admin", Password: password, Role: models.RoleAdmin},
+		{Username: "demo-supervisor", Password: password, Role: models.RoleReadOnly, OrgUnits: []string{"demo-arusha"}},
+	}
+	districts := []string{"demo-arumeru", "demo-monduli", "demo-meru"}
+	for i := 0; i < 6; i++ {
+		users = append(users, seed.User{
+			Username: fmt.Sprintf("demo-enumerator%d", i+1),
+			Password: password,
+			Role:     models.RoleReadWrite,
+			OrgUnits: []string{districts[i%len(districts)]},
+		})
+	}
+	return &seed.Seed{
+		OrgUnits: []seed.OrgUnit{
+			{Code: "demo-arusha", Name: "Arusha", Level: "region"},
+			{Code: "demo-arumeru", Name: "Arumeru", Level: "district", Parent: "demo-arusha"},
+			{Code: "demo-monduli", Name: "Monduli", Level: "district", Parent: "demo-arusha"},
+			{Code: "demo-meru", Name: "Meru", Level: "district", Parent: "demo-arusha"},
+		},
+		Users: users,
+	}
+}
+
+// Provision pushes the sample app bundle when the server has none, creates the demo users and
+// generates observations when the database has none. A restarted sandbox keeps its data.
+func (p *Provisioner) Provision(ctx context.Context) (*Result, error) {
+	result := &Result{}
+
+	versions, err := p.bundles.GetVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app bundle versions: %w", err)
+	}
+	if len(versions) == 0 {
+		bundle, err := Bundle()
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := p.bundles.PushBundle(ctx, bytes.NewReader(bundle), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to push the demo app bundle: %w", err)
+		}
+		if err := p.bundles.SwitchVersion(ctx, manifest.Version); err != nil {
+			return nil, fmt.Errorf("failed to switch to the demo app bundle: %w", err)
+		}
+		result.BundleVersion = manifest.Version
+	}
+
+	users := Users(p.config.Password)
+	if result.Seed, err = p.seeder.Apply(ctx, users); err != nil {
+		return nil, fmt.Errorf("failed to create demo users: %w", err)
+	}
+
+	existing, err := p.observations.GetRecordsSinceVersion(ctx, 0, ClientID, nil, 1, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for observations: %w", err)
+	}
+	if len(existing.Records) > 0 || p.config.Observations <= 0 {
+		p.log.Info("Demo sandbox ready", "bundleVersion", result.BundleVersion, "users", result.Seed.Users)
+		return result, nil
+	}
+	if result.Observations, err = p.generate(ctx, users); err != nil {
+		return nil, err
+	}
+	p.log.Info("Demo sandbox ready", "bundleVersion", result.BundleVersion, "users", result.Seed.Users, "observations", result.Observations)
+	return result, nil
+}
+
+// generate pushes synthetic observations of every form of the current app bundle, each by one
+// of the enumerators in one of their org units
+func (p *Provisioner) generate(ctx context.Context, users *seed.Seed) (int, error) {
+	manifest, err := p.bundles.GetManifest(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the app bundle manifest: %w", err)
+	}
+	forms := make(map[string]map[string]any)
+	var formTypes []string
+	for _, file := range manifest.Files {
+		parts := strings.Split(file.Path, "/")
+		if len(parts) != 3 || parts[0] != "forms" || parts[2] != "schema.json" {
+			continue
+		}
+		schema, err := p.schema(ctx, file.Path)
+		if err != nil {
+			return 0, err
+		}
+		forms[parts[1]] = schema
+		formTypes = append(formTypes, parts[1])
+	}
+	if len(formTypes) == 0 {
+		p.log.Warn("The app bundle has no forms; no demo observations generated")
+		return 0, nil
+	}
+
+	units, err := p.orgUnits.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list org units: %w", err)
+	}
+	unitIDs := make(map[string]string, len(units))
+	for _, unit := range units {
+		if unit.Code != nil {
+			unitIDs[*unit.Code] = unit.ID
+		}
+	}
+	var enumerators []seed.User
+	for _, user := range users.Users {
+		if user.Role == models.RoleReadWrite {
+			enumerators = append(enumerators, user)
+		}
+	}
+
+	generator := NewGenerator(p.config.Seed, time.Now())
+	ids := rand.New(rand.NewSource(p.config.Seed))
+	byUser := make(map[string][]sync.Observation)
+	for i := 0; i < p.config.Observations; i++ {
+		formType := formTypes[i%len(formTypes)]
+		user := enumerators[i%len(enumerators)]
+		id, err := uuid.NewRandomFromReader(ids)
+		if err != nil {
+			return 0, err
+		}
+		data, err := json.Marshal(generator.Data(forms[formType]))
+		if err != nil {
+			return 0, err
+		}
+		createdAt := generator.Time().UTC().Format(time.RFC3339)
+		observation := sync.Observation{
+			ObservationID: id.String(),
+			FormType:      formType,
+			FormVersion:   manifest.Version,
+			Data:          data,
+			CreatedAt:     createdAt,
+			UpdatedAt:     createdAt,
+		}
+		if len(user.OrgUnits) > 0 {
+			if unitID, ok := unitIDs[user.OrgUnits[0]]; ok {
+				observation.OrgUnitID = &unitID
+			}
+		}
+		byUser[user.Username] = append(byUser[user.Username], observation)
+	}
+
+	// Each enumerator pushes their own observations, so they own them
+	pushed := 0
+	for _, user := range enumerators {
+		records := byUser[user.Username]
+		userCtx := sync.WithUsername(ctx, user.Username)
+		for start := 0; start < len(records); start += batchSize {
+			end := min(start+batchSize, len(records))
+			transmissionID := fmt.Sprintf("demo-%s-%d", user.Username, start)
+			outcome, err := p.observations.ProcessPushedRecords(userCtx, records[start:end], ClientID, transmissionID)
+			if err != nil {
+				return pushed, fmt.Errorf("failed to push demo observations: %w", err)
+			}
+			pushed += outcome.SuccessCount
+		}
+	}
+	return pushed, nil
+}
+
+// schema reads a form schema of the current app bundle
+func (p *Provisioner) schema(ctx context.Context, path string) (map[string]any, error) {
+	file, _, err := p.bundles.GetFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+	var schema map[string]any
+	if err := json.NewDecoder(file).Decode(&schema); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// Bundle returns the sample app bundle as a ZIP
+func Bundle() ([]byte, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	err := fs.WalkDir(bundleFiles, "bundle", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := bundleFiles.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dst, err := zipWriter.Create(strings.TrimPrefix(path, "bundle/"))
+		if err != nil {
+			return err
+		}
+		_, err = dst.Write(content)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the demo app bundle: %w", err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,88 @@
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/seed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorData(t *testing.T) {
+	var schema map[string]any
+	content, err := bundleFiles.ReadFile("bundle/forms/household/schema.json")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, &schema))
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	first := NewGenerator(42, now).Data(schema)
+	assert.Equal(t, first, NewGenerator(42, now).Data(schema), "the same seed yields the same data")
+
+	for _, field := range []string{"head_name", "village", "interview_date", "household_size", "water_source"} {
+		assert.Contains(t, first, field, "required fields are always filled")
+	}
+	assert.Contains(t, villages, first["village"])
+	size := first["household_size"].(int64)
+	assert.True(t, size >= 1 && size <= 15)
+	date, err := time.Parse("2006-01-02", first["interview_date"].(string))
+	require.NoError(t, err)
+	assert.True(t, date.After(now.AddDate(0, 0, -91)) && !date.After(now))
+	assert.Contains(t, []any{"piped", "borehole", "protected_well", "unprotected_well", "river", "rainwater"}, first["water_source"])
+
+	// Server-assigned fields are left to the server
+	data := NewGenerator(1, now).Data(map[string]any{
+		"type":     "object",
+		"required": []any{"code", "name"},
+		"properties": map[string]any{
+			"code": map[string]any{"type": "string", "x-server-assigned": "sequence"},
+			"name": map[string]any{"type": "string", "maxLength": float64(4)},
+		},
+	})
+	assert.NotContains(t, data, "code")
+	assert.LessOrEqual(t, len(data["name"].(string)), 4)
+}
+
+func TestProvision(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewLogger()
+	dir := t.TempDir()
+	bundleConfig := appbundle.DefaultConfig()
+	bundleConfig.BundlePath = filepath.Join(dir, "bundle")
+	bundleConfig.VersionsPath = filepath.Join(dir, "versions")
+	bundles := appbundle.NewService(bundleConfig, log)
+	require.NoError(t, bundles.Initialize(ctx))
+
+	observations := mocks.NewMockSyncService()
+	require.NoError(t, observations.Initialize(ctx))
+	orgUnits := mocks.NewMockOrgUnitService()
+	seeder := seed.NewSeeder(mocks.NewMockUserService(), orgUnits, mocks.NewMockSettingsService(), log)
+	provisioner := NewProvisioner(bundles, observations, orgUnits, seeder, Config{Observations: 120, Password: "demo", Seed: 7}, log)
+
+	result, err := provisioner.Provision(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.BundleVersion)
+	assert.Equal(t, 8, result.Seed.Users)
+	assert.Equal(t, 120, result.Observations)
+
+	manifest, err := bundles.GetManifest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, result.BundleVersion, manifest.Version)
+	pulled, err := observations.GetRecordsSinceVersion(ctx, 0, "trainer-tablet", nil, 500, nil)
+	require.NoError(t, err)
+	require.Len(t, pulled.Records, 120)
+	assert.NotNil(t, pulled.Records[0].OrgUnitID)
+
+	// A restarted sandbox keeps its bundle and data
+	result, err = provisioner.Provision(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.BundleVersion)
+	assert.Zero(t, result.Seed.Users)
+	assert.Zero(t, result.Observations)
+}
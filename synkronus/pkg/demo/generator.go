@@ -0,0 +1,243 @@
+package demo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	firstNames = []string{"Amina", "Baraka", "Neema", "Juma", "Rehema", "Daudi", "Zawadi", "Hamisi", "Upendo", "Salim",
+		"Halima", "Emmanuel", "Grace", "Joseph", "Mwanaidi", "Peter", "Fatuma", "John", "Esther", "Khamis"}
+	lastNames = []string{"Mwakalinga", "Kimaro", "Mushi", "Njau", "Swai", "Massawe", "Lyimo", "Mollel", "Laizer", "Kweka",
+		"Temba", "Shirima", "Urio", "Minja", "Kileo", "Mbwambo"}
+	villages = []string{"Ngaramtoni", "Oldonyosambu", "Mlangarini", "Kiranyi", "Bangata", "Olturumet", "Sokon II",
+		"Oljoro", "Mateves", "Kimnyaki", "Nduruma", "Mwandeti"}
+	notes = []string{"Household head was away, spouse answered.", "Follow-up visit needed next month.",
+		"Respondent asked about the water project.", "Roof damaged in the last rains.", "No issues reported.",
+		"Neighbour helped with translation."}
+)
+
+// Generator fills form data from JSON schemas with plausible values. Field names pick the kind
+// of value where the schema leaves it open, e.g. a string field named village gets a village.
+type Generator struct {
+	rng *rand.Rand
+	now time.Time
+	// Days is how far back generated dates go
+	Days int
+}
+
+// NewGenerator creates a generator; the same seed and time yield the same values
+func NewGenerator(seed int64, now time.Time) *Generator {
+	return &Generator{rng: rand.New(rand.NewSource(seed)), now: now, Days: 90}
+}
+
+// Data generates the data of a form from its schema. Required fields are always filled and
+// other fields most of the time; fields the server assigns are left out.
+func (g *Generator) Data(schema map[string]any) map[string]any {
+	data, _ := g.value("", schema).(map[string]any)
+	if data == nil {
+		data = map[string]any{}
+	}
+	return data
+}
+
+// Time returns a time within the generator's period
+func (g *Generator) Time() time.Time {
+	return g.now.Add(-time.Duration(g.rng.Int63n(int64(g.Days) * int64(24*time.Hour))))
+}
+
+// value generates a value of a schema; name is the field's property name
+func (g *Generator) value(name string, schema map[string]any) any {
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[g.rng.Intn(len(enum))]
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return g.object(schema)
+	case "array":
+		return g.array(name, schema)
+	case "integer":
+		low, high := bounds(name, schema)
+		return int64(low) + g.rng.Int63n(int64(high-low)+1)
+	case "number":
+		low, high := bounds(name, schema)
+		return math.Round((low+g.rng.Float64()*(high-low))*10) / 10
+	case "boolean":
+		return g.rng.Intn(2) == 0
+	case "string":
+		return g.text(name, schema)
+	}
+	return nil
+}
+
+// object fills the properties of an object schema in name order, so values are reproducible
+func (g *Generator) object(schema map[string]any) map[string]any {
+	properties, _ := schema["properties"].(map[string]any)
+	required := make(map[string]bool)
+	if names, ok := schema["required"].([]any); ok {
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := make(map[string]any, len(properties))
+	for _, name := range names {
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, assigned := property["x-server-assigned"]; assigned {
+			continue
+		}
+		if readOnly, _ := property["readOnly"].(bool); readOnly {
+			continue
+		}
+		if !required[name] && g.rng.Intn(100) >= 80 {
+			continue
+		}
+		if value := g.value(name, property); value != nil {
+			data[name] = value
+		}
+	}
+	return data
+}
+
+// array generates between minItems and maxItems items, distinct for enums
+func (g *Generator) array(name string, schema map[string]any) []any {
+	items, _ := schema["items"].(map[string]any)
+	if items == nil {
+		return []any{}
+	}
+	low, high := 1, 3
+	if n, ok := number(schema["minItems"]); ok {
+		low = int(n)
+	}
+	if n, ok := number(schema["maxItems"]); ok {
+		high = int(n)
+	}
+	if high < low {
+		high = low
+	}
+	count := low + g.rng.Intn(high-low+1)
+
+	if enum, ok := items["enum"].([]any); ok {
+		picked := make([]any, 0, count)
+		for _, i := range g.rng.Perm(len(enum)) {
+			if len(picked) == count {
+				break
+			}
+			picked = append(picked, enum[i])
+		}
+		return picked
+	}
+	values := make([]any, 0, count)
+	for i := 0; i < count; i++ {
+		values = append(values, g.value(name, items))
+	}
+	return values
+}
+
+// text generates a string by format, then by field name, within minLength and maxLength
+func (g *Generator) text(name string, schema map[string]any) string {
+	name = strings.ToLower(name)
+	format, _ := schema["format"].(string)
+
+	var text string
+	switch {
+	case format == "date" && strings.Contains(name, "birth"):
+		text = g.now.AddDate(-18-g.rng.Intn(60), 0, -g.rng.Intn(365)).Format("2006-01-02")
+	case format == "date":
+		text = g.Time().Format("2006-01-02")
+	case format == "date-time":
+		text = g.Time().UTC().Format(time.RFC3339)
+	case format == "time":
+		text = fmt.Sprintf("%02d:%02d", 7+g.rng.Intn(11), g.rng.Intn(60))
+	case format == "email" || strings.Contains(name, "email"):
+		text = strings.ToLower(g.pick(firstNames) + "." + g.pick(lastNames) + "@example.org")
+	case strings.Contains(name, "phone") || strings.Contains(name, "mobile"):
+		text = fmt.Sprintf("+2557%08d", g.rng.Intn(100000000))
+	case strings.Contains(name, "village") || strings.Contains(name, "community") || strings.Contains(name, "location"):
+		text = g.pick(villages)
+	case strings.Contains(name, "first"):
+		text = g.pick(firstNames)
+	case strings.Contains(name, "last") || strings.Contains(name, "surname"):
+		text = g.pick(lastNames)
+	case strings.Contains(name, "name"):
+		text = g.pick(firstNames) + " " + g.pick(lastNames)
+	default:
+		text = g.pick(notes)
+	}
+
+	if n, ok := number(schema["maxLength"]); ok && len(text) > int(n) {
+		text = text[:int(n)]
+	}
+	if n, ok := number(schema["minLength"]); ok {
+		for len(text) < int(n) {
+			text += "x"
+		}
+	}
+	return text
+}
+
+// pick returns a random item of a list
+func (g *Generator) pick(values []string) string {
+	return values[g.rng.Intn(len(values))]
+}
+
+// schemaType returns the type of a schema, the first non-null one of a type list
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if item, ok := item.(string); ok && item != "null" {
+				return item
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// bounds returns the range of a numeric field: the schema's, or one fitting the field name
+func bounds(name string, schema map[string]any) (float64, float64) {
+	low, high := 0.0, 100.0
+	if strings.Contains(strings.ToLower(name), "age") {
+		high = 90
+	}
+	if n, ok := number(schema["minimum"]); ok {
+		low = n
+	}
+	if n, ok := number(schema["maximum"]); ok {
+		high = n
+	}
+	if high < low {
+		high = low
+	}
+	return low, high
+}
+
+// number converts a decoded JSON number
+func number(value any) (float64, bool) {
+	n, ok := value.(float64)
+	return n, ok
+}
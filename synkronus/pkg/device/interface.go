@@ -0,0 +1,121 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Statuses of a registered client
+const (
+	// StatusActive clients pull and push as usual; clients are active when first seen
+	StatusActive = "active"
+	// StatusSuspended clients keep pulling but their pushes are rejected, e.g. while a device is
+	// checked after reports of bad data
+	StatusSuspended = "suspended"
+	// StatusBlocked clients are refused pulls and pushes, e.g. lost or stolen devices
+	StatusBlocked = "blocked"
+)
+
+// Sync operations recorded as a client's last sync
+const (
+	SyncPull = "pull"
+	SyncPush = "push"
+)
+
+// Common errors
+var (
+	// ErrNotFound is returned when no client with the ID has synced
+	ErrNotFound = errors.New("client not found")
+	// ErrInvalidStatus is returned when a status is not active, suspended or blocked
+	ErrInvalidStatus = errors.New("invalid client status")
+	// ErrInvalidLabel is returned when a label is too long
+	ErrInvalidLabel = errors.New("invalid client label")
+)
+
+// maxLabelLength matches the label column of the clients table
+const maxLabelLength = 255
+
+// Device is a client registered by its client_id when it first synced
+type Device struct {
+	ClientID        string     `json:"client_id"`
+	Label           string     `json:"label"`
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedBy string     `json:"status_changed_by,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	FirstSeenAt     time.Time  `json:"first_seen_at"`
+	LastSeenAt      time.Time  `json:"last_seen_at"`
+	LastPullAt      *time.Time `json:"last_pull_at,omitempty"`
+	LastPushAt      *time.Time `json:"last_push_at,omitempty"`
+	// LastVersion is the current version the server reported to the client in its last sync
+	LastVersion  int64  `json:"last_version"`
+	LastUsername string `json:"last_username"`
+}
+
+// CanPull reports whether the client may pull
+func (d *Device) CanPull() bool {
+	return d.Status != StatusBlocked
+}
+
+// CanPush reports whether the client may push
+func (d *Device) CanPush() bool {
+	return d.Status == StatusActive
+}
+
+// Sync is a pull or push a client made
+type Sync struct {
+	ClientID  string
+	Operation string
+	Username  string
+	Version   int64
+}
+
+// Update changes the label or status of a client; nil fields are left unchanged
+type Update struct {
+	Label  *string `json:"label,omitempty"`
+	Status *string `json:"status,omitempty"`
+	// Reason is kept with a status change, e.g. "reported stolen"
+	Reason string `json:"reason,omitempty"`
+}
+
+// Filter narrows a client listing
+type Filter struct {
+	// Status lists only clients with the status; empty lists all
+	Status string
+}
+
+// Service keeps the registry of clients seen in sync
+type Service interface {
+	// Get returns a client, or ErrNotFound when it has not synced
+	Get(ctx context.Context, clientID string) (*Device, error)
+
+	// RecordSync registers a client when it is first seen and records its last sync
+	RecordSync(ctx context.Context, sync Sync) error
+
+	// List returns the clients matching a filter, most recently seen first
+	List(ctx context.Context, filter Filter) ([]Device, error)
+
+	// Update labels a client or changes its status, and returns the updated client
+	Update(ctx context.Context, clientID string, update Update, updatedBy string) (*Device, error)
+}
+
+// Validate checks an update before it is applied
+func (u Update) Validate() error {
+	if u.Label != nil && len(*u.Label) > maxLabelLength {
+		return ErrInvalidLabel
+	}
+	if u.Status != nil {
+		return ValidateStatus(*u.Status)
+	}
+	return nil
+}
+
+// ValidateStatus checks a client status
+func ValidateStatus(status string) error {
+	switch status {
+	case StatusActive, StatusSuspended, StatusBlocked:
+		return nil
+	}
+	return ErrInvalidStatus
+}
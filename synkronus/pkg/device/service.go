@@ -0,0 +1,134 @@
+package device
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// columns are the clients columns scanned by scanDevice
+const columns = `client_id, label, status, status_reason, status_changed_by, status_changed_at,
+	first_seen_at, last_seen_at, last_pull_at, last_push_at, last_version, last_username`
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new client registry service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Get returns a client, or ErrNotFound when it has not synced
+func (s *service) Get(ctx context.Context, clientID string) (*Device, error) {
+	d, err := scanDevice(s.db.QueryRowContext(ctx, "SELECT "+columns+" FROM clients WHERE client_id = $1", clientID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	return d, nil
+}
+
+// RecordSync registers a client when it is first seen and records its last sync
+func (s *service) RecordSync(ctx context.Context, sync Sync) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO clients (client_id, last_username, last_version, last_pull_at, last_push_at)
+		VALUES ($1, $2, $3, CASE WHEN $4 = 'pull' THEN NOW() END, CASE WHEN $4 = 'push' THEN NOW() END)
+		ON CONFLICT (client_id) DO UPDATE
+		SET last_seen_at = NOW(), last_username = EXCLUDED.last_username, last_version = EXCLUDED.last_version,
+		    last_pull_at = COALESCE(EXCLUDED.last_pull_at, clients.last_pull_at),
+		    last_push_at = COALESCE(EXCLUDED.last_push_at, clients.last_push_at)`,
+		sync.ClientID, sync.Username, sync.Version, sync.Operation)
+	if err != nil {
+		return fmt.Errorf("failed to record client sync: %w", err)
+	}
+	return nil
+}
+
+// List returns the clients matching a filter, most recently seen first
+func (s *service) List(ctx context.Context, filter Filter) ([]Device, error) {
+	query := "SELECT " + columns + " FROM clients"
+	var args []any
+	if filter.Status != "" {
+		query += " WHERE status = $1"
+		args = append(args, filter.Status)
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY last_seen_at DESC, client_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients: %w", err)
+	}
+	defer rows.Close()
+
+	devices := make([]Device, 0)
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		devices = append(devices, *d)
+	}
+	return devices, rows.Err()
+}
+
+// Update labels a client or changes its status, and returns the updated client
+func (s *service) Update(ctx context.Context, clientID string, update Update, updatedBy string) (*Device, error) {
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	args := []any{clientID}
+	var sets []string
+	if update.Label != nil {
+		args = append(args, *update.Label)
+		sets = append(sets, fmt.Sprintf("label = $%d", len(args)))
+	}
+	if update.Status != nil {
+		args = append(args, *update.Status, update.Reason, updatedBy)
+		sets = append(sets, fmt.Sprintf("status = $%d, status_reason = $%d, status_changed_by = $%d, status_changed_at = NOW()",
+			len(args)-2, len(args)-1, len(args)))
+	}
+	if len(sets) == 0 {
+		return s.Get(ctx, clientID)
+	}
+
+	d, err := scanDevice(s.db.QueryRowContext(ctx,
+		"UPDATE clients SET "+strings.Join(sets, ", ")+" WHERE client_id = $1 RETURNING "+columns, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update client: %w", err)
+	}
+
+	if update.Status != nil {
+		s.log.Info("Client status changed", "clientId", clientID, "status", d.Status, "reason", d.StatusReason, "updatedBy", updatedBy)
+	}
+	return d, nil
+}
+
+// scanDevice reads a client selected with columns
+func scanDevice(row interface{ Scan(...any) error }) (*Device, error) {
+	var d Device
+	var changedAt, pullAt, pushAt sql.NullTime
+	if err := row.Scan(&d.ClientID, &d.Label, &d.Status, &d.StatusReason, &d.StatusChangedBy, &changedAt,
+		&d.FirstSeenAt, &d.LastSeenAt, &pullAt, &pushAt, &d.LastVersion, &d.LastUsername); err != nil {
+		return nil, err
+	}
+	if changedAt.Valid {
+		d.StatusChangedAt = &changedAt.Time
+	}
+	if pullAt.Valid {
+		d.LastPullAt = &pullAt.Time
+	}
+	if pushAt.Valid {
+		d.LastPushAt = &pushAt.Time
+	}
+	return &d, nil
+}
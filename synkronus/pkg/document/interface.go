@@ -0,0 +1,97 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Common errors
+var (
+	// ErrDocumentNotFound is returned when a document does not exist or was removed
+	ErrDocumentNotFound = errors.New("document not found")
+	// ErrObservationNotFound is returned when attaching to an observation that does not exist
+	ErrObservationNotFound = errors.New("observation not found")
+	// ErrTypeNotAllowed is returned when the uploaded content is not of an allowed type
+	ErrTypeNotAllowed = errors.New("document type not allowed")
+	// ErrTooLarge is returned when the uploaded content exceeds the size limit
+	ErrTooLarge = errors.New("document too large")
+)
+
+// Audit actions recorded for supporting documents
+const (
+	ActionAttach   = "attach"
+	ActionDownload = "download"
+	ActionRemove   = "remove"
+)
+
+// Document is a supporting document (e.g. a consent scan or correction memo) attached to an
+// observation by office staff. Documents are kept apart from client-collected attachments and
+// never sync to devices.
+type Document struct {
+	ID            string  `json:"id" db:"id"`
+	ObservationID string  `json:"observation_id" db:"observation_id"`
+	Filename      string  `json:"filename" db:"filename"`
+	ContentType   string  `json:"content_type" db:"content_type"`
+	Size          int64   `json:"size" db:"size"`
+	SHA256        string  `json:"sha256" db:"sha256"`
+	Description   *string `json:"description,omitempty" db:"description"`
+	UploadedBy    string  `json:"uploaded_by" db:"uploaded_by"`
+	UploadedAt    string  `json:"uploaded_at" db:"uploaded_at"`
+}
+
+// AuditEntry records an action taken on a supporting document
+type AuditEntry struct {
+	ID            int64  `json:"id" db:"id"`
+	DocumentID    string `json:"document_id" db:"document_id"`
+	ObservationID string `json:"observation_id" db:"observation_id"`
+	Action        string `json:"action" db:"action"`
+	Username      string `json:"username" db:"username"`
+	At            string `json:"at" db:"at"`
+}
+
+// Upload holds the content and metadata of a document to attach
+type Upload struct {
+	Filename    string
+	Description *string
+	Content     io.Reader
+}
+
+// Config contains supporting document storage configuration
+type Config struct {
+	// StoragePath is the directory document files are stored in
+	StoragePath string
+
+	// AllowedTypes lists the content types accepted, detected from the uploaded bytes
+	AllowedTypes []string
+
+	// MaxSize is the largest accepted document in bytes
+	MaxSize int64
+}
+
+// DefaultConfig returns a default configuration accepting PDFs and scanned images up to 20 MB
+func DefaultConfig() Config {
+	return Config{
+		StoragePath:  "documents",
+		AllowedTypes: []string{"application/pdf", "image/jpeg", "image/png"},
+		MaxSize:      20 << 20,
+	}
+}
+
+// Service manages supporting documents attached to observations
+type Service interface {
+	// List returns the documents attached to an observation, oldest first
+	List(ctx context.Context, observationID string) ([]Document, error)
+
+	// Attach stores a document and attaches it to an observation
+	Attach(ctx context.Context, observationID string, upload Upload, username string) (*Document, error)
+
+	// Open returns a document and its content, recording the download
+	Open(ctx context.Context, observationID, documentID, username string) (*Document, io.ReadCloser, error)
+
+	// Remove detaches a document from an observation; its file and audit trail are kept
+	Remove(ctx context.Context, observationID, documentID, username string) error
+
+	// ListAudit returns the audit trail of an observation's documents, oldest first
+	ListAudit(ctx context.Context, observationID string) ([]AuditEntry, error)
+}
@@ -0,0 +1,324 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// documentColumns lists the columns selected for a Document in scan order
+const documentColumns = "id, observation_id, filename, content_type, size, sha256, description, uploaded_by, uploaded_at"
+
+type service struct {
+	db     *sql.DB
+	config Config
+	log    *logger.Logger
+}
+
+// NewService creates a new supporting document service, creating the storage directory if needed
+func NewService(db *sql.DB, config Config, log *logger.Logger) (Service, error) {
+	if err := os.MkdirAll(config.StoragePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create document storage: %w", err)
+	}
+
+	// Tolerate "application/pdf, image/png" style lists from the environment
+	allowed := make([]string, 0, len(config.AllowedTypes))
+	for _, t := range config.AllowedTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			allowed = append(allowed, t)
+		}
+	}
+	config.AllowedTypes = allowed
+
+	return &service{db: db, config: config, log: log}, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDocument(row rowScanner) (*Document, error) {
+	var d Document
+	if err := row.Scan(&d.ID, &d.ObservationID, &d.Filename, &d.ContentType, &d.Size, &d.SHA256, &d.Description, &d.UploadedBy, &d.UploadedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// path returns the storage location of a document file
+func (s *service) path(documentID string) string {
+	return filepath.Join(s.config.StoragePath, documentID)
+}
+
+// List returns the documents attached to an observation, oldest first
+func (s *service) List(ctx context.Context, observationID string) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+documentColumns+" FROM observation_documents WHERE observation_id = $1 AND removed_at IS NULL ORDER BY uploaded_at, id",
+		observationID)
+	if err != nil {
+		s.log.Error("Failed to query documents", "error", err, "observationId", observationID)
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]Document, 0)
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return documents, nil
+}
+
+// Attach stores a document and attaches it to an observation. The content type is detected from
+// the uploaded bytes rather than trusted from the client.
+func (s *service) Attach(ctx context.Context, observationID string, upload Upload, username string) (*Document, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM observations WHERE observation_id = $1)", observationID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check observation: %w", err)
+	}
+	if !exists {
+		return nil, ErrObservationNotFound
+	}
+
+	// Sniff the content type from the first bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	head = head[:n]
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if !slices.Contains(s.config.AllowedTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	// Write to a temporary file first so a failed upload never leaves a partial document
+	id := uuid.New().String()
+	tmp, err := os.CreateTemp(s.config.StoragePath, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	content := io.MultiReader(bytes.NewReader(head), upload.Content)
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(content, s.config.MaxSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+	if size > s.config.MaxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, s.config.MaxSize)
+	}
+	if err := os.Rename(tmp.Name(), s.path(id)); err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+
+	filename := filepath.Base(upload.Filename)
+	if filename == "." || filename == string(filepath.Separator) {
+		filename = id
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		os.Remove(s.path(id))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+			os.Remove(s.path(id))
+		}
+	}()
+
+	doc, err := scanDocument(tx.QueryRowContext(ctx, `
+		INSERT INTO observation_documents (id, observation_id, filename, content_type, size, sha256, description, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+documentColumns,
+		id, observationID, filename, contentType, size, hex.EncodeToString(hash.Sum(nil)), upload.Description, username))
+	if err != nil {
+		s.log.Error("Failed to insert document", "error", err, "observationId", observationID)
+		return nil, fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	if err := recordAudit(ctx, tx, doc.ID, observationID, ActionAttach, username); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Document attached",
+		"documentId", doc.ID,
+		"observationId", observationID,
+		"contentType", contentType,
+		"size", size,
+		"user", username)
+
+	return doc, nil
+}
+
+// Open returns a document and its content, recording the download
+func (s *service) Open(ctx context.Context, observationID, documentID, username string) (*Document, io.ReadCloser, error) {
+	doc, err := s.get(ctx, observationID, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(s.path(doc.ID))
+	if err != nil {
+		s.log.Error("Failed to open document file", "error", err, "documentId", doc.ID)
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+
+	if err := recordAudit(ctx, s.db, doc.ID, observationID, ActionDownload, username); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return doc, file, nil
+}
+
+// Remove detaches a document from an observation; its file and audit trail are kept
+func (s *service) Remove(ctx context.Context, observationID, documentID, username string) error {
+	if _, err := uuid.Parse(documentID); err != nil {
+		return ErrDocumentNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE observation_documents SET removed_by = $3, removed_at = NOW()
+		WHERE id = $1 AND observation_id = $2 AND removed_at IS NULL`,
+		documentID, observationID, username)
+	if err != nil {
+		s.log.Error("Failed to remove document", "error", err, "documentId", documentID)
+		return fmt.Errorf("failed to remove document: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrDocumentNotFound
+	}
+
+	if err := recordAudit(ctx, tx, documentID, observationID, ActionRemove, username); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Document removed", "documentId", documentID, "observationId", observationID, "user", username)
+	return nil
+}
+
+// ListAudit returns the audit trail of an observation's documents, oldest first
+func (s *service) ListAudit(ctx context.Context, observationID string) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, document_id, observation_id, action, username, at
+		FROM observation_document_audit
+		WHERE observation_id = $1
+		ORDER BY id`, observationID)
+	if err != nil {
+		s.log.Error("Failed to query document audit", "error", err, "observationId", observationID)
+		return nil, fmt.Errorf("failed to query document audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.DocumentID, &e.ObservationID, &e.Action, &e.Username, &e.At); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// get returns an attached document of an observation
+func (s *service) get(ctx context.Context, observationID, documentID string) (*Document, error) {
+	if _, err := uuid.Parse(documentID); err != nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	doc, err := scanDocument(s.db.QueryRowContext(ctx,
+		"SELECT "+documentColumns+" FROM observation_documents WHERE id = $1 AND observation_id = $2 AND removed_at IS NULL",
+		documentID, observationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return doc, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// recordAudit appends an entry to the document audit trail
+func recordAudit(ctx context.Context, db execer, documentID, observationID, action, username string) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO observation_document_audit (document_id, observation_id, action, username)
+		VALUES ($1, $2, $3, $4)`,
+		documentID, observationID, action, username)
+	if err != nil {
+		return fmt.Errorf("failed to record document audit: %w", err)
+	}
+	return nil
+}
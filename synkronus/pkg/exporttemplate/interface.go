@@ -0,0 +1,128 @@
+package exporttemplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+)
+
+// Common errors
+var (
+	// ErrTemplateNotFound is returned when an export template does not exist
+	ErrTemplateNotFound = errors.New("export template not found")
+	// ErrInvalidTemplate is returned when a template definition is not valid
+	ErrInvalidTemplate = errors.New("invalid export template")
+)
+
+// FormatParquet is a ZIP archive with a Parquet file per form type, as served by
+// /dataexport/parquet
+const FormatParquet = "parquet"
+
+// namePattern restricts template names to short, URL safe identifiers
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+
+// columnPattern matches the form data columns of an export
+var columnPattern = regexp.MustCompile(`^data_[A-Za-z0-9_]+$`)
+
+// ValidName reports whether name can be used as a template name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Definition is what a template exports and how
+type Definition struct {
+	// Format is the export format; only parquet is supported and it is the default
+	Format string `json:"format"`
+	// FormTypes limits the export to these form types; all when empty
+	FormTypes []string `json:"form_types,omitempty"`
+	// OrgUnitID limits the export to an org unit and its descendants
+	OrgUnitID string `json:"org_unit_id,omitempty"`
+	// Columns limits the form data columns to these, e.g. data_household_size; all when empty
+	Columns []string `json:"columns,omitempty"`
+	// Masks is the masking profile: form data columns redacted or hashed before delivery
+	Masks []dataexport.ColumnMask `json:"masks,omitempty"`
+}
+
+// Validate checks a definition, filling in the default format
+func (d *Definition) Validate() error {
+	if d.Format == "" {
+		d.Format = FormatParquet
+	}
+	if d.Format != FormatParquet {
+		return fmt.Errorf("%w: format must be %q", ErrInvalidTemplate, FormatParquet)
+	}
+	for _, formType := range d.FormTypes {
+		if strings.TrimSpace(formType) == "" {
+			return fmt.Errorf("%w: form types cannot be empty", ErrInvalidTemplate)
+		}
+	}
+	if d.OrgUnitID != "" {
+		if _, err := uuid.Parse(d.OrgUnitID); err != nil {
+			return fmt.Errorf("%w: org_unit_id must be an org unit id", ErrInvalidTemplate)
+		}
+	}
+	for _, column := range d.Columns {
+		if !columnPattern.MatchString(column) {
+			return fmt.Errorf("%w: column %q must name a form data column such as data_age", ErrInvalidTemplate, column)
+		}
+	}
+	masked := make(map[string]bool, len(d.Masks))
+	for _, mask := range d.Masks {
+		if !columnPattern.MatchString(mask.Column) {
+			return fmt.Errorf("%w: masked column %q must name a form data column such as data_phone", ErrInvalidTemplate, mask.Column)
+		}
+		if mask.Method != dataexport.MaskRedact && mask.Method != dataexport.MaskHash {
+			return fmt.Errorf("%w: mask method must be %q or %q", ErrInvalidTemplate, dataexport.MaskRedact, dataexport.MaskHash)
+		}
+		if masked[mask.Column] {
+			return fmt.Errorf("%w: column %s is masked twice", ErrInvalidTemplate, mask.Column)
+		}
+		masked[mask.Column] = true
+	}
+	return nil
+}
+
+// Options returns the data export options of a definition with the given mask key
+func (d Definition) Options(maskKey []byte) *dataexport.ExportOptions {
+	return &dataexport.ExportOptions{
+		OrgUnitID: d.OrgUnitID,
+		FormTypes: d.FormTypes,
+		Columns:   d.Columns,
+		Masks:     d.Masks,
+		MaskKey:   maskKey,
+	}
+}
+
+// Template is a named export configuration admins set up once for a recurring deliverable
+type Template struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Definition  Definition `json:"definition"`
+	UpdatedBy   *string    `json:"updated_by,omitempty"`
+	CreatedAt   string     `json:"created_at"`
+	UpdatedAt   string     `json:"updated_at"`
+}
+
+// Service manages export templates
+type Service interface {
+	// List returns all templates ordered by name
+	List(ctx context.Context) ([]Template, error)
+
+	// Get returns a single template
+	Get(ctx context.Context, name string) (*Template, error)
+
+	// Save creates or replaces a template after validating its definition
+	Save(ctx context.Context, template Template, updatedBy string) (*Template, error)
+
+	// Delete removes a template
+	Delete(ctx context.Context, name string) error
+
+	// ExportOptions returns the data export options a template stands for, including the key
+	// its hashed columns are keyed with
+	ExportOptions(ctx context.Context, name string) (*dataexport.ExportOptions, error)
+}
@@ -0,0 +1,156 @@
+package exporttemplate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// maskKeyBytes is the size of the key hashed columns of a template are keyed with
+const maskKeyBytes = 32
+
+// templateColumns lists the columns selected for a Template in scan order
+const templateColumns = "name, description, definition, updated_by, created_at, updated_at"
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new export template service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTemplate(row rowScanner, extra ...any) (*Template, error) {
+	var t Template
+	var definition []byte
+	if err := row.Scan(append([]any{&t.Name, &t.Description, &definition, &t.UpdatedBy, &t.CreatedAt, &t.UpdatedAt}, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(definition, &t.Definition); err != nil {
+		return nil, fmt.Errorf("failed to decode definition of %s: %w", t.Name, err)
+	}
+	return &t, nil
+}
+
+// List returns all templates ordered by name
+func (s *service) List(ctx context.Context) ([]Template, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+templateColumns+" FROM export_templates ORDER BY name")
+	if err != nil {
+		s.log.Error("Failed to query export templates", "error", err)
+		return nil, fmt.Errorf("failed to query export templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]Template, 0)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return templates, nil
+}
+
+// Get returns a single template
+func (s *service) Get(ctx context.Context, name string) (*Template, error) {
+	t, err := scanTemplate(s.db.QueryRowContext(ctx, "SELECT "+templateColumns+" FROM export_templates WHERE name = $1", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		s.log.Error("Failed to get export template", "error", err, "name", name)
+		return nil, fmt.Errorf("failed to get export template: %w", err)
+	}
+	return t, nil
+}
+
+// Save creates or replaces a template after validating its definition. A replaced template
+// keeps its mask key, so hashed columns stay comparable across deliveries.
+func (s *service) Save(ctx context.Context, template Template, updatedBy string) (*Template, error) {
+	if !ValidName(template.Name) {
+		return nil, fmt.Errorf("%w: name must be lowercase letters, digits, '_' or '-'", ErrInvalidTemplate)
+	}
+	if err := template.Definition.Validate(); err != nil {
+		return nil, err
+	}
+
+	definition, err := json.Marshal(template.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode definition: %w", err)
+	}
+	maskKey := make([]byte, maskKeyBytes)
+	if _, err := rand.Read(maskKey); err != nil {
+		return nil, fmt.Errorf("failed to generate mask key: %w", err)
+	}
+
+	saved, err := scanTemplate(s.db.QueryRowContext(ctx, `
+		INSERT INTO export_templates (name, description, definition, mask_key, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (name)
+		DO UPDATE SET description = EXCLUDED.description, definition = EXCLUDED.definition,
+			updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING `+templateColumns,
+		template.Name, template.Description, definition, maskKey, updatedBy,
+	))
+	if err != nil {
+		s.log.Error("Failed to save export template", "error", err, "name", template.Name)
+		return nil, fmt.Errorf("failed to save export template: %w", err)
+	}
+
+	s.log.Info("Export template updated", "name", template.Name, "updatedBy", updatedBy)
+	return saved, nil
+}
+
+// Delete removes a template
+func (s *service) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM export_templates WHERE name = $1", name)
+	if err != nil {
+		s.log.Error("Failed to delete export template", "error", err, "name", name)
+		return fmt.Errorf("failed to delete export template: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrTemplateNotFound
+	}
+
+	s.log.Info("Export template deleted", "name", name)
+	return nil
+}
+
+// ExportOptions returns the data export options a template stands for
+func (s *service) ExportOptions(ctx context.Context, name string) (*dataexport.ExportOptions, error) {
+	var maskKey []byte
+	t, err := scanTemplate(s.db.QueryRowContext(ctx,
+		"SELECT "+templateColumns+", mask_key FROM export_templates WHERE name = $1", name), &maskKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		s.log.Error("Failed to get export template", "error", err, "name", name)
+		return nil, fmt.Errorf("failed to get export template: %w", err)
+	}
+	return t.Definition.Options(maskKey), nil
+}
@@ -0,0 +1,65 @@
+package exporttemplate
+
+import (
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/dataexport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func monthlyDelivery() Definition {
+	return Definition{
+		FormTypes: []string{"household"},
+		OrgUnitID: "3f0c8a9e-1d2b-4c5d-8e7f-6a5b4c3d2e1f",
+		Columns:   []string{"data_village", "data_head_name", "data_phone"},
+		Masks: []dataexport.ColumnMask{
+			{Column: "data_head_name", Method: dataexport.MaskHash},
+			{Column: "data_phone", Method: dataexport.MaskRedact},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	definition := monthlyDelivery()
+	require.NoError(t, definition.Validate())
+	assert.Equal(t, FormatParquet, definition.Format)
+
+	tests := []struct {
+		name   string
+		modify func(d *Definition)
+	}{
+		{"unknown format", func(d *Definition) { d.Format = "xlsx" }},
+		{"empty form type", func(d *Definition) { d.FormTypes = []string{" "} }},
+		{"bad org unit", func(d *Definition) { d.OrgUnitID = "north" }},
+		{"observation column", func(d *Definition) { d.Columns[0] = "created_by" }},
+		{"column injection", func(d *Definition) { d.Columns[0] = "data_a'; DROP TABLE users; --" }},
+		{"unknown mask method", func(d *Definition) { d.Masks[0].Method = "shuffle" }},
+		{"bad masked column", func(d *Definition) { d.Masks[1].Column = "phone" }},
+		{"column masked twice", func(d *Definition) { d.Masks[1].Column = d.Masks[0].Column }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition := monthlyDelivery()
+			tt.modify(&definition)
+			assert.ErrorIs(t, definition.Validate(), ErrInvalidTemplate)
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	definition := monthlyDelivery()
+	opts := definition.Options([]byte("key"))
+	assert.Equal(t, definition.FormTypes, opts.FormTypes)
+	assert.Equal(t, definition.OrgUnitID, opts.OrgUnitID)
+	assert.Equal(t, definition.Columns, opts.Columns)
+	assert.Equal(t, definition.Masks, opts.Masks)
+	assert.Equal(t, []byte("key"), opts.MaskKey)
+	assert.Zero(t, opts.AsOfVersion)
+}
+
+func TestValidName(t *testing.T) {
+	assert.True(t, ValidName("monthly-ministry_report"))
+	assert.False(t, ValidName("Monthly Report"))
+	assert.False(t, ValidName(""))
+}
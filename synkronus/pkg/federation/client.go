@@ -0,0 +1,207 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// requestTimeout bounds a single request to the upstream server
+const requestTimeout = 2 * time.Minute
+
+// probeTimeout bounds the reachability check of the status API
+const probeTimeout = 5 * time.Second
+
+// client talks to the upstream server through its public sync API
+type client struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+	token    string
+}
+
+// newClient creates a client for the upstream server at baseURL
+func newClient(baseURL, username, password string) *client {
+	return &client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// pullCursor is the position of a pull on the upstream server
+type pullCursor struct {
+	Version int64  `json:"version"`
+	ID      string `json:"id"`
+}
+
+type pullRequest struct {
+	ClientID string      `json:"client_id"`
+	Since    *pullCursor `json:"since,omitempty"`
+}
+
+type pullResponse struct {
+	CurrentVersion int64              `json:"current_version"`
+	Records        []sync.Observation `json:"records"`
+	ChangeCutoff   int64              `json:"change_cutoff"`
+	HasMore        bool               `json:"has_more"`
+}
+
+type pushRequest struct {
+	TransmissionID string             `json:"transmission_id"`
+	ClientID       string             `json:"client_id"`
+	Records        []sync.Observation `json:"records"`
+}
+
+type pushResponse struct {
+	CurrentVersion int64              `json:"current_version"`
+	SuccessCount   int                `json:"success_count"`
+	FailedRecords  []failedRecord     `json:"failed_records,omitempty"`
+	Warnings       []sync.SyncWarning `json:"warnings,omitempty"`
+}
+
+type failedRecord struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type idRangeRequest struct {
+	ClientID string `json:"client_id"`
+	Sequence string `json:"sequence"`
+	Count    int    `json:"count"`
+}
+
+// login obtains an access token for the configured account
+func (c *client) login(ctx context.Context) error {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	body := map[string]string{"username": c.username, "password": c.password}
+	if err := c.send(ctx, http.MethodPost, "/auth/login", body, &resp); err != nil {
+		return fmt.Errorf("failed to log in upstream: %w", err)
+	}
+	c.token = resp.Token
+	return nil
+}
+
+// pull fetches one page of upstream changes after since
+func (c *client) pull(ctx context.Context, clientID string, since *pullCursor, limit int) (*pullResponse, error) {
+	var resp pullResponse
+	path := "/sync/pull?limit=" + strconv.Itoa(limit)
+	if err := c.do(ctx, http.MethodPost, path, pullRequest{ClientID: clientID, Since: since}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// push sends local changes upstream in one transmission
+func (c *client) push(ctx context.Context, req pushRequest) (*pushResponse, error) {
+	var resp pushResponse
+	if err := c.do(ctx, http.MethodPost, "/sync/push", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// allocateIDRange reserves a block of a sequence upstream
+func (c *client) allocateIDRange(ctx context.Context, clientID, sequence string, count int) (*sync.IDRange, error) {
+	var resp sync.IDRange
+	req := idRangeRequest{ClientID: clientID, Sequence: sequence, Count: count}
+	if err := c.do(ctx, http.MethodPost, "/sync/id-ranges", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// probe reports whether the upstream server answers its health check
+func (c *client) probe(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/health", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// do sends an authenticated request, logging in first and once more when the token has expired
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	if c.token == "" {
+		if err := c.login(ctx); err != nil {
+			return err
+		}
+	}
+	err := c.send(ctx, method, path, body, out)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusUnauthorized {
+		if err := c.login(ctx); err != nil {
+			return err
+		}
+		err = c.send(ctx, method, path, body, out)
+	}
+	return err
+}
+
+// statusError is an unsuccessful response from the upstream server
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: status %d: %s", ErrUpstreamRejected, e.status, e.body)
+}
+
+func (e *statusError) Unwrap() error {
+	return ErrUpstreamRejected
+}
+
+// send performs a single JSON request
+func (c *client) send(ctx context.Context, method, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{status: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode upstream response: %w", err)
+	}
+	return nil
+}
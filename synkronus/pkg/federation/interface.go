@@ -0,0 +1,123 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// Common errors
+var (
+	// ErrUpstreamUnavailable is returned when the upstream server cannot be reached
+	ErrUpstreamUnavailable = errors.New("upstream server unavailable")
+	// ErrUpstreamRejected is returned when the upstream server refuses a request
+	ErrUpstreamRejected = errors.New("upstream server rejected request")
+)
+
+// Defaults applied to zero values of Config
+const (
+	DefaultInterval    = 5 * time.Minute
+	DefaultBatchSize   = 500
+	DefaultIDBlockSize = 1000
+)
+
+// Config holds the connection to the upstream server an edge server federates with
+type Config struct {
+	// UpstreamURL is the base URL of the central synkronus server; empty disables federation
+	UpstreamURL string
+	// Username and Password are the credentials of a read-write account on the upstream server
+	Username string
+	Password string
+	// ClientID identifies this edge server to the upstream server
+	ClientID string
+	// Interval is the time between replication attempts
+	Interval time.Duration
+	// BatchSize is the number of records pulled or pushed per request
+	BatchSize int
+	// IDBlockSize is the number of IDs reserved upstream at a time for each sequence
+	IDBlockSize int
+	// IDSequences lists the ID sequences handed out locally, kept stocked with upstream blocks
+	IDSequences []string
+}
+
+// Enabled reports whether an upstream server is configured
+func (c Config) Enabled() bool {
+	return c.UpstreamURL != ""
+}
+
+// Store is the local side of replication, implemented by *sync.Service
+type Store interface {
+	// ApplyReplicatedRecords writes records pulled from the upstream server
+	ApplyReplicatedRecords(ctx context.Context, records []sync.Observation, sourceID string) (*sync.ReplicationResult, error)
+	// PendingReplication returns local changes not yet accepted upstream
+	PendingReplication(ctx context.Context, afterVersion int64, limit int) ([]sync.Observation, error)
+	// MarkReplicated records local versions accepted upstream
+	MarkReplicated(ctx context.Context, versions map[string]int64) error
+	// AddIDBlock stores an ID block reserved upstream
+	AddIDBlock(ctx context.Context, block sync.IDRange) error
+	// IDBlockStock returns the IDs left in reserved blocks per sequence
+	IDBlockStock(ctx context.Context, sequences []string) ([]sync.IDBlockStock, error)
+	// ReplicationBacklog counts local changes and upstream conflicts still outstanding
+	ReplicationBacklog(ctx context.Context) (*sync.ReplicationBacklog, error)
+}
+
+// Reporter reports replication health, e.g. to field IT staff through the status API
+type Reporter interface {
+	// Status returns the current replication status, probing the upstream server
+	Status(ctx context.Context) (*Status, error)
+}
+
+// State is the replication progress with the upstream server
+type State struct {
+	// UpstreamVersion and UpstreamCursorID are the pull position on the upstream server
+	UpstreamVersion  int64      `json:"upstream_version"`
+	UpstreamCursorID string     `json:"upstream_cursor_id,omitempty"`
+	LastAttemptAt    *time.Time `json:"last_attempt_at,omitempty"`
+	LastSuccessAt    *time.Time `json:"last_success_at,omitempty"`
+	LastError        *string    `json:"last_error,omitempty"`
+	LastErrorAt      *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Replication states reported in Status
+const (
+	// StateNeverRun means no replication cycle has been attempted yet
+	StateNeverRun = "never_run"
+	// StateOK means the last replication cycle succeeded
+	StateOK = "ok"
+	// StateFailing means the last replication cycle failed
+	StateFailing = "failing"
+)
+
+// Status describes replication with the upstream server
+type Status struct {
+	UpstreamURL string `json:"upstream_url"`
+	ClientID    string `json:"client_id"`
+	// Reachable tells whether the upstream server answered just now
+	Reachable bool `json:"reachable"`
+	// State is the outcome of the last replication cycle
+	State string `json:"state"`
+	// UpstreamVersion is the upstream version up to which changes have been pulled
+	UpstreamVersion int64      `json:"upstream_version"`
+	LastAttemptAt   *time.Time `json:"last_attempt_at,omitempty"`
+	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
+	LastError       *string    `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+	// PendingRecords counts local changes not yet accepted upstream
+	PendingRecords int64 `json:"pending_records"`
+	// PendingConflicts counts upstream records held for review in the conflict backlog
+	PendingConflicts int64 `json:"pending_conflicts"`
+	// IDBlocks lists the IDs left per configured sequence
+	IDBlocks []sync.IDBlockStock `json:"id_blocks,omitempty"`
+}
+
+// Report summarizes one replication cycle
+type Report struct {
+	Pulled    int `json:"pulled"`
+	Applied   int `json:"applied"`
+	Conflicts int `json:"conflicts"`
+	Pushed    int `json:"pushed"`
+	Rejected  int `json:"rejected"`
+	IDBlocks  int `json:"id_blocks"`
+}
@@ -0,0 +1,327 @@
+package federation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	gosync "sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// Service replicates an edge server with its upstream server: it pulls upstream changes, pushes
+// local changes and keeps blocks of upstream ID sequences in stock for offline allocation
+type Service struct {
+	store  Store
+	state  stateStore
+	client *client
+	config Config
+	log    *logger.Logger
+
+	// mu serializes replication cycles
+	mu gosync.Mutex
+}
+
+// NewService creates a federation service replicating the local store with the configured upstream server
+func NewService(db *sql.DB, store Store, config Config, log *logger.Logger) *Service {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.IDBlockSize <= 0 {
+		config.IDBlockSize = DefaultIDBlockSize
+	}
+	return &Service{
+		store:  store,
+		state:  &dbState{db: db},
+		client: newClient(config.UpstreamURL, config.Username, config.Password),
+		config: config,
+		log:    log,
+	}
+}
+
+// Run replicates immediately and then every configured interval until ctx is cancelled.
+// Failures are logged and recorded in the federation state; the next cycle retries.
+func (s *Service) Run(ctx context.Context) {
+	s.log.Info("Federation enabled", "upstream", s.config.UpstreamURL, "interval", s.config.Interval)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Replicate(ctx); err != nil && ctx.Err() == nil {
+			s.log.Warn("Replication with upstream server failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replicate runs one replication cycle. Upstream changes are pulled before local ones are pushed, so
+// records edited on both sides are detected here and held for review instead of being overwritten.
+func (s *Service) Replicate(ctx context.Context) (*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &Report{}
+	err := s.replicate(ctx, report)
+	if stateErr := s.state.recordAttempt(ctx, time.Now(), err); stateErr != nil {
+		s.log.Error("Failed to record replication attempt", "error", stateErr)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	s.log.Info("Replicated with upstream server",
+		"pulled", report.Pulled,
+		"applied", report.Applied,
+		"conflicts", report.Conflicts,
+		"pushed", report.Pushed,
+		"rejected", report.Rejected,
+		"idBlocks", report.IDBlocks)
+	return report, nil
+}
+
+func (s *Service) replicate(ctx context.Context, report *Report) error {
+	if err := s.pullChanges(ctx, report); err != nil {
+		return err
+	}
+	if err := s.pushChanges(ctx, report); err != nil {
+		return err
+	}
+	return s.stockIDBlocks(ctx, report)
+}
+
+// pullChanges applies upstream changes page by page, saving the cursor after each page
+func (s *Service) pullChanges(ctx context.Context, report *Report) error {
+	state, err := s.state.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	var since *pullCursor
+	if state.UpstreamVersion > 0 || state.UpstreamCursorID != "" {
+		since = &pullCursor{Version: state.UpstreamVersion, ID: state.UpstreamCursorID}
+	}
+
+	for {
+		page, err := s.client.pull(ctx, s.config.ClientID, since, s.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to pull upstream changes: %w", err)
+		}
+		if len(page.Records) == 0 {
+			return nil
+		}
+
+		result, err := s.store.ApplyReplicatedRecords(ctx, page.Records, s.config.ClientID)
+		if err != nil {
+			return fmt.Errorf("failed to apply upstream changes: %w", err)
+		}
+		report.Pulled += len(page.Records)
+		report.Applied += result.Applied
+		report.Conflicts += result.Conflicts
+
+		last := page.Records[len(page.Records)-1]
+		since = &pullCursor{Version: last.Version, ID: last.ObservationID}
+		if err := s.state.saveCursor(ctx, since.Version, since.ID); err != nil {
+			return err
+		}
+
+		if !page.HasMore {
+			return nil
+		}
+	}
+}
+
+// pushChanges sends pending local changes upstream in batches and marks the accepted ones
+func (s *Service) pushChanges(ctx context.Context, report *Report) error {
+	var after int64
+	for {
+		records, err := s.store.PendingReplication(ctx, after, s.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get pending changes: %w", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		after = records[len(records)-1].Version
+
+		resp, err := s.client.push(ctx, pushRequest{
+			TransmissionID: uuid.NewString(),
+			ClientID:       s.config.ClientID,
+			Records:        records,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to push local changes: %w", err)
+		}
+
+		// Failed records and records of form types paused upstream stay pending for the next cycle
+		held := make(map[string]bool)
+		for _, failed := range resp.FailedRecords {
+			if failed.Index >= 0 && failed.Index < len(records) {
+				held[records[failed.Index].ObservationID] = true
+				s.log.Warn("Upstream server rejected record",
+					"observationId", records[failed.Index].ObservationID, "error", failed.Error)
+			}
+		}
+		for _, warning := range resp.Warnings {
+			if warning.Code == sync.WarningCodeFormPaused {
+				held[warning.ID] = true
+			}
+		}
+
+		accepted := make(map[string]int64, len(records))
+		for _, record := range records {
+			if !held[record.ObservationID] {
+				accepted[record.ObservationID] = record.Version
+			}
+		}
+		if err := s.store.MarkReplicated(ctx, accepted); err != nil {
+			return fmt.Errorf("failed to mark pushed changes: %w", err)
+		}
+		report.Pushed += len(accepted)
+		report.Rejected += len(records) - len(accepted)
+
+		if len(records) < s.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+// stockIDBlocks reserves a new upstream block for each sequence running below half a block
+func (s *Service) stockIDBlocks(ctx context.Context, report *Report) error {
+	if len(s.config.IDSequences) == 0 {
+		return nil
+	}
+
+	stock, err := s.store.IDBlockStock(ctx, s.config.IDSequences)
+	if err != nil {
+		return fmt.Errorf("failed to get ID block stock: %w", err)
+	}
+
+	for _, sequence := range stock {
+		if sequence.Remaining >= int64(s.config.IDBlockSize/2) {
+			continue
+		}
+		block, err := s.client.allocateIDRange(ctx, s.config.ClientID, sequence.Sequence, s.config.IDBlockSize)
+		if err != nil {
+			return fmt.Errorf("failed to reserve ID block for %q: %w", sequence.Sequence, err)
+		}
+		if err := s.store.AddIDBlock(ctx, *block); err != nil {
+			return fmt.Errorf("failed to store ID block for %q: %w", sequence.Sequence, err)
+		}
+		report.IDBlocks++
+	}
+	return nil
+}
+
+// Status returns the replication progress together with a live check of the upstream server
+func (s *Service) Status(ctx context.Context) (*Status, error) {
+	state, err := s.state.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backlog, err := s.store.ReplicationBacklog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count replication backlog: %w", err)
+	}
+
+	status := &Status{
+		UpstreamURL:      s.config.UpstreamURL,
+		ClientID:         s.config.ClientID,
+		Reachable:        s.client.probe(ctx),
+		State:            StateNeverRun,
+		UpstreamVersion:  state.UpstreamVersion,
+		LastAttemptAt:    state.LastAttemptAt,
+		LastSuccessAt:    state.LastSuccessAt,
+		LastError:        state.LastError,
+		LastErrorAt:      state.LastErrorAt,
+		PendingRecords:   backlog.PendingRecords,
+		PendingConflicts: backlog.PendingConflicts,
+	}
+	if state.LastAttemptAt != nil {
+		status.State = StateFailing
+		if state.LastSuccessAt != nil && !state.LastSuccessAt.Before(*state.LastAttemptAt) {
+			status.State = StateOK
+		}
+	}
+
+	if len(s.config.IDSequences) > 0 {
+		if status.IDBlocks, err = s.store.IDBlockStock(ctx, s.config.IDSequences); err != nil {
+			return nil, fmt.Errorf("failed to get ID block stock: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// stateStore persists replication progress
+type stateStore interface {
+	load(ctx context.Context) (*State, error)
+	saveCursor(ctx context.Context, version int64, id string) error
+	recordAttempt(ctx context.Context, at time.Time, err error) error
+}
+
+// dbState keeps replication progress in the single row of federation_state
+type dbState struct {
+	db *sql.DB
+}
+
+func (d *dbState) load(ctx context.Context) (*State, error) {
+	var state State
+	err := d.db.QueryRowContext(ctx, `
+		SELECT upstream_version, upstream_cursor_id, last_attempt_at, last_success_at, last_error, last_error_at
+		FROM federation_state
+		WHERE id = 1`).Scan(&state.UpstreamVersion, &state.UpstreamCursorID,
+		&state.LastAttemptAt, &state.LastSuccessAt, &state.LastError, &state.LastErrorAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load federation state: %w", err)
+	}
+	return &state, nil
+}
+
+func (d *dbState) saveCursor(ctx context.Context, version int64, id string) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO federation_state (id, upstream_version, upstream_cursor_id)
+		VALUES (1, $1, $2)
+		ON CONFLICT (id)
+		DO UPDATE SET upstream_version = EXCLUDED.upstream_version, upstream_cursor_id = EXCLUDED.upstream_cursor_id`,
+		version, id)
+	if err != nil {
+		return fmt.Errorf("failed to save federation cursor: %w", err)
+	}
+	return nil
+}
+
+func (d *dbState) recordAttempt(ctx context.Context, at time.Time, replicationErr error) error {
+	var err error
+	if replicationErr == nil {
+		_, err = d.db.ExecContext(ctx, `
+			INSERT INTO federation_state (id, last_attempt_at, last_success_at)
+			VALUES (1, $1, $1)
+			ON CONFLICT (id)
+			DO UPDATE SET last_attempt_at = EXCLUDED.last_attempt_at, last_success_at = EXCLUDED.last_success_at`, at)
+	} else {
+		_, err = d.db.ExecContext(ctx, `
+			INSERT INTO federation_state (id, last_attempt_at, last_error, last_error_at)
+			VALUES (1, $1, $2, $1)
+			ON CONFLICT (id)
+			DO UPDATE SET last_attempt_at = EXCLUDED.last_attempt_at, last_error = EXCLUDED.last_error,
+				last_error_at = EXCLUDED.last_error_at`, at, replicationErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record replication attempt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore is an in-memory local side of replication
+type fakeStore struct {
+	applied  []sync.Observation
+	pending  []sync.Observation
+	marked   map[string]int64
+	blocks   []sync.IDRange
+	stock    map[string]int64
+	sourceID string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{marked: make(map[string]int64), stock: make(map[string]int64)}
+}
+
+func (f *fakeStore) ApplyReplicatedRecords(ctx context.Context, records []sync.Observation, sourceID string) (*sync.ReplicationResult, error) {
+	f.applied = append(f.applied, records...)
+	f.sourceID = sourceID
+	return &sync.ReplicationResult{Applied: len(records)}, nil
+}
+
+func (f *fakeStore) PendingReplication(ctx context.Context, afterVersion int64, limit int) ([]sync.Observation, error) {
+	var records []sync.Observation
+	for _, record := range f.pending {
+		if _, ok := f.marked[record.ObservationID]; ok || record.Version <= afterVersion {
+			continue
+		}
+		records = append(records, record)
+		if len(records) == limit {
+			break
+		}
+	}
+	return records, nil
+}
+
+func (f *fakeStore) MarkReplicated(ctx context.Context, versions map[string]int64) error {
+	for id, version := range versions {
+		f.marked[id] = version
+	}
+	return nil
+}
+
+func (f *fakeStore) AddIDBlock(ctx context.Context, block sync.IDRange) error {
+	f.blocks = append(f.blocks, block)
+	f.stock[block.Sequence] += block.RangeEnd - block.RangeStart + 1
+	return nil
+}
+
+func (f *fakeStore) ReplicationBacklog(ctx context.Context) (*sync.ReplicationBacklog, error) {
+	var pending int64
+	for _, record := range f.pending {
+		if _, ok := f.marked[record.ObservationID]; !ok {
+			pending++
+		}
+	}
+	return &sync.ReplicationBacklog{PendingRecords: pending}, nil
+}
+
+func (f *fakeStore) IDBlockStock(ctx context.Context, sequences []string) ([]sync.IDBlockStock, error) {
+	stock := make([]sync.IDBlockStock, 0, len(sequences))
+	for _, sequence := range sequences {
+		stock = append(stock, sync.IDBlockStock{Sequence: sequence, Remaining: f.stock[sequence]})
+	}
+	return stock, nil
+}
+
+// memoryState keeps replication progress in memory
+type memoryState struct {
+	state State
+}
+
+func (m *memoryState) load(ctx context.Context) (*State, error) {
+	state := m.state
+	return &state, nil
+}
+
+func (m *memoryState) saveCursor(ctx context.Context, version int64, id string) error {
+	m.state.UpstreamVersion = version
+	m.state.UpstreamCursorID = id
+	return nil
+}
+
+func (m *memoryState) recordAttempt(ctx context.Context, at time.Time, err error) error {
+	m.state.LastAttemptAt = &at
+	if err == nil {
+		m.state.LastSuccessAt = &at
+		return nil
+	}
+	msg := err.Error()
+	m.state.LastError = &msg
+	m.state.LastErrorAt = &at
+	return nil
+}
+
+// fakeUpstream is a minimal upstream synkronus server
+type fakeUpstream struct {
+	records     []sync.Observation
+	pushed      []pushRequest
+	pullSince   []*pullCursor
+	logins      int
+	rejectIndex int
+	expireOnce  bool
+}
+
+func (u *fakeUpstream) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		u.logins++
+		json.NewEncoder(w).Encode(map[string]string{"token": "token"})
+	})
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" || u.expireOnce {
+				u.expireOnce = false
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("/sync/pull", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var req pullRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		u.pullSince = append(u.pullSince, req.Since)
+
+		var page []sync.Observation
+		for _, record := range u.records {
+			if req.Since == nil || record.Version > req.Since.Version {
+				page = append(page, record)
+			}
+		}
+		hasMore := len(page) > 1
+		if hasMore {
+			page = page[:1]
+		}
+		json.NewEncoder(w).Encode(pullResponse{Records: page, HasMore: hasMore})
+	}))
+	mux.HandleFunc("/sync/push", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var req pushRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		u.pushed = append(u.pushed, req)
+
+		resp := pushResponse{SuccessCount: len(req.Records)}
+		if u.rejectIndex >= 0 && u.rejectIndex < len(req.Records) {
+			resp.SuccessCount--
+			resp.FailedRecords = []failedRecord{{Index: u.rejectIndex, Error: "record is locked"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	mux.HandleFunc("/sync/id-ranges", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var req idRangeRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(sync.IDRange{
+			Sequence:   req.Sequence,
+			ClientID:   req.ClientID,
+			RangeStart: 5001,
+			RangeEnd:   5000 + int64(req.Count),
+		})
+	}))
+	return mux
+}
+
+func newTestService(t *testing.T, upstream *fakeUpstream, store Store, config Config) (*Service, *memoryState) {
+	server := httptest.NewServer(upstream.handler(t))
+	t.Cleanup(server.Close)
+
+	config.UpstreamURL = server.URL
+	config.ClientID = "edge-1"
+	s := NewService(nil, store, config, logger.NewLogger())
+	state := &memoryState{}
+	s.state = state
+	return s, state
+}
+
+func TestReplicate_PullsAllPagesAndSavesCursor(t *testing.T) {
+	upstream := &fakeUpstream{
+		rejectIndex: -1,
+		records: []sync.Observation{
+			{ObservationID: "obs-1", Version: 3},
+			{ObservationID: "obs-2", Version: 7},
+		},
+	}
+	store := newFakeStore()
+	s, state := newTestService(t, upstream, store, Config{})
+
+	report, err := s.Replicate(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.Pulled)
+	assert.Equal(t, 2, report.Applied)
+	assert.Len(t, store.applied, 2)
+	assert.Equal(t, "edge-1", store.sourceID)
+	assert.Equal(t, int64(7), state.state.UpstreamVersion)
+	assert.Equal(t, "obs-2", state.state.UpstreamCursorID)
+	assert.NotNil(t, state.state.LastSuccessAt)
+
+	// The next cycle resumes after the saved cursor
+	_, err = s.Replicate(context.Background())
+	require.NoError(t, err)
+	last := upstream.pullSince[len(upstream.pullSince)-1]
+	require.NotNil(t, last)
+	assert.Equal(t, int64(7), last.Version)
+	assert.Len(t, store.applied, 2)
+}
+
+func TestReplicate_PushesPendingAndKeepsRejected(t *testing.T) {
+	upstream := &fakeUpstream{rejectIndex: 1}
+	store := newFakeStore()
+	store.pending = []sync.Observation{
+		{ObservationID: "obs-1", Version: 10},
+		{ObservationID: "obs-2", Version: 11},
+		{ObservationID: "obs-3", Version: 12},
+	}
+	s, _ := newTestService(t, upstream, store, Config{BatchSize: 2})
+
+	report, err := s.Replicate(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, upstream.pushed, 2)
+	assert.Equal(t, "edge-1", upstream.pushed[0].ClientID)
+	assert.NotEmpty(t, upstream.pushed[0].TransmissionID)
+	assert.Equal(t, 2, report.Pushed)
+	assert.Equal(t, 1, report.Rejected)
+	assert.Equal(t, map[string]int64{"obs-1": 10, "obs-3": 12}, store.marked)
+}
+
+func TestReplicate_StocksIDBlocks(t *testing.T) {
+	upstream := &fakeUpstream{rejectIndex: -1}
+	store := newFakeStore()
+	store.stock["household"] = 600
+	store.stock["sample"] = 10
+	s, _ := newTestService(t, upstream, store, Config{IDBlockSize: 1000, IDSequences: []string{"household", "sample"}})
+
+	report, err := s.Replicate(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.IDBlocks)
+	require.Len(t, store.blocks, 1)
+	assert.Equal(t, "sample", store.blocks[0].Sequence)
+	assert.Equal(t, int64(5001), store.blocks[0].RangeStart)
+	assert.Equal(t, int64(6000), store.blocks[0].RangeEnd)
+}
+
+func TestReplicate_LogsInAgainWhenTokenExpires(t *testing.T) {
+	upstream := &fakeUpstream{rejectIndex: -1}
+	s, _ := newTestService(t, upstream, newFakeStore(), Config{})
+
+	_, err := s.Replicate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, upstream.logins)
+
+	upstream.expireOnce = true
+	_, err = s.Replicate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, upstream.logins)
+}
+
+func TestReplicate_RecordsUnreachableUpstream(t *testing.T) {
+	s := NewService(nil, newFakeStore(), Config{UpstreamURL: "http://127.0.0.1:1", ClientID: "edge-1"}, logger.NewLogger())
+	state := &memoryState{}
+	s.state = state
+
+	_, err := s.Replicate(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	require.NotNil(t, state.state.LastError)
+	assert.Nil(t, state.state.LastSuccessAt)
+}
+
+func TestStatus(t *testing.T) {
+	upstream := &fakeUpstream{rejectIndex: 0}
+	store := newFakeStore()
+	store.pending = []sync.Observation{{ObservationID: "obs-1", Version: 10}, {ObservationID: "obs-2", Version: 11}}
+	s, _ := newTestService(t, upstream, store, Config{IDSequences: []string{"household"}})
+
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, StateNeverRun, status.State)
+	assert.True(t, status.Reachable)
+	assert.Equal(t, "edge-1", status.ClientID)
+	assert.Equal(t, int64(2), status.PendingRecords)
+
+	_, err = s.Replicate(context.Background())
+	require.NoError(t, err)
+
+	status, err = s.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, StateOK, status.State)
+	assert.NotNil(t, status.LastSuccessAt)
+	assert.Equal(t, int64(1), status.PendingRecords)
+	require.Len(t, status.IDBlocks, 1)
+	assert.Equal(t, int64(1000), status.IDBlocks[0].Remaining)
+}
+
+func TestStatus_UpstreamUnreachable(t *testing.T) {
+	s := NewService(nil, newFakeStore(), Config{UpstreamURL: "http://127.0.0.1:1", ClientID: "edge-1"}, logger.NewLogger())
+	s.state = &memoryState{}
+
+	_, err := s.Replicate(context.Background())
+	require.Error(t, err)
+
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	assert.False(t, status.Reachable)
+	assert.Equal(t, StateFailing, status.State)
+	require.NotNil(t, status.LastError)
+}
@@ -0,0 +1,140 @@
+package formcomponent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+)
+
+// Common errors
+var (
+	// ErrNotFound is returned when a component or version does not exist
+	ErrNotFound = errors.New("form component not found")
+	// ErrInvalidName is returned when a component name or reference is malformed
+	ErrInvalidName = errors.New("invalid form component name")
+	// ErrInvalidComponent is returned when a component's schema or UI cannot be included in forms
+	ErrInvalidComponent = errors.New("invalid form component")
+)
+
+// namePattern is the form of component names, as written in x-include references
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+// Component is a published version of a shared form component
+type Component struct {
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	// Schema is an object schema whose properties and required fields are merged into the
+	// forms that include the component
+	Schema json.RawMessage `json:"schema"`
+	// UI replaces {"x-include": "<name>"} in the UI schema of the forms that include the component
+	UI        json.RawMessage `json:"ui,omitempty"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Input is the content of a new component version
+type Input struct {
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+	UI          json.RawMessage `json:"ui,omitempty"`
+}
+
+// Service keeps the library of shared form components
+type Service interface {
+	// Publish adds the next version of a component, starting at 1 for a new component
+	Publish(ctx context.Context, name string, input Input, createdBy string) (*Component, error)
+
+	// Get returns a version of a component; version 0 returns the latest
+	Get(ctx context.Context, name string, version int) (*Component, error)
+
+	// List returns the latest version of every component, by name
+	List(ctx context.Context) ([]Component, error)
+
+	// Versions returns every version of a component, newest first
+	Versions(ctx context.Context, name string) ([]Component, error)
+
+	// ResolveInclude returns the component an x-include reference names; see
+	// appbundle.IncludeResolver
+	ResolveInclude(ctx context.Context, ref string) (*appbundle.IncludedComponent, error)
+}
+
+// ParseRef splits an x-include reference, "name@version" or "name" for the latest version
+func ParseRef(ref string) (string, int, error) {
+	name, versionText, pinned := strings.Cut(ref, "@")
+	if !namePattern.MatchString(name) {
+		return "", 0, fmt.Errorf("%w: %q", ErrInvalidName, ref)
+	}
+	if !pinned {
+		return name, 0, nil
+	}
+	version, err := strconv.Atoi(versionText)
+	if err != nil || version < 1 {
+		return "", 0, fmt.Errorf("%w: %q has no valid version", ErrInvalidName, ref)
+	}
+	return name, version, nil
+}
+
+// Validate checks a component before it is published: the name must be a valid reference
+// name, the schema an object schema with properties, and neither may include other components
+func Validate(name string, input Input) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: use lowercase letters, digits, '-' and '_', starting with a letter", ErrInvalidName)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(input.Schema, &schema); err != nil || schema == nil {
+		return fmt.Errorf("%w: schema must be a JSON object", ErrInvalidComponent)
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	if len(properties) == 0 {
+		return fmt.Errorf("%w: schema must define properties", ErrInvalidComponent)
+	}
+	if required, ok := schema["required"]; ok {
+		list, ok := required.([]any)
+		if !ok {
+			return fmt.Errorf("%w: required must be a list of property names", ErrInvalidComponent)
+		}
+		for _, item := range list {
+			field, _ := item.(string)
+			if _, ok := properties[field]; !ok {
+				return fmt.Errorf("%w: required field %v is not a property", ErrInvalidComponent, item)
+			}
+		}
+	}
+
+	if len(input.UI) > 0 && !bytes.Equal(bytes.TrimSpace(input.UI), []byte("null")) {
+		var ui map[string]any
+		if err := json.Unmarshal(input.UI, &ui); err != nil || ui == nil {
+			return fmt.Errorf("%w: ui must be a JSON object", ErrInvalidComponent)
+		}
+	}
+
+	// Components are included one level deep, so their content stays visible where it is edited
+	if bytes.Contains(input.Schema, []byte(`"`+appbundle.IncludeKey+`"`)) || bytes.Contains(input.UI, []byte(`"`+appbundle.IncludeKey+`"`)) {
+		return fmt.Errorf("%w: components cannot include other components", ErrInvalidComponent)
+	}
+	return nil
+}
+
+// included converts a component to the form the app bundle service merges into forms
+func included(c *Component) (*appbundle.IncludedComponent, error) {
+	result := &appbundle.IncludedComponent{Name: c.Name, Version: c.Version}
+	if err := json.Unmarshal(c.Schema, &result.Schema); err != nil {
+		return nil, fmt.Errorf("failed to decode schema of form component %s@%d: %w", c.Name, c.Version, err)
+	}
+	if len(c.UI) > 0 {
+		if err := json.Unmarshal(c.UI, &result.UI); err != nil {
+			return nil, fmt.Errorf("failed to decode UI of form component %s@%d: %w", c.Name, c.Version, err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package formcomponent
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// columns are the form_components columns scanned by scanComponent
+const columns = "name, version, description, schema, ui, created_by, created_at"
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new form component library service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Publish adds the next version of a component, starting at 1 for a new component
+func (s *service) Publish(ctx context.Context, name string, input Input, createdBy string) (*Component, error) {
+	if err := Validate(name, input); err != nil {
+		return nil, err
+	}
+
+	var ui any
+	if trimmed := bytes.TrimSpace(input.UI); len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		ui = []byte(trimmed)
+	}
+	c, err := scanComponent(s.db.QueryRowContext(ctx, `
+		INSERT INTO form_components (name, version, description, schema, ui, created_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5 FROM form_components WHERE name = $1
+		RETURNING `+columns,
+		name, input.Description, []byte(input.Schema), ui, createdBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish form component: %w", err)
+	}
+
+	s.log.Info("Form component published", "name", c.Name, "version", c.Version, "createdBy", createdBy)
+	return c, nil
+}
+
+// Get returns a version of a component; version 0 returns the latest
+func (s *service) Get(ctx context.Context, name string, version int) (*Component, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+columns+" FROM form_components WHERE name = $1 ORDER BY version DESC LIMIT 1", name)
+	if version > 0 {
+		row = s.db.QueryRowContext(ctx,
+			"SELECT "+columns+" FROM form_components WHERE name = $1 AND version = $2", name, version)
+	}
+	c, err := scanComponent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get form component: %w", err)
+	}
+	return c, nil
+}
+
+// List returns the latest version of every component, by name
+func (s *service) List(ctx context.Context) ([]Component, error) {
+	return s.query(ctx, "SELECT DISTINCT ON (name) "+columns+" FROM form_components ORDER BY name, version DESC")
+}
+
+// Versions returns every version of a component, newest first
+func (s *service) Versions(ctx context.Context, name string) ([]Component, error) {
+	components, err := s.query(ctx, "SELECT "+columns+" FROM form_components WHERE name = $1 ORDER BY version DESC", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		return nil, ErrNotFound
+	}
+	return components, nil
+}
+
+// ResolveInclude returns the component an x-include reference names
+func (s *service) ResolveInclude(ctx context.Context, ref string) (*appbundle.IncludedComponent, error) {
+	name, version, err := ParseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.Get(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	return included(c)
+}
+
+func (s *service) query(ctx context.Context, query string, args ...any) ([]Component, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list form components: %w", err)
+	}
+	defer rows.Close()
+
+	components := make([]Component, 0)
+	for rows.Next() {
+		c, err := scanComponent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan form component: %w", err)
+		}
+		components = append(components, *c)
+	}
+	return components, rows.Err()
+}
+
+// scanComponent reads a component selected with columns
+func scanComponent(row interface{ Scan(...any) error }) (*Component, error) {
+	var c Component
+	var schema, ui []byte
+	if err := row.Scan(&c.Name, &c.Version, &c.Description, &schema, &ui, &c.CreatedBy, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.Schema = schema
+	if len(ui) > 0 {
+		c.UI = ui
+	}
+	return &c, nil
+}
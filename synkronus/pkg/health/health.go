@@ -0,0 +1,158 @@
+// Package health checks the dependencies a server needs to serve requests, for readiness probes
+// of load balancers and orchestrators.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of a check and of a report
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// checkTimeout bounds each check, so a hanging dependency fails readiness instead of the probe
+const checkTimeout = 3 * time.Second
+
+// Check is a dependency the server needs; Run returns why it cannot be used
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Error tells why the check failed
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of all checks; it is ok when every check is
+type Report struct {
+	Status string    `json:"status"`
+	Checks []Result  `json:"checks"`
+	Time   time.Time `json:"timestamp"`
+}
+
+// Checker runs the checks of a server
+type Checker struct {
+	checks []Check
+}
+
+// NewChecker creates a checker running checks in the given order of reporting
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Ready runs all checks concurrently, each bounded by a timeout
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make([]Result, len(c.checks)), Time: time.Now().UTC()}
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFailed
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := Result{Name: check.Name, Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Database checks that the database accepts connections
+func Database(db *sql.DB) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("database is unreachable: %w", err)
+		}
+		return nil
+	}}
+}
+
+// Migrations checks that the database schema is at the latest of the goose migrations in
+// migrations, so a server started against a database another version migrated is not ready
+func Migrations(db *sql.DB, migrations fs.FS) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		latest, err := latestMigration(migrations)
+		if err != nil {
+			return err
+		}
+		var applied sql.NullInt64
+		if err := db.QueryRowContext(ctx, "SELECT MAX(version_id) FROM goose_db_version WHERE is_applied").Scan(&applied); err != nil {
+			return fmt.Errorf("failed to read the schema version: %w", err)
+		}
+		if applied.Int64 < latest {
+			return fmt.Errorf("schema version %d is behind the latest migration %d", applied.Int64, latest)
+		}
+		return nil
+	}}
+}
+
+// latestMigration returns the version of the last migration, from the numeric prefix of its
+// file name
+func latestMigration(migrations fs.FS) (int64, error) {
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(path.Base(name), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
+
+// WritableDir checks that files can be created in dir, as the server does when app bundle
+// versions are pushed
+func WritableDir(name, dir string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		file, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", dir, err)
+		}
+		file.Close()
+		return os.Remove(file.Name())
+	}}
+}
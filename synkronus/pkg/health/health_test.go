@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	ok := Check{Name: "ok", Run: func(ctx context.Context) error { return nil }}
+	report := NewChecker(ok).Ready(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, Result{Name: "ok", Status: StatusOK}, report.Checks[0])
+
+	failing := Check{Name: "failing", Run: func(ctx context.Context) error { return errors.New("connection refused") }}
+	hanging := Check{Name: "hanging", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	// Checks are also bounded by the request
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report = NewChecker(ok, failing, hanging).Ready(ctx)
+	assert.Equal(t, StatusFailed, report.Status)
+	require.Len(t, report.Checks, 3)
+	assert.Equal(t, "ok", report.Checks[0].Name, "results keep the order of the checks")
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+	assert.Equal(t, StatusFailed, report.Checks[2].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[2].Error)
+}
+
+func TestLatestMigration(t *testing.T) {
+	latest, err := latestMigration(fstest.MapFS{
+		"20250101000001_create_users.sql":   {},
+		"20251012000001_create_reports.sql": {},
+		"20250601000001_add_org_units.sql":  {},
+		"README.md":                         {},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(20251012000001), latest)
+
+	_, err = latestMigration(fstest.MapFS{})
+	assert.Error(t, err)
+}
+
+func TestWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	check := WritableDir("app_bundle_dir", dir)
+	require.NoError(t, check.Run(context.Background()))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the probe file is removed")
+
+	missing := WritableDir("app_bundle_dir", filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, missing.Run(context.Background()), "is not writable")
+}
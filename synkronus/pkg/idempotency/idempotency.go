@@ -0,0 +1,91 @@
+// Package idempotency remembers the responses to requests that carry a client-chosen key, so that
+// a request retried after its response was lost is answered again instead of applied twice
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// pendingTTL bounds how long a claimed key blocks retries when its request never completes, as
+// when the server stops while processing it
+const pendingTTL = 5 * time.Minute
+
+var (
+	// ErrInProgress is returned for a key whose first request is still being processed
+	ErrInProgress = errors.New("a request with this key is still being processed")
+	// ErrKeyReused is returned for a key that was used for a request with a different payload
+	ErrKeyReused = errors.New("the key was already used for a different request")
+)
+
+// States of a key reported by Lookup
+const (
+	// StateProcessing is the state of a key whose request is still being processed
+	StateProcessing = "processing"
+	// StateCompleted is the state of a key whose response is kept
+	StateCompleted = "completed"
+)
+
+// Response is a response kept for retries
+type Response struct {
+	Status int    `json:"status"`
+	Body   []byte `json:"body"`
+}
+
+// Store keeps idempotency keys with the fingerprint of their request and, once processed, its
+// response
+type Store interface {
+	// Claim reserves key for the request with the given fingerprint. It returns a nil response
+	// when the caller should process the request, the kept response when it was processed
+	// before, ErrInProgress while it is being processed and ErrKeyReused when the fingerprint
+	// differs.
+	Claim(ctx context.Context, key, fingerprint string) (*Response, error)
+	// Complete keeps the response of a claimed key for the store's retention
+	Complete(ctx context.Context, key, fingerprint string, response Response) error
+	// Release forgets a claimed key whose request failed, so that a retry is processed
+	Release(ctx context.Context, key string) error
+	// Lookup returns the state of a key, or nil when it is unknown or expired
+	Lookup(ctx context.Context, key string) (*Status, error)
+	// Retention is how long responses are kept
+	Retention() time.Duration
+}
+
+// Status is the state of a key; Response is only set once its request completed
+type Status struct {
+	State    string
+	Expires  time.Time
+	Response *Response
+}
+
+// record is the value kept for a key; Response is nil while the request is processed
+type record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"`
+	Expires     time.Time `json:"expires"`
+}
+
+// outcome decides a claim of a key that is already taken
+func (r record) outcome(fingerprint string) (*Response, error) {
+	if r.Fingerprint != fingerprint {
+		return nil, ErrKeyReused
+	}
+	if r.Response == nil {
+		return nil, ErrInProgress
+	}
+	return r.Response, nil
+}
+
+// status reports the state of a record
+func (r record) status() *Status {
+	if r.Response == nil {
+		return &Status{State: StateProcessing, Expires: r.Expires}
+	}
+	return &Status{State: StateCompleted, Expires: r.Expires, Response: r.Response}
+}
+
+func encode(r record) []byte {
+	data, _ := json.Marshal(r)
+	return data
+}
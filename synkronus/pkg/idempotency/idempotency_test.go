@@ -0,0 +1,85 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/redis"
+	"github.com/opendataensemble/synkronus/pkg/redis/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	response, err := store.Claim(ctx, "push:1", "a")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	// Retries while the first request is processed are turned away
+	_, err = store.Claim(ctx, "push:1", "a")
+	assert.ErrorIs(t, err, ErrInProgress)
+	status, err := store.Lookup(ctx, "push:1")
+	require.NoError(t, err)
+	require.NotNil(t, status)
+	assert.Equal(t, StateProcessing, status.State)
+	assert.Nil(t, status.Response)
+	_, err = store.Claim(ctx, "push:1", "b")
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	// Retries of a completed request get its response
+	require.NoError(t, store.Complete(ctx, "push:1", "a", Response{Status: 200, Body: []byte(`{"ok":true}`)}))
+	response, err = store.Claim(ctx, "push:1", "a")
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, 200, response.Status)
+	assert.Equal(t, `{"ok":true}`, string(response.Body))
+	_, err = store.Claim(ctx, "push:1", "b")
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	// Released keys are processed again
+	_, err = store.Claim(ctx, "push:2", "a")
+	require.NoError(t, err)
+	require.NoError(t, store.Release(ctx, "push:2"))
+	response, err = store.Claim(ctx, "push:2", "a")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(time.Hour))
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	// Claims of requests that never complete lapse
+	_, err := store.Claim(ctx, "push:1", "a")
+	require.NoError(t, err)
+	now = now.Add(pendingTTL)
+	response, err := store.Claim(ctx, "push:1", "a")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	require.NoError(t, store.Complete(ctx, "push:1", "a", Response{Status: 200}))
+	now = now.Add(2 * time.Hour)
+	response, err = store.Claim(ctx, "push:1", "b")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+	assert.Len(t, store.records, 1)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := redistest.NewServer(t, "")
+	client, err := redis.New(server.URL(""))
+	require.NoError(t, err)
+	testStore(t, NewRedisStore(client, time.Hour))
+
+	_, ok := server.Value(keyPrefix + "push:1")
+	assert.True(t, ok)
+}
@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps keys in the server's memory, for deployments with a single server
+type MemoryStore struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	records map[string]record
+	swept   time.Time
+}
+
+// NewMemoryStore creates a store keeping responses for retention
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{retention: retention, now: time.Now, records: make(map[string]record)}
+}
+
+// Claim reserves a key; see Store
+func (s *MemoryStore) Claim(_ context.Context, key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if r, ok := s.records[key]; ok && now.Before(r.Expires) {
+		return r.outcome(fingerprint)
+	}
+	s.records[key] = record{Fingerprint: fingerprint, Expires: now.Add(pendingTTL)}
+	return nil, nil
+}
+
+// Complete keeps the response of a key; see Store
+func (s *MemoryStore) Complete(_ context.Context, key, fingerprint string, response Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record{Fingerprint: fingerprint, Response: &response, Expires: s.now().Add(s.retention)}
+	return nil
+}
+
+// Release forgets a key; see Store
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// Lookup returns the state of a key; see Store
+func (s *MemoryStore) Lookup(_ context.Context, key string) (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[key]; ok && s.now().Before(r.Expires) {
+		return r.status(), nil
+	}
+	return nil, nil
+}
+
+// Retention is how long responses are kept; see Store
+func (s *MemoryStore) Retention() time.Duration {
+	return s.retention
+}
+
+// sweep drops expired keys, at most once a minute
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+	for key, r := range s.records {
+		if !now.Before(r.Expires) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// transmissionPrefix starts the keys of sync push transmissions
+const transmissionPrefix = "sync-push:"
+
+// TransmissionKey is the key of a sync push transmission of a client, pushed by a user of a
+// tenant. Transmissions of other users or tenants have other keys, so they are neither looked up
+// nor replayed by mistake. All but the transmission ID are escaped, so a key names exactly one
+// tenant, user, client and transmission.
+func TransmissionKey(tenantID, username, clientID, transmissionID string) string {
+	return transmissionPrefix + url.QueryEscape(tenantID) + ":" + url.QueryEscape(username) + ":" +
+		url.QueryEscape(clientID) + ":" + transmissionID
+}
+
+// transmission names a transmission by the parts of its key
+type transmission struct {
+	tenantID, username, clientID, id string
+}
+
+// parseTransmissionKey splits a key made by TransmissionKey
+func parseTransmissionKey(key string) (transmission, error) {
+	rest, ok := strings.CutPrefix(key, transmissionPrefix)
+	if !ok {
+		return transmission{}, fmt.Errorf("not a transmission key: %s", key)
+	}
+	parts := strings.SplitN(rest, ":", 4)
+	if len(parts) != 4 {
+		return transmission{}, fmt.Errorf("not a transmission key: %s", key)
+	}
+	var t transmission
+	for i, field := range []*string{&t.tenantID, &t.username, &t.clientID} {
+		value, err := url.QueryUnescape(parts[i])
+		if err != nil {
+			return transmission{}, fmt.Errorf("not a transmission key: %s", key)
+		}
+		*field = value
+	}
+	t.id = parts[3]
+	return t, nil
+}
+
+// PostgresStore keeps sync push transmissions in the sync_transmissions table, keyed by tenant,
+// user, client and transmission ID, so retries are recognized by every server sharing the database and
+// across restarts. It only takes keys made by TransmissionKey.
+type PostgresStore struct {
+	db        *sql.DB
+	retention time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+// NewPostgresStore creates a store keeping responses for retention
+func NewPostgresStore(db *sql.DB, retention time.Duration) *PostgresStore {
+	return &PostgresStore{db: db, retention: retention, now: time.Now}
+}
+
+// Claim reserves a key; see Store
+func (s *PostgresStore) Claim(ctx context.Context, key, fingerprint string) (*Response, error) {
+	t, err := parseTransmissionKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s.sweep(ctx)
+
+	// A key that expires between the two statements is claimed again
+	for attempt := 0; attempt < 2; attempt++ {
+		now := s.now()
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO sync_transmissions (tenant_id, username, client_id, transmission_id, fingerprint, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (tenant_id, username, client_id, transmission_id) DO UPDATE
+			SET fingerprint = EXCLUDED.fingerprint, response_status = NULL, response_body = NULL,
+			    created_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE sync_transmissions.expires_at <= $7`,
+			t.tenantID, t.username, t.clientID, t.id, fingerprint, now.Add(pendingTTL), now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim transmission: %w", err)
+		}
+		if claimed, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if claimed > 0 {
+			return nil, nil
+		}
+		r, err := s.get(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+		return r.outcome(fingerprint)
+	}
+	return nil, ErrInProgress
+}
+
+// Complete keeps the response of a key; see Store
+func (s *PostgresStore) Complete(ctx context.Context, key, fingerprint string, response Response) error {
+	t, err := parseTransmissionKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO sync_transmissions (tenant_id, username, client_id, transmission_id, fingerprint, response_status, response_body, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id, username, client_id, transmission_id) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, response_status = EXCLUDED.response_status,
+		    response_body = EXCLUDED.response_body, expires_at = EXCLUDED.expires_at`,
+		t.tenantID, t.username, t.clientID, t.id, fingerprint, response.Status, response.Body, s.now().Add(s.retention))
+	if err != nil {
+		return fmt.Errorf("failed to keep transmission response: %w", err)
+	}
+	return nil
+}
+
+// Release forgets a key; see Store
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	t, err := parseTransmissionKey(key)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM sync_transmissions
+		WHERE tenant_id = $1 AND username = $2 AND client_id = $3 AND transmission_id = $4`,
+		t.tenantID, t.username, t.clientID, t.id); err != nil {
+		return fmt.Errorf("failed to release transmission: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the state of a key; see Store
+func (s *PostgresStore) Lookup(ctx context.Context, key string) (*Status, error) {
+	t, err := parseTransmissionKey(key)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.get(ctx, t)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	return r.status(), nil
+}
+
+// Retention is how long responses are kept; see Store
+func (s *PostgresStore) Retention() time.Duration {
+	return s.retention
+}
+
+// get reads the unexpired record of a transmission, or nil when there is none
+func (s *PostgresStore) get(ctx context.Context, t transmission) (*record, error) {
+	var r record
+	var status sql.NullInt64
+	var body []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, response_status, response_body, expires_at
+		FROM sync_transmissions
+		WHERE tenant_id = $1 AND username = $2 AND client_id = $3 AND transmission_id = $4 AND expires_at > $5`,
+		t.tenantID, t.username, t.clientID, t.id, s.now()).Scan(&r.Fingerprint, &status, &body, &r.Expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transmission: %w", err)
+	}
+	if status.Valid {
+		r.Response = &Response{Status: int(status.Int64), Body: body}
+	}
+	return &r, nil
+}
+
+// sweep deletes expired transmissions, at most once a minute. Failures are left for the next
+// sweep; expired rows are ignored meanwhile.
+func (s *PostgresStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	now := s.now()
+	if now.Sub(s.swept) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.swept = now
+	s.mu.Unlock()
+	_, _ = s.db.ExecContext(ctx, `DELETE FROM sync_transmissions WHERE expires_at <= $1`, now)
+}
@@ -0,0 +1,91 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransmissionKey(t *testing.T) {
+	for _, ids := range [][4]string{
+		{"default", "alice", "tablet-7", "tx-1"},
+		{"acme", "a:b", "c", "d"},
+		{"acme", "a", "b:c", "d"},
+		{"default", "field worker", "field team 3", "tx:2"},
+	} {
+		parsed, err := parseTransmissionKey(TransmissionKey(ids[0], ids[1], ids[2], ids[3]))
+		require.NoError(t, err)
+		assert.Equal(t, transmission{tenantID: ids[0], username: ids[1], clientID: ids[2], id: ids[3]}, parsed)
+	}
+	assert.NotEqual(t, TransmissionKey("default", "a:b", "c", "tx"), TransmissionKey("default", "a", "b:c", "tx"))
+	assert.NotEqual(t, TransmissionKey("acme", "alice", "tablet-7", "tx-1"), TransmissionKey("globex", "alice", "tablet-7", "tx-1"))
+	assert.Equal(t, "sync-push:default:alice:tablet-7:tx-1", TransmissionKey("default", "alice", "tablet-7", "tx-1"), "keys of plain IDs are unchanged")
+
+	_, err := parseTransmissionKey("export:1")
+	assert.Error(t, err)
+	_, err = parseTransmissionKey("sync-push:tablet-7:tx-1")
+	assert.Error(t, err)
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewPostgresStore(db, time.Hour)
+	store.now = func() time.Time { return now }
+	key := TransmissionKey("acme", "alice", "tablet-7", "tx-1")
+	columns := []string{"fingerprint", "response_status", "response_body", "expires_at"}
+
+	// The first push claims the transmission
+	mock.ExpectExec("DELETE FROM sync_transmissions WHERE expires_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO sync_transmissions").
+		WithArgs("acme", "alice", "tablet-7", "tx-1", "a", now.Add(pendingTTL), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	response, err := store.Claim(ctx, key, "a")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	// Its response is kept
+	mock.ExpectExec("INSERT INTO sync_transmissions").
+		WithArgs("acme", "alice", "tablet-7", "tx-1", "a", 200, []byte(`{"success_count":2}`), now.Add(time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Complete(ctx, key, "a", Response{Status: 200, Body: []byte(`{"success_count":2}`)}))
+
+	// A retry gets the kept response instead of being applied again
+	mock.ExpectExec("INSERT INTO sync_transmissions").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT fingerprint, response_status, response_body, expires_at").
+		WithArgs("acme", "alice", "tablet-7", "tx-1", now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("a", 200, []byte(`{"success_count":2}`), now.Add(time.Hour)))
+	response, err = store.Claim(ctx, key, "a")
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, `{"success_count":2}`, string(response.Body))
+
+	// The transmission ID cannot be reused for other records
+	mock.ExpectExec("INSERT INTO sync_transmissions").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT fingerprint").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("a", 200, []byte(`{}`), now.Add(time.Hour)))
+	_, err = store.Claim(ctx, key, "b")
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	// A transmission still being processed is reported as such
+	mock.ExpectQuery("SELECT fingerprint").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("a", nil, nil, now.Add(pendingTTL)))
+	status, err := store.Lookup(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, StateProcessing, status.State)
+
+	mock.ExpectExec("DELETE FROM sync_transmissions WHERE tenant_id").
+		WithArgs("acme", "alice", "tablet-7", "tx-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Release(ctx, key))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/redis"
+)
+
+// keyPrefix namespaces the keys in a redis shared with other applications
+const keyPrefix = "synkronus:idempotency:"
+
+// RedisStore keeps keys in redis, so that a retry reaching another server behind the load
+// balancer is recognized
+type RedisStore struct {
+	client    *redis.Client
+	retention time.Duration
+}
+
+// NewRedisStore creates a store keeping responses for retention
+func NewRedisStore(client *redis.Client, retention time.Duration) *RedisStore {
+	return &RedisStore{client: client, retention: retention}
+}
+
+// Claim reserves a key; see Store
+func (s *RedisStore) Claim(ctx context.Context, key, fingerprint string) (*Response, error) {
+	// A key that expires between the two commands is claimed again
+	for attempt := 0; attempt < 2; attempt++ {
+		pending := record{Fingerprint: fingerprint, Expires: time.Now().Add(pendingTTL)}
+		claimed, err := s.client.SetNX(ctx, keyPrefix+key, encode(pending), pendingTTL)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, nil
+		}
+		r, err := s.get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+		return r.outcome(fingerprint)
+	}
+	return nil, ErrInProgress
+}
+
+// Complete keeps the response of a key; see Store
+func (s *RedisStore) Complete(ctx context.Context, key, fingerprint string, response Response) error {
+	completed := record{Fingerprint: fingerprint, Response: &response, Expires: time.Now().Add(s.retention)}
+	return s.client.Set(ctx, keyPrefix+key, encode(completed), s.retention)
+}
+
+// Release forgets a key; see Store
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, keyPrefix+key)
+}
+
+// Lookup returns the state of a key; see Store
+func (s *RedisStore) Lookup(ctx context.Context, key string) (*Status, error) {
+	r, err := s.get(ctx, key)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	return r.status(), nil
+}
+
+// Retention is how long responses are kept; see Store
+func (s *RedisStore) Retention() time.Duration {
+	return s.retention
+}
+
+// get reads the record of a key, or nil when there is none
+func (s *RedisStore) get(ctx context.Context, key string) (*record, error) {
+	data, ok, err := s.client.Get(ctx, keyPrefix+key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid idempotency record for %s: %w", key, err)
+	}
+	return &r, nil
+}
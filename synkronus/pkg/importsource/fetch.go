@@ -0,0 +1,243 @@
+// Package importsource pulls submissions from ODK Central and KoboToolbox forms into data imports,
+// for migrating to synkronus or for running both side by side for a while.
+//
+// Each submission becomes one CSV row of the import. Fields inside groups are named by their
+// path, such as "household/members". Repeats, locations and other structured values are written
+// as JSON. ODK Central submissions have the columns "__id" (the instance ID) and
+// "__system/submissionDate"; KoboToolbox submissions have "_uuid" and "_submission_time". These
+// are the default observation ID and creation date columns, so pulling a submission again
+// updates its observation instead of adding another.
+package importsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pageSize is the number of submissions requested at a time
+const pageSize = 1000
+
+// submission is a submission flattened into CSV cells by column
+type submission struct {
+	fields map[string]string
+	// cursor is the submission time as the server compares it, for the next pull
+	cursor string
+}
+
+// idColumn returns the column of the instance ID of submissions of a kind
+func idColumn(kind string) string {
+	if kind == KindKobo {
+		return "_uuid"
+	}
+	return "__id"
+}
+
+// submittedAtColumn returns the column of the submission time of submissions of a kind
+func submittedAtColumn(kind string) string {
+	if kind == KindKobo {
+		return "_submission_time"
+	}
+	return "__system/submissionDate"
+}
+
+// fetch returns up to limit submissions of a source received after the cursor, oldest first
+func fetch(ctx context.Context, client *http.Client, source Source, password string, limit int) ([]submission, error) {
+	if source.Kind == KindKobo {
+		return fetchKobo(ctx, client, source, password, limit)
+	}
+	return fetchCentral(ctx, client, source, password, limit)
+}
+
+// fetchCentral pages through the OData feed of an ODK Central form
+func fetchCentral(ctx context.Context, client *http.Client, source Source, password string, limit int) ([]submission, error) {
+	query := url.Values{}
+	query.Set("$top", strconv.Itoa(min(pageSize, limit)))
+	query.Set("$orderby", "__system/submissionDate")
+	if source.Cursor != "" {
+		query.Set("$filter", "__system/submissionDate gt "+source.Cursor)
+	}
+	next := fmt.Sprintf("%s/v1/projects/%s/forms/%s.svc/Submissions?%s",
+		source.URL, url.PathEscape(source.ProjectID), url.PathEscape(source.FormID), query.Encode())
+
+	var submissions []submission
+	for next != "" && len(submissions) < limit {
+		var page struct {
+			Value    []map[string]any `json:"value"`
+			NextLink string           `json:"@odata.nextLink"`
+		}
+		err := getJSON(ctx, client, next, func(req *http.Request) {
+			req.SetBasicAuth(source.Username, password)
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range page.Value {
+			fields := make(map[string]string)
+			flatten("", value, fields)
+			submissions = append(submissions, submission{fields: fields, cursor: fields["__system/submissionDate"]})
+		}
+		next = page.NextLink
+	}
+	return truncate(submissions, limit), nil
+}
+
+// fetchKobo pages through the data API of a KoboToolbox form
+func fetchKobo(ctx context.Context, client *http.Client, source Source, password string, limit int) ([]submission, error) {
+	query := url.Values{}
+	query.Set("format", "json")
+	query.Set("limit", strconv.Itoa(min(pageSize, limit)))
+	query.Set("sort", `{"_submission_time":1}`)
+	if source.Cursor != "" {
+		filter, err := json.Marshal(map[string]any{"_submission_time": map[string]string{"$gt": source.Cursor}})
+		if err != nil {
+			return nil, err
+		}
+		query.Set("query", string(filter))
+	}
+	next := fmt.Sprintf("%s/api/v2/assets/%s/data/?%s", source.URL, url.PathEscape(source.FormID), query.Encode())
+
+	var submissions []submission
+	for next != "" && len(submissions) < limit {
+		var page struct {
+			Next    string           `json:"next"`
+			Results []map[string]any `json:"results"`
+		}
+		err := getJSON(ctx, client, next, func(req *http.Request) {
+			req.Header.Set("Authorization", "Token "+password)
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range page.Results {
+			fields := make(map[string]string)
+			flatten("", result, fields)
+			// Submission times are UTC without a zone; the cursor keeps them as the server
+			// compares them
+			cursor := fields["_submission_time"]
+			if cursor != "" && !strings.HasSuffix(cursor, "Z") && !strings.Contains(cursor, "+") {
+				fields["_submission_time"] = cursor + "Z"
+			}
+			submissions = append(submissions, submission{fields: fields, cursor: cursor})
+		}
+		next = page.Next
+	}
+	return truncate(submissions, limit), nil
+}
+
+func truncate(submissions []submission, limit int) []submission {
+	if len(submissions) > limit {
+		return submissions[:limit]
+	}
+	return submissions
+}
+
+// getJSON sends an authenticated GET request and decodes the JSON response
+func getJSON(ctx context.Context, client *http.Client, rawURL string, authenticate func(*http.Request), v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	authenticate(req)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server answered %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// flatten writes the fields of a submission as CSV cells named by their path. OData links to
+// repeats are left out; GeoJSON locations, arrays and other values that are not text are written
+// as JSON.
+func flatten(prefix string, value any, fields map[string]string) {
+	switch v := value.(type) {
+	case nil:
+		fields[prefix] = ""
+	case string:
+		fields[prefix] = v
+	case bool:
+		fields[prefix] = strconv.FormatBool(v)
+	case float64:
+		fields[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		if _, geo := v["coordinates"]; geo && prefix != "" {
+			encoded, _ := json.Marshal(v)
+			fields[prefix] = string(encoded)
+			return
+		}
+		for key, child := range v {
+			if strings.Contains(key, "@odata") {
+				continue
+			}
+			path := key
+			if prefix != "" {
+				path = prefix + "/" + key
+			}
+			flatten(path, child, fields)
+		}
+	default:
+		encoded, _ := json.Marshal(v)
+		fields[prefix] = string(encoded)
+	}
+}
+
+// buildCSV writes submissions as a CSV file with one column per field of any submission and per
+// column the mapping names. Cells of columns mapped to array fields that are not JSON are split
+// on spaces, the way ODK separates the choices of select_multiple questions.
+func buildCSV(submissions []submission, columns []string, arrays map[string]bool) ([]byte, error) {
+	seen := make(map[string]bool)
+	for _, s := range submissions {
+		for column := range s.fields {
+			seen[column] = true
+		}
+	}
+	for _, column := range columns {
+		if column != "" {
+			seen[column] = true
+		}
+	}
+	header := make([]string, 0, len(seen))
+	for column := range seen {
+		header = append(header, column)
+	}
+	sort.Strings(header)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	row := make([]string, len(header))
+	for _, s := range submissions {
+		for i, column := range header {
+			cell := s.fields[column]
+			if arrays[column] && cell != "" && !strings.HasPrefix(cell, "[") {
+				encoded, _ := json.Marshal(strings.Fields(cell))
+				cell = string(encoded)
+			}
+			row[i] = cell
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
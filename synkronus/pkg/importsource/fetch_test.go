@@ -0,0 +1,125 @@
+package importsource
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCentral(t *testing.T) {
+	var filters []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "data@example.org" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/v1/projects/3/forms/household.svc/Submissions", r.URL.Path)
+		filters = append(filters, r.URL.Query().Get("$filter"))
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value":[{"__id":"uuid:a1","__system":{"submissionDate":"2024-03-01T10:00:00.000Z"},
+				"head_name":"Amina","members":4,"household":{"assets":"radio tv","member@odata.navigationLink":"Submissions('uuid:a1')/household/member"},
+				"location":{"type":"Point","coordinates":[36.8,-1.3,1700]}}],
+				"@odata.nextLink":"%s/v1/projects/3/forms/household.svc/Submissions?$skiptoken=abc"}`, server.URL)
+			return
+		}
+		fmt.Fprint(w, `{"value":[{"__id":"uuid:a2","__system":{"submissionDate":"2024-03-02T09:30:00.000Z"},"head_name":"Joseph","members":null,"household":{"assets":null}}]}`)
+	}))
+	defer server.Close()
+
+	source := Source{Kind: KindODKCentral, URL: server.URL, ProjectID: "3", FormID: "household", Username: "data@example.org"}
+	submissions, err := fetch(context.Background(), server.Client(), source, "secret", 10)
+	require.NoError(t, err)
+	require.Len(t, submissions, 2)
+	assert.Equal(t, map[string]string{
+		"__id":                    "uuid:a1",
+		"__system/submissionDate": "2024-03-01T10:00:00.000Z",
+		"head_name":               "Amina",
+		"members":                 "4",
+		"household/assets":        "radio tv",
+		"location":                `{"coordinates":[36.8,-1.3,1700],"type":"Point"}`,
+	}, submissions[0].fields)
+	assert.Equal(t, "2024-03-02T09:30:00.000Z", submissions[1].cursor)
+	assert.Equal(t, []string{"", ""}, filters)
+
+	// Later pulls only ask for newer submissions
+	source.Cursor = submissions[1].cursor
+	_, err = fetch(context.Background(), server.Client(), source, "secret", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "__system/submissionDate gt 2024-03-02T09:30:00.000Z", filters[2])
+
+	_, err = fetch(context.Background(), server.Client(), source, "wrong", 10)
+	assert.ErrorContains(t, err, "server answered 401")
+}
+
+func TestFetchKobo(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token kobo-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/api/v2/assets/aBc123/data/", r.URL.Path)
+		queries = append(queries, r.URL.Query().Get("query"))
+		fmt.Fprint(w, `{"count":1,"next":null,"results":[{"_id":17,"_uuid":"5f1c","_submission_time":"2024-03-01T10:00:00",
+			"household/assets":"radio","_geolocation":[-1.3,36.8],"consent":true}]}`)
+	}))
+	defer server.Close()
+
+	source := Source{Kind: KindKobo, URL: server.URL, FormID: "aBc123", Cursor: "2024-02-28T08:00:00"}
+	submissions, err := fetch(context.Background(), server.Client(), source, "kobo-token", 10)
+	require.NoError(t, err)
+	require.Len(t, submissions, 1)
+	assert.Equal(t, map[string]string{
+		"_id":              "17",
+		"_uuid":            "5f1c",
+		"_submission_time": "2024-03-01T10:00:00Z",
+		"household/assets": "radio",
+		"_geolocation":     "[-1.3,36.8]",
+		"consent":          "true",
+	}, submissions[0].fields)
+	// The cursor keeps the time as Kobo compares it
+	assert.Equal(t, "2024-03-01T10:00:00", submissions[0].cursor)
+	assert.Equal(t, []string{`{"_submission_time":{"$gt":"2024-02-28T08:00:00"}}`}, queries)
+}
+
+func TestBuildCSV(t *testing.T) {
+	content, err := buildCSV([]submission{
+		{fields: map[string]string{"__id": "uuid:a1", "household/assets": "radio tv", "note": "said \"no\", twice"}},
+		{fields: map[string]string{"__id": "uuid:a2", "household/assets": `["radio"]`}},
+	}, []string{"__id", "", "district"}, map[string]bool{"household/assets": true})
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"__id", "district", "household/assets", "note"},
+		{"uuid:a1", "", `["radio","tv"]`, `said "no", twice`},
+		{"uuid:a2", "", `["radio"]`, ""},
+	}, rows)
+}
+
+func TestValidate(t *testing.T) {
+	input := SourceInput{
+		Name: "Household survey", Kind: KindODKCentral, URL: "https://central.example.org/", ProjectID: "3",
+		FormID: "household", Username: "data@example.org", Password: "secret",
+		Mapping: dataimport.Mapping{FormType: "household", Columns: map[string]string{"head_name": "head_name"}},
+	}
+	require.NoError(t, input.Validate())
+	assert.Equal(t, "https://central.example.org", input.URL)
+	assert.Equal(t, "__id", input.Mapping.ObservationIDColumn)
+	assert.Equal(t, "__system/submissionDate", input.Mapping.CreatedAtColumn)
+
+	err := (&SourceInput{Kind: "ona", URL: "ftp://example.org", IntervalMinutes: -1}).Validate()
+	assert.ErrorIs(t, err, ErrInvalidSource)
+	assert.EqualError(t, err, `invalid import source: name is required; kind must be "odk-central" or "kobo"; url must be an http or https URL; form_id is required; password is required; mapping.form_type is required; interval_minutes must be between 0 and 10080`)
+}
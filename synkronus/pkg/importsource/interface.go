@@ -0,0 +1,145 @@
+package importsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+)
+
+// Kinds of servers submissions are pulled from
+const (
+	// KindODKCentral pulls from the OData feed of an ODK Central form, signing in with the email
+	// and password of a web user or app user
+	KindODKCentral = "odk-central"
+	// KindKobo pulls from the data API of a KoboToolbox form with an API token
+	KindKobo = "kobo"
+)
+
+// Common errors
+var (
+	// ErrInvalidSource is returned when a source cannot be saved as given
+	ErrInvalidSource = errors.New("invalid import source")
+	// ErrNotFound is returned when a source does not exist
+	ErrNotFound = errors.New("import source not found")
+	// ErrFetchFailed is returned when the server of a source cannot be reached or refuses a pull
+	ErrFetchFailed = errors.New("failed to fetch submissions")
+)
+
+// MaxInterval is the longest schedule, in minutes, a source can be pulled on
+const MaxInterval = 7 * 24 * 60
+
+// Source is a form on an ODK Central or KoboToolbox server whose submissions are imported as
+// observations through a data import mapping. Columns are the submission fields by path, such as
+// "household/members"; see the package documentation for the columns every submission has.
+type Source struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// URL is the base URL of the server, e.g. https://central.example.org or https://kf.kobotoolbox.org
+	URL string `json:"url"`
+	// ProjectID is the numeric project ID of an ODK Central form; unused for KoboToolbox
+	ProjectID string `json:"project_id,omitempty"`
+	// FormID is the xmlFormId of an ODK Central form or the asset UID of a KoboToolbox form
+	FormID string `json:"form_id"`
+	// Username is the ODK Central email; KoboToolbox uses the API token alone
+	Username string             `json:"username,omitempty"`
+	Mapping  dataimport.Mapping `json:"mapping"`
+	// IntervalMinutes pulls new submissions on this schedule; 0 pulls them once, as a migration
+	IntervalMinutes int     `json:"interval_minutes"`
+	NextRunAt       *string `json:"next_run_at,omitempty"`
+	// Cursor is the submission time of the last submission pulled; later runs pull newer ones
+	Cursor       string  `json:"cursor,omitempty"`
+	LastRunAt    *string `json:"last_run_at,omitempty"`
+	LastImportID *string `json:"last_import_id,omitempty"`
+	LastError    string  `json:"last_error,omitempty"`
+	CreatedBy    string  `json:"created_by"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// SourceInput is a source to create. Password is the ODK Central password or the KoboToolbox
+// API token; it is stored encrypted and never returned.
+type SourceInput struct {
+	Name            string             `json:"name"`
+	Kind            string             `json:"kind"`
+	URL             string             `json:"url"`
+	ProjectID       string             `json:"project_id,omitempty"`
+	FormID          string             `json:"form_id"`
+	Username        string             `json:"username,omitempty"`
+	Password        string             `json:"password"`
+	Mapping         dataimport.Mapping `json:"mapping"`
+	IntervalMinutes int                `json:"interval_minutes,omitempty"`
+}
+
+// Validate checks a source before it is created, filling in the default observation ID and
+// creation date columns of its kind
+func (in *SourceInput) Validate() error {
+	var problems []string
+	if strings.TrimSpace(in.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	switch in.Kind {
+	case KindODKCentral:
+		if in.ProjectID == "" {
+			problems = append(problems, "project_id is required for ODK Central")
+		}
+		if in.Username == "" {
+			problems = append(problems, "username is required for ODK Central")
+		}
+	case KindKobo:
+	default:
+		problems = append(problems, fmt.Sprintf("kind must be %q or %q", KindODKCentral, KindKobo))
+	}
+	if u, err := url.Parse(in.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		problems = append(problems, "url must be an http or https URL")
+	}
+	if in.FormID == "" {
+		problems = append(problems, "form_id is required")
+	}
+	if in.Password == "" {
+		problems = append(problems, "password is required")
+	}
+	if in.Mapping.FormType == "" {
+		problems = append(problems, "mapping.form_type is required")
+	}
+	if in.IntervalMinutes < 0 || in.IntervalMinutes > MaxInterval {
+		problems = append(problems, fmt.Sprintf("interval_minutes must be between 0 and %d", MaxInterval))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidSource, strings.Join(problems, "; "))
+	}
+
+	in.URL = strings.TrimSuffix(in.URL, "/")
+	if in.Mapping.ObservationIDColumn == "" {
+		in.Mapping.ObservationIDColumn = idColumn(in.Kind)
+	}
+	if in.Mapping.CreatedAtColumn == "" {
+		in.Mapping.CreatedAtColumn = submittedAtColumn(in.Kind)
+	}
+	return nil
+}
+
+// Service keeps import sources and pulls their submissions into data imports
+type Service interface {
+	// Create checks and saves a source; it is pulled for the first time right away
+	Create(ctx context.Context, input SourceInput, username string) (*Source, error)
+
+	// Get returns a source
+	Get(ctx context.Context, id string) (*Source, error)
+
+	// List returns all sources ordered by name
+	List(ctx context.Context) ([]Source, error)
+
+	// Delete removes a source; imports it queued are kept
+	Delete(ctx context.Context, id string) error
+
+	// Pull fetches the submissions received since the last pull and queues them as a data import.
+	// It returns nil when there are no new submissions.
+	Pull(ctx context.Context, id string) (*dataimport.Job, error)
+
+	// Run pulls sources when they are due until ctx is cancelled
+	Run(ctx context.Context)
+}
@@ -0,0 +1,360 @@
+package importsource
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/pkg/dataimport"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/outbound"
+	"github.com/opendataensemble/synkronus/pkg/secretbox"
+)
+
+// credentialPurpose separates the encryption key of source credentials from other sealed values
+const credentialPurpose = "synkronus import source:"
+
+// pollInterval is how often Run looks for sources that are due
+const pollInterval = time.Minute
+
+// Config contains import source configuration
+type Config struct {
+	// Secrets encrypt source credentials; the first encrypts, all are tried to decrypt
+	Secrets [][]byte
+	// Outbound restricts the servers sources can point at
+	Outbound outbound.Policy
+	// Timeout bounds each request to a source server
+	Timeout time.Duration
+}
+
+type service struct {
+	db       *sql.DB
+	importer dataimport.Service
+	forms    dataimport.Forms
+	config   Config
+	client   *http.Client
+	log      *logger.Logger
+	wake     chan struct{}
+}
+
+// NewService creates an import source service; start Run to pull sources on schedule
+func NewService(db *sql.DB, importer dataimport.Service, forms dataimport.Forms, config Config, log *logger.Logger) Service {
+	if config.Timeout == 0 {
+		config.Timeout = time.Minute
+	}
+	return &service{
+		db:       db,
+		importer: importer,
+		forms:    forms,
+		config:   config,
+		client:   outbound.NewClient(config.Outbound, config.Timeout),
+		log:      log,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+const sourceColumns = `id, name, kind, url, project_id, form_id, username, mapping, interval_minutes,
+	next_run_at, cursor, last_run_at, last_import_id, last_error, created_by, created_at`
+
+// Create checks and saves a source, scheduling its first pull right away
+func (s *service) Create(ctx context.Context, input SourceInput, username string) (*Source, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if len(s.config.Secrets) == 0 {
+		return nil, errors.New("no secret is configured to encrypt source credentials")
+	}
+	if err := s.config.Outbound.CheckURL(ctx, input.URL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	credential, err := secretbox.Seal(credentialPurpose, s.config.Secrets[0], []byte(input.Password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+	mapping, err := json.Marshal(input.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode mapping: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		INSERT INTO import_sources (id, name, kind, url, project_id, form_id, username, credential, mapping, interval_minutes, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), $11)
+		RETURNING `+sourceColumns,
+		uuid.New(), strings.TrimSpace(input.Name), input.Kind, input.URL, input.ProjectID, input.FormID, input.Username,
+		credential, mapping, input.IntervalMinutes, username,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import source: %w", err)
+	}
+	sources, err := scanSources(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Import source created", "id", sources[0].ID, "kind", input.Kind, "form", input.FormID, "user", username)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return &sources[0], nil
+}
+
+// Get returns a source
+func (s *service) Get(ctx context.Context, id string) (*Source, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT "+sourceColumns+" FROM import_sources WHERE id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import source: %w", err)
+	}
+	sources, err := scanSources(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, ErrNotFound
+	}
+	return &sources[0], nil
+}
+
+// List returns all sources ordered by name
+func (s *service) List(ctx context.Context) ([]Source, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+sourceColumns+" FROM import_sources ORDER BY name, created_at")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import sources: %w", err)
+	}
+	return scanSources(rows)
+}
+
+// Delete removes a source
+func (s *service) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	result, err := s.db.ExecContext(ctx, "DELETE FROM import_sources WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete import source: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanSources reads sources from rows and closes them
+func scanSources(rows *sql.Rows) ([]Source, error) {
+	defer rows.Close()
+	sources := make([]Source, 0)
+	for rows.Next() {
+		var source Source
+		var mapping []byte
+		if err := rows.Scan(&source.ID, &source.Name, &source.Kind, &source.URL, &source.ProjectID, &source.FormID,
+			&source.Username, &mapping, &source.IntervalMinutes, &source.NextRunAt, &source.Cursor, &source.LastRunAt,
+			&source.LastImportID, &source.LastError, &source.CreatedBy, &source.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan import source: %w", err)
+		}
+		if err := json.Unmarshal(mapping, &source.Mapping); err != nil {
+			return nil, fmt.Errorf("invalid mapping of import source %s: %w", source.ID, err)
+		}
+		sources = append(sources, source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list import sources: %w", err)
+	}
+	return sources, nil
+}
+
+// Pull fetches new submissions of a source and queues them as a data import
+func (s *service) Pull(ctx context.Context, id string) (*dataimport.Job, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	job, _, err := s.pull(ctx, id, false)
+	return job, err
+}
+
+// Run pulls due sources immediately, then whenever one is created and every poll interval,
+// until ctx is cancelled
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.pullDue(ctx); err != nil && ctx.Err() == nil {
+			s.log.Warn("Failed to pull import sources", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// pullDue pulls every source whose next run has come
+func (s *service) pullDue(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM import_sources WHERE next_run_at <= NOW() ORDER BY next_run_at")
+	if err != nil {
+		return fmt.Errorf("failed to list due import sources: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan import source: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list due import sources: %w", err)
+	}
+
+	for _, id := range ids {
+		job, pulled, err := s.pull(ctx, id, true)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			s.log.Warn("Failed to pull import source", "error", err, "id", id)
+		case job != nil:
+			s.log.Info("Import source pulled", "id", id, "import", job.ID, "submissions", pulled)
+		}
+	}
+	return nil
+}
+
+// pull fetches the submissions of a source received since its cursor, queues them as a data
+// import and schedules the next pull. The source row stays locked meanwhile, so a source is never
+// pulled twice at once; scheduled pulls skip sources another replica is pulling or that are no
+// longer due. It returns the queued import, nil when there was nothing new, and the number of
+// submissions pulled.
+func (s *service) pull(ctx context.Context, id string, scheduled bool) (*dataimport.Job, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "SELECT " + sourceColumns + ", credential FROM import_sources WHERE id = $1 FOR UPDATE"
+	if scheduled {
+		query = "SELECT " + sourceColumns + ", credential FROM import_sources WHERE id = $1 AND next_run_at <= NOW() FOR UPDATE SKIP LOCKED"
+	}
+	var source Source
+	var mapping, credential []byte
+	err = tx.QueryRowContext(ctx, query, id).Scan(&source.ID, &source.Name, &source.Kind, &source.URL, &source.ProjectID,
+		&source.FormID, &source.Username, &mapping, &source.IntervalMinutes, &source.NextRunAt, &source.Cursor,
+		&source.LastRunAt, &source.LastImportID, &source.LastError, &source.CreatedBy, &source.CreatedAt, &credential)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if scheduled {
+				return nil, 0, nil
+			}
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to get import source: %w", err)
+	}
+	if err := json.Unmarshal(mapping, &source.Mapping); err != nil {
+		return nil, 0, fmt.Errorf("invalid mapping of import source %s: %w", source.ID, err)
+	}
+
+	job, cursor, submissions, pullErr := s.fetchAndQueue(ctx, source, credential)
+
+	// Pull again soon while submissions are left over; a failed one-time pull waits for an admin
+	next := "NULL"
+	switch {
+	case pullErr == nil && submissions == dataimport.MaxRows:
+		next = "NOW()"
+	case source.IntervalMinutes > 0:
+		next = "NOW() + interval_minutes * INTERVAL '1 minute'"
+	}
+	lastError := ""
+	if pullErr != nil {
+		lastError = pullErr.Error()
+	}
+	var importID *string
+	if job != nil {
+		importID = &job.ID
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE import_sources
+		SET next_run_at = `+next+`, last_run_at = NOW(), last_error = $2, cursor = $3,
+			last_import_id = COALESCE($4, last_import_id)
+		WHERE id = $1`,
+		id, lastError, cursor, importID,
+	); err != nil {
+		return nil, 0, fmt.Errorf("failed to update import source: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to update import source: %w", err)
+	}
+	return job, submissions, pullErr
+}
+
+// fetchAndQueue fetches the submissions of a source received since its cursor and queues them as
+// a data import. It returns the import, nil when there was nothing new, the cursor to continue
+// from and the number of submissions.
+func (s *service) fetchAndQueue(ctx context.Context, source Source, credential []byte) (*dataimport.Job, string, int, error) {
+	password, err := secretbox.Open(credentialPurpose, s.config.Secrets, credential)
+	if err != nil {
+		return nil, source.Cursor, 0, fmt.Errorf("failed to decrypt credentials; was JWT_SECRET changed without listing the old one in JWT_PREVIOUS_SECRETS? %w", err)
+	}
+	submissions, err := fetch(ctx, s.client, source, string(password), dataimport.MaxRows)
+	if err != nil {
+		return nil, source.Cursor, 0, fmt.Errorf("%w from %s: %v", ErrFetchFailed, source.URL, err)
+	}
+	if len(submissions) == 0 {
+		return nil, source.Cursor, 0, nil
+	}
+
+	mapping := source.Mapping
+	columns := []string{mapping.ObservationIDColumn, mapping.CreatedAtColumn, mapping.OrgUnitIDColumn}
+	for column := range mapping.Columns {
+		columns = append(columns, column)
+	}
+	content, err := buildCSV(submissions, columns, s.arrayColumns(ctx, mapping))
+	if err != nil {
+		return nil, source.Cursor, 0, fmt.Errorf("failed to write submissions: %w", err)
+	}
+	filename := fmt.Sprintf("%s-%s.csv", source.FormID, time.Now().UTC().Format("20060102T150405Z"))
+	job, err := s.importer.Start(ctx, filename, bytes.NewReader(content), mapping, source.CreatedBy)
+	if err != nil {
+		return nil, source.Cursor, 0, fmt.Errorf("failed to queue import: %w", err)
+	}
+	return job, submissions[len(submissions)-1].cursor, len(submissions), nil
+}
+
+// arrayColumns returns the columns mapped to array fields of the mapping's form in the active app
+// bundle, which imports are checked against. Failures to read the form are left to the import,
+// which reports them.
+func (s *service) arrayColumns(ctx context.Context, mapping dataimport.Mapping) map[string]bool {
+	arrays := make(map[string]bool)
+	manifest, err := s.forms.GetManifest(ctx)
+	if err != nil {
+		return arrays
+	}
+	info, err := s.forms.GetAppInfo(ctx, manifest.Version)
+	if err != nil {
+		return arrays
+	}
+	types := make(map[string]string)
+	for _, field := range info.Forms[mapping.FormType].Fields {
+		types[field.Name] = field.Type
+	}
+	for column, field := range mapping.Columns {
+		if types[field] == "array" {
+			arrays[column] = true
+		}
+	}
+	return arrays
+}
@@ -0,0 +1,69 @@
+package invite
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+)
+
+// Common errors
+var (
+	// ErrInvitationNotFound is returned when revoking an invitation that does not exist or was accepted
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvalidInvitation is returned when accepting with a token that is unknown, expired or already used
+	ErrInvalidInvitation = errors.New("invitation is invalid or has expired")
+	// ErrInvalidEmail is returned when the invitee's email address cannot be parsed
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailNotConfigured is returned when inviting without an SMTP server configured
+	ErrEmailNotConfigured = errors.New("email delivery is not configured")
+)
+
+// DefaultLifetime is how long an invitation can be accepted for unless configured otherwise
+const DefaultLifetime = 72 * time.Hour
+
+// Invitation is a pending or accepted invitation to create an account. The token that
+// accepts it is only ever sent to the invitee; the server keeps a hash of it.
+type Invitation struct {
+	ID         string      `json:"id" db:"id"`
+	Username   string      `json:"username" db:"username"`
+	Email      string      `json:"email" db:"email"`
+	Role       models.Role `json:"role" db:"role"`
+	InvitedBy  string      `json:"invited_by" db:"invited_by"`
+	CreatedAt  string      `json:"created_at" db:"created_at"`
+	ExpiresAt  string      `json:"expires_at" db:"expires_at"`
+	AcceptedAt *string     `json:"accepted_at,omitempty" db:"accepted_at"`
+}
+
+// Mailer delivers invitation emails
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// Config contains invitation settings
+type Config struct {
+	// Lifetime is how long an invitation can be accepted for
+	Lifetime time.Duration
+	// AcceptURL, when set, is included in the email with the token appended as a query
+	// parameter, e.g. https://example.org/invite?token=...
+	AcceptURL string
+	// Mailer sends the invitations; without one, inviting fails with ErrEmailNotConfigured
+	Mailer Mailer
+}
+
+// Service invites users by email and creates their account when they accept
+type Service interface {
+	// Invite records an invitation and emails its token to the invitee. A pending invitation
+	// for the same username is replaced.
+	Invite(ctx context.Context, username, email string, role models.Role, invitedBy string) (*Invitation, error)
+
+	// Accept creates the invited account with the invitee's chosen password
+	Accept(ctx context.Context, token, password string) (*models.User, *Invitation, error)
+
+	// List returns pending invitations, newest first
+	List(ctx context.Context) ([]Invitation, error)
+
+	// Revoke deletes a pending invitation
+	Revoke(ctx context.Context, id string) error
+}
@@ -0,0 +1,41 @@
+package invite
+
+import (
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMailer(t *testing.T) {
+	mailer := NewSMTPMailer(SMTPConfig{Host: "smtp.example.org", Port: 587, Username: "synkronus", Password: "secret", From: "synkronus@example.org"})
+	var addr, message string
+	mailer.send = func(a string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		addr, message = a, string(msg)
+		assert.NotNil(t, auth)
+		assert.Equal(t, []string{"amina@example.org"}, to)
+		return nil
+	}
+
+	require.NoError(t, mailer.Send(context.Background(), "amina@example.org", "Invited", "line one\nline two\n"))
+	assert.Equal(t, "smtp.example.org:587", addr)
+	assert.True(t, strings.HasPrefix(message, "From: synkronus@example.org\r\nTo: amina@example.org\r\nSubject: Invited\r\n"))
+	assert.True(t, strings.HasSuffix(message, "\r\n\r\nline one\r\nline two\r\n"))
+}
+
+func TestMessage(t *testing.T) {
+	s := &service{config: Config{Lifetime: 72 * time.Hour}}
+	body := s.message("amina", models.RoleReadWrite, "abc123")
+	assert.Contains(t, body, `as "amina" with the read-write role`)
+	assert.Contains(t, body, "Your invitation code is:\nabc123\n")
+	assert.NotContains(t, body, "http")
+
+	s.config.AcceptURL = "https://example.org/invite?lang=en"
+	body = s.message("amina", models.RoleReadWrite, "abc123")
+	assert.Contains(t, body, "https://example.org/invite?lang=en&token=abc123\n")
+}
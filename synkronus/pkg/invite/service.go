@@ -0,0 +1,215 @@
+package invite
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/user"
+)
+
+// tokenBytes is the number of random bytes in an invitation token
+const tokenBytes = 32
+
+type service struct {
+	db          *sql.DB
+	userService user.UserServiceInterface
+	config      Config
+	log         *logger.Logger
+}
+
+// NewService creates a new invitation service; accepted invitations create accounts through userService
+func NewService(db *sql.DB, userService user.UserServiceInterface, config Config, log *logger.Logger) Service {
+	if config.Lifetime <= 0 {
+		config.Lifetime = DefaultLifetime
+	}
+	return &service{db: db, userService: userService, config: config, log: log}
+}
+
+// hashToken returns the form a token is stored and looked up in
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Invite records an invitation and emails its token to the invitee
+func (s *service) Invite(ctx context.Context, username, email string, role models.Role, invitedBy string) (*Invitation, error) {
+	if role != models.RoleReadOnly && role != models.RoleReadWrite && role != models.RoleAdmin {
+		return nil, user.ErrInvalidRole
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	if s.config.Mailer == nil {
+		return nil, ErrEmailNotConfigured
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)", username).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check for existing user: %w", err)
+	}
+	if exists {
+		return nil, user.ErrUserExists
+	}
+
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_invitations WHERE username = $1 AND accepted_at IS NULL", username); err != nil {
+		return nil, fmt.Errorf("failed to replace pending invitation: %w", err)
+	}
+
+	var inv Invitation
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_invitations (id, username, email, role, token_hash, invited_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7)
+		RETURNING id, username, email, role, invited_by, created_at, expires_at`,
+		uuid.New(), username, address.Address, role, hashToken(token), invitedBy, time.Now().Add(s.config.Lifetime),
+	).Scan(&inv.ID, &inv.Username, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt)
+	if err != nil {
+		s.log.Error("Failed to create invitation", "error", err, "username", username)
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	// Only keep the invitation if the invitee can receive it
+	if err := s.config.Mailer.Send(ctx, address.Address, "You have been invited to Synkronus", s.message(username, role, token)); err != nil {
+		s.log.Error("Failed to send invitation", "error", err, "username", username)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invitation: %w", err)
+	}
+
+	s.log.Info("User invited", "username", username, "role", role, "invitedBy", invitedBy)
+	return &inv, nil
+}
+
+// message formats the invitation email
+func (s *service) message(username string, role models.Role, token string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You have been invited to Synkronus as %q with the %s role.\n\n", username, role)
+	if s.config.AcceptURL != "" {
+		fmt.Fprintf(&b, "Choose your password here:\n%s\n\n", s.acceptLink(token))
+	}
+	fmt.Fprintf(&b, "Your invitation code is:\n%s\n\n", token)
+	fmt.Fprintf(&b, "The invitation expires in %s and can only be used once.\n", s.config.Lifetime)
+	return b.String()
+}
+
+// acceptLink appends the token to the configured accept URL
+func (s *service) acceptLink(token string) string {
+	link, err := url.Parse(s.config.AcceptURL)
+	if err != nil {
+		return s.config.AcceptURL
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// Accept creates the invited account with the invitee's chosen password
+func (s *service) Accept(ctx context.Context, token, password string) (*models.User, *Invitation, error) {
+	// Claiming the invitation first means a token can only ever create one account
+	var inv Invitation
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE user_invitations SET accepted_at = NOW()
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		RETURNING id, username, email, role, invited_by, created_at, expires_at, accepted_at`,
+		hashToken(token),
+	).Scan(&inv.ID, &inv.Username, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidInvitation
+		}
+		s.log.Error("Failed to claim invitation", "error", err)
+		return nil, nil, fmt.Errorf("failed to claim invitation: %w", err)
+	}
+
+	newUser, err := s.userService.CreateUser(ctx, inv.Username, password, inv.Role)
+	if err != nil {
+		// Release the claim so the invitee can try again, e.g. with a valid password
+		if _, releaseErr := s.db.ExecContext(ctx, "UPDATE user_invitations SET accepted_at = NULL WHERE id = $1", inv.ID); releaseErr != nil {
+			s.log.Error("Failed to release invitation", "error", releaseErr, "id", inv.ID)
+		}
+		return nil, nil, err
+	}
+
+	s.log.Info("Invitation accepted", "username", inv.Username, "invitedBy", inv.InvitedBy)
+	return newUser, &inv, nil
+}
+
+// List returns pending invitations, newest first
+func (s *service) List(ctx context.Context) ([]Invitation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, username, email, role, invited_by, created_at, expires_at, accepted_at
+		FROM user_invitations WHERE accepted_at IS NULL
+		ORDER BY created_at DESC`)
+	if err != nil {
+		s.log.Error("Failed to query invitations", "error", err)
+		return nil, fmt.Errorf("failed to query invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := make([]Invitation, 0)
+	for rows.Next() {
+		var inv Invitation
+		if err := rows.Scan(&inv.ID, &inv.Username, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// Revoke deletes a pending invitation
+func (s *service) Revoke(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvitationNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM user_invitations WHERE id = $1 AND accepted_at IS NULL", id)
+	if err != nil {
+		s.log.Error("Failed to revoke invitation", "error", err, "id", id)
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrInvitationNotFound
+	}
+
+	s.log.Info("Invitation revoked", "id", id)
+	return nil
+}
@@ -0,0 +1,69 @@
+package latency
+
+import (
+	"math"
+	"sort"
+)
+
+// growth is the ratio between the upper bounds of neighbouring buckets
+const growth = 1.1
+
+// maxBucket caps the buckets at 1.1^250 ms, longer than any request lives
+const maxBucket = 250
+
+// bucketOf returns the bucket of a duration in milliseconds: the smallest i with ms <= 1.1^i
+func bucketOf(ms float64) int {
+	if ms <= 1 {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(ms) / math.Log(growth)))
+	// Guard against rounding just below the bound
+	if math.Pow(growth, float64(i)) < ms {
+		i++
+	}
+	return min(i, maxBucket)
+}
+
+// upperBound returns the longest duration in milliseconds counted by a bucket
+func upperBound(bucket int) float64 {
+	return math.Round(math.Pow(growth, float64(bucket))*100) / 100
+}
+
+// histogram counts operations per bucket
+type histogram map[int]int64
+
+func (h histogram) add(other histogram) {
+	for bucket, count := range other {
+		h[bucket] += count
+	}
+}
+
+// stats returns the count and percentiles of the histogram
+func (h histogram) stats(operation string) Stats {
+	buckets := make([]int, 0, len(h))
+	var total int64
+	for bucket, count := range h {
+		buckets = append(buckets, bucket)
+		total += count
+	}
+	sort.Ints(buckets)
+
+	percentile := func(q float64) float64 {
+		rank := int64(math.Ceil(q * float64(total)))
+		var seen int64
+		for _, bucket := range buckets {
+			seen += h[bucket]
+			if seen >= rank {
+				return upperBound(bucket)
+			}
+		}
+		return 0
+	}
+	return Stats{
+		Operation: operation,
+		Count:     total,
+		P50Ms:     percentile(0.50),
+		P95Ms:     percentile(0.95),
+		P99Ms:     percentile(0.99),
+	}
+}
@@ -0,0 +1,77 @@
+// Package latency tracks how long pull, push and export operations take, in per-day
+// histograms stored in the database, and reports their percentiles for SLO reviews.
+package latency
+
+import (
+	"context"
+	"time"
+)
+
+// Operations whose latency is tracked
+const (
+	OperationPull   = "pull"
+	OperationPush   = "push"
+	OperationExport = "export"
+)
+
+// Operations lists the tracked operations in report order
+var Operations = []string{OperationPull, OperationPush, OperationExport}
+
+// Stats summarises the latency of one operation. Percentiles are upper bounds, at most 10%
+// above the exact value.
+type Stats struct {
+	Operation string  `json:"operation"`
+	Count     int64   `json:"count"`
+	P50Ms     float64 `json:"p50Ms"`
+	P95Ms     float64 `json:"p95Ms"`
+	P99Ms     float64 `json:"p99Ms"`
+}
+
+// DayStats is the latency of one operation on one UTC day
+type DayStats struct {
+	Day string `json:"day"`
+	Stats
+}
+
+// Report is the latency of the tracked operations over a range of days
+type Report struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Days holds a rollup per day and operation, oldest first; days without operations are left out
+	Days []DayStats `json:"days"`
+	// Overall holds one rollup per operation over the whole range
+	Overall []Stats `json:"overall"`
+}
+
+// Filter selects the days and operation of a report
+type Filter struct {
+	// From and To are the first and last UTC day of the report
+	From time.Time
+	To   time.Time
+	// Operation limits the report to one operation; empty reports all
+	Operation string
+}
+
+// Config controls flushing and slow operation logging
+type Config struct {
+	// FlushInterval is how often the observed latencies are added to the database
+	FlushInterval time.Duration
+	// SlowThreshold logs a warning for every operation taking longer; 0 disables it
+	SlowThreshold time.Duration
+}
+
+// Service records operation latencies and reports their daily percentiles
+type Service interface {
+	// Observe records the duration of an operation. It only counts in memory; the counts are
+	// added to the database by Flush.
+	Observe(operation string, duration time.Duration)
+
+	// Flush adds the latencies observed since the last flush to the database
+	Flush(ctx context.Context) error
+
+	// Run flushes on every FlushInterval until ctx is done
+	Run(ctx context.Context)
+
+	// Report returns the latency percentiles of the filtered days
+	Report(ctx context.Context, filter Filter) (*Report, error)
+}
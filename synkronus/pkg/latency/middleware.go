@@ -0,0 +1,57 @@
+package latency
+
+import (
+	"net/http"
+	"time"
+)
+
+// Track records the latency of the wrapped handlers as the given operation. Responses with a
+// client error status are not counted, since they are rejected before any work is done. With a
+// nil service requests pass through unchanged.
+func Track(s Service, operation string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if s == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(status, r)
+			if status.status < 400 || status.status >= 500 {
+				s.Observe(operation, time.Since(start))
+			}
+		})
+	}
+}
+
+// statusWriter notes the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush lets streamed exports flush through the wrapper
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
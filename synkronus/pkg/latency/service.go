@@ -0,0 +1,231 @@
+package latency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// dayFormat is the format of report days
+const dayFormat = "2006-01-02"
+
+// defaultFlushInterval is used when the configuration sets none
+const defaultFlushInterval = time.Minute
+
+// key identifies the histogram of one operation on one day
+type key struct {
+	day       string
+	operation string
+}
+
+// store persists the daily histograms
+type store interface {
+	// add adds bucket counts to the stored histograms
+	add(ctx context.Context, counts map[key]histogram) error
+	// histograms returns the stored histograms of the days from and to, inclusive
+	histograms(ctx context.Context, from, to string, operation string) (map[key]histogram, error)
+}
+
+type service struct {
+	store  store
+	config Config
+	log    *logger.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[key]histogram
+}
+
+// NewService creates a new latency service storing histograms in the latency_histograms table
+func NewService(db *sql.DB, config Config, log *logger.Logger) Service {
+	return newService(&sqlStore{db: db}, config, log)
+}
+
+func newService(store store, config Config, log *logger.Logger) *service {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+	return &service{store: store, config: config, log: log, now: time.Now, pending: make(map[key]histogram)}
+}
+
+// Observe counts an operation in the histogram of the current UTC day
+func (s *service) Observe(operation string, duration time.Duration) {
+	if s.config.SlowThreshold > 0 && duration > s.config.SlowThreshold {
+		s.log.Warn("Slow operation", "operation", operation, "durationMs", duration.Milliseconds())
+	}
+
+	k := key{day: s.now().UTC().Format(dayFormat), operation: operation}
+	bucket := bucketOf(float64(duration) / float64(time.Millisecond))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[k] == nil {
+		s.pending[k] = make(histogram)
+	}
+	s.pending[k][bucket]++
+}
+
+// Flush adds the pending counts to the database. Counts that fail to be stored are kept for
+// the next flush.
+func (s *service) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	counts := s.pending
+	s.pending = make(map[key]histogram)
+	s.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+	if err := s.store.add(ctx, counts); err != nil {
+		s.mu.Lock()
+		for k, h := range counts {
+			if s.pending[k] == nil {
+				s.pending[k] = make(histogram)
+			}
+			s.pending[k].add(h)
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("failed to store latency histograms: %w", err)
+	}
+	return nil
+}
+
+// Run flushes on every FlushInterval until ctx is done. The caller flushes once more after
+// the server has stopped, so that the last requests are counted.
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				s.log.Error("Failed to flush latency histograms", "error", err)
+			}
+		}
+	}
+}
+
+// Report returns the daily and overall percentiles of the filtered days
+func (s *service) Report(ctx context.Context, filter Filter) (*Report, error) {
+	from := filter.From.UTC().Format(dayFormat)
+	to := filter.To.UTC().Format(dayFormat)
+	stored, err := s.store.histograms(ctx, from, to, filter.Operation)
+	if err != nil {
+		s.log.Error("Failed to query latency histograms", "error", err)
+		return nil, fmt.Errorf("failed to query latency histograms: %w", err)
+	}
+
+	// Include what this server has not flushed yet, so today's figures are current
+	s.mu.Lock()
+	for k, h := range s.pending {
+		if k.day < from || k.day > to || (filter.Operation != "" && k.operation != filter.Operation) {
+			continue
+		}
+		if stored[k] == nil {
+			stored[k] = make(histogram)
+		}
+		stored[k].add(h)
+	}
+	s.mu.Unlock()
+
+	keys := make([]key, 0, len(stored))
+	for k := range stored {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b key) int {
+		if a.day != b.day {
+			if a.day < b.day {
+				return -1
+			}
+			return 1
+		}
+		return slices.Index(Operations, a.operation) - slices.Index(Operations, b.operation)
+	})
+
+	report := &Report{From: from, To: to, Days: make([]DayStats, 0, len(keys)), Overall: make([]Stats, 0)}
+	overall := make(map[string]histogram)
+	for _, k := range keys {
+		report.Days = append(report.Days, DayStats{Day: k.day, Stats: stored[k].stats(k.operation)})
+		if overall[k.operation] == nil {
+			overall[k.operation] = make(histogram)
+		}
+		overall[k.operation].add(stored[k])
+	}
+	for _, operation := range Operations {
+		if h, ok := overall[operation]; ok {
+			report.Overall = append(report.Overall, h.stats(operation))
+		}
+	}
+	return report, nil
+}
+
+// sqlStore keeps the histograms in the latency_histograms table
+type sqlStore struct {
+	db *sql.DB
+}
+
+func (st *sqlStore) add(ctx context.Context, counts map[key]histogram) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO latency_histograms (day, operation, bucket, count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (day, operation, bucket) DO UPDATE SET count = latency_histograms.count + EXCLUDED.count`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, h := range counts {
+		for bucket, count := range h {
+			if _, err := stmt.ExecContext(ctx, k.day, k.operation, bucket, count); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (st *sqlStore) histograms(ctx context.Context, from, to string, operation string) (map[key]histogram, error) {
+	query := "SELECT to_char(day, 'YYYY-MM-DD'), operation, bucket, count FROM latency_histograms WHERE day BETWEEN $1 AND $2"
+	args := []any{from, to}
+	if operation != "" {
+		query += " AND operation = $3"
+		args = append(args, operation)
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[key]histogram)
+	for rows.Next() {
+		var k key
+		var bucket int
+		var count int64
+		if err := rows.Scan(&k.day, &k.operation, &bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan latency histogram: %w", err)
+		}
+		if stored[k] == nil {
+			stored[k] = make(histogram)
+		}
+		stored[k][bucket] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return stored, nil
+}
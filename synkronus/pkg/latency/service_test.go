@@ -0,0 +1,161 @@
+package latency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore keeps histograms in memory
+type memStore struct {
+	stored map[key]histogram
+	fail   bool
+}
+
+func (m *memStore) add(ctx context.Context, counts map[key]histogram) error {
+	if m.fail {
+		return errors.New("database unavailable")
+	}
+	for k, h := range counts {
+		if m.stored[k] == nil {
+			m.stored[k] = make(histogram)
+		}
+		m.stored[k].add(h)
+	}
+	return nil
+}
+
+func (m *memStore) histograms(ctx context.Context, from, to string, operation string) (map[key]histogram, error) {
+	result := make(map[key]histogram)
+	for k, h := range m.stored {
+		if k.day >= from && k.day <= to && (operation == "" || k.operation == operation) {
+			result[k] = make(histogram)
+			result[k].add(h)
+		}
+	}
+	return result, nil
+}
+
+func setupService(day time.Time) (*service, *memStore) {
+	st := &memStore{stored: make(map[key]histogram)}
+	s := newService(st, Config{}, logger.NewLogger())
+	s.now = func() time.Time { return day }
+	return s, st
+}
+
+func TestBucketOf(t *testing.T) {
+	for _, ms := range []float64{0, 0.5, 1, 1.05, 1.1, 7, 180, 999, 1000, 1001, 45000} {
+		bucket := bucketOf(ms)
+		assert.LessOrEqual(t, ms, upperBound(bucket)*1.0001, "duration %v above its bucket", ms)
+		if bucket > 0 {
+			assert.Greater(t, ms, upperBound(bucket-1), "duration %v fits a smaller bucket", ms)
+		}
+	}
+	assert.Equal(t, maxBucket, bucketOf(1e30))
+}
+
+func TestHistogramStats(t *testing.T) {
+	h := make(histogram)
+	for i := 1; i <= 100; i++ {
+		h[bucketOf(float64(i*10))]++
+	}
+
+	stats := h.stats(OperationPull)
+	assert.Equal(t, int64(100), stats.Count)
+	assert.InDelta(t, 500, stats.P50Ms, 50)
+	assert.InDelta(t, 950, stats.P95Ms, 95)
+	assert.InDelta(t, 990, stats.P99Ms, 99)
+	assert.GreaterOrEqual(t, stats.P99Ms, 990.0)
+}
+
+func TestReport(t *testing.T) {
+	day1 := time.Date(2025, 9, 29, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	s, _ := setupService(day1)
+	ctx := context.Background()
+
+	for i := 0; i < 19; i++ {
+		s.Observe(OperationPull, 100*time.Millisecond)
+	}
+	s.Observe(OperationPull, 3*time.Second)
+	s.Observe(OperationExport, 40*time.Second)
+	require.NoError(t, s.Flush(ctx))
+
+	// The next day's counts are reported before they are flushed
+	s.now = func() time.Time { return day2 }
+	s.Observe(OperationPull, 200*time.Millisecond)
+	s.Observe(OperationPush, 50*time.Millisecond)
+
+	report, err := s.Report(ctx, Filter{From: day1, To: day2})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-09-29", report.From)
+	assert.Equal(t, "2025-09-30", report.To)
+
+	days := make([]string, 0, len(report.Days))
+	for _, d := range report.Days {
+		days = append(days, d.Day+" "+d.Operation)
+	}
+	assert.Equal(t, []string{"2025-09-29 pull", "2025-09-29 export", "2025-09-30 pull", "2025-09-30 push"}, days)
+
+	pull := report.Days[0]
+	assert.Equal(t, int64(20), pull.Count)
+	assert.InDelta(t, 100, pull.P50Ms, 10)
+	assert.InDelta(t, 100, pull.P95Ms, 10)
+	assert.InDelta(t, 3000, pull.P99Ms, 300)
+
+	require.Len(t, report.Overall, 3)
+	assert.Equal(t, OperationPull, report.Overall[0].Operation)
+	assert.Equal(t, int64(21), report.Overall[0].Count)
+	assert.Equal(t, OperationPush, report.Overall[1].Operation)
+	assert.Equal(t, OperationExport, report.Overall[2].Operation)
+
+	report, err = s.Report(ctx, Filter{From: day2, To: day2, Operation: OperationPush})
+	require.NoError(t, err)
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, OperationPush, report.Days[0].Operation)
+}
+
+func TestFlush_KeepsCountsOnFailure(t *testing.T) {
+	day := time.Date(2025, 9, 29, 10, 0, 0, 0, time.UTC)
+	s, st := setupService(day)
+	ctx := context.Background()
+
+	st.fail = true
+	s.Observe(OperationPush, 80*time.Millisecond)
+	require.Error(t, s.Flush(ctx))
+	assert.Empty(t, st.stored)
+
+	st.fail = false
+	s.Observe(OperationPush, 80*time.Millisecond)
+	require.NoError(t, s.Flush(ctx))
+	assert.Equal(t, int64(2), st.stored[key{day: "2025-09-29", operation: OperationPush}].stats(OperationPush).Count)
+	assert.Empty(t, s.pending)
+}
+
+func TestTrack(t *testing.T) {
+	s, _ := setupService(time.Date(2025, 9, 29, 10, 0, 0, 0, time.UTC))
+	status := http.StatusOK
+	handler := Track(s, OperationPull)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	for _, status = range []int{http.StatusOK, http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/sync/pull", nil))
+	}
+
+	// Client errors are left out
+	assert.Equal(t, int64(2), s.pending[key{day: "2025-09-29", operation: OperationPull}].stats(OperationPull).Count)
+
+	// Without a service requests pass through
+	passed := false
+	Track(nil, OperationPull)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { passed = true })).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/sync/pull", nil))
+	assert.True(t, passed)
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/apikey"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// APIKeyHeader carries an API key; keys are also accepted as bearer tokens
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyKey is the context key for the API key a request was authenticated with
+const APIKeyKey ContextKey = "api_key"
+
+// APIKeyMiddleware authenticates requests carrying an API key as the key's owner. Requests
+// without one are passed on for AuthMiddleware to validate their JWT; requests with an invalid
+// key are rejected rather than falling back to other credentials.
+func APIKeyMiddleware(keyService apikey.Service, log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Requests accepted by an authenticator ahead of this middleware need no key
+			if GetAuthenticatorFromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(APIKeyHeader)
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && key == "" && apikey.IsKey(bearer) {
+				key = bearer
+			}
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, apiKey, err := keyService.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalidKey) {
+					log.Warn("Invalid API key")
+				} else {
+					log.Error("Failed to authenticate API key", "error", err)
+				}
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserKey, user)
+			ctx = context.WithValue(ctx, APIKeyKey, apiKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetAPIKeyFromContext gets the API key a request was authenticated with, or nil for requests
+// authenticated with a JWT
+func GetAPIKeyFromContext(ctx context.Context) *apikey.APIKey {
+	key, _ := ctx.Value(APIKeyKey).(*apikey.APIKey)
+	return key
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// ErrInvalidCredentials is returned by authenticators for requests carrying their credentials
+// in a form that cannot be accepted
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// AuthenticatorKey is the context key for the name of the authenticator that accepted a request
+const AuthenticatorKey ContextKey = "authenticator"
+
+// peerAddrKey is the context key for the address of the connection's peer
+const peerAddrKey ContextKey = "peer_addr"
+
+// Authenticator accepts requests whose users were authenticated by other means than a JWT or
+// API key, e.g. by an SSO gateway in front of the server
+type Authenticator interface {
+	// Name identifies the authenticator in logs
+	Name() string
+	// Authenticate returns the user of a request, or nil when the request does not carry
+	// credentials of this authenticator
+	Authenticate(r *http.Request) (*models.User, error)
+}
+
+// PeerAddrMiddleware keeps the address of the connection's peer before RealIP replaces the
+// remote address with the one forwarded by proxies, so authenticators can tell whether a
+// request came through a trusted proxy and limiters key clients on an address they cannot spoof
+func PeerAddrMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), peerAddrKey, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PeerAddr returns the address of the connection's peer, which unlike the remote address is
+// never taken from client-supplied headers
+func PeerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
+
+// ParseTrustedProxies parses a comma separated list of proxy addresses and CIDR ranges
+func ParseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return parsePrefixes(values)
+}
+
+// ClientAddr returns the address of a request's client. Requests from a trusted proxy are
+// attributed to the last address in X-Forwarded-For, or X-Real-IP, that is not itself a trusted
+// proxy; the headers of other peers are ignored, as any client could set them.
+func ClientAddr(r *http.Request, proxies []netip.Prefix) string {
+	addr := PeerAddr(r)
+	if !trusted(proxies, addr) {
+		return addr
+	}
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded == "" {
+		forwarded = r.Header.Get("X-Real-IP")
+	}
+	hops := strings.Split(forwarded, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		addr = hop
+		if !trusted(proxies, hop) {
+			break
+		}
+	}
+	return addr
+}
+
+// AuthenticatorMiddleware tries the authenticators in order and authenticates the request as
+// the user returned by the first that recognizes it. Requests none of them recognize are passed
+// on for APIKeyMiddleware and AuthMiddleware; requests with credentials an authenticator
+// rejects are refused rather than falling back to other credentials.
+func AuthenticatorMiddleware(authenticators []Authenticator, log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				user, err := authenticator.Authenticate(r)
+				if err != nil {
+					if errors.Is(err, ErrInvalidCredentials) {
+						log.Warn("Request refused by authenticator", "authenticator", authenticator.Name(), "error", err)
+					} else {
+						log.Error("Authenticator failed", "authenticator", authenticator.Name(), "error", err)
+					}
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				if user == nil {
+					continue
+				}
+
+				ctx := context.WithValue(r.Context(), UserKey, user)
+				ctx = context.WithValue(ctx, AuthenticatorKey, authenticator.Name())
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetAuthenticatorFromContext gets the name of the authenticator that accepted a request, or
+// an empty string for requests authenticated with a JWT or API key
+func GetAuthenticatorFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(AuthenticatorKey).(string)
+	return name
+}
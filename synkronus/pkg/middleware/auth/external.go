@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// Authenticator types available in an authenticators file
+const (
+	// AuthenticatorProxyHeader trusts a username set in a header by a reverse proxy or SSO gateway
+	AuthenticatorProxyHeader = "proxy_header"
+	// AuthenticatorClientCert maps a verified TLS client certificate to a username
+	AuthenticatorClientCert = "client_cert"
+)
+
+// AuthenticatorConfig declares one authenticator of an authenticators file
+type AuthenticatorConfig struct {
+	// Type is proxy_header or client_cert
+	Type string `json:"type"`
+	// Name identifies the authenticator in logs; defaults to the type
+	Name string `json:"name,omitempty"`
+	// TrustedProxies lists the addresses and CIDR ranges of the proxies allowed to set the
+	// headers read by the authenticator. Required for proxy_header and for client_cert with
+	// a cert_header.
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+	// UserHeader carries the username for proxy_header, e.g. X-Forwarded-User
+	UserHeader string `json:"user_header,omitempty"`
+	// RoleHeader optionally carries the role for proxy_header
+	RoleHeader string `json:"role_header,omitempty"`
+	// CertHeader carries the URL-encoded PEM client certificate forwarded by a proxy that
+	// terminates TLS; client_cert reads the connection's certificate, verified against
+	// TLS_CLIENT_CA_FILE, when it is empty
+	CertHeader string `json:"cert_header,omitempty"`
+	// UsernameFrom picks the certificate field holding the username: cn (default) or email
+	UsernameFrom string `json:"username_from,omitempty"`
+	// Role is given to every user the authenticator accepts. Without a role (or role header)
+	// users must exist locally and keep their local role and tenant.
+	Role models.Role `json:"role,omitempty"`
+	// Tenant is the tenant of the users given a role by the authenticator; required for role
+	// and role_header with multi-tenancy enabled
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// UserLookup finds local users; implemented by the user repository. It returns nil for unknown users.
+type UserLookup interface {
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+}
+
+// LoadAuthenticators reads the authenticators declared in a JSON file as an array of
+// AuthenticatorConfig. With multiTenancy, authenticators that give users a role must name their
+// tenant, so that their users are not taken for users of the default tenant.
+func LoadAuthenticators(path string, users UserLookup, multiTenancy bool) ([]Authenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authenticators file: %w", err)
+	}
+	var configs []AuthenticatorConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse authenticators file: %w", err)
+	}
+
+	authenticators := make([]Authenticator, 0, len(configs))
+	for i, config := range configs {
+		if multiTenancy && (config.Role != "" || config.RoleHeader != "") && config.Tenant == "" {
+			return nil, fmt.Errorf("authenticator %d: role and role_header require tenant with multi-tenancy enabled", i+1)
+		}
+		authenticator, err := NewAuthenticator(config, users)
+		if err != nil {
+			return nil, fmt.Errorf("authenticator %d: %w", i+1, err)
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	return authenticators, nil
+}
+
+// NewAuthenticator creates the authenticator declared by config
+func NewAuthenticator(config AuthenticatorConfig, users UserLookup) (Authenticator, error) {
+	if config.Name == "" {
+		config.Name = config.Type
+	}
+	if config.Role != "" && !validRole(config.Role) {
+		return nil, fmt.Errorf("unknown role %q", config.Role)
+	}
+	if config.Tenant != "" {
+		if err := tenant.Validate(config.Tenant); err != nil {
+			return nil, fmt.Errorf("invalid tenant %q: %w", config.Tenant, err)
+		}
+	}
+	proxies, err := parsePrefixes(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	base := externalUser{name: config.Name, role: config.Role, tenant: config.Tenant, users: users}
+
+	switch config.Type {
+	case AuthenticatorProxyHeader:
+		if config.UserHeader == "" {
+			return nil, fmt.Errorf("%s requires user_header", config.Type)
+		}
+		if len(proxies) == 0 {
+			return nil, fmt.Errorf("%s requires trusted_proxies", config.Type)
+		}
+		return &proxyHeaderAuthenticator{externalUser: base, proxies: proxies,
+			userHeader: config.UserHeader, roleHeader: config.RoleHeader}, nil
+	case AuthenticatorClientCert:
+		if config.CertHeader != "" && len(proxies) == 0 {
+			return nil, fmt.Errorf("%s with cert_header requires trusted_proxies", config.Type)
+		}
+		switch config.UsernameFrom {
+		case "":
+			config.UsernameFrom = "cn"
+		case "cn", "email":
+		default:
+			return nil, fmt.Errorf("username_from must be cn or email, not %q", config.UsernameFrom)
+		}
+		return &clientCertAuthenticator{externalUser: base, proxies: proxies,
+			certHeader: config.CertHeader, usernameFrom: config.UsernameFrom}, nil
+	default:
+		return nil, fmt.Errorf("unknown authenticator type %q", config.Type)
+	}
+}
+
+// externalUser resolves usernames vouched for by an authenticator to users
+type externalUser struct {
+	name   string
+	role   models.Role
+	tenant string
+	users  UserLookup
+}
+
+func (e externalUser) Name() string {
+	return e.name
+}
+
+// user returns the user of a username; role, when set, overrides the configured role. Users
+// given a role belong to the configured tenant, local users to their own.
+func (e externalUser) user(ctx context.Context, username string, role models.Role) (*models.User, error) {
+	if role == "" {
+		role = e.role
+	}
+	if role != "" {
+		if !validRole(role) {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidCredentials, role)
+		}
+		return &models.User{Username: username, Role: role, TenantID: e.tenant}, nil
+	}
+	if e.users == nil {
+		return nil, fmt.Errorf("%w: no role for %q", ErrInvalidCredentials, username)
+	}
+
+	user, err := e.users.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: no local user %q", ErrInvalidCredentials, username)
+	}
+	return &models.User{Username: user.Username, Role: user.Role, TenantID: user.TenantID}, nil
+}
+
+// proxyHeaderAuthenticator trusts the username a proxy in front of the server sets in a header
+type proxyHeaderAuthenticator struct {
+	externalUser
+	proxies    []netip.Prefix
+	userHeader string
+	roleHeader string
+}
+
+func (p *proxyHeaderAuthenticator) Authenticate(r *http.Request) (*models.User, error) {
+	username := strings.TrimSpace(r.Header.Get(p.userHeader))
+	if username == "" {
+		return nil, nil
+	}
+	// Anyone else setting the header is trying to pass as another user
+	if !trusted(p.proxies, PeerAddr(r)) {
+		return nil, fmt.Errorf("%w: %s set by untrusted peer %s", ErrInvalidCredentials, p.userHeader, PeerAddr(r))
+	}
+	var role models.Role
+	if p.roleHeader != "" {
+		role = models.Role(strings.TrimSpace(r.Header.Get(p.roleHeader)))
+	}
+	return p.user(r.Context(), username, role)
+}
+
+// clientCertAuthenticator maps the subject of a verified client certificate to a username
+type clientCertAuthenticator struct {
+	externalUser
+	proxies      []netip.Prefix
+	certHeader   string
+	usernameFrom string
+}
+
+func (c *clientCertAuthenticator) Authenticate(r *http.Request) (*models.User, error) {
+	cert, err := c.certificate(r)
+	if cert == nil || err != nil {
+		return nil, err
+	}
+
+	var username string
+	switch c.usernameFrom {
+	case "email":
+		if len(cert.EmailAddresses) > 0 {
+			username = cert.EmailAddresses[0]
+		}
+	default:
+		username = cert.Subject.CommonName
+	}
+	if username == "" {
+		return nil, fmt.Errorf("%w: client certificate has no %s", ErrInvalidCredentials, c.usernameFrom)
+	}
+	return c.user(r.Context(), username, "")
+}
+
+// certificate returns the client certificate of a request, forwarded by a trusted proxy or
+// verified by the server's own TLS listener
+func (c *clientCertAuthenticator) certificate(r *http.Request) (*x509.Certificate, error) {
+	if c.certHeader == "" {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return nil, nil
+		}
+		return r.TLS.VerifiedChains[0][0], nil
+	}
+
+	value := r.Header.Get(c.certHeader)
+	if value == "" {
+		return nil, nil
+	}
+	if !trusted(c.proxies, PeerAddr(r)) {
+		return nil, fmt.Errorf("%w: %s set by untrusted peer %s", ErrInvalidCredentials, c.certHeader, PeerAddr(r))
+	}
+	decoded, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not URL-encoded", ErrInvalidCredentials, c.certHeader)
+	}
+	block, _ := pem.Decode([]byte(decoded))
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s holds no PEM certificate", ErrInvalidCredentials, c.certHeader)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCredentials, c.certHeader, err)
+	}
+	return cert, nil
+}
+
+// parsePrefixes parses addresses and CIDR ranges
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", value)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// trusted reports whether the host of addr lies in one of the prefixes
+func trusted(prefixes []netip.Prefix, addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validRole reports whether role is one of the roles of the server
+func validRole(role models.Role) bool {
+	return role == models.RoleReadOnly || role == models.RoleReadWrite || role == models.RoleAdmin
+}
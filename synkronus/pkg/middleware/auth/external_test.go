@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localUsers is a UserLookup over a fixed set of users
+type localUsers map[string]models.Role
+
+func (l localUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	role, ok := l[username]
+	if !ok {
+		return nil, nil
+	}
+	return &models.User{Username: username, Role: role}, nil
+}
+
+// authenticate runs a request from peer through the authenticators and returns the status and
+// the user the request was authenticated as
+func authenticate(t *testing.T, authenticators []Authenticator, peer string, header http.Header) (int, *models.User) {
+	t.Helper()
+	var user *models.User
+	handler := PeerAddrMiddleware(AuthenticatorMiddleware(authenticators, logger.NewLogger())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user = GetUserFromContext(r.Context())
+		})))
+
+	r := httptest.NewRequest(http.MethodGet, "/sync/pull", nil)
+	r.RemoteAddr = peer
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code, user
+}
+
+func TestProxyHeaderAuthenticator(t *testing.T) {
+	users := localUsers{"alice": models.RoleReadWrite}
+	gateway, err := NewAuthenticator(AuthenticatorConfig{
+		Type: AuthenticatorProxyHeader, UserHeader: "X-Forwarded-User", TrustedProxies: []string{"10.0.0.0/8"},
+	}, users)
+	require.NoError(t, err)
+	authenticators := []Authenticator{gateway}
+
+	code, user := authenticate(t, authenticators, "10.1.2.3:5000", http.Header{"X-Forwarded-User": {"alice"}})
+	assert.Equal(t, http.StatusOK, code)
+	require.NotNil(t, user)
+	assert.Equal(t, models.RoleReadWrite, user.Role, "local users keep their role")
+
+	code, _ = authenticate(t, authenticators, "10.1.2.3:5000", http.Header{"X-Forwarded-User": {"mallory"}})
+	assert.Equal(t, http.StatusUnauthorized, code, "unknown users are refused")
+
+	code, _ = authenticate(t, authenticators, "203.0.113.7:5000", http.Header{"X-Forwarded-User": {"alice"}})
+	assert.Equal(t, http.StatusUnauthorized, code, "the header is only trusted from the gateway")
+
+	code, user = authenticate(t, authenticators, "203.0.113.7:5000", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, user, "requests without the header are left to the JWT")
+}
+
+func TestProxyHeaderAuthenticator_RoleHeader(t *testing.T) {
+	gateway, err := NewAuthenticator(AuthenticatorConfig{
+		Type: AuthenticatorProxyHeader, UserHeader: "X-Forwarded-User", RoleHeader: "X-Forwarded-Role",
+		TrustedProxies: []string{"192.168.1.10"}, Role: models.RoleReadOnly,
+	}, nil)
+	require.NoError(t, err)
+	authenticators := []Authenticator{gateway}
+
+	_, user := authenticate(t, authenticators, "192.168.1.10:443", http.Header{"X-Forwarded-User": {"bob"}, "X-Forwarded-Role": {"admin"}})
+	require.NotNil(t, user)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+
+	_, user = authenticate(t, authenticators, "192.168.1.10:443", http.Header{"X-Forwarded-User": {"bob"}})
+	require.NotNil(t, user)
+	assert.Equal(t, models.RoleReadOnly, user.Role, "the configured role applies without a role header")
+
+	code, _ := authenticate(t, authenticators, "192.168.1.10:443", http.Header{"X-Forwarded-User": {"bob"}, "X-Forwarded-Role": {"root"}})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestClientCertAuthenticator_ForwardedCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "field-tablet-7"},
+		EmailAddresses: []string{"tablet7@example.org"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	forwarded := url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+
+	byCN, err := NewAuthenticator(AuthenticatorConfig{
+		Type: AuthenticatorClientCert, CertHeader: "X-SSL-Client-Cert", TrustedProxies: []string{"127.0.0.1"},
+	}, localUsers{"field-tablet-7": models.RoleReadWrite})
+	require.NoError(t, err)
+	_, user := authenticate(t, []Authenticator{byCN}, "127.0.0.1:9000", http.Header{"X-Ssl-Client-Cert": {forwarded}})
+	require.NotNil(t, user)
+	assert.Equal(t, "field-tablet-7", user.Username)
+
+	byEmail, err := NewAuthenticator(AuthenticatorConfig{
+		Type: AuthenticatorClientCert, CertHeader: "X-SSL-Client-Cert", TrustedProxies: []string{"127.0.0.1"},
+		UsernameFrom: "email", Role: models.RoleReadOnly,
+	}, nil)
+	require.NoError(t, err)
+	_, user = authenticate(t, []Authenticator{byEmail}, "127.0.0.1:9000", http.Header{"X-Ssl-Client-Cert": {forwarded}})
+	require.NotNil(t, user)
+	assert.Equal(t, "tablet7@example.org", user.Username)
+
+	code, _ := authenticate(t, []Authenticator{byCN}, "127.0.0.1:9000", http.Header{"X-Ssl-Client-Cert": {"garbage"}})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestLoadAuthenticators(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "authenticators.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"type": "proxy_header", "name": "sso", "user_header": "X-Forwarded-User", "trusted_proxies": ["10.0.0.0/8"]},
+		{"type": "client_cert", "role": "read-only"}
+	]`), 0o600))
+
+	authenticators, err := LoadAuthenticators(path, localUsers{}, false)
+	require.NoError(t, err)
+	require.Len(t, authenticators, 2)
+	assert.Equal(t, "sso", authenticators[0].Name())
+	assert.Equal(t, AuthenticatorClientCert, authenticators[1].Name())
+
+	invalid := []AuthenticatorConfig{
+		{Type: "ldap"},
+		{Type: AuthenticatorProxyHeader, UserHeader: "X-Forwarded-User"},
+		{Type: AuthenticatorProxyHeader, TrustedProxies: []string{"10.0.0.0/8"}},
+		{Type: AuthenticatorProxyHeader, UserHeader: "X-Forwarded-User", TrustedProxies: []string{"gateway"}},
+		{Type: AuthenticatorClientCert, CertHeader: "X-SSL-Client-Cert"},
+		{Type: AuthenticatorClientCert, UsernameFrom: "serial"},
+		{Type: AuthenticatorClientCert, Role: "root"},
+		{Type: AuthenticatorClientCert, Role: models.RoleReadOnly, Tenant: "Acme Corp"},
+	}
+	for _, config := range invalid {
+		_, err := NewAuthenticator(config, nil)
+		assert.Error(t, err, "%+v", config)
+	}
+}
+
+// tenantUsers is a UserLookup over users of several tenants
+type tenantUsers map[string]models.User
+
+func (u tenantUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, ok := u[username]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func TestExternalAuthenticator_Tenant(t *testing.T) {
+	users := tenantUsers{"alice": {Username: "alice", Role: models.RoleAdmin, TenantID: "acme"}}
+	gateway, err := NewAuthenticator(AuthenticatorConfig{
+		Type: AuthenticatorProxyHeader, UserHeader: "X-Forwarded-User", RoleHeader: "X-Forwarded-Role",
+		TrustedProxies: []string{"10.0.0.0/8"}, Tenant: "globex",
+	}, users)
+	require.NoError(t, err)
+	authenticators := []Authenticator{gateway}
+
+	_, user := authenticate(t, authenticators, "10.1.2.3:5000", http.Header{"X-Forwarded-User": {"alice"}})
+	require.NotNil(t, user)
+	assert.Equal(t, "acme", user.TenantID, "local users keep their tenant")
+
+	_, user = authenticate(t, authenticators, "10.1.2.3:5000", http.Header{"X-Forwarded-User": {"bob"}, "X-Forwarded-Role": {"read-write"}})
+	require.NotNil(t, user)
+	assert.Equal(t, "globex", user.TenantID, "users given a role belong to the configured tenant")
+
+	// With tenancy, authenticators giving roles must name the tenant
+	path := filepath.Join(t.TempDir(), "authenticators.json")
+	for file, valid := range map[string]bool{
+		`[{"type": "client_cert", "role": "read-only"}]`: false,
+		`[{"type": "proxy_header", "user_header": "X-Forwarded-User", "role_header": "X-Forwarded-Role", "trusted_proxies": ["10.0.0.0/8"]}]`: false,
+		`[{"type": "client_cert", "role": "read-only", "tenant": "acme"}]`:                                                                    true,
+		`[{"type": "client_cert"}]`: true,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o600))
+		_, err := LoadAuthenticators(path, users, true)
+		assert.Equal(t, valid, err == nil, "%s: %v", file, err)
+	}
+}
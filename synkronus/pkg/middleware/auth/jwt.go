@@ -57,6 +57,7 @@ func JWTMiddleware(authService auth.AuthServiceInterface, log *logger.Logger) fu
 			user := &models.User{
 				Username: claims.Username,
 				Role:     claims.Role,
+				TenantID: claims.Tenant,
 			}
 
 			// Add user to context
@@ -10,10 +10,17 @@ import (
 	"github.com/opendataensemble/synkronus/pkg/logger"
 )
 
-// AuthMiddleware creates a middleware that validates JWT tokens using the auth service interface
+// AuthMiddleware creates a middleware that validates JWT tokens using the auth service interface;
+// requests authenticated by APIKeyMiddleware or AuthenticatorMiddleware are passed through
 func AuthMiddleware(authService auth.AuthServiceInterface, log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Requests already authenticated by APIKeyMiddleware or an authenticator carry no JWT
+			if GetAPIKeyFromContext(r.Context()) != nil || GetAuthenticatorFromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Get token from Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
@@ -44,10 +51,17 @@ func AuthMiddleware(authService auth.AuthServiceInterface, log *logger.Logger) f
 			user := &models.User{
 				Username: claims.Username,
 				Role:     getModelRole(string(claims.Role)), // Convert auth.Role to models.Role
+				TenantID: claims.Tenant,
+			}
+
+			// Every request made while impersonating is logged with the admin behind it
+			if impersonator := claims.Impersonator(); impersonator != "" {
+				log.Info("Impersonated request", "username", claims.Username, "impersonator", impersonator, "method", r.Method, "path", r.URL.Path)
 			}
 
-			// Add user to context
+			// Add user and claims to context
 			ctx := context.WithValue(r.Context(), UserKey, user)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
 
 			// Call the next handler with the updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// TenantConfig configures TenantMiddleware
+type TenantConfig struct {
+	// Header names the tenant a request acts for; only admins of the default tenant may set it
+	// to another tenant than their own
+	Header string
+	// Routes are the paths whose handlers keep tenants apart; requests acting for other tenants
+	// than the default one are refused on every other path. Entries ending in /* match the
+	// paths below them, and entries starting with a method, as in "GET /terms", only match
+	// requests with that method.
+	Routes []string
+}
+
+// TenantMiddleware resolves the tenant a request acts for and adds it to the context. It must
+// run after authentication. The tenant is the user's, from the JWT tenant claim or the API key
+// owner; admins of the default tenant, who run the server, may act for any tenant by naming it
+// in the header, e.g. to create its first admin. Naming another tenant is refused for everyone
+// else.
+func TenantMiddleware(config TenantConfig, log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			if user == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			id := tenant.OrDefault(user.TenantID)
+			if requested := r.Header.Get(config.Header); requested != "" && requested != id {
+				if id != tenant.Default || user.Role != models.RoleAdmin {
+					log.Warn("Refused request for another tenant", "username", user.Username, "tenant", id, "requested", requested)
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+				if err := tenant.Validate(requested); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				id = requested
+			}
+
+			if id != tenant.Default && !matchesRoute(config.Routes, r) {
+				http.Error(w, "Not available to tenants", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), id)))
+		})
+	}
+}
+
+// matchesRoute reports whether a request matches one of routes
+func matchesRoute(routes []string, r *http.Request) bool {
+	for _, route := range routes {
+		if method, path, ok := strings.Cut(route, " "); ok {
+			if method != r.Method {
+				continue
+			}
+			route = path
+		}
+		if prefix, ok := strings.CutSuffix(route, "/*"); ok {
+			if strings.HasPrefix(r.URL.Path, prefix+"/") {
+				return true
+			}
+		} else if r.URL.Path == route {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	middleware := TenantMiddleware(TenantConfig{
+		Header: "X-Tenant-ID",
+		Routes: []string{"/sync/pull", "/app-bundle/*", "GET /terms"},
+	}, logger.NewLogger())
+
+	var resolved string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resolved = tenant.FromContext(r.Context())
+	}))
+
+	operator := &models.User{Username: "operator", Role: models.RoleAdmin}
+	acmeAdmin := &models.User{Username: "alice", Role: models.RoleAdmin, TenantID: "acme"}
+	enumerator := &models.User{Username: "bob", Role: models.RoleReadWrite}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		method string
+		path   string
+		header string
+		status int
+		tenant string
+	}{
+		{"default tenant user", enumerator, "POST", "/sync/pull", "", http.StatusOK, tenant.Default},
+		{"default tenant reaches every route", enumerator, "GET", "/data/export", "", http.StatusOK, tenant.Default},
+		{"tenant from the user", acmeAdmin, "POST", "/sync/pull", "", http.StatusOK, "acme"},
+		{"header naming the user's own tenant", acmeAdmin, "GET", "/app-bundle/versions", "acme", http.StatusOK, "acme"},
+		{"tenant refused on server routes", acmeAdmin, "GET", "/data/export", "", http.StatusForbidden, ""},
+		{"method of a route", acmeAdmin, "PUT", "/terms", "", http.StatusForbidden, ""},
+		{"operator acting for a tenant", operator, "GET", "/app-bundle/manifest", "acme", http.StatusOK, "acme"},
+		{"operator acting for an invalid tenant", operator, "GET", "/app-bundle/manifest", "Acme/..", http.StatusBadRequest, ""},
+		{"tenant naming another tenant", acmeAdmin, "POST", "/sync/pull", "globex", http.StatusForbidden, ""},
+		{"non-admin naming a tenant", enumerator, "POST", "/sync/pull", "acme", http.StatusForbidden, ""},
+		{"unauthenticated", nil, "POST", "/sync/pull", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved = ""
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.header != "" {
+				r.Header.Set("X-Tenant-ID", tc.header)
+			}
+			if tc.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), UserKey, tc.user))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, tc.tenant, resolved)
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Package faults injects latency, errors and partial failures into requests so that client
+// retry logic can be tested against a misbehaving server. It is only wired into builds made
+// with the dev build tag.
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of injected failure
+const (
+	// KindError answers with Status instead of calling the handler
+	KindError = "error"
+	// KindLostResponse lets the handler process the request, then discards its response and
+	// answers with Status, as if the response had been lost on the way back
+	KindLostResponse = "lost_response"
+	// KindDrop closes the connection without answering
+	KindDrop = "drop"
+	// KindTruncate sends the first TruncateBytes of the handler's response, then closes the
+	// connection
+	KindTruncate = "truncate"
+)
+
+// maxDelay bounds injected latency so that a mistyped rule cannot hang a test server
+const maxDelay = 5 * time.Minute
+
+// Rule describes a fault injected into matching requests
+type Rule struct {
+	ID string `json:"id"`
+	// Path is matched as a prefix of the request path, e.g. /sync/push or /app-bundle
+	Path string `json:"path"`
+	// Method restricts the rule to one HTTP method; empty matches every method
+	Method string `json:"method,omitempty"`
+	// Probability of a matching request being affected, between 0 and 1; 0 means always
+	Probability float64 `json:"probability,omitempty"`
+	// Remaining is how many more requests the rule affects; 0 means unlimited
+	Remaining int `json:"remaining,omitempty"`
+	// DelayMs is latency added before the request is handled
+	DelayMs int `json:"delay_ms,omitempty"`
+	// Kind is the failure injected after the delay; empty only adds latency
+	Kind string `json:"kind,omitempty"`
+	// Status is the response status of error and lost_response faults, 500 when empty
+	Status int `json:"status,omitempty"`
+	// TruncateBytes is how much of the response a truncate fault sends
+	TruncateBytes int `json:"truncate_bytes,omitempty"`
+}
+
+// Injector holds the active rules and applies them to requests
+type Injector struct {
+	mu     sync.Mutex
+	rules  []Rule
+	nextID int
+	random func() float64
+	sleep  func(r *http.Request, d time.Duration)
+}
+
+// New creates an injector without rules
+func New() *Injector {
+	return &Injector{
+		random: rand.Float64,
+		sleep: func(r *http.Request, d time.Duration) {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+			}
+		},
+	}
+}
+
+// Validate checks a rule and fills in its defaults
+func (rule *Rule) Validate() error {
+	rule.Path = strings.TrimSpace(rule.Path)
+	if !strings.HasPrefix(rule.Path, "/") {
+		return errors.New("path must start with /")
+	}
+	rule.Method = strings.ToUpper(strings.TrimSpace(rule.Method))
+	if rule.Probability < 0 || rule.Probability > 1 {
+		return errors.New("probability must be between 0 and 1")
+	}
+	if rule.Remaining < 0 {
+		return errors.New("remaining must not be negative")
+	}
+	if rule.DelayMs < 0 || time.Duration(rule.DelayMs)*time.Millisecond > maxDelay {
+		return fmt.Errorf("delay_ms must be between 0 and %d", maxDelay.Milliseconds())
+	}
+	switch rule.Kind {
+	case "", KindDrop:
+	case KindError, KindLostResponse:
+		if rule.Status == 0 {
+			rule.Status = http.StatusInternalServerError
+		}
+		if rule.Status < 400 || rule.Status > 599 {
+			return errors.New("status must be between 400 and 599")
+		}
+	case KindTruncate:
+		if rule.TruncateBytes < 0 {
+			return errors.New("truncate_bytes must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+	if rule.Kind == "" && rule.DelayMs == 0 {
+		return errors.New("a rule needs a kind or a delay")
+	}
+	return nil
+}
+
+// Add validates a rule and activates it
+func (i *Injector) Add(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.nextID++
+	rule.ID = strconv.Itoa(i.nextID)
+	i.rules = append(i.rules, rule)
+	return rule, nil
+}
+
+// Remove deactivates a rule and reports whether it existed
+func (i *Injector) Remove(id string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for n, rule := range i.rules {
+		if rule.ID == id {
+			i.rules = append(i.rules[:n], i.rules[n+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear deactivates every rule
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = nil
+}
+
+// Rules returns the active rules
+func (i *Injector) Rules() []Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]Rule{}, i.rules...)
+}
+
+// match returns the first rule affecting a request, consuming one of its remaining uses
+func (i *Injector) match(r *http.Request) (Rule, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for n := range i.rules {
+		rule := &i.rules[n]
+		if !strings.HasPrefix(r.URL.Path, rule.Path) || (rule.Method != "" && rule.Method != r.Method) {
+			continue
+		}
+		if rule.Probability > 0 && i.random() >= rule.Probability {
+			continue
+		}
+		matched := *rule
+		if rule.Remaining > 0 {
+			rule.Remaining--
+			if rule.Remaining == 0 {
+				i.rules = append(i.rules[:n], i.rules[n+1:]...)
+			}
+		}
+		return matched, true
+	}
+	return Rule{}, false
+}
+
+// Middleware applies the first matching rule to each request
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := i.match(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rule.DelayMs > 0 {
+			i.sleep(r, time.Duration(rule.DelayMs)*time.Millisecond)
+		}
+		switch rule.Kind {
+		case KindError:
+			writeFault(w, rule)
+		case KindLostResponse:
+			next.ServeHTTP(&discardWriter{header: http.Header{}}, r)
+			writeFault(w, rule)
+		case KindDrop:
+			panic(http.ErrAbortHandler)
+		case KindTruncate:
+			tw := &truncateWriter{ResponseWriter: w, remaining: rule.TruncateBytes}
+			next.ServeHTTP(tw, r)
+			http.NewResponseController(w).Flush()
+			panic(http.ErrAbortHandler)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// writeFault answers with the error body of the API
+func writeFault(w http.ResponseWriter, rule Rule) {
+	w.Header().Set("X-Injected-Fault", rule.ID)
+	writeError(w, rule.Status, "Injected fault "+rule.ID)
+}
+
+// discardWriter swallows the response of a request whose answer is lost
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+// truncateWriter passes on the first bytes of a response and drops the rest
+type truncateWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (t *truncateWriter) Write(p []byte) (int, error) {
+	if t.remaining <= 0 {
+		return len(p), nil
+	}
+	n := len(p)
+	if n > t.remaining {
+		p = p[:t.remaining]
+	}
+	if _, err := t.ResponseWriter.Write(p); err != nil {
+		return 0, err
+	}
+	t.remaining -= len(p)
+	return n, nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (t *truncateWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
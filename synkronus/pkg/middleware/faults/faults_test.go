@@ -0,0 +1,187 @@
+package faults
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInjector() (*Injector, *time.Duration) {
+	slept := new(time.Duration)
+	i := New()
+	i.random = func() float64 { return 0.5 }
+	i.sleep = func(r *http.Request, d time.Duration) { *slept += d }
+	return i, slept
+}
+
+// countingHandler answers with a fixed body and counts the requests it processed
+func countingHandler(calls *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		err  string
+	}{
+		{"relative path", Rule{Path: "sync", Kind: KindError}, "path must start with /"},
+		{"probability", Rule{Path: "/sync", Kind: KindError, Probability: 1.5}, "probability"},
+		{"status", Rule{Path: "/sync", Kind: KindError, Status: 200}, "status"},
+		{"kind", Rule{Path: "/sync", Kind: "explode"}, "unknown kind"},
+		{"nothing to inject", Rule{Path: "/sync"}, "kind or a delay"},
+		{"delay", Rule{Path: "/sync", DelayMs: 10 * 60 * 1000}, "delay_ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	rule := Rule{Path: "/sync/push", Method: "post", Kind: KindError}
+	require.NoError(t, rule.Validate())
+	assert.Equal(t, "POST", rule.Method)
+	assert.Equal(t, http.StatusInternalServerError, rule.Status)
+}
+
+func TestMiddlewareError(t *testing.T) {
+	i, slept := newTestInjector()
+	_, err := i.Add(Rule{Path: "/sync/push", Method: http.MethodPost, Kind: KindError, Status: http.StatusServiceUnavailable, DelayMs: 1500})
+	require.NoError(t, err)
+
+	calls := 0
+	handler := i.Middleware(countingHandler(&calls))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sync/push", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Injected-Fault"))
+	assert.Equal(t, 1500*time.Millisecond, *slept)
+	assert.Equal(t, 0, calls)
+
+	// Other methods and paths are not affected
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sync/pull", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sync/push", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddlewareLostResponse(t *testing.T) {
+	i, _ := newTestInjector()
+	_, err := i.Add(Rule{Path: "/sync/push", Kind: KindLostResponse, Status: http.StatusBadGateway})
+	require.NoError(t, err)
+
+	calls := 0
+	w := httptest.NewRecorder()
+	i.Middleware(countingHandler(&calls)).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sync/push", nil))
+
+	assert.Equal(t, 1, calls, "the request is processed")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.NotContains(t, w.Body.String(), "ok")
+}
+
+func TestMiddlewareRemainingAndProbability(t *testing.T) {
+	i, _ := newTestInjector()
+	_, err := i.Add(Rule{Path: "/app-bundle", Kind: KindError, Remaining: 2})
+	require.NoError(t, err)
+	_, err = i.Add(Rule{Path: "/sync", Kind: KindError, Probability: 0.4})
+	require.NoError(t, err)
+
+	calls := 0
+	handler := i.Middleware(countingHandler(&calls))
+	statuses := make([]int, 0, 3)
+	for n := 0; n < 3; n++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app-bundle/manifest", nil))
+		statuses = append(statuses, w.Code)
+	}
+	assert.Equal(t, []int{500, 500, 200}, statuses)
+	assert.Len(t, i.Rules(), 1, "exhausted rules are removed")
+
+	// The random draw of 0.5 is above the probability
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sync/pull", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMiddlewareTruncate(t *testing.T) {
+	i := New()
+	_, err := i.Add(Rule{Path: "/app-bundle", Kind: KindTruncate, TruncateBytes: 5})
+	require.NoError(t, err)
+
+	body := strings.Repeat("x", 100)
+	server := httptest.NewServer(i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(body))
+	})))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/app-bundle/download/index.html")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	buf := make([]byte, 200)
+	n, _ := resp.Body.Read(buf)
+	assert.LessOrEqual(t, n, 5)
+	_, err = resp.Body.Read(buf)
+	assert.Error(t, err, "the body ends early")
+}
+
+func TestMiddlewareDrop(t *testing.T) {
+	i := New()
+	_, err := i.Add(Rule{Path: "/sync", Kind: KindDrop})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer server.Close()
+
+	_, err = http.Post(server.URL+"/sync/pull", "application/json", nil)
+	assert.Error(t, err)
+}
+
+func TestRoutes(t *testing.T) {
+	i, _ := newTestInjector()
+	r := chi.NewRouter()
+	r.Route("/dev/faults", i.RegisterRoutes)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/dev/faults", strings.NewReader(`{"path":"/sync/pull","kind":"error","status":503}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"1"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/dev/faults", strings.NewReader(`{"path":"/sync/pull","kind":"boom"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dev/faults", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"path":"/sync/pull"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/dev/faults/1", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/dev/faults/1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, err := i.Add(Rule{Path: "/sync", DelayMs: 100})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/dev/faults", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, i.Rules())
+}
@@ -0,0 +1,57 @@
+package faults
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterRoutes adds the endpoints managing the rules of an injector
+func (i *Injector) RegisterRoutes(r chi.Router) {
+	r.Get("/", i.listRules)
+	r.Post("/", i.addRule)
+	r.Delete("/", i.clearRules)
+	r.Delete("/{id}", i.removeRule)
+}
+
+func (i *Injector) listRules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rules": i.Rules()})
+}
+
+func (i *Injector) addRule(w http.ResponseWriter, r *http.Request) {
+	var rule Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule, err := i.Add(rule)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (i *Injector) clearRules(w http.ResponseWriter, r *http.Request) {
+	i.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (i *Injector) removeRule(w http.ResponseWriter, r *http.Request) {
+	if !i.Remove(chi.URLParam(r, "id")) {
+		writeError(w, http.StatusNotFound, "Fault rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": http.StatusText(status), "message": message})
+}
@@ -0,0 +1,204 @@
+// Package ratelimit budgets the requests of each client so that a misbehaving device, such as
+// one retrying a failed sync in a tight loop, cannot overload the server. Requests beyond a
+// client's budget are rejected with 429 Too Many Requests and a Retry-After header.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+)
+
+// Backends of the budgets
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// idleTimeout is how long an unused client bucket is kept before it is forgotten
+const idleTimeout = 10 * time.Minute
+
+// Config controls the request budgets of clients
+type Config struct {
+	// Backend is "memory", which keeps budgets per server, or "redis", which shares them between
+	// servers; empty or "off" disables rate limiting
+	Backend string
+	// UserPerMinute is the sustained request rate of each authenticated user; 0 leaves users
+	// unlimited
+	UserPerMinute int
+	// AddressPerMinute is the sustained request rate of each address for requests without a
+	// user, such as logins; 0 leaves them unlimited
+	AddressPerMinute int
+	// Burst is how many requests a client that has been idle may make at once
+	Burst int
+	// TrustedProxies is a comma separated list of the addresses and CIDR ranges of reverse
+	// proxies, whose forwarded client addresses are budgeted instead of their own
+	TrustedProxies string
+	// Redis is the client of the redis backend; budgets fall back to this server's own while
+	// redis is unreachable
+	Redis *redis.Client
+}
+
+// budget is the sustained rate and burst of one kind of client
+type budget struct {
+	name  string
+	rate  float64 // requests per second; 0 is unlimited
+	burst float64
+}
+
+// Limiter rejects requests of clients that exceed their budget. Authenticated requests are
+// budgeted per user, since the devices of a field team often share one address behind a mobile
+// hotspot; other requests are budgeted per address.
+type Limiter struct {
+	user    budget
+	address budget
+	proxies []netip.Prefix
+	now     func() time.Time
+	shared  *redis.Client
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastSweep   time.Time
+	sharedRetry time.Time // while redis is failing, budgets are kept locally until then
+}
+
+// bucket is a token bucket holding the requests a client may currently make
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter, or returns nil if rate limiting is disabled. A nil limiter's
+// Middleware passes requests through unchanged.
+func New(config Config) (*Limiter, error) {
+	if config.Backend == "" || config.Backend == "off" {
+		return nil, nil
+	}
+	if config.UserPerMinute <= 0 && config.AddressPerMinute <= 0 {
+		return nil, nil
+	}
+	proxies, err := auth.ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Limiter{
+		user:    newBudget("user", config.UserPerMinute, config.Burst),
+		address: newBudget("addr", config.AddressPerMinute, config.Burst),
+		proxies: proxies,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+	switch config.Backend {
+	case BackendMemory:
+	case BackendRedis:
+		if config.Redis == nil {
+			return nil, errors.New("the redis rate limiter needs REDIS_URL")
+		}
+		l.shared = config.Redis
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q (expected %s or %s)", config.Backend, BackendMemory, BackendRedis)
+	}
+	return l, nil
+}
+
+// newBudget converts a per-minute rate; the burst is at least one request
+func newBudget(name string, perMinute, burst int) budget {
+	if perMinute <= 0 {
+		return budget{name: name}
+	}
+	return budget{name: name, rate: float64(perMinute) / 60, burst: float64(max(burst, 1))}
+}
+
+// Middleware rejects requests beyond the client's budget. It must run after authentication for
+// authenticated routes so that clients are told apart by user rather than by address.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, key := l.user, ""
+		if user, ok := r.Context().Value(auth.UserKey).(*models.User); ok && user != nil {
+			key = user.Username
+		} else {
+			b, key = l.address, l.remoteHost(r)
+		}
+		if b.rate > 0 {
+			if wait := l.take(r.Context(), b, key); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteHost returns the address of a request's client without its port. The remote address is
+// not used as RealIP replaces it with forwarded headers, which a client could rotate to get a
+// fresh budget with every request; they are only taken from trusted proxies.
+func (l *Limiter) remoteHost(r *http.Request) string {
+	addr := auth.ClientAddr(r, l.proxies)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// take spends one request of a client's budget, or returns how long the client has to wait
+// for the next one
+func (l *Limiter) take(ctx context.Context, b budget, key string) time.Duration {
+	key = b.name + ":" + key
+	if l.sharedAvailable() {
+		wait, err := l.takeShared(ctx, b, key)
+		if err == nil {
+			return wait
+		}
+		l.sharedFailed()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bk, ok := l.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: b.burst, last: now}
+		l.buckets[key] = bk
+	}
+
+	bk.tokens = min(b.burst, bk.tokens+now.Sub(bk.last).Seconds()*b.rate)
+	bk.last = now
+	if bk.tokens >= 1 {
+		bk.tokens--
+		return 0
+	}
+	return time.Duration((1 - bk.tokens) / b.rate * float64(time.Second))
+}
+
+// sweep forgets buckets that have been idle long enough to be full again
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
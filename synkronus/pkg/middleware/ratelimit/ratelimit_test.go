@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+	"github.com/opendataensemble/synkronus/pkg/redis/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(t *testing.T, config Config) (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l, err := New(config)
+	require.NoError(t, err)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func serve(l *Limiter, username, addr string) *httptest.ResponseRecorder {
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest(http.MethodPost, "/sync/pull", nil)
+	r.RemoteAddr = addr + ":51234"
+	if username != "" {
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserKey, &models.User{Username: username}))
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestLimiter_RejectsBeyondBudget(t *testing.T) {
+	l, now := newTestLimiter(t, Config{Backend: BackendMemory, UserPerMinute: 60, AddressPerMinute: 6, Burst: 3})
+
+	// The burst is allowed at once, the next request has to wait a second
+	for range 3 {
+		assert.Equal(t, http.StatusOK, serve(l, "tablet-1", "10.0.0.1").Code)
+	}
+	w := serve(l, "tablet-1", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Other users behind the same address have their own budget
+	assert.Equal(t, http.StatusOK, serve(l, "tablet-2", "10.0.0.1").Code)
+
+	*now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, serve(l, "tablet-1", "10.0.0.1").Code)
+}
+
+func TestLimiter_BudgetsAddressesOfUnauthenticatedRequests(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{Backend: BackendMemory, UserPerMinute: 60, AddressPerMinute: 6, Burst: 1})
+
+	assert.Equal(t, http.StatusOK, serve(l, "", "10.0.0.1").Code)
+	w := serve(l, "", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve(l, "", "10.0.0.2").Code)
+}
+
+func TestLimiter_IgnoresForwardedAddresses(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{Backend: BackendMemory, UserPerMinute: 60, AddressPerMinute: 6, Burst: 1})
+	handler := auth.PeerAddrMiddleware(middleware.RealIP(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	// Rotating X-Forwarded-For does not give a client a fresh budget
+	for i, forwarded := range []string{"192.0.2.1", "192.0.2.2"} {
+		r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		r.RemoteAddr = "10.0.0.1:51234"
+		r.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		}
+	}
+}
+
+func TestLimiter_BudgetsClientsOfTrustedProxies(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{Backend: BackendMemory, AddressPerMinute: 6, Burst: 1, TrustedProxies: "10.0.0.0/8"})
+	handler := auth.PeerAddrMiddleware(middleware.RealIP(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+	login := func(peer, forwarded string) int {
+		r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		r.RemoteAddr = peer + ":51234"
+		r.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Clients behind the proxy have their own budgets, whatever they prepend to the header
+	assert.Equal(t, http.StatusOK, login("10.0.0.1", "192.0.2.1"))
+	assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.1", "203.0.113.9, 192.0.2.1"))
+	assert.Equal(t, http.StatusOK, login("10.0.0.1", "192.0.2.2"))
+
+	// Other peers are budgeted by their own address
+	assert.Equal(t, http.StatusOK, login("198.51.100.1", "192.0.2.3"))
+	assert.Equal(t, http.StatusTooManyRequests, login("198.51.100.1", "192.0.2.4"))
+
+	_, err := New(Config{Backend: BackendMemory, AddressPerMinute: 6, TrustedProxies: "proxy.local"})
+	assert.Error(t, err)
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	for _, config := range []Config{{}, {Backend: "off", UserPerMinute: 60}, {Backend: BackendMemory}} {
+		l, err := New(config)
+		require.NoError(t, err)
+		require.Nil(t, l)
+		assert.Equal(t, http.StatusOK, serve(l, "tablet-1", "10.0.0.1").Code)
+	}
+
+	_, err := New(Config{Backend: BackendRedis, UserPerMinute: 60})
+	assert.Error(t, err, "redis backend without a client")
+	_, err = New(Config{Backend: "disk", UserPerMinute: 60})
+	assert.Error(t, err)
+}
+
+func TestLimiter_SharedFallsBackToLocal(t *testing.T) {
+	// The fake redis does not run scripts, as a redis that cannot be reached would not
+	client, err := redis.New(redistest.NewServer(t, "").URL(""))
+	require.NoError(t, err)
+	l, _ := newTestLimiter(t, Config{Backend: BackendRedis, UserPerMinute: 60, Burst: 1, Redis: client})
+
+	assert.Equal(t, http.StatusOK, serve(l, "tablet-1", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(l, "tablet-1", "10.0.0.1").Code)
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// sharedTimeout bounds a request to redis before the local budget is used instead
+const sharedTimeout = 200 * time.Millisecond
+
+// sharedBackoff is how long budgets stay local after redis failed, so that an unreachable redis
+// does not slow every request down by the timeout
+const sharedBackoff = 10 * time.Second
+
+// takeScript is the token bucket of take kept as a theoretical arrival time: the moment, in
+// milliseconds of the redis clock, at which the requests made so far have been spent at the
+// sustained rate. A request is allowed while that moment lies within the burst allowance, and
+// rejected with the time until it does otherwise.
+const takeScript = `
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local wait = tat - (burst - 1) * interval - now
+if wait > 0 then
+	return math.ceil(wait)
+end
+tat = tat + interval
+redis.call('SET', KEYS[1], tostring(tat), 'PX', math.ceil(tat - now) + 1000)
+return 0`
+
+// sharedAvailable reports whether budgets are to be kept in redis
+func (l *Limiter) sharedAvailable() bool {
+	if l.shared == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.now().Before(l.sharedRetry)
+}
+
+// sharedFailed keeps budgets local for a while
+func (l *Limiter) sharedFailed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sharedRetry = l.now().Add(sharedBackoff)
+}
+
+// takeShared spends one request of a client's budget in redis and returns how long to wait
+func (l *Limiter) takeShared(ctx context.Context, b budget, key string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, sharedTimeout)
+	defer cancel()
+	interval := 1000 / b.rate
+	reply, err := l.shared.Eval(ctx, takeScript, []string{"synkronus:ratelimit:" + key},
+		strconv.FormatFloat(interval, 'f', -1, 64), strconv.FormatFloat(b.burst, 'f', -1, 64))
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
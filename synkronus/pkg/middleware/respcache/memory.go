@@ -0,0 +1,96 @@
+package respcache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxMemoryEntries bounds how many keys the memory store holds
+const maxMemoryEntries = 10000
+
+// MemoryStore keeps cached responses in the server's memory
+type MemoryStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time // zero for keys that do not expire
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: make(map[string]memoryEntry)}
+}
+
+// Get returns the value of a key that has not expired
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || s.expired(e) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set stores a value, making room by dropping expired keys and then arbitrary cached responses
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= maxMemoryEntries {
+		s.evict()
+	}
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expires = s.now().Add(ttl)
+	}
+	s.entries[key] = e
+	return nil
+}
+
+// Incr increments the integer value of a key, starting from 0
+func (s *MemoryStore) Incr(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	if e, ok := s.entries[key]; ok && !s.expired(e) {
+		n, _ = strconv.ParseInt(string(e.value), 10, 64)
+	}
+	n++
+	s.entries[key] = memoryEntry{value: []byte(strconv.FormatInt(n, 10))}
+	return n, nil
+}
+
+func (s *MemoryStore) expired(e memoryEntry) bool {
+	return !e.expires.IsZero() && !s.now().Before(e.expires)
+}
+
+// evict drops expired keys, and a tenth of the expiring keys if none had expired. Keys without
+// expiry are scope generations and are kept.
+func (s *MemoryStore) evict() {
+	before := len(s.entries)
+	for key, e := range s.entries {
+		if s.expired(e) {
+			delete(s.entries, key)
+		}
+	}
+	if len(s.entries) < before {
+		return
+	}
+	drop := maxMemoryEntries / 10
+	for key, e := range s.entries {
+		if drop == 0 {
+			break
+		}
+		if !e.expires.IsZero() {
+			delete(s.entries, key)
+			drop--
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Package respcache caches the responses of expensive read endpoints, so that a fleet of devices
+// polling the same endpoint does not repeat the same work. Cached responses are dropped by
+// invalidating the scopes they depend on when a write changes them.
+package respcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// Backends of the cache
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// maxEntrySize bounds the responses that are cached; larger responses are passed through
+const maxEntrySize = 1 << 20
+
+// storeTimeout bounds each store operation, so a slow store cannot hold up requests
+const storeTimeout = 500 * time.Millisecond
+
+// keyPrefix namespaces the cache's keys in a shared store
+const keyPrefix = "synkronus:respcache:"
+
+// Scope names the data a cached response depends on
+type Scope string
+
+// Scopes of cached responses
+const (
+	// ScopeBundle is the active app bundle and its versions
+	ScopeBundle Scope = "bundle"
+	// ScopeData is observation data, its org unit scoping and the queries run over it
+	ScopeData Scope = "data"
+)
+
+// Store keeps cached responses and scope generations. Keys written without a ttl do not expire.
+// A *redis.Client is a Store.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// Config controls the response cache
+type Config struct {
+	// Backend is "memory", which caches per server, or "redis", which shares the cache and its
+	// invalidations between servers; empty or "off" disables caching
+	Backend string
+	// TTL is how long a response is served from the cache at most
+	TTL time.Duration
+	// Redis is the client of the redis backend
+	Redis *redis.Client
+}
+
+// Cache serves cached responses of the routes it wraps
+type Cache struct {
+	store Store
+	ttl   time.Duration
+	log   *logger.Logger
+}
+
+// New creates a cache, or returns nil if caching is disabled. A nil cache's middleware passes
+// requests through unchanged.
+func New(config Config, log *logger.Logger) (*Cache, error) {
+	if config.Backend == "" || config.Backend == "off" {
+		return nil, nil
+	}
+	if config.TTL <= 0 {
+		return nil, errors.New("response cache TTL must be positive")
+	}
+
+	var store Store
+	switch config.Backend {
+	case BackendMemory:
+		store = NewMemoryStore()
+	case BackendRedis:
+		if config.Redis == nil {
+			return nil, errors.New("the redis response cache needs REDIS_URL")
+		}
+		store = config.Redis
+	default:
+		return nil, fmt.Errorf("unknown response cache backend %q (expected %s or %s)", config.Backend, BackendMemory, BackendRedis)
+	}
+	return NewWithStore(store, config.TTL, log), nil
+}
+
+// NewWithStore creates a cache over the given store
+func NewWithStore(store Store, ttl time.Duration, log *logger.Logger) *Cache {
+	return &Cache{store: store, ttl: ttl, log: log}
+}
+
+// entry is a cached response
+type entry struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// Shared caches the responses of the wrapped GET handlers once for all users of a tenant. Use it
+// only for responses that do not depend on who asks.
+func (c *Cache) Shared(scopes ...Scope) func(http.Handler) http.Handler {
+	return c.middleware(false, scopes)
+}
+
+// PerUser caches the responses of the wrapped GET handlers separately for each user and role.
+// It must run after authentication.
+func (c *Cache) PerUser(scopes ...Scope) func(http.Handler) http.Handler {
+	return c.middleware(true, scopes)
+}
+
+func (c *Cache) middleware(perUser bool, scopes []Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if c == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := c.key(r, perUser, scopes)
+			if err != nil {
+				c.log.Warn("Response cache unavailable", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cached, ok := c.load(r.Context(), key); ok {
+				serve(w, r, cached)
+				return
+			}
+
+			recorder := &recorder{ResponseWriter: w, status: http.StatusOK}
+			w.Header().Set("X-Cache", "MISS")
+			next.ServeHTTP(recorder, r)
+			if recorder.cacheable() {
+				c.save(r.Context(), key, &entry{Header: recorder.header, Body: recorder.body.Bytes()})
+			}
+		})
+	}
+}
+
+// Invalidates drops the cached responses of the given scopes whenever a wrapped handler
+// succeeds at a write; GET and HEAD requests leave the cache alone
+func (c *Cache) Invalidates(scopes ...Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if c == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(status, r)
+			if status.status < 300 {
+				c.Invalidate(r.Context(), scopes...)
+			}
+		})
+	}
+}
+
+// Invalidate drops the cached responses of the given scopes. Responses are not deleted but
+// become unreachable, as each scope's generation is part of the cache key.
+func (c *Cache) Invalidate(ctx context.Context, scopes ...Scope) {
+	if c == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer cancel()
+	for _, scope := range scopes {
+		if _, err := c.store.Incr(ctx, generationKey(scope)); err != nil {
+			c.log.Error("Failed to invalidate cached responses", "error", err, "scope", scope)
+		}
+	}
+}
+
+func generationKey(scope Scope) string {
+	return keyPrefix + "generation:" + string(scope)
+}
+
+// key identifies a request's response: its URL and tenant, the current generation of each scope
+// and, for per-user responses, the user and role
+func (c *Cache) key(r *http.Request, perUser bool, scopes []Scope) (string, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), storeTimeout)
+	defer cancel()
+
+	parts := []string{r.URL.Path, r.URL.Query().Encode(), "tenant=" + tenant.FromContext(r.Context())}
+	for _, scope := range scopes {
+		value, _, err := c.store.Get(ctx, generationKey(scope))
+		if err != nil {
+			return "", err
+		}
+		generation, _ := strconv.ParseInt(string(value), 10, 64)
+		parts = append(parts, string(scope)+"="+strconv.FormatInt(generation, 10))
+	}
+	if perUser {
+		user, ok := r.Context().Value(auth.UserKey).(*models.User)
+		if !ok || user == nil {
+			return "", errors.New("per-user response cache used without authentication")
+		}
+		parts = append(parts, "user="+user.Username, "role="+string(user.Role))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return keyPrefix + "response:" + hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Cache) load(ctx context.Context, key string) (*entry, bool) {
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.log.Warn("Failed to read cached response", "error", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		c.log.Warn("Discarding unreadable cached response", "error", err)
+		return nil, false
+	}
+	return &cached, true
+}
+
+func (c *Cache) save(ctx context.Context, key string, cached *entry) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer cancel()
+	if err := c.store.Set(ctx, key, data, c.ttl); err != nil {
+		c.log.Warn("Failed to cache response", "error", err)
+	}
+}
+
+// serve writes a cached response, answering a matching If-None-Match with 304 Not Modified
+func serve(w http.ResponseWriter, r *http.Request, cached *entry) {
+	for name, values := range cached.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("X-Cache", "HIT")
+	if etag := cached.Header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(cached.Body)
+}
+
+// recorder passes a response through while keeping a copy to cache
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+	tooLarge    bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.status = status
+		r.header = r.ResponseWriter.Header().Clone()
+		r.header.Del("X-Cache")
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.tooLarge {
+		if r.body.Len()+len(p) > maxEntrySize {
+			r.tooLarge = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// cacheable reports whether the recorded response may be served to later requests
+func (r *recorder) cacheable() bool {
+	return r.wroteHeader && r.status == http.StatusOK && !r.tooLarge && r.header.Get("Set-Cookie") == ""
+}
+
+// statusWriter notes the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,181 @@
+package respcache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+	"github.com/opendataensemble/synkronus/pkg/redis/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingHandler answers with how often it has been called
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", fmt.Sprintf("\"%d\"", h.calls))
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+	fmt.Fprintf(w, `{"calls":%d}`, h.calls)
+}
+
+func get(handler http.Handler, target, username string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if username != "" {
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserKey, &models.User{Username: username, Role: models.RoleReadOnly}))
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestSharedCache(t *testing.T) {
+	c := NewWithStore(NewMemoryStore(), time.Minute, logger.NewLogger())
+	next := &countingHandler{}
+	handler := c.Shared(ScopeBundle)(next)
+
+	w := get(handler, "/app-bundle/manifest", "alice")
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	w = get(handler, "/app-bundle/manifest", "bob")
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, `{"calls":1}`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, 1, next.calls)
+
+	// Conditional requests are answered from the cache
+	w = get(handler, "/app-bundle/manifest", "", "If-None-Match", `"1"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Query strings are part of the key
+	get(handler, "/app-bundle/manifest?v=2", "")
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestPerUserCache(t *testing.T) {
+	c := NewWithStore(NewMemoryStore(), time.Minute, logger.NewLogger())
+	next := &countingHandler{}
+	handler := c.PerUser(ScopeData)(next)
+
+	get(handler, "/queries/visits/run", "alice")
+	assert.Equal(t, `{"calls":2}`, get(handler, "/queries/visits/run", "bob").Body.String())
+	assert.Equal(t, `{"calls":1}`, get(handler, "/queries/visits/run", "alice").Body.String())
+
+	// Without a user the response is not cached
+	get(handler, "/queries/visits/run", "")
+	get(handler, "/queries/visits/run", "")
+	assert.Equal(t, 4, next.calls)
+}
+
+func TestInvalidation(t *testing.T) {
+	c := NewWithStore(NewMemoryStore(), time.Minute, logger.NewLogger())
+	next := &countingHandler{}
+	handler := c.Shared(ScopeBundle)(next)
+	get(handler, "/app-bundle/manifest", "")
+
+	write := func(status int) {
+		h := c.Invalidates(ScopeBundle)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/app-bundle/switch/0002", nil))
+	}
+
+	// Failed writes leave the cache alone
+	write(http.StatusBadRequest)
+	assert.Equal(t, "HIT", get(handler, "/app-bundle/manifest", "").Header().Get("X-Cache"))
+
+	write(http.StatusOK)
+	assert.Equal(t, `{"calls":2}`, get(handler, "/app-bundle/manifest", "").Body.String())
+
+	// Other scopes are unaffected
+	c.Invalidate(context.Background(), ScopeData)
+	assert.Equal(t, "HIT", get(handler, "/app-bundle/manifest", "").Header().Get("X-Cache"))
+}
+
+func TestUncacheableResponses(t *testing.T) {
+	c := NewWithStore(NewMemoryStore(), time.Minute, logger.NewLogger())
+	next := &countingHandler{status: http.StatusInternalServerError}
+	handler := c.Shared(ScopeBundle)(next)
+	get(handler, "/app-bundle/manifest", "")
+	get(handler, "/app-bundle/manifest", "")
+	assert.Equal(t, 2, next.calls)
+
+	large := c.Shared(ScopeBundle)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, maxEntrySize+1))
+	}))
+	get(large, "/app-bundle/versions", "")
+	assert.Equal(t, "MISS", get(large, "/app-bundle/versions", "").Header().Get("X-Cache"))
+}
+
+func TestDisabledCache(t *testing.T) {
+	c, err := New(Config{Backend: "off"}, logger.NewLogger())
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	next := &countingHandler{}
+	handler := c.Shared(ScopeBundle)(next)
+	get(handler, "/app-bundle/manifest", "")
+	get(handler, "/app-bundle/manifest", "")
+	assert.Equal(t, 2, next.calls)
+	c.Invalidate(context.Background(), ScopeBundle)
+
+	_, err = New(Config{Backend: "memcached", TTL: time.Minute}, logger.NewLogger())
+	assert.Error(t, err)
+	_, err = New(Config{Backend: BackendRedis, TTL: time.Minute}, logger.NewLogger())
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, s.Set(ctx, "response", []byte("cached"), time.Minute))
+	_, err := s.Incr(ctx, "generation")
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, ok, _ := s.Get(ctx, "response")
+	assert.False(t, ok)
+	value, ok, _ := s.Get(ctx, "generation")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+}
+
+func TestRedisBackend(t *testing.T) {
+	server := redistest.NewServer(t, "")
+	ctx := context.Background()
+
+	// Two servers sharing a redis see each other's cached responses and invalidations
+	newCache := func() *Cache {
+		client, err := redis.New(server.URL(""))
+		require.NoError(t, err)
+		c, err := New(Config{Backend: BackendRedis, TTL: time.Minute, Redis: client}, logger.NewLogger())
+		require.NoError(t, err)
+		return c
+	}
+	next := &countingHandler{}
+	first, second := newCache(), newCache()
+	get(first.Shared(ScopeBundle)(next), "/app-bundle/manifest", "")
+	assert.Equal(t, "HIT", get(second.Shared(ScopeBundle)(next), "/app-bundle/manifest", "").Header().Get("X-Cache"))
+	second.Invalidate(ctx, ScopeBundle)
+	assert.Equal(t, "MISS", get(first.Shared(ScopeBundle)(next), "/app-bundle/manifest", "").Header().Get("X-Cache"))
+}
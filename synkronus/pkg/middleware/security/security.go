@@ -0,0 +1,106 @@
+// Package security sets browser security headers on responses: a Content Security Policy,
+// X-Content-Type-Options, Referrer-Policy and the frame ancestors allowed to embed a page.
+package security
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Default policies used when a deployment does not configure its own
+const (
+	// DefaultAPIPolicy forbids API responses from loading anything, as they are never rendered
+	DefaultAPIPolicy = "default-src 'none'"
+	// DefaultContentPolicy lets hosted app bundle pages load their own scripts, styles and
+	// images and talk back to this server
+	DefaultContentPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; " +
+		"img-src 'self' data: blob:; font-src 'self' data:; connect-src 'self'"
+	// DefaultFrameAncestors stops other sites from embedding any response
+	DefaultFrameAncestors = "'none'"
+	// DefaultReferrerPolicy keeps URLs, which may contain record ids, from leaking to other sites
+	DefaultReferrerPolicy = "no-referrer"
+)
+
+// Config controls the headers set on responses. An empty value omits the corresponding header.
+type Config struct {
+	// APIPolicy is the Content Security Policy of API responses
+	APIPolicy string
+	// ContentPolicy is the Content Security Policy of served bundle content
+	ContentPolicy string
+	// FrameAncestors is the CSP frame-ancestors source list, e.g. 'none', 'self' or an origin
+	FrameAncestors string
+	// ReferrerPolicy is the Referrer-Policy header
+	ReferrerPolicy string
+}
+
+// Headers applies a Config to responses
+type Headers struct {
+	config Config
+}
+
+// New creates security headers from a deployment's configuration
+func New(config Config) *Headers {
+	return &Headers{config: config}
+}
+
+// Middleware sets the headers of API responses on every request. Routes serving bundle
+// content additionally use Content, which replaces the Content Security Policy.
+func (h *Headers) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if h.config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", h.config.ReferrerPolicy)
+		}
+		if frameOptions := h.frameOptions(); frameOptions != "" {
+			header.Set("X-Frame-Options", frameOptions)
+		}
+		h.setPolicy(header, h.config.APIPolicy)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Content sets the Content Security Policy of served bundle content
+func (h *Headers) Content(next http.Handler) http.Handler {
+	return h.Policy(h.config.ContentPolicy)(next)
+}
+
+// Policy returns a middleware replacing the Content Security Policy with policy, keeping the
+// configured frame ancestors. It is used for pages with needs of their own, such as the API docs.
+func (h *Headers) Policy(policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.setPolicy(w.Header(), policy)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setPolicy sets or clears the Content Security Policy, appending the frame ancestors
+func (h *Headers) setPolicy(header http.Header, policy string) {
+	directives := make([]string, 0, 2)
+	if policy = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(policy), ";")); policy != "" {
+		directives = append(directives, policy)
+	}
+	if h.config.FrameAncestors != "" {
+		directives = append(directives, "frame-ancestors "+h.config.FrameAncestors)
+	}
+	if len(directives) == 0 {
+		header.Del("Content-Security-Policy")
+		return
+	}
+	header.Set("Content-Security-Policy", strings.Join(directives, "; "))
+}
+
+// frameOptions mirrors frame ancestors in X-Frame-Options for browsers without CSP support.
+// Origin lists have no equivalent, so the header is left out for them.
+func (h *Headers) frameOptions() string {
+	switch h.config.FrameAncestors {
+	case "'none'":
+		return "DENY"
+	case "'self'":
+		return "SAMEORIGIN"
+	default:
+		return ""
+	}
+}
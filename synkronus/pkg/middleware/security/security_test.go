@@ -0,0 +1,72 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func defaultConfig() Config {
+	return Config{
+		APIPolicy:      DefaultAPIPolicy,
+		ContentPolicy:  DefaultContentPolicy,
+		FrameAncestors: DefaultFrameAncestors,
+		ReferrerPolicy: DefaultReferrerPolicy,
+	}
+}
+
+func serve(handler http.Handler) http.Header {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Header()
+}
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMiddleware_APIResponses(t *testing.T) {
+	header := serve(New(defaultConfig()).Middleware(ok))
+
+	assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+	assert.Equal(t, "no-referrer", header.Get("Referrer-Policy"))
+	assert.Equal(t, "DENY", header.Get("X-Frame-Options"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", header.Get("Content-Security-Policy"))
+}
+
+func TestContent_ReplacesPolicy(t *testing.T) {
+	h := New(defaultConfig())
+	header := serve(h.Middleware(h.Content(ok)))
+
+	assert.Equal(t, DefaultContentPolicy+"; frame-ancestors 'none'", header.Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+}
+
+func TestMiddleware_Configured(t *testing.T) {
+	config := defaultConfig()
+	config.FrameAncestors = "https://portal.example.org"
+	config.ReferrerPolicy = ""
+	config.ContentPolicy = "default-src 'self';"
+	h := New(config)
+
+	header := serve(h.Middleware(ok))
+	assert.Empty(t, header.Get("Referrer-Policy"))
+	assert.Empty(t, header.Get("X-Frame-Options"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors https://portal.example.org", header.Get("Content-Security-Policy"))
+
+	header = serve(h.Middleware(h.Content(ok)))
+	assert.Equal(t, "default-src 'self'; frame-ancestors https://portal.example.org", header.Get("Content-Security-Policy"))
+
+	config.FrameAncestors = "'self'"
+	config.APIPolicy = ""
+	header = serve(New(config).Middleware(ok))
+	assert.Equal(t, "SAMEORIGIN", header.Get("X-Frame-Options"))
+	assert.Equal(t, "frame-ancestors 'self'", header.Get("Content-Security-Policy"))
+
+	config.FrameAncestors = ""
+	header = serve(New(config).Middleware(ok))
+	_, set := header["Content-Security-Policy"]
+	assert.False(t, set)
+}
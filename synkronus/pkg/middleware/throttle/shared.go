@@ -0,0 +1,68 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// sharedTimeout bounds a reservation in redis before the local budget is used instead
+const sharedTimeout = 200 * time.Millisecond
+
+// sharedBackoff is how long budgets stay local after redis failed, so that an unreachable redis
+// does not slow every chunk down by the timeout
+const sharedBackoff = 10 * time.Second
+
+// reserveScript is the token bucket of reserve kept as a theoretical arrival time: the moment,
+// in milliseconds of the redis clock, at which everything reserved so far has been sent at the
+// sustained rate. A reservation waits for whatever lies beyond the burst allowance.
+const reserveScript = `
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+tat = tat + tonumber(ARGV[3]) * 1000 / rate
+redis.call('SET', KEYS[1], tostring(tat), 'PX', math.ceil(tat - now) + 1000)
+local wait = tat - burst * 1000 / rate - now
+if wait <= 0 then
+	return 0
+end
+return math.ceil(wait)`
+
+// sharedAvailable reports whether budgets are to be kept in redis
+func (l *Limiter) sharedAvailable() bool {
+	if l.shared == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.now().Before(l.sharedRetry)
+}
+
+// sharedFailed keeps budgets local for a while
+func (l *Limiter) sharedFailed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sharedRetry = l.now().Add(sharedBackoff)
+}
+
+// reserveShared takes n bytes from a client's budget in redis and returns how long to wait
+func (l *Limiter) reserveShared(ctx context.Context, key string, n int) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, sharedTimeout)
+	defer cancel()
+	reply, err := l.shared.Eval(ctx, reserveScript, []string{"synkronus:throttle:" + key},
+		strconv.FormatFloat(l.rate, 'f', -1, 64), strconv.FormatFloat(l.burst, 'f', -1, 64), strconv.Itoa(n))
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
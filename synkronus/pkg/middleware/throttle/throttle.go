@@ -0,0 +1,202 @@
+// Package throttle paces response bodies per client so that a single large transfer cannot
+// saturate a shared uplink. Requests are never rejected; writes are slowed down instead.
+package throttle
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+)
+
+// maxChunk bounds how much of a response is sent per wait, so concurrent transfers interleave smoothly
+const maxChunk = 32 * 1024
+
+// writeGrace is how long a paced chunk may take to send once its wait is over
+const writeGrace = 15 * time.Second
+
+// idleTimeout is how long an unused client bucket is kept before it is forgotten
+const idleTimeout = 10 * time.Minute
+
+// Config controls the bandwidth available to each client
+type Config struct {
+	// BytesPerSecond is the sustained throughput per client; 0 disables shaping
+	BytesPerSecond int64
+	// Burst is how many bytes a client that has been idle may receive at full speed
+	Burst int64
+	// Redis, when set, keeps the budgets in redis so that servers behind a load balancer share
+	// each client's bandwidth; budgets fall back to this server's own while redis is unreachable
+	Redis *redis.Client
+}
+
+// Limiter shapes response throughput per client. Concurrent requests of the same client share
+// one budget, so opening many parallel downloads does not multiply a client's bandwidth.
+type Limiter struct {
+	rate   float64
+	burst  float64
+	now    func() time.Time
+	sleep  func(r *http.Request, d time.Duration) error
+	shared *redis.Client
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastSweep   time.Time
+	sharedRetry time.Time // while redis is failing, budgets are kept locally until then
+}
+
+// bucket is a token bucket holding the bytes a client may currently receive without waiting.
+// Tokens go negative when a write is reserved ahead of time; the writer then waits it off.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter, or returns nil if shaping is disabled. A nil limiter's Middleware
+// passes requests through unchanged.
+func New(config Config) *Limiter {
+	if config.BytesPerSecond <= 0 {
+		return nil
+	}
+	burst := config.Burst
+	if burst <= 0 {
+		burst = config.BytesPerSecond
+	}
+	return &Limiter{
+		rate:    float64(config.BytesPerSecond),
+		burst:   float64(burst),
+		now:     time.Now,
+		sleep:   sleepContext,
+		shared:  config.Redis,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Middleware paces the response bodies of the wrapped handlers per client. It must run after
+// authentication so that clients are told apart by user rather than by address.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&writer{ResponseWriter: w, limiter: l, key: clientKey(r), request: r}, r)
+	})
+}
+
+// clientKey identifies the client of a request: the authenticated user, else the address of the
+// connection's peer. Forwarded addresses are not trusted, as a client could rotate them to get a
+// fresh bucket with every request.
+func clientKey(r *http.Request) string {
+	if user, ok := r.Context().Value(auth.UserKey).(*models.User); ok && user != nil {
+		return "user:" + user.Username
+	}
+	addr := auth.PeerAddr(r)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return "addr:" + host
+}
+
+// reserve takes n bytes from a client's bucket and returns how long to wait before sending them
+func (l *Limiter) reserve(ctx context.Context, key string, n int) time.Duration {
+	if l.sharedAvailable() {
+		wait, err := l.reserveShared(ctx, key, n)
+		if err == nil {
+			return wait
+		}
+		l.sharedFailed()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+// sweep forgets buckets that have been idle long enough to be full again
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// sleepContext waits for d or until the request is cancelled
+func sleepContext(r *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+// writer paces writes to the underlying response writer
+type writer struct {
+	http.ResponseWriter
+	limiter *Limiter
+	key     string
+	request *http.Request
+}
+
+// Write sends p in small chunks, waiting for each chunk's share of the client's bandwidth
+func (w *writer) Write(p []byte) (int, error) {
+	chunk := max(min(int(w.limiter.burst), maxChunk), 1)
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), chunk)
+		if wait := w.limiter.reserve(w.request.Context(), w.key, n); wait > 0 {
+			// Shaped transfers outlast the server's write timeout, so push the deadline out as we go
+			_ = http.NewResponseController(w.ResponseWriter).SetWriteDeadline(time.Now().Add(wait + writeGrace))
+			if err := w.limiter.sleep(w.request, wait); err != nil {
+				return written, err
+			}
+		}
+		m, err := w.ResponseWriter.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Flush forwards to the underlying writer when it supports flushing
+func (w *writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,111 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/middleware/auth"
+	"github.com/opendataensemble/synkronus/pkg/redis"
+	"github.com/opendataensemble/synkronus/pkg/redis/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances only when the limiter sleeps, so tests measure waits without real delays
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func newTestLimiter(bytesPerSecond, burst int64) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(Config{BytesPerSecond: bytesPerSecond, Burst: burst})
+	l.now = func() time.Time { return clock.now }
+	l.sleep = func(r *http.Request, d time.Duration) error {
+		clock.now = clock.now.Add(d)
+		clock.slept += d
+		return nil
+	}
+	return l, clock
+}
+
+func serve(l *Limiter, username string, body []byte) *httptest.ResponseRecorder {
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/attachments/a", nil)
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserKey, &models.User{Username: username}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestLimiter_PacesResponses(t *testing.T) {
+	l, clock := newTestLimiter(1000, 1000)
+	body := bytes.Repeat([]byte("x"), 3000)
+
+	// The burst is sent at once, the remaining 2000 bytes take two seconds
+	w := serve(l, "laptop", body)
+	assert.Equal(t, body, w.Body.Bytes())
+	assert.Equal(t, 2*time.Second, clock.slept)
+
+	// The same client has used up its budget, another client has not
+	clock.slept = 0
+	serve(l, "laptop", body[:500])
+	assert.Equal(t, 500*time.Millisecond, clock.slept)
+
+	clock.slept = 0
+	serve(l, "tablet", body[:1000])
+	assert.Zero(t, clock.slept)
+}
+
+func TestLimiter_RefillsWhenIdle(t *testing.T) {
+	l, clock := newTestLimiter(1000, 1000)
+	serve(l, "laptop", make([]byte, 1000))
+
+	clock.now = clock.now.Add(time.Minute)
+	clock.slept = 0
+	serve(l, "laptop", make([]byte, 1000))
+	assert.Zero(t, clock.slept)
+}
+
+func TestLimiter_IgnoresForwardedAddresses(t *testing.T) {
+	l, clock := newTestLimiter(1000, 1000)
+	handler := auth.PeerAddrMiddleware(middleware.RealIP(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1000))
+	}))))
+
+	// Anonymous requests from one peer share a bucket whatever address they claim to forward
+	for _, forwarded := range []string{"192.0.2.1", "192.0.2.2"} {
+		r := httptest.NewRequest(http.MethodGet, "/attachments/a", nil)
+		r.RemoteAddr = "10.0.0.1:51234"
+		r.Header.Set("X-Forwarded-For", forwarded)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	assert.Equal(t, time.Second, clock.slept)
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	l := New(Config{})
+	require.Nil(t, l)
+
+	w := serve(l, "laptop", []byte("hello"))
+	assert.Equal(t, "hello", w.Body.String())
+}
+
+func TestLimiter_SharedFallsBackToLocal(t *testing.T) {
+	// The fake redis does not run scripts, as a redis that cannot be reached would not
+	client, err := redis.New(redistest.NewServer(t, "").URL(""))
+	require.NoError(t, err)
+	l, clock := newTestLimiter(1000, 1000)
+	l.shared = client
+
+	serve(l, "laptop", make([]byte, 3000))
+	assert.Equal(t, 2*time.Second, clock.slept)
+}
@@ -0,0 +1,28 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create sync_conflicts table to keep a backlog of conflicting pushes for manual review
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+    id BIGSERIAL PRIMARY KEY,
+    observation_id VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    transmission_id VARCHAR(255),
+    server_record JSONB NOT NULL,
+    client_record JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'resolved')),
+    resolution VARCHAR(20) CHECK (resolution IN ('keep_server', 'keep_client', 'merge')),
+    resolved_by VARCHAR(255),
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes for efficient querying
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_status ON sync_conflicts(status);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_observation_id ON sync_conflicts(observation_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP INDEX IF EXISTS idx_sync_conflicts_observation_id;
+DROP INDEX IF EXISTS idx_sync_conflicts_status;
+DROP TABLE IF EXISTS sync_conflicts;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create form_sync_controls table holding admin switches that pause sync per form type
+CREATE TABLE IF NOT EXISTS form_sync_controls (
+    form_type VARCHAR(255) PRIMARY KEY,
+    push_paused BOOLEAN NOT NULL DEFAULT FALSE,
+    pull_paused BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Sync version at the moment pull was paused, used to re-publish changes made while paused
+    pull_paused_at_version BIGINT,
+    reason TEXT,
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS form_sync_controls;
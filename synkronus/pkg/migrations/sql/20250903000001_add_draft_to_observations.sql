@@ -0,0 +1,17 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Add draft state to observations. Drafts only sync to devices of the user who
+-- owns them and are excluded from exports until finalized.
+ALTER TABLE observations ADD COLUMN draft BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE observations ADD COLUMN draft_owner VARCHAR(255);
+
+-- Create partial index for looking up a user's drafts
+CREATE INDEX IF NOT EXISTS idx_observations_draft_owner ON observations(draft_owner) WHERE draft;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP INDEX IF EXISTS idx_observations_draft_owner;
+ALTER TABLE observations DROP COLUMN IF EXISTS draft_owner;
+ALTER TABLE observations DROP COLUMN IF EXISTS draft;
@@ -0,0 +1,49 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create observation_history table keeping every version of every observation
+CREATE TABLE IF NOT EXISTS observation_history (
+    id BIGSERIAL PRIMARY KEY,
+    observation_id VARCHAR(255) NOT NULL,
+    version BIGINT NOT NULL,
+    form_type VARCHAR(255) NOT NULL,
+    form_version VARCHAR(50) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    synced_at TIMESTAMP WITH TIME ZONE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    draft BOOLEAN NOT NULL DEFAULT FALSE,
+    draft_owner VARCHAR(255),
+    geolocation JSONB,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes for reconstructing the dataset at a past version
+CREATE INDEX IF NOT EXISTS idx_observation_history_observation_version ON observation_history(observation_id, version DESC);
+CREATE INDEX IF NOT EXISTS idx_observation_history_version ON observation_history(version);
+CREATE INDEX IF NOT EXISTS idx_observation_history_recorded_at ON observation_history(recorded_at);
+
+-- Seed the history with the current state of existing observations
+INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation)
+SELECT observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation
+FROM observations;
+
+-- Create function recording each stored observation version
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation); RETURN NULL; END;' LANGUAGE plpgsql;
+
+-- Record history after the version trigger has assigned the final version
+CREATE TRIGGER observations_history_trigger
+    AFTER INSERT OR UPDATE ON observations
+    FOR EACH ROW
+    EXECUTE FUNCTION record_observation_history();
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TRIGGER IF EXISTS observations_history_trigger ON observations;
+DROP FUNCTION IF EXISTS record_observation_history();
+DROP INDEX IF EXISTS idx_observation_history_recorded_at;
+DROP INDEX IF EXISTS idx_observation_history_version;
+DROP INDEX IF EXISTS idx_observation_history_observation_version;
+DROP TABLE IF EXISTS observation_history;
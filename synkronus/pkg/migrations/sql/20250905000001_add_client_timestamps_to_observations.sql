@@ -0,0 +1,15 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Keep the timestamps reported by the device next to the normalized ones,
+-- together with the time the server received the record
+ALTER TABLE observations ADD COLUMN client_created_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE observations ADD COLUMN client_updated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE observations ADD COLUMN received_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+ALTER TABLE observations DROP COLUMN IF EXISTS received_at;
+ALTER TABLE observations DROP COLUMN IF EXISTS client_updated_at;
+ALTER TABLE observations DROP COLUMN IF EXISTS client_created_at;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Track the user who created each observation and the user currently responsible for it
+ALTER TABLE observations ADD COLUMN created_by VARCHAR(255);
+ALTER TABLE observations ADD COLUMN owner VARCHAR(255);
+ALTER TABLE observation_history ADD COLUMN created_by VARCHAR(255);
+ALTER TABLE observation_history ADD COLUMN owner VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_observations_owner ON observations(owner);
+
+-- Record ownership in the observation history as well
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner); RETURN NULL; END;' LANGUAGE plpgsql;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation); RETURN NULL; END;' LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_observations_owner;
+ALTER TABLE observation_history DROP COLUMN IF EXISTS owner;
+ALTER TABLE observation_history DROP COLUMN IF EXISTS created_by;
+ALTER TABLE observations DROP COLUMN IF EXISTS owner;
+ALTER TABLE observations DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,51 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create org_units table holding the organisational hierarchy (e.g. region > district > facility).
+-- path is the materialized list of ancestor ids ("/<root>/<child>/") used for subtree lookups.
+CREATE TABLE IF NOT EXISTS org_units (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(100) UNIQUE,
+    level VARCHAR(50),
+    parent_id UUID REFERENCES org_units(id) ON DELETE RESTRICT,
+    path TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_units_parent_id ON org_units(parent_id);
+CREATE INDEX IF NOT EXISTS idx_org_units_path ON org_units(path text_pattern_ops);
+
+-- Create user_org_units table assigning users to org units
+CREATE TABLE IF NOT EXISTS user_org_units (
+    username VARCHAR(255) NOT NULL,
+    org_unit_id UUID NOT NULL REFERENCES org_units(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, org_unit_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_org_units_org_unit_id ON user_org_units(org_unit_id);
+
+-- Assign observations to org units
+ALTER TABLE observations ADD COLUMN org_unit_id UUID REFERENCES org_units(id) ON DELETE RESTRICT;
+ALTER TABLE observation_history ADD COLUMN org_unit_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_observations_org_unit_id ON observations(org_unit_id);
+
+-- Record org unit assignments in the observation history as well
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner, org_unit_id) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner, NEW.org_unit_id); RETURN NULL; END;' LANGUAGE plpgsql;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner); RETURN NULL; END;' LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_observations_org_unit_id;
+ALTER TABLE observation_history DROP COLUMN IF EXISTS org_unit_id;
+ALTER TABLE observations DROP COLUMN IF EXISTS org_unit_id;
+DROP INDEX IF EXISTS idx_user_org_units_org_unit_id;
+DROP TABLE IF EXISTS user_org_units;
+DROP INDEX IF EXISTS idx_org_units_path;
+DROP INDEX IF EXISTS idx_org_units_parent_id;
+DROP TABLE IF EXISTS org_units;
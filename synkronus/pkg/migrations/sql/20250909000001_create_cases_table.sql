@@ -0,0 +1,50 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create cases table grouping observations for longitudinal follow-up (e.g. maternal health visits).
+-- case_id is generated by clients, like observation_id, so cases can be opened offline.
+CREATE TABLE IF NOT EXISTS cases (
+    case_id VARCHAR(255) PRIMARY KEY,
+    case_type VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMP WITH TIME ZONE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version BIGINT NOT NULL DEFAULT 1,
+    created_by VARCHAR(255),
+    org_unit_id UUID REFERENCES org_units(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_cases_version ON cases(version);
+CREATE INDEX IF NOT EXISTS idx_cases_case_type ON cases(case_type);
+
+-- Cases share the global sync version with observations
+CREATE TRIGGER cases_version_trigger
+    BEFORE INSERT OR UPDATE ON cases
+    FOR EACH ROW
+    EXECUTE FUNCTION increment_sync_version();
+
+-- Link observations to cases. There is no foreign key, since a device may push
+-- follow-up visits before the case itself reaches the server.
+ALTER TABLE observations ADD COLUMN case_id VARCHAR(255);
+ALTER TABLE observation_history ADD COLUMN case_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_observations_case_id ON observations(case_id);
+
+-- Record case links in the observation history as well
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner, org_unit_id, case_id) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner, NEW.org_unit_id, NEW.case_id); RETURN NULL; END;' LANGUAGE plpgsql;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner, org_unit_id) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner, NEW.org_unit_id); RETURN NULL; END;' LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_observations_case_id;
+ALTER TABLE observation_history DROP COLUMN IF EXISTS case_id;
+ALTER TABLE observations DROP COLUMN IF EXISTS case_id;
+DROP TRIGGER IF EXISTS cases_version_trigger ON cases;
+DROP INDEX IF EXISTS idx_cases_case_type;
+DROP INDEX IF EXISTS idx_cases_version;
+DROP TABLE IF EXISTS cases;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create reporting_period_locks table closing date ranges per form type once figures are reported upstream.
+-- mode 'reject' refuses pushes into the period, 'approval' parks them in sync_conflicts for review.
+CREATE TABLE IF NOT EXISTS reporting_period_locks (
+    id BIGSERIAL PRIMARY KEY,
+    form_type VARCHAR(255) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    mode VARCHAR(20) NOT NULL DEFAULT 'reject' CHECK (mode IN ('reject', 'approval')),
+    reason TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (period_end >= period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reporting_period_locks_form_type ON reporting_period_locks(form_type);
+
+-- Distinguish records awaiting approval from edit conflicts in the review backlog
+ALTER TABLE sync_conflicts ADD COLUMN reason VARCHAR(30) NOT NULL DEFAULT 'conflict' CHECK (reason IN ('conflict', 'period_locked'));
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+ALTER TABLE sync_conflicts DROP COLUMN IF EXISTS reason;
+DROP INDEX IF EXISTS idx_reporting_period_locks_form_type;
+DROP TABLE IF EXISTS reporting_period_locks;
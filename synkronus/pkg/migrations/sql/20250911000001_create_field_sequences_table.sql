@@ -0,0 +1,17 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create field_sequences table holding the last number handed out for each server-assigned
+-- sequence field (e.g. registration numbers) declared in form schemas.
+CREATE TABLE IF NOT EXISTS field_sequences (
+    form_type VARCHAR(255) NOT NULL,
+    field VARCHAR(255) NOT NULL,
+    last_value BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (form_type, field)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS field_sequences;
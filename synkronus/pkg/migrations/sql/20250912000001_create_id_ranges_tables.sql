@@ -0,0 +1,31 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create id_sequences table holding the last number reserved for each named ID sequence
+-- (e.g. registration numbers) that offline clients draw from.
+CREATE TABLE IF NOT EXISTS id_sequences (
+    name VARCHAR(255) PRIMARY KEY,
+    last_value BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create id_range_allocations table recording which client holds which block of a sequence
+CREATE TABLE IF NOT EXISTS id_range_allocations (
+    id BIGSERIAL PRIMARY KEY,
+    sequence VARCHAR(255) NOT NULL REFERENCES id_sequences(name) ON DELETE CASCADE,
+    client_id VARCHAR(255) NOT NULL,
+    range_start BIGINT NOT NULL,
+    range_end BIGINT NOT NULL,
+    allocated_by VARCHAR(255),
+    allocated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (range_end >= range_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_id_range_allocations_client_id ON id_range_allocations(client_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP INDEX IF EXISTS idx_id_range_allocations_client_id;
+DROP TABLE IF EXISTS id_range_allocations;
+DROP TABLE IF EXISTS id_sequences;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create observation_documents table for supporting documents (consent scans, correction memos)
+-- attached to observations by office staff. Removed documents are kept with removed_at set.
+CREATE TABLE IF NOT EXISTS observation_documents (
+    id UUID PRIMARY KEY,
+    observation_id VARCHAR(255) NOT NULL REFERENCES observations(observation_id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    description TEXT,
+    uploaded_by VARCHAR(255) NOT NULL,
+    uploaded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    removed_by VARCHAR(255),
+    removed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_observation_documents_observation_id ON observation_documents(observation_id);
+
+-- Create observation_document_audit table recording who attached, downloaded or removed a document
+CREATE TABLE IF NOT EXISTS observation_document_audit (
+    id BIGSERIAL PRIMARY KEY,
+    document_id UUID NOT NULL,
+    observation_id VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('attach', 'download', 'remove')),
+    username VARCHAR(255) NOT NULL,
+    at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_observation_document_audit_observation_id ON observation_document_audit(observation_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP INDEX IF EXISTS idx_observation_document_audit_observation_id;
+DROP TABLE IF EXISTS observation_document_audit;
+DROP INDEX IF EXISTS idx_observation_documents_observation_id;
+DROP TABLE IF EXISTS observation_documents;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create record_locks table holding short-lived edit claims on observations, so two editors of the
+-- same record cannot overwrite each other. Expired rows are ignored and replaced on the next claim.
+CREATE TABLE IF NOT EXISTS record_locks (
+    observation_id VARCHAR(255) PRIMARY KEY,
+    locked_by VARCHAR(255) NOT NULL,
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_locks_expires_at ON record_locks(expires_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP INDEX IF EXISTS idx_record_locks_expires_at;
+DROP TABLE IF EXISTS record_locks;
@@ -0,0 +1,55 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create replicated_records table tracking, per observation, the local version last known to
+-- match the upstream server. Records whose version differs have local changes still to push.
+CREATE TABLE IF NOT EXISTS replicated_records (
+    observation_id VARCHAR(255) PRIMARY KEY,
+    synced_version BIGINT NOT NULL,
+    synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create id_range_blocks table holding blocks of ID sequences reserved on the upstream server,
+-- from which an edge server hands out ID ranges while offline
+CREATE TABLE IF NOT EXISTS id_range_blocks (
+    id BIGSERIAL PRIMARY KEY,
+    sequence VARCHAR(255) NOT NULL,
+    range_start BIGINT NOT NULL,
+    range_end BIGINT NOT NULL,
+    next_value BIGINT NOT NULL,
+    reserved_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT id_range_blocks_range CHECK (range_start <= range_end),
+    CONSTRAINT id_range_blocks_unique UNIQUE (sequence, range_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_id_range_blocks_sequence ON id_range_blocks(sequence);
+
+-- Create federation_state table holding the single row of replication progress with the upstream server
+CREATE TABLE IF NOT EXISTS federation_state (
+    id INTEGER PRIMARY KEY DEFAULT 1,
+    upstream_version BIGINT NOT NULL DEFAULT 0,
+    upstream_cursor_id VARCHAR(255) NOT NULL DEFAULT '',
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_success_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    last_error_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT federation_state_single_row CHECK (id = 1)
+);
+
+INSERT INTO federation_state (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+-- Upstream records conflicting with unsynced local edits are held in the conflict backlog
+ALTER TABLE sync_conflicts DROP CONSTRAINT IF EXISTS sync_conflicts_reason_check;
+ALTER TABLE sync_conflicts ADD CONSTRAINT sync_conflicts_reason_check CHECK (reason IN ('conflict', 'period_locked', 'upstream'));
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+UPDATE sync_conflicts SET reason = 'conflict' WHERE reason = 'upstream';
+ALTER TABLE sync_conflicts DROP CONSTRAINT IF EXISTS sync_conflicts_reason_check;
+ALTER TABLE sync_conflicts ADD CONSTRAINT sync_conflicts_reason_check CHECK (reason IN ('conflict', 'period_locked'));
+
+DROP TABLE IF EXISTS federation_state;
+DROP INDEX IF EXISTS idx_id_range_blocks_sequence;
+DROP TABLE IF EXISTS id_range_blocks;
+DROP TABLE IF EXISTS replicated_records;
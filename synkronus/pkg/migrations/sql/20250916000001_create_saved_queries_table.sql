@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create saved_queries table holding named, parameterized queries over observation data that
+-- dashboards run through the API. definition is a structured query, never raw SQL.
+CREATE TABLE IF NOT EXISTS saved_queries (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    definition JSONB NOT NULL,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS saved_queries;
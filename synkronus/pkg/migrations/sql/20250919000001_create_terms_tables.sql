@@ -0,0 +1,28 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create terms_documents table, the published versions of the terms of use and
+-- data-use agreement; the highest version is current
+CREATE TABLE IF NOT EXISTS terms_documents (
+    version SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    published_by VARCHAR(255) NOT NULL DEFAULT '',
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create terms_acknowledgements table recording which users confirmed which version
+CREATE TABLE IF NOT EXISTS terms_acknowledgements (
+    username VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL REFERENCES terms_documents(version) ON DELETE CASCADE,
+    acknowledged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_terms_acknowledgements_version ON terms_acknowledgements(version);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS terms_acknowledgements;
+DROP TABLE IF EXISTS terms_documents;
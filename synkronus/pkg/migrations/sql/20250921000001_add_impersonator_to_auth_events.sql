@@ -0,0 +1,10 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Flag events caused by an admin impersonating the user
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS impersonator VARCHAR(255) NOT NULL DEFAULT '';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+ALTER TABLE auth_events DROP COLUMN IF EXISTS impersonator;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create sync_compactions table; the highest cutoffs tell which pulls must resync and which
+-- as-of reads can no longer be answered exactly
+CREATE TABLE IF NOT EXISTS sync_compactions (
+    id BIGSERIAL PRIMARY KEY,
+    tombstone_cutoff_version BIGINT NOT NULL DEFAULT 0,
+    history_cutoff_version BIGINT NOT NULL DEFAULT 0,
+    tombstones_purged BIGINT NOT NULL DEFAULT 0,
+    history_versions_removed BIGINT NOT NULL DEFAULT 0,
+    triggered_by VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Tombstones are purged by version
+CREATE INDEX IF NOT EXISTS idx_observations_tombstones ON observations(version) WHERE deleted;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP INDEX IF EXISTS idx_observations_tombstones;
+DROP TABLE IF EXISTS sync_compactions;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create export_templates table holding named export configurations for recurring deliverables.
+-- mask_key keys the HMAC of hashed columns; it is kept when a template is replaced so hashes
+-- stay comparable across deliveries, and is never returned by the API.
+CREATE TABLE IF NOT EXISTS export_templates (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    definition JSONB NOT NULL,
+    mask_key BYTEA NOT NULL,
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS export_templates;
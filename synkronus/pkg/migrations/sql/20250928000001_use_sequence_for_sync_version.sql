@@ -0,0 +1,31 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Versions come from a sequence instead of the sync_version row, whose lock serialized every
+-- write until commit. The row is no longer updated; it is kept for rolling back.
+CREATE SEQUENCE IF NOT EXISTS sync_version_seq;
+SELECT setval('sync_version_seq', GREATEST((SELECT current_version FROM sync_version WHERE id = 1), 1));
+
+-- Create function handing out versions. The first version of a transaction takes a lease: a shared
+-- advisory lock keyed by a lower bound of the versions the transaction takes, held until it ends.
+-- The lower bound relies on the sequence not caching values per session.
+CREATE OR REPLACE FUNCTION next_sync_version() RETURNS BIGINT AS 'BEGIN IF current_setting(''synkronus.sync_version_lease'', true) IS DISTINCT FROM ''held'' THEN PERFORM pg_advisory_xact_lock_shared((SELECT last_value FROM sync_version_seq) + 1); PERFORM set_config(''synkronus.sync_version_lease'', ''held'', true); END IF; RETURN nextval(''sync_version_seq''); END;' LANGUAGE plpgsql;
+
+-- Create function returning the highest version below every version a transaction in progress
+-- may still commit, so that clients reading up to it never miss a record. The sequence is read
+-- before the leases, since a transaction takes its lease before its first version.
+CREATE OR REPLACE FUNCTION sync_stable_version() RETURNS BIGINT AS 'DECLARE issued BIGINT; pending BIGINT; BEGIN SELECT last_value INTO issued FROM sync_version_seq; SELECT MIN((classid::BIGINT << 32) | objid::BIGINT) INTO pending FROM pg_locks WHERE locktype = ''advisory'' AND objsubid = 1 AND pid <> pg_backend_pid() AND database = (SELECT oid FROM pg_database WHERE datname = current_database()) AND ((classid::BIGINT << 32) | objid::BIGINT) <= issued; RETURN LEAST(issued, pending - 1); END;' LANGUAGE plpgsql;
+
+-- Replace the version triggers of observations, cases and attachment operations
+CREATE OR REPLACE FUNCTION increment_sync_version() RETURNS TRIGGER AS 'BEGIN NEW.version = next_sync_version(); NEW.updated_at = NOW(); RETURN NEW; END;' LANGUAGE plpgsql;
+CREATE OR REPLACE FUNCTION increment_attachment_sync_version() RETURNS TRIGGER AS 'BEGIN NEW.version = next_sync_version(); NEW.created_at = NOW(); RETURN NEW; END;' LANGUAGE plpgsql;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+UPDATE sync_version SET current_version = (SELECT last_value FROM sync_version_seq), updated_at = NOW() WHERE id = 1;
+CREATE OR REPLACE FUNCTION increment_attachment_sync_version() RETURNS TRIGGER AS 'BEGIN UPDATE sync_version SET current_version = current_version + 1, updated_at = NOW() WHERE id = 1; NEW.version = (SELECT current_version FROM sync_version WHERE id = 1); NEW.created_at = NOW(); RETURN NEW; END;' LANGUAGE plpgsql;
+CREATE OR REPLACE FUNCTION increment_sync_version() RETURNS TRIGGER AS 'BEGIN UPDATE sync_version SET current_version = current_version + 1, updated_at = NOW() WHERE id = 1; NEW.version = (SELECT current_version FROM sync_version WHERE id = 1); NEW.updated_at = NOW(); RETURN NEW; END;' LANGUAGE plpgsql;
+DROP FUNCTION IF EXISTS sync_stable_version();
+DROP FUNCTION IF EXISTS next_sync_version();
+DROP SEQUENCE IF EXISTS sync_version_seq;
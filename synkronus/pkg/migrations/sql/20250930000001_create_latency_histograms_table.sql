@@ -0,0 +1,18 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create latency_histograms table, the per-day latency distribution of pull, push and export
+-- operations. Bucket i counts operations that took at most 1.1^i milliseconds, so servers
+-- behind a load balancer add their counts to the same rows and percentiles stay exact to 10%.
+CREATE TABLE IF NOT EXISTS latency_histograms (
+    day DATE NOT NULL,
+    operation VARCHAR(32) NOT NULL,
+    bucket SMALLINT NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, operation, bucket)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS latency_histograms;
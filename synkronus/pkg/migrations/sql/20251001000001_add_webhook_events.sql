@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Subscriptions select the events they receive; existing subscriptions keep receiving pushed
+-- observations only
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS events TEXT[] NOT NULL DEFAULT '{observation.pushed}';
+
+-- Bundle and user events are not about an observation
+ALTER TABLE webhook_deliveries ALTER COLUMN observation_id DROP NOT NULL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DELETE FROM webhook_deliveries WHERE observation_id IS NULL;
+ALTER TABLE webhook_deliveries ALTER COLUMN observation_id SET NOT NULL;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS events;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create app_bundle_channels table; clients listed here get the app bundle version of their
+-- channel instead of the current one, e.g. test devices on the preview channel
+CREATE TABLE IF NOT EXISTS app_bundle_channels (
+    client_id VARCHAR(255) PRIMARY KEY,
+    channel VARCHAR(32) NOT NULL CHECK (channel IN ('preview')),
+    assigned_by VARCHAR(255) NOT NULL DEFAULT '',
+    assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS app_bundle_channels;
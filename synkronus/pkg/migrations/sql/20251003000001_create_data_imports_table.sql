@@ -0,0 +1,32 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create data_imports table; CSV files queued for import are claimed by a worker, which appends
+-- the result of every row as it goes. The file is kept until the import finishes.
+CREATE TABLE IF NOT EXISTS data_imports (
+    id UUID PRIMARY KEY,
+    form_type VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    mapping JSONB NOT NULL,
+    bundle_version VARCHAR(255) NOT NULL,
+    content BYTEA,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    total_rows INTEGER NOT NULL,
+    imported_rows INTEGER NOT NULL DEFAULT 0,
+    held_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    results JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    heartbeat_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_imports_status ON data_imports(status, created_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS data_imports;
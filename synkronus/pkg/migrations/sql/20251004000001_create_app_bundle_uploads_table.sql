@@ -0,0 +1,27 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create app_bundle_uploads table; large app bundles are uploaded in chunks that are appended in
+-- order, so an interrupted upload resumes at the received byte count. Uploads left untouched for
+-- a day are removed when the next one starts.
+CREATE TABLE IF NOT EXISTS app_bundle_uploads (
+    id UUID PRIMARY KEY,
+    size BIGINT NOT NULL CHECK (size > 0),
+    received BIGINT NOT NULL DEFAULT 0 CHECK (received <= size),
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS app_bundle_upload_chunks (
+    upload_id UUID NOT NULL REFERENCES app_bundle_uploads(id) ON DELETE CASCADE,
+    start_offset BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (upload_id, start_offset)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS app_bundle_upload_chunks;
+DROP TABLE IF EXISTS app_bundle_uploads;
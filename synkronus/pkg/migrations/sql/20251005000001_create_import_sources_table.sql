@@ -0,0 +1,32 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create import_sources table; submissions are pulled from an ODK Central or KoboToolbox form and
+-- queued as data imports, once or on a schedule. The cursor holds the submission time of the last
+-- submission pulled, and credentials are encrypted with the JWT secret.
+CREATE TABLE IF NOT EXISTS import_sources (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('odk-central', 'kobo')),
+    url TEXT NOT NULL,
+    project_id VARCHAR(255) NOT NULL DEFAULT '',
+    form_id VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    credential BYTEA NOT NULL,
+    mapping JSONB NOT NULL,
+    interval_minutes INTEGER NOT NULL DEFAULT 0 CHECK (interval_minutes >= 0),
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    cursor VARCHAR(64) NOT NULL DEFAULT '',
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_import_id UUID,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_sources_next_run_at ON import_sources(next_run_at) WHERE next_run_at IS NOT NULL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS import_sources;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create sync_transmissions table; a sync push is recorded under its client and transmission ID
+-- while it is processed, then with its response, so a retried transmission is answered with the
+-- original response instead of being applied twice. Rows are deleted once they expire.
+CREATE TABLE IF NOT EXISTS sync_transmissions (
+    client_id VARCHAR(255) NOT NULL,
+    transmission_id VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    response_status INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (client_id, transmission_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_transmissions_expires_at ON sync_transmissions(expires_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS sync_transmissions;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create clients table; every client_id seen in a sync pull or push is registered here with its
+-- last sync, so admins can tell devices apart, label them and suspend or block lost ones
+CREATE TABLE IF NOT EXISTS clients (
+    client_id VARCHAR(255) PRIMARY KEY,
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'blocked')),
+    status_reason TEXT NOT NULL DEFAULT '',
+    status_changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    status_changed_at TIMESTAMP WITH TIME ZONE,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_pull_at TIMESTAMP WITH TIME ZONE,
+    last_push_at TIMESTAMP WITH TIME ZONE,
+    last_version BIGINT NOT NULL DEFAULT 0,
+    last_username VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_clients_last_seen_at ON clients(last_seen_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS clients;
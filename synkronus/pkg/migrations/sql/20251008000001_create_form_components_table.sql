@@ -0,0 +1,21 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create form_components table; a shared form component is a group of questions, such as a
+-- demographics or consent block, that forms include with x-include. Every publish adds a
+-- version; versions are never changed, so bundles resolved against one stay reproducible.
+CREATE TABLE IF NOT EXISTS form_components (
+    name VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    schema JSONB NOT NULL,
+    ui JSONB,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (name, version)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS form_components;
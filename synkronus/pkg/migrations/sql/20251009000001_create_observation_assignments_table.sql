@@ -0,0 +1,27 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create observation_assignments table; each row assigns a record to an enumerator or to a team
+-- (an org unit). With SYNC_PULL_SCOPE=assigned, field users only pull the records they own or
+-- are assigned, directly or through an org unit within their scope.
+CREATE TABLE IF NOT EXISTS observation_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    observation_id VARCHAR(255) NOT NULL REFERENCES observations(observation_id) ON DELETE CASCADE,
+    username VARCHAR(255),
+    org_unit_id UUID REFERENCES org_units(id) ON DELETE CASCADE,
+    assigned_by VARCHAR(255) NOT NULL DEFAULT '',
+    assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((username IS NULL) <> (org_unit_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_observation_assignments_user
+    ON observation_assignments(observation_id, username) WHERE username IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_observation_assignments_org_unit
+    ON observation_assignments(observation_id, org_unit_id) WHERE org_unit_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_observation_assignments_username ON observation_assignments(username);
+CREATE INDEX IF NOT EXISTS idx_observation_assignments_org_unit_id ON observation_assignments(org_unit_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS observation_assignments;
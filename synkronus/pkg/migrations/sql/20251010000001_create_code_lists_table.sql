@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create code_lists table; a code list, such as an ICD subset or a facility registry, holds the
+-- codes form fields referencing it with x-code-list may take. Lists are published separately
+-- from app bundles because they change far more often; every publish adds a version.
+CREATE TABLE IF NOT EXISTS code_lists (
+    name VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    entries JSONB NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (name, version)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS code_lists;
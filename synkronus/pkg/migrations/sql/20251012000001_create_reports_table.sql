@@ -0,0 +1,30 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Create reports table holding report templates: sections laying out saved query results,
+-- rendered on request or delivered by email and webhook on a schedule. next_run_at is NULL for
+-- reports delivered on request only.
+CREATE TABLE IF NOT EXISTS reports (
+    name VARCHAR(100) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    sections JSONB NOT NULL,
+    schedule VARCHAR(16) NOT NULL DEFAULT '' CHECK (schedule IN ('', 'daily', 'weekly', 'monthly')),
+    hour INTEGER NOT NULL DEFAULT 0 CHECK (hour BETWEEN 0 AND 23),
+    format VARCHAR(8) NOT NULL DEFAULT 'html' CHECK (format IN ('html', 'pdf')),
+    recipients TEXT[] NOT NULL DEFAULT '{}',
+    webhook_url TEXT NOT NULL DEFAULT '',
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_next_run_at ON reports(next_run_at) WHERE next_run_at IS NOT NULL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DROP TABLE IF EXISTS reports;
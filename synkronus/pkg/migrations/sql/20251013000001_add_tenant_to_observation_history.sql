@@ -0,0 +1,22 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Historical pulls read the observation history, so it records the tenant of each state as well.
+-- History recorded before takes the tenant of its observation; observation IDs are not reused
+-- across tenants.
+ALTER TABLE observation_history ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+UPDATE observation_history h SET tenant_id = o.tenant_id
+FROM observations o
+WHERE o.observation_id = h.observation_id AND o.tenant_id <> 'default';
+
+CREATE INDEX IF NOT EXISTS idx_observation_history_tenant_version ON observation_history(tenant_id, version);
+
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner, org_unit_id, case_id, tenant_id) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner, NEW.org_unit_id, NEW.case_id, NEW.tenant_id); RETURN NULL; END;' LANGUAGE plpgsql;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS 'BEGIN INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, geolocation, created_by, owner, org_unit_id, case_id) VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.geolocation, NEW.created_by, NEW.owner, NEW.org_unit_id, NEW.case_id); RETURN NULL; END;' LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_observation_history_tenant_version;
+ALTER TABLE observation_history DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Transmissions are kept per tenant and pushing user, so that users never look up or replay each
+-- other's push responses. Kept responses are dropped rather than assigned to a user; they only
+-- answer retries for a short while.
+DELETE FROM sync_transmissions;
+ALTER TABLE sync_transmissions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE sync_transmissions ADD COLUMN IF NOT EXISTS username VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE sync_transmissions DROP CONSTRAINT IF EXISTS sync_transmissions_pkey;
+ALTER TABLE sync_transmissions ADD PRIMARY KEY (tenant_id, username, client_id, transmission_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+
+DELETE FROM sync_transmissions;
+ALTER TABLE sync_transmissions DROP CONSTRAINT IF EXISTS sync_transmissions_pkey;
+ALTER TABLE sync_transmissions DROP COLUMN IF EXISTS username;
+ALTER TABLE sync_transmissions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE sync_transmissions ADD PRIMARY KEY (client_id, transmission_id);
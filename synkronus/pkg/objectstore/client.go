@@ -0,0 +1,299 @@
+// Package objectstore is a small client for S3-compatible object storage such as Amazon S3 and
+// MinIO. It covers the object operations the server needs: put, get, delete and list.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// requestTimeout bounds a single request to the object store
+const requestTimeout = 5 * time.Minute
+
+// Config holds the connection settings of a bucket
+type Config struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.eu-west-1.amazonaws.com or
+	// http://minio:9000
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket as endpoint/bucket rather than bucket.endpoint; MinIO
+	// needs path-style addressing
+	PathStyle bool
+}
+
+// Object describes a stored object
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Client reads and writes the objects of one bucket
+type Client struct {
+	config   Config
+	endpoint *url.URL
+	http     *http.Client
+	now      func() time.Time
+}
+
+// NewClient creates a client for the bucket in config
+func NewClient(config Config) (*Client, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("object store bucket is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("object store credentials are required")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid object store endpoint %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &Client{
+		config:   config,
+		endpoint: endpoint,
+		http:     &http.Client{Timeout: requestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// PutObject stores data under key, replacing any existing object
+func (c *Client) PutObject(ctx context.Context, key string, data []byte, contentType string) error {
+	return c.PutObjectWithClass(ctx, key, data, contentType, "")
+}
+
+// PutObjectWithClass stores data under key in a storage class such as STANDARD_IA or
+// GLACIER_IR, replacing any existing object. An empty class uses the bucket's default.
+func (c *Client) PutObjectWithClass(ctx context.Context, key string, data []byte, contentType, storageClass string) error {
+	header := http.Header{}
+	if storageClass != "" {
+		header.Set("X-Amz-Storage-Class", storageClass)
+	}
+	req, err := c.newRequestWithHeader(ctx, http.MethodPut, key, nil, data, header)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObject opens the object stored under key. It returns ErrNotFound when there is none.
+func (c *Client) GetObject(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	object := &Object{Key: key, Size: resp.ContentLength}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.LastModified = modified
+	}
+	return resp.Body, object, nil
+}
+
+// DeleteObject removes the object stored under key. Deleting a missing object is not an error.
+func (c *Client) DeleteObject(ctx context.Context, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// ListObjects lists the objects whose keys start with prefix. With a delimiter, keys containing
+// the delimiter after the prefix are rolled up into common prefixes instead, like directories.
+func (c *Client) ListObjects(ctx context.Context, prefix, delimiter string) ([]Object, []string, error) {
+	var objects []Object
+	var prefixes []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := c.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse object list: %w", err)
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, LastModified: content.LastModified})
+		}
+		for _, common := range result.CommonPrefixes {
+			prefixes = append(prefixes, common.Prefix)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, prefixes, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// listBucketResult is the response of ListObjectsV2
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// errorResponse is the body of a failed request
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// newRequest builds a signed request for key, or for the bucket itself when key is empty
+func (c *Client) newRequest(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request, error) {
+	return c.newRequestWithHeader(ctx, method, key, query, body, nil)
+}
+
+// newRequestWithHeader builds a signed request carrying header, which the signature covers
+func (c *Client) newRequestWithHeader(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header) (*http.Request, error) {
+	u := *c.endpoint
+	u.Path, u.RawPath = "/"+key, "/"+escapePath(key)
+	if c.config.PathStyle {
+		u.Path, u.RawPath = "/"+c.config.Bucket+u.Path, "/"+escape(c.config.Bucket)+u.RawPath
+		if key == "" {
+			u.Path, u.RawPath = "/"+c.config.Bucket, "/"+escape(c.config.Bucket)
+		}
+	} else {
+		u.Host = c.config.Bucket + "." + u.Host
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	sum := sha256.Sum256(body)
+	c.sign(req, hex.EncodeToString(sum[:]), c.now().UTC())
+	return req, nil
+}
+
+// do sends the request and turns error responses into errors, closing their bodies
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var failure errorResponse
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &failure) == nil && failure.Code != "" {
+		return nil, fmt.Errorf("object store returned %d %s: %s", resp.StatusCode, failure.Code, failure.Message)
+	}
+	return nil, fmt.Errorf("object store returned status %d", resp.StatusCode)
+}
+
+// escapePath escapes each segment of an object key, keeping the slashes between them
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent-encodes everything except unreserved characters, as signature version 4 requires
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes the query sorted by key, as signature version 4 requires
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, escape(key)+"="+escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
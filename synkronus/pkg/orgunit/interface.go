@@ -0,0 +1,68 @@
+package orgunit
+
+import (
+	"context"
+	"errors"
+)
+
+// Common errors
+var (
+	// ErrOrgUnitNotFound is returned when an org unit does not exist
+	ErrOrgUnitNotFound = errors.New("org unit not found")
+	// ErrInvalidOrgUnit is returned when an org unit definition is invalid
+	ErrInvalidOrgUnit = errors.New("invalid org unit")
+	// ErrOrgUnitInUse is returned when deleting an org unit that still has children or observations
+	ErrOrgUnitInUse = errors.New("org unit is in use")
+	// ErrCycle is returned when moving an org unit below one of its own descendants
+	ErrCycle = errors.New("org unit cannot be moved below itself")
+)
+
+// OrgUnit is a node in the organisational hierarchy, e.g. a region, district or facility
+type OrgUnit struct {
+	ID        string  `json:"id" db:"id"`
+	Name      string  `json:"name" db:"name"`
+	Code      *string `json:"code,omitempty" db:"code"`
+	Level     *string `json:"level,omitempty" db:"level"`
+	ParentID  *string `json:"parent_id,omitempty" db:"parent_id"`
+	Path      string  `json:"path" db:"path"`
+	CreatedAt string  `json:"created_at" db:"created_at"`
+	UpdatedAt string  `json:"updated_at" db:"updated_at"`
+}
+
+// OrgUnitInput holds the editable fields of an org unit
+type OrgUnitInput struct {
+	Name     string  `json:"name"`
+	Code     *string `json:"code,omitempty"`
+	Level    *string `json:"level,omitempty"`
+	ParentID *string `json:"parent_id,omitempty"`
+}
+
+// Service manages the org unit hierarchy and its assignments
+type Service interface {
+	// List returns all org units ordered by path, so parents precede their children
+	List(ctx context.Context) ([]OrgUnit, error)
+
+	// Get returns a single org unit
+	Get(ctx context.Context, id string) (*OrgUnit, error)
+
+	// Create adds a new org unit
+	Create(ctx context.Context, input OrgUnitInput) (*OrgUnit, error)
+
+	// Update changes an org unit, moving its subtree when the parent changes
+	Update(ctx context.Context, id string, input OrgUnitInput) (*OrgUnit, error)
+
+	// Delete removes an org unit without children or observations
+	Delete(ctx context.Context, id string) error
+
+	// ListUsers returns the usernames assigned to an org unit
+	ListUsers(ctx context.Context, id string) ([]string, error)
+
+	// AssignUser assigns a user to an org unit
+	AssignUser(ctx context.Context, id, username string) error
+
+	// UnassignUser removes a user from an org unit
+	UnassignUser(ctx context.Context, id, username string) error
+
+	// AssignObservations moves observations to an org unit and returns the number changed
+	AssignObservations(ctx context.Context, id string, observationIDs []string) (int64, error)
+}
@@ -0,0 +1,338 @@
+package orgunit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// orgUnitColumns lists the columns selected for an OrgUnit in scan order
+const orgUnitColumns = "id, name, code, level, parent_id, path, created_at, updated_at"
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new org unit service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrgUnit(row rowScanner) (*OrgUnit, error) {
+	var ou OrgUnit
+	if err := row.Scan(&ou.ID, &ou.Name, &ou.Code, &ou.Level, &ou.ParentID, &ou.Path, &ou.CreatedAt, &ou.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &ou, nil
+}
+
+// validID reports whether id is a well formed org unit id
+func validID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// List returns all org units ordered by path, so parents precede their children
+func (s *service) List(ctx context.Context) ([]OrgUnit, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+orgUnitColumns+" FROM org_units ORDER BY path")
+	if err != nil {
+		s.log.Error("Failed to query org units", "error", err)
+		return nil, fmt.Errorf("failed to query org units: %w", err)
+	}
+	defer rows.Close()
+
+	units := make([]OrgUnit, 0)
+	for rows.Next() {
+		ou, err := scanOrgUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan org unit: %w", err)
+		}
+		units = append(units, *ou)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return units, nil
+}
+
+// Get returns a single org unit
+func (s *service) Get(ctx context.Context, id string) (*OrgUnit, error) {
+	if !validID(id) {
+		return nil, ErrOrgUnitNotFound
+	}
+
+	ou, err := scanOrgUnit(s.db.QueryRowContext(ctx, "SELECT "+orgUnitColumns+" FROM org_units WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrgUnitNotFound
+		}
+		s.log.Error("Failed to get org unit", "error", err, "orgUnitId", id)
+		return nil, fmt.Errorf("failed to get org unit: %w", err)
+	}
+	return ou, nil
+}
+
+// parentPath returns the path of the parent org unit, or "/" for root units
+func (s *service) parentPath(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, parentID *string) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+	if !validID(*parentID) {
+		return "", fmt.Errorf("%w: parent org unit not found", ErrInvalidOrgUnit)
+	}
+
+	var path string
+	err := q.QueryRowContext(ctx, "SELECT path FROM org_units WHERE id = $1", *parentID).Scan(&path)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: parent org unit not found", ErrInvalidOrgUnit)
+		}
+		return "", fmt.Errorf("failed to get parent org unit: %w", err)
+	}
+	return path, nil
+}
+
+// Create adds a new org unit
+func (s *service) Create(ctx context.Context, input OrgUnitInput) (*OrgUnit, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidOrgUnit)
+	}
+
+	parentPath, err := s.parentPath(ctx, s.db, input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	ou, err := scanOrgUnit(s.db.QueryRowContext(ctx, `
+		INSERT INTO org_units (id, name, code, level, parent_id, path)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+orgUnitColumns,
+		id, input.Name, input.Code, input.Level, input.ParentID, parentPath+id+"/"))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: code is already used", ErrInvalidOrgUnit)
+		}
+		s.log.Error("Failed to create org unit", "error", err)
+		return nil, fmt.Errorf("failed to create org unit: %w", err)
+	}
+
+	s.log.Info("Org unit created", "orgUnitId", ou.ID, "name", ou.Name)
+	return ou, nil
+}
+
+// Update changes an org unit, moving its subtree when the parent changes
+func (s *service) Update(ctx context.Context, id string, input OrgUnitInput) (*OrgUnit, error) {
+	if !validID(id) {
+		return nil, ErrOrgUnitNotFound
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidOrgUnit)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	current, err := scanOrgUnit(tx.QueryRowContext(ctx, "SELECT "+orgUnitColumns+" FROM org_units WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrgUnitNotFound
+		}
+		return nil, fmt.Errorf("failed to get org unit: %w", err)
+	}
+
+	parentPath, err := s.parentPath(ctx, tx, input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(parentPath, current.Path) {
+		return nil, ErrCycle
+	}
+
+	newPath := parentPath + id + "/"
+	if newPath != current.Path {
+		// Re-root the whole subtree below the new parent
+		_, err := tx.ExecContext(ctx, `
+			UPDATE org_units
+			SET path = $1 || substr(path, $2), updated_at = NOW()
+			WHERE path LIKE $3 AND id <> $4`,
+			newPath, len(current.Path)+1, current.Path+"%", id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move org unit subtree: %w", err)
+		}
+	}
+
+	updated, err := scanOrgUnit(tx.QueryRowContext(ctx, `
+		UPDATE org_units
+		SET name = $1, code = $2, level = $3, parent_id = $4, path = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING `+orgUnitColumns,
+		input.Name, input.Code, input.Level, input.ParentID, newPath, id))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: code is already used", ErrInvalidOrgUnit)
+		}
+		s.log.Error("Failed to update org unit", "error", err, "orgUnitId", id)
+		return nil, fmt.Errorf("failed to update org unit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Org unit updated", "orgUnitId", id, "moved", newPath != current.Path)
+	return updated, nil
+}
+
+// Delete removes an org unit without children or observations
+func (s *service) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrOrgUnitNotFound
+	}
+
+	var inUse bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM org_units WHERE parent_id = $1)
+		    OR EXISTS (SELECT 1 FROM observations WHERE org_unit_id = $1)`, id).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to check org unit usage: %w", err)
+	}
+	if inUse {
+		return ErrOrgUnitInUse
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM org_units WHERE id = $1", id)
+	if err != nil {
+		s.log.Error("Failed to delete org unit", "error", err, "orgUnitId", id)
+		return fmt.Errorf("failed to delete org unit: %w", err)
+	}
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		return ErrOrgUnitNotFound
+	}
+
+	s.log.Info("Org unit deleted", "orgUnitId", id)
+	return nil
+}
+
+// ListUsers returns the usernames assigned to an org unit
+func (s *service) ListUsers(ctx context.Context, id string) ([]string, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT username FROM user_org_units WHERE org_unit_id = $1 ORDER BY username", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query org unit users: %w", err)
+	}
+	defer rows.Close()
+
+	usernames := make([]string, 0)
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("failed to scan username: %w", err)
+		}
+		usernames = append(usernames, username)
+	}
+
+	return usernames, rows.Err()
+}
+
+// AssignUser assigns a user to an org unit
+func (s *service) AssignUser(ctx context.Context, id, username string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO user_org_units (username, org_unit_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		username, id)
+	if err != nil {
+		s.log.Error("Failed to assign user to org unit", "error", err, "orgUnitId", id, "username", username)
+		return fmt.Errorf("failed to assign user to org unit: %w", err)
+	}
+
+	s.log.Info("User assigned to org unit", "orgUnitId", id, "username", username)
+	return nil
+}
+
+// UnassignUser removes a user from an org unit
+func (s *service) UnassignUser(ctx context.Context, id, username string) error {
+	if !validID(id) {
+		return ErrOrgUnitNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM user_org_units WHERE username = $1 AND org_unit_id = $2", username, id)
+	if err != nil {
+		return fmt.Errorf("failed to unassign user from org unit: %w", err)
+	}
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		return ErrOrgUnitNotFound
+	}
+
+	s.log.Info("User unassigned from org unit", "orgUnitId", id, "username", username)
+	return nil
+}
+
+// AssignObservations moves observations to an org unit. The version trigger gives each
+// moved record a new version, so scoped clients gain or lose it on their next pull.
+func (s *service) AssignObservations(ctx context.Context, id string, observationIDs []string) (int64, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return 0, err
+	}
+	if len(observationIDs) == 0 {
+		return 0, fmt.Errorf("%w: observation_ids is required", ErrInvalidOrgUnit)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE observations SET org_unit_id = $1 WHERE observation_id = ANY($2) AND org_unit_id IS DISTINCT FROM $1",
+		id, pq.Array(observationIDs))
+	if err != nil {
+		s.log.Error("Failed to assign observations to org unit", "error", err, "orgUnitId", id)
+		return 0, fmt.Errorf("failed to assign observations to org unit: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	s.log.Info("Observations assigned to org unit", "orgUnitId", id, "recordCount", count)
+	return count, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
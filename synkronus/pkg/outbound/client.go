@@ -0,0 +1,40 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// NewClient returns an HTTP client that only connects to destinations the policy allows. The
+// check runs when connecting, against the addresses actually dialled, so redirects and DNS
+// changes after CheckURL cannot reach blocked addresses. Proxy settings from the environment are
+// ignored, since a proxy would connect on the client's behalf.
+func NewClient(policy Policy, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := policy.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		// Dial the checked addresses themselves rather than the name, which could resolve differently
+		var dialErr error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
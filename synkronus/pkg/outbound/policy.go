@@ -0,0 +1,150 @@
+// Package outbound guards HTTP requests the server makes to URLs supplied by admins, such as
+// webhook receivers, so they cannot be pointed at internal services.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// ErrBlocked is returned when the policy forbids a destination
+var ErrBlocked = errors.New("outbound request blocked")
+
+// Policy decides which destinations outbound requests may reach. Entries of Allow and Deny are
+// hostnames ("hooks.example.org"), wildcard domains ("*.example.org", matching subdomains only)
+// or IP prefixes ("10.20.0.0/16", "192.0.2.7").
+//
+// Denied destinations are always blocked. When Allow is not empty only allowed destinations can
+// be reached. Loopback, private, link-local and other non-public addresses are blocked unless the
+// destination is allowed explicitly.
+type Policy struct {
+	Allow []string
+	Deny  []string
+}
+
+// blockedPrefixes are address ranges that are not publicly routable, beyond those the net/netip
+// predicates cover
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, which can reach IPv4 internal addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4, which embeds IPv4 addresses
+}
+
+// isPublic reports whether addr is a publicly routable unicast address
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchHost reports whether host matches one of the hostname entries
+func matchHost(entries []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if entry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddr reports whether addr falls in one of the IP entries
+func matchAddr(entries []string, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+		} else if ip, err := netip.ParseAddr(entry); err == nil && ip.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost applies the hostname rules. It reports whether the host was allowed by name, which
+// exempts its addresses from the allowlist and the non-public address block.
+func (p Policy) checkHost(host string) (allowedByName bool, err error) {
+	if matchHost(p.Deny, host) {
+		return false, fmt.Errorf("%w: %s is denied", ErrBlocked, host)
+	}
+	return matchHost(p.Allow, host), nil
+}
+
+// checkAddr applies the address rules to an address host resolved to
+func (p Policy) checkAddr(host string, addr netip.Addr, allowedByName bool) error {
+	if matchAddr(p.Deny, addr) {
+		return fmt.Errorf("%w: %s (%s) is denied", ErrBlocked, host, addr)
+	}
+	if allowedByName || matchAddr(p.Allow, addr) {
+		return nil
+	}
+	if len(p.Allow) > 0 {
+		return fmt.Errorf("%w: %s is not in the allowlist", ErrBlocked, host)
+	}
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s resolves to non-public address %s", ErrBlocked, host, addr)
+	}
+	return nil
+}
+
+// resolve returns the addresses of host that the policy allows connecting to, in resolver order
+func (p Policy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	allowedByName, err := p.checkHost(host)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else if addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host); err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+
+	// Every address must pass, so a name cannot mix public and internal addresses
+	for _, addr := range addrs {
+		if err := p.checkAddr(host, addr, allowedByName); err != nil {
+			return nil, err
+		}
+	}
+	return addrs, nil
+}
+
+// CheckURL reports whether rawURL is an http or https URL the policy lets requests reach. It
+// resolves the host; requests through NewClient are checked again when they connect.
+func (p Policy) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+	_, err = p.resolve(ctx, u.Hostname())
+	return err
+}
@@ -0,0 +1,94 @@
+package outbound
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublic(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::1":   true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"::1":                  false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"64:ff9b::a00:1":       false,
+		"2002:a00:1::1":        false,
+		"255.255.255.255":      false,
+		"224.0.0.1":            false,
+		"::ffff:93.184.216.34": true,
+	} {
+		assert.Equal(t, public, isPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := t.Context()
+
+	// Internal addresses are blocked by default
+	assert.NoError(t, Policy{}.CheckURL(ctx, "https://93.184.216.34/hook"))
+	assert.ErrorIs(t, Policy{}.CheckURL(ctx, "http://127.0.0.1:8080/hook"), ErrBlocked)
+	assert.ErrorIs(t, Policy{}.CheckURL(ctx, "http://169.254.169.254/latest/meta-data"), ErrBlocked)
+	assert.ErrorIs(t, Policy{}.CheckURL(ctx, "http://[::1]/hook"), ErrBlocked)
+	assert.ErrorIs(t, Policy{}.CheckURL(ctx, "http://localhost/hook"), ErrBlocked)
+	assert.Error(t, Policy{}.CheckURL(ctx, "file:///etc/passwd"))
+
+	// Allowing a prefix or a name opens internal addresses
+	internal := Policy{Allow: []string{"10.20.0.0/16", "localhost"}}
+	assert.NoError(t, internal.CheckURL(ctx, "http://10.20.3.4/hook"))
+	assert.NoError(t, internal.CheckURL(ctx, "http://localhost/hook"))
+	assert.ErrorIs(t, internal.CheckURL(ctx, "http://10.30.3.4/hook"), ErrBlocked)
+
+	// With an allowlist nothing else is reachable
+	allow := Policy{Allow: []string{"*.example.org", "192.0.2.7"}}
+	assert.NoError(t, allow.CheckURL(ctx, "http://192.0.2.7/hook"))
+	assert.ErrorIs(t, allow.CheckURL(ctx, "https://93.184.216.34/hook"), ErrBlocked)
+	assert.True(t, matchHost(allow.Allow, "hooks.example.org"))
+	assert.False(t, matchHost(allow.Allow, "example.org"))
+	assert.False(t, matchHost(allow.Allow, "hooks.example.org.evil.net"))
+
+	// The denylist wins over everything
+	deny := Policy{Allow: []string{"127.0.0.0/8"}, Deny: []string{"127.0.0.2", "*.internal.example.org"}}
+	assert.NoError(t, deny.CheckURL(ctx, "http://127.0.0.1/hook"))
+	assert.ErrorIs(t, deny.CheckURL(ctx, "http://127.0.0.2/hook"), ErrBlocked)
+	_, err := deny.checkHost("db.internal.example.org")
+	assert.ErrorIs(t, err, ErrBlocked)
+}
+
+func TestNewClient(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// The test receiver listens on loopback, which is blocked until allowed
+	_, err := NewClient(Policy{}, time.Second).Get(receiver.URL)
+	assert.ErrorIs(t, err, ErrBlocked)
+
+	resp, err := NewClient(Policy{Allow: []string{"127.0.0.1"}}, time.Second).Get(receiver.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Redirects are checked when they connect
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer redirector.Close()
+	_, err = NewClient(Policy{Allow: []string{"127.0.0.1"}}, time.Second).Get(redirector.URL)
+	assert.ErrorIs(t, err, ErrBlocked)
+}
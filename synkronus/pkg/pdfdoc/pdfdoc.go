@@ -0,0 +1,228 @@
+// Package pdfdoc sets lines of text on A4 pages and writes them as a PDF document, for printable
+// codebooks and reports. Text is set in the standard Helvetica fonts, which need not be embedded;
+// characters outside their Latin-1 range are replaced.
+package pdfdoc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page geometry, in points: A4 with 50pt margins
+const (
+	PageWidth  = 595
+	PageHeight = 842
+	Margin     = 50
+	// TextWidth is the width of the text between the margins
+	TextWidth = PageWidth - 2*Margin
+)
+
+// Fonts text is set in
+const (
+	FontRegular = "F1"
+	FontBold    = "F2"
+)
+
+// Document sets lines of text on pages, starting a new page when one is full. Every page ends
+// with a footer and its page number.
+type Document struct {
+	footer  string
+	pages   []*bytes.Buffer
+	content *bytes.Buffer
+	y       float64
+}
+
+// New creates a document with the given footer and starts its first page
+func New(footer string) *Document {
+	d := &Document{footer: footer}
+	d.NewPage()
+	return d
+}
+
+// NewPage finishes the current page with its footer and starts the next one
+func (d *Document) NewPage() {
+	d.finishPage()
+	d.content = &bytes.Buffer{}
+	d.pages = append(d.pages, d.content)
+	d.y = PageHeight - Margin
+}
+
+func (d *Document) finishPage() {
+	if d.content == nil {
+		return
+	}
+	text := fmt.Sprintf("%s - page %d", d.footer, len(d.pages))
+	fmt.Fprintf(d.content, "BT /%s 8 Tf %d %d Td (%s) Tj ET\n", FontRegular, Margin, Margin/2, pdfText(text))
+}
+
+// Space leaves vertical space, unless at the top of a page
+func (d *Document) Space(points float64) {
+	if d.y < PageHeight-Margin {
+		d.y -= points
+	}
+}
+
+// Line sets text in a font, indented by points and wrapped to the page width
+func (d *Document) Line(font string, size, indent float64, text string) {
+	for _, part := range Wrap(text, size, TextWidth-indent) {
+		d.advance(size)
+		d.text(font, size, Margin+indent, part)
+	}
+}
+
+// Cell is text set on a row at X points from the left margin, cut to fit Width points
+type Cell struct {
+	X     float64
+	Width float64
+	Text  string
+	// Right aligns the text with the right edge of the cell, as for numbers
+	Right bool
+}
+
+// Row sets cells of text side by side on one line
+func (d *Document) Row(font string, size float64, cells []Cell) {
+	d.advance(size)
+	for _, cell := range cells {
+		text := cut(cell.Text, size, cell.Width)
+		x := Margin + cell.X
+		if cell.Right {
+			x += cell.Width - Width(text, size)
+		}
+		d.text(font, size, x, text)
+	}
+}
+
+// advance moves down a line of text of size points, starting a new page when it would not fit
+func (d *Document) advance(size float64) {
+	leading := size * 1.3
+	if d.y-leading < Margin {
+		d.NewPage()
+	}
+	d.y -= leading
+}
+
+func (d *Document) text(font string, size, x float64, text string) {
+	fmt.Fprintf(d.content, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, pdfText(text))
+}
+
+// Write writes the document: catalog, page tree, fonts, then each page and its content
+func (d *Document) Write(w io.Writer) error {
+	d.finishPage()
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, FontRegular, FontBold, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// charWidth is the average width of a Helvetica character relative to the font size
+const charWidth = 0.52
+
+// Width estimates the width of text in Helvetica of size points
+func Width(text string, size float64) float64 {
+	return float64(len([]rune(text))) * size * charWidth
+}
+
+// Wrap breaks text into lines that fit width points in Helvetica of size points. Widths are
+// estimated from the average character width; words longer than a line are broken.
+func Wrap(text string, size, width float64) []string {
+	limit := int(width / (size * charWidth))
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > limit {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:limit]))
+			word = string(runes[limit:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case len([]rune(current))+1+len([]rune(word)) <= limit:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// cut shortens text that does not fit width points, ending it with "..."
+func cut(text string, size, width float64) string {
+	// Rounded so that text measured with Width fits exactly
+	limit := int(width/(size*charWidth) + 1e-9)
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	if limit <= 3 {
+		return string(runes[:max(limit, 0)])
+	}
+	return string(runes[:limit-3]) + "..."
+}
+
+// typography maps characters outside Latin-1 to the closest text Helvetica can set
+var typography = map[rune]string{
+	'‘': "'", '’': "'", '“': `"`, '”': `"`, '–': "-", '—': "-", '…': "...",
+	'≥': ">=", '≤': "<=", '≠': "!=", '→': "->",
+}
+
+// pdfText encodes text as the content of a PDF string in WinAnsiEncoding
+func pdfText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case typography[r] != "":
+			b.WriteString(typography[r])
+		case r == '\t' || r == '\n' || r == '\r':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
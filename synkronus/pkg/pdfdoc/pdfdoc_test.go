@@ -0,0 +1,39 @@
+package pdfdoc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	lines := Wrap(strings.Repeat("word ", 100), 10, 100)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), 19)
+	}
+	assert.Equal(t, []string{""}, Wrap("", 10, 100))
+	assert.Len(t, Wrap(strings.Repeat("x", 40), 10, 100), 3, "long words are broken")
+}
+
+func TestCut(t *testing.T) {
+	assert.Equal(t, "Kibera", cut("Kibera", 10, 100))
+	assert.Equal(t, "Kibera West Dist...", cut("Kibera West District Office", 10, 100))
+	assert.Equal(t, "Ki", cut("Kibera", 10, 12))
+}
+
+func TestRow(t *testing.T) {
+	doc := New("Report")
+	doc.Row(FontRegular, 10, []Cell{
+		{X: 0, Width: 200, Text: "Kibera (East)"},
+		{X: 200, Width: 100, Text: "42", Right: true},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.Write(&buf))
+	assert.Contains(t, buf.String(), "50.00 779.00 Td (Kibera \\(East\\)) Tj")
+	// Right aligned with the cell's right edge at 350pt
+	assert.Contains(t, buf.String(), "339.60 779.00 Td (42) Tj")
+}
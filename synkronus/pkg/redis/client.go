@@ -0,0 +1,315 @@
+// Package redis is a small redis client for the state that replicas behind a load balancer share:
+// cached responses, bandwidth budgets, idempotency keys and notifications. It speaks the few
+// commands it needs of the redis protocol itself.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIdleConns bounds the connections kept open between commands
+const maxIdleConns = 8
+
+// dialTimeout bounds connecting to the server
+const dialTimeout = 2 * time.Second
+
+// commandTimeout bounds a command whose context has no deadline
+const commandTimeout = 2 * time.Second
+
+// resubscribeDelay is how long Subscribe waits before reconnecting after losing its connection
+const resubscribeDelay = time.Second
+
+// Error is an error reply of the server; the connection it came on stays usable
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// Client runs commands on a pool of connections to one server
+type Client struct {
+	address  string
+	password string
+	database int
+	idle     chan *conn
+}
+
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// New creates a client for a redis://[:password@]host[:port][/database] URL. It does not connect
+// until the first command.
+func New(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid redis URL %q (expected redis://[:password@]host[:port][/database])", rawURL)
+	}
+	c := &Client{address: u.Host, idle: make(chan *conn, maxIdleConns)}
+	if u.Port() == "" {
+		c.address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		c.password = password
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.database, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Ping checks that the server can be reached
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Get returns the value of a key
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected redis reply to GET: %v", reply)
+	}
+	return value, true, nil
+}
+
+// Set stores a value, expiring it after ttl when ttl is positive
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.Do(ctx, withTTL([]string{"SET", key, string(value)}, ttl)...)
+	return err
+}
+
+// SetNX stores a value unless the key exists, and reports whether it was stored
+func (c *Client) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	reply, err := c.Do(ctx, withTTL([]string{"SET", key, string(value), "NX"}, ttl)...)
+	return reply != nil, err
+}
+
+func withTTL(args []string, ttl time.Duration) []string {
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	return args
+}
+
+// Incr increments the integer value of a key, starting from 0
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected redis reply to INCR: %v", reply)
+	}
+	return n, nil
+}
+
+// Del deletes keys
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	_, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Eval runs a Lua script atomically
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...string) (any, error) {
+	command := append([]string{"EVAL", script, strconv.Itoa(len(keys))}, keys...)
+	return c.Do(ctx, append(command, args...)...)
+}
+
+// Publish sends a message to the subscribers of a channel
+func (c *Client) Publish(ctx context.Context, channel, message string) error {
+	_, err := c.Do(ctx, "PUBLISH", channel, message)
+	return err
+}
+
+// Subscribe calls handle with each message published to channel until ctx is done, reconnecting
+// when the connection is lost. Messages published while it reconnects are missed.
+func (c *Client) Subscribe(ctx context.Context, channel string, handle func(message string)) {
+	for ctx.Err() == nil {
+		if err := c.subscribe(ctx, channel, handle); err != nil && ctx.Err() == nil {
+			select {
+			case <-time.After(resubscribeDelay):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+func (c *Client) subscribe(ctx context.Context, channel string, handle func(message string)) error {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	cn, err := c.dial(dialCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	stop := context.AfterFunc(ctx, func() { cn.Close() })
+	defer stop()
+
+	cn.SetDeadline(time.Time{})
+	if _, err := cn.command("SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	for {
+		reply, err := cn.reply()
+		if err != nil {
+			return err
+		}
+		// Pushed messages are ["message", channel, payload]
+		if parts, ok := reply.([]any); ok && len(parts) == 3 {
+			if kind, _ := parts[0].([]byte); string(kind) == "message" {
+				if payload, ok := parts[2].([]byte); ok {
+					handle(string(payload))
+				}
+			}
+		}
+	}
+}
+
+// Do runs a command on a pooled connection. Connections that fail are closed rather than
+// returned to the pool.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(commandTimeout)
+	}
+	cn.SetDeadline(deadline)
+
+	reply, err := cn.command(args...)
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		cn.Close()
+		return nil, err
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+	return reply, err
+}
+
+// conn takes an idle connection or opens a new one
+func (c *Client) conn(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+	return c.dial(ctx)
+}
+
+// dial opens an authenticated connection to the configured database
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	cn := &conn{Conn: nc, reader: bufio.NewReader(nc)}
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.SetDeadline(deadline)
+	}
+	if c.password != "" {
+		if _, err := cn.command("AUTH", c.password); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+	if c.database != 0 {
+		if _, err := cn.command("SELECT", strconv.Itoa(c.database)); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("failed to select redis database: %w", err)
+		}
+	}
+	return cn, nil
+}
+
+// command sends a command as an array of bulk strings and reads its reply
+func (cn *conn) command(args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(cn, b.String()); err != nil {
+		return nil, err
+	}
+	return cn.reply()
+}
+
+// reply reads a reply: simple strings as string, integers as int64, bulk strings as []byte,
+// arrays as []any and nil bulk strings and arrays as nil
+func (cn *conn) reply() (any, error) {
+	line, err := cn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(cn.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = cn.reply(); err != nil {
+				var replyErr Error
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = replyErr
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported redis reply %q", line)
+}
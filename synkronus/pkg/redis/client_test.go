@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/redis/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New("redis://:secret@cache.internal/2")
+	require.NoError(t, err)
+	assert.Equal(t, "cache.internal:6379", c.address)
+	assert.Equal(t, "secret", c.password)
+	assert.Equal(t, 2, c.database)
+
+	for _, invalid := range []string{"", "localhost:6379", "http://localhost", "redis://localhost/db"} {
+		_, err := New(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	server := redistest.NewServer(t, "secret")
+	c, err := New(server.URL("secret"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, c.Ping(ctx))
+
+	_, ok, err := c.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	stored, err := c.SetNX(ctx, "key", []byte("other"), 0)
+	require.NoError(t, err)
+	assert.False(t, stored)
+	stored, err = c.SetNX(ctx, "new", []byte("other"), 0)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	n, err := c.Incr(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, c.Del(ctx, "key", "new"))
+	_, ok, _ = c.Get(ctx, "key")
+	assert.False(t, ok)
+
+	// Error replies are returned without dropping the connection
+	_, err = c.Do(ctx, "NOSUCHCOMMAND")
+	var replyErr Error
+	assert.ErrorAs(t, err, &replyErr)
+	require.NoError(t, c.Ping(ctx))
+
+	// Wrong passwords fail
+	wrong, err := New(server.URL("wrong"))
+	require.NoError(t, err)
+	assert.Error(t, wrong.Ping(ctx))
+}
+
+func TestReconnect(t *testing.T) {
+	server := redistest.NewServer(t, "")
+	c, err := New(server.URL(""))
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, c.Ping(ctx))
+
+	// The pooled connection is gone; the failing command closes it and the next one reconnects
+	server.DropConnections()
+	_ = c.Ping(ctx)
+	require.NoError(t, c.Ping(ctx))
+}
+
+func TestSubscribe(t *testing.T) {
+	server := redistest.NewServer(t, "")
+	c, err := New(server.URL(""))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		c.Subscribe(ctx, "events", func(message string) { messages <- message })
+		close(done)
+	}()
+
+	// Publish until the subscription is in place; a publish on a dropped connection fails
+	publish := func(message string) string {
+		for {
+			_ = c.Publish(ctx, "events", message)
+			select {
+			case received := <-messages:
+				return received
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}
+	assert.Equal(t, "first", publish("first"))
+
+	// Subscriptions survive a lost connection
+	server.DropConnections()
+	assert.Equal(t, "second", publish("second"))
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe did not return after its context was cancelled")
+	}
+}
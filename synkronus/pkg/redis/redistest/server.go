@@ -0,0 +1,233 @@
+// Package redistest runs an in-process server speaking enough of the redis protocol for tests of
+// the packages that share state through redis
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server keeps keys in memory and serves PING, AUTH, SELECT, GET, SET (with NX and PX), INCR, DEL,
+// PUBLISH and SUBSCRIBE
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr     string
+	password string
+
+	mu          sync.Mutex
+	values      map[string]value
+	subscribers map[string][]net.Conn
+	conns       []net.Conn
+}
+
+type value struct {
+	data    string
+	expires time.Time
+}
+
+// NewServer starts a server that requires password when it is not empty; it stops when the test
+// ends
+func NewServer(t testing.TB, password string) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake redis: %v", err)
+	}
+	s := &Server{
+		Addr:        listener.Addr().String(),
+		password:    password,
+		values:      make(map[string]value),
+		subscribers: make(map[string][]net.Conn),
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, conn := range s.conns {
+			conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// URL returns the redis URL of the server, with the given password
+func (s *Server) URL(password string) string {
+	if password == "" {
+		return "redis://" + s.Addr
+	}
+	return "redis://:" + password + "@" + s.Addr
+}
+
+// Value returns the value of a key
+func (s *Server) Value(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.get(key)
+	return v.data, ok
+}
+
+// DropConnections closes every client connection, as a server restart would, keeping the keys
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.subscribers = make(map[string][]net.Conn)
+}
+
+func (s *Server) get(key string) (value, bool) {
+	v, ok := s.values[key]
+	if ok && !v.expires.IsZero() && !time.Now().Before(v.expires) {
+		delete(s.values, key)
+		return value{}, false
+	}
+	return v, ok
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil || len(args) == 0 {
+			return
+		}
+		command := strings.ToUpper(args[0])
+		if command == "AUTH" {
+			authenticated = len(args) == 2 && args[1] == s.password
+			if authenticated {
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+			}
+			continue
+		}
+		if !authenticated {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if command == "SUBSCRIBE" {
+			s.mu.Lock()
+			for _, channel := range args[1:] {
+				s.subscribers[channel] = append(s.subscribers[channel], conn)
+				fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n%s:%d\r\n", bulk(channel), len(s.subscribers[channel]))
+			}
+			s.mu.Unlock()
+			continue
+		}
+		fmt.Fprint(conn, s.run(command, args[1:]))
+	}
+}
+
+// run executes a command and returns its encoded reply
+func (s *Server) run(command string, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch command {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		if v, ok := s.get(args[0]); ok {
+			return bulk(v.data)
+		}
+		return "$-1\r\n"
+	case "SET":
+		v := value{data: args[1]}
+		nx := false
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				i++
+				ms, _ := strconv.Atoi(args[i])
+				v.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		if _, exists := s.get(args[0]); nx && exists {
+			return "$-1\r\n"
+		}
+		s.values[args[0]] = v
+		return "+OK\r\n"
+	case "INCR":
+		v, _ := s.get(args[0])
+		n, _ := strconv.ParseInt(v.data, 10, 64)
+		n++
+		v.data = strconv.FormatInt(n, 10)
+		s.values[args[0]] = v
+		return fmt.Sprintf(":%d\r\n", n)
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.get(key); ok {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "PUBLISH":
+		subscribers := s.subscribers[args[0]]
+		for _, conn := range subscribers {
+			fmt.Fprintf(conn, "*3\r\n$7\r\nmessage\r\n%s%s", bulk(args[0]), bulk(args[1]))
+		}
+		return fmt.Sprintf(":%d\r\n", len(subscribers))
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", command)
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
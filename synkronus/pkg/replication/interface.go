@@ -0,0 +1,70 @@
+// Package replication copies app bundle versions and attachments to a secondary storage
+// location in the background, so that losing the primary volume or bucket does not lose form
+// history or collected media. Copies are never deleted: versions removed by version cleanup and
+// deleted attachments stay in the secondary location.
+package replication
+
+import (
+	"context"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+)
+
+// BundleSource lists the app bundle storages to replicate
+type BundleSource interface {
+	// BundleStorages returns the storage of the app bundle versions of each tenant, by tenant
+	BundleStorages(ctx context.Context) (map[string]appbundle.Storage, error)
+}
+
+// Run summarizes one replication pass
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// BundleVersions counts the app bundle versions copied, BundleBlobs the files of their
+	// content not copied with an earlier version
+	BundleVersions int `json:"bundle_versions"`
+	BundleBlobs    int `json:"bundle_blobs"`
+	// Attachments counts the attachment content and record files copied
+	Attachments int   `json:"attachments"`
+	Bytes       int64 `json:"bytes"`
+	// Failed counts the versions and files left for the next pass after an error
+	Failed int `json:"failed"`
+}
+
+// BundleStatus compares the app bundle versions of a tenant with their copies
+type BundleStatus struct {
+	Tenant   string `json:"tenant"`
+	Versions int    `json:"versions"`
+	// Missing lists the versions not copied yet
+	Missing        []string `json:"missing"`
+	CurrentVersion string   `json:"current_version"`
+	// ReplicaCurrentVersion is the current version recorded in the secondary location, which a
+	// server restored from it starts with
+	ReplicaCurrentVersion string `json:"replica_current_version"`
+}
+
+// Status reports how far the secondary location is behind
+type Status struct {
+	// Target describes the secondary location, e.g. s3://bucket/prefix
+	Target          string `json:"target"`
+	IntervalMinutes int    `json:"interval_minutes"`
+	Running         bool   `json:"running"`
+	// LastRun is the latest pass of this server, if any
+	LastRun *Run `json:"last_run,omitempty"`
+	// LastSuccess is when the latest pass without failures finished
+	LastSuccess *time.Time     `json:"last_success,omitempty"`
+	Bundles     []BundleStatus `json:"bundles"`
+	// BundlesInSync is true when every app bundle version is copied and the secondary location
+	// records the current versions
+	BundlesInSync bool `json:"bundles_in_sync"`
+}
+
+// Service replicates to the secondary location and reports on it
+type Service interface {
+	// Replicate copies the app bundle versions and attachments not copied yet
+	Replicate(ctx context.Context) (*Run, error)
+
+	// Status compares the app bundle versions with their copies and reports the latest pass
+	Status(ctx context.Context) (*Status, error)
+}
@@ -0,0 +1,350 @@
+package replication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// ErrRunning is returned when a pass is started while another one is running
+var ErrRunning = errors.New("replication is already running")
+
+// Config holds what is replicated where
+type Config struct {
+	// Bundles lists the app bundle storages to copy
+	Bundles BundleSource
+	// AttachmentsDir is the attachment storage directory; empty skips attachments. Content
+	// moved to cold storage is not on local disk and is not copied.
+	AttachmentsDir string
+	Target         Target
+	// Interval is the time between passes
+	Interval time.Duration
+}
+
+// Replicator copies app bundle versions and attachments to the secondary location
+type Replicator struct {
+	config Config
+	log    *logger.Logger
+
+	// running serializes passes
+	running sync.Mutex
+
+	mu          sync.Mutex
+	busy        bool
+	lastRun     *Run
+	lastSuccess *time.Time
+}
+
+var _ Service = (*Replicator)(nil)
+
+// NewReplicator creates a replicator for config
+func NewReplicator(config Config, log *logger.Logger) *Replicator {
+	return &Replicator{config: config, log: log}
+}
+
+// Run replicates once at start and then every interval until ctx is cancelled. Failures are
+// logged and retried on the next pass.
+func (r *Replicator) Run(ctx context.Context) {
+	if r.config.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Replicate(ctx); err != nil && ctx.Err() == nil {
+			r.log.Warn("Replication failed", "target", r.config.Target.String(), "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replicate copies the app bundle versions and attachments not copied yet. A version is copied
+// once its files are; the secondary location then records the current version of each tenant.
+func (r *Replicator) Replicate(ctx context.Context) (*Run, error) {
+	if !r.running.TryLock() {
+		return nil, ErrRunning
+	}
+	defer r.running.Unlock()
+	r.setBusy(true)
+	defer r.setBusy(false)
+
+	run := &Run{StartedAt: time.Now().UTC()}
+	storages, err := r.config.Bundles.BundleStorages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app bundle storages: %w", err)
+	}
+	tenants := make([]string, 0, len(storages))
+	for id := range storages {
+		tenants = append(tenants, id)
+	}
+	sort.Strings(tenants)
+	for _, id := range tenants {
+		if err := r.replicateBundles(ctx, id, storages[id], run); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			r.log.Warn("Failed to replicate app bundle versions", "tenant", id, "error", err)
+			run.Failed++
+		}
+	}
+
+	if err := r.replicateAttachments(ctx, run); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		r.log.Warn("Failed to replicate attachments", "error", err)
+		run.Failed++
+	}
+	run.FinishedAt = time.Now().UTC()
+
+	r.mu.Lock()
+	r.lastRun = run
+	if run.Failed == 0 {
+		r.lastSuccess = &run.FinishedAt
+	}
+	r.mu.Unlock()
+
+	r.log.Info("Replication completed", "target", r.config.Target.String(), "bundleVersions", run.BundleVersions,
+		"bundleBlobs", run.BundleBlobs, "attachments", run.Attachments, "bytes", run.Bytes, "failed", run.Failed)
+	return run, nil
+}
+
+func (r *Replicator) setBusy(busy bool) {
+	r.mu.Lock()
+	r.busy = busy
+	r.mu.Unlock()
+}
+
+// replicateBundles copies the versions of a tenant missing in the secondary location, oldest
+// first, and records the current version once it is copied
+func (r *Replicator) replicateBundles(ctx context.Context, id string, primary appbundle.Storage, run *Run) error {
+	replica := r.config.Target.BundleStorage(id)
+	versions, err := primary.ListVersions(ctx)
+	if err != nil {
+		return err
+	}
+	copied, err := replica.ListVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Blobs of copied versions are not copied again
+	blobs := make(map[string]bool)
+	for _, version := range copied {
+		files, err := replica.ListFiles(ctx, version)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			blobs[file.Hash] = true
+		}
+	}
+
+	sort.Strings(versions)
+	for _, version := range versions {
+		if slices.Contains(copied, version) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := copyVersion(ctx, primary, replica, version, blobs, run); err != nil {
+			r.log.Warn("Failed to replicate app bundle version", "tenant", id, "version", version, "error", err)
+			run.Failed++
+			continue
+		}
+		copied = append(copied, version)
+		run.BundleVersions++
+	}
+
+	current, err := primary.CurrentVersion(ctx)
+	if err != nil || current == "" || !slices.Contains(copied, current) {
+		return err
+	}
+	replicaCurrent, err := replica.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if replicaCurrent != current {
+		return replica.SetCurrentVersion(ctx, current)
+	}
+	return nil
+}
+
+// copyVersion copies the blobs of a version missing in blobs and then its index. Files of
+// versions stored before blobs were shared get their hash on the way.
+func copyVersion(ctx context.Context, primary, replica appbundle.Storage, version string, blobs map[string]bool, run *Run) error {
+	files, err := primary.ListFiles(ctx, version)
+	if err != nil {
+		return err
+	}
+	index := make([]appbundle.StoredFile, 0, len(files))
+	for _, file := range files {
+		if file.Hash == "" || !blobs[file.Hash] {
+			data, err := readFile(ctx, primary, version, file.Path)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(data)
+			file.Hash = hex.EncodeToString(sum[:])
+			if !blobs[file.Hash] {
+				if err := replica.WriteBlob(ctx, file.Hash, data); err != nil {
+					return err
+				}
+				blobs[file.Hash] = true
+				run.BundleBlobs++
+				run.Bytes += int64(len(data))
+			}
+		}
+		index = append(index, file)
+	}
+	return replica.WriteVersion(ctx, version, index)
+}
+
+func readFile(ctx context.Context, storage appbundle.Storage, version, path string) ([]byte, error) {
+	file, _, err := storage.OpenFile(ctx, version, path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// replicateAttachments copies the attachment files whose copy is missing or of another size.
+// Content files are named by their hash and never change, so their size tells them apart.
+func (r *Replicator) replicateAttachments(ctx context.Context, run *Run) error {
+	dir := r.config.AttachmentsDir
+	if dir == "" {
+		return nil
+	}
+	copied, err := r.config.Target.ListFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Uploads in progress and records being written are temporary files
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".upload-") || strings.HasSuffix(name, ".tmp") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if size, ok := copied[rel]; ok && size == info.Size() {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err == nil {
+			err = r.config.Target.PutFile(ctx, rel, data)
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Deleted or moved to cold storage since the walk listed it
+				return nil
+			}
+			r.log.Warn("Failed to replicate attachment file", "path", rel, "error", err)
+			run.Failed++
+			return nil
+		}
+		run.Attachments++
+		run.Bytes += int64(len(data))
+		return nil
+	})
+}
+
+// Status compares the app bundle versions of each tenant with their copies and reports the
+// latest pass of this server
+func (r *Replicator) Status(ctx context.Context) (*Status, error) {
+	status := &Status{
+		Target:          r.config.Target.String(),
+		IntervalMinutes: int(r.config.Interval.Minutes()),
+		Bundles:         []BundleStatus{},
+		BundlesInSync:   true,
+	}
+
+	storages, err := r.config.Bundles.BundleStorages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app bundle storages: %w", err)
+	}
+	for id, primary := range storages {
+		bundle, err := bundleStatus(ctx, id, primary, r.config.Target.BundleStorage(id))
+		if err != nil {
+			return nil, err
+		}
+		status.Bundles = append(status.Bundles, *bundle)
+		if len(bundle.Missing) > 0 || bundle.ReplicaCurrentVersion != bundle.CurrentVersion {
+			status.BundlesInSync = false
+		}
+	}
+	sort.Slice(status.Bundles, func(i, j int) bool { return status.Bundles[i].Tenant < status.Bundles[j].Tenant })
+
+	r.mu.Lock()
+	status.Running = r.busy
+	status.LastRun = r.lastRun
+	status.LastSuccess = r.lastSuccess
+	r.mu.Unlock()
+	return status, nil
+}
+
+func bundleStatus(ctx context.Context, id string, primary, replica appbundle.Storage) (*BundleStatus, error) {
+	versions, err := primary.ListVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app bundle versions of tenant %s: %w", id, err)
+	}
+	copied, err := replica.ListVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list copied app bundle versions of tenant %s: %w", id, err)
+	}
+	status := &BundleStatus{Tenant: id, Versions: len(versions), Missing: []string{}}
+	for _, version := range versions {
+		if !slices.Contains(copied, version) {
+			status.Missing = append(status.Missing, version)
+		}
+	}
+	sort.Strings(status.Missing)
+
+	if status.CurrentVersion, err = primary.CurrentVersion(ctx); err != nil {
+		return nil, err
+	}
+	if status.ReplicaCurrentVersion, err = replica.CurrentVersion(ctx); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,132 @@
+package replication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storages is a BundleSource with fixed storages
+type storages map[string]appbundle.Storage
+
+func (s storages) BundleStorages(ctx context.Context) (map[string]appbundle.Storage, error) {
+	return s, nil
+}
+
+// writeVersion stores a version of the given files in storage
+func writeVersion(t *testing.T, storage appbundle.Storage, version string, files map[string]string) {
+	t.Helper()
+	ctx := context.Background()
+	var index []appbundle.StoredFile
+	for path, content := range files {
+		sum := sha256.Sum256([]byte(content))
+		hash := hex.EncodeToString(sum[:])
+		require.NoError(t, storage.WriteBlob(ctx, hash, []byte(content)))
+		index = append(index, appbundle.StoredFile{Path: path, Hash: hash, Size: int64(len(content)), ModTime: time.Now()})
+	}
+	require.NoError(t, storage.WriteVersion(ctx, version, index))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func newTestReplicator(t *testing.T) (*Replicator, appbundle.Storage, string, string) {
+	t.Helper()
+	primary := appbundle.NewLocalStorage(t.TempDir())
+	attachments := t.TempDir()
+	replica := t.TempDir()
+	r := NewReplicator(Config{
+		Bundles:        storages{tenant.Default: primary},
+		AttachmentsDir: attachments,
+		Target:         NewDirTarget(replica),
+		Interval:       time.Hour,
+	}, logger.NewLogger())
+	return r, primary, attachments, replica
+}
+
+func TestReplicate(t *testing.T) {
+	ctx := context.Background()
+	r, primary, attachments, replica := newTestReplicator(t)
+
+	writeVersion(t, primary, "0001", map[string]string{"forms/survey/schema.json": `{"v":1}`, "app/index.html": "<html>"})
+	writeVersion(t, primary, "0002", map[string]string{"forms/survey/schema.json": `{"v":2}`, "app/index.html": "<html>"})
+	require.NoError(t, primary.SetCurrentVersion(ctx, "0002"))
+	writeFile(t, filepath.Join(attachments, ".blobs", "abc123"), "photo")
+	writeFile(t, filepath.Join(attachments, ".meta", "photo.jpg.json"), `{"id":"photo.jpg"}`)
+	writeFile(t, filepath.Join(attachments, ".blobs", ".upload-42"), "partial")
+
+	run, err := r.Replicate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, run.BundleVersions)
+	// index.html is the same in both versions and copied once
+	assert.Equal(t, 3, run.BundleBlobs)
+	assert.Equal(t, 2, run.Attachments)
+	assert.Zero(t, run.Failed)
+
+	copies := NewDirTarget(replica).BundleStorage(tenant.Default)
+	file, _, err := copies.OpenFile(ctx, "0001", "forms/survey/schema.json")
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	file.Close()
+	require.NoError(t, err)
+	assert.Equal(t, `{"v":1}`, string(data))
+	current, err := copies.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0002", current)
+
+	data, err = os.ReadFile(filepath.Join(replica, "attachments", ".blobs", "abc123"))
+	require.NoError(t, err)
+	assert.Equal(t, "photo", string(data))
+	assert.NoFileExists(t, filepath.Join(replica, "attachments", ".blobs", ".upload-42"))
+
+	// A second pass only copies what changed, and keeps versions the primary deleted
+	require.NoError(t, primary.DeleteVersion(ctx, "0001"))
+	writeVersion(t, primary, "0003", map[string]string{"forms/survey/schema.json": `{"v":3}`, "app/index.html": "<html>"})
+	run, err = r.Replicate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.BundleVersions)
+	assert.Equal(t, 1, run.BundleBlobs)
+	assert.Zero(t, run.Attachments)
+	versions, err := copies.ListVersions(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"0001", "0002", "0003"}, versions)
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	r, primary, _, _ := newTestReplicator(t)
+	writeVersion(t, primary, "0001", map[string]string{"forms/survey/schema.json": `{}`})
+	require.NoError(t, primary.SetCurrentVersion(ctx, "0001"))
+
+	status, err := r.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.BundlesInSync)
+	assert.Nil(t, status.LastRun)
+	require.Len(t, status.Bundles, 1)
+	assert.Equal(t, []string{"0001"}, status.Bundles[0].Missing)
+	assert.Equal(t, 60, status.IntervalMinutes)
+
+	_, err = r.Replicate(ctx)
+	require.NoError(t, err)
+	status, err = r.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.BundlesInSync)
+	assert.Empty(t, status.Bundles[0].Missing)
+	assert.Equal(t, "0001", status.Bundles[0].ReplicaCurrentVersion)
+	require.NotNil(t, status.LastRun)
+	require.NotNil(t, status.LastSuccess)
+}
@@ -0,0 +1,163 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/appbundle"
+	"github.com/opendataensemble/synkronus/pkg/objectstore"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// Target is the secondary location copies are kept in. App bundle versions are kept in the
+// layout of app bundle storage, so a server can be pointed at the copies to restore them.
+type Target interface {
+	// BundleStorage returns the storage the app bundle versions of a tenant are copied to
+	BundleStorage(tenant string) appbundle.Storage
+
+	// ListFiles returns the sizes of the copied attachment files by slash separated path
+	ListFiles(ctx context.Context) (map[string]int64, error)
+
+	// PutFile stores a copy of an attachment file
+	PutFile(ctx context.Context, path string, data []byte) error
+
+	// String describes the location
+	String() string
+}
+
+// bundleDir is where the app bundle versions of a tenant are kept in the secondary location:
+// app-bundle for the default tenant, tenants/<tenant>/app-bundle for the others
+func bundleDir(id string) string {
+	if id == tenant.Default {
+		return "app-bundle"
+	}
+	return path.Join("tenants", id, "app-bundle")
+}
+
+// attachmentsDir is where attachment files are kept in the secondary location
+const attachmentsDir = "attachments"
+
+// DirTarget keeps copies in a directory, such as a second disk or a network share
+type DirTarget struct {
+	root string
+}
+
+// NewDirTarget creates a target keeping copies under root
+func NewDirTarget(root string) *DirTarget {
+	return &DirTarget{root: root}
+}
+
+// BundleStorage keeps the versions of a tenant under <root>/app-bundle or
+// <root>/tenants/<tenant>/app-bundle
+func (d *DirTarget) BundleStorage(tenant string) appbundle.Storage {
+	return appbundle.NewLocalStorage(filepath.Join(d.root, filepath.FromSlash(bundleDir(tenant))))
+}
+
+// ListFiles returns the sizes of the files under <root>/attachments
+func (d *DirTarget) ListFiles(ctx context.Context) (map[string]int64, error) {
+	dir := filepath.Join(d.root, attachmentsDir)
+	files := make(map[string]int64)
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list copied attachments: %w", err)
+	}
+	return files, nil
+}
+
+// PutFile writes a copy under <root>/attachments, replacing an earlier copy atomically
+func (d *DirTarget) PutFile(ctx context.Context, name string, data []byte) error {
+	target := filepath.Join(d.root, attachmentsDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// String returns the directory
+func (d *DirTarget) String() string {
+	return d.root
+}
+
+// S3Target keeps copies in an S3-compatible bucket under a key prefix, usually a bucket in
+// another region than the primary one
+type S3Target struct {
+	client *objectstore.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Target creates a target keeping copies in the bucket of client under prefix
+func NewS3Target(client *objectstore.Client, bucket, prefix string) *S3Target {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Target{client: client, bucket: bucket, prefix: prefix}
+}
+
+// BundleStorage keeps the versions of a tenant under <prefix>app-bundle/ or
+// <prefix>tenants/<tenant>/app-bundle/
+func (s *S3Target) BundleStorage(tenant string) appbundle.Storage {
+	return appbundle.NewS3Storage(s.client, s.prefix+bundleDir(tenant))
+}
+
+// ListFiles returns the sizes of the objects under <prefix>attachments/
+func (s *S3Target) ListFiles(ctx context.Context) (map[string]int64, error) {
+	prefix := s.prefix + attachmentsDir + "/"
+	objects, _, err := s.client.ListObjects(ctx, prefix, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list copied attachments: %w", err)
+	}
+	files := make(map[string]int64, len(objects))
+	for _, object := range objects {
+		files[strings.TrimPrefix(object.Key, prefix)] = object.Size
+	}
+	return files, nil
+}
+
+// PutFile stores a copy under <prefix>attachments/
+func (s *S3Target) PutFile(ctx context.Context, name string, data []byte) error {
+	return s.client.PutObject(ctx, s.prefix+attachmentsDir+"/"+name, data, "application/octet-stream")
+}
+
+// String returns the location as s3://bucket/prefix
+func (s *S3Target) String() string {
+	return "s3://" + s.bucket + "/" + strings.TrimSuffix(s.prefix, "/")
+}
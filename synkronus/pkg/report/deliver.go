@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportHeader names the report in webhook deliveries
+const ReportHeader = "X-Synkronus-Report"
+
+// SMTPConfig contains the SMTP server reports are sent through
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with the server; empty for an open relay
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer sends reports through an SMTP server
+type SMTPMailer struct {
+	config SMTPConfig
+	send   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPMailer creates a mailer sending through an SMTP server
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config, send: smtp.SendMail}
+}
+
+// Send mails a plain text message with an attachment. net/smtp cannot be cancelled, so ctx is
+// not honoured once sending started.
+func (m *SMTPMailer) Send(ctx context.Context, to []string, subject, body string, attachment Attachment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	msg, err := m.message(to, subject, body, attachment)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	if err := m.send(addr, auth, m.config.From, to, msg); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
+
+// message formats a multipart email with the body as text and the attachment in base64
+func (m *SMTPMailer) message(to []string, subject, body string, attachment Attachment) ([]byte, error) {
+	var msg bytes.Buffer
+	parts := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.ReplaceAll(subject, "\n", " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", parts.Boundary())
+
+	text, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	text.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+
+	file, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.Filename})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Lines of encoded content are limited to 76 characters
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		file.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	file.Write([]byte(encoded + "\r\n"))
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// webhookPayload is posted to the webhook of a report: the data of its sections and the
+// rendered report, base64 encoded
+type webhookPayload struct {
+	Report      string            `json:"report"`
+	Title       string            `json:"title"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Sections    []RenderedSection `json:"sections"`
+	Filename    string            `json:"filename"`
+	ContentType string            `json:"content_type"`
+	Content     []byte            `json:"content"`
+}
+
+// post delivers a rendered report to a webhook and fails unless it answers with a 2xx status
+func post(ctx context.Context, client *http.Client, url string, doc *Document, attachment Attachment) error {
+	body, err := json.Marshal(webhookPayload{
+		Report:      doc.Name,
+		Title:       doc.Title,
+		GeneratedAt: doc.GeneratedAt,
+		Sections:    doc.Sections,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Content:     attachment.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ReportHeader, doc.Name)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+// Package report renders report templates: titled sections laying out the results of saved
+// queries as tables or pivot tables, rendered to HTML or PDF on demand or on a schedule and
+// delivered by email or webhook.
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+)
+
+// Common errors
+var (
+	// ErrNotFound is returned when a report does not exist
+	ErrNotFound = errors.New("report not found")
+	// ErrInvalidReport is returned when a report cannot be saved as given
+	ErrInvalidReport = errors.New("invalid report")
+	// ErrNoRecipients is returned when a report is delivered without recipients or a webhook
+	ErrNoRecipients = errors.New("report has no recipients")
+	// ErrEmailNotConfigured is returned when a report is emailed without an SMTP server
+	ErrEmailNotConfigured = errors.New("email is not configured")
+	// ErrDeliveryFailed is returned when a rendered report could not be emailed or posted
+	ErrDeliveryFailed = errors.New("report delivery failed")
+)
+
+// Layouts of a report section
+const (
+	// LayoutTable shows the rows of the query result as they are
+	LayoutTable = "table"
+	// LayoutPivot shows the values of one grouped field as rows and those of another as
+	// columns, with the query value in the cells
+	LayoutPivot = "pivot"
+)
+
+// Schedules reports are delivered on; times are in UTC
+const (
+	// ScheduleDaily delivers a report every day at its hour
+	ScheduleDaily = "daily"
+	// ScheduleWeekly delivers a report every Monday at its hour
+	ScheduleWeekly = "weekly"
+	// ScheduleMonthly delivers a report on the first day of every month at its hour
+	ScheduleMonthly = "monthly"
+)
+
+// Formats reports are rendered in
+const (
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// Limits of a report
+const (
+	// MaxSections is the most sections a report may have
+	MaxSections = 20
+	// MaxRecipients is the most addresses a report may be emailed to
+	MaxRecipients = 50
+)
+
+// Section is a part of a report: a title, an optional text, and the result of a saved query
+// laid out as a table or pivot table. A section without a query shows its text only.
+type Section struct {
+	Title string `json:"title"`
+	Text  string `json:"text,omitempty"`
+	// Query names the saved query whose result the section shows
+	Query string `json:"query,omitempty"`
+	// Params are the parameters the query is run with
+	Params map[string]string `json:"params,omitempty"`
+	// Layout is table (default) or pivot
+	Layout string `json:"layout,omitempty"`
+	// PivotRows and PivotColumns name the grouped fields of a pivot table shown as rows and as
+	// columns; the query must group by exactly these two fields
+	PivotRows    string `json:"pivot_rows,omitempty"`
+	PivotColumns string `json:"pivot_columns,omitempty"`
+	// Labels renames result columns, such as {"data.village": "Village", "value": "Households"}
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Report is a report template admins define once and render whenever needed
+type Report struct {
+	Name        string    `json:"name"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Sections    []Section `json:"sections"`
+	// Schedule is daily, weekly or monthly to deliver the report on its own; empty delivers it
+	// on request only
+	Schedule string `json:"schedule,omitempty"`
+	// Hour is the hour of the day, in UTC, scheduled deliveries are made
+	Hour int `json:"hour"`
+	// Format is the format delivered reports are rendered in: html (default) or pdf
+	Format string `json:"format"`
+	// Recipients are the email addresses the report is delivered to
+	Recipients []string `json:"recipients"`
+	// WebhookURL receives the rendered report and the data of its sections as JSON
+	WebhookURL string  `json:"webhook_url,omitempty"`
+	NextRunAt  *string `json:"next_run_at,omitempty"`
+	LastRunAt  *string `json:"last_run_at,omitempty"`
+	LastError  string  `json:"last_error,omitempty"`
+	UpdatedBy  *string `json:"updated_by,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+	UpdatedAt  string  `json:"updated_at"`
+}
+
+// Validate checks a report before it is saved, filling in the default layout and format
+func (r *Report) Validate() error {
+	var problems []string
+	if !savedquery.ValidName(r.Name) {
+		problems = append(problems, "name must be lowercase letters, digits, '_' or '-'")
+	}
+	if strings.TrimSpace(r.Title) == "" {
+		problems = append(problems, "title is required")
+	}
+	if len(r.Sections) == 0 || len(r.Sections) > MaxSections {
+		problems = append(problems, fmt.Sprintf("a report needs 1 to %d sections", MaxSections))
+	}
+	for i := range r.Sections {
+		section := &r.Sections[i]
+		if section.Layout == "" {
+			section.Layout = LayoutTable
+		}
+		switch {
+		case section.Query == "" && section.Text == "":
+			problems = append(problems, fmt.Sprintf("section %d needs a query or a text", i+1))
+		case section.Layout != LayoutTable && section.Layout != LayoutPivot:
+			problems = append(problems, fmt.Sprintf("section %d: layout must be table or pivot", i+1))
+		case section.Layout == LayoutPivot && (section.PivotRows == "" || section.PivotColumns == "" || section.PivotRows == section.PivotColumns):
+			problems = append(problems, fmt.Sprintf("section %d: a pivot needs two different fields in pivot_rows and pivot_columns", i+1))
+		}
+	}
+
+	switch r.Schedule {
+	case "", ScheduleDaily, ScheduleWeekly, ScheduleMonthly:
+	default:
+		problems = append(problems, "schedule must be daily, weekly or monthly")
+	}
+	if r.Hour < 0 || r.Hour > 23 {
+		problems = append(problems, "hour must be between 0 and 23")
+	}
+	if r.Format == "" {
+		r.Format = FormatHTML
+	}
+	if r.Format != FormatHTML && r.Format != FormatPDF {
+		problems = append(problems, "format must be html or pdf")
+	}
+	if len(r.Recipients) > MaxRecipients {
+		problems = append(problems, fmt.Sprintf("a report may have at most %d recipients", MaxRecipients))
+	}
+	for _, recipient := range r.Recipients {
+		if address, err := mail.ParseAddress(recipient); err != nil || address.Address != recipient {
+			problems = append(problems, fmt.Sprintf("%q is not an email address", recipient))
+		}
+	}
+	if r.WebhookURL != "" {
+		if u, err := url.Parse(r.WebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			problems = append(problems, "webhook_url must be an http or https URL")
+		}
+	}
+	if r.Schedule != "" && len(r.Recipients) == 0 && r.WebhookURL == "" {
+		problems = append(problems, "a scheduled report needs recipients or a webhook_url")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidReport, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// NextRun returns the first time after after a report on schedule is delivered at hour (UTC)
+func NextRun(schedule string, hour int, after time.Time) time.Time {
+	after = after.UTC()
+	next := time.Date(after.Year(), after.Month(), after.Day(), hour, 0, 0, 0, time.UTC)
+	switch schedule {
+	case ScheduleWeekly:
+		next = next.AddDate(0, 0, -int((next.Weekday()+6)%7))
+		for !next.After(after) {
+			next = next.AddDate(0, 0, 7)
+		}
+	case ScheduleMonthly:
+		next = time.Date(after.Year(), after.Month(), 1, hour, 0, 0, 0, time.UTC)
+		if !next.After(after) {
+			next = next.AddDate(0, 1, 0)
+		}
+	default:
+		if !next.After(after) {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// Table is a laid out query result: a header and rows of formatted cells
+type Table struct {
+	Header []string   `json:"header"`
+	Rows   [][]string `json:"rows"`
+	// Numeric marks the columns holding numbers, which are aligned right
+	Numeric []bool `json:"numeric"`
+}
+
+// RenderedSection is a section with the current result of its query
+type RenderedSection struct {
+	Title string `json:"title"`
+	Text  string `json:"text,omitempty"`
+	// Result is the query result the table was laid out from
+	Result *savedquery.Result `json:"result,omitempty"`
+	Table  *Table             `json:"table,omitempty"`
+	// Notes explain the table, such as rows cut off by the query limit or withheld values
+	Notes []string `json:"notes,omitempty"`
+	// Error tells why the query of the section could not be run; the other sections are
+	// rendered regardless
+	Error string `json:"error,omitempty"`
+}
+
+// Document is a report rendered from current data
+type Document struct {
+	Name        string            `json:"name"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Sections    []RenderedSection `json:"sections"`
+}
+
+// Queries runs the saved queries reports are built from
+type Queries interface {
+	Run(ctx context.Context, name string, params map[string]string, username string, role models.Role) (*savedquery.Result, error)
+}
+
+// Mailer delivers reports by email, with the rendered report attached
+type Mailer interface {
+	Send(ctx context.Context, to []string, subject, body string, attachment Attachment) error
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Service keeps report templates, renders them and delivers them
+type Service interface {
+	// List returns all reports ordered by name
+	List(ctx context.Context) ([]Report, error)
+
+	// Get returns a single report
+	Get(ctx context.Context, name string) (*Report, error)
+
+	// Save creates or replaces a report after validating it, scheduling its next delivery
+	Save(ctx context.Context, report Report, updatedBy string) (*Report, error)
+
+	// Delete removes a report
+	Delete(ctx context.Context, name string) error
+
+	// Render runs the queries of a report for a user and lays out their results
+	Render(ctx context.Context, name string, username string) (*Document, error)
+
+	// Deliver renders a report and sends it to its recipients and webhook right away
+	Deliver(ctx context.Context, name string, username string) error
+
+	// Run delivers scheduled reports when they are due until ctx is cancelled
+	Run(ctx context.Context)
+}
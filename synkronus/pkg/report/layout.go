@@ -0,0 +1,200 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+)
+
+// valueColumn is the column of a query result holding the aggregated value
+const valueColumn = "value"
+
+// Cell texts of values that are not shown
+const (
+	// blankText shows a group of records without a value for the grouped field
+	blankText = "(blank)"
+	// withheldText shows a value withheld for covering too few records
+	withheldText = "–"
+)
+
+// layout lays out a query result as the table or pivot table of a section
+func layout(section Section, result *savedquery.Result) (*Table, error) {
+	if section.Layout == LayoutPivot {
+		return pivot(section, result)
+	}
+
+	table := &Table{Header: make([]string, len(result.Columns)), Rows: make([][]string, 0, len(result.Rows))}
+	for i, column := range result.Columns {
+		table.Header[i] = label(section, column)
+	}
+	for _, row := range result.Rows {
+		cells := make([]string, len(result.Columns))
+		for i, column := range result.Columns {
+			cells[i] = cellText(column, row[column])
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+	table.Numeric = make([]bool, len(result.Columns))
+	for i, column := range result.Columns {
+		table.Numeric[i] = numericColumn(result.Rows, column)
+	}
+	return table, nil
+}
+
+// pivot lays out a result grouped by two fields with the values of one as rows and those of
+// the other as columns. Rows and columns are sorted by value, groups without a value last.
+func pivot(section Section, result *savedquery.Result) (*Table, error) {
+	grouped := make(map[string]bool)
+	for _, column := range result.Columns {
+		if column != valueColumn {
+			grouped[column] = true
+		}
+	}
+	if len(grouped) != 2 || !grouped[section.PivotRows] || !grouped[section.PivotColumns] {
+		return nil, fmt.Errorf("the query of a pivot must group by exactly %s and %s", section.PivotRows, section.PivotColumns)
+	}
+
+	rowKeys := distinct(result.Rows, section.PivotRows)
+	columnKeys := distinct(result.Rows, section.PivotColumns)
+	cells := make(map[[2]string]string, len(result.Rows))
+	for _, row := range result.Rows {
+		key := [2]string{cellText(section.PivotRows, row[section.PivotRows]), cellText(section.PivotColumns, row[section.PivotColumns])}
+		cells[key] = cellText(valueColumn, row[valueColumn])
+	}
+
+	table := &Table{
+		Header:  []string{label(section, section.PivotRows)},
+		Rows:    make([][]string, 0, len(rowKeys)),
+		Numeric: []bool{numericColumn(result.Rows, section.PivotRows)},
+	}
+	for _, columnKey := range columnKeys {
+		table.Header = append(table.Header, columnKey)
+		table.Numeric = append(table.Numeric, true)
+	}
+	for _, rowKey := range rowKeys {
+		row := []string{rowKey}
+		for _, columnKey := range columnKeys {
+			row = append(row, cells[[2]string{rowKey, columnKey}])
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// distinct returns the texts of the distinct values of a column, sorted by value
+func distinct(rows []map[string]any, column string) []string {
+	seen := make(map[string]bool)
+	var values []any
+	for _, row := range rows {
+		text := cellText(column, row[column])
+		if !seen[text] {
+			seen[text] = true
+			values = append(values, row[column])
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return less(values[i], values[j]) })
+
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = cellText(column, value)
+	}
+	return texts
+}
+
+// less orders values numerically when both are numbers and as text otherwise, nil last
+func less(a, b any) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	}
+	x, xNumber := number(a)
+	y, yNumber := number(b)
+	if xNumber && yNumber {
+		return x < y
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// number returns the value of a numeric result value
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// numericColumn reports whether every value of a column is a number
+func numericColumn(rows []map[string]any, column string) bool {
+	numeric := false
+	for _, row := range rows {
+		if row[column] == nil {
+			continue
+		}
+		if _, ok := number(row[column]); !ok {
+			return false
+		}
+		numeric = true
+	}
+	return numeric
+}
+
+// label returns the heading of a result column
+func label(section Section, column string) string {
+	if l, ok := section.Labels[column]; ok {
+		return l
+	}
+	if column == valueColumn {
+		return "Value"
+	}
+	return column
+}
+
+// cellText formats a result value of a column for display
+func cellText(column string, value any) string {
+	switch v := value.(type) {
+	case nil:
+		if column == valueColumn {
+			return withheldText
+		}
+		return blankText
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		text := strconv.FormatFloat(v, 'f', 2, 64)
+		return strings.TrimSuffix(strings.TrimRight(text, "0"), ".")
+	case string:
+		// Dates bucketed by day or coarser are shown without their time
+		if t, err := time.Parse(time.RFC3339, v); err == nil && t.Equal(t.Truncate(24*time.Hour)) {
+			return t.Format(time.DateOnly)
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// notes explains what a table leaves out
+func notes(result *savedquery.Result) []string {
+	var notes []string
+	if result.Truncated {
+		notes = append(notes, fmt.Sprintf("Only the first %d rows are shown; the query matched more.", len(result.Rows)))
+	}
+	if result.Privacy != nil {
+		if result.Suppressed > 0 {
+			notes = append(notes, fmt.Sprintf("%s marks %d values withheld for covering fewer than %d records.", withheldText, result.Suppressed, result.Privacy.MinGroupSize))
+		}
+		if result.Privacy.Noise > 0 {
+			notes = append(notes, "Counts and sums include random noise to protect small groups.")
+		}
+	}
+	return notes
+}
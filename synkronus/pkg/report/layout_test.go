@@ -0,0 +1,122 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	r := Report{
+		Name:     "monthly-summary",
+		Title:    "Monthly summary",
+		Sections: []Section{{Title: "Households", Query: "households-by-village"}},
+	}
+	require.NoError(t, r.Validate())
+	assert.Equal(t, LayoutTable, r.Sections[0].Layout)
+	assert.Equal(t, FormatHTML, r.Format)
+
+	invalid := Report{
+		Name:       "Monthly Summary",
+		Sections:   []Section{{Title: "Empty"}, {Title: "Pivot", Query: "q", Layout: LayoutPivot, PivotRows: "a", PivotColumns: "a"}},
+		Schedule:   "hourly",
+		Hour:       24,
+		Format:     "docx",
+		Recipients: []string{"Someone <someone@example.org>"},
+		WebhookURL: "ftp://example.org",
+	}
+	err := invalid.Validate()
+	require.ErrorIs(t, err, ErrInvalidReport)
+	for _, problem := range []string{"name", "title is required", "section 1 needs a query or a text", "section 2: a pivot",
+		"schedule", "hour", "format", "is not an email address", "webhook_url"} {
+		assert.Contains(t, err.Error(), problem)
+	}
+
+	unsent := Report{Name: "daily", Title: "Daily", Sections: r.Sections, Schedule: ScheduleDaily}
+	assert.ErrorContains(t, unsent.Validate(), "needs recipients or a webhook_url")
+}
+
+func TestNextRun(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, 10, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		hour     int
+		want     time.Time
+	}{
+		{ScheduleDaily, 12, time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)},
+		{ScheduleDaily, 6, time.Date(2025, 10, 16, 6, 0, 0, 0, time.UTC)},
+		{ScheduleWeekly, 6, time.Date(2025, 10, 20, 6, 0, 0, 0, time.UTC)},
+		{ScheduleMonthly, 6, time.Date(2025, 11, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NextRun(tt.schedule, tt.hour, now), tt.schedule)
+	}
+
+	monday := time.Date(2025, 10, 20, 5, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 10, 20, 6, 0, 0, 0, time.UTC), NextRun(ScheduleWeekly, 6, monday))
+	assert.Equal(t, time.Date(2025, 10, 27, 6, 0, 0, 0, time.UTC), NextRun(ScheduleWeekly, 6, monday.Add(time.Hour)))
+	firstOfMonth := time.Date(2025, 12, 1, 5, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 12, 1, 6, 0, 0, 0, time.UTC), NextRun(ScheduleMonthly, 6, firstOfMonth))
+	assert.Equal(t, time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC), NextRun(ScheduleMonthly, 6, firstOfMonth.Add(time.Hour)))
+}
+
+func TestLayout_Table(t *testing.T) {
+	result := &savedquery.Result{
+		Columns: []string{"data.village", "created_at:month", "value"},
+		Rows: []map[string]any{
+			{"data.village": "Kibera", "created_at:month": "2025-09-01T00:00:00Z", "value": int64(42)},
+			{"data.village": nil, "created_at:month": "2025-09-01T00:00:00Z", "value": nil},
+		},
+	}
+	table, err := layout(Section{Labels: map[string]string{"data.village": "Village"}}, result)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Village", "created_at:month", "Value"}, table.Header)
+	assert.Equal(t, [][]string{{"Kibera", "2025-09-01", "42"}, {blankText, "2025-09-01", withheldText}}, table.Rows)
+	assert.Equal(t, []bool{false, false, true}, table.Numeric)
+}
+
+func TestLayout_Pivot(t *testing.T) {
+	section := Section{Layout: LayoutPivot, PivotRows: "data.village", PivotColumns: "data.year"}
+	result := &savedquery.Result{
+		Columns: []string{"data.village", "data.year", "value"},
+		Rows: []map[string]any{
+			{"data.village": "Mathare", "data.year": int64(2025), "value": 2.5},
+			{"data.village": "Kibera", "data.year": int64(2025), "value": int64(3)},
+			{"data.village": nil, "data.year": int64(2024), "value": int64(1)},
+			{"data.village": "Kibera", "data.year": int64(2024), "value": 1.126},
+		},
+	}
+	table, err := layout(section, result)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data.village", "2024", "2025"}, table.Header)
+	assert.Equal(t, [][]string{
+		{"Kibera", "1.13", "3"},
+		{"Mathare", "", "2.5"},
+		{blankText, "1", ""},
+	}, table.Rows)
+	assert.Equal(t, []bool{false, true, true}, table.Numeric)
+
+	section.PivotColumns = "data.month"
+	_, err = layout(section, result)
+	assert.ErrorContains(t, err, "must group by exactly data.village and data.month")
+}
+
+func TestNotes(t *testing.T) {
+	assert.Empty(t, notes(&savedquery.Result{}))
+
+	result := &savedquery.Result{
+		Rows:       make([]map[string]any, 3),
+		Truncated:  true,
+		Privacy:    &savedquery.Privacy{MinGroupSize: 5, Noise: 1},
+		Suppressed: 2,
+	}
+	assert.Equal(t, []string{
+		"Only the first 3 rows are shown; the query matched more.",
+		"– marks 2 values withheld for covering fewer than 5 records.",
+		"Counts and sums include random noise to protect small groups.",
+	}, notes(result))
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/opendataensemble/synkronus/pkg/pdfdoc"
+)
+
+//go:embed report.html
+var pageSource string
+
+var pageTemplate = template.Must(template.New("report.html").Parse(pageSource))
+
+// WriteHTML writes a rendered report as an HTML page
+func WriteHTML(w io.Writer, doc *Document) error {
+	return pageTemplate.Execute(w, doc)
+}
+
+// Table geometry of PDF reports, in points
+const (
+	tableFontSize      = 9
+	smallTableFontSize = 7
+	// cellPadding separates the text of neighbouring cells
+	cellPadding = 8
+)
+
+// WritePDF writes a rendered report as a PDF document. Tables too wide for the page have their
+// columns narrowed and long texts cut.
+func WritePDF(w io.Writer, doc *Document) error {
+	pdf := pdfdoc.New(doc.Title + " - generated " + doc.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	pdf.Line(pdfdoc.FontBold, 18, 0, doc.Title)
+	pdf.Line(pdfdoc.FontRegular, 10, 0, "Generated "+doc.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	if doc.Description != "" {
+		pdf.Space(4)
+		pdf.Line(pdfdoc.FontRegular, 10, 0, doc.Description)
+	}
+
+	for _, section := range doc.Sections {
+		pdf.Space(16)
+		pdf.Line(pdfdoc.FontBold, 14, 0, section.Title)
+		if section.Text != "" {
+			pdf.Line(pdfdoc.FontRegular, 10, 0, section.Text)
+		}
+		if section.Error != "" {
+			pdf.Line(pdfdoc.FontRegular, 10, 0, "This section could not be rendered: "+section.Error)
+		}
+		if section.Table != nil {
+			pdf.Space(4)
+			writeTable(pdf, section.Table)
+		}
+		for _, note := range section.Notes {
+			pdf.Line(pdfdoc.FontRegular, 8, 0, note)
+		}
+	}
+	return pdf.Write(w)
+}
+
+// writeTable sets a table with a bold header row
+func writeTable(pdf *pdfdoc.Document, table *Table) {
+	size := float64(tableFontSize)
+	if len(table.Header) > 8 {
+		size = smallTableFontSize
+	}
+	widths := columnWidths(table, size)
+
+	row := func(font string, texts []string) {
+		cells := make([]pdfdoc.Cell, len(texts))
+		x := 0.0
+		for i, text := range texts {
+			cells[i] = pdfdoc.Cell{X: x, Width: widths[i] - cellPadding, Text: text, Right: table.Numeric[i]}
+			x += widths[i]
+		}
+		pdf.Row(font, size, cells)
+	}
+	row(pdfdoc.FontBold, table.Header)
+	for _, cells := range table.Rows {
+		row(pdfdoc.FontRegular, cells)
+	}
+	if len(table.Rows) == 0 {
+		pdf.Line(pdfdoc.FontRegular, size, 0, "No records")
+	}
+}
+
+// columnWidths sizes columns to their longest text, narrowing them all alike when the table
+// is wider than the page
+func columnWidths(table *Table, size float64) []float64 {
+	widths := make([]float64, len(table.Header))
+	total := 0.0
+	for i := range table.Header {
+		widths[i] = pdfdoc.Width(table.Header[i], size)
+		for _, row := range table.Rows {
+			widths[i] = max(widths[i], pdfdoc.Width(row[i], size))
+		}
+		widths[i] += cellPadding
+		total += widths[i]
+	}
+	if total > pdfdoc.TextWidth {
+		for i := range widths {
+			widths[i] *= pdfdoc.TextWidth / total
+		}
+	}
+	return widths
+}
@@ -0,0 +1,198 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/savedquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueries returns canned results, failing for queries it does not know
+type fakeQueries struct {
+	results map[string]*savedquery.Result
+	runs    []string
+}
+
+func (f *fakeQueries) Run(ctx context.Context, name string, params map[string]string, username string, role models.Role) (*savedquery.Result, error) {
+	f.runs = append(f.runs, name+" as "+username+" ("+string(role)+")")
+	if result, ok := f.results[name]; ok {
+		return result, nil
+	}
+	return nil, savedquery.ErrQueryNotFound
+}
+
+func testDocument(t *testing.T) (*Document, *fakeQueries) {
+	queries := &fakeQueries{results: map[string]*savedquery.Result{
+		"households-by-village": {
+			Columns: []string{"data.village", "value"},
+			Rows: []map[string]any{
+				{"data.village": "Kibera <East>", "value": int64(42)},
+				{"data.village": "Mathare", "value": nil},
+			},
+			Privacy:    &savedquery.Privacy{MinGroupSize: 5},
+			Suppressed: 1,
+		},
+	}}
+	s := &service{queries: queries, log: logger.NewLogger(), now: func() time.Time {
+		return time.Date(2025, 10, 15, 9, 30, 0, 0, time.UTC)
+	}}
+	report := &Report{
+		Name:  "monthly-summary",
+		Title: "Monthly summary",
+		Sections: []Section{
+			{Title: "Introduction", Text: "Households visited this month."},
+			{Title: "Households", Query: "households-by-village", Labels: map[string]string{"data.village": "Village"}},
+			{Title: "Missing", Query: "deleted-query"},
+		},
+	}
+	doc, err := s.render(context.Background(), report, "alice")
+	require.NoError(t, err)
+	return doc, queries
+}
+
+func TestRender(t *testing.T) {
+	doc, queries := testDocument(t)
+	assert.Equal(t, []string{"households-by-village as alice (admin)", "deleted-query as alice (admin)"}, queries.runs)
+	require.Len(t, doc.Sections, 3)
+
+	assert.Nil(t, doc.Sections[0].Table)
+	households := doc.Sections[1]
+	require.NotNil(t, households.Table)
+	assert.Equal(t, []string{"Village", "Value"}, households.Table.Header)
+	assert.Equal(t, [][]string{{"Kibera <East>", "42"}, {"Mathare", withheldText}}, households.Table.Rows)
+	assert.Len(t, households.Notes, 1)
+	assert.Equal(t, savedquery.ErrQueryNotFound.Error(), doc.Sections[2].Error, "a failing section does not fail the report")
+}
+
+func TestWriteHTML(t *testing.T) {
+	doc, _ := testDocument(t)
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, doc))
+
+	page := buf.String()
+	assert.Contains(t, page, "<title>Monthly summary</title>")
+	assert.Contains(t, page, "Generated 2025-10-15 09:30 UTC")
+	assert.Contains(t, page, "<th>Village</th>")
+	assert.Contains(t, page, "<td>Kibera &lt;East&gt;</td>", "values are escaped")
+	assert.Contains(t, page, `<td class="number">42</td>`)
+	assert.Contains(t, page, "This section could not be rendered: saved query not found")
+}
+
+func TestWritePDF(t *testing.T) {
+	doc, _ := testDocument(t)
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, doc))
+
+	pdf := buf.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	assert.Contains(t, pdf, "(Monthly summary) Tj")
+	assert.Contains(t, pdf, "(Kibera <East>) Tj")
+	assert.Contains(t, pdf, "(Value) Tj")
+}
+
+func TestColumnWidths(t *testing.T) {
+	narrow := &Table{Header: []string{"a", "value"}, Rows: [][]string{{"Kibera", "1"}}}
+	widths := columnWidths(narrow, tableFontSize)
+	assert.InDelta(t, 6*tableFontSize*0.52+cellPadding, widths[0], 1e-9)
+
+	wide := &Table{Header: []string{strings.Repeat("x", 200), strings.Repeat("y", 100)}}
+	widths = columnWidths(wide, tableFontSize)
+	assert.InDelta(t, 495, widths[0]+widths[1], 1e-9, "wide tables are narrowed to the page")
+	assert.Greater(t, widths[0], widths[1])
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "smtp.example.org", Port: 587, Username: "reports", Password: "secret", From: "synkronus@example.org"})
+	var addr string
+	var recipients []string
+	var sent []byte
+	m.send = func(a string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		addr, recipients, sent = a, to, msg
+		return nil
+	}
+
+	data := bytes.Repeat([]byte("report "), 40)
+	attachment := Attachment{Filename: "monthly-summary-2025-10-15.pdf", ContentType: "application/pdf", Data: data}
+	require.NoError(t, m.Send(context.Background(), []string{"a@example.org", "b@example.org"}, "Monthly – summary", "See attached.\n", attachment))
+	assert.Equal(t, "smtp.example.org:587", addr)
+	assert.Equal(t, []string{"a@example.org", "b@example.org"}, recipients)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(sent))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Monthly – summary", subject)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	text, err := parts.NextPart()
+	require.NoError(t, err)
+	body, _ := io.ReadAll(text)
+	assert.Equal(t, "See attached.\r\n", string(body))
+
+	file, err := parts.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "monthly-summary-2025-10-15.pdf", file.FileName())
+	encoded, _ := io.ReadAll(file)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		assert.LessOrEqual(t, len(line), 76)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, data, decoded)
+
+	m.send = func(string, smtp.Auth, string, []string, []byte) error { return errors.New("connection refused") }
+	assert.ErrorContains(t, m.Send(context.Background(), []string{"a@example.org"}, "s", "b", attachment), "failed to send report email")
+}
+
+func TestPost(t *testing.T) {
+	doc, _ := testDocument(t)
+	var payload webhookPayload
+	var header string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(ReportHeader)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	attachment, err := encode(doc, FormatHTML)
+	require.NoError(t, err)
+	assert.Equal(t, "monthly-summary-2025-10-15.html", attachment.Filename)
+
+	require.NoError(t, post(context.Background(), server.Client(), server.URL, doc, attachment))
+	assert.Equal(t, "monthly-summary", header)
+	assert.Equal(t, "Monthly summary", payload.Title)
+	assert.Len(t, payload.Sections, 3)
+	assert.Equal(t, attachment.Data, payload.Content)
+
+	status = http.StatusBadGateway
+	assert.ErrorContains(t, post(context.Background(), server.Client(), server.URL, doc, attachment), "status 502")
+}
+
+func TestDeliver_Recipients(t *testing.T) {
+	s := &service{log: logger.NewLogger()}
+	assert.ErrorIs(t, s.deliver(context.Background(), &Report{}, "alice"), ErrNoRecipients)
+	assert.ErrorIs(t, s.deliver(context.Background(), &Report{Recipients: []string{"a@example.org"}}, "alice"), ErrEmailNotConfigured)
+}
@@ -0,0 +1,416 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/outbound"
+)
+
+// pollInterval is how often Run looks for reports that are due
+const pollInterval = time.Minute
+
+// Config contains report delivery settings
+type Config struct {
+	// Mailer emails reports; without one, emailing fails with ErrEmailNotConfigured
+	Mailer Mailer
+	// Outbound restricts the webhooks reports can be posted to
+	Outbound outbound.Policy
+	// Timeout bounds each webhook request
+	Timeout time.Duration
+}
+
+type service struct {
+	db      *sql.DB
+	queries Queries
+	config  Config
+	client  *http.Client
+	log     *logger.Logger
+	now     func() time.Time
+}
+
+// NewService creates a report service running the saved queries of reports through queries;
+// start Run to deliver reports on schedule
+func NewService(db *sql.DB, queries Queries, config Config, log *logger.Logger) Service {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &service{
+		db:      db,
+		queries: queries,
+		config:  config,
+		client:  outbound.NewClient(config.Outbound, config.Timeout),
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// reportColumns lists the columns selected for a Report in scan order
+const reportColumns = `name, title, description, sections, schedule, hour, format, recipients, webhook_url,
+	next_run_at, last_run_at, last_error, updated_by, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReport(row rowScanner) (*Report, error) {
+	var r Report
+	var sections []byte
+	var recipients pq.StringArray
+	if err := row.Scan(&r.Name, &r.Title, &r.Description, &sections, &r.Schedule, &r.Hour, &r.Format, &recipients,
+		&r.WebhookURL, &r.NextRunAt, &r.LastRunAt, &r.LastError, &r.UpdatedBy, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(sections, &r.Sections); err != nil {
+		return nil, fmt.Errorf("failed to decode sections of %s: %w", r.Name, err)
+	}
+	r.Recipients = []string(recipients)
+	if r.Recipients == nil {
+		r.Recipients = []string{}
+	}
+	return &r, nil
+}
+
+// List returns all reports ordered by name
+func (s *service) List(ctx context.Context) ([]Report, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+reportColumns+" FROM reports ORDER BY name")
+	if err != nil {
+		s.log.Error("Failed to query reports", "error", err)
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]Report, 0)
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return reports, nil
+}
+
+// Get returns a single report
+func (s *service) Get(ctx context.Context, name string) (*Report, error) {
+	r, err := scanReport(s.db.QueryRowContext(ctx, "SELECT "+reportColumns+" FROM reports WHERE name = $1", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		s.log.Error("Failed to get report", "error", err, "name", name)
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	return r, nil
+}
+
+// Save creates or replaces a report after validating it. Scheduled reports are next delivered
+// at the first time their schedule comes round.
+func (s *service) Save(ctx context.Context, report Report, updatedBy string) (*Report, error) {
+	if err := report.Validate(); err != nil {
+		return nil, err
+	}
+	sections, err := json.Marshal(report.Sections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sections: %w", err)
+	}
+	var nextRun *time.Time
+	if report.Schedule != "" {
+		next := NextRun(report.Schedule, report.Hour, s.now())
+		nextRun = &next
+	}
+
+	saved, err := scanReport(s.db.QueryRowContext(ctx, `
+		INSERT INTO reports (name, title, description, sections, schedule, hour, format, recipients,
+			webhook_url, next_run_at, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		ON CONFLICT (name)
+		DO UPDATE SET title = EXCLUDED.title, description = EXCLUDED.description, sections = EXCLUDED.sections,
+			schedule = EXCLUDED.schedule, hour = EXCLUDED.hour, format = EXCLUDED.format,
+			recipients = EXCLUDED.recipients, webhook_url = EXCLUDED.webhook_url,
+			next_run_at = EXCLUDED.next_run_at, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING `+reportColumns,
+		report.Name, report.Title, report.Description, sections, report.Schedule, report.Hour, report.Format,
+		pq.StringArray(report.Recipients), report.WebhookURL, nextRun, updatedBy,
+	))
+	if err != nil {
+		s.log.Error("Failed to save report", "error", err, "name", report.Name)
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+
+	s.log.Info("Report updated", "name", report.Name, "updatedBy", updatedBy, "schedule", report.Schedule)
+	return saved, nil
+}
+
+// Delete removes a report
+func (s *service) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM reports WHERE name = $1", name)
+	if err != nil {
+		s.log.Error("Failed to delete report", "error", err, "name", name)
+		return fmt.Errorf("failed to delete report: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	s.log.Info("Report deleted", "name", name)
+	return nil
+}
+
+// Render runs the queries of a report for a user and lays out their results
+func (s *service) Render(ctx context.Context, name string, username string) (*Document, error) {
+	report, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.render(ctx, report, username)
+}
+
+// render runs the queries of a report as an admin restricted to the org unit scope of username.
+// A section whose query fails shows the error; the others are rendered regardless.
+func (s *service) render(ctx context.Context, report *Report, username string) (*Document, error) {
+	doc := &Document{
+		Name:        report.Name,
+		Title:       report.Title,
+		Description: report.Description,
+		GeneratedAt: s.now().UTC(),
+		Sections:    make([]RenderedSection, 0, len(report.Sections)),
+	}
+	for _, section := range report.Sections {
+		rendered := RenderedSection{Title: section.Title, Text: section.Text}
+		if section.Query != "" {
+			result, err := s.queries.Run(ctx, section.Query, section.Params, username, models.RoleAdmin)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				s.log.Warn("Failed to run report query", "report", report.Name, "query", section.Query, "error", err)
+				rendered.Error = err.Error()
+			} else if rendered.Table, err = layout(section, result); err != nil {
+				rendered.Error = err.Error()
+			} else {
+				rendered.Result = result
+				rendered.Notes = notes(result)
+			}
+		}
+		doc.Sections = append(doc.Sections, rendered)
+	}
+	return doc, nil
+}
+
+// Deliver renders a report and sends it to its recipients and webhook right away
+func (s *service) Deliver(ctx context.Context, name string, username string) error {
+	report, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	deliverErr := s.deliver(ctx, report, username)
+	if errors.Is(deliverErr, ErrNoRecipients) || errors.Is(deliverErr, ErrEmailNotConfigured) {
+		return deliverErr
+	}
+	if err := s.recordRun(ctx, s.db, report.Name, nil, deliverErr); err != nil {
+		return err
+	}
+	return deliverErr
+}
+
+// deliver renders a report in its format and sends it by email and to its webhook. Both are
+// tried when one fails.
+func (s *service) deliver(ctx context.Context, report *Report, username string) error {
+	if len(report.Recipients) == 0 && report.WebhookURL == "" {
+		return ErrNoRecipients
+	}
+	if len(report.Recipients) > 0 && s.config.Mailer == nil {
+		return ErrEmailNotConfigured
+	}
+
+	doc, err := s.render(ctx, report, username)
+	if err != nil {
+		return err
+	}
+	attachment, err := encode(doc, report.Format)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if len(report.Recipients) > 0 {
+		subject := doc.Title + " - " + doc.GeneratedAt.Format(time.DateOnly)
+		if err := s.config.Mailer.Send(ctx, report.Recipients, subject, summary(doc), attachment); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if report.WebhookURL != "" {
+		if err := post(ctx, s.client, report.WebhookURL, doc, attachment); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrDeliveryFailed, err)
+	}
+	s.log.Info("Report delivered", "name", report.Name, "recipients", len(report.Recipients), "webhook", report.WebhookURL != "")
+	return nil
+}
+
+// encode renders a document in format as an attachment named after the report and its date
+func encode(doc *Document, format string) (Attachment, error) {
+	attachment := Attachment{Filename: doc.Name + "-" + doc.GeneratedAt.Format(time.DateOnly) + "." + format}
+	var buf bytes.Buffer
+	var err error
+	if format == FormatPDF {
+		attachment.ContentType = "application/pdf"
+		err = WritePDF(&buf, doc)
+	} else {
+		attachment.ContentType = "text/html; charset=utf-8"
+		err = WriteHTML(&buf, doc)
+	}
+	if err != nil {
+		return attachment, fmt.Errorf("failed to render report: %w", err)
+	}
+	attachment.Data = buf.Bytes()
+	return attachment, nil
+}
+
+// summary is the text of a report email, listing the sections that could not be rendered
+func summary(doc *Document) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The report %q generated on %s is attached.\n", doc.Title, doc.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	if doc.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", doc.Description)
+	}
+	for _, section := range doc.Sections {
+		if section.Error != "" {
+			fmt.Fprintf(&b, "\nThe section %q could not be rendered: %s\n", section.Title, section.Error)
+		}
+	}
+	return b.String()
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// recordRun records a delivery and its error, and the next scheduled delivery when given
+func (s *service) recordRun(ctx context.Context, db execer, name string, nextRun *time.Time, deliverErr error) error {
+	lastError := ""
+	if deliverErr != nil {
+		lastError = deliverErr.Error()
+	}
+	query := "UPDATE reports SET last_run_at = NOW(), last_error = $2 WHERE name = $1"
+	args := []any{name, lastError}
+	if nextRun != nil {
+		query = "UPDATE reports SET last_run_at = NOW(), last_error = $2, next_run_at = $3 WHERE name = $1"
+		args = append(args, *nextRun)
+	}
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record report delivery: %w", err)
+	}
+	return nil
+}
+
+// Run delivers due reports every poll interval until ctx is cancelled
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.deliverDue(ctx); err != nil && ctx.Err() == nil {
+			s.log.Warn("Failed to deliver scheduled reports", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue delivers every report whose next delivery has come
+func (s *service) deliverDue(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM reports WHERE next_run_at <= NOW() ORDER BY next_run_at")
+	if err != nil {
+		return fmt.Errorf("failed to list due reports: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan report: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list due reports: %w", err)
+	}
+
+	for _, name := range names {
+		err := s.deliverScheduled(ctx, name)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			s.log.Warn("Failed to deliver scheduled report", "error", err, "name", name)
+		}
+	}
+	return nil
+}
+
+// deliverScheduled delivers a due report and schedules its next delivery. The report row stays
+// locked meanwhile, so replicas skip a report another one is delivering. A failed delivery is
+// not retried before the next one is due; its error is kept in last_error.
+func (s *service) deliverScheduled(ctx context.Context, name string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report, err := scanReport(tx.QueryRowContext(ctx,
+		"SELECT "+reportColumns+" FROM reports WHERE name = $1 AND next_run_at <= NOW() FOR UPDATE SKIP LOCKED", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get report: %w", err)
+	}
+
+	// Queries are run with the org unit scope of the admin who last saved the report
+	username := ""
+	if report.UpdatedBy != nil {
+		username = *report.UpdatedBy
+	}
+	deliverErr := s.deliver(ctx, report, username)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	next := NextRun(report.Schedule, report.Hour, s.now())
+	if err := s.recordRun(ctx, tx, report.Name, &next, deliverErr); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to record report delivery: %w", err)
+	}
+	return deliverErr
+}
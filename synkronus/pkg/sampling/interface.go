@@ -0,0 +1,84 @@
+package sampling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidRequest is returned when a sample request is not valid
+var ErrInvalidRequest = errors.New("invalid sample request")
+
+// Strata observations can be sampled by
+const (
+	// StratifyEnumerator samples each user who collected records separately
+	StratifyEnumerator = "enumerator"
+	// StratifyDay samples each UTC day of collection separately
+	StratifyDay = "day"
+)
+
+// Guardrails applied to every sample
+const (
+	// MaxSampleSize is the most records a sample may return
+	MaxSampleSize = 5000
+	// MaxPopulation is the most records a sample may be drawn from; narrower date ranges or
+	// form types are needed beyond it
+	MaxPopulation = 500000
+	// StatementTimeout bounds the run time of each query of a sample
+	StatementTimeout = 30 * time.Second
+)
+
+// Request describes the records to sample from and how many to pick. Exactly one of
+// Percent and Size must be set. Deleted records and drafts are never sampled.
+type Request struct {
+	// FormType restricts the population to one form type; empty samples all form types
+	FormType string `json:"form_type,omitempty"`
+	// From and To restrict the population to records created in [From, To)
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	// Percent picks this share of every stratum, rounded up so that no stratum goes unchecked
+	Percent float64 `json:"percent,omitempty"`
+	// Size picks this many records in total, spread over the strata in proportion to their size
+	Size int `json:"size,omitempty"`
+	// StratifyBy lists enumerator and/or day
+	StratifyBy []string `json:"stratify_by,omitempty"`
+	// Seed makes a sample reproducible; a random seed is chosen and returned when omitted
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// Record is a sampled observation
+type Record struct {
+	ObservationID string          `json:"observation_id"`
+	FormType      string          `json:"form_type"`
+	FormVersion   string          `json:"form_version"`
+	Data          json.RawMessage `json:"data"`
+	CreatedAt     string          `json:"created_at"`
+	UpdatedAt     string          `json:"updated_at"`
+	CreatedBy     *string         `json:"created_by,omitempty"`
+	OrgUnitID     *string         `json:"org_unit_id,omitempty"`
+}
+
+// Stratum reports how many records of one stratum were sampled. Enumerator and Day are only
+// set when the sample is stratified by them; records of unknown users have an empty enumerator.
+type Stratum struct {
+	Enumerator *string `json:"enumerator,omitempty"`
+	Day        string  `json:"day,omitempty"`
+	Population int     `json:"population"`
+	Sampled    int     `json:"sampled"`
+}
+
+// Sample is the outcome of a sample request
+type Sample struct {
+	Seed       int64     `json:"seed"`
+	Population int       `json:"population"`
+	Strata     []Stratum `json:"strata"`
+	Records    []Record  `json:"records"`
+}
+
+// Service draws samples of observations for back-checking
+type Service interface {
+	// Sample draws a random or stratified sample of the observations within the org unit
+	// scope of the user
+	Sample(ctx context.Context, req Request, username string) (*Sample, error)
+}
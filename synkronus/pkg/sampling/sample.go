@@ -0,0 +1,162 @@
+// Package sampling draws random and stratified samples of observations so that QA teams can
+// pick records for back-checks without exporting the whole dataset.
+package sampling
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Validate checks a sample request
+func (req Request) Validate() error {
+	switch {
+	case req.Percent != 0 && req.Size != 0:
+		return fmt.Errorf("%w: set either percent or size, not both", ErrInvalidRequest)
+	case req.Percent == 0 && req.Size == 0:
+		return fmt.Errorf("%w: percent or size is required", ErrInvalidRequest)
+	case req.Percent < 0 || req.Percent > 100:
+		return fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidRequest)
+	case req.Size < 0 || req.Size > MaxSampleSize:
+		return fmt.Errorf("%w: size must be between 1 and %d", ErrInvalidRequest, MaxSampleSize)
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidRequest)
+	}
+
+	seen := make(map[string]bool, len(req.StratifyBy))
+	for _, stratum := range req.StratifyBy {
+		if stratum != StratifyEnumerator && stratum != StratifyDay {
+			return fmt.Errorf("%w: cannot stratify by %q", ErrInvalidRequest, stratum)
+		}
+		if seen[stratum] {
+			return fmt.Errorf("%w: %s is listed twice in stratify_by", ErrInvalidRequest, stratum)
+		}
+		seen[stratum] = true
+	}
+	return nil
+}
+
+// stratifies reports whether the request stratifies by the given stratum
+func (req Request) stratifies(stratum string) bool {
+	for _, s := range req.StratifyBy {
+		if s == stratum {
+			return true
+		}
+	}
+	return false
+}
+
+// member is a record of the population, identified by its stratum
+type member struct {
+	ObservationID string
+	Enumerator    string
+	Day           string
+}
+
+// stratumKey identifies the stratum of a member under a request
+type stratumKey struct {
+	enumerator string
+	day        string
+}
+
+func (req Request) keyOf(m member) stratumKey {
+	var key stratumKey
+	if req.stratifies(StratifyEnumerator) {
+		key.enumerator = m.Enumerator
+	}
+	if req.stratifies(StratifyDay) {
+		key.day = m.Day
+	}
+	return key
+}
+
+// choose picks the sampled observation ids from the population, which must be ordered by
+// observation id so that a seed always picks the same records. Strata are reported in order
+// of enumerator and day.
+func choose(req Request, population []member, seed int64) ([]Stratum, []string, error) {
+	groups := make(map[stratumKey][]string)
+	keys := make([]stratumKey, 0)
+	for _, m := range population {
+		key := req.keyOf(m)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], m.ObservationID)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].enumerator != keys[j].enumerator {
+			return keys[i].enumerator < keys[j].enumerator
+		}
+		return keys[i].day < keys[j].day
+	})
+
+	sizes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		sizes = append(sizes, len(groups[key]))
+	}
+	allocation := allocate(req, sizes, len(population))
+
+	total := 0
+	for _, n := range allocation {
+		total += n
+	}
+	if total > MaxSampleSize {
+		return nil, nil, fmt.Errorf("%w: the sample would hold %d records, more than %d", ErrInvalidRequest, total, MaxSampleSize)
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	strata := make([]Stratum, 0, len(keys))
+	ids := make([]string, 0, total)
+	for i, key := range keys {
+		members := groups[key]
+		for _, n := range random.Perm(len(members))[:allocation[i]] {
+			ids = append(ids, members[n])
+		}
+
+		stratum := Stratum{Population: len(members), Sampled: allocation[i]}
+		if req.stratifies(StratifyEnumerator) {
+			enumerator := key.enumerator
+			stratum.Enumerator = &enumerator
+		}
+		stratum.Day = key.day
+		strata = append(strata, stratum)
+	}
+	return strata, ids, nil
+}
+
+// allocate decides how many records to pick from strata of the given sizes. A percentage is
+// rounded up per stratum; a total size is spread in proportion to the strata sizes, handing
+// the records lost to rounding down to the strata with the largest remainders.
+func allocate(req Request, sizes []int, population int) []int {
+	allocation := make([]int, len(sizes))
+	if req.Percent > 0 {
+		for i, size := range sizes {
+			allocation[i] = min(size, int(math.Ceil(float64(size)*req.Percent/100)))
+		}
+		return allocation
+	}
+
+	target := min(req.Size, population)
+	if target == 0 {
+		return allocation
+	}
+	remainders := make([]float64, len(sizes))
+	assigned := 0
+	for i, size := range sizes {
+		share := float64(size) * float64(target) / float64(population)
+		allocation[i] = int(share)
+		remainders[i] = share - float64(allocation[i])
+		assigned += allocation[i]
+	}
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:target-assigned] {
+		allocation[i]++
+	}
+	return allocation
+}
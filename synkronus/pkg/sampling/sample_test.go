@@ -0,0 +1,103 @@
+package sampling
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPopulation holds 60 records of alice over two days and 40 of bob on one day
+func testPopulation() []member {
+	population := make([]member, 0, 100)
+	for i := 0; i < 100; i++ {
+		m := member{ObservationID: fmt.Sprintf("obs-%03d", i), Enumerator: "alice", Day: "2025-06-01"}
+		switch {
+		case i >= 60:
+			m.Enumerator = "bob"
+		case i >= 30:
+			m.Day = "2025-06-02"
+		}
+		population = append(population, m)
+	}
+	return population
+}
+
+func TestValidate(t *testing.T) {
+	from := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{"neither percent nor size", Request{}},
+		{"both percent and size", Request{Percent: 5, Size: 10}},
+		{"percent above 100", Request{Percent: 150}},
+		{"size too large", Request{Size: MaxSampleSize + 1}},
+		{"unknown stratum", Request{Percent: 5, StratifyBy: []string{"village"}}},
+		{"repeated stratum", Request{Percent: 5, StratifyBy: []string{"day", "day"}}},
+		{"empty date range", Request{Percent: 5, From: &from, To: &to}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.req.Validate(), ErrInvalidRequest)
+		})
+	}
+
+	assert.NoError(t, Request{Percent: 5, StratifyBy: []string{StratifyEnumerator, StratifyDay}}.Validate())
+}
+
+func TestChoosePercentRoundsUpPerStratum(t *testing.T) {
+	req := Request{Percent: 5, StratifyBy: []string{StratifyEnumerator, StratifyDay}}
+	strata, ids, err := choose(req, testPopulation(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, strata, 3)
+	assert.Equal(t, "alice", *strata[0].Enumerator)
+	assert.Equal(t, "2025-06-01", strata[0].Day)
+	assert.Equal(t, 30, strata[0].Population)
+	// 5% of 30 and 40 rounded up
+	assert.Equal(t, []int{2, 2, 2}, []int{strata[0].Sampled, strata[1].Sampled, strata[2].Sampled})
+	assert.Len(t, ids, 6)
+}
+
+func TestChooseSizeIsProportional(t *testing.T) {
+	req := Request{Size: 10, StratifyBy: []string{StratifyEnumerator}}
+	strata, ids, err := choose(req, testPopulation(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, strata, 2)
+	assert.Equal(t, 6, strata[0].Sampled)
+	assert.Equal(t, 4, strata[1].Sampled)
+	assert.Empty(t, strata[0].Day, "day is only reported when stratified by it")
+	assert.Len(t, ids, 10)
+
+	// Without strata the whole population is one stratum
+	strata, ids, err = choose(Request{Size: 500}, testPopulation(), 1)
+	require.NoError(t, err)
+	require.Len(t, strata, 1)
+	assert.Nil(t, strata[0].Enumerator)
+	assert.Len(t, ids, 100, "a sample cannot exceed its population")
+}
+
+func TestChooseIsReproducible(t *testing.T) {
+	req := Request{Percent: 10, StratifyBy: []string{StratifyDay}}
+	_, first, err := choose(req, testPopulation(), 42)
+	require.NoError(t, err)
+	_, again, err := choose(req, testPopulation(), 42)
+	require.NoError(t, err)
+	_, other, err := choose(req, testPopulation(), 43)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first, other)
+}
+
+func TestAllocateLargestRemainder(t *testing.T) {
+	// Shares of 3.33 each; the leftover record goes to the first stratum
+	assert.Equal(t, []int{4, 3, 3}, allocate(Request{Size: 10}, []int{10, 10, 10}, 30))
+	assert.Equal(t, []int{0, 0}, allocate(Request{Size: 10}, []int{0, 0}, 0))
+}
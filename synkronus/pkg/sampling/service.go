@@ -0,0 +1,166 @@
+package sampling
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new sampling service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Sample draws a random or stratified sample of the observations within the org unit scope of
+// the user. The population is read in a read-only transaction with a statement timeout.
+func (s *service) Sample(ctx context.Context, req Request, username string) (*Sample, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	seed := rand.Int63()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Read-only, so rolling back is all that is ever needed
+	defer tx.Rollback()
+
+	timeout := strconv.FormatInt(StatementTimeout.Milliseconds(), 10)
+	if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = "+timeout); err != nil {
+		return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	population, err := readPopulation(ctx, tx, req, username)
+	if err != nil {
+		s.log.Error("Failed to read sample population", "error", err)
+		return nil, err
+	}
+
+	strata, ids, err := choose(req, population, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := readRecords(ctx, tx, ids)
+	if err != nil {
+		s.log.Error("Failed to read sampled records", "error", err)
+		return nil, err
+	}
+
+	s.log.Info("Observations sampled", "username", username, "population", len(population), "sampled", len(records), "seed", seed)
+	return &Sample{Seed: seed, Population: len(population), Strata: strata, Records: records}, nil
+}
+
+// readPopulation returns the records a sample is drawn from, ordered by observation id
+func readPopulation(ctx context.Context, tx *sql.Tx, req Request, username string) ([]member, error) {
+	var query strings.Builder
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	query.WriteString(`SELECT observation_id, COALESCE(created_by, ''), to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')
+		FROM observations WHERE NOT deleted AND NOT draft`)
+	if req.FormType != "" {
+		query.WriteString(" AND form_type = " + arg(req.FormType))
+	}
+	if req.From != nil {
+		query.WriteString(" AND created_at >= " + arg(*req.From))
+	}
+	if req.To != nil {
+		query.WriteString(" AND created_at < " + arg(*req.To))
+	}
+
+	// Users assigned to org units only sample records within their part of the hierarchy,
+	// as in sync pulls
+	if username != "" {
+		user := arg(username)
+		query.WriteString(` AND (org_unit_id IS NULL` +
+			` OR NOT EXISTS (SELECT 1 FROM user_org_units WHERE username = ` + user + `)` +
+			` OR org_unit_id IN (SELECT child.id FROM user_org_units uou` +
+			` JOIN org_units parent ON parent.id = uou.org_unit_id` +
+			` JOIN org_units child ON child.path LIKE parent.path || '%'` +
+			` WHERE uou.username = ` + user + `))`)
+	}
+	query.WriteString(" ORDER BY observation_id LIMIT " + arg(MaxPopulation+1))
+
+	rows, err := tx.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sample population: %w", err)
+	}
+	defer rows.Close()
+
+	population := make([]member, 0)
+	for rows.Next() {
+		if len(population) == MaxPopulation {
+			return nil, fmt.Errorf("%w: more than %d records match; narrow the form type or dates", ErrInvalidRequest, MaxPopulation)
+		}
+		var m member
+		if err := rows.Scan(&m.ObservationID, &m.Enumerator, &m.Day); err != nil {
+			return nil, fmt.Errorf("failed to scan sample population: %w", err)
+		}
+		population = append(population, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sample population: %w", err)
+	}
+	return population, nil
+}
+
+// readRecords returns the sampled records in the order of ids
+func readRecords(ctx context.Context, tx *sql.Tx, ids []string) ([]Record, error) {
+	records := make([]Record, 0, len(ids))
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT observation_id, form_type, form_version, data, created_at, updated_at, created_by, org_unit_id
+		FROM observations WHERE observation_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sampled records: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]Record, len(ids))
+	for rows.Next() {
+		var record Record
+		var createdAt, updatedAt time.Time
+		var data []byte
+		if err := rows.Scan(&record.ObservationID, &record.FormType, &record.FormVersion, &data,
+			&createdAt, &updatedAt, &record.CreatedBy, &record.OrgUnitID); err != nil {
+			return nil, fmt.Errorf("failed to scan sampled record: %w", err)
+		}
+		record.Data = data
+		record.CreatedAt = createdAt.Format(time.RFC3339Nano)
+		record.UpdatedAt = updatedAt.Format(time.RFC3339Nano)
+		byID[record.ObservationID] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sampled records: %w", err)
+	}
+
+	for _, id := range ids {
+		if record, ok := byID[id]; ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,403 @@
+package savedquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// columns maps the observation columns a query may use to their SQL expression
+var columns = map[string]string{
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"created_by":   "created_by",
+	"owner":        "owner",
+	"form_version": "form_version",
+	"org_unit_id":  "org_unit_id::TEXT",
+	"case_id":      "case_id",
+}
+
+// timeColumns are the columns holding timestamps, which compare against dates and may be bucketed
+var timeColumns = map[string]bool{"created_at": true, "updated_at": true}
+
+// buckets lists the date_trunc units group by fields of time columns may use
+var buckets = map[string]bool{"day": true, "week": true, "month": true, "year": true}
+
+// operators maps filter operators to SQL
+var operators = map[string]string{
+	"eq":       "=",
+	"ne":       "<>",
+	"gt":       ">",
+	"gte":      ">=",
+	"lt":       "<",
+	"lte":      "<=",
+	"contains": "ILIKE",
+}
+
+// aggregates lists the supported aggregate functions and whether they need a field
+var aggregates = map[string]bool{
+	"count":          false,
+	"count_distinct": true,
+	"sum":            true,
+	"avg":            true,
+	"min":            true,
+	"max":            true,
+}
+
+// pathSegmentPattern restricts the segments of data paths
+var pathSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,100}$`)
+
+// numericPattern matches text that can safely be cast to NUMERIC
+const numericPattern = `'^\s*-?[0-9]+(\.[0-9]+)?\s*$'`
+
+// builder collects the arguments of a compiled query
+type builder struct {
+	args []any
+}
+
+// arg binds a value and returns its placeholder
+func (b *builder) arg(value any) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// field is a resolved query field
+type field struct {
+	expr   string
+	isTime bool
+}
+
+// dataPath splits a data.<path> field into its segments
+func dataPath(name string) ([]string, bool) {
+	if !strings.HasPrefix(name, "data.") {
+		return nil, false
+	}
+	segments := strings.Split(strings.TrimPrefix(name, "data."), ".")
+	for _, segment := range segments {
+		if !pathSegmentPattern.MatchString(segment) {
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+// validField reports whether name is a column or data path a query may use
+func validField(name string) bool {
+	if _, ok := columns[name]; ok {
+		return true
+	}
+	_, ok := dataPath(name)
+	return ok
+}
+
+// resolve turns a field name into SQL; data paths are bound as arguments
+func (b *builder) resolve(name string) (field, error) {
+	if expr, ok := columns[name]; ok {
+		return field{expr: expr, isTime: timeColumns[name]}, nil
+	}
+	segments, ok := dataPath(name)
+	if !ok {
+		return field{}, fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, name)
+	}
+	return field{expr: "(data #>> " + b.arg(pq.Array(segments)) + "::TEXT[])"}, nil
+}
+
+// numeric casts a text expression to NUMERIC, yielding NULL for values that are not numbers
+func numeric(expr string) string {
+	return "(CASE WHEN " + expr + " ~ " + numericPattern + " THEN (" + expr + ")::NUMERIC END)"
+}
+
+// splitBucket separates a "created_at:month" group by field into field and bucket
+func splitBucket(name string) (string, string) {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// parseDate accepts a date or an RFC 3339 timestamp
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseParameter converts a supplied parameter value to its declared type
+func parseParameter(p Parameter, value string) (any, error) {
+	switch p.Type {
+	case ParameterNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidParameter, p.Name)
+		}
+		return n, nil
+	case ParameterDate:
+		t, err := parseDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a date (YYYY-MM-DD) or RFC 3339 timestamp", ErrInvalidParameter, p.Name)
+		}
+		return t, nil
+	default:
+		return value, nil
+	}
+}
+
+// Validate checks a query definition against the guardrails
+func (d Definition) Validate() error {
+	if d.FormType == "" {
+		return fmt.Errorf("%w: form_type is required", ErrInvalidQuery)
+	}
+
+	params := make(map[string]Parameter, len(d.Parameters))
+	for _, p := range d.Parameters {
+		if !namePattern.MatchString(p.Name) {
+			return fmt.Errorf("%w: parameter names must be lowercase letters, digits, '_' or '-'", ErrInvalidQuery)
+		}
+		if _, dup := params[p.Name]; dup {
+			return fmt.Errorf("%w: duplicate parameter %q", ErrInvalidQuery, p.Name)
+		}
+		if p.Type != ParameterString && p.Type != ParameterNumber && p.Type != ParameterDate {
+			return fmt.Errorf("%w: parameter %q must have type string, number or date", ErrInvalidQuery, p.Name)
+		}
+		if p.Default != nil {
+			if _, err := parseParameter(p, *p.Default); err != nil {
+				return fmt.Errorf("%w: default of parameter %q: %v", ErrInvalidQuery, p.Name, err)
+			}
+		}
+		params[p.Name] = p
+	}
+
+	if len(d.Filters) > MaxFilters {
+		return fmt.Errorf("%w: at most %d filters are allowed", ErrInvalidQuery, MaxFilters)
+	}
+	for _, f := range d.Filters {
+		if !validField(f.Field) {
+			return fmt.Errorf("%w: unknown filter field %q", ErrInvalidQuery, f.Field)
+		}
+		if _, ok := operators[f.Op]; !ok {
+			return fmt.Errorf("%w: filter op must be eq, ne, gt, gte, lt, lte or contains", ErrInvalidQuery)
+		}
+		if (f.Param == "") == (len(f.Value) == 0) {
+			return fmt.Errorf("%w: filter on %q needs either param or value", ErrInvalidQuery, f.Field)
+		}
+
+		valueType := ParameterString
+		if f.Param != "" {
+			p, ok := params[f.Param]
+			if !ok {
+				return fmt.Errorf("%w: filter uses undeclared parameter %q", ErrInvalidQuery, f.Param)
+			}
+			valueType = p.Type
+		} else {
+			var value any
+			if err := json.Unmarshal(f.Value, &value); err != nil {
+				return fmt.Errorf("%w: filter value must be a string or number", ErrInvalidQuery)
+			}
+			switch v := value.(type) {
+			case float64:
+				valueType = ParameterNumber
+			case string:
+				if timeColumns[f.Field] {
+					if _, err := parseDate(v); err != nil {
+						return fmt.Errorf("%w: filter on %q needs a date value", ErrInvalidQuery, f.Field)
+					}
+				}
+			default:
+				return fmt.Errorf("%w: filter value must be a string or number", ErrInvalidQuery)
+			}
+		}
+
+		if f.Op == "contains" && (valueType != ParameterString || timeColumns[f.Field]) {
+			return fmt.Errorf("%w: contains only compares text", ErrInvalidQuery)
+		}
+		if timeColumns[f.Field] && valueType == ParameterNumber {
+			return fmt.Errorf("%w: filter on %q compares dates", ErrInvalidQuery, f.Field)
+		}
+	}
+
+	if len(d.GroupBy) > MaxGroupBy {
+		return fmt.Errorf("%w: at most %d group by fields are allowed", ErrInvalidQuery, MaxGroupBy)
+	}
+	for _, g := range d.GroupBy {
+		name, bucket := splitBucket(g)
+		if !validField(name) {
+			return fmt.Errorf("%w: unknown group by field %q", ErrInvalidQuery, g)
+		}
+		if bucket != "" && (!timeColumns[name] || !buckets[bucket]) {
+			return fmt.Errorf("%w: only created_at and updated_at group by :day, :week, :month or :year", ErrInvalidQuery)
+		}
+	}
+
+	needsField, ok := aggregates[d.Aggregate.Func]
+	if !ok {
+		return fmt.Errorf("%w: aggregate func must be count, count_distinct, sum, avg, min or max", ErrInvalidQuery)
+	}
+	if needsField && !validField(d.Aggregate.Field) {
+		return fmt.Errorf("%w: aggregate %s needs a valid field", ErrInvalidQuery, d.Aggregate.Func)
+	}
+	if (d.Aggregate.Func == "sum" || d.Aggregate.Func == "avg") && timeColumns[d.Aggregate.Field] {
+		return fmt.Errorf("%w: %s needs a numeric field", ErrInvalidQuery, d.Aggregate.Func)
+	}
+
+	if d.Privacy != nil {
+		if d.Privacy.MinGroupSize < 0 || d.Privacy.Noise < 0 {
+			return fmt.Errorf("%w: privacy settings must not be negative", ErrInvalidQuery)
+		}
+		if d.Privacy.Noise > 0 && !noisyAggregates[d.Aggregate.Func] {
+			return fmt.Errorf("%w: noise only applies to count, count_distinct and sum", ErrInvalidQuery)
+		}
+	}
+
+	if d.Limit < 0 || d.Limit > MaxLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+	return nil
+}
+
+// compile builds the SQL of a validated definition for a run with the supplied parameters.
+// Every value, including data paths, is bound as an argument; only fixed fragments from the
+// tables above are written into the SQL.
+func compile(d Definition, supplied map[string]string, username string) (string, []any, []string, error) {
+	values := make(map[string]any, len(d.Parameters))
+	for _, p := range d.Parameters {
+		raw, ok := supplied[p.Name]
+		if !ok || raw == "" {
+			if p.Default == nil {
+				if p.Required {
+					return "", nil, nil, fmt.Errorf("%w: %s is required", ErrInvalidParameter, p.Name)
+				}
+				continue
+			}
+			raw = *p.Default
+		}
+		value, err := parseParameter(p, raw)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		values[p.Name] = value
+	}
+
+	b := &builder{}
+	var selects, columnNames []string
+	for _, g := range d.GroupBy {
+		name, bucket := splitBucket(g)
+		f, err := b.resolve(name)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		expr := f.expr
+		if bucket != "" {
+			expr = "date_trunc('" + bucket + "', " + expr + ")"
+		}
+		selects = append(selects, expr)
+		columnNames = append(columnNames, g)
+	}
+
+	aggregate := "COUNT(*)"
+	if d.Aggregate.Func != "count" {
+		f, err := b.resolve(d.Aggregate.Field)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		switch {
+		case d.Aggregate.Func == "count_distinct":
+			aggregate = "COUNT(DISTINCT " + f.expr + ")"
+		case f.isTime:
+			aggregate = strings.ToUpper(d.Aggregate.Func) + "(" + f.expr + ")"
+		default:
+			aggregate = strings.ToUpper(d.Aggregate.Func) + "(" + numeric(f.expr) + ")"
+		}
+	}
+	selects = append(selects, aggregate)
+	columnNames = append(columnNames, "value")
+	if countsRecords(d) {
+		// Groups are suppressed by the records they cover, not by the aggregated value
+		selects = append(selects, "COUNT(*)")
+	}
+
+	var query strings.Builder
+	query.WriteString("SELECT " + strings.Join(selects, ", ") + " FROM observations")
+	query.WriteString(" WHERE form_type = " + b.arg(d.FormType) + " AND NOT deleted AND NOT draft")
+
+	for _, filter := range d.Filters {
+		var value any
+		if filter.Param != "" {
+			v, ok := values[filter.Param]
+			if !ok {
+				// Optional parameter not supplied
+				continue
+			}
+			value = v
+		} else if err := json.Unmarshal(filter.Value, &value); err != nil {
+			return "", nil, nil, fmt.Errorf("%w: filter value must be a string or number", ErrInvalidQuery)
+		}
+
+		f, err := b.resolve(filter.Field)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		op := operators[filter.Op]
+
+		switch v := value.(type) {
+		case float64:
+			query.WriteString(" AND " + numeric(f.expr) + " " + op + " " + b.arg(v) + "::NUMERIC")
+		case time.Time:
+			if f.isTime {
+				query.WriteString(" AND " + f.expr + " " + op + " " + b.arg(v) + "::TIMESTAMPTZ")
+			} else {
+				query.WriteString(" AND " + f.expr + " " + op + " " + b.arg(v.Format("2006-01-02")) + "::TEXT")
+			}
+		case string:
+			switch {
+			case f.isTime:
+				t, err := parseDate(v)
+				if err != nil {
+					return "", nil, nil, fmt.Errorf("%w: %q is not a date", ErrInvalidParameter, v)
+				}
+				query.WriteString(" AND " + f.expr + " " + op + " " + b.arg(t) + "::TIMESTAMPTZ")
+			case filter.Op == "contains":
+				query.WriteString(" AND " + f.expr + " ILIKE '%' || " + b.arg(v) + "::TEXT || '%'")
+			default:
+				query.WriteString(" AND " + f.expr + " " + op + " " + b.arg(v) + "::TEXT")
+			}
+		default:
+			return "", nil, nil, fmt.Errorf("%w: filter value must be a string or number", ErrInvalidQuery)
+		}
+	}
+
+	// Users assigned to org units only see records within their part of the hierarchy,
+	// as in sync pulls
+	if username != "" {
+		user := b.arg(username)
+		query.WriteString(` AND (org_unit_id IS NULL` +
+			` OR NOT EXISTS (SELECT 1 FROM user_org_units WHERE username = ` + user + `)` +
+			` OR org_unit_id IN (SELECT child.id FROM user_org_units uou` +
+			` JOIN org_units parent ON parent.id = uou.org_unit_id` +
+			` JOIN org_units child ON child.path LIKE parent.path || '%'` +
+			` WHERE uou.username = ` + user + `))`)
+	}
+
+	if len(d.GroupBy) > 0 {
+		positions := make([]string, len(d.GroupBy))
+		for i := range positions {
+			positions[i] = strconv.Itoa(i + 1)
+		}
+		query.WriteString(" GROUP BY " + strings.Join(positions, ", "))
+		query.WriteString(" ORDER BY " + strings.Join(positions, ", "))
+	}
+
+	limit := d.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	// One extra row tells whether the result was truncated
+	query.WriteString(" LIMIT " + b.arg(limit+1))
+
+	return query.String(), b.args, columnNames, nil
+}
@@ -0,0 +1,143 @@
+package savedquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+)
+
+// Common errors
+var (
+	// ErrQueryNotFound is returned when a saved query does not exist
+	ErrQueryNotFound = errors.New("saved query not found")
+	// ErrInvalidQuery is returned when a query definition is not valid
+	ErrInvalidQuery = errors.New("invalid saved query")
+	// ErrInvalidParameter is returned when a run is missing a parameter or one has the wrong type
+	ErrInvalidParameter = errors.New("invalid query parameter")
+	// ErrForbidden is returned when the caller's role may not run a query
+	ErrForbidden = errors.New("role may not run this query")
+)
+
+// Guardrails applied to every query
+const (
+	// DefaultLimit is the number of rows returned when a query sets no limit
+	DefaultLimit = 1000
+	// MaxLimit is the most rows a query may return
+	MaxLimit = 10000
+	// MaxFilters is the most filters a query may have
+	MaxFilters = 20
+	// MaxGroupBy is the most fields a query may group by
+	MaxGroupBy = 3
+	// StatementTimeout bounds the run time of a single query
+	StatementTimeout = 10 * time.Second
+)
+
+// namePattern restricts query and parameter names to short, URL safe identifiers
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+
+// ValidName reports whether name can be used as a query name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// ParameterType is the type a query parameter is parsed as
+type ParameterType string
+
+const (
+	// ParameterString compares values as text
+	ParameterString ParameterType = "string"
+	// ParameterNumber compares values numerically
+	ParameterNumber ParameterType = "number"
+	// ParameterDate accepts YYYY-MM-DD or RFC 3339 timestamps
+	ParameterDate ParameterType = "date"
+)
+
+// Parameter is a value supplied in the query string when running a query
+type Parameter struct {
+	Name     string        `json:"name"`
+	Type     ParameterType `json:"type"`
+	Required bool          `json:"required,omitempty"`
+	// Default is used when the parameter is not supplied; without one, filters using an
+	// optional parameter are skipped
+	Default *string `json:"default,omitempty"`
+}
+
+// Filter restricts the records a query covers. Field is a column such as created_at or a
+// path into the record data such as data.household.size. The compared value is either
+// a parameter or a fixed JSON string or number.
+type Filter struct {
+	Field string          `json:"field"`
+	Op    string          `json:"op"`
+	Param string          `json:"param,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Aggregate computes the value of each result row. Func is count, count_distinct, sum,
+// avg, min or max; all but count need a field.
+type Aggregate struct {
+	Func  string `json:"func"`
+	Field string `json:"field,omitempty"`
+}
+
+// Definition is a structured query over the observations of one form type. Deleted records
+// and drafts are never included.
+type Definition struct {
+	FormType   string      `json:"form_type"`
+	Parameters []Parameter `json:"parameters,omitempty"`
+	Filters    []Filter    `json:"filters,omitempty"`
+	// GroupBy lists fields to group by; created_at and updated_at may be bucketed with a
+	// :day, :week, :month or :year suffix
+	GroupBy   []string  `json:"group_by,omitempty"`
+	Aggregate Aggregate `json:"aggregate"`
+	Limit     int       `json:"limit,omitempty"`
+	// Privacy tightens the server's privacy settings for this query
+	Privacy *Privacy `json:"privacy,omitempty"`
+}
+
+// Query is a named query admins save for dashboards
+type Query struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Definition  Definition `json:"definition"`
+	// Roles lists the roles besides admin that may run the query
+	Roles     []models.Role `json:"roles"`
+	UpdatedBy *string       `json:"updated_by,omitempty"`
+	CreatedAt string        `json:"created_at"`
+	UpdatedAt string        `json:"updated_at"`
+}
+
+// Result holds the rows of a query run, keyed by group by field and "value"
+type Result struct {
+	Name    string           `json:"name"`
+	Columns []string         `json:"columns"`
+	Rows    []map[string]any `json:"rows"`
+	// Truncated tells that more rows matched than the query limit
+	Truncated bool `json:"truncated"`
+	// Privacy holds the settings applied to the values, if any
+	Privacy *Privacy `json:"privacy,omitempty"`
+	// Suppressed counts the rows whose value was withheld for covering too few records
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Service manages saved queries and runs them
+type Service interface {
+	// List returns all saved queries ordered by name
+	List(ctx context.Context) ([]Query, error)
+
+	// Get returns a single saved query
+	Get(ctx context.Context, name string) (*Query, error)
+
+	// Save creates or replaces a saved query after validating its definition
+	Save(ctx context.Context, query Query, updatedBy string) (*Query, error)
+
+	// Delete removes a saved query
+	Delete(ctx context.Context, name string) error
+
+	// Run executes a saved query with the given parameters for a user, restricted to the
+	// user's org unit scope
+	Run(ctx context.Context, name string, params map[string]string, username string, role models.Role) (*Result, error)
+}
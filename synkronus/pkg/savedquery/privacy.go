@@ -0,0 +1,71 @@
+package savedquery
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Privacy protects the people behind small counts in results shared beyond data managers,
+// such as on public dashboards fed by a saved query
+type Privacy struct {
+	// MinGroupSize withholds the value of groups covering fewer records
+	MinGroupSize int `json:"min_group_size,omitempty"`
+	// Noise is the scale of the Laplace noise added to count, count_distinct and sum values;
+	// 0 adds none
+	Noise float64 `json:"noise,omitempty"`
+}
+
+// enabled reports whether any setting applies
+func (p Privacy) enabled() bool {
+	return p.MinGroupSize > 0 || p.Noise > 0
+}
+
+// stricter combines two sets of settings, keeping the stricter value of each
+func (p Privacy) stricter(other *Privacy) Privacy {
+	if other == nil {
+		return p
+	}
+	return Privacy{MinGroupSize: max(p.MinGroupSize, other.MinGroupSize), Noise: math.Max(p.Noise, other.Noise)}
+}
+
+// noisyAggregates lists the aggregates noise is added to. Noise of a fixed scale says little
+// about averages, minimums and maximums, so those are only protected by suppression.
+var noisyAggregates = map[string]bool{"count": true, "count_distinct": true, "sum": true}
+
+// countsRecords reports whether a compiled query selects the number of records of each group
+// besides its value. Counts are their own record count.
+func countsRecords(d Definition) bool {
+	return d.Privacy != nil && d.Privacy.MinGroupSize > 0 && d.Aggregate.Func != "count"
+}
+
+// laplace draws noise from a Laplace distribution centred on 0
+func laplace(scale float64) float64 {
+	u := rand.Float64() - 0.5
+	for u == -0.5 {
+		u = rand.Float64() - 0.5
+	}
+	if u < 0 {
+		return scale * math.Log(1+2*u)
+	}
+	return -scale * math.Log(1-2*u)
+}
+
+// protect applies the settings to a result row covering records records, withholding or
+// perturbing its value. It reports whether the value was withheld.
+func (p Privacy) protect(row map[string]any, records int64, aggregate string, noise func(scale float64) float64) bool {
+	if p.MinGroupSize > 0 && records < int64(p.MinGroupSize) {
+		row["value"] = nil
+		return true
+	}
+	if p.Noise <= 0 || !noisyAggregates[aggregate] {
+		return false
+	}
+	switch v := row["value"].(type) {
+	case int64:
+		// Counts stay whole and never drop below zero
+		row["value"] = max(0, int64(math.Round(float64(v)+noise(p.Noise))))
+	case float64:
+		row["value"] = v + noise(p.Noise)
+	}
+	return false
+}
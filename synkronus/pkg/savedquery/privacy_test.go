@@ -0,0 +1,65 @@
+package savedquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivacy_Stricter(t *testing.T) {
+	floor := Privacy{MinGroupSize: 5}
+	assert.Equal(t, floor, floor.stricter(nil))
+	assert.Equal(t, Privacy{MinGroupSize: 5, Noise: 2}, floor.stricter(&Privacy{MinGroupSize: 3, Noise: 2}))
+	assert.Equal(t, Privacy{MinGroupSize: 10}, floor.stricter(&Privacy{MinGroupSize: 10}))
+}
+
+func TestPrivacy_Protect(t *testing.T) {
+	noise := func(scale float64) float64 { return -2.4 * scale }
+	p := Privacy{MinGroupSize: 5, Noise: 1}
+
+	small := map[string]any{"data.village": "Kibera", "value": int64(3)}
+	assert.True(t, p.protect(small, 3, "count", noise))
+	assert.Nil(t, small["value"])
+	assert.Equal(t, "Kibera", small["data.village"], "group values are kept")
+
+	count := map[string]any{"value": int64(12)}
+	assert.False(t, p.protect(count, 12, "count", noise))
+	assert.Equal(t, int64(10), count["value"])
+
+	sum := map[string]any{"value": 40.0}
+	assert.False(t, p.protect(sum, 8, "sum", noise))
+	assert.InDelta(t, 37.6, sum["value"], 1e-9)
+
+	// Averages are only suppressed, and counts never go negative
+	avg := map[string]any{"value": 4.5}
+	assert.False(t, p.protect(avg, 8, "avg", noise))
+	assert.Equal(t, 4.5, avg["value"])
+	assert.True(t, p.protect(map[string]any{"value": 4.5}, 4, "avg", noise))
+
+	zero := map[string]any{"value": int64(1)}
+	assert.False(t, Privacy{Noise: 1}.protect(zero, 1, "count", noise))
+	assert.Equal(t, int64(0), zero["value"])
+}
+
+func TestLaplace(t *testing.T) {
+	var sum float64
+	for range 10000 {
+		sum += laplace(2)
+	}
+	assert.InDelta(t, 0, sum/10000, 0.2, "noise is centred on 0")
+}
+
+func TestCompile_RecordCount(t *testing.T) {
+	d := householdsByVillage()
+	d.Privacy = &Privacy{MinGroupSize: 5}
+	query, _, columns, err := compile(d, map[string]string{"since": "2025-01-01"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data.village", "created_at:month", "value"}, columns, "the record count is not a column")
+	assert.Contains(t, query, "::NUMERIC END)), COUNT(*) FROM observations")
+
+	// Counts are their own record count
+	query, _, _, err = compile(Definition{FormType: "household", Aggregate: Aggregate{Func: "count"}, Privacy: d.Privacy}, nil, "")
+	require.NoError(t, err)
+	assert.Contains(t, query, "SELECT COUNT(*) FROM observations")
+}
@@ -0,0 +1,265 @@
+package savedquery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// queryColumns lists the columns selected for a Query in scan order
+const queryColumns = "name, description, definition, roles, updated_by, created_at, updated_at"
+
+type service struct {
+	db      *sql.DB
+	privacy Privacy
+	log     *logger.Logger
+	// noise draws the noise added to values
+	noise func(scale float64) float64
+}
+
+// NewService creates a new saved query service. privacy is applied to every query run;
+// queries may only make it stricter.
+func NewService(db *sql.DB, privacy Privacy, log *logger.Logger) Service {
+	return &service{db: db, privacy: privacy, log: log, noise: laplace}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanQuery(row rowScanner) (*Query, error) {
+	var q Query
+	var definition []byte
+	var roles pq.StringArray
+	if err := row.Scan(&q.Name, &q.Description, &definition, &roles, &q.UpdatedBy, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(definition, &q.Definition); err != nil {
+		return nil, fmt.Errorf("failed to decode definition of %s: %w", q.Name, err)
+	}
+	q.Roles = make([]models.Role, 0, len(roles))
+	for _, role := range roles {
+		q.Roles = append(q.Roles, models.Role(role))
+	}
+	return &q, nil
+}
+
+// List returns all saved queries ordered by name
+func (s *service) List(ctx context.Context) ([]Query, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+queryColumns+" FROM saved_queries ORDER BY name")
+	if err != nil {
+		s.log.Error("Failed to query saved queries", "error", err)
+		return nil, fmt.Errorf("failed to query saved queries: %w", err)
+	}
+	defer rows.Close()
+
+	queries := make([]Query, 0)
+	for rows.Next() {
+		q, err := scanQuery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved query: %w", err)
+		}
+		queries = append(queries, *q)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return queries, nil
+}
+
+// Get returns a single saved query
+func (s *service) Get(ctx context.Context, name string) (*Query, error) {
+	q, err := scanQuery(s.db.QueryRowContext(ctx, "SELECT "+queryColumns+" FROM saved_queries WHERE name = $1", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQueryNotFound
+		}
+		s.log.Error("Failed to get saved query", "error", err, "name", name)
+		return nil, fmt.Errorf("failed to get saved query: %w", err)
+	}
+	return q, nil
+}
+
+// Save creates or replaces a saved query after validating its definition
+func (s *service) Save(ctx context.Context, query Query, updatedBy string) (*Query, error) {
+	if !ValidName(query.Name) {
+		return nil, fmt.Errorf("%w: name must be lowercase letters, digits, '_' or '-'", ErrInvalidQuery)
+	}
+	if err := query.Definition.Validate(); err != nil {
+		return nil, err
+	}
+	roles := make(pq.StringArray, 0, len(query.Roles))
+	for _, role := range query.Roles {
+		if role != models.RoleReadOnly && role != models.RoleReadWrite && role != models.RoleAdmin {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidQuery, role)
+		}
+		roles = append(roles, string(role))
+	}
+
+	definition, err := json.Marshal(query.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode definition: %w", err)
+	}
+
+	saved, err := scanQuery(s.db.QueryRowContext(ctx, `
+		INSERT INTO saved_queries (name, description, definition, roles, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (name)
+		DO UPDATE SET description = EXCLUDED.description, definition = EXCLUDED.definition,
+			roles = EXCLUDED.roles, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING `+queryColumns,
+		query.Name, query.Description, definition, roles, updatedBy,
+	))
+	if err != nil {
+		s.log.Error("Failed to save saved query", "error", err, "name", query.Name)
+		return nil, fmt.Errorf("failed to save saved query: %w", err)
+	}
+
+	s.log.Info("Saved query updated", "name", query.Name, "updatedBy", updatedBy)
+	return saved, nil
+}
+
+// Delete removes a saved query
+func (s *service) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM saved_queries WHERE name = $1", name)
+	if err != nil {
+		s.log.Error("Failed to delete saved query", "error", err, "name", name)
+		return fmt.Errorf("failed to delete saved query: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrQueryNotFound
+	}
+
+	s.log.Info("Saved query deleted", "name", name)
+	return nil
+}
+
+// allowed reports whether role may run query
+func allowed(query *Query, role models.Role) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+	for _, r := range query.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes a saved query with the given parameters for a user, restricted to the
+// user's org unit scope. Queries run in a read-only transaction with a statement timeout.
+func (s *service) Run(ctx context.Context, name string, params map[string]string, username string, role models.Role) (*Result, error) {
+	query, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed(query, role) {
+		return nil, ErrForbidden
+	}
+
+	definition := query.Definition
+	privacy := s.privacy.stricter(definition.Privacy)
+	definition.Privacy = &privacy
+
+	statement, args, columnNames, err := compile(definition, params, username)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Read-only, so rolling back is all that is ever needed
+	defer tx.Rollback()
+
+	timeout := strconv.FormatInt(StatementTimeout.Milliseconds(), 10)
+	if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = "+timeout); err != nil {
+		return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, statement, args...)
+	if err != nil {
+		s.log.Error("Failed to run saved query", "error", err, "name", name)
+		return nil, fmt.Errorf("failed to run saved query: %w", err)
+	}
+	defer rows.Close()
+
+	limit := query.Definition.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	scanned := len(columnNames)
+	if countsRecords(definition) {
+		scanned++
+	}
+	result := &Result{Name: name, Columns: columnNames, Rows: make([]map[string]any, 0)}
+	if privacy.enabled() {
+		result.Privacy = &privacy
+	}
+	for rows.Next() {
+		if len(result.Rows) == limit {
+			result.Truncated = true
+			break
+		}
+		values := make([]any, scanned)
+		dest := make([]any, scanned)
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan result row: %w", err)
+		}
+		row := make(map[string]any, len(columnNames))
+		for i, column := range columnNames {
+			row[column] = resultValue(values[i])
+		}
+		if privacy.enabled() {
+			// The record count is the last column selected, or the value of counts
+			records, _ := values[scanned-1].(int64)
+			if privacy.protect(row, records, definition.Aggregate.Func, s.noise) {
+				result.Suppressed++
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	s.log.Info("Saved query run", "name", name, "username", username, "rows", len(result.Rows), "suppressed", result.Suppressed)
+	return result, nil
+}
+
+// resultValue converts a scanned value to its JSON form; NUMERIC arrives as text
+func resultValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		if n, err := strconv.ParseFloat(string(v), 64); err == nil {
+			return n
+		}
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,124 @@
+package savedquery
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func householdsByVillage() Definition {
+	return Definition{
+		FormType: "household",
+		Parameters: []Parameter{
+			{Name: "since", Type: ParameterDate, Required: true},
+			{Name: "min-size", Type: ParameterNumber},
+			{Name: "region", Type: ParameterString, Default: strPtr("north")},
+		},
+		Filters: []Filter{
+			{Field: "created_at", Op: "gte", Param: "since"},
+			{Field: "data.household.size", Op: "gte", Param: "min-size"},
+			{Field: "data.region", Op: "eq", Param: "region"},
+			{Field: "data.status", Op: "ne", Value: json.RawMessage(`"refused"`)},
+		},
+		GroupBy:   []string{"data.village", "created_at:month"},
+		Aggregate: Aggregate{Func: "sum", Field: "data.household.size"},
+		Limit:     50,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, householdsByVillage().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(d *Definition)
+	}{
+		{"missing form type", func(d *Definition) { d.FormType = "" }},
+		{"unknown column", func(d *Definition) { d.Filters[0].Field = "password_hash" }},
+		{"path injection", func(d *Definition) { d.Filters[1].Field = "data.size'); DROP TABLE users; --" }},
+		{"unknown op", func(d *Definition) { d.Filters[0].Op = "like" }},
+		{"undeclared param", func(d *Definition) { d.Filters[0].Param = "until" }},
+		{"param and value", func(d *Definition) { d.Filters[0].Value = json.RawMessage(`"2025-01-01"`) }},
+		{"object value", func(d *Definition) { d.Filters[3].Value = json.RawMessage(`{"a":1}`) }},
+		{"contains number", func(d *Definition) { d.Filters[1].Op = "contains" }},
+		{"bad default", func(d *Definition) { d.Parameters[1].Default = strPtr("many") }},
+		{"bad bucket", func(d *Definition) { d.GroupBy[1] = "created_at:hour" }},
+		{"bucket on data", func(d *Definition) { d.GroupBy[0] = "data.village:month" }},
+		{"too many group by", func(d *Definition) { d.GroupBy = []string{"owner", "created_by", "case_id", "form_version"} }},
+		{"unknown aggregate", func(d *Definition) { d.Aggregate.Func = "median" }},
+		{"aggregate without field", func(d *Definition) { d.Aggregate.Field = "" }},
+		{"limit too high", func(d *Definition) { d.Limit = MaxLimit + 1 }},
+		{"negative group size", func(d *Definition) { d.Privacy = &Privacy{MinGroupSize: -1} }},
+		{"noise on average", func(d *Definition) { d.Aggregate.Func = "avg"; d.Privacy = &Privacy{Noise: 1} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := householdsByVillage()
+			tt.modify(&d)
+			assert.ErrorIs(t, d.Validate(), ErrInvalidQuery)
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	query, args, columns, err := compile(householdsByVillage(), map[string]string{"since": "2025-01-01"}, "alice")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"data.village", "created_at:month", "value"}, columns)
+	assert.Equal(t, "SELECT (data #>> $1::TEXT[]), date_trunc('month', created_at), "+
+		"SUM((CASE WHEN (data #>> $2::TEXT[]) ~ "+numericPattern+" THEN ((data #>> $2::TEXT[]))::NUMERIC END)) "+
+		"FROM observations WHERE form_type = $3 AND NOT deleted AND NOT draft "+
+		"AND created_at >= $4::TIMESTAMPTZ "+
+		"AND (data #>> $5::TEXT[]) = $6::TEXT "+
+		"AND (data #>> $7::TEXT[]) <> $8::TEXT "+
+		"AND (org_unit_id IS NULL OR NOT EXISTS (SELECT 1 FROM user_org_units WHERE username = $9) "+
+		"OR org_unit_id IN (SELECT child.id FROM user_org_units uou JOIN org_units parent ON parent.id = uou.org_unit_id "+
+		"JOIN org_units child ON child.path LIKE parent.path || '%' WHERE uou.username = $9)) "+
+		"GROUP BY 1, 2 ORDER BY 1, 2 LIMIT $10", query)
+
+	// The optional min-size filter is skipped and region falls back to its default
+	require.Len(t, args, 10)
+	assert.Equal(t, pq.Array([]string{"village"}), args[0])
+	assert.Equal(t, "household", args[2])
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), args[3])
+	assert.Equal(t, "north", args[5])
+	assert.Equal(t, "refused", args[7])
+	assert.Equal(t, "alice", args[8])
+	assert.Equal(t, 51, args[9])
+}
+
+func TestCompile_Parameters(t *testing.T) {
+	d := householdsByVillage()
+
+	_, _, _, err := compile(d, map[string]string{}, "alice")
+	assert.ErrorIs(t, err, ErrInvalidParameter)
+
+	_, _, _, err = compile(d, map[string]string{"since": "last week"}, "alice")
+	assert.ErrorIs(t, err, ErrInvalidParameter)
+
+	_, _, _, err = compile(d, map[string]string{"since": "2025-01-01", "min-size": "big"}, "alice")
+	assert.ErrorIs(t, err, ErrInvalidParameter)
+
+	query, args, _, err := compile(d, map[string]string{"since": "2025-01-01T00:00:00Z", "min-size": "4"}, "alice")
+	require.NoError(t, err)
+	assert.Contains(t, query, ") >= $6::NUMERIC")
+	assert.Equal(t, 4.0, args[5])
+}
+
+func TestCompile_Count(t *testing.T) {
+	query, args, columns, err := compile(Definition{FormType: "household", Aggregate: Aggregate{Func: "count"}}, nil, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"value"}, columns)
+	assert.Equal(t, "SELECT COUNT(*) FROM observations WHERE form_type = $1 AND NOT deleted AND NOT draft LIMIT $2", query)
+	assert.Equal(t, []any{"household", DefaultLimit + 1}, args)
+}
@@ -0,0 +1,61 @@
+// Package secretbox encrypts small values kept in the database, such as private keys and
+// credentials, with keys derived from the server's secrets
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// ErrNoSecret is returned when none of the secrets decrypts a sealed value
+var ErrNoSecret = errors.New("no secret decrypts the value")
+
+// key derives the AES key of a purpose, e.g. "synkronus signing key:", from a secret, so the
+// same secret yields different keys for different kinds of values
+func key(purpose string, secret []byte) []byte {
+	sum := sha256.Sum256(append([]byte(purpose), secret...))
+	return sum[:]
+}
+
+// Seal encrypts a value with AES-GCM, prefixing the nonce
+func Seal(purpose string, secret, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key(purpose, secret))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts a sealed value with the first secret that fits, so values sealed with a former
+// secret stay readable after it is rotated
+func Open(purpose string, secrets [][]byte, sealed []byte) ([]byte, error) {
+	for _, secret := range secrets {
+		block, err := aes.NewCipher(key(purpose, secret))
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return nil, errors.New("sealed value too short")
+		}
+		nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		if plaintext, err := gcm.Open(nil, nonce, ciphertext, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrNoSecret
+}
@@ -0,0 +1,23 @@
+package secretbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	sealed, err := Seal("test:", []byte("old secret"), []byte("password"))
+	require.NoError(t, err)
+
+	// A rotated secret still opens values sealed with the former one
+	plaintext, err := Open("test:", [][]byte{[]byte("new secret"), []byte("old secret")}, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "password", string(plaintext))
+
+	_, err = Open("test:", [][]byte{[]byte("new secret")}, sealed)
+	assert.ErrorIs(t, err, ErrNoSecret)
+	_, err = Open("other:", [][]byte{[]byte("old secret")}, sealed)
+	assert.ErrorIs(t, err, ErrNoSecret)
+}
@@ -0,0 +1,297 @@
+// Package seed applies declarative seed files of users, org units and settings, so demo and CI
+// environments come up configured. Seeding only adds what is missing: existing users, org units
+// and settings are left as they are, so seed files can be applied at every startup.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/orgunit"
+	"github.com/opendataensemble/synkronus/pkg/settings"
+	"github.com/opendataensemble/synkronus/pkg/user"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidSeed is returned when a seed file cannot be applied as written
+var ErrInvalidSeed = errors.New("invalid seed")
+
+// updatedBy is recorded as the author of seeded settings
+const updatedBy = "seed"
+
+// Seed is the content of one or more seed files
+type Seed struct {
+	// OrgUnits are matched by code; a parent is named by its code
+	OrgUnits []OrgUnit `yaml:"org_units"`
+	Users    []User    `yaml:"users"`
+	// Settings are set when the key has no value yet
+	Settings map[string]any `yaml:"settings"`
+}
+
+// OrgUnit is a seeded org unit
+type OrgUnit struct {
+	Code   string `yaml:"code"`
+	Name   string `yaml:"name"`
+	Level  string `yaml:"level"`
+	Parent string `yaml:"parent"`
+}
+
+// User is a seeded user, created with the password and role when the username is free. The
+// user is assigned to the org units named by their codes.
+type User struct {
+	Username string      `yaml:"username"`
+	Password string      `yaml:"password"`
+	Role     models.Role `yaml:"role"`
+	OrgUnits []string    `yaml:"org_units"`
+}
+
+// Result counts what applying a seed created
+type Result struct {
+	OrgUnits int
+	Users    int
+	// Assignments counts the org unit assignments of seeded users, including existing ones
+	Assignments int
+	Settings    int
+}
+
+// envReference matches ${NAME} references to environment variables in seed files
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Load reads seed files, or directories of .yaml, .yml and .json files applied in name order,
+// and merges them in order. ${NAME} in a file is replaced by the environment variable NAME, so
+// passwords need not be committed with the seed.
+func Load(paths ...string) (*Seed, error) {
+	merged := &Seed{Settings: make(map[string]any)}
+	for _, path := range paths {
+		files, err := seedFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			seed, err := loadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			merged.OrgUnits = append(merged.OrgUnits, seed.OrgUnits...)
+			merged.Users = append(merged.Users, seed.Users...)
+			for key, value := range seed.Settings {
+				merged.Settings[key] = value
+			}
+		}
+	}
+	if err := merged.validate(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// seedFiles lists the seed files of a path
+func seedFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadFile parses a YAML or JSON seed file; JSON is read as YAML
+func loadFile(path string) (*Seed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+	var missing []string
+	data = envReference.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := string(envReference.FindSubmatch(ref)[1])
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s references unset environment variables %s", ErrInvalidSeed, path, strings.Join(missing, ", "))
+	}
+
+	var seed Seed
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&seed); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSeed, path, err)
+	}
+	return &seed, nil
+}
+
+// validate checks a seed before anything is applied
+func (s *Seed) validate() error {
+	codes := make(map[string]bool)
+	for _, unit := range s.OrgUnits {
+		if unit.Code == "" || strings.TrimSpace(unit.Name) == "" {
+			return fmt.Errorf("%w: org units need a code and a name", ErrInvalidSeed)
+		}
+		if codes[unit.Code] {
+			return fmt.Errorf("%w: org unit code %q is seeded twice", ErrInvalidSeed, unit.Code)
+		}
+		codes[unit.Code] = true
+	}
+	usernames := make(map[string]bool)
+	for _, u := range s.Users {
+		if u.Username == "" || u.Password == "" {
+			return fmt.Errorf("%w: users need a username and a password", ErrInvalidSeed)
+		}
+		if u.Role != models.RoleReadOnly && u.Role != models.RoleReadWrite && u.Role != models.RoleAdmin {
+			return fmt.Errorf("%w: user %s has role %q; use read-only, read-write or admin", ErrInvalidSeed, u.Username, u.Role)
+		}
+		if usernames[u.Username] {
+			return fmt.Errorf("%w: user %s is seeded twice", ErrInvalidSeed, u.Username)
+		}
+		usernames[u.Username] = true
+	}
+	for key := range s.Settings {
+		if !settings.ValidKey(key) {
+			return fmt.Errorf("%w: setting key %q", ErrInvalidSeed, key)
+		}
+	}
+	return nil
+}
+
+// Seeder applies seeds through the services that manage the seeded data
+type Seeder struct {
+	users    user.UserServiceInterface
+	orgUnits orgunit.Service
+	settings settings.Service
+	log      *logger.Logger
+}
+
+// NewSeeder creates a new seeder
+func NewSeeder(users user.UserServiceInterface, orgUnits orgunit.Service, settings settings.Service, log *logger.Logger) *Seeder {
+	return &Seeder{users: users, orgUnits: orgUnits, settings: settings, log: log}
+}
+
+// Apply creates the org units, users, org unit assignments and settings of a seed that do not
+// exist yet. Org units come first, parents before their children whatever the order in the
+// seed, so users can be assigned to them.
+func (s *Seeder) Apply(ctx context.Context, seed *Seed) (*Result, error) {
+	result := &Result{}
+
+	units, err := s.orgUnits.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list org units: %w", err)
+	}
+	idByCode := make(map[string]string, len(units))
+	for _, unit := range units {
+		if unit.Code != nil {
+			idByCode[*unit.Code] = unit.ID
+		}
+	}
+	pending := seed.OrgUnits
+	for len(pending) > 0 {
+		var waiting []OrgUnit
+		for _, unit := range pending {
+			if _, exists := idByCode[unit.Code]; exists {
+				continue
+			}
+			input := orgunit.OrgUnitInput{Name: unit.Name, Code: stringPtr(unit.Code), Level: stringPtr(unit.Level)}
+			if unit.Parent != "" {
+				parentID, ok := idByCode[unit.Parent]
+				if !ok {
+					waiting = append(waiting, unit)
+					continue
+				}
+				input.ParentID = &parentID
+			}
+			created, err := s.orgUnits.Create(ctx, input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create org unit %s: %w", unit.Code, err)
+			}
+			idByCode[unit.Code] = created.ID
+			result.OrgUnits++
+		}
+		if len(waiting) == len(pending) {
+			return nil, fmt.Errorf("%w: org unit %s has unknown parent %s", ErrInvalidSeed, waiting[0].Code, waiting[0].Parent)
+		}
+		pending = waiting
+	}
+
+	for _, u := range seed.Users {
+		_, err := s.users.CreateUser(ctx, u.Username, u.Password, u.Role)
+		switch {
+		case err == nil:
+			result.Users++
+		case !errors.Is(err, user.ErrUserExists):
+			return nil, fmt.Errorf("failed to create user %s: %w", u.Username, err)
+		}
+		for _, code := range u.OrgUnits {
+			id, ok := idByCode[code]
+			if !ok {
+				return nil, fmt.Errorf("%w: user %s is assigned to unknown org unit %s", ErrInvalidSeed, u.Username, code)
+			}
+			if err := s.orgUnits.AssignUser(ctx, id, u.Username); err != nil {
+				return nil, fmt.Errorf("failed to assign user %s to org unit %s: %w", u.Username, code, err)
+			}
+			result.Assignments++
+		}
+	}
+
+	keys := make([]string, 0, len(seed.Settings))
+	for key := range seed.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, err := s.settings.Get(ctx, key)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, settings.ErrSettingNotFound) {
+			return nil, fmt.Errorf("failed to get setting %s: %w", key, err)
+		}
+		value, err := json.Marshal(seed.Settings[key])
+		if err != nil {
+			return nil, fmt.Errorf("%w: setting %s: %v", ErrInvalidSeed, key, err)
+		}
+		if _, err := s.settings.Set(ctx, key, value, updatedBy); err != nil {
+			return nil, fmt.Errorf("failed to set setting %s: %w", key, err)
+		}
+		result.Settings++
+	}
+
+	s.log.Info("Applied seed", "orgUnits", result.OrgUnits, "users", result.Users, "assignments", result.Assignments, "settings", result.Settings)
+	return result, nil
+}
+
+// stringPtr returns nil for an empty string
+func stringPtr(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
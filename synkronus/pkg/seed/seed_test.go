@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/internal/handlers/mocks"
+	"github.com/opendataensemble/synkronus/internal/models"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const demoSeed = `
+org_units:
+  - code: arusha-dc
+    name: Arusha DC
+    level: district
+    parent: arusha
+  - code: arusha
+    name: Arusha
+    level: region
+users:
+  - username: enumerator1
+    password: ${SEED_TEST_PASSWORD}
+    role: read-write
+    org_units: [arusha-dc]
+settings:
+  reporting.period_label: Month
+`
+
+func writeSeed(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeSeed(t, dir, "01-demo.yaml", demoSeed)
+	writeSeed(t, dir, "02-admins.json", `{"users": [{"username": "supervisor", "password": "secret", "role": "admin"}], "settings": {"reporting.period_label": "Week"}}`)
+	writeSeed(t, dir, "README.md", "not a seed")
+
+	_, err := Load(dir)
+	assert.ErrorIs(t, err, ErrInvalidSeed, "unset environment variables are refused")
+
+	t.Setenv("SEED_TEST_PASSWORD", "enumerator-secret")
+	seed, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, seed.Users, 2)
+	assert.Equal(t, "enumerator-secret", seed.Users[0].Password)
+	assert.Equal(t, models.RoleAdmin, seed.Users[1].Role)
+	assert.Equal(t, "Week", seed.Settings["reporting.period_label"], "later files win")
+
+	for name, content := range map[string]string{
+		"role.yaml":      "users: [{username: a, password: b, role: owner}]",
+		"unknown.yaml":   "groups: []",
+		"duplicate.yaml": "org_units: [{code: a, name: A}, {code: a, name: B}]",
+		"key.yaml":       "settings: {Bad Key: 1}",
+	} {
+		_, err := Load(writeSeed(t, t.TempDir(), name, content))
+		assert.ErrorIs(t, err, ErrInvalidSeed, name)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SEED_TEST_PASSWORD", "enumerator-secret")
+	seed, err := Load(writeSeed(t, t.TempDir(), "demo.yaml", demoSeed))
+	require.NoError(t, err)
+
+	users := mocks.NewMockUserService()
+	orgUnits := mocks.NewMockOrgUnitService()
+	settingsService := mocks.NewMockSettingsService()
+	seeder := NewSeeder(users, orgUnits, settingsService, logger.NewLogger())
+
+	result, err := seeder.Apply(ctx, seed)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{OrgUnits: 2, Users: 1, Assignments: 1, Settings: 1}, result)
+
+	units, err := orgUnits.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, units, 2)
+	assert.Equal(t, "arusha", *units[0].Code)
+	assert.Equal(t, units[0].ID, *units[1].ParentID)
+	assigned, err := orgUnits.ListUsers(ctx, units[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"enumerator1"}, assigned)
+	setting, err := settingsService.Get(ctx, "reporting.period_label")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"Month"`, string(setting.Value))
+
+	// Applying again leaves existing data alone, including settings changed since
+	_, err = settingsService.Set(ctx, "reporting.period_label", json.RawMessage(`"Quarter"`), "admin")
+	require.NoError(t, err)
+	result, err = seeder.Apply(ctx, seed)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Assignments: 1}, result)
+	setting, err = settingsService.Get(ctx, "reporting.period_label")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"Quarter"`, string(setting.Value))
+
+	// Parents must be seeded or exist already
+	seed.OrgUnits = []OrgUnit{{Code: "moshi", Name: "Moshi", Parent: "kilimanjaro"}}
+	_, err = seeder.Apply(ctx, seed)
+	assert.ErrorIs(t, err, ErrInvalidSeed)
+}
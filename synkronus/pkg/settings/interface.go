@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+// Common errors
+var (
+	// ErrSettingNotFound is returned when a setting key does not exist
+	ErrSettingNotFound = errors.New("setting not found")
+	// ErrInvalidKey is returned when a setting key is not allowed
+	ErrInvalidKey = errors.New("invalid setting key")
+	// ErrInvalidValue is returned when a setting value is not valid JSON
+	ErrInvalidValue = errors.New("invalid setting value")
+)
+
+// keyPattern restricts keys to short, URL safe identifiers such as "reporting.period_label"
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// ValidKey reports whether key can be used as a setting key
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Setting is a deployment level key-value pair
+type Setting struct {
+	Key       string          `json:"key" db:"key"`
+	Value     json.RawMessage `json:"value" db:"value"`
+	UpdatedBy *string         `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt string          `json:"updated_at" db:"updated_at"`
+}
+
+// Service manages deployment level settings
+type Service interface {
+	// List returns all settings ordered by key
+	List(ctx context.Context) ([]Setting, error)
+
+	// Get returns a single setting
+	Get(ctx context.Context, key string) (*Setting, error)
+
+	// Set creates or replaces a setting
+	Set(ctx context.Context, key string, value json.RawMessage, updatedBy string) (*Setting, error)
+
+	// Delete removes a setting
+	Delete(ctx context.Context, key string) error
+}
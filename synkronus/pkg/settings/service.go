@@ -0,0 +1,109 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new settings service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// List returns all settings ordered by key
+func (s *service) List(ctx context.Context) ([]Setting, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT key, value, updated_by, updated_at FROM deployment_settings ORDER BY key")
+	if err != nil {
+		s.log.Error("Failed to query settings", "error", err)
+		return nil, fmt.Errorf("failed to query settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make([]Setting, 0)
+	for rows.Next() {
+		var setting Setting
+		if err := rows.Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		settings = append(settings, setting)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return settings, nil
+}
+
+// Get returns a single setting
+func (s *service) Get(ctx context.Context, key string) (*Setting, error) {
+	var setting Setting
+	err := s.db.QueryRowContext(ctx,
+		"SELECT key, value, updated_by, updated_at FROM deployment_settings WHERE key = $1", key,
+	).Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSettingNotFound
+		}
+		s.log.Error("Failed to get setting", "error", err, "key", key)
+		return nil, fmt.Errorf("failed to get setting: %w", err)
+	}
+	return &setting, nil
+}
+
+// Set creates or replaces a setting
+func (s *service) Set(ctx context.Context, key string, value json.RawMessage, updatedBy string) (*Setting, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+	if !json.Valid(value) {
+		return nil, ErrInvalidValue
+	}
+
+	var setting Setting
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO deployment_settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (key)
+		DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING key, value, updated_by, updated_at`,
+		key, []byte(value), updatedBy,
+	).Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt)
+	if err != nil {
+		s.log.Error("Failed to save setting", "error", err, "key", key)
+		return nil, fmt.Errorf("failed to save setting: %w", err)
+	}
+
+	s.log.Info("Setting updated", "key", key, "updatedBy", updatedBy)
+	return &setting, nil
+}
+
+// Delete removes a setting
+func (s *service) Delete(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM deployment_settings WHERE key = $1", key)
+	if err != nil {
+		s.log.Error("Failed to delete setting", "error", err, "key", key)
+		return fmt.Errorf("failed to delete setting: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrSettingNotFound
+	}
+
+	s.log.Info("Setting deleted", "key", key)
+	return nil
+}
@@ -0,0 +1,186 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// unscopedKey is the context key marking a pull exempt from the pull scope
+const unscopedKey contextKey = "syncUnscoped"
+
+// WithUnscopedPull returns a context whose pulls return every record regardless of the pull
+// scope, as admins' pulls do. Drafts and org unit scopes still apply.
+func WithUnscopedPull(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey, true)
+}
+
+// IsUnscopedPull reports whether WithUnscopedPull marked the context
+func IsUnscopedPull(ctx context.Context) bool {
+	unscoped, _ := ctx.Value(unscopedKey).(bool)
+	return unscoped
+}
+
+// assignedOnly reports whether a pull in ctx is limited to the user's own and assigned records
+func (s *Service) assignedOnly(ctx context.Context) bool {
+	return s.config.PullScope == PullScopeAssigned && UsernameFromContext(ctx) != "" && !IsUnscopedPull(ctx)
+}
+
+// assignmentFilterSQL restricts the observations of table to those the user bound to argument n
+// owns or is assigned, directly or through an org unit within the user's scope
+func assignmentFilterSQL(table string, n int) string {
+	placeholder := "$" + strconv.Itoa(n)
+	return " AND (" + table + ".owner = " + placeholder +
+		" OR EXISTS (SELECT 1 FROM observation_assignments oa WHERE oa.observation_id = " + table + ".observation_id" +
+		" AND (oa.username = " + placeholder + " OR oa.org_unit_id IN (" + orgUnitScopeSQL(placeholder) + "))))"
+}
+
+// Validate checks that the request names observations and exactly one assignee
+func (r AssignmentRequest) Validate() error {
+	if len(r.ObservationIDs) == 0 {
+		return fmt.Errorf("%w: observation_ids is required", ErrInvalidData)
+	}
+	if (r.Username == "") == (r.OrgUnitID == "") {
+		return fmt.Errorf("%w: exactly one of username and org_unit_id is required", ErrInvalidData)
+	}
+	if r.OrgUnitID != "" {
+		if _, err := uuid.Parse(r.OrgUnitID); err != nil {
+			return fmt.Errorf("%w: unknown org unit %q", ErrInvalidData, r.OrgUnitID)
+		}
+	}
+	return nil
+}
+
+// assignee returns the username and org unit columns of the request's assignee
+func (r AssignmentRequest) assignee() (sql.NullString, sql.NullString) {
+	return sql.NullString{String: r.Username, Valid: r.Username != ""},
+		sql.NullString{String: r.OrgUnitID, Valid: r.OrgUnitID != ""}
+}
+
+// ListAssignments returns the observation assignments matching the filter, newest first
+func (s *Service) ListAssignments(ctx context.Context, filter AssignmentFilter) ([]Assignment, error) {
+	var queryBuilder strings.Builder
+	var args []interface{}
+	queryBuilder.WriteString(`
+		SELECT observation_id, username, org_unit_id, assigned_by, assigned_at
+		FROM observation_assignments WHERE TRUE`)
+	if filter.ObservationID != "" {
+		args = append(args, filter.ObservationID)
+		queryBuilder.WriteString(" AND observation_id = $" + strconv.Itoa(len(args)))
+	}
+	if filter.Username != "" {
+		args = append(args, filter.Username)
+		queryBuilder.WriteString(" AND username = $" + strconv.Itoa(len(args)))
+	}
+	if filter.OrgUnitID != "" {
+		if _, err := uuid.Parse(filter.OrgUnitID); err != nil {
+			return nil, fmt.Errorf("%w: unknown org unit %q", ErrInvalidData, filter.OrgUnitID)
+		}
+		args = append(args, filter.OrgUnitID)
+		queryBuilder.WriteString(" AND org_unit_id = $" + strconv.Itoa(len(args)))
+	}
+	queryBuilder.WriteString(" ORDER BY assigned_at DESC, id DESC")
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		s.log.Error("Failed to list observation assignments", "error", err)
+		return nil, fmt.Errorf("failed to list observation assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := make([]Assignment, 0)
+	for rows.Next() {
+		var a Assignment
+		var username, orgUnitID sql.NullString
+		if err := rows.Scan(&a.ObservationID, &username, &orgUnitID, &a.AssignedBy, &a.AssignedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan observation assignment: %w", err)
+		}
+		if username.Valid {
+			a.Username = &username.String
+		}
+		if orgUnitID.Valid {
+			a.OrgUnitID = &orgUnitID.String
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// AssignObservations assigns observations to a user or org unit. Unknown observations and
+// existing assignments are skipped. Newly assigned records get a new version through the
+// version trigger, so the assignee's devices pull them even if they lie before their last pull.
+func (s *Service) AssignObservations(ctx context.Context, req AssignmentRequest, assignedBy string) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	username, orgUnitID := req.assignee()
+	result, err := s.db.ExecContext(ctx, `
+		WITH assigned AS (
+			INSERT INTO observation_assignments (observation_id, username, org_unit_id, assigned_by)
+			SELECT observation_id, $2, $3::UUID, $4 FROM observations WHERE observation_id = ANY($1)
+			ON CONFLICT DO NOTHING
+			RETURNING observation_id
+		)
+		UPDATE observations SET owner = owner
+		WHERE observation_id IN (SELECT observation_id FROM assigned)`,
+		pq.Array(req.ObservationIDs), username, orgUnitID, assignedBy)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return 0, fmt.Errorf("%w: unknown org unit %q", ErrInvalidData, req.OrgUnitID)
+		}
+		s.log.Error("Failed to assign observations", "error", err)
+		return 0, fmt.Errorf("failed to assign observations: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get assigned count: %w", err)
+	}
+
+	s.log.Info("Assigned observations",
+		"username", req.Username,
+		"orgUnitId", req.OrgUnitID,
+		"assignedBy", assignedBy,
+		"recordCount", count)
+
+	return count, nil
+}
+
+// UnassignObservations removes assignments of observations to a user or org unit. Devices that
+// already pulled the records keep them; they only stop receiving later changes.
+func (s *Service) UnassignObservations(ctx context.Context, req AssignmentRequest) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	username, orgUnitID := req.assignee()
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM observation_assignments
+		WHERE observation_id = ANY($1)
+		  AND username IS NOT DISTINCT FROM $2 AND org_unit_id IS NOT DISTINCT FROM $3::UUID`,
+		pq.Array(req.ObservationIDs), username, orgUnitID)
+	if err != nil {
+		s.log.Error("Failed to unassign observations", "error", err)
+		return 0, fmt.Errorf("failed to unassign observations: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unassigned count: %w", err)
+	}
+
+	s.log.Info("Unassigned observations",
+		"username", req.Username,
+		"orgUnitId", req.OrgUnitID,
+		"recordCount", count)
+
+	return count, nil
+}
@@ -0,0 +1,32 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAssignmentRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     AssignmentRequest
+		wantErr bool
+	}{
+		{"user", AssignmentRequest{ObservationIDs: []string{"obs-1"}, Username: "amina"}, false},
+		{"org unit", AssignmentRequest{ObservationIDs: []string{"obs-1"}, OrgUnitID: "8f14e45f-ceea-467f-a3b4-1b2c3d4e5f60"}, false},
+		{"no observations", AssignmentRequest{Username: "amina"}, true},
+		{"no assignee", AssignmentRequest{ObservationIDs: []string{"obs-1"}}, true},
+		{"both assignees", AssignmentRequest{ObservationIDs: []string{"obs-1"}, Username: "amina", OrgUnitID: "8f14e45f-ceea-467f-a3b4-1b2c3d4e5f60"}, true},
+		{"malformed org unit", AssignmentRequest{ObservationIDs: []string{"obs-1"}, OrgUnitID: "team-a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidData) {
+				t.Errorf("Validate() error = %v, want ErrInvalidData", err)
+			}
+		})
+	}
+}
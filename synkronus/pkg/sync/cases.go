@@ -0,0 +1,289 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// caseColumns lists the columns selected for a Case in scan order
+const caseColumns = "case_id, case_type, status, data, created_at, updated_at, closed_at, deleted, version, created_by, org_unit_id"
+
+// scanCase reads a single case row selected in caseColumns order
+func scanCase(row interface{ Scan(dest ...any) error }) (*Case, error) {
+	var c Case
+	var closedAt sql.NullString
+	err := row.Scan(&c.CaseID, &c.CaseType, &c.Status, &c.Data, &c.CreatedAt, &c.UpdatedAt,
+		&closedAt, &c.Deleted, &c.Version, &c.CreatedBy, &c.OrgUnitID)
+	if err != nil {
+		return nil, err
+	}
+	if closedAt.Valid {
+		c.ClosedAt = &closedAt.String
+	}
+	return &c, nil
+}
+
+// GetCasesSinceVersion retrieves cases that have changed since the specified version.
+// Users assigned to org units only receive cases within their scope.
+func (s *Service) GetCasesSinceVersion(ctx context.Context, sinceVersion int64, caseTypes []string, limit int) (*CaseSyncResult, error) {
+	currentVersion, err := s.GetCurrentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = s.config.DefaultLimit
+	}
+	if limit > s.config.MaxRecordsPerSync {
+		limit = s.config.MaxRecordsPerSync
+	}
+
+	var queryBuilder strings.Builder
+	args := []interface{}{sinceVersion, currentVersion}
+
+	// Cases committed after the current version was read are left to the next pull
+	queryBuilder.WriteString("SELECT " + caseColumns + " FROM cases WHERE version > $1 AND version <= $2")
+
+	if len(caseTypes) > 0 {
+		args = append(args, pq.Array(caseTypes))
+		queryBuilder.WriteString(" AND case_type = ANY($" + strconv.Itoa(len(args)) + ")")
+	}
+
+	if username := UsernameFromContext(ctx); username != "" {
+		args = append(args, username)
+		queryBuilder.WriteString(orgUnitFilterSQL(len(args)))
+	}
+
+	args = append(args, limit+1)
+	queryBuilder.WriteString(" ORDER BY version ASC, case_id ASC LIMIT $" + strconv.Itoa(len(args)))
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		s.log.Error("Failed to query cases", "error", err)
+		return nil, fmt.Errorf("failed to query cases: %w", err)
+	}
+	defer rows.Close()
+
+	cases := make([]Case, 0)
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			s.log.Error("Failed to scan case row", "error", err)
+			return nil, fmt.Errorf("failed to scan case: %w", err)
+		}
+		cases = append(cases, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	hasMore := len(cases) > limit
+	if hasMore {
+		cases = cases[:limit]
+	}
+
+	changeCutoff := sinceVersion
+	if len(cases) > 0 {
+		changeCutoff = cases[len(cases)-1].Version
+	}
+
+	s.log.Info("Retrieved cases since version",
+		"sinceVersion", sinceVersion,
+		"currentVersion", currentVersion,
+		"caseCount", len(cases),
+		"hasMore", hasMore)
+
+	return &CaseSyncResult{
+		CurrentVersion: currentVersion,
+		Cases:          cases,
+		ChangeCutoff:   changeCutoff,
+		HasMore:        hasMore,
+	}, nil
+}
+
+// ProcessPushedCases creates or updates cases pushed from a client. Invalid cases are
+// reported in FailedRecords without affecting the others.
+func (s *Service) ProcessPushedCases(ctx context.Context, cases []Case, clientID string) (*SyncPushResult, error) {
+	var successCount int
+	var failedRecords []map[string]interface{}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	username := UsernameFromContext(ctx)
+	var pushedBy *string
+	if username != "" {
+		pushedBy = &username
+	}
+
+	fail := func(i int, c Case, message string) {
+		failedRecords = append(failedRecords, map[string]interface{}{
+			"index":  i,
+			"error":  message,
+			"record": c,
+		})
+	}
+
+	for i, c := range cases {
+		if c.CaseID == "" {
+			fail(i, c, "case_id is required")
+			continue
+		}
+		if c.CaseType == "" {
+			fail(i, c, "case_type is required")
+			continue
+		}
+		if c.Status == "" {
+			c.Status = CaseStatusOpen
+		}
+		if c.Status != CaseStatusOpen && c.Status != CaseStatusClosed {
+			fail(i, c, fmt.Sprintf("status must be %q or %q", CaseStatusOpen, CaseStatusClosed))
+			continue
+		}
+		if len(c.Data) == 0 {
+			c.Data = json.RawMessage(`{}`)
+		}
+
+		orgUnitID, defaultOrgUnitID, err := s.resolveOrgUnit(ctx, tx, username, c.OrgUnitID)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidData) {
+				return nil, err
+			}
+			fail(i, c, err.Error())
+			continue
+		}
+
+		var createdAt *string
+		if c.CreatedAt != "" {
+			createdAt = &c.CreatedAt
+		}
+
+		// closed_at is kept from the first close until the case is reopened
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO cases (case_id, case_type, status, data, created_at, closed_at, deleted, created_by, org_unit_id)
+			VALUES ($1, $2, $3, $4, COALESCE($5::TIMESTAMPTZ, NOW()), CASE WHEN $3 = 'closed' THEN NOW() END, $6, $7,
+				COALESCE($8::UUID, $9::UUID))
+			ON CONFLICT (case_id)
+			DO UPDATE SET
+				case_type = EXCLUDED.case_type,
+				status = EXCLUDED.status,
+				data = EXCLUDED.data,
+				closed_at = CASE WHEN EXCLUDED.status = 'closed' THEN COALESCE(cases.closed_at, NOW()) END,
+				deleted = EXCLUDED.deleted,
+				org_unit_id = COALESCE($8::UUID, cases.org_unit_id)`,
+			c.CaseID, c.CaseType, string(c.Status), c.Data, createdAt, c.Deleted, pushedBy,
+			orgUnitID, defaultOrgUnitID)
+		if err != nil {
+			s.log.Error("Failed to insert/update case", "error", err, "caseId", c.CaseID)
+			fail(i, c, fmt.Sprintf("database error: %v", err))
+			continue
+		}
+
+		successCount++
+	}
+
+	var currentVersion int64
+	err = tx.QueryRowContext(ctx, issuedVersionQuery).Scan(&currentVersion)
+	if err != nil {
+		s.log.Error("Failed to get current version within transaction", "error", err)
+		return nil, fmt.Errorf("failed to get current version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Processed pushed cases",
+		"clientId", clientID,
+		"totalCases", len(cases),
+		"successCount", successCount,
+		"failedCount", len(failedRecords),
+		"currentVersion", currentVersion)
+
+	return &SyncPushResult{
+		CurrentVersion: currentVersion,
+		SuccessCount:   successCount,
+		FailedRecords:  failedRecords,
+	}, nil
+}
+
+// GetCase returns a single case. Cases outside the user's org unit scope are reported as not found.
+func (s *Service) GetCase(ctx context.Context, caseID string) (*Case, error) {
+	query := "SELECT " + caseColumns + " FROM cases WHERE case_id = $1"
+	args := []interface{}{caseID}
+	if username := UsernameFromContext(ctx); username != "" {
+		args = append(args, username)
+		query += orgUnitFilterSQL(len(args))
+	}
+
+	c, err := scanCase(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCaseNotFound
+		}
+		s.log.Error("Failed to get case", "error", err, "caseId", caseID)
+		return nil, fmt.Errorf("failed to get case: %w", err)
+	}
+	return c, nil
+}
+
+// GetCaseObservations returns the observations linked to a case, oldest first, applying
+// the same draft and org unit visibility rules as pull
+func (s *Service) GetCaseObservations(ctx context.Context, caseID string) ([]Observation, error) {
+	var queryBuilder strings.Builder
+	args := []interface{}{caseID}
+
+	queryBuilder.WriteString(`
+		SELECT observation_id, form_type, form_version, data,
+		       created_at, updated_at, synced_at, deleted, version, draft, created_by, owner, org_unit_id, case_id
+		FROM observations
+		WHERE case_id = $1 AND NOT deleted`)
+
+	if username := UsernameFromContext(ctx); username != "" {
+		args = append(args, username)
+		queryBuilder.WriteString(" AND (NOT draft OR draft_owner = $" + strconv.Itoa(len(args)) + ")")
+		args = append(args, username)
+		queryBuilder.WriteString(orgUnitFilterSQL(len(args)))
+	} else {
+		queryBuilder.WriteString(" AND NOT draft")
+	}
+
+	queryBuilder.WriteString(" ORDER BY created_at ASC, version ASC")
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		s.log.Error("Failed to query case observations", "error", err, "caseId", caseID)
+		return nil, fmt.Errorf("failed to query case observations: %w", err)
+	}
+	defer rows.Close()
+
+	records, err := s.scanObservations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []Observation{}
+	}
+	return records, nil
+}
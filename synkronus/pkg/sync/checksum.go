@@ -0,0 +1,59 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ChecksumMismatch describes a pushed record whose content hash did not match its payload
+type ChecksumMismatch struct {
+	ObservationID string `json:"observation_id"`
+	Expected      string `json:"expected"`
+	Actual        string `json:"actual"`
+}
+
+// ChecksumError is returned when pushed records fail checksum verification.
+// It wraps ErrChecksumMismatch so callers can match it with errors.Is.
+type ChecksumError struct {
+	Mismatches []ChecksumMismatch
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s: %d record(s) failed verification", ErrChecksumMismatch, len(e.Mismatches))
+}
+
+func (e *ChecksumError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// ContentHash returns the hex encoded SHA-256 hash of a payload
+func ContentHash(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRecordHashes checks the optional per-record hash against the SHA-256 of the
+// record data exactly as sent by the client. Records without a hash are not checked.
+func VerifyRecordHashes(records []Observation) error {
+	var mismatches []ChecksumMismatch
+	for _, record := range records {
+		if record.Hash == "" {
+			continue
+		}
+		actual := ContentHash(record.Data)
+		if !strings.EqualFold(record.Hash, actual) {
+			mismatches = append(mismatches, ChecksumMismatch{
+				ObservationID: record.ObservationID,
+				Expected:      record.Hash,
+				Actual:        actual,
+			})
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return &ChecksumError{Mismatches: mismatches}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRecordHashes(t *testing.T) {
+	data := json.RawMessage(`{"name":"test"}`)
+	validHash := ContentHash(data)
+
+	tests := []struct {
+		name       string
+		records    []Observation
+		mismatches int
+	}{
+		{"no hashes", []Observation{{ObservationID: "obs-1", Data: data}}, 0},
+		{"valid hash", []Observation{{ObservationID: "obs-1", Data: data, Hash: validHash}}, 0},
+		{"uppercase hash", []Observation{{ObservationID: "obs-1", Data: data, Hash: strings.ToUpper(validHash)}}, 0},
+		{"corrupted data", []Observation{
+			{ObservationID: "obs-1", Data: json.RawMessage(`{"name":"tesu"}`), Hash: validHash},
+			{ObservationID: "obs-2", Data: data, Hash: validHash},
+		}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyRecordHashes(tt.records)
+			if tt.mismatches == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var checksumErr *ChecksumError
+			if !errors.As(err, &checksumErr) {
+				t.Fatalf("expected ChecksumError, got %v", err)
+			}
+			if !errors.Is(err, ErrChecksumMismatch) {
+				t.Error("expected error to wrap ErrChecksumMismatch")
+			}
+			if len(checksumErr.Mismatches) != tt.mismatches {
+				t.Errorf("expected %d mismatches, got %d", tt.mismatches, len(checksumErr.Mismatches))
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// codeListChecker checks the values of coded fields in pushed records. Each list is loaded at
+// most once per push.
+type codeListChecker struct {
+	source CodeListSource
+	fields map[string][]CodedField
+	// codes holds the codes of each list loaded so far; nil marks a list that does not exist
+	codes map[string]map[string]bool
+	log   *logger.Logger
+}
+
+// loadCodeListChecker returns a checker of the coded fields declared per form type, if a source
+// is configured
+func (s *Service) loadCodeListChecker(ctx context.Context) (*codeListChecker, error) {
+	if s.codeLists == nil {
+		return nil, nil
+	}
+	fields, err := s.codeLists.CodedFields(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load coded fields: %w", err)
+	}
+	return &codeListChecker{
+		source: s.codeLists,
+		fields: fields,
+		codes:  make(map[string]map[string]bool),
+		log:    s.log,
+	}, nil
+}
+
+// check returns an ErrInvalidData error naming the first value of a coded field of the record
+// that is not a code of its list. Deleted records and empty values are not checked, and fields
+// referencing a list that does not exist accept any value.
+func (c *codeListChecker) check(ctx context.Context, record Observation) error {
+	if c == nil || record.Deleted || len(c.fields[record.FormType]) == 0 {
+		return nil
+	}
+
+	data := make(map[string]any)
+	trimmed := bytes.TrimSpace(record.Data)
+	if len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return fmt.Errorf("%w: data must be a JSON object for forms with coded fields", ErrInvalidData)
+		}
+	}
+
+	for _, field := range c.fields[record.FormType] {
+		value, ok := data[field.Field]
+		if !ok || value == nil {
+			continue
+		}
+		codes, err := c.listCodes(ctx, field.List)
+		if err != nil {
+			return err
+		}
+		if codes == nil {
+			continue
+		}
+
+		values, ok := codeValues(value)
+		if !ok {
+			return fmt.Errorf("%w: field '%s' must hold codes of list '%s'", ErrInvalidData, field.Field, field.List)
+		}
+		for _, v := range values {
+			if !codes[v] {
+				return fmt.Errorf("%w: field '%s' value '%s' is not a code of list '%s'", ErrInvalidData, field.Field, v, field.List)
+			}
+		}
+	}
+	return nil
+}
+
+// listCodes returns the codes of a list, loading them on first use
+func (c *codeListChecker) listCodes(ctx context.Context, list string) (map[string]bool, error) {
+	if codes, ok := c.codes[list]; ok {
+		return codes, nil
+	}
+
+	loaded, err := c.source.Codes(ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load code list %s: %w", list, err)
+	}
+	var codes map[string]bool
+	if loaded == nil {
+		c.log.Warn("Form field references a code list that does not exist; values are not checked", "codeList", list)
+	} else {
+		codes = make(map[string]bool, len(loaded))
+		for _, code := range loaded {
+			codes[code] = true
+		}
+	}
+	c.codes[list] = codes
+	return codes, nil
+}
+
+// codeValues returns the codes a coded field value holds: a single code, or a list of codes for
+// multiple-choice questions. Empty strings hold no code.
+func codeValues(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil, true
+		}
+		return []string{v}, true
+	case json.Number:
+		return []string{v.String()}, true
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			switch code := item.(type) {
+			case string:
+				values = append(values, code)
+			case json.Number:
+				values = append(values, code.String())
+			default:
+				return nil, false
+			}
+		}
+		return values, true
+	default:
+		return nil, false
+	}
+}
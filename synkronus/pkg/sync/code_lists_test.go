@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+// staticCodeLists is a CodeListSource over fixed declarations that counts code list loads
+type staticCodeLists struct {
+	fields map[string][]CodedField
+	lists  map[string][]string
+	loads  int
+}
+
+func (s *staticCodeLists) CodedFields(ctx context.Context) (map[string][]CodedField, error) {
+	return s.fields, nil
+}
+
+func (s *staticCodeLists) Codes(ctx context.Context, list string) ([]string, error) {
+	s.loads++
+	return s.lists[list], nil
+}
+
+func TestCodeListCheck(t *testing.T) {
+	source := &staticCodeLists{
+		fields: map[string][]CodedField{
+			"visit": {
+				{Field: "diagnosis", List: "icd-subset"},
+				{Field: "facility", List: "facilities"},
+				{Field: "referral", List: "retired-list"},
+			},
+		},
+		lists: map[string][]string{
+			"icd-subset": {"A00", "A01", "B20"},
+			"facilities": {"101", "102"},
+		},
+	}
+	service := NewService(nil, DefaultConfig(), logger.NewLogger(), WithCodeLists(source))
+	checker, err := service.loadCodeListChecker(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		formType string
+		data     string
+		deleted  bool
+		wantErr  bool
+	}{
+		{"valid codes", "visit", `{"diagnosis":"A00","facility":101}`, false, false},
+		{"multiple choice", "visit", `{"diagnosis":["A00","B20"]}`, false, false},
+		{"empty values", "visit", `{"diagnosis":"","facility":null}`, false, false},
+		{"unknown code", "visit", `{"diagnosis":"Z99"}`, false, true},
+		{"unknown code in list", "visit", `{"diagnosis":["A00","Z99"]}`, false, true},
+		{"object value", "visit", `{"facility":{"id":"101"}}`, false, true},
+		{"missing list accepts any value", "visit", `{"referral":"anything"}`, false, false},
+		{"deleted record", "visit", `{"diagnosis":"Z99"}`, true, false},
+		{"form without coded fields", "household", `{"diagnosis":"Z99"}`, false, false},
+		{"data not an object", "visit", `["A00"]`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := Observation{ObservationID: "obs-1", FormType: tt.formType, Data: json.RawMessage(tt.data), Deleted: tt.deleted}
+			err := checker.check(context.Background(), record)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidData) {
+					t.Fatalf("expected invalid data error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	if source.loads != 3 {
+		t.Errorf("expected each code list to be loaded once, got %d loads", source.loads)
+	}
+}
+
+func TestCodeListCheckWithoutSource(t *testing.T) {
+	service := NewService(nil, DefaultConfig(), logger.NewLogger())
+	checker, err := service.loadCodeListChecker(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record := Observation{ObservationID: "obs-1", FormType: "visit", Data: json.RawMessage(`{"diagnosis":"Z99"}`)}
+	if err := checker.check(context.Background(), record); err != nil {
+		t.Errorf("expected no check without a code list source, got %v", err)
+	}
+}
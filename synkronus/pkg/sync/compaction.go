@@ -0,0 +1,256 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Compact purges tombstones and collapses superseded record versions older than the retention
+// windows. Retention is measured on the sync log: the cutoff is the last version recorded before
+// the window, so records deleted at or before it are purged together with their history, and of
+// the versions of a record recorded at or before it only the latest is kept.
+//
+// Clients pulling from a version before the tombstone cutoff can no longer learn about every
+// deletion and are told to resync; as-of reads before either cutoff are refused.
+func (s *Service) Compact(ctx context.Context, options CompactionOptions) (*CompactionReport, error) {
+	tombstoneRetention := options.TombstoneRetention
+	if tombstoneRetention <= 0 {
+		tombstoneRetention = s.config.TombstoneRetention
+	}
+	historyRetention := options.HistoryRetention
+	if historyRetention <= 0 {
+		historyRetention = s.config.HistoryRetention
+	}
+	if tombstoneRetention <= 0 && historyRetention <= 0 {
+		return nil, fmt.Errorf("%w: no tombstone or history retention is configured", ErrInvalidData)
+	}
+
+	now := time.Now().UTC()
+	report := &CompactionReport{
+		DryRun:      options.DryRun,
+		TriggeredBy: options.TriggeredBy,
+		StartedAt:   now.Format(time.RFC3339Nano),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Replicas compacting at the same time take turns
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE sync_compactions IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return nil, fmt.Errorf("failed to lock compactions: %w", err)
+	}
+
+	if tombstoneRetention > 0 {
+		if report.TombstoneCutoffVersion, err = versionAtTime(ctx, tx, now.Add(-tombstoneRetention)); err != nil {
+			return nil, err
+		}
+		if err := purgeTombstones(ctx, tx, report); err != nil {
+			return nil, err
+		}
+	}
+	if historyRetention > 0 {
+		if report.HistoryCutoffVersion, err = versionAtTime(ctx, tx, now.Add(-historyRetention)); err != nil {
+			return nil, err
+		}
+		if err := collapseHistory(ctx, tx, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.DryRun {
+		report.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		return report, nil
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO sync_compactions (tombstone_cutoff_version, history_cutoff_version, tombstones_purged,
+			history_versions_removed, triggered_by, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, finished_at`,
+		report.TombstoneCutoffVersion, report.HistoryCutoffVersion, report.TombstonesPurged,
+		report.HistoryVersionsRemoved, report.TriggeredBy, now).Scan(&report.ID, &report.FinishedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record compaction: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit compaction", "error", err)
+		return nil, fmt.Errorf("failed to commit compaction: %w", err)
+	}
+
+	s.log.Info("Compacted sync log",
+		"tombstoneCutoffVersion", report.TombstoneCutoffVersion,
+		"tombstonesPurged", report.TombstonesPurged,
+		"historyCutoffVersion", report.HistoryCutoffVersion,
+		"historyVersionsRemoved", report.HistoryVersionsRemoved,
+		"triggeredBy", report.TriggeredBy)
+	return report, nil
+}
+
+// versionAtTime returns the latest version recorded at or before the given time, or 0
+func versionAtTime(ctx context.Context, tx *sql.Tx, at time.Time) (int64, error) {
+	var version int64
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM observation_history WHERE recorded_at <= $1",
+		at).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get version at time: %w", err)
+	}
+	return version, nil
+}
+
+// purgeTombstones removes records deleted at or before the tombstone cutoff, with their history
+func purgeTombstones(ctx context.Context, tx *sql.Tx, report *CompactionReport) error {
+	if report.TombstoneCutoffVersion == 0 {
+		return nil
+	}
+
+	if report.DryRun {
+		return tx.QueryRowContext(ctx, `
+			SELECT COUNT(*), COALESCE(SUM(h.versions), 0)
+			FROM observations o
+			LEFT JOIN (SELECT observation_id, COUNT(*) AS versions FROM observation_history GROUP BY observation_id) h
+				ON h.observation_id = o.observation_id
+			WHERE o.deleted AND o.version <= $1`,
+			report.TombstoneCutoffVersion).Scan(&report.TombstonesPurged, &report.HistoryVersionsRemoved)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM observation_history h
+		USING observations o
+		WHERE o.observation_id = h.observation_id AND o.deleted AND o.version <= $1`,
+		report.TombstoneCutoffVersion)
+	if err != nil {
+		return fmt.Errorf("failed to remove tombstone history: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	report.HistoryVersionsRemoved += removed
+
+	result, err = tx.ExecContext(ctx,
+		"DELETE FROM observations WHERE deleted AND version <= $1",
+		report.TombstoneCutoffVersion)
+	if err != nil {
+		return fmt.Errorf("failed to purge tombstones: %w", err)
+	}
+	report.TombstonesPurged, _ = result.RowsAffected()
+	return nil
+}
+
+// collapseHistory removes record versions superseded at or before the history cutoff, keeping the
+// latest version of each record so as-of reads from the cutoff on stay exact
+func collapseHistory(ctx context.Context, tx *sql.Tx, report *CompactionReport) error {
+	if report.HistoryCutoffVersion == 0 {
+		return nil
+	}
+
+	const superseded = `
+		h.version <= $1 AND EXISTS (
+			SELECT 1 FROM observation_history newer
+			WHERE newer.observation_id = h.observation_id AND newer.version > h.version AND newer.version <= $1)`
+
+	if report.DryRun {
+		// Versions of tombstones counted as purged are not counted again
+		var count int64
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM observation_history h WHERE `+superseded+` AND NOT EXISTS (
+				SELECT 1 FROM observations o
+				WHERE o.observation_id = h.observation_id AND o.deleted AND o.version <= $2)`,
+			report.HistoryCutoffVersion, report.TombstoneCutoffVersion).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count superseded versions: %w", err)
+		}
+		report.HistoryVersionsRemoved += count
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM observation_history h WHERE "+superseded,
+		report.HistoryCutoffVersion)
+	if err != nil {
+		return fmt.Errorf("failed to collapse superseded versions: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	report.HistoryVersionsRemoved += removed
+	return nil
+}
+
+// ListCompactions returns the most recent compactions, newest first
+func (s *Service) ListCompactions(ctx context.Context, limit int) ([]CompactionReport, error) {
+	if limit <= 0 {
+		limit = s.config.DefaultLimit
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, tombstone_cutoff_version, history_cutoff_version, tombstones_purged,
+		       history_versions_removed, triggered_by, started_at, finished_at
+		FROM sync_compactions
+		ORDER BY id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		s.log.Error("Failed to query compactions", "error", err)
+		return nil, fmt.Errorf("failed to query compactions: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]CompactionReport, 0)
+	for rows.Next() {
+		var report CompactionReport
+		if err := rows.Scan(&report.ID, &report.TombstoneCutoffVersion, &report.HistoryCutoffVersion,
+			&report.TombstonesPurged, &report.HistoryVersionsRemoved, &report.TriggeredBy,
+			&report.StartedAt, &report.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan compaction: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// compactedVersions returns the highest tombstone and history cutoffs of past compactions
+func (s *Service) compactedVersions(ctx context.Context) (tombstones, history int64, err error) {
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(tombstone_cutoff_version), 0), COALESCE(MAX(history_cutoff_version), 0)
+		FROM sync_compactions`).Scan(&tombstones, &history)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get compacted versions: %w", err)
+	}
+	return tombstones, history, nil
+}
+
+// resyncRequiredWarning tells a client that deletions it has not pulled were purged
+func resyncRequiredWarning(sinceVersion, cutoff int64) SyncWarning {
+	return SyncWarning{
+		ID:   strconv.FormatInt(sinceVersion, 10),
+		Code: WarningCodeResyncRequired,
+		Message: fmt.Sprintf("records deleted up to version %d were purged; pull again from version 0 and "+
+			"discard local records the server no longer returns", cutoff),
+	}
+}
+
+// RunCompaction compacts the sync log every configured interval until ctx is cancelled. Failures
+// are logged; the next run retries.
+func (s *Service) RunCompaction(ctx context.Context) {
+	if s.config.CompactionInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.CompactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.Compact(ctx, CompactionOptions{TriggeredBy: "schedule"}); err != nil && ctx.Err() == nil {
+			s.log.Warn("Scheduled sync log compaction failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// conflictColumns lists the columns selected for a Conflict in scan order
+const conflictColumns = `id, observation_id, client_id, transmission_id, server_record, client_record,
+		       status, reason, resolution, resolved_by, detected_at, resolved_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanConflict scans a single conflict row
+func scanConflict(row rowScanner) (*Conflict, error) {
+	var c Conflict
+	var transmissionID, resolution, resolvedBy, resolvedAt sql.NullString
+	var serverRecord, clientRecord []byte
+
+	if err := row.Scan(
+		&c.ID, &c.ObservationID, &c.ClientID, &transmissionID, &serverRecord, &clientRecord,
+		&c.Status, &c.Reason, &resolution, &resolvedBy, &c.DetectedAt, &resolvedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(serverRecord, &c.ServerRecord); err != nil {
+		return nil, fmt.Errorf("failed to decode server record: %w", err)
+	}
+	if err := json.Unmarshal(clientRecord, &c.ClientRecord); err != nil {
+		return nil, fmt.Errorf("failed to decode client record: %w", err)
+	}
+
+	if transmissionID.Valid {
+		c.TransmissionID = transmissionID.String
+	}
+	if resolution.Valid {
+		r := ConflictResolution(resolution.String)
+		c.Resolution = &r
+	}
+	if resolvedBy.Valid {
+		c.ResolvedBy = &resolvedBy.String
+	}
+	if resolvedAt.Valid {
+		c.ResolvedAt = &resolvedAt.String
+	}
+
+	return &c, nil
+}
+
+// ListConflicts returns detected sync conflicts matching the filter, newest first
+func (s *Service) ListConflicts(ctx context.Context, filter ConflictFilter) ([]Conflict, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = s.config.DefaultLimit
+	}
+	if limit > s.config.MaxRecordsPerSync {
+		limit = s.config.MaxRecordsPerSync
+	}
+
+	var queryBuilder strings.Builder
+	var args []interface{}
+
+	queryBuilder.WriteString("SELECT " + conflictColumns + " FROM sync_conflicts WHERE 1=1")
+
+	if filter.Status != "" {
+		args = append(args, string(filter.Status))
+		queryBuilder.WriteString(" AND status = $" + strconv.Itoa(len(args)))
+	}
+	if filter.ObservationID != "" {
+		args = append(args, filter.ObservationID)
+		queryBuilder.WriteString(" AND observation_id = $" + strconv.Itoa(len(args)))
+	}
+	if filter.Reason != "" {
+		args = append(args, string(filter.Reason))
+		queryBuilder.WriteString(" AND reason = $" + strconv.Itoa(len(args)))
+	}
+
+	args = append(args, limit)
+	queryBuilder.WriteString(" ORDER BY detected_at DESC, id DESC LIMIT $" + strconv.Itoa(len(args)))
+	args = append(args, filter.Offset)
+	queryBuilder.WriteString(" OFFSET $" + strconv.Itoa(len(args)))
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		s.log.Error("Failed to query sync conflicts", "error", err)
+		return nil, fmt.Errorf("failed to query sync conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := make([]Conflict, 0)
+	for rows.Next() {
+		c, err := scanConflict(rows)
+		if err != nil {
+			s.log.Error("Failed to scan sync conflict row", "error", err)
+			return nil, fmt.Errorf("failed to scan sync conflict: %w", err)
+		}
+		conflicts = append(conflicts, *c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return conflicts, nil
+}
+
+// GetConflict returns a single sync conflict by ID
+func (s *Service) GetConflict(ctx context.Context, id int64) (*Conflict, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+conflictColumns+" FROM sync_conflicts WHERE id = $1", id)
+	c, err := scanConflict(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrConflictNotFound
+		}
+		s.log.Error("Failed to get sync conflict", "error", err, "conflictId", id)
+		return nil, fmt.Errorf("failed to get sync conflict: %w", err)
+	}
+	return c, nil
+}
+
+// ResolveConflict applies a manual resolution to a pending conflict.
+// keep_server leaves the stored observation untouched, keep_client writes the
+// client version back and merge writes the client record with the supplied data.
+func (s *Service) ResolveConflict(ctx context.Context, id int64, req ConflictResolveRequest, resolvedBy string) (*Conflict, error) {
+	switch req.Resolution {
+	case ResolutionKeepServer, ResolutionKeepClient:
+	case ResolutionMerge:
+		if len(req.Data) == 0 {
+			return nil, fmt.Errorf("%w: data is required for merge", ErrInvalidResolution)
+		}
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	// Lock the conflict row so concurrent reviewers cannot resolve it twice
+	row := tx.QueryRowContext(ctx, "SELECT "+conflictColumns+" FROM sync_conflicts WHERE id = $1 FOR UPDATE", id)
+	conflict, err := scanConflict(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrConflictNotFound
+		}
+		return nil, fmt.Errorf("failed to get sync conflict: %w", err)
+	}
+
+	if conflict.Status == ConflictStatusResolved {
+		return nil, ErrConflictAlreadyResolved
+	}
+
+	if req.Resolution != ResolutionKeepServer {
+		record := conflict.ClientRecord
+		if req.Resolution == ResolutionMerge {
+			record.Data = req.Data
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO observations (observation_id, form_type, form_version, data, created_at, updated_at, deleted)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (observation_id)
+			DO UPDATE SET
+				form_type = EXCLUDED.form_type,
+				form_version = EXCLUDED.form_version,
+				data = EXCLUDED.data,
+				updated_at = EXCLUDED.updated_at,
+				deleted = EXCLUDED.deleted,
+				version = observations.version + 1
+		`, record.ObservationID, record.FormType, record.FormVersion,
+			record.Data, record.CreatedAt, record.UpdatedAt, record.Deleted)
+		if err != nil {
+			s.log.Error("Failed to apply conflict resolution", "error", err, "conflictId", id)
+			return nil, fmt.Errorf("failed to apply conflict resolution: %w", err)
+		}
+	}
+
+	row = tx.QueryRowContext(ctx, `
+		UPDATE sync_conflicts
+		SET status = $1, resolution = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE id = $4
+		RETURNING `+conflictColumns,
+		string(ConflictStatusResolved), string(req.Resolution), resolvedBy, id)
+	resolved, err := scanConflict(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update sync conflict: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Resolved sync conflict",
+		"conflictId", id,
+		"observationId", resolved.ObservationID,
+		"resolution", req.Resolution,
+		"resolvedBy", resolvedBy)
+
+	return resolved, nil
+}
+
+// detectConflict reports whether a pushed record would overwrite changes the client has not
+// pulled. A client sending the version it last pulled conflicts once the stored version has moved
+// on; without a version, the stored record conflicts when it was updated later. Pushing the
+// stored content again never conflicts, so retransmitted pushes are harmless.
+func detectConflict(record Observation, updatedAt string, stored *Observation) bool {
+	if stored == nil || sameContent(*stored, record) {
+		return false
+	}
+	if record.Version > 0 {
+		return stored.Version > record.Version
+	}
+	return updatedAfter(stored.UpdatedAt, updatedAt)
+}
+
+// conflictOutcome decides under the configured policy what becomes of a conflicting record
+func (s *Service) conflictOutcome(updatedAt string, stored *Observation) PushConflictOutcome {
+	switch s.config.ConflictPolicy {
+	case ConflictPolicyServerWins:
+		return PushConflictServerKept
+	case ConflictPolicyRejectAndReport:
+		return PushConflictPendingReview
+	}
+	if updatedAfter(updatedAt, stored.UpdatedAt) {
+		return PushConflictApplied
+	}
+	return PushConflictServerKept
+}
+
+// sameContent reports whether a pushed record carries the stored content. Data is compared by
+// value since the database does not keep the client's JSON formatting.
+func sameContent(stored, record Observation) bool {
+	if stored.FormType != record.FormType || stored.Deleted != record.Deleted {
+		return false
+	}
+	var storedData, recordData any
+	if json.Unmarshal(stored.Data, &storedData) != nil || json.Unmarshal(record.Data, &recordData) != nil {
+		return bytes.Equal(bytes.TrimSpace(stored.Data), bytes.TrimSpace(record.Data))
+	}
+	return reflect.DeepEqual(storedData, recordData)
+}
+
+// updatedAfter reports whether timestamp a is later than b; unparseable timestamps never are
+func updatedAfter(a, b string) bool {
+	at, err := time.Parse(time.RFC3339Nano, a)
+	if err != nil {
+		return false
+	}
+	bt, err := time.Parse(time.RFC3339Nano, b)
+	if err != nil {
+		return false
+	}
+	return at.After(bt)
+}
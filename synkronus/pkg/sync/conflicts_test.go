@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+func TestDetectConflict(t *testing.T) {
+	stored := &Observation{
+		ObservationID: "obs-1",
+		FormType:      "survey",
+		Data:          json.RawMessage(`{"name": "Amina", "age": 34}`),
+		UpdatedAt:     "2025-09-05T12:00:00Z",
+		Version:       7,
+	}
+
+	tests := []struct {
+		name      string
+		data      string
+		version   int64
+		updatedAt string
+		want      bool
+	}{
+		{"pulled version", `{"name": "Amina J"}`, 7, "2025-09-05T11:00:00Z", false},
+		{"stale version", `{"name": "Amina J"}`, 5, "2025-09-05T13:00:00Z", true},
+		{"newer edit without version", `{"name": "Amina J"}`, 0, "2025-09-05T13:00:00Z", false},
+		{"older edit without version", `{"name": "Amina J"}`, 0, "2025-09-05T11:00:00Z", true},
+		{"same content retransmitted", `{"age":34,"name":"Amina"}`, 5, "2025-09-05T11:00:00Z", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := Observation{ObservationID: "obs-1", FormType: "survey", Data: json.RawMessage(tt.data), Version: tt.version}
+			if got := detectConflict(record, tt.updatedAt, stored); got != tt.want {
+				t.Errorf("detectConflict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if detectConflict(Observation{ObservationID: "obs-2", Version: 3}, "2025-09-05T11:00:00Z", nil) {
+		t.Error("new records never conflict")
+	}
+}
+
+func TestConflictOutcome(t *testing.T) {
+	stored := &Observation{UpdatedAt: "2025-09-05T12:00:00Z"}
+
+	tests := []struct {
+		policy    ConflictPolicy
+		updatedAt string
+		want      PushConflictOutcome
+	}{
+		{ConflictPolicyLastWriteWins, "2025-09-05T13:00:00Z", PushConflictApplied},
+		{ConflictPolicyLastWriteWins, "2025-09-05T11:00:00Z", PushConflictServerKept},
+		{ConflictPolicyServerWins, "2025-09-05T13:00:00Z", PushConflictServerKept},
+		{ConflictPolicyRejectAndReport, "2025-09-05T13:00:00Z", PushConflictPendingReview},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.ConflictPolicy = tt.policy
+		service := NewService(nil, config, logger.NewLogger())
+		if got := service.conflictOutcome(tt.updatedAt, stored); got != tt.want {
+			t.Errorf("%s with client update at %s = %s, want %s", tt.policy, tt.updatedAt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package sync
+
+import "context"
+
+type contextKey string
+
+// usernameKey is the context key holding the username of the syncing user
+const usernameKey contextKey = "syncUsername"
+
+// WithUsername returns a context carrying the username of the user performing a sync.
+// The username decides which draft records are visible to and owned by the caller.
+func WithUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// UsernameFromContext returns the username set by WithUsername, or an empty string
+func UsernameFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(usernameKey).(string)
+	return username
+}
@@ -0,0 +1,139 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// loadFieldAssignments returns the server-assigned fields declared per form type, if a source is configured
+func (s *Service) loadFieldAssignments(ctx context.Context) (map[string][]FieldAssignment, error) {
+	if s.assignments == nil {
+		return nil, nil
+	}
+	assignments, err := s.assignments.FieldAssignments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server-assigned fields: %w", err)
+	}
+	return assignments, nil
+}
+
+// assignFields fills the server-assigned fields of a pushed record and returns the updated data
+// together with the assigned values. Values stored by earlier pushes are kept, so numbers never
+// change once handed out and client edits to assigned fields are overwritten. Drafts and deleted
+// records keep stored values but receive no new ones, so they do not leave gaps in sequences.
+func (s *Service) assignFields(ctx context.Context, tx *sql.Tx, record Observation, stored *Observation, assignments []FieldAssignment, orgUnitID, defaultOrgUnitID *string) (json.RawMessage, map[string]any, error) {
+	data, err := decodeDataObject(record.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var storedData map[string]any
+	if stored != nil {
+		// Stored data has passed this check on an earlier push; non-objects just keep nothing
+		storedData, _ = decodeDataObject(stored.Data)
+	}
+
+	assigned := make(map[string]any)
+	for _, assignment := range assignments {
+		if value, ok := storedData[assignment.Field]; ok && value != nil && value != "" {
+			data[assignment.Field] = value
+			assigned[assignment.Field] = value
+			continue
+		}
+		delete(data, assignment.Field)
+		if record.Draft || record.Deleted {
+			continue
+		}
+
+		var value any
+		switch assignment.Kind {
+		case FieldAssignmentSequence:
+			n, err := nextSequenceValue(ctx, tx, record.FormType, assignment.Field)
+			if err != nil {
+				return nil, nil, err
+			}
+			value = fmt.Sprintf("%s%0*d", assignment.Prefix, assignment.Padding, n)
+
+		case FieldAssignmentOrgUnitCode:
+			unitID := orgUnitID
+			if unitID == nil && stored != nil {
+				unitID = stored.OrgUnitID
+			}
+			if unitID == nil && stored == nil {
+				unitID = defaultOrgUnitID
+			}
+			if unitID == nil {
+				continue
+			}
+			code, err := orgUnitCode(ctx, tx, *unitID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if code == nil {
+				continue
+			}
+			value = *code
+
+		default:
+			s.log.Warn("Ignoring unknown server-assigned field kind", "formType", record.FormType, "field", assignment.Field, "kind", assignment.Kind)
+			continue
+		}
+
+		data[assignment.Field] = value
+		assigned[assignment.Field] = value
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode record data: %w", err)
+	}
+	return encoded, assigned, nil
+}
+
+// decodeDataObject parses record data that must be a JSON object; empty data yields an empty object
+func decodeDataObject(raw json.RawMessage) (map[string]any, error) {
+	data := make(map[string]any)
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: data must be a JSON object for forms with server-assigned fields", ErrInvalidData)
+	}
+	return data, nil
+}
+
+// nextSequenceValue hands out the next number of a form field's sequence. The row lock taken
+// by the upsert is held until the push transaction ends, so concurrent pushes never share a number.
+func nextSequenceValue(ctx context.Context, tx *sql.Tx, formType, field string) (int64, error) {
+	var value int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO field_sequences (form_type, field, last_value)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (form_type, field)
+		DO UPDATE SET last_value = field_sequences.last_value + 1, updated_at = NOW()
+		RETURNING last_value`, formType, field).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next sequence value: %w", err)
+	}
+	return value, nil
+}
+
+// orgUnitCode returns the code of an org unit, or nil if it has none
+func orgUnitCode(ctx context.Context, tx *sql.Tx, orgUnitID string) (*string, error) {
+	var code *string
+	err := tx.QueryRowContext(ctx, "SELECT code FROM org_units WHERE id = $1", orgUnitID).Scan(&code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get org unit code: %w", err)
+	}
+	return code, nil
+}
@@ -0,0 +1,233 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// pausedFormTypes returns the form types whose pull (or push) sync is currently paused
+func pausedFormTypes(ctx context.Context, q queryer, pull bool) ([]string, error) {
+	query := "SELECT form_type FROM form_sync_controls WHERE push_paused ORDER BY form_type"
+	if pull {
+		query = "SELECT form_type FROM form_sync_controls WHERE pull_paused ORDER BY form_type"
+	}
+
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paused form types: %w", err)
+	}
+	defer rows.Close()
+
+	var formTypes []string
+	for rows.Next() {
+		var formType string
+		if err := rows.Scan(&formType); err != nil {
+			return nil, fmt.Errorf("failed to scan paused form type: %w", err)
+		}
+		formTypes = append(formTypes, formType)
+	}
+
+	return formTypes, rows.Err()
+}
+
+// formPausedWarning builds the structured warning returned for a paused form type
+func formPausedWarning(id, formType, direction string) SyncWarning {
+	return SyncWarning{
+		ID:      id,
+		Code:    WarningCodeFormPaused,
+		Message: fmt.Sprintf("sync %s is paused for form type %q", direction, formType),
+	}
+}
+
+// ListFormSyncControls returns the sync switches of all form types that have one
+func (s *Service) ListFormSyncControls(ctx context.Context) ([]FormSyncControl, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT form_type, push_paused, pull_paused, reason, updated_by, updated_at
+		FROM form_sync_controls
+		ORDER BY form_type`)
+	if err != nil {
+		s.log.Error("Failed to query form sync controls", "error", err)
+		return nil, fmt.Errorf("failed to query form sync controls: %w", err)
+	}
+	defer rows.Close()
+
+	controls := make([]FormSyncControl, 0)
+	for rows.Next() {
+		var c FormSyncControl
+		var reason, updatedBy sql.NullString
+		if err := rows.Scan(&c.FormType, &c.PushPaused, &c.PullPaused, &reason, &updatedBy, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan form sync control: %w", err)
+		}
+		if reason.Valid {
+			c.Reason = &reason.String
+		}
+		if updatedBy.Valid {
+			c.UpdatedBy = &updatedBy.String
+		}
+		controls = append(controls, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return controls, nil
+}
+
+// SetFormSyncControl creates or updates the sync switches of a form type.
+// When pull is resumed, records of the form type changed while it was paused are
+// re-versioned so clients whose change_cutoff moved past them still receive them.
+func (s *Service) SetFormSyncControl(ctx context.Context, control FormSyncControl) (*FormSyncControl, error) {
+	if control.FormType == "" {
+		return nil, fmt.Errorf("%w: form_type is required", ErrInvalidData)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	var pausedAt sql.NullInt64
+	var wasPullPaused bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT pull_paused, pull_paused_at_version FROM form_sync_controls WHERE form_type = $1 FOR UPDATE",
+		control.FormType).Scan(&wasPullPaused, &pausedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get form sync control: %w", err)
+	}
+
+	switch {
+	case control.PullPaused && !wasPullPaused:
+		if err := tx.QueryRowContext(ctx, stableVersionQuery).Scan(&pausedAt); err != nil {
+			return nil, fmt.Errorf("failed to get current version: %w", err)
+		}
+	case !control.PullPaused && wasPullPaused:
+		if err := s.republishFormType(ctx, tx, control.FormType, pausedAt); err != nil {
+			return nil, err
+		}
+		pausedAt = sql.NullInt64{}
+	}
+
+	var result FormSyncControl
+	var reason, updatedBy sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO form_sync_controls (form_type, push_paused, pull_paused, pull_paused_at_version, reason, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (form_type)
+		DO UPDATE SET
+			push_paused = EXCLUDED.push_paused,
+			pull_paused = EXCLUDED.pull_paused,
+			pull_paused_at_version = EXCLUDED.pull_paused_at_version,
+			reason = EXCLUDED.reason,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING form_type, push_paused, pull_paused, reason, updated_by, updated_at`,
+		control.FormType, control.PushPaused, control.PullPaused, pausedAt, control.Reason, control.UpdatedBy,
+	).Scan(&result.FormType, &result.PushPaused, &result.PullPaused, &reason, &updatedBy, &result.UpdatedAt)
+	if err != nil {
+		s.log.Error("Failed to save form sync control", "error", err, "formType", control.FormType)
+		return nil, fmt.Errorf("failed to save form sync control: %w", err)
+	}
+	if reason.Valid {
+		result.Reason = &reason.String
+	}
+	if updatedBy.Valid {
+		result.UpdatedBy = &updatedBy.String
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Form sync control updated",
+		"formType", result.FormType,
+		"pushPaused", result.PushPaused,
+		"pullPaused", result.PullPaused)
+
+	return &result, nil
+}
+
+// DeleteFormSyncControl removes the sync switches of a form type, resuming sync in both directions
+func (s *Service) DeleteFormSyncControl(ctx context.Context, formType string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	var pullPaused bool
+	var pausedAt sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM form_sync_controls WHERE form_type = $1 RETURNING pull_paused, pull_paused_at_version",
+		formType).Scan(&pullPaused, &pausedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFormControlNotFound
+		}
+		return fmt.Errorf("failed to delete form sync control: %w", err)
+	}
+
+	if pullPaused {
+		if err := s.republishFormType(ctx, tx, formType, pausedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Form sync control removed", "formType", formType)
+	return nil
+}
+
+// republishFormType bumps the version of records changed since pull was paused.
+// The observations version trigger assigns each touched row a fresh version.
+func (s *Service) republishFormType(ctx context.Context, tx *sql.Tx, formType string, pausedAt sql.NullInt64) error {
+	if !pausedAt.Valid {
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE observations SET version = version WHERE form_type = $1 AND version > $2",
+		formType, pausedAt.Int64)
+	if err != nil {
+		s.log.Error("Failed to republish paused form type", "error", err, "formType", formType)
+		return fmt.Errorf("failed to republish records for form type %s: %w", formType, err)
+	}
+
+	if count, err := result.RowsAffected(); err == nil {
+		s.log.Info("Republished records changed while pull was paused", "formType", formType, "recordCount", count)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned when a client supports none of the server's sync format versions
+var ErrUnsupportedFormat = errors.New("no common sync format version")
+
+// Sync format versions of pull and push payloads
+const (
+	// FormatVersion10 is the original format, assumed for clients that advertise no versions
+	FormatVersion10 = "1.0"
+	// FormatVersion11 sends deleted records as tombstones whose data is null
+	FormatVersion11 = "1.1"
+)
+
+// formatShim translates records between a sync format version and the form the server stores
+// them in. Nil functions leave records unchanged.
+type formatShim struct {
+	// pull translates a record sent to the client
+	pull func(Observation) Observation
+	// push translates a record received from the client
+	push func(Observation) Observation
+}
+
+// formatShims holds every supported sync format version. A protocol upgrade adds a version
+// here, and the shims of older versions keep their clients working until they upgrade.
+var formatShims = map[string]formatShim{
+	FormatVersion10: {},
+	FormatVersion11: {pull: omitTombstoneData},
+}
+
+// omitTombstoneData drops the data of deleted records, which clients only need to remove
+func omitTombstoneData(obs Observation) Observation {
+	if obs.Deleted {
+		obs.Data = nil
+	}
+	return obs
+}
+
+// SupportedFormatVersions returns the sync format versions the server can speak, oldest first
+func SupportedFormatVersions() []string {
+	versions := make([]string, 0, len(formatShims))
+	for version := range formatShims {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareFormatVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// NegotiateFormat returns the highest sync format version both the client and the server
+// support. Clients that advertise no versions get FormatVersion10.
+func NegotiateFormat(advertised []string) (string, error) {
+	if len(advertised) == 0 {
+		return FormatVersion10, nil
+	}
+	var best string
+	for _, version := range advertised {
+		if _, ok := formatShims[version]; !ok {
+			continue
+		}
+		if best == "" || compareFormatVersions(version, best) > 0 {
+			best = version
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: the server supports %s", ErrUnsupportedFormat, strings.Join(SupportedFormatVersions(), ", "))
+	}
+	return best, nil
+}
+
+// TranslatePulled translates a record to be sent to a client speaking version
+func TranslatePulled(version string, obs Observation) Observation {
+	if shim := formatShims[version]; shim.pull != nil {
+		return shim.pull(obs)
+	}
+	return obs
+}
+
+// TranslatePushed translates records received from a client speaking version
+func TranslatePushed(version string, records []Observation) []Observation {
+	shim := formatShims[version]
+	if shim.push == nil {
+		return records
+	}
+	translated := make([]Observation, len(records))
+	for i, record := range records {
+		translated[i] = shim.push(record)
+	}
+	return translated
+}
+
+// compareFormatVersions orders major.minor versions numerically, so that 1.10 follows 1.9
+func compareFormatVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		advertised []string
+		want       string
+	}{
+		{"nothing advertised", nil, FormatVersion10},
+		{"highest common version", []string{"1.0", "1.1"}, FormatVersion11},
+		{"order does not matter", []string{"1.1", "1.0"}, FormatVersion11},
+		{"versions unknown to the server are skipped", []string{"1.0", "2.0"}, FormatVersion10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NegotiateFormat(tt.advertised)
+			if err != nil {
+				t.Fatalf("NegotiateFormat() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NegotiateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NegotiateFormat([]string{"0.9", "2.0"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestSupportedFormatVersions(t *testing.T) {
+	if got := SupportedFormatVersions(); !reflect.DeepEqual(got, []string{FormatVersion10, FormatVersion11}) {
+		t.Errorf("SupportedFormatVersions() = %v", got)
+	}
+	if compareFormatVersions("1.10", "1.9") <= 0 {
+		t.Error("expected 1.10 to follow 1.9")
+	}
+}
+
+func TestTranslatePulled(t *testing.T) {
+	tombstone := Observation{ObservationID: "obs-1", Deleted: true, Data: json.RawMessage(`{"name":"x"}`)}
+
+	if got := TranslatePulled(FormatVersion10, tombstone); string(got.Data) != `{"name":"x"}` {
+		t.Errorf("expected 1.0 tombstones to keep their data, got %s", got.Data)
+	}
+	if got := TranslatePulled(FormatVersion11, tombstone); got.Data != nil {
+		t.Errorf("expected 1.1 tombstones without data, got %s", got.Data)
+	}
+	if string(tombstone.Data) != `{"name":"x"}` {
+		t.Error("expected the stored record to be left unchanged")
+	}
+
+	live := Observation{ObservationID: "obs-2", Data: json.RawMessage(`{}`)}
+	if got := TranslatePulled(FormatVersion11, live); string(got.Data) != `{}` {
+		t.Errorf("expected live records to keep their data, got %s", got.Data)
+	}
+}
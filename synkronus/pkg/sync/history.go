@@ -0,0 +1,147 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// GetRecordsAsOfVersion retrieves records as they existed at asOfVersion, reconstructed
+// from the observation history. Only records whose state at asOfVersion is newer than
+// sinceVersion are returned, so clients can page through a snapshot with the usual cursor.
+// Pull pauses do not apply to historical reads.
+func (s *Service) GetRecordsAsOfVersion(ctx context.Context, asOfVersion, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *SyncPullCursor) (*SyncResult, error) {
+	currentVersion, err := s.GetCurrentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if asOfVersion <= 0 || asOfVersion > currentVersion {
+		return nil, fmt.Errorf("%w: as_of version must be between 1 and %d", ErrInvalidData, currentVersion)
+	}
+
+	// Compaction keeps the dataset exact only from the latest cutoff on
+	tombstoneCutoff, historyCutoff, err := s.compactedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if floor := max(tombstoneCutoff, historyCutoff); asOfVersion < floor {
+		return nil, fmt.Errorf("%w: history before version %d has been compacted", ErrInvalidData, floor)
+	}
+
+	if limit <= 0 {
+		limit = s.config.DefaultLimit
+	}
+	if limit > s.config.MaxRecordsPerSync {
+		limit = s.config.MaxRecordsPerSync
+	}
+
+	var queryBuilder strings.Builder
+	var args []interface{}
+
+	// Latest stored state of every observation of the tenant at or before the requested version
+	args = append(args, asOfVersion, tenant.FromContext(ctx))
+	queryBuilder.WriteString(`
+		SELECT observation_id, form_type, form_version, data,
+		       created_at, updated_at, synced_at, deleted, version, draft, created_by, owner, org_unit_id, case_id
+		FROM (
+			SELECT DISTINCT ON (observation_id) *
+			FROM observation_history
+			WHERE version <= $1 AND tenant_id = $2
+			ORDER BY observation_id, version DESC
+		) snapshot
+		WHERE version > $`)
+	args = append(args, sinceVersion)
+	queryBuilder.WriteString(strconv.Itoa(len(args)))
+
+	if len(schemaTypes) > 0 {
+		args = append(args, pq.Array(schemaTypes))
+		queryBuilder.WriteString(" AND form_type = ANY($" + strconv.Itoa(len(args)) + ")")
+	}
+
+	if username := UsernameFromContext(ctx); username != "" {
+		args = append(args, username)
+		queryBuilder.WriteString(" AND (NOT draft OR draft_owner = $" + strconv.Itoa(len(args)) + ")")
+		args = append(args, username)
+		queryBuilder.WriteString(orgUnitFilterSQL(len(args)))
+		if s.assignedOnly(ctx) {
+			args = append(args, username)
+			queryBuilder.WriteString(assignmentFilterSQL("snapshot", len(args)))
+		}
+	} else {
+		queryBuilder.WriteString(" AND NOT draft")
+	}
+
+	filterSQL, args := pullFilterSQL(ctx, args)
+	queryBuilder.WriteString(filterSQL)
+
+	if cursor != nil {
+		args = append(args, cursor.Version, cursor.ID)
+		queryBuilder.WriteString(" AND (version > $" + strconv.Itoa(len(args)-1) +
+			"::BIGINT OR (version = $" + strconv.Itoa(len(args)-1) +
+			"::BIGINT AND observation_id > $" + strconv.Itoa(len(args)) + "::VARCHAR))")
+	}
+
+	args = append(args, limit+1)
+	queryBuilder.WriteString(" ORDER BY version ASC, observation_id ASC LIMIT $" + strconv.Itoa(len(args)))
+
+	sqlStmt := queryBuilder.String()
+	s.log.Debug("SQL query", "sql", sqlStmt, "args", args)
+	rows, err := s.db.QueryContext(ctx, sqlStmt, args...)
+	if err != nil {
+		s.log.Error("Failed to query observation history", "error", err)
+		return nil, fmt.Errorf("failed to query observation history: %w", err)
+	}
+	defer rows.Close()
+
+	records, err := s.scanObservations(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(records) > limit
+	if hasMore {
+		records = records[:limit]
+	}
+
+	changeCutoff := sinceVersion
+	if len(records) > 0 {
+		changeCutoff = records[len(records)-1].Version
+	}
+
+	s.log.Info("Retrieved records as of version",
+		"asOfVersion", asOfVersion,
+		"sinceVersion", sinceVersion,
+		"recordCount", len(records),
+		"hasMore", hasMore,
+		"clientId", clientID)
+
+	return &SyncResult{
+		CurrentVersion: asOfVersion,
+		Records:        records,
+		ChangeCutoff:   changeCutoff,
+		HasMore:        hasMore,
+	}, nil
+}
+
+// GetVersionAtTime returns the latest sync version recorded for the tenant at or before the given time
+func (s *Service) GetVersionAtTime(ctx context.Context, at time.Time) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM observation_history WHERE recorded_at <= $1 AND tenant_id = $2",
+		at, tenant.FromContext(ctx)).Scan(&version)
+	if err != nil {
+		s.log.Error("Failed to get version at time", "error", err)
+		return 0, fmt.Errorf("failed to get version at time: %w", err)
+	}
+
+	if version == 0 {
+		return 0, fmt.Errorf("%w: no data recorded at or before %s", ErrInvalidData, at.Format(time.RFC3339))
+	}
+	return version, nil
+}
@@ -0,0 +1,209 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// sequenceNamePattern restricts ID sequence names to simple identifiers
+var sequenceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
+// idRangeColumns lists the id_range_allocations columns in scan order
+const idRangeColumns = `id, sequence, client_id, range_start, range_end, allocated_by, allocated_at`
+
+// scanIDRange scans a single ID range allocation row
+func scanIDRange(row rowScanner) (*IDRange, error) {
+	var r IDRange
+	if err := row.Scan(&r.ID, &r.Sequence, &r.ClientID, &r.RangeStart, &r.RangeEnd, &r.AllocatedBy, &r.AllocatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// AllocateIDRange reserves the next count numbers of a sequence for a client. The sequence row
+// is advanced and locked by a single upsert, so concurrent requests always receive disjoint ranges.
+func (s *Service) AllocateIDRange(ctx context.Context, sequence, clientID string, count int) (*IDRange, error) {
+	if !sequenceNamePattern.MatchString(sequence) {
+		return nil, fmt.Errorf("%w: sequence must be 1-100 letters, digits, '_', '.' or '-'", ErrInvalidData)
+	}
+	if clientID == "" {
+		return nil, fmt.Errorf("%w: client_id is required", ErrInvalidData)
+	}
+	if count < 1 || count > MaxIDRangeSize {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidData, MaxIDRangeSize)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	var last int64
+	if s.upstreamIDRanges {
+		last, err = takeFromIDBlocks(ctx, tx, sequence, count)
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO id_sequences (name, last_value)
+			VALUES ($1, $2)
+			ON CONFLICT (name)
+			DO UPDATE SET last_value = id_sequences.last_value + EXCLUDED.last_value, updated_at = NOW()
+			RETURNING last_value`, sequence, count).Scan(&last)
+	}
+	if err != nil {
+		s.log.Error("Failed to advance ID sequence", "error", err, "sequence", sequence)
+		if errors.Is(err, ErrIDRangesExhausted) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to advance ID sequence: %w", err)
+	}
+
+	var allocatedBy *string
+	if username := UsernameFromContext(ctx); username != "" {
+		allocatedBy = &username
+	}
+
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO id_range_allocations (sequence, client_id, range_start, range_end, allocated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+idRangeColumns,
+		sequence, clientID, last-int64(count)+1, last, allocatedBy)
+	allocated, err := scanIDRange(row)
+	if err != nil {
+		s.log.Error("Failed to record ID range allocation", "error", err, "sequence", sequence)
+		return nil, fmt.Errorf("failed to record ID range allocation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	s.log.Info("Allocated ID range",
+		"sequence", sequence,
+		"clientId", clientID,
+		"rangeStart", allocated.RangeStart,
+		"rangeEnd", allocated.RangeEnd)
+
+	return allocated, nil
+}
+
+// ListIDRanges returns the ID ranges reserved for a client, oldest first
+func (s *Service) ListIDRanges(ctx context.Context, clientID string) ([]IDRange, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+idRangeColumns+`
+		FROM id_range_allocations
+		WHERE client_id = $1
+		ORDER BY id`, clientID)
+	if err != nil {
+		s.log.Error("Failed to query ID ranges", "error", err, "clientId", clientID)
+		return nil, fmt.Errorf("failed to query ID ranges: %w", err)
+	}
+	defer rows.Close()
+
+	ranges := make([]IDRange, 0)
+	for rows.Next() {
+		r, err := scanIDRange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ID range: %w", err)
+		}
+		ranges = append(ranges, *r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return ranges, nil
+}
+
+// takeFromIDBlocks carves count numbers from the first block reserved upstream that still has room
+// and returns the last number taken. The local sequence row only records the highest number used.
+func takeFromIDBlocks(ctx context.Context, tx *sql.Tx, sequence string, count int) (int64, error) {
+	var blockID, next int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, next_value
+		FROM id_range_blocks
+		WHERE sequence = $1 AND range_end - next_value + 1 >= $2
+		ORDER BY range_start
+		LIMIT 1
+		FOR UPDATE`, sequence, count).Scan(&blockID, &next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w %q", ErrIDRangesExhausted, sequence)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ID block: %w", err)
+	}
+
+	last := next + int64(count) - 1
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE id_range_blocks SET next_value = $1 WHERE id = $2`, last+1, blockID); err != nil {
+		return 0, fmt.Errorf("failed to advance ID block: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO id_sequences (name, last_value)
+		VALUES ($1, $2)
+		ON CONFLICT (name)
+		DO UPDATE SET last_value = GREATEST(id_sequences.last_value, EXCLUDED.last_value), updated_at = NOW()`,
+		sequence, last)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record ID sequence: %w", err)
+	}
+	return last, nil
+}
+
+// AddIDBlock stores a block of a sequence reserved on the upstream server for local allocation.
+// Adding a block that is already stored has no effect.
+func (s *Service) AddIDBlock(ctx context.Context, block IDRange) error {
+	if !sequenceNamePattern.MatchString(block.Sequence) || block.RangeStart > block.RangeEnd {
+		return fmt.Errorf("%w: invalid ID block", ErrInvalidData)
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO id_range_blocks (sequence, range_start, range_end, next_value)
+		VALUES ($1, $2, $3, $2)
+		ON CONFLICT (sequence, range_start) DO NOTHING`,
+		block.Sequence, block.RangeStart, block.RangeEnd)
+	if err != nil {
+		s.log.Error("Failed to store ID block", "error", err, "sequence", block.Sequence)
+		return fmt.Errorf("failed to store ID block: %w", err)
+	}
+
+	s.log.Info("Stored upstream ID block",
+		"sequence", block.Sequence,
+		"rangeStart", block.RangeStart,
+		"rangeEnd", block.RangeEnd)
+	return nil
+}
+
+// IDBlockStock returns how many numbers of each sequence are left in the blocks reserved upstream
+func (s *Service) IDBlockStock(ctx context.Context, sequences []string) ([]IDBlockStock, error) {
+	stock := make([]IDBlockStock, 0, len(sequences))
+	for _, sequence := range sequences {
+		var remaining int64
+		err := s.db.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(range_end - next_value + 1), 0)
+			FROM id_range_blocks
+			WHERE sequence = $1 AND next_value <= range_end`, sequence).Scan(&remaining)
+		if err != nil {
+			s.log.Error("Failed to get ID block stock", "error", err, "sequence", sequence)
+			return nil, fmt.Errorf("failed to get ID block stock: %w", err)
+		}
+		stock = append(stock, IDBlockStock{Sequence: sequence, Remaining: remaining})
+	}
+	return stock, nil
+}
@@ -3,12 +3,14 @@ package sync
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 )
 
 // TestDatabaseIntegration_VersionIncrement tests that database operations correctly increment current_version
@@ -361,3 +363,310 @@ func TestDatabaseIntegration_VersionConsistency(t *testing.T) {
 		t.Errorf("Expected total version increment of %d, got %d", numOperations, finalIncrement)
 	}
 }
+
+// TestDatabaseIntegration_OutOfOrderCommits tests that a pull never passes a version still held by
+// a transaction in progress, so records committing out of version order are not skipped
+func TestDatabaseIntegration_OutOfOrderCommits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database integration test in short mode")
+	}
+
+	db, cleanup := SetupTestDatabase(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger())
+	ctx := context.Background()
+
+	initialVersion, err := service.GetCurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get initial version: %v", err)
+	}
+
+	// A slow push takes the next version and stays open
+	slow, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer slow.Rollback()
+	if _, err := slow.ExecContext(ctx,
+		"INSERT INTO observations (observation_id, form_type, form_version, data) VALUES ('slow-obs', 'survey', '1.0', '{}')"); err != nil {
+		t.Fatalf("Failed to insert slow record: %v", err)
+	}
+
+	// A later push commits first with a higher version
+	fast := Observation{
+		ObservationID: "fast-obs",
+		FormType:      "survey",
+		FormVersion:   "1.0",
+		Data:          json.RawMessage(`{}`),
+		CreatedAt:     time.Now().Format(time.RFC3339),
+		UpdatedAt:     time.Now().Format(time.RFC3339),
+	}
+	pushed, err := service.ProcessPushedRecords(ctx, []Observation{fast}, "fast-client", "fast-transmission")
+	if err != nil {
+		t.Fatalf("Failed to push records: %v", err)
+	}
+	if pushed.CurrentVersion <= initialVersion+1 {
+		t.Errorf("Expected the push to report a version above %d, got %d", initialVersion+1, pushed.CurrentVersion)
+	}
+
+	// Pulls stop below the open transaction's version
+	pulled, err := service.GetRecordsSinceVersion(ctx, initialVersion, "puller", nil, 10, nil)
+	if err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	if pulled.CurrentVersion != initialVersion || len(pulled.Records) != 0 {
+		t.Fatalf("Expected an empty pull at version %d, got %d records at version %d",
+			initialVersion, len(pulled.Records), pulled.CurrentVersion)
+	}
+
+	if err := slow.Commit(); err != nil {
+		t.Fatalf("Failed to commit slow transaction: %v", err)
+	}
+	pulled, err = service.GetRecordsSinceVersion(ctx, initialVersion, "puller", nil, 10, nil)
+	if err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	if len(pulled.Records) != 2 || pulled.Records[0].ObservationID != "slow-obs" {
+		t.Errorf("Expected both records starting with the slow one, got %+v", pulled.Records)
+	}
+	if pulled.CurrentVersion != pushed.CurrentVersion {
+		t.Errorf("Expected current version %d, got %d", pushed.CurrentVersion, pulled.CurrentVersion)
+	}
+}
+
+// TestDatabaseIntegration_StreamRecords tests that a streamed pull passes the same page to the
+// callback as a buffered pull returns, and stops when the callback fails
+func TestDatabaseIntegration_StreamRecords(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database integration test in short mode")
+	}
+
+	db, cleanup := SetupTestDatabase(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger())
+	ctx := context.Background()
+
+	var records []Observation
+	for _, id := range []string{"stream-1", "stream-2", "stream-3"} {
+		records = append(records, Observation{
+			ObservationID: id,
+			FormType:      "survey",
+			FormVersion:   "1.0",
+			Data:          json.RawMessage(`{}`),
+			CreatedAt:     time.Now().Format(time.RFC3339),
+			UpdatedAt:     time.Now().Format(time.RFC3339),
+		})
+	}
+	if _, err := service.ProcessPushedRecords(ctx, records, "stream-client", "stream-transmission"); err != nil {
+		t.Fatalf("Failed to push records: %v", err)
+	}
+
+	buffered, err := service.GetRecordsSinceVersion(ctx, 0, "stream-client", nil, 2, nil)
+	if err != nil {
+		t.Fatalf("Failed to pull records: %v", err)
+	}
+
+	var streamed []Observation
+	result, err := service.StreamRecordsSinceVersion(ctx, 0, "stream-client", nil, 2, nil, func(obs Observation) error {
+		streamed = append(streamed, obs)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream records: %v", err)
+	}
+	if len(streamed) != 2 || len(buffered.Records) != 2 {
+		t.Fatalf("Expected 2 streamed and buffered records, got %d and %d", len(streamed), len(buffered.Records))
+	}
+	for i := range streamed {
+		if streamed[i].ObservationID != buffered.Records[i].ObservationID {
+			t.Errorf("Record %d: streamed %s, buffered %s", i, streamed[i].ObservationID, buffered.Records[i].ObservationID)
+		}
+	}
+	if result.Records != nil {
+		t.Errorf("Expected no records in the streamed result, got %d", len(result.Records))
+	}
+	if !result.HasMore || result.ChangeCutoff != buffered.ChangeCutoff || result.CurrentVersion != buffered.CurrentVersion {
+		t.Errorf("Streamed result %+v does not match buffered result %+v", result, buffered)
+	}
+
+	// A failing callback stops the pull
+	stop := errors.New("client went away")
+	calls := 0
+	_, err = service.StreamRecordsSinceVersion(ctx, 0, "stream-client", nil, 10, nil, func(obs Observation) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected the callback error after one call, got %v after %d calls", err, calls)
+	}
+}
+
+func TestDatabaseIntegration_AssignedPullScope(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database integration test in short mode")
+	}
+
+	db, cleanup := SetupTestDatabase(t)
+	defer cleanup()
+
+	config := DefaultConfig()
+	config.PullScope = PullScopeAssigned
+	service := NewService(db, config, logger.NewLogger())
+	ctx := context.Background()
+
+	// The coordinator pushes the household listing; enumerators only get what is assigned to them
+	var records []Observation
+	for _, id := range []string{"household-1", "household-2", "household-3"} {
+		records = append(records, Observation{
+			ObservationID: id,
+			FormType:      "household",
+			FormVersion:   "1.0",
+			Data:          json.RawMessage(`{}`),
+			CreatedAt:     time.Now().Format(time.RFC3339),
+			UpdatedAt:     time.Now().Format(time.RFC3339),
+		})
+	}
+	if _, err := service.ProcessPushedRecords(WithUsername(ctx, "coordinator"), records, "office", "listing"); err != nil {
+		t.Fatalf("Failed to push records: %v", err)
+	}
+	pulled := func(ctx context.Context, since int64) []string {
+		t.Helper()
+		result, err := service.GetRecordsSinceVersion(ctx, since, "tablet", nil, 100, nil)
+		if err != nil {
+			t.Fatalf("Failed to pull records: %v", err)
+		}
+		var ids []string
+		for _, obs := range result.Records {
+			ids = append(ids, obs.ObservationID)
+		}
+		return ids
+	}
+	enumerator := WithUsername(ctx, "amina")
+	if ids := pulled(enumerator, 0); len(ids) != 0 {
+		t.Fatalf("Expected no records before assignment, got %v", ids)
+	}
+	sinceVersion, err := service.GetCurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get current version: %v", err)
+	}
+
+	// Assigned records are pulled even though they are older than the enumerator's last pull
+	count, err := service.AssignObservations(ctx, AssignmentRequest{ObservationIDs: []string{"household-2", "missing"}, Username: "amina"}, "admin")
+	if err != nil || count != 1 {
+		t.Fatalf("Expected one assignment, got %d: %v", count, err)
+	}
+	if ids := pulled(enumerator, sinceVersion); len(ids) != 1 || ids[0] != "household-2" {
+		t.Errorf("Expected household-2 after assignment, got %v", ids)
+	}
+
+	// Team assignments reach the users of the org unit
+	var teamID string
+	if err := db.QueryRow(`INSERT INTO org_units (name, path) VALUES ('Team A', '/') RETURNING id`).Scan(&teamID); err != nil {
+		t.Fatalf("Failed to create org unit: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE org_units SET path = '/' || id || '/' WHERE id = $1`, teamID); err != nil {
+		t.Fatalf("Failed to set org unit path: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO user_org_units (username, org_unit_id) VALUES ('baraka', $1)`, teamID); err != nil {
+		t.Fatalf("Failed to assign user to org unit: %v", err)
+	}
+	if _, err := service.AssignObservations(ctx, AssignmentRequest{ObservationIDs: []string{"household-3"}, OrgUnitID: teamID}, "admin"); err != nil {
+		t.Fatalf("Failed to assign to team: %v", err)
+	}
+	if ids := pulled(WithUsername(ctx, "baraka"), 0); len(ids) != 1 || ids[0] != "household-3" {
+		t.Errorf("Expected household-3 for the team member, got %v", ids)
+	}
+
+	// Owners and unscoped pulls still see their records
+	if ids := pulled(WithUsername(ctx, "coordinator"), 0); len(ids) != 3 {
+		t.Errorf("Expected the owner to pull all 3 records, got %v", ids)
+	}
+	if ids := pulled(WithUnscopedPull(enumerator), 0); len(ids) != 3 {
+		t.Errorf("Expected an unscoped pull to return all 3 records, got %v", ids)
+	}
+
+	if count, err := service.UnassignObservations(ctx, AssignmentRequest{ObservationIDs: []string{"household-2"}, Username: "amina"}); err != nil || count != 1 {
+		t.Fatalf("Expected one unassignment, got %d: %v", count, err)
+	}
+	if ids := pulled(enumerator, 0); len(ids) != 0 {
+		t.Errorf("Expected no records after unassignment, got %v", ids)
+	}
+}
+
+// TestDatabaseIntegration_Tenants tests that tenants neither pull nor overwrite each other's records
+func TestDatabaseIntegration_Tenants(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database integration test in short mode")
+	}
+
+	db, cleanup := SetupTestDatabase(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger())
+	acme := tenant.WithTenant(WithUsername(context.Background(), "alice"), "acme")
+	operator := WithUsername(context.Background(), "operator")
+
+	record := Observation{
+		ObservationID: "household-1",
+		FormType:      "household",
+		FormVersion:   "1.0",
+		Data:          json.RawMessage(`{"members": 4}`),
+		CreatedAt:     time.Now().Format(time.RFC3339),
+		UpdatedAt:     time.Now().Format(time.RFC3339),
+	}
+	if _, err := service.ProcessPushedRecords(acme, []Observation{record}, "tablet-1", "acme-push"); err != nil {
+		t.Fatalf("Failed to push records: %v", err)
+	}
+
+	pulled := func(ctx context.Context) int {
+		t.Helper()
+		result, err := service.GetRecordsSinceVersion(ctx, 0, "tablet", nil, 100, nil)
+		if err != nil {
+			t.Fatalf("Failed to pull records: %v", err)
+		}
+		return len(result.Records)
+	}
+	if count := pulled(acme); count != 1 {
+		t.Errorf("Expected the tenant to pull its record, got %d", count)
+	}
+	if count := pulled(operator); count != 0 {
+		t.Errorf("Expected the default tenant to pull no records, got %d", count)
+	}
+
+	// Historical pulls read the history of the tenant only
+	current, err := service.GetCurrentVersion(acme)
+	if err != nil {
+		t.Fatalf("Failed to get current version: %v", err)
+	}
+	for ctx, want := range map[context.Context]int{acme: 1, operator: 0} {
+		result, err := service.GetRecordsAsOfVersion(ctx, current, 0, "tablet", nil, 100, nil)
+		if err != nil {
+			t.Fatalf("Failed to pull records as of version %d: %v", current, err)
+		}
+		if len(result.Records) != want {
+			t.Errorf("Expected %d records as of version %d for %s, got %d", want, current, tenant.FromContext(ctx), len(result.Records))
+		}
+	}
+	if _, err := service.GetVersionAtTime(operator, time.Now()); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected no version recorded for the default tenant, got %v", err)
+	}
+
+	// Another tenant cannot overwrite the record by reusing its ID
+	record.Data = json.RawMessage(`{"members": 0}`)
+	result, err := service.ProcessPushedRecords(operator, []Observation{record}, "tablet-2", "operator-push")
+	if err != nil {
+		t.Fatalf("Failed to push records: %v", err)
+	}
+	if len(result.FailedRecords) != 1 {
+		t.Errorf("Expected the reused ID to fail, got %d failed records", len(result.FailedRecords))
+	}
+	var data string
+	if err := db.QueryRow(`SELECT data::text FROM observations WHERE observation_id = 'household-1'`).Scan(&data); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if data != `{"members": 4}` {
+		t.Errorf("Expected the tenant's record to be unchanged, got %s", data)
+	}
+}
@@ -2,8 +2,11 @@ package sync
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 )
 
 // Common errors
@@ -16,8 +19,96 @@ var (
 	ErrSyncFailed = errors.New("sync operation failed")
 	// ErrVersionConflict is returned when there's a version conflict
 	ErrVersionConflict = errors.New("version conflict")
+	// ErrConflictNotFound is returned when a sync conflict does not exist
+	ErrConflictNotFound = errors.New("conflict not found")
+	// ErrConflictAlreadyResolved is returned when resolving a conflict that was already resolved
+	ErrConflictAlreadyResolved = errors.New("conflict already resolved")
+	// ErrInvalidResolution is returned when a conflict resolution is not recognised
+	ErrInvalidResolution = errors.New("invalid conflict resolution")
+	// ErrFormControlNotFound is returned when no sync control exists for a form type
+	ErrFormControlNotFound = errors.New("form sync control not found")
+	// ErrChecksumMismatch is returned when a pushed payload does not match its content hash
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrCaseNotFound is returned when a case does not exist
+	ErrCaseNotFound = errors.New("case not found")
+	// ErrPeriodLockNotFound is returned when a reporting period lock does not exist
+	ErrPeriodLockNotFound = errors.New("reporting period lock not found")
+	// ErrRecordLocked is returned when a record is locked by another user
+	ErrRecordLocked = errors.New("record is locked")
+	// ErrRecordLockNotFound is returned when a record has no active lock
+	ErrRecordLockNotFound = errors.New("record lock not found")
+	// ErrIDRangesExhausted is returned by an edge server whose ID blocks reserved upstream are used up
+	ErrIDRangesExhausted = errors.New("no ID blocks left for sequence")
 )
 
+// Warning codes returned in sync results
+const (
+	// WarningCodeFormPaused is returned when sync is paused for a form type
+	WarningCodeFormPaused = "FORM_PAUSED"
+	// WarningCodeTimestampSkewed is returned when a client timestamp is implausible but was kept
+	WarningCodeTimestampSkewed = "TIMESTAMP_SKEWED"
+	// WarningCodeTimestampCorrected is returned when a client timestamp was replaced by the server receive time
+	WarningCodeTimestampCorrected = "TIMESTAMP_CORRECTED"
+	// WarningCodePendingApproval is returned when a record falls into a locked reporting period and awaits approval
+	WarningCodePendingApproval = "PENDING_APPROVAL"
+	// WarningCodeResyncRequired is returned when deletions the client has not pulled were purged by compaction
+	WarningCodeResyncRequired = "RESYNC_REQUIRED"
+)
+
+// TimestampPolicy controls how implausible client timestamps are handled
+type TimestampPolicy string
+
+const (
+	// TimestampPolicyFlag stores implausible timestamps unchanged and returns a warning
+	TimestampPolicyFlag TimestampPolicy = "flag"
+	// TimestampPolicyCorrect replaces implausible timestamps with the server receive time
+	TimestampPolicyCorrect TimestampPolicy = "correct"
+)
+
+// ConflictPolicy controls what happens to a pushed record that conflicts with a newer stored one
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyLastWriteWins applies whichever version was updated last
+	ConflictPolicyLastWriteWins ConflictPolicy = "last-write-wins"
+	// ConflictPolicyServerWins keeps the stored record and discards the pushed one
+	ConflictPolicyServerWins ConflictPolicy = "server-wins"
+	// ConflictPolicyRejectAndReport keeps the stored record and queues the pushed one for review
+	ConflictPolicyRejectAndReport ConflictPolicy = "reject-and-report"
+)
+
+// PullScope controls which records a pull returns to users other than admins
+type PullScope string
+
+const (
+	// PullScopeAll returns every record within the user's org unit scope
+	PullScopeAll PullScope = "all"
+	// PullScopeAssigned only returns records the user owns or is assigned, directly or through
+	// an org unit within their scope
+	PullScopeAssigned PullScope = "assigned"
+)
+
+// PushConflictOutcome tells what became of a conflicting pushed record
+type PushConflictOutcome string
+
+const (
+	// PushConflictApplied means the pushed record overwrote the stored one
+	PushConflictApplied PushConflictOutcome = "applied"
+	// PushConflictServerKept means the pushed record was discarded
+	PushConflictServerKept PushConflictOutcome = "server_kept"
+	// PushConflictPendingReview means the pushed record awaits an admin in the conflict backlog
+	PushConflictPendingReview PushConflictOutcome = "pending_review"
+)
+
+// PushConflict reports a pushed record that conflicted with changes the client had not pulled.
+// ServerRecord is the stored record the push was compared with, so the client can reconcile.
+type PushConflict struct {
+	ObservationID string              `json:"observation_id"`
+	Outcome       PushConflictOutcome `json:"outcome"`
+	ClientVersion int64               `json:"client_version,omitempty"`
+	ServerRecord  Observation         `json:"server_record"`
+}
+
 // Geolocation represents geographic coordinates and accuracy information
 type Geolocation struct {
 	Latitude         float64  `json:"latitude"`
@@ -40,6 +131,53 @@ type Observation struct {
 	Deleted       bool         `json:"deleted" db:"deleted"`
 	Version       int64        `json:"version" db:"version"`
 	Geolocation   *Geolocation `json:"geolocation,omitempty" db:"geolocation,json"`
+	// Draft marks a record that only syncs to its owner's devices until finalized
+	Draft bool `json:"draft,omitempty" db:"draft"`
+	// Hash is an optional hex SHA-256 of Data supplied by the client on push for integrity checks
+	Hash string `json:"hash,omitempty" db:"-"`
+	// CreatedBy and Owner are assigned by the server; values sent by clients are ignored
+	CreatedBy *string `json:"created_by,omitempty" db:"created_by"`
+	Owner     *string `json:"owner,omitempty" db:"owner"`
+	// OrgUnitID places the record in the org unit hierarchy and scopes who can pull it
+	OrgUnitID *string `json:"org_unit_id,omitempty" db:"org_unit_id"`
+	// CaseID links the record to a case for longitudinal follow-up
+	CaseID *string `json:"case_id,omitempty" db:"case_id"`
+}
+
+// CaseStatus represents the lifecycle state of a case
+type CaseStatus string
+
+const (
+	// CaseStatusOpen marks a case still under follow-up
+	CaseStatusOpen CaseStatus = "open"
+	// CaseStatusClosed marks a case whose follow-up has ended
+	CaseStatusClosed CaseStatus = "closed"
+)
+
+// Case groups observations of the same subject across visits, e.g. a pregnancy followed
+// through antenatal care. Cases sync alongside observations and share their version sequence.
+type Case struct {
+	// CaseID is generated by the client, so cases can be opened offline
+	CaseID    string          `json:"case_id" db:"case_id"`
+	CaseType  string          `json:"case_type" db:"case_type"`
+	Status    CaseStatus      `json:"status" db:"status"`
+	Data      json.RawMessage `json:"data,omitempty" db:"data"`
+	CreatedAt string          `json:"created_at" db:"created_at"`
+	UpdatedAt string          `json:"updated_at" db:"updated_at"`
+	ClosedAt  *string         `json:"closed_at,omitempty" db:"closed_at"`
+	Deleted   bool            `json:"deleted" db:"deleted"`
+	Version   int64           `json:"version" db:"version"`
+	// CreatedBy is assigned by the server; values sent by clients are ignored
+	CreatedBy *string `json:"created_by,omitempty" db:"created_by"`
+	OrgUnitID *string `json:"org_unit_id,omitempty" db:"org_unit_id"`
+}
+
+// CaseSyncResult represents the result of a case pull
+type CaseSyncResult struct {
+	CurrentVersion int64  `json:"current_version"`
+	Cases          []Case `json:"cases"`
+	ChangeCutoff   int64  `json:"change_cutoff"`
+	HasMore        bool   `json:"has_more"`
 }
 
 // SyncPullCursor represents pagination cursor for sync pull operations
@@ -54,6 +192,7 @@ type SyncResult struct {
 	Records        []Observation `json:"records"`
 	ChangeCutoff   int64         `json:"change_cutoff"`
 	HasMore        bool          `json:"has_more"`
+	Warnings       []SyncWarning `json:"warnings,omitempty"`
 }
 
 // SyncPushResult represents the result of a sync push operation
@@ -62,6 +201,67 @@ type SyncPushResult struct {
 	SuccessCount   int                      `json:"success_count"`
 	FailedRecords  []map[string]interface{} `json:"failed_records,omitempty"`
 	Warnings       []SyncWarning            `json:"warnings,omitempty"`
+	// AssignedFields holds the server-assigned data fields of accepted records by observation ID
+	AssignedFields map[string]map[string]any `json:"assigned_fields,omitempty"`
+	// Conflicts lists pushed records that conflicted with newer stored ones
+	Conflicts []PushConflict `json:"conflicts,omitempty"`
+	// Versions holds the stored version of accepted records that are not drafts by observation
+	// ID, for issuing receipts
+	Versions map[string]int64 `json:"-"`
+}
+
+// FieldAssignmentKind names how the server fills a data field on push
+type FieldAssignmentKind string
+
+const (
+	// FieldAssignmentSequence assigns the next number of a per-form, per-field sequence
+	FieldAssignmentSequence FieldAssignmentKind = "sequence"
+	// FieldAssignmentOrgUnitCode assigns the code of the record's org unit
+	FieldAssignmentOrgUnitCode FieldAssignmentKind = "org_unit_code"
+)
+
+// FieldAssignment declares a data field of a form type whose value is assigned by the server
+type FieldAssignment struct {
+	Field string
+	Kind  FieldAssignmentKind
+	// Prefix and Padding format sequence numbers, e.g. "REG-" and 6 give "REG-000042"
+	Prefix  string
+	Padding int
+}
+
+// FieldAssignmentSource provides the server-assigned fields declared per form type
+type FieldAssignmentSource interface {
+	FieldAssignments(ctx context.Context) (map[string][]FieldAssignment, error)
+}
+
+// FieldAssignmentSourceFunc adapts a function to a FieldAssignmentSource
+type FieldAssignmentSourceFunc func(ctx context.Context) (map[string][]FieldAssignment, error)
+
+// FieldAssignments calls f(ctx)
+func (f FieldAssignmentSourceFunc) FieldAssignments(ctx context.Context) (map[string][]FieldAssignment, error) {
+	return f(ctx)
+}
+
+// CodedField declares a data field of a form type whose values must be codes of a code list
+type CodedField struct {
+	Field string
+	List  string
+}
+
+// CodeListSource provides the coded fields declared per form type and the codes of each list
+type CodeListSource interface {
+	CodedFields(ctx context.Context) (map[string][]CodedField, error)
+	// Codes returns the codes a field referencing the list accepts, or nil when the list does
+	// not exist
+	Codes(ctx context.Context, list string) ([]string, error)
+}
+
+// PushListener is notified of the observations written by each push
+type PushListener interface {
+	// ObservationsPushed is called within the push transaction with the records as stored, so
+	// anything it writes through tx commits or rolls back together with them. Returning an
+	// error fails the push.
+	ObservationsPushed(ctx context.Context, tx *sql.Tx, records []Observation) error
 }
 
 // SyncWarning represents a warning during sync operations
@@ -71,6 +271,239 @@ type SyncWarning struct {
 	Message string `json:"message"`
 }
 
+// ConflictStatus represents the review state of a sync conflict
+type ConflictStatus string
+
+const (
+	// ConflictStatusPending marks a conflict awaiting manual review
+	ConflictStatusPending ConflictStatus = "pending"
+	// ConflictStatusResolved marks a conflict that has been resolved
+	ConflictStatusResolved ConflictStatus = "resolved"
+)
+
+// ConflictResolution describes how a conflict was (or should be) resolved
+type ConflictResolution string
+
+const (
+	// ResolutionKeepServer keeps the stored server record and discards the client version
+	ResolutionKeepServer ConflictResolution = "keep_server"
+	// ResolutionKeepClient overwrites the server record with the client version
+	ResolutionKeepClient ConflictResolution = "keep_client"
+	// ResolutionMerge stores manually merged data supplied by the reviewer
+	ResolutionMerge ConflictResolution = "merge"
+)
+
+// ConflictReason tells why a record was put up for review
+type ConflictReason string
+
+const (
+	// ConflictReasonConflict marks a client edit conflicting with the stored record
+	ConflictReasonConflict ConflictReason = "conflict"
+	// ConflictReasonPeriodLocked marks a push into a locked reporting period awaiting approval
+	ConflictReasonPeriodLocked ConflictReason = "period_locked"
+	// ConflictReasonUpstream marks an upstream record conflicting with an unsynced local edit on an
+	// edge server; the server record is the local one and the client record the upstream one
+	ConflictReasonUpstream ConflictReason = "upstream"
+)
+
+// Conflict represents a detected sync conflict with both versions of the record
+type Conflict struct {
+	ID             int64               `json:"id" db:"id"`
+	ObservationID  string              `json:"observation_id" db:"observation_id"`
+	ClientID       string              `json:"client_id" db:"client_id"`
+	TransmissionID string              `json:"transmission_id,omitempty" db:"transmission_id"`
+	ServerRecord   Observation         `json:"server_record" db:"server_record,json"`
+	ClientRecord   Observation         `json:"client_record" db:"client_record,json"`
+	Status         ConflictStatus      `json:"status" db:"status"`
+	Reason         ConflictReason      `json:"reason" db:"reason"`
+	Resolution     *ConflictResolution `json:"resolution,omitempty" db:"resolution"`
+	ResolvedBy     *string             `json:"resolved_by,omitempty" db:"resolved_by"`
+	DetectedAt     string              `json:"detected_at" db:"detected_at"`
+	ResolvedAt     *string             `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// ConflictFilter narrows down the conflicts returned by ListConflicts
+type ConflictFilter struct {
+	Status        ConflictStatus
+	ObservationID string
+	Reason        ConflictReason
+	Limit         int
+	Offset        int
+}
+
+// ConflictResolveRequest describes a manual resolution of a conflict
+type ConflictResolveRequest struct {
+	Resolution ConflictResolution `json:"resolution"`
+	// Data holds the merged record data and is required for the merge resolution
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// FormSyncControl holds the admin switches pausing sync for a single form type
+type FormSyncControl struct {
+	FormType   string  `json:"form_type" db:"form_type"`
+	PushPaused bool    `json:"push_paused" db:"push_paused"`
+	PullPaused bool    `json:"pull_paused" db:"pull_paused"`
+	Reason     *string `json:"reason,omitempty" db:"reason"`
+	UpdatedBy  *string `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt  string  `json:"updated_at" db:"updated_at"`
+}
+
+// PeriodLockMode controls what happens to pushes into a locked reporting period
+type PeriodLockMode string
+
+const (
+	// PeriodLockModeReject fails records falling into the locked period
+	PeriodLockModeReject PeriodLockMode = "reject"
+	// PeriodLockModeApproval holds records falling into the locked period for admin approval
+	PeriodLockModeApproval PeriodLockMode = "approval"
+)
+
+// ReportingPeriodLock closes a date range of a form type once its figures have been reported
+// upstream. A record falls into the period when its created_at date (UTC), before or after
+// the push, lies within PeriodStart and PeriodEnd inclusive.
+type ReportingPeriodLock struct {
+	ID       int64  `json:"id" db:"id"`
+	FormType string `json:"form_type" db:"form_type"`
+	// PeriodStart and PeriodEnd are dates in YYYY-MM-DD format
+	PeriodStart string         `json:"period_start" db:"period_start"`
+	PeriodEnd   string         `json:"period_end" db:"period_end"`
+	Mode        PeriodLockMode `json:"mode" db:"mode"`
+	Reason      *string        `json:"reason,omitempty" db:"reason"`
+	CreatedBy   *string        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   string         `json:"created_at" db:"created_at"`
+}
+
+// CompactionOptions controls a compaction of the sync log. Zero retentions fall back to the
+// configured ones.
+type CompactionOptions struct {
+	TombstoneRetention time.Duration
+	HistoryRetention   time.Duration
+	// DryRun counts what would be removed without removing it
+	DryRun bool
+	// TriggeredBy names the admin who requested the run, or "schedule"
+	TriggeredBy string
+}
+
+// CompactionReport describes a compaction of the sync log. Records deleted at or before
+// TombstoneCutoffVersion were purged, and record versions superseded at or before
+// HistoryCutoffVersion were collapsed into the latest one.
+type CompactionReport struct {
+	ID                     int64  `json:"id,omitempty" db:"id"`
+	TombstoneCutoffVersion int64  `json:"tombstone_cutoff_version" db:"tombstone_cutoff_version"`
+	HistoryCutoffVersion   int64  `json:"history_cutoff_version" db:"history_cutoff_version"`
+	TombstonesPurged       int64  `json:"tombstones_purged" db:"tombstones_purged"`
+	HistoryVersionsRemoved int64  `json:"history_versions_removed" db:"history_versions_removed"`
+	DryRun                 bool   `json:"dry_run" db:"-"`
+	TriggeredBy            string `json:"triggered_by" db:"triggered_by"`
+	StartedAt              string `json:"started_at" db:"started_at"`
+	FinishedAt             string `json:"finished_at" db:"finished_at"`
+}
+
+// MaxIDRangeSize is the largest block of IDs a client can reserve in one request
+const MaxIDRangeSize = 10000
+
+// IDRange is a block of numbers of a named sequence reserved for one client.
+// Both bounds are inclusive; no other client is ever handed a number within them.
+type IDRange struct {
+	ID          int64   `json:"id" db:"id"`
+	Sequence    string  `json:"sequence" db:"sequence"`
+	ClientID    string  `json:"client_id" db:"client_id"`
+	RangeStart  int64   `json:"range_start" db:"range_start"`
+	RangeEnd    int64   `json:"range_end" db:"range_end"`
+	AllocatedBy *string `json:"allocated_by,omitempty" db:"allocated_by"`
+	AllocatedAt string  `json:"allocated_at" db:"allocated_at"`
+}
+
+// ReplicationResult summarizes applying a page of upstream records on an edge server
+type ReplicationResult struct {
+	// Applied counts records written locally
+	Applied int `json:"applied"`
+	// Unchanged counts records already matching the local copy
+	Unchanged int `json:"unchanged"`
+	// Conflicts counts records held for review because the local copy has unsynced edits
+	Conflicts int `json:"conflicts"`
+}
+
+// ReplicationBacklog is the work an edge server still has to do with its upstream server
+type ReplicationBacklog struct {
+	// PendingRecords counts local changes not yet accepted upstream
+	PendingRecords int64 `json:"pending_records"`
+	// PendingConflicts counts upstream records held for review
+	PendingConflicts int64 `json:"pending_conflicts"`
+}
+
+// IDBlockStock is how many numbers of a sequence an edge server can still hand out
+// from the blocks it reserved upstream
+type IDBlockStock struct {
+	Sequence  string `json:"sequence"`
+	Remaining int64  `json:"remaining"`
+}
+
+// Record lock durations
+const (
+	// DefaultRecordLockTTL is how long a record lock lasts when the claim does not say
+	DefaultRecordLockTTL = 15 * time.Minute
+	// MaxRecordLockTTL is the longest a single claim may hold a record
+	MaxRecordLockTTL = 8 * time.Hour
+)
+
+// RecordLock is an edit claim on an observation. While it is active, pushes of the record by
+// other users fail; the holder extends it by claiming again before it expires.
+type RecordLock struct {
+	ObservationID string `json:"observation_id" db:"observation_id"`
+	LockedBy      string `json:"locked_by" db:"locked_by"`
+	LockedAt      string `json:"locked_at" db:"locked_at"`
+	ExpiresAt     string `json:"expires_at" db:"expires_at"`
+}
+
+// RecordLockedError is returned when a record is locked by another user.
+// It wraps ErrRecordLocked so callers can match it with errors.Is.
+type RecordLockedError struct {
+	Lock RecordLock
+}
+
+func (e *RecordLockedError) Error() string {
+	return fmt.Sprintf("%s by %s until %s", ErrRecordLocked, e.Lock.LockedBy, e.Lock.ExpiresAt)
+}
+
+func (e *RecordLockedError) Unwrap() error {
+	return ErrRecordLocked
+}
+
+// ReassignRequest selects observations to hand over from one user to another
+type ReassignRequest struct {
+	FromUser string `json:"from_user"`
+	ToUser   string `json:"to_user"`
+	// ObservationIDs optionally limits the reassignment to specific observations
+	ObservationIDs []string `json:"observation_ids,omitempty"`
+	// FormType optionally limits the reassignment to a single form type
+	FormType string `json:"form_type,omitempty"`
+}
+
+// Assignment assigns an observation to an enumerator or to a team, an org unit whose users
+// pull it when the pull scope is PullScopeAssigned. Exactly one of Username and OrgUnitID is set.
+type Assignment struct {
+	ObservationID string    `json:"observation_id"`
+	Username      *string   `json:"username,omitempty"`
+	OrgUnitID     *string   `json:"org_unit_id,omitempty"`
+	AssignedBy    string    `json:"assigned_by"`
+	AssignedAt    time.Time `json:"assigned_at"`
+}
+
+// AssignmentRequest assigns observations to, or unassigns them from, a user or an org unit
+type AssignmentRequest struct {
+	ObservationIDs []string `json:"observation_ids"`
+	Username       string   `json:"username,omitempty"`
+	OrgUnitID      string   `json:"org_unit_id,omitempty"`
+}
+
+// AssignmentFilter narrows a list of assignments; empty fields match everything
+type AssignmentFilter struct {
+	ObservationID string
+	Username      string
+	OrgUnitID     string
+}
+
 // SyncItem represents an item to be synchronized
 type SyncItem any
 
@@ -79,12 +512,93 @@ type ServiceInterface interface {
 	// GetRecordsSinceVersion retrieves records that have changed since the specified version
 	GetRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *SyncPullCursor) (*SyncResult, error)
 
+	// StreamRecordsSinceVersion retrieves the same records, passing each to fn as it is read
+	StreamRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *SyncPullCursor, fn func(Observation) error) (*SyncResult, error)
+
 	// ProcessPushedRecords processes records pushed from a client
 	ProcessPushedRecords(ctx context.Context, records []Observation, clientID string, transmissionID string) (*SyncPushResult, error)
 
+	// GetRecordsAsOfVersion retrieves records as they existed at a past version
+	GetRecordsAsOfVersion(ctx context.Context, asOfVersion, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *SyncPullCursor) (*SyncResult, error)
+
+	// GetVersionAtTime returns the latest sync version recorded at or before the given time
+	GetVersionAtTime(ctx context.Context, at time.Time) (int64, error)
+
 	// GetCurrentVersion returns the current database version
 	GetCurrentVersion(ctx context.Context) (int64, error)
 
+	// ListConflicts returns detected sync conflicts matching the filter
+	ListConflicts(ctx context.Context, filter ConflictFilter) ([]Conflict, error)
+
+	// GetConflict returns a single sync conflict by ID
+	GetConflict(ctx context.Context, id int64) (*Conflict, error)
+
+	// ResolveConflict applies a manual resolution to a pending conflict
+	ResolveConflict(ctx context.Context, id int64, req ConflictResolveRequest, resolvedBy string) (*Conflict, error)
+
+	// ListFormSyncControls returns the sync switches of all form types that have one
+	ListFormSyncControls(ctx context.Context) ([]FormSyncControl, error)
+
+	// SetFormSyncControl creates or updates the sync switches of a form type
+	SetFormSyncControl(ctx context.Context, control FormSyncControl) (*FormSyncControl, error)
+
+	// DeleteFormSyncControl removes the sync switches of a form type, resuming sync in both directions
+	DeleteFormSyncControl(ctx context.Context, formType string) error
+
+	// ListPeriodLocks returns all reporting period locks
+	ListPeriodLocks(ctx context.Context) ([]ReportingPeriodLock, error)
+
+	// CreatePeriodLock locks a reporting period of a form type
+	CreatePeriodLock(ctx context.Context, lock ReportingPeriodLock) (*ReportingPeriodLock, error)
+
+	// DeletePeriodLock removes a reporting period lock, reopening the period
+	DeletePeriodLock(ctx context.Context, id int64) error
+
+	// AllocateIDRange reserves the next count numbers of a sequence for a client
+	AllocateIDRange(ctx context.Context, sequence, clientID string, count int) (*IDRange, error)
+
+	// ListIDRanges returns the ID ranges reserved for a client, oldest first
+	ListIDRanges(ctx context.Context, clientID string) ([]IDRange, error)
+
+	// ClaimRecordLock locks an observation for the user in the context, or extends their lock
+	ClaimRecordLock(ctx context.Context, observationID string, ttl time.Duration) (*RecordLock, error)
+
+	// ReleaseRecordLock releases the lock of the user in the context; force releases anyone's lock
+	ReleaseRecordLock(ctx context.Context, observationID string, force bool) error
+
+	// ListRecordLocks returns all active record locks
+	ListRecordLocks(ctx context.Context) ([]RecordLock, error)
+
+	// ReassignObservations transfers ownership of observations between users and returns the number changed
+	ReassignObservations(ctx context.Context, req ReassignRequest) (int64, error)
+
+	// ListAssignments returns the observation assignments matching the filter
+	ListAssignments(ctx context.Context, filter AssignmentFilter) ([]Assignment, error)
+
+	// AssignObservations assigns observations to a user or org unit and returns the number of new assignments
+	AssignObservations(ctx context.Context, req AssignmentRequest, assignedBy string) (int64, error)
+
+	// UnassignObservations removes assignments of observations to a user or org unit and returns the number removed
+	UnassignObservations(ctx context.Context, req AssignmentRequest) (int64, error)
+
+	// GetCasesSinceVersion retrieves cases that have changed since the specified version
+	GetCasesSinceVersion(ctx context.Context, sinceVersion int64, caseTypes []string, limit int) (*CaseSyncResult, error)
+
+	// ProcessPushedCases creates or updates cases pushed from a client
+	ProcessPushedCases(ctx context.Context, cases []Case, clientID string) (*SyncPushResult, error)
+
+	// GetCase returns a single case
+	GetCase(ctx context.Context, caseID string) (*Case, error)
+
+	// GetCaseObservations returns the observations linked to a case, oldest first
+	GetCaseObservations(ctx context.Context, caseID string) ([]Observation, error)
+
+	// Compact purges old tombstones and collapses superseded record versions
+	Compact(ctx context.Context, options CompactionOptions) (*CompactionReport, error)
+
+	// ListCompactions returns the most recent compactions, newest first
+	ListCompactions(ctx context.Context, limit int) ([]CompactionReport, error)
+
 	// Initialize initializes the sync service
 	Initialize(ctx context.Context) error
 }
@@ -96,4 +610,30 @@ type Config struct {
 
 	// DefaultLimit is the default limit when none is specified
 	DefaultLimit int
+
+	// MaxClockSkew is how far in the future a client timestamp may be; zero disables the check
+	MaxClockSkew time.Duration
+
+	// MinValidTimestamp is the earliest plausible client timestamp; zero disables the check
+	MinValidTimestamp time.Time
+
+	// TimestampPolicy decides what happens to client timestamps outside the valid window
+	TimestampPolicy TimestampPolicy
+
+	// ConflictPolicy decides what happens to pushed records conflicting with newer stored ones
+	ConflictPolicy ConflictPolicy
+
+	// TombstoneRetention is how long deleted records are kept for clients to pull; zero keeps them
+	TombstoneRetention time.Duration
+
+	// HistoryRetention is how long superseded record versions are kept for as-of reads; zero keeps them
+	HistoryRetention time.Duration
+
+	// CompactionInterval is how often the sync log is compacted in the background; zero only
+	// compacts on request
+	CompactionInterval time.Duration
+
+	// PullScope decides whether users other than admins pull every record or only those
+	// assigned to them
+	PullScope PullScope
 }
@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// orgUnitScopeSQL selects the ids of all org units at or below the units assigned
+// to the user bound to the given placeholder
+func orgUnitScopeSQL(placeholder string) string {
+	return `SELECT child.id FROM user_org_units uou
+		JOIN org_units parent ON parent.id = uou.org_unit_id
+		JOIN org_units child ON child.path LIKE parent.path || '%'
+		WHERE uou.username = ` + placeholder
+}
+
+// orgUnitFilterSQL restricts observations to the org unit scope of the user bound to
+// argument n. Users without any assignment, and observations without an org unit,
+// are not restricted.
+func orgUnitFilterSQL(n int) string {
+	placeholder := "$" + strconv.Itoa(n)
+	return " AND (org_unit_id IS NULL" +
+		" OR NOT EXISTS (SELECT 1 FROM user_org_units WHERE username = " + placeholder + ")" +
+		" OR org_unit_id IN (" + orgUnitScopeSQL(placeholder) + "))"
+}
+
+// resolveOrgUnit determines the org unit stored with a pushed record. An explicit org
+// unit must exist and lie within the user's scope. Without one, a user assigned to
+// exactly one org unit files new records there.
+func (s *Service) resolveOrgUnit(ctx context.Context, tx *sql.Tx, username string, requested *string) (explicit, fallback *string, err error) {
+	if requested != nil {
+		if _, err := uuid.Parse(*requested); err != nil {
+			return nil, nil, fmt.Errorf("%w: unknown org unit %q", ErrInvalidData, *requested)
+		}
+
+		var exists, inScope bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM org_units WHERE id = $1),
+			       NOT EXISTS (SELECT 1 FROM user_org_units WHERE username = $2)
+			       OR $1 IN (`+orgUnitScopeSQL("$2")+`)`,
+			*requested, username).Scan(&exists, &inScope)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check org unit: %w", err)
+		}
+		if !exists {
+			return nil, nil, fmt.Errorf("%w: unknown org unit %q", ErrInvalidData, *requested)
+		}
+		if !inScope {
+			return nil, nil, fmt.Errorf("%w: org unit %q is outside the user's scope", ErrInvalidData, *requested)
+		}
+		return requested, nil, nil
+	}
+
+	if username == "" {
+		return nil, nil, nil
+	}
+
+	var assigned string
+	err = tx.QueryRowContext(ctx, `
+		SELECT MIN(org_unit_id::TEXT) FROM user_org_units
+		WHERE username = $1
+		HAVING COUNT(*) = 1`, username).Scan(&assigned)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get user org unit: %w", err)
+	}
+	return nil, &assigned, nil
+}
@@ -0,0 +1,59 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ReassignObservations transfers ownership of observations from one user to another,
+// e.g. when an enumerator leaves. Drafts move to the new owner as well. Every changed
+// record gets a new version, so the new ownership reaches clients on their next pull.
+func (s *Service) ReassignObservations(ctx context.Context, req ReassignRequest) (int64, error) {
+	if req.FromUser == "" || req.ToUser == "" {
+		return 0, fmt.Errorf("%w: from_user and to_user are required", ErrInvalidData)
+	}
+	if req.FromUser == req.ToUser {
+		return 0, fmt.Errorf("%w: from_user and to_user must differ", ErrInvalidData)
+	}
+
+	var queryBuilder strings.Builder
+	args := []interface{}{req.ToUser, req.FromUser}
+
+	queryBuilder.WriteString(`
+		UPDATE observations
+		SET owner = $1,
+		    draft_owner = CASE WHEN draft THEN $1 ELSE draft_owner END
+		WHERE owner = $2`)
+
+	if len(req.ObservationIDs) > 0 {
+		args = append(args, pq.Array(req.ObservationIDs))
+		queryBuilder.WriteString(" AND observation_id = ANY($" + strconv.Itoa(len(args)) + ")")
+	}
+	if req.FormType != "" {
+		args = append(args, req.FormType)
+		queryBuilder.WriteString(" AND form_type = $" + strconv.Itoa(len(args)))
+	}
+
+	result, err := s.db.ExecContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		s.log.Error("Failed to reassign observations", "error", err, "fromUser", req.FromUser, "toUser", req.ToUser)
+		return 0, fmt.Errorf("failed to reassign observations: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reassigned count: %w", err)
+	}
+
+	s.log.Info("Reassigned observations",
+		"fromUser", req.FromUser,
+		"toUser", req.ToUser,
+		"formType", req.FormType,
+		"recordCount", count)
+
+	return count, nil
+}
@@ -0,0 +1,131 @@
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidPageToken is returned for page tokens that were not issued by this server, were
+	// altered, or belong to another client or filter
+	ErrInvalidPageToken = errors.New("invalid page token")
+	// ErrPageTokenExpired is returned for page tokens used after their expiry
+	ErrPageTokenExpired = errors.New("page token expired")
+)
+
+// pageTokenVersion is the format version of issued page tokens
+const pageTokenVersion = 1
+
+// PageToken is the state of a paginated pull carried between its pages. Tokens are opaque to
+// clients: they are signed, so the server needs to keep nothing to resume a pull.
+type PageToken struct {
+	V int `json:"v"`
+	// SinceVersion is the since version of the first page, kept for every following page
+	SinceVersion int64 `json:"s"`
+	// Cursor is the position of the last record sent
+	Cursor SyncPullCursor `json:"c"`
+	// Watermark is the current version when the token was issued; the server must not have
+	// gone back behind it, e.g. by restoring a backup
+	Watermark int64 `json:"w"`
+	// Filter is the hash of the schema types and filters the pull was made with
+	Filter   string `json:"f"`
+	ClientID string `json:"cl"`
+	// ExpiresAt is the Unix time after which the token is refused
+	ExpiresAt int64 `json:"e"`
+}
+
+// PageTokenSigner issues and verifies page tokens
+type PageTokenSigner struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewPageTokenSigner creates a signer keyed from secret; tokens are valid for ttl
+func NewPageTokenSigner(secret string, ttl time.Duration) *PageTokenSigner {
+	// Derive a key of its own so page tokens can never pass for other signed values
+	key := sha256.Sum256([]byte("synkronus-page-token:" + secret))
+	return &PageTokenSigner{key: key[:], ttl: ttl, now: time.Now}
+}
+
+// Issue returns the opaque token of a pull position
+func (s *PageTokenSigner) Issue(token PageToken) (string, error) {
+	token.V = pageTokenVersion
+	token.ExpiresAt = s.now().Add(s.ttl).Unix()
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), nil
+}
+
+// Verify checks a token's signature, format and expiry and returns the position it carries.
+// Binding it to the client and filter of the request is left to the caller.
+func (s *PageTokenSigner) Verify(raw string) (*PageToken, error) {
+	encoded, signature, ok := strings.Cut(raw, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalidPageToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	var token PageToken
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&token); err != nil || token.V != pageTokenVersion {
+		return nil, ErrInvalidPageToken
+	}
+	if token.Cursor.Version <= token.SinceVersion || token.Cursor.Version > token.Watermark || token.Cursor.ID == "" {
+		return nil, ErrInvalidPageToken
+	}
+	if s.now().Unix() > token.ExpiresAt {
+		return nil, ErrPageTokenExpired
+	}
+	return &token, nil
+}
+
+func (s *PageTokenSigner) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PullFilterHash returns a hash of what narrows a pull, so a page token cannot be resumed with
+// other schema types or filters. The order of schema types does not matter; asOf tells how a
+// past state was selected and is empty for live pulls.
+func PullFilterHash(schemaTypes []string, filter PullFilter, asOf string) string {
+	types := append([]string(nil), schemaTypes...)
+	sort.Strings(types)
+	content, _ := json.Marshal(struct {
+		SchemaTypes []string   `json:"schema_types"`
+		Filter      PullFilter `json:"filter"`
+		AsOf        string     `json:"as_of"`
+	}{types, filter, asOf})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Validate checks that a verified token is used by the client it was issued to, with the same
+// filter, against a server that has not gone back behind its watermark
+func (t *PageToken) Validate(clientID, filterHash string, currentVersion int64) error {
+	if t.ClientID != clientID {
+		return fmt.Errorf("%w: issued to another client", ErrInvalidPageToken)
+	}
+	if t.Filter != filterHash {
+		return fmt.Errorf("%w: schema types or filters changed", ErrInvalidPageToken)
+	}
+	if t.Watermark > currentVersion {
+		return fmt.Errorf("%w: the server's data is older than the token", ErrInvalidPageToken)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/tenant"
+)
+
+// periodDateLayout is the format of reporting period bounds
+const periodDateLayout = "2006-01-02"
+
+// periodLock is a reporting period lock with parsed bounds
+type periodLock struct {
+	FormType string
+	Start    time.Time
+	End      time.Time
+	Mode     PeriodLockMode
+}
+
+// contains reports whether the UTC date of t lies within the lock period
+func (l periodLock) contains(t time.Time) bool {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.Before(l.Start) && !day.After(l.End)
+}
+
+// message describes the lock for records it rejects
+func (l periodLock) message() string {
+	return fmt.Sprintf("reporting period %s to %s is locked for form type %q",
+		l.Start.Format(periodDateLayout), l.End.Format(periodDateLayout), l.FormType)
+}
+
+// loadPeriodLocks returns all reporting period locks grouped by form type
+func loadPeriodLocks(ctx context.Context, q queryer) (map[string][]periodLock, error) {
+	rows, err := q.QueryContext(ctx, "SELECT form_type, period_start, period_end, mode FROM reporting_period_locks")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reporting period locks: %w", err)
+	}
+	defer rows.Close()
+
+	locks := make(map[string][]periodLock)
+	for rows.Next() {
+		var lock periodLock
+		if err := rows.Scan(&lock.FormType, &lock.Start, &lock.End, &lock.Mode); err != nil {
+			return nil, fmt.Errorf("failed to scan reporting period lock: %w", err)
+		}
+		locks[lock.FormType] = append(locks[lock.FormType], lock)
+	}
+
+	return locks, rows.Err()
+}
+
+// matchPeriodLock returns the strictest lock of the record's form type covering any of the
+// given collection timestamps, or nil. Reject locks take precedence over approval locks.
+func matchPeriodLock(locks []periodLock, timestamps ...string) *periodLock {
+	var match *periodLock
+	for _, value := range timestamps {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			continue
+		}
+		for i := range locks {
+			if !locks[i].contains(t) {
+				continue
+			}
+			if match == nil || locks[i].Mode == PeriodLockModeReject {
+				match = &locks[i]
+			}
+		}
+	}
+	return match
+}
+
+// storedObservation returns the stored version of an observation of the tenant in ctx within
+// tx, or nil if it is new
+func (s *Service) storedObservation(ctx context.Context, tx *sql.Tx, observationID string) (*Observation, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT observation_id, form_type, form_version, data,
+		       created_at, updated_at, synced_at, deleted, version, draft, created_by, owner, org_unit_id, case_id
+		FROM observations
+		WHERE observation_id = $1 AND tenant_id = $2`, observationID, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored observation: %w", err)
+	}
+	defer rows.Close()
+
+	records, err := s.scanObservations(rows)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// queueForApproval parks a record pushed into a locked reporting period in the review
+// backlog. Approving it with the keep_client resolution writes it to observations.
+func queueForApproval(ctx context.Context, tx *sql.Tx, record Observation, stored *Observation, clientID, transmissionID string) error {
+	return queueConflict(ctx, tx, record, stored, clientID, transmissionID, ConflictReasonPeriodLocked)
+}
+
+// queueConflict records a pushed record that was not applied, next to the stored one, for review
+func queueConflict(ctx context.Context, tx *sql.Tx, record Observation, stored *Observation, clientID, transmissionID string, reason ConflictReason) error {
+	if stored == nil {
+		stored = &Observation{}
+	}
+	serverRecord, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode server record: %w", err)
+	}
+	clientRecord, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode client record: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_conflicts (observation_id, client_id, transmission_id, server_record, client_record, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		record.ObservationID, clientID, transmissionID, serverRecord, clientRecord, string(reason))
+	if err != nil {
+		return fmt.Errorf("failed to queue record for review: %w", err)
+	}
+	return nil
+}
+
+// ListPeriodLocks returns all reporting period locks ordered by form type and period
+func (s *Service) ListPeriodLocks(ctx context.Context) ([]ReportingPeriodLock, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, form_type, period_start, period_end, mode, reason, created_by, created_at
+		FROM reporting_period_locks
+		ORDER BY form_type, period_start`)
+	if err != nil {
+		s.log.Error("Failed to query reporting period locks", "error", err)
+		return nil, fmt.Errorf("failed to query reporting period locks: %w", err)
+	}
+	defer rows.Close()
+
+	locks := make([]ReportingPeriodLock, 0)
+	for rows.Next() {
+		lock, err := scanPeriodLock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reporting period lock: %w", err)
+		}
+		locks = append(locks, *lock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return locks, nil
+}
+
+// scanPeriodLock scans a single reporting period lock row
+func scanPeriodLock(row rowScanner) (*ReportingPeriodLock, error) {
+	var lock ReportingPeriodLock
+	var start, end time.Time
+	if err := row.Scan(&lock.ID, &lock.FormType, &start, &end, &lock.Mode, &lock.Reason, &lock.CreatedBy, &lock.CreatedAt); err != nil {
+		return nil, err
+	}
+	lock.PeriodStart = start.Format(periodDateLayout)
+	lock.PeriodEnd = end.Format(periodDateLayout)
+	return &lock, nil
+}
+
+// CreatePeriodLock locks a reporting period of a form type
+func (s *Service) CreatePeriodLock(ctx context.Context, lock ReportingPeriodLock) (*ReportingPeriodLock, error) {
+	if lock.FormType == "" {
+		return nil, fmt.Errorf("%w: form_type is required", ErrInvalidData)
+	}
+	start, err := time.Parse(periodDateLayout, lock.PeriodStart)
+	if err != nil {
+		return nil, fmt.Errorf("%w: period_start must be a YYYY-MM-DD date", ErrInvalidData)
+	}
+	end, err := time.Parse(periodDateLayout, lock.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: period_end must be a YYYY-MM-DD date", ErrInvalidData)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: period_end must not be before period_start", ErrInvalidData)
+	}
+	if lock.Mode == "" {
+		lock.Mode = PeriodLockModeReject
+	}
+	if lock.Mode != PeriodLockModeReject && lock.Mode != PeriodLockModeApproval {
+		return nil, fmt.Errorf("%w: mode must be %q or %q", ErrInvalidData, PeriodLockModeReject, PeriodLockModeApproval)
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO reporting_period_locks (form_type, period_start, period_end, mode, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, form_type, period_start, period_end, mode, reason, created_by, created_at`,
+		lock.FormType, lock.PeriodStart, lock.PeriodEnd, string(lock.Mode), lock.Reason, lock.CreatedBy)
+	created, err := scanPeriodLock(row)
+	if err != nil {
+		s.log.Error("Failed to create reporting period lock", "error", err, "formType", lock.FormType)
+		return nil, fmt.Errorf("failed to create reporting period lock: %w", err)
+	}
+
+	s.log.Info("Reporting period locked",
+		"formType", created.FormType,
+		"periodStart", created.PeriodStart,
+		"periodEnd", created.PeriodEnd,
+		"mode", created.Mode)
+
+	return created, nil
+}
+
+// DeletePeriodLock removes a reporting period lock, reopening the period
+func (s *Service) DeletePeriodLock(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM reporting_period_locks WHERE id = $1", id)
+	if err != nil {
+		s.log.Error("Failed to delete reporting period lock", "error", err, "lockId", id)
+		return fmt.Errorf("failed to delete reporting period lock: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrPeriodLockNotFound
+	}
+
+	s.log.Info("Reporting period lock removed", "lockId", id)
+	return nil
+}
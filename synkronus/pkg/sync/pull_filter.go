@@ -0,0 +1,116 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// pullFilterKey is the context key holding the pull filter of a sync
+const pullFilterKey contextKey = "syncPullFilter"
+
+// BoundingBox selects observations whose geolocation lies within it, edges included. A box
+// whose minimum longitude is greater than its maximum crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// PullFilter narrows a pull to recent or local observations. Unset fields do not filter.
+type PullFilter struct {
+	CreatedAfter *time.Time
+	UpdatedAfter *time.Time
+	BoundingBox  *BoundingBox
+}
+
+// WithPullFilter returns a context carrying a filter applied to the records of a pull
+func WithPullFilter(ctx context.Context, filter PullFilter) context.Context {
+	return context.WithValue(ctx, pullFilterKey, filter)
+}
+
+// PullFilterFromContext returns the filter set by WithPullFilter, or an empty filter
+func PullFilterFromContext(ctx context.Context) PullFilter {
+	filter, _ := ctx.Value(pullFilterKey).(PullFilter)
+	return filter
+}
+
+// IsEmpty reports whether the filter lets every record through
+func (f PullFilter) IsEmpty() bool {
+	return f.CreatedAfter == nil && f.UpdatedAfter == nil && f.BoundingBox == nil
+}
+
+// Validate checks that the bounding box describes a region on the globe
+func (f PullFilter) Validate() error {
+	box := f.BoundingBox
+	if box == nil {
+		return nil
+	}
+	if box.MinLatitude < -90 || box.MaxLatitude > 90 || box.MinLatitude > box.MaxLatitude {
+		return fmt.Errorf("%w: bounding box latitudes must satisfy -90 <= min_latitude <= max_latitude <= 90", ErrInvalidData)
+	}
+	if box.MinLongitude < -180 || box.MinLongitude > 180 || box.MaxLongitude < -180 || box.MaxLongitude > 180 {
+		return fmt.Errorf("%w: bounding box longitudes must be between -180 and 180", ErrInvalidData)
+	}
+	return nil
+}
+
+// Matches reports whether an observation passes the filter. Observations without a
+// geolocation never lie within a bounding box.
+func (f PullFilter) Matches(obs Observation) bool {
+	if !timestampAfter(obs.CreatedAt, f.CreatedAfter) || !timestampAfter(obs.UpdatedAt, f.UpdatedAfter) {
+		return false
+	}
+	if box := f.BoundingBox; box != nil {
+		geo := obs.Geolocation
+		if geo == nil || geo.Latitude < box.MinLatitude || geo.Latitude > box.MaxLatitude {
+			return false
+		}
+		if box.MinLongitude <= box.MaxLongitude {
+			return geo.Longitude >= box.MinLongitude && geo.Longitude <= box.MaxLongitude
+		}
+		return geo.Longitude >= box.MinLongitude || geo.Longitude <= box.MaxLongitude
+	}
+	return true
+}
+
+// timestampAfter reports whether an RFC 3339 timestamp lies after bound; a nil bound always passes
+func timestampAfter(value string, bound *time.Time) bool {
+	if bound == nil {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	return err == nil && t.After(*bound)
+}
+
+// pullFilterSQL appends the conditions of the pull filter in ctx to a query over observations
+// or their history, whose arguments so far are args
+func pullFilterSQL(ctx context.Context, args []interface{}) (string, []interface{}) {
+	filter := PullFilterFromContext(ctx)
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var clause string
+	if filter.CreatedAfter != nil {
+		clause += " AND created_at > " + placeholder(*filter.CreatedAfter)
+	}
+	if filter.UpdatedAfter != nil {
+		clause += " AND updated_at > " + placeholder(*filter.UpdatedAfter)
+	}
+	if box := filter.BoundingBox; box != nil {
+		latitude := "(geolocation->>'latitude')::DOUBLE PRECISION"
+		longitude := "(geolocation->>'longitude')::DOUBLE PRECISION"
+		clause += " AND geolocation IS NOT NULL AND " + latitude + " BETWEEN " +
+			placeholder(box.MinLatitude) + " AND " + placeholder(box.MaxLatitude)
+		if box.MinLongitude <= box.MaxLongitude {
+			clause += " AND " + longitude + " BETWEEN " + placeholder(box.MinLongitude) + " AND " + placeholder(box.MaxLongitude)
+		} else {
+			clause += " AND (" + longitude + " >= " + placeholder(box.MinLongitude) + " OR " + longitude + " <= " + placeholder(box.MaxLongitude) + ")"
+		}
+	}
+	return clause, args
+}
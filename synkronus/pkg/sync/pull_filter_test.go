@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPullFilterMatchesBoundingBox(t *testing.T) {
+	// A box around Fiji crosses the antimeridian
+	fiji := PullFilter{BoundingBox: &BoundingBox{MinLatitude: -21, MinLongitude: 176, MaxLatitude: -12, MaxLongitude: -178}}
+
+	tests := []struct {
+		name string
+		geo  *Geolocation
+		want bool
+	}{
+		{"west of the antimeridian", &Geolocation{Latitude: -18.1, Longitude: 178.4}, true},
+		{"east of the antimeridian", &Geolocation{Latitude: -16.5, Longitude: -179.9}, true},
+		{"outside the longitudes", &Geolocation{Latitude: -18.1, Longitude: 170}, false},
+		{"outside the latitudes", &Geolocation{Latitude: -30, Longitude: 178}, false},
+		{"without geolocation", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fiji.Matches(Observation{Geolocation: tt.geo}); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPullFilterMatchesTimestamps(t *testing.T) {
+	bound := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	filter := PullFilter{CreatedAfter: &bound}
+
+	if !filter.Matches(Observation{CreatedAt: "2025-05-01T00:00:01Z"}) {
+		t.Error("expected a record created after the bound to match")
+	}
+	if filter.Matches(Observation{CreatedAt: "2025-05-01T00:00:00Z"}) {
+		t.Error("expected a record created at the bound not to match")
+	}
+	if filter.Matches(Observation{CreatedAt: "not a timestamp"}) {
+		t.Error("expected an unparseable timestamp not to match")
+	}
+}
+
+func TestPullFilterSQL(t *testing.T) {
+	bound := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	ctx := WithPullFilter(context.Background(), PullFilter{
+		UpdatedAfter: &bound,
+		BoundingBox:  &BoundingBox{MinLatitude: -21, MinLongitude: 176, MaxLatitude: -12, MaxLongitude: -178},
+	})
+
+	clause, args := pullFilterSQL(ctx, []interface{}{int64(0), int64(10)})
+	if len(args) != 7 {
+		t.Fatalf("expected 7 arguments, got %d", len(args))
+	}
+	for _, want := range []string{"updated_at > $3", "BETWEEN $4 AND $5", ">= $6 OR", "<= $7)"} {
+		if !strings.Contains(clause, want) {
+			t.Errorf("expected %q in %q", want, clause)
+		}
+	}
+
+	if clause, args := pullFilterSQL(context.Background(), nil); clause != "" || len(args) != 0 {
+		t.Errorf("expected no conditions without a filter, got %q", clause)
+	}
+}
@@ -0,0 +1,31 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ReadReplica picks the database pulled records are read from
+type ReadReplica interface {
+	// Reader returns a database that sees every transaction committed on the primary before the
+	// call: a read replica that has caught up, or the primary
+	Reader(ctx context.Context) *sql.DB
+}
+
+// WithReadReplica reads pulled records from a read replica, taking load off the primary when
+// many devices sync. Pushes and the current version stay on the primary.
+func WithReadReplica(replica ReadReplica) Option {
+	return func(s *Service) {
+		s.replica = replica
+	}
+}
+
+// pullDB returns the database pulled records are read from. It is called after the current
+// version is read on the primary, so a replica is only used once it has every record up to that
+// version and a client never moves past records it has not received.
+func (s *Service) pullDB(ctx context.Context) *sql.DB {
+	if s.replica == nil {
+		return s.db
+	}
+	return s.replica.Reader(ctx)
+}
@@ -0,0 +1,72 @@
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"strconv"
+	"strings"
+)
+
+// receiptAlphabet is Crockford's base32, which leaves out letters easily mistaken for digits
+const receiptAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// receiptCodeLength is the number of characters of a code, 40 bits of its HMAC
+const receiptCodeLength = 8
+
+// Receipt proves that a version of an observation reached the server. Its code is short enough
+// for a field worker to write down or read out to a supervisor.
+type Receipt struct {
+	ObservationID string `json:"observation_id"`
+	Version       int64  `json:"version"`
+	// Code is an HMAC of the observation ID and version, as XXXX-XXXX
+	Code string `json:"code"`
+}
+
+// ReceiptSigner issues and verifies the verification codes of receipts
+type ReceiptSigner struct {
+	key []byte
+}
+
+// NewReceiptSigner creates a signer keyed from secret. Codes issued with one secret do not
+// verify with another, so the secret must be kept as long as receipts are to be checked.
+func NewReceiptSigner(secret string) *ReceiptSigner {
+	// Derive a key of its own so codes can never pass for other signed values
+	key := sha256.Sum256([]byte("synkronus-receipt:" + secret))
+	return &ReceiptSigner{key: key[:]}
+}
+
+// Issue returns the receipt of a stored version of an observation of a tenant
+func (s *ReceiptSigner) Issue(tenantID, observationID string, version int64) Receipt {
+	return Receipt{ObservationID: observationID, Version: version, Code: s.code(tenantID, observationID, version)}
+}
+
+// Verify reports whether a receipt's code was issued for its observation and version. Codes
+// are compared case-insensitively, ignoring dashes and spaces, and with O, I and L read as the
+// digits they are mistaken for.
+func (s *ReceiptSigner) Verify(tenantID string, receipt Receipt) bool {
+	code := normalizeReceiptCode(receipt.Code)
+	expected := normalizeReceiptCode(s.code(tenantID, receipt.ObservationID, receipt.Version))
+	return hmac.Equal([]byte(code), []byte(expected))
+}
+
+func (s *ReceiptSigner) code(tenantID, observationID string, version int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(tenantID + "\n" + observationID + "\n" + strconv.FormatInt(version, 10)))
+	sum := mac.Sum(nil)
+
+	var bits uint64
+	for _, b := range sum[:5] {
+		bits = bits<<8 | uint64(b)
+	}
+	code := make([]byte, receiptCodeLength)
+	for i := receiptCodeLength - 1; i >= 0; i-- {
+		code[i] = receiptAlphabet[bits&31]
+		bits >>= 5
+	}
+	return string(code[:4]) + "-" + string(code[4:])
+}
+
+// normalizeReceiptCode undoes the ways a code is commonly copied by hand
+func normalizeReceiptCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1").Replace(strings.ToUpper(code))
+}
@@ -0,0 +1,46 @@
+package sync
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReceiptSigner(t *testing.T) {
+	signer := NewReceiptSigner("secret")
+	receipt := signer.Issue("default", "household-1", 42)
+
+	if !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}$`).MatchString(receipt.Code) {
+		t.Fatalf("Unexpected code format %q", receipt.Code)
+	}
+	if receipt.ObservationID != "household-1" || receipt.Version != 42 {
+		t.Errorf("Unexpected receipt %+v", receipt)
+	}
+
+	copied := strings.ToLower(strings.ReplaceAll(receipt.Code, "-", " "))
+	copied = strings.NewReplacer("0", "o", "1", "l").Replace(copied)
+
+	tests := []struct {
+		name    string
+		signer  *ReceiptSigner
+		tenant  string
+		receipt Receipt
+		valid   bool
+	}{
+		{"issued receipt", signer, "default", receipt, true},
+		{"code copied by hand", signer, "default", Receipt{"household-1", 42, copied}, true},
+		{"other version", signer, "default", Receipt{"household-1", 43, receipt.Code}, false},
+		{"other observation", signer, "default", Receipt{"household-2", 42, receipt.Code}, false},
+		{"other tenant", signer, "acme", receipt, false},
+		{"other secret", NewReceiptSigner("other"), "default", receipt, false},
+		{"empty code", signer, "default", Receipt{"household-1", 42, ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := tt.signer.Verify(tt.tenant, tt.receipt); valid != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// recordLockColumns lists the record_locks columns in scan order
+const recordLockColumns = `observation_id, locked_by, locked_at, expires_at`
+
+// scanRecordLock scans a single record lock row
+func scanRecordLock(row rowScanner) (*RecordLock, error) {
+	var l RecordLock
+	if err := row.Scan(&l.ObservationID, &l.LockedBy, &l.LockedAt, &l.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ClaimRecordLock locks an observation for the user in the context. Claiming a record the user
+// already holds extends the lock; an expired lock of another user is taken over. The claim is a
+// single upsert, so of two concurrent claims exactly one wins.
+func (s *Service) ClaimRecordLock(ctx context.Context, observationID string, ttl time.Duration) (*RecordLock, error) {
+	username := UsernameFromContext(ctx)
+	if username == "" {
+		return nil, fmt.Errorf("%w: record locks require an authenticated user", ErrInvalidData)
+	}
+	if observationID == "" {
+		return nil, fmt.Errorf("%w: observation_id is required", ErrInvalidData)
+	}
+	if ttl == 0 {
+		ttl = DefaultRecordLockTTL
+	}
+	if ttl < time.Second || ttl > MaxRecordLockTTL {
+		return nil, fmt.Errorf("%w: lock duration must be between 1s and %s", ErrInvalidData, MaxRecordLockTTL)
+	}
+
+	lock, err := scanRecordLock(s.db.QueryRowContext(ctx, `
+		INSERT INTO record_locks (observation_id, locked_by, locked_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW() + make_interval(secs => $3))
+		ON CONFLICT (observation_id) DO UPDATE
+		SET locked_by = EXCLUDED.locked_by,
+			locked_at = CASE WHEN record_locks.locked_by = EXCLUDED.locked_by AND record_locks.expires_at > NOW()
+				THEN record_locks.locked_at ELSE EXCLUDED.locked_at END,
+			expires_at = EXCLUDED.expires_at
+		WHERE record_locks.locked_by = EXCLUDED.locked_by OR record_locks.expires_at <= NOW()
+		RETURNING `+recordLockColumns,
+		observationID, username, ttl.Seconds()))
+	if err == nil {
+		s.log.Info("Record lock claimed", "observationId", observationID, "user", username, "expiresAt", lock.ExpiresAt)
+		return lock, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		s.log.Error("Failed to claim record lock", "error", err, "observationId", observationID)
+		return nil, fmt.Errorf("failed to claim record lock: %w", err)
+	}
+
+	// Another user holds an active lock
+	holder, err := scanRecordLock(s.db.QueryRowContext(ctx,
+		"SELECT "+recordLockColumns+" FROM record_locks WHERE observation_id = $1", observationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Released in the meantime; the caller may simply retry
+			return nil, &RecordLockedError{Lock: RecordLock{ObservationID: observationID}}
+		}
+		return nil, fmt.Errorf("failed to get record lock: %w", err)
+	}
+	return nil, &RecordLockedError{Lock: *holder}
+}
+
+// ReleaseRecordLock releases the active lock the user in the context holds on an observation.
+// With force, the lock is released whoever holds it; callers must restrict this to admins.
+func (s *Service) ReleaseRecordLock(ctx context.Context, observationID string, force bool) error {
+	username := UsernameFromContext(ctx)
+
+	if force {
+		result, err := s.db.ExecContext(ctx,
+			"DELETE FROM record_locks WHERE observation_id = $1 AND expires_at > NOW()", observationID)
+		if err != nil {
+			s.log.Error("Failed to release record lock", "error", err, "observationId", observationID)
+			return fmt.Errorf("failed to release record lock: %w", err)
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if count == 0 {
+			return ErrRecordLockNotFound
+		}
+		s.log.Info("Record lock force-released", "observationId", observationID, "user", username)
+		return nil
+	}
+
+	holder, err := scanRecordLock(s.db.QueryRowContext(ctx, `
+		SELECT `+recordLockColumns+` FROM record_locks
+		WHERE observation_id = $1 AND expires_at > NOW()`, observationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordLockNotFound
+		}
+		return fmt.Errorf("failed to get record lock: %w", err)
+	}
+	if holder.LockedBy != username {
+		return &RecordLockedError{Lock: *holder}
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		"DELETE FROM record_locks WHERE observation_id = $1 AND locked_by = $2", observationID, username); err != nil {
+		s.log.Error("Failed to release record lock", "error", err, "observationId", observationID)
+		return fmt.Errorf("failed to release record lock: %w", err)
+	}
+
+	s.log.Info("Record lock released", "observationId", observationID, "user", username)
+	return nil
+}
+
+// ListRecordLocks returns all active record locks, soonest expiring first
+func (s *Service) ListRecordLocks(ctx context.Context) ([]RecordLock, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+recordLockColumns+`
+		FROM record_locks
+		WHERE expires_at > NOW()
+		ORDER BY expires_at, observation_id`)
+	if err != nil {
+		s.log.Error("Failed to query record locks", "error", err)
+		return nil, fmt.Errorf("failed to query record locks: %w", err)
+	}
+	defer rows.Close()
+
+	locks := make([]RecordLock, 0)
+	for rows.Next() {
+		l, err := scanRecordLock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan record lock: %w", err)
+		}
+		locks = append(locks, *l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return locks, nil
+}
+
+// loadRecordLocks returns the active record locks held by users other than username, by observation ID
+func loadRecordLocks(ctx context.Context, q queryer, username string) (map[string]RecordLock, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+recordLockColumns+`
+		FROM record_locks
+		WHERE expires_at > NOW() AND locked_by <> $1`, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query record locks: %w", err)
+	}
+	defer rows.Close()
+
+	locks := make(map[string]RecordLock)
+	for rows.Next() {
+		l, err := scanRecordLock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan record lock: %w", err)
+		}
+		locks[l.ObservationID] = *l
+	}
+
+	return locks, rows.Err()
+}
@@ -0,0 +1,265 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// WithUpstreamIDRanges makes the service hand out ID ranges only from blocks reserved on an
+// upstream server, so numbers allocated at an edge server never collide with other sites
+func WithUpstreamIDRanges() Option {
+	return func(s *Service) {
+		s.upstreamIDRanges = true
+	}
+}
+
+// PendingReplication returns finalized observations changed locally since they were last known to
+// match the upstream server, ordered by version. Records held in the conflict backlog because of an
+// upstream conflict wait until an admin resolves it.
+func (s *Service) PendingReplication(ctx context.Context, afterVersion int64, limit int) ([]Observation, error) {
+	if limit <= 0 {
+		limit = s.config.DefaultLimit
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.observation_id, o.form_type, o.form_version, o.data,
+		       o.created_at, o.updated_at, o.synced_at, o.deleted, o.version, o.draft, o.created_by, o.owner, o.org_unit_id, o.case_id
+		FROM observations o
+		LEFT JOIN replicated_records r ON r.observation_id = o.observation_id
+		WHERE o.version > $1
+		  AND NOT o.draft
+		  AND (r.synced_version IS NULL OR r.synced_version <> o.version)
+		  AND NOT EXISTS (
+			SELECT 1 FROM sync_conflicts c
+			WHERE c.observation_id = o.observation_id AND c.reason = $2 AND c.status = $3)
+		ORDER BY o.version ASC
+		LIMIT $4`,
+		afterVersion, string(ConflictReasonUpstream), string(ConflictStatusPending), limit)
+	if err != nil {
+		s.log.Error("Failed to query records pending replication", "error", err)
+		return nil, fmt.Errorf("failed to query records pending replication: %w", err)
+	}
+	defer rows.Close()
+
+	records, err := s.scanObservations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []Observation{}
+	}
+	return records, nil
+}
+
+// ReplicationBacklog returns how many records wait to be pushed upstream and how many upstream
+// conflicts wait for review
+func (s *Service) ReplicationBacklog(ctx context.Context) (*ReplicationBacklog, error) {
+	var backlog ReplicationBacklog
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*)
+			 FROM observations o
+			 LEFT JOIN replicated_records r ON r.observation_id = o.observation_id
+			 WHERE NOT o.draft
+			   AND (r.synced_version IS NULL OR r.synced_version <> o.version)
+			   AND NOT EXISTS (
+				SELECT 1 FROM sync_conflicts c
+				WHERE c.observation_id = o.observation_id AND c.reason = $1 AND c.status = $2)),
+			(SELECT COUNT(*) FROM sync_conflicts WHERE reason = $1 AND status = $2)`,
+		string(ConflictReasonUpstream), string(ConflictStatusPending)).Scan(&backlog.PendingRecords, &backlog.PendingConflicts)
+	if err != nil {
+		s.log.Error("Failed to count replication backlog", "error", err)
+		return nil, fmt.Errorf("failed to count replication backlog: %w", err)
+	}
+	return &backlog, nil
+}
+
+// MarkReplicated records that the given local versions of observations, keyed by observation ID,
+// were accepted upstream
+func (s *Service) MarkReplicated(ctx context.Context, versions map[string]int64) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.Error("Failed to begin transaction", "error", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	for observationID, version := range versions {
+		if err := markReplicated(ctx, tx, observationID, version); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("Failed to commit transaction", "error", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// markReplicated upserts the synced version of one observation within tx
+func markReplicated(ctx context.Context, tx *sql.Tx, observationID string, version int64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO replicated_records (observation_id, synced_version)
+		VALUES ($1, $2)
+		ON CONFLICT (observation_id)
+		DO UPDATE SET synced_version = EXCLUDED.synced_version, synced_at = NOW()`,
+		observationID, version)
+	if err != nil {
+		return fmt.Errorf("failed to mark record replicated: %w", err)
+	}
+	return nil
+}
+
+// ApplyReplicatedRecords writes records pulled from the upstream server. Server-assigned fields of
+// upstream records are kept as they are. A record whose local copy has edits not yet pushed upstream
+// is not applied but held in the conflict backlog with reason upstream, and the local edit is not
+// pushed until the conflict is resolved.
+func (s *Service) ApplyReplicatedRecords(ctx context.Context, records []Observation, sourceID string) (*ReplicationResult, error) {
+	result := &ReplicationResult{}
+
+	for _, record := range records {
+		if record.ObservationID == "" {
+			return nil, fmt.Errorf("%w: upstream record without observation_id", ErrInvalidData)
+		}
+		// Drafts stay on the site they were created at
+		if record.Draft {
+			continue
+		}
+		outcome, err := s.applyReplicatedRecord(ctx, record, sourceID)
+		if err != nil {
+			s.log.Error("Failed to apply upstream record", "error", err, "observationId", record.ObservationID)
+			return nil, err
+		}
+		switch outcome {
+		case replicationApplied:
+			result.Applied++
+		case replicationUnchanged:
+			result.Unchanged++
+		case replicationConflict:
+			result.Conflicts++
+		}
+	}
+
+	s.log.Info("Applied upstream records",
+		"sourceId", sourceID,
+		"applied", result.Applied,
+		"unchanged", result.Unchanged,
+		"conflicts", result.Conflicts)
+
+	return result, nil
+}
+
+// replicationOutcome tells what happened to a single upstream record
+type replicationOutcome int
+
+const (
+	replicationApplied replicationOutcome = iota
+	replicationUnchanged
+	replicationConflict
+)
+
+// applyReplicatedRecord applies one upstream record in its own transaction
+func (s *Service) applyReplicatedRecord(ctx context.Context, record Observation, sourceID string) (replicationOutcome, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				s.log.Error("Failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	stored, err := s.storedObservation(ctx, tx, record.ObservationID)
+	if err != nil {
+		return 0, err
+	}
+
+	var syncedVersion sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		`SELECT synced_version FROM replicated_records WHERE observation_id = $1 FOR UPDATE`,
+		record.ObservationID).Scan(&syncedVersion)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get replication state: %w", err)
+	}
+
+	outcome := replicationApplied
+	switch {
+	case stored != nil && sameReplicatedContent(*stored, record):
+		if err := markReplicated(ctx, tx, record.ObservationID, stored.Version); err != nil {
+			return 0, err
+		}
+		outcome = replicationUnchanged
+	case stored != nil && !stored.Draft && (!syncedVersion.Valid || syncedVersion.Int64 != stored.Version):
+		if err := queueConflict(ctx, tx, record, stored, sourceID, "", ConflictReasonUpstream); err != nil {
+			return 0, err
+		}
+		outcome = replicationConflict
+	default:
+		var version int64
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO observations (observation_id, form_type, form_version, data, created_at, updated_at, deleted,
+				created_by, owner, org_unit_id, case_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, (SELECT id FROM org_units WHERE id::TEXT = $10), $11)
+			ON CONFLICT (observation_id)
+			DO UPDATE SET
+				form_type = EXCLUDED.form_type,
+				form_version = EXCLUDED.form_version,
+				data = EXCLUDED.data,
+				created_at = EXCLUDED.created_at,
+				updated_at = EXCLUDED.updated_at,
+				deleted = EXCLUDED.deleted,
+				draft = FALSE,
+				draft_owner = NULL,
+				created_by = EXCLUDED.created_by,
+				owner = EXCLUDED.owner,
+				org_unit_id = COALESCE(EXCLUDED.org_unit_id, observations.org_unit_id),
+				case_id = EXCLUDED.case_id,
+				version = observations.version + 1
+			RETURNING version`,
+			record.ObservationID, record.FormType, record.FormVersion, record.Data,
+			record.CreatedAt, record.UpdatedAt, record.Deleted,
+			record.CreatedBy, record.Owner, record.OrgUnitID, record.CaseID).Scan(&version)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store upstream record: %w", err)
+		}
+		if err := markReplicated(ctx, tx, record.ObservationID, version); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	return outcome, nil
+}
+
+// sameReplicatedContent reports whether an upstream record carries the same content as the local copy
+func sameReplicatedContent(stored, upstream Observation) bool {
+	return stored.FormType == upstream.FormType &&
+		stored.FormVersion == upstream.FormVersion &&
+		stored.Deleted == upstream.Deleted &&
+		bytes.Equal(bytes.TrimSpace(stored.Data), bytes.TrimSpace(upstream.Data))
+}
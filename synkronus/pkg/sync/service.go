@@ -3,35 +3,82 @@ package sync
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 )
 
 // Service provides version-based synchronization functionality with PostgreSQL
 type Service struct {
-	db     *sql.DB
-	config Config
-	log    *logger.Logger
+	db          *sql.DB
+	config      Config
+	log         *logger.Logger
+	assignments FieldAssignmentSource
+	codeLists   CodeListSource
+	listeners   []PushListener
+	// upstreamIDRanges restricts ID range allocation to blocks reserved upstream (edge server mode)
+	upstreamIDRanges bool
+	// replica serves pulled records when set
+	replica ReadReplica
+}
+
+// Option configures optional dependencies of the sync service
+type Option func(*Service)
+
+// WithFieldAssignments sets the source of server-assigned field declarations
+func WithFieldAssignments(source FieldAssignmentSource) Option {
+	return func(s *Service) {
+		s.assignments = source
+	}
+}
+
+// WithCodeLists sets the source of code lists pushed values of coded fields are checked against
+func WithCodeLists(source CodeListSource) Option {
+	return func(s *Service) {
+		s.codeLists = source
+	}
+}
+
+// WithPushListener registers a listener notified of the observations written by each push
+func WithPushListener(listener PushListener) Option {
+	return func(s *Service) {
+		s.listeners = append(s.listeners, listener)
+	}
 }
 
 // NewService creates a new version-based sync service
-func NewService(db *sql.DB, config Config, log *logger.Logger) *Service {
-	return &Service{
+func NewService(db *sql.DB, config Config, log *logger.Logger, opts ...Option) *Service {
+	s := &Service{
 		db:     db,
 		config: config,
 		log:    log,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() Config {
 	return Config{
-		MaxRecordsPerSync: 1000,
-		DefaultLimit:      100,
+		MaxRecordsPerSync:  1000,
+		DefaultLimit:       100,
+		MaxClockSkew:       24 * time.Hour,
+		MinValidTimestamp:  time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		TimestampPolicy:    TimestampPolicyFlag,
+		ConflictPolicy:     ConflictPolicyLastWriteWins,
+		TombstoneRetention: 90 * 24 * time.Hour,
+		HistoryRetention:   365 * 24 * time.Hour,
+		CompactionInterval: 24 * time.Hour,
+		PullScope:          PullScopeAll,
 	}
 }
 
@@ -41,10 +88,19 @@ func (s *Service) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// stableVersionQuery selects the current version: the highest version below every version that a
+// transaction still in progress may commit. Versions come from a sequence and commit out of order,
+// so records up to this version are all visible and a client that pulled them never misses one.
+const stableVersionQuery = "SELECT sync_stable_version()"
+
+// issuedVersionQuery selects the highest version handed out, which covers the writes of the
+// current transaction but may run ahead of the current version while other writes are in progress
+const issuedVersionQuery = "SELECT last_value FROM sync_version_seq"
+
 // GetCurrentVersion returns the current database version
 func (s *Service) GetCurrentVersion(ctx context.Context) (int64, error) {
 	var version int64
-	query := "SELECT current_version FROM sync_version WHERE id = 1"
+	query := stableVersionQuery
 
 	err := s.db.QueryRowContext(ctx, query).Scan(&version)
 	if err != nil {
@@ -57,7 +113,23 @@ func (s *Service) GetCurrentVersion(ctx context.Context) (int64, error) {
 
 // GetRecordsSinceVersion retrieves records that have changed since the specified version
 func (s *Service) GetRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *SyncPullCursor) (*SyncResult, error) {
-	// Get current version first
+	var records []Observation
+	result, err := s.StreamRecordsSinceVersion(ctx, sinceVersion, clientID, schemaTypes, limit, cursor, func(obs Observation) error {
+		records = append(records, obs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Records = records
+	return result, nil
+}
+
+// StreamRecordsSinceVersion retrieves the same records as GetRecordsSinceVersion but passes each
+// one to fn as it is read from the database, so a page is never held in memory. The result
+// carries no records. An error returned by fn stops the pull and is returned as is.
+func (s *Service) StreamRecordsSinceVersion(ctx context.Context, sinceVersion int64, clientID string, schemaTypes []string, limit int, cursor *SyncPullCursor, fn func(Observation) error) (*SyncResult, error) {
+	// Get current version first; records committed after it was read are left to the next pull
 	currentVersion, err := s.GetCurrentVersion(ctx)
 	if err != nil {
 		return nil, err
@@ -73,6 +145,13 @@ func (s *Service) GetRecordsSinceVersion(ctx context.Context, sinceVersion int64
 		limit = s.config.MaxRecordsPerSync
 	}
 
+	// Clients that last pulled before purged deletions must start over
+	purgedVersion, _, err := s.compactedVersions(ctx)
+	if err != nil {
+		s.log.Error("Failed to get compacted versions", "error", err)
+		return nil, err
+	}
+
 	// Build query with optional filters
 	var queryBuilder strings.Builder
 	var args []interface{}
@@ -80,11 +159,19 @@ func (s *Service) GetRecordsSinceVersion(ctx context.Context, sinceVersion int64
 
 	queryBuilder.WriteString(`
 		SELECT observation_id, form_type, form_version, data, 
-		       created_at, updated_at, synced_at, deleted, version
+		       created_at, updated_at, synced_at, deleted, version, draft, created_by, owner, org_unit_id, case_id
 		FROM observations 
 		WHERE version > $`)
 	queryBuilder.WriteString(strconv.Itoa(argIndex))
-	args = append(args, sinceVersion)
+	queryBuilder.WriteString(" AND version <= $")
+	queryBuilder.WriteString(strconv.Itoa(argIndex + 1))
+	args = append(args, sinceVersion, currentVersion)
+	argIndex += 2
+
+	// Tenants only see their own records
+	queryBuilder.WriteString(" AND tenant_id = $")
+	queryBuilder.WriteString(strconv.Itoa(argIndex))
+	args = append(args, tenant.FromContext(ctx))
 	argIndex++
 
 	// Add schema type filter if specified
@@ -96,6 +183,48 @@ func (s *Service) GetRecordsSinceVersion(ctx context.Context, sinceVersion int64
 		argIndex++
 	}
 
+	// Exclude form types whose pull is paused by an admin
+	pausedTypes, err := pausedFormTypes(ctx, s.db, true)
+	if err != nil {
+		s.log.Error("Failed to get paused form types", "error", err)
+		return nil, err
+	}
+	if len(pausedTypes) > 0 {
+		queryBuilder.WriteString(" AND form_type <> ALL($")
+		queryBuilder.WriteString(strconv.Itoa(argIndex))
+		queryBuilder.WriteString(")")
+		args = append(args, pq.Array(pausedTypes))
+		argIndex++
+	}
+
+	// Drafts are only visible to the user who owns them
+	if username := UsernameFromContext(ctx); username != "" {
+		queryBuilder.WriteString(" AND (NOT draft OR draft_owner = $")
+		queryBuilder.WriteString(strconv.Itoa(argIndex))
+		queryBuilder.WriteString(")")
+		args = append(args, username)
+		argIndex++
+
+		// Users assigned to org units only see records within their part of the hierarchy
+		queryBuilder.WriteString(orgUnitFilterSQL(argIndex))
+		args = append(args, username)
+		argIndex++
+
+		// With assignment-based sync, users only see records they own or are assigned
+		if s.assignedOnly(ctx) {
+			queryBuilder.WriteString(assignmentFilterSQL("observations", argIndex))
+			args = append(args, username)
+			argIndex++
+		}
+	} else {
+		queryBuilder.WriteString(" AND NOT draft")
+	}
+
+	// Narrow to recent or local records if the client asked to
+	filterSQL, args := pullFilterSQL(ctx, args)
+	queryBuilder.WriteString(filterSQL)
+	argIndex = len(args) + 1
+
 	// Add cursor pagination if provided
 	if cursor != nil {
 		queryBuilder.WriteString(" AND (version > $")
@@ -113,80 +242,69 @@ func (s *Service) GetRecordsSinceVersion(ctx context.Context, sinceVersion int64
 	queryBuilder.WriteString(" ORDER BY version ASC, observation_id ASC")
 
 	// Add limit + 1 to check if there are more records
-	// Calculate the correct parameter index based on whether we have schema types or not
-	limitParamIndex := 1 // for sinceVersion
-	if len(schemaTypes) > 0 {
-		limitParamIndex = 2 // for sinceVersion and schemaTypes
-	}
-	if cursor != nil {
-		limitParamIndex += 3 // for cursor.Version, cursor.Version, cursor.ID
-	}
-	limitParamIndex++ // for the limit parameter itself
-
 	queryBuilder.WriteString(" LIMIT $")
-	queryBuilder.WriteString(strconv.Itoa(limitParamIndex))
+	queryBuilder.WriteString(strconv.Itoa(argIndex))
 	args = append(args, limit+1)
 
 	// Execute query
 	sqlStmt := queryBuilder.String()
 	s.log.Debug("SQL query", "sql", sqlStmt, "args", args)
-	rows, err := s.db.QueryContext(ctx, sqlStmt, args...)
+	rows, err := s.pullDB(ctx).QueryContext(ctx, sqlStmt, args...)
 	if err != nil {
 		s.log.Error("Failed to query observations", "error", err)
 		return nil, fmt.Errorf("failed to query observations: %w", err)
 	}
 	defer rows.Close()
 
-	var records []Observation
+	// The extra row beyond the limit only tells that there are more records
+	recordCount := 0
+	hasMore := false
+	var changeCutoff int64 = sinceVersion
 	for rows.Next() {
-		var obs Observation
-		var syncedAt sql.NullString
-
-		err := rows.Scan(
-			&obs.ObservationID, &obs.FormType, &obs.FormVersion,
-			&obs.Data, &obs.CreatedAt, &obs.UpdatedAt, &syncedAt,
-			&obs.Deleted, &obs.Version,
-		)
+		if recordCount == limit {
+			hasMore = true
+			break
+		}
+		obs, err := s.scanObservation(rows)
 		if err != nil {
-			s.log.Error("Failed to scan observation row", "error", err)
-			return nil, fmt.Errorf("failed to scan observation: %w", err)
+			return nil, err
 		}
-
-		if syncedAt.Valid {
-			obs.SyncedAt = &syncedAt.String
+		if err := fn(obs); err != nil {
+			return nil, err
 		}
+		recordCount++
 
-		records = append(records, obs)
+		// Determine change cutoff (version of the last record returned)
+		changeCutoff = obs.Version
 	}
-
 	if err := rows.Err(); err != nil {
 		s.log.Error("Error iterating observation rows", "error", err)
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	// Check if there are more records
-	hasMore := len(records) > limit
-	if hasMore {
-		records = records[:limit] // Remove the extra record
+	// Tell the client which of the requested form types are being held back
+	var warnings []SyncWarning
+	for _, formType := range pausedTypes {
+		if len(schemaTypes) > 0 && !containsString(schemaTypes, formType) {
+			continue
+		}
+		warnings = append(warnings, formPausedWarning(formType, formType, "pull"))
 	}
-
-	// Determine change cutoff (version of the last record returned)
-	var changeCutoff int64 = sinceVersion
-	if len(records) > 0 {
-		changeCutoff = records[len(records)-1].Version
+	if sinceVersion > 0 && sinceVersion < purgedVersion {
+		warnings = append(warnings, resyncRequiredWarning(sinceVersion, purgedVersion))
 	}
 
 	result := &SyncResult{
 		CurrentVersion: currentVersion,
-		Records:        records,
 		ChangeCutoff:   changeCutoff,
 		HasMore:        hasMore,
+		Warnings:       warnings,
 	}
 
 	s.log.Info("Retrieved records since version",
 		"sinceVersion", sinceVersion,
 		"currentVersion", currentVersion,
-		"recordCount", len(records),
+		"recordCount", recordCount,
 		"hasMore", hasMore,
 		"changeCutoff", changeCutoff,
 		"clientId", clientID)
@@ -199,6 +317,13 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 	var successCount int
 	var failedRecords []map[string]interface{}
 	var warnings []SyncWarning
+	var conflicts []PushConflict
+
+	// Reject corrupted payloads before anything is written
+	if err := VerifyRecordHashes(records); err != nil {
+		s.log.Warn("Rejected push with corrupted records", "transmissionId", transmissionID, "clientId", clientID, "error", err)
+		return nil, err
+	}
 
 	// Begin transaction for atomic processing
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -216,6 +341,46 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 		}
 	}()
 
+	username := UsernameFromContext(ctx)
+	receivedAt := time.Now().UTC()
+
+	// Load form types whose push is paused by an admin
+	pausedTypes, err := pausedFormTypes(ctx, tx, false)
+	if err != nil {
+		s.log.Error("Failed to get paused form types", "error", err)
+		return nil, err
+	}
+
+	// Load locked reporting periods
+	periodLocks, err := loadPeriodLocks(ctx, tx)
+	if err != nil {
+		s.log.Error("Failed to get reporting period locks", "error", err)
+		return nil, err
+	}
+
+	// Load records other users are editing
+	recordLocks, err := loadRecordLocks(ctx, tx, username)
+	if err != nil {
+		s.log.Error("Failed to get record locks", "error", err)
+		return nil, err
+	}
+
+	// Load server-assigned field declarations
+	fieldAssignments, err := s.loadFieldAssignments(ctx)
+	if err != nil {
+		s.log.Error("Failed to get server-assigned fields", "error", err)
+		return nil, err
+	}
+	// Load coded field declarations; code lists are loaded as records need them
+	codeLists, err := s.loadCodeListChecker(ctx)
+	if err != nil {
+		s.log.Error("Failed to get coded fields", "error", err)
+		return nil, err
+	}
+	assignedFields := make(map[string]map[string]any)
+	versions := make(map[string]int64)
+	var written []Observation
+
 	for i, record := range records {
 		// Validate required fields
 		if record.ObservationID == "" {
@@ -227,6 +392,166 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 			continue
 		}
 
+		// Skip records of paused form types; the client keeps them and retries later
+		if containsString(pausedTypes, record.FormType) {
+			warnings = append(warnings, formPausedWarning(record.ObservationID, record.FormType, "push"))
+			continue
+		}
+
+		// Records locked by another user are rejected so concurrent edits are not overwritten
+		if lock, ok := recordLocks[record.ObservationID]; ok {
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  (&RecordLockedError{Lock: lock}).Error(),
+				"record": record,
+			})
+			continue
+		}
+
+		// Values of coded fields must be codes of the list the form references
+		if err := codeLists.check(ctx, record); err != nil {
+			if !errors.Is(err, ErrInvalidData) {
+				return nil, err
+			}
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  err.Error(),
+				"record": record,
+			})
+			continue
+		}
+
+		// The pushing user becomes creator and owner of new records
+		var pushedBy *string
+		if username != "" {
+			pushedBy = &username
+		}
+
+		// Drafts need an owner, otherwise nobody could ever pull them again
+		var draftOwner *string
+		if record.Draft {
+			if username == "" {
+				failedRecords = append(failedRecords, map[string]interface{}{
+					"index":  i,
+					"error":  "draft records require an authenticated user",
+					"record": record,
+				})
+				continue
+			}
+			draftOwner = &username
+		}
+
+		// Place the record in the hierarchy
+		orgUnitID, defaultOrgUnitID, err := s.resolveOrgUnit(ctx, tx, username, record.OrgUnitID)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidData) {
+				return nil, err
+			}
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  err.Error(),
+				"record": record,
+			})
+			continue
+		}
+
+		// Validate client timestamps against server time
+		timestamps, timestampWarnings, err := s.normalizeTimestamps(record, receivedAt)
+		if err != nil {
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  err.Error(),
+				"record": record,
+			})
+			continue
+		}
+		warnings = append(warnings, timestampWarnings...)
+
+		// Look up the stored record for conflict detection, period locks and server-assigned fields
+		locks := periodLocks[record.FormType]
+		assignments := fieldAssignments[record.FormType]
+		stored, err := s.storedObservation(ctx, tx, record.ObservationID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Records created in a locked reporting period, or moved into or out of one, are
+		// rejected or held for approval
+		if len(locks) > 0 {
+			dates := []string{timestamps.CreatedAt}
+			if stored != nil {
+				dates = append(dates, stored.CreatedAt)
+			}
+
+			if lock := matchPeriodLock(locks, dates...); lock != nil {
+				if lock.Mode == PeriodLockModeReject {
+					failedRecords = append(failedRecords, map[string]interface{}{
+						"index":  i,
+						"error":  lock.message(),
+						"record": record,
+					})
+					continue
+				}
+
+				pending := record
+				pending.CreatedAt = timestamps.CreatedAt
+				pending.UpdatedAt = timestamps.UpdatedAt
+				if err := queueForApproval(ctx, tx, pending, stored, clientID, transmissionID); err != nil {
+					s.log.Error("Failed to queue record for approval", "error", err, "observationId", record.ObservationID)
+					return nil, err
+				}
+				warnings = append(warnings, SyncWarning{
+					ID:      record.ObservationID,
+					Code:    WarningCodePendingApproval,
+					Message: lock.message() + "; the record is held for approval",
+				})
+				continue
+			}
+		}
+
+		// Fill server-assigned fields, keeping values assigned by earlier pushes
+		var assigned map[string]any
+		if len(assignments) > 0 {
+			var data json.RawMessage
+			data, assigned, err = s.assignFields(ctx, tx, record, stored, assignments, orgUnitID, defaultOrgUnitID)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidData) {
+					return nil, err
+				}
+				failedRecords = append(failedRecords, map[string]interface{}{
+					"index":  i,
+					"error":  err.Error(),
+					"record": record,
+				})
+				continue
+			}
+			record.Data = data
+		}
+
+		// Records overwriting changes the client has not pulled are settled by the conflict policy
+		if detectConflict(record, timestamps.UpdatedAt, stored) {
+			conflict := PushConflict{
+				ObservationID: record.ObservationID,
+				Outcome:       s.conflictOutcome(timestamps.UpdatedAt, stored),
+				ClientVersion: record.Version,
+				ServerRecord:  *stored,
+			}
+			conflicts = append(conflicts, conflict)
+
+			if conflict.Outcome == PushConflictPendingReview {
+				pending := record
+				pending.CreatedAt = timestamps.CreatedAt
+				pending.UpdatedAt = timestamps.UpdatedAt
+				if err := queueConflict(ctx, tx, pending, stored, clientID, transmissionID, ConflictReasonConflict); err != nil {
+					s.log.Error("Failed to queue conflicting record", "error", err, "observationId", record.ObservationID)
+					return nil, err
+				}
+			}
+			if conflict.Outcome != PushConflictApplied {
+				continue
+			}
+		}
+
 		// Generate warnings for missing optional fields
 		if record.FormType == "" {
 			warnings = append(warnings, SyncWarning{
@@ -236,10 +561,13 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 			})
 		}
 
-		// Insert or update the observation
+		// Insert or update the observation. A finalized record never returns to draft,
+		// and the draft owner is kept from the first push until finalization. The user's
+		// default org unit only applies to new records; an explicit one always wins.
 		query := `
-			INSERT INTO observations (observation_id, form_type, form_version, data, created_at, updated_at, deleted)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			INSERT INTO observations (observation_id, form_type, form_version, data, created_at, updated_at, deleted, draft, draft_owner,
+				client_created_at, client_updated_at, received_at, created_by, owner, org_unit_id, case_id, tenant_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13, COALESCE($14::UUID, $15::UUID), $16, $17)
 			ON CONFLICT (observation_id) 
 			DO UPDATE SET 
 				form_type = EXCLUDED.form_type,
@@ -247,13 +575,43 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 				data = EXCLUDED.data,
 				updated_at = EXCLUDED.updated_at,
 				deleted = EXCLUDED.deleted,
+				draft = observations.draft AND EXCLUDED.draft,
+				draft_owner = CASE WHEN observations.draft AND EXCLUDED.draft
+					THEN COALESCE(observations.draft_owner, EXCLUDED.draft_owner) END,
+				client_updated_at = EXCLUDED.client_updated_at,
+				received_at = EXCLUDED.received_at,
+				org_unit_id = COALESCE($14::UUID, observations.org_unit_id),
+				case_id = COALESCE(EXCLUDED.case_id, observations.case_id),
 				version = observations.version + 1
+			WHERE observations.tenant_id = EXCLUDED.tenant_id
+			RETURNING created_at, version, draft, created_by, owner, org_unit_id, case_id
 		`
 
-		_, err := tx.ExecContext(ctx, query,
+		saved := &Observation{
+			ObservationID: record.ObservationID,
+			FormType:      record.FormType,
+			FormVersion:   record.FormVersion,
+			Data:          record.Data,
+			UpdatedAt:     timestamps.UpdatedAt,
+			Deleted:       record.Deleted,
+		}
+		err = tx.QueryRowContext(ctx, query,
 			record.ObservationID, record.FormType, record.FormVersion,
-			record.Data, record.CreatedAt, record.UpdatedAt, record.Deleted)
-
+			record.Data, timestamps.CreatedAt, timestamps.UpdatedAt, record.Deleted,
+			record.Draft, draftOwner,
+			timestamps.ClientCreatedAt, timestamps.ClientUpdatedAt, timestamps.ReceivedAt, pushedBy,
+			orgUnitID, defaultOrgUnitID, record.CaseID, tenant.FromContext(ctx),
+		).Scan(&saved.CreatedAt, &saved.Version, &saved.Draft, &saved.CreatedBy, &saved.Owner, &saved.OrgUnitID, &saved.CaseID)
+
+		// The ID is taken by a record of another tenant, which is left alone
+		if errors.Is(err, sql.ErrNoRows) {
+			failedRecords = append(failedRecords, map[string]interface{}{
+				"index":  i,
+				"error":  "observation_id is already in use",
+				"record": record,
+			})
+			continue
+		}
 		if err != nil {
 			s.log.Error("Failed to insert/update observation", "error", err, "observationId", record.ObservationID)
 			failedRecords = append(failedRecords, map[string]interface{}{
@@ -265,11 +623,28 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 		}
 
 		successCount++
+		written = append(written, *saved)
+		if len(assigned) > 0 {
+			assignedFields[record.ObservationID] = assigned
+		}
+		if !saved.Draft {
+			versions[record.ObservationID] = saved.Version
+		}
 	}
 
-	// Get the current version WITHIN the transaction to ensure consistency
+	// Let listeners queue their work in the same transaction
+	if len(written) > 0 {
+		for _, listener := range s.listeners {
+			if err := listener.ObservationsPushed(ctx, tx, written); err != nil {
+				s.log.Error("Push listener failed", "error", err, "transmissionId", transmissionID)
+				return nil, fmt.Errorf("failed to notify push listener: %w", err)
+			}
+		}
+	}
+
+	// Get the version WITHIN the transaction, so that it covers the pushed records
 	var currentVersion int64
-	err = tx.QueryRowContext(ctx, "SELECT current_version FROM sync_version ORDER BY id DESC LIMIT 1").Scan(&currentVersion)
+	err = tx.QueryRowContext(ctx, issuedVersionQuery).Scan(&currentVersion)
 	if err != nil {
 		s.log.Error("Failed to get current version within transaction", "error", err)
 		return nil, fmt.Errorf("failed to get current version: %w", err)
@@ -287,6 +662,11 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 		SuccessCount:   successCount,
 		FailedRecords:  failedRecords,
 		Warnings:       warnings,
+		Conflicts:      conflicts,
+		Versions:       versions,
+	}
+	if len(assignedFields) > 0 {
+		result.AssignedFields = assignedFields
 	}
 
 	s.log.Info("Processed pushed records",
@@ -296,7 +676,58 @@ func (s *Service) ProcessPushedRecords(ctx context.Context, records []Observatio
 		"successCount", successCount,
 		"failedCount", len(failedRecords),
 		"warningCount", len(warnings),
+		"conflictCount", len(conflicts),
 		"currentVersion", currentVersion)
 
 	return result, nil
 }
+
+// scanObservations reads observation rows selected in pull column order
+func (s *Service) scanObservations(rows *sql.Rows) ([]Observation, error) {
+	var records []Observation
+	for rows.Next() {
+		obs, err := s.scanObservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, obs)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("Error iterating observation rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return records, nil
+}
+
+// scanObservation scans the observation at the current row
+func (s *Service) scanObservation(rows *sql.Rows) (Observation, error) {
+	var obs Observation
+	var syncedAt sql.NullString
+
+	err := rows.Scan(
+		&obs.ObservationID, &obs.FormType, &obs.FormVersion,
+		&obs.Data, &obs.CreatedAt, &obs.UpdatedAt, &syncedAt,
+		&obs.Deleted, &obs.Version, &obs.Draft, &obs.CreatedBy, &obs.Owner, &obs.OrgUnitID, &obs.CaseID,
+	)
+	if err != nil {
+		s.log.Error("Failed to scan observation row", "error", err)
+		return obs, fmt.Errorf("failed to scan observation: %w", err)
+	}
+
+	if syncedAt.Valid {
+		obs.SyncedAt = &syncedAt.String
+	}
+	return obs, nil
+}
+
+// containsString reports whether the slice contains the given value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -223,3 +224,389 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 	t.Skip("Test database not configured - implement setupTestDB for your environment")
 	return nil, func() {}
 }
+
+// TestService_FieldAssignments tests that server-assigned fields are filled once and kept on updates
+func TestService_FieldAssignments(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	source := FieldAssignmentSourceFunc(func(ctx context.Context) (map[string][]FieldAssignment, error) {
+		return map[string][]FieldAssignment{
+			"registration": {{Field: "registration_number", Kind: FieldAssignmentSequence, Prefix: "REG-", Padding: 4}},
+		}, nil
+	})
+	service := NewService(db, DefaultConfig(), logger.NewLogger(), WithFieldAssignments(source))
+	ctx := context.Background()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	push := func(id, data string) *SyncPushResult {
+		t.Helper()
+		result, err := service.ProcessPushedRecords(ctx, []Observation{{
+			ObservationID: id,
+			FormType:      "registration",
+			FormVersion:   "1.0",
+			Data:          json.RawMessage(data),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}}, "test-client", "tx-"+id+data)
+		if err != nil {
+			t.Fatalf("Failed to process records: %v", err)
+		}
+		return result
+	}
+
+	first := push("reg-1", `{"name": "Amina"}`)
+	second := push("reg-2", `{"name": "Baraka", "registration_number": "forged"}`)
+	if got := first.AssignedFields["reg-1"]["registration_number"]; got != "REG-0001" {
+		t.Errorf("Expected REG-0001, got %v", got)
+	}
+	if got := second.AssignedFields["reg-2"]["registration_number"]; got != "REG-0002" {
+		t.Errorf("Expected REG-0002, got %v", got)
+	}
+
+	// Updates keep the number handed out on the first push
+	updated := push("reg-1", `{"name": "Amina Juma"}`)
+	if got := updated.AssignedFields["reg-1"]["registration_number"]; got != "REG-0001" {
+		t.Errorf("Expected REG-0001 to be kept, got %v", got)
+	}
+
+	var data string
+	if err := db.QueryRow("SELECT data::TEXT FROM observations WHERE observation_id = 'reg-1'").Scan(&data); err != nil {
+		t.Fatalf("Failed to read stored record: %v", err)
+	}
+	var stored map[string]any
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		t.Fatalf("Failed to decode stored data: %v", err)
+	}
+	if stored["registration_number"] != "REG-0001" || stored["name"] != "Amina Juma" {
+		t.Errorf("Unexpected stored data: %s", data)
+	}
+}
+
+// TestService_PushConflicts tests that stale pushes are settled by the conflict policy
+func TestService_PushConflicts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	config := DefaultConfig()
+	config.ConflictPolicy = ConflictPolicyRejectAndReport
+	service := NewService(db, config, logger.NewLogger())
+	ctx := context.Background()
+
+	push := func(data, updatedAt string, version int64) *SyncPushResult {
+		t.Helper()
+		result, err := service.ProcessPushedRecords(ctx, []Observation{{
+			ObservationID: "obs-1",
+			FormType:      "survey",
+			FormVersion:   "1.0",
+			Data:          json.RawMessage(data),
+			CreatedAt:     "2025-09-01T08:00:00Z",
+			UpdatedAt:     updatedAt,
+			Version:       version,
+		}}, "test-client", "tx-"+updatedAt)
+		if err != nil {
+			t.Fatalf("Failed to process records: %v", err)
+		}
+		return result
+	}
+
+	push(`{"name": "Amina"}`, "2025-09-01T08:00:00Z", 0)
+	push(`{"name": "Amina Juma"}`, "2025-09-02T08:00:00Z", 1)
+
+	// A device still editing version 1 conflicts with the stored version 2
+	stale := push(`{"name": "Amina J."}`, "2025-09-03T08:00:00Z", 1)
+	if stale.SuccessCount != 0 || len(stale.Conflicts) != 1 {
+		t.Fatalf("Expected one conflict, got %d succeeded and %v", stale.SuccessCount, stale.Conflicts)
+	}
+	conflict := stale.Conflicts[0]
+	if conflict.Outcome != PushConflictPendingReview || conflict.ServerRecord.Version != 2 {
+		t.Errorf("Unexpected conflict: %+v", conflict)
+	}
+
+	conflicts, err := service.ListConflicts(ctx, ConflictFilter{Reason: ConflictReasonConflict})
+	if err != nil {
+		t.Fatalf("Failed to list conflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].ObservationID != "obs-1" {
+		t.Errorf("Expected the conflict to be queued for review, got %v", conflicts)
+	}
+
+	var name string
+	if err := db.QueryRow("SELECT data->>'name' FROM observations WHERE observation_id = 'obs-1'").Scan(&name); err != nil {
+		t.Fatalf("Failed to read stored record: %v", err)
+	}
+	if name != "Amina Juma" {
+		t.Errorf("Expected the stored record to be kept, got %s", name)
+	}
+
+	// Under last-write-wins the later edit is applied and still reported
+	service.config.ConflictPolicy = ConflictPolicyLastWriteWins
+	applied := push(`{"name": "Amina J."}`, "2025-09-03T08:00:00Z", 1)
+	if applied.SuccessCount != 1 || len(applied.Conflicts) != 1 || applied.Conflicts[0].Outcome != PushConflictApplied {
+		t.Errorf("Expected the later edit to be applied, got %+v", applied)
+	}
+}
+
+// TestService_Compaction tests that old tombstones and superseded versions are removed
+func TestService_Compaction(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger())
+	ctx := context.Background()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	push := func(id, data string, deleted bool) {
+		t.Helper()
+		_, err := service.ProcessPushedRecords(ctx, []Observation{{
+			ObservationID: id, FormType: "survey", FormVersion: "1.0", Data: json.RawMessage(data),
+			CreatedAt: now, UpdatedAt: now, Deleted: deleted,
+		}}, "test-client", "tx-"+id+data)
+		if err != nil {
+			t.Fatalf("Failed to process records: %v", err)
+		}
+	}
+
+	push("kept", `{"n": 1}`, false)
+	push("kept", `{"n": 2}`, false)
+	push("removed", `{"n": 1}`, false)
+	push("removed", `{"n": 1}`, true)
+	oldVersion, err := service.GetCurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get current version: %v", err)
+	}
+
+	// Everything so far happened two days ago
+	if _, err := db.Exec("UPDATE observation_history SET recorded_at = NOW() - INTERVAL '2 days'"); err != nil {
+		t.Fatalf("Failed to age history: %v", err)
+	}
+	push("kept", `{"n": 3}`, false)
+
+	options := CompactionOptions{TombstoneRetention: 24 * time.Hour, HistoryRetention: 24 * time.Hour, TriggeredBy: "admin"}
+	options.DryRun = true
+	preview, err := service.Compact(ctx, options)
+	if err != nil {
+		t.Fatalf("Failed to preview compaction: %v", err)
+	}
+
+	options.DryRun = false
+	report, err := service.Compact(ctx, options)
+	if err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if report.TombstonesPurged != 1 || report.HistoryVersionsRemoved != 3 {
+		t.Errorf("Expected 1 tombstone and 3 versions removed, got %+v", report)
+	}
+	if preview.TombstonesPurged != report.TombstonesPurged || preview.HistoryVersionsRemoved != report.HistoryVersionsRemoved {
+		t.Errorf("Dry run %+v does not match the compaction %+v", preview, report)
+	}
+
+	var versions int
+	if err := db.QueryRow("SELECT COUNT(*) FROM observation_history WHERE observation_id = 'kept'").Scan(&versions); err != nil {
+		t.Fatalf("Failed to count history: %v", err)
+	}
+	if versions != 2 {
+		t.Errorf("Expected the latest old version and the new one to be kept, got %d versions", versions)
+	}
+
+	// A client that pulled before the purge is told to resync
+	result, err := service.GetRecordsSinceVersion(ctx, 1, "test-client", nil, 10, nil)
+	if err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != WarningCodeResyncRequired {
+		t.Errorf("Expected a resync warning, got %v", result.Warnings)
+	}
+
+	if _, err := service.GetRecordsAsOfVersion(ctx, oldVersion-1, 0, "test-client", nil, 10, nil); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected as-of reads before the cutoff to fail, got %v", err)
+	}
+
+	compactions, err := service.ListCompactions(ctx, 10)
+	if err != nil || len(compactions) != 1 || compactions[0].TriggeredBy != "admin" {
+		t.Errorf("Expected one recorded compaction, got %v (%v)", compactions, err)
+	}
+}
+
+// TestService_AllocateIDRange tests that concurrent ID range reservations never overlap
+func TestService_AllocateIDRange(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger())
+	ctx := context.Background()
+
+	const clients = 8
+	results := make(chan *IDRange, clients)
+	errs := make(chan error, clients)
+	for i := 0; i < clients; i++ {
+		go func(i int) {
+			r, err := service.AllocateIDRange(ctx, "registration", fmt.Sprintf("client-%d", i), 50)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- r
+		}(i)
+	}
+
+	seen := make(map[int64]bool)
+	for i := 0; i < clients; i++ {
+		select {
+		case err := <-errs:
+			t.Fatalf("Failed to allocate ID range: %v", err)
+		case r := <-results:
+			if r.RangeEnd-r.RangeStart+1 != 50 {
+				t.Errorf("Expected 50 IDs, got range %d-%d", r.RangeStart, r.RangeEnd)
+			}
+			for n := r.RangeStart; n <= r.RangeEnd; n++ {
+				if seen[n] {
+					t.Fatalf("ID %d allocated twice", n)
+				}
+				seen[n] = true
+			}
+		}
+	}
+	if len(seen) != clients*50 {
+		t.Errorf("Expected %d distinct IDs, got %d", clients*50, len(seen))
+	}
+}
+
+func TestService_RecordLocks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger())
+	alice := WithUsername(context.Background(), "alice")
+	bob := WithUsername(context.Background(), "bob")
+
+	if _, err := service.ClaimRecordLock(alice, "obs-1", time.Minute); err != nil {
+		t.Fatalf("Failed to claim record lock: %v", err)
+	}
+
+	var lockedErr *RecordLockedError
+	if _, err := service.ClaimRecordLock(bob, "obs-1", time.Minute); !errors.As(err, &lockedErr) || lockedErr.Lock.LockedBy != "alice" {
+		t.Fatalf("Expected lock held by alice, got %v", err)
+	}
+
+	// Bob's push of the locked record fails
+	result, err := service.ProcessPushedRecords(bob, []Observation{{
+		ObservationID: "obs-1", FormType: "survey", FormVersion: "1", Data: json.RawMessage(`{}`),
+	}}, "client-1", "tx-1")
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if result.SuccessCount != 0 || len(result.FailedRecords) != 1 {
+		t.Errorf("Expected locked record to fail, got %d succeeded", result.SuccessCount)
+	}
+
+	// An expired lock is taken over
+	if _, err := db.Exec("UPDATE record_locks SET expires_at = NOW() - INTERVAL '1 second'"); err != nil {
+		t.Fatalf("Failed to expire lock: %v", err)
+	}
+	lock, err := service.ClaimRecordLock(bob, "obs-1", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to take over expired lock: %v", err)
+	}
+	if lock.LockedBy != "bob" {
+		t.Errorf("Expected bob to hold the lock, got %s", lock.LockedBy)
+	}
+
+	if err := service.ReleaseRecordLock(alice, "obs-1", false); !errors.Is(err, ErrRecordLocked) {
+		t.Errorf("Expected ErrRecordLocked, got %v", err)
+	}
+	if err := service.ReleaseRecordLock(alice, "obs-1", true); err != nil {
+		t.Errorf("Failed to force-release lock: %v", err)
+	}
+	if locks, err := service.ListRecordLocks(context.Background()); err != nil || len(locks) != 0 {
+		t.Errorf("Expected no locks, got %v (%v)", locks, err)
+	}
+}
+
+func TestService_Replication(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(db, DefaultConfig(), logger.NewLogger(), WithUpstreamIDRanges())
+	ctx := WithUsername(context.Background(), "alice")
+
+	if _, err := service.ProcessPushedRecords(ctx, []Observation{{
+		ObservationID: "obs-1", FormType: "survey", FormVersion: "1", Data: json.RawMessage(`{"a":1}`),
+	}}, "client-1", "tx-1"); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	pending, err := service.PendingReplication(ctx, 0, 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected one pending record, got %d (%v)", len(pending), err)
+	}
+	if err := service.MarkReplicated(ctx, map[string]int64{"obs-1": pending[0].Version}); err != nil {
+		t.Fatalf("Failed to mark replicated: %v", err)
+	}
+
+	// An upstream edit of a record without local changes is applied
+	upstream := Observation{ObservationID: "obs-1", FormType: "survey", FormVersion: "1", Data: json.RawMessage(`{"a": 2}`),
+		CreatedAt: "2025-01-01T00:00:00Z", UpdatedAt: "2025-01-02T00:00:00Z"}
+	result, err := service.ApplyReplicatedRecords(ctx, []Observation{upstream}, "edge-1")
+	if err != nil || result.Applied != 1 {
+		t.Fatalf("Expected upstream record applied, got %+v (%v)", result, err)
+	}
+	if pending, _ := service.PendingReplication(ctx, 0, 10); len(pending) != 0 {
+		t.Errorf("Expected nothing pending after applying upstream record, got %d", len(pending))
+	}
+
+	// A local edit not yet pushed conflicts with the next upstream edit and is held back
+	if _, err := service.ProcessPushedRecords(ctx, []Observation{{
+		ObservationID: "obs-1", FormType: "survey", FormVersion: "1", Data: json.RawMessage(`{"a":3}`),
+	}}, "client-1", "tx-2"); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	upstream.Data = json.RawMessage(`{"a": 4}`)
+	result, err = service.ApplyReplicatedRecords(ctx, []Observation{upstream}, "edge-1")
+	if err != nil || result.Conflicts != 1 {
+		t.Fatalf("Expected upstream conflict, got %+v (%v)", result, err)
+	}
+	if pending, _ := service.PendingReplication(ctx, 0, 10); len(pending) != 0 {
+		t.Errorf("Expected conflicting record held back, got %d pending", len(pending))
+	}
+
+	// ID ranges come only from blocks reserved upstream
+	if _, err := service.AllocateIDRange(ctx, "household", "tablet-1", 10); !errors.Is(err, ErrIDRangesExhausted) {
+		t.Fatalf("Expected exhausted ID blocks, got %v", err)
+	}
+	if err := service.AddIDBlock(ctx, IDRange{Sequence: "household", RangeStart: 5001, RangeEnd: 5100}); err != nil {
+		t.Fatalf("Failed to add ID block: %v", err)
+	}
+	allocated, err := service.AllocateIDRange(ctx, "household", "tablet-1", 10)
+	if err != nil || allocated.RangeStart != 5001 || allocated.RangeEnd != 5010 {
+		t.Fatalf("Expected 5001-5010 from the upstream block, got %+v (%v)", allocated, err)
+	}
+	stock, err := service.IDBlockStock(ctx, []string{"household"})
+	if err != nil || stock[0].Remaining != 90 {
+		t.Errorf("Expected 90 IDs left, got %+v (%v)", stock, err)
+	}
+}
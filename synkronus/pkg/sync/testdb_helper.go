@@ -107,10 +107,30 @@ func pingWithTimeout(db *sql.DB, timeout time.Duration) error {
 func ensureTestSchema(db *sql.DB) error {
 	// Drop existing tables to ensure clean state
 	dropQueries := []string{
+		"DROP TABLE IF EXISTS cases",
+		"DROP TABLE IF EXISTS observation_assignments",
 		"DROP TRIGGER IF EXISTS observations_version_trigger ON observations",
 		"DROP FUNCTION IF EXISTS update_sync_version()",
+		"DROP FUNCTION IF EXISTS next_sync_version()",
+		"DROP FUNCTION IF EXISTS sync_stable_version()",
+		"DROP SEQUENCE IF EXISTS sync_version_seq",
+		"DROP TRIGGER IF EXISTS observations_history_trigger ON observations",
+		"DROP FUNCTION IF EXISTS record_observation_history()",
+		"DROP TABLE IF EXISTS observation_history",
 		"DROP TABLE IF EXISTS observations",
 		"DROP TABLE IF EXISTS sync_version",
+		"DROP TABLE IF EXISTS form_sync_controls",
+		"DROP TABLE IF EXISTS reporting_period_locks",
+		"DROP TABLE IF EXISTS field_sequences",
+		"DROP TABLE IF EXISTS id_range_allocations",
+		"DROP TABLE IF EXISTS id_sequences",
+		"DROP TABLE IF EXISTS record_locks",
+		"DROP TABLE IF EXISTS replicated_records",
+		"DROP TABLE IF EXISTS id_range_blocks",
+		"DROP TABLE IF EXISTS sync_compactions",
+		"DROP TABLE IF EXISTS sync_conflicts",
+		"DROP TABLE IF EXISTS user_org_units",
+		"DROP TABLE IF EXISTS org_units",
 	}
 
 	for _, query := range dropQueries {
@@ -120,21 +140,38 @@ func ensureTestSchema(db *sql.DB) error {
 		}
 	}
 
-	// Create sync_version table
-	syncVersionSQL := `
-		CREATE TABLE sync_version (
-			id SERIAL PRIMARY KEY,
-			current_version BIGINT NOT NULL DEFAULT 1,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`
-	if _, err := db.Exec(syncVersionSQL); err != nil {
-		return fmt.Errorf("failed to create sync_version table: %w", err)
+	// Create the sequence handing out versions and the functions leasing and reading them
+	syncVersionSQL := []string{
+		"CREATE SEQUENCE sync_version_seq",
+		"SELECT setval('sync_version_seq', 1)",
+		`CREATE OR REPLACE FUNCTION next_sync_version() RETURNS BIGINT AS $$
+		BEGIN
+			IF current_setting('synkronus.sync_version_lease', true) IS DISTINCT FROM 'held' THEN
+				PERFORM pg_advisory_xact_lock_shared((SELECT last_value FROM sync_version_seq) + 1);
+				PERFORM set_config('synkronus.sync_version_lease', 'held', true);
+			END IF;
+			RETURN nextval('sync_version_seq');
+		END;
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION sync_stable_version() RETURNS BIGINT AS $$
+		DECLARE
+			issued BIGINT;
+			pending BIGINT;
+		BEGIN
+			SELECT last_value INTO issued FROM sync_version_seq;
+			SELECT MIN((classid::BIGINT << 32) | objid::BIGINT) INTO pending
+			FROM pg_locks
+			WHERE locktype = 'advisory' AND objsubid = 1 AND pid <> pg_backend_pid()
+				AND database = (SELECT oid FROM pg_database WHERE datname = current_database())
+				AND ((classid::BIGINT << 32) | objid::BIGINT) <= issued;
+			RETURN LEAST(issued, pending - 1);
+		END;
+		$$ LANGUAGE plpgsql`,
 	}
-
-	// Insert initial version
-	if _, err := db.Exec("INSERT INTO sync_version (current_version) VALUES (1)"); err != nil {
-		return fmt.Errorf("failed to insert initial version: %w", err)
+	for _, query := range syncVersionSQL {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create sync version sequence: %w", err)
+		}
 	}
 
 	// Enable UUID extension
@@ -143,6 +180,31 @@ func ensureTestSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to enable uuid-ossp extension: %w", err)
 	}
 
+	// Create org unit tables
+	orgUnitsSQL := []string{
+		`CREATE TABLE org_units (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			name VARCHAR(255) NOT NULL,
+			code VARCHAR(100) UNIQUE,
+			level VARCHAR(50),
+			parent_id UUID REFERENCES org_units(id) ON DELETE RESTRICT,
+			path TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE user_org_units (
+			username VARCHAR(255) NOT NULL,
+			org_unit_id UUID NOT NULL REFERENCES org_units(id) ON DELETE CASCADE,
+			assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (username, org_unit_id)
+		)`,
+	}
+	for _, query := range orgUnitsSQL {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create org unit tables: %w", err)
+		}
+	}
+
 	// Create observations table
 	observationsSQL := `
 		CREATE TABLE observations (
@@ -155,19 +217,183 @@ func ensureTestSchema(db *sql.DB) error {
 			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
 			synced_at TIMESTAMP WITH TIME ZONE,
 			deleted BOOLEAN NOT NULL DEFAULT FALSE,
-			version BIGINT NOT NULL DEFAULT 1
+			version BIGINT NOT NULL DEFAULT 1,
+			draft BOOLEAN NOT NULL DEFAULT FALSE,
+			draft_owner VARCHAR(255),
+			client_created_at TIMESTAMP WITH TIME ZONE,
+			client_updated_at TIMESTAMP WITH TIME ZONE,
+			received_at TIMESTAMP WITH TIME ZONE,
+			created_by VARCHAR(255),
+			owner VARCHAR(255),
+			org_unit_id UUID REFERENCES org_units(id) ON DELETE RESTRICT,
+			case_id VARCHAR(255),
+			tenant_id VARCHAR(64) NOT NULL DEFAULT 'default'
 		)
 	`
 	if _, err := db.Exec(observationsSQL); err != nil {
 		return fmt.Errorf("failed to create observations table: %w", err)
 	}
 
+	// Create observation assignments table
+	assignmentsSQL := `
+		CREATE TABLE observation_assignments (
+			id BIGSERIAL PRIMARY KEY,
+			observation_id VARCHAR(255) NOT NULL REFERENCES observations(observation_id) ON DELETE CASCADE,
+			username VARCHAR(255),
+			org_unit_id UUID REFERENCES org_units(id) ON DELETE CASCADE,
+			assigned_by VARCHAR(255) NOT NULL DEFAULT '',
+			assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			CHECK ((username IS NULL) <> (org_unit_id IS NULL))
+		);
+		CREATE UNIQUE INDEX idx_observation_assignments_user
+			ON observation_assignments(observation_id, username) WHERE username IS NOT NULL;
+		CREATE UNIQUE INDEX idx_observation_assignments_org_unit
+			ON observation_assignments(observation_id, org_unit_id) WHERE org_unit_id IS NOT NULL
+	`
+	if _, err := db.Exec(assignmentsSQL); err != nil {
+		return fmt.Errorf("failed to create observation assignments table: %w", err)
+	}
+
+	// Create form_sync_controls table
+	formSyncControlsSQL := `
+		CREATE TABLE form_sync_controls (
+			form_type VARCHAR(255) PRIMARY KEY,
+			push_paused BOOLEAN NOT NULL DEFAULT FALSE,
+			pull_paused BOOLEAN NOT NULL DEFAULT FALSE,
+			pull_paused_at_version BIGINT,
+			reason TEXT,
+			updated_by VARCHAR(255),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`
+	if _, err := db.Exec(formSyncControlsSQL); err != nil {
+		return fmt.Errorf("failed to create form_sync_controls table: %w", err)
+	}
+
+	// Create reporting period lock and review backlog tables
+	reviewSQL := []string{
+		`CREATE TABLE reporting_period_locks (
+			id BIGSERIAL PRIMARY KEY,
+			form_type VARCHAR(255) NOT NULL,
+			period_start DATE NOT NULL,
+			period_end DATE NOT NULL,
+			mode VARCHAR(20) NOT NULL DEFAULT 'reject' CHECK (mode IN ('reject', 'approval')),
+			reason TEXT,
+			created_by VARCHAR(255),
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			CHECK (period_end >= period_start)
+		)`,
+		`CREATE TABLE sync_conflicts (
+			id BIGSERIAL PRIMARY KEY,
+			observation_id VARCHAR(255) NOT NULL,
+			client_id VARCHAR(255) NOT NULL,
+			transmission_id VARCHAR(255),
+			server_record JSONB NOT NULL,
+			client_record JSONB NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'resolved')),
+			reason VARCHAR(30) NOT NULL DEFAULT 'conflict' CHECK (reason IN ('conflict', 'period_locked', 'upstream')),
+			resolution VARCHAR(20) CHECK (resolution IN ('keep_server', 'keep_client', 'merge')),
+			resolved_by VARCHAR(255),
+			detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			resolved_at TIMESTAMP WITH TIME ZONE
+		)`,
+	}
+	for _, query := range reviewSQL {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create review tables: %w", err)
+		}
+	}
+
+	// Create server-assigned field sequences table
+	fieldSequencesSQL := `
+		CREATE TABLE field_sequences (
+			form_type VARCHAR(255) NOT NULL,
+			field VARCHAR(255) NOT NULL,
+			last_value BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (form_type, field)
+		)`
+	if _, err := db.Exec(fieldSequencesSQL); err != nil {
+		return fmt.Errorf("failed to create field_sequences table: %w", err)
+	}
+
+	// Create ID range allocation tables
+	idRangesSQL := []string{
+		`CREATE TABLE id_sequences (
+			name VARCHAR(255) PRIMARY KEY,
+			last_value BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE id_range_allocations (
+			id BIGSERIAL PRIMARY KEY,
+			sequence VARCHAR(255) NOT NULL REFERENCES id_sequences(name) ON DELETE CASCADE,
+			client_id VARCHAR(255) NOT NULL,
+			range_start BIGINT NOT NULL,
+			range_end BIGINT NOT NULL,
+			allocated_by VARCHAR(255),
+			allocated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			CHECK (range_end >= range_start)
+		)`,
+	}
+	for _, query := range idRangesSQL {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create ID range tables: %w", err)
+		}
+	}
+
+	// Create record locks table
+	recordLocksSQL := `
+		CREATE TABLE record_locks (
+			observation_id VARCHAR(255) PRIMARY KEY,
+			locked_by VARCHAR(255) NOT NULL,
+			locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)`
+	if _, err := db.Exec(recordLocksSQL); err != nil {
+		return fmt.Errorf("failed to create record_locks table: %w", err)
+	}
+
+	// Create replication tracking tables used in edge server mode
+	replicationSQL := `
+		CREATE TABLE replicated_records (
+			observation_id VARCHAR(255) PRIMARY KEY,
+			synced_version BIGINT NOT NULL,
+			synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE id_range_blocks (
+			id BIGSERIAL PRIMARY KEY,
+			sequence VARCHAR(255) NOT NULL,
+			range_start BIGINT NOT NULL,
+			range_end BIGINT NOT NULL,
+			next_value BIGINT NOT NULL,
+			reserved_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			UNIQUE (sequence, range_start)
+		)`
+	if _, err := db.Exec(replicationSQL); err != nil {
+		return fmt.Errorf("failed to create replication tables: %w", err)
+	}
+
+	// Create sync log compactions table
+	compactionsSQL := `
+		CREATE TABLE sync_compactions (
+			id BIGSERIAL PRIMARY KEY,
+			tombstone_cutoff_version BIGINT NOT NULL DEFAULT 0,
+			history_cutoff_version BIGINT NOT NULL DEFAULT 0,
+			tombstones_purged BIGINT NOT NULL DEFAULT 0,
+			history_versions_removed BIGINT NOT NULL DEFAULT 0,
+			triggered_by VARCHAR(255) NOT NULL,
+			started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			finished_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`
+	if _, err := db.Exec(compactionsSQL); err != nil {
+		return fmt.Errorf("failed to create sync_compactions table: %w", err)
+	}
+
 	// Create trigger function
 	triggerFunctionSQL := `
 		CREATE OR REPLACE FUNCTION update_sync_version() RETURNS TRIGGER AS $$
 		BEGIN
-			UPDATE sync_version SET current_version = current_version + 1, updated_at = CURRENT_TIMESTAMP;
-			NEW.version = (SELECT current_version FROM sync_version ORDER BY id DESC LIMIT 1);
+			NEW.version = next_sync_version();
 			RETURN NEW;
 		END;
 		$$ LANGUAGE plpgsql;
@@ -186,18 +412,149 @@ func ensureTestSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to create trigger: %w", err)
 	}
 
+	// Create cases table sharing the sync version
+	casesSQL := []string{
+		`CREATE TABLE cases (
+			case_id VARCHAR(255) PRIMARY KEY,
+			case_type VARCHAR(255) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+			data JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			closed_at TIMESTAMP WITH TIME ZONE,
+			deleted BOOLEAN NOT NULL DEFAULT FALSE,
+			version BIGINT NOT NULL DEFAULT 1,
+			created_by VARCHAR(255),
+			org_unit_id UUID REFERENCES org_units(id) ON DELETE RESTRICT
+		)`,
+		`CREATE TRIGGER cases_version_trigger
+			BEFORE INSERT OR UPDATE ON cases
+			FOR EACH ROW EXECUTE FUNCTION update_sync_version()`,
+	}
+	for _, query := range casesSQL {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create cases table: %w", err)
+		}
+	}
+
+	// Create observation_history table and the trigger filling it
+	historySQL := []string{
+		`CREATE TABLE observation_history (
+			id BIGSERIAL PRIMARY KEY,
+			observation_id VARCHAR(255) NOT NULL,
+			version BIGINT NOT NULL,
+			form_type VARCHAR(255) NOT NULL,
+			form_version VARCHAR(50) NOT NULL,
+			data JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			synced_at TIMESTAMP WITH TIME ZONE,
+			deleted BOOLEAN NOT NULL DEFAULT FALSE,
+			draft BOOLEAN NOT NULL DEFAULT FALSE,
+			draft_owner VARCHAR(255),
+			created_by VARCHAR(255),
+			owner VARCHAR(255),
+			org_unit_id UUID,
+			case_id VARCHAR(255),
+			tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+			recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE OR REPLACE FUNCTION record_observation_history() RETURNS TRIGGER AS $$
+		BEGIN
+			INSERT INTO observation_history (observation_id, version, form_type, form_version, data, created_at, updated_at, synced_at, deleted, draft, draft_owner, created_by, owner, org_unit_id, case_id, tenant_id)
+			VALUES (NEW.observation_id, NEW.version, NEW.form_type, NEW.form_version, NEW.data, NEW.created_at, NEW.updated_at, NEW.synced_at, NEW.deleted, NEW.draft, NEW.draft_owner, NEW.created_by, NEW.owner, NEW.org_unit_id, NEW.case_id, NEW.tenant_id);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`CREATE TRIGGER observations_history_trigger
+			AFTER INSERT OR UPDATE ON observations
+			FOR EACH ROW EXECUTE FUNCTION record_observation_history()`,
+	}
+	for _, query := range historySQL {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create observation history: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // ResetTestData cleans all test data and resets version to 1
 func ResetTestData(db *sql.DB) error {
+	// Clean observation assignments
+	if _, err := db.Exec("DELETE FROM observation_assignments"); err != nil {
+		return fmt.Errorf("failed to clean observation assignments: %w", err)
+	}
+
 	// Clean observations
 	if _, err := db.Exec("DELETE FROM observations"); err != nil {
 		return fmt.Errorf("failed to clean observations: %w", err)
 	}
 
+	// Clean observation history
+	if _, err := db.Exec("DELETE FROM observation_history"); err != nil {
+		return fmt.Errorf("failed to clean observation history: %w", err)
+	}
+
+	// Clean form sync controls
+	if _, err := db.Exec("DELETE FROM form_sync_controls"); err != nil {
+		return fmt.Errorf("failed to clean form sync controls: %w", err)
+	}
+
+	// Clean reporting period locks and the review backlog
+	if _, err := db.Exec("DELETE FROM reporting_period_locks"); err != nil {
+		return fmt.Errorf("failed to clean reporting period locks: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM sync_conflicts"); err != nil {
+		return fmt.Errorf("failed to clean sync conflicts: %w", err)
+	}
+
+	// Clean server-assigned field sequences
+	if _, err := db.Exec("DELETE FROM field_sequences"); err != nil {
+		return fmt.Errorf("failed to clean field sequences: %w", err)
+	}
+
+	// Clean ID range allocations
+	if _, err := db.Exec("DELETE FROM id_range_allocations"); err != nil {
+		return fmt.Errorf("failed to clean ID range allocations: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM id_sequences"); err != nil {
+		return fmt.Errorf("failed to clean ID sequences: %w", err)
+	}
+
+	// Clean record locks
+	if _, err := db.Exec("DELETE FROM record_locks"); err != nil {
+		return fmt.Errorf("failed to clean record locks: %w", err)
+	}
+
+	// Clean replication state
+	if _, err := db.Exec("DELETE FROM replicated_records"); err != nil {
+		return fmt.Errorf("failed to clean replicated records: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM id_range_blocks"); err != nil {
+		return fmt.Errorf("failed to clean ID range blocks: %w", err)
+	}
+
+	// Clean compactions
+	if _, err := db.Exec("DELETE FROM sync_compactions"); err != nil {
+		return fmt.Errorf("failed to clean compactions: %w", err)
+	}
+
+	// Clean cases
+	if _, err := db.Exec("DELETE FROM cases"); err != nil {
+		return fmt.Errorf("failed to clean cases: %w", err)
+	}
+
+	// Clean org units
+	if _, err := db.Exec("DELETE FROM user_org_units"); err != nil {
+		return fmt.Errorf("failed to clean user org units: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM org_units"); err != nil {
+		return fmt.Errorf("failed to clean org units: %w", err)
+	}
+
 	// Reset sync version
-	if _, err := db.Exec("UPDATE sync_version SET current_version = 1, updated_at = CURRENT_TIMESTAMP"); err != nil {
+	if _, err := db.Exec("SELECT setval('sync_version_seq', 1)"); err != nil {
 		return fmt.Errorf("failed to reset sync version: %w", err)
 	}
 
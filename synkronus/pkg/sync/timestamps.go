@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+)
+
+// normalizedTimestamps holds the timestamps stored for a pushed record
+type normalizedTimestamps struct {
+	CreatedAt string
+	UpdatedAt string
+	// ClientCreatedAt and ClientUpdatedAt keep what the device reported, if it was parseable
+	ClientCreatedAt *time.Time
+	ClientUpdatedAt *time.Time
+	ReceivedAt      time.Time
+}
+
+// normalizeTimestamps validates the client provided created_at/updated_at of a record
+// against the server receive time. Missing timestamps are filled in with the receive
+// time; timestamps outside the plausible window are flagged or corrected according to
+// the configured policy. An error is returned for unparseable timestamps that cannot
+// be corrected.
+func (s *Service) normalizeTimestamps(record Observation, receivedAt time.Time) (normalizedTimestamps, []SyncWarning, error) {
+	result := normalizedTimestamps{ReceivedAt: receivedAt}
+	var warnings []SyncWarning
+
+	normalize := func(field, value string) (string, *time.Time, error) {
+		if value == "" {
+			warnings = append(warnings, SyncWarning{
+				ID:      record.ObservationID,
+				Code:    WarningCodeTimestampCorrected,
+				Message: fmt.Sprintf("%s is empty and was set to the server receive time", field),
+			})
+			return receivedAt.Format(time.RFC3339Nano), nil, nil
+		}
+
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			if s.config.TimestampPolicy == TimestampPolicyCorrect {
+				warnings = append(warnings, SyncWarning{
+					ID:      record.ObservationID,
+					Code:    WarningCodeTimestampCorrected,
+					Message: fmt.Sprintf("%s %q is not a valid RFC 3339 timestamp and was set to the server receive time", field, value),
+				})
+				return receivedAt.Format(time.RFC3339Nano), nil, nil
+			}
+			return "", nil, fmt.Errorf("%s %q is not a valid RFC 3339 timestamp", field, value)
+		}
+
+		var problem string
+		switch {
+		case !s.config.MinValidTimestamp.IsZero() && parsed.Before(s.config.MinValidTimestamp):
+			problem = fmt.Sprintf("is before %s", s.config.MinValidTimestamp.Format(time.RFC3339))
+		case s.config.MaxClockSkew > 0 && parsed.After(receivedAt.Add(s.config.MaxClockSkew)):
+			problem = fmt.Sprintf("is more than %s ahead of server time", s.config.MaxClockSkew)
+		}
+		if problem == "" {
+			return value, &parsed, nil
+		}
+
+		if s.config.TimestampPolicy == TimestampPolicyCorrect {
+			warnings = append(warnings, SyncWarning{
+				ID:      record.ObservationID,
+				Code:    WarningCodeTimestampCorrected,
+				Message: fmt.Sprintf("%s %s %s and was set to the server receive time", field, value, problem),
+			})
+			return receivedAt.Format(time.RFC3339Nano), &parsed, nil
+		}
+
+		warnings = append(warnings, SyncWarning{
+			ID:      record.ObservationID,
+			Code:    WarningCodeTimestampSkewed,
+			Message: fmt.Sprintf("%s %s %s; check the device clock", field, value, problem),
+		})
+		return value, &parsed, nil
+	}
+
+	var err error
+	if result.CreatedAt, result.ClientCreatedAt, err = normalize("created_at", record.CreatedAt); err != nil {
+		return result, nil, err
+	}
+	if result.UpdatedAt, result.ClientUpdatedAt, err = normalize("updated_at", record.UpdatedAt); err != nil {
+		return result, nil, err
+	}
+
+	return result, warnings, nil
+}
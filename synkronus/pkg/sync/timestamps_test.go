@@ -0,0 +1,68 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+func TestNormalizeTimestamps(t *testing.T) {
+	receivedAt := time.Date(2025, time.September, 5, 12, 0, 0, 0, time.UTC)
+	received := receivedAt.Format(time.RFC3339Nano)
+
+	tests := []struct {
+		name        string
+		policy      TimestampPolicy
+		createdAt   string
+		wantCreated string
+		wantCode    string
+		wantErr     bool
+	}{
+		{"valid timestamp", TimestampPolicyFlag, "2025-09-05T10:00:00Z", "2025-09-05T10:00:00Z", "", false},
+		{"small future skew", TimestampPolicyFlag, "2025-09-05T13:00:00Z", "2025-09-05T13:00:00Z", "", false},
+		{"empty timestamp", TimestampPolicyFlag, "", received, WarningCodeTimestampCorrected, false},
+		{"dead clock flagged", TimestampPolicyFlag, "1970-01-01T00:00:00Z", "1970-01-01T00:00:00Z", WarningCodeTimestampSkewed, false},
+		{"dead clock corrected", TimestampPolicyCorrect, "1970-01-01T00:00:00Z", received, WarningCodeTimestampCorrected, false},
+		{"far future flagged", TimestampPolicyFlag, "2030-01-01T00:00:00Z", "2030-01-01T00:00:00Z", WarningCodeTimestampSkewed, false},
+		{"unparseable flagged", TimestampPolicyFlag, "yesterday", "", "", true},
+		{"unparseable corrected", TimestampPolicyCorrect, "yesterday", received, WarningCodeTimestampCorrected, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.TimestampPolicy = tt.policy
+			service := NewService(nil, config, logger.NewLogger())
+
+			record := Observation{ObservationID: "obs-1", CreatedAt: tt.createdAt, UpdatedAt: "2025-09-05T10:00:00Z"}
+			result, warnings, err := service.normalizeTimestamps(record, receivedAt)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.CreatedAt != tt.wantCreated {
+				t.Errorf("expected created_at %q, got %q", tt.wantCreated, result.CreatedAt)
+			}
+			if !result.ReceivedAt.Equal(receivedAt) {
+				t.Errorf("expected received_at %v, got %v", receivedAt, result.ReceivedAt)
+			}
+
+			if tt.wantCode == "" {
+				if len(warnings) != 0 {
+					t.Errorf("expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || warnings[0].Code != tt.wantCode {
+				t.Errorf("expected one %s warning, got %v", tt.wantCode, warnings)
+			}
+		})
+	}
+}
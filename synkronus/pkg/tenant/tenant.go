@@ -0,0 +1,49 @@
+// Package tenant identifies the tenant a request acts for. With multi-tenancy enabled, one
+// server keeps the users, observations and app bundles of independent projects apart; users
+// and data created before it was enabled belong to the default tenant.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Default is the tenant of single-tenant servers and of data created before tenancy was enabled
+const Default = "default"
+
+// ErrInvalidID is returned for tenant IDs that are not lowercase slugs
+var ErrInvalidID = errors.New("tenant IDs must be 1-63 lowercase letters, digits and dashes, starting with a letter or digit")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Validate checks that id can name a tenant; IDs are used in storage paths and prefixes
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrInvalidID
+	}
+	return nil
+}
+
+// OrDefault returns id, or Default for the empty ID of users and tokens that predate tenancy
+func OrDefault(id string) string {
+	if id == "" {
+		return Default
+	}
+	return id
+}
+
+type contextKey string
+
+const tenantKey contextKey = "tenant"
+
+// WithTenant returns a context acting for tenant id
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey, id)
+}
+
+// FromContext returns the tenant set by WithTenant, or Default
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey).(string)
+	return OrDefault(id)
+}
@@ -0,0 +1,60 @@
+package terms
+
+import (
+	"context"
+	"errors"
+)
+
+// Common errors
+var (
+	// ErrNoDocument is returned when no terms document has been published
+	ErrNoDocument = errors.New("no terms document published")
+	// ErrInvalidDocument is returned when a document has no title or body
+	ErrInvalidDocument = errors.New("invalid terms document")
+	// ErrNotCurrentVersion is returned when acknowledging a version that has been superseded
+	ErrNotCurrentVersion = errors.New("terms version is not current")
+	// ErrNotAcknowledged is returned when a user has not acknowledged the current version
+	ErrNotAcknowledged = errors.New("terms not acknowledged")
+)
+
+// Document is a version of the terms of use and data-use agreement users confirm before
+// syncing. Publishing a new version requires every user to acknowledge it again.
+type Document struct {
+	Version     int    `json:"version" db:"version"`
+	Title       string `json:"title" db:"title"`
+	Body        string `json:"body" db:"body"`
+	PublishedBy string `json:"published_by" db:"published_by"`
+	PublishedAt string `json:"published_at" db:"published_at"`
+}
+
+// Acknowledgement records that a user confirmed a version of the terms
+type Acknowledgement struct {
+	Username       string `json:"username" db:"username"`
+	Version        int    `json:"version" db:"version"`
+	AcknowledgedAt string `json:"acknowledged_at" db:"acknowledged_at"`
+}
+
+// Status is the current document together with a user's acknowledgement of it
+type Status struct {
+	Document       *Document `json:"document"`
+	Acknowledged   bool      `json:"acknowledged"`
+	AcknowledgedAt *string   `json:"acknowledged_at,omitempty"`
+}
+
+// Service manages the terms document and users' acknowledgements of it
+type Service interface {
+	// Current returns the latest published document, or ErrNoDocument
+	Current(ctx context.Context) (*Document, error)
+
+	// Publish stores a new version of the document and makes it current
+	Publish(ctx context.Context, title, body, publishedBy string) (*Document, error)
+
+	// Status returns the current document and whether username has acknowledged it
+	Status(ctx context.Context, username string) (*Status, error)
+
+	// Acknowledge records that username confirmed version, which must be the current one
+	Acknowledge(ctx context.Context, username string, version int) (*Acknowledgement, error)
+
+	// ListAcknowledgements returns the acknowledgements of a version, oldest first
+	ListAcknowledgements(ctx context.Context, version int) ([]Acknowledgement, error)
+}
@@ -0,0 +1,148 @@
+package terms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+)
+
+type service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new terms service
+func NewService(db *sql.DB, log *logger.Logger) Service {
+	return &service{db: db, log: log}
+}
+
+// Current returns the latest published document, or ErrNoDocument
+func (s *service) Current(ctx context.Context) (*Document, error) {
+	var doc Document
+	err := s.db.QueryRowContext(ctx, `
+		SELECT version, title, body, published_by, published_at
+		FROM terms_documents ORDER BY version DESC LIMIT 1`,
+	).Scan(&doc.Version, &doc.Title, &doc.Body, &doc.PublishedBy, &doc.PublishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoDocument
+		}
+		s.log.Error("Failed to get terms document", "error", err)
+		return nil, fmt.Errorf("failed to get terms document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Publish stores a new version of the document and makes it current
+func (s *service) Publish(ctx context.Context, title, body, publishedBy string) (*Document, error) {
+	if strings.TrimSpace(title) == "" || strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: title and body are required", ErrInvalidDocument)
+	}
+
+	var doc Document
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO terms_documents (title, body, published_by, published_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING version, title, body, published_by, published_at`,
+		title, body, publishedBy,
+	).Scan(&doc.Version, &doc.Title, &doc.Body, &doc.PublishedBy, &doc.PublishedAt)
+	if err != nil {
+		s.log.Error("Failed to publish terms document", "error", err)
+		return nil, fmt.Errorf("failed to publish terms document: %w", err)
+	}
+
+	s.log.Info("Terms document published", "version", doc.Version, "publishedBy", publishedBy)
+	return &doc, nil
+}
+
+// Status returns the current document and whether username has acknowledged it
+func (s *service) Status(ctx context.Context, username string) (*Status, error) {
+	var doc Document
+	var acknowledgedAt sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT d.version, d.title, d.body, d.published_by, d.published_at, a.acknowledged_at
+		FROM terms_documents d
+		LEFT JOIN terms_acknowledgements a ON a.version = d.version AND a.username = $1
+		ORDER BY d.version DESC LIMIT 1`,
+		username,
+	).Scan(&doc.Version, &doc.Title, &doc.Body, &doc.PublishedBy, &doc.PublishedAt, &acknowledgedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoDocument
+		}
+		s.log.Error("Failed to get terms status", "error", err, "username", username)
+		return nil, fmt.Errorf("failed to get terms status: %w", err)
+	}
+
+	status := &Status{Document: &doc, Acknowledged: acknowledgedAt.Valid}
+	if acknowledgedAt.Valid {
+		status.AcknowledgedAt = &acknowledgedAt.String
+	}
+	return status, nil
+}
+
+// Acknowledge records that username confirmed version, which must be the current one.
+// Acknowledging a version again keeps the time of the first acknowledgement.
+func (s *service) Acknowledge(ctx context.Context, username string, version int) (*Acknowledgement, error) {
+	current, err := s.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version != current.Version {
+		return nil, fmt.Errorf("%w: the current version is %d", ErrNotCurrentVersion, current.Version)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO terms_acknowledgements (username, version, acknowledged_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (username, version) DO NOTHING`,
+		username, version,
+	); err != nil {
+		s.log.Error("Failed to record terms acknowledgement", "error", err, "username", username, "version", version)
+		return nil, fmt.Errorf("failed to record terms acknowledgement: %w", err)
+	}
+
+	ack := Acknowledgement{Username: username, Version: version}
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT acknowledged_at FROM terms_acknowledgements WHERE username = $1 AND version = $2",
+		username, version,
+	).Scan(&ack.AcknowledgedAt); err != nil {
+		return nil, fmt.Errorf("failed to get terms acknowledgement: %w", err)
+	}
+
+	s.log.Info("Terms acknowledged", "username", username, "version", version)
+	return &ack, nil
+}
+
+// ListAcknowledgements returns the acknowledgements of a version, oldest first
+func (s *service) ListAcknowledgements(ctx context.Context, version int) ([]Acknowledgement, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT username, version, acknowledged_at FROM terms_acknowledgements
+		WHERE version = $1 ORDER BY acknowledged_at, username`,
+		version,
+	)
+	if err != nil {
+		s.log.Error("Failed to query terms acknowledgements", "error", err, "version", version)
+		return nil, fmt.Errorf("failed to query terms acknowledgements: %w", err)
+	}
+	defer rows.Close()
+
+	acks := make([]Acknowledgement, 0)
+	for rows.Next() {
+		var ack Acknowledgement
+		if err := rows.Scan(&ack.Username, &ack.Version, &ack.AcknowledgedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan terms acknowledgement: %w", err)
+		}
+		acks = append(acks, ack)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return acks, nil
+}
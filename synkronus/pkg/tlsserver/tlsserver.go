@@ -0,0 +1,208 @@
+// Package tlsserver serves the API over HTTPS without a reverse proxy in front, with a
+// certificate from files or obtained from Let's Encrypt, and redirects plain HTTP to it.
+package tlsserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certificateCheckInterval is how often certificate files are checked for a renewal
+const certificateCheckInterval = time.Minute
+
+// Config holds the HTTPS settings: a certificate from files, or domains to obtain certificates
+// for from an ACME CA such as Let's Encrypt
+type Config struct {
+	// CertFile and KeyFile are the PEM certificate chain and its private key. They are read again
+	// when they change on disk, so renewed certificates are used without a restart.
+	CertFile string
+	KeyFile  string
+	// Domains are the host names certificates are obtained for over ACME; they are renewed
+	// before they expire
+	Domains []string
+	// Email is given to the CA for notices about the certificates
+	Email string
+	// CacheDir keeps the obtained certificates and the ACME account key across restarts
+	CacheDir string
+	// DirectoryURL is the ACME directory; empty uses Let's Encrypt production
+	DirectoryURL string
+	// RedirectAddr is the address of a plain HTTP listener redirecting to HTTPS and answering
+	// ACME HTTP-01 challenges; empty disables it
+	RedirectAddr string
+	// ClientCAFile holds the PEM certificates of the CAs whose client certificates are verified,
+	// for the client_cert authenticator. Clients without a certificate are still accepted.
+	ClientCAFile string
+}
+
+// Enabled reports whether HTTPS is configured
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || len(c.Domains) > 0
+}
+
+// validate checks the settings before the server starts
+func (c Config) validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("TLS needs both a certificate and a key file")
+	}
+	if c.CertFile != "" && len(c.Domains) > 0 {
+		return errors.New("use either certificate files or automatic certificates, not both")
+	}
+	if len(c.Domains) > 0 && c.CacheDir == "" {
+		return errors.New("automatic certificates need a cache directory")
+	}
+	return nil
+}
+
+// Configure sets server up to serve HTTPS and returns the plain HTTP server redirecting to it,
+// or nil without a RedirectAddr. Serve server with ListenAndServeTLS("", "") and the returned
+// server with ListenAndServe.
+func Configure(server *http.Server, c Config, log *logger.Logger) (*http.Server, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	redirect := http.Handler(redirectHandler(server.Addr))
+	if len(c.Domains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(c.Domains...),
+			Cache:      autocert.DirCache(c.CacheDir),
+			Email:      c.Email,
+		}
+		if c.DirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: c.DirectoryURL}
+		}
+		server.TLSConfig = manager.TLSConfig()
+		// Challenges are answered on the plain HTTP listener, everything else is redirected
+		redirect = manager.HTTPHandler(redirect)
+		log.Info("Obtaining TLS certificates automatically", "domains", c.Domains)
+	} else {
+		certs := &certificateFiles{certFile: c.CertFile, keyFile: c.KeyFile, log: log}
+		if err := certs.reload(); err != nil {
+			return nil, err
+		}
+		certs.checked = time.Now()
+		server.TLSConfig = &tls.Config{GetCertificate: certs.getCertificate}
+	}
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+	if c.ClientCAFile != "" {
+		pool, err := loadClientCAs(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig.ClientCAs = pool
+		server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		log.Info("Verifying TLS client certificates", "file", c.ClientCAFile)
+	}
+
+	if c.RedirectAddr == "" {
+		return nil, nil
+	}
+	return &http.Server{
+		Addr:         c.RedirectAddr,
+		Handler:      redirect,
+		ReadTimeout:  server.ReadTimeout,
+		WriteTimeout: server.WriteTimeout,
+		IdleTimeout:  server.IdleTimeout,
+	}, nil
+}
+
+// loadClientCAs reads the CA certificates client certificates are verified against
+func loadClientCAs(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in TLS client CA file %s", file)
+	}
+	return pool, nil
+}
+
+// redirectHandler redirects GET and HEAD requests to the same URL over HTTPS on the port of
+// httpsAddr. Other requests are refused, as their body has already been sent in the clear.
+func redirectHandler(httpsAddr string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Use HTTPS", http.StatusBadRequest)
+			return
+		}
+		host := (&url.URL{Host: r.Host}).Hostname()
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}
+}
+
+// certificateFiles serves the certificate of a pair of PEM files, reading them again when they
+// change. A renewal that is half written, such as a new certificate next to the old key, keeps
+// the previous certificate until both files match.
+type certificateFiles struct {
+	certFile string
+	keyFile  string
+	log      *logger.Logger
+
+	mu      sync.Mutex
+	checked time.Time
+	modTime time.Time
+	cert    *tls.Certificate
+}
+
+// getCertificate implements tls.Config.GetCertificate
+func (f *certificateFiles) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if time.Since(f.checked) >= certificateCheckInterval {
+		f.checked = time.Now()
+		if err := f.reload(); err != nil {
+			f.log.Warn("Failed to read TLS certificate, using the previous one", "error", err)
+		}
+	}
+	return f.cert, nil
+}
+
+// reload reads the files when they changed since the certificate was last read
+func (f *certificateFiles) reload() error {
+	var modTime time.Time
+	for _, name := range []string{f.certFile, f.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS certificate: %w", err)
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if f.cert != nil && modTime.Equal(f.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return fmt.Errorf("invalid TLS certificate: %w", err)
+	}
+	if f.cert != nil {
+		f.log.Info("TLS certificate changed, serving the new one", "file", f.certFile)
+	}
+	f.cert = &cert
+	f.modTime = modTime
+	return nil
+}
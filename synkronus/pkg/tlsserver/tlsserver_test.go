@@ -0,0 +1,151 @@
+package tlsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed PEM certificate and its key
+func writeCertificate(t *testing.T, certFile, keyFile, name string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	if keyFile != "" {
+		require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	}
+}
+
+// commonName returns the subject of the certificate served
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed.Subject.CommonName
+}
+
+func TestConfig(t *testing.T) {
+	assert.False(t, Config{RedirectAddr: ":80"}.Enabled())
+	assert.True(t, Config{Domains: []string{"sync.example.org"}}.Enabled())
+
+	assert.ErrorContains(t, Config{CertFile: "server.crt"}.validate(), "both a certificate and a key")
+	assert.ErrorContains(t, Config{CertFile: "server.crt", KeyFile: "server.key", Domains: []string{"sync.example.org"}}.validate(), "not both")
+	assert.ErrorContains(t, Config{Domains: []string{"sync.example.org"}}.validate(), "cache directory")
+	assert.NoError(t, Config{Domains: []string{"sync.example.org"}, CacheDir: t.TempDir()}.validate())
+}
+
+func TestConfigure_CertificateFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, certFile, keyFile, "first.example.org")
+
+	server := &http.Server{Addr: ":8443", ReadTimeout: 15 * time.Second}
+	redirect, err := Configure(server, Config{CertFile: certFile, KeyFile: keyFile, RedirectAddr: ":8080"}, logger.NewLogger())
+	require.NoError(t, err)
+	require.NotNil(t, redirect)
+	assert.Equal(t, ":8080", redirect.Addr)
+	assert.Equal(t, 15*time.Second, redirect.ReadTimeout)
+	assert.Equal(t, uint16(tls.VersionTLS12), server.TLSConfig.MinVersion)
+
+	cert, err := server.TLSConfig.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "first.example.org", commonName(t, cert))
+
+	_, err = Configure(&http.Server{}, Config{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile}, logger.NewLogger())
+	assert.Error(t, err)
+}
+
+func TestConfigure_ClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "clients.crt")
+	writeCertificate(t, certFile, keyFile, "sync.example.org")
+	writeCertificate(t, caFile, "", "Field Devices CA")
+
+	server := &http.Server{Addr: ":8443"}
+	_, err := Configure(server, Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}, logger.NewLogger())
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, server.TLSConfig.ClientAuth)
+	assert.NotNil(t, server.TLSConfig.ClientCAs)
+
+	_, err = Configure(&http.Server{}, Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, logger.NewLogger())
+	assert.ErrorContains(t, err, "no certificates")
+}
+
+func TestCertificateFiles_Renewal(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, certFile, keyFile, "first.example.org")
+	files := &certificateFiles{certFile: certFile, keyFile: keyFile, log: logger.NewLogger()}
+	require.NoError(t, files.reload())
+
+	// A new certificate next to the old key keeps the previous certificate
+	writeCertificate(t, certFile, "", "second.example.org")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	cert, err := files.getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "first.example.org", commonName(t, cert))
+
+	writeCertificate(t, certFile, keyFile, "second.example.org")
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	files.checked = time.Time{}
+	cert, err = files.getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "second.example.org", commonName(t, cert))
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsAddr string
+		method    string
+		host      string
+		target    string
+		status    int
+		location  string
+	}{
+		{"default port", ":443", http.MethodGet, "sync.example.org", "/sync/pull?since=3", http.StatusMovedPermanently, "https://sync.example.org/sync/pull?since=3"},
+		{"other port", ":8443", http.MethodGet, "sync.example.org:8080", "/health", http.StatusMovedPermanently, "https://sync.example.org:8443/health"},
+		{"IPv6 address", ":443", http.MethodHead, "[::1]:80", "/", http.StatusMovedPermanently, "https://[::1]/"},
+		{"body sent in the clear", ":443", http.MethodPost, "sync.example.org", "/auth/login", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			redirectHandler(tt.httpsAddr)(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+}
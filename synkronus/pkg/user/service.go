@@ -9,6 +9,7 @@ import (
 	"github.com/opendataensemble/synkronus/internal/repository"
 	"github.com/opendataensemble/synkronus/pkg/auth"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 )
 
 // Service implements the UserServiceInterface
@@ -50,13 +51,14 @@ func (s *Service) CreateUser(ctx context.Context, username, password string, rol
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Create the user
+	// Create the user in the tenant the admin acts for
 	user := models.NewUser(
 		uuid.New(),
 		username,
 		hashedPassword,
 		role,
 	)
+	user.TenantID = tenant.FromContext(ctx)
 
 	// Save the user to the database
 	if err := s.userRepo.Create(ctx, user); err != nil {
@@ -70,13 +72,9 @@ func (s *Service) CreateUser(ctx context.Context, username, password string, rol
 // DeleteUser deletes a user by username
 func (s *Service) DeleteUser(ctx context.Context, username string) error {
 	// Get the user
-	user, err := s.userRepo.GetByUsername(ctx, username)
+	user, err := s.tenantUser(ctx, username)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
-	}
-
-	if user == nil {
-		return ErrUserNotFound
+		return err
 	}
 
 	// Delete the user
@@ -91,13 +89,9 @@ func (s *Service) DeleteUser(ctx context.Context, username string) error {
 // ResetPassword resets a user's password (admin operation)
 func (s *Service) ResetPassword(ctx context.Context, username, newPassword string) error {
 	// Get the user
-	user, err := s.userRepo.GetByUsername(ctx, username)
+	user, err := s.tenantUser(ctx, username)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
-	}
-
-	if user == nil {
-		return ErrUserNotFound
+		return err
 	}
 
 	// Hash the new password
@@ -149,7 +143,20 @@ func (s *Service) ChangePassword(ctx context.Context, username, currentPassword,
 	return nil
 }
 
-// ListUsers lists all users in the system (admin operation)
+// tenantUser returns the user with the given username if it belongs to the tenant in ctx;
+// admins cannot act on the users of other tenants
+func (s *Service) tenantUser(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || tenant.OrDefault(user.TenantID) != tenant.FromContext(ctx) {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ListUsers lists the users of the tenant in ctx (admin operation)
 func (s *Service) ListUsers(ctx context.Context) ([]models.User, error) {
 	userList, err := s.userRepo.List(ctx)
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"github.com/opendataensemble/synkronus/internal/models"
 	"github.com/opendataensemble/synkronus/pkg/auth"
 	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -533,3 +534,37 @@ func TestChangePassword_UserNotFound(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 	mockAuthService.AssertExpectations(t)
 }
+
+func TestUsersOfOtherTenants(t *testing.T) {
+	// Create mocks
+	mockRepo := new(MockUserRepository)
+	mockAuthService := new(MockAuthService)
+
+	// Create the service with mocks
+	service := &Service{
+		userRepo:    mockRepo,
+		authService: mockAuthService,
+		log:         logger.NewLogger(),
+	}
+
+	// An admin acting for tenant acme, and a user of the default tenant
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	mockRepo.On("GetByUsername", ctx, "other").Return(&models.User{ID: uuid.New(), Username: "other"}, nil)
+	mockRepo.On("GetByUsername", ctx, "newuser").Return(nil, nil)
+	mockAuthService.On("HashPassword", "password123").Return("hashed", nil)
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(u *models.User) bool { return u.TenantID == "acme" })).Return(nil)
+
+	// Users of other tenants cannot be deleted or have their password reset
+	assert.ErrorIs(t, service.DeleteUser(ctx, "other"), ErrUserNotFound)
+	assert.ErrorIs(t, service.ResetPassword(ctx, "other", "password123"), ErrUserNotFound)
+
+	// New users join the tenant the admin acts for
+	created, err := service.CreateUser(ctx, "newuser", "password123", models.RoleReadWrite)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", created.TenantID)
+
+	// Verify all expectations were met; nothing was deleted or updated
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
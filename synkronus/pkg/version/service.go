@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"runtime"
+	"runtime/debug"
 )
 
 // Service provides version information
@@ -51,13 +52,42 @@ type BuildInfo struct {
 	GoVersion string `json:"go_version"`
 }
 
-// These will be set during build using -ldflags
+// These will be set during build using -ldflags, see build.sh
 var (
 	version   = "dev"
 	commit    = ""
 	buildTime = ""
 )
 
+// init falls back to the VCS revision stamped by the Go toolchain when the commit was not set
+// with -ldflags, e.g. for a plain `go build` from a git checkout
+func init() {
+	if commit != "" {
+		return
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			commit = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if commit != "" && modified {
+		commit += "-dirty"
+	}
+}
+
+// Version returns the server version set at build time
+func Version() string {
+	return version
+}
+
 // GetVersion returns version and system information
 func (s *service) GetVersion(ctx context.Context) (*SystemVersionInfo, error) {
 	// Get database info
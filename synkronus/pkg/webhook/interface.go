@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opendataensemble/synkronus/pkg/outbound"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+// Common errors
+var (
+	// ErrSubscriptionNotFound is returned when a subscription does not exist
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	// ErrInvalidSubscription is returned when a subscription has no name, a bad URL or bad rules
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+	// ErrDeliveryNotFound is returned when a delivery does not exist
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrInvalidReplay is returned when a replay selects neither deliveries nor a subscription
+	ErrInvalidReplay = errors.New("invalid replay request")
+	// ErrInvalidEvent is returned when Notify is given an event it cannot queue
+	ErrInvalidEvent = errors.New("invalid webhook event")
+)
+
+// Events subscriptions can select
+const (
+	// EventObservationPushed is delivered for every finalized observation written by a push
+	EventObservationPushed = "observation.pushed"
+	// EventBundleActivated is delivered when an app bundle version is made the active one
+	EventBundleActivated = "bundle.activated"
+	// EventUserCreated is delivered when a user is created by an admin or by accepting an invitation
+	EventUserCreated = "user.created"
+)
+
+// Events lists every event subscriptions can select
+var Events = []string{EventObservationPushed, EventBundleActivated, EventUserCreated}
+
+// Dispatcher defaults
+const (
+	// DefaultPollInterval is how often queued deliveries are picked up
+	DefaultPollInterval = 2 * time.Second
+	// DefaultMaxAttempts is how often a delivery is tried before it is marked failed
+	DefaultMaxAttempts = 10
+	// DefaultTimeout bounds each delivery request
+	DefaultTimeout = 10 * time.Second
+)
+
+// MaskMethod selects how a masked field is rewritten before delivery
+type MaskMethod string
+
+const (
+	// MaskRedact replaces the value with null
+	MaskRedact MaskMethod = "redact"
+	// MaskHash replaces the value with an HMAC-SHA256 keyed with the subscription secret, so
+	// receivers can match records of the same person without learning who it is
+	MaskHash MaskMethod = "hash"
+)
+
+// MaskRule masks one field of the observation data. Field is a dotted path into the data,
+// e.g. "patient.phone".
+type MaskRule struct {
+	Field  string     `json:"field"`
+	Method MaskMethod `json:"method"`
+}
+
+// Subscription delivers the selected events to a URL
+type Subscription struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	URL  string `json:"url" db:"url"`
+	// Secret signs deliveries and keys hashed fields; it is only returned when the subscription is created
+	Secret string `json:"secret,omitempty" db:"secret"`
+	// Events selects the events delivered; empty selects observation.pushed only
+	Events []string `json:"events" db:"events"`
+	// FormTypes limits observation deliveries to these form types; empty selects all
+	FormTypes []string `json:"form_types" db:"form_types"`
+	// Fields limits the delivered data to these dotted paths; empty delivers all of it
+	Fields    []string   `json:"fields" db:"fields"`
+	Masks     []MaskRule `json:"masks" db:"masks"`
+	CreatedBy string     `json:"created_by" db:"created_by"`
+	CreatedAt string     `json:"created_at" db:"created_at"`
+}
+
+// DeliveryStatus is the state of a queued delivery
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is waiting for its first or next attempt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered was accepted by the receiver with a 2xx response
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed gave up after the maximum number of attempts. Failed deliveries form the
+	// dead-letter list and stay there until an admin replays them.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Delivery is one event queued for a subscription. ObservationID is only set for observation.pushed.
+type Delivery struct {
+	ID             int64          `json:"id" db:"id"`
+	SubscriptionID string         `json:"subscription_id" db:"subscription_id"`
+	Event          string         `json:"event" db:"event"`
+	ObservationID  *string        `json:"observation_id,omitempty" db:"observation_id"`
+	Status         DeliveryStatus `json:"status" db:"status"`
+	Attempts       int            `json:"attempts" db:"attempts"`
+	LastError      *string        `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      string         `json:"created_at" db:"created_at"`
+	NextAttemptAt  *string        `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt    *string        `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// Attempt is one try at sending a delivery. StatusCode is nil when the receiver could not be reached.
+type Attempt struct {
+	ID          int64   `json:"id" db:"id"`
+	DeliveryID  int64   `json:"delivery_id" db:"delivery_id"`
+	AttemptedAt string  `json:"attempted_at" db:"attempted_at"`
+	StatusCode  *int    `json:"status_code,omitempty" db:"status_code"`
+	Error       *string `json:"error,omitempty" db:"error"`
+	DurationMs  int64   `json:"duration_ms" db:"duration_ms"`
+}
+
+// ReplayRequest selects dead-lettered deliveries to send again: the listed ones, all of a
+// subscription, or the listed ones of a subscription when both are given
+type ReplayRequest struct {
+	DeliveryIDs    []int64 `json:"delivery_ids"`
+	SubscriptionID string  `json:"subscription_id"`
+}
+
+// Payload is the JSON body posted for an event. Exactly one of Observation, Bundle and User is
+// set, matching the event. Observation data has the subscription's field filter and masks applied.
+type Payload struct {
+	Event          string            `json:"event"`
+	SubscriptionID string            `json:"subscription_id"`
+	OccurredAt     string            `json:"occurred_at"`
+	Observation    *sync.Observation `json:"observation,omitempty"`
+	Bundle         *BundleEvent      `json:"bundle,omitempty"`
+	User           *UserEvent        `json:"user,omitempty"`
+}
+
+// BundleEvent describes the app bundle version of a bundle.activated event
+type BundleEvent struct {
+	Version     string `json:"version"`
+	ActivatedBy string `json:"activated_by"`
+}
+
+// UserEvent describes the user of a user.created event. CreatedBy is the admin who created the
+// user or sent the accepted invitation.
+type UserEvent struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	CreatedBy string `json:"created_by"`
+}
+
+// Config contains the dispatcher settings; zero values select the defaults
+type Config struct {
+	PollInterval time.Duration
+	MaxAttempts  int
+	Timeout      time.Duration
+	// Outbound restricts the receivers subscriptions can point at
+	Outbound outbound.Policy
+}
+
+// Service manages webhook subscriptions and delivers queued events to them
+type Service interface {
+	// PushListener queues a delivery per matching subscription within the push transaction
+	sync.PushListener
+
+	// Notify queues a bundle.activated or user.created delivery for every subscription selecting
+	// the event. Observation events are queued by the PushListener instead.
+	Notify(ctx context.Context, payload Payload) error
+
+	// Create validates and stores a subscription, generating its secret when none is given
+	Create(ctx context.Context, sub Subscription) (*Subscription, error)
+
+	// List returns all subscriptions without their secrets, oldest first
+	List(ctx context.Context) ([]Subscription, error)
+
+	// Delete removes a subscription together with its queued deliveries
+	Delete(ctx context.Context, id string) error
+
+	// ListDeliveries returns the most recent deliveries of a subscription, newest first
+	ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error)
+
+	// ListAttempts returns the attempts at sending a delivery of a subscription, oldest first
+	ListAttempts(ctx context.Context, subscriptionID string, deliveryID int64) ([]Attempt, error)
+
+	// ListDeadLetters returns failed deliveries, newest first, optionally of one subscription
+	ListDeadLetters(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error)
+
+	// Replay queues the selected failed deliveries again with a fresh set of attempts and
+	// returns how many were queued
+	Replay(ctx context.Context, req ReplayRequest) (int, error)
+
+	// Run delivers queued events until ctx is cancelled
+	Run(ctx context.Context)
+}
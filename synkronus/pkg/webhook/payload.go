@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// validate checks a subscription before it is stored
+func validate(sub Subscription) error {
+	if strings.TrimSpace(sub.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSubscription)
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	for _, event := range sub.Events {
+		if !contains(Events, event) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidSubscription, event)
+		}
+	}
+	for _, field := range sub.Fields {
+		if !validPath(field) {
+			return fmt.Errorf("%w: invalid field %q", ErrInvalidSubscription, field)
+		}
+	}
+	for _, mask := range sub.Masks {
+		if !validPath(mask.Field) {
+			return fmt.Errorf("%w: invalid mask field %q", ErrInvalidSubscription, mask.Field)
+		}
+		if mask.Method != MaskRedact && mask.Method != MaskHash {
+			return fmt.Errorf("%w: mask method must be %q or %q", ErrInvalidSubscription, MaskRedact, MaskHash)
+		}
+	}
+	return nil
+}
+
+// checkNotification checks that a payload passed to Notify carries the subject of its event
+func checkNotification(payload Payload) error {
+	switch payload.Event {
+	case EventBundleActivated:
+		if payload.Bundle == nil || payload.Bundle.Version == "" {
+			return fmt.Errorf("%w: %s requires a bundle version", ErrInvalidEvent, payload.Event)
+		}
+	case EventUserCreated:
+		if payload.User == nil || payload.User.Username == "" {
+			return fmt.Errorf("%w: %s requires a username", ErrInvalidEvent, payload.Event)
+		}
+	default:
+		return fmt.Errorf("%w: %q cannot be notified", ErrInvalidEvent, payload.Event)
+	}
+	return nil
+}
+
+// validPath reports whether path is a dotted path without empty segments
+func validPath(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRules returns data reduced to the subscription's fields with its masks applied. Data that
+// is not a JSON object is passed through unless fields are selected, which then yields an empty object.
+func applyRules(data json.RawMessage, sub Subscription) (json.RawMessage, error) {
+	if len(sub.Fields) == 0 && len(sub.Masks) == 0 {
+		return data, nil
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		if len(sub.Fields) == 0 {
+			return data, nil
+		}
+		doc = map[string]any{}
+	}
+
+	if len(sub.Fields) > 0 {
+		filtered := map[string]any{}
+		for _, field := range sub.Fields {
+			if value, ok := lookup(doc, field); ok {
+				set(filtered, field, value)
+			}
+		}
+		doc = filtered
+	}
+
+	for _, mask := range sub.Masks {
+		value, ok := lookup(doc, mask.Field)
+		if !ok {
+			continue
+		}
+		switch mask.Method {
+		case MaskRedact:
+			set(doc, mask.Field, nil)
+		case MaskHash:
+			set(doc, mask.Field, hashValue(sub.Secret, value))
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+// lookup returns the value at a dotted path
+func lookup(doc map[string]any, path string) (any, bool) {
+	segments := strings.Split(path, ".")
+	current := doc
+	for i, segment := range segments {
+		value, ok := current[segment]
+		if !ok {
+			return nil, false
+		}
+		if i == len(segments)-1 {
+			return value, true
+		}
+		if current, ok = value.(map[string]any); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// set stores value at a dotted path, creating intermediate objects
+func set(doc map[string]any, path string, value any) {
+	segments := strings.Split(path, ".")
+	current := doc
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := current[segment].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			current[segment] = next
+		}
+		current = next
+	}
+	current[segments[len(segments)-1]] = value
+}
+
+// hashValue returns the keyed hash of a masked value. Strings are hashed as is, other values
+// as their JSON encoding.
+func hashValue(secret string, value any) string {
+	var input []byte
+	if s, ok := value.(string); ok {
+		input = []byte(s)
+	} else {
+		input, _ = json.Marshal(value)
+	}
+	return "hmac-sha256:" + sign(secret, input)
+}
+
+// sign returns the hex HMAC-SHA256 of body keyed with secret
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
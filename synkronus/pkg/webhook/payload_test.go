@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Subscription{Name: "referrals", URL: "https://referrals.example.org/hook"}
+	assert.NoError(t, validate(valid))
+
+	for name, sub := range map[string]Subscription{
+		"no name":        {URL: valid.URL},
+		"relative url":   {Name: "x", URL: "/hook"},
+		"ftp url":        {Name: "x", URL: "ftp://example.org/hook"},
+		"empty segment":  {Name: "x", URL: valid.URL, Fields: []string{"patient..name"}},
+		"bad mask field": {Name: "x", URL: valid.URL, Masks: []MaskRule{{Field: "", Method: MaskRedact}}},
+		"bad method":     {Name: "x", URL: valid.URL, Masks: []MaskRule{{Field: "phone", Method: "rot13"}}},
+		"unknown event":  {Name: "x", URL: valid.URL, Events: []string{"bundle.deleted"}},
+	} {
+		assert.ErrorIs(t, validate(sub), ErrInvalidSubscription, name)
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	data := json.RawMessage(`{"patient":{"name":"Amina","phone":"555-0101","age":34},"danger_signs":["fever"],"notes":"private"}`)
+
+	// Without rules the data is delivered unchanged
+	out, err := applyRules(data, Subscription{})
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(out))
+
+	sub := Subscription{
+		Secret: "s3cret",
+		Fields: []string{"patient.name", "patient.phone", "danger_signs", "missing.field"},
+		Masks: []MaskRule{
+			{Field: "patient.name", Method: MaskRedact},
+			{Field: "patient.phone", Method: MaskHash},
+		},
+	}
+	out, err = applyRules(data, sub)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"patient":{"name":null,"phone":"hmac-sha256:`+sign("s3cret", []byte("555-0101"))+`"},"danger_signs":["fever"]}`, string(out))
+
+	// Hashes are stable per secret so receivers can link records, and differ between subscriptions
+	assert.Equal(t, hashValue("s3cret", "555-0101"), hashValue("s3cret", "555-0101"))
+	assert.NotEqual(t, hashValue("s3cret", "555-0101"), hashValue("other", "555-0101"))
+
+	// Masks apply to all data when no fields are selected
+	out, err = applyRules(data, Subscription{Masks: []MaskRule{{Field: "notes", Method: MaskRedact}}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"patient":{"name":"Amina","phone":"555-0101","age":34},"danger_signs":["fever"],"notes":null}`, string(out))
+}
+
+func TestSenderSignsPayload(t *testing.T) {
+	payload := []byte(`{"event":"observation.pushed"}`)
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	s := newSender(&http.Client{Timeout: time.Second})
+	status, err := s.send(t.Context(), receiver.URL, "s3cret", EventObservationPushed, 42, payload)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, payload, body)
+	assert.Equal(t, "sha256="+sign("s3cret", payload), got.Header.Get(SignatureHeader))
+	assert.Equal(t, EventObservationPushed, got.Header.Get(EventHeader))
+	assert.Equal(t, "42", got.Header.Get(DeliveryHeader))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	status, err = s.send(t.Context(), failing.URL, "s3cret", EventObservationPushed, 43, payload)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, backoff(1))
+	assert.Equal(t, 20*time.Second, backoff(2))
+	assert.Equal(t, 80*time.Second, backoff(4))
+	assert.Equal(t, time.Hour, backoff(20))
+}
+
+func TestCheckNotification(t *testing.T) {
+	assert.NoError(t, checkNotification(Payload{Event: EventBundleActivated, Bundle: &BundleEvent{Version: "0007"}}))
+	assert.NoError(t, checkNotification(Payload{Event: EventUserCreated, User: &UserEvent{Username: "amina"}}))
+
+	for name, payload := range map[string]Payload{
+		"observation event": {Event: EventObservationPushed},
+		"unknown event":     {Event: "bundle.deleted"},
+		"no bundle":         {Event: EventBundleActivated},
+		"no username":       {Event: EventUserCreated, User: &UserEvent{Role: "read-only"}},
+	} {
+		assert.ErrorIs(t, checkNotification(payload), ErrInvalidEvent, name)
+	}
+}
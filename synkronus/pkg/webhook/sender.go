@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Delivery request headers
+const (
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the body keyed with the
+	// subscription secret
+	SignatureHeader = "X-Synkronus-Signature"
+	// EventHeader names the event, e.g. observation.pushed
+	EventHeader = "X-Synkronus-Event"
+	// DeliveryHeader carries the delivery ID, which stays the same across retries
+	DeliveryHeader = "X-Synkronus-Delivery"
+)
+
+// sender posts signed payloads to receivers
+type sender struct {
+	client *http.Client
+}
+
+func newSender(client *http.Client) *sender {
+	return &sender{client: client}
+}
+
+// send posts payload to url and fails unless the receiver answers with a 2xx status. It returns
+// the response status, or zero when the receiver could not be reached.
+func (s *sender) send(ctx context.Context, url, secret, event string, deliveryID int64, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+sign(secret, payload))
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(deliveryID, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,561 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/opendataensemble/synkronus/pkg/logger"
+	"github.com/opendataensemble/synkronus/pkg/outbound"
+	"github.com/opendataensemble/synkronus/pkg/sync"
+)
+
+const (
+	// secretBytes is the number of random bytes in a generated secret
+	secretBytes = 32
+	// batchSize is the number of deliveries claimed per poll
+	batchSize = 100
+	// claimLease keeps claimed deliveries from other dispatchers while they are being sent
+	claimLease = 5 * time.Minute
+	// retryBase and retryMax bound the exponential backoff between attempts
+	retryBase = 10 * time.Second
+	retryMax  = time.Hour
+	// defaultDeliveryLimit is the number of deliveries listed when no limit is given
+	defaultDeliveryLimit = 100
+)
+
+type service struct {
+	db     *sql.DB
+	sender *sender
+	config Config
+	log    *logger.Logger
+}
+
+// NewService creates a webhook service; register it with sync.WithPushListener and start Run to deliver
+func NewService(db *sql.DB, config Config, log *logger.Logger) Service {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	client := outbound.NewClient(config.Outbound, config.Timeout)
+	return &service{db: db, sender: newSender(client), config: config, log: log}
+}
+
+// Create validates and stores a subscription, generating its secret when none is given
+func (s *service) Create(ctx context.Context, sub Subscription) (*Subscription, error) {
+	if err := validate(sub); err != nil {
+		return nil, err
+	}
+	if err := s.config.Outbound.CheckURL(ctx, sub.URL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	if sub.Secret == "" {
+		raw := make([]byte, secretBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		sub.Secret = hex.EncodeToString(raw)
+	}
+	if len(sub.Events) == 0 {
+		sub.Events = []string{EventObservationPushed}
+	}
+	if sub.FormTypes == nil {
+		sub.FormTypes = []string{}
+	}
+	if sub.Fields == nil {
+		sub.Fields = []string{}
+	}
+	if sub.Masks == nil {
+		sub.Masks = []MaskRule{}
+	}
+	masks, err := json.Marshal(sub.Masks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode masks: %w", err)
+	}
+
+	sub.ID = uuid.New().String()
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, name, url, secret, events, form_types, fields, masks, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at`,
+		sub.ID, sub.Name, sub.URL, sub.Secret, pq.Array(sub.Events), pq.Array(sub.FormTypes), pq.Array(sub.Fields), masks, sub.CreatedBy,
+	).Scan(&sub.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	s.log.Info("Webhook subscription created", "id", sub.ID, "name", sub.Name, "createdBy", sub.CreatedBy)
+	return &sub, nil
+}
+
+// List returns all subscriptions without their secrets, oldest first
+func (s *service) List(ctx context.Context) ([]Subscription, error) {
+	subs, err := s.subscriptions(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// subscriptions loads all subscriptions including their secrets
+func (s *service) subscriptions(ctx context.Context, q queryer) ([]Subscription, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, name, url, secret, events, form_types, fields, masks, created_by, created_at
+		FROM webhook_subscriptions
+		ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		var sub Subscription
+		var events, formTypes, fields pq.StringArray
+		var masks []byte
+		if err := rows.Scan(&sub.ID, &sub.Name, &sub.URL, &sub.Secret, &events, &formTypes, &fields, &masks, &sub.CreatedBy, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		sub.Events = events
+		sub.FormTypes = formTypes
+		sub.Fields = fields
+		if err := json.Unmarshal(masks, &sub.Masks); err != nil {
+			return nil, fmt.Errorf("failed to decode masks of webhook subscription %s: %w", sub.ID, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Delete removes a subscription together with its queued deliveries
+func (s *service) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrSubscriptionNotFound
+	}
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	s.log.Info("Webhook subscription deleted", "id", id)
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription, newest first
+func (s *service) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)", subscriptionID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	if !exists {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	return s.deliveries(ctx, "WHERE subscription_id = $1", limit, subscriptionID)
+}
+
+// deliveries lists the deliveries matching where, newest first. where refers to args as $1..$n.
+func (s *service) deliveries(ctx context.Context, where string, limit int, args ...any) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, subscription_id, event, observation_id, status, attempts, last_error, created_at, next_attempt_at, delivered_at
+		FROM webhook_deliveries
+		`+where+`
+		ORDER BY id DESC
+		LIMIT $`+strconv.Itoa(len(args)+1), append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.ObservationID, &d.Status, &d.Attempts,
+			&d.LastError, &d.CreatedAt, &d.NextAttemptAt, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ListAttempts returns the attempts at sending a delivery of a subscription, oldest first
+func (s *service) ListAttempts(ctx context.Context, subscriptionID string, deliveryID int64) ([]Attempt, error) {
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2)",
+		deliveryID, subscriptionID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if !exists {
+		return nil, ErrDeliveryNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, delivery_id, attempted_at, status_code, error, duration_ms
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id`, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []Attempt{}
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// ListDeadLetters returns failed deliveries, newest first, optionally of one subscription
+func (s *service) ListDeadLetters(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	if subscriptionID == "" {
+		return s.deliveries(ctx, "WHERE status = 'failed'", limit)
+	}
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return s.deliveries(ctx, "WHERE status = 'failed' AND subscription_id = $1", limit, subscriptionID)
+}
+
+// Replay queues the selected failed deliveries again with a fresh set of attempts. Their
+// attempt history is kept.
+func (s *service) Replay(ctx context.Context, req ReplayRequest) (int, error) {
+	if len(req.DeliveryIDs) == 0 && req.SubscriptionID == "" {
+		return 0, fmt.Errorf("%w: select delivery IDs or a subscription", ErrInvalidReplay)
+	}
+	var subscriptionID *string
+	if req.SubscriptionID != "" {
+		if _, err := uuid.Parse(req.SubscriptionID); err != nil {
+			return 0, ErrSubscriptionNotFound
+		}
+		subscriptionID = &req.SubscriptionID
+	}
+	var deliveryIDs any
+	if len(req.DeliveryIDs) > 0 {
+		deliveryIDs = pq.Array(req.DeliveryIDs)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, last_error = NULL, next_attempt_at = NOW()
+		WHERE status = 'failed'
+		  AND ($1::BIGINT[] IS NULL OR id = ANY($1))
+		  AND ($2::UUID IS NULL OR subscription_id = $2)`, deliveryIDs, subscriptionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay webhook deliveries: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay webhook deliveries: %w", err)
+	}
+
+	s.log.Info("Webhook deliveries replayed", "count", n, "subscriptionId", req.SubscriptionID, "deliveryIds", req.DeliveryIDs)
+	return int(n), nil
+}
+
+// ObservationsPushed queues an observation.pushed delivery for every finalized record and every
+// subscription selecting its form type. Payloads are filtered and masked here, so unmasked data
+// is never stored in the delivery queue.
+func (s *service) ObservationsPushed(ctx context.Context, tx *sql.Tx, records []sync.Observation) error {
+	subs, err := s.subscriptions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	occurredAt := time.Now().UTC().Format(time.RFC3339)
+	for _, record := range records {
+		if record.Draft {
+			continue
+		}
+		for _, sub := range subs {
+			if !contains(sub.Events, EventObservationPushed) {
+				continue
+			}
+			if len(sub.FormTypes) > 0 && !contains(sub.FormTypes, record.FormType) {
+				continue
+			}
+
+			observation := record
+			if observation.Data, err = applyRules(record.Data, sub); err != nil {
+				return fmt.Errorf("failed to apply rules of webhook subscription %s: %w", sub.ID, err)
+			}
+			payload, err := json.Marshal(Payload{
+				Event:          EventObservationPushed,
+				SubscriptionID: sub.ID,
+				OccurredAt:     occurredAt,
+				Observation:    &observation,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to encode webhook payload: %w", err)
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO webhook_deliveries (subscription_id, event, observation_id, payload)
+				VALUES ($1, $2, $3, $4)`,
+				sub.ID, EventObservationPushed, record.ObservationID, payload); err != nil {
+				return fmt.Errorf("failed to queue webhook delivery: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Notify queues a bundle.activated or user.created delivery for every subscription selecting the
+// event. The deliveries are sent by Run like observation events, with the same retries.
+func (s *service) Notify(ctx context.Context, payload Payload) error {
+	if err := checkNotification(payload); err != nil {
+		return err
+	}
+	subs, err := s.subscriptions(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	payload.OccurredAt = time.Now().UTC().Format(time.RFC3339)
+	queued := 0
+	for _, sub := range subs {
+		if !contains(sub.Events, payload.Event) {
+			continue
+		}
+		payload.SubscriptionID = sub.ID
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook payload: %w", err)
+		}
+		if _, err := s.db.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (subscription_id, event, payload)
+			VALUES ($1, $2, $3)`,
+			sub.ID, payload.Event, body); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+		queued++
+	}
+	if queued > 0 {
+		s.log.Info("Webhook event queued", "event", payload.Event, "deliveries", queued)
+	}
+	return nil
+}
+
+// Run delivers queued events immediately and then every poll interval until ctx is cancelled
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.dispatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.log.Warn("Failed to dispatch webhook deliveries", "error", err)
+			}
+			// Keep going while full batches are waiting
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimed is a delivery claimed for sending
+type claimed struct {
+	id       int64
+	event    string
+	payload  []byte
+	attempts int
+	url      string
+	secret   string
+}
+
+// dispatch claims a batch of due deliveries, sends them and records the outcome
+func (s *service) dispatch(ctx context.Context) (int, error) {
+	batch, err := s.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range batch {
+		started := time.Now()
+		statusCode, sendErr := s.sender.send(ctx, d.url, d.secret, d.event, d.id, d.payload)
+		if ctx.Err() != nil {
+			// Shutting down; the lease expires and the delivery is retried after restart
+			return len(batch), ctx.Err()
+		}
+		if err := s.record(ctx, d, statusCode, time.Since(started), sendErr); err != nil {
+			return len(batch), err
+		}
+	}
+	return len(batch), nil
+}
+
+// claim locks due deliveries and pushes their next attempt past the lease, so other dispatchers
+// skip them while they are sent
+func (s *service) claim(ctx context.Context) ([]claimed, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.id, d.event, d.payload, d.attempts, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+		ORDER BY d.id
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED`, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	var batch []claimed
+	var ids []int64
+	for rows.Next() {
+		var d claimed
+		if err := rows.Scan(&d.id, &d.event, &d.payload, &d.attempts, &d.url, &d.secret); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		batch = append(batch, d)
+		ids = append(ids, d.id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id = ANY($1)`, pq.Array(ids), claimLease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return batch, nil
+}
+
+// record stores an attempt and its outcome, scheduling a retry with exponential backoff until
+// the maximum number of attempts is reached and the delivery is dead-lettered
+func (s *service) record(ctx context.Context, d claimed, statusCode int, duration time.Duration, sendErr error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	var lastError *string
+	if sendErr != nil {
+		message := sendErr.Error()
+		lastError = &message
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4)`, d.id, code, lastError, duration.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	attempts := d.attempts + 1
+	switch {
+	case sendErr == nil:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', attempts = $2, last_error = NULL, next_attempt_at = NULL, delivered_at = NOW()
+			WHERE id = $1`, d.id, attempts)
+	case attempts >= s.config.MaxAttempts:
+		s.log.Warn("Webhook delivery dead-lettered", "deliveryId", d.id, "attempts", attempts, "error", sendErr)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'failed', attempts = $2, last_error = $3, next_attempt_at = NULL
+			WHERE id = $1`, d.id, attempts, lastError)
+	default:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET attempts = $2, last_error = $3, next_attempt_at = NOW() + $4 * INTERVAL '1 second'
+			WHERE id = $1`, d.id, attempts, lastError, backoff(attempts).Seconds())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// backoff returns the delay before the attempt following the given number of attempts
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMax {
+			return retryMax
+		}
+	}
+	return delay
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
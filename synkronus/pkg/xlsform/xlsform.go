@@ -0,0 +1,564 @@
+// Package xlsform converts ODK XLSForms into the schema.json and ui.json of an app bundle form.
+// Questions, choices and groups are converted; XPath logic and question types the formplayer has
+// no equivalent for are listed as issues instead, so form designers know what to rebuild by hand.
+package xlsform
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidForm is returned when a file is not an XLSForm
+var ErrInvalidForm = errors.New("invalid XLSForm")
+
+// Issue is a part of an XLSForm that was not converted
+type Issue struct {
+	Sheet string `json:"sheet"`
+	// Row is the row of the sheet, counting the header as row 1
+	Row     int    `json:"row"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// Form is an XLSForm converted to an app bundle form
+type Form struct {
+	// FormType is the form_id setting, or else the file name without its extension
+	FormType string `json:"form_type"`
+	Title    string `json:"title,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// Schema and UI are the contents of the form's schema.json and ui.json
+	Schema map[string]any `json:"schema"`
+	UI     map[string]any `json:"ui"`
+	// Unsupported lists what was not converted, in sheet order
+	Unsupported []Issue `json:"unsupported"`
+}
+
+// questionTypes are the schemas of the XLSForm question types with a formplayer equivalent
+var questionTypes = map[string]map[string]any{
+	"text":        {"type": "string"},
+	"integer":     {"type": "integer"},
+	"int":         {"type": "integer"},
+	"decimal":     {"type": "number"},
+	"date":        {"type": "string", "format": "date"},
+	"time":        {"type": "string", "format": "time"},
+	"datetime":    {"type": "string", "format": "date-time"},
+	"acknowledge": {"type": "boolean"},
+	"trigger":     {"type": "boolean"},
+	"image":       {"type": "object", "format": "photo"},
+	"photo":       {"type": "object", "format": "photo"},
+	"audio":       {"type": "object", "format": "audio"},
+	"video":       {"type": "object", "format": "video"},
+	"file":        {"type": "object", "format": "select_file"},
+	"barcode":     {"type": "string", "format": "qrcode"},
+}
+
+// metadataTypes are XLSForm types recording facts about the submission rather than asking a question
+var metadataTypes = map[string]bool{
+	"start": true, "end": true, "today": true, "deviceid": true, "username": true, "email": true,
+	"phonenumber": true, "simserial": true, "subscriberid": true, "audit": true,
+	"start-geopoint": true, "background-geopoint": true,
+}
+
+// logicColumns are survey columns holding XPath logic, which the formplayer has no equivalent for
+var logicColumns = []struct{ column, description string }{
+	{"relevant", "relevant condition"},
+	{"constraint", "constraint"},
+	{"calculation", "calculation"},
+	{"choice_filter", "choice filter"},
+	{"repeat_count", "repeat count"},
+	{"trigger", "trigger"},
+}
+
+// Convert converts the XLSX file of an XLSForm. The file name is the form type when the settings
+// sheet has no form_id.
+func Convert(filename string, data []byte) (*Form, error) {
+	sheets, err := readWorkbook(data)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := sheets["survey"]
+	if !ok {
+		return nil, fmt.Errorf("%w: the workbook has no survey sheet", ErrInvalidForm)
+	}
+	survey := newSheet("survey", rows)
+	if !survey.has("type") || !survey.has("name") {
+		return nil, fmt.Errorf("%w: the survey sheet needs type and name columns", ErrInvalidForm)
+	}
+	if len(survey.rows) == 0 {
+		return nil, fmt.Errorf("%w: the survey sheet has no questions", ErrInvalidForm)
+	}
+
+	c := &converter{form: &Form{
+		FormType:    strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)),
+		Unsupported: []Issue{},
+	}}
+	c.settings(newSheet("settings", sheets["settings"]))
+	if c.form.FormType == "" {
+		c.form.FormType = "form"
+	}
+	c.pickLanguage(survey)
+	c.readChoices(newSheet("choices", sheets["choices"]))
+	c.survey(survey)
+	return c.form, nil
+}
+
+// sheet is a sheet of the XLSForm with a header row
+type sheet struct {
+	name string
+	// columns holds the index of each lower-case header; headers keeps them as written
+	columns map[string]int
+	headers []string
+	rows    [][]string
+}
+
+func newSheet(name string, rows [][]string) *sheet {
+	s := &sheet{name: name, columns: make(map[string]int)}
+	if len(rows) == 0 {
+		return s
+	}
+	for i, header := range rows[0] {
+		header = strings.TrimSpace(header)
+		s.headers = append(s.headers, header)
+		if key := strings.ToLower(header); key != "" {
+			if _, ok := s.columns[key]; !ok {
+				s.columns[key] = i
+			}
+		}
+	}
+	s.rows = rows[1:]
+	return s
+}
+
+func (s *sheet) has(column string) bool {
+	_, ok := s.columns[column]
+	return ok
+}
+
+// cell returns the trimmed text of a column of a row, empty when the column does not exist
+func (s *sheet) cell(row []string, column string) string {
+	i, ok := s.columns[column]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// converter builds a Form, collecting issues as it goes
+type converter struct {
+	form *Form
+	// language is the lower-case language of the label and hint columns read; empty for the
+	// plain label and hint columns
+	language        string
+	defaultLanguage string
+	choices         map[string][]any
+}
+
+func (c *converter) issue(sheet string, row int, name, format string, args ...any) {
+	c.form.Unsupported = append(c.form.Unsupported, Issue{Sheet: sheet, Row: row, Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// settings reads the form ID, title and version from the settings sheet
+func (c *converter) settings(s *sheet) {
+	if len(s.rows) == 0 {
+		return
+	}
+	row := s.rows[0]
+	for _, header := range s.headers {
+		column := strings.ToLower(header)
+		value := s.cell(row, column)
+		if value == "" {
+			continue
+		}
+		switch column {
+		case "form_id", "id_string":
+			c.form.FormType = value
+		case "form_title":
+			c.form.Title = value
+		case "version":
+			c.form.Version = value
+		case "default_language":
+			c.defaultLanguage = value
+		default:
+			c.issue(s.name, 2, "", "setting %q is not converted", header)
+		}
+	}
+}
+
+// pickLanguage picks the label language: the default language, else the labels without a
+// language, else the first language of the survey sheet
+func (c *converter) pickLanguage(survey *sheet) {
+	var languages []string
+	for _, header := range survey.headers {
+		if strings.HasPrefix(strings.ToLower(header), "label::") {
+			languages = append(languages, header[len("label::"):])
+		}
+	}
+	if len(languages) == 0 {
+		return
+	}
+
+	chosen := ""
+	switch {
+	case c.defaultLanguage != "" && survey.has("label::"+strings.ToLower(c.defaultLanguage)):
+		chosen = c.defaultLanguage
+	case survey.has("label"):
+	default:
+		chosen = languages[0]
+	}
+	c.language = strings.ToLower(chosen)
+
+	var others []string
+	for _, language := range languages {
+		if strings.ToLower(language) != c.language {
+			others = append(others, language)
+		}
+	}
+	if len(others) > 0 {
+		shown := chosen
+		if shown == "" {
+			shown = "default"
+		}
+		c.issue(survey.name, 1, "", "only the %s labels are converted; the form also has %s", shown, strings.Join(others, ", "))
+	}
+}
+
+// text returns the label or hint of a row in the chosen language
+func (c *converter) text(s *sheet, row []string, kind string) string {
+	if c.language != "" && s.has(kind+"::"+c.language) {
+		return s.cell(row, kind+"::"+c.language)
+	}
+	return s.cell(row, kind)
+}
+
+// readChoices reads the choice lists as oneOf entries
+func (c *converter) readChoices(s *sheet) {
+	c.choices = make(map[string][]any)
+	listColumn := "list_name"
+	if !s.has(listColumn) {
+		listColumn = "list name"
+	}
+	seen := make(map[string]map[string]bool)
+	for i, row := range s.rows {
+		list := s.cell(row, listColumn)
+		if list == "" {
+			continue
+		}
+		name := s.cell(row, "name")
+		if name == "" {
+			c.issue(s.name, i+2, "", "choice of list %q has no name", list)
+			continue
+		}
+		if seen[list] == nil {
+			seen[list] = make(map[string]bool)
+		}
+		if seen[list][name] {
+			c.issue(s.name, i+2, name, "choice %q appears more than once in list %q", name, list)
+			continue
+		}
+		seen[list][name] = true
+
+		choice := map[string]any{"const": name}
+		if label := c.text(s, row, "label"); label != "" {
+			choice["title"] = label
+		}
+		c.choices[list] = append(c.choices[list], choice)
+	}
+}
+
+// frame is a group or repeat being converted; the survey itself is the outermost frame
+type frame struct {
+	kind string
+	name string
+	row  int
+	// object is the schema object questions are added to: the form's, or the items of a repeat
+	object   map[string]any
+	elements []any
+	// close adds the finished frame to its parent
+	close func(elements []any)
+}
+
+// survey converts the questions, groups and repeats of the survey sheet
+func (c *converter) survey(s *sheet) {
+	root := map[string]any{"type": "object", "properties": map[string]any{}}
+	if c.form.Title != "" {
+		root["title"] = c.form.Title
+	}
+	c.form.Schema = root
+	stack := []*frame{{object: root, close: func(elements []any) {
+		// Each question, group and repeat at the top of the survey is a page, as ODK Collect
+		// shows them one screen at a time
+		c.form.UI = map[string]any{"type": "SwipeLayout", "elements": elements}
+	}}}
+
+	for i, row := range s.rows {
+		line := i + 2
+		words := strings.Fields(s.cell(row, "type"))
+		name := s.cell(row, "name")
+		if len(words) == 0 {
+			if name != "" {
+				c.issue(s.name, line, name, "question has no type")
+			}
+			continue
+		}
+		base := strings.ToLower(words[0])
+		// "begin group" may also be written "begin_group"
+		if (base == "begin" || base == "end") && len(words) > 1 {
+			base += "_" + strings.ToLower(words[1])
+			words = words[1:]
+		}
+		top := stack[len(stack)-1]
+
+		switch base {
+		case "end_group", "end_repeat":
+			kind := strings.TrimPrefix(base, "end_")
+			if top.kind != kind {
+				c.issue(s.name, line, name, "end %s has no matching begin %s", kind, kind)
+				continue
+			}
+			top.close(top.elements)
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if name == "" {
+			c.issue(s.name, line, "", "%s has no name", strings.Join(words, " "))
+			continue
+		}
+		if !c.supported(s, line, name, base) {
+			continue
+		}
+		c.logic(s, row, line, name)
+		label := c.text(s, row, "label")
+		if strings.Contains(label, "${") {
+			c.issue(s.name, line, name, "label refers to other answers, which are shown as written")
+		}
+
+		switch base {
+		case "begin_group":
+			c.appearance(s, row, line, name, base, nil)
+			layout := map[string]any{"type": "VerticalLayout"}
+			var elements []any
+			if label != "" {
+				elements = append(elements, map[string]any{"type": "Label", "text": label})
+			}
+			stack = append(stack, &frame{kind: "group", name: name, row: line, object: top.object, elements: elements, close: func(elements []any) {
+				layout["elements"] = elements
+				top.elements = append(top.elements, layout)
+			}})
+		case "begin_repeat":
+			items := map[string]any{"type": "object", "properties": map[string]any{}}
+			property := map[string]any{"type": "array", "items": items}
+			if label != "" {
+				property["title"] = label
+			}
+			if !c.add(s, line, top.object, name, property) {
+				// The repeat's questions still need a frame; they are dropped with it
+				stack = append(stack, &frame{kind: "repeat", name: name, row: line, object: items, close: func([]any) {}})
+				continue
+			}
+			c.required(s, row, line, name, top.object)
+			stack = append(stack, &frame{kind: "repeat", name: name, row: line, object: items, close: func(elements []any) {
+				top.elements = append(top.elements, map[string]any{
+					"type":    "Control",
+					"scope":   "#/properties/" + name,
+					"options": map[string]any{"detail": map[string]any{"type": "VerticalLayout", "elements": elements}},
+				})
+			}})
+		case "note":
+			if label != "" {
+				top.elements = append(top.elements, map[string]any{"type": "Label", "text": label})
+			}
+		default:
+			property, ok := c.property(words, base, s, line, name)
+			if !ok {
+				continue
+			}
+			if label != "" {
+				property["title"] = label
+			}
+			if hint := c.text(s, row, "hint"); hint != "" {
+				property["description"] = hint
+			}
+			if text := s.cell(row, "default"); text != "" {
+				if value, ok := defaultValue(property, text); ok {
+					property["default"] = value
+				} else {
+					c.issue(s.name, line, name, "default %q is not converted", text)
+				}
+			}
+			if !c.add(s, line, top.object, name, property) {
+				continue
+			}
+			c.required(s, row, line, name, top.object)
+
+			control := map[string]any{"type": "Control", "scope": "#/properties/" + name}
+			options := map[string]any{}
+			c.appearance(s, row, line, name, base, func(appearance string) bool {
+				switch {
+				case appearance == "multiline" && base == "text":
+					options["multi"] = true
+				case appearance == "signature" && (base == "image" || base == "photo"):
+					property["format"] = "signature"
+				default:
+					return false
+				}
+				return true
+			})
+			if yes(s.cell(row, "read_only")) {
+				options["readonly"] = true
+			}
+			if len(options) > 0 {
+				control["options"] = options
+			}
+			top.elements = append(top.elements, control)
+		}
+	}
+
+	for len(stack) > 1 {
+		top := stack[len(stack)-1]
+		c.issue(s.name, top.row, top.name, "%s %q is not closed", top.kind, top.name)
+		top.close(top.elements)
+		stack = stack[:len(stack)-1]
+	}
+	stack[0].close(stack[0].elements)
+}
+
+// supported reports whether a question type can be converted, noting an issue when it cannot
+func (c *converter) supported(s *sheet, line int, name, base string) bool {
+	switch {
+	case base == "begin_group" || base == "begin_repeat" || base == "note":
+	case base == "select_one" || base == "select_multiple":
+	case questionTypes[base] != nil:
+	case metadataTypes[base]:
+		c.issue(s.name, line, name, "%s metadata is not converted", base)
+		return false
+	case base == "calculate":
+		c.issue(s.name, line, name, "calculations are not converted")
+		return false
+	case base == "geopoint":
+		c.issue(s.name, line, name, "locations are not converted; the app records the location of each observation")
+		return false
+	default:
+		c.issue(s.name, line, name, "question type %q is not supported", base)
+		return false
+	}
+	return true
+}
+
+// property returns the schema of a question
+func (c *converter) property(words []string, base string, s *sheet, line int, name string) (map[string]any, bool) {
+	if base != "select_one" && base != "select_multiple" {
+		return maps.Clone(questionTypes[base]), true
+	}
+
+	if len(words) < 2 {
+		c.issue(s.name, line, name, "%s has no choice list", base)
+		return nil, false
+	}
+	list := words[1]
+	if len(words) > 2 && strings.ToLower(words[2]) == "or_other" {
+		c.issue(s.name, line, name, "or_other is not converted; add an other choice to list %q", list)
+	}
+	choices := map[string]any{"type": "string"}
+	if options, ok := c.choices[list]; ok {
+		choices["oneOf"] = options
+	} else {
+		c.issue(s.name, line, name, "choice list %q is not in the choices sheet", list)
+	}
+	if base == "select_one" {
+		return choices, true
+	}
+	return map[string]any{"type": "array", "uniqueItems": true, "items": choices}, true
+}
+
+// add adds a property to a schema object, noting an issue when the name is taken
+func (c *converter) add(s *sheet, line int, object map[string]any, name string, property map[string]any) bool {
+	properties := object["properties"].(map[string]any)
+	if _, ok := properties[name]; ok {
+		c.issue(s.name, line, name, "name %q is used more than once; only the first question is converted", name)
+		return false
+	}
+	properties[name] = property
+	return true
+}
+
+// required marks a question required when its required column is yes; conditions are noted
+func (c *converter) required(s *sheet, row []string, line int, name string, object map[string]any) {
+	value := s.cell(row, "required")
+	switch {
+	case yes(value):
+		required, _ := object["required"].([]any)
+		object["required"] = append(required, name)
+	case value != "" && !no(value):
+		c.issue(s.name, line, name, "required condition %q is not converted; the question is optional", value)
+	}
+}
+
+// logic notes the XPath logic of a row
+func (c *converter) logic(s *sheet, row []string, line int, name string) {
+	for _, logic := range logicColumns {
+		if value := s.cell(row, logic.column); value != "" {
+			c.issue(s.name, line, name, "%s %q is not converted", logic.description, value)
+		}
+	}
+}
+
+// appearance notes the appearances of a row that apply does not convert. Groups are always
+// shown as a list of fields, so field-list needs no conversion.
+func (c *converter) appearance(s *sheet, row []string, line int, name, base string, apply func(string) bool) {
+	for _, appearance := range strings.Fields(strings.ToLower(s.cell(row, "appearance"))) {
+		if appearance == "field-list" && base == "begin_group" {
+			continue
+		}
+		if apply == nil || !apply(appearance) {
+			c.issue(s.name, line, name, "appearance %q is not converted", appearance)
+		}
+	}
+}
+
+// defaultValue converts a literal default to the property's type; defaults computed from other
+// answers are not converted
+func defaultValue(property map[string]any, text string) (any, bool) {
+	if strings.Contains(text, "${") || strings.Contains(text, "(") {
+		return nil, false
+	}
+	switch property["type"] {
+	case "string":
+		return text, true
+	case "integer":
+		n, err := strconv.ParseInt(text, 10, 64)
+		return n, err == nil
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		return n, err == nil
+	case "array":
+		values := []any{}
+		for _, value := range strings.Fields(text) {
+			values = append(values, value)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// yes reports whether a cell holds an XLSForm yes
+func yes(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "true", "true()", "1":
+		return true
+	}
+	return false
+}
+
+// no reports whether a cell holds an XLSForm no
+func no(value string) bool {
+	switch strings.ToLower(value) {
+	case "no", "false", "false()", "0":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package xlsform
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSheet struct {
+	name string
+	rows [][]string
+}
+
+// buildWorkbook writes sheets to an XLSX file the way spreadsheet programs do: text in shared
+// strings, numbers as values
+func buildWorkbook(t *testing.T, sheets ...testSheet) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	write := func(name, content string) {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	var strs []string
+	var workbook, rels strings.Builder
+	for i, sheet := range sheets {
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, html.EscapeString(sheet.name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+
+		var data strings.Builder
+		for r, row := range sheet.rows {
+			fmt.Fprintf(&data, `<row r="%d">`, r+1)
+			for c, value := range row {
+				if value == "" {
+					continue
+				}
+				ref := fmt.Sprintf("%c%d", 'A'+c, r+1)
+				if _, err := fmt.Sscanf(value, "%d", new(int)); err == nil && !strings.ContainsAny(value, " .") {
+					fmt.Fprintf(&data, `<c r="%s"><v>%s</v></c>`, ref, value)
+					continue
+				}
+				fmt.Fprintf(&data, `<c r="%s" t="s"><v>%d</v></c>`, ref, len(strs))
+				strs = append(strs, value)
+			}
+			data.WriteString(`</row>`)
+		}
+		write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+data.String()+`</sheetData></worksheet>`)
+	}
+
+	var shared strings.Builder
+	for _, s := range strs {
+		fmt.Fprintf(&shared, `<si><t>%s</t></si>`, html.EscapeString(s))
+	}
+	write("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+workbook.String()+`</sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+	write("xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+shared.String()+`</sst>`)
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+var householdSurvey = testSheet{"survey", [][]string{
+	{"type", "name", "label::English (en)", "label::Français (fr)", "hint::English (en)", "required", "relevant", "appearance", "default"},
+	{"start", "start"},
+	{"text", "head_name", "Name of the household head", "Nom", "As on the ID card", "yes"},
+	{"integer", "members", "How many members?", "Combien?", "", "true", "", "", "1"},
+	{"select_one yes_no", "has_water", "Is there running water?", "Eau?", "", "yes"},
+	{"text", "water_source", "Where does the water come from?", "", "", "", "${has_water} = 'no'", "multiline"},
+	{"begin group", "household", "Household", "Ménage", "", "", "", "field-list"},
+	{"select_multiple assets", "assets", "Assets owned", "Biens", "", "", "", "", "radio tv"},
+	{"begin repeat", "member", "Member"},
+	{"text", "member_name", "Name"},
+	{"decimal", "weight", "Weight (kg)", "", "", "yes"},
+	{"end repeat"},
+	{"end group"},
+	{"note", "thanks", "Thank you, ${head_name}"},
+	{"calculate", "total", ""},
+	{"geopoint", "location", "Location"},
+	{"image", "signature", "Signature", "", "", "", "", "signature"},
+	{"range", "rating", "Rating"},
+}}
+
+var householdChoices = testSheet{"choices", [][]string{
+	{"list_name", "name", "label::English (en)", "label::Français (fr)"},
+	{"yes_no", "yes", "Yes", "Oui"},
+	{"yes_no", "no", "No", "Non"},
+	{"assets", "radio", "Radio"},
+	{"assets", "tv", "Television"},
+	{"assets", "tv", "TV"},
+}}
+
+var householdSettings = testSheet{"settings", [][]string{
+	{"form_title", "form_id", "version", "default_language", "instance_name"},
+	{"Household survey", "household", "2024031501", "English (en)", "concat(${head_name})"},
+}}
+
+func TestConvert(t *testing.T) {
+	form, err := Convert("Household Survey v3.xlsx", buildWorkbook(t, householdSurvey, householdChoices, householdSettings))
+	require.NoError(t, err)
+
+	assert.Equal(t, "household", form.FormType)
+	assert.Equal(t, "Household survey", form.Title)
+	assert.Equal(t, "2024031501", form.Version)
+
+	properties := form.Schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "title": "Name of the household head", "description": "As on the ID card"}, properties["head_name"])
+	assert.Equal(t, map[string]any{"type": "integer", "title": "How many members?", "default": int64(1)}, properties["members"])
+	assert.Equal(t, map[string]any{"type": "string", "title": "Is there running water?", "oneOf": []any{
+		map[string]any{"const": "yes", "title": "Yes"},
+		map[string]any{"const": "no", "title": "No"},
+	}}, properties["has_water"])
+	assert.Equal(t, map[string]any{"type": "array", "uniqueItems": true, "title": "Assets owned", "default": []any{"radio", "tv"}, "items": map[string]any{
+		"type": "string", "oneOf": []any{map[string]any{"const": "radio", "title": "Radio"}, map[string]any{"const": "tv", "title": "Television"}},
+	}}, properties["assets"])
+	assert.Equal(t, map[string]any{"type": "array", "title": "Member", "items": map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"member_name": map[string]any{"type": "string", "title": "Name"},
+			"weight":      map[string]any{"type": "number", "title": "Weight (kg)"},
+		},
+		"required": []any{"weight"},
+	}}, properties["member"])
+	assert.Equal(t, map[string]any{"type": "object", "format": "signature", "title": "Signature"}, properties["signature"])
+	assert.Equal(t, []any{"head_name", "members", "has_water"}, form.Schema["required"])
+	for _, name := range []string{"start", "total", "location", "rating", "household", "thanks"} {
+		assert.NotContains(t, properties, name)
+	}
+
+	// Top-level questions and groups become pages
+	assert.Equal(t, "SwipeLayout", form.UI["type"])
+	pages := form.UI["elements"].([]any)
+	require.Len(t, pages, 7)
+	assert.Equal(t, map[string]any{"type": "Control", "scope": "#/properties/water_source", "options": map[string]any{"multi": true}}, pages[3])
+	assert.Equal(t, map[string]any{"type": "VerticalLayout", "elements": []any{
+		map[string]any{"type": "Label", "text": "Household"},
+		map[string]any{"type": "Control", "scope": "#/properties/assets"},
+		map[string]any{"type": "Control", "scope": "#/properties/member", "options": map[string]any{"detail": map[string]any{
+			"type": "VerticalLayout",
+			"elements": []any{
+				map[string]any{"type": "Control", "scope": "#/properties/member_name"},
+				map[string]any{"type": "Control", "scope": "#/properties/weight"},
+			},
+		}}},
+	}}, pages[4])
+	assert.Equal(t, map[string]any{"type": "Label", "text": "Thank you, ${head_name}"}, pages[5])
+
+	var issues []string
+	for _, issue := range form.Unsupported {
+		issues = append(issues, fmt.Sprintf("%s:%d %s", issue.Sheet, issue.Row, issue.Message))
+	}
+	assert.Equal(t, []string{
+		`settings:2 setting "instance_name" is not converted`,
+		"survey:1 only the English (en) labels are converted; the form also has Français (fr)",
+		`choices:6 choice "tv" appears more than once in list "assets"`,
+		"survey:2 start metadata is not converted",
+		`survey:6 relevant condition "${has_water} = 'no'" is not converted`,
+		"survey:14 label refers to other answers, which are shown as written",
+		"survey:15 calculations are not converted",
+		"survey:16 locations are not converted; the app records the location of each observation",
+		`survey:18 question type "range" is not supported`,
+	}, issues)
+}
+
+func TestConvertStructure(t *testing.T) {
+	form, err := Convert("clinic.xlsx", buildWorkbook(t, testSheet{"survey", [][]string{
+		{"type", "name", "label", "required", "constraint"},
+		{"begin_group", "visit", "Visit"},
+		{"integer", "age", "Age", "${consent} = 'yes'", ". < 120"},
+		{"select_one sites or_other", "site", "Site"},
+		{"text", "age", "Age again"},
+		{"end repeat"},
+		{"text", "", "No name"},
+	}}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "clinic", form.FormType)
+	assert.Equal(t, map[string]any{"type": "string", "title": "Site"}, form.Schema["properties"].(map[string]any)["site"])
+	assert.NotContains(t, form.Schema, "required")
+	require.Len(t, form.UI["elements"], 1)
+
+	var issues []string
+	for _, issue := range form.Unsupported {
+		issues = append(issues, fmt.Sprintf("%d %s", issue.Row, issue.Message))
+	}
+	assert.Equal(t, []string{
+		`3 constraint ". < 120" is not converted`,
+		`3 required condition "${consent} = 'yes'" is not converted; the question is optional`,
+		`4 or_other is not converted; add an other choice to list "sites"`,
+		`4 choice list "sites" is not in the choices sheet`,
+		`5 name "age" is used more than once; only the first question is converted`,
+		"6 end repeat has no matching begin repeat",
+		"7 text has no name",
+		`2 group "visit" is not closed`,
+	}, issues)
+}
+
+func TestConvertInvalid(t *testing.T) {
+	_, err := Convert("form.xlsx", []byte("type,name\ntext,a\n"))
+	assert.ErrorIs(t, err, ErrInvalidForm)
+
+	_, err = Convert("form.xlsx", buildWorkbook(t, testSheet{"Sheet1", [][]string{{"type", "name"}}}))
+	assert.ErrorContains(t, err, "no survey sheet")
+
+	_, err = Convert("form.xlsx", buildWorkbook(t, testSheet{"survey", [][]string{{"type", "label"}, {"text", "Name"}}}))
+	assert.ErrorContains(t, err, "needs type and name columns")
+}
+
+func TestReadWorkbook(t *testing.T) {
+	sheets, err := readWorkbook(buildWorkbook(t, testSheet{"Survey", [][]string{{"type", "", "name"}, {}, {"integer", "", "42"}}}))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"type", "", "name"}, nil, {"integer", "", "42"}}, sheets["survey"])
+
+	column, ok := columnIndex("AB12")
+	assert.True(t, ok)
+	assert.Equal(t, 27, column)
+	_, ok = columnIndex("12")
+	assert.False(t, ok)
+}
@@ -0,0 +1,206 @@
+package xlsform
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds each decompressed part of a workbook, so a small file cannot expand into
+// gigabytes of XML
+const maxPartSize = 32 << 20
+
+// readWorkbook reads the cell text of every sheet of an XLSX file, keyed by the lower-case sheet
+// name. Rows are indexed from 0 for row 1 of the sheet; missing rows and cells are empty.
+func readWorkbook(data []byte) (map[string][][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not an XLSX file", ErrInvalidForm)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name  string `xml:"name,attr"`
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := readPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := readPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+
+	var sharedStrings struct {
+		Items []richText `xml:"si"`
+	}
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readPart(files, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+	strs := make([]string, len(sharedStrings.Items))
+	for i, item := range sharedStrings.Items {
+		strs[i] = item.String()
+	}
+
+	sheets := make(map[string][][]string, len(workbook.Sheets))
+	for _, sheet := range workbook.Sheets {
+		target, ok := targets[sheet.RelID]
+		if !ok {
+			return nil, fmt.Errorf("%w: sheet %q has no worksheet", ErrInvalidForm, sheet.Name)
+		}
+		rows, err := readSheet(files, target, strs)
+		if err != nil {
+			return nil, err
+		}
+		sheets[strings.ToLower(strings.TrimSpace(sheet.Name))] = rows
+	}
+	return sheets, nil
+}
+
+// richText is a shared or inline string, either plain or made of formatted runs
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// readSheet reads the cell text of a worksheet part
+func readSheet(files map[string]*zip.File, name string, strs []string) ([][]string, error) {
+	var worksheet struct {
+		Rows []struct {
+			Ref   int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := readPart(files, name, &worksheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range worksheet.Rows {
+		// Rows and cells without a reference follow the previous one
+		index := len(rows)
+		if row.Ref > 0 {
+			index = row.Ref - 1
+		}
+		if index < len(rows) || index > 1<<20 {
+			return nil, fmt.Errorf("%w: row %d of %s is out of order", ErrInvalidForm, row.Ref, path.Base(name))
+		}
+		for len(rows) < index {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for _, cell := range row.Cells {
+			column := len(cells)
+			if cell.Ref != "" {
+				var ok bool
+				if column, ok = columnIndex(cell.Ref); !ok {
+					return nil, fmt.Errorf("%w: invalid cell reference %q", ErrInvalidForm, cell.Ref)
+				}
+			}
+			for len(cells) <= column {
+				cells = append(cells, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				i, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+				if err != nil || i < 0 || i >= len(strs) {
+					return nil, fmt.Errorf("%w: cell %s refers to a missing shared string", ErrInvalidForm, cell.Ref)
+				}
+				cells[column] = strs[i]
+			case "inlineStr":
+				cells[column] = cell.Inline.String()
+			case "b":
+				cells[column] = strconv.FormatBool(cell.Value == "1")
+			default:
+				cells[column] = cell.Value
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12"
+func columnIndex(ref string) (int, bool) {
+	column := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return 0, false
+	}
+	return column - 1, true
+}
+
+// readPart decodes an XML part of the archive
+func readPart(files map[string]*zip.File, name string, v any) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: %s is missing, the file is not an XLSX workbook", ErrInvalidForm, name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: cannot read %s: %v", ErrInvalidForm, name, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxPartSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: cannot read %s: %v", ErrInvalidForm, name, err)
+	}
+	if len(data) > maxPartSize {
+		return fmt.Errorf("%w: %s is too large", ErrInvalidForm, name)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: invalid XML in %s: %v", ErrInvalidForm, name, err)
+	}
+	return nil
+}